@@ -1,126 +1,391 @@
 package analysis
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"regexp"
 	"time"
 
 	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/debugtrace"
+	huskydocker "github.com/huskyci-org/huskyCI/api/dockers"
+	"github.com/huskyci-org/huskyCI/api/export"
+	"github.com/huskyci-org/huskyCI/api/gitauth"
 	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/remediation"
 	"github.com/huskyci-org/huskyCI/api/securitytest"
+	"github.com/huskyci-org/huskyCI/api/statusreport"
+	"github.com/huskyci-org/huskyCI/api/tracing"
 	"github.com/huskyci-org/huskyCI/api/types"
 	"github.com/huskyci-org/huskyCI/api/util"
 	apiUtil "github.com/huskyci-org/huskyCI/api/util/api"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const logActionStart = "StartAnalysis"
 const logInfoAnalysis = "ANALYSIS"
 
-// StartAnalysis starts the analysis given a RID and a repository.
-func StartAnalysis(RID string, repository types.Repository) {
+// LockTTL is how long the lock taken by ReceiveRequest for a repository and
+// branch is kept if the analysis never gets registered.
+const LockTTL = 2 * time.Minute
+
+// LockName returns the name of the distributed lock that prevents two API
+// replicas from starting an analysis for the same repository and branch.
+func LockName(repository types.Repository) string {
+	return fmt.Sprintf("analysis:%s:%s", repository.URL, repository.Branch)
+}
+
+// ErrInvalidType is returned by ResolveType for an analysis type other than
+// git or upload.
+var ErrInvalidType = errors.New("analysisType must be git or upload")
+
+// ErrInvalidUpload is returned by ResolveType for an upload analysis without
+// a valid RID its zip was uploaded under.
+var ErrInvalidUpload = errors.New("uploadID must be the RID the zip was uploaded under")
+
+var uploadIDRegexp = regexp.MustCompile(`^[-a-zA-Z0-9]+$`)
+
+// ErrFileURL is returned by ResolveType for a git analysis of a file:// URL.
+var ErrFileURL = errors.New("file:// URLs can only be analyzed as uploads")
+
+// ResolveType sets the AnalysisType of repository, and its UploadID and URL
+// for an upload analysis. A file://<RID> URL without a type, as sent by older
+// clients, is an upload analysis of the zip uploaded under RID.
+func ResolveType(repository *types.Repository) error {
+	if repository.AnalysisType == "" {
+		repository.AnalysisType = types.AnalysisTypeGit
+		if util.IsFileURL(repository.URL) {
+			repository.AnalysisType = types.AnalysisTypeUpload
+		}
+	}
+	switch repository.AnalysisType {
+	case types.AnalysisTypeGit:
+		if util.IsFileURL(repository.URL) {
+			return ErrFileURL
+		}
+		repository.UploadID = ""
+		return nil
+	case types.AnalysisTypeUpload:
+		if repository.UploadID == "" {
+			repository.UploadID = util.ExtractRIDFromFileURL(repository.URL)
+		}
+		if !uploadIDRegexp.MatchString(repository.UploadID) {
+			return ErrInvalidUpload
+		}
+		repository.URL = util.UploadURL(repository.UploadID)
+		return nil
+	default:
+		return ErrInvalidType
+	}
+}
+
+// ErrEnryTree is returned by CheckEnryOutput for the Enry output of a git
+// analysis without the commit and the hash of the tree it was computed on.
+var ErrEnryTree = errors.New("enryOutput of a git analysis requires its commit and the hash of its tree in enryTree")
+
+// ErrEnryOutput is returned by CheckEnryOutput for an Enry output that does
+// not map languages to files.
+var ErrEnryOutput = errors.New("enryOutput must map each language to its files")
+
+var treeRegexp = regexp.MustCompile(`^([0-9a-fA-F]{40}|[0-9a-fA-F]{64})$`)
+
+// CheckEnryOutput validates the Enry output the client of repository sent to
+// skip running Enry. For a git analysis, it must come with the commit and the
+// hash of the tree it was computed on.
+func CheckEnryOutput(repository types.Repository) error {
+	if repository.EnryOutput == "" {
+		return nil
+	}
+	if repository.AnalysisType == types.AnalysisTypeGit && (repository.Commit == "" || !treeRegexp.MatchString(repository.EnryTree)) {
+		return ErrEnryTree
+	}
+	languages := map[string][]string{}
+	if err := json.Unmarshal([]byte(repository.EnryOutput), &languages); err != nil {
+		return ErrEnryOutput
+	}
+	return nil
+}
+
+// StartAnalysis starts the analysis given a RID and a repository. The
+// context of the request that triggered it is only used to link traces.
+func StartAnalysis(requestCtx context.Context, RID string, repository types.Repository) {
+	runAnalysis(requestCtx, RID, repository, func() error {
+		return registerNewAnalysis(RID, repository)
+	})
+}
+
+// runAnalysis runs the analysis RID of repository once register made it
+// running in the database.
+func runAnalysis(requestCtx context.Context, RID string, repository types.Repository, register func() error) {
+	ctx, span := tracing.StartLinkedSpan(requestCtx, logActionStart,
+		attribute.String("huskyci.rid", RID),
+		attribute.String("huskyci.repository", repository.URL),
+		attribute.String("huskyci.branch", repository.Branch))
+	defer span.End()
+	ctx, done := trackCancellation(ctx, RID)
+	defer done()
+	// the analysis queued for the branch starts once this one is over, even
+	// if it could not be registered or its results could not be
+	defer startNextIfQueueing(repository)
+
 	// step 1: create a new analysis into MongoDB based on repository received
-	if err := registerNewAnalysis(RID, repository); err != nil {
+	err := register()
+	// the running analysis is now visible to every replica, so the lock can go
+	if errLock := apiContext.APIConfiguration.DBInstance.ReleaseLock(LockName(repository), RID); errLock != nil {
+		log.Error(logActionStart, logInfoAnalysis, 2018, errLock)
+	}
+	if err != nil {
 		return
 	}
 	log.Info(logActionStart, logInfoAnalysis, 101, RID)
+	broadcast(types.AnalysisEvent{Type: EventCreated, RID: RID, URL: repository.URL})
 
 	// step 2: run enry as huskyCI initial step
-	enryScan := securitytest.SecTestScanInfo{}
+	policy := repositoryPolicy(repository)
+	enryScan := securitytest.SecTestScanInfo{
+		Ctx:               ctx,
+		Commit:            repository.Commit,
+		PullRequest:       repository.PullRequest,
+		UploadID:          repository.UploadID,
+		IgnoreRules:       securitytest.IgnoreRules(policy),
+		SeverityOverrides: securitytest.SeverityOverrides(policy),
+		SecurityTests:     securitytest.SelectSecurityTests(repository.SecurityTests),
+	}
 	enryScan.SecurityTestName = "enry"
-	allScansResults := securitytest.RunAllInfo{}
+	testProgress := progress{RID: RID, URL: repository.URL}
+	allScansResults := securitytest.RunAllInfo{OnTestFinished: testProgress.testFinished}
 
-	defer func() {
-		err := registerFinishedAnalysis(RID, &allScansResults)
-		if err != nil {
-			log.Error(logActionStart, logInfoAnalysis, 2011, err)
-		}
-	}()
+	defer finishAnalysis(ctx, RID, repository.URL, &allScansResults)
 
-	infrastructureSelected, hasSelected := os.LookupEnv("HUSKYCI_INFRASTRUCTURE_USE")
-	if !hasSelected {
-		err := errors.New("HUSKYCI_INFRASTRUCTURE_USE environment variable not set")
+	infrastructureSelected, apiHost, err := analysisHost()
+	if err != nil {
 		log.Error(logActionStart, logInfoAnalysis, 2011, err)
 		return
 	}
 
-	var apiHost string
+	upload := repository.AnalysisType == types.AnalysisTypeUpload
+	removeVolume, err := mountUpload(infrastructureSelected, apiHost, RID, repository)
+	if err != nil {
+		log.Error(logActionStart, logInfoAnalysis, 3030, RID, err)
+		allScansResults.SetAnalysisError(err)
+		return
+	}
+	defer removeVolume()
+
+	log.Info("StartAnalysisTest", apiHost, 2012, RID)
 
-	if infrastructureSelected == "docker" {
-		dockerAPIHost, err := apiContext.APIConfiguration.DBInstance.FindAndModifyDockerAPIAddresses()
-		if err != nil {
-			log.Error(logActionStart, logInfoAnalysis, 2011, err)
+	if err := enryScan.New(RID, repository.URL, repository.Branch, enryScan.SecurityTestName, repository.LanguageExclusions, apiHost); err != nil {
+		log.Error(logActionStart, logInfoAnalysis, 2011, err)
+		return
+	}
+	if err := enryScan.RefreshGitMirror(); err != nil {
+		// the securityTests then clone the repository without the mirror
+		log.Warning(logActionStart, logInfoAnalysis, 135, RID, err)
+	}
+	if !knownLanguages(&enryScan, repository) {
+		if err := enryScan.Start(); err != nil {
+			allScansResults.SetAnalysisError(err)
 			return
 		}
+		if !upload {
+			enryScan.CacheEnryOutput(enryCommit(repository))
+		}
+	}
 
-		configAPI, err := apiContext.DefaultConf.GetAPIConfig()
+	// step 3: run generic and languages security tests based on enryScan result in parallel
+	broadcast(types.AnalysisEvent{Type: EventStarted, RID: RID, URL: repository.URL})
+	if err := allScansResults.Start(enryScan); err != nil {
+		allScansResults.SetAnalysisError(err)
+		return
+	}
+
+	log.Info("StartAnalysis", logInfoAnalysis, 102, RID)
+}
+
+// finishAnalysis registers the results of the analysis RID of the repository
+// URL, unless it was cancelled, and reports them. The findings of a pull
+// request are also compared with those of its target branch.
+func finishAnalysis(ctx context.Context, RID, URL string, results *securitytest.RunAllInfo) {
+	if !cancelled(ctx) {
+		err := registerFinishedAnalysis(RID, results)
 		if err != nil {
 			log.Error(logActionStart, logInfoAnalysis, 2011, err)
 			return
 		}
+		publish(types.AnalysisEvent{Type: EventStatus, RID: RID, URL: URL, Status: results.Status, Result: results.FinalResult})
+	}
+	analysis, err := apiContext.APIConfiguration.DBInstance.FindOneDBAnalysis(map[string]interface{}{"RID": RID})
+	if err != nil {
+		log.Error(logActionStart, logInfoAnalysis, 1052, err)
+		return
+	}
+	reportStatus(analysis)
+	if !cancelled(ctx) {
+		diffTarget(analysis)
+		openRemediation(analysis)
+		exportFindings(analysis)
+	}
+}
 
-		apiHost, err = apiUtil.FormatDockerHostAddress(dockerAPIHost, configAPI)
+// analysisHost returns the infrastructure the securityTests run on, set by
+// HUSKYCI_INFRASTRUCTURE_USE, and the address of the Docker API or of the
+// Kubernetes cluster running them.
+func analysisHost() (string, string, error) {
+	infrastructureSelected, hasSelected := os.LookupEnv("HUSKYCI_INFRASTRUCTURE_USE")
+	if !hasSelected {
+		return "", "", errors.New("HUSKYCI_INFRASTRUCTURE_USE environment variable not set")
+	}
+
+	switch infrastructureSelected {
+	case "docker":
+		dockerAPIHost, err := apiContext.APIConfiguration.DBInstance.FindAndModifyDockerAPIAddresses()
 		if err != nil {
-			log.Error(logActionStart, logInfoAnalysis, 2011, err)
-			return
+			return "", "", err
+		}
+		// the hosts may have been changed through another API replica
+		huskydocker.SetHostCertPaths(dockerAPIHost.Hosts)
+		configAPI, err := apiContext.DefaultConf.GetAPIConfig()
+		if err != nil {
+			return "", "", err
+		}
+		apiHost, err := apiUtil.FormatDockerHostAddress(dockerAPIHost, configAPI)
+		if err != nil {
+			return "", "", err
 		}
-	} else if infrastructureSelected == "kubernetes" {
+		return infrastructureSelected, apiHost, nil
+	case "kubernetes":
 		// Assume that the Kubernetes host is set properly in the configuration or environment variables
 		// Implement any specific logic to get the Kubernetes API host if needed
-		apiHost = "kubernetes.default.svc" // Example host, replace with actual logic if needed
-	} else {
-		err := errors.New("invalid HUSKYCI_INFRASTRUCTURE_USE value")
-		log.Error(logActionStart, logInfoAnalysis, 2011, err)
-		return
+		return infrastructureSelected, "kubernetes.default.svc", nil // Example host, replace with actual logic if needed
+	default:
+		return "", "", errors.New("invalid HUSKYCI_INFRASTRUCTURE_USE value")
 	}
+}
 
-	log.Info("StartAnalysisTest", apiHost, 2012, RID)
+// mountUpload extracts the code uploaded for repository into a volume only
+// the analysis RID mounts, unless it is copied into each of its containers,
+// and returns the function removing that volume.
+func mountUpload(infrastructureSelected, apiHost, RID string, repository types.Repository) (func(), error) {
+	upload := repository.AnalysisType == types.AnalysisTypeUpload
+	if infrastructureSelected != "docker" || !upload || apiContext.APIConfiguration.DockerHostsConfig.CopiesCode() {
+		return func() {}, nil
+	}
+	zipPath := util.GetZipFilePath(repository.UploadID)
+	if err := huskydocker.CreateAnalysisVolume(apiHost, RID, zipPath); err != nil {
+		return nil, err
+	}
+	return func() { huskydocker.RemoveAnalysisVolume(apiHost, RID) }, nil
+}
+
+// repositoryPolicy returns the policy of repository, which is empty if it has
+// none.
+func repositoryPolicy(repository types.Repository) types.Policy {
+	if repository.AnalysisType == types.AnalysisTypeUpload {
+		return types.Policy{}
+	}
+	policyQuery := map[string]interface{}{"repositoryURL": gitauth.NormalizeURL(repository.URL)}
+	policy, err := apiContext.APIConfiguration.DBInstance.FindOneDBPolicy(policyQuery)
+	if err != nil {
+		if err != mongo.ErrNoDocuments && err.Error() != "No data found" {
+			log.Error(logActionStart, logInfoAnalysis, 1063, err)
+		}
+		return types.Policy{}
+	}
+	return policy
+}
 
-	// For file:// URLs, check if Enry output was provided by CLI
-	// This avoids docker-in-docker issues where Enry can't see extracted files
-	if util.IsFileURL(repository.URL) && repository.EnryOutput != "" {
-		log.Info(logActionStart, logInfoAnalysis, 16, fmt.Sprintf("Using Enry output provided by CLI for file:// URL: %s", repository.URL))
-		// Parse the provided Enry output and populate enryScan.Codes directly
+// knownLanguages sets the Codes of enryScan from the Enry output sent by the
+// client or from the one cached for the commit analyzed, and returns false if
+// Enry has to run.
+func knownLanguages(enryScan *securitytest.SecTestScanInfo, repository types.Repository) bool {
+	if repository.EnryOutput != "" {
+		// This avoids docker-in-docker issues where Enry can't see extracted files
 		if err := enryScan.ParseProvidedEnryOutput(repository.EnryOutput, repository.LanguageExclusions); err != nil {
 			log.Error(logActionStart, logInfoAnalysis, 2011, fmt.Errorf("failed to parse provided Enry output: %w", err))
-			// Fall back to running Enry if parsing fails
 			log.Info(logActionStart, logInfoAnalysis, 16, "Falling back to running Enry in container")
-		} else {
-			log.Info(logActionStart, logInfoAnalysis, 16, fmt.Sprintf("Successfully parsed %d languages from provided Enry output", len(enryScan.Codes)))
-			// Skip running Enry since we have the output
-			goto skipEnryRun
+			return false
 		}
+		log.Info(logActionStart, logInfoAnalysis, 16, fmt.Sprintf("Using %d languages from the Enry output provided by the client", len(enryScan.Codes)))
+		debugtrace.Record(debugtrace.Lifecycle, "enry", "provided", enryScan.RID, "commit", repository.Commit, "tree", repository.EnryTree)
+		return true
 	}
-
-	if err := enryScan.New(RID, repository.URL, repository.Branch, enryScan.SecurityTestName, repository.LanguageExclusions, apiHost); err != nil {
-		log.Error(logActionStart, logInfoAnalysis, 2011, err)
-		return
+	if repository.AnalysisType == types.AnalysisTypeGit && enryScan.LoadCachedEnryOutput(enryCommit(repository)) {
+		log.Info(logActionStart, logInfoAnalysis, 16, fmt.Sprintf("Using %d languages from the Enry output cached for commit %s", len(enryScan.Codes), repository.Commit))
+		debugtrace.Record(debugtrace.Lifecycle, "enry", "cached", enryScan.RID, "commit", repository.Commit)
+		return true
 	}
-	if err := enryScan.Start(); err != nil {
-		allScansResults.SetAnalysisError(err)
-		return
+	return false
+}
+
+// enryCommit returns the commit the Enry output of repository is cached for,
+// or an empty string for a pull request, whose merge is analyzed instead.
+func enryCommit(repository types.Repository) string {
+	if repository.PullRequest != nil {
+		return ""
 	}
+	return repository.Commit
+}
 
-skipEnryRun:
+// reportStatus posts the result of analysis to the code review tool of its
+// repository, if it has a status reporter.
+func reportStatus(analysis types.Analysis) {
+	reported, err := statusreport.Report(context.Background(), analysis)
+	switch {
+	case errors.Is(err, statusreport.ErrNoCommit):
+		log.Warning(logActionStart, logInfoAnalysis, 119, analysis.RID)
+	case err != nil:
+		log.Error(logActionStart, logInfoAnalysis, 1052, err)
+	case reported:
+		log.Info(logActionStart, logInfoAnalysis, 49, analysis.RID)
+	}
+}
 
-	// step 3: run generic and languages security tests based on enryScan result in parallel
-	if err := allScansResults.Start(enryScan); err != nil {
-		allScansResults.SetAnalysisError(err)
+// openRemediation opens a pull request upgrading the vulnerable dependencies
+// found by a finished analysis, if its repository opted in.
+func openRemediation(analysis types.Analysis) {
+	if analysis.Status != "finished" {
 		return
 	}
+	pullRequestURL, err := remediation.Open(context.Background(), analysis)
+	switch {
+	case errors.Is(err, remediation.ErrAlreadyOpen):
+		log.Warning(logActionStart, logInfoAnalysis, 120, analysis.RID)
+	case err != nil:
+		log.Error(logActionStart, logInfoAnalysis, 1056, err)
+	case pullRequestURL != "":
+		log.Info(logActionStart, logInfoAnalysis, 50, analysis.RID, pullRequestURL)
+	}
+}
 
-	log.Info("StartAnalysis", logInfoAnalysis, 102, RID)
+// exportFindings queues the findings of a finished analysis to be exported,
+// if an export is configured.
+func exportFindings(analysis types.Analysis) {
+	exporter := apiContext.APIConfiguration.Exporter
+	if exporter == nil || analysis.Status != "finished" {
+		return
+	}
+	if dropped := exporter.Enqueue(export.Records(analysis, securitytest.Findings(analysis.HuskyCIResults))); dropped > 0 {
+		log.Warning(logActionStart, logInfoAnalysis, 125, analysis.RID, dropped)
+	}
 }
 
 func registerNewAnalysis(RID string, repository types.Repository) error {
 
 	newAnalysis := types.Analysis{
-		RID:       RID,
-		URL:       repository.URL,
-		Branch:    repository.Branch,
-		Status:    "running",
-		StartedAt: time.Now(),
+		RID:             RID,
+		URL:             repository.URL,
+		Branch:          repository.Branch,
+		Commit:          repository.Commit,
+		PullRequest:     repository.PullRequest,
+		Status:          "running",
+		StartedAt:       time.Now(),
+		IdempotencyKey:  repository.Idempotency.Key,
+		IdempotencyHash: repository.Idempotency.Hash,
 	}
 
 	if err := apiContext.APIConfiguration.DBInstance.InsertDBAnalysis(newAnalysis); err != nil {
@@ -133,7 +398,8 @@ func registerNewAnalysis(RID string, repository types.Repository) error {
 }
 
 func registerFinishedAnalysis(RID string, allScanResults *securitytest.RunAllInfo) error {
-	analysisQuery := map[string]interface{}{"RID": RID}
+	// an analysis cancelled meanwhile keeps its cancelled status
+	analysisQuery := map[string]interface{}{"RID": RID, "status": "running"}
 	var errorString string
 	if _, ok := allScanResults.ErrorFound.(error); ok {
 		errorString = allScanResults.ErrorFound.Error()
@@ -148,6 +414,7 @@ func registerFinishedAnalysis(RID string, allScanResults *securitytest.RunAllInf
 		"huskyciresults": allScanResults.HuskyCIResults,
 		"codes":          allScanResults.Codes,
 		"errorFound":     errorString,
+		"warnings":       allScanResults.Warnings,
 		"finishedAt":     time.Now(),
 	}
 
@@ -155,5 +422,6 @@ func registerFinishedAnalysis(RID string, allScanResults *securitytest.RunAllInf
 		log.Error("registerFinishedAnalysis", logInfoAnalysis, 2011, err)
 		return err
 	}
+	invalidate(RID)
 	return nil
 }
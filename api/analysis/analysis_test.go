@@ -0,0 +1,89 @@
+package analysis_test
+
+import (
+	"github.com/huskyci-org/huskyCI/api/analysis"
+	"github.com/huskyci-org/huskyCI/api/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ResolveType", func() {
+
+	Context("When no type is set", func() {
+		It("Should analyze a git repository", func() {
+			repository := types.Repository{URL: "https://github.com/huskyci-org/huskyCI.git", UploadID: "abc"}
+			Expect(analysis.ResolveType(&repository)).To(Succeed())
+			Expect(repository.AnalysisType).To(Equal(types.AnalysisTypeGit))
+			Expect(repository.UploadID).To(BeEmpty())
+		})
+		It("Should analyze the upload of a file:// URL", func() {
+			repository := types.Repository{URL: "file://a1b2-c3d4"}
+			Expect(analysis.ResolveType(&repository)).To(Succeed())
+			Expect(repository.AnalysisType).To(Equal(types.AnalysisTypeUpload))
+			Expect(repository.UploadID).To(Equal("a1b2-c3d4"))
+		})
+	})
+
+	Context("When the type is upload", func() {
+		It("Should set the URL of the upload", func() {
+			repository := types.Repository{AnalysisType: types.AnalysisTypeUpload, UploadID: "a1b2-c3d4"}
+			Expect(analysis.ResolveType(&repository)).To(Succeed())
+			Expect(repository.URL).To(Equal("file://a1b2-c3d4"))
+		})
+		It("Should refuse a missing or invalid uploadID", func() {
+			repository := types.Repository{AnalysisType: types.AnalysisTypeUpload}
+			Expect(analysis.ResolveType(&repository)).To(MatchError(analysis.ErrInvalidUpload))
+			repository = types.Repository{AnalysisType: types.AnalysisTypeUpload, UploadID: "../../etc"}
+			Expect(analysis.ResolveType(&repository)).To(MatchError(analysis.ErrInvalidUpload))
+		})
+	})
+
+	Context("When the type is git", func() {
+		It("Should refuse a file:// URL", func() {
+			repository := types.Repository{AnalysisType: types.AnalysisTypeGit, URL: "file:///etc"}
+			Expect(analysis.ResolveType(&repository)).To(MatchError(analysis.ErrFileURL))
+		})
+	})
+
+	Context("When the type is unknown", func() {
+		It("Should return ErrInvalidType", func() {
+			repository := types.Repository{AnalysisType: "svn", URL: "https://github.com/huskyci-org/huskyCI.git"}
+			Expect(analysis.ResolveType(&repository)).To(MatchError(analysis.ErrInvalidType))
+		})
+	})
+})
+
+var _ = Describe("CheckEnryOutput", func() {
+
+	enryOutput := `{"Go":["main.go"]}`
+	tree := "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+	Context("When a git analysis sends the commit and tree of its Enry output", func() {
+		It("Should accept it", func() {
+			repository := types.Repository{AnalysisType: types.AnalysisTypeGit, EnryOutput: enryOutput, Commit: "a1b2c3d", EnryTree: tree}
+			Expect(analysis.CheckEnryOutput(repository)).To(Succeed())
+		})
+	})
+
+	Context("When a git analysis sends an Enry output without its tree", func() {
+		It("Should return ErrEnryTree", func() {
+			repository := types.Repository{AnalysisType: types.AnalysisTypeGit, EnryOutput: enryOutput, Commit: "a1b2c3d"}
+			Expect(analysis.CheckEnryOutput(repository)).To(MatchError(analysis.ErrEnryTree))
+		})
+	})
+
+	Context("When an upload analysis sends an Enry output", func() {
+		It("Should accept it without a tree", func() {
+			repository := types.Repository{AnalysisType: types.AnalysisTypeUpload, EnryOutput: enryOutput}
+			Expect(analysis.CheckEnryOutput(repository)).To(Succeed())
+		})
+	})
+
+	Context("When the Enry output does not map languages to files", func() {
+		It("Should return ErrEnryOutput", func() {
+			repository := types.Repository{AnalysisType: types.AnalysisTypeUpload, EnryOutput: `{"Go":"main.go"}`}
+			Expect(analysis.CheckEnryOutput(repository)).To(MatchError(analysis.ErrEnryOutput))
+		})
+	})
+})
@@ -0,0 +1,93 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/types"
+)
+
+const logActionCancel = "CancelAnalysis"
+
+// StatusCancelled is the status of an analysis cancelled before it finished.
+const StatusCancelled = "cancelled"
+
+// CancelCheckInterval is how often a running analysis checks whether it was
+// cancelled through another API replica.
+var CancelCheckInterval = 10 * time.Second
+
+var running = struct {
+	sync.Mutex
+	cancels map[string]context.CancelFunc
+}{cancels: map[string]context.CancelFunc{}}
+
+// Cancel marks the analysis RID, running or queued as status tells, as
+// cancelled. The securityTests not started yet are skipped, while the
+// containers already running are left to finish or time out. The analysis is
+// stopped right away when it runs on this replica and within
+// CancelCheckInterval otherwise, and a queued one never starts.
+func Cancel(RID, status string) error {
+	analysisQuery := map[string]interface{}{"RID": RID, "status": status}
+	cancelQuery := map[string]interface{}{
+		"status":     StatusCancelled,
+		"result":     StatusCancelled,
+		"errorFound": "analysis cancelled by the client",
+		"finishedAt": time.Now(),
+	}
+	if err := apiContext.APIConfiguration.DBInstance.UpdateOneDBAnalysisContainer(analysisQuery, cancelQuery); err != nil {
+		return err
+	}
+	invalidate(RID)
+
+	running.Lock()
+	cancel, found := running.cancels[RID]
+	running.Unlock()
+	if found {
+		cancel()
+	}
+	publish(types.AnalysisEvent{Type: EventStatus, RID: RID, Status: StatusCancelled, Result: StatusCancelled})
+	log.Info(logActionCancel, logInfoAnalysis, 40, RID)
+	return nil
+}
+
+// trackCancellation returns a context of ctx that is done when the analysis RID
+// is cancelled, and the function to call once the analysis is over.
+func trackCancellation(ctx context.Context, RID string) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	running.Lock()
+	running.cancels[RID] = cancel
+	running.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(CancelCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			analysis, err := apiContext.APIConfiguration.DBInstance.FindOneDBAnalysisSummary(map[string]interface{}{"RID": RID})
+			if err == nil && analysis.Status == StatusCancelled {
+				cancel()
+				return
+			}
+		}
+	}()
+
+	return ctx, func() {
+		running.Lock()
+		delete(running.cancels, RID)
+		running.Unlock()
+		cancel()
+	}
+}
+
+// cancelled returns true if the analysis of ctx was cancelled.
+func cancelled(ctx context.Context) bool {
+	return errors.Is(ctx.Err(), context.Canceled)
+}
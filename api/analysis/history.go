@@ -0,0 +1,121 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/types"
+)
+
+const logActionHistory = "ReadHistory"
+
+// HistorySize is how many of the last finished analyses the durations of an
+// analysis and of its securityTests are estimated from.
+var HistorySize = 20
+
+// HistoryExpiration is how long the durations read for a repository are
+// cached, as they are read again every time a running analysis is polled.
+var HistoryExpiration = time.Minute
+
+// History is how long the last finished analyses took.
+type History struct {
+	// Analyses is how many analyses were read and Seconds their mean duration.
+	Analyses int
+	Seconds  float64
+	// Tools is the mean duration of each securityTest.
+	Tools map[string]float64
+	// Last is the securityTests the last analysis ran.
+	Last []string
+}
+
+// recentHistory returns the History of the last finished analyses of URL, or
+// of any repository if anyRepository is true and URL was never analyzed.
+func recentHistory(URL string, anyRepository bool) History {
+	cacheKey := fmt.Sprintf("history:%t:%s", anyRepository, URL)
+	cache := apiContext.APIConfiguration.Cache
+	if cache != nil {
+		if history, found := cache.Get(cacheKey); found {
+			return history.(History)
+		}
+	}
+
+	database := apiContext.APIConfiguration.DBInstance
+	filter := types.AnalysisFilter{URL: URL, Status: "finished", SortField: "finishedAt", SortDescending: true, Page: 1, PageSize: HistorySize}
+	recent, _, err := database.FindPageDBAnalysis(filter)
+	if err == nil && len(recent) == 0 && anyRepository {
+		filter.URL = ""
+		recent, _, err = database.FindPageDBAnalysis(filter)
+	}
+	if err != nil {
+		log.Error(logActionHistory, logInfoAnalysis, 1075, err)
+	}
+
+	history := History{Tools: map[string]float64{}, Last: []string{}}
+	counts := map[string]int{}
+	for i, summary := range recent {
+		if summary.StartedAt.IsZero() || summary.FinishedAt.Before(summary.StartedAt) {
+			continue
+		}
+		analysis, err := database.FindOneDBAnalysis(map[string]interface{}{"RID": summary.RID})
+		if err != nil {
+			continue
+		}
+		history.Analyses++
+		history.Seconds += summary.FinishedAt.Sub(summary.StartedAt).Seconds()
+		for _, container := range analysis.Containers {
+			if i == 0 {
+				history.Last = append(history.Last, container.SecurityTest.Name)
+			}
+			if container.CStatus != "finished" || container.StartedAt.IsZero() || container.FinishedAt.Before(container.StartedAt) {
+				continue
+			}
+			history.Tools[container.SecurityTest.Name] += container.FinishedAt.Sub(container.StartedAt).Seconds()
+			counts[container.SecurityTest.Name]++
+		}
+	}
+	if history.Analyses > 0 {
+		history.Seconds /= float64(history.Analyses)
+	}
+	for name, count := range counts {
+		history.Tools[name] /= float64(count)
+	}
+
+	if cache != nil && err == nil {
+		cache.Set(cacheKey, history, HistoryExpiration)
+	}
+	return history
+}
+
+// EstimateRemaining returns the seconds the running analysis should still
+// take, or false if its repository never finished an analysis. The
+// securityTests its last analysis ran that did not finish yet are expected to
+// take their mean duration, after the part of an analysis spent outside of
+// them, such as running Enry, and it is expected to take its mean duration
+// once they all finished.
+func EstimateRemaining(analysis types.Analysis, now time.Time) (int, bool) {
+	history := recentHistory(analysis.URL, false)
+	if history.Analyses == 0 {
+		return 0, false
+	}
+	finished := map[string]bool{}
+	for _, container := range analysis.Containers {
+		finished[container.SecurityTest.Name] = true
+	}
+	longest, pending := 0.0, 0.0
+	for _, name := range history.Last {
+		longest = max(longest, history.Tools[name])
+		if !finished[name] {
+			pending = max(pending, history.Tools[name])
+		}
+	}
+
+	expected := history.Seconds
+	if pending > 0 {
+		expected = max(history.Seconds-longest, 0) + pending
+	}
+	remaining := math.Ceil(expected - now.Sub(analysis.StartedAt).Seconds())
+	return int(max(remaining, 0)), true
+}
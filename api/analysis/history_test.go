@@ -0,0 +1,65 @@
+package analysis_test
+
+import (
+	"time"
+
+	"github.com/huskyci-org/huskyCI/api/analysis"
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EstimateRemaining", func() {
+
+	var previousConfig *apiContext.APIConfig
+	startedAt := time.Date(2024, 5, 3, 10, 0, 0, 0, time.UTC)
+
+	BeforeEach(func() {
+		database := &planDB{
+			analyses: []types.Analysis{
+				finishedAnalysis("a", "https://github.com/org/repo.git", 45, finished("gosec", 30), finished("gitleaks", 10)),
+				finishedAnalysis("b", "https://github.com/org/repo.git", 65, finished("gosec", 50), finished("gitauthors", 5)),
+				finishedAnalysis("c", "https://github.com/org/other.git", 110, finished("npmaudit", 100)),
+			},
+		}
+		previousConfig = apiContext.APIConfiguration
+		apiContext.APIConfiguration = &apiContext.APIConfig{DBInstance: database}
+	})
+
+	AfterEach(func() {
+		apiContext.APIConfiguration = previousConfig
+	})
+
+	Context("When the repository finished analyses before", func() {
+		It("Should expect the securityTests left to take their mean duration", func() {
+			running := types.Analysis{URL: "https://github.com/org/repo.git", Status: "running", StartedAt: startedAt}
+			remaining, estimated := analysis.EstimateRemaining(running, startedAt.Add(20*time.Second))
+			Expect(estimated).To(BeTrue())
+			Expect(remaining).To(Equal(35))
+
+			running.Containers = []types.Container{finished("gitleaks", 10)}
+			remaining, _ = analysis.EstimateRemaining(running, startedAt.Add(20*time.Second))
+			Expect(remaining).To(Equal(35))
+
+			running.Containers = []types.Container{finished("gosec", 20)}
+			remaining, _ = analysis.EstimateRemaining(running, startedAt.Add(20*time.Second))
+			Expect(remaining).To(Equal(5))
+		})
+		It("Should not go below zero once the analysis takes longer", func() {
+			running := types.Analysis{URL: "https://github.com/org/repo.git", Status: "running", StartedAt: startedAt}
+			remaining, estimated := analysis.EstimateRemaining(running, startedAt.Add(2*time.Minute))
+			Expect(estimated).To(BeTrue())
+			Expect(remaining).To(BeZero())
+		})
+	})
+
+	Context("When the repository was never analyzed", func() {
+		It("Should not estimate anything", func() {
+			running := types.Analysis{URL: "https://github.com/org/new.git", Status: "running", StartedAt: startedAt}
+			_, estimated := analysis.EstimateRemaining(running, startedAt.Add(20*time.Second))
+			Expect(estimated).To(BeFalse())
+		})
+	})
+})
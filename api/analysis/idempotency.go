@@ -0,0 +1,54 @@
+package analysis
+
+import (
+	"errors"
+	"time"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/types"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const logActionReplay = "ReplayAnalysis"
+
+// ErrIdempotencyKeyReused is returned by Replay when the Idempotency-Key of a
+// request already started an analysis of another request.
+var ErrIdempotencyKeyReused = errors.New("idempotency key reused with another request")
+
+// Replay returns the RID of the analysis of repository started by a request
+// with the same Idempotency-Key and body less than IdempotencyTTL ago, or an
+// empty RID if there is none, and then the request starts a new analysis. A
+// key found on an older analysis is cleared from it, so that it can be used
+// again. Trigger stores the key with the analysis while it holds the lock of
+// the branch, so the same request sent again before then is told the
+// analysis of the branch is being started rather than given its RID.
+func Replay(repository types.Repository) (string, error) {
+	if repository.Idempotency.Key == "" {
+		return "", nil
+	}
+	database := apiContext.APIConfiguration.DBInstance
+	query := map[string]interface{}{"idempotencyKey": repository.Idempotency.Key, "repositoryURL": repository.URL}
+	started, err := database.FindOneDBAnalysis(query)
+	if err != nil {
+		if err == mongo.ErrNoDocuments || err.Error() == "No data found" {
+			return "", nil
+		}
+		log.Error(logActionReplay, logInfoAnalysis, 2020, err)
+		return "", err
+	}
+	if time.Since(started.StartedAt) > apiContext.APIConfiguration.IdempotencyTTL {
+		cleared := map[string]interface{}{"idempotencyKey": "", "idempotencyHash": ""}
+		if err := database.UpdateOneDBAnalysis(map[string]interface{}{"RID": started.RID}, cleared); err != nil {
+			log.Error(logActionReplay, logInfoAnalysis, 2020, err)
+			return "", err
+		}
+		return "", nil
+	}
+	if started.IdempotencyHash != repository.Idempotency.Hash {
+		log.Warning(logActionReplay, logInfoAnalysis, 131, started.RID)
+		return "", ErrIdempotencyKeyReused
+	}
+	log.Info(logActionReplay, logInfoAnalysis, 70, started.RID)
+	return started.RID, nil
+}
@@ -0,0 +1,97 @@
+package analysis_test
+
+import (
+	"errors"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/api/analysis"
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/db"
+	"github.com/huskyci-org/huskyCI/api/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// idempotencyDB holds an analysis started with an Idempotency-Key.
+type idempotencyDB struct {
+	db.Requests
+	started *types.Analysis
+	cleared []string
+}
+
+func (i *idempotencyDB) FindOneDBAnalysis(mapParams map[string]interface{}) (types.Analysis, error) {
+	if i.started == nil || mapParams["idempotencyKey"] != i.started.IdempotencyKey || mapParams["repositoryURL"] != i.started.URL {
+		return types.Analysis{}, errors.New("No data found")
+	}
+	return *i.started, nil
+}
+
+func (i *idempotencyDB) UpdateOneDBAnalysis(mapParams, updatedAnalysis map[string]interface{}) error {
+	i.cleared = append(i.cleared, mapParams["RID"].(string))
+	return nil
+}
+
+var _ = Describe("Replay", func() {
+
+	var database *idempotencyDB
+	var previousConfig *apiContext.APIConfig
+	var repository types.Repository
+
+	BeforeEach(func() {
+		repository = types.Repository{
+			URL:         "https://github.com/org/repo.git",
+			Branch:      "main",
+			Idempotency: types.Idempotency{Key: "ci-job-42", Hash: "hash"},
+		}
+		database = &idempotencyDB{started: &types.Analysis{
+			RID:             "started",
+			URL:             repository.URL,
+			StartedAt:       time.Now().Add(-time.Hour),
+			IdempotencyKey:  "ci-job-42",
+			IdempotencyHash: "hash",
+		}}
+		previousConfig = apiContext.APIConfiguration
+		apiContext.APIConfiguration = &apiContext.APIConfig{DBInstance: database, IdempotencyTTL: 24 * time.Hour}
+	})
+
+	AfterEach(func() {
+		apiContext.APIConfiguration = previousConfig
+	})
+
+	Context("When the request has no Idempotency-Key", func() {
+		It("Should return no RID", func() {
+			repository.Idempotency = types.Idempotency{}
+			Expect(analysis.Replay(repository)).To(BeEmpty())
+		})
+	})
+
+	Context("When the same request started an analysis within the TTL", func() {
+		It("Should return its RID", func() {
+			Expect(analysis.Replay(repository)).To(Equal("started"))
+		})
+	})
+
+	Context("When the key started an analysis of another request", func() {
+		It("Should return ErrIdempotencyKeyReused", func() {
+			repository.Idempotency.Hash = "other"
+			_, err := analysis.Replay(repository)
+			Expect(err).To(MatchError(analysis.ErrIdempotencyKeyReused))
+		})
+	})
+
+	Context("When the key started an analysis of another repository", func() {
+		It("Should return no RID", func() {
+			repository.URL = "https://github.com/org/other.git"
+			Expect(analysis.Replay(repository)).To(BeEmpty())
+		})
+	})
+
+	Context("When the analysis of the key is older than the TTL", func() {
+		It("Should return no RID and clear the key", func() {
+			database.started.StartedAt = time.Now().Add(-48 * time.Hour)
+			Expect(analysis.Replay(repository)).To(BeEmpty())
+			Expect(database.cleared).To(Equal([]string{"started"}))
+		})
+	})
+})
@@ -0,0 +1,181 @@
+package analysis
+
+import (
+	"archive/zip"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/securitytest"
+	"github.com/huskyci-org/huskyCI/api/signature"
+	"github.com/huskyci-org/huskyCI/api/types"
+)
+
+const logActionPlan = "PlanAnalysis"
+
+// Where the languages of a Plan come from: the Enry output sent by the
+// client, the one cached for the commit, the files of the uploaded zip or
+// nowhere, until Enry runs.
+const (
+	LanguagesProvided = "enryOutput"
+	LanguagesCached   = "cache"
+	LanguagesZip      = "zip"
+	LanguagesUnknown  = "unknown"
+)
+
+// zipExtensions maps the extensions of the files of an uploaded zip to the
+// languages Enry names them after.
+var zipExtensions = map[string]string{
+	".go":   "Go",
+	".py":   "Python",
+	".rb":   "Ruby",
+	".js":   "JavaScript",
+	".jsx":  "JavaScript",
+	".mjs":  "JavaScript",
+	".cjs":  "JavaScript",
+	".ts":   "TypeScript",
+	".tsx":  "TypeScript",
+	".java": "Java",
+	".kt":   "Kotlin",
+	".cs":   "C#",
+	".php":  "PHP",
+	".tf":   "HCL",
+}
+
+// zipVendored are the directories of an uploaded zip Enry skips as vendored.
+var zipVendored = []string{"node_modules/", "vendor/", ".git/"}
+
+// PlannedTest is a securityTest an analysis would run. EstimatedSeconds is 0
+// when it did not run recently, and for Enry, whose containers are not kept.
+type PlannedTest struct {
+	Name             string  `json:"name"`
+	Type             string  `json:"type"`
+	Language         string  `json:"language,omitempty"`
+	Image            string  `json:"image"`
+	TimeOutInSeconds int     `json:"timeOutSeconds"`
+	EstimatedSeconds float64 `json:"estimatedSeconds"`
+}
+
+// Plan is what an analysis of a repository would run. Its securityTests run
+// at once, after Enry when Enry has to run, so EstimatedSeconds is the one of
+// the longest of them.
+type Plan struct {
+	URL              string        `json:"repositoryURL"`
+	Branch           string        `json:"repositoryBranch"`
+	AnalysisType     string        `json:"analysisType"`
+	Languages        []string      `json:"languages"`
+	LanguagesFrom    string        `json:"languagesFrom"`
+	SecurityTests    []PlannedTest `json:"securityTests"`
+	EstimatedSeconds float64       `json:"estimatedSeconds"`
+	Warnings         []string      `json:"warnings,omitempty"`
+}
+
+// NewPlan returns the Plan of an analysis of repository, without running
+// anything. The languages of an upload are read from the file names of the
+// zip at zipPath, which Enry could name differently.
+func NewPlan(repository types.Repository, zipPath string) (Plan, error) {
+	plan := Plan{URL: repository.URL, Branch: repository.Branch, AnalysisType: repository.AnalysisType, Languages: []string{}, SecurityTests: []PlannedTest{}}
+	upload := repository.AnalysisType == types.AnalysisTypeUpload
+
+	enryScan := securitytest.SecTestScanInfo{URL: repository.URL, UploadID: repository.UploadID, LanguageExclusions: repository.LanguageExclusions}
+	switch {
+	case repository.EnryOutput != "" && enryScan.ParseProvidedEnryOutput(repository.EnryOutput, repository.LanguageExclusions) == nil:
+		plan.LanguagesFrom = LanguagesProvided
+	case !upload && enryScan.LoadCachedEnryOutput(enryCommit(repository)):
+		plan.LanguagesFrom = LanguagesCached
+	case upload:
+		codes, err := ZipLanguages(zipPath, repository.LanguageExclusions)
+		if err != nil {
+			log.Error(logActionPlan, logInfoAnalysis, 1074, err)
+			return plan, err
+		}
+		enryScan.Codes = codes
+		plan.LanguagesFrom = LanguagesZip
+	default:
+		plan.LanguagesFrom = LanguagesUnknown
+		plan.Warnings = append(plan.Warnings, "the languages are only known once Enry ran: send enryOutput or the commit of a cached analysis to plan the securityTests of each language")
+	}
+	for _, code := range enryScan.Codes {
+		plan.Languages = append(plan.Languages, code.Language)
+	}
+	sort.Strings(plan.Languages)
+
+	securityTests, err := securitytest.PlannedSecurityTests(enryScan.Codes, upload, securitytest.SelectSecurityTests(repository.SecurityTests))
+	if err != nil {
+		log.Error(logActionPlan, logInfoAnalysis, 1074, err)
+		return plan, err
+	}
+	if plan.LanguagesFrom != LanguagesProvided && plan.LanguagesFrom != LanguagesCached {
+		enry, err := apiContext.APIConfiguration.DBInstance.FindOneDBSecurityTest(map[string]interface{}{"name": "enry"})
+		if err != nil {
+			log.Error(logActionPlan, logInfoAnalysis, 1074, err)
+			return plan, err
+		}
+		securityTests = append([]types.SecurityTest{enry}, securityTests...)
+	}
+
+	durations := recentHistory(repository.URL, true).Tools
+	for _, securityTest := range securityTests {
+		securityTest.Image = apiContext.APIConfiguration.OfflineConfig.MirrorImage(securityTest.Image)
+		planned := PlannedTest{
+			Name:             securityTest.Name,
+			Type:             securityTest.Type,
+			Language:         securityTest.Language,
+			Image:            signature.ImageReference(securityTest),
+			TimeOutInSeconds: securityTest.TimeOutInSeconds,
+			EstimatedSeconds: durations[securityTest.Name],
+		}
+		plan.SecurityTests = append(plan.SecurityTests, planned)
+		if securityTest.Type == "Enry" {
+			continue
+		}
+		if _, ran := durations[securityTest.Name]; !ran {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("%s did not run recently: its duration is not estimated", securityTest.Name))
+		}
+		if planned.EstimatedSeconds > plan.EstimatedSeconds {
+			plan.EstimatedSeconds = planned.EstimatedSeconds
+		}
+	}
+	return plan, nil
+}
+
+// ZipLanguages returns the languages of the files of the zip at zipPath by
+// their extension, as Enry would, but for the excluded ones.
+func ZipLanguages(zipPath string, languageExclusions map[string]bool) ([]types.Code, error) {
+	archive, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	files := map[string][]string{}
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() || vendored(file.Name) {
+			continue
+		}
+		language, found := zipExtensions[strings.ToLower(path.Ext(file.Name))]
+		if !found || languageExclusions[language] {
+			continue
+		}
+		files[language] = append(files[language], file.Name)
+	}
+
+	codes := []types.Code{}
+	for language, names := range files {
+		codes = append(codes, types.Code{Language: language, Files: names})
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i].Language < codes[j].Language })
+	return codes, nil
+}
+
+func vendored(name string) bool {
+	for _, dir := range zipVendored {
+		if strings.HasPrefix(name, dir) || strings.Contains(name, "/"+dir) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,177 @@
+package analysis_test
+
+import (
+	"archive/zip"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/api/analysis"
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/db"
+	"github.com/huskyci-org/huskyCI/api/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// planDB is a database holding securityTests and finished analyses.
+type planDB struct {
+	db.Requests
+	securityTests []types.SecurityTest
+	analyses      []types.Analysis
+}
+
+func (p *planDB) FindAllDBSecurityTest(mapParams map[string]interface{}) ([]types.SecurityTest, error) {
+	found := []types.SecurityTest{}
+	for _, securityTest := range p.securityTests {
+		if securityTest.Type == mapParams["type"] || (securityTest.Language != "" && securityTest.Language == mapParams["language"]) {
+			found = append(found, securityTest)
+		}
+	}
+	return found, nil
+}
+
+func (p *planDB) FindOneDBSecurityTest(mapParams map[string]interface{}) (types.SecurityTest, error) {
+	for _, securityTest := range p.securityTests {
+		if securityTest.Name == mapParams["name"] {
+			return securityTest, nil
+		}
+	}
+	return types.SecurityTest{}, errors.New("No data found")
+}
+
+func (p *planDB) FindPageDBAnalysis(filter types.AnalysisFilter) ([]types.AnalysisSummary, int64, error) {
+	found := []types.AnalysisSummary{}
+	for _, analysis := range p.analyses {
+		if filter.URL == "" || analysis.URL == filter.URL {
+			found = append(found, types.AnalysisSummary{RID: analysis.RID, URL: analysis.URL, StartedAt: analysis.StartedAt, FinishedAt: analysis.FinishedAt})
+		}
+	}
+	return found, int64(len(found)), nil
+}
+
+func (p *planDB) FindOneDBAnalysis(mapParams map[string]interface{}) (types.Analysis, error) {
+	for _, analysis := range p.analyses {
+		if analysis.RID == mapParams["RID"] {
+			return analysis, nil
+		}
+	}
+	return types.Analysis{}, errors.New("No data found")
+}
+
+// finished returns the container of a securityTest that ran for seconds.
+func finished(name string, seconds int) types.Container {
+	startedAt := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+	return types.Container{
+		SecurityTest: types.SecurityTest{Name: name},
+		CStatus:      "finished",
+		StartedAt:    startedAt,
+		FinishedAt:   startedAt.Add(time.Duration(seconds) * time.Second),
+	}
+}
+
+// finishedAnalysis returns an analysis of URL that finished after seconds.
+func finishedAnalysis(RID, URL string, seconds int, containers ...types.Container) types.Analysis {
+	startedAt := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+	return types.Analysis{RID: RID, URL: URL, Status: "finished", StartedAt: startedAt, FinishedAt: startedAt.Add(time.Duration(seconds) * time.Second), Containers: containers}
+}
+
+func names(tests []analysis.PlannedTest) []string {
+	found := []string{}
+	for _, test := range tests {
+		found = append(found, test.Name)
+	}
+	return found
+}
+
+var _ = Describe("NewPlan", func() {
+
+	var database *planDB
+	var previousConfig *apiContext.APIConfig
+
+	BeforeEach(func() {
+		database = &planDB{
+			securityTests: []types.SecurityTest{
+				{Name: "enry", Type: "Enry", Image: "huskyciorg/enry", ImageTag: "latest"},
+				{Name: "gitauthors", Type: "Generic", Image: "huskyciorg/gitauthors", ImageTag: "latest"},
+				{Name: "gitleaks", Type: "Generic", Image: "huskyciorg/gitleaks", ImageTag: "v8.30.0"},
+				{Name: "gosec", Type: "Language", Language: "Go", Image: "huskyciorg/gosec", ImageDigest: "sha256:0123", TimeOutInSeconds: 360},
+				{Name: "bandit", Type: "Language", Language: "Python", Image: "huskyciorg/bandit", ImageTag: "1.7"},
+				{Name: "npmaudit", Type: "Language", Language: "JavaScript", Image: "huskyciorg/npmaudit", ImageTag: "latest"},
+			},
+			analyses: []types.Analysis{
+				finishedAnalysis("a", "https://github.com/org/repo.git", 45, finished("gosec", 30), finished("gitleaks", 10)),
+				finishedAnalysis("b", "https://github.com/org/repo.git", 65, finished("gosec", 50), finished("gitauthors", 5)),
+				finishedAnalysis("c", "https://github.com/org/other.git", 110, finished("npmaudit", 100)),
+			},
+		}
+		previousConfig = apiContext.APIConfiguration
+		apiContext.APIConfiguration = &apiContext.APIConfig{DBInstance: database}
+	})
+
+	AfterEach(func() {
+		apiContext.APIConfiguration = previousConfig
+	})
+
+	Context("When the client sends the Enry output", func() {
+		It("Should plan the securityTests of its languages from the history of the repository", func() {
+			repository := types.Repository{
+				URL:                "https://github.com/org/repo.git",
+				Branch:             "main",
+				AnalysisType:       types.AnalysisTypeGit,
+				EnryOutput:         `{"Go":["main.go"],"Python":["app.py"]}`,
+				LanguageExclusions: map[string]bool{"Python": true},
+			}
+			plan, err := analysis.NewPlan(repository, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plan.LanguagesFrom).To(Equal(analysis.LanguagesProvided))
+			Expect(plan.Languages).To(Equal([]string{"Go"}))
+			Expect(names(plan.SecurityTests)).To(Equal([]string{"gitauthors", "gitleaks", "gosec"}))
+			Expect(plan.SecurityTests[2].Image).To(Equal("huskyciorg/gosec@sha256:0123"))
+			Expect(plan.SecurityTests[2].EstimatedSeconds).To(Equal(40.0))
+			Expect(plan.EstimatedSeconds).To(Equal(40.0))
+			Expect(plan.Warnings).To(BeEmpty())
+		})
+	})
+
+	Context("When the languages of a git analysis are unknown", func() {
+		It("Should plan Enry and the generic securityTests from the history of any repository", func() {
+			repository := types.Repository{URL: "https://github.com/org/new.git", Branch: "main", AnalysisType: types.AnalysisTypeGit}
+			plan, err := analysis.NewPlan(repository, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plan.LanguagesFrom).To(Equal(analysis.LanguagesUnknown))
+			Expect(plan.Languages).To(BeEmpty())
+			Expect(names(plan.SecurityTests)).To(Equal([]string{"enry", "gitauthors", "gitleaks"}))
+			Expect(plan.EstimatedSeconds).To(Equal(10.0))
+			Expect(plan.Warnings).To(HaveLen(1))
+		})
+	})
+
+	Context("When an upload is planned", func() {
+		It("Should read its languages from the zip and skip gitauthors", func() {
+			dir, err := os.MkdirTemp("", "plan")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(dir)
+			zipPath := filepath.Join(dir, "code.zip")
+			file, err := os.Create(zipPath)
+			Expect(err).NotTo(HaveOccurred())
+			archive := zip.NewWriter(file)
+			for _, name := range []string{"main.go", "web/app.JS", "vendor/lib/lib.go", "web/node_modules/dep/index.js", "app.py", "README.md"} {
+				_, err := archive.Create(name)
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(archive.Close()).To(Succeed())
+			Expect(file.Close()).To(Succeed())
+
+			repository := types.Repository{URL: "file://a1b2", Branch: "main", AnalysisType: types.AnalysisTypeUpload, UploadID: "a1b2", LanguageExclusions: map[string]bool{"Python": true}}
+			plan, err := analysis.NewPlan(repository, zipPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plan.LanguagesFrom).To(Equal(analysis.LanguagesZip))
+			Expect(plan.Languages).To(Equal([]string{"Go", "JavaScript"}))
+			Expect(names(plan.SecurityTests)).To(Equal([]string{"enry", "gitleaks", "gosec", "npmaudit"}))
+			Expect(plan.EstimatedSeconds).To(Equal(100.0))
+		})
+	})
+})
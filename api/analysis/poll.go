@@ -0,0 +1,17 @@
+package analysis
+
+import "time"
+
+// Bounds of the poll interval suggested to the clients of a running analysis.
+const (
+	MinPollHint = 5 * time.Second
+	MaxPollHint = 60 * time.Second
+)
+
+// PollHint returns how long a client should wait before polling again an
+// analysis running since startedAt. Most analyses finish within minutes, so
+// young analyses are polled often and older ones a fourth of their age apart.
+func PollHint(startedAt, now time.Time) time.Duration {
+	hint := now.Sub(startedAt) / 4
+	return min(max(hint, MinPollHint), MaxPollHint).Round(time.Second)
+}
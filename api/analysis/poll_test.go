@@ -0,0 +1,31 @@
+package analysis_test
+
+import (
+	"time"
+
+	"github.com/huskyci-org/huskyCI/api/analysis"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PollHint", func() {
+
+	now := time.Now()
+
+	Context("When the analysis has just started", func() {
+		It("Should return the minimum hint", func() {
+			Expect(analysis.PollHint(now.Add(-time.Second), now)).To(Equal(analysis.MinPollHint))
+		})
+	})
+	Context("When the analysis has been running for two minutes", func() {
+		It("Should return a fourth of its age", func() {
+			Expect(analysis.PollHint(now.Add(-2*time.Minute), now)).To(Equal(30 * time.Second))
+		})
+	})
+	Context("When the analysis has been running for an hour", func() {
+		It("Should return the maximum hint", func() {
+			Expect(analysis.PollHint(now.Add(-time.Hour), now)).To(Equal(analysis.MaxPollHint))
+		})
+	})
+})
@@ -0,0 +1,105 @@
+package analysis
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/fingerprint"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/securitytest"
+	"github.com/huskyci-org/huskyCI/api/types"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const logActionPullRequest = "DiffPullRequest"
+
+// ErrPullRequest is returned by CheckPullRequest for a pull request that
+// cannot be analyzed.
+var ErrPullRequest = errors.New("invalid pullRequest")
+
+// pullRequestBranchRegexp matches the branches of a pull request. Unlike the
+// branch of a repository, they are not passed to git after -b, so they must
+// not start with a dash to be taken for an option.
+var pullRequestBranchRegexp = regexp.MustCompile(`^[a-zA-Z0-9_\/.\+À-ÿ][a-zA-Z0-9_\/.\-\+À-ÿ]*$`)
+
+// mergeRefRegexp matches the refs a pull request is merged at, such as
+// refs/pull/42/merge on GitHub or refs/merge-requests/42/merge on GitLab.
+var mergeRefRegexp = regexp.MustCompile(`^refs/[a-zA-Z0-9_\-]+(/[a-zA-Z0-9_\-]+)*$`)
+
+// CheckPullRequest checks the pull request of repository, if it has one. Its
+// source branch is the branch of repository, which it sets when empty.
+func CheckPullRequest(repository *types.Repository) error {
+	pullRequest := repository.PullRequest
+	if pullRequest == nil {
+		return nil
+	}
+	if repository.AnalysisType != types.AnalysisTypeGit {
+		return fmt.Errorf("%w: only git analyses analyze pull requests", ErrPullRequest)
+	}
+	if pullRequest.Number < 1 {
+		return fmt.Errorf("%w: number must be positive", ErrPullRequest)
+	}
+	if pullRequest.SourceBranch == "" {
+		pullRequest.SourceBranch = repository.Branch
+	}
+	if repository.Branch == "" {
+		repository.Branch = pullRequest.SourceBranch
+	}
+	if pullRequest.SourceBranch != repository.Branch {
+		return fmt.Errorf("%w: sourceBranch must be the repositoryBranch", ErrPullRequest)
+	}
+	if !pullRequestBranchRegexp.MatchString(pullRequest.SourceBranch) || !pullRequestBranchRegexp.MatchString(pullRequest.TargetBranch) {
+		return fmt.Errorf("%w: sourceBranch and targetBranch must be valid branch names", ErrPullRequest)
+	}
+	if pullRequest.MergeRef != "" && !mergeRefRegexp.MatchString(pullRequest.MergeRef) {
+		return fmt.Errorf("%w: mergeRef must be a ref such as refs/pull/%d/merge", ErrPullRequest, pullRequest.Number)
+	}
+	return nil
+}
+
+// diffTarget compares the findings of the finished analysis of a pull request
+// with those of the latest finished analysis of its target branch, and stores
+// the TargetDiff with the analysis.
+func diffTarget(analysis types.Analysis) {
+	if analysis.PullRequest == nil || analysis.Status != "finished" {
+		return
+	}
+	database := apiContext.APIConfiguration.DBInstance
+	diff := types.TargetDiff{}
+	target := []types.HuskyCIVulnerability{}
+	filter := types.AnalysisFilter{
+		URL:            analysis.URL,
+		Branch:         analysis.PullRequest.TargetBranch,
+		Status:         "finished",
+		SortField:      "finishedAt",
+		SortDescending: true,
+		Page:           1,
+		PageSize:       1,
+	}
+	summaries, _, err := database.FindPageDBAnalysis(filter)
+	if err != nil && err != mongo.ErrNoDocuments && err.Error() != "No data found" {
+		log.Error(logActionPullRequest, logInfoAnalysis, 2022, analysis.RID, err)
+		return
+	}
+	if len(summaries) > 0 {
+		targetAnalysis, err := database.FindOneDBAnalysis(map[string]interface{}{"RID": summaries[0].RID})
+		if err != nil {
+			log.Error(logActionPullRequest, logInfoAnalysis, 2022, analysis.RID, err)
+			return
+		}
+		diff.RID = targetAnalysis.RID
+		target = securitytest.Findings(targetAnalysis.HuskyCIResults)
+	}
+
+	comparison := fingerprint.Compare(target, securitytest.Findings(analysis.HuskyCIResults))
+	diff.New = comparison.OnlyInB
+	diff.Fixed = comparison.OnlyInA
+	diff.Unchanged = len(comparison.InBoth)
+	if err := database.UpdateOneDBAnalysisContainer(map[string]interface{}{"RID": analysis.RID}, map[string]interface{}{"targetDiff": &diff}); err != nil {
+		log.Error(logActionPullRequest, logInfoAnalysis, 2022, analysis.RID, err)
+		return
+	}
+	invalidate(analysis.RID)
+}
@@ -0,0 +1,79 @@
+package analysis_test
+
+import (
+	"github.com/huskyci-org/huskyCI/api/analysis"
+	"github.com/huskyci-org/huskyCI/api/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CheckPullRequest", func() {
+
+	var repository types.Repository
+
+	BeforeEach(func() {
+		repository = types.Repository{
+			URL:          "https://github.com/org/repo.git",
+			AnalysisType: types.AnalysisTypeGit,
+			PullRequest:  &types.PullRequest{Number: 42, SourceBranch: "feature", TargetBranch: "main", MergeRef: "refs/pull/42/merge"},
+		}
+	})
+
+	Context("When the request has no pull request", func() {
+		It("Should return nil", func() {
+			repository.PullRequest = nil
+			Expect(analysis.CheckPullRequest(&repository)).To(Succeed())
+		})
+	})
+
+	Context("When the pull request is valid", func() {
+		It("Should analyze its source branch", func() {
+			Expect(analysis.CheckPullRequest(&repository)).To(Succeed())
+			Expect(repository.Branch).To(Equal("feature"))
+		})
+	})
+
+	Context("When the pull request has no source branch", func() {
+		It("Should take the branch of the repository", func() {
+			repository.Branch = "feature"
+			repository.PullRequest.SourceBranch = ""
+			Expect(analysis.CheckPullRequest(&repository)).To(Succeed())
+			Expect(repository.PullRequest.SourceBranch).To(Equal("feature"))
+		})
+	})
+
+	Context("When the pull request is not of the branch of the repository", func() {
+		It("Should return ErrPullRequest", func() {
+			repository.Branch = "other"
+			Expect(analysis.CheckPullRequest(&repository)).To(MatchError(analysis.ErrPullRequest))
+		})
+	})
+
+	Context("When the pull request has no number or target branch", func() {
+		It("Should return ErrPullRequest", func() {
+			repository.PullRequest.Number = 0
+			Expect(analysis.CheckPullRequest(&repository)).To(MatchError(analysis.ErrPullRequest))
+			repository.PullRequest.Number = 42
+			repository.PullRequest.TargetBranch = ""
+			Expect(analysis.CheckPullRequest(&repository)).To(MatchError(analysis.ErrPullRequest))
+		})
+	})
+
+	Context("When the merge ref or target branch could inject a command or an option", func() {
+		It("Should return ErrPullRequest", func() {
+			repository.PullRequest.MergeRef = "refs/pull/42/merge; rm -rf /"
+			Expect(analysis.CheckPullRequest(&repository)).To(MatchError(analysis.ErrPullRequest))
+			repository.PullRequest.MergeRef = ""
+			repository.PullRequest.TargetBranch = "--upload-pack=touch"
+			Expect(analysis.CheckPullRequest(&repository)).To(MatchError(analysis.ErrPullRequest))
+		})
+	})
+
+	Context("When the analysis is of an upload", func() {
+		It("Should return ErrPullRequest", func() {
+			repository.AnalysisType = types.AnalysisTypeUpload
+			Expect(analysis.CheckPullRequest(&repository)).To(MatchError(analysis.ErrPullRequest))
+		})
+	})
+})
@@ -0,0 +1,213 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/types"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const logActionQueue = "QueueAnalysis"
+
+// StatusQueued is the status of an analysis waiting for the analysis of its
+// branch running to finish, when HUSKYCI_API_BRANCH_CONCURRENCY is queue.
+const StatusQueued = "queued"
+
+// QueueLockTimeout is how long Enqueue and the start of a queued analysis
+// wait for another API replica changing the queue of the same branch.
+var QueueLockTimeout = 5 * time.Second
+
+// errQueueBusy is returned when the queue of a branch stayed locked for
+// QueueLockTimeout.
+var errQueueBusy = errors.New("queue of the branch is locked")
+
+// queueLockName returns the name of the distributed lock that prevents two
+// API replicas from changing the queue of the branch of repository at once.
+func queueLockName(repository types.Repository) string {
+	return "queue:" + LockName(repository)
+}
+
+// Enqueue queues the analysis of repository under RID, to start once the
+// analysis of its branch running finishes, and returns its RID. Only the
+// newest request of a branch is kept: when an analysis of the branch is
+// queued already, its request is replaced with repository and its RID is
+// returned instead of RID.
+func Enqueue(RID string, repository types.Repository) (string, error) {
+	database := apiContext.APIConfiguration.DBInstance
+	lockName := queueLockName(repository)
+	if err := lockQueue(lockName, RID); err != nil {
+		log.Error(logActionQueue, logInfoAnalysis, 2021, err)
+		return "", err
+	}
+	queuedRID, err := enqueue(RID, repository)
+	if errLock := database.ReleaseLock(lockName, RID); errLock != nil {
+		log.Error(logActionQueue, logInfoAnalysis, 2018, errLock)
+	}
+	if err != nil {
+		log.Error(logActionQueue, logInfoAnalysis, 2021, err)
+		return "", err
+	}
+	// the running analysis may have finished before this one was queued
+	startNext(repository)
+	return queuedRID, nil
+}
+
+// enqueue queues the analysis of repository while the queue of its branch is
+// locked.
+func enqueue(RID string, repository types.Repository) (string, error) {
+	database := apiContext.APIConfiguration.DBInstance
+	queuedQuery := map[string]interface{}{"repositoryURL": repository.URL, "repositoryBranch": repository.Branch, "status": StatusQueued}
+	queued, err := database.FindOneDBAnalysisSummary(queuedQuery)
+	if err == nil {
+		updateQuery := map[string]interface{}{
+			"commit":          repository.Commit,
+			"queuedRequest":   &repository,
+			"idempotencyKey":  repository.Idempotency.Key,
+			"idempotencyHash": repository.Idempotency.Hash,
+		}
+		if err := database.UpdateOneDBAnalysisContainer(map[string]interface{}{"RID": queued.RID, "status": StatusQueued}, updateQuery); err != nil {
+			return "", err
+		}
+		invalidate(queued.RID)
+		log.Info(logActionQueue, logInfoAnalysis, 72, queued.RID)
+		return queued.RID, nil
+	}
+	if err != mongo.ErrNoDocuments && err.Error() != "No data found" {
+		return "", err
+	}
+	queuedAnalysis := types.Analysis{
+		RID:             RID,
+		URL:             repository.URL,
+		Branch:          repository.Branch,
+		Commit:          repository.Commit,
+		Status:          StatusQueued,
+		StartedAt:       time.Now(),
+		IdempotencyKey:  repository.Idempotency.Key,
+		IdempotencyHash: repository.Idempotency.Hash,
+		QueuedRequest:   &repository,
+	}
+	if err := database.InsertDBAnalysis(queuedAnalysis); err != nil {
+		return "", err
+	}
+	log.Info(logActionQueue, logInfoAnalysis, 71, RID)
+	return RID, nil
+}
+
+// StartQueued starts the analyses left queued, such as by an API replica
+// that stopped while the analysis of their branch ran. It is meant to run
+// once when the API starts, when analyses are queued.
+func StartQueued() {
+	queuedQuery := map[string]interface{}{"status": StatusQueued}
+	queued, err := apiContext.APIConfiguration.DBInstance.FindAllDBAnalysis(queuedQuery)
+	if err != nil {
+		if err != mongo.ErrNoDocuments && err.Error() != "No data found" {
+			log.Error(logActionQueue, logInfoAnalysis, 2021, err)
+		}
+		return
+	}
+	for _, queuedAnalysis := range queued {
+		startNext(types.Repository{URL: queuedAnalysis.URL, Branch: queuedAnalysis.Branch})
+	}
+}
+
+// startNext starts the analysis queued for the branch of repository, if there
+// is one and no analysis of the branch is running or being started. Otherwise
+// it is started when that analysis finishes. It tries again as long as the
+// queue of the branch is locked by another replica, which may have changed
+// the queue before the analysis running finished.
+func startNext(repository types.Repository) {
+	database := apiContext.APIConfiguration.DBInstance
+	owner := strconv.FormatInt(time.Now().UnixNano(), 36)
+	lockName := queueLockName(repository)
+	if err := lockQueue(lockName, owner); err != nil {
+		log.Error(logActionQueue, logInfoAnalysis, 2021, err)
+		if errors.Is(err, errQueueBusy) {
+			go startNext(repository)
+		}
+		return
+	}
+	defer func() {
+		if err := database.ReleaseLock(lockName, owner); err != nil {
+			log.Error(logActionQueue, logInfoAnalysis, 2018, err)
+		}
+	}()
+
+	queuedQuery := map[string]interface{}{"repositoryURL": repository.URL, "repositoryBranch": repository.Branch, "status": StatusQueued}
+	queued, err := database.FindOneDBAnalysis(queuedQuery)
+	if err != nil {
+		if err != mongo.ErrNoDocuments && err.Error() != "No data found" {
+			log.Error(logActionQueue, logInfoAnalysis, 2021, err)
+		}
+		return
+	}
+	if queued.QueuedRequest == nil {
+		log.Error(logActionQueue, logInfoAnalysis, 2021, "queued analysis without request: "+queued.RID)
+		return
+	}
+	next := *queued.QueuedRequest
+
+	// as Trigger does, make sure no analysis of the branch runs or starts
+	branchLock := LockName(next)
+	acquired, err := database.AcquireLock(branchLock, queued.RID, LockTTL)
+	if err != nil || !acquired {
+		if err != nil {
+			log.Error(logActionQueue, logInfoAnalysis, 2018, err)
+		}
+		return
+	}
+	runningQuery := map[string]interface{}{"repositoryURL": next.URL, "repositoryBranch": next.Branch, "status": "running"}
+	_, err = database.FindOneDBAnalysisSummary(runningQuery)
+	if err == nil || (err != mongo.ErrNoDocuments && err.Error() != "No data found") {
+		if err != nil {
+			log.Error(logActionQueue, logInfoAnalysis, 1009, err)
+		}
+		if errLock := database.ReleaseLock(branchLock, queued.RID); errLock != nil {
+			log.Error(logActionQueue, logInfoAnalysis, 2018, errLock)
+		}
+		return
+	}
+
+	startQuery := map[string]interface{}{"status": "running", "startedAt": time.Now(), "queuedRequest": nil}
+	if err := database.UpdateOneDBAnalysisContainer(map[string]interface{}{"RID": queued.RID, "status": StatusQueued}, startQuery); err != nil {
+		log.Error(logActionQueue, logInfoAnalysis, 2021, err)
+		if errLock := database.ReleaseLock(branchLock, queued.RID); errLock != nil {
+			log.Error(logActionQueue, logInfoAnalysis, 2018, errLock)
+		}
+		return
+	}
+	invalidate(queued.RID)
+	log.Info(logActionQueue, logInfoAnalysis, 73, queued.RID)
+	go runAnalysis(context.Background(), queued.RID, next, func() error { return nil })
+}
+
+// startNextIfQueueing starts the analysis queued for the branch of
+// repository when analyses are queued rather than rejected.
+func startNextIfQueueing(repository types.Repository) {
+	if apiContext.APIConfiguration.BranchConcurrency == apiContext.BranchConcurrencyQueue {
+		startNext(repository)
+	}
+}
+
+// lockQueue acquires the lock name of the queue of a branch for owner,
+// waiting up to QueueLockTimeout for another replica to release it.
+func lockQueue(name, owner string) error {
+	deadline := time.Now().Add(QueueLockTimeout)
+	for {
+		acquired, err := apiContext.APIConfiguration.DBInstance.AcquireLock(name, owner, LockTTL)
+		if err != nil {
+			return err
+		}
+		if acquired {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return errQueueBusy
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
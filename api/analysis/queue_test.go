@@ -0,0 +1,273 @@
+package analysis_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/api/analysis"
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/db"
+	"github.com/huskyci-org/huskyCI/api/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// queueDB is a database where an analysis of main is running and another one
+// may be queued.
+type queueDB struct {
+	db.Requests
+	running  *types.AnalysisSummary
+	queued   *types.Analysis
+	updates  []map[string]interface{}
+	released []string
+}
+
+func (q *queueDB) AcquireLock(name, owner string, ttl time.Duration) (bool, error) {
+	return true, nil
+}
+
+func (q *queueDB) ReleaseLock(name, owner string) error {
+	q.released = append(q.released, name)
+	return nil
+}
+
+func (q *queueDB) FindOneDBAnalysisSummary(mapParams map[string]interface{}) (types.AnalysisSummary, error) {
+	switch {
+	case mapParams["status"] == "running" && q.running != nil:
+		return *q.running, nil
+	case mapParams["status"] == analysis.StatusQueued && q.queued != nil:
+		return types.AnalysisSummary{RID: q.queued.RID, URL: q.queued.URL, Branch: q.queued.Branch, Status: q.queued.Status}, nil
+	}
+	return types.AnalysisSummary{}, errors.New("No data found")
+}
+
+func (q *queueDB) FindOneDBAnalysis(mapParams map[string]interface{}) (types.Analysis, error) {
+	if mapParams["status"] != analysis.StatusQueued || q.queued == nil {
+		return types.Analysis{}, errors.New("No data found")
+	}
+	return *q.queued, nil
+}
+
+func (q *queueDB) FindAllDBAnalysis(mapParams map[string]interface{}) ([]types.Analysis, error) {
+	if mapParams["status"] != analysis.StatusQueued || q.queued == nil {
+		return nil, errors.New("No data found")
+	}
+	return []types.Analysis{*q.queued}, nil
+}
+
+func (q *queueDB) InsertDBAnalysis(queued types.Analysis) error {
+	q.queued = &queued
+	return nil
+}
+
+func (q *queueDB) UpdateOneDBAnalysisContainer(mapParams, updateQuery map[string]interface{}) error {
+	q.updates = append(q.updates, updateQuery)
+	if commit, ok := updateQuery["commit"].(string); ok {
+		q.queued.Commit = commit
+	}
+	return nil
+}
+
+// unregisteredDB is a queueDB where the analysis being started cannot be
+// registered while another one of its branch is queued, and where the results
+// of the queued one cannot be registered either.
+type unregisteredDB struct {
+	queueDB
+	sync.Mutex
+	started       bool
+	queueReleases int
+}
+
+func (u *unregisteredDB) InsertDBAnalysis(newAnalysis types.Analysis) error {
+	return errors.New("insert failed")
+}
+
+func (u *unregisteredDB) FindOneDBAnalysis(mapParams map[string]interface{}) (types.Analysis, error) {
+	u.Lock()
+	defer u.Unlock()
+	if mapParams["status"] != analysis.StatusQueued || u.started {
+		return types.Analysis{}, errors.New("No data found")
+	}
+	return *u.queued, nil
+}
+
+func (u *unregisteredDB) FindOneDBPolicy(mapParams map[string]interface{}) (types.Policy, error) {
+	return types.Policy{}, errors.New("No data found")
+}
+
+func (u *unregisteredDB) UpdateOneDBAnalysisContainer(mapParams, updateQuery map[string]interface{}) error {
+	u.Lock()
+	defer u.Unlock()
+	if mapParams["status"] == "running" {
+		return errors.New("update failed")
+	}
+	if mapParams["status"] == analysis.StatusQueued && updateQuery["status"] == "running" {
+		u.started = true
+	}
+	return nil
+}
+
+func (u *unregisteredDB) ReleaseLock(name, owner string) error {
+	u.Lock()
+	defer u.Unlock()
+	if name == "queue:"+analysis.LockName(types.Repository{URL: u.queued.URL, Branch: u.queued.Branch}) {
+		u.queueReleases++
+	}
+	return nil
+}
+
+func (u *unregisteredDB) releasedQueue() int {
+	u.Lock()
+	defer u.Unlock()
+	return u.queueReleases
+}
+
+func (u *unregisteredDB) hasStarted() bool {
+	u.Lock()
+	defer u.Unlock()
+	return u.started
+}
+
+// lockedQueueDB is an unregisteredDB whose queue locks are held by another
+// replica for the first locked attempts to acquire them.
+type lockedQueueDB struct {
+	unregisteredDB
+	locked int
+}
+
+func (l *lockedQueueDB) AcquireLock(name, owner string, ttl time.Duration) (bool, error) {
+	l.Lock()
+	defer l.Unlock()
+	if strings.HasPrefix(name, "queue:") && l.locked > 0 {
+		l.locked--
+		return false, nil
+	}
+	return true, nil
+}
+
+var _ = Describe("StartAnalysis", func() {
+
+	var previousConfig *apiContext.APIConfig
+	var previousInfrastructure string
+	repository := types.Repository{URL: "https://github.com/org/repo.git", Branch: "main", Commit: "new"}
+
+	BeforeEach(func() {
+		previousConfig = apiContext.APIConfiguration
+		// the queued analysis runs until no analysis host is found
+		previousInfrastructure = os.Getenv("HUSKYCI_INFRASTRUCTURE_USE")
+		os.Setenv("HUSKYCI_INFRASTRUCTURE_USE", "none")
+	})
+
+	AfterEach(func() {
+		apiContext.APIConfiguration = previousConfig
+		os.Setenv("HUSKYCI_INFRASTRUCTURE_USE", previousInfrastructure)
+	})
+
+	Context("When the analysis cannot be registered", func() {
+		It("Should still start the analysis queued for the branch", func() {
+			queued := repository
+			queued.Commit = "queued"
+			database := &unregisteredDB{queueDB: queueDB{queued: &types.Analysis{RID: "queued", URL: repository.URL, Branch: "main", Status: analysis.StatusQueued, QueuedRequest: &queued}}}
+			apiContext.APIConfiguration = &apiContext.APIConfig{DBInstance: database, BranchConcurrency: apiContext.BranchConcurrencyQueue}
+
+			analysis.StartAnalysis(context.Background(), "new", repository)
+			database.Lock()
+			Expect(database.started).To(BeTrue())
+			database.Unlock()
+			// the queued analysis starts the next one although its results
+			// could not be registered
+			Eventually(database.releasedQueue).Should(Equal(2))
+		})
+	})
+})
+
+var _ = Describe("StartQueued", func() {
+
+	var previousConfig *apiContext.APIConfig
+	var previousInfrastructure string
+	var previousTimeout time.Duration
+	queued := types.Repository{URL: "https://github.com/org/repo.git", Branch: "main", Commit: "queued"}
+	queuedAnalysis := types.Analysis{RID: "queued", URL: queued.URL, Branch: "main", Status: analysis.StatusQueued, QueuedRequest: &queued}
+
+	BeforeEach(func() {
+		previousConfig = apiContext.APIConfiguration
+		previousInfrastructure = os.Getenv("HUSKYCI_INFRASTRUCTURE_USE")
+		os.Setenv("HUSKYCI_INFRASTRUCTURE_USE", "none")
+		previousTimeout = analysis.QueueLockTimeout
+		analysis.QueueLockTimeout = 10 * time.Millisecond
+	})
+
+	AfterEach(func() {
+		apiContext.APIConfiguration = previousConfig
+		os.Setenv("HUSKYCI_INFRASTRUCTURE_USE", previousInfrastructure)
+		analysis.QueueLockTimeout = previousTimeout
+	})
+
+	Context("When an analysis was left queued", func() {
+		It("Should start it", func() {
+			database := &unregisteredDB{queueDB: queueDB{queued: &queuedAnalysis}}
+			apiContext.APIConfiguration = &apiContext.APIConfig{DBInstance: database, BranchConcurrency: apiContext.BranchConcurrencyQueue}
+
+			analysis.StartQueued()
+			Expect(database.hasStarted()).To(BeTrue())
+			Eventually(database.releasedQueue).Should(Equal(2))
+		})
+	})
+
+	Context("When the queue of its branch is locked by another replica", func() {
+		It("Should try again until it starts it", func() {
+			database := &lockedQueueDB{unregisteredDB: unregisteredDB{queueDB: queueDB{queued: &queuedAnalysis}}, locked: 3}
+			apiContext.APIConfiguration = &apiContext.APIConfig{DBInstance: database, BranchConcurrency: apiContext.BranchConcurrencyQueue}
+
+			analysis.StartQueued()
+			Eventually(database.hasStarted).Should(BeTrue())
+			Eventually(database.releasedQueue).Should(Equal(2))
+		})
+	})
+})
+
+var _ = Describe("Enqueue", func() {
+
+	var database *queueDB
+	var previousConfig *apiContext.APIConfig
+	repository := types.Repository{URL: "https://github.com/org/repo.git", Branch: "main", Commit: "new"}
+
+	BeforeEach(func() {
+		database = &queueDB{running: &types.AnalysisSummary{RID: "running", URL: repository.URL, Branch: "main", Status: "running"}}
+		previousConfig = apiContext.APIConfiguration
+		apiContext.APIConfiguration = &apiContext.APIConfig{DBInstance: database, BranchConcurrency: apiContext.BranchConcurrencyQueue}
+	})
+
+	AfterEach(func() {
+		apiContext.APIConfiguration = previousConfig
+	})
+
+	Context("When no analysis of the branch is queued", func() {
+		It("Should queue the analysis under its RID while the branch is analyzed", func() {
+			RID, err := analysis.Enqueue("new", repository)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(RID).To(Equal("new"))
+			Expect(database.queued.Status).To(Equal(analysis.StatusQueued))
+			Expect(database.queued.QueuedRequest).To(Equal(&repository))
+			Expect(database.updates).To(BeEmpty())
+			Expect(database.released).To(ContainElement(analysis.LockName(repository)))
+		})
+	})
+
+	Context("When an analysis of the branch is queued already", func() {
+		It("Should replace its request and return its RID", func() {
+			database.queued = &types.Analysis{RID: "queued", URL: repository.URL, Branch: "main", Commit: "old", Status: analysis.StatusQueued}
+			RID, err := analysis.Enqueue("new", repository)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(RID).To(Equal("queued"))
+			Expect(database.queued.Commit).To(Equal("new"))
+			Expect(database.updates).To(HaveLen(1))
+			Expect(database.updates[0]["queuedRequest"]).To(Equal(&repository))
+		})
+	})
+})
@@ -0,0 +1,154 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/securitytest"
+	"github.com/huskyci-org/huskyCI/api/tracing"
+	"github.com/huskyci-org/huskyCI/api/types"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const logActionRerun = "RerunSecurityTest"
+
+// ErrNotFinished is returned by Rerun for an analysis that is still running
+// or was cancelled.
+var ErrNotFinished = errors.New("analysis is not finished")
+
+// ErrNotRun is returned by Rerun for a securityTest the analysis did not run.
+var ErrNotRun = errors.New("securityTest did not run in the analysis")
+
+// Rerun runs the securityTest name of the finished analysis again in the
+// background, on the same code: the branch of a git analysis is cloned again
+// and an upload analysis reuses the zip uploaded for it. The container and the
+// findings of the securityTest are replaced and the result of the analysis is
+// decided again, so that a securityTest failing for a flaky reason does not
+// take a whole new analysis. The analysis is running meanwhile, and Rerun
+// returns ErrAlreadyRunning when an analysis of the same branch already is.
+func Rerun(requestCtx context.Context, analysis types.Analysis, name string) error {
+	if analysis.Status != "finished" && analysis.Status != "error running" {
+		return ErrNotFinished
+	}
+	if !ranSecurityTest(analysis, name) {
+		return ErrNotRun
+	}
+	repository := types.Repository{URL: analysis.URL, Branch: analysis.Branch, Commit: analysis.Commit, PullRequest: analysis.PullRequest}
+	if err := ResolveType(&repository); err != nil {
+		return err
+	}
+
+	// make sure no other API replica is starting an analysis of this branch
+	database := apiContext.APIConfiguration.DBInstance
+	lockName := LockName(repository)
+	acquired, err := database.AcquireLock(lockName, analysis.RID, LockTTL)
+	if err != nil {
+		log.Error(logActionRerun, logInfoAnalysis, 2018, err)
+		return err
+	}
+	if !acquired {
+		log.Warning(logActionRerun, logInfoAnalysis, 104, repository.URL)
+		return ErrAlreadyRunning
+	}
+	defer func() {
+		if err := database.ReleaseLock(lockName, analysis.RID); err != nil {
+			log.Error(logActionRerun, logInfoAnalysis, 2018, err)
+		}
+	}()
+
+	runningQuery := map[string]interface{}{"repositoryURL": repository.URL, "repositoryBranch": repository.Branch, "status": "running"}
+	running, err := database.FindOneDBAnalysisSummary(runningQuery)
+	if err != nil && err != mongo.ErrNoDocuments && err.Error() != "No data found" {
+		log.Error(logActionRerun, logInfoAnalysis, 1009, err)
+		return err
+	}
+	if err == nil && running.Status == "running" {
+		log.Warning(logActionRerun, logInfoAnalysis, 104, running.URL)
+		return ErrAlreadyRunning
+	}
+
+	analysisQuery := map[string]interface{}{"RID": analysis.RID, "status": analysis.Status}
+	if err := database.UpdateOneDBAnalysisContainer(analysisQuery, map[string]interface{}{"status": "running"}); err != nil {
+		log.Error(logActionRerun, logInfoAnalysis, 2011, err)
+		return err
+	}
+	invalidate(analysis.RID)
+	publish(types.AnalysisEvent{Type: EventStatus, RID: analysis.RID, URL: analysis.URL, Status: "running"})
+	log.Info(logActionRerun, logInfoAnalysis, 66, name, analysis.RID)
+
+	go rerun(requestCtx, analysis, repository, name)
+	return nil
+}
+
+// ranSecurityTest returns true if the analysis ran the securityTest name.
+func ranSecurityTest(analysis types.Analysis, name string) bool {
+	for _, container := range analysis.Containers {
+		if container.SecurityTest.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// rerun runs the securityTest name of the analysis of repository again and
+// registers its new results.
+func rerun(requestCtx context.Context, analysis types.Analysis, repository types.Repository, name string) {
+	RID := analysis.RID
+	ctx, span := tracing.StartLinkedSpan(requestCtx, logActionRerun,
+		attribute.String("huskyci.rid", RID),
+		attribute.String("huskyci.repository", repository.URL),
+		attribute.String("huskyci.securitytest", name))
+	defer span.End()
+	ctx, done := trackCancellation(ctx, RID)
+	defer done()
+	defer startNextIfQueueing(repository)
+
+	// the containers saved as the securityTest finishes are the other ones and
+	// its new one
+	results := securitytest.Results(analysis)
+	testProgress := progress{RID: RID, URL: repository.URL}
+	for _, container := range analysis.Containers {
+		if container.SecurityTest.Name != name {
+			testProgress.containers = append(testProgress.containers, container)
+		}
+	}
+	results.OnTestFinished = testProgress.testFinished
+	defer finishAnalysis(ctx, RID, repository.URL, results)
+
+	infrastructureSelected, apiHost, err := analysisHost()
+	if err != nil {
+		log.Error(logActionRerun, logInfoAnalysis, 1079, err)
+		results.SetAnalysisError(err)
+		return
+	}
+	removeVolume, err := mountUpload(infrastructureSelected, apiHost, RID, repository)
+	if err != nil {
+		log.Error(logActionRerun, logInfoAnalysis, 3030, RID, err)
+		results.SetAnalysisError(err)
+		return
+	}
+	defer removeVolume()
+
+	policy := repositoryPolicy(repository)
+	scan := securitytest.SecTestScanInfo{
+		Ctx:               ctx,
+		Commit:            repository.Commit,
+		PullRequest:       repository.PullRequest,
+		UploadID:          repository.UploadID,
+		IgnoreRules:       securitytest.IgnoreRules(policy),
+		SeverityOverrides: securitytest.SeverityOverrides(policy),
+	}
+	if err := scan.New(RID, repository.URL, repository.Branch, name, nil, apiHost); err != nil {
+		log.Error(logActionRerun, logInfoAnalysis, 1079, err)
+		results.SetAnalysisError(err)
+		return
+	}
+	if err := results.Rerun(scan); err != nil {
+		log.Error(logActionRerun, logInfoAnalysis, 1079, err)
+		return
+	}
+	log.Info(logActionRerun, logInfoAnalysis, 102, RID)
+}
@@ -0,0 +1,65 @@
+package analysis_test
+
+import (
+	"context"
+
+	"github.com/huskyci-org/huskyCI/api/analysis"
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Rerun", func() {
+
+	var database *triggerDB
+	var previousConfig *apiContext.APIConfig
+	var finished types.Analysis
+
+	BeforeEach(func() {
+		database = &triggerDB{}
+		previousConfig = apiContext.APIConfiguration
+		apiContext.APIConfiguration = &apiContext.APIConfig{DBInstance: database}
+		finished = types.Analysis{
+			RID:        "finished",
+			URL:        "https://github.com/org/repo.git",
+			Branch:     "main",
+			Status:     "finished",
+			Containers: []types.Container{{SecurityTest: types.SecurityTest{Name: "gosec"}, CResult: "error"}},
+		}
+	})
+
+	AfterEach(func() {
+		apiContext.APIConfiguration = previousConfig
+	})
+
+	Context("When the analysis is still running", func() {
+		It("Should return ErrNotFinished", func() {
+			finished.Status = "running"
+			Expect(analysis.Rerun(context.Background(), finished, "gosec")).To(MatchError(analysis.ErrNotFinished))
+		})
+	})
+
+	Context("When the analysis did not run the securityTest", func() {
+		It("Should return ErrNotRun", func() {
+			Expect(analysis.Rerun(context.Background(), finished, "bandit")).To(MatchError(analysis.ErrNotRun))
+		})
+	})
+
+	Context("When another analysis of the branch is running", func() {
+		It("Should return ErrAlreadyRunning and release the lock", func() {
+			database.running = &types.AnalysisSummary{RID: "running", URL: finished.URL, Branch: "main", Status: "running"}
+			Expect(analysis.Rerun(context.Background(), finished, "gosec")).To(MatchError(analysis.ErrAlreadyRunning))
+			Expect(database.released).To(HaveLen(1))
+		})
+	})
+
+	Context("When another replica is starting an analysis of the branch", func() {
+		It("Should return ErrAlreadyRunning", func() {
+			database.locked = true
+			Expect(analysis.Rerun(context.Background(), finished, "gosec")).To(MatchError(analysis.ErrAlreadyRunning))
+			Expect(database.released).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,64 @@
+package analysis
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/types"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const logActionCheckSecurityTests = "CheckSecurityTests"
+
+// ErrSecurityTests is returned by CheckSecurityTests when the securityTests
+// selected by a request are unknown or leave out the ones the policy of the
+// repository requires.
+var ErrSecurityTests = errors.New("invalid securityTests")
+
+// CheckSecurityTests validates the securityTests selected by the request of
+// repository, once trimmed, lower cased and deduplicated: they must exist and
+// include the RequiredSecurityTests of the policy of the repository.
+func CheckSecurityTests(repository *types.Repository) error {
+	if len(repository.SecurityTests) == 0 {
+		return nil
+	}
+	selected := []string{}
+	seen := map[string]bool{}
+	for _, name := range repository.SecurityTests {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			return fmt.Errorf("%w: securityTests must not be blank", ErrSecurityTests)
+		}
+		if !seen[name] {
+			seen[name] = true
+			selected = append(selected, name)
+		}
+	}
+
+	database := apiContext.APIConfiguration.DBInstance
+	for _, name := range selected {
+		_, err := database.FindOneDBSecurityTest(map[string]interface{}{"name": name})
+		if err == mongo.ErrNoDocuments || (err != nil && err.Error() == "No data found") {
+			return fmt.Errorf("%w: unknown securityTest %s", ErrSecurityTests, name)
+		}
+		if err != nil {
+			log.Error(logActionCheckSecurityTests, logInfoAnalysis, 2012, err)
+			return err
+		}
+	}
+
+	missing := []string{}
+	for _, name := range repositoryPolicy(*repository).RequiredSecurityTests {
+		if !seen[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: the policy of the repository requires %s", ErrSecurityTests, strings.Join(missing, ", "))
+	}
+	repository.SecurityTests = selected
+	return nil
+}
@@ -0,0 +1,79 @@
+package analysis_test
+
+import (
+	"errors"
+
+	"github.com/huskyci-org/huskyCI/api/analysis"
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/db"
+	"github.com/huskyci-org/huskyCI/api/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// selectionDB is a database knowing gosec and gitleaks, where the policy of
+// every repository requires gitleaks.
+type selectionDB struct {
+	db.Requests
+}
+
+func (s *selectionDB) FindOneDBSecurityTest(mapParams map[string]interface{}) (types.SecurityTest, error) {
+	switch mapParams["name"] {
+	case "gosec", "gitleaks":
+		return types.SecurityTest{Name: mapParams["name"].(string)}, nil
+	}
+	return types.SecurityTest{}, errors.New("No data found")
+}
+
+func (s *selectionDB) FindOneDBPolicy(mapParams map[string]interface{}) (types.Policy, error) {
+	return types.Policy{RequiredSecurityTests: []string{"gitleaks"}}, nil
+}
+
+var _ = Describe("CheckSecurityTests", func() {
+
+	var previousConfig *apiContext.APIConfig
+
+	BeforeEach(func() {
+		previousConfig = apiContext.APIConfiguration
+		apiContext.APIConfiguration = &apiContext.APIConfig{DBInstance: &selectionDB{}}
+	})
+
+	AfterEach(func() {
+		apiContext.APIConfiguration = previousConfig
+	})
+
+	repository := func(securityTests ...string) *types.Repository {
+		return &types.Repository{URL: "https://github.com/org/repo.git", AnalysisType: types.AnalysisTypeGit, SecurityTests: securityTests}
+	}
+
+	Context("When no securityTest is selected", func() {
+		It("Should run all of them", func() {
+			Expect(analysis.CheckSecurityTests(repository())).To(Succeed())
+		})
+	})
+
+	Context("When the securityTests required by the policy are selected", func() {
+		It("Should normalize them", func() {
+			selected := repository(" GoSec", "gitleaks", "gosec")
+			Expect(analysis.CheckSecurityTests(selected)).To(Succeed())
+			Expect(selected.SecurityTests).To(Equal([]string{"gosec", "gitleaks"}))
+		})
+	})
+
+	Context("When a securityTest is unknown", func() {
+		It("Should return ErrSecurityTests", func() {
+			err := analysis.CheckSecurityTests(repository("gitleaks", "semgrep"))
+			Expect(errors.Is(err, analysis.ErrSecurityTests)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("semgrep"))
+		})
+	})
+
+	Context("When a securityTest required by the policy is left out", func() {
+		It("Should return ErrSecurityTests", func() {
+			err := analysis.CheckSecurityTests(repository("gosec"))
+			Expect(errors.Is(err, analysis.ErrSecurityTests)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("requires gitleaks"))
+		})
+	})
+})
@@ -0,0 +1,33 @@
+package analysis
+
+import (
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/types"
+)
+
+// Find returns the analysis RID from the status cache, if it is enabled, or
+// from the database. Only running analyses are cached: the finished ones do
+// not change anymore and may be too large.
+func Find(RID string) (types.Analysis, error) {
+	statusCache := apiContext.APIConfiguration.StatusCache
+	if statusCache != nil {
+		if analysis, found := statusCache.Get(RID); found {
+			return analysis, nil
+		}
+	}
+	analysis, err := apiContext.APIConfiguration.DBInstance.FindOneDBAnalysis(map[string]interface{}{"RID": RID})
+	if err != nil {
+		return analysis, err
+	}
+	if statusCache != nil && analysis.Status == "running" {
+		statusCache.Set(analysis)
+	}
+	return analysis, nil
+}
+
+// invalidate drops the analysis RID from the status cache once it changed.
+func invalidate(RID string) {
+	if statusCache := apiContext.APIConfiguration.StatusCache; statusCache != nil {
+		statusCache.Delete(RID)
+	}
+}
@@ -0,0 +1,239 @@
+package analysis
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/types"
+)
+
+const logActionStream = "StreamEvents"
+
+// Types of the events only pushed to the clients streaming every analysis.
+const (
+	EventCreated = "created"
+	EventStarted = "started"
+)
+
+// StreamRetention is how long the stream remembers an analysis once over, so
+// that the events read again from the database are not pushed twice.
+var StreamRetention = time.Minute
+
+// streamed is what the stream already pushed of an analysis.
+type streamed struct {
+	URL      string
+	created  bool
+	started  bool
+	status   string
+	finished map[string]bool
+	over     time.Time
+}
+
+var stream = struct {
+	sync.Mutex
+	subscribers map[chan types.AnalysisEvent]bool
+	analyses    map[string]*streamed
+	stop        context.CancelFunc
+	stopped     chan struct{}
+}{subscribers: map[chan types.AnalysisEvent]bool{}, analyses: map[string]*streamed{}}
+
+// subscribeStream returns the channel the events of every analysis are sent
+// to, and the function to call to stop receiving them. The database is only
+// read while some client streams the events.
+func subscribeStream() (chan types.AnalysisEvent, func()) {
+	events := make(chan types.AnalysisEvent, 64)
+	stream.Lock()
+	stream.subscribers[events] = true
+	if len(stream.subscribers) == 1 {
+		ctx, stop := context.WithCancel(context.Background())
+		stopped := make(chan struct{})
+		stream.stop, stream.stopped = stop, stopped
+		go func() {
+			defer close(stopped)
+			pollStream(ctx)
+		}()
+	}
+	stream.Unlock()
+
+	return events, func() {
+		var stopped chan struct{}
+		stream.Lock()
+		delete(stream.subscribers, events)
+		if len(stream.subscribers) == 0 {
+			stream.stop()
+			stopped = stream.stopped
+			stream.analyses = map[string]*streamed{}
+		}
+		stream.Unlock()
+		if stopped != nil {
+			<-stopped
+		}
+	}
+}
+
+// broadcast sends event to the clients streaming every analysis, unless it
+// was already sent. A client too slow to receive it misses it.
+func broadcast(event types.AnalysisEvent) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	stream.Lock()
+	defer stream.Unlock()
+	if len(stream.subscribers) == 0 {
+		return
+	}
+	event, ok := record(event)
+	if !ok {
+		return
+	}
+	for events := range stream.subscribers {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
+
+// record notes that event is pushed and returns it with the repository of
+// its analysis, or false if it already was. stream must be locked.
+func record(event types.AnalysisEvent) (types.AnalysisEvent, bool) {
+	analysis := stream.analyses[event.RID]
+	if analysis == nil {
+		analysis = &streamed{finished: map[string]bool{}}
+		stream.analyses[event.RID] = analysis
+	}
+	if analysis.URL == "" {
+		analysis.URL = event.URL
+	}
+	event.URL = analysis.URL
+
+	switch event.Type {
+	case EventCreated:
+		if analysis.created {
+			return event, false
+		}
+		analysis.created = true
+	case EventStarted:
+		if analysis.started {
+			return event, false
+		}
+		analysis.started = true
+	case EventSecurityTest:
+		if analysis.finished[event.SecurityTest] {
+			return event, false
+		}
+		analysis.finished[event.SecurityTest] = true
+	case EventStatus:
+		if analysis.status == event.Status {
+			return event, false
+		}
+		analysis.status = event.Status
+		if event.Status != "running" {
+			analysis.over = time.Now()
+		}
+	}
+	return event, true
+}
+
+// pollStream reads the running analyses from the database every
+// WatchInterval until ctx is done, to push the events of the analyses
+// running on another API replica. The analyses already running when it
+// starts are recorded without being pushed.
+func pollStream(ctx context.Context) {
+	ticker := time.NewTicker(WatchInterval)
+	defer ticker.Stop()
+	for seed := true; ; seed = false {
+		pollStreamOnce(seed)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func pollStreamOnce(seed bool) {
+	running, err := apiContext.APIConfiguration.DBInstance.FindAllDBAnalysis(map[string]interface{}{"status": "running"})
+	if err != nil {
+		log.Error(logActionStream, logInfoAnalysis, 1071, err)
+		return
+	}
+	seen := map[string]bool{}
+	for _, analysis := range running {
+		seen[analysis.RID] = true
+		events := []types.AnalysisEvent{{Type: EventCreated, RID: analysis.RID, URL: analysis.URL, Time: analysis.StartedAt}}
+		if len(analysis.Containers) > 0 {
+			events = append(events, types.AnalysisEvent{Type: EventStarted, RID: analysis.RID, URL: analysis.URL, Time: analysis.StartedAt})
+		}
+		for _, container := range analysis.Containers {
+			event := securityTestEvent(analysis.RID, container)
+			event.URL = analysis.URL
+			events = append(events, event)
+		}
+		for _, event := range events {
+			if seed {
+				stream.Lock()
+				record(event)
+				stream.Unlock()
+			} else {
+				broadcast(event)
+			}
+		}
+	}
+
+	// the analyses no longer running are over, and the old ones forgotten
+	gone := []string{}
+	stream.Lock()
+	for RID, analysis := range stream.analyses {
+		switch {
+		case !analysis.over.IsZero() && time.Since(analysis.over) > StreamRetention:
+			delete(stream.analyses, RID)
+		case analysis.over.IsZero() && !seen[RID]:
+			gone = append(gone, RID)
+		}
+	}
+	stream.Unlock()
+	for _, RID := range gone {
+		analysis, err := Find(RID)
+		if err != nil || analysis.Status == "running" {
+			continue
+		}
+		for _, container := range analysis.Containers {
+			event := securityTestEvent(RID, container)
+			event.URL = analysis.URL
+			broadcast(event)
+		}
+		broadcast(types.AnalysisEvent{Type: EventStatus, RID: RID, URL: analysis.URL, Status: analysis.Status, Result: analysis.Result, Time: analysis.FinishedAt})
+	}
+}
+
+// Stream calls send with the events of every analysis until ctx is done or
+// send fails: created when an analysis is registered, started when its
+// securityTests start, securitytest when one of them finishes and status
+// when it is over. Events come as they happen when the analysis runs on this
+// replica and within WatchInterval otherwise. A keepalive event is sent every
+// KeepAliveInterval.
+func Stream(ctx context.Context, send func(types.AnalysisEvent) error) error {
+	events, unsubscribe := subscribeStream()
+	defer unsubscribe()
+
+	keepAlive := time.NewTicker(KeepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event := <-events:
+			err = send(event)
+		case <-keepAlive.C:
+			err = send(types.AnalysisEvent{Type: EventKeepAlive, Time: time.Now()})
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package analysis_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/api/analysis"
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/db"
+	"github.com/huskyci-org/huskyCI/api/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// analysesDB is a database holding analyses by RID.
+type analysesDB struct {
+	db.Requests
+	sync.Mutex
+	analyses map[string]types.Analysis
+}
+
+func (a *analysesDB) FindOneDBAnalysis(mapParams map[string]interface{}) (types.Analysis, error) {
+	a.Lock()
+	defer a.Unlock()
+	found, ok := a.analyses[mapParams["RID"].(string)]
+	if !ok {
+		return types.Analysis{}, errors.New("No data found")
+	}
+	return found, nil
+}
+
+func (a *analysesDB) FindAllDBAnalysis(mapParams map[string]interface{}) ([]types.Analysis, error) {
+	a.Lock()
+	defer a.Unlock()
+	found := []types.Analysis{}
+	for _, analysis := range a.analyses {
+		if analysis.Status == mapParams["status"] {
+			found = append(found, analysis)
+		}
+	}
+	return found, nil
+}
+
+func (a *analysesDB) set(analysis types.Analysis) {
+	a.Lock()
+	a.analyses[analysis.RID] = analysis
+	a.Unlock()
+}
+
+var _ = Describe("Stream", func() {
+
+	var database *analysesDB
+	var previousConfig *apiContext.APIConfig
+	var previousInterval time.Duration
+
+	gosec := types.Container{SecurityTest: types.SecurityTest{Name: "gosec"}, CStatus: "finished", CResult: "passed"}
+
+	BeforeEach(func() {
+		database = &analysesDB{analyses: map[string]types.Analysis{}}
+		previousConfig = apiContext.APIConfiguration
+		apiContext.APIConfiguration = &apiContext.APIConfig{DBInstance: database}
+		previousInterval = analysis.WatchInterval
+		analysis.WatchInterval = 10 * time.Millisecond
+	})
+
+	AfterEach(func() {
+		apiContext.APIConfiguration = previousConfig
+		analysis.WatchInterval = previousInterval
+	})
+
+	Context("When analyses run on another replica", func() {
+		It("Should send each of their events once, but not the past ones", func() {
+			database.set(types.Analysis{RID: "old", URL: "https://github.com/org/old", Status: "running", Containers: []types.Container{gosec}})
+			ctx, cancel := context.WithCancel(context.Background())
+			events := make(chan types.AnalysisEvent, 20)
+			streamErr := make(chan error)
+			go func() {
+				streamErr <- analysis.Stream(ctx, func(event types.AnalysisEvent) error {
+					events <- event
+					return nil
+				})
+			}()
+			Consistently(events, 50*time.Millisecond).ShouldNot(Receive())
+
+			database.set(types.Analysis{RID: "new", URL: "https://github.com/org/new", Status: "running"})
+			Eventually(events).Should(Receive(And(HaveField("Type", analysis.EventCreated), HaveField("URL", "https://github.com/org/new"))))
+			database.set(types.Analysis{RID: "new", URL: "https://github.com/org/new", Status: "running", Containers: []types.Container{gosec}})
+			Eventually(events).Should(Receive(HaveField("Type", analysis.EventStarted)))
+			Eventually(events).Should(Receive(And(HaveField("Type", analysis.EventSecurityTest), HaveField("SecurityTest", "gosec"))))
+			database.set(types.Analysis{RID: "new", URL: "https://github.com/org/new", Status: "finished", Result: "passed", Containers: []types.Container{gosec}})
+			Eventually(events).Should(Receive(And(HaveField("RID", "new"), HaveField("Status", "finished"))))
+
+			database.set(types.Analysis{RID: "old", URL: "https://github.com/org/old", Status: "finished", Result: "failed", Containers: []types.Container{gosec}})
+			Eventually(events).Should(Receive(And(HaveField("RID", "old"), HaveField("Status", "finished"), HaveField("URL", "https://github.com/org/old"))))
+			Consistently(events, 50*time.Millisecond).ShouldNot(Receive())
+			cancel()
+			Eventually(streamErr).Should(Receive(MatchError(context.Canceled)))
+		})
+	})
+
+	Context("When the client is gone", func() {
+		It("Should return the send error", func() {
+			previousKeepAlive := analysis.KeepAliveInterval
+			analysis.KeepAliveInterval = 10 * time.Millisecond
+			defer func() { analysis.KeepAliveInterval = previousKeepAlive }()
+
+			err := analysis.Stream(context.Background(), func(event types.AnalysisEvent) error {
+				return errors.New("broken pipe")
+			})
+			Expect(err).To(MatchError("broken pipe"))
+		})
+	})
+})
@@ -0,0 +1,87 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/types"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const logActionTrigger = "TriggerAnalysis"
+
+// ErrAlreadyRunning is returned by Trigger when an analysis of the same
+// repository and branch is already running, queued or being started.
+var ErrAlreadyRunning = errors.New("analysis already running")
+
+// Trigger registers repository if it was never analyzed, registers its
+// analysis under RID and runs it in the background. When an analysis of the same branch
+// is already running or queued, it returns ErrAlreadyRunning along with the RID of that
+// analysis, which is empty if another API replica is still starting it.
+func Trigger(requestCtx context.Context, RID string, repository types.Repository) (string, error) {
+	database := apiContext.APIConfiguration.DBInstance
+
+	// make sure no other API replica is starting this same analysis
+	lockName := LockName(repository)
+	acquired, err := database.AcquireLock(lockName, RID, LockTTL)
+	if err != nil {
+		log.Error(logActionTrigger, logInfoAnalysis, 2018, err)
+		return "", err
+	}
+	if !acquired {
+		log.Warning(logActionTrigger, logInfoAnalysis, 104, repository.URL)
+		return "", ErrAlreadyRunning
+	}
+	analysisStarted := false
+	defer func() {
+		if !analysisStarted {
+			if err := database.ReleaseLock(lockName, RID); err != nil {
+				log.Error(logActionTrigger, logInfoAnalysis, 2018, err)
+			}
+		}
+	}()
+
+	repositoryQuery := map[string]interface{}{"repositoryURL": repository.URL}
+	_, err = database.FindOneDBRepository(repositoryQuery)
+	if err != nil {
+		if err != mongo.ErrNoDocuments && err.Error() != "No data found" {
+			log.Error(logActionTrigger, logInfoAnalysis, 1013, err)
+			return "", err
+		}
+		// repository not found! insert it
+		repository.CreatedAt = time.Now()
+		if err := database.InsertDBRepository(repository); err != nil {
+			log.Error(logActionTrigger, logInfoAnalysis, 1010, err)
+			return "", err
+		}
+	} else {
+		// repository found! does it have an analysis of this branch running,
+		// or queued to start once the running one finishes?
+		for _, status := range []string{"running", StatusQueued} {
+			analysisQuery := map[string]interface{}{"repositoryURL": repository.URL, "repositoryBranch": repository.Branch, "status": status}
+			busy, err := database.FindOneDBAnalysisSummary(analysisQuery)
+			if err != nil && err != mongo.ErrNoDocuments && err.Error() != "No data found" {
+				log.Error(logActionTrigger, logInfoAnalysis, 1009, err)
+				return "", err
+			}
+			if err == nil && busy.Status == status {
+				log.Warning(logActionTrigger, logInfoAnalysis, 104, busy.URL)
+				return busy.RID, ErrAlreadyRunning
+			}
+		}
+	}
+
+	// the analysis is registered while the lock is held, so that the same
+	// request sent again once it is refused by the lock finds its
+	// Idempotency-Key
+	if err := registerNewAnalysis(RID, repository); err != nil {
+		return "", err
+	}
+	log.Info(logActionTrigger, logInfoAnalysis, 16, repository.Branch, repository.URL)
+	analysisStarted = true
+	go runAnalysis(requestCtx, RID, repository, func() error { return nil })
+	return RID, nil
+}
@@ -0,0 +1,94 @@
+package analysis_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/api/analysis"
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/db"
+	"github.com/huskyci-org/huskyCI/api/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// triggerDB is a database where repo.git is known and may be analyzed.
+type triggerDB struct {
+	db.Requests
+	locked   bool
+	running  *types.AnalysisSummary
+	queued   *types.AnalysisSummary
+	released []string
+}
+
+func (t *triggerDB) AcquireLock(name, owner string, ttl time.Duration) (bool, error) {
+	return !t.locked, nil
+}
+
+func (t *triggerDB) ReleaseLock(name, owner string) error {
+	t.released = append(t.released, name)
+	return nil
+}
+
+func (t *triggerDB) FindOneDBRepository(mapParams map[string]interface{}) (types.Repository, error) {
+	return types.Repository{URL: "https://github.com/org/repo.git"}, nil
+}
+
+func (t *triggerDB) FindOneDBAnalysisSummary(mapParams map[string]interface{}) (types.AnalysisSummary, error) {
+	switch {
+	case mapParams["status"] == "running" && t.running != nil:
+		return *t.running, nil
+	case mapParams["status"] == analysis.StatusQueued && t.queued != nil:
+		return *t.queued, nil
+	}
+	return types.AnalysisSummary{}, errors.New("No data found")
+}
+
+var _ = Describe("Trigger", func() {
+
+	var database *triggerDB
+	var previousConfig *apiContext.APIConfig
+	repository := types.Repository{URL: "https://github.com/org/repo.git", Branch: "main", AnalysisType: types.AnalysisTypeGit}
+
+	BeforeEach(func() {
+		database = &triggerDB{}
+		previousConfig = apiContext.APIConfiguration
+		apiContext.APIConfiguration = &apiContext.APIConfig{DBInstance: database}
+	})
+
+	AfterEach(func() {
+		apiContext.APIConfiguration = previousConfig
+	})
+
+	Context("When another replica is starting the same analysis", func() {
+		It("Should return ErrAlreadyRunning without a RID", func() {
+			database.locked = true
+			RID, err := analysis.Trigger(context.Background(), "new", repository)
+			Expect(err).To(MatchError(analysis.ErrAlreadyRunning))
+			Expect(RID).To(BeEmpty())
+			Expect(database.released).To(BeEmpty())
+		})
+	})
+
+	Context("When the branch is already analyzed", func() {
+		It("Should return the RID of the running analysis and release the lock", func() {
+			database.running = &types.AnalysisSummary{RID: "running", URL: repository.URL, Branch: "main", Status: "running"}
+			RID, err := analysis.Trigger(context.Background(), "new", repository)
+			Expect(err).To(MatchError(analysis.ErrAlreadyRunning))
+			Expect(RID).To(Equal("running"))
+			Expect(database.released).To(Equal([]string{analysis.LockName(repository)}))
+		})
+	})
+
+	Context("When an analysis of the branch is queued", func() {
+		It("Should return the RID of the queued analysis and release the lock", func() {
+			database.queued = &types.AnalysisSummary{RID: "queued", URL: repository.URL, Branch: "main", Status: analysis.StatusQueued}
+			RID, err := analysis.Trigger(context.Background(), "new", repository)
+			Expect(err).To(MatchError(analysis.ErrAlreadyRunning))
+			Expect(RID).To(Equal("queued"))
+			Expect(database.released).To(Equal([]string{analysis.LockName(repository)}))
+		})
+	})
+})
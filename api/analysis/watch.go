@@ -0,0 +1,185 @@
+package analysis
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/types"
+)
+
+// Types of the events pushed to the clients watching an analysis.
+const (
+	EventStatus       = "status"
+	EventSecurityTest = "securitytest"
+	EventKeepAlive    = "keepalive"
+)
+
+// WatchInterval is how often a watched analysis is read from the database to
+// catch the events of the analyses running on another API replica.
+var WatchInterval = 2 * time.Second
+
+// KeepAliveInterval is how often a keepalive event is pushed to a client, so
+// that proxies do not close an idle connection and a gone client is noticed.
+var KeepAliveInterval = 30 * time.Second
+
+var watchers = struct {
+	sync.Mutex
+	events map[string]map[chan types.AnalysisEvent]bool
+}{events: map[string]map[chan types.AnalysisEvent]bool{}}
+
+// subscribe returns the channel the events of the analysis RID running on this
+// replica are sent to, and the function to call to stop receiving them.
+func subscribe(RID string) (chan types.AnalysisEvent, func()) {
+	events := make(chan types.AnalysisEvent, 16)
+	watchers.Lock()
+	if watchers.events[RID] == nil {
+		watchers.events[RID] = map[chan types.AnalysisEvent]bool{}
+	}
+	watchers.events[RID][events] = true
+	watchers.Unlock()
+
+	return events, func() {
+		watchers.Lock()
+		delete(watchers.events[RID], events)
+		if len(watchers.events[RID]) == 0 {
+			delete(watchers.events, RID)
+		}
+		watchers.Unlock()
+	}
+}
+
+// publish sends event to the watchers of its analysis on this replica and to
+// the clients streaming every analysis. A watcher too slow to receive it
+// misses it until its next database read.
+func publish(event types.AnalysisEvent) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	watchers.Lock()
+	for events := range watchers.events[event.RID] {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+	watchers.Unlock()
+	broadcast(event)
+}
+
+// progress saves the securityTests of the analysis RID as they finish, so that
+// its watchers are told right away whatever replica they are connected to.
+type progress struct {
+	sync.Mutex
+	RID        string
+	URL        string
+	containers []types.Container
+}
+
+func (p *progress) testFinished(container types.Container) {
+	p.Lock()
+	defer p.Unlock()
+	p.containers = append(p.containers, container)
+	analysisQuery := map[string]interface{}{"RID": p.RID, "status": "running"}
+	updateQuery := map[string]interface{}{"containers": append([]types.Container{}, p.containers...)}
+	if err := apiContext.APIConfiguration.DBInstance.UpdateOneDBAnalysisContainer(analysisQuery, updateQuery); err != nil {
+		log.Error(logActionStart, logInfoAnalysis, 2011, err)
+	}
+	invalidate(p.RID)
+	event := securityTestEvent(p.RID, container)
+	event.URL = p.URL
+	publish(event)
+}
+
+func securityTestEvent(RID string, container types.Container) types.AnalysisEvent {
+	return types.AnalysisEvent{
+		Type:         EventSecurityTest,
+		RID:          RID,
+		SecurityTest: container.SecurityTest.Name,
+		CStatus:      container.CStatus,
+		CResult:      container.CResult,
+		Time:         container.FinishedAt,
+	}
+}
+
+// Watch calls send with the events of the analysis RID until it is over, ctx
+// is done or send fails. The first event is the current status of the analysis,
+// preceded by its securityTests already finished. Events come as they happen
+// when the analysis runs on this replica and within WatchInterval otherwise.
+func Watch(ctx context.Context, RID string, send func(types.AnalysisEvent) error) error {
+	events, unsubscribe := subscribe(RID)
+	defer unsubscribe()
+
+	w := watcher{RID: RID, send: send, finished: map[string]bool{}}
+	done, err := w.check()
+
+	ticker := time.NewTicker(WatchInterval)
+	defer ticker.Stop()
+	keepAlive := time.NewTicker(KeepAliveInterval)
+	defer keepAlive.Stop()
+	for !done && err == nil {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event := <-events:
+			if event.Type == EventStatus {
+				// the database holds the securityTests this replica may have missed
+				done, err = w.check()
+			} else {
+				done, err = w.forward(event)
+			}
+		case <-ticker.C:
+			done, err = w.check()
+		case <-keepAlive.C:
+			err = send(types.AnalysisEvent{Type: EventKeepAlive, RID: RID, Time: time.Now()})
+		}
+	}
+	return err
+}
+
+// watcher sends the events of an analysis once each, whether they were
+// published on this replica or found in the database.
+type watcher struct {
+	RID      string
+	send     func(types.AnalysisEvent) error
+	status   string
+	finished map[string]bool
+}
+
+// check reads the analysis from the database and sends what changed since the
+// last events. It returns true once the analysis is over.
+func (w *watcher) check() (bool, error) {
+	analysis, err := Find(w.RID)
+	if err != nil {
+		return false, err
+	}
+	for _, container := range analysis.Containers {
+		if _, err := w.forward(securityTestEvent(w.RID, container)); err != nil {
+			return false, err
+		}
+	}
+	return w.forward(types.AnalysisEvent{Type: EventStatus, RID: w.RID, Status: analysis.Status, Result: analysis.Result, Time: time.Now()})
+}
+
+// forward sends event unless it was already sent. It returns true once the
+// analysis is over.
+func (w *watcher) forward(event types.AnalysisEvent) (bool, error) {
+	switch event.Type {
+	case EventSecurityTest:
+		if w.finished[event.SecurityTest] {
+			return false, nil
+		}
+		w.finished[event.SecurityTest] = true
+	case EventStatus:
+		if event.Status == w.status {
+			return false, nil
+		}
+		w.status = event.Status
+	}
+	if err := w.send(event); err != nil {
+		return false, err
+	}
+	return w.status != "" && w.status != "running" && w.status != StatusQueued, nil
+}
@@ -0,0 +1,122 @@
+package analysis_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/api/analysis"
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/db"
+	"github.com/huskyci-org/huskyCI/api/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// analysisDB is a database holding a single analysis.
+type analysisDB struct {
+	db.Requests
+	sync.Mutex
+	analysis types.Analysis
+}
+
+func (a *analysisDB) FindOneDBAnalysis(mapParams map[string]interface{}) (types.Analysis, error) {
+	a.Lock()
+	defer a.Unlock()
+	if mapParams["RID"] != a.analysis.RID {
+		return types.Analysis{}, errors.New("No data found")
+	}
+	return a.analysis, nil
+}
+
+func (a *analysisDB) set(analysis types.Analysis) {
+	a.Lock()
+	a.analysis = analysis
+	a.Unlock()
+}
+
+var _ = Describe("Watch", func() {
+
+	var database *analysisDB
+	var previousConfig *apiContext.APIConfig
+	var previousInterval time.Duration
+
+	gosec := types.Container{SecurityTest: types.SecurityTest{Name: "gosec"}, CStatus: "finished", CResult: "passed"}
+	gitleaks := types.Container{SecurityTest: types.SecurityTest{Name: "gitleaks"}, CStatus: "finished", CResult: "failed"}
+
+	BeforeEach(func() {
+		database = &analysisDB{}
+		previousConfig = apiContext.APIConfiguration
+		apiContext.APIConfiguration = &apiContext.APIConfig{DBInstance: database}
+		previousInterval = analysis.WatchInterval
+		analysis.WatchInterval = 10 * time.Millisecond
+	})
+
+	AfterEach(func() {
+		apiContext.APIConfiguration = previousConfig
+		analysis.WatchInterval = previousInterval
+	})
+
+	Context("When the analysis is already over", func() {
+		It("Should send its securityTests and status and return", func() {
+			database.set(types.Analysis{RID: "over", Status: "finished", Result: "failed", Containers: []types.Container{gosec, gitleaks}})
+			events := []types.AnalysisEvent{}
+			err := analysis.Watch(context.Background(), "over", func(event types.AnalysisEvent) error {
+				events = append(events, event)
+				return nil
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(events).To(HaveLen(3))
+			Expect(events[0].Type).To(Equal(analysis.EventSecurityTest))
+			Expect(events[0].SecurityTest).To(Equal("gosec"))
+			Expect(events[1].SecurityTest).To(Equal("gitleaks"))
+			Expect(events[1].CResult).To(Equal("failed"))
+			Expect(events[2].Type).To(Equal(analysis.EventStatus))
+			Expect(events[2].Status).To(Equal("finished"))
+			Expect(events[2].Result).To(Equal("failed"))
+		})
+	})
+
+	Context("When the analysis runs on another replica", func() {
+		It("Should send each change found in the database once", func() {
+			database.set(types.Analysis{RID: "running", Status: "running"})
+			events := make(chan types.AnalysisEvent, 10)
+			watchErr := make(chan error)
+			go func() {
+				watchErr <- analysis.Watch(context.Background(), "running", func(event types.AnalysisEvent) error {
+					events <- event
+					return nil
+				})
+			}()
+
+			Eventually(events).Should(Receive(HaveField("Status", "running")))
+			database.set(types.Analysis{RID: "running", Status: "running", Containers: []types.Container{gosec}})
+			Eventually(events).Should(Receive(HaveField("SecurityTest", "gosec")))
+			database.set(types.Analysis{RID: "running", Status: "finished", Result: "passed", Containers: []types.Container{gosec}})
+			Eventually(events).Should(Receive(HaveField("Status", "finished")))
+			Eventually(watchErr).Should(Receive(BeNil()))
+			Expect(events).NotTo(Receive())
+		})
+	})
+
+	Context("When the analysis does not exist", func() {
+		It("Should return the database error", func() {
+			err := analysis.Watch(context.Background(), "missing", func(event types.AnalysisEvent) error {
+				return nil
+			})
+			Expect(err).To(MatchError("No data found"))
+		})
+	})
+
+	Context("When the client is gone", func() {
+		It("Should return the send error", func() {
+			database.set(types.Analysis{RID: "gone", Status: "running"})
+			err := analysis.Watch(context.Background(), "gone", func(event types.AnalysisEvent) error {
+				return errors.New("broken pipe")
+			})
+			Expect(err).To(MatchError("broken pipe"))
+		})
+	})
+})
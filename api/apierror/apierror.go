@@ -0,0 +1,56 @@
+// Package apierror builds the error replies of the huskyCI API, with the code
+// of pkg/errcode their clients switch on to retry a request or fail at once.
+package apierror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/huskyci-org/huskyCI/pkg/errcode"
+	"github.com/labstack/echo/v4"
+)
+
+// Reply returns the body of the error reply of code to the request of c. err
+// is the short reason of the error, such as "analysis not found", and message
+// tells users what to do about it. The details hold the ID of the request, so
+// that users can point operators to its logs.
+func Reply(c echo.Context, code, err, message string) map[string]interface{} {
+	reply := map[string]interface{}{
+		"success": false,
+		"error":   err,
+		"code":    code,
+		"message": message,
+		"docsURL": errcode.Docs(code),
+	}
+	if requestID := c.Response().Header().Get(echo.HeaderXRequestID); requestID != "" {
+		reply["details"] = map[string]interface{}{"requestID": requestID}
+	}
+	return reply
+}
+
+// Handler is the error handler of the echo server of the API. It replies to
+// the errors returned by middlewares and handlers, such as a failed basic
+// authentication or an unknown route, as Reply does.
+func Handler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+	status, message := http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError)
+	var httpError *echo.HTTPError
+	if errors.As(err, &httpError) {
+		status = httpError.Code
+		message = fmt.Sprint(httpError.Message)
+	}
+	code := errcode.FromStatus(status)
+	reply := Reply(c, code, strings.ToLower(http.StatusText(status)), message)
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(status)
+	} else {
+		err = c.JSON(status, reply)
+	}
+	if err != nil {
+		c.Logger().Error(err)
+	}
+}
@@ -0,0 +1,61 @@
+package apierror_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/huskyci-org/huskyCI/api/apierror"
+	"github.com/huskyci-org/huskyCI/pkg/errcode"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Handler", func() {
+
+	serve := func(path string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		e := echo.New()
+		e.HTTPErrorHandler = Handler
+		e.Use(middleware.RequestID())
+		e.GET("/analysis/:id", func(c echo.Context) error {
+			return c.JSON(http.StatusNotFound, Reply(c, errcode.NotFound, "analysis not found", "No analysis has this RID."))
+		})
+		e.GET("/admin", func(c echo.Context) error {
+			return echo.ErrUnauthorized
+		})
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		reply := map[string]interface{}{}
+		Expect(json.Unmarshal(rec.Body.Bytes(), &reply)).To(Succeed())
+		return rec, reply
+	}
+
+	Context("When a handler replies with Reply", func() {
+		It("Should return the code, its docs and the ID of the request", func() {
+			rec, reply := serve("/analysis/abc")
+			Expect(rec.Code).To(Equal(http.StatusNotFound))
+			Expect(reply["success"]).To(BeFalse())
+			Expect(reply["error"]).To(Equal("analysis not found"))
+			Expect(reply["code"]).To(Equal(errcode.NotFound))
+			Expect(reply["docsURL"]).To(Equal(errcode.DocsURL + "#not_found"))
+			Expect(reply["details"]).To(HaveKeyWithValue("requestID", rec.Header().Get(echo.HeaderXRequestID)))
+		})
+	})
+	Context("When a handler returns an echo error", func() {
+		It("Should reply with the code of its status", func() {
+			rec, reply := serve("/admin")
+			Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+			Expect(reply["code"]).To(Equal(errcode.Unauthorized))
+			Expect(reply["message"]).To(Equal("Unauthorized"))
+		})
+	})
+	Context("When the route does not exist", func() {
+		It("Should reply with not_found", func() {
+			rec, reply := serve("/unknown")
+			Expect(rec.Code).To(Equal(http.StatusNotFound))
+			Expect(reply["code"]).To(Equal(errcode.NotFound))
+		})
+	})
+})
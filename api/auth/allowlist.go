@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/huskyci-org/huskyCI/api/apierror"
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/pkg/errcode"
+	"github.com/labstack/echo/v4"
+)
+
+// AllowTokenIPs rejects the requests to the token routes made from outside
+// of the networks of HUSKYCI_API_ALLOWLIST_TOKEN.
+var AllowTokenIPs = allowIPs(func(c *apiContext.IPAllowListConfig) []*net.IPNet { return c.Token })
+
+// AllowAdminIPs rejects the requests to the admin routes made from outside
+// of the networks of HUSKYCI_API_ALLOWLIST_ADMIN.
+var AllowAdminIPs = allowIPs(func(c *apiContext.IPAllowListConfig) []*net.IPNet { return c.Admin })
+
+// AllowAnalysisIPs rejects the requests starting analyses made from outside
+// of the networks of HUSKYCI_API_ALLOWLIST_ANALYSIS.
+var AllowAnalysisIPs = allowIPs(func(c *apiContext.IPAllowListConfig) []*net.IPNet { return c.Analysis })
+
+// allowIPs returns a middleware rejecting the requests whose client address
+// is not in the networks returned by networks. Without an allow-list, it
+// lets every request through.
+func allowIPs(networks func(*apiContext.IPAllowListConfig) []*net.IPNet) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if apiContext.APIConfiguration == nil || apiContext.APIConfiguration.IPAllowListConfig == nil {
+				return next(c)
+			}
+			if !apiContext.AllowsIP(networks(apiContext.APIConfiguration.IPAllowListConfig), net.ParseIP(c.RealIP())) {
+				reply := apierror.Reply(c, errcode.Forbidden, "address not allowed", "This endpoint does not accept requests from your network address.")
+				return c.JSON(http.StatusForbidden, reply)
+			}
+			return next(c)
+		}
+	}
+}
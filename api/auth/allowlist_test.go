@@ -0,0 +1,57 @@
+package auth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/huskyci-org/huskyCI/api/auth"
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/labstack/echo/v4"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AllowTokenIPs", func() {
+	var previous *apiContext.APIConfig
+	BeforeEach(func() {
+		previous = apiContext.APIConfiguration
+	})
+	AfterEach(func() {
+		apiContext.APIConfiguration = previous
+	})
+
+	serve := func(remoteAddr string) int {
+		e := echo.New()
+		e.IPExtractor = echo.ExtractIPDirect()
+		e.POST("/token", func(c echo.Context) error {
+			return c.String(http.StatusOK, "ok")
+		}, AllowTokenIPs)
+		req := httptest.NewRequest(http.MethodPost, "/token", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", "10.0.0.1")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	Context("When the token routes have no allow-list", func() {
+		It("Should let requests from anywhere through", func() {
+			apiContext.APIConfiguration = &apiContext.APIConfig{IPAllowListConfig: &apiContext.IPAllowListConfig{}}
+			Expect(serve("203.0.113.7:4242")).To(Equal(http.StatusOK))
+		})
+	})
+
+	Context("When the token routes have an allow-list", func() {
+		BeforeEach(func() {
+			networks, err := apiContext.ParseNetworks("10.0.0.0/8")
+			Expect(err).NotTo(HaveOccurred())
+			apiContext.APIConfiguration = &apiContext.APIConfig{IPAllowListConfig: &apiContext.IPAllowListConfig{Token: networks}}
+		})
+		It("Should let requests from its networks through", func() {
+			Expect(serve("10.20.30.40:4242")).To(Equal(http.StatusOK))
+		})
+		It("Should reject requests from elsewhere, whatever X-Forwarded-For says", func() {
+			Expect(serve("203.0.113.7:4242")).To(Equal(http.StatusForbidden))
+		})
+	})
+})
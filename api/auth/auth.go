@@ -5,7 +5,8 @@ import (
 )
 
 // ValidateUser is called by the echo's middleware for
-// basic auth validation
+// basic auth validation. The username and role of a valid
+// user are stored in c under UsernameKey and RoleKey.
 func ValidateUser(username, password string, c echo.Context) (bool, error) {
 	clientMongo := ClientPbkdf2{
 		HashGen: &Pbkdf2Caller{},
@@ -13,7 +14,12 @@ func ValidateUser(username, password string, c echo.Context) (bool, error) {
 	basicClient := MongoBasic{
 		ClientHandler: &clientMongo,
 	}
-	return basicClient.IsValidUser(username, password)
+	isValid, err := basicClient.IsValidUser(username, password)
+	if isValid {
+		c.Set(UsernameKey, username)
+		c.Set(RoleKey, clientMongo.Role)
+	}
+	return isValid, err
 }
 
 // IsValidUser will verify if it has a valid user for the username passed
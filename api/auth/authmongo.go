@@ -12,12 +12,17 @@ import (
 
 // GetPassFromDB will search for a valid user entry in DB through the
 // received username. It will set all parameters required for PBKDF's
-// hash generation and return the hash password stored.
+// hash generation and return the hash password stored. Disabled
+// users are refused with ErrUserDisabled.
 func (cM *ClientPbkdf2) GetPassFromDB(username string) (string, error) {
 	userCreds, err := cM.HashGen.GetCredsFromDB(username)
 	if err != nil {
 		return "", err
 	}
+	if userCreds.Disabled {
+		return "", ErrUserDisabled
+	}
+	cM.Role = RoleOf(userCreds)
 	cM.HashFunction = userCreds.HashFunction
 	cM.Iterations = userCreds.Iterations
 	cM.KeyLen = userCreds.KeyLen
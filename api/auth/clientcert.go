@@ -0,0 +1,26 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/huskyci-org/huskyCI/api/apierror"
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/pkg/errcode"
+	"github.com/labstack/echo/v4"
+)
+
+// RequireClientCert rejects the requests made without a client certificate
+// verified by the TLS handshake, when the API is set with a client CA
+// bundle. Without one, it lets every request through.
+func RequireClientCert(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if apiContext.APIConfiguration == nil || apiContext.APIConfiguration.ClientCAFile == "" {
+			return next(c)
+		}
+		if state := c.Request().TLS; state == nil || len(state.VerifiedChains) == 0 {
+			reply := apierror.Reply(c, errcode.Unauthorized, "client certificate required", "This endpoint requires a client certificate signed by a CA the API trusts. Configure the certificate and key of your client.")
+			return c.JSON(http.StatusUnauthorized, reply)
+		}
+		return next(c)
+	}
+}
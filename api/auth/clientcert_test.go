@@ -0,0 +1,59 @@
+package auth_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/huskyci-org/huskyCI/api/auth"
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/labstack/echo/v4"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RequireClientCert", func() {
+	var previous *apiContext.APIConfig
+	BeforeEach(func() {
+		previous = apiContext.APIConfiguration
+	})
+	AfterEach(func() {
+		apiContext.APIConfiguration = previous
+	})
+
+	serve := func(state *tls.ConnectionState) int {
+		e := echo.New()
+		e.POST("/analysis", func(c echo.Context) error {
+			return c.String(http.StatusOK, "ok")
+		}, RequireClientCert)
+		req := httptest.NewRequest(http.MethodPost, "/analysis", nil)
+		req.TLS = state
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	Context("When the API has no client CA bundle", func() {
+		It("Should let requests without a client certificate through", func() {
+			apiContext.APIConfiguration = &apiContext.APIConfig{}
+			Expect(serve(nil)).To(Equal(http.StatusOK))
+		})
+	})
+
+	Context("When the API has a client CA bundle", func() {
+		BeforeEach(func() {
+			apiContext.APIConfiguration = &apiContext.APIConfig{UseTLS: true, ClientCAFile: "/etc/huskyci/client-ca.pem"}
+		})
+		It("Should reject plain HTTP requests", func() {
+			Expect(serve(nil)).To(Equal(http.StatusUnauthorized))
+		})
+		It("Should reject TLS requests without a verified client certificate", func() {
+			Expect(serve(&tls.ConnectionState{})).To(Equal(http.StatusUnauthorized))
+		})
+		It("Should let requests with a verified client certificate through", func() {
+			state := &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}}
+			Expect(serve(state)).To(Equal(http.StatusOK))
+		})
+	})
+})
@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/api/apierror"
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/pkg/errcode"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+const logActionLockout = "Lockout"
+
+// sweepSize is the number of usernames and addresses tracked above which
+// the ones whose failures expired are forgotten.
+const sweepSize = 10000
+
+// Lockout tracks the failed basic auths of each username and client address
+// and locks them out once they fail too often, so that passwords can not be
+// guessed. A nil Lockout locks nothing out.
+type Lockout struct {
+	config apiContext.LockoutConfig
+	// Now returns the current time, time.Now unless set in tests.
+	Now func() time.Time
+
+	mu       sync.Mutex
+	failures map[string]*failures
+}
+
+// failures are the failed basic auths in a row of a username or an address.
+type failures struct {
+	count int
+	last  time.Time
+	until time.Time
+}
+
+// NewLockout returns a Lockout following config, or nil if config is nil or
+// disables the lockout.
+func NewLockout(config *apiContext.LockoutConfig) *Lockout {
+	if config == nil || config.MaxAttempts <= 0 {
+		return nil
+	}
+	return &Lockout{
+		config:   *config,
+		Now:      time.Now,
+		failures: map[string]*failures{},
+	}
+}
+
+var (
+	defaultLockout     *Lockout
+	defaultLockoutOnce sync.Once
+)
+
+// DefaultLockout returns the Lockout of the API, following the lockout
+// configuration of APIConfiguration.
+func DefaultLockout() *Lockout {
+	defaultLockoutOnce.Do(func() {
+		if apiContext.APIConfiguration != nil {
+			defaultLockout = NewLockout(apiContext.APIConfiguration.LockoutConfig)
+		}
+	})
+	return defaultLockout
+}
+
+// keys returns the keys the failures of username and ip are tracked under.
+func keys(username, ip string) []string {
+	k := []string{"ip:" + ip}
+	if username != "" {
+		k = append(k, "user:"+username)
+	}
+	return k
+}
+
+// LockedFor returns how long username or ip are still locked out for, or 0.
+func (l *Lockout) LockedFor(username, ip string) time.Duration {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.Now()
+	var locked time.Duration
+	for _, key := range keys(username, ip) {
+		if f, ok := l.failures[key]; ok && f.until.After(now) && f.until.Sub(now) > locked {
+			locked = f.until.Sub(now)
+		}
+	}
+	return locked
+}
+
+// Fail records a failed basic auth of username from ip and returns how long
+// they are locked out for because of it, or 0. Past MaxAttempts failures in
+// a row, each failure locks out twice as long as the previous one, from
+// BaseDelay up to MaxDelay. Failures older than MaxDelay are forgotten.
+func (l *Lockout) Fail(username, ip string) time.Duration {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.Now()
+	if len(l.failures) >= sweepSize {
+		l.sweep(now)
+	}
+	var locked time.Duration
+	for _, key := range keys(username, ip) {
+		f, ok := l.failures[key]
+		if !ok || now.Sub(f.last) > l.config.MaxDelay {
+			f = &failures{}
+			l.failures[key] = f
+		}
+		f.count++
+		f.last = now
+		if f.count < l.config.MaxAttempts {
+			continue
+		}
+		delay := l.config.BaseDelay
+		for i := l.config.MaxAttempts; i < f.count && delay < l.config.MaxDelay; i++ {
+			delay *= 2
+		}
+		if delay > l.config.MaxDelay {
+			delay = l.config.MaxDelay
+		}
+		f.until = now.Add(delay)
+		if delay > locked {
+			locked = delay
+		}
+	}
+	return locked
+}
+
+// Succeed forgets the failed basic auths of username. Those of its address
+// are kept, so that guessing the passwords of other users from it stays
+// locked out.
+func (l *Lockout) Succeed(username string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.failures, "user:"+username)
+}
+
+// sweep forgets the failures that are neither locking out nor recent enough
+// to count anymore.
+func (l *Lockout) sweep(now time.Time) {
+	for key, f := range l.failures {
+		if !f.until.After(now) && now.Sub(f.last) > l.config.MaxDelay {
+			delete(l.failures, key)
+		}
+	}
+}
+
+// Reject replies to c with 429 and a Retry-After header if username or the
+// client address of c are locked out, returning whether it did.
+func (l *Lockout) Reject(c echo.Context, username string) (bool, error) {
+	locked := l.LockedFor(username, c.RealIP())
+	if locked <= 0 {
+		return false, nil
+	}
+	seconds := int((locked + time.Second - 1) / time.Second)
+	c.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
+	reply := apierror.Reply(c, errcode.RateLimited, "too many failed attempts", "Too many wrong passwords were sent for this user or from this address. Retry later.")
+	return true, c.JSON(http.StatusTooManyRequests, reply)
+}
+
+// Record records whether the basic auth of username from the client address
+// of c was valid, logging the failures and the lockouts they start.
+func (l *Lockout) Record(c echo.Context, username string, isValid bool) {
+	if isValid {
+		l.Succeed(username)
+		return
+	}
+	ip := c.RealIP()
+	log.Warning(logActionLockout, "AUTH", 132, username, ip)
+	if locked := l.Fail(username, ip); locked > 0 {
+		log.Warning(logActionLockout, "AUTH", 133, username, ip, locked.String())
+	}
+}
+
+// BasicAuth returns the basic auth middleware of the API: it validates the
+// credentials with ValidateUser and rejects those of the usernames and
+// addresses lockout locked out.
+func BasicAuth(lockout *Lockout) echo.MiddlewareFunc {
+	basicAuth := middleware.BasicAuth(func(username, password string, c echo.Context) (bool, error) {
+		isValid, err := ValidateUser(username, password, c)
+		if err == nil {
+			lockout.Record(c, username, isValid)
+		}
+		return isValid, err
+	})
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		h := basicAuth(next)
+		return func(c echo.Context) error {
+			username, _, _ := c.Request().BasicAuth()
+			if rejected, err := lockout.Reject(c, username); rejected {
+				return err
+			}
+			return h(c)
+		}
+	}
+}
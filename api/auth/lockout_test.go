@@ -0,0 +1,114 @@
+package auth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/huskyci-org/huskyCI/api/auth"
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/labstack/echo/v4"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Lockout", func() {
+	var (
+		lockout *Lockout
+		now     time.Time
+	)
+	BeforeEach(func() {
+		now = time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+		lockout = NewLockout(&apiContext.LockoutConfig{MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: 5 * time.Minute})
+		lockout.Now = func() time.Time { return now }
+	})
+
+	Context("When the lockout is disabled", func() {
+		It("Should lock nothing out", func() {
+			disabled := NewLockout(&apiContext.LockoutConfig{})
+			Expect(disabled).To(BeNil())
+			for i := 0; i < 10; i++ {
+				Expect(disabled.Fail("admin", "10.0.0.1")).To(BeZero())
+			}
+			Expect(disabled.LockedFor("admin", "10.0.0.1")).To(BeZero())
+		})
+	})
+	Context("When a username fails less than MaxAttempts times", func() {
+		It("Should not lock it out", func() {
+			Expect(lockout.Fail("admin", "10.0.0.1")).To(BeZero())
+			Expect(lockout.Fail("admin", "10.0.0.1")).To(BeZero())
+			Expect(lockout.LockedFor("admin", "10.0.0.1")).To(BeZero())
+		})
+	})
+	Context("When a username keeps failing", func() {
+		It("Should lock it out twice as long each time, up to MaxDelay", func() {
+			lockout.Fail("admin", "10.0.0.1")
+			lockout.Fail("admin", "10.0.0.2")
+			Expect(lockout.Fail("admin", "10.0.0.3")).To(Equal(time.Minute))
+			Expect(lockout.LockedFor("admin", "10.0.0.4")).To(Equal(time.Minute))
+			Expect(lockout.LockedFor("operator", "10.0.0.4")).To(BeZero())
+			Expect(lockout.Fail("admin", "10.0.0.1")).To(Equal(2 * time.Minute))
+			Expect(lockout.Fail("admin", "10.0.0.1")).To(Equal(4 * time.Minute))
+			Expect(lockout.Fail("admin", "10.0.0.1")).To(Equal(5 * time.Minute))
+
+			now = now.Add(5 * time.Minute)
+			Expect(lockout.LockedFor("admin", "10.0.0.1")).To(BeZero())
+		})
+	})
+	Context("When an address fails with many usernames", func() {
+		It("Should lock the address out", func() {
+			lockout.Fail("admin", "10.0.0.1")
+			lockout.Fail("root", "10.0.0.1")
+			lockout.Fail("huskyci", "10.0.0.1")
+			Expect(lockout.LockedFor("operator", "10.0.0.1")).To(Equal(time.Minute))
+			Expect(lockout.LockedFor("operator", "10.0.0.2")).To(BeZero())
+		})
+	})
+	Context("When a username succeeds", func() {
+		It("Should forget its failures but not those of its address", func() {
+			lockout.Fail("admin", "10.0.0.1")
+			lockout.Fail("admin", "10.0.0.1")
+			lockout.Succeed("admin")
+			Expect(lockout.Fail("admin", "10.0.0.2")).To(BeZero())
+			Expect(lockout.Fail("admin", "10.0.0.1")).To(Equal(time.Minute))
+		})
+	})
+	Context("When the failures are older than MaxDelay", func() {
+		It("Should forget them", func() {
+			lockout.Fail("admin", "10.0.0.1")
+			lockout.Fail("admin", "10.0.0.1")
+			now = now.Add(6 * time.Minute)
+			Expect(lockout.Fail("admin", "10.0.0.1")).To(BeZero())
+		})
+	})
+
+	Describe("BasicAuth", func() {
+		serve := func() *httptest.ResponseRecorder {
+			e := echo.New()
+			e.IPExtractor = echo.ExtractIPDirect()
+			e.POST("/token", func(c echo.Context) error {
+				return c.String(http.StatusOK, "ok")
+			}, BasicAuth(lockout))
+			req := httptest.NewRequest(http.MethodPost, "/token", nil)
+			req.RemoteAddr = "10.0.0.1:4242"
+			req.SetBasicAuth("admin", "guess")
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			return rec
+		}
+
+		Context("When the username is locked out", func() {
+			It("Should reply 429 with Retry-After before checking the password", func() {
+				for i := 0; i < 3; i++ {
+					lockout.Fail("admin", "10.0.0.9")
+				}
+				now = now.Add(30*time.Second + time.Millisecond)
+				rec := serve()
+				Expect(rec.Code).To(Equal(http.StatusTooManyRequests))
+				Expect(rec.Header().Get("Retry-After")).To(Equal("30"))
+				Expect(rec.Body.String()).To(ContainSubstring("rate_limited"))
+			})
+		})
+	})
+})
@@ -38,4 +38,5 @@ type ClientPbkdf2 struct {
 	Iterations   int
 	KeyLen       int
 	HashFunction string
+	Role         string
 }
@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/huskyci-org/huskyCI/api/apierror"
+	"github.com/huskyci-org/huskyCI/api/types"
+	"github.com/huskyci-org/huskyCI/pkg/errcode"
+	"github.com/labstack/echo/v4"
+)
+
+// Roles of the API users. Admins manage the other users, operators use every
+// other route of the API authenticated with basic auth.
+const (
+	RoleAdmin    = "admin"
+	RoleOperator = "operator"
+)
+
+// Keys of the echo context ValidateUser stores the authenticated user in.
+const (
+	UsernameKey = "huskyci.username"
+	RoleKey     = "huskyci.role"
+)
+
+// MinPasswordLength is the length passwords set through the API need at least.
+const MinPasswordLength = 12
+
+// ErrUserDisabled is returned when the credentials of a disabled user are
+// checked.
+var ErrUserDisabled = errors.New("user is disabled")
+
+// IsValidRole returns whether role is a role of the API users.
+func IsValidRole(role string) bool {
+	return role == RoleAdmin || role == RoleOperator
+}
+
+// RoleOf returns the role of user. Users stored before roles existed, such as
+// the default user, are admins.
+func RoleOf(user types.User) string {
+	if user.Role == "" {
+		return RoleAdmin
+	}
+	return user.Role
+}
+
+// CheckPasswordComplexity returns why password is too weak to be set for
+// username, or nil. A password needs MinPasswordLength characters, three of
+// lower case letters, upper case letters, digits and symbols, and must not
+// contain the username.
+func CheckPasswordComplexity(username, password string) error {
+	if len([]rune(password)) < MinPasswordLength {
+		return fmt.Errorf("the password must have at least %d characters", MinPasswordLength)
+	}
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	classes := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			classes++
+		}
+	}
+	if classes < 3 {
+		return errors.New("the password must mix three of lower case letters, upper case letters, digits and symbols")
+	}
+	if username != "" && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		return errors.New("the password must not contain the username")
+	}
+	return nil
+}
+
+// SetPassword hashes password with PBKDF2 and a new salt, with the hash
+// function, iterations and key length of hashGen, and stores it in user.
+func SetPassword(hashGen Pbkdf2Generator, user *types.User, password string) error {
+	salt, err := hashGen.GenerateSalt()
+	if err != nil {
+		return err
+	}
+	hashFunction, isValid := GetValidHashFunction(hashGen.GetHashName())
+	if !isValid {
+		return fmt.Errorf("invalid hash function: %s", hashGen.GetHashName())
+	}
+	decodedSalt, err := hashGen.DecodeSaltValue(salt)
+	if err != nil {
+		return err
+	}
+	user.Salt = salt
+	user.HashFunction = hashGen.GetHashName()
+	user.Iterations = hashGen.GetIterations()
+	user.KeyLen = hashGen.GetKeyLength()
+	user.Password = hashGen.GenHashValue([]byte(password), decodedSalt, user.Iterations, user.KeyLen, hashFunction)
+	return nil
+}
+
+// RequireAdmin rejects the requests of the users ValidateUser authenticated
+// that are not admins.
+func RequireAdmin(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if role, _ := c.Get(RoleKey).(string); role != RoleAdmin {
+			reply := apierror.Reply(c, errcode.Forbidden, "admin role required", "This endpoint can only be used by users with the admin role.")
+			return c.JSON(http.StatusForbidden, reply)
+		}
+		return next(c)
+	}
+}
@@ -0,0 +1,132 @@
+package auth_test
+
+import (
+	"encoding/base64"
+	"hash"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/huskyci-org/huskyCI/api/auth"
+	"github.com/huskyci-org/huskyCI/api/types"
+	"github.com/labstack/echo/v4"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// saltGen hashes nothing, it returns the salt and parameters SetPassword
+// stores.
+type saltGen struct {
+	FakeGen
+}
+
+func (sG *saltGen) GenerateSalt() (string, error) {
+	return base64.StdEncoding.EncodeToString([]byte("salt")), nil
+}
+
+func (sG *saltGen) DecodeSaltValue(salt string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(salt)
+}
+
+func (sG *saltGen) GenHashValue(value, salt []byte, iter, keyLen int, h hash.Hash) string {
+	return string(value) + "+" + string(salt)
+}
+
+func (sG *saltGen) GetHashName() string {
+	return "SHA512"
+}
+
+func (sG *saltGen) GetIterations() int {
+	return 100000
+}
+
+func (sG *saltGen) GetKeyLength() int {
+	return 512
+}
+
+var _ = Describe("CheckPasswordComplexity", func() {
+	Context("When the password is too short", func() {
+		It("Should return an error", func() {
+			Expect(CheckPasswordComplexity("jane", "Sh0rt!")).To(MatchError(ContainSubstring("at least 12 characters")))
+		})
+	})
+	Context("When the password mixes two kinds of characters", func() {
+		It("Should return an error", func() {
+			Expect(CheckPasswordComplexity("jane", "lowercase1234")).To(MatchError(ContainSubstring("three of")))
+		})
+	})
+	Context("When the password contains the username", func() {
+		It("Should return an error", func() {
+			Expect(CheckPasswordComplexity("jane", "Jane-Doe-2026")).To(MatchError(ContainSubstring("username")))
+		})
+	})
+	Context("When the password is strong enough", func() {
+		It("Should return nil", func() {
+			Expect(CheckPasswordComplexity("jane", "correct-Horse-battery")).To(Succeed())
+			Expect(CheckPasswordComplexity("jane", "Tr0ub4dor&3xyz")).To(Succeed())
+		})
+	})
+})
+
+var _ = Describe("SetPassword", func() {
+	It("Should store the hash of the password with its PBKDF2 parameters", func() {
+		user := types.User{Username: "jane", Role: RoleOperator}
+		Expect(SetPassword(&saltGen{}, &user, "correct-Horse-battery")).To(Succeed())
+		Expect(user.Password).To(Equal("correct-Horse-battery+salt"))
+		Expect(user.Salt).To(Equal(base64.StdEncoding.EncodeToString([]byte("salt"))))
+		Expect(user.HashFunction).To(Equal("SHA512"))
+		Expect(user.Iterations).To(Equal(100000))
+		Expect(user.KeyLen).To(Equal(512))
+		Expect(user.Role).To(Equal(RoleOperator))
+	})
+})
+
+var _ = Describe("GetPassFromDB", func() {
+	Context("When the user is disabled", func() {
+		It("Should return ErrUserDisabled", func() {
+			pbkdf2Client := ClientPbkdf2{
+				HashGen: &FakeGen{expectedPbkdf2: types.User{Password: "MyHashedPassword", Disabled: true}},
+			}
+			pass, err := pbkdf2Client.GetPassFromDB("jane")
+			Expect(pass).To(BeEmpty())
+			Expect(err).To(Equal(ErrUserDisabled))
+		})
+	})
+	Context("When the user was stored before roles existed", func() {
+		It("Should be an admin", func() {
+			pbkdf2Client := ClientPbkdf2{
+				HashGen: &FakeGen{expectedPbkdf2: types.User{Password: "MyHashedPassword"}},
+			}
+			_, err := pbkdf2Client.GetPassFromDB("husky")
+			Expect(err).To(BeNil())
+			Expect(pbkdf2Client.Role).To(Equal(RoleAdmin))
+		})
+	})
+})
+
+var _ = Describe("RequireAdmin", func() {
+
+	serve := func(role string) int {
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/api/v2/admin/users", nil), rec)
+		if role != "" {
+			c.Set(RoleKey, role)
+		}
+		handler := RequireAdmin(func(c echo.Context) error {
+			return c.NoContent(http.StatusOK)
+		})
+		Expect(handler(c)).To(Succeed())
+		return rec.Code
+	}
+
+	It("Should let admins through", func() {
+		Expect(serve(RoleAdmin)).To(Equal(http.StatusOK))
+	})
+	It("Should refuse operators", func() {
+		Expect(serve(RoleOperator)).To(Equal(http.StatusForbidden))
+	})
+	It("Should refuse requests no user was authenticated for", func() {
+		Expect(serve("")).To(Equal(http.StatusForbidden))
+	})
+})
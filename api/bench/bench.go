@@ -0,0 +1,350 @@
+// Package bench runs a synthetic workload on the Docker API hosts, so that
+// operators can size the runner hosts and check the tuning of their Docker
+// daemons before analyses run there.
+package bench
+
+import (
+	"archive/zip"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	huskydocker "github.com/huskyci-org/huskyCI/api/dockers"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/util"
+	apiUtil "github.com/huskyci-org/huskyCI/api/util/api"
+)
+
+const logActionBench = "Bench"
+const logInfoBench = "BENCH"
+
+// Kinds of job of a benchmark. A noop job runs a container that exits at once, as
+// each securityTest does besides running its tool. An unzip job delivers a
+// synthetic upload to the containers of an analysis, as HUSKYCI_DOCKERAPI_CODE_DELIVERY
+// sets: extracted into a volume, or copied into a container.
+const (
+	JobNoop  = "noop"
+	JobUnzip = "unzip"
+)
+
+// Limits of the workload of a benchmark, so that it can not take the Docker
+// API hosts down.
+const (
+	MaxJobs        = 1000
+	MaxConcurrency = 64
+	MaxZipFiles    = 10000
+)
+
+// jobTimeout is how long a job may run, in seconds.
+const jobTimeout = 300
+
+// zipFileSize is the size of each file of the synthetic upload, in bytes.
+const zipFileSize = 16 << 10
+
+// ErrAlreadyRunning is returned by Start while a benchmark runs.
+var ErrAlreadyRunning = errors.New("benchmark already running")
+
+// Workload of a benchmark: Jobs of each kind of Kinds run on each Docker API
+// host, Concurrency of them at once, and the uploads of the unzip jobs have
+// ZipFiles files of 16 KB.
+type Workload struct {
+	Jobs        int      `json:"jobs"`
+	Concurrency int      `json:"concurrency"`
+	Kinds       []string `json:"kinds"`
+	ZipFiles    int      `json:"zipFiles"`
+}
+
+// Latency holds percentiles of the durations of the jobs, in seconds.
+type Latency struct {
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
+}
+
+// KindReport holds how the jobs of a kind ran on a Docker API host:
+// Throughput is in jobs finished per second and Latency only counts the jobs
+// that did not fail. Errors holds the first errors of the failed jobs.
+type KindReport struct {
+	Kind       string   `json:"kind"`
+	Jobs       int      `json:"jobs"`
+	Failed     int      `json:"failed"`
+	Seconds    float64  `json:"seconds"`
+	Throughput float64  `json:"throughput"`
+	Latency    Latency  `json:"latency"`
+	Errors     []string `json:"errors,omitempty"`
+}
+
+// HostReport holds the results of a Docker API host, by kind of job.
+type HostReport struct {
+	Host  string       `json:"host"`
+	Kinds []KindReport `json:"kinds"`
+}
+
+// Benchmark is the report of a benchmark. Running is true until it finished.
+type Benchmark struct {
+	Running    bool         `json:"running"`
+	Workload   Workload     `json:"workload"`
+	StartedAt  time.Time    `json:"startedAt"`
+	FinishedAt time.Time    `json:"finishedAt"`
+	Hosts      []HostReport `json:"hosts"`
+	Error      string       `json:"error,omitempty"`
+}
+
+// maxErrors is how many errors of the failed jobs of a kind are kept.
+const maxErrors = 5
+
+var (
+	last   *Benchmark
+	lastMu sync.Mutex
+)
+
+// Last returns a copy of the report of the running or last benchmark, or nil
+// if none ran since the API started.
+func Last() *Benchmark {
+	lastMu.Lock()
+	defer lastMu.Unlock()
+	if last == nil {
+		return nil
+	}
+	current := *last
+	current.Hosts = append([]HostReport(nil), last.Hosts...)
+	return &current
+}
+
+// Normalize sets the default settings left empty, all kinds of job and 20
+// jobs of each, 4 at once, with uploads of 64 files, and returns an error if
+// any setting is invalid.
+func (workload *Workload) Normalize() error {
+	if workload.Jobs == 0 {
+		workload.Jobs = 20
+	}
+	if workload.Concurrency == 0 {
+		workload.Concurrency = 4
+	}
+	if workload.ZipFiles == 0 {
+		workload.ZipFiles = 64
+	}
+	if len(workload.Kinds) == 0 {
+		workload.Kinds = []string{JobNoop, JobUnzip}
+	}
+	if workload.Jobs < 0 || workload.Jobs > MaxJobs {
+		return fmt.Errorf("'jobs' must be between 1 and %d", MaxJobs)
+	}
+	if workload.Concurrency < 0 || workload.Concurrency > MaxConcurrency {
+		return fmt.Errorf("'concurrency' must be between 1 and %d", MaxConcurrency)
+	}
+	if workload.ZipFiles < 0 || workload.ZipFiles > MaxZipFiles {
+		return fmt.Errorf("'zipFiles' must be between 1 and %d", MaxZipFiles)
+	}
+	for _, kind := range workload.Kinds {
+		if _, ok := Jobs[kind]; !ok {
+			return fmt.Errorf("'kinds' must be %s or %s", JobNoop, JobUnzip)
+		}
+	}
+	return nil
+}
+
+// Start runs a benchmark of workload, already normalized, on every Docker
+// API host in background, one host after the other. Its report is read with
+// Last. Only one benchmark runs at a time in the API.
+func Start(workload Workload) error {
+	lastMu.Lock()
+	defer lastMu.Unlock()
+	if last != nil && last.Running {
+		return ErrAlreadyRunning
+	}
+	last = &Benchmark{Running: true, Workload: workload, StartedAt: time.Now(), Hosts: []HostReport{}}
+	log.Info(logActionBench, logInfoBench, 68, workload.Jobs, workload.Concurrency, workload.Kinds)
+	go run(workload)
+	return nil
+}
+
+// run runs the benchmark of workload and records its results in last.
+func run(workload Workload) {
+	err := runHosts(workload)
+	lastMu.Lock()
+	defer lastMu.Unlock()
+	last.Running = false
+	last.FinishedAt = time.Now()
+	if err != nil {
+		log.Error(logActionBench, logInfoBench, 1080, err)
+		last.Error = err.Error()
+		return
+	}
+	log.Info(logActionBench, logInfoBench, 69, last.FinishedAt.Sub(last.StartedAt))
+}
+
+// runHosts runs the jobs of workload on every Docker API host.
+func runHosts(workload Workload) error {
+	hosts := apiUtil.DockerHosts(apiContext.APIConfiguration)
+	if len(hosts) == 0 {
+		return errors.New("no Docker API host is configured")
+	}
+	zipPath, err := writeZip(workload.ZipFiles)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(zipPath)
+
+	for _, host := range hosts {
+		hostReport := HostReport{Host: host}
+		for _, kind := range workload.Kinds {
+			hostReport.Kinds = append(hostReport.Kinds, runKind(host, kind, zipPath, workload))
+		}
+		lastMu.Lock()
+		last.Hosts = append(last.Hosts, hostReport)
+		lastMu.Unlock()
+	}
+	return nil
+}
+
+// Jobs run the job number n of each kind on host. zipPath is the synthetic
+// upload of the unzip jobs. They are noopJob and unzipJob unless tests replace
+// them.
+var Jobs = map[string]func(host string, n int, zipPath string) error{
+	JobNoop:  noopJob,
+	JobUnzip: unzipJob,
+}
+
+// runKind runs the jobs of kind on host and returns how they ran.
+func runKind(host, kind, zipPath string, workload Workload) KindReport {
+	result := KindReport{Kind: kind, Jobs: workload.Jobs}
+	durations := []time.Duration{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	next := make(chan int)
+	started := time.Now()
+	for i := 0; i < workload.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range next {
+				jobStarted := time.Now()
+				err := Jobs[kind](host, n, zipPath)
+				duration := time.Since(jobStarted)
+				mu.Lock()
+				if err != nil {
+					result.Failed++
+					if len(result.Errors) < maxErrors {
+						result.Errors = append(result.Errors, err.Error())
+					}
+				} else {
+					durations = append(durations, duration)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for n := 0; n < workload.Jobs; n++ {
+		next <- n
+	}
+	close(next)
+	wg.Wait()
+
+	elapsed := time.Since(started)
+	result.Seconds = elapsed.Seconds()
+	if elapsed > 0 {
+		result.Throughput = float64(len(durations)) / elapsed.Seconds()
+	}
+	result.Latency = latency(durations)
+	return result
+}
+
+// latency returns the percentiles of durations.
+func latency(durations []time.Duration) Latency {
+	if len(durations) == 0 {
+		return Latency{}
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	return Latency{
+		P50: percentile(durations, 50),
+		P90: percentile(durations, 90),
+		P99: percentile(durations, 99),
+		Max: durations[len(durations)-1].Seconds(),
+	}
+}
+
+// percentile returns the p-th percentile of the sorted durations, in seconds,
+// by the nearest rank.
+func percentile(sorted []time.Duration, p int) float64 {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1].Seconds()
+}
+
+// noopJob runs a container of the helper image that exits at once.
+func noopJob(host string, n int, zipPath string) error {
+	image := apiContext.APIConfiguration.OfflineConfig.MirrorImage("alpine")
+	_, _, err := huskydocker.DockerRun(image, "latest", "true", host, jobTimeout)
+	return err
+}
+
+// unzipJob delivers the upload at zipPath as the code of an analysis: it
+// extracts it into the volume of the analysis, or copies it into a container
+// when the code is copied.
+func unzipJob(host string, n int, zipPath string) error {
+	RID := fmt.Sprintf("bench-%d-%d", time.Now().UnixNano(), n)
+	if !apiContext.APIConfiguration.DockerHostsConfig.CopiesCode() {
+		if err := huskydocker.CreateAnalysisVolume(host, RID, zipPath); err != nil {
+			return err
+		}
+		return huskydocker.RemoveAnalysisVolume(host, RID)
+	}
+	workspace := filepath.Join(os.TempDir(), RID)
+	defer os.RemoveAll(workspace)
+	if err := util.ExtractZip(zipPath, workspace); err != nil {
+		return err
+	}
+	image := apiContext.APIConfiguration.OfflineConfig.MirrorImage("alpine")
+	options := huskydocker.ContainerOptions{RID: RID, Workspace: workspace}
+	_, _, err := huskydocker.DockerRunWithVolume(image, "latest", "true", host, "", jobTimeout, options)
+	return err
+}
+
+// writeZip writes a zip of files files of random bytes in the zip storage
+// directory, seen by the Docker API hosts as the uploads are, and returns its
+// path.
+func writeZip(files int) (string, error) {
+	if err := util.EnsureZipStorageDir(); err != nil {
+		return "", err
+	}
+	zipPath := util.GetZipFilePath(fmt.Sprintf("bench-%d", time.Now().UnixNano()))
+	file, err := os.Create(zipPath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	writer := zip.NewWriter(file)
+	content := make([]byte, zipFileSize)
+	for i := 0; i < files; i++ {
+		entry, err := writer.Create(fmt.Sprintf("src/%d/file%d.txt", i/100, i))
+		if err != nil {
+			os.Remove(zipPath)
+			return "", err
+		}
+		// random bytes do not compress, as most code does a bit
+		if _, err := rand.Read(content); err != nil {
+			os.Remove(zipPath)
+			return "", err
+		}
+		if _, err := entry.Write(content); err != nil {
+			os.Remove(zipPath)
+			return "", err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		os.Remove(zipPath)
+		return "", err
+	}
+	return zipPath, nil
+}
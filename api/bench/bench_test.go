@@ -0,0 +1,126 @@
+package bench_test
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/api/bench"
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bench", func() {
+
+	var previousConfig *apiContext.APIConfig
+	var previousJobs map[string]func(host string, n int, zipPath string) error
+	var previousAddr string
+
+	BeforeEach(func() {
+		previousConfig = apiContext.APIConfiguration
+		apiContext.APIConfiguration = &apiContext.APIConfig{}
+		previousJobs = bench.Jobs
+		previousAddr = os.Getenv("HUSKYCI_DOCKERAPI_ADDR")
+		os.Setenv("HUSKYCI_DOCKERAPI_ADDR", "runner1 runner2")
+	})
+
+	AfterEach(func() {
+		// wait for the benchmark started by the test, if any
+		Eventually(func() bool {
+			result := bench.Last()
+			return result != nil && result.Running
+		}).Should(BeFalse())
+		apiContext.APIConfiguration = previousConfig
+		bench.Jobs = previousJobs
+		os.Setenv("HUSKYCI_DOCKERAPI_ADDR", previousAddr)
+	})
+
+	Describe("Normalize", func() {
+
+		Context("When no option is set", func() {
+			It("Should run 20 jobs of each kind, 4 at once", func() {
+				workload := bench.Workload{}
+				Expect(workload.Normalize()).To(Succeed())
+				Expect(workload).To(Equal(bench.Workload{Jobs: 20, Concurrency: 4, Kinds: []string{bench.JobNoop, bench.JobUnzip}, ZipFiles: 64}))
+			})
+		})
+
+		Context("When an option is out of its limits", func() {
+			It("Should return an error", func() {
+				Expect((&bench.Workload{Jobs: bench.MaxJobs + 1}).Normalize()).NotTo(Succeed())
+				Expect((&bench.Workload{Concurrency: -1}).Normalize()).NotTo(Succeed())
+				Expect((&bench.Workload{Kinds: []string{"clone"}}).Normalize()).NotTo(Succeed())
+			})
+		})
+	})
+
+	Describe("Start", func() {
+
+		Context("When the jobs run on every host", func() {
+			It("Should report their throughput, latency and failures by host and kind", func() {
+				bench.Jobs = map[string]func(host string, n int, zipPath string) error{
+					bench.JobNoop: func(host string, n int, zipPath string) error {
+						time.Sleep(time.Duration(n) * time.Millisecond)
+						return nil
+					},
+					bench.JobUnzip: func(host string, n int, zipPath string) error {
+						if _, err := os.Stat(zipPath); err != nil {
+							return err
+						}
+						if n == 0 {
+							return errors.New("no space left on device")
+						}
+						return nil
+					},
+				}
+				workload := bench.Workload{Jobs: 10, Concurrency: 3}
+				Expect(workload.Normalize()).To(Succeed())
+				Expect(bench.Start(workload)).To(Succeed())
+				Eventually(func() bool { return bench.Last().Running }).Should(BeFalse())
+
+				result := bench.Last()
+				Expect(result.Error).To(BeEmpty())
+				Expect(result.Hosts).To(HaveLen(2))
+				for _, host := range result.Hosts {
+					Expect(host.Kinds).To(HaveLen(2))
+					noop, unzip := host.Kinds[0], host.Kinds[1]
+					Expect(noop.Kind).To(Equal(bench.JobNoop))
+					Expect(noop.Failed).To(BeZero())
+					Expect(noop.Throughput).To(BeNumerically(">", 0))
+					Expect(noop.Latency.P50).To(BeNumerically("<=", noop.Latency.P90))
+					Expect(noop.Latency.P90).To(BeNumerically("<=", noop.Latency.P99))
+					Expect(noop.Latency.Max).To(BeNumerically(">=", 0.009))
+					Expect(unzip.Failed).To(Equal(1))
+					Expect(unzip.Errors).To(Equal([]string{"no space left on device"}))
+				}
+			})
+		})
+
+		Context("When a benchmark is already running", func() {
+			It("Should return ErrAlreadyRunning", func() {
+				release := make(chan struct{})
+				bench.Jobs = map[string]func(host string, n int, zipPath string) error{
+					bench.JobNoop: func(host string, n int, zipPath string) error {
+						<-release
+						return nil
+					},
+				}
+				workload := bench.Workload{Jobs: 1, Concurrency: 1, Kinds: []string{bench.JobNoop}}
+				Expect(bench.Start(workload)).To(Succeed())
+				Expect(bench.Start(workload)).To(MatchError(bench.ErrAlreadyRunning))
+				close(release)
+			})
+		})
+
+		Context("When no Docker API host is configured", func() {
+			It("Should report an error", func() {
+				os.Setenv("HUSKYCI_DOCKERAPI_ADDR", "")
+				Expect(bench.Start(bench.Workload{Jobs: 1, Concurrency: 1, Kinds: []string{bench.JobNoop}})).To(Succeed())
+				Eventually(func() bool { return bench.Last().Running }).Should(BeFalse())
+				Expect(bench.Last().Error).To(ContainSubstring("no Docker API host"))
+			})
+		})
+	})
+})
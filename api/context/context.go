@@ -2,6 +2,7 @@ package context
 
 import (
 	"fmt"
+	"net"
 	"strings"
 	"sync"
 	"time"
@@ -10,7 +11,18 @@ import (
 
 	"github.com/huskyci-org/huskyCI/api/db"
 	postgres "github.com/huskyci-org/huskyCI/api/db/postgres"
+	"github.com/huskyci-org/huskyCI/api/debugtrace"
+	"github.com/huskyci-org/huskyCI/api/export"
+	"github.com/huskyci-org/huskyCI/api/fieldcrypt"
+	"github.com/huskyci-org/huskyCI/api/limits"
+	"github.com/huskyci-org/huskyCI/api/signature"
+	"github.com/huskyci-org/huskyCI/api/statuscache"
+	"github.com/huskyci-org/huskyCI/api/storage"
+	"github.com/huskyci-org/huskyCI/api/tlscert"
+	"github.com/huskyci-org/huskyCI/api/tracing"
 	"github.com/huskyci-org/huskyCI/api/types"
+	"github.com/huskyci-org/huskyCI/api/upload"
+	"github.com/huskyci-org/huskyCI/api/vault"
 )
 
 // APIConfiguration holds all API configuration.
@@ -38,6 +50,22 @@ type DBConfig struct {
 	MaxOpenConns    int
 	MaxIdleConns    int
 	ConnMaxLifetime time.Duration
+	// mu guards Username and Password, which Vault rotates while the API runs.
+	mu sync.RWMutex
+}
+
+// Credentials returns the username and password of the database.
+func (dbConfig *DBConfig) Credentials() (string, string) {
+	dbConfig.mu.RLock()
+	defer dbConfig.mu.RUnlock()
+	return dbConfig.Username, dbConfig.Password
+}
+
+// SetCredentials changes the username and password of the database.
+func (dbConfig *DBConfig) SetCredentials(username, password string) {
+	dbConfig.mu.Lock()
+	defer dbConfig.mu.Unlock()
+	dbConfig.Username, dbConfig.Password = username, password
 }
 
 // DockerHostsConfig represents Docker Hosts configuration.
@@ -47,17 +75,150 @@ type DockerHostsConfig struct {
 	PathCertificate string
 	Host            string
 	TLSVerify       int
+	EgressNetwork   string
+	EgressProxy     string
+	ProxyAddress    string
+	NoProxy         string
+	HostProxies     map[string]string
+	CodeDelivery    string
+}
+
+// How the code of an upload analysis reaches its containers on Docker.
+// CodeDeliveryVolume extracts it into a volume the containers mount, which
+// requires the uploads directory of the API to be shared with the Docker
+// daemon. CodeDeliveryCopy copies it into each container before it starts.
+const (
+	CodeDeliveryVolume = "volume"
+	CodeDeliveryCopy   = "copy"
+)
+
+// CopiesCode returns true if the code of upload
+// analyses is copied into their containers rather than
+// extracted into a volume.
+func (dC *DockerHostsConfig) CopiesCode() bool {
+	return dC != nil && dC.CodeDelivery == CodeDeliveryCopy
+}
+
+// ProxyFor returns the proxy scan containers started
+// in host must use. A proxy set for host in
+// HUSKYCI_DOCKERAPI_HOST_PROXIES takes precedence over
+// HUSKYCI_DOCKERAPI_PROXY_ADDRESS.
+func (dC *DockerHostsConfig) ProxyFor(host string) string {
+	if dC == nil {
+		return ""
+	}
+	if proxy, ok := dC.HostProxies[host]; ok {
+		return proxy
+	}
+	return dC.ProxyAddress
+}
+
+// IPAllowListConfig represents the networks each group of
+// routes accepts requests from: Token the token routes, Admin
+// the admin routes and Analysis the routes starting analyses.
+// A group without networks accepts requests from anywhere, and
+// one whose networks are invalid from nowhere. The address of
+// the client is read from X-Forwarded-For only for requests
+// coming from TrustedProxies.
+type IPAllowListConfig struct {
+	Token          []*net.IPNet
+	Admin          []*net.IPNet
+	Analysis       []*net.IPNet
+	TrustedProxies []*net.IPNet
+}
+
+// LockoutConfig represents the lockout of the usernames and
+// addresses failing basic auth too often: after MaxAttempts
+// failures in a row, they are locked out for BaseDelay, twice
+// as long after each further failure, up to MaxDelay. A zero
+// MaxAttempts disables it.
+type LockoutConfig struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// AllowsIP returns true if ip is in networks or if networks
+// is nil, so that routes without allow-list accept any client.
+func AllowsIP(networks []*net.IPNet, ip net.IP) bool {
+	if networks == nil {
+		return true
+	}
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseNetworks returns the networks of value, CIDRs or IP
+// addresses separated by commas or spaces.
+func ParseNetworks(value string) ([]*net.IPNet, error) {
+	networks := []*net.IPNet{}
+	for _, field := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if !strings.Contains(field, "/") {
+			ip := net.ParseIP(field)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address or network '%s'", field)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP address or network '%s'", field)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
 }
 
 // KubernetesConfig represents Kubernetes API configuration.
+// JobBackoffLimit is how many times the pod of a securityTest Job
+// is retried once it failed, and JobTTL how many seconds a finished
+// Job is kept before Kubernetes removes it, at least minJobTTL so
+// that its output can be read.
 type KubernetesConfig struct {
 	ConfigFilePath       string
 	Namespace            string
 	ProxyAddress         string
 	NoProxyAddresses     string
 	PodSchedulingTimeout int
+	JobBackoffLimit      int
+	JobTTL               int
 }
 
+// minJobTTL is the fewest seconds a finished Job is kept, so that
+// Kubernetes does not remove it before huskyCI read its output.
+const minJobTTL = 60
+
+// ContainerSecurityConfig represents the hardening of every
+// container huskyCI starts. User is the uid[:gid] they run as
+// instead of the user of their image, CapAdd the capabilities
+// kept once all of them are dropped, WritablePaths the tmpfs
+// mounted when the root filesystem is ReadOnlyRootfs, and
+// SeccompProfile and AppArmorProfile replace the default
+// profiles of the container runtime.
+type ContainerSecurityConfig struct {
+	User            string
+	NoNewPrivileges bool
+	ReadOnlyRootfs  bool
+	WritablePaths   []string
+	CapAdd          []string
+	SeccompProfile  string
+	AppArmorProfile string
+}
+
+// DefaultCapAdd are the capabilities containers keep unless
+// HUSKYCI_CONTAINER_CAP_ADD is set: the ones package managers
+// need to install the dependencies of the code analyzed.
+var DefaultCapAdd = []string{"CHOWN", "DAC_OVERRIDE", "FOWNER", "FSETID", "SETGID", "SETUID"}
+
 // GraylogConfig represents Graylog configuration.
 type GraylogConfig struct {
 	Address        string
@@ -67,20 +228,138 @@ type GraylogConfig struct {
 	DevelopmentEnv bool
 }
 
+// JanitorConfig represents the periodic cleanup configuration.
+type JanitorConfig struct {
+	Interval        time.Duration
+	ContainerMaxAge time.Duration
+	ZipMaxAge       time.Duration
+}
+
+// ImageGCConfig represents the garbage collection of the
+// images of the Docker API hosts: every Interval, the
+// versions of the securityTest images no securityTest
+// references anymore and the dangling images are removed,
+// once unreferenced for ProtectionWindow. A zero Interval
+// disables the collection.
+type ImageGCConfig struct {
+	Interval         time.Duration
+	ProtectionWindow time.Duration
+}
+
+// WarmPoolConfig represents the warm pool of securityTest
+// containers, used by the securityTests with WarmPool set:
+// up to Size idle containers are kept per image on each
+// Docker API host, removed once idle for IdleTimeout or
+// once they ran for MaxLifetime. A zero Size disables it.
+type WarmPoolConfig struct {
+	Size        int
+	IdleTimeout time.Duration
+	MaxLifetime time.Duration
+}
+
+// DockerClientPoolConfig represents the reuse of the
+// clients of the Docker API hosts: every operation on a
+// host shares its client, closed once idle for IdleTimeout
+// or once it fails the ping sent every HealthInterval. A
+// zero IdleTimeout disables the reuse.
+type DockerClientPoolConfig struct {
+	IdleTimeout    time.Duration
+	HealthInterval time.Duration
+}
+
+// ScalingConfig represents the hints given to scale the
+// runner hosts, read every Interval: AnalysesPerHost
+// analyses run at once on each of the Hosts. Once the
+// backlog exceeds BacklogThreshold analyses, the hook
+// is called at most once per Cooldown, asking for
+// between MinHosts and MaxHosts hosts, unless MaxHosts
+// is 0: a webhook at WebhookURL or the scale
+// subresource of KubernetesTarget, as kind/name, in
+// KubernetesNamespace.
+type ScalingConfig struct {
+	Interval            time.Duration
+	Hosts               int
+	AnalysesPerHost     int
+	BacklogThreshold    int
+	Cooldown            time.Duration
+	MinHosts            int
+	MaxHosts            int
+	WebhookURL          string
+	KubernetesTarget    string
+	KubernetesNamespace string
+}
+
+// OfflineConfig represents the air-gapped mode configuration.
+// AdvisoryDBs maps a securityTest name to the host directory
+// holding its local advisory database.
+type OfflineConfig struct {
+	Enabled        bool
+	RegistryMirror string
+	NpmRegistry    string
+	AdvisoryDBs    map[string]string
+}
+
+// GitCloneConfig represents how securityTest containers
+// clone repositories. A positive Depth makes clones shallow
+// and CacheVolume keeps a git mirror of every repository
+// scanned: it is the prefix of the Docker volume of each
+// mirror, or the host directory holding them all.
+type GitCloneConfig struct {
+	Depth       int
+	CacheVolume string
+}
+
+// GitCachePath is where the git mirror cache is
+// mounted inside securityTest containers.
+const GitCachePath = "/huskyci/git-cache"
+
+// AdvisoryDBPath is where a local advisory database is
+// mounted inside securityTest containers.
+const AdvisoryDBPath = "/huskyci/advisories"
+
+// MirrorImage returns image rewritten to be pulled from the
+// configured registry mirror. The original registry host,
+// if any, is replaced by the mirror.
+func (oC *OfflineConfig) MirrorImage(image string) string {
+	if oC == nil || oC.RegistryMirror == "" {
+		return image
+	}
+	mirror := strings.TrimSuffix(oC.RegistryMirror, "/")
+	if strings.HasPrefix(image, mirror+"/") {
+		return image
+	}
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		image = parts[1]
+	}
+	return mirror + "/" + image
+}
+
 // APIConfig represents API configuration.
 type APIConfig struct {
 	Port                         int
 	Version                      string
 	ReleaseDate                  string
-	AllowOriginValue             string
+	Commit                       string
+	BuildDate                    string
+	MinClientVersion             string
+	AllowOrigins                 []string
+	GzipLevel                    int
 	UseTLS                       bool
+	ClientCAFile                 string
+	IPAllowListConfig            *IPAllowListConfig
+	LockoutConfig                *LockoutConfig
+	TLSCertConfig                *tlscert.Config
 	GitPrivateSSHKey             string
+	GitCloneConfig               *GitCloneConfig
 	GraylogConfig                *GraylogConfig
 	DBConfig                     *DBConfig
+	DBEncryptionConfig           *fieldcrypt.Config
 	DockerHostsConfig            *DockerHostsConfig
 	KubernetesConfig             *KubernetesConfig
 	EnrySecurityTest             *types.SecurityTest
 	GitAuthorsSecurityTest       *types.SecurityTest
+	GitBlameSecurityTest         *types.SecurityTest
 	GosecSecurityTest            *types.SecurityTest
 	BanditSecurityTest           *types.SecurityTest
 	BrakemanSecurityTest         *types.SecurityTest
@@ -90,9 +369,35 @@ type APIConfig struct {
 	GitleaksSecurityTest         *types.SecurityTest
 	SafetySecurityTest           *types.SecurityTest
 	TFSecSecurityTest            *types.SecurityTest
+	TrivyConfigSecurityTest      *types.SecurityTest
 	SecurityCodeScanSecurityTest *types.SecurityTest
+	StorageConfig                *storage.Config
+	TracingConfig                *tracing.Config
+	PrepullInterval              time.Duration
+	JavaBuildTimeout             time.Duration
+	MaxOutputSize                int64
+	RawOutputMaxSize             int
+	IdempotencyTTL               time.Duration
+	BranchConcurrency            string
+	V1Sunset                     time.Time
+	JanitorConfig                *JanitorConfig
+	ImageGCConfig                *ImageGCConfig
+	WarmPoolConfig               *WarmPoolConfig
+	DockerClientPoolConfig       *DockerClientPoolConfig
+	ScalingConfig                *ScalingConfig
+	SignatureConfig              *signature.Config
+	OfflineConfig                *OfflineConfig
+	UploadConfig                 *upload.Config
+	RequestLimitsConfig          *limits.Config
+	ContainerSecurityConfig      *ContainerSecurityConfig
+	DebugTraceConfig             *debugtrace.Config
+	StatusCacheConfig            *statuscache.Config
+	ExportConfig                 *export.Config
 	DBInstance                   db.Requests
 	Cache                        *cache.Cache
+	Storage                      storage.Storage
+	StatusCache                  statuscache.Cache
+	Exporter                     *export.Exporter
 }
 
 // DefaultConfig is the struct that stores the caller for testing.
@@ -119,15 +424,26 @@ func (dF DefaultConfig) SetOnceConfig() {
 			Port:                         dF.GetAPIPort(),
 			Version:                      dF.GetAPIVersion(),
 			ReleaseDate:                  dF.GetAPIReleaseDate(),
-			AllowOriginValue:             dF.GetAllowOriginValue(),
+			Commit:                       buildCommit,
+			BuildDate:                    buildDate,
+			MinClientVersion:             dF.GetMinClientVersion(),
+			AllowOrigins:                 dF.GetAllowOrigins(),
+			GzipLevel:                    dF.GetGzipLevel(),
 			UseTLS:                       dF.GetAPIUseTLS(),
+			ClientCAFile:                 dF.getClientCAFile(),
+			IPAllowListConfig:            dF.getIPAllowListConfig(),
+			LockoutConfig:                dF.getLockoutConfig(),
+			TLSCertConfig:                dF.getTLSCertConfig(),
 			GitPrivateSSHKey:             dF.getGitPrivateSSHKey(),
+			GitCloneConfig:               dF.getGitCloneConfig(),
 			GraylogConfig:                dF.getGraylogConfig(),
 			DBConfig:                     dF.getDBConfig(),
+			DBEncryptionConfig:           dF.getDBEncryptionConfig(),
 			DockerHostsConfig:            dF.getDockerHostsConfig(),
 			KubernetesConfig:             dF.getKubernetesConfig(),
 			EnrySecurityTest:             dF.getSecurityTestConfig("enry"),
 			GitAuthorsSecurityTest:       dF.getSecurityTestConfig("gitauthors"),
+			GitBlameSecurityTest:         dF.getSecurityTestConfig("gitblame"),
 			GosecSecurityTest:            dF.getSecurityTestConfig("gosec"),
 			BanditSecurityTest:           dF.getSecurityTestConfig("bandit"),
 			BrakemanSecurityTest:         dF.getSecurityTestConfig("brakeman"),
@@ -137,10 +453,36 @@ func (dF DefaultConfig) SetOnceConfig() {
 			GitleaksSecurityTest:         dF.getSecurityTestConfig("gitleaks"),
 			SafetySecurityTest:           dF.getSecurityTestConfig("safety"),
 			TFSecSecurityTest:            dF.getSecurityTestConfig("tfsec"),
+			TrivyConfigSecurityTest:      dF.getSecurityTestConfig("trivyconfig"),
 			SecurityCodeScanSecurityTest: dF.getSecurityTestConfig("securitycodescan"),
+			StorageConfig:                dF.getStorageConfig(),
+			TracingConfig:                dF.getTracingConfig(),
+			PrepullInterval:              dF.GetPrepullInterval(),
+			JavaBuildTimeout:             dF.GetJavaBuildTimeout(),
+			MaxOutputSize:                dF.GetMaxOutputSize(),
+			RawOutputMaxSize:             dF.GetRawOutputMaxSize(),
+			IdempotencyTTL:               dF.GetIdempotencyTTL(),
+			BranchConcurrency:            dF.GetBranchConcurrency(),
+			V1Sunset:                     dF.GetV1Sunset(),
+			JanitorConfig:                dF.getJanitorConfig(),
+			ImageGCConfig:                dF.getImageGCConfig(),
+			WarmPoolConfig:               dF.getWarmPoolConfig(),
+			DockerClientPoolConfig:       dF.getDockerClientPoolConfig(),
+			ScalingConfig:                dF.getScalingConfig(),
+			SignatureConfig:              dF.getSignatureConfig(),
+			OfflineConfig:                dF.getOfflineConfig(),
+			UploadConfig:                 dF.getUploadConfig(),
+			RequestLimitsConfig:          dF.getRequestLimitsConfig(),
+			ContainerSecurityConfig:      dF.getContainerSecurityConfig(),
+			DebugTraceConfig:             dF.getDebugTraceConfig(),
+			StatusCacheConfig:            dF.getStatusCacheConfig(),
+			ExportConfig:                 dF.getExportConfig(),
 			DBInstance:                   dF.GetDB(),
 			Cache:                        dF.GetCache(),
 		}
+		APIConfiguration.Storage = dF.GetStorage(APIConfiguration.StorageConfig)
+		APIConfiguration.StatusCache = dF.GetStatusCache(APIConfiguration.StatusCacheConfig)
+		APIConfiguration.Exporter = dF.GetExporter(APIConfiguration.ExportConfig)
 	})
 }
 
@@ -156,8 +498,28 @@ func (dF DefaultConfig) GetAPIPort() int {
 	return apiPort
 }
 
-// GetAPIVersion returns current API version
+// Build information of the API binary, set by SetBuildInfo.
+var (
+	buildVersion string
+	buildCommit  string
+	buildDate    string
+)
+
+// SetBuildInfo sets the version, commit and date the API binary was built
+// with, as linked into it by the Makefile. It must be called before the
+// configuration is loaded.
+func SetBuildInfo(version, commit, date string) {
+	buildVersion = version
+	buildCommit = commit
+	buildDate = date
+}
+
+// GetAPIVersion returns current API version, the one the binary was built
+// with if any.
 func (dF DefaultConfig) GetAPIVersion() string {
+	if buildVersion != "" {
+		return buildVersion
+	}
 	return "0.14.0"
 }
 
@@ -166,13 +528,39 @@ func (dF DefaultConfig) GetAPIReleaseDate() string {
 	return "2020-06-24"
 }
 
-// GetAllowOriginValue returns the allow origin value
-func (dF DefaultConfig) GetAllowOriginValue() string {
-	urlCORS := dF.Caller.GetEnvironmentVariable("HUSKYCI_API_ALLOW_ORIGIN_CORS")
-	if urlCORS == "" {
-		return "http://127.0.0.1:8888"
+// GetMinClientVersion returns the oldest huskyci-client version the API
+// supports, which older clients warn about. It is empty if every version is
+// supported.
+func (dF DefaultConfig) GetMinClientVersion() string {
+	return dF.Caller.GetEnvironmentVariable("HUSKYCI_API_MIN_CLIENT_VERSION")
+}
+
+// GetAllowOrigins returns the origins allowed to call
+// the API from a browser, set in
+// HUSKYCI_API_ALLOW_ORIGIN_CORS separated by commas.
+func (dF DefaultConfig) GetAllowOrigins() []string {
+	origins := []string{}
+	for _, origin := range strings.Split(dF.Caller.GetEnvironmentVariable("HUSKYCI_API_ALLOW_ORIGIN_CORS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	if len(origins) == 0 {
+		return []string{"http://127.0.0.1:8888"}
+	}
+	return origins
+}
+
+// GetGzipLevel returns the level responses are
+// compressed with, set in HUSKYCI_API_GZIP_LEVEL from
+// 1 to 9. It is the default level of gzip if it is not
+// set, and 0 does not compress them.
+func (dF DefaultConfig) GetGzipLevel() int {
+	level, err := dF.Caller.ConvertStrToInt(dF.Caller.GetEnvironmentVariable("HUSKYCI_API_GZIP_LEVEL"))
+	if err != nil || level < 0 || level > 9 {
+		return -1
 	}
-	return urlCORS
+	return level
 }
 
 // GetAPIUseTLS returns a boolean. If true, Husky API
@@ -186,10 +574,136 @@ func (dF DefaultConfig) GetAPIUseTLS() bool {
 	return false
 }
 
+// getClientCAFile returns the PEM bundle of the CAs the certificates of the
+// clients are verified against, which turns mutual TLS on. This depends on
+// HUSKYCI_API_TLS_CLIENT_CA variable.
+func (dF DefaultConfig) getClientCAFile() string {
+	return dF.Caller.GetEnvironmentVariable("HUSKYCI_API_TLS_CLIENT_CA")
+}
+
+// getTLSCertConfig returns how the TLS certificate of the API is obtained: with
+// ACME for the comma separated domains of HUSKYCI_API_ACME_DOMAINS, or else
+// from its certificate and key files.
+func (dF DefaultConfig) getTLSCertConfig() *tlscert.Config {
+	domains := []string{}
+	for _, domain := range strings.Split(dF.Caller.GetEnvironmentVariable("HUSKYCI_API_ACME_DOMAINS"), ",") {
+		if domain = strings.TrimSpace(domain); domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+	cacheDir := dF.Caller.GetEnvironmentVariable("HUSKYCI_API_ACME_CACHE_DIR")
+	if cacheDir == "" {
+		cacheDir = tlscert.DefaultACMECacheDir
+	}
+	return &tlscert.Config{
+		ACMEDomains:      domains,
+		ACMEEmail:        dF.Caller.GetEnvironmentVariable("HUSKYCI_API_ACME_EMAIL"),
+		ACMECacheDir:     cacheDir,
+		ACMEDirectoryURL: dF.Caller.GetEnvironmentVariable("HUSKYCI_API_ACME_DIRECTORY_URL"),
+		ACMEHTTPAddr:     dF.Caller.GetEnvironmentVariable("HUSKYCI_API_ACME_HTTP_ADDR"),
+	}
+}
+
 func (dF DefaultConfig) getGitPrivateSSHKey() string {
 	return dF.Caller.GetEnvironmentVariable("HUSKYCI_API_GIT_PRIVATE_SSH_KEY")
 }
 
+func (dF DefaultConfig) getGitCredentialsKey() string {
+	return dF.Caller.GetEnvironmentVariable("HUSKYCI_GIT_CREDENTIALS_KEY")
+}
+
+func (dF DefaultConfig) getDBEncryptionConfig() *fieldcrypt.Config {
+	return &fieldcrypt.Config{
+		Keys:             dF.Caller.GetEnvironmentVariable("HUSKYCI_DB_ENCRYPTION_KEYS"),
+		KeysFile:         dF.Caller.GetEnvironmentVariable("HUSKYCI_DB_ENCRYPTION_KEYS_FILE"),
+		CurrentKeyID:     dF.Caller.GetEnvironmentVariable("HUSKYCI_DB_ENCRYPTION_KEY_ID"),
+		LegacyPassphrase: dF.getGitCredentialsKey(),
+	}
+}
+
+func (dF DefaultConfig) getIPAllowListConfig() *IPAllowListConfig {
+	return &IPAllowListConfig{
+		Token:          dF.getAllowedNetworks("HUSKYCI_API_ALLOWLIST_TOKEN"),
+		Admin:          dF.getAllowedNetworks("HUSKYCI_API_ALLOWLIST_ADMIN"),
+		Analysis:       dF.getAllowedNetworks("HUSKYCI_API_ALLOWLIST_ANALYSIS"),
+		TrustedProxies: dF.getAllowedNetworks("HUSKYCI_API_TRUSTED_PROXIES"),
+	}
+}
+
+// getLockoutConfig returns the lockout configuration. Usernames
+// and addresses are locked out after 5 failed basic auths in a
+// row unless HUSKYCI_API_LOCKOUT_MAX_ATTEMPTS is set, 0
+// disabling the lockout, for 1 minute at first and 60 at most
+// unless HUSKYCI_API_LOCKOUT_BASE_SECONDS and
+// HUSKYCI_API_LOCKOUT_MAX_MINUTES are set.
+func (dF DefaultConfig) getLockoutConfig() *LockoutConfig {
+	maxAttempts, err := dF.Caller.ConvertStrToInt(dF.Caller.GetEnvironmentVariable("HUSKYCI_API_LOCKOUT_MAX_ATTEMPTS"))
+	if err != nil || maxAttempts < 0 {
+		maxAttempts = 5
+	}
+	baseSeconds, err := dF.Caller.ConvertStrToInt(dF.Caller.GetEnvironmentVariable("HUSKYCI_API_LOCKOUT_BASE_SECONDS"))
+	if err != nil || baseSeconds <= 0 {
+		baseSeconds = 60
+	}
+	return &LockoutConfig{
+		MaxAttempts: maxAttempts,
+		BaseDelay:   time.Duration(baseSeconds) * time.Second,
+		MaxDelay:    dF.getMinutesFromEnv("HUSKYCI_API_LOCKOUT_MAX_MINUTES", 60),
+	}
+}
+
+// getAllowedNetworks returns the networks set in envVar, nil
+// if it is not set. Invalid networks allow nothing, so that a
+// typo does not open the routes to everyone.
+func (dF DefaultConfig) getAllowedNetworks(envVar string) []*net.IPNet {
+	value := dF.Caller.GetEnvironmentVariable(envVar)
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	networks, err := ParseNetworks(value)
+	if err != nil {
+		fmt.Println("Error configuring "+envVar+": ", err)
+		return []*net.IPNet{}
+	}
+	return networks
+}
+
+// GetVaultConfig returns how the API reads its secrets from HashiCorp Vault,
+// before the rest of its configuration as they fill its environment
+// variables. Vault is not used unless HUSKYCI_VAULT_ADDR is set.
+func (dF DefaultConfig) GetVaultConfig() *vault.Config {
+	return &vault.Config{
+		Address:    dF.Caller.GetEnvironmentVariable("HUSKYCI_VAULT_ADDR"),
+		Namespace:  dF.Caller.GetEnvironmentVariable("HUSKYCI_VAULT_NAMESPACE"),
+		CACert:     dF.Caller.GetEnvironmentVariable("HUSKYCI_VAULT_CACERT"),
+		AuthMethod: dF.Caller.GetEnvironmentVariable("HUSKYCI_VAULT_AUTH_METHOD"),
+		AuthMount:  dF.Caller.GetEnvironmentVariable("HUSKYCI_VAULT_AUTH_MOUNT"),
+		Token:      dF.Caller.GetEnvironmentVariable("HUSKYCI_VAULT_TOKEN"),
+		RoleID:     dF.Caller.GetEnvironmentVariable("HUSKYCI_VAULT_ROLE_ID"),
+		SecretID:   dF.Caller.GetEnvironmentVariable("HUSKYCI_VAULT_SECRET_ID"),
+		Role:       dF.Caller.GetEnvironmentVariable("HUSKYCI_VAULT_ROLE"),
+		Secrets:    dF.Caller.GetEnvironmentVariable("HUSKYCI_VAULT_SECRETS"),
+	}
+}
+
+func (dF DefaultConfig) getGitCloneConfig() *GitCloneConfig {
+	return &GitCloneConfig{
+		Depth:       dF.GetGitCloneDepth(),
+		CacheVolume: dF.Caller.GetEnvironmentVariable("HUSKYCI_API_GIT_CACHE_VOLUME"),
+	}
+}
+
+// GetGitCloneDepth returns the depth of the shallow
+// clones made by securityTest containers. It depends on
+// HUSKYCI_API_GIT_CLONE_DEPTH and 0 means a full clone.
+func (dF DefaultConfig) GetGitCloneDepth() int {
+	depth, err := dF.Caller.ConvertStrToInt(dF.Caller.GetEnvironmentVariable("HUSKYCI_API_GIT_CLONE_DEPTH"))
+	if err != nil || depth < 0 {
+		return 0
+	}
+	return depth
+}
+
 func (dF DefaultConfig) getGraylogConfig() *GraylogConfig {
 	appName := dF.Caller.GetEnvironmentVariable("HUSKYCI_LOGGING_GRAYLOG_APP_NAME")
 	if appName == "" {
@@ -318,7 +832,40 @@ func (dF DefaultConfig) getDockerHostsConfig() *DockerHostsConfig {
 		PathCertificate: dockerHostsPathCertificates,
 		Host:            fmt.Sprintf("%s:%d", dockerHostsAddresses[0], dockerAPIPort),
 		TLSVerify:       dF.GetDockerAPITLSVerify(),
+		EgressNetwork:   dF.Caller.GetEnvironmentVariable("HUSKYCI_DOCKERAPI_EGRESS_NETWORK"),
+		EgressProxy:     dF.Caller.GetEnvironmentVariable("HUSKYCI_DOCKERAPI_EGRESS_PROXY"),
+		ProxyAddress:    dF.Caller.GetEnvironmentVariable("HUSKYCI_DOCKERAPI_PROXY_ADDRESS"),
+		NoProxy:         dF.Caller.GetEnvironmentVariable("HUSKYCI_DOCKERAPI_NO_PROXY_ADDRESSES"),
+		HostProxies:     dF.GetDockerAPIHostProxies(),
+		CodeDelivery:    dF.GetDockerAPICodeDelivery(),
+	}
+}
+
+// GetDockerAPICodeDelivery returns how the code of
+// upload analyses reaches their containers, copy if
+// HUSKYCI_DOCKERAPI_CODE_DELIVERY is copy and volume
+// otherwise.
+func (dF DefaultConfig) GetDockerAPICodeDelivery() string {
+	if strings.EqualFold(dF.Caller.GetEnvironmentVariable("HUSKYCI_DOCKERAPI_CODE_DELIVERY"), CodeDeliveryCopy) {
+		return CodeDeliveryCopy
+	}
+	return CodeDeliveryVolume
+}
+
+// GetDockerAPIHostProxies returns the per host proxies set in
+// HUSKYCI_DOCKERAPI_HOST_PROXIES as space separated
+// host=proxy pairs, such as
+// "dockerapi-1=http://proxy-a:3128 dockerapi-2=http://proxy-b:3128".
+func (dF DefaultConfig) GetDockerAPIHostProxies() map[string]string {
+	hostProxies := map[string]string{}
+	for _, pair := range strings.Fields(dF.Caller.GetEnvironmentVariable("HUSKYCI_DOCKERAPI_HOST_PROXIES")) {
+		host, proxy, found := strings.Cut(pair, "=")
+		if !found || host == "" {
+			continue
+		}
+		hostProxies[host] = proxy
 	}
+	return hostProxies
 }
 
 func (dF DefaultConfig) getKubernetesConfig() *KubernetesConfig {
@@ -330,12 +877,22 @@ func (dF DefaultConfig) getKubernetesConfig() *KubernetesConfig {
 	if err != nil {
 		podSchedulingTimeout = 60
 	}
+	jobBackoffLimit, err := dF.Caller.ConvertStrToInt(dF.Caller.GetEnvironmentVariable("HUSKYCI_KUBERNETES_JOB_BACKOFF_LIMIT"))
+	if err != nil || jobBackoffLimit < 0 {
+		jobBackoffLimit = 2
+	}
+	jobTTL, err := dF.Caller.ConvertStrToInt(dF.Caller.GetEnvironmentVariable("HUSKYCI_KUBERNETES_JOB_TTL_SECONDS"))
+	if err != nil || jobTTL < minJobTTL {
+		jobTTL = 300
+	}
 	return &KubernetesConfig{
 		ConfigFilePath:       configFilePath,
 		Namespace:            namespace,
 		ProxyAddress:         proxyAddress,
 		NoProxyAddresses:     noProxyAddresses,
 		PodSchedulingTimeout: podSchedulingTimeout,
+		JobBackoffLimit:      jobBackoffLimit,
+		JobTTL:               jobTTL,
 	}
 }
 
@@ -352,9 +909,9 @@ func (dF DefaultConfig) GetDockerAPIPort() int {
 
 // GetDockerAPITLSVerify returns an int that is
 // interpreted as a boolean. If HUSKYCI_DOCKERAPI_TLS_VERIFY
-// is false, it will return 0 and TLS won't be configured
-// in the Docker API. Otherwise, it will return 1 and Docker
-// API will use TLS protocol.
+// is false, it will return 0 and the certificates of the
+// Docker API hosts won't be verified. Otherwise, it will
+// return 1 and they will be verified against their ca.pem.
 func (dF DefaultConfig) GetDockerAPITLSVerify() int {
 	option := dF.Caller.GetEnvironmentVariable("HUSKYCI_DOCKERAPI_TLS_VERIFY")
 	if strings.EqualFold(option, "false") || option == "0" {
@@ -363,16 +920,542 @@ func (dF DefaultConfig) GetDockerAPITLSVerify() int {
 	return 1
 }
 
+func (dF DefaultConfig) getStorageConfig() *storage.Config {
+	return &storage.Config{
+		Backend:    dF.Caller.GetEnvironmentVariable("HUSKYCI_STORAGE_BACKEND"),
+		Endpoint:   dF.Caller.GetEnvironmentVariable("HUSKYCI_STORAGE_ENDPOINT"),
+		Region:     dF.Caller.GetEnvironmentVariable("HUSKYCI_STORAGE_REGION"),
+		Bucket:     dF.Caller.GetEnvironmentVariable("HUSKYCI_STORAGE_BUCKET"),
+		AccessKey:  dF.Caller.GetEnvironmentVariable("HUSKYCI_STORAGE_ACCESS_KEY"),
+		SecretKey:  dF.Caller.GetEnvironmentVariable("HUSKYCI_STORAGE_SECRET_KEY"),
+		Prefix:     dF.Caller.GetEnvironmentVariable("HUSKYCI_STORAGE_PREFIX"),
+		UseSSL:     dF.GetStorageUseSSL(),
+		Expiration: dF.GetStorageExpiration(),
+		RawOutputs: dF.GetStorageRawOutputs(),
+	}
+}
+
+func (dF DefaultConfig) getJanitorConfig() *JanitorConfig {
+	return &JanitorConfig{
+		Interval:        dF.getMinutesFromEnv("HUSKYCI_JANITOR_INTERVAL_MINUTES", 10),
+		ContainerMaxAge: dF.getMinutesFromEnv("HUSKYCI_JANITOR_CONTAINER_MAX_AGE_MINUTES", 60),
+		ZipMaxAge:       dF.getMinutesFromEnv("HUSKYCI_JANITOR_ZIP_MAX_AGE_MINUTES", 24*60),
+	}
+}
+
+// getImageGCConfig returns the image garbage collection
+// configuration. Images are collected every 6 hours unless
+// HUSKYCI_IMAGE_GC_INTERVAL_MINUTES is set, 0 disabling the
+// collection, once unreferenced for 24 hours unless
+// HUSKYCI_IMAGE_GC_PROTECTION_HOURS is set.
+func (dF DefaultConfig) getImageGCConfig() *ImageGCConfig {
+	intervalMinutes, err := dF.Caller.ConvertStrToInt(dF.Caller.GetEnvironmentVariable("HUSKYCI_IMAGE_GC_INTERVAL_MINUTES"))
+	if err != nil || intervalMinutes < 0 {
+		intervalMinutes = 6 * 60
+	}
+	protectionHours, err := dF.Caller.ConvertStrToInt(dF.Caller.GetEnvironmentVariable("HUSKYCI_IMAGE_GC_PROTECTION_HOURS"))
+	if err != nil || protectionHours < 0 {
+		protectionHours = 24
+	}
+	return &ImageGCConfig{
+		Interval:         time.Duration(intervalMinutes) * time.Minute,
+		ProtectionWindow: time.Duration(protectionHours) * time.Hour,
+	}
+}
+
+// getWarmPoolConfig returns the warm pool configuration.
+// 2 containers are kept per image unless HUSKYCI_WARM_POOL_SIZE
+// is set, 0 disabling the pool.
+func (dF DefaultConfig) getWarmPoolConfig() *WarmPoolConfig {
+	size, err := dF.Caller.ConvertStrToInt(dF.Caller.GetEnvironmentVariable("HUSKYCI_WARM_POOL_SIZE"))
+	if err != nil || size < 0 {
+		size = 2
+	}
+	return &WarmPoolConfig{
+		Size:        size,
+		IdleTimeout: dF.getMinutesFromEnv("HUSKYCI_WARM_POOL_IDLE_TIMEOUT_MINUTES", 10),
+		MaxLifetime: dF.getMinutesFromEnv("HUSKYCI_WARM_POOL_MAX_LIFETIME_MINUTES", 60),
+	}
+}
+
+// getDockerClientPoolConfig returns the configuration of the
+// reuse of the Docker API clients. A client is closed once
+// idle for 5 minutes unless HUSKYCI_DOCKERAPI_CLIENT_IDLE_MINUTES
+// is set, 0 disabling the reuse, and pinged every 30 seconds
+// unless HUSKYCI_DOCKERAPI_CLIENT_HEALTH_SECONDS is set.
+func (dF DefaultConfig) getDockerClientPoolConfig() *DockerClientPoolConfig {
+	idleMinutes, err := dF.Caller.ConvertStrToInt(dF.Caller.GetEnvironmentVariable("HUSKYCI_DOCKERAPI_CLIENT_IDLE_MINUTES"))
+	if err != nil || idleMinutes < 0 {
+		idleMinutes = 5
+	}
+	healthSeconds, err := dF.Caller.ConvertStrToInt(dF.Caller.GetEnvironmentVariable("HUSKYCI_DOCKERAPI_CLIENT_HEALTH_SECONDS"))
+	if err != nil || healthSeconds <= 0 {
+		healthSeconds = 30
+	}
+	return &DockerClientPoolConfig{
+		IdleTimeout:    time.Duration(idleMinutes) * time.Minute,
+		HealthInterval: time.Duration(healthSeconds) * time.Second,
+	}
+}
+
+// getScalingConfig returns the scaling hints configuration.
+// The hosts are the Docker API hosts unless
+// HUSKYCI_SCALING_HOSTS is set, and 4 analyses run at once
+// on each of them unless HUSKYCI_SCALING_ANALYSES_PER_HOST
+// is set.
+func (dF DefaultConfig) getScalingConfig() *ScalingConfig {
+	hosts, err := dF.Caller.ConvertStrToInt(dF.Caller.GetEnvironmentVariable("HUSKYCI_SCALING_HOSTS"))
+	if err != nil || hosts <= 0 {
+		hosts = len(strings.Fields(dF.Caller.GetEnvironmentVariable("HUSKYCI_DOCKERAPI_ADDR")))
+	}
+	if hosts <= 0 {
+		hosts = 1
+	}
+	analysesPerHost, err := dF.Caller.ConvertStrToInt(dF.Caller.GetEnvironmentVariable("HUSKYCI_SCALING_ANALYSES_PER_HOST"))
+	if err != nil || analysesPerHost <= 0 {
+		analysesPerHost = 4
+	}
+	intervalSeconds, err := dF.Caller.ConvertStrToInt(dF.Caller.GetEnvironmentVariable("HUSKYCI_SCALING_INTERVAL_SECONDS"))
+	if err != nil || intervalSeconds <= 0 {
+		intervalSeconds = 30
+	}
+	backlogThreshold, err := dF.Caller.ConvertStrToInt(dF.Caller.GetEnvironmentVariable("HUSKYCI_SCALING_BACKLOG_THRESHOLD"))
+	if err != nil || backlogThreshold < 0 {
+		backlogThreshold = 0
+	}
+	minHosts, err := dF.Caller.ConvertStrToInt(dF.Caller.GetEnvironmentVariable("HUSKYCI_SCALING_MIN_HOSTS"))
+	if err != nil || minHosts <= 0 {
+		minHosts = 1
+	}
+	maxHosts, err := dF.Caller.ConvertStrToInt(dF.Caller.GetEnvironmentVariable("HUSKYCI_SCALING_MAX_HOSTS"))
+	if err != nil || maxHosts < 0 {
+		maxHosts = 0
+	}
+	return &ScalingConfig{
+		Interval:            time.Duration(intervalSeconds) * time.Second,
+		Hosts:               hosts,
+		AnalysesPerHost:     analysesPerHost,
+		BacklogThreshold:    backlogThreshold,
+		Cooldown:            dF.getMinutesFromEnv("HUSKYCI_SCALING_COOLDOWN_MINUTES", 5),
+		MinHosts:            minHosts,
+		MaxHosts:            maxHosts,
+		WebhookURL:          dF.Caller.GetEnvironmentVariable("HUSKYCI_SCALING_WEBHOOK_URL"),
+		KubernetesTarget:    dF.Caller.GetEnvironmentVariable("HUSKYCI_SCALING_KUBERNETES_TARGET"),
+		KubernetesNamespace: dF.Caller.GetEnvironmentVariable("HUSKYCI_SCALING_KUBERNETES_NAMESPACE"),
+	}
+}
+
+// getMinutesFromEnv returns the duration in minutes
+// set in envName or defaultMinutes if it is not a
+// positive integer.
+func (dF DefaultConfig) getMinutesFromEnv(envName string, defaultMinutes int) time.Duration {
+	minutes, err := dF.Caller.ConvertStrToInt(dF.Caller.GetEnvironmentVariable(envName))
+	if err != nil || minutes <= 0 {
+		minutes = defaultMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// GetPrepullInterval returns how often securityTest
+// images are pulled again on every Docker API host.
+// It depends on HUSKYCI_PREPULL_INTERVAL_HOURS and
+// a zero value disables the background job.
+func (dF DefaultConfig) GetPrepullInterval() time.Duration {
+	intervalHours, err := dF.Caller.ConvertStrToInt(
+		dF.Caller.GetEnvironmentVariable("HUSKYCI_PREPULL_INTERVAL_HOURS"))
+	if err != nil || intervalHours < 0 {
+		return 24 * time.Hour
+	}
+	return time.Duration(intervalHours) * time.Hour
+}
+
+// GetJavaBuildTimeout returns how long the build of
+// a Java project scanned by SpotBugs may take, set
+// in HUSKYCI_JAVA_BUILD_TIMEOUT_SECONDS. It is 30
+// minutes if it is not set.
+func (dF DefaultConfig) GetJavaBuildTimeout() time.Duration {
+	timeoutSeconds, err := dF.Caller.ConvertStrToInt(
+		dF.Caller.GetEnvironmentVariable("HUSKYCI_JAVA_BUILD_TIMEOUT_SECONDS"))
+	if err != nil || timeoutSeconds <= 0 {
+		return 30 * time.Minute
+	}
+	return time.Duration(timeoutSeconds) * time.Second
+}
+
+// GetV1Sunset returns when the deprecated v1 of
+// the API is removed, set in HUSKYCI_API_V1_SUNSET
+// as a YYYY-MM-DD date. It is zero, and announced
+// to no one, if it is not set.
+func (dF DefaultConfig) GetV1Sunset() time.Time {
+	sunset, err := time.Parse("2006-01-02",
+		dF.Caller.GetEnvironmentVariable("HUSKYCI_API_V1_SUNSET"))
+	if err != nil {
+		return time.Time{}
+	}
+	return sunset
+}
+
+func (dF DefaultConfig) getTracingConfig() *tracing.Config {
+	serviceName := dF.Caller.GetEnvironmentVariable("HUSKYCI_TRACING_SERVICE_NAME")
+	if serviceName == "" {
+		serviceName = "huskyci-api"
+	}
+	return &tracing.Config{
+		Endpoint:    dF.Caller.GetEnvironmentVariable("HUSKYCI_TRACING_OTLP_ENDPOINT"),
+		ServiceName: serviceName,
+		Insecure:    dF.GetTracingInsecure(),
+	}
+}
+
+// getRequestLimitsConfig returns the limits of the
+// requests: the size of their bodies in kilobytes, set in
+// HUSKYCI_API_MAX_BODY_SIZE_KB, how deeply their JSON
+// can be nested, set in HUSKYCI_API_MAX_JSON_DEPTH, and
+// how long they can take, set in
+// HUSKYCI_API_REQUEST_TIMEOUT_SECONDS.
+func (dF DefaultConfig) getRequestLimitsConfig() *limits.Config {
+	maxBodySize := int64(limits.DefaultMaxBodySize)
+	maxBodySizeKB, err := dF.Caller.ConvertStrToInt(dF.Caller.GetEnvironmentVariable("HUSKYCI_API_MAX_BODY_SIZE_KB"))
+	if err == nil && maxBodySizeKB > 0 {
+		maxBodySize = int64(maxBodySizeKB) << 10
+	}
+	maxJSONDepth, err := dF.Caller.ConvertStrToInt(dF.Caller.GetEnvironmentVariable("HUSKYCI_API_MAX_JSON_DEPTH"))
+	if err != nil || maxJSONDepth <= 0 {
+		maxJSONDepth = limits.DefaultMaxJSONDepth
+	}
+	timeoutSeconds, err := dF.Caller.ConvertStrToInt(dF.Caller.GetEnvironmentVariable("HUSKYCI_API_REQUEST_TIMEOUT_SECONDS"))
+	if err != nil || timeoutSeconds <= 0 {
+		timeoutSeconds = limits.DefaultTimeoutSeconds
+	}
+	return &limits.Config{
+		MaxBodySize:  maxBodySize,
+		MaxJSONDepth: maxJSONDepth,
+		Timeout:      time.Duration(timeoutSeconds) * time.Second,
+	}
+}
+
+// getUploadConfig returns how the uploaded zips
+// are checked: their maximum size in megabytes,
+// set in HUSKYCI_UPLOAD_MAX_SIZE_MB, and the
+// antivirus they are scanned with, a clamd daemon
+// at HUSKYCI_UPLOAD_CLAMD_ADDRESS or the command
+// in HUSKYCI_UPLOAD_SCAN_COMMAND, for up to
+// HUSKYCI_UPLOAD_SCAN_TIMEOUT_SECONDS.
+func (dF DefaultConfig) getUploadConfig() *upload.Config {
+	maxSize := int64(upload.DefaultMaxSize)
+	maxSizeMB, err := dF.Caller.ConvertStrToInt(dF.Caller.GetEnvironmentVariable("HUSKYCI_UPLOAD_MAX_SIZE_MB"))
+	if err == nil && maxSizeMB > 0 {
+		maxSize = int64(maxSizeMB) << 20
+	}
+	timeoutSeconds, err := dF.Caller.ConvertStrToInt(dF.Caller.GetEnvironmentVariable("HUSKYCI_UPLOAD_SCAN_TIMEOUT_SECONDS"))
+	if err != nil || timeoutSeconds <= 0 {
+		timeoutSeconds = 60
+	}
+	return &upload.Config{
+		MaxSize:      maxSize,
+		ClamdAddress: dF.Caller.GetEnvironmentVariable("HUSKYCI_UPLOAD_CLAMD_ADDRESS"),
+		ScanCommand:  dF.Caller.GetEnvironmentVariable("HUSKYCI_UPLOAD_SCAN_COMMAND"),
+		Timeout:      time.Duration(timeoutSeconds) * time.Second,
+	}
+}
+
+func (dF DefaultConfig) getContainerSecurityConfig() *ContainerSecurityConfig {
+	writablePaths := strings.Fields(dF.Caller.GetEnvironmentVariable("HUSKYCI_CONTAINER_WRITABLE_PATHS"))
+	if len(writablePaths) == 0 {
+		writablePaths = []string{"/tmp", "/root"}
+	}
+	return &ContainerSecurityConfig{
+		User:            dF.Caller.GetEnvironmentVariable("HUSKYCI_CONTAINER_USER"),
+		NoNewPrivileges: dF.GetContainerNoNewPrivileges(),
+		ReadOnlyRootfs:  dF.GetContainerReadOnlyRootfs(),
+		WritablePaths:   writablePaths,
+		CapAdd:          dF.GetContainerCapAdd(),
+		SeccompProfile:  dF.Caller.GetEnvironmentVariable("HUSKYCI_CONTAINER_SECCOMP_PROFILE"),
+		AppArmorProfile: dF.Caller.GetEnvironmentVariable("HUSKYCI_CONTAINER_APPARMOR_PROFILE"),
+	}
+}
+
+// GetContainerNoNewPrivileges returns false if the
+// processes of containers may gain privileges, as
+// through setuid binaries. This depends on
+// HUSKYCI_CONTAINER_NO_NEW_PRIVILEGES.
+func (dF DefaultConfig) GetContainerNoNewPrivileges() bool {
+	option := dF.Caller.GetEnvironmentVariable("HUSKYCI_CONTAINER_NO_NEW_PRIVILEGES")
+	if strings.EqualFold(option, "false") || option == "0" {
+		return false
+	}
+	return true
+}
+
+// GetContainerReadOnlyRootfs returns true if the root
+// filesystem of containers is read-only. This depends
+// on HUSKYCI_CONTAINER_READ_ONLY_ROOTFS.
+func (dF DefaultConfig) GetContainerReadOnlyRootfs() bool {
+	option := dF.Caller.GetEnvironmentVariable("HUSKYCI_CONTAINER_READ_ONLY_ROOTFS")
+	if strings.EqualFold(option, "true") || option == "1" {
+		return true
+	}
+	return false
+}
+
+// GetContainerCapAdd returns the capabilities set in
+// HUSKYCI_CONTAINER_CAP_ADD as a space separated list,
+// DefaultCapAdd if it is not set or none if it is "none".
+func (dF DefaultConfig) GetContainerCapAdd() []string {
+	option := dF.Caller.GetEnvironmentVariable("HUSKYCI_CONTAINER_CAP_ADD")
+	if strings.EqualFold(option, "none") {
+		return []string{}
+	}
+	capAdd := strings.Fields(option)
+	if len(capAdd) == 0 {
+		return append([]string{}, DefaultCapAdd...)
+	}
+	return capAdd
+}
+
+// getDebugTraceConfig returns how much the debug
+// tracing records, from nothing (0, the default) to
+// the commands and outputs of the containers (2),
+// set in HUSKYCI_DEBUG_TRACE_LEVEL, and how many
+// events it keeps, set in HUSKYCI_DEBUG_TRACE_SIZE.
+func (dF DefaultConfig) getDebugTraceConfig() *debugtrace.Config {
+	level, err := dF.Caller.ConvertStrToInt(dF.Caller.GetEnvironmentVariable("HUSKYCI_DEBUG_TRACE_LEVEL"))
+	if err != nil || level < debugtrace.Off {
+		level = debugtrace.Off
+	}
+	if level > debugtrace.Verbose {
+		level = debugtrace.Verbose
+	}
+	size, err := dF.Caller.ConvertStrToInt(dF.Caller.GetEnvironmentVariable("HUSKYCI_DEBUG_TRACE_SIZE"))
+	if err != nil || size <= 0 {
+		size = debugtrace.DefaultSize
+	}
+	return &debugtrace.Config{Level: level, Size: size}
+}
+
+func (dF DefaultConfig) getOfflineConfig() *OfflineConfig {
+	return &OfflineConfig{
+		Enabled:        dF.GetOfflineMode(),
+		RegistryMirror: dF.Caller.GetEnvironmentVariable("HUSKYCI_OFFLINE_REGISTRY_MIRROR"),
+		NpmRegistry:    dF.Caller.GetEnvironmentVariable("HUSKYCI_OFFLINE_NPM_REGISTRY"),
+		AdvisoryDBs:    dF.GetAdvisoryDBs(),
+	}
+}
+
+// GetOfflineMode returns true if huskyCI runs
+// without internet access. This depends on
+// HUSKYCI_OFFLINE variable.
+func (dF DefaultConfig) GetOfflineMode() bool {
+	option := dF.Caller.GetEnvironmentVariable("HUSKYCI_OFFLINE")
+	if strings.EqualFold(option, "true") || option == "1" {
+		return true
+	}
+	return false
+}
+
+// GetAdvisoryDBs returns the local advisory databases set in
+// HUSKYCI_OFFLINE_ADVISORY_DBS as space separated
+// securityTest=hostPath pairs, such as
+// "safety=/opt/safety-db trivy=/opt/trivy-cache".
+func (dF DefaultConfig) GetAdvisoryDBs() map[string]string {
+	advisoryDBs := map[string]string{}
+	for _, pair := range strings.Fields(dF.Caller.GetEnvironmentVariable("HUSKYCI_OFFLINE_ADVISORY_DBS")) {
+		securityTestName, hostPath, found := strings.Cut(pair, "=")
+		if !found || securityTestName == "" || hostPath == "" {
+			continue
+		}
+		advisoryDBs[securityTestName] = hostPath
+	}
+	return advisoryDBs
+}
+
+func (dF DefaultConfig) getSignatureConfig() *signature.Config {
+	return &signature.Config{
+		Enforce:    dF.GetImageSignatureEnforce(),
+		PublicKey:  dF.Caller.GetEnvironmentVariable("HUSKYCI_COSIGN_PUBLIC_KEY"),
+		CosignPath: dF.Caller.GetEnvironmentVariable("HUSKYCI_COSIGN_PATH"),
+		Timeout:    2 * time.Minute,
+	}
+}
+
+// GetImageSignatureEnforce returns true if
+// securityTest images must be pinned by digest
+// and signed by HUSKYCI_COSIGN_PUBLIC_KEY.
+// This depends on HUSKYCI_IMAGE_SIGNATURE_ENFORCE.
+func (dF DefaultConfig) GetImageSignatureEnforce() bool {
+	option := dF.Caller.GetEnvironmentVariable("HUSKYCI_IMAGE_SIGNATURE_ENFORCE")
+	if strings.EqualFold(option, "true") || option == "1" {
+		return true
+	}
+	return false
+}
+
+// GetTracingInsecure returns true if spans
+// should be exported to the OTLP collector
+// without TLS. This depends on
+// HUSKYCI_TRACING_INSECURE variable.
+func (dF DefaultConfig) GetTracingInsecure() bool {
+	option := dF.Caller.GetEnvironmentVariable("HUSKYCI_TRACING_INSECURE")
+	if strings.EqualFold(option, "true") || option == "1" {
+		return true
+	}
+	return false
+}
+
+// GetStorageUseSSL returns false only if
+// HUSKYCI_STORAGE_USE_SSL is explicitly
+// disabled. Object storage is reached over
+// HTTPS by default.
+func (dF DefaultConfig) GetStorageUseSSL() bool {
+	option := dF.Caller.GetEnvironmentVariable("HUSKYCI_STORAGE_USE_SSL")
+	if strings.EqualFold(option, "false") || option == "0" {
+		return false
+	}
+	return true
+}
+
+// GetStorageExpiration returns for how long
+// objects are kept in the object storage before
+// being expired. It depends on an env called
+// HUSKYCI_STORAGE_EXPIRATION_HOURS.
+func (dF DefaultConfig) GetStorageExpiration() time.Duration {
+	expirationHours, err := dF.Caller.ConvertStrToInt(
+		dF.Caller.GetEnvironmentVariable("HUSKYCI_STORAGE_EXPIRATION_HOURS"))
+	if err != nil || expirationHours <= 0 {
+		return 24 * time.Hour
+	}
+	return time.Duration(expirationHours) * time.Hour
+}
+
+// GetStorageRawOutputs returns true if raw
+// securityTest outputs should also be stored
+// in the object storage. This depends on
+// HUSKYCI_STORAGE_RAW_OUTPUTS variable.
+func (dF DefaultConfig) GetStorageRawOutputs() bool {
+	option := dF.Caller.GetEnvironmentVariable("HUSKYCI_STORAGE_RAW_OUTPUTS")
+	if strings.EqualFold(option, "true") || option == "1" {
+		return true
+	}
+	return false
+}
+
+// GetStorage returns the object storage backend
+// configured by HUSKYCI_STORAGE_BACKEND. A nil
+// Storage means zips are kept only on local disk.
+func (dF DefaultConfig) GetStorage(config *storage.Config) storage.Storage {
+	objectStorage, err := storage.New(config)
+	if err != nil {
+		fmt.Println("Error configuring object storage: ", err)
+		return nil
+	}
+	return objectStorage
+}
+
+func (dF DefaultConfig) getStatusCacheConfig() *statuscache.Config {
+	ttlSeconds, err := dF.Caller.ConvertStrToInt(dF.Caller.GetEnvironmentVariable("HUSKYCI_STATUS_CACHE_TTL_SECONDS"))
+	ttl := statuscache.DefaultTTL
+	if err == nil && ttlSeconds > 0 {
+		ttl = time.Duration(ttlSeconds) * time.Second
+	}
+	return &statuscache.Config{
+		Backend:       dF.Caller.GetEnvironmentVariable("HUSKYCI_STATUS_CACHE"),
+		RedisAddress:  dF.Caller.GetEnvironmentVariable("HUSKYCI_STATUS_CACHE_REDIS_ADDR"),
+		RedisPassword: dF.Caller.GetEnvironmentVariable("HUSKYCI_STATUS_CACHE_REDIS_PASSWORD"),
+		TTL:           ttl,
+	}
+}
+
+// GetStatusCache returns the cache of the running
+// analyses configured by HUSKYCI_STATUS_CACHE, memory
+// or redis. A nil Cache means they are always read
+// from the database.
+func (dF DefaultConfig) GetStatusCache(config *statuscache.Config) statuscache.Cache {
+	statusCache, err := statuscache.New(config)
+	if err != nil {
+		fmt.Println("Error configuring status cache: ", err)
+		return nil
+	}
+	return statusCache
+}
+
+func (dF DefaultConfig) getExportConfig() *export.Config {
+	sinks := []string{}
+	for _, sink := range strings.Split(dF.Caller.GetEnvironmentVariable("HUSKYCI_EXPORT_SINKS"), ",") {
+		if sink = strings.TrimSpace(sink); sink != "" {
+			sinks = append(sinks, sink)
+		}
+	}
+	batchSize, err := dF.Caller.ConvertStrToInt(dF.Caller.GetEnvironmentVariable("HUSKYCI_EXPORT_BATCH_SIZE"))
+	if err != nil || batchSize <= 0 {
+		batchSize = export.DefaultBatchSize
+	}
+	flushSeconds, err := dF.Caller.ConvertStrToInt(dF.Caller.GetEnvironmentVariable("HUSKYCI_EXPORT_FLUSH_SECONDS"))
+	flushInterval := export.DefaultFlushInterval
+	if err == nil && flushSeconds > 0 {
+		flushInterval = time.Duration(flushSeconds) * time.Second
+	}
+	maxRetries, err := dF.Caller.ConvertStrToInt(dF.Caller.GetEnvironmentVariable("HUSKYCI_EXPORT_MAX_RETRIES"))
+	if err != nil || maxRetries < 0 {
+		maxRetries = export.DefaultMaxRetries
+	}
+	return &export.Config{
+		Sinks:         sinks,
+		BatchSize:     batchSize,
+		FlushInterval: flushInterval,
+		MaxRetries:    maxRetries,
+		S3: &storage.Config{
+			Backend:   "s3",
+			Endpoint:  dF.Caller.GetEnvironmentVariable("HUSKYCI_EXPORT_S3_ENDPOINT"),
+			Region:    dF.Caller.GetEnvironmentVariable("HUSKYCI_EXPORT_S3_REGION"),
+			Bucket:    dF.Caller.GetEnvironmentVariable("HUSKYCI_EXPORT_S3_BUCKET"),
+			AccessKey: dF.Caller.GetEnvironmentVariable("HUSKYCI_EXPORT_S3_ACCESS_KEY"),
+			SecretKey: dF.Caller.GetEnvironmentVariable("HUSKYCI_EXPORT_S3_SECRET_KEY"),
+			Prefix:    dF.Caller.GetEnvironmentVariable("HUSKYCI_EXPORT_S3_PREFIX"),
+			UseSSL:    !strings.EqualFold(dF.Caller.GetEnvironmentVariable("HUSKYCI_EXPORT_S3_USE_SSL"), "false"),
+		},
+		BigQuery: export.BigQueryConfig{
+			Project:         dF.Caller.GetEnvironmentVariable("HUSKYCI_EXPORT_BIGQUERY_PROJECT"),
+			Dataset:         dF.Caller.GetEnvironmentVariable("HUSKYCI_EXPORT_BIGQUERY_DATASET"),
+			Table:           dF.Caller.GetEnvironmentVariable("HUSKYCI_EXPORT_BIGQUERY_TABLE"),
+			CredentialsFile: dF.Caller.GetEnvironmentVariable("HUSKYCI_EXPORT_BIGQUERY_CREDENTIALS"),
+		},
+		Elasticsearch: export.ElasticsearchConfig{
+			URL:      dF.Caller.GetEnvironmentVariable("HUSKYCI_EXPORT_ELASTICSEARCH_URL"),
+			Index:    dF.Caller.GetEnvironmentVariable("HUSKYCI_EXPORT_ELASTICSEARCH_INDEX"),
+			Username: dF.Caller.GetEnvironmentVariable("HUSKYCI_EXPORT_ELASTICSEARCH_USERNAME"),
+			Password: dF.Caller.GetEnvironmentVariable("HUSKYCI_EXPORT_ELASTICSEARCH_PASSWORD"),
+			APIKey:   dF.Caller.GetEnvironmentVariable("HUSKYCI_EXPORT_ELASTICSEARCH_API_KEY"),
+		},
+	}
+}
+
+// GetExporter returns the exporter of the findings
+// of finished analyses to the sinks listed in
+// HUSKYCI_EXPORT_SINKS. A nil Exporter means they
+// are not exported.
+func (dF DefaultConfig) GetExporter(config *export.Config) *export.Exporter {
+	exporter, err := export.New(config)
+	if err != nil {
+		fmt.Println("Error configuring the export of findings: ", err)
+		return nil
+	}
+	return exporter
+}
+
 func (dF DefaultConfig) getSecurityTestConfig(securityTestName string) *types.SecurityTest {
 	return &types.SecurityTest{
 		Name:             dF.Caller.GetStringFromConfigFile(fmt.Sprintf("%s.name", securityTestName)),
 		Image:            dF.Caller.GetStringFromConfigFile(fmt.Sprintf("%s.image", securityTestName)),
 		ImageTag:         dF.Caller.GetStringFromConfigFile(fmt.Sprintf("%s.imageTag", securityTestName)),
+		ImageDigest:      dF.Caller.GetStringFromConfigFile(fmt.Sprintf("%s.imageDigest", securityTestName)),
 		Cmd:              dF.Caller.GetStringFromConfigFile(fmt.Sprintf("%s.cmd", securityTestName)),
 		Type:             dF.Caller.GetStringFromConfigFile(fmt.Sprintf("%s.type", securityTestName)),
 		Language:         dF.Caller.GetStringFromConfigFile(fmt.Sprintf("%s.language", securityTestName)),
 		Default:          dF.Caller.GetBoolFromConfigFile(fmt.Sprintf("%s.default", securityTestName)),
 		TimeOutInSeconds: dF.Caller.GetIntFromConfigFile(fmt.Sprintf("%s.timeOutInSeconds", securityTestName)),
+		NetworkMode:      dF.Caller.GetStringFromConfigFile(fmt.Sprintf("%s.networkMode", securityTestName)),
+		MaxOutputSizeKB:  dF.Caller.GetIntFromConfigFile(fmt.Sprintf("%s.maxOutputSizeKB", securityTestName)),
+		WarmPool:         dF.Caller.GetBoolFromConfigFile(fmt.Sprintf("%s.warmPool", securityTestName)),
 	}
 }
 
@@ -396,9 +1479,102 @@ func (dF DefaultConfig) GetDB() db.Requests {
 			DataRetriever: &sqlJSONRetriever,
 			JSONHandler:   &jsonHandler,
 		}
-		return &postgres
+		return db.NewEncryptedRequests(&postgres, dF.getDBKeyring())
+	}
+	return db.NewEncryptedRequests(&db.MongoRequests{CompressResultsAbove: dF.GetDBCompressResultsAbove()}, dF.getDBKeyring())
+}
+
+// getDBKeyring returns the keys the sensitive fields stored in the
+// database are encrypted with, from HUSKYCI_DB_ENCRYPTION_KEYS, the file
+// HUSKYCI_DB_ENCRYPTION_KEYS_FILE and the legacy passphrase
+// HUSKYCI_GIT_CREDENTIALS_KEY. Without keys, these fields can not be stored.
+func (dF DefaultConfig) getDBKeyring() *fieldcrypt.Keyring {
+	keyring, err := fieldcrypt.NewKeyring(*dF.getDBEncryptionConfig())
+	if err != nil {
+		fmt.Println("Error configuring the database encryption keys: ", err)
+		return nil
+	}
+	return keyring
+}
+
+// GetDBCompressResultsAbove returns the size in bytes
+// above which the results of an analysis are stored
+// compressed in MongoDB. This depends on an environment
+// var called HUSKYCI_DATABASE_COMPRESS_RESULTS_KB, 1024
+// by default. A negative value disables the compression.
+func (dF DefaultConfig) GetDBCompressResultsAbove() int {
+	compressResultsKB, err := dF.Caller.ConvertStrToInt(dF.Caller.GetEnvironmentVariable("HUSKYCI_DATABASE_COMPRESS_RESULTS_KB"))
+	if err != nil || compressResultsKB == 0 {
+		return 1024 << 10
+	}
+	if compressResultsKB < 0 {
+		return 0
+	}
+	return compressResultsKB << 10
+}
+
+// GetMaxOutputSize returns the size in bytes the output
+// of a securityTest container is cut at, unless its
+// securityTest sets its own. It depends on
+// HUSKYCI_CONTAINER_MAX_OUTPUT_SIZE_MB, 64 by default,
+// and a negative value keeps the whole output.
+func (dF DefaultConfig) GetMaxOutputSize() int64 {
+	maxOutputSizeMB, err := dF.Caller.ConvertStrToInt(dF.Caller.GetEnvironmentVariable("HUSKYCI_CONTAINER_MAX_OUTPUT_SIZE_MB"))
+	if err != nil || maxOutputSizeMB == 0 {
+		return 64 << 20
+	}
+	if maxOutputSizeMB < 0 {
+		return 0
+	}
+	return int64(maxOutputSizeMB) << 20
+}
+
+// GetRawOutputMaxSize returns the size in bytes the raw
+// output of a securityTest container is cut at as it is
+// stored compressed in the database. It depends on
+// HUSKYCI_CONTAINER_RAW_OUTPUT_MAX_SIZE_KB, 1024 by
+// default, and a negative value stores no raw output.
+func (dF DefaultConfig) GetRawOutputMaxSize() int {
+	rawOutputMaxSizeKB, err := dF.Caller.ConvertStrToInt(dF.Caller.GetEnvironmentVariable("HUSKYCI_CONTAINER_RAW_OUTPUT_MAX_SIZE_KB"))
+	if err != nil || rawOutputMaxSizeKB == 0 {
+		return 1024 << 10
+	}
+	if rawOutputMaxSizeKB < 0 {
+		return 0
+	}
+	return rawOutputMaxSizeKB << 10
+}
+
+// GetIdempotencyTTL returns how long a POST /analysis
+// with an Idempotency-Key returns the analysis it
+// started when it is sent again, set in
+// HUSKYCI_API_IDEMPOTENCY_TTL_HOURS. It is 24 hours
+// if it is not set.
+func (dF DefaultConfig) GetIdempotencyTTL() time.Duration {
+	ttlHours, err := dF.Caller.ConvertStrToInt(dF.Caller.GetEnvironmentVariable("HUSKYCI_API_IDEMPOTENCY_TTL_HOURS"))
+	if err != nil || ttlHours <= 0 {
+		return 24 * time.Hour
+	}
+	return time.Duration(ttlHours) * time.Hour
+}
+
+// Modes of a POST /analysis of a branch whose analysis
+// is running: BranchConcurrencyReject answers 409 and
+// BranchConcurrencyQueue queues it to start next.
+const (
+	BranchConcurrencyReject = "reject"
+	BranchConcurrencyQueue  = "queue"
+)
+
+// GetBranchConcurrency returns what a POST /analysis
+// of a branch whose analysis is running does, set in
+// HUSKYCI_API_BRANCH_CONCURRENCY. It is rejected
+// unless it is set to queue.
+func (dF DefaultConfig) GetBranchConcurrency() string {
+	if dF.Caller.GetEnvironmentVariable("HUSKYCI_API_BRANCH_CONCURRENCY") == BranchConcurrencyQueue {
+		return BranchConcurrencyQueue
 	}
-	return &db.MongoRequests{}
+	return BranchConcurrencyReject
 }
 
 // GetCache returns a new cache based on the HUSKYCI_CACHE_DEFAULT_EXPIRATION
@@ -2,6 +2,7 @@ package context_test
 
 import (
 	"errors"
+	"net"
 	"time"
 
 	. "github.com/onsi/ginkgo"
@@ -9,7 +10,17 @@ import (
 
 	. "github.com/huskyci-org/huskyCI/api/context"
 	"github.com/huskyci-org/huskyCI/api/db"
+	"github.com/huskyci-org/huskyCI/api/debugtrace"
+	"github.com/huskyci-org/huskyCI/api/export"
+	"github.com/huskyci-org/huskyCI/api/fieldcrypt"
+	"github.com/huskyci-org/huskyCI/api/limits"
+	"github.com/huskyci-org/huskyCI/api/signature"
+	"github.com/huskyci-org/huskyCI/api/statuscache"
+	"github.com/huskyci-org/huskyCI/api/storage"
+	"github.com/huskyci-org/huskyCI/api/tlscert"
+	"github.com/huskyci-org/huskyCI/api/tracing"
 	"github.com/huskyci-org/huskyCI/api/types"
+	"github.com/huskyci-org/huskyCI/api/upload"
 )
 
 type FakeCaller struct {
@@ -215,6 +226,41 @@ var _ = Describe("Context", func() {
 			})
 		})
 	})
+	Describe("GetDBCompressResultsAbove", func() {
+		Context("When ConvertStr returns an error", func() {
+			It("Should compress the results above 1MB", func() {
+				fakeCaller := FakeCaller{
+					expectedConvertStrToIntError: errors.New("Error during the convertion from string to integer"),
+				}
+				config := DefaultConfig{
+					Caller: &fakeCaller,
+				}
+				Expect(config.GetDBCompressResultsAbove()).To(Equal(1 << 20))
+			})
+		})
+		Context("When ConvertStr returns a negative value", func() {
+			It("Should not compress the results", func() {
+				fakeCaller := FakeCaller{
+					expectedIntegerValue: -1,
+				}
+				config := DefaultConfig{
+					Caller: &fakeCaller,
+				}
+				Expect(config.GetDBCompressResultsAbove()).To(Equal(0))
+			})
+		})
+		Context("When ConvertStr returns a valid value", func() {
+			It("Should return the value in bytes", func() {
+				fakeCaller := FakeCaller{
+					expectedIntegerValue: 64,
+				}
+				config := DefaultConfig{
+					Caller: &fakeCaller,
+				}
+				Expect(config.GetDBCompressResultsAbove()).To(Equal(64 << 10))
+			})
+		})
+	})
 	Describe("GetDockerAPIPort", func() {
 		Context("When ConvertStrToInt returns an error", func() {
 			It("Should return 2376 port", func() {
@@ -265,6 +311,159 @@ var _ = Describe("Context", func() {
 			})
 		})
 	})
+	Describe("GetDockerAPICodeDelivery", func() {
+		Context("When GetEnvironmentVariable returns copy", func() {
+			It("Should return CodeDeliveryCopy", func() {
+				fakeCaller := FakeCaller{
+					expectedEnvVar: "copy",
+				}
+				config := DefaultConfig{
+					Caller: &fakeCaller,
+				}
+				Expect(config.GetDockerAPICodeDelivery()).To(Equal(CodeDeliveryCopy))
+			})
+		})
+		Context("When GetEnvironmentVariable returns anything else", func() {
+			It("Should return CodeDeliveryVolume", func() {
+				fakeCaller := FakeCaller{
+					expectedEnvVar: "",
+				}
+				config := DefaultConfig{
+					Caller: &fakeCaller,
+				}
+				Expect(config.GetDockerAPICodeDelivery()).To(Equal(CodeDeliveryVolume))
+			})
+		})
+	})
+	Describe("ProxyFor", func() {
+		dockerHostsConfig := &DockerHostsConfig{
+			ProxyAddress: "http://proxy:3128",
+			HostProxies:  map[string]string{"dockerapi-2": "http://proxy-2:3128", "dockerapi-3": ""},
+		}
+		Context("When the host has its own proxy", func() {
+			It("Should return the host proxy", func() {
+				Expect(dockerHostsConfig.ProxyFor("dockerapi-2")).To(Equal("http://proxy-2:3128"))
+			})
+		})
+		Context("When the host proxy is explicitly empty", func() {
+			It("Should return no proxy", func() {
+				Expect(dockerHostsConfig.ProxyFor("dockerapi-3")).To(Equal(""))
+			})
+		})
+		Context("When the host has no proxy of its own", func() {
+			It("Should return the default proxy", func() {
+				Expect(dockerHostsConfig.ProxyFor("dockerapi-1")).To(Equal("http://proxy:3128"))
+			})
+		})
+	})
+
+	Describe("DBConfig credentials", func() {
+		It("Should be changed while they are read", func() {
+			dbConfig := &DBConfig{Username: "huskyci-1", Password: "secret-1"}
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for i := 0; i < 100; i++ {
+					dbConfig.Credentials()
+				}
+			}()
+			dbConfig.SetCredentials("huskyci-2", "secret-2")
+			<-done
+			username, password := dbConfig.Credentials()
+			Expect(username).To(Equal("huskyci-2"))
+			Expect(password).To(Equal("secret-2"))
+		})
+	})
+
+	Describe("ParseNetworks", func() {
+		Context("When the networks are valid", func() {
+			It("Should return the networks and the addresses as hosts", func() {
+				networks, err := ParseNetworks("10.0.0.0/8, 192.168.1.10 2001:db8::/32")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(networks).To(HaveLen(3))
+				Expect(AllowsIP(networks, net.ParseIP("10.1.2.3"))).To(BeTrue())
+				Expect(AllowsIP(networks, net.ParseIP("192.168.1.10"))).To(BeTrue())
+				Expect(AllowsIP(networks, net.ParseIP("192.168.1.11"))).To(BeFalse())
+				Expect(AllowsIP(networks, net.ParseIP("2001:db8::1"))).To(BeTrue())
+			})
+		})
+		Context("When a network is invalid", func() {
+			It("Should return an error", func() {
+				_, err := ParseNetworks("10.0.0.0/33")
+				Expect(err).To(HaveOccurred())
+			})
+		})
+		Context("When there is no allow-list", func() {
+			It("Should allow any address, unlike an empty one", func() {
+				Expect(AllowsIP(nil, net.ParseIP("203.0.113.7"))).To(BeTrue())
+				Expect(AllowsIP([]*net.IPNet{}, net.ParseIP("203.0.113.7"))).To(BeFalse())
+			})
+		})
+	})
+
+	Describe("GetAllowOrigins", func() {
+		Context("When several origins are set", func() {
+			It("Should return each of them", func() {
+				config := DefaultConfig{Caller: &FakeCaller{expectedEnvVar: "https://a.example.com, https://b.example.com"}}
+				Expect(config.GetAllowOrigins()).To(Equal([]string{"https://a.example.com", "https://b.example.com"}))
+			})
+		})
+		Context("When no origin is set", func() {
+			It("Should return the default one", func() {
+				config := DefaultConfig{Caller: &FakeCaller{}}
+				Expect(config.GetAllowOrigins()).To(Equal([]string{"http://127.0.0.1:8888"}))
+			})
+		})
+	})
+
+	Describe("MirrorImage", func() {
+		offlineConfig := &OfflineConfig{RegistryMirror: "registry.local:5000/huskyci/"}
+		Context("When the image has no registry host", func() {
+			It("Should prefix the image with the mirror", func() {
+				Expect(offlineConfig.MirrorImage("huskyciorg/gosec")).To(Equal("registry.local:5000/huskyci/huskyciorg/gosec"))
+				Expect(offlineConfig.MirrorImage("alpine")).To(Equal("registry.local:5000/huskyci/alpine"))
+			})
+		})
+		Context("When the image has a registry host", func() {
+			It("Should replace the host with the mirror", func() {
+				Expect(offlineConfig.MirrorImage("ghcr.io/huskyci-org/gosec")).To(Equal("registry.local:5000/huskyci/huskyci-org/gosec"))
+			})
+		})
+		Context("When the image already points to the mirror", func() {
+			It("Should keep the image untouched", func() {
+				Expect(offlineConfig.MirrorImage("registry.local:5000/huskyci/alpine")).To(Equal("registry.local:5000/huskyci/alpine"))
+			})
+		})
+		Context("When no mirror is configured", func() {
+			It("Should keep the image untouched", func() {
+				var nilConfig *OfflineConfig
+				Expect(nilConfig.MirrorImage("alpine")).To(Equal("alpine"))
+				Expect((&OfflineConfig{}).MirrorImage("alpine")).To(Equal("alpine"))
+			})
+		})
+	})
+
+	Describe("GetContainerCapAdd", func() {
+		Context("When GetEnvironmentVariable returns an empty value", func() {
+			It("Should return the default capabilities", func() {
+				config := DefaultConfig{Caller: &FakeCaller{expectedEnvVar: ""}}
+				Expect(config.GetContainerCapAdd()).To(Equal(DefaultCapAdd))
+			})
+		})
+		Context("When GetEnvironmentVariable returns none", func() {
+			It("Should return no capability", func() {
+				config := DefaultConfig{Caller: &FakeCaller{expectedEnvVar: "none"}}
+				Expect(config.GetContainerCapAdd()).To(BeEmpty())
+			})
+		})
+		Context("When GetEnvironmentVariable returns a list", func() {
+			It("Should return every capability of the list", func() {
+				config := DefaultConfig{Caller: &FakeCaller{expectedEnvVar: "CHOWN  SETUID"}}
+				Expect(config.GetContainerCapAdd()).To(Equal([]string{"CHOWN", "SETUID"}))
+			})
+		})
+	})
+
 	Describe("GetAPIConfig", func() {
 		Context("When SetConfigFile returns an error", func() {
 			It("Should return the expected error", func() {
@@ -298,9 +497,34 @@ var _ = Describe("Context", func() {
 					Port:             fakeCaller.expectedIntegerValue,
 					Version:          "0.14.0",
 					ReleaseDate:      "2020-06-24",
-					AllowOriginValue: fakeCaller.expectedEnvVar,
+					MinClientVersion: fakeCaller.expectedEnvVar,
+					AllowOrigins:     []string{fakeCaller.expectedEnvVar},
+					GzipLevel:        -1,
 					UseTLS:           true,
+					ClientCAFile:     fakeCaller.expectedEnvVar,
+					IPAllowListConfig: &IPAllowListConfig{
+						Token:          []*net.IPNet{},
+						Admin:          []*net.IPNet{},
+						Analysis:       []*net.IPNet{},
+						TrustedProxies: []*net.IPNet{},
+					},
+					LockoutConfig: &LockoutConfig{
+						MaxAttempts: fakeCaller.expectedIntegerValue,
+						BaseDelay:   time.Duration(fakeCaller.expectedIntegerValue) * time.Second,
+						MaxDelay:    time.Duration(fakeCaller.expectedIntegerValue) * time.Minute,
+					},
+					TLSCertConfig: &tlscert.Config{
+						ACMEDomains:      []string{fakeCaller.expectedEnvVar},
+						ACMEEmail:        fakeCaller.expectedEnvVar,
+						ACMECacheDir:     fakeCaller.expectedEnvVar,
+						ACMEDirectoryURL: fakeCaller.expectedEnvVar,
+						ACMEHTTPAddr:     fakeCaller.expectedEnvVar,
+					},
 					GitPrivateSSHKey: fakeCaller.expectedEnvVar,
+					GitCloneConfig: &GitCloneConfig{
+						Depth:       fakeCaller.expectedIntegerValue,
+						CacheVolume: fakeCaller.expectedEnvVar,
+					},
 					GraylogConfig: &GraylogConfig{
 						Address:        fakeCaller.expectedEnvVar,
 						Protocol:       fakeCaller.expectedEnvVar,
@@ -320,12 +544,24 @@ var _ = Describe("Context", func() {
 						MaxIdleConns:    fakeCaller.expectedIntegerValue,
 						ConnMaxLifetime: time.Duration(fakeCaller.expectedIntegerValue) * time.Hour,
 					},
+					DBEncryptionConfig: &fieldcrypt.Config{
+						Keys:             fakeCaller.expectedEnvVar,
+						KeysFile:         fakeCaller.expectedEnvVar,
+						CurrentKeyID:     fakeCaller.expectedEnvVar,
+						LegacyPassphrase: fakeCaller.expectedEnvVar,
+					},
 					DockerHostsConfig: &DockerHostsConfig{
 						Address:         "1",
 						DockerAPIPort:   fakeCaller.expectedIntegerValue,
 						PathCertificate: fakeCaller.expectedEnvVar,
 						Host:            "1:1234",
 						TLSVerify:       1,
+						EgressNetwork:   fakeCaller.expectedEnvVar,
+						EgressProxy:     fakeCaller.expectedEnvVar,
+						ProxyAddress:    fakeCaller.expectedEnvVar,
+						NoProxy:         fakeCaller.expectedEnvVar,
+						HostProxies:     map[string]string{},
+						CodeDelivery:    CodeDeliveryVolume,
 					},
 					KubernetesConfig: &KubernetesConfig{
 						ConfigFilePath:       fakeCaller.expectedEnvVar,
@@ -333,128 +569,330 @@ var _ = Describe("Context", func() {
 						ProxyAddress:         fakeCaller.expectedEnvVar,
 						NoProxyAddresses:     fakeCaller.expectedEnvVar,
 						PodSchedulingTimeout: fakeCaller.expectedIntegerValue,
+						JobBackoffLimit:      fakeCaller.expectedIntegerValue,
+						JobTTL:               fakeCaller.expectedIntegerValue,
 					},
 					EnrySecurityTest: &types.SecurityTest{
 						Name:             fakeCaller.expectedStringFromConfig,
 						Image:            fakeCaller.expectedStringFromConfig,
 						ImageTag:         fakeCaller.expectedStringFromConfig,
+						ImageDigest:      fakeCaller.expectedStringFromConfig,
 						Cmd:              fakeCaller.expectedStringFromConfig,
 						Type:             fakeCaller.expectedStringFromConfig,
 						Language:         fakeCaller.expectedStringFromConfig,
 						Default:          fakeCaller.expectedBoolFromConfig,
 						TimeOutInSeconds: fakeCaller.expectedIntFromConfig,
+						NetworkMode:      fakeCaller.expectedStringFromConfig,
+						MaxOutputSizeKB:  fakeCaller.expectedIntFromConfig,
+						WarmPool:         fakeCaller.expectedBoolFromConfig,
 					},
 					GitAuthorsSecurityTest: &types.SecurityTest{
 						Name:             fakeCaller.expectedStringFromConfig,
 						Image:            fakeCaller.expectedStringFromConfig,
 						ImageTag:         fakeCaller.expectedStringFromConfig,
+						ImageDigest:      fakeCaller.expectedStringFromConfig,
 						Cmd:              fakeCaller.expectedStringFromConfig,
 						Type:             fakeCaller.expectedStringFromConfig,
 						Language:         fakeCaller.expectedStringFromConfig,
 						Default:          fakeCaller.expectedBoolFromConfig,
 						TimeOutInSeconds: fakeCaller.expectedIntFromConfig,
+						NetworkMode:      fakeCaller.expectedStringFromConfig,
+						MaxOutputSizeKB:  fakeCaller.expectedIntFromConfig,
+						WarmPool:         fakeCaller.expectedBoolFromConfig,
+					},
+					GitBlameSecurityTest: &types.SecurityTest{
+						Name:             fakeCaller.expectedStringFromConfig,
+						Image:            fakeCaller.expectedStringFromConfig,
+						ImageTag:         fakeCaller.expectedStringFromConfig,
+						ImageDigest:      fakeCaller.expectedStringFromConfig,
+						Cmd:              fakeCaller.expectedStringFromConfig,
+						Type:             fakeCaller.expectedStringFromConfig,
+						Language:         fakeCaller.expectedStringFromConfig,
+						Default:          fakeCaller.expectedBoolFromConfig,
+						TimeOutInSeconds: fakeCaller.expectedIntFromConfig,
+						NetworkMode:      fakeCaller.expectedStringFromConfig,
+						MaxOutputSizeKB:  fakeCaller.expectedIntFromConfig,
+						WarmPool:         fakeCaller.expectedBoolFromConfig,
 					},
 					GosecSecurityTest: &types.SecurityTest{
 						Name:             fakeCaller.expectedStringFromConfig,
 						Image:            fakeCaller.expectedStringFromConfig,
 						ImageTag:         fakeCaller.expectedStringFromConfig,
+						ImageDigest:      fakeCaller.expectedStringFromConfig,
 						Cmd:              fakeCaller.expectedStringFromConfig,
 						Type:             fakeCaller.expectedStringFromConfig,
 						Language:         fakeCaller.expectedStringFromConfig,
 						Default:          fakeCaller.expectedBoolFromConfig,
 						TimeOutInSeconds: fakeCaller.expectedIntFromConfig,
+						NetworkMode:      fakeCaller.expectedStringFromConfig,
+						MaxOutputSizeKB:  fakeCaller.expectedIntFromConfig,
+						WarmPool:         fakeCaller.expectedBoolFromConfig,
 					},
 					BanditSecurityTest: &types.SecurityTest{
 						Name:             fakeCaller.expectedStringFromConfig,
 						Image:            fakeCaller.expectedStringFromConfig,
 						ImageTag:         fakeCaller.expectedStringFromConfig,
+						ImageDigest:      fakeCaller.expectedStringFromConfig,
 						Cmd:              fakeCaller.expectedStringFromConfig,
 						Type:             fakeCaller.expectedStringFromConfig,
 						Language:         fakeCaller.expectedStringFromConfig,
 						Default:          fakeCaller.expectedBoolFromConfig,
 						TimeOutInSeconds: fakeCaller.expectedIntFromConfig,
+						NetworkMode:      fakeCaller.expectedStringFromConfig,
+						MaxOutputSizeKB:  fakeCaller.expectedIntFromConfig,
+						WarmPool:         fakeCaller.expectedBoolFromConfig,
 					},
 					BrakemanSecurityTest: &types.SecurityTest{
 						Name:             fakeCaller.expectedStringFromConfig,
 						Image:            fakeCaller.expectedStringFromConfig,
 						ImageTag:         fakeCaller.expectedStringFromConfig,
+						ImageDigest:      fakeCaller.expectedStringFromConfig,
 						Cmd:              fakeCaller.expectedStringFromConfig,
 						Type:             fakeCaller.expectedStringFromConfig,
 						Language:         fakeCaller.expectedStringFromConfig,
 						Default:          fakeCaller.expectedBoolFromConfig,
 						TimeOutInSeconds: fakeCaller.expectedIntFromConfig,
+						NetworkMode:      fakeCaller.expectedStringFromConfig,
+						MaxOutputSizeKB:  fakeCaller.expectedIntFromConfig,
+						WarmPool:         fakeCaller.expectedBoolFromConfig,
 					},
 					NpmAuditSecurityTest: &types.SecurityTest{
 						Name:             fakeCaller.expectedStringFromConfig,
 						Image:            fakeCaller.expectedStringFromConfig,
 						ImageTag:         fakeCaller.expectedStringFromConfig,
+						ImageDigest:      fakeCaller.expectedStringFromConfig,
 						Cmd:              fakeCaller.expectedStringFromConfig,
 						Type:             fakeCaller.expectedStringFromConfig,
 						Language:         fakeCaller.expectedStringFromConfig,
 						Default:          fakeCaller.expectedBoolFromConfig,
 						TimeOutInSeconds: fakeCaller.expectedIntFromConfig,
+						NetworkMode:      fakeCaller.expectedStringFromConfig,
+						MaxOutputSizeKB:  fakeCaller.expectedIntFromConfig,
+						WarmPool:         fakeCaller.expectedBoolFromConfig,
 					},
 					YarnAuditSecurityTest: &types.SecurityTest{
 						Name:             fakeCaller.expectedStringFromConfig,
 						Image:            fakeCaller.expectedStringFromConfig,
 						ImageTag:         fakeCaller.expectedStringFromConfig,
+						ImageDigest:      fakeCaller.expectedStringFromConfig,
 						Cmd:              fakeCaller.expectedStringFromConfig,
 						Type:             fakeCaller.expectedStringFromConfig,
 						Language:         fakeCaller.expectedStringFromConfig,
 						Default:          fakeCaller.expectedBoolFromConfig,
 						TimeOutInSeconds: fakeCaller.expectedIntFromConfig,
+						NetworkMode:      fakeCaller.expectedStringFromConfig,
+						MaxOutputSizeKB:  fakeCaller.expectedIntFromConfig,
+						WarmPool:         fakeCaller.expectedBoolFromConfig,
 					},
 					SafetySecurityTest: &types.SecurityTest{
 						Name:             fakeCaller.expectedStringFromConfig,
 						Image:            fakeCaller.expectedStringFromConfig,
 						ImageTag:         fakeCaller.expectedStringFromConfig,
+						ImageDigest:      fakeCaller.expectedStringFromConfig,
 						Cmd:              fakeCaller.expectedStringFromConfig,
 						Type:             fakeCaller.expectedStringFromConfig,
 						Language:         fakeCaller.expectedStringFromConfig,
 						Default:          fakeCaller.expectedBoolFromConfig,
 						TimeOutInSeconds: fakeCaller.expectedIntFromConfig,
+						NetworkMode:      fakeCaller.expectedStringFromConfig,
+						MaxOutputSizeKB:  fakeCaller.expectedIntFromConfig,
+						WarmPool:         fakeCaller.expectedBoolFromConfig,
 					},
 					GitleaksSecurityTest: &types.SecurityTest{
 						Name:             fakeCaller.expectedStringFromConfig,
 						Image:            fakeCaller.expectedStringFromConfig,
 						ImageTag:         fakeCaller.expectedStringFromConfig,
+						ImageDigest:      fakeCaller.expectedStringFromConfig,
 						Cmd:              fakeCaller.expectedStringFromConfig,
 						Type:             fakeCaller.expectedStringFromConfig,
 						Language:         fakeCaller.expectedStringFromConfig,
 						Default:          fakeCaller.expectedBoolFromConfig,
 						TimeOutInSeconds: fakeCaller.expectedIntFromConfig,
+						NetworkMode:      fakeCaller.expectedStringFromConfig,
+						MaxOutputSizeKB:  fakeCaller.expectedIntFromConfig,
+						WarmPool:         fakeCaller.expectedBoolFromConfig,
 					},
 					SpotBugsSecurityTest: &types.SecurityTest{
 						Name:             fakeCaller.expectedStringFromConfig,
 						Image:            fakeCaller.expectedStringFromConfig,
 						ImageTag:         fakeCaller.expectedStringFromConfig,
+						ImageDigest:      fakeCaller.expectedStringFromConfig,
 						Cmd:              fakeCaller.expectedStringFromConfig,
 						Type:             fakeCaller.expectedStringFromConfig,
 						Language:         fakeCaller.expectedStringFromConfig,
 						Default:          fakeCaller.expectedBoolFromConfig,
 						TimeOutInSeconds: fakeCaller.expectedIntFromConfig,
+						NetworkMode:      fakeCaller.expectedStringFromConfig,
+						MaxOutputSizeKB:  fakeCaller.expectedIntFromConfig,
+						WarmPool:         fakeCaller.expectedBoolFromConfig,
 					},
 					TFSecSecurityTest: &types.SecurityTest{
 						Name:             fakeCaller.expectedStringFromConfig,
 						Image:            fakeCaller.expectedStringFromConfig,
 						ImageTag:         fakeCaller.expectedStringFromConfig,
+						ImageDigest:      fakeCaller.expectedStringFromConfig,
+						Cmd:              fakeCaller.expectedStringFromConfig,
+						Type:             fakeCaller.expectedStringFromConfig,
+						Language:         fakeCaller.expectedStringFromConfig,
+						Default:          fakeCaller.expectedBoolFromConfig,
+						TimeOutInSeconds: fakeCaller.expectedIntFromConfig,
+						NetworkMode:      fakeCaller.expectedStringFromConfig,
+						MaxOutputSizeKB:  fakeCaller.expectedIntFromConfig,
+						WarmPool:         fakeCaller.expectedBoolFromConfig,
+					},
+					TrivyConfigSecurityTest: &types.SecurityTest{
+						Name:             fakeCaller.expectedStringFromConfig,
+						Image:            fakeCaller.expectedStringFromConfig,
+						ImageTag:         fakeCaller.expectedStringFromConfig,
+						ImageDigest:      fakeCaller.expectedStringFromConfig,
 						Cmd:              fakeCaller.expectedStringFromConfig,
 						Type:             fakeCaller.expectedStringFromConfig,
 						Language:         fakeCaller.expectedStringFromConfig,
 						Default:          fakeCaller.expectedBoolFromConfig,
 						TimeOutInSeconds: fakeCaller.expectedIntFromConfig,
+						NetworkMode:      fakeCaller.expectedStringFromConfig,
+						MaxOutputSizeKB:  fakeCaller.expectedIntFromConfig,
+						WarmPool:         fakeCaller.expectedBoolFromConfig,
 					},
 					SecurityCodeScanSecurityTest: &types.SecurityTest{
 						Name:             fakeCaller.expectedStringFromConfig,
 						Image:            fakeCaller.expectedStringFromConfig,
 						ImageTag:         fakeCaller.expectedStringFromConfig,
+						ImageDigest:      fakeCaller.expectedStringFromConfig,
 						Cmd:              fakeCaller.expectedStringFromConfig,
 						Type:             fakeCaller.expectedStringFromConfig,
 						Language:         fakeCaller.expectedStringFromConfig,
 						Default:          fakeCaller.expectedBoolFromConfig,
 						TimeOutInSeconds: fakeCaller.expectedIntFromConfig,
+						NetworkMode:      fakeCaller.expectedStringFromConfig,
+						MaxOutputSizeKB:  fakeCaller.expectedIntFromConfig,
+						WarmPool:         fakeCaller.expectedBoolFromConfig,
+					},
+					StorageConfig: &storage.Config{
+						Backend:    fakeCaller.expectedEnvVar,
+						Endpoint:   fakeCaller.expectedEnvVar,
+						Region:     fakeCaller.expectedEnvVar,
+						Bucket:     fakeCaller.expectedEnvVar,
+						AccessKey:  fakeCaller.expectedEnvVar,
+						SecretKey:  fakeCaller.expectedEnvVar,
+						Prefix:     fakeCaller.expectedEnvVar,
+						UseSSL:     true,
+						Expiration: time.Duration(fakeCaller.expectedIntegerValue) * time.Hour,
+						RawOutputs: true,
+					},
+					TracingConfig: &tracing.Config{
+						Endpoint:    fakeCaller.expectedEnvVar,
+						ServiceName: fakeCaller.expectedEnvVar,
+						Insecure:    true,
+					},
+					PrepullInterval:   time.Duration(fakeCaller.expectedIntegerValue) * time.Hour,
+					JavaBuildTimeout:  time.Duration(fakeCaller.expectedIntegerValue) * time.Second,
+					MaxOutputSize:     int64(fakeCaller.expectedIntegerValue) << 20,
+					RawOutputMaxSize:  fakeCaller.expectedIntegerValue << 10,
+					IdempotencyTTL:    time.Duration(fakeCaller.expectedIntegerValue) * time.Hour,
+					BranchConcurrency: BranchConcurrencyReject,
+					JanitorConfig: &JanitorConfig{
+						Interval:        time.Duration(fakeCaller.expectedIntegerValue) * time.Minute,
+						ContainerMaxAge: time.Duration(fakeCaller.expectedIntegerValue) * time.Minute,
+						ZipMaxAge:       time.Duration(fakeCaller.expectedIntegerValue) * time.Minute,
+					},
+					ImageGCConfig: &ImageGCConfig{
+						Interval:         time.Duration(fakeCaller.expectedIntegerValue) * time.Minute,
+						ProtectionWindow: time.Duration(fakeCaller.expectedIntegerValue) * time.Hour,
+					},
+					WarmPoolConfig: &WarmPoolConfig{
+						Size:        fakeCaller.expectedIntegerValue,
+						IdleTimeout: time.Duration(fakeCaller.expectedIntegerValue) * time.Minute,
+						MaxLifetime: time.Duration(fakeCaller.expectedIntegerValue) * time.Minute,
+					},
+					DockerClientPoolConfig: &DockerClientPoolConfig{
+						IdleTimeout:    time.Duration(fakeCaller.expectedIntegerValue) * time.Minute,
+						HealthInterval: time.Duration(fakeCaller.expectedIntegerValue) * time.Second,
+					},
+					ScalingConfig: &ScalingConfig{
+						Interval:            time.Duration(fakeCaller.expectedIntegerValue) * time.Second,
+						Hosts:               fakeCaller.expectedIntegerValue,
+						AnalysesPerHost:     fakeCaller.expectedIntegerValue,
+						BacklogThreshold:    fakeCaller.expectedIntegerValue,
+						Cooldown:            time.Duration(fakeCaller.expectedIntegerValue) * time.Minute,
+						MinHosts:            fakeCaller.expectedIntegerValue,
+						MaxHosts:            fakeCaller.expectedIntegerValue,
+						WebhookURL:          fakeCaller.expectedEnvVar,
+						KubernetesTarget:    fakeCaller.expectedEnvVar,
+						KubernetesNamespace: fakeCaller.expectedEnvVar,
+					},
+					UploadConfig: &upload.Config{
+						MaxSize:      int64(fakeCaller.expectedIntegerValue) << 20,
+						ClamdAddress: fakeCaller.expectedEnvVar,
+						ScanCommand:  fakeCaller.expectedEnvVar,
+						Timeout:      time.Duration(fakeCaller.expectedIntegerValue) * time.Second,
+					},
+					RequestLimitsConfig: &limits.Config{
+						MaxBodySize:  int64(fakeCaller.expectedIntegerValue) << 10,
+						MaxJSONDepth: fakeCaller.expectedIntegerValue,
+						Timeout:      time.Duration(fakeCaller.expectedIntegerValue) * time.Second,
+					},
+					ContainerSecurityConfig: &ContainerSecurityConfig{
+						User:            fakeCaller.expectedEnvVar,
+						NoNewPrivileges: true,
+						ReadOnlyRootfs:  true,
+						WritablePaths:   []string{fakeCaller.expectedEnvVar},
+						CapAdd:          []string{fakeCaller.expectedEnvVar},
+						SeccompProfile:  fakeCaller.expectedEnvVar,
+						AppArmorProfile: fakeCaller.expectedEnvVar,
+					},
+					DebugTraceConfig: &debugtrace.Config{
+						Level: debugtrace.Verbose,
+						Size:  fakeCaller.expectedIntegerValue,
+					},
+					OfflineConfig: &OfflineConfig{
+						Enabled:        true,
+						RegistryMirror: fakeCaller.expectedEnvVar,
+						NpmRegistry:    fakeCaller.expectedEnvVar,
+						AdvisoryDBs:    map[string]string{},
+					},
+					SignatureConfig: &signature.Config{
+						Enforce:    true,
+						PublicKey:  fakeCaller.expectedEnvVar,
+						CosignPath: fakeCaller.expectedEnvVar,
+						Timeout:    2 * time.Minute,
+					},
+					StatusCacheConfig: &statuscache.Config{
+						Backend:       fakeCaller.expectedEnvVar,
+						RedisAddress:  fakeCaller.expectedEnvVar,
+						RedisPassword: fakeCaller.expectedEnvVar,
+						TTL:           time.Duration(fakeCaller.expectedIntegerValue) * time.Second,
+					},
+					ExportConfig: &export.Config{
+						Sinks:         []string{fakeCaller.expectedEnvVar},
+						BatchSize:     fakeCaller.expectedIntegerValue,
+						FlushInterval: time.Duration(fakeCaller.expectedIntegerValue) * time.Second,
+						MaxRetries:    fakeCaller.expectedIntegerValue,
+						S3: &storage.Config{
+							Backend:   "s3",
+							Endpoint:  fakeCaller.expectedEnvVar,
+							Region:    fakeCaller.expectedEnvVar,
+							Bucket:    fakeCaller.expectedEnvVar,
+							AccessKey: fakeCaller.expectedEnvVar,
+							SecretKey: fakeCaller.expectedEnvVar,
+							Prefix:    fakeCaller.expectedEnvVar,
+							UseSSL:    true,
+						},
+						BigQuery: export.BigQueryConfig{
+							Project:         fakeCaller.expectedEnvVar,
+							Dataset:         fakeCaller.expectedEnvVar,
+							Table:           fakeCaller.expectedEnvVar,
+							CredentialsFile: fakeCaller.expectedEnvVar,
+						},
+						Elasticsearch: export.ElasticsearchConfig{
+							URL:      fakeCaller.expectedEnvVar,
+							Index:    fakeCaller.expectedEnvVar,
+							Username: fakeCaller.expectedEnvVar,
+							Password: fakeCaller.expectedEnvVar,
+							APIKey:   fakeCaller.expectedEnvVar,
+						},
 					},
-					DBInstance: &db.MongoRequests{},
+					DBInstance: db.NewEncryptedRequests(&db.MongoRequests{CompressResultsAbove: fakeCaller.expectedIntegerValue << 10}, nil),
 					Cache:      apiConfig.Cache, // cannot be compared due to channels inside the structure
 				}
 				Expect(apiConfig).To(Equal(expectedConfig))
@@ -1,11 +1,12 @@
 package context
 
 import (
-	"os"
 	"strconv"
 	"time"
 
 	"github.com/spf13/viper"
+
+	"github.com/huskyci-org/huskyCI/api/vault"
 )
 
 // ExternalCalls is the extruct that performs exernal calls.
@@ -18,9 +19,10 @@ func (eC *ExternalCalls) SetConfigFile(configName, configPath string) error {
 	return viper.ReadInConfig()
 }
 
-// GetEnvironmentVariable will return the value of an env var.
+// GetEnvironmentVariable will return the value of an env var, or the secret
+// Vault issued for it.
 func (eC *ExternalCalls) GetEnvironmentVariable(envName string) string {
-	return os.Getenv(envName)
+	return vault.Getenv(envName)
 }
 
 // ConvertStrToInt converts a string into int.
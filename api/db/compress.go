@@ -0,0 +1,224 @@
+package db
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/api/spool"
+	"github.com/huskyci-org/huskyCI/api/types"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// compressedResultsField is the field of an analysis document holding its
+// huskyciresults and codes once compressed.
+const compressedResultsField = "compressedResults"
+
+// findingCountsField is the field of an analysis document holding the
+// FindingCounts of its huskyciresults, compressed or not.
+const findingCountsField = "findingCounts"
+
+// FindingCount is how many findings of an analysis securityTool found with
+// the same title and severity, the field of HuskyCISecurityTestOutput holding
+// them, such as highvulns. They are stored inline, so that the stats count
+// the findings of the analyses whose results are compressed.
+type FindingCount struct {
+	SecurityTool string `bson:"securityTool"`
+	Title        string `bson:"title"`
+	Severity     string `bson:"severity"`
+	Count        int    `bson:"count"`
+}
+
+// analysisResults are the fields of an analysis that grow with the size of
+// the repository analyzed.
+type analysisResults struct {
+	HuskyCIResults types.HuskyCIResults `bson:"huskyciresults"`
+	Codes          []types.Code         `bson:"codes"`
+}
+
+// CompressResults returns updateQuery with the huskyciresults and codes it
+// sets replaced by their gzip compressed BSON when it is larger than
+// threshold bytes, so that the analyses of large repositories fit in a
+// MongoDB document. The languages of the codes are kept inline, without their
+// files, for the stats. A threshold that is not positive compresses nothing.
+func CompressResults(updateQuery map[string]interface{}, threshold int) (map[string]interface{}, error) {
+	huskyCIResults, ok := updateQuery["huskyciresults"].(types.HuskyCIResults)
+	if !ok || threshold <= 0 {
+		return updateQuery, nil
+	}
+	codes, _ := updateQuery["codes"].([]types.Code)
+	raw, err := bson.Marshal(analysisResults{HuskyCIResults: huskyCIResults, Codes: codes})
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) <= threshold {
+		return updateQuery, nil
+	}
+
+	compressed, err := compress(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	compressedQuery := map[string]interface{}{}
+	for field, value := range updateQuery {
+		compressedQuery[field] = value
+	}
+	delete(compressedQuery, "huskyciresults")
+	if codes != nil {
+		languages := make([]types.Code, len(codes))
+		for i, code := range codes {
+			languages[i] = types.Code{Language: code.Language}
+		}
+		compressedQuery["codes"] = languages
+	}
+	compressedQuery[compressedResultsField] = compressed
+	return compressedQuery, nil
+}
+
+// ResultsUpdate returns the MongoDB update setting updateQuery on an analysis.
+// When updateQuery sets huskyciresults, their FindingCounts are set as well
+// and they are compressed as CompressResults does. The huskyciresults or the
+// compressedResults of a previous update they replace are then unset, so
+// that older results are never read instead of them.
+func ResultsUpdate(updateQuery map[string]interface{}, threshold int) (bson.M, error) {
+	huskyCIResults, ok := updateQuery["huskyciresults"].(types.HuskyCIResults)
+	if !ok {
+		return bson.M{"$set": updateQuery}, nil
+	}
+	counts, err := CountFindings(huskyCIResults)
+	if err != nil {
+		return nil, err
+	}
+	compressedQuery, err := CompressResults(updateQuery, threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	setQuery := bson.M{findingCountsField: counts}
+	for field, value := range compressedQuery {
+		setQuery[field] = value
+	}
+	replaced := compressedResultsField
+	if _, compressed := setQuery[compressedResultsField]; compressed {
+		replaced = "huskyciresults"
+	}
+	return bson.M{"$set": setQuery, "$unset": bson.M{replaced: ""}}, nil
+}
+
+// CountFindings returns the FindingCounts of results, sorted by severity,
+// securityTool and title.
+func CountFindings(results types.HuskyCIResults) ([]FindingCount, error) {
+	raw, err := bson.Marshal(results)
+	if err != nil {
+		return nil, err
+	}
+	// the results of each language, by securityTest and severity
+	type finding struct {
+		SecurityTool string `bson:"securitytool"`
+		Title        string `bson:"title"`
+	}
+	languages := map[string]map[string]map[string][]finding{}
+	if err := bson.Unmarshal(raw, &languages); err != nil {
+		return nil, err
+	}
+
+	counted := map[FindingCount]int{}
+	for _, securityTests := range languages {
+		for _, severities := range securityTests {
+			for severity, findings := range severities {
+				for _, f := range findings {
+					counted[FindingCount{SecurityTool: f.SecurityTool, Title: f.Title, Severity: severity}]++
+				}
+			}
+		}
+	}
+	counts := []FindingCount{}
+	for count, findings := range counted {
+		count.Count = findings
+		counts = append(counts, count)
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Severity != counts[j].Severity {
+			return counts[i].Severity < counts[j].Severity
+		}
+		if counts[i].SecurityTool != counts[j].SecurityTool {
+			return counts[i].SecurityTool < counts[j].SecurityTool
+		}
+		return counts[i].Title < counts[j].Title
+	})
+	return counts, nil
+}
+
+// DecompressResults restores the huskyciresults and codes of analysis if
+// they were compressed by CompressResults.
+func DecompressResults(analysis *types.Analysis) error {
+	if len(analysis.CompressedResults) == 0 {
+		return nil
+	}
+	raw, err := decompress(analysis.CompressedResults)
+	if err != nil {
+		return err
+	}
+	results := analysisResults{}
+	if err := bson.Unmarshal(raw, &results); err != nil {
+		return err
+	}
+	analysis.HuskyCIResults = results.HuskyCIResults
+	analysis.Codes = results.Codes
+	analysis.CompressedResults = nil
+	return nil
+}
+
+// CompressOutput returns the raw output of the container of securityTest that
+// ran in the analysis RID as it is stored, gzip compressed and cut at limit
+// bytes with the spool.TruncationMarker if limit is positive.
+func CompressOutput(RID, securityTest, output string, limit int) (types.ContainerOutput, error) {
+	containerOutput := types.ContainerOutput{RID: RID, SecurityTest: securityTest, CreatedAt: time.Now()}
+	if limit > 0 && len(output) > limit {
+		output = output[:limit] + fmt.Sprintf(spool.TruncationMarker, limit)
+		containerOutput.Truncated = true
+	}
+	compressed, err := compress([]byte(output))
+	if err != nil {
+		return types.ContainerOutput{}, err
+	}
+	containerOutput.Output = compressed
+	containerOutput.Size = len(output)
+	return containerOutput, nil
+}
+
+// DecompressOutput returns the raw output stored by CompressOutput.
+func DecompressOutput(containerOutput types.ContainerOutput) (string, error) {
+	raw, err := decompress(containerOutput.Output)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+// compress returns raw gzip compressed.
+func compress(raw []byte) ([]byte, error) {
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write(raw); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return compressed.Bytes(), nil
+}
+
+// decompress returns the bytes gzip compressed in compressed.
+func decompress(compressed []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
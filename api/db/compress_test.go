@@ -0,0 +1,163 @@
+package db_test
+
+import (
+	. "github.com/huskyci-org/huskyCI/api/db"
+	"github.com/huskyci-org/huskyCI/api/types"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var _ = Describe("CompressResults", func() {
+
+	results := types.HuskyCIResults{}
+	results.GoResults.HuskyCIGosecOutput.HighVulns = []types.HuskyCIVulnerability{
+		{Language: "Go", SecurityTool: "GoSec", Severity: "HIGH", File: "main.go", Line: "10", Details: "G104: Errors unhandled"},
+	}
+	codes := []types.Code{{Language: "Go", Files: []string{"main.go", "util.go"}}}
+
+	Context("When the results are smaller than the threshold", func() {
+		It("Should keep them inline", func() {
+			updateQuery := map[string]interface{}{"status": "finished", "huskyciresults": results, "codes": codes}
+			compressedQuery, err := CompressResults(updateQuery, 1<<20)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(compressedQuery).To(Equal(updateQuery))
+		})
+	})
+	Context("When the threshold is not positive", func() {
+		It("Should keep them inline", func() {
+			updateQuery := map[string]interface{}{"huskyciresults": results}
+			compressedQuery, err := CompressResults(updateQuery, 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(compressedQuery).To(Equal(updateQuery))
+		})
+	})
+	Context("When the results are larger than the threshold", func() {
+		It("Should compress them and keep the languages inline", func() {
+			updateQuery := map[string]interface{}{"status": "finished", "huskyciresults": results, "codes": codes}
+			compressedQuery, err := CompressResults(updateQuery, 1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(compressedQuery).NotTo(HaveKey("huskyciresults"))
+			Expect(compressedQuery).To(HaveKeyWithValue("status", "finished"))
+			Expect(compressedQuery).To(HaveKeyWithValue("codes", []types.Code{{Language: "Go"}}))
+			Expect(updateQuery).To(HaveKey("huskyciresults"))
+
+			analysis := types.Analysis{CompressedResults: compressedQuery["compressedResults"].([]byte)}
+			Expect(DecompressResults(&analysis)).To(Succeed())
+			Expect(analysis.HuskyCIResults).To(Equal(results))
+			Expect(analysis.Codes).To(Equal(codes))
+			Expect(analysis.CompressedResults).To(BeNil())
+		})
+	})
+})
+
+var _ = Describe("ResultsUpdate", func() {
+
+	results := types.HuskyCIResults{}
+	results.GoResults.HuskyCIGosecOutput.HighVulns = []types.HuskyCIVulnerability{
+		{SecurityTool: "GoSec", Severity: "HIGH", Title: "G104: Errors unhandled", File: "main.go", Line: "10"},
+		{SecurityTool: "GoSec", Severity: "HIGH", Title: "G104: Errors unhandled", File: "util.go", Line: "4"},
+	}
+	results.GoResults.HuskyCIGosecOutput.NoSecVulns = []types.HuskyCIVulnerability{
+		{SecurityTool: "GoSec", Severity: "LOW", Title: "G101: Hardcoded credentials", File: "main.go", Line: "3"},
+	}
+	results.GenericResults.HuskyCIGitleaksOutput.MediumVulns = []types.HuskyCIVulnerability{
+		{SecurityTool: "GitLeaks", Severity: "MEDIUM", Title: "AWS Access Key", File: "config.yaml", Line: "1"},
+	}
+	counts := []FindingCount{
+		{SecurityTool: "GoSec", Title: "G104: Errors unhandled", Severity: "highvulns", Count: 2},
+		{SecurityTool: "GitLeaks", Title: "AWS Access Key", Severity: "mediumvulns", Count: 1},
+		{SecurityTool: "GoSec", Title: "G101: Hardcoded credentials", Severity: "nosecvulns", Count: 1},
+	}
+
+	Context("When updateQuery sets no results", func() {
+		It("Should only set it", func() {
+			updateQuery := map[string]interface{}{"status": "running"}
+			Expect(ResultsUpdate(updateQuery, 1)).To(Equal(bson.M{"$set": updateQuery}))
+		})
+	})
+	Context("When the results are kept inline", func() {
+		It("Should set their counts and unset the results compressed before", func() {
+			updateQuery := map[string]interface{}{"status": "finished", "huskyciresults": results}
+			update, err := ResultsUpdate(updateQuery, 1<<20)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(update).To(Equal(bson.M{
+				"$set":   bson.M{"status": "finished", "huskyciresults": results, "findingCounts": counts},
+				"$unset": bson.M{"compressedResults": ""},
+			}))
+			Expect(updateQuery).NotTo(HaveKey("findingCounts"))
+		})
+	})
+	Context("When the results are compressed", func() {
+		It("Should set their counts inline and unset the results stored inline before", func() {
+			update, err := ResultsUpdate(map[string]interface{}{"huskyciresults": results}, 1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(update["$set"]).To(HaveKeyWithValue("findingCounts", counts))
+			Expect(update["$set"]).To(HaveKey("compressedResults"))
+			Expect(update["$set"]).NotTo(HaveKey("huskyciresults"))
+			Expect(update["$unset"]).To(Equal(bson.M{"huskyciresults": ""}))
+		})
+	})
+	Context("When there are no findings", func() {
+		It("Should set empty counts", func() {
+			update, err := ResultsUpdate(map[string]interface{}{"huskyciresults": types.HuskyCIResults{}}, 1<<20)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(update["$set"]).To(HaveKeyWithValue("findingCounts", []FindingCount{}))
+		})
+	})
+})
+
+var _ = Describe("DecompressResults", func() {
+	Context("When the results are not compressed", func() {
+		It("Should leave the analysis untouched", func() {
+			analysis := types.Analysis{RID: "abc", Codes: []types.Code{{Language: "Go"}}}
+			Expect(DecompressResults(&analysis)).To(Succeed())
+			Expect(analysis).To(Equal(types.Analysis{RID: "abc", Codes: []types.Code{{Language: "Go"}}}))
+		})
+	})
+	Context("When the compressed results are corrupted", func() {
+		It("Should return an error", func() {
+			analysis := types.Analysis{CompressedResults: []byte("not gzip")}
+			Expect(DecompressResults(&analysis)).NotTo(Succeed())
+		})
+	})
+})
+
+var _ = Describe("CompressOutput", func() {
+	Context("When the output is smaller than the limit", func() {
+		It("Should keep all of it", func() {
+			containerOutput, err := CompressOutput("abc", "gosec", "error: go.mod not found\n", 1024)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(containerOutput.RID).To(Equal("abc"))
+			Expect(containerOutput.SecurityTest).To(Equal("gosec"))
+			Expect(containerOutput.Truncated).To(BeFalse())
+			Expect(containerOutput.Size).To(Equal(24))
+			Expect(DecompressOutput(containerOutput)).To(Equal("error: go.mod not found\n"))
+		})
+	})
+	Context("When the output is larger than the limit", func() {
+		It("Should cut it and end it with the truncation marker", func() {
+			containerOutput, err := CompressOutput("abc", "gosec", "0123456789", 4)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(containerOutput.Truncated).To(BeTrue())
+			Expect(DecompressOutput(containerOutput)).To(Equal("0123\n[huskyCI] output truncated at 4 bytes\n"))
+		})
+	})
+	Context("When the limit is not positive", func() {
+		It("Should keep all of it", func() {
+			containerOutput, err := CompressOutput("abc", "gosec", "0123456789", 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(containerOutput.Truncated).To(BeFalse())
+			Expect(DecompressOutput(containerOutput)).To(Equal("0123456789"))
+		})
+	})
+})
+
+var _ = Describe("DecompressOutput", func() {
+	Context("When the output is corrupted", func() {
+		It("Should return an error", func() {
+			_, err := DecompressOutput(types.ContainerOutput{Output: []byte("not gzip")})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,269 @@
+package db
+
+import (
+	"time"
+
+	"github.com/huskyci-org/huskyCI/api/fieldcrypt"
+	"github.com/huskyci-org/huskyCI/api/types"
+)
+
+// rotationLock is the lock a single replica holds while it encrypts the
+// sensitive fields again with the current key.
+const rotationLock = "fieldcrypt-rotation"
+
+// EncryptedRequests implements Requests on top of another Requests,
+// encrypting the secrets of git and registry credentials, status reporters
+// and remediations before they are stored and decrypting them once read.
+type EncryptedRequests struct {
+	Requests
+	Keyring *fieldcrypt.Keyring
+}
+
+// NewEncryptedRequests returns requests with their sensitive fields encrypted
+// with keyring.
+func NewEncryptedRequests(requests Requests, keyring *fieldcrypt.Keyring) *EncryptedRequests {
+	return &EncryptedRequests{Requests: requests, Keyring: keyring}
+}
+
+// encrypt returns secret encrypted, or an empty secret as is.
+func (eR *EncryptedRequests) encrypt(secret string) (string, error) {
+	if secret == "" {
+		return "", nil
+	}
+	return eR.Keyring.Encrypt(secret)
+}
+
+// decrypt returns secret decrypted, or an empty secret as is.
+func (eR *EncryptedRequests) decrypt(secret string) (string, error) {
+	if secret == "" {
+		return "", nil
+	}
+	return eR.Keyring.Decrypt(secret)
+}
+
+// FindOneDBGitCredential returns the git credential of mapParams, its secret decrypted.
+func (eR *EncryptedRequests) FindOneDBGitCredential(mapParams map[string]interface{}) (types.GitCredential, error) {
+	credential, err := eR.Requests.FindOneDBGitCredential(mapParams)
+	if err != nil {
+		return credential, err
+	}
+	if credential.Secret, err = eR.decrypt(credential.Secret); err != nil {
+		return types.GitCredential{}, err
+	}
+	return credential, nil
+}
+
+// FindAllDBGitCredential returns the git credentials of mapParams, their secrets decrypted.
+func (eR *EncryptedRequests) FindAllDBGitCredential(mapParams map[string]interface{}) ([]types.GitCredential, error) {
+	credentials, err := eR.Requests.FindAllDBGitCredential(mapParams)
+	if err != nil {
+		return credentials, err
+	}
+	for i := range credentials {
+		if credentials[i].Secret, err = eR.decrypt(credentials[i].Secret); err != nil {
+			return nil, err
+		}
+	}
+	return credentials, nil
+}
+
+// UpsertOneDBGitCredential stores credential with its secret encrypted.
+func (eR *EncryptedRequests) UpsertOneDBGitCredential(mapParams map[string]interface{}, credential types.GitCredential) error {
+	var err error
+	if credential.Secret, err = eR.encrypt(credential.Secret); err != nil {
+		return err
+	}
+	return eR.Requests.UpsertOneDBGitCredential(mapParams, credential)
+}
+
+// FindOneDBRegistryCredential returns the registry credential of mapParams, its secret decrypted.
+func (eR *EncryptedRequests) FindOneDBRegistryCredential(mapParams map[string]interface{}) (types.RegistryCredential, error) {
+	credential, err := eR.Requests.FindOneDBRegistryCredential(mapParams)
+	if err != nil {
+		return credential, err
+	}
+	if credential.Secret, err = eR.decrypt(credential.Secret); err != nil {
+		return types.RegistryCredential{}, err
+	}
+	return credential, nil
+}
+
+// FindAllDBRegistryCredential returns the registry credentials of mapParams, their secrets decrypted.
+func (eR *EncryptedRequests) FindAllDBRegistryCredential(mapParams map[string]interface{}) ([]types.RegistryCredential, error) {
+	credentials, err := eR.Requests.FindAllDBRegistryCredential(mapParams)
+	if err != nil {
+		return credentials, err
+	}
+	for i := range credentials {
+		if credentials[i].Secret, err = eR.decrypt(credentials[i].Secret); err != nil {
+			return nil, err
+		}
+	}
+	return credentials, nil
+}
+
+// UpsertOneDBRegistryCredential stores credential with its secret encrypted.
+func (eR *EncryptedRequests) UpsertOneDBRegistryCredential(mapParams map[string]interface{}, credential types.RegistryCredential) error {
+	var err error
+	if credential.Secret, err = eR.encrypt(credential.Secret); err != nil {
+		return err
+	}
+	return eR.Requests.UpsertOneDBRegistryCredential(mapParams, credential)
+}
+
+// FindOneDBStatusReporter returns the status reporter of mapParams, its secret decrypted.
+func (eR *EncryptedRequests) FindOneDBStatusReporter(mapParams map[string]interface{}) (types.StatusReporter, error) {
+	reporter, err := eR.Requests.FindOneDBStatusReporter(mapParams)
+	if err != nil {
+		return reporter, err
+	}
+	if reporter.Secret, err = eR.decrypt(reporter.Secret); err != nil {
+		return types.StatusReporter{}, err
+	}
+	return reporter, nil
+}
+
+// FindAllDBStatusReporter returns the status reporters of mapParams, their secrets decrypted.
+func (eR *EncryptedRequests) FindAllDBStatusReporter(mapParams map[string]interface{}) ([]types.StatusReporter, error) {
+	reporters, err := eR.Requests.FindAllDBStatusReporter(mapParams)
+	if err != nil {
+		return reporters, err
+	}
+	for i := range reporters {
+		if reporters[i].Secret, err = eR.decrypt(reporters[i].Secret); err != nil {
+			return nil, err
+		}
+	}
+	return reporters, nil
+}
+
+// UpsertOneDBStatusReporter stores reporter with its secret encrypted.
+func (eR *EncryptedRequests) UpsertOneDBStatusReporter(mapParams map[string]interface{}, reporter types.StatusReporter) error {
+	var err error
+	if reporter.Secret, err = eR.encrypt(reporter.Secret); err != nil {
+		return err
+	}
+	return eR.Requests.UpsertOneDBStatusReporter(mapParams, reporter)
+}
+
+// FindOneDBRemediation returns the remediation of mapParams, its secret decrypted.
+func (eR *EncryptedRequests) FindOneDBRemediation(mapParams map[string]interface{}) (types.Remediation, error) {
+	remediation, err := eR.Requests.FindOneDBRemediation(mapParams)
+	if err != nil {
+		return remediation, err
+	}
+	if remediation.Secret, err = eR.decrypt(remediation.Secret); err != nil {
+		return types.Remediation{}, err
+	}
+	return remediation, nil
+}
+
+// FindAllDBRemediation returns the remediations of mapParams, their secrets decrypted.
+func (eR *EncryptedRequests) FindAllDBRemediation(mapParams map[string]interface{}) ([]types.Remediation, error) {
+	remediations, err := eR.Requests.FindAllDBRemediation(mapParams)
+	if err != nil {
+		return remediations, err
+	}
+	for i := range remediations {
+		if remediations[i].Secret, err = eR.decrypt(remediations[i].Secret); err != nil {
+			return nil, err
+		}
+	}
+	return remediations, nil
+}
+
+// UpsertOneDBRemediation stores remediation with its secret encrypted.
+func (eR *EncryptedRequests) UpsertOneDBRemediation(mapParams map[string]interface{}, remediation types.Remediation) error {
+	var err error
+	if remediation.Secret, err = eR.encrypt(remediation.Secret); err != nil {
+		return err
+	}
+	return eR.Requests.UpsertOneDBRemediation(mapParams, remediation)
+}
+
+// RotateKeys encrypts again with the current key the secrets that were
+// encrypted with another one, so that the previous keys can then be removed.
+// Only one replica rotates them at a time: it returns 0 if another one does.
+// It returns how many records were encrypted again.
+func (eR *EncryptedRequests) RotateKeys(owner string, ttl time.Duration) (int, error) {
+	acquired, err := eR.Requests.AcquireLock(rotationLock, owner, ttl)
+	if err != nil || !acquired {
+		return 0, err
+	}
+	defer eR.Requests.ReleaseLock(rotationLock, owner)
+
+	all := map[string]interface{}{}
+	rotated := 0
+	credentials, err := eR.Requests.FindAllDBGitCredential(all)
+	if err != nil {
+		return rotated, err
+	}
+	for _, credential := range credentials {
+		if !eR.Keyring.NeedsRotation(credential.Secret) {
+			continue
+		}
+		if credential.Secret, err = eR.decrypt(credential.Secret); err != nil {
+			return rotated, err
+		}
+		query := map[string]interface{}{"repositoryURL": credential.RepositoryURL}
+		if err := eR.UpsertOneDBGitCredential(query, credential); err != nil {
+			return rotated, err
+		}
+		rotated++
+	}
+
+	registryCredentials, err := eR.Requests.FindAllDBRegistryCredential(all)
+	if err != nil {
+		return rotated, err
+	}
+	for _, credential := range registryCredentials {
+		if !eR.Keyring.NeedsRotation(credential.Secret) {
+			continue
+		}
+		if credential.Secret, err = eR.decrypt(credential.Secret); err != nil {
+			return rotated, err
+		}
+		query := map[string]interface{}{"registry": credential.Registry}
+		if err := eR.UpsertOneDBRegistryCredential(query, credential); err != nil {
+			return rotated, err
+		}
+		rotated++
+	}
+
+	reporters, err := eR.Requests.FindAllDBStatusReporter(all)
+	if err != nil {
+		return rotated, err
+	}
+	for _, reporter := range reporters {
+		if !eR.Keyring.NeedsRotation(reporter.Secret) {
+			continue
+		}
+		if reporter.Secret, err = eR.decrypt(reporter.Secret); err != nil {
+			return rotated, err
+		}
+		query := map[string]interface{}{"repositoryURL": reporter.RepositoryURL}
+		if err := eR.UpsertOneDBStatusReporter(query, reporter); err != nil {
+			return rotated, err
+		}
+		rotated++
+	}
+
+	remediations, err := eR.Requests.FindAllDBRemediation(all)
+	if err != nil {
+		return rotated, err
+	}
+	for _, remediation := range remediations {
+		if !eR.Keyring.NeedsRotation(remediation.Secret) {
+			continue
+		}
+		if remediation.Secret, err = eR.decrypt(remediation.Secret); err != nil {
+			return rotated, err
+		}
+		query := map[string]interface{}{"repositoryURL": remediation.RepositoryURL}
+		if err := eR.UpsertOneDBRemediation(query, remediation); err != nil {
+			return rotated, err
+		}
+		rotated++
+	}
+	return rotated, nil
+}
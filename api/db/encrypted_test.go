@@ -0,0 +1,142 @@
+package db_test
+
+import (
+	"encoding/base64"
+	"strings"
+	"time"
+
+	. "github.com/huskyci-org/huskyCI/api/db"
+	"github.com/huskyci-org/huskyCI/api/fieldcrypt"
+	"github.com/huskyci-org/huskyCI/api/types"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// credentialsDB stores git and registry credentials in memory. The other
+// requests are left to the nil Requests it embeds.
+type credentialsDB struct {
+	Requests
+	credentials map[string]types.GitCredential
+	registries  map[string]types.RegistryCredential
+}
+
+func (c *credentialsDB) FindOneDBGitCredential(mapParams map[string]interface{}) (types.GitCredential, error) {
+	return c.credentials[mapParams["repositoryURL"].(string)], nil
+}
+
+func (c *credentialsDB) FindAllDBGitCredential(mapParams map[string]interface{}) ([]types.GitCredential, error) {
+	credentials := []types.GitCredential{}
+	for _, credential := range c.credentials {
+		credentials = append(credentials, credential)
+	}
+	return credentials, nil
+}
+
+func (c *credentialsDB) UpsertOneDBGitCredential(mapParams map[string]interface{}, credential types.GitCredential) error {
+	c.credentials[mapParams["repositoryURL"].(string)] = credential
+	return nil
+}
+
+func (c *credentialsDB) FindOneDBRegistryCredential(mapParams map[string]interface{}) (types.RegistryCredential, error) {
+	return c.registries[mapParams["registry"].(string)], nil
+}
+
+func (c *credentialsDB) FindAllDBRegistryCredential(mapParams map[string]interface{}) ([]types.RegistryCredential, error) {
+	credentials := []types.RegistryCredential{}
+	for _, credential := range c.registries {
+		credentials = append(credentials, credential)
+	}
+	return credentials, nil
+}
+
+func (c *credentialsDB) UpsertOneDBRegistryCredential(mapParams map[string]interface{}, credential types.RegistryCredential) error {
+	c.registries[mapParams["registry"].(string)] = credential
+	return nil
+}
+
+func (c *credentialsDB) FindAllDBStatusReporter(mapParams map[string]interface{}) ([]types.StatusReporter, error) {
+	return nil, nil
+}
+
+func (c *credentialsDB) FindAllDBRemediation(mapParams map[string]interface{}) ([]types.Remediation, error) {
+	return nil, nil
+}
+
+func (c *credentialsDB) AcquireLock(name, owner string, ttl time.Duration) (bool, error) {
+	return true, nil
+}
+
+func (c *credentialsDB) ReleaseLock(name, owner string) error {
+	return nil
+}
+
+func keyring(keys, current string) *fieldcrypt.Keyring {
+	keyring, err := fieldcrypt.NewKeyring(fieldcrypt.Config{Keys: keys, CurrentKeyID: current})
+	Expect(err).To(BeNil())
+	return keyring
+}
+
+var _ = Describe("EncryptedRequests", func() {
+
+	k1 := "k1:" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("a", 32)))
+	k2 := "k2:" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("b", 32)))
+	query := map[string]interface{}{"repositoryURL": "https://github.com/org/repo"}
+	credential := types.GitCredential{RepositoryURL: "https://github.com/org/repo", Type: "token", Secret: "ghp_token"}
+
+	Context("When a git credential is stored", func() {
+		It("Should store its secret encrypted and read it decrypted", func() {
+			stored := &credentialsDB{credentials: map[string]types.GitCredential{}}
+			requests := NewEncryptedRequests(stored, keyring(k1, ""))
+			Expect(requests.UpsertOneDBGitCredential(query, credential)).To(Succeed())
+			Expect(stored.credentials["https://github.com/org/repo"].Secret).To(HavePrefix("v1:k1:"))
+			found, err := requests.FindOneDBGitCredential(query)
+			Expect(err).To(BeNil())
+			Expect(found).To(Equal(credential))
+		})
+	})
+	Context("When a registry credential is stored", func() {
+		It("Should store its secret encrypted and encrypt it again when the current key changed", func() {
+			stored := &credentialsDB{credentials: map[string]types.GitCredential{}, registries: map[string]types.RegistryCredential{}}
+			registryQuery := map[string]interface{}{"registry": "registry.example.com"}
+			registry := types.RegistryCredential{Registry: "registry.example.com", Username: "huskyci", Secret: "s3cr3t"}
+			Expect(NewEncryptedRequests(stored, keyring(k1, "")).UpsertOneDBRegistryCredential(registryQuery, registry)).To(Succeed())
+			Expect(stored.registries["registry.example.com"].Secret).To(HavePrefix("v1:k1:"))
+
+			requests := NewEncryptedRequests(stored, keyring(k1+","+k2, "k2"))
+			rotated, err := requests.RotateKeys("test", time.Minute)
+			Expect(err).To(BeNil())
+			Expect(rotated).To(Equal(1))
+			found, err := NewEncryptedRequests(stored, keyring(k2, "")).FindOneDBRegistryCredential(registryQuery)
+			Expect(err).To(BeNil())
+			Expect(found).To(Equal(registry))
+		})
+	})
+	Context("When no key is configured", func() {
+		It("Should not store the git credential", func() {
+			stored := &credentialsDB{credentials: map[string]types.GitCredential{}}
+			requests := NewEncryptedRequests(stored, nil)
+			Expect(requests.UpsertOneDBGitCredential(query, credential)).To(Equal(fieldcrypt.ErrNoKey))
+			Expect(stored.credentials).To(BeEmpty())
+		})
+	})
+	Context("When the current key changed", func() {
+		It("Should encrypt the secrets again with it", func() {
+			stored := &credentialsDB{credentials: map[string]types.GitCredential{}}
+			Expect(NewEncryptedRequests(stored, keyring(k1, "")).UpsertOneDBGitCredential(query, credential)).To(Succeed())
+
+			requests := NewEncryptedRequests(stored, keyring(k1+","+k2, "k2"))
+			rotated, err := requests.RotateKeys("test", time.Minute)
+			Expect(err).To(BeNil())
+			Expect(rotated).To(Equal(1))
+			Expect(stored.credentials["https://github.com/org/repo"].Secret).To(HavePrefix("v1:k2:"))
+
+			found, err := NewEncryptedRequests(stored, keyring(k2, "")).FindOneDBGitCredential(query)
+			Expect(err).To(BeNil())
+			Expect(found.Secret).To(Equal("ghp_token"))
+
+			rotated, err = requests.RotateKeys("test", time.Minute)
+			Expect(err).To(BeNil())
+			Expect(rotated).To(Equal(0))
+		})
+	})
+})
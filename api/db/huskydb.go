@@ -6,6 +6,7 @@ import (
 	mongoHuskyCI "github.com/huskyci-org/huskyCI/api/db/mongo"
 	"github.com/huskyci-org/huskyCI/api/types"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // ConnectDB will call Connect function
@@ -30,6 +31,34 @@ func (mR *MongoRequests) ConnectDB(address string, dbName string,
 		timeout)
 }
 
+// PingDB returns an error if MongoDB does not answer.
+func (mR *MongoRequests) PingDB() error {
+	return mongoHuskyCI.Ping()
+}
+
+// analysisIndexes are the indexes of the queries run on AnalysisCollection
+// as the analyses are polled, started, replayed, listed and searched, and on
+// ContainerOutputCollection as the outputs of their containers are read.
+var analysisIndexes = []mongoHuskyCI.Index{
+	{Collection: mongoHuskyCI.AnalysisCollection, Keys: []string{"RID"}},
+	{Collection: mongoHuskyCI.AnalysisCollection, Keys: []string{"repositoryURL", "repositoryBranch"}},
+	{Collection: mongoHuskyCI.AnalysisCollection, Keys: []string{"status"}},
+	{Collection: mongoHuskyCI.AnalysisCollection, Keys: []string{"startedAt"}},
+	{Collection: mongoHuskyCI.AnalysisCollection, Keys: []string{"finishedAt"}},
+	{Collection: mongoHuskyCI.AnalysisCollection, Keys: []string{"status", "startedAt"}},
+	{Collection: mongoHuskyCI.AnalysisCollection, Keys: []string{"idempotencyKey", "repositoryURL"}},
+	{Collection: mongoHuskyCI.ContainerOutputCollection, Keys: []string{"RID", "securityTest"}},
+}
+
+// analysisSummarySelectors are the fields of an analysis read to summarize it.
+var analysisSummarySelectors = []string{"RID", "repositoryURL", "repositoryBranch", "status", "result", "errorFound", "startedAt", "finishedAt"}
+
+// EnsureIndexes creates the indexes of AnalysisCollection and
+// ContainerOutputCollection that do not exist yet.
+func (mR *MongoRequests) EnsureIndexes() error {
+	return mongoHuskyCI.Conn().CreateIndexes(analysisIndexes)
+}
+
 // FindOneDBRepository checks if a given repository is present into RepositoryCollection.
 func (mR *MongoRequests) FindOneDBRepository(mapParams map[string]interface{}) (types.Repository, error) {
 	repositoryResponse := types.Repository{}
@@ -38,7 +67,7 @@ func (mR *MongoRequests) FindOneDBRepository(mapParams map[string]interface{}) (
 		repositoryQuery = append(repositoryQuery, bson.M{k: v})
 	}
 	repositoryFinalQuery := bson.M{"$and": repositoryQuery}
-	err := mongoHuskyCI.Conn.SearchOne(repositoryFinalQuery, nil, mongoHuskyCI.RepositoryCollection, &repositoryResponse)
+	err := mongoHuskyCI.Conn().SearchOne(repositoryFinalQuery, nil, mongoHuskyCI.RepositoryCollection, &repositoryResponse)
 	return repositoryResponse, err
 }
 
@@ -50,7 +79,7 @@ func (mR *MongoRequests) FindOneDBSecurityTest(mapParams map[string]interface{})
 		securityTestQuery = append(securityTestQuery, bson.M{k: v})
 	}
 	securityTestFinalQuery := bson.M{"$and": securityTestQuery}
-	err := mongoHuskyCI.Conn.SearchOne(securityTestFinalQuery, nil, mongoHuskyCI.SecurityTestCollection, &securityTestResponse)
+	err := mongoHuskyCI.Conn().SearchOne(securityTestFinalQuery, nil, mongoHuskyCI.SecurityTestCollection, &securityTestResponse)
 	return securityTestResponse, err
 }
 
@@ -63,7 +92,24 @@ func (mR *MongoRequests) FindOneDBAnalysis(mapParams map[string]interface{}) (ty
 	}
 	analysisFinalQuery := bson.M{"$and": analysisQuery}
 
-	err := mongoHuskyCI.Conn.SearchOne(analysisFinalQuery, nil, mongoHuskyCI.AnalysisCollection, &analysisResponse)
+	err := mongoHuskyCI.Conn().SearchOne(analysisFinalQuery, nil, mongoHuskyCI.AnalysisCollection, &analysisResponse)
+	if err != nil {
+		return analysisResponse, err
+	}
+	err = DecompressResults(&analysisResponse)
+	return analysisResponse, err
+}
+
+// FindOneDBAnalysisSummary checks if a given analysis is present into AnalysisCollection
+// and reads only the fields of its summary, for the queries run often.
+func (mR *MongoRequests) FindOneDBAnalysisSummary(mapParams map[string]interface{}) (types.AnalysisSummary, error) {
+	analysisResponse := types.AnalysisSummary{}
+	analysisQuery := []bson.M{}
+	for k, v := range mapParams {
+		analysisQuery = append(analysisQuery, bson.M{k: v})
+	}
+	analysisFinalQuery := bson.M{"$and": analysisQuery}
+	err := mongoHuskyCI.Conn().SearchOne(analysisFinalQuery, analysisSummarySelectors, mongoHuskyCI.AnalysisCollection, &analysisResponse)
 	return analysisResponse, err
 }
 
@@ -75,7 +121,7 @@ func (mR *MongoRequests) FindOneDBUser(mapParams map[string]interface{}) (types.
 		userQuery = append(userQuery, bson.M{k: v})
 	}
 	userFinalQuery := bson.M{"$and": userQuery}
-	err := mongoHuskyCI.Conn.SearchOne(userFinalQuery, nil, mongoHuskyCI.UserCollection, &userResponse)
+	err := mongoHuskyCI.Conn().SearchOne(userFinalQuery, nil, mongoHuskyCI.UserCollection, &userResponse)
 	return userResponse, err
 }
 
@@ -87,7 +133,7 @@ func (mR *MongoRequests) FindOneDBAccessToken(mapParams map[string]interface{})
 		aTokenQuery = append(aTokenQuery, bson.M{k: v})
 	}
 	aTokenFinalQuery := bson.M{"$and": aTokenQuery}
-	err := mongoHuskyCI.Conn.SearchOne(aTokenFinalQuery, nil, mongoHuskyCI.AccessTokenCollection, &aTokenResponse)
+	err := mongoHuskyCI.Conn().SearchOne(aTokenFinalQuery, nil, mongoHuskyCI.AccessTokenCollection, &aTokenResponse)
 	return aTokenResponse, err
 }
 
@@ -98,8 +144,12 @@ func (mR *MongoRequests) FindAllDBRepository(mapParams map[string]interface{}) (
 		repositoryQuery = append(repositoryQuery, bson.M{k: v})
 	}
 	repositoryFinalQuery := bson.M{"$and": repositoryQuery}
+	if len(repositoryQuery) == 0 {
+		// MongoDB rejects an empty $and, so match every document instead
+		repositoryFinalQuery = bson.M{}
+	}
 	repositoryResponse := []types.Repository{}
-	err := mongoHuskyCI.Conn.Search(repositoryFinalQuery, nil, mongoHuskyCI.RepositoryCollection, &repositoryResponse)
+	err := mongoHuskyCI.Conn().Search(repositoryFinalQuery, nil, mongoHuskyCI.RepositoryCollection, &repositoryResponse)
 	return repositoryResponse, err
 }
 
@@ -110,8 +160,12 @@ func (mR *MongoRequests) FindAllDBSecurityTest(mapParams map[string]interface{})
 		securityTestQuery = append(securityTestQuery, bson.M{k: v})
 	}
 	securityTestFinalQuery := bson.M{"$and": securityTestQuery}
+	if len(securityTestQuery) == 0 {
+		// MongoDB rejects an empty $and, so match every document instead
+		securityTestFinalQuery = bson.M{}
+	}
 	securityTestResponse := []types.SecurityTest{}
-	err := mongoHuskyCI.Conn.Search(securityTestFinalQuery, nil, mongoHuskyCI.SecurityTestCollection, &securityTestResponse)
+	err := mongoHuskyCI.Conn().Search(securityTestFinalQuery, nil, mongoHuskyCI.SecurityTestCollection, &securityTestResponse)
 	return securityTestResponse, err
 }
 
@@ -122,9 +176,64 @@ func (mR *MongoRequests) FindAllDBAnalysis(mapParams map[string]interface{}) ([]
 		analysisQuery = append(analysisQuery, bson.M{k: v})
 	}
 	analysisFinalQuery := bson.M{"$and": analysisQuery}
+	if len(analysisQuery) == 0 {
+		// MongoDB rejects an empty $and, so match every document instead
+		analysisFinalQuery = bson.M{}
+	}
 	analysisResponse := []types.Analysis{}
-	err := mongoHuskyCI.Conn.Search(analysisFinalQuery, nil, mongoHuskyCI.AnalysisCollection, &analysisResponse)
-	return analysisResponse, err
+	err := mongoHuskyCI.Conn().Search(analysisFinalQuery, nil, mongoHuskyCI.AnalysisCollection, &analysisResponse)
+	if err != nil {
+		return analysisResponse, err
+	}
+	for i := range analysisResponse {
+		if err := DecompressResults(&analysisResponse[i]); err != nil {
+			return analysisResponse, err
+		}
+	}
+	return analysisResponse, nil
+}
+
+// FindPageDBAnalysis returns a page of the analyses that match filter present into AnalysisCollection
+// and how many analyses match it in total.
+func (mR *MongoRequests) FindPageDBAnalysis(filter types.AnalysisFilter) ([]types.AnalysisSummary, int64, error) {
+	analysisQuery := []bson.M{}
+	if filter.URL != "" {
+		analysisQuery = append(analysisQuery, bson.M{"repositoryURL": filter.URL})
+	}
+	if filter.Branch != "" {
+		analysisQuery = append(analysisQuery, bson.M{"repositoryBranch": filter.Branch})
+	}
+	if filter.Status != "" {
+		analysisQuery = append(analysisQuery, bson.M{"status": filter.Status})
+	}
+	if !filter.From.IsZero() {
+		analysisQuery = append(analysisQuery, bson.M{"startedAt": bson.M{"$gte": filter.From}})
+	}
+	if !filter.To.IsZero() {
+		analysisQuery = append(analysisQuery, bson.M{"startedAt": bson.M{"$lte": filter.To}})
+	}
+	analysisFinalQuery := bson.M{"$and": analysisQuery}
+	if len(analysisQuery) == 0 {
+		analysisFinalQuery = bson.M{}
+	}
+	sortOrder := 1
+	if filter.SortDescending {
+		sortOrder = -1
+	}
+	sort := bson.D{{Key: analysisSortField(filter.SortField), Value: sortOrder}, {Key: "RID", Value: sortOrder}}
+	skip := int64((filter.Page - 1) * filter.PageSize)
+	analysisResponse := []types.AnalysisSummary{}
+	total, err := mongoHuskyCI.Conn().SearchPage(analysisFinalQuery, analysisSummarySelectors, sort, skip, int64(filter.PageSize), mongoHuskyCI.AnalysisCollection, &analysisResponse)
+	return analysisResponse, total, err
+}
+
+// analysisSortField returns sortField if analyses can be sorted by it or startedAt otherwise.
+func analysisSortField(sortField string) string {
+	switch sortField {
+	case "startedAt", "finishedAt", "repositoryURL", "status":
+		return sortField
+	}
+	return "startedAt"
 }
 
 // InsertDBRepository inserts a new repository into RepositoryCollection.
@@ -133,7 +242,7 @@ func (mR *MongoRequests) InsertDBRepository(repository types.Repository) error {
 		"repositoryURL": repository.URL,
 		"createdAt":     repository.CreatedAt,
 	}
-	err := mongoHuskyCI.Conn.Insert(newRepository, mongoHuskyCI.RepositoryCollection)
+	err := mongoHuskyCI.Conn().Insert(newRepository, mongoHuskyCI.RepositoryCollection)
 	return err
 }
 
@@ -148,7 +257,7 @@ func (mR *MongoRequests) InsertDBSecurityTest(securityTest types.SecurityTest) e
 		"default":        securityTest.Default,
 		"timeOutSeconds": securityTest.TimeOutInSeconds,
 	}
-	err := mongoHuskyCI.Conn.Insert(newSecurityTest, mongoHuskyCI.SecurityTestCollection)
+	err := mongoHuskyCI.Conn().Insert(newSecurityTest, mongoHuskyCI.SecurityTestCollection)
 	return err
 }
 
@@ -163,7 +272,20 @@ func (mR *MongoRequests) InsertDBAnalysis(analysis types.Analysis) error {
 		"containers":       analysis.Containers,
 		"startedAt":        analysis.StartedAt,
 	}
-	err := mongoHuskyCI.Conn.Insert(newAnalysis, mongoHuskyCI.AnalysisCollection)
+	if analysis.Commit != "" {
+		newAnalysis["commit"] = analysis.Commit
+	}
+	if analysis.IdempotencyKey != "" {
+		newAnalysis["idempotencyKey"] = analysis.IdempotencyKey
+		newAnalysis["idempotencyHash"] = analysis.IdempotencyHash
+	}
+	if analysis.QueuedRequest != nil {
+		newAnalysis["queuedRequest"] = analysis.QueuedRequest
+	}
+	if analysis.PullRequest != nil {
+		newAnalysis["pullRequest"] = analysis.PullRequest
+	}
+	err := mongoHuskyCI.Conn().Insert(newAnalysis, mongoHuskyCI.AnalysisCollection)
 	return err
 }
 
@@ -176,8 +298,10 @@ func (mR *MongoRequests) InsertDBUser(user types.User) error {
 		"iterations":   user.Iterations,
 		"keylen":       user.KeyLen,
 		"hashfunction": user.HashFunction,
+		"role":         user.Role,
+		"disabled":     user.Disabled,
 	}
-	err := mongoHuskyCI.Conn.Insert(newUser, mongoHuskyCI.UserCollection)
+	err := mongoHuskyCI.Conn().Insert(newUser, mongoHuskyCI.UserCollection)
 	return err
 }
 
@@ -191,7 +315,7 @@ func (mR *MongoRequests) InsertDBAccessToken(accessToken types.DBToken) error {
 		"salt":          accessToken.Salt,
 		"uuid":          accessToken.UUID,
 	}
-	err := mongoHuskyCI.Conn.Insert(newAccessToken, mongoHuskyCI.AccessTokenCollection)
+	err := mongoHuskyCI.Conn().Insert(newAccessToken, mongoHuskyCI.AccessTokenCollection)
 	return err
 }
 
@@ -202,7 +326,7 @@ func (mR *MongoRequests) UpdateOneDBRepository(mapParams, updateQuery map[string
 		repositoryQuery = append(repositoryQuery, bson.M{k: v})
 	}
 	repositoryFinalQuery := bson.M{"$and": repositoryQuery}
-	err := mongoHuskyCI.Conn.Update(repositoryFinalQuery, updateQuery, mongoHuskyCI.RepositoryCollection)
+	err := mongoHuskyCI.Conn().Update(repositoryFinalQuery, updateQuery, mongoHuskyCI.RepositoryCollection)
 	return err
 }
 
@@ -213,7 +337,7 @@ func (mR *MongoRequests) UpsertOneDBSecurityTest(mapParams map[string]interface{
 		securityTestQuery = append(securityTestQuery, bson.M{k: v})
 	}
 	securityTestFinalQuery := bson.M{"$and": securityTestQuery}
-	changeInfo, err := mongoHuskyCI.Conn.Upsert(securityTestFinalQuery, updatedSecurityTest, mongoHuskyCI.SecurityTestCollection)
+	changeInfo, err := mongoHuskyCI.Conn().Upsert(securityTestFinalQuery, updatedSecurityTest, mongoHuskyCI.SecurityTestCollection)
 	return changeInfo, err
 }
 
@@ -227,7 +351,7 @@ func (mR *MongoRequests) UpdateOneDBAnalysis(mapParams map[string]interface{}, u
 		analysisQuery = append(analysisQuery, bson.M{k: v})
 	}
 	analysisFinalQuery := bson.M{"$and": analysisQuery}
-	err := mongoHuskyCI.Conn.Update(analysisFinalQuery, updatedQuery, mongoHuskyCI.AnalysisCollection)
+	err := mongoHuskyCI.Conn().Update(analysisFinalQuery, updatedQuery, mongoHuskyCI.AnalysisCollection)
 	return err
 }
 
@@ -238,21 +362,55 @@ func (mR *MongoRequests) UpdateOneDBUser(mapParams map[string]interface{}, updat
 		userQuery = append(userQuery, bson.M{k: v})
 	}
 	userFinalQuery := bson.M{"$and": userQuery}
-	err := mongoHuskyCI.Conn.Update(userFinalQuery, updatedUser, mongoHuskyCI.UserCollection)
+	err := mongoHuskyCI.Conn().Update(userFinalQuery, updatedUser, mongoHuskyCI.UserCollection)
 	return err
 }
 
+// FindAllDBUser returns all users of a given query present into UserCollection.
+func (mR *MongoRequests) FindAllDBUser(mapParams map[string]interface{}) ([]types.User, error) {
+	userQuery := []bson.M{}
+	for k, v := range mapParams {
+		userQuery = append(userQuery, bson.M{k: v})
+	}
+	userFinalQuery := bson.M{"$and": userQuery}
+	if len(userQuery) == 0 {
+		userFinalQuery = bson.M{}
+	}
+	userResponse := []types.User{}
+	err := mongoHuskyCI.Conn().Search(userFinalQuery, nil, mongoHuskyCI.UserCollection, &userResponse)
+	return userResponse, err
+}
+
+// DeleteOneDBUser removes a given user from UserCollection.
+func (mR *MongoRequests) DeleteOneDBUser(mapParams map[string]interface{}) error {
+	userQuery := []bson.M{}
+	for k, v := range mapParams {
+		userQuery = append(userQuery, bson.M{k: v})
+	}
+	userFinalQuery := bson.M{"$and": userQuery}
+	deleted, err := mongoHuskyCI.Conn().Delete(userFinalQuery, mongoHuskyCI.UserCollection)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
 // UpdateOneDBAnalysisContainer checks if a given analysis is present into AnalysisCollection and update the container associated in it.
+// Large results are compressed, see ResultsUpdate.
 func (mR *MongoRequests) UpdateOneDBAnalysisContainer(mapParams, updateQuery map[string]interface{}) error {
-	updatedQuery := bson.M{
-		"$set": updateQuery,
+	updatedQuery, err := ResultsUpdate(updateQuery, mR.CompressResultsAbove)
+	if err != nil {
+		return err
 	}
 	analysisQuery := []bson.M{}
 	for k, v := range mapParams {
 		analysisQuery = append(analysisQuery, bson.M{k: v})
 	}
 	analysisFinalQuery := bson.M{"$and": analysisQuery}
-	err := mongoHuskyCI.Conn.Update(analysisFinalQuery, updatedQuery, mongoHuskyCI.AnalysisCollection)
+	err = mongoHuskyCI.Conn().Update(analysisFinalQuery, updatedQuery, mongoHuskyCI.AnalysisCollection)
 	return err
 }
 
@@ -263,7 +421,7 @@ func (mR *MongoRequests) UpdateOneDBAccessToken(mapParams map[string]interface{}
 		aTokenQuery = append(aTokenQuery, bson.M{k: v})
 	}
 	aTokenFinalQuery := bson.M{"$and": aTokenQuery}
-	err := mongoHuskyCI.Conn.Update(aTokenFinalQuery, updatedAccessToken, mongoHuskyCI.AccessTokenCollection)
+	err := mongoHuskyCI.Conn().Update(aTokenFinalQuery, updatedAccessToken, mongoHuskyCI.AccessTokenCollection)
 	return err
 }
 
@@ -272,6 +430,438 @@ func (mR *MongoRequests) FindAndModifyDockerAPIAddresses() (types.DockerAPIAddre
 	findQuery := bson.M{}
 	updateQuery := bson.M{"$inc": bson.M{"currentHostIndex": 1}}
 	result := types.DockerAPIAddresses{}
-	err := mongoHuskyCI.Conn.FindAndModify(findQuery, updateQuery, mongoHuskyCI.DockerAPIAddressesCollection, &result)
+	err := mongoHuskyCI.Conn().FindAndModify(findQuery, updateQuery, mongoHuskyCI.DockerAPIAddressesCollection, &result)
 	return result, err
 }
+
+// UpsertDockerAPIAddresses stores the Docker API hosts shared by every huskyCI
+// API replica, and the list of the active ones, keeping the current
+// round-robin index if it already exists.
+func (mR *MongoRequests) UpsertDockerAPIAddresses(hosts []types.DockerHost) error {
+	hostList := []string{}
+	for _, host := range hosts {
+		if host.State == types.DockerHostActive {
+			hostList = append(hostList, host.Address)
+		}
+	}
+	_, err := mongoHuskyCI.Conn().Upsert(bson.M{}, bson.M{"hostList": hostList, "hosts": hosts}, mongoHuskyCI.DockerAPIAddressesCollection)
+	return err
+}
+
+// FindOneDBDockerHost checks if a given Docker API host is present into DockerHostCollection.
+func (mR *MongoRequests) FindOneDBDockerHost(mapParams map[string]interface{}) (types.DockerHost, error) {
+	hostResponse := types.DockerHost{}
+	hostQuery := []bson.M{}
+	for k, v := range mapParams {
+		hostQuery = append(hostQuery, bson.M{k: v})
+	}
+	hostFinalQuery := bson.M{"$and": hostQuery}
+	err := mongoHuskyCI.Conn().SearchOne(hostFinalQuery, nil, mongoHuskyCI.DockerHostCollection, &hostResponse)
+	return hostResponse, err
+}
+
+// FindAllDBDockerHost returns all Docker API hosts of a given query present into DockerHostCollection.
+func (mR *MongoRequests) FindAllDBDockerHost(mapParams map[string]interface{}) ([]types.DockerHost, error) {
+	hostQuery := []bson.M{}
+	for k, v := range mapParams {
+		hostQuery = append(hostQuery, bson.M{k: v})
+	}
+	hostFinalQuery := bson.M{"$and": hostQuery}
+	if len(hostQuery) == 0 {
+		hostFinalQuery = bson.M{}
+	}
+	hostResponse := []types.DockerHost{}
+	err := mongoHuskyCI.Conn().Search(hostFinalQuery, nil, mongoHuskyCI.DockerHostCollection, &hostResponse)
+	return hostResponse, err
+}
+
+// UpsertOneDBDockerHost inserts or replaces a given Docker API host into DockerHostCollection.
+func (mR *MongoRequests) UpsertOneDBDockerHost(mapParams map[string]interface{}, host types.DockerHost) error {
+	hostQuery := []bson.M{}
+	for k, v := range mapParams {
+		hostQuery = append(hostQuery, bson.M{k: v})
+	}
+	hostFinalQuery := bson.M{"$and": hostQuery}
+	_, err := mongoHuskyCI.Conn().Upsert(hostFinalQuery, host, mongoHuskyCI.DockerHostCollection)
+	return err
+}
+
+// DeleteOneDBDockerHost removes a given Docker API host from DockerHostCollection.
+func (mR *MongoRequests) DeleteOneDBDockerHost(mapParams map[string]interface{}) error {
+	hostQuery := []bson.M{}
+	for k, v := range mapParams {
+		hostQuery = append(hostQuery, bson.M{k: v})
+	}
+	hostFinalQuery := bson.M{"$and": hostQuery}
+	deleted, err := mongoHuskyCI.Conn().Delete(hostFinalQuery, mongoHuskyCI.DockerHostCollection)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// FindOneDBGitCredential checks if a given git credential is present into GitCredentialCollection.
+func (mR *MongoRequests) FindOneDBGitCredential(mapParams map[string]interface{}) (types.GitCredential, error) {
+	credentialResponse := types.GitCredential{}
+	credentialQuery := []bson.M{}
+	for k, v := range mapParams {
+		credentialQuery = append(credentialQuery, bson.M{k: v})
+	}
+	credentialFinalQuery := bson.M{"$and": credentialQuery}
+	err := mongoHuskyCI.Conn().SearchOne(credentialFinalQuery, nil, mongoHuskyCI.GitCredentialCollection, &credentialResponse)
+	return credentialResponse, err
+}
+
+// FindAllDBGitCredential returns all git credentials of a given query present into GitCredentialCollection.
+func (mR *MongoRequests) FindAllDBGitCredential(mapParams map[string]interface{}) ([]types.GitCredential, error) {
+	credentialQuery := []bson.M{}
+	for k, v := range mapParams {
+		credentialQuery = append(credentialQuery, bson.M{k: v})
+	}
+	credentialFinalQuery := bson.M{"$and": credentialQuery}
+	if len(credentialQuery) == 0 {
+		credentialFinalQuery = bson.M{}
+	}
+	credentialResponse := []types.GitCredential{}
+	err := mongoHuskyCI.Conn().Search(credentialFinalQuery, nil, mongoHuskyCI.GitCredentialCollection, &credentialResponse)
+	return credentialResponse, err
+}
+
+// UpsertOneDBGitCredential inserts a git credential into GitCredentialCollection or replaces it if it already exists.
+func (mR *MongoRequests) UpsertOneDBGitCredential(mapParams map[string]interface{}, credential types.GitCredential) error {
+	credentialQuery := []bson.M{}
+	for k, v := range mapParams {
+		credentialQuery = append(credentialQuery, bson.M{k: v})
+	}
+	credentialFinalQuery := bson.M{"$and": credentialQuery}
+	_, err := mongoHuskyCI.Conn().Upsert(credentialFinalQuery, credential, mongoHuskyCI.GitCredentialCollection)
+	return err
+}
+
+// DeleteOneDBGitCredential removes a given git credential from GitCredentialCollection.
+func (mR *MongoRequests) DeleteOneDBGitCredential(mapParams map[string]interface{}) error {
+	credentialQuery := []bson.M{}
+	for k, v := range mapParams {
+		credentialQuery = append(credentialQuery, bson.M{k: v})
+	}
+	credentialFinalQuery := bson.M{"$and": credentialQuery}
+	deleted, err := mongoHuskyCI.Conn().Delete(credentialFinalQuery, mongoHuskyCI.GitCredentialCollection)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// FindOneDBRegistryCredential checks if a given registry credential is present into RegistryCredentialCollection.
+func (mR *MongoRequests) FindOneDBRegistryCredential(mapParams map[string]interface{}) (types.RegistryCredential, error) {
+	credentialResponse := types.RegistryCredential{}
+	credentialQuery := []bson.M{}
+	for k, v := range mapParams {
+		credentialQuery = append(credentialQuery, bson.M{k: v})
+	}
+	credentialFinalQuery := bson.M{"$and": credentialQuery}
+	err := mongoHuskyCI.Conn().SearchOne(credentialFinalQuery, nil, mongoHuskyCI.RegistryCredentialCollection, &credentialResponse)
+	return credentialResponse, err
+}
+
+// FindAllDBRegistryCredential returns all registry credentials of a given query present into RegistryCredentialCollection.
+func (mR *MongoRequests) FindAllDBRegistryCredential(mapParams map[string]interface{}) ([]types.RegistryCredential, error) {
+	credentialQuery := []bson.M{}
+	for k, v := range mapParams {
+		credentialQuery = append(credentialQuery, bson.M{k: v})
+	}
+	credentialFinalQuery := bson.M{"$and": credentialQuery}
+	if len(credentialQuery) == 0 {
+		credentialFinalQuery = bson.M{}
+	}
+	credentialResponse := []types.RegistryCredential{}
+	err := mongoHuskyCI.Conn().Search(credentialFinalQuery, nil, mongoHuskyCI.RegistryCredentialCollection, &credentialResponse)
+	return credentialResponse, err
+}
+
+// UpsertOneDBRegistryCredential inserts a registry credential into RegistryCredentialCollection or replaces it if it already exists.
+func (mR *MongoRequests) UpsertOneDBRegistryCredential(mapParams map[string]interface{}, credential types.RegistryCredential) error {
+	credentialQuery := []bson.M{}
+	for k, v := range mapParams {
+		credentialQuery = append(credentialQuery, bson.M{k: v})
+	}
+	credentialFinalQuery := bson.M{"$and": credentialQuery}
+	_, err := mongoHuskyCI.Conn().Upsert(credentialFinalQuery, credential, mongoHuskyCI.RegistryCredentialCollection)
+	return err
+}
+
+// DeleteOneDBRegistryCredential removes a given registry credential from RegistryCredentialCollection.
+func (mR *MongoRequests) DeleteOneDBRegistryCredential(mapParams map[string]interface{}) error {
+	credentialQuery := []bson.M{}
+	for k, v := range mapParams {
+		credentialQuery = append(credentialQuery, bson.M{k: v})
+	}
+	credentialFinalQuery := bson.M{"$and": credentialQuery}
+	deleted, err := mongoHuskyCI.Conn().Delete(credentialFinalQuery, mongoHuskyCI.RegistryCredentialCollection)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// FindOneDBStatusReporter checks if a given status reporter is present into StatusReporterCollection.
+func (mR *MongoRequests) FindOneDBStatusReporter(mapParams map[string]interface{}) (types.StatusReporter, error) {
+	reporterResponse := types.StatusReporter{}
+	reporterQuery := []bson.M{}
+	for k, v := range mapParams {
+		reporterQuery = append(reporterQuery, bson.M{k: v})
+	}
+	reporterFinalQuery := bson.M{"$and": reporterQuery}
+	err := mongoHuskyCI.Conn().SearchOne(reporterFinalQuery, nil, mongoHuskyCI.StatusReporterCollection, &reporterResponse)
+	return reporterResponse, err
+}
+
+// FindAllDBStatusReporter returns all status reporters of a given query present into StatusReporterCollection.
+func (mR *MongoRequests) FindAllDBStatusReporter(mapParams map[string]interface{}) ([]types.StatusReporter, error) {
+	reporterQuery := []bson.M{}
+	for k, v := range mapParams {
+		reporterQuery = append(reporterQuery, bson.M{k: v})
+	}
+	reporterFinalQuery := bson.M{"$and": reporterQuery}
+	if len(reporterQuery) == 0 {
+		reporterFinalQuery = bson.M{}
+	}
+	reporterResponse := []types.StatusReporter{}
+	err := mongoHuskyCI.Conn().Search(reporterFinalQuery, nil, mongoHuskyCI.StatusReporterCollection, &reporterResponse)
+	return reporterResponse, err
+}
+
+// UpsertOneDBStatusReporter inserts a status reporter into StatusReporterCollection or replaces it if it already exists.
+func (mR *MongoRequests) UpsertOneDBStatusReporter(mapParams map[string]interface{}, reporter types.StatusReporter) error {
+	reporterQuery := []bson.M{}
+	for k, v := range mapParams {
+		reporterQuery = append(reporterQuery, bson.M{k: v})
+	}
+	reporterFinalQuery := bson.M{"$and": reporterQuery}
+	_, err := mongoHuskyCI.Conn().Upsert(reporterFinalQuery, reporter, mongoHuskyCI.StatusReporterCollection)
+	return err
+}
+
+// DeleteOneDBStatusReporter removes a given status reporter from StatusReporterCollection.
+func (mR *MongoRequests) DeleteOneDBStatusReporter(mapParams map[string]interface{}) error {
+	reporterQuery := []bson.M{}
+	for k, v := range mapParams {
+		reporterQuery = append(reporterQuery, bson.M{k: v})
+	}
+	reporterFinalQuery := bson.M{"$and": reporterQuery}
+	deleted, err := mongoHuskyCI.Conn().Delete(reporterFinalQuery, mongoHuskyCI.StatusReporterCollection)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// FindOneDBRemediation checks if a given remediation is present into RemediationCollection.
+func (mR *MongoRequests) FindOneDBRemediation(mapParams map[string]interface{}) (types.Remediation, error) {
+	remediationResponse := types.Remediation{}
+	remediationQuery := []bson.M{}
+	for k, v := range mapParams {
+		remediationQuery = append(remediationQuery, bson.M{k: v})
+	}
+	remediationFinalQuery := bson.M{"$and": remediationQuery}
+	err := mongoHuskyCI.Conn().SearchOne(remediationFinalQuery, nil, mongoHuskyCI.RemediationCollection, &remediationResponse)
+	return remediationResponse, err
+}
+
+// FindAllDBRemediation returns all remediations of a given query present into RemediationCollection.
+func (mR *MongoRequests) FindAllDBRemediation(mapParams map[string]interface{}) ([]types.Remediation, error) {
+	remediationQuery := []bson.M{}
+	for k, v := range mapParams {
+		remediationQuery = append(remediationQuery, bson.M{k: v})
+	}
+	remediationFinalQuery := bson.M{"$and": remediationQuery}
+	if len(remediationQuery) == 0 {
+		remediationFinalQuery = bson.M{}
+	}
+	remediationResponse := []types.Remediation{}
+	err := mongoHuskyCI.Conn().Search(remediationFinalQuery, nil, mongoHuskyCI.RemediationCollection, &remediationResponse)
+	return remediationResponse, err
+}
+
+// UpsertOneDBRemediation inserts a remediation into RemediationCollection or replaces it if it already exists.
+func (mR *MongoRequests) UpsertOneDBRemediation(mapParams map[string]interface{}, remediation types.Remediation) error {
+	remediationQuery := []bson.M{}
+	for k, v := range mapParams {
+		remediationQuery = append(remediationQuery, bson.M{k: v})
+	}
+	remediationFinalQuery := bson.M{"$and": remediationQuery}
+	_, err := mongoHuskyCI.Conn().Upsert(remediationFinalQuery, remediation, mongoHuskyCI.RemediationCollection)
+	return err
+}
+
+// DeleteOneDBRemediation removes a given remediation from RemediationCollection.
+func (mR *MongoRequests) DeleteOneDBRemediation(mapParams map[string]interface{}) error {
+	remediationQuery := []bson.M{}
+	for k, v := range mapParams {
+		remediationQuery = append(remediationQuery, bson.M{k: v})
+	}
+	remediationFinalQuery := bson.M{"$and": remediationQuery}
+	deleted, err := mongoHuskyCI.Conn().Delete(remediationFinalQuery, mongoHuskyCI.RemediationCollection)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// FindOneDBPolicy checks if a given policy is present into PolicyCollection.
+func (mR *MongoRequests) FindOneDBPolicy(mapParams map[string]interface{}) (types.Policy, error) {
+	policyResponse := types.Policy{}
+	policyQuery := []bson.M{}
+	for k, v := range mapParams {
+		policyQuery = append(policyQuery, bson.M{k: v})
+	}
+	policyFinalQuery := bson.M{"$and": policyQuery}
+	err := mongoHuskyCI.Conn().SearchOne(policyFinalQuery, nil, mongoHuskyCI.PolicyCollection, &policyResponse)
+	return policyResponse, err
+}
+
+// FindAllDBPolicy returns all policies of a given query present into PolicyCollection.
+func (mR *MongoRequests) FindAllDBPolicy(mapParams map[string]interface{}) ([]types.Policy, error) {
+	policyQuery := []bson.M{}
+	for k, v := range mapParams {
+		policyQuery = append(policyQuery, bson.M{k: v})
+	}
+	policyFinalQuery := bson.M{"$and": policyQuery}
+	if len(policyQuery) == 0 {
+		policyFinalQuery = bson.M{}
+	}
+	policyResponse := []types.Policy{}
+	err := mongoHuskyCI.Conn().Search(policyFinalQuery, nil, mongoHuskyCI.PolicyCollection, &policyResponse)
+	return policyResponse, err
+}
+
+// UpsertOneDBPolicy inserts a policy into PolicyCollection or replaces it if it already exists.
+func (mR *MongoRequests) UpsertOneDBPolicy(mapParams map[string]interface{}, policy types.Policy) error {
+	policyQuery := []bson.M{}
+	for k, v := range mapParams {
+		policyQuery = append(policyQuery, bson.M{k: v})
+	}
+	policyFinalQuery := bson.M{"$and": policyQuery}
+	_, err := mongoHuskyCI.Conn().Upsert(policyFinalQuery, policy, mongoHuskyCI.PolicyCollection)
+	return err
+}
+
+// DeleteOneDBPolicy removes a given policy from PolicyCollection.
+func (mR *MongoRequests) DeleteOneDBPolicy(mapParams map[string]interface{}) error {
+	policyQuery := []bson.M{}
+	for k, v := range mapParams {
+		policyQuery = append(policyQuery, bson.M{k: v})
+	}
+	policyFinalQuery := bson.M{"$and": policyQuery}
+	deleted, err := mongoHuskyCI.Conn().Delete(policyFinalQuery, mongoHuskyCI.PolicyCollection)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// FindOneDBGroup checks if a given repository group is present into GroupCollection.
+func (mR *MongoRequests) FindOneDBGroup(mapParams map[string]interface{}) (types.RepositoryGroup, error) {
+	groupResponse := types.RepositoryGroup{}
+	groupQuery := []bson.M{}
+	for k, v := range mapParams {
+		groupQuery = append(groupQuery, bson.M{k: v})
+	}
+	groupFinalQuery := bson.M{"$and": groupQuery}
+	err := mongoHuskyCI.Conn().SearchOne(groupFinalQuery, nil, mongoHuskyCI.GroupCollection, &groupResponse)
+	return groupResponse, err
+}
+
+// FindAllDBGroup returns all repository groups of a given query present into GroupCollection.
+func (mR *MongoRequests) FindAllDBGroup(mapParams map[string]interface{}) ([]types.RepositoryGroup, error) {
+	groupQuery := []bson.M{}
+	for k, v := range mapParams {
+		groupQuery = append(groupQuery, bson.M{k: v})
+	}
+	groupFinalQuery := bson.M{"$and": groupQuery}
+	if len(groupQuery) == 0 {
+		groupFinalQuery = bson.M{}
+	}
+	groupResponse := []types.RepositoryGroup{}
+	err := mongoHuskyCI.Conn().Search(groupFinalQuery, nil, mongoHuskyCI.GroupCollection, &groupResponse)
+	return groupResponse, err
+}
+
+// UpsertOneDBGroup inserts a repository group into GroupCollection or replaces it if it already exists.
+func (mR *MongoRequests) UpsertOneDBGroup(mapParams map[string]interface{}, group types.RepositoryGroup) error {
+	groupQuery := []bson.M{}
+	for k, v := range mapParams {
+		groupQuery = append(groupQuery, bson.M{k: v})
+	}
+	groupFinalQuery := bson.M{"$and": groupQuery}
+	_, err := mongoHuskyCI.Conn().Upsert(groupFinalQuery, group, mongoHuskyCI.GroupCollection)
+	return err
+}
+
+// DeleteOneDBGroup removes a given repository group from GroupCollection.
+func (mR *MongoRequests) DeleteOneDBGroup(mapParams map[string]interface{}) error {
+	groupQuery := []bson.M{}
+	for k, v := range mapParams {
+		groupQuery = append(groupQuery, bson.M{k: v})
+	}
+	groupFinalQuery := bson.M{"$and": groupQuery}
+	deleted, err := mongoHuskyCI.Conn().Delete(groupFinalQuery, mongoHuskyCI.GroupCollection)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// FindOneDBContainerOutput checks if a given container output is present into ContainerOutputCollection.
+func (mR *MongoRequests) FindOneDBContainerOutput(mapParams map[string]interface{}) (types.ContainerOutput, error) {
+	outputResponse := types.ContainerOutput{}
+	outputQuery := []bson.M{}
+	for k, v := range mapParams {
+		outputQuery = append(outputQuery, bson.M{k: v})
+	}
+	outputFinalQuery := bson.M{"$and": outputQuery}
+	err := mongoHuskyCI.Conn().SearchOne(outputFinalQuery, nil, mongoHuskyCI.ContainerOutputCollection, &outputResponse)
+	return outputResponse, err
+}
+
+// UpsertOneDBContainerOutput inserts a container output into ContainerOutputCollection or replaces it if it already exists.
+func (mR *MongoRequests) UpsertOneDBContainerOutput(mapParams map[string]interface{}, output types.ContainerOutput) error {
+	outputQuery := []bson.M{}
+	for k, v := range mapParams {
+		outputQuery = append(outputQuery, bson.M{k: v})
+	}
+	outputFinalQuery := bson.M{"$and": outputQuery}
+	_, err := mongoHuskyCI.Conn().Upsert(outputFinalQuery, output, mongoHuskyCI.ContainerOutputCollection)
+	return err
+}
+
+// AcquireLock tries to acquire a distributed lock shared by all huskyCI API replicas.
+func (mR *MongoRequests) AcquireLock(name, owner string, ttl time.Duration) (bool, error) {
+	return mongoHuskyCI.Conn().AcquireLock(name, owner, ttl)
+}
+
+// ReleaseLock releases a distributed lock previously acquired by owner.
+func (mR *MongoRequests) ReleaseLock(name, owner string) error {
+	return mongoHuskyCI.Conn().ReleaseLock(name, owner)
+}
@@ -20,10 +20,22 @@ var statsQueryStringParams = map[string][]string{
 	"author":          []string{timeRangeQS},
 	"severity":        []string{timeRangeQS},
 	"historyanalysis": []string{timeRangeQS},
+	"analysisperday":  []string{timeRangeQS},
+	"tooltime":        []string{timeRangeQS},
+	"toprules":        []string{timeRangeQS},
+	"openfindings":    []string{timeRangeQS},
 }
 
 const aggHour = 1000 * 60 * 60
 
+// topLimit is how many rules or repositories the toprules and openfindings
+// metrics return.
+const topLimit = 20
+
+// openSeverities are the results of a tool counted as findings by the
+// toprules and openfindings metrics, the ones without a nosec.
+var openSeverities = []string{"highvulns", "mediumvulns", "lowvulns"}
+
 var statsQueryBase = map[string][]bson.M{
 	"language":  generateSimpleAggr("codes", "language", "codes.language"),
 	"container": generateSimpleAggr("containers", "container", "containers.securityTest.name"),
@@ -120,41 +132,17 @@ var statsQueryBase = map[string][]bson.M{
 	"severity": []bson.M{
 		bson.M{
 			"$project": bson.M{
-				"huskyresults": bson.M{
-					"$objectToArray": "$huskyciresults",
-				},
+				"findings": findingCounts(),
 			},
 		},
 		bson.M{
-			"$unwind": "$huskyresults",
-		},
-		bson.M{
-			"$project": bson.M{
-				"languageresults": bson.M{
-					"$objectToArray": "$huskyresults.v",
-				},
-			},
-		},
-		bson.M{
-			"$unwind": "$languageresults",
-		},
-		bson.M{
-			"$project": bson.M{
-				"results": bson.M{
-					"$objectToArray": "$languageresults.v",
-				},
-			},
-		},
-		bson.M{
-			"$unwind": "$results",
+			"$unwind": "$findings",
 		},
 		bson.M{
 			"$group": bson.M{
-				"_id": "$results.k",
+				"_id": "$findings.severity",
 				"count": bson.M{
-					"$sum": bson.M{
-						"$size": "$results.v",
-					},
+					"$sum": "$findings.count",
 				},
 			},
 		},
@@ -251,6 +239,186 @@ var statsQueryBase = map[string][]bson.M{
 			},
 		},
 	},
+	"analysisperday": []bson.M{
+		bson.M{
+			"$match": bson.M{
+				"status": "finished",
+			},
+		},
+		bson.M{
+			"$group": bson.M{
+				"_id": bson.M{
+					"$dateToString": bson.M{
+						"format": "%Y-%m-%d",
+						"date":   "$finishedAt",
+					},
+				},
+				"total": bson.M{
+					"$sum": 1,
+				},
+				"passed":  countResult("passed"),
+				"warning": countResult("warning"),
+				"failed":  countResult("failed"),
+				"error":   countResult("error"),
+			},
+		},
+		bson.M{
+			"$sort": bson.M{
+				"_id": 1,
+			},
+		},
+		bson.M{
+			"$project": bson.M{
+				"date":    "$_id",
+				"_id":     0,
+				"total":   1,
+				"passed":  1,
+				"warning": 1,
+				"failed":  1,
+				"error":   1,
+			},
+		},
+	},
+	"tooltime": []bson.M{
+		bson.M{
+			"$project": bson.M{
+				"containers": 1,
+			},
+		},
+		bson.M{
+			"$unwind": "$containers",
+		},
+		bson.M{
+			"$match": bson.M{
+				"containers.cStatus": "finished",
+			},
+		},
+		bson.M{
+			"$group": bson.M{
+				"_id": "$containers.securityTest.name",
+				"meanSeconds": bson.M{
+					"$avg": bson.M{
+						"$divide": []interface{}{
+							bson.M{
+								"$subtract": []string{
+									"$containers.finishedAt",
+									"$containers.startedAt",
+								},
+							},
+							1000,
+						},
+					},
+				},
+				"count": bson.M{
+					"$sum": 1,
+				},
+			},
+		},
+		bson.M{
+			"$sort": bson.M{
+				"meanSeconds": -1,
+			},
+		},
+		bson.M{
+			"$project": bson.M{
+				"tool":        "$_id",
+				"_id":         0,
+				"meanSeconds": 1,
+				"count":       1,
+			},
+		},
+	},
+	"toprules": append(unwindFindings(),
+		bson.M{
+			"$group": bson.M{
+				"_id": bson.M{
+					"securityTool": "$findings.securityTool",
+					"title":        "$findings.title",
+				},
+				"count": bson.M{
+					"$sum": "$findings.count",
+				},
+			},
+		},
+		bson.M{
+			"$sort": bson.M{
+				"count": -1,
+			},
+		},
+		bson.M{
+			"$limit": topLimit,
+		},
+		bson.M{
+			"$project": bson.M{
+				"securityTool": "$_id.securityTool",
+				"title":        "$_id.title",
+				"_id":          0,
+				"count":        1,
+			},
+		},
+	),
+	"openfindings": append([]bson.M{
+		bson.M{
+			"$match": bson.M{
+				"status": "finished",
+			},
+		},
+		bson.M{
+			"$sort": bson.M{
+				"finishedAt": -1,
+			},
+		},
+		bson.M{
+			"$group": bson.M{
+				"_id": bson.M{
+					"repositoryURL":    "$repositoryURL",
+					"repositoryBranch": "$repositoryBranch",
+				},
+				"finishedAt": bson.M{
+					"$first": "$finishedAt",
+				},
+				"huskyciresults": bson.M{
+					"$first": "$huskyciresults",
+				},
+				findingCountsField: bson.M{
+					"$first": "$" + findingCountsField,
+				},
+			},
+		},
+	}, append(unwindFindings(),
+		bson.M{
+			"$group": bson.M{
+				"_id":        "$_id",
+				"finishedAt": bson.M{"$first": "$finishedAt"},
+				"high":       countSeverity("highvulns"),
+				"medium":     countSeverity("mediumvulns"),
+				"low":        countSeverity("lowvulns"),
+				"total": bson.M{
+					"$sum": "$findings.count",
+				},
+			},
+		},
+		bson.M{
+			"$sort": bson.M{
+				"total": -1,
+			},
+		},
+		bson.M{
+			"$limit": topLimit,
+		},
+		bson.M{
+			"$project": bson.M{
+				"repositoryURL":    "$_id.repositoryURL",
+				"repositoryBranch": "$_id.repositoryBranch",
+				"_id":              0,
+				"finishedAt":       1,
+				"high":             1,
+				"medium":           1,
+				"low":              1,
+				"total":            1,
+			},
+		},
+	)...),
 }
 
 var validAggrTimeFilterStages = []string{"today", "yesterday", "last7days", "last30days"}
@@ -278,7 +446,7 @@ func (mR *MongoRequests) GetMetricByType(metricType string, queryStringParams ma
 			}
 		}
 	}
-	return mongoHuskyCI.Conn.Aggregation(query, mongoHuskyCI.AnalysisCollection)
+	return mongoHuskyCI.Conn().Aggregation(query, mongoHuskyCI.AnalysisCollection)
 }
 
 func getTimeFilterStage(timeRange string) []bson.M {
@@ -324,6 +492,83 @@ func generateSimpleAggr(field, finalName, groupID string) []bson.M {
 	}
 }
 
+// unwindFindings generates the stages that turn each analysis into one
+// document per title and severity of its findings, under findings, with their
+// count, as findingCounts returns them. Findings marked as nosec are left
+// out.
+func unwindFindings() []bson.M {
+	return []bson.M{
+		bson.M{
+			"$project": bson.M{
+				"finishedAt": 1,
+				"findings":   findingCounts(),
+			},
+		},
+		bson.M{
+			"$unwind": "$findings",
+		},
+		bson.M{
+			"$match": bson.M{
+				"findings.severity": bson.M{
+					"$in": openSeverities,
+				},
+			},
+		},
+	}
+}
+
+// findingCounts generates an expression returning the findingCounts of an
+// analysis, set along with its results whether they are compressed or not.
+// The analyses stored before them get one count per finding of their
+// huskyciresults instead.
+func findingCounts() bson.M {
+	// the concatenation of what in returns for each field of the object input
+	concatFields := func(input interface{}, in interface{}) bson.M {
+		return bson.M{
+			"$reduce": bson.M{
+				"input":        bson.M{"$objectToArray": input},
+				"initialValue": bson.A{},
+				"in":           bson.M{"$concatArrays": bson.A{"$$value", in}},
+			},
+		}
+	}
+	perFinding := bson.M{
+		"$map": bson.M{
+			"input": bson.M{"$ifNull": bson.A{"$$this.v", bson.A{}}},
+			"as":    "finding",
+			"in": bson.M{
+				"securityTool": "$$finding.securitytool",
+				"title":        "$$finding.title",
+				"severity":     "$$this.k",
+				"count":        1,
+			},
+		},
+	}
+	// languages, then securityTests, then severities
+	perResult := concatFields(bson.M{"$ifNull": bson.A{"$huskyciresults", bson.M{}}},
+		concatFields("$$this.v",
+			concatFields("$$this.v", perFinding)))
+	return bson.M{"$ifNull": bson.A{"$" + findingCountsField, perResult}}
+}
+
+// countResult generates an accumulator counting the analyses of result.
+func countResult(result string) bson.M {
+	return bson.M{
+		"$sum": bson.M{
+			"$cond": []interface{}{bson.M{"$eq": []string{"$result", result}}, 1, 0},
+		},
+	}
+}
+
+// countSeverity generates an accumulator counting the findings of severity.
+func countSeverity(severity string) bson.M {
+	return bson.M{
+		"$sum": bson.M{
+			"$cond": []interface{}{bson.M{"$eq": []string{"$findings.severity", severity}}, "$findings.count", 0},
+		},
+	}
+}
+
 // generateTimeFilterStage generates a stage that filter records by time range
 func generateTimeFilterStage(rangeInitDays, rangeEndDays int) []bson.M {
 	return []bson.M{
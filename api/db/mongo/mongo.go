@@ -2,7 +2,10 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/huskyci-org/huskyCI/api/log"
@@ -10,10 +13,21 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
 )
 
-// Conn is the MongoDB connection variable.
-var Conn *DB
+// conn is the current MongoDB connection. Connect replaces it when called
+// again, such as with the credentials Vault renewed.
+var conn atomic.Pointer[DB]
+
+// Conn returns the current MongoDB connection.
+func Conn() *DB {
+	return conn.Load()
+}
+
+// startReconnect starts autoReconnect once, however many times Connect is
+// called.
+var startReconnect sync.Once
 
 // Collections names used in MongoDB.
 var (
@@ -23,12 +37,25 @@ var (
 	UserCollection               = "user"
 	AccessTokenCollection        = "accessToken"
 	DockerAPIAddressesCollection = "dockerAPIAddresses"
+	DockerHostCollection         = "dockerHost"
+	LockCollection               = "lock"
+	GitCredentialCollection      = "gitCredential"
+	RegistryCredentialCollection = "registryCredential"
+	StatusReporterCollection     = "statusReporter"
+	RemediationCollection        = "remediation"
+	PolicyCollection             = "policy"
+	GroupCollection              = "repositoryGroup"
+	ContainerOutputCollection    = "containerOutput"
 )
 
 // DB is the struct that represents mongo client.
 type DB struct {
 	Client *mongo.Client
 	DB     *mongo.Database
+	// ops is held for reading by each operation on the client and for
+	// writing by close, so that the client is only closed once they are done
+	ops    sync.RWMutex
+	closed bool
 }
 
 const logActionConnect = "Connect"
@@ -54,7 +81,7 @@ func Connect(address, dbName, username, password string, poolLimit, port int, ti
 	clientOptions := options.Client().ApplyURI(dbAddress).SetAuth(options.Credential{
 		Username: username,
 		Password: password,
-	}).SetMaxPoolSize(uint64(poolLimit)).SetConnectTimeout(timeout)
+	}).SetMaxPoolSize(uint64(poolLimit)).SetConnectTimeout(timeout).SetMonitor(otelmongo.NewMonitor())
 
 	client, err := mongo.Connect(context.TODO(), clientOptions)
 	if err != nil {
@@ -67,56 +94,109 @@ func Connect(address, dbName, username, password string, poolLimit, port int, ti
 		return err
 	}
 
-	Conn = &DB{Client: client, DB: client.Database(dbName)}
-	go autoReconnect()
+	previous := conn.Swap(&DB{Client: client, DB: client.Database(dbName)})
+	if previous != nil {
+		// connecting again, such as with new credentials, lets the queries
+		// running on the previous client finish before it is closed
+		go previous.close()
+	}
+	startReconnect.Do(func() { go autoReconnect() })
 
 	return nil
 }
 
+// Ping returns an error if the primary of MongoDB does not answer.
+func Ping() error {
+	db := Conn()
+	if db == nil {
+		return errors.New("not connected to MongoDB")
+	}
+	db, done := db.acquire()
+	defer done()
+	return db.Client.Ping(context.TODO(), readpref.Primary())
+}
+
+// acquire returns db or, if db was closed since it was read from Conn, the
+// current connection. Its client is not closed before done is called.
+func (db *DB) acquire() (*DB, func()) {
+	for {
+		db.ops.RLock()
+		if !db.closed {
+			return db, db.ops.RUnlock
+		}
+		db.ops.RUnlock()
+		db = Conn()
+	}
+}
+
+// collection returns the named collection of the client acquire returns.
+func (db *DB) collection(name string) (*mongo.Collection, func()) {
+	db, done := db.acquire()
+	return db.DB.Collection(name), done
+}
+
+// close disconnects the client of db once the operations running on it
+// returned. The operations started on db afterwards run on the current
+// connection instead.
+func (db *DB) close() {
+	db.ops.Lock()
+	defer db.ops.Unlock()
+	db.closed = true
+	if err := db.Client.Disconnect(context.TODO()); err != nil {
+		log.Error(logActionConnect, logInfoMongo, 2023, err)
+	}
+}
+
 // autoReconnect checks mongo's connection each second and, if an error is found, reconnect to it.
 func autoReconnect() {
 	log.Info(logActionReconnect, logInfoMongo, 22)
 	var err error
 	for {
-		err = Conn.Client.Ping(context.TODO(), readpref.Primary())
+		db, done := Conn().acquire()
+		err = db.Client.Ping(context.TODO(), readpref.Primary())
 		if err != nil {
 			log.Error(logActionReconnect, logInfoMongo, 2003, err)
-			Conn.Client.Disconnect(context.TODO())
-			err = Conn.Client.Connect(context.TODO())
+			db.Client.Disconnect(context.TODO())
+			err = db.Client.Connect(context.TODO())
 			if err == nil {
 				log.Info(logActionReconnect, logInfoMongo, 23)
 			} else {
 				log.Error(logActionReconnect, logInfoMongo, 2004, err)
 			}
 		}
+		done()
 		time.Sleep(time.Second * 1)
 	}
 }
 
 // Insert inserts a new document.
 func (db *DB) Insert(obj interface{}, collection string) error {
-	c := db.DB.Collection(collection)
+	c, done := db.collection(collection)
+	defer done()
 	_, err := c.InsertOne(context.TODO(), obj)
 	return err
 }
 
 // Update updates a single document.
 func (db *DB) Update(query, updateQuery interface{}, collection string) error {
-	c := db.DB.Collection(collection)
+	c, done := db.collection(collection)
+	defer done()
 	_, err := c.UpdateOne(context.TODO(), query, updateQuery)
 	return err
 }
 
 // UpdateAll updates all documents that match the query.
 func (db *DB) UpdateAll(query, updateQuery interface{}, collection string) error {
-	c := db.DB.Collection(collection)
+	c, done := db.collection(collection)
+	defer done()
 	_, err := c.UpdateMany(context.TODO(), query, updateQuery)
 	return err
 }
 
 // FindAndModify finds a document matching the query and updates it, returning the updated document.
 func (db *DB) FindAndModify(findQuery, updateQuery interface{}, collection string, obj interface{}) error {
-	c := db.DB.Collection(collection)
+	c, done := db.collection(collection)
+	defer done()
 	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
 	err := c.FindOneAndUpdate(context.TODO(), findQuery, updateQuery, opts).Decode(obj)
 	return err
@@ -124,7 +204,8 @@ func (db *DB) FindAndModify(findQuery, updateQuery interface{}, collection strin
 
 // Search searches all documents that match the query. If selectors are present, the return will be only the chosen fields.
 func (db *DB) Search(query bson.M, selectors []string, collection string, obj interface{}) error {
-	c := db.DB.Collection(collection)
+	c, done := db.collection(collection)
+	defer done()
 	opts := options.Find()
 	if selectors != nil {
 		projection := bson.M{}
@@ -141,9 +222,36 @@ func (db *DB) Search(query bson.M, selectors []string, collection string, obj in
 	return cursor.All(context.TODO(), obj)
 }
 
+// SearchPage returns in obj, sorted by sort, the documents that match the query
+// after skipping skip of them, up to limit. It also returns how many documents
+// match the query in total.
+func (db *DB) SearchPage(query bson.M, selectors []string, sort bson.D, skip, limit int64, collection string, obj interface{}) (int64, error) {
+	c, done := db.collection(collection)
+	defer done()
+	total, err := c.CountDocuments(context.TODO(), query)
+	if err != nil {
+		return 0, err
+	}
+	opts := options.Find().SetSort(sort).SetSkip(skip).SetLimit(limit)
+	if selectors != nil {
+		projection := bson.M{}
+		for _, v := range selectors {
+			projection[v] = 1
+		}
+		opts.SetProjection(projection)
+	}
+	cursor, err := c.Find(context.TODO(), query, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(context.TODO())
+	return total, cursor.All(context.TODO(), obj)
+}
+
 // Aggregation prepares a pipeline to aggregate.
 func (db *DB) Aggregation(aggregation []bson.M, collection string) (interface{}, error) {
-	c := db.DB.Collection(collection)
+	c, done := db.collection(collection)
+	defer done()
 	cursor, err := c.Aggregate(context.TODO(), aggregation)
 	if err != nil {
 		return nil, err
@@ -155,7 +263,8 @@ func (db *DB) Aggregation(aggregation []bson.M, collection string) (interface{},
 
 // SearchOne searches for the first element that matches with the given query.
 func (db *DB) SearchOne(query bson.M, selectors []string, collection string, obj interface{}) error {
-	c := db.DB.Collection(collection)
+	c, done := db.collection(collection)
+	defer done()
 	opts := options.FindOne()
 	if selectors != nil {
 		projection := bson.M{}
@@ -168,9 +277,73 @@ func (db *DB) SearchOne(query bson.M, selectors []string, collection string, obj
 	return err
 }
 
+// Delete removes the first document that matches the query.
+func (db *DB) Delete(query bson.M, collection string) (int64, error) {
+	c, done := db.collection(collection)
+	defer done()
+	result, err := c.DeleteOne(context.TODO(), query)
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
 // Upsert inserts a document or update it if it already exists.
 func (db *DB) Upsert(query bson.M, obj interface{}, collection string) (*mongo.UpdateResult, error) {
-	c := db.DB.Collection(collection)
+	c, done := db.collection(collection)
+	defer done()
 	opts := options.Update().SetUpsert(true)
 	return c.UpdateOne(context.TODO(), query, bson.M{"$set": obj}, opts)
 }
+
+// AcquireLock tries to take the lease called name on behalf of owner for ttl. It
+// returns false if another owner holds a lease that has not expired yet.
+func (db *DB) AcquireLock(name, owner string, ttl time.Duration) (bool, error) {
+	c, done := db.collection(LockCollection)
+	defer done()
+	now := time.Now()
+	query := bson.M{"_id": name, "$or": []bson.M{{"expiresAt": bson.M{"$lt": now}}, {"owner": owner}}}
+	update := bson.M{"$set": bson.M{"owner": owner, "expiresAt": now.Add(ttl)}}
+	opts := options.Update().SetUpsert(true)
+	_, err := c.UpdateOne(context.TODO(), query, update, opts)
+	if mongo.IsDuplicateKeyError(err) {
+		// the lease exists and is still held by someone else
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ReleaseLock releases the lease called name if it is still held by owner.
+func (db *DB) ReleaseLock(name, owner string) error {
+	c, done := db.collection(LockCollection)
+	defer done()
+	_, err := c.DeleteOne(context.TODO(), bson.M{"_id": name, "owner": owner})
+	return err
+}
+
+// Index is an ascending index on the fields of Keys, in order.
+type Index struct {
+	Collection string
+	Keys       []string
+}
+
+// CreateIndexes creates indexes. The ones that already exist are left as
+// they are.
+func (db *DB) CreateIndexes(indexes []Index) error {
+	db, done := db.acquire()
+	defer done()
+	for _, index := range indexes {
+		keys := bson.D{}
+		for _, key := range index.Keys {
+			keys = append(keys, bson.E{Key: key, Value: 1})
+		}
+		c := db.DB.Collection(index.Collection)
+		if _, err := c.Indexes().CreateOne(context.TODO(), mongo.IndexModel{Keys: keys}); err != nil {
+			return fmt.Errorf("index %v of %s: %w", index.Keys, index.Collection, err)
+		}
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/huskyci-org/huskyCI/api/types"
@@ -33,6 +34,32 @@ func (pR *PostgresRequests) ConnectDB(
 		connMaxLifetime)
 }
 
+// PingDB returns an error if Postgres does not answer.
+func (pR *PostgresRequests) PingDB() error {
+	_, err := pR.DataRetriever.WriteInDB("SELECT 1")
+	return err
+}
+
+// analysisIndexStatements create the indexes of the queries run on the
+// analysis table as the analyses are polled, started and listed. "RID" is
+// already indexed by its unique constraint.
+var analysisIndexStatements = []string{
+	`CREATE INDEX IF NOT EXISTS "analysis_repositoryURL_repositoryBranch_idx" ON analysis ("repositoryURL", "repositoryBranch")`,
+	`CREATE INDEX IF NOT EXISTS "analysis_status_idx" ON analysis (status)`,
+	`CREATE INDEX IF NOT EXISTS "analysis_startedAt_idx" ON analysis ("startedAt")`,
+	`CREATE INDEX IF NOT EXISTS "analysis_finishedAt_idx" ON analysis ("finishedAt")`,
+}
+
+// EnsureIndexes creates the indexes of the analysis table that do not exist yet.
+func (pR *PostgresRequests) EnsureIndexes() error {
+	for _, statement := range analysisIndexStatements {
+		if _, err := pR.DataRetriever.WriteInDB(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // FindOneDBRepository checks if a given repository is present into repository table.
 func (pR *PostgresRequests) FindOneDBRepository(
 	mapParams map[string]interface{}) (types.Repository, error) {
@@ -66,6 +93,38 @@ func (pR *PostgresRequests) FindOneDBAnalysis(
 		query, &analysisResponse, []string{"commitAuthors"}, params...); err != nil {
 		return types.Analysis{}, err
 	}
+	if _, ok := mapParams["idempotencyKey"]; ok {
+		// the Idempotency-Key columns are not mapped from JSON, so that
+		// the API never returns them, and are read on their own
+		idempotencyResponse := []analysisIdempotency{}
+		query, params := ConfigureQuery(`SELECT "idempotencyKey", "idempotencyHash" FROM "analysis"`, mapParams)
+		if err := pR.DataRetriever.RetrieveFromDB(
+			query, &idempotencyResponse, []string{}, params...); err != nil {
+			return types.Analysis{}, err
+		}
+		analysisResponse[0].IdempotencyKey = idempotencyResponse[0].Key
+		analysisResponse[0].IdempotencyHash = idempotencyResponse[0].Hash
+	}
+	return analysisResponse[0], nil
+}
+
+// analysisIdempotency holds the Idempotency-Key columns of an analysis.
+type analysisIdempotency struct {
+	Key  string `json:"idempotencyKey"`
+	Hash string `json:"idempotencyHash"`
+}
+
+// FindOneDBAnalysisSummary checks if a given analysis is present into analysis table
+// and reads only the columns of its summary, for the queries run often.
+func (pR *PostgresRequests) FindOneDBAnalysisSummary(
+	mapParams map[string]interface{}) (types.AnalysisSummary, error) {
+	analysisResponse := []types.AnalysisSummary{}
+	query, params := ConfigureQuery(
+		`SELECT "RID", "repositoryURL", "repositoryBranch", status, result, "errorFound", "startedAt", "finishedAt" FROM analysis`, mapParams)
+	if err := pR.DataRetriever.RetrieveFromDB(
+		query, &analysisResponse, []string{}, params...); err != nil {
+		return types.AnalysisSummary{}, err
+	}
 	return analysisResponse[0], nil
 }
 
@@ -81,6 +140,15 @@ func (pR *PostgresRequests) FindOneDBUser(
 	return userResponse[0], nil
 }
 
+// FindAllDBUser returns all users of a given query present into user table.
+func (pR *PostgresRequests) FindAllDBUser(
+	mapParams map[string]interface{}) ([]types.User, error) {
+	userResponse := []types.User{}
+	query, params := ConfigureQuery(`SELECT * FROM "user"`, mapParams)
+	err := pR.DataRetriever.RetrieveFromDB(query, &userResponse, []string{}, params...)
+	return userResponse, err
+}
+
 // FindOneDBAccessToken checks if a given accessToken exists in accessToken table.
 func (pR *PostgresRequests) FindOneDBAccessToken(
 	mapParams map[string]interface{}) (types.DBToken, error) {
@@ -130,6 +198,61 @@ func (pR *PostgresRequests) FindAllDBAnalysis(
 	return analysisResponse, nil
 }
 
+// FindPageDBAnalysis returns a page of the analyses that match filter present into analysis table
+// and how many analyses match it in total.
+func (pR *PostgresRequests) FindPageDBAnalysis(
+	filter types.AnalysisFilter) ([]types.AnalysisSummary, int64, error) {
+	conditions := []string{}
+	params := []interface{}{}
+	for _, column := range [][2]string{
+		{"repositoryURL", filter.URL},
+		{"repositoryBranch", filter.Branch},
+		{"status", filter.Status},
+	} {
+		if column[1] != "" {
+			params = append(params, column[1])
+			conditions = append(conditions, fmt.Sprintf(`"%s" = $%d`, column[0], len(params)))
+		}
+	}
+	if !filter.From.IsZero() {
+		params = append(params, filter.From)
+		conditions = append(conditions, fmt.Sprintf(`"startedAt" >= $%d`, len(params)))
+	}
+	if !filter.To.IsZero() {
+		params = append(params, filter.To)
+		conditions = append(conditions, fmt.Sprintf(`"startedAt" <= $%d`, len(params)))
+	}
+	where := ""
+	if len(conditions) != 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	analysisResponse := []types.AnalysisSummary{}
+	count := []struct {
+		Total int64 `json:"total"`
+	}{}
+	if err := pR.DataRetriever.RetrieveFromDB(
+		`SELECT COUNT(*) AS total FROM analysis`+where, &count, []string{}, params...); err != nil {
+		return analysisResponse, 0, err
+	}
+	if len(count) == 0 || count[0].Total == 0 {
+		return analysisResponse, 0, nil
+	}
+
+	direction := "ASC"
+	if filter.SortDescending {
+		direction = "DESC"
+	}
+	query := fmt.Sprintf(
+		`SELECT "RID", "repositoryURL", "repositoryBranch", status, result, "errorFound", "startedAt", "finishedAt" FROM analysis%s ORDER BY "%s" %s, "RID" %s LIMIT %d OFFSET %d`,
+		where, analysisSortField(filter.SortField), direction, direction, filter.PageSize, (filter.Page-1)*filter.PageSize)
+	if err := pR.DataRetriever.RetrieveFromDB(
+		query, &analysisResponse, []string{}, params...); err != nil && err.Error() != "No data found" {
+		return analysisResponse, 0, err
+	}
+	return analysisResponse, count[0].Total, nil
+}
+
 // InsertDBRepository inserts a new repository into repository table.
 func (pR *PostgresRequests) InsertDBRepository(repository types.Repository) error {
 	if repository.URL == "" || time.Time.IsZero(repository.CreatedAt) {
@@ -157,14 +280,18 @@ func (pR *PostgresRequests) InsertDBSecurityTest(securityTest types.SecurityTest
 		return errors.New("Empty SecurityTest data")
 	}
 	securityTestMap := map[string]interface{}{
-		"name":           securityTest.Name,
-		"image":          securityTest.Image,
-		"imageTag":       securityTest.ImageTag,
-		"cmd":            securityTest.Cmd,
-		"language":       securityTest.Language,
-		"type":           securityTest.Type,
-		"default":        securityTest.Default,
-		"timeOutSeconds": securityTest.TimeOutInSeconds,
+		"name":            securityTest.Name,
+		"image":           securityTest.Image,
+		"imageTag":        securityTest.ImageTag,
+		"imageDigest":     securityTest.ImageDigest,
+		"cmd":             securityTest.Cmd,
+		"language":        securityTest.Language,
+		"type":            securityTest.Type,
+		"default":         securityTest.Default,
+		"timeOutSeconds":  securityTest.TimeOutInSeconds,
+		"networkMode":     securityTest.NetworkMode,
+		"maxOutputSizeKB": securityTest.MaxOutputSizeKB,
+		"warmPool":        securityTest.WarmPool,
 	}
 	finalQuery, values := ConfigureInsertQuery(
 		`INSERT into "securityTest"`, securityTestMap)
@@ -187,9 +314,20 @@ func (pR *PostgresRequests) InsertDBAnalysis(analysis types.Analysis) error {
 		"RID":              analysis.RID,
 		"repositoryURL":    analysis.URL,
 		"repositoryBranch": analysis.Branch,
+		"commit":           analysis.Commit,
 		"status":           analysis.Status,
 		"startedAt":        analysis.StartedAt,
 	}
+	if analysis.IdempotencyKey != "" {
+		analysisMap["idempotencyKey"] = analysis.IdempotencyKey
+		analysisMap["idempotencyHash"] = analysis.IdempotencyHash
+	}
+	if analysis.QueuedRequest != nil {
+		analysisMap["queuedRequest"] = analysis.QueuedRequest
+	}
+	if analysis.PullRequest != nil {
+		analysisMap["pullRequest"] = analysis.PullRequest
+	}
 	analysisMap, err := pR.ConfigureAnalysisData(analysisMap)
 	if err != nil {
 		return err
@@ -218,6 +356,8 @@ func (pR *PostgresRequests) InsertDBUser(user types.User) error {
 		"iterations":   user.Iterations,
 		"keylen":       user.KeyLen,
 		"hashfunction": user.HashFunction,
+		"role":         user.Role,
+		"disabled":     user.Disabled,
 	}
 	finalQuery, values := ConfigureInsertQuery(
 		`INSERT into "user"`, userMap)
@@ -289,14 +429,18 @@ func (pR *PostgresRequests) UpsertOneDBSecurityTest(
 		return nil, errors.New("Empty fields to search")
 	}
 	updatedSecurityMap := map[string]interface{}{
-		"name":           updatedSecurityTest.Name,
-		"image":          updatedSecurityTest.Image,
-		"imageTag":       updatedSecurityTest.ImageTag,
-		"cmd":            updatedSecurityTest.Cmd,
-		"type":           updatedSecurityTest.Type,
-		"language":       updatedSecurityTest.Language,
-		"default":        updatedSecurityTest.Default,
-		"timeOutSeconds": updatedSecurityTest.TimeOutInSeconds,
+		"name":            updatedSecurityTest.Name,
+		"image":           updatedSecurityTest.Image,
+		"imageTag":        updatedSecurityTest.ImageTag,
+		"imageDigest":     updatedSecurityTest.ImageDigest,
+		"cmd":             updatedSecurityTest.Cmd,
+		"type":            updatedSecurityTest.Type,
+		"language":        updatedSecurityTest.Language,
+		"default":         updatedSecurityTest.Default,
+		"timeOutSeconds":  updatedSecurityTest.TimeOutInSeconds,
+		"networkMode":     updatedSecurityTest.NetworkMode,
+		"maxOutputSizeKB": updatedSecurityTest.MaxOutputSizeKB,
+		"warmPool":        updatedSecurityTest.WarmPool,
 	}
 	finalQuery, values := ConfigureUpsertQuery(
 		`INSERT into "securityTest"`, mapParams, updatedSecurityMap)
@@ -353,6 +497,8 @@ func (pR *PostgresRequests) UpdateOneDBUser(
 		"iterations":   updatedUser.Iterations,
 		"keylen":       updatedUser.KeyLen,
 		"hashfunction": updatedUser.HashFunction,
+		"role":         updatedUser.Role,
+		"disabled":     updatedUser.Disabled,
 	}
 	finalQuery, values := ConfigureUpdateQuery(
 		`UPDATE "user"`, mapParams, updatedUserMap)
@@ -421,9 +567,555 @@ func (pR *PostgresRequests) UpdateOneDBAccessToken(
 	return nil
 }
 
-// FindAndModifyDockerAPIAddresses finds and modifies Docker API addresses, incrementing the current host index.
+// dockerHostIndex is the round-robin index of the Docker API hosts, which is
+// not shared between API replicas with Postgres.
+var dockerHostIndex int64
+
+// FindAndModifyDockerAPIAddresses returns the Docker API hosts of dockerHost
+// table, incrementing the current host index.
 func (pR *PostgresRequests) FindAndModifyDockerAPIAddresses() (types.DockerAPIAddresses, error) {
-	return types.DockerAPIAddresses{}, nil
+	hosts, err := pR.FindAllDBDockerHost(map[string]interface{}{})
+	if err != nil && err.Error() != "No data found" {
+		return types.DockerAPIAddresses{}, err
+	}
+	addresses := types.DockerAPIAddresses{
+		CurrentHostIndex: int(atomic.AddInt64(&dockerHostIndex, 1)),
+		HostList:         []string{},
+		Hosts:            hosts,
+	}
+	for _, host := range hosts {
+		if host.State == types.DockerHostActive {
+			addresses.HostList = append(addresses.HostList, host.Address)
+		}
+	}
+	return addresses, nil
+}
+
+// UpsertDockerAPIAddresses does nothing: the Docker API hosts are read from
+// dockerHost table.
+func (pR *PostgresRequests) UpsertDockerAPIAddresses(hosts []types.DockerHost) error {
+	return nil
+}
+
+// FindOneDBDockerHost checks if a given Docker API host is present into dockerHost table.
+func (pR *PostgresRequests) FindOneDBDockerHost(
+	mapParams map[string]interface{}) (types.DockerHost, error) {
+	hostResponse := []types.DockerHost{}
+	query, params := ConfigureQuery(`SELECT * FROM "dockerHost"`, mapParams)
+	if err := pR.DataRetriever.RetrieveFromDB(
+		query, &hostResponse, []string{}, params...); err != nil {
+		return types.DockerHost{}, err
+	}
+	return hostResponse[0], nil
+}
+
+// FindAllDBDockerHost returns all Docker API hosts of a given query present into dockerHost table.
+func (pR *PostgresRequests) FindAllDBDockerHost(
+	mapParams map[string]interface{}) ([]types.DockerHost, error) {
+	hostResponse := []types.DockerHost{}
+	query, params := ConfigureQuery(`SELECT * FROM "dockerHost"`, mapParams)
+	err := pR.DataRetriever.RetrieveFromDB(query, &hostResponse, []string{}, params...)
+	return hostResponse, err
+}
+
+// UpsertOneDBDockerHost inserts a Docker API host into dockerHost table
+// or replaces it if it already exists.
+func (pR *PostgresRequests) UpsertOneDBDockerHost(
+	mapParams map[string]interface{}, host types.DockerHost) error {
+	if len(mapParams) == 0 {
+		return errors.New("Empty fields to search")
+	}
+	hostMap := map[string]interface{}{
+		"address":   host.Address,
+		"state":     host.State,
+		"certPath":  host.CertPath,
+		"createdAt": host.CreatedAt,
+		"updatedAt": host.UpdatedAt,
+	}
+	finalQuery, values := ConfigureUpsertQuery(
+		`INSERT into "dockerHost"`, mapParams, hostMap)
+	rowsAff, err := pR.DataRetriever.WriteInDB(finalQuery, values...)
+	if err != nil {
+		return err
+	}
+	if rowsAff == int64(0) {
+		return errors.New("No data was updated")
+	}
+	return nil
+}
+
+// DeleteOneDBDockerHost removes a given Docker API host from dockerHost table.
+func (pR *PostgresRequests) DeleteOneDBDockerHost(mapParams map[string]interface{}) error {
+	if len(mapParams) == 0 {
+		return errors.New("Empty fields to search")
+	}
+	finalQuery, values := ConfigureQuery(`DELETE FROM "dockerHost"`, mapParams)
+	rowsAff, err := pR.DataRetriever.WriteInDB(finalQuery, values...)
+	if err != nil {
+		return err
+	}
+	if rowsAff == int64(0) {
+		return errors.New("No data found")
+	}
+	return nil
+}
+
+// FindOneDBGitCredential checks if a given git credential is present into gitCredential table.
+func (pR *PostgresRequests) FindOneDBGitCredential(
+	mapParams map[string]interface{}) (types.GitCredential, error) {
+	credentialResponse := []types.GitCredential{}
+	query, params := ConfigureQuery(`SELECT * FROM "gitCredential"`, mapParams)
+	if err := pR.DataRetriever.RetrieveFromDB(
+		query, &credentialResponse, []string{}, params...); err != nil {
+		return types.GitCredential{}, err
+	}
+	return credentialResponse[0], nil
+}
+
+// FindAllDBGitCredential returns all git credentials of a given query present into gitCredential table.
+func (pR *PostgresRequests) FindAllDBGitCredential(
+	mapParams map[string]interface{}) ([]types.GitCredential, error) {
+	credentialResponse := []types.GitCredential{}
+	query, params := ConfigureQuery(`SELECT * FROM "gitCredential"`, mapParams)
+	err := pR.DataRetriever.RetrieveFromDB(query, &credentialResponse, []string{}, params...)
+	return credentialResponse, err
+}
+
+// UpsertOneDBGitCredential inserts a git credential into gitCredential table
+// or replaces it if it already exists.
+func (pR *PostgresRequests) UpsertOneDBGitCredential(
+	mapParams map[string]interface{}, credential types.GitCredential) error {
+	if len(mapParams) == 0 {
+		return errors.New("Empty fields to search")
+	}
+	credentialMap := map[string]interface{}{
+		"repositoryURL": credential.RepositoryURL,
+		"type":          credential.Type,
+		"username":      credential.Username,
+		"secret":        credential.Secret,
+		"updatedAt":     credential.UpdatedAt,
+	}
+	finalQuery, values := ConfigureUpsertQuery(
+		`INSERT into "gitCredential"`, mapParams, credentialMap)
+	rowsAff, err := pR.DataRetriever.WriteInDB(finalQuery, values...)
+	if err != nil {
+		return err
+	}
+	if rowsAff == int64(0) {
+		return errors.New("No data was updated")
+	}
+	return nil
+}
+
+// DeleteOneDBGitCredential removes a given git credential from gitCredential table.
+func (pR *PostgresRequests) DeleteOneDBGitCredential(mapParams map[string]interface{}) error {
+	if len(mapParams) == 0 {
+		return errors.New("Empty fields to search")
+	}
+	finalQuery, values := ConfigureQuery(`DELETE FROM "gitCredential"`, mapParams)
+	rowsAff, err := pR.DataRetriever.WriteInDB(finalQuery, values...)
+	if err != nil {
+		return err
+	}
+	if rowsAff == int64(0) {
+		return errors.New("No data found")
+	}
+	return nil
+}
+
+// FindOneDBRegistryCredential checks if a given registry credential is present into registryCredential table.
+func (pR *PostgresRequests) FindOneDBRegistryCredential(
+	mapParams map[string]interface{}) (types.RegistryCredential, error) {
+	credentialResponse := []types.RegistryCredential{}
+	query, params := ConfigureQuery(`SELECT * FROM "registryCredential"`, mapParams)
+	if err := pR.DataRetriever.RetrieveFromDB(
+		query, &credentialResponse, []string{}, params...); err != nil {
+		return types.RegistryCredential{}, err
+	}
+	return credentialResponse[0], nil
+}
+
+// FindAllDBRegistryCredential returns all registry credentials of a given query present into registryCredential table.
+func (pR *PostgresRequests) FindAllDBRegistryCredential(
+	mapParams map[string]interface{}) ([]types.RegistryCredential, error) {
+	credentialResponse := []types.RegistryCredential{}
+	query, params := ConfigureQuery(`SELECT * FROM "registryCredential"`, mapParams)
+	err := pR.DataRetriever.RetrieveFromDB(query, &credentialResponse, []string{}, params...)
+	return credentialResponse, err
+}
+
+// UpsertOneDBRegistryCredential inserts a registry credential into
+// registryCredential table or replaces it if it already exists.
+func (pR *PostgresRequests) UpsertOneDBRegistryCredential(
+	mapParams map[string]interface{}, credential types.RegistryCredential) error {
+	if len(mapParams) == 0 {
+		return errors.New("Empty fields to search")
+	}
+	credentialMap := map[string]interface{}{
+		"registry":  credential.Registry,
+		"username":  credential.Username,
+		"secret":    credential.Secret,
+		"updatedAt": credential.UpdatedAt,
+	}
+	finalQuery, values := ConfigureUpsertQuery(
+		`INSERT into "registryCredential"`, mapParams, credentialMap)
+	rowsAff, err := pR.DataRetriever.WriteInDB(finalQuery, values...)
+	if err != nil {
+		return err
+	}
+	if rowsAff == int64(0) {
+		return errors.New("No data was updated")
+	}
+	return nil
+}
+
+// DeleteOneDBRegistryCredential removes a given registry credential from registryCredential table.
+func (pR *PostgresRequests) DeleteOneDBRegistryCredential(mapParams map[string]interface{}) error {
+	if len(mapParams) == 0 {
+		return errors.New("Empty fields to search")
+	}
+	finalQuery, values := ConfigureQuery(`DELETE FROM "registryCredential"`, mapParams)
+	rowsAff, err := pR.DataRetriever.WriteInDB(finalQuery, values...)
+	if err != nil {
+		return err
+	}
+	if rowsAff == int64(0) {
+		return errors.New("No data found")
+	}
+	return nil
+}
+
+// FindOneDBStatusReporter checks if a given status reporter is present into statusReporter table.
+func (pR *PostgresRequests) FindOneDBStatusReporter(
+	mapParams map[string]interface{}) (types.StatusReporter, error) {
+	reporterResponse := []types.StatusReporter{}
+	query, params := ConfigureQuery(`SELECT * FROM "statusReporter"`, mapParams)
+	if err := pR.DataRetriever.RetrieveFromDB(
+		query, &reporterResponse, []string{}, params...); err != nil {
+		return types.StatusReporter{}, err
+	}
+	return reporterResponse[0], nil
+}
+
+// FindAllDBStatusReporter returns all status reporters of a given query present into statusReporter table.
+func (pR *PostgresRequests) FindAllDBStatusReporter(
+	mapParams map[string]interface{}) ([]types.StatusReporter, error) {
+	reporterResponse := []types.StatusReporter{}
+	query, params := ConfigureQuery(`SELECT * FROM "statusReporter"`, mapParams)
+	err := pR.DataRetriever.RetrieveFromDB(query, &reporterResponse, []string{}, params...)
+	return reporterResponse, err
+}
+
+// UpsertOneDBStatusReporter inserts a status reporter into statusReporter table
+// or replaces it if it already exists.
+func (pR *PostgresRequests) UpsertOneDBStatusReporter(
+	mapParams map[string]interface{}, reporter types.StatusReporter) error {
+	if len(mapParams) == 0 {
+		return errors.New("Empty fields to search")
+	}
+	reporterMap := map[string]interface{}{
+		"repositoryURL": reporter.RepositoryURL,
+		"provider":      reporter.Provider,
+		"endpoint":      reporter.Endpoint,
+		"username":      reporter.Username,
+		"secret":        reporter.Secret,
+		"label":         reporter.Label,
+		"detailsURL":    reporter.DetailsURL,
+		"updatedAt":     reporter.UpdatedAt,
+	}
+	finalQuery, values := ConfigureUpsertQuery(
+		`INSERT into "statusReporter"`, mapParams, reporterMap)
+	rowsAff, err := pR.DataRetriever.WriteInDB(finalQuery, values...)
+	if err != nil {
+		return err
+	}
+	if rowsAff == int64(0) {
+		return errors.New("No data was updated")
+	}
+	return nil
+}
+
+// DeleteOneDBStatusReporter removes a given status reporter from statusReporter table.
+func (pR *PostgresRequests) DeleteOneDBStatusReporter(mapParams map[string]interface{}) error {
+	if len(mapParams) == 0 {
+		return errors.New("Empty fields to search")
+	}
+	finalQuery, values := ConfigureQuery(`DELETE FROM "statusReporter"`, mapParams)
+	rowsAff, err := pR.DataRetriever.WriteInDB(finalQuery, values...)
+	if err != nil {
+		return err
+	}
+	if rowsAff == int64(0) {
+		return errors.New("No data found")
+	}
+	return nil
+}
+
+// FindOneDBRemediation checks if a given remediation is present into remediation table.
+func (pR *PostgresRequests) FindOneDBRemediation(
+	mapParams map[string]interface{}) (types.Remediation, error) {
+	remediationResponse := []types.Remediation{}
+	query, params := ConfigureQuery(`SELECT * FROM "remediation"`, mapParams)
+	if err := pR.DataRetriever.RetrieveFromDB(
+		query, &remediationResponse, []string{}, params...); err != nil {
+		return types.Remediation{}, err
+	}
+	return remediationResponse[0], nil
+}
+
+// FindAllDBRemediation returns all remediations of a given query present into remediation table.
+func (pR *PostgresRequests) FindAllDBRemediation(
+	mapParams map[string]interface{}) ([]types.Remediation, error) {
+	remediationResponse := []types.Remediation{}
+	query, params := ConfigureQuery(`SELECT * FROM "remediation"`, mapParams)
+	err := pR.DataRetriever.RetrieveFromDB(query, &remediationResponse, []string{}, params...)
+	return remediationResponse, err
+}
+
+// UpsertOneDBRemediation inserts a remediation into remediation table
+// or replaces it if it already exists.
+func (pR *PostgresRequests) UpsertOneDBRemediation(
+	mapParams map[string]interface{}, remediation types.Remediation) error {
+	if len(mapParams) == 0 {
+		return errors.New("Empty fields to search")
+	}
+	remediationMap := map[string]interface{}{
+		"repositoryURL": remediation.RepositoryURL,
+		"provider":      remediation.Provider,
+		"endpoint":      remediation.Endpoint,
+		"secret":        remediation.Secret,
+		"baseBranch":    remediation.BaseBranch,
+		"updatedAt":     remediation.UpdatedAt,
+	}
+	finalQuery, values := ConfigureUpsertQuery(
+		`INSERT into "remediation"`, mapParams, remediationMap)
+	rowsAff, err := pR.DataRetriever.WriteInDB(finalQuery, values...)
+	if err != nil {
+		return err
+	}
+	if rowsAff == int64(0) {
+		return errors.New("No data was updated")
+	}
+	return nil
+}
+
+// DeleteOneDBRemediation removes a given remediation from remediation table.
+func (pR *PostgresRequests) DeleteOneDBRemediation(mapParams map[string]interface{}) error {
+	if len(mapParams) == 0 {
+		return errors.New("Empty fields to search")
+	}
+	finalQuery, values := ConfigureQuery(`DELETE FROM "remediation"`, mapParams)
+	rowsAff, err := pR.DataRetriever.WriteInDB(finalQuery, values...)
+	if err != nil {
+		return err
+	}
+	if rowsAff == int64(0) {
+		return errors.New("No data found")
+	}
+	return nil
+}
+
+// FindOneDBPolicy checks if a given policy is present into policy table.
+func (pR *PostgresRequests) FindOneDBPolicy(
+	mapParams map[string]interface{}) (types.Policy, error) {
+	policyResponse := []types.Policy{}
+	query, params := ConfigureQuery(`SELECT * FROM "policy"`, mapParams)
+	if err := pR.DataRetriever.RetrieveFromDB(
+		query, &policyResponse, []string{"ignoreRules", "requiredSecurityTests"}, params...); err != nil {
+		return types.Policy{}, err
+	}
+	return policyResponse[0], nil
+}
+
+// FindAllDBPolicy returns all policies of a given query present into policy table.
+func (pR *PostgresRequests) FindAllDBPolicy(
+	mapParams map[string]interface{}) ([]types.Policy, error) {
+	policyResponse := []types.Policy{}
+	query, params := ConfigureQuery(`SELECT * FROM "policy"`, mapParams)
+	err := pR.DataRetriever.RetrieveFromDB(query, &policyResponse, []string{"ignoreRules", "requiredSecurityTests"}, params...)
+	return policyResponse, err
+}
+
+// UpsertOneDBPolicy inserts a policy into policy table
+// or replaces it if it already exists. Its severity overrides are stored as
+// JSON.
+func (pR *PostgresRequests) UpsertOneDBPolicy(
+	mapParams map[string]interface{}, policy types.Policy) error {
+	if len(mapParams) == 0 {
+		return errors.New("Empty fields to search")
+	}
+	overridesJSON, err := pR.JSONHandler.Marshal(policy.SeverityOverrides)
+	if err != nil {
+		return err
+	}
+	policyMap := map[string]interface{}{
+		"repositoryURL":         policy.RepositoryURL,
+		"failOn":                policy.FailOn,
+		"ignoreRules":           pR.DataRetriever.PqArray(policy.IgnoreRules),
+		"severityOverrides":     overridesJSON,
+		"requiredSecurityTests": pR.DataRetriever.PqArray(policy.RequiredSecurityTests),
+		"updatedAt":             policy.UpdatedAt,
+	}
+	finalQuery, values := ConfigureUpsertQuery(
+		`INSERT into "policy"`, mapParams, policyMap)
+	rowsAff, err := pR.DataRetriever.WriteInDB(finalQuery, values...)
+	if err != nil {
+		return err
+	}
+	if rowsAff == int64(0) {
+		return errors.New("No data was updated")
+	}
+	return nil
+}
+
+// DeleteOneDBPolicy removes a given policy from policy table.
+func (pR *PostgresRequests) DeleteOneDBPolicy(mapParams map[string]interface{}) error {
+	if len(mapParams) == 0 {
+		return errors.New("Empty fields to search")
+	}
+	finalQuery, values := ConfigureQuery(`DELETE FROM "policy"`, mapParams)
+	rowsAff, err := pR.DataRetriever.WriteInDB(finalQuery, values...)
+	if err != nil {
+		return err
+	}
+	if rowsAff == int64(0) {
+		return errors.New("No data found")
+	}
+	return nil
+}
+
+// DeleteOneDBUser removes a given user from user table.
+func (pR *PostgresRequests) DeleteOneDBUser(mapParams map[string]interface{}) error {
+	if len(mapParams) == 0 {
+		return errors.New("Empty fields to search")
+	}
+	finalQuery, values := ConfigureQuery(`DELETE FROM "user"`, mapParams)
+	rowsAff, err := pR.DataRetriever.WriteInDB(finalQuery, values...)
+	if err != nil {
+		return err
+	}
+	if rowsAff == int64(0) {
+		return errors.New("No data found")
+	}
+	return nil
+}
+
+// FindOneDBGroup checks if a given repository group is present into
+// repositoryGroup table.
+func (pR *PostgresRequests) FindOneDBGroup(
+	mapParams map[string]interface{}) (types.RepositoryGroup, error) {
+	groupResponse := []types.RepositoryGroup{}
+	query, params := ConfigureQuery(`SELECT * FROM "repositoryGroup"`, mapParams)
+	if err := pR.DataRetriever.RetrieveFromDB(
+		query, &groupResponse, []string{}, params...); err != nil {
+		return types.RepositoryGroup{}, err
+	}
+	return groupResponse[0], nil
+}
+
+// FindAllDBGroup returns all repository groups of a given query present into
+// repositoryGroup table.
+func (pR *PostgresRequests) FindAllDBGroup(
+	mapParams map[string]interface{}) ([]types.RepositoryGroup, error) {
+	groupResponse := []types.RepositoryGroup{}
+	query, params := ConfigureQuery(`SELECT * FROM "repositoryGroup"`, mapParams)
+	err := pR.DataRetriever.RetrieveFromDB(query, &groupResponse, []string{}, params...)
+	return groupResponse, err
+}
+
+// UpsertOneDBGroup inserts a repository group into repositoryGroup table
+// or replaces it if it already exists. Its repositories are stored as JSON.
+func (pR *PostgresRequests) UpsertOneDBGroup(
+	mapParams map[string]interface{}, group types.RepositoryGroup) error {
+	if len(mapParams) == 0 {
+		return errors.New("Empty fields to search")
+	}
+	repositoriesJSON, err := pR.JSONHandler.Marshal(group.Repositories)
+	if err != nil {
+		return err
+	}
+	groupMap := map[string]interface{}{
+		"name":         group.Name,
+		"description":  group.Description,
+		"repositories": repositoriesJSON,
+		"updatedAt":    group.UpdatedAt,
+	}
+	finalQuery, values := ConfigureUpsertQuery(
+		`INSERT into "repositoryGroup"`, mapParams, groupMap)
+	rowsAff, err := pR.DataRetriever.WriteInDB(finalQuery, values...)
+	if err != nil {
+		return err
+	}
+	if rowsAff == int64(0) {
+		return errors.New("No data was updated")
+	}
+	return nil
+}
+
+// DeleteOneDBGroup removes a given repository group from repositoryGroup table.
+func (pR *PostgresRequests) DeleteOneDBGroup(mapParams map[string]interface{}) error {
+	if len(mapParams) == 0 {
+		return errors.New("Empty fields to search")
+	}
+	finalQuery, values := ConfigureQuery(`DELETE FROM "repositoryGroup"`, mapParams)
+	rowsAff, err := pR.DataRetriever.WriteInDB(finalQuery, values...)
+	if err != nil {
+		return err
+	}
+	if rowsAff == int64(0) {
+		return errors.New("No data found")
+	}
+	return nil
+}
+
+// FindOneDBContainerOutput checks if a given container output is present into
+// containerOutput table.
+func (pR *PostgresRequests) FindOneDBContainerOutput(
+	mapParams map[string]interface{}) (types.ContainerOutput, error) {
+	outputResponse := []types.ContainerOutput{}
+	query, params := ConfigureQuery(`SELECT * FROM "containerOutput"`, mapParams)
+	if err := pR.DataRetriever.RetrieveFromDB(
+		query, &outputResponse, []string{}, params...); err != nil {
+		return types.ContainerOutput{}, err
+	}
+	return outputResponse[0], nil
+}
+
+// UpsertOneDBContainerOutput inserts a container output into containerOutput
+// table or replaces it if it already exists.
+func (pR *PostgresRequests) UpsertOneDBContainerOutput(
+	mapParams map[string]interface{}, output types.ContainerOutput) error {
+	if len(mapParams) == 0 {
+		return errors.New("Empty fields to search")
+	}
+	outputMap := map[string]interface{}{
+		"RID":          output.RID,
+		"securityTest": output.SecurityTest,
+		"output":       output.Output,
+		"size":         output.Size,
+		"truncated":    output.Truncated,
+		"createdAt":    output.CreatedAt,
+	}
+	finalQuery, values := ConfigureUpsertQuery(
+		`INSERT into "containerOutput"`, mapParams, outputMap)
+	rowsAff, err := pR.DataRetriever.WriteInDB(finalQuery, values...)
+	if err != nil {
+		return err
+	}
+	if rowsAff == int64(0) {
+		return errors.New("No data was updated")
+	}
+	return nil
+}
+
+// AcquireLock always succeeds in postgres, as a single API replica is assumed.
+func (pR *PostgresRequests) AcquireLock(name, owner string, ttl time.Duration) (bool, error) {
+	return true, nil
+}
+
+// ReleaseLock releases a distributed lock previously acquired by owner.
+func (pR *PostgresRequests) ReleaseLock(name, owner string) error {
+	return nil
 }
 
 // GetMetricByType returns data about the metric received
@@ -576,6 +1268,34 @@ func (pR *PostgresRequests) ConfigureAnalysisData(
 		}
 		updatedAnalysis["huskyciresults"] = huskyJSON
 	}
+	if warnings, ok := updatedAnalysis["warnings"].([]string); ok {
+		warningsJSON, err := pR.JSONHandler.Marshal(warnings)
+		if err != nil {
+			return updatedAnalysis, err
+		}
+		updatedAnalysis["warnings"] = warningsJSON
+	}
+	if queuedRequest, ok := updatedAnalysis["queuedRequest"].(*types.Repository); ok && queuedRequest != nil {
+		queuedJSON, err := pR.JSONHandler.Marshal(queuedRequest)
+		if err != nil {
+			return updatedAnalysis, err
+		}
+		updatedAnalysis["queuedRequest"] = queuedJSON
+	}
+	if pullRequest, ok := updatedAnalysis["pullRequest"].(*types.PullRequest); ok && pullRequest != nil {
+		pullRequestJSON, err := pR.JSONHandler.Marshal(pullRequest)
+		if err != nil {
+			return updatedAnalysis, err
+		}
+		updatedAnalysis["pullRequest"] = pullRequestJSON
+	}
+	if targetDiff, ok := updatedAnalysis["targetDiff"].(*types.TargetDiff); ok && targetDiff != nil {
+		targetDiffJSON, err := pR.JSONHandler.Marshal(targetDiff)
+		if err != nil {
+			return updatedAnalysis, err
+		}
+		updatedAnalysis["targetDiff"] = targetDiffJSON
+	}
 	if myCodes, ok := updatedAnalysis["codes"].([]types.Code); ok {
 		codeJSON, err := pR.JSONHandler.Marshal(myCodes)
 		if err != nil {
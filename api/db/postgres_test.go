@@ -15,8 +15,10 @@ type FakeRetriever struct {
 	expectedRepository    types.Repository
 	expectedSecurityTest  types.SecurityTest
 	expectedAnalysis      types.Analysis
+	expectedSummary       types.AnalysisSummary
 	expectedUser          types.User
 	expectedDBToken       types.DBToken
+	expectedDockerHost    types.DockerHost
 	expectedWriteError    error
 	expectedNumberRows    int64
 	expectedConnectError  error
@@ -47,12 +49,16 @@ func (fR *FakeRetriever) RetrieveFromDB(
 		case *[]types.Analysis:
 			// newV := response.(*[]types.Analysis)
 			(*r) = append((*r), fR.expectedAnalysis)
+		case *[]types.AnalysisSummary:
+			(*r) = append((*r), fR.expectedSummary)
 		case *[]types.User:
 			// newV := response.(*[]types.User)
 			(*r) = append((*r), fR.expectedUser)
 		case *[]types.DBToken:
 			// newV := response.(*[]types.DBToken)
 			(*r) = append((*r), fR.expectedDBToken)
+		case *[]types.DockerHost:
+			(*r) = append((*r), fR.expectedDockerHost)
 		}
 	}
 	return fR.expectedRetrieveError
@@ -242,6 +248,60 @@ var _ = Describe("Postgres", func() {
 			})
 		})
 	})
+	Describe("FindOneDBAnalysisSummary", func() {
+		Context("When RetrieveFromDB returns an error", func() {
+			It("Should return an empty AnalysisSummary with the same error", func() {
+				fakeRetriever := FakeRetriever{
+					expectedRetrieveError: errors.New("Failed to retrieve data"),
+				}
+				postgres := PostgresRequests{
+					DataRetriever: &fakeRetriever,
+				}
+				summary, err := postgres.FindOneDBAnalysisSummary(
+					map[string]interface{}{"RID": "teste"})
+				Expect(summary).To(Equal(types.AnalysisSummary{}))
+				Expect(err).To(Equal(fakeRetriever.expectedRetrieveError))
+			})
+		})
+		Context("When RetrieveFromDB returns the valid AnalysisSummary struct", func() {
+			It("Should return the expected AnalysisSummary with a nil error", func() {
+				fakeRetriever := FakeRetriever{
+					expectedSummary: types.AnalysisSummary{
+						RID:    "teste",
+						Status: "running",
+					},
+				}
+				postgres := PostgresRequests{
+					DataRetriever: &fakeRetriever,
+				}
+				summary, err := postgres.FindOneDBAnalysisSummary(
+					map[string]interface{}{"RID": "teste"})
+				Expect(summary).To(Equal(fakeRetriever.expectedSummary))
+				Expect(err).To(BeNil())
+			})
+		})
+	})
+	Describe("EnsureIndexes", func() {
+		Context("When WriteInDB returns an error", func() {
+			It("Should return the same error", func() {
+				fakeRetriever := FakeRetriever{
+					expectedWriteError: errors.New("Failed to create index"),
+				}
+				postgres := PostgresRequests{
+					DataRetriever: &fakeRetriever,
+				}
+				Expect(postgres.EnsureIndexes()).To(Equal(fakeRetriever.expectedWriteError))
+			})
+		})
+		Context("When WriteInDB succeeds", func() {
+			It("Should return a nil error", func() {
+				postgres := PostgresRequests{
+					DataRetriever: &FakeRetriever{},
+				}
+				Expect(postgres.EnsureIndexes()).To(Succeed())
+			})
+		})
+	})
 	Describe("FindOneDBUser", func() {
 		Context("When RetrieveFromDB returns an error", func() {
 			It("Should return an empty User with the same error", func() {
@@ -923,6 +983,109 @@ var _ = Describe("Postgres", func() {
 		})
 	})
 
+	Describe("FindAllDBUser", func() {
+		Context("When RetrieveFromDB returns the users", func() {
+			It("Should return them with a nil error", func() {
+				fakeRetriever := FakeRetriever{
+					expectedUser: types.User{Username: "operator", Role: "operator", Disabled: true},
+				}
+				postgres := PostgresRequests{
+					DataRetriever: &fakeRetriever,
+				}
+				users, err := postgres.FindAllDBUser(map[string]interface{}{})
+				Expect(users).To(Equal([]types.User{fakeRetriever.expectedUser}))
+				Expect(err).To(BeNil())
+			})
+		})
+	})
+
+	Describe("DeleteOneDBUser", func() {
+		Context("When an empty mapParams is passed as argument", func() {
+			It("Should return the expected error", func() {
+				postgres := PostgresRequests{}
+				Expect(postgres.DeleteOneDBUser(map[string]interface{}{})).To(
+					Equal(errors.New("Empty fields to search")))
+			})
+		})
+		Context("When WriteInDB returns 0 rows affected", func() {
+			It("Should return the expected error", func() {
+				fakeRetriever := FakeRetriever{
+					expectedNumberRows: 0,
+				}
+				postgres := PostgresRequests{
+					DataRetriever: &fakeRetriever,
+				}
+				Expect(postgres.DeleteOneDBUser(validParams)).To(
+					Equal(errors.New("No data found")))
+			})
+		})
+		Context("When WriteInDB returns a number of rows affected", func() {
+			It("Should return a nil error", func() {
+				fakeRetriever := FakeRetriever{
+					expectedNumberRows: 1,
+				}
+				postgres := PostgresRequests{
+					DataRetriever: &fakeRetriever,
+				}
+				Expect(postgres.DeleteOneDBUser(validParams)).To(BeNil())
+			})
+		})
+	})
+
+	Describe("FindAndModifyDockerAPIAddresses", func() {
+		Context("When dockerHost table has an active host", func() {
+			It("Should list it and move the round-robin index", func() {
+				fakeRetriever := FakeRetriever{
+					expectedDockerHost: types.DockerHost{Address: "dockerapi", State: types.DockerHostActive},
+				}
+				postgres := PostgresRequests{
+					DataRetriever: &fakeRetriever,
+				}
+				first, err := postgres.FindAndModifyDockerAPIAddresses()
+				Expect(err).To(BeNil())
+				Expect(first.HostList).To(Equal([]string{"dockerapi"}))
+				Expect(first.Hosts).To(Equal([]types.DockerHost{fakeRetriever.expectedDockerHost}))
+				second, _ := postgres.FindAndModifyDockerAPIAddresses()
+				Expect(second.CurrentHostIndex).To(Equal(first.CurrentHostIndex + 1))
+			})
+		})
+		Context("When the only host is draining", func() {
+			It("Should not list it among the active ones", func() {
+				fakeRetriever := FakeRetriever{
+					expectedDockerHost: types.DockerHost{Address: "dockerapi", State: types.DockerHostDraining},
+				}
+				postgres := PostgresRequests{
+					DataRetriever: &fakeRetriever,
+				}
+				addresses, err := postgres.FindAndModifyDockerAPIAddresses()
+				Expect(err).To(BeNil())
+				Expect(addresses.HostList).To(BeEmpty())
+				Expect(addresses.Hosts).To(HaveLen(1))
+			})
+		})
+	})
+
+	Describe("UpsertOneDBDockerHost", func() {
+		Context("When an empty mapParams is passed as argument", func() {
+			It("Should return the expected error", func() {
+				postgres := PostgresRequests{}
+				Expect(postgres.UpsertOneDBDockerHost(map[string]interface{}{}, types.DockerHost{})).To(
+					Equal(errors.New("Empty fields to search")))
+			})
+		})
+		Context("When WriteInDB returns a number of rows affected", func() {
+			It("Should return a nil error", func() {
+				fakeRetriever := FakeRetriever{
+					expectedNumberRows: 1,
+				}
+				postgres := PostgresRequests{
+					DataRetriever: &fakeRetriever,
+				}
+				Expect(postgres.UpsertOneDBDockerHost(validParams, types.DockerHost{Address: "dockerapi"})).To(BeNil())
+			})
+		})
+	})
+
 	Describe("UpdateOneDBAnalysisContainer", func() {
 		Context("When an empty updateQuery is passed as argument", func() {
 			It("Should return the expected error", func() {
@@ -14,14 +14,19 @@ import (
 // implementing Requests.
 type Requests interface {
 	ConnectDB(address string, dbName string, username string, password string, timeout time.Duration, poolLimit int, port int, maxOpenConns int, maxIdleConns int, connMaxLifetime time.Duration) error
+	PingDB() error
+	EnsureIndexes() error
 	FindOneDBRepository(mapParams map[string]interface{}) (types.Repository, error)
 	FindOneDBSecurityTest(mapParams map[string]interface{}) (types.SecurityTest, error)
 	FindOneDBAnalysis(mapParams map[string]interface{}) (types.Analysis, error)
+	FindOneDBAnalysisSummary(mapParams map[string]interface{}) (types.AnalysisSummary, error)
 	FindOneDBUser(mapParams map[string]interface{}) (types.User, error)
 	FindOneDBAccessToken(mapParams map[string]interface{}) (types.DBToken, error)
 	FindAllDBRepository(mapParams map[string]interface{}) ([]types.Repository, error)
 	FindAllDBSecurityTest(mapParams map[string]interface{}) ([]types.SecurityTest, error)
 	FindAllDBAnalysis(mapParams map[string]interface{}) ([]types.Analysis, error)
+	FindPageDBAnalysis(filter types.AnalysisFilter) ([]types.AnalysisSummary, int64, error)
+	FindAllDBUser(mapParams map[string]interface{}) ([]types.User, error)
 	InsertDBRepository(repository types.Repository) error
 	InsertDBSecurityTest(securityTest types.SecurityTest) error
 	InsertDBAnalysis(analysis types.Analysis) error
@@ -33,13 +38,51 @@ type Requests interface {
 	UpdateOneDBUser(mapParams map[string]interface{}, updatedUser types.User) error
 	UpdateOneDBAnalysisContainer(mapParams, updateQuery map[string]interface{}) error
 	UpdateOneDBAccessToken(mapParams map[string]interface{}, updatedAccessToken types.DBToken) error
+	DeleteOneDBUser(mapParams map[string]interface{}) error
 	FindAndModifyDockerAPIAddresses() (types.DockerAPIAddresses, error)
+	UpsertDockerAPIAddresses(hosts []types.DockerHost) error
+	FindOneDBDockerHost(mapParams map[string]interface{}) (types.DockerHost, error)
+	FindAllDBDockerHost(mapParams map[string]interface{}) ([]types.DockerHost, error)
+	UpsertOneDBDockerHost(mapParams map[string]interface{}, host types.DockerHost) error
+	DeleteOneDBDockerHost(mapParams map[string]interface{}) error
+	FindOneDBGitCredential(mapParams map[string]interface{}) (types.GitCredential, error)
+	FindAllDBGitCredential(mapParams map[string]interface{}) ([]types.GitCredential, error)
+	UpsertOneDBGitCredential(mapParams map[string]interface{}, credential types.GitCredential) error
+	DeleteOneDBGitCredential(mapParams map[string]interface{}) error
+	FindOneDBRegistryCredential(mapParams map[string]interface{}) (types.RegistryCredential, error)
+	FindAllDBRegistryCredential(mapParams map[string]interface{}) ([]types.RegistryCredential, error)
+	UpsertOneDBRegistryCredential(mapParams map[string]interface{}, credential types.RegistryCredential) error
+	DeleteOneDBRegistryCredential(mapParams map[string]interface{}) error
+	FindOneDBStatusReporter(mapParams map[string]interface{}) (types.StatusReporter, error)
+	FindAllDBStatusReporter(mapParams map[string]interface{}) ([]types.StatusReporter, error)
+	UpsertOneDBStatusReporter(mapParams map[string]interface{}, reporter types.StatusReporter) error
+	DeleteOneDBStatusReporter(mapParams map[string]interface{}) error
+	FindOneDBRemediation(mapParams map[string]interface{}) (types.Remediation, error)
+	FindAllDBRemediation(mapParams map[string]interface{}) ([]types.Remediation, error)
+	UpsertOneDBRemediation(mapParams map[string]interface{}, remediation types.Remediation) error
+	DeleteOneDBRemediation(mapParams map[string]interface{}) error
+	FindOneDBPolicy(mapParams map[string]interface{}) (types.Policy, error)
+	FindAllDBPolicy(mapParams map[string]interface{}) ([]types.Policy, error)
+	UpsertOneDBPolicy(mapParams map[string]interface{}, policy types.Policy) error
+	DeleteOneDBPolicy(mapParams map[string]interface{}) error
+	FindOneDBGroup(mapParams map[string]interface{}) (types.RepositoryGroup, error)
+	FindAllDBGroup(mapParams map[string]interface{}) ([]types.RepositoryGroup, error)
+	UpsertOneDBGroup(mapParams map[string]interface{}, group types.RepositoryGroup) error
+	DeleteOneDBGroup(mapParams map[string]interface{}) error
+	FindOneDBContainerOutput(mapParams map[string]interface{}) (types.ContainerOutput, error)
+	UpsertOneDBContainerOutput(mapParams map[string]interface{}, output types.ContainerOutput) error
+	AcquireLock(name, owner string, ttl time.Duration) (bool, error)
+	ReleaseLock(name, owner string) error
 	GetMetricByType(metricType string, queryStringParams map[string][]string) (interface{}, error)
 }
 
 // MongoRequests implements Requests
-// for Mongo, a non-relational DB.
-type MongoRequests struct{}
+// for Mongo, a non-relational DB. The results of
+// the analyses larger than CompressResultsAbove
+// bytes are stored compressed.
+type MongoRequests struct {
+	CompressResultsAbove int
+}
 
 // JSON interface defines the functions that will threat data
 // to be transformed to JSON or a JSON that will be mapped in
@@ -0,0 +1,151 @@
+// Package debugtrace records the steps the API goes through while it receives
+// and runs analyses, for operators to follow one of them when the logs are not
+// enough. It records nothing unless it is enabled, and keeps only the latest
+// events in memory.
+package debugtrace
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Verbosity levels of the events.
+const (
+	// Off records no event.
+	Off = 0
+	// Lifecycle records the uploads, extractions and container runs.
+	Lifecycle = 1
+	// Verbose also records the commands and the outputs of the containers.
+	Verbose = 2
+)
+
+// DefaultSize is how many events are kept when no size is configured.
+const DefaultSize = 1000
+
+// maxValueLength bounds the values of the fields of an event, so that a
+// container output does not take the whole buffer.
+const maxValueLength = 4096
+
+// Config represents the debug tracing configuration.
+type Config struct {
+	Level int
+	Size  int
+}
+
+// Event is a step of the lifecycle of an analysis.
+type Event struct {
+	Time      time.Time         `json:"time"`
+	Level     int               `json:"level"`
+	Component string            `json:"component"`
+	Action    string            `json:"action"`
+	RID       string            `json:"rid,omitempty"`
+	Fields    map[string]string `json:"fields,omitempty"`
+}
+
+// Trace is the state of a Recorder and the events it kept, oldest first.
+type Trace struct {
+	Level  int     `json:"level"`
+	Size   int     `json:"size"`
+	Events []Event `json:"events"`
+}
+
+// Recorder keeps the latest events up to its level in a ring buffer.
+type Recorder struct {
+	mu     sync.Mutex
+	level  int
+	events []Event
+	next   int
+	full   bool
+}
+
+// New returns a Recorder keeping the latest size events up to level.
+func New(level, size int) *Recorder {
+	if size <= 0 {
+		size = DefaultSize
+	}
+	return &Recorder{level: level, events: make([]Event, size)}
+}
+
+var std = New(Off, 1)
+
+// Init sets the level and the size of the Recorder of the API.
+func Init(config *Config) {
+	if config == nil {
+		return
+	}
+	std = New(config.Level, config.Size)
+}
+
+// Enabled returns true if events of level are recorded.
+func (r *Recorder) Enabled(level int) bool {
+	return level > Off && level <= r.level
+}
+
+// Record keeps an event of level about RID, if events of level are recorded.
+// fields are key and value pairs, the values being formatted as with
+// fmt.Sprint.
+func (r *Recorder) Record(level int, component, action, RID string, fields ...interface{}) {
+	if !r.Enabled(level) {
+		return
+	}
+	event := Event{Time: time.Now(), Level: level, Component: component, Action: action, RID: RID}
+	if len(fields) > 0 {
+		event.Fields = map[string]string{}
+	}
+	for i := 0; i < len(fields); i += 2 {
+		key := fmt.Sprint(fields[i])
+		value := ""
+		if i+1 < len(fields) {
+			value = fmt.Sprint(fields[i+1])
+		}
+		if len(value) > maxValueLength {
+			value = value[:maxValueLength] + "...(truncated)"
+		}
+		event.Fields[key] = value
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events[r.next] = event
+	r.next = (r.next + 1) % len(r.events)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Trace returns the latest limit events about RID, or about every analysis
+// if RID is empty. A limit that is not positive returns all of them.
+func (r *Recorder) Trace(RID string, limit int) Trace {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ordered := r.events[:r.next]
+	if r.full {
+		ordered = append(append([]Event{}, r.events[r.next:]...), r.events[:r.next]...)
+	}
+	events := []Event{}
+	for _, event := range ordered {
+		if RID == "" || event.RID == RID {
+			events = append(events, event)
+		}
+	}
+	if limit > 0 && len(events) > limit {
+		events = events[len(events)-limit:]
+	}
+	return Trace{Level: r.level, Size: len(r.events), Events: events}
+}
+
+// Enabled returns true if the API records events of level.
+func Enabled(level int) bool {
+	return std.Enabled(level)
+}
+
+// Record keeps an event in the Recorder of the API. See Recorder.Record.
+func Record(level int, component, action, RID string, fields ...interface{}) {
+	std.Record(level, component, action, RID, fields...)
+}
+
+// Get returns the latest events kept by the API. See Recorder.Trace.
+func Get(RID string, limit int) Trace {
+	return std.Trace(RID, limit)
+}
@@ -0,0 +1,13 @@
+package debugtrace_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestDebugtrace(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Debugtrace Suite")
+}
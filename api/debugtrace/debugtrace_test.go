@@ -0,0 +1,73 @@
+package debugtrace_test
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/huskyci-org/huskyCI/api/debugtrace"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Recorder", func() {
+	Context("When it is off", func() {
+		It("Should record nothing", func() {
+			recorder := debugtrace.New(debugtrace.Off, 10)
+			recorder.Record(debugtrace.Lifecycle, "docker", "run", "RID")
+			Expect(recorder.Trace("", 0).Events).To(BeEmpty())
+		})
+	})
+
+	Context("When it records lifecycle events", func() {
+		It("Should skip verbose events", func() {
+			recorder := debugtrace.New(debugtrace.Lifecycle, 10)
+			recorder.Record(debugtrace.Lifecycle, "docker", "run", "RID", "image", "alpine", "timeout", 30)
+			recorder.Record(debugtrace.Verbose, "docker", "output", "RID", "output", "hello")
+
+			trace := recorder.Trace("", 0)
+			Expect(trace.Level).To(Equal(debugtrace.Lifecycle))
+			Expect(trace.Events).To(HaveLen(1))
+			Expect(trace.Events[0].Action).To(Equal("run"))
+			Expect(trace.Events[0].Fields).To(Equal(map[string]string{"image": "alpine", "timeout": "30"}))
+		})
+	})
+
+	Context("When the buffer is full", func() {
+		It("Should keep the latest events, oldest first", func() {
+			recorder := debugtrace.New(debugtrace.Verbose, 3)
+			for i := 0; i < 5; i++ {
+				recorder.Record(debugtrace.Lifecycle, "docker", fmt.Sprint(i), "RID")
+			}
+			actions := []string{}
+			for _, event := range recorder.Trace("", 0).Events {
+				actions = append(actions, event.Action)
+			}
+			Expect(actions).To(Equal([]string{"2", "3", "4"}))
+		})
+	})
+
+	Context("When the trace is filtered", func() {
+		It("Should return the latest events of the RID up to the limit", func() {
+			recorder := debugtrace.New(debugtrace.Lifecycle, 10)
+			recorder.Record(debugtrace.Lifecycle, "docker", "create", "A")
+			recorder.Record(debugtrace.Lifecycle, "docker", "create", "B")
+			recorder.Record(debugtrace.Lifecycle, "docker", "start", "A")
+			recorder.Record(debugtrace.Lifecycle, "docker", "remove", "A")
+
+			events := recorder.Trace("A", 2).Events
+			Expect(events).To(HaveLen(2))
+			Expect(events[0].Action).To(Equal("start"))
+			Expect(events[1].Action).To(Equal("remove"))
+		})
+	})
+
+	Context("When a field is too long", func() {
+		It("Should truncate it", func() {
+			recorder := debugtrace.New(debugtrace.Verbose, 10)
+			recorder.Record(debugtrace.Verbose, "docker", "output", "RID", "output", strings.Repeat("a", 10000))
+			Expect(recorder.Trace("", 0).Events[0].Fields["output"]).To(HaveSuffix("...(truncated)"))
+			Expect(len(recorder.Trace("", 0).Events[0].Fields["output"])).To(BeNumerically("<", 5000))
+		})
+	})
+})
@@ -2,26 +2,82 @@ package dockers
 
 import (
 	"bufio"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	dockerTypes "github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/system"
+	"github.com/docker/docker/api/types/volume"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/go-connections/tlsconfig"
 	apiContext "github.com/huskyci-org/huskyCI/api/context"
 	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/registryauth"
+	"github.com/huskyci-org/huskyCI/api/spool"
+	"github.com/huskyci-org/huskyCI/api/types"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	goContext "golang.org/x/net/context"
 )
 
 // Docker is the docker struct
 type Docker struct {
-	CID    string `json:"Id"`
-	client *client.Client
+	CID      string `json:"Id"`
+	client   Client
+	proxyEnv []string
+	host     string
+	osType   string
+}
+
+// Client is the part of the Docker SDK client huskyCI uses. It is implemented
+// by *client.Client and by fakes in tests.
+type Client interface {
+	ContainerCreate(ctx goContext.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error)
+	ContainerStart(ctx goContext.Context, containerID string, options container.StartOptions) error
+	ContainerWait(ctx goContext.Context, containerID string, condition container.WaitCondition) (<-chan container.WaitResponse, <-chan error)
+	ContainerStop(ctx goContext.Context, containerID string, options container.StopOptions) error
+	ContainerRemove(ctx goContext.Context, containerID string, options container.RemoveOptions) error
+	ContainerList(ctx goContext.Context, options container.ListOptions) ([]dockerTypes.Container, error)
+	ContainerLogs(ctx goContext.Context, containerID string, options container.LogsOptions) (io.ReadCloser, error)
+	ContainerExecCreate(ctx goContext.Context, containerID string, config dockerTypes.ExecConfig) (dockerTypes.IDResponse, error)
+	ContainerExecAttach(ctx goContext.Context, execID string, config dockerTypes.ExecStartCheck) (dockerTypes.HijackedResponse, error)
+	ContainerExecInspect(ctx goContext.Context, execID string) (dockerTypes.ContainerExecInspect, error)
+	ContainerDiff(ctx goContext.Context, containerID string) ([]container.FilesystemChange, error)
+	CopyToContainer(ctx goContext.Context, containerID, dstPath string, content io.Reader, options dockerTypes.CopyToContainerOptions) error
+	ImagePull(ctx goContext.Context, refStr string, options dockerTypes.ImagePullOptions) (io.ReadCloser, error)
+	ImageList(ctx goContext.Context, options dockerTypes.ImageListOptions) ([]image.Summary, error)
+	ImageRemove(ctx goContext.Context, imageID string, options dockerTypes.ImageRemoveOptions) ([]image.DeleteResponse, error)
+	ImagesPrune(ctx goContext.Context, pruneFilters filters.Args) (dockerTypes.ImagesPruneReport, error)
+	VolumeCreate(ctx goContext.Context, options volume.CreateOptions) (volume.Volume, error)
+	VolumeRemove(ctx goContext.Context, volumeID string, force bool) error
+	VolumeList(ctx goContext.Context, options volume.ListOptions) (volume.ListResponse, error)
+	Ping(ctx goContext.Context) (dockerTypes.Ping, error)
+	Info(ctx goContext.Context) (system.Info, error)
+}
+
+// Open returns the Docker of dockerHost. It is NewDocker unless tests replace
+// it to run the functions of this package against a fake Client.
+var Open = NewDocker
+
+// NewDockerWithClient returns a Docker sending its requests to c.
+func NewDockerWithClient(c Client) *Docker {
+	return &Docker{client: c}
 }
 
 // CreateContainerPayload is a struct that represents all data needed to create a container.
@@ -31,6 +87,37 @@ type CreateContainerPayload struct {
 	Cmd   []string `json:"Cmd"`
 }
 
+// ContainerOptions holds the optional settings of a container created by
+// huskyCI. NetworkMode is a Docker network mode, such as "none" or the name of
+// a network, and an empty value keeps the daemon default. Env is a list of
+// KEY=value variables set in the container and Mounts are bound read-only
+// unless they are Writable. MaxOutputSize is the size in bytes the output of
+// the container is cut at, if positive. Workspace is a directory of the API copied to
+// WorkspacePath in the container before it starts, instead of mounting a
+// volume there. RID is the analysis the container runs for, as recorded in the
+// debug trace. Every container is hardened as set in the
+// ContainerSecurityConfig of the API, and ImageUser keeps the user of the image
+// instead of the one configured there. Labels are set on the container besides
+// HuskyCILabel.
+type ContainerOptions struct {
+	NetworkMode string
+	Env         []string
+	Mounts      []types.VolumeMount
+	Workspace     string
+	RID           string
+	ImageUser     bool
+	MaxOutputSize int64
+	Labels        map[string]string
+}
+
+// HuskyCILabel is set on every container created by huskyCI so they can be
+// told apart from other containers running in the same Docker API host.
+const HuskyCILabel = "huskyci"
+
+// AnalysisLabel is set on the volume holding the code of an analysis, with
+// the RID of the analysis as value.
+const AnalysisLabel = "huskyci.rid"
+
 const logActionNew = "NewDocker"
 const logInfoAPI = "DOCKERAPI"
 
@@ -84,66 +171,250 @@ func NewDocker(dockerHost string) (*Docker, error) {
 		return nil, fmt.Errorf("Docker host is empty; set HUSKYCI_DOCKERAPI_ADDR (e.g. dockerapi)")
 	}
 
-	// Set env vars so WithTLSClientConfigFromEnv can read them; do not rely on DOCKER_HOST for client creation
-	// to avoid races when multiple goroutines call NewDocker (each gets explicit host via WithHost).
-	err = os.Setenv("DOCKER_HOST", dockerHost)
-	if err != nil {
-		log.Error(logActionNew, logInfoAPI, 3001, err)
-		return nil, err
-	}
-	isUnixSocket := strings.HasPrefix(dockerHost, "unix://")
-	if !isUnixSocket && configAPI.DockerHostsConfig != nil {
-		err = os.Setenv("DOCKER_CERT_PATH", configAPI.DockerHostsConfig.PathCertificate)
-		if err != nil {
-			log.Error(logActionNew, logInfoAPI, 3019, err)
-			return nil, err
+	// Each host gets its own HTTP client carrying its TLS material, rather
+	// than the process-wide DOCKER_* variables shared by every goroutine,
+	// and the client is shared by the operations on the host.
+	withTLS := !strings.HasPrefix(dockerHost, "unix://") && configAPI.DockerHostsConfig != nil
+	key := dockerHost
+	if withTLS {
+		key = fmt.Sprintf("%s|%s|%d", dockerHost, hostCertDir(configAPI.DockerHostsConfig, dockerHostname(dockerHost)), configAPI.DockerHostsConfig.TLSVerify)
+	}
+	cli, err := SharedClient(key, func() (Client, error) {
+		opts := []client.Opt{client.WithAPIVersionNegotiation()}
+		if withTLS {
+			httpClient, err := hostHTTPClient(configAPI.DockerHostsConfig, dockerHostname(dockerHost))
+			if err != nil {
+				log.Error(logActionNew, logInfoAPI, 3019, dockerHost, err)
+				return nil, err
+			}
+			if httpClient != nil {
+				opts = append(opts, client.WithHTTPClient(httpClient))
+			}
 		}
-		err = os.Setenv("DOCKER_TLS_VERIFY", strconv.Itoa(configAPI.DockerHostsConfig.TLSVerify))
+		opts = append(opts, client.WithHost(dockerHost))
+		cli, err := client.NewClientWithOpts(opts...)
 		if err != nil {
-			log.Error(logActionNew, logInfoAPI, 3020, err)
+			log.Error(logActionNew, logInfoAPI, 3002, err)
 			return nil, err
 		}
-	} else if isUnixSocket {
-		os.Unsetenv("DOCKER_CERT_PATH")
-		os.Unsetenv("DOCKER_TLS_VERIFY")
-	}
-
-	opts := []client.Opt{client.WithHost(dockerHost), client.WithAPIVersionNegotiation()}
-	if !isUnixSocket {
-		opts = append(opts, client.WithTLSClientConfigFromEnv())
-	}
-	cli, err := client.NewClientWithOpts(opts...)
+		return cli, nil
+	})
 	if err != nil {
-		log.Error(logActionNew, logInfoAPI, 3002, err)
 		return nil, err
 	}
 	docker := &Docker{
 		client: cli,
+		host:   dockerHost,
+	}
+	if configAPI.DockerHostsConfig != nil {
+		proxy := configAPI.DockerHostsConfig.ProxyFor(dockerHostname(dockerHost))
+		docker.proxyEnv = ProxyEnv(proxy, configAPI.DockerHostsConfig.NoProxy)
 	}
 	return docker, nil
 }
 
+// ProxyEnv returns the proxy environment variables, in both lower and upper
+// case, that make the tools running in a container use proxy. It returns nil if
+// proxy is empty.
+func ProxyEnv(proxy, noProxy string) []string {
+	if proxy == "" {
+		return nil
+	}
+	env := []string{"http_proxy=" + proxy, "https_proxy=" + proxy, "HTTP_PROXY=" + proxy, "HTTPS_PROXY=" + proxy}
+	if noProxy != "" {
+		env = append(env, "no_proxy="+noProxy, "NO_PROXY="+noProxy)
+	}
+	return env
+}
+
+// dockerHostname returns the hostname of a Docker API address such as
+// https://dockerapi:2376, as listed in HUSKYCI_DOCKERAPI_ADDR.
+func dockerHostname(dockerHost string) string {
+	parsed, err := url.Parse(dockerHost)
+	if err != nil || parsed.Hostname() == "" {
+		return dockerHost
+	}
+	return parsed.Hostname()
+}
+
+// hostCertPaths holds the directory of the TLS material of the Docker API
+// hosts registered with their own, by address.
+var hostCertPaths = struct {
+	sync.RWMutex
+	byHost map[string]string
+}{byHost: map[string]string{}}
+
+// SetHostCertPaths sets the directories of the TLS material of the Docker API
+// hosts registered with their own, replacing the ones set before.
+func SetHostCertPaths(hosts []types.DockerHost) {
+	byHost := map[string]string{}
+	for _, host := range hosts {
+		if host.CertPath != "" {
+			byHost[host.Address] = host.CertPath
+		}
+	}
+	hostCertPaths.Lock()
+	defer hostCertPaths.Unlock()
+	hostCertPaths.byHost = byHost
+}
+
+// HostCertPath returns the directory of the TLS material of the Docker API
+// host address, or an empty string if it uses the one of the configuration.
+func HostCertPath(address string) string {
+	hostCertPaths.RLock()
+	defer hostCertPaths.RUnlock()
+	return hostCertPaths.byHost[address]
+}
+
+// hostCertDir returns the directory of the TLS material of the Docker API
+// host address: its registered one, or else HUSKYCI_DOCKERAPI_CERT_PATH.
+func hostCertDir(config *apiContext.DockerHostsConfig, address string) string {
+	if certPath := HostCertPath(address); certPath != "" {
+		return certPath
+	}
+	return config.PathCertificate
+}
+
+// hostTLSConfig returns the TLS configuration of the clients of the Docker
+// API host address, built from the ca.pem, cert.pem and key.pem of its
+// registered directory, or else of HUSKYCI_DOCKERAPI_CERT_PATH. The
+// certificate of the host is verified unless HUSKYCI_DOCKERAPI_TLS_VERIFY is
+// false. It returns nil if the host has no TLS material.
+func hostTLSConfig(config *apiContext.DockerHostsConfig, address string) (*tls.Config, error) {
+	certPath := hostCertDir(config, address)
+	if certPath == "" {
+		return nil, nil
+	}
+	return tlsconfig.Client(tlsconfig.Options{
+		CAFile:             filepath.Join(certPath, "ca.pem"),
+		CertFile:           filepath.Join(certPath, "cert.pem"),
+		KeyFile:            filepath.Join(certPath, "key.pem"),
+		InsecureSkipVerify: config.TLSVerify == 0,
+	})
+}
+
+// hostHTTPClient returns the HTTP client of the Docker API host address,
+// using its TLS configuration, or nil if it has no TLS material and the
+// default client of the Docker client fits.
+func hostHTTPClient(config *apiContext.DockerHostsConfig, address string) (*http.Client, error) {
+	tlsConfig, err := hostTLSConfig(config, address)
+	if err != nil || tlsConfig == nil {
+		return nil, err
+	}
+	return &http.Client{
+		Transport:     &http.Transport{TLSClientConfig: tlsConfig},
+		CheckRedirect: client.CheckRedirect,
+	}, nil
+}
+
+// OSWindows is the OSType of the Docker daemons running Windows containers.
+const OSWindows = "windows"
+
+// daemonOS holds the OSType of each Docker API host, read once.
+var daemonOS = struct {
+	sync.Mutex
+	byHost map[string]string
+}{byHost: map[string]string{}}
+
+// DetectOS reads the operating system of the Docker daemon of d, once per
+// Docker API host, so that its containers are created the way it runs them.
+func (d *Docker) DetectOS() error {
+	if d.osType != "" {
+		return nil
+	}
+	daemonOS.Lock()
+	d.osType = daemonOS.byHost[d.host]
+	daemonOS.Unlock()
+	if d.osType != "" {
+		return nil
+	}
+
+	info, err := d.client.Info(goContext.Background())
+	if err != nil {
+		log.Error("DetectOS", logInfoAPI, 3033, err)
+		return err
+	}
+	d.osType = info.OSType
+	if d.host != "" {
+		daemonOS.Lock()
+		daemonOS.byHost[d.host] = info.OSType
+		daemonOS.Unlock()
+	}
+	return nil
+}
+
+// IsWindows returns true if the Docker daemon of d runs Windows containers,
+// as read by DetectOS.
+func (d Docker) IsWindows() bool {
+	return d.osType == OSWindows
+}
+
+// shellCommand returns the command running cmd with the shell of the
+// containers of d: cmd on Windows and sh otherwise.
+func (d Docker) shellCommand(cmd string) []string {
+	if d.IsWindows() {
+		return []string{"cmd", "/S", "/C", cmd}
+	}
+	return []string{"/bin/sh", "-c", cmd}
+}
+
+// containerPath returns where path, written the unix way as in the
+// configuration of huskyCI, is found in the containers of d. On Windows,
+// /workspace is C:\workspace.
+func (d Docker) containerPath(path string) string {
+	if d.IsWindows() && strings.HasPrefix(path, "/") {
+		return "C:" + strings.ReplaceAll(path, "/", `\`)
+	}
+	return path
+}
+
 // CreateContainer creates a new container and return its CID and an error
 func (d Docker) CreateContainer(image, cmd string) (string, error) {
-	return d.CreateContainerWithVolume(image, cmd, "")
+	return d.CreateContainerWithVolume(image, cmd, "", ContainerOptions{})
 }
 
 // CreateContainerWithVolume creates a new container with an optional volume mount and returns its CID and an error
-func (d Docker) CreateContainerWithVolume(image, cmd, volumePath string) (string, error) {
+func (d Docker) CreateContainerWithVolume(image, cmd, volumePath string, options ContainerOptions) (string, error) {
 	ctx := goContext.Background()
 	config := &container.Config{
-		Image: image,
-		Tty:   true,
-		Cmd:   []string{"/bin/sh", "-c", cmd},
+		Image:  image,
+		Tty:    true,
+		Cmd:    d.shellCommand(cmd),
+		Labels: map[string]string{HuskyCILabel: "true"},
+	}
+	for key, value := range options.Labels {
+		config.Labels[key] = value
 	}
+	if options.NetworkMode != "none" {
+		config.Env = append(config.Env, d.proxyEnv...)
+	}
+	// options.Env comes last so an egress proxy overrides the host proxy
+	config.Env = append(config.Env, options.Env...)
 	
-	var hostConfig *container.HostConfig
+	hostConfig := &container.HostConfig{
+		NetworkMode: container.NetworkMode(options.NetworkMode),
+	}
 	if volumePath != "" {
-		// For docker-in-docker, bind mounts are resolved relative to the Docker daemon's host (dockerapi)
-		// Since dockerapi has /tmp/huskyci-zips-host:/tmp/huskyci-zips mounted, the path should work
+		// volumePath is the name of a volume or a path resolved by the Docker daemon's host (dockerapi)
 		// Mount the volume at /workspace in the container
-		hostConfig = &container.HostConfig{
-			Binds: []string{fmt.Sprintf("%s:/workspace:ro", volumePath)},
+		hostConfig.Binds = []string{fmt.Sprintf("%s:%s:ro", volumePath, d.containerPath(WorkspacePath))}
+	}
+	if options.Workspace != "" {
+		// the code is copied into an anonymous volume, removed with the container
+		config.Volumes = map[string]struct{}{d.containerPath(WorkspacePath): {}}
+	}
+	for _, mount := range options.Mounts {
+		mode := "ro"
+		if mount.Writable {
+			mode = "rw"
+		}
+		hostConfig.Binds = append(hostConfig.Binds, fmt.Sprintf("%s:%s:%s", mount.HostPath, d.containerPath(mount.ContainerPath), mode))
+	}
+	// Windows containers have no capabilities, seccomp nor AppArmor to harden
+	if apiContext.APIConfiguration != nil && !d.IsWindows() {
+		if err := harden(config, hostConfig, apiContext.APIConfiguration.ContainerSecurityConfig, options.ImageUser); err != nil {
+			log.Error("CreateContainer", logInfoAPI, 3005, err)
+			return "", err
 		}
 	}
 	
@@ -160,36 +431,40 @@ func (d Docker) CreateContainerWithVolume(image, cmd, volumePath string) (string
 	return resp.ID, nil
 }
 
-// CreateContainerWithVolumeRW creates a new container with a read-write volume mount
-func (d Docker) CreateContainerWithVolumeRW(image, cmd, volumePath string) (string, error) {
-	ctx := goContext.Background()
-	config := &container.Config{
-		Image: image,
-		Tty:   true,
-		Cmd:   []string{"/bin/sh", "-c", cmd},
-	}
-	
-	var hostConfig *container.HostConfig
-	if volumePath != "" {
-		// For docker-in-docker, bind mounts are resolved relative to the Docker daemon's host (dockerapi)
-		// Since dockerapi has /tmp/huskyci-zips-host:/tmp/huskyci-zips mounted, the path should work
-		// Mount the volume at /workspace in the container with read-write access
-		hostConfig = &container.HostConfig{
-			Binds: []string{fmt.Sprintf("%s:/workspace", volumePath)}, // No :ro, so it's read-write
+// harden drops every capability of a container but the ones kept in
+// security, and applies the rest of its hardening. The container keeps the
+// user of its image if imageUser is true.
+func harden(config *container.Config, hostConfig *container.HostConfig, security *apiContext.ContainerSecurityConfig, imageUser bool) error {
+	if security == nil {
+		return nil
+	}
+	if security.User != "" && !imageUser {
+		config.User = security.User
+	}
+	hostConfig.CapDrop = []string{"ALL"}
+	hostConfig.CapAdd = security.CapAdd
+	if security.NoNewPrivileges {
+		hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, "no-new-privileges")
+	}
+	if security.ReadOnlyRootfs {
+		hostConfig.ReadonlyRootfs = true
+		hostConfig.Tmpfs = map[string]string{}
+		for _, path := range security.WritablePaths {
+			hostConfig.Tmpfs[path] = ""
 		}
 	}
-	
-	resp, err := d.client.ContainerCreate(ctx, config, hostConfig, nil, nil, "")
-	
-	if err != nil {
-		log.Error("CreateContainer", logInfoAPI, 3005, err)
-		// If volume mount fails, log the error with more context
-		if volumePath != "" {
-			log.Error("CreateContainer", logInfoAPI, 3005, fmt.Errorf("failed to create container with volume mount %s: %v", volumePath, err))
+	if security.SeccompProfile != "" {
+		// the Docker API takes the content of the profile, not its path
+		profile, err := os.ReadFile(security.SeccompProfile)
+		if err != nil {
+			return fmt.Errorf("failed to read seccomp profile: %w", err)
 		}
-		return "", err
+		hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, "seccomp="+string(profile))
 	}
-	return resp.ID, nil
+	if security.AppArmorProfile != "" {
+		hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, "apparmor="+security.AppArmorProfile)
+	}
+	return nil
 }
 
 // StartContainer starts a container and returns its error.
@@ -198,13 +473,25 @@ func (d Docker) StartContainer() error {
 	return d.client.ContainerStart(ctx, d.CID, dockerTypes.ContainerStartOptions{})
 }
 
-// WaitContainer returns when container finishes executing cmd.
+// ErrTimeout is returned when a container runs for longer than its timeout.
+var ErrTimeout = errors.New("container timed out")
+
+// WaitContainer returns when container finishes executing cmd, or ErrTimeout
+// once it ran for timeOutInSeconds, if it is positive.
 func (d Docker) WaitContainer(timeOutInSeconds int) error {
 	ctx := goContext.Background()
+	if timeOutInSeconds > 0 {
+		var cancel goContext.CancelFunc
+		ctx, cancel = goContext.WithTimeout(ctx, time.Duration(timeOutInSeconds)*time.Second)
+		defer cancel()
+	}
 	containerWaitC, errC := d.client.ContainerWait(ctx, d.CID, container.WaitConditionNotRunning)
 
 	select {
 	case err := <-errC:
+		if ctx.Err() == goContext.DeadlineExceeded {
+			return fmt.Errorf("%w after %d seconds", ErrTimeout, timeOutInSeconds)
+		}
 		if err != nil {
 			return err
 		}
@@ -230,7 +517,7 @@ func (d Docker) StopContainer() error {
 // RemoveContainer removes a container by it's CID
 func (d Docker) RemoveContainer() error {
 	ctx := goContext.Background()
-	err := d.client.ContainerRemove(ctx, d.CID, dockerTypes.ContainerRemoveOptions{})
+	err := d.client.ContainerRemove(ctx, d.CID, dockerTypes.ContainerRemoveOptions{RemoveVolumes: true})
 	if err != nil {
 		log.Error("RemoveContainer", logInfoAPI, 3023, err)
 	}
@@ -266,6 +553,111 @@ func (d Docker) ListStoppedContainers() ([]Docker, error) {
 	return dockerList, nil
 }
 
+// RemoveExitedContainers removes every exited container created by huskyCI
+// before olderThan. It returns how many containers were removed and how many
+// bytes of their writable layers were reclaimed.
+func (d Docker) RemoveExitedContainers(olderThan time.Time) (int, int64, error) {
+	ctx := goContext.Background()
+	dockerFilters := filters.NewArgs()
+	dockerFilters.Add("status", "exited")
+	dockerFilters.Add("label", HuskyCILabel)
+	options := dockerTypes.ContainerListOptions{
+		All:     true,
+		Size:    true,
+		Filters: dockerFilters,
+	}
+
+	containerList, err := d.client.ContainerList(ctx, options)
+	if err != nil {
+		log.Error("RemoveExitedContainers", logInfoAPI, 3021, err)
+		return 0, 0, err
+	}
+
+	removed := 0
+	var reclaimed int64
+	for _, c := range containerList {
+		if time.Unix(c.Created, 0).After(olderThan) {
+			continue
+		}
+		if err := d.client.ContainerRemove(ctx, c.ID, dockerTypes.ContainerRemoveOptions{RemoveVolumes: true}); err != nil {
+			log.Error("RemoveExitedContainers", logInfoAPI, 3023, err)
+			continue
+		}
+		removed++
+		reclaimed += c.SizeRw
+	}
+	return removed, reclaimed, nil
+}
+
+// CountRunningContainers returns how many containers created by huskyCI to
+// run a securityTest are running, leaving out the idle ones of the warm pool.
+func (d Docker) CountRunningContainers() (int, error) {
+	ctx := goContext.Background()
+	dockerFilters := filters.NewArgs()
+	dockerFilters.Add("status", "running")
+	dockerFilters.Add("label", HuskyCILabel)
+	containerList, err := d.client.ContainerList(ctx, dockerTypes.ContainerListOptions{Filters: dockerFilters})
+	if err != nil {
+		log.Error("CountRunningContainers", logInfoAPI, 3021, err)
+		return 0, err
+	}
+	running := 0
+	for _, c := range containerList {
+		if _, warm := c.Labels[WarmPoolLabel]; !warm {
+			running++
+		}
+	}
+	return running, nil
+}
+
+// CreateVolume creates the volume name holding the code of the analysis RID.
+func (d Docker) CreateVolume(name, RID string) error {
+	ctx := goContext.Background()
+	_, err := d.client.VolumeCreate(ctx, volume.CreateOptions{
+		Name:   name,
+		Labels: map[string]string{HuskyCILabel: "true", AnalysisLabel: RID},
+	})
+	if err != nil {
+		log.Error("CreateVolume", logInfoAPI, 3030, name, err)
+	}
+	return err
+}
+
+// RemoveVolume removes the volume name.
+func (d Docker) RemoveVolume(name string) error {
+	ctx := goContext.Background()
+	err := d.client.VolumeRemove(ctx, name, true)
+	if err != nil {
+		log.Error("RemoveVolume", logInfoAPI, 3031, name, err)
+	}
+	return err
+}
+
+// RemoveStaleVolumes removes every analysis volume created by huskyCI before
+// olderThan and no longer used by a container. It returns how many volumes
+// were removed.
+func (d Docker) RemoveStaleVolumes(olderThan time.Time) (int, error) {
+	ctx := goContext.Background()
+	volumeList, err := d.client.VolumeList(ctx, volume.ListOptions{Filters: filters.NewArgs(filters.Arg("label", AnalysisLabel))})
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, v := range volumeList.Volumes {
+		createdAt, err := time.Parse(time.RFC3339, v.CreatedAt)
+		if err != nil || createdAt.After(olderThan) {
+			continue
+		}
+		if err := d.client.VolumeRemove(ctx, v.Name, false); err != nil {
+			log.Error("RemoveStaleVolumes", logInfoAPI, 3031, v.Name, err)
+			continue
+		}
+		removed++
+	}
+	return removed, nil
+}
+
 // DieContainers stops and removes all containers
 func (d Docker) DieContainers() error {
 	containerList, err := d.ListStoppedContainers()
@@ -289,19 +681,30 @@ func (d Docker) DieContainers() error {
 
 // ReadOutput returns STDOUT of a given containerID.
 func (d Docker) ReadOutput() (string, error) {
+	return d.ReadOutputLimited(0)
+}
+
+// ReadOutputLimited returns STDOUT of a given containerID, spooled on disk
+// while it is read and cut at limit bytes if limit is positive.
+func (d Docker) ReadOutputLimited(limit int64) (string, error) {
 	ctx := goContext.Background()
 	out, err := d.client.ContainerLogs(ctx, d.CID, dockerTypes.ContainerLogsOptions{ShowStdout: true})
 	if err != nil {
 		log.Error("ReadOutput", logInfoAPI, 3006, err)
 		return "", nil
 	}
+	defer out.Close()
 
-	body, err := ioutil.ReadAll(out)
+	output, err := spool.Capture(out, limit)
 	if err != nil {
 		log.Error("ReadOutput", logInfoAPI, 3007, err)
 		return "", err
 	}
-	return string(body), err
+	defer output.Close()
+	if output.Truncated {
+		log.Warning("ReadOutput", logInfoAPI, 127, d.CID, limit)
+	}
+	return output.String()
 }
 
 // ReadOutputStderr returns STDERR of a given containerID.
@@ -321,11 +724,31 @@ func (d Docker) ReadOutputStderr() (string, error) {
 	return string(body), err
 }
 
-// PullImage pulls an image, like docker pull.
+// pullOptions returns the options image is pulled with: the credential of
+// its registry, if it has one.
+func pullOptions(image string) (dockerTypes.ImagePullOptions, error) {
+	credential, found, err := registryauth.ForImage(image)
+	if err != nil || !found {
+		return dockerTypes.ImagePullOptions{}, err
+	}
+	auth, err := registryauth.EncodeAuth(credential)
+	if err != nil {
+		return dockerTypes.ImagePullOptions{}, err
+	}
+	return dockerTypes.ImagePullOptions{RegistryAuth: auth}, nil
+}
+
+// PullImage pulls an image, like docker pull, with the credential of its
+// registry if it has one.
 // It reads the pull stream to capture detailed error messages, including platform mismatch errors.
 func (d Docker) PullImage(image string) error {
 	ctx := goContext.Background()
-	reader, err := d.client.ImagePull(ctx, image, dockerTypes.ImagePullOptions{})
+	options, err := pullOptions(image)
+	if err != nil {
+		log.Error("PullImage", logInfoAPI, 3009, fmt.Sprintf("Failed to read the registry credential for %s: %v", image, err))
+		return err
+	}
+	reader, err := d.client.ImagePull(ctx, image, options)
 	if err != nil {
 		log.Error("PullImage", logInfoAPI, 3009, fmt.Sprintf("Failed to start image pull for %s: %v", image, err))
 		return err
@@ -411,9 +834,120 @@ func (d Docker) RemoveImage(imageID string) ([]dockerTypes.ImageDeleteResponseIt
 	return d.client.ImageRemove(ctx, imageID, dockerTypes.ImageRemoveOptions{Force: true})
 }
 
+// UnreferencedImages returns the images of d tagged or pulled only in
+// repositories and with none of the references of keep, such as the
+// versions of a scanner image no securityTest runs anymore. repositories and
+// keep hold familiar references, as returned by ImageRepository and
+// FamiliarReference.
+func (d Docker) UnreferencedImages(repositories, keep map[string]bool) ([]image.Summary, error) {
+	ctx := goContext.Background()
+	images, err := d.client.ImageList(ctx, dockerTypes.ImageListOptions{})
+	if err != nil {
+		log.Error("UnreferencedImages", logInfoAPI, 3036, err)
+		return nil, err
+	}
+	unreferenced := []image.Summary{}
+	for _, img := range images {
+		references := imageReferences(img)
+		if len(references) == 0 {
+			continue
+		}
+		candidate := true
+		for _, reference := range references {
+			if keep[FamiliarReference(reference)] || !repositories[ImageRepository(reference)] {
+				candidate = false
+				break
+			}
+		}
+		if candidate {
+			unreferenced = append(unreferenced, img)
+		}
+	}
+	return unreferenced, nil
+}
+
+// imageReferences returns the tags and digests img is known by.
+func imageReferences(img image.Summary) []string {
+	references := []string{}
+	for _, reference := range append(append([]string{}, img.RepoTags...), img.RepoDigests...) {
+		if reference != "" && reference != "<none>:<none>" && reference != "<none>@<none>" {
+			references = append(references, reference)
+		}
+	}
+	return references
+}
+
+// FamiliarReference returns the image reference the way the Docker API lists
+// it, without the docker.io registry and library namespace.
+func FamiliarReference(reference string) string {
+	reference = strings.TrimPrefix(reference, "docker.io/")
+	return strings.TrimPrefix(reference, "library/")
+}
+
+// ImageRepository returns the repository of the image reference, without
+// its tag and digest, the way the Docker API lists it.
+func ImageRepository(reference string) string {
+	if i := strings.Index(reference, "@"); i >= 0 {
+		reference = reference[:i]
+	}
+	if i := strings.LastIndex(reference, ":"); i > strings.LastIndex(reference, "/") {
+		reference = reference[:i]
+	}
+	return FamiliarReference(reference)
+}
+
+// RemoveUnusedImage removes img from d, untagging each of its tags and then
+// the image itself, and returns whether it was removed. An image a
+// container still uses is left alone, without error.
+func (d Docker) RemoveUnusedImage(img image.Summary) (bool, error) {
+	ctx := goContext.Background()
+	options := dockerTypes.ImageRemoveOptions{PruneChildren: true}
+	removals := []string{}
+	for _, tag := range img.RepoTags {
+		if tag != "" && tag != "<none>:<none>" {
+			removals = append(removals, tag)
+		}
+	}
+	if len(removals) == 0 {
+		removals = append(removals, img.ID)
+	}
+	for _, reference := range removals {
+		deleted, err := d.client.ImageRemove(ctx, reference, options)
+		if errdefs.IsConflict(err) {
+			return false, nil
+		}
+		if err != nil && !errdefs.IsNotFound(err) {
+			log.Error("RemoveUnusedImage", logInfoAPI, 3037, reference, err)
+			return false, err
+		}
+		for _, response := range deleted {
+			if response.Deleted != "" {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// PruneDanglingImages removes the untagged images of d created before
+// createdBefore that no other image or container uses, and returns how many
+// were removed and how many bytes were reclaimed.
+func (d Docker) PruneDanglingImages(createdBefore time.Time) (int, int64, error) {
+	ctx := goContext.Background()
+	dockerFilters := filters.NewArgs()
+	dockerFilters.Add("dangling", "true")
+	dockerFilters.Add("until", strconv.FormatInt(createdBefore.Unix(), 10))
+	report, err := d.client.ImagesPrune(ctx, dockerFilters)
+	if err != nil {
+		log.Error("PruneDanglingImages", logInfoAPI, 3037, err)
+		return 0, 0, err
+	}
+	return len(report.ImagesDeleted), int64(report.SpaceReclaimed), nil
+}
+
 // HealthCheckDockerAPI returns true if a 200 status code is received from dockerAddress or false otherwise.
 func HealthCheckDockerAPI(dockerHost string) error {
-	d, err := NewDocker(dockerHost)
+	d, err := Open(dockerHost)
 	if err != nil {
 		log.Error("HealthCheckDockerAPI", logInfoAPI, 3011, err)
 		return err
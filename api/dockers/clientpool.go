@@ -0,0 +1,132 @@
+package dockers
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/log"
+	goContext "golang.org/x/net/context"
+)
+
+const logActionClientPool = "DockerClientPool"
+
+// healthCheckTimeout is how long a pooled client is given to answer the ping
+// of its health check.
+const healthCheckTimeout = 10 * time.Second
+
+// clientPool holds the clients shared by the operations on each Docker API
+// host, so that they reuse the connections and TLS sessions of the client
+// instead of opening new ones.
+var clientPool = struct {
+	sync.Mutex
+	clients map[string]*pooledClient
+}{clients: map[string]*pooledClient{}}
+
+// pooledClient is a client of the pool and when it was last handed out.
+type pooledClient struct {
+	client   Client
+	lastUsed time.Time
+}
+
+// clientPoolConfig returns the configuration of the client pool, or nil if
+// the reuse of the clients is disabled.
+func clientPoolConfig() *apiContext.DockerClientPoolConfig {
+	if apiContext.APIConfiguration == nil {
+		return nil
+	}
+	config := apiContext.APIConfiguration.DockerClientPoolConfig
+	if config == nil || config.IdleTimeout <= 0 {
+		return nil
+	}
+	return config
+}
+
+// SharedClient returns the client pooled under key, a Docker API host and
+// its TLS material, creating it with create and pooling it if there is none.
+// Without a pool, it returns a new client from create every time.
+func SharedClient(key string, create func() (Client, error)) (Client, error) {
+	if clientPoolConfig() == nil {
+		return create()
+	}
+	clientPool.Lock()
+	defer clientPool.Unlock()
+	if p, ok := clientPool.clients[key]; ok {
+		p.lastUsed = time.Now()
+		return p.client, nil
+	}
+	c, err := create()
+	if err != nil {
+		return nil, err
+	}
+	clientPool.clients[key] = &pooledClient{client: c, lastUsed: time.Now()}
+	return c, nil
+}
+
+// ReapClients closes the pooled clients last handed out before idleBefore
+// and, if ping is true, the ones that do not answer a ping, and returns how
+// many idle clients were closed. Closing a client only closes its idle
+// connections: the operations still running with it are not interrupted.
+func ReapClients(idleBefore time.Time, ping bool) int {
+	idle := map[string]Client{}
+	alive := map[string]Client{}
+	clientPool.Lock()
+	for key, p := range clientPool.clients {
+		if p.lastUsed.Before(idleBefore) {
+			idle[key] = p.client
+			delete(clientPool.clients, key)
+		} else {
+			alive[key] = p.client
+		}
+	}
+	clientPool.Unlock()
+
+	for _, c := range idle {
+		closeClient(c)
+	}
+	if ping {
+		for key, c := range alive {
+			ctx, cancel := goContext.WithTimeout(goContext.Background(), healthCheckTimeout)
+			_, err := c.Ping(ctx)
+			cancel()
+			if err != nil {
+				log.Error(logActionClientPool, logInfoAPI, 3035, key, err)
+				evictClient(key, c)
+			}
+		}
+	}
+	return len(idle)
+}
+
+// evictClient closes c and takes it out of the pool, unless it was already
+// replaced there by another client of key.
+func evictClient(key string, c Client) {
+	clientPool.Lock()
+	if p, ok := clientPool.clients[key]; ok && p.client == c {
+		delete(clientPool.clients, key)
+	}
+	clientPool.Unlock()
+	closeClient(c)
+}
+
+// closeClient closes the idle connections of c, if it holds any.
+func closeClient(c Client) {
+	if closer, ok := c.(io.Closer); ok {
+		closer.Close()
+	}
+}
+
+// ScheduleClientReaper closes the Docker API clients idle for longer than
+// the IdleTimeout of config and the ones failing their health check, every
+// HealthInterval of config. It never returns and is meant to run in its own
+// goroutine.
+func ScheduleClientReaper(config *apiContext.DockerClientPoolConfig) {
+	ticker := time.NewTicker(config.HealthInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if reaped := ReapClients(time.Now().Add(-config.IdleTimeout), true); reaped > 0 {
+			log.Info(logActionClientPool, logInfoAPI, 81, reaped)
+		}
+	}
+}
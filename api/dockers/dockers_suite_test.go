@@ -0,0 +1,13 @@
+package dockers_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestDockers(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Dockers Suite")
+}
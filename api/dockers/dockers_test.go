@@ -0,0 +1,878 @@
+package dockers_test
+
+import (
+	"archive/tar"
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	dockerTypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/api/types/system"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/errdefs"
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/dockers"
+	"github.com/huskyci-org/huskyCI/api/registryauth"
+	"github.com/huskyci-org/huskyCI/api/spool"
+	"github.com/huskyci-org/huskyCI/api/types"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	goContext "golang.org/x/net/context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fakeClient is a Docker API holding images and volumes in memory. Its
+// containers print logs and exit with exitCode as soon as they are waited on.
+type fakeClient struct {
+	dockers.Client
+	sync.Mutex
+	images map[string]bool
+	// pullStreams are the streams returned by each pull in turn, the last one
+	// being repeated. A pull with no error in its stream loads the image.
+	pullStreams []string
+	pullErr     error
+	pulls       []string
+	pullAuths   []string
+	createErr   error
+	configs     []*container.Config
+	hostConfigs []*container.HostConfig
+	started     []string
+	exitCode    int64
+	hangs       bool
+	stopped     []string
+	logs        string
+	copied      map[string]string
+	removed     []string
+	volumes     []*volume.Volume
+	removedVols []string
+	osType      string
+	listed      []dockerTypes.Container
+	pingErr     error
+	closed      bool
+	// summaries are the images listed without filter, inUse the references
+	// of the ones a container uses and untagged the references removed
+	summaries []image.Summary
+	inUse     map[string]bool
+	untagged  []string
+	pruned    []filters.Args
+	// execs are the commands run in the containers, and gone the containers
+	// that can no longer run any
+	execs    []dockerTypes.ExecConfig
+	execCIDs []string
+	gone     map[string]bool
+	// diffs are the changes of the filesystems of the containers
+	diffs map[string][]container.FilesystemChange
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{images: map[string]bool{}, copied: map[string]string{}, gone: map[string]bool{}, diffs: map[string][]container.FilesystemChange{}, osType: "linux"}
+}
+
+func (f *fakeClient) Info(ctx goContext.Context) (system.Info, error) {
+	return system.Info{OSType: f.osType}, nil
+}
+
+func (f *fakeClient) Ping(ctx goContext.Context) (dockerTypes.Ping, error) {
+	return dockerTypes.Ping{}, f.pingErr
+}
+
+func (f *fakeClient) Close() error {
+	f.closed = true
+	return nil
+}
+
+func (f *fakeClient) ImageList(ctx goContext.Context, options dockerTypes.ImageListOptions) ([]image.Summary, error) {
+	f.Lock()
+	defer f.Unlock()
+	if options.Filters.Len() == 0 {
+		return f.summaries, nil
+	}
+	summaries := []image.Summary{}
+	for _, reference := range options.Filters.Get("reference") {
+		if f.images[reference] {
+			summaries = append(summaries, image.Summary{RepoTags: []string{reference}})
+		}
+	}
+	return summaries, nil
+}
+
+func (f *fakeClient) ImageRemove(ctx goContext.Context, imageID string, options dockerTypes.ImageRemoveOptions) ([]image.DeleteResponse, error) {
+	f.Lock()
+	defer f.Unlock()
+	if f.inUse[imageID] {
+		return nil, errdefs.Conflict(errors.New("image is being used by a container"))
+	}
+	f.untagged = append(f.untagged, imageID)
+	for i, img := range f.summaries {
+		tags := []string{}
+		for _, tag := range img.RepoTags {
+			if tag != imageID {
+				tags = append(tags, tag)
+			}
+		}
+		if img.ID != imageID && len(tags) == len(img.RepoTags) {
+			continue
+		}
+		if img.ID == imageID || len(tags) == 0 {
+			f.summaries = append(f.summaries[:i:i], f.summaries[i+1:]...)
+			return []image.DeleteResponse{{Untagged: imageID}, {Deleted: img.ID}}, nil
+		}
+		f.summaries[i].RepoTags = tags
+		return []image.DeleteResponse{{Untagged: imageID}}, nil
+	}
+	return nil, errdefs.NotFound(errors.New("no such image"))
+}
+
+func (f *fakeClient) ImagesPrune(ctx goContext.Context, pruneFilters filters.Args) (dockerTypes.ImagesPruneReport, error) {
+	f.Lock()
+	defer f.Unlock()
+	f.pruned = append(f.pruned, pruneFilters)
+	return dockerTypes.ImagesPruneReport{ImagesDeleted: []image.DeleteResponse{{Deleted: "sha256:dangling"}}, SpaceReclaimed: 100}, nil
+}
+
+func (f *fakeClient) ImagePull(ctx goContext.Context, refStr string, options dockerTypes.ImagePullOptions) (io.ReadCloser, error) {
+	f.Lock()
+	defer f.Unlock()
+	f.pulls = append(f.pulls, refStr)
+	f.pullAuths = append(f.pullAuths, options.RegistryAuth)
+	if f.pullErr != nil {
+		return nil, f.pullErr
+	}
+	stream := `{"status":"Downloaded newer image"}`
+	if len(f.pullStreams) > 0 {
+		stream = f.pullStreams[0]
+		if len(f.pullStreams) > 1 {
+			f.pullStreams = f.pullStreams[1:]
+		}
+	}
+	if !strings.Contains(stream, "error") {
+		f.images[strings.TrimPrefix(refStr, "docker.io/")] = true
+	}
+	return io.NopCloser(strings.NewReader(stream)), nil
+}
+
+func (f *fakeClient) ContainerCreate(ctx goContext.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error) {
+	f.Lock()
+	defer f.Unlock()
+	if f.createErr != nil {
+		return container.CreateResponse{}, f.createErr
+	}
+	f.configs = append(f.configs, config)
+	f.hostConfigs = append(f.hostConfigs, hostConfig)
+	return container.CreateResponse{ID: fmt.Sprintf("cid-%d", len(f.configs))}, nil
+}
+
+func (f *fakeClient) ContainerStart(ctx goContext.Context, containerID string, options container.StartOptions) error {
+	f.Lock()
+	defer f.Unlock()
+	f.started = append(f.started, containerID)
+	return nil
+}
+
+func (f *fakeClient) ContainerWait(ctx goContext.Context, containerID string, condition container.WaitCondition) (<-chan container.WaitResponse, <-chan error) {
+	f.Lock()
+	defer f.Unlock()
+	waitC := make(chan container.WaitResponse, 1)
+	errC := make(chan error, 1)
+	if f.hangs {
+		go func() {
+			<-ctx.Done()
+			errC <- ctx.Err()
+		}()
+		return waitC, errC
+	}
+	waitC <- container.WaitResponse{StatusCode: f.exitCode}
+	return waitC, errC
+}
+
+func (f *fakeClient) ContainerStop(ctx goContext.Context, containerID string, options container.StopOptions) error {
+	f.Lock()
+	defer f.Unlock()
+	f.stopped = append(f.stopped, containerID)
+	return nil
+}
+
+func (f *fakeClient) ContainerLogs(ctx goContext.Context, containerID string, options container.LogsOptions) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader(f.logs)), nil
+}
+
+func (f *fakeClient) CopyToContainer(ctx goContext.Context, containerID, dstPath string, content io.Reader, options dockerTypes.CopyToContainerOptions) error {
+	f.Lock()
+	defer f.Unlock()
+	tr := tar.NewReader(content)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		body, err := io.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		f.copied[dstPath+header.Name] = string(body)
+	}
+}
+
+func (f *fakeClient) ContainerRemove(ctx goContext.Context, containerID string, options container.RemoveOptions) error {
+	f.Lock()
+	defer f.Unlock()
+	f.removed = append(f.removed, containerID)
+	return nil
+}
+
+func (f *fakeClient) ContainerExecCreate(ctx goContext.Context, containerID string, config dockerTypes.ExecConfig) (dockerTypes.IDResponse, error) {
+	f.Lock()
+	defer f.Unlock()
+	if f.gone[containerID] {
+		return dockerTypes.IDResponse{}, fmt.Errorf("No such container: %s", containerID)
+	}
+	f.execs = append(f.execs, config)
+	f.execCIDs = append(f.execCIDs, containerID)
+	return dockerTypes.IDResponse{ID: fmt.Sprintf("%d", len(f.execs)-1)}, nil
+}
+
+// isCleanup returns true if the exec execID empties the /tmp of a container
+// once its job finished.
+func (f *fakeClient) isCleanup(execID string) bool {
+	f.Lock()
+	defer f.Unlock()
+	var index int
+	fmt.Sscanf(execID, "%d", &index)
+	return strings.Contains(strings.Join(f.execs[index].Cmd, " "), "find /tmp")
+}
+
+func (f *fakeClient) ContainerExecAttach(ctx goContext.Context, execID string, config dockerTypes.ExecStartCheck) (dockerTypes.HijackedResponse, error) {
+	conn, server := net.Pipe()
+	response := dockerTypes.HijackedResponse{Conn: conn, Reader: bufio.NewReader(strings.NewReader(""))}
+	if f.isCleanup(execID) {
+		server.Close()
+		return response, nil
+	}
+	if f.hangs {
+		// nothing is written until the connection is closed
+		response.Reader = bufio.NewReader(conn)
+		return response, nil
+	}
+	server.Close()
+	response.Reader = bufio.NewReader(strings.NewReader(f.logs))
+	return response, nil
+}
+
+func (f *fakeClient) ContainerExecInspect(ctx goContext.Context, execID string) (dockerTypes.ContainerExecInspect, error) {
+	if f.isCleanup(execID) {
+		return dockerTypes.ContainerExecInspect{ExecID: execID}, nil
+	}
+	return dockerTypes.ContainerExecInspect{ExecID: execID, ExitCode: int(f.exitCode)}, nil
+}
+
+func (f *fakeClient) ContainerDiff(ctx goContext.Context, containerID string) ([]container.FilesystemChange, error) {
+	f.Lock()
+	defer f.Unlock()
+	return f.diffs[containerID], nil
+}
+
+func (f *fakeClient) ContainerList(ctx goContext.Context, options container.ListOptions) ([]dockerTypes.Container, error) {
+	return f.listed, nil
+}
+
+func (f *fakeClient) VolumeList(ctx goContext.Context, options volume.ListOptions) (volume.ListResponse, error) {
+	f.Lock()
+	defer f.Unlock()
+	return volume.ListResponse{Volumes: f.volumes}, nil
+}
+
+func (f *fakeClient) VolumeRemove(ctx goContext.Context, volumeID string, force bool) error {
+	f.Lock()
+	defer f.Unlock()
+	f.removedVols = append(f.removedVols, volumeID)
+	return nil
+}
+
+var _ = Describe("Dockers", func() {
+
+	var fake *fakeClient
+	var previousOpen func(string) (*dockers.Docker, error)
+	var previousConfig *apiContext.APIConfig
+	var previousInterval, previousTimeout time.Duration
+
+	BeforeEach(func() {
+		fake = newFakeClient()
+		previousOpen = dockers.Open
+		dockers.Open = func(dockerHost string) (*dockers.Docker, error) {
+			return dockers.NewDockerWithClient(fake), nil
+		}
+		previousConfig = apiContext.APIConfiguration
+		apiContext.APIConfiguration = &apiContext.APIConfig{}
+		previousInterval = dockers.PullRetryInterval
+		previousTimeout = dockers.PullTimeout
+		dockers.PullRetryInterval = time.Millisecond
+		dockers.PullTimeout = time.Minute
+	})
+
+	AfterEach(func() {
+		dockers.Open = previousOpen
+		apiContext.APIConfiguration = previousConfig
+		dockers.PullRetryInterval = previousInterval
+		dockers.PullTimeout = previousTimeout
+	})
+
+	Describe("DockerRunWithVolume", func() {
+
+		Context("When the image is already loaded", func() {
+			It("Should run the container without pulling and remove it", func() {
+				fake.images["huskyci/gosec:latest"] = true
+				fake.logs = "gosec output"
+
+				CID, output, err := dockers.DockerRunWithVolume("huskyci/gosec", "latest", "gosec ./...", "dockerapi", "huskyci-rid", 60, dockers.ContainerOptions{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(CID).To(Equal("cid-1"))
+				Expect(output).To(Equal("gosec output"))
+				Expect(fake.pulls).To(BeEmpty())
+				Expect(fake.configs[0].Image).To(Equal("huskyci/gosec:latest"))
+				Expect(fake.configs[0].Cmd).To(BeEquivalentTo([]string{"/bin/sh", "-c", "gosec ./..."}))
+				Expect(fake.configs[0].Labels).To(HaveKeyWithValue(dockers.HuskyCILabel, "true"))
+				Expect(fake.hostConfigs[0].Binds).To(Equal([]string{"huskyci-rid:/workspace:ro"}))
+				Expect(fake.started).To(Equal([]string{"cid-1"}))
+				Expect(fake.removed).To(Equal([]string{"cid-1"}))
+			})
+		})
+
+		Context("When the image is not loaded", func() {
+			It("Should pull it from its canonical reference first", func() {
+				_, _, err := dockers.DockerRunWithVolume("huskyci/gosec", "latest", "gosec ./...", "dockerapi", "", 60, dockers.ContainerOptions{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fake.pulls).To(Equal([]string{"docker.io/huskyci/gosec:latest"}))
+				Expect(fake.started).To(HaveLen(1))
+			})
+		})
+
+		Context("When the container can not be created", func() {
+			It("Should return the error without starting anything", func() {
+				fake.images["huskyci/gosec:latest"] = true
+				fake.createErr = errors.New("no space left on device")
+
+				_, _, err := dockers.DockerRunWithVolume("huskyci/gosec", "latest", "gosec ./...", "dockerapi", "", 60, dockers.ContainerOptions{})
+				Expect(err).To(MatchError("no space left on device"))
+				Expect(fake.started).To(BeEmpty())
+			})
+		})
+
+		Context("When the container exits with an error", func() {
+			It("Should return an error with its status code", func() {
+				fake.images["huskyci/gosec:latest"] = true
+				fake.exitCode = 2
+
+				_, _, err := dockers.DockerRunWithVolume("huskyci/gosec", "latest", "gosec ./...", "dockerapi", "", 60, dockers.ContainerOptions{})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("statusCode 2"))
+			})
+		})
+
+		Context("When the container runs for longer than its timeout", func() {
+			It("Should stop it and return ErrTimeout with what it printed", func() {
+				fake.images["huskyci/gosec:latest"] = true
+				fake.hangs = true
+				fake.logs = "partial output"
+
+				CID, output, err := dockers.DockerRunWithVolume("huskyci/gosec", "latest", "gosec ./...", "dockerapi", "", 1, dockers.ContainerOptions{})
+				Expect(errors.Is(err, dockers.ErrTimeout)).To(BeTrue())
+				Expect(CID).To(Equal("cid-1"))
+				Expect(output).To(Equal("partial output"))
+				Expect(fake.stopped).To(Equal([]string{"cid-1"}))
+				Expect(fake.removed).To(Equal([]string{"cid-1"}))
+			})
+		})
+
+		Context("When the output is larger than MaxOutputSize", func() {
+			It("Should cut it and mark it as truncated", func() {
+				fake.images["huskyci/gosec:latest"] = true
+				fake.logs = strings.Repeat("a", 100)
+
+				_, output, err := dockers.DockerRunWithVolume("huskyci/gosec", "latest", "gosec ./...", "dockerapi", "", 60, dockers.ContainerOptions{MaxOutputSize: 10})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(output).To(Equal(strings.Repeat("a", 10) + fmt.Sprintf(spool.TruncationMarker, 10)))
+			})
+		})
+
+		Context("When a workspace is set", func() {
+			It("Should copy it into a volume of the container before starting it", func() {
+				fake.images["huskyci/gosec:latest"] = true
+				workspace, err := os.MkdirTemp("", "huskyci-workspace")
+				Expect(err).NotTo(HaveOccurred())
+				defer os.RemoveAll(workspace)
+				Expect(os.MkdirAll(filepath.Join(workspace, "cmd"), 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workspace, "cmd", "main.go"), []byte("package main"), 0644)).To(Succeed())
+
+				_, _, err = dockers.DockerRunWithVolume("huskyci/gosec", "latest", "gosec ./...", "dockerapi", "", 60, dockers.ContainerOptions{Workspace: workspace})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fake.configs[0].Volumes).To(HaveKey(dockers.WorkspacePath))
+				Expect(fake.hostConfigs[0].Binds).To(BeEmpty())
+				Expect(fake.copied).To(HaveKeyWithValue("/workspace/cmd/main.go", "package main"))
+				Expect(fake.copied).To(HaveKey("/workspace/cmd/"))
+			})
+		})
+
+		Context("When containers are hardened", func() {
+			It("Should drop every capability but the ones kept", func() {
+				fake.images["huskyci/gosec:latest"] = true
+				apiContext.APIConfiguration.ContainerSecurityConfig = &apiContext.ContainerSecurityConfig{
+					User:            "1000:1000",
+					NoNewPrivileges: true,
+					CapAdd:          []string{"CHOWN"},
+				}
+
+				_, _, err := dockers.DockerRunWithVolume("huskyci/gosec", "latest", "gosec ./...", "dockerapi", "", 60, dockers.ContainerOptions{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fake.configs[0].User).To(Equal("1000:1000"))
+				Expect(fake.hostConfigs[0].CapDrop).To(BeEquivalentTo([]string{"ALL"}))
+				Expect(fake.hostConfigs[0].CapAdd).To(BeEquivalentTo([]string{"CHOWN"}))
+				Expect(fake.hostConfigs[0].SecurityOpt).To(ContainElement("no-new-privileges"))
+			})
+		})
+	})
+
+	Describe("DockerRunWarm", func() {
+
+		BeforeEach(func() {
+			fake.images["huskyci/gosec:latest"] = true
+			fake.logs = "gosec output"
+			apiContext.APIConfiguration.WarmPoolConfig = &apiContext.WarmPoolConfig{Size: 1, IdleTimeout: time.Minute, MaxLifetime: time.Hour}
+		})
+
+		AfterEach(func() {
+			// empty the pool for the next test
+			dockers.ReapWarmContainers(time.Now().Add(time.Hour), time.Now())
+		})
+
+		Context("When two jobs run one after the other", func() {
+			It("Should run both in the same container through exec", func() {
+				for i := 0; i < 2; i++ {
+					CID, output, err := dockers.DockerRunWarm("huskyci/gosec", "latest", "gosec ./...", "dockerapi", 60, dockers.ContainerOptions{})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(CID).To(Equal("cid-1"))
+					Expect(output).To(Equal("gosec output"))
+				}
+				Expect(fake.configs).To(HaveLen(1))
+				Expect(fake.configs[0].Cmd).To(BeEquivalentTo([]string{"/bin/sh", "-c", "sleep 3600"}))
+				Expect(fake.configs[0].Labels).To(HaveKeyWithValue(dockers.WarmPoolLabel, "true"))
+				Expect(fake.started).To(Equal([]string{"cid-1"}))
+				Expect(fake.removed).To(BeEmpty())
+				Expect(fake.execs).To(HaveLen(4))
+				Expect(fake.execs[0].Cmd[2]).To(HavePrefix("mkdir -p " + dockers.JobPath + " && cd " + dockers.JobPath))
+				Expect(fake.execs[0].Cmd[2]).To(HaveSuffix("gosec ./..."))
+				Expect(fake.execs[0].Env).To(Equal([]string{"HOME=" + dockers.JobPath}))
+				Expect(fake.execs[1].Cmd[2]).To(HavePrefix("kill -9 -1"))
+				Expect(fake.execs[1].Cmd[2]).To(ContainSubstring("find /tmp"))
+			})
+		})
+
+		Context("When a job changes the filesystem of its container outside /tmp", func() {
+			It("Should remove the container and run the next job in a new one", func() {
+				fake.diffs["cid-1"] = []container.FilesystemChange{{Kind: container.ChangeAdd, Path: "/data"}}
+				_, _, err := dockers.DockerRunWarm("huskyci/gosec", "latest", "gosec ./...", "dockerapi", 60, dockers.ContainerOptions{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fake.removed).To(BeEmpty())
+
+				fake.diffs["cid-1"] = append(fake.diffs["cid-1"],
+					container.FilesystemChange{Kind: container.ChangeModify, Path: "/tmp"},
+					container.FilesystemChange{Kind: container.ChangeModify, Path: "/usr/local/bin/gosec"})
+				_, _, err = dockers.DockerRunWarm("huskyci/gosec", "latest", "gosec ./...", "dockerapi", 60, dockers.ContainerOptions{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fake.removed).To(Equal([]string{"cid-1"}))
+
+				CID, _, err := dockers.DockerRunWarm("huskyci/gosec", "latest", "gosec ./...", "dockerapi", 60, dockers.ContainerOptions{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(CID).To(Equal("cid-2"))
+			})
+		})
+
+		Context("When the job exits with an error", func() {
+			It("Should return an error with its status code and keep the container", func() {
+				fake.exitCode = 2
+
+				_, _, err := dockers.DockerRunWarm("huskyci/gosec", "latest", "gosec ./...", "dockerapi", 60, dockers.ContainerOptions{})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("statusCode 2"))
+				Expect(fake.removed).To(BeEmpty())
+			})
+		})
+
+		Context("When the job runs for longer than its timeout", func() {
+			It("Should remove the container and return ErrTimeout", func() {
+				fake.hangs = true
+
+				CID, _, err := dockers.DockerRunWarm("huskyci/gosec", "latest", "gosec ./...", "dockerapi", 1, dockers.ContainerOptions{})
+				Expect(errors.Is(err, dockers.ErrTimeout)).To(BeTrue())
+				Expect(CID).To(Equal("cid-1"))
+				Expect(fake.removed).To(Equal([]string{"cid-1"}))
+
+				fake.hangs = false
+				CID, _, err = dockers.DockerRunWarm("huskyci/gosec", "latest", "gosec ./...", "dockerapi", 60, dockers.ContainerOptions{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(CID).To(Equal("cid-2"))
+			})
+		})
+
+		Context("When the idle container is gone", func() {
+			It("Should run the job in a new container", func() {
+				_, _, err := dockers.DockerRunWarm("huskyci/gosec", "latest", "gosec ./...", "dockerapi", 60, dockers.ContainerOptions{})
+				Expect(err).NotTo(HaveOccurred())
+				fake.gone["cid-1"] = true
+
+				CID, output, err := dockers.DockerRunWarm("huskyci/gosec", "latest", "gosec ./...", "dockerapi", 60, dockers.ContainerOptions{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(CID).To(Equal("cid-2"))
+				Expect(output).To(Equal("gosec output"))
+				Expect(fake.removed).To(Equal([]string{"cid-1"}))
+			})
+		})
+
+		Context("When the containers run with other options", func() {
+			It("Should not share them", func() {
+				_, _, err := dockers.DockerRunWarm("huskyci/gosec", "latest", "gosec ./...", "dockerapi", 60, dockers.ContainerOptions{})
+				Expect(err).NotTo(HaveOccurred())
+				CID, _, err := dockers.DockerRunWarm("huskyci/gosec", "latest", "gosec ./...", "dockerapi", 60, dockers.ContainerOptions{NetworkMode: "none"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(CID).To(Equal("cid-2"))
+			})
+		})
+
+		Context("When the pool is disabled", func() {
+			It("Should run the job in its own container", func() {
+				apiContext.APIConfiguration.WarmPoolConfig.Size = 0
+
+				_, _, err := dockers.DockerRunWarm("huskyci/gosec", "latest", "gosec ./...", "dockerapi", 60, dockers.ContainerOptions{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fake.configs[0].Cmd).To(BeEquivalentTo([]string{"/bin/sh", "-c", "gosec ./..."}))
+				Expect(fake.execs).To(BeEmpty())
+				Expect(fake.removed).To(Equal([]string{"cid-1"}))
+			})
+		})
+
+		Context("When a container is idle for too long", func() {
+			It("Should be reaped", func() {
+				_, _, err := dockers.DockerRunWarm("huskyci/gosec", "latest", "gosec ./...", "dockerapi", 60, dockers.ContainerOptions{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(dockers.ReapWarmContainers(time.Now().Add(-time.Minute), time.Now().Add(-time.Hour))).To(Equal(0))
+				Expect(dockers.ReapWarmContainers(time.Now(), time.Now().Add(-time.Hour))).To(Equal(1))
+				Expect(fake.removed).To(Equal([]string{"cid-1"}))
+			})
+		})
+	})
+
+	Describe("Windows", func() {
+
+		BeforeEach(func() {
+			fake.osType = dockers.OSWindows
+			fake.images["huskyci/securitycodescan:latest"] = true
+		})
+
+		Context("When the Docker daemon runs Windows containers", func() {
+			It("Should run the command with cmd and bind Windows paths", func() {
+				apiContext.APIConfiguration.ContainerSecurityConfig = &apiContext.ContainerSecurityConfig{User: "1000:1000", NoNewPrivileges: true}
+				options := dockers.ContainerOptions{Mounts: []types.VolumeMount{{HostPath: `D:\nuget`, ContainerPath: "/nuget"}}}
+
+				_, _, err := dockers.DockerRunWithVolume("huskyci/securitycodescan", "latest", "scs.exe", "dockerapi", "huskyci-rid", 60, options)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fake.configs[0].Cmd).To(BeEquivalentTo([]string{"cmd", "/S", "/C", "scs.exe"}))
+				Expect(fake.hostConfigs[0].Binds).To(Equal([]string{`huskyci-rid:C:\workspace:ro`, `D:\nuget:C:\nuget:ro`}))
+				Expect(fake.configs[0].User).To(BeEmpty())
+				Expect(fake.hostConfigs[0].CapDrop).To(BeEmpty())
+			})
+		})
+
+		Context("When the code of an analysis is extracted into a volume", func() {
+			It("Should ask for it to be copied instead", func() {
+				err := dockers.CreateAnalysisVolume("dockerapi", "rid", "/tmp/rid.zip")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("HUSKYCI_DOCKERAPI_CODE_DELIVERY"))
+				Expect(fake.configs).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("PrepullImage", func() {
+
+		Context("When a pull fails and then succeeds", func() {
+			It("Should retry it", func() {
+				fake.pullStreams = []string{
+					`{"errorDetail":{"message":"connection reset by peer"},"error":"connection reset by peer"}`,
+					`{"status":"Downloaded newer image"}`,
+				}
+
+				err := dockers.PrepullImage("huskyci/gosec", "latest", "dockerapi", false)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fake.pulls).To(HaveLen(2))
+			})
+		})
+
+		Context("When every pull fails", func() {
+			It("Should give up after three attempts", func() {
+				fake.pullErr = errors.New("connection refused")
+
+				err := dockers.PrepullImage("huskyci/gosec", "latest", "dockerapi", false)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("after 3 attempts"))
+				Expect(fake.pulls).To(HaveLen(3))
+			})
+		})
+
+		Context("When the image has no manifest for the platform", func() {
+			It("Should fail at once", func() {
+				fake.pullStreams = []string{`{"errorDetail":{"message":"no matching manifest for linux/arm64 in the manifest list entries"}}`}
+
+				err := dockers.PrepullImage("huskyci/gosec", "latest", "dockerapi", false)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("platform mismatch"))
+				Expect(fake.pulls).To(HaveLen(1))
+			})
+		})
+
+		Context("When the pull takes longer than PullTimeout", func() {
+			It("Should return a timeout error", func() {
+				dockers.PullRetryInterval = time.Hour
+				dockers.PullTimeout = 10 * time.Millisecond
+				fake.pullErr = errors.New("connection refused")
+
+				err := dockers.PrepullImage("huskyci/gosec", "latest", "dockerapi", false)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("timeout"))
+				Expect(fake.pulls).To(HaveLen(1))
+			})
+		})
+
+		Context("When the pull is the first attempt", func() {
+			It("Should not wait for PullRetryInterval", func() {
+				dockers.PullRetryInterval = time.Hour
+
+				Expect(dockers.PrepullImage("huskyci/gosec", "latest", "dockerapi", false)).To(Succeed())
+				Expect(fake.pulls).To(HaveLen(1))
+			})
+		})
+
+		Context("When many analyses need the same missing image at once", func() {
+			It("Should pull it once", func() {
+				fake.pullStreams = []string{
+					`{"errorDetail":{"message":"connection reset by peer"},"error":"connection reset by peer"}`,
+					`{"status":"Downloaded newer image"}`,
+				}
+				dockers.PullRetryInterval = 50 * time.Millisecond
+
+				var wg sync.WaitGroup
+				errs := make(chan error, 5)
+				for i := 0; i < 5; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						errs <- dockers.PrepullImage("huskyci/gosec", "latest", "dockerapi", false)
+					}()
+				}
+				wg.Wait()
+				close(errs)
+				for err := range errs {
+					Expect(err).NotTo(HaveOccurred())
+				}
+				Expect(fake.pulls).To(HaveLen(2))
+			})
+		})
+
+		Context("When the image is loaded and refresh is true", func() {
+			It("Should pull it again", func() {
+				fake.images["huskyci/gosec:latest"] = true
+
+				Expect(dockers.PrepullImage("huskyci/gosec", "latest", "dockerapi", false)).To(Succeed())
+				Expect(fake.pulls).To(BeEmpty())
+				Expect(dockers.PrepullImage("huskyci/gosec", "latest", "dockerapi", true)).To(Succeed())
+				Expect(fake.pulls).To(Equal([]string{"docker.io/huskyci/gosec:latest"}))
+			})
+		})
+
+		Context("When the registry of the image has a credential", func() {
+			BeforeEach(func() {
+				os.Setenv(registryauth.CredentialsEnv, "registry.example.com=huskyci:s3cr3t")
+			})
+			AfterEach(func() {
+				os.Unsetenv(registryauth.CredentialsEnv)
+			})
+
+			It("Should pull it with that credential only", func() {
+				Expect(dockers.PrepullImage("registry.example.com/org/scanner", "1.0", "dockerapi", false)).To(Succeed())
+				Expect(dockers.PrepullImage("huskyci/gosec", "latest", "dockerapi", false)).To(Succeed())
+				Expect(fake.pullAuths).To(HaveLen(2))
+				auth, err := registry.DecodeAuthConfig(fake.pullAuths[0])
+				Expect(err).NotTo(HaveOccurred())
+				Expect(auth.Username).To(Equal("huskyci"))
+				Expect(auth.Password).To(Equal("s3cr3t"))
+				Expect(fake.pullAuths[1]).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("CleanupStaleVolumes", func() {
+		It("Should only remove the volumes created before olderThan", func() {
+			now := time.Now()
+			fake.volumes = []*volume.Volume{
+				{Name: "huskyci-old", CreatedAt: now.Add(-2 * time.Hour).Format(time.RFC3339)},
+				{Name: "huskyci-new", CreatedAt: now.Format(time.RFC3339)},
+			}
+
+			removed, err := dockers.CleanupStaleVolumes("dockerapi", now.Add(-time.Hour))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(removed).To(Equal(1))
+			Expect(fake.removedVols).To(Equal([]string{"huskyci-old"}))
+		})
+	})
+
+	Describe("RunningContainers", func() {
+		It("Should leave out the warm pool", func() {
+			fake.listed = []dockerTypes.Container{
+				{ID: "scan", Labels: map[string]string{dockers.HuskyCILabel: "true"}},
+				{ID: "warm", Labels: map[string]string{dockers.HuskyCILabel: "true", dockers.WarmPoolLabel: "true"}},
+			}
+
+			running, err := dockers.RunningContainers("dockerapi")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(running).To(Equal(1))
+		})
+	})
+
+	Describe("SharedClient", func() {
+		var created int
+		create := func() (dockers.Client, error) {
+			created++
+			return newFakeClient(), nil
+		}
+
+		BeforeEach(func() {
+			created = 0
+			apiContext.APIConfiguration.DockerClientPoolConfig = &apiContext.DockerClientPoolConfig{IdleTimeout: time.Minute, HealthInterval: time.Second}
+		})
+
+		AfterEach(func() {
+			// empty the pool for the next test
+			dockers.ReapClients(time.Now().Add(time.Hour), false)
+		})
+
+		Context("When a host is used twice", func() {
+			It("Should share its client", func() {
+				first, err := dockers.SharedClient("https://dockerapi:2376", create)
+				Expect(err).NotTo(HaveOccurred())
+				second, err := dockers.SharedClient("https://dockerapi:2376", create)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(second).To(BeIdenticalTo(first))
+				Expect(created).To(Equal(1))
+
+				_, err = dockers.SharedClient("https://docker2:2376", create)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(created).To(Equal(2))
+			})
+		})
+		Context("When the reuse is disabled", func() {
+			It("Should create a client every time", func() {
+				apiContext.APIConfiguration.DockerClientPoolConfig.IdleTimeout = 0
+				dockers.SharedClient("https://dockerapi:2376", create)
+				dockers.SharedClient("https://dockerapi:2376", create)
+				Expect(created).To(Equal(2))
+			})
+		})
+		Context("When a client is idle for too long", func() {
+			It("Should close it", func() {
+				c, _ := dockers.SharedClient("https://dockerapi:2376", create)
+				Expect(dockers.ReapClients(time.Now().Add(-time.Minute), false)).To(Equal(0))
+				Expect(dockers.ReapClients(time.Now().Add(time.Minute), false)).To(Equal(1))
+				Expect(c.(*fakeClient).closed).To(BeTrue())
+				dockers.SharedClient("https://dockerapi:2376", create)
+				Expect(created).To(Equal(2))
+			})
+		})
+		Context("When a client fails its health check", func() {
+			It("Should close it and create a new one", func() {
+				c, _ := dockers.SharedClient("https://dockerapi:2376", create)
+				c.(*fakeClient).pingErr = errors.New("connection refused")
+				Expect(dockers.ReapClients(time.Now().Add(-time.Minute), true)).To(Equal(0))
+				Expect(c.(*fakeClient).closed).To(BeTrue())
+				again, _ := dockers.SharedClient("https://dockerapi:2376", create)
+				Expect(again).NotTo(BeIdenticalTo(c))
+			})
+		})
+	})
+
+	Describe("CollectImages", func() {
+		var repositories, keep map[string]bool
+
+		BeforeEach(func() {
+			repositories = map[string]bool{"huskyci/gosec": true, "huskyci/bandit": true}
+			keep = map[string]bool{"huskyci/gosec:2.0": true, "huskyci/bandit:1.7": true}
+			fake.inUse = map[string]bool{}
+			fake.summaries = []image.Summary{
+				{ID: "sha256:gosec20", RepoTags: []string{"huskyci/gosec:2.0"}, Size: 10},
+				{ID: "sha256:gosec19", RepoTags: []string{"huskyci/gosec:1.9"}, Size: 20},
+				{ID: "sha256:bandit16", RepoTags: []string{"huskyci/bandit:1.6", "huskyci/bandit:stable"}, Size: 30},
+				{ID: "sha256:both", RepoTags: []string{"huskyci/bandit:1.5", "huskyci/bandit:1.7"}, Size: 40},
+				{ID: "sha256:nginx", RepoTags: []string{"nginx:1.0"}, Size: 50},
+			}
+		})
+
+		AfterEach(func() {
+			// forget the images found unreferenced for the next test
+			fake.summaries = nil
+			dockers.CollectImages("dockerapi", repositories, keep, time.Hour)
+		})
+
+		Context("When scanner images are no longer referenced", func() {
+			It("Should remove them and prune the dangling images", func() {
+				result, err := dockers.CollectImages("dockerapi", repositories, keep, 0)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.ImagesRemoved).To(Equal(2))
+				Expect(result.ImagesPruned).To(Equal(1))
+				Expect(result.Reclaimed).To(Equal(int64(20 + 30 + 100)))
+				Expect(fake.untagged).To(ConsistOf("huskyci/gosec:1.9", "huskyci/bandit:1.6", "huskyci/bandit:stable"))
+				Expect(fake.pruned).To(HaveLen(1))
+				Expect(fake.pruned[0].ExactMatch("dangling", "true")).To(BeTrue())
+			})
+		})
+		Context("When scanner images were found unreferenced within the protection window", func() {
+			It("Should keep them until the window passed", func() {
+				result, err := dockers.CollectImages("dockerapi", repositories, keep, time.Hour)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.ImagesRemoved).To(Equal(0))
+				Expect(fake.untagged).To(BeEmpty())
+			})
+		})
+		Context("When an unreferenced image is used by a container", func() {
+			It("Should keep it without error", func() {
+				fake.inUse["huskyci/gosec:1.9"] = true
+				result, err := dockers.CollectImages("dockerapi", repositories, keep, 0)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.ImagesRemoved).To(Equal(1))
+				Expect(fake.untagged).NotTo(ContainElement("huskyci/gosec:1.9"))
+			})
+		})
+	})
+
+	Describe("ImageRepository", func() {
+		It("Should leave out the tag, the digest and the docker.io registry", func() {
+			Expect(dockers.ImageRepository("huskyci/gosec:2.0")).To(Equal("huskyci/gosec"))
+			Expect(dockers.ImageRepository("docker.io/library/alpine@sha256:abc")).To(Equal("alpine"))
+			Expect(dockers.ImageRepository("registry:5000/huskyci/gosec")).To(Equal("registry:5000/huskyci/gosec"))
+			Expect(dockers.ImageRepository("registry:5000/huskyci/gosec:2.0")).To(Equal("registry:5000/huskyci/gosec"))
+		})
+	})
+})
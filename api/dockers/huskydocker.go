@@ -9,17 +9,33 @@ import (
 
 	"regexp"
 
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/debugtrace"
 	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/types"
+	"golang.org/x/sync/singleflight"
 )
 
 const logActionRun = "DockerRun"
 const logInfoHuskyDocker = "HUSKYDOCKER"
 const logActionPull = "pullImage"
 
+// PullRetryInterval is how long pullImage waits before each new attempt to
+// pull an image once one failed.
+var PullRetryInterval = 15 * time.Second
+
+// PullTimeout bounds how long pullImage tries to pull an image.
+var PullTimeout = 15 * time.Minute
+
 const urlRegexp = `([\w\-_]+(?:(?:\.[\w\-_]+)+))([\w\-\.,@?^=%&amp;:/~\+#]*[\w\-\@?^=%&amp;/~\+#])?`
 
+// configureImagePath returns the canonical and full reference of image.
+// A tag in the sha256:<hex> form is a digest and is joined with "@".
 func configureImagePath(image, tag string) (string, string) {
 	fullContainerImage := fmt.Sprintf("%s:%s", image, tag)
+	if strings.HasPrefix(tag, "sha256:") {
+		fullContainerImage = fmt.Sprintf("%s@%s", image, tag)
+	}
 	regex := regexp.MustCompile(urlRegexp)
 	canonicalURL := image
 	if !regex.MatchString(canonicalURL) {
@@ -33,60 +49,104 @@ func configureImagePath(image, tag string) (string, string) {
 
 // DockerRun starts a new container and returns its output and an error.
 func DockerRun(image, imageTag, cmd, dockerHost string, timeOutInSeconds int) (string, string, error) {
-	return DockerRunWithVolume(image, imageTag, cmd, dockerHost, "", timeOutInSeconds)
+	return DockerRunWithVolume(image, imageTag, cmd, dockerHost, "", timeOutInSeconds, ContainerOptions{})
+}
+
+// NetworkOptions returns the ContainerOptions that isolate a container according
+// to networkMode. The egress-proxy mode attaches the container to the network set
+// in HUSKYCI_DOCKERAPI_EGRESS_NETWORK, which is expected to only reach the proxy
+// set in HUSKYCI_DOCKERAPI_EGRESS_PROXY.
+func NetworkOptions(networkMode string) (ContainerOptions, error) {
+	switch networkMode {
+	case "", types.NetworkModeFull:
+		return ContainerOptions{}, nil
+	case types.NetworkModeNone:
+		return ContainerOptions{NetworkMode: "none"}, nil
+	case types.NetworkModeEgressProxy:
+		dockerHostsConfig := apiContext.APIConfiguration.DockerHostsConfig
+		if dockerHostsConfig == nil || dockerHostsConfig.EgressNetwork == "" || dockerHostsConfig.EgressProxy == "" {
+			return ContainerOptions{}, errors.New("egress-proxy network mode requires HUSKYCI_DOCKERAPI_EGRESS_NETWORK and HUSKYCI_DOCKERAPI_EGRESS_PROXY")
+		}
+		proxy := dockerHostsConfig.EgressProxy
+		return ContainerOptions{
+			NetworkMode: dockerHostsConfig.EgressNetwork,
+			Env:         []string{"http_proxy=" + proxy, "https_proxy=" + proxy, "HTTP_PROXY=" + proxy, "HTTPS_PROXY=" + proxy},
+		}, nil
+	default:
+		return ContainerOptions{}, fmt.Errorf("invalid network mode: %s", networkMode)
+	}
 }
 
 // DockerRunWithVolume starts a new container with an optional volume mount and returns its output and an error.
-func DockerRunWithVolume(image, imageTag, cmd, dockerHost, volumePath string, timeOutInSeconds int) (string, string, error) {
+func DockerRunWithVolume(image, imageTag, cmd, dockerHost, volumePath string, timeOutInSeconds int, options ContainerOptions) (string, string, error) {
 
 	// step 1: create a new docker API client
-	d, err := NewDocker(dockerHost)
+	d, err := Open(dockerHost)
 	if err != nil {
 		return "", "", err
 	}
+	if err := d.DetectOS(); err != nil {
+		return "", "", err
+	}
 
-	canonicalURL, fullContainerImage := configureImagePath(image, imageTag)
 	// step 2: pull image if it is not there yet
-	if !d.ImageIsLoaded(fullContainerImage) {
-		if err := pullImage(d, canonicalURL, fullContainerImage); err != nil {
-			return "", "", err
-		}
+	fullContainerImage, err := loadImage(d, image, imageTag, dockerHost, options.RID)
+	if err != nil {
+		return "", "", err
 	}
 
-	// step 2.5: For file:// URLs, ensure dockerapi can see the files
-	// docker-in-docker has issues with bind mounts - dockerapi can't see files written by API container
-	// Use a temporary container to refresh dockerapi's view of the mount
 	if volumePath != "" {
-		log.Info(logActionRun, logInfoHuskyDocker, 16, fmt.Sprintf("Mounting volume path: %s (resolved relative to Docker daemon host)", volumePath))
-		// Sync files to dockerapi using a temporary container
-		if err := syncFilesToDockerAPI(d, volumePath); err != nil {
-			log.Error(logActionRun, logInfoHuskyDocker, 3016, fmt.Errorf("failed to sync files to dockerapi: %v (continuing anyway)", err))
-			// Continue anyway - the mount might still work
-		}
+		log.Info(logActionRun, logInfoHuskyDocker, 16, fmt.Sprintf("Mounting volume: %s", volumePath))
 	}
 
 	// step 3: create a new container given an image and it's cmd
-	CID, err := d.CreateContainerWithVolume(fullContainerImage, cmd, volumePath)
+	CID, err := d.CreateContainerWithVolume(fullContainerImage, cmd, volumePath, options)
 	if err != nil {
+		debugtrace.Record(debugtrace.Lifecycle, "docker", "create.failed", options.RID, "image", fullContainerImage, "error", err)
 		return "", "", err
 	}
 	d.CID = CID
+	debugtrace.Record(debugtrace.Lifecycle, "docker", "create", options.RID, "host", dockerHost, "image", fullContainerImage, "cid", CID, "volume", volumePath, "network", options.NetworkMode)
+
+	// step 3a: copy the code into the container when it is not in a volume
+	if options.Workspace != "" {
+		if err := d.CopyDirectory(options.Workspace, WorkspacePath); err != nil {
+			log.Error(logActionRun, logInfoHuskyDocker, 3032, CID, err)
+			debugtrace.Record(debugtrace.Lifecycle, "docker", "copy.failed", options.RID, "cid", CID, "error", err)
+			d.RemoveContainer()
+			return "", "", err
+		}
+		debugtrace.Record(debugtrace.Lifecycle, "docker", "copy", options.RID, "cid", CID, "workspace", options.Workspace)
+	}
 
 	// step 4: start container
 	if err := d.StartContainer(); err != nil {
 		log.Error(logActionRun, logInfoHuskyDocker, 3015, err)
+		debugtrace.Record(debugtrace.Lifecycle, "docker", "start.failed", options.RID, "cid", CID, "error", err)
 		return "", "", err
 	}
 	log.Info(logActionRun, logInfoHuskyDocker, 32, fullContainerImage, d.CID)
+	started := time.Now()
 
 	// step 5: wait container finish
 	if err := d.WaitContainer(timeOutInSeconds); err != nil {
 		log.Error(logActionRun, logInfoHuskyDocker, 3016, err)
+		debugtrace.Record(debugtrace.Lifecycle, "docker", "wait.failed", options.RID, "cid", CID, "duration", time.Since(started), "error", err)
+		if errors.Is(err, ErrTimeout) {
+			// keep what the container printed so far, and stop it
+			cOutput, _ := d.ReadOutputLimited(options.MaxOutputSize)
+			d.StopContainer()
+			if err := d.RemoveContainer(); err != nil {
+				log.Error(logActionRun, logInfoHuskyDocker, 3027, err)
+			}
+			return CID, cOutput, err
+		}
 		return "", "", err
 	}
+	debugtrace.Record(debugtrace.Lifecycle, "docker", "exit", options.RID, "cid", CID, "duration", time.Since(started))
 
 	// step 6: read container's output when it finishes
-	cOutput, err := d.ReadOutput()
+	cOutput, err := d.ReadOutputLimited(options.MaxOutputSize)
 	if err != nil {
 		return "", "", err
 	}
@@ -97,196 +157,245 @@ func DockerRunWithVolume(image, imageTag, cmd, dockerHost, volumePath string, ti
 		log.Error(logActionRun, logInfoHuskyDocker, 3027, err)
 		return "", "", err
 	}
+	debugtrace.Record(debugtrace.Lifecycle, "docker", "remove", options.RID, "cid", CID)
 
 	return CID, cOutput, nil
 }
 
-// ExtractZipInDockerAPI extracts a zip file directly in dockerapi using a temporary container
-// This ensures dockerapi's Docker daemon can see the extracted files immediately
-// Since docker-in-docker doesn't properly share bind mounts, we need to ensure dockerapi can see the files
-// The zip file exists on the host at /tmp/huskyci-zips-host/, and dockerapi has this mounted at /tmp/huskyci-zips/
-// When dockerapi creates containers, it resolves paths relative to dockerapi's filesystem
-// So /tmp/huskyci-zips/<RID>.zip in dockerapi = /tmp/huskyci-zips-host/<RID>.zip on host
-func ExtractZipInDockerAPI(dockerHost, zipPath, destDir string) error {
-	// Extract zip file name and directory from path
-	zipFileName := filepath.Base(zipPath)
-	parentDir := filepath.Dir(zipPath)
-	
-	// Use a temporary alpine container with unzip to extract files
-	// Mount the parent directory - dockerapi resolves this relative to its filesystem
-	// Since dockerapi has /tmp/huskyci-zips-host:/tmp/huskyci-zips mounted,
-	// mounting /tmp/huskyci-zips should work and access files on the host
-	// The volume is mounted at /workspace in the container (see CreateContainerWithVolume)
-	// So we need to use /workspace instead of the original path
-	// The zip file and destination directory are relative to the parent directory
-	destDirName := filepath.Base(destDir)
-	// Add retry logic to wait for file to be visible to dockerapi's Docker daemon
-	// Use a loop with small delays to check if file exists before attempting extraction
-	// Retry up to 30 times with 0.5s delay (15s total) so large uploads are visible to dockerapi before extraction
-	extractCmd := fmt.Sprintf("sh -c 'apk add --no-cache unzip > /dev/null 2>&1 && cd /workspace && "+
-		"for i in $(seq 1 30); do "+
-		"if [ -f %s ]; then "+
-		"mkdir -p %s && unzip -q -o %s -d %s && echo \"Extraction successful\" && exit 0; "+
-		"fi; "+
-		"sleep 0.5; "+
-		"done; "+
-		"echo \"ERROR: Zip file %s not found in /workspace after retries\"; "+
-		"ls -la /workspace 2>&1; "+
-		"exit 1'", zipFileName, destDirName, zipFileName, destDirName, zipFileName)
-	
-	// Create Docker client for dockerapi
-	d, err := NewDocker(dockerHost)
+// loadImage pulls image:imageTag into the Docker API host of d unless it is
+// already there, and returns its full reference.
+func loadImage(d *Docker, image, imageTag, dockerHost, RID string) (string, error) {
+	canonicalURL, fullContainerImage := configureImagePath(image, imageTag)
+	if !d.ImageIsLoaded(fullContainerImage) {
+		debugtrace.Record(debugtrace.Lifecycle, "docker", "pull", RID, "host", dockerHost, "image", fullContainerImage)
+		if err := pullImage(d, canonicalURL, fullContainerImage); err != nil {
+			debugtrace.Record(debugtrace.Lifecycle, "docker", "pull.failed", RID, "image", fullContainerImage, "error", err)
+			return "", err
+		}
+	}
+	return fullContainerImage, nil
+}
+
+// AnalysisVolume returns the name of the Docker volume holding the uploaded
+// code of the analysis RID.
+func AnalysisVolume(RID string) string {
+	return "huskyci-" + RID
+}
+
+// CreateAnalysisVolume creates the volume of the analysis RID in dockerHost and
+// extracts the zip at zipPath into it. Only the containers of the analysis
+// mount this volume, so they can not read the code of any other analysis.
+// The directory of zipPath must be visible to the Docker daemon at the same
+// path, as the zip is read from there by a temporary container.
+func CreateAnalysisVolume(dockerHost, RID, zipPath string) error {
+	d, err := Open(dockerHost)
 	if err != nil {
 		return fmt.Errorf("failed to create Docker client: %w", err)
 	}
-	
-	// Mount the parent directory - dockerapi will resolve this relative to its filesystem
-	// We need read-write access to extract files, so we'll mount it as rw
-	volumePath := parentDir
-	
-	// Ensure alpine:latest image is available in dockerapi
-	canonicalURL, fullContainerImage := configureImagePath("alpine", "latest")
-	log.Info("ExtractZipInDockerAPI", logInfoHuskyDocker, 16, fmt.Sprintf("Checking for image %s (canonical: %s) in dockerapi...", fullContainerImage, canonicalURL))
-	isLoaded := d.ImageIsLoaded(fullContainerImage)
-	log.Info("ExtractZipInDockerAPI", logInfoHuskyDocker, 16, fmt.Sprintf("Image %s loaded: %v", fullContainerImage, isLoaded))
-	if !isLoaded {
-		log.Info("ExtractZipInDockerAPI", logInfoHuskyDocker, 31, fmt.Sprintf("Pulling image %s (canonical: %s) in dockerapi...", fullContainerImage, canonicalURL))
+	if err := d.DetectOS(); err != nil {
+		return fmt.Errorf("failed to read the operating system of the Docker API: %w", err)
+	}
+	if d.IsWindows() {
+		// the code is extracted by an alpine container, which Windows can not run
+		return fmt.Errorf("the code of analyses run by Windows Docker API hosts is copied into their containers: set HUSKYCI_DOCKERAPI_CODE_DELIVERY to %s", apiContext.CodeDeliveryCopy)
+	}
+
+	canonicalURL, fullContainerImage := configureImagePath(helperImage(), "latest")
+	if !d.ImageIsLoaded(fullContainerImage) {
 		if err := pullImage(d, canonicalURL, fullContainerImage); err != nil {
-			return fmt.Errorf("failed to pull alpine:latest image: %w", err)
+			return fmt.Errorf("failed to pull %s image: %w", fullContainerImage, err)
 		}
-		log.Info("ExtractZipInDockerAPI", logInfoHuskyDocker, 35, fmt.Sprintf("Successfully pulled image %s", fullContainerImage))
-	} else {
-		log.Info("ExtractZipInDockerAPI", logInfoHuskyDocker, 35, fmt.Sprintf("Image %s already loaded, skipping pull", fullContainerImage))
 	}
-	
-	log.Info("ExtractZipInDockerAPI", logInfoHuskyDocker, 16, fmt.Sprintf("Extracting zip in dockerapi: zipPath=%s, destDir=%s, volumePath=%s", zipPath, destDir, volumePath))
-	
-	// Create container with read-write mount so we can extract files
-	// We need to use CreateContainerWithVolumeRW instead of CreateContainerWithVolume
-	CID, err := d.CreateContainerWithVolumeRW(fullContainerImage, extractCmd, volumePath)
+
+	volumeName := AnalysisVolume(RID)
+	if err := d.CreateVolume(volumeName, RID); err != nil {
+		debugtrace.Record(debugtrace.Lifecycle, "extract", "volume.failed", RID, "host", dockerHost, "volume", volumeName, "error", err)
+		return err
+	}
+	debugtrace.Record(debugtrace.Lifecycle, "extract", "volume", RID, "host", dockerHost, "volume", volumeName)
+	started := time.Now()
+	if err := extractZip(d, fullContainerImage, RID, volumeName, zipPath); err != nil {
+		debugtrace.Record(debugtrace.Lifecycle, "extract", "failed", RID, "zip", zipPath, "duration", time.Since(started), "error", err)
+		d.RemoveVolume(volumeName)
+		return err
+	}
+	debugtrace.Record(debugtrace.Lifecycle, "extract", "done", RID, "zip", zipPath, "duration", time.Since(started))
+	log.Info("CreateAnalysisVolume", logInfoHuskyDocker, 16, fmt.Sprintf("Extracted %s into volume %s", zipPath, volumeName))
+	return nil
+}
+
+// extractZip extracts the zip at zipPath into volumeName using a temporary
+// container that mounts the directory of the zip read-only at /uploads and the
+// volume at /workspace.
+func extractZip(d *Docker, image, RID, volumeName, zipPath string) error {
+	zipFileName := filepath.Base(zipPath)
+	// Retry up to 30 times with 0.5s delay (15s total) so large uploads are visible to dockerapi before extraction
+	// If apk can not reach the internet (air-gapped mode), the busybox unzip applet is used instead
+	extractCmd := fmt.Sprintf("sh -c 'apk add --no-cache unzip > /dev/null 2>&1; "+
+		"for i in $(seq 1 30); do "+
+		"if [ -f /uploads/%s ]; then "+
+		"unzip -q -o /uploads/%s -d /workspace && echo \"Extraction successful\" && exit 0; "+
+		"fi; "+
+		"sleep 0.5; "+
+		"done; "+
+		"echo \"ERROR: Zip file %s not found in /uploads after retries\"; "+
+		"exit 1'", zipFileName, zipFileName, zipFileName)
+
+	// the volume is owned by root, so the container keeps the root user of alpine
+	options := ContainerOptions{RID: RID, ImageUser: true, Mounts: []types.VolumeMount{
+		{HostPath: volumeName, ContainerPath: "/workspace", Writable: true},
+		{HostPath: filepath.Dir(zipPath), ContainerPath: "/uploads"},
+	}}
+	CID, err := d.CreateContainerWithVolume(image, extractCmd, "", options)
 	if err != nil {
 		return fmt.Errorf("failed to create extract container: %w", err)
 	}
 	d.CID = CID
-	
-	// Start container
+	defer func() {
+		if err := d.RemoveContainer(); err != nil {
+			log.Error("extractZip", logInfoHuskyDocker, 3027, fmt.Errorf("failed to remove extract container: %v", err))
+		}
+	}()
+
 	if err := d.StartContainer(); err != nil {
-		d.RemoveContainer()
 		return fmt.Errorf("failed to start extract container: %w", err)
 	}
-	
-	// Wait for container to finish (allow up to 5 minutes for large zip files)
+	// Allow up to 5 minutes for large zip files
 	if err := d.WaitContainer(300); err != nil {
-		// Read container output to see what went wrong
 		output, _ := d.ReadOutput()
-		d.RemoveContainer()
 		return fmt.Errorf("extract container error: %w (output: %s)", err, output)
 	}
-	
-	// Verify extraction succeeded by reading output
 	output, _ := d.ReadOutput()
+	debugtrace.Record(debugtrace.Verbose, "extract", "output", RID, "cid", CID, "output", output)
 	if strings.Contains(output, "ERROR") {
-		d.RemoveContainer()
 		return fmt.Errorf("extraction failed: %s", output)
 	}
-	
-	// Clean up
-	if err := d.RemoveContainer(); err != nil {
-		log.Error("ExtractZipInDockerAPI", logInfoHuskyDocker, 3027, fmt.Errorf("failed to remove extract container: %v", err))
-	}
-	
 	return nil
 }
 
-// syncFilesToDockerAPI ensures dockerapi can see files by using a temporary container
-// to refresh dockerapi's view of the mount. Since docker-in-docker doesn't properly
-// share bind mounts between containers, we use a temporary container to ensure
-// dockerapi's Docker daemon can see files written by the API container.
-func syncFilesToDockerAPI(d *Docker, volumePath string) error {
-	// Use a temporary alpine container to list files in the volume
-	// This forces dockerapi's Docker daemon to refresh its view of the mount
-	// The container mounts the volume and lists files to ensure they're visible
-	syncCmd := fmt.Sprintf("sh -c 'ls -la %s > /dev/null 2>&1 || true'", volumePath)
-	
-	// Create a temporary container with the volume mounted
-	tempCID, err := d.CreateContainerWithVolume("alpine:latest", syncCmd, volumePath)
+// RemoveAnalysisVolume removes the volume of the analysis RID from dockerHost.
+func RemoveAnalysisVolume(dockerHost, RID string) error {
+	d, err := Open(dockerHost)
 	if err != nil {
-		return fmt.Errorf("failed to create sync container: %w", err)
+		return err
 	}
-	
-	// Start and wait for the container
-	d.CID = tempCID
-	if err := d.StartContainer(); err != nil {
-		d.RemoveContainer() // Clean up on error
-		return fmt.Errorf("failed to start sync container: %w", err)
+	debugtrace.Record(debugtrace.Lifecycle, "extract", "volume.remove", RID, "host", dockerHost, "volume", AnalysisVolume(RID))
+	return d.RemoveVolume(AnalysisVolume(RID))
+}
+
+// CleanupStaleVolumes removes from dockerHost the analysis volumes created
+// before olderThan and left behind by analyses that never finished.
+func CleanupStaleVolumes(dockerHost string, olderThan time.Time) (int, error) {
+	d, err := Open(dockerHost)
+	if err != nil {
+		return 0, err
 	}
-	
-	// Wait for container to finish (should be very quick)
-	if err := d.WaitContainer(30); err != nil {
-		d.RemoveContainer()
-		return fmt.Errorf("sync container error: %w", err)
+	return d.RemoveStaleVolumes(olderThan)
+}
+
+// helperImage returns the alpine image used by the helper containers, pulled
+// from the registry mirror when one is configured.
+func helperImage() string {
+	return apiContext.APIConfiguration.OfflineConfig.MirrorImage("alpine")
+}
+
+// CleanupExitedContainers removes huskyCI containers that exited before
+// olderThan from dockerHost and returns how many were removed and how many
+// bytes were reclaimed.
+func CleanupExitedContainers(dockerHost string, olderThan time.Time) (int, int64, error) {
+	d, err := Open(dockerHost)
+	if err != nil {
+		return 0, 0, err
 	}
-	
-	// Clean up temporary container
-	if err := d.RemoveContainer(); err != nil {
-		// Log but don't fail - this is cleanup
-		log.Error(logActionRun, logInfoHuskyDocker, 3027, fmt.Errorf("failed to remove sync container: %v", err))
+	return d.RemoveExitedContainers(olderThan)
+}
+
+// RunningContainers returns how many securityTests are running in
+// dockerHost, so that a draining host can be told drained.
+func RunningContainers(dockerHost string) (int, error) {
+	d, err := Open(dockerHost)
+	if err != nil {
+		return 0, err
 	}
-	
-	return nil
+	return d.CountRunningContainers()
 }
 
+// PrepullImage makes sure image:imageTag is present in dockerHost ahead of any
+// analysis. If refresh is true the image is pulled again even if it is already
+// loaded, so mutable tags such as latest are kept up to date.
+func PrepullImage(image, imageTag, dockerHost string, refresh bool) error {
+	d, err := Open(dockerHost)
+	if err != nil {
+		return err
+	}
+	canonicalURL, fullContainerImage := configureImagePath(image, imageTag)
+	if d.ImageIsLoaded(fullContainerImage) {
+		if !refresh {
+			return nil
+		}
+		return d.PullImage(canonicalURL)
+	}
+	return pullImage(d, canonicalURL, fullContainerImage)
+}
+
+// pulls holds the image pulls in flight, by Docker API host and image, so
+// that the analyses starting together wait for a single pull of their image.
+var pulls singleflight.Group
+
+// pullImage pulls image into the Docker API host of d, waiting for the pull
+// of image another analysis already started there instead of starting one.
 func pullImage(d *Docker, canonicalURL, image string) error {
-	timeout := time.After(15 * time.Minute)
-	retryTick := time.NewTicker(15 * time.Second)
+	_, err, _ := pulls.Do(d.host+"|"+image, func() (interface{}, error) {
+		return nil, retryPullImage(d, canonicalURL, image)
+	})
+	return err
+}
+
+// retryPullImage pulls image right away and, if it fails, up to twice more,
+// PullRetryInterval apart, failing at once if the image has no manifest for
+// the platform of the host and once PullTimeout passed.
+func retryPullImage(d *Docker, canonicalURL, image string) error {
+	timeout := time.After(PullTimeout)
 	maxRetries := 3
 	retryCount := 0
-	
+
 	for {
-		select {
-		case <-timeout:
-			timeOutErr := errors.New("timeout after 15 minutes")
-			log.Error(logActionPull, logInfoHuskyDocker, 3013, fmt.Sprintf("Image pull timeout for %s: %v", image, timeOutErr))
-			return timeOutErr
-		case <-retryTick.C:
-			log.Info(logActionPull, logInfoHuskyDocker, 31, fmt.Sprintf("Attempting to pull image: %s (attempt %d)", image, retryCount+1))
-			
-			// Check if image is already loaded
-			if d.ImageIsLoaded(image) {
-				log.Info(logActionPull, logInfoHuskyDocker, 35, fmt.Sprintf("Image already loaded: %s", image))
-				return nil
+		log.Info(logActionPull, logInfoHuskyDocker, 31, fmt.Sprintf("Attempting to pull image: %s (attempt %d)", image, retryCount+1))
+
+		// Check if image is already loaded
+		if d.ImageIsLoaded(image) {
+			log.Info(logActionPull, logInfoHuskyDocker, 35, fmt.Sprintf("Image already loaded: %s", image))
+			return nil
+		}
+
+		// Attempt to pull the image
+		if err := d.PullImage(canonicalURL); err != nil {
+			retryCount++
+
+			// Check if it's a platform mismatch error - fail immediately
+			errStr := err.Error()
+			if strings.Contains(strings.ToLower(errStr), "no matching manifest") ||
+				strings.Contains(strings.ToLower(errStr), "platform") ||
+				strings.Contains(strings.ToLower(errStr), "manifest unknown") ||
+				strings.Contains(strings.ToLower(errStr), "manifest not found") {
+				log.Error(logActionPull, logInfoHuskyDocker, 3013, fmt.Sprintf("Platform mismatch error for %s - failing immediately: %v", image, err))
+				return fmt.Errorf("platform mismatch or manifest not found for %s: %w", image, err)
 			}
-			
-			// Attempt to pull the image
-			if err := d.PullImage(canonicalURL); err != nil {
-				retryCount++
-				
-				// Check if it's a platform mismatch error - fail immediately
-				errStr := err.Error()
-				if strings.Contains(strings.ToLower(errStr), "no matching manifest") ||
-					strings.Contains(strings.ToLower(errStr), "platform") ||
-					strings.Contains(strings.ToLower(errStr), "manifest unknown") ||
-					strings.Contains(strings.ToLower(errStr), "manifest not found") {
-					log.Error(logActionPull, logInfoHuskyDocker, 3013, fmt.Sprintf("Platform mismatch error for %s - failing immediately: %v", image, err))
-					return fmt.Errorf("platform mismatch or manifest not found for %s: %w", image, err)
-				}
-				
-				// For other errors, retry up to maxRetries times
-				if retryCount >= maxRetries {
-					log.Error(logActionPull, logInfoHuskyDocker, 3013, fmt.Sprintf("Failed to pull image %s (attempt %d/%d): %v", image, retryCount, maxRetries, err))
-					return fmt.Errorf("failed to pull image %s after %d attempts: %w", image, maxRetries, err)
-				}
-				
-				log.Info(logActionPull, logInfoHuskyDocker, 31, fmt.Sprintf("Failed to pull image %s (attempt %d/%d), retrying in 15 seconds...", image, retryCount, maxRetries))
-				continue
+
+			// For other errors, retry up to maxRetries times
+			if retryCount >= maxRetries {
+				log.Error(logActionPull, logInfoHuskyDocker, 3013, fmt.Sprintf("Failed to pull image %s (attempt %d/%d): %v", image, retryCount, maxRetries, err))
+				return fmt.Errorf("failed to pull image %s after %d attempts: %w", image, maxRetries, err)
 			}
-			
+
+			log.Info(logActionPull, logInfoHuskyDocker, 31, fmt.Sprintf("Failed to pull image %s (attempt %d/%d), retrying in %s...", image, retryCount, maxRetries, PullRetryInterval))
+		} else {
 			// Pull succeeded, verify image is loaded
 			if d.ImageIsLoaded(image) {
 				log.Info(logActionPull, logInfoHuskyDocker, 35, fmt.Sprintf("Successfully pulled and loaded image: %s", image))
 				return nil
 			}
-			
+
 			// Pull reported success but image not loaded - retry
 			retryCount++
 			if retryCount >= maxRetries {
@@ -294,5 +403,13 @@ func pullImage(d *Docker, canonicalURL, image string) error {
 			}
 			log.Info(logActionPull, logInfoHuskyDocker, 31, fmt.Sprintf("Pull succeeded but image not found, retrying for %s...", image))
 		}
+
+		select {
+		case <-timeout:
+			timeOutErr := fmt.Errorf("timeout after %s", PullTimeout)
+			log.Error(logActionPull, logInfoHuskyDocker, 3013, fmt.Sprintf("Image pull timeout for %s: %v", image, timeOutErr))
+			return timeOutErr
+		case <-time.After(PullRetryInterval):
+		}
 	}
 }
@@ -0,0 +1,86 @@
+package dockers
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// ImageGCResult is what a garbage collection of the images of a Docker API
+// host removed.
+type ImageGCResult struct {
+	ImagesRemoved int
+	ImagesPruned  int
+	Reclaimed     int64
+}
+
+// unreferencedImages holds since when each image found unreferenced by
+// CollectImages is, by Docker API host and image ID, so that an image is
+// only removed once it has stayed unreferenced for the protection window.
+var unreferencedImages = struct {
+	sync.Mutex
+	since map[string]time.Time
+}{since: map[string]time.Time{}}
+
+// CollectImages removes from dockerHost the images of repositories none of
+// the references of keep uses anymore, once they have been found so for
+// longer than protection, and the dangling images older than protection.
+// An image a container still uses is kept until the next collection.
+func CollectImages(dockerHost string, repositories, keep map[string]bool, protection time.Duration) (ImageGCResult, error) {
+	result := ImageGCResult{}
+	d, err := Open(dockerHost)
+	if err != nil {
+		return result, err
+	}
+	images, err := d.UnreferencedImages(repositories, keep)
+	if err != nil {
+		return result, err
+	}
+
+	now := time.Now()
+	expired := []int{}
+	unreferencedImages.Lock()
+	found := map[string]bool{}
+	for i, img := range images {
+		key := dockerHost + "|" + img.ID
+		found[key] = true
+		since, ok := unreferencedImages.since[key]
+		if !ok {
+			unreferencedImages.since[key] = now
+			since = now
+		}
+		if now.Sub(since) >= protection {
+			expired = append(expired, i)
+		}
+	}
+	for key := range unreferencedImages.since {
+		if strings.HasPrefix(key, dockerHost+"|") && !found[key] {
+			delete(unreferencedImages.since, key)
+		}
+	}
+	unreferencedImages.Unlock()
+
+	var lastErr error
+	for _, i := range expired {
+		removed, err := d.RemoveUnusedImage(images[i])
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if removed {
+			result.ImagesRemoved++
+			result.Reclaimed += images[i].Size
+			unreferencedImages.Lock()
+			delete(unreferencedImages.since, dockerHost+"|"+images[i].ID)
+			unreferencedImages.Unlock()
+		}
+	}
+
+	pruned, reclaimed, err := d.PruneDanglingImages(now.Add(-protection))
+	if err != nil {
+		return result, err
+	}
+	result.ImagesPruned = pruned
+	result.Reclaimed += reclaimed
+	return result, lastErr
+}
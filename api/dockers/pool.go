@@ -0,0 +1,382 @@
+package dockers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	dockerTypes "github.com/docker/docker/api/types"
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/debugtrace"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/spool"
+	goContext "golang.org/x/net/context"
+)
+
+const logActionWarmPool = "WarmPool"
+
+// WarmPoolLabel is set on the containers of the warm pool, which run the jobs
+// of securityTests through exec instead of running a single command.
+const WarmPoolLabel = "huskyci.warm"
+
+// JobPath is the working and home directory of a job run in a warm container.
+// It is created empty before the job and removed with the rest of /tmp after
+// it. The mounts of the container, such as the git cache, are shared by its
+// jobs, and a container whose filesystem a job changed elsewhere is removed
+// rather than reused.
+const JobPath = "/tmp/huskyci-job"
+
+// jobCleanupCmd kills every process a job left running, all of them but the
+// idle command of the container belonging to its user, and empties /tmp, and
+// so JobPath, once the job finished. Mounts are not crossed, so the git cache
+// and advisory databases are kept.
+const jobCleanupCmd = "kill -9 -1 2> /dev/null; find /tmp -xdev -mindepth 1 -delete 2> /dev/null; exit 0"
+
+// jobCleanupTimeout bounds how long jobCleanupCmd may take, in seconds.
+const jobCleanupTimeout = 60
+
+// errExecStart is returned by Exec when cmd could not be started.
+var errExecStart = errors.New("could not start exec")
+
+// warmContainer is a container of the warm pool. baseline holds the paths
+// its filesystem had changed once started, such as the mount points of its
+// volumes, which its jobs are not blamed for.
+type warmContainer struct {
+	docker    Docker
+	created   time.Time
+	idleSince time.Time
+	baseline  map[string]bool
+}
+
+// warmPool holds the idle warm containers, by pool key. A container is taken
+// out of it while it runs a job, so it runs one job at a time.
+var warmPool = struct {
+	sync.Mutex
+	idle map[string][]*warmContainer
+}{idle: map[string][]*warmContainer{}}
+
+// DockerRunWarm runs cmd like DockerRunWithVolume but in a container of the
+// warm pool of image: an idle one if there is one, or a new one otherwise. The
+// container is kept running once the job finished, to run the next job of the
+// same image, options and Docker API host without being created and started
+// again. Each job runs in an empty JobPath. Containers copying a workspace,
+// Windows containers, jobs without a timeout or as long as the MaxLifetime of
+// the pool and a disabled pool fall back to DockerRunWithVolume.
+func DockerRunWarm(image, imageTag, cmd, dockerHost string, timeOutInSeconds int, options ContainerOptions) (string, string, error) {
+	config := warmPoolConfig()
+	// the container must live long enough to run the whole job
+	jobDuration := time.Duration(timeOutInSeconds) * time.Second
+	if config == nil || options.Workspace != "" || timeOutInSeconds <= 0 || jobDuration >= config.MaxLifetime {
+		return DockerRunWithVolume(image, imageTag, cmd, dockerHost, "", timeOutInSeconds, options)
+	}
+
+	d, err := Open(dockerHost)
+	if err != nil {
+		return "", "", err
+	}
+	if err := d.DetectOS(); err != nil {
+		return "", "", err
+	}
+	if d.IsWindows() {
+		return DockerRunWithVolume(image, imageTag, cmd, dockerHost, "", timeOutInSeconds, options)
+	}
+	fullContainerImage, err := loadImage(d, image, imageTag, dockerHost, options.RID)
+	if err != nil {
+		return "", "", err
+	}
+
+	key := poolKey(dockerHost, fullContainerImage, options)
+	jobCmd := fmt.Sprintf("mkdir -p %s && cd %s || exit 1\n%s", JobPath, JobPath, cmd)
+	jobEnv := []string{"HOME=" + JobPath}
+	w := takeWarmContainer(key, time.Now().Add(jobDuration-config.MaxLifetime))
+	started := time.Now()
+	var cOutput string
+	if w != nil {
+		debugtrace.Record(debugtrace.Lifecycle, "docker", "warm.job", options.RID, "host", dockerHost, "image", fullContainerImage, "cid", w.docker.CID)
+		cOutput, err = w.docker.Exec(jobCmd, jobEnv, timeOutInSeconds, options.MaxOutputSize)
+		if errors.Is(err, errExecStart) {
+			// the idle container is gone, such as after a restart of the daemon
+			log.Error(logActionWarmPool, logInfoHuskyDocker, 3034, w.docker.CID, err)
+			w.docker.removeWarmContainer()
+			w = nil
+		}
+	}
+	if w == nil {
+		if w, err = startWarmContainer(d, fullContainerImage, config, options); err != nil {
+			return "", "", err
+		}
+		debugtrace.Record(debugtrace.Lifecycle, "docker", "warm.job", options.RID, "host", dockerHost, "image", fullContainerImage, "cid", w.docker.CID)
+		started = time.Now()
+		cOutput, err = w.docker.Exec(jobCmd, jobEnv, timeOutInSeconds, options.MaxOutputSize)
+	}
+	if err != nil {
+		log.Error(logActionWarmPool, logInfoHuskyDocker, 3034, w.docker.CID, err)
+		debugtrace.Record(debugtrace.Lifecycle, "docker", "warm.failed", options.RID, "cid", w.docker.CID, "duration", time.Since(started), "error", err)
+		if errors.Is(err, ErrTimeout) {
+			// the job may still be running, so the container can not be reused
+			w.docker.removeWarmContainer()
+			return w.docker.CID, cOutput, err
+		}
+		giveBackWarmContainer(key, w, config.Size)
+		return "", "", err
+	}
+	debugtrace.Record(debugtrace.Lifecycle, "docker", "warm.exit", options.RID, "cid", w.docker.CID, "duration", time.Since(started))
+	log.Info(logActionRun, logInfoHuskyDocker, 34, fullContainerImage, w.docker.CID)
+
+	giveBackWarmContainer(key, w, config.Size)
+	return w.docker.CID, cOutput, nil
+}
+
+// warmPoolConfig returns the configuration of the warm pool, or nil if it is
+// disabled.
+func warmPoolConfig() *apiContext.WarmPoolConfig {
+	if apiContext.APIConfiguration == nil {
+		return nil
+	}
+	config := apiContext.APIConfiguration.WarmPoolConfig
+	if config == nil || config.Size <= 0 {
+		return nil
+	}
+	return config
+}
+
+// poolKey returns the key of the warm containers of image in dockerHost that
+// can run a job with options: the ones created with the same options.
+func poolKey(dockerHost, image string, options ContainerOptions) string {
+	return fmt.Sprintf("%s|%s|%s|%q|%v|%t", dockerHost, image, options.NetworkMode, options.Env, options.Mounts, options.ImageUser)
+}
+
+// takeWarmContainer takes out of the pool the most recently idle container
+// of key created after createdAfter, if there is one.
+func takeWarmContainer(key string, createdAfter time.Time) *warmContainer {
+	warmPool.Lock()
+	defer warmPool.Unlock()
+	idle := warmPool.idle[key]
+	for i := len(idle) - 1; i >= 0; i-- {
+		if idle[i].created.After(createdAfter) {
+			w := idle[i]
+			warmPool.idle[key] = append(idle[:i:i], idle[i+1:]...)
+			return w
+		}
+	}
+	return nil
+}
+
+// startWarmContainer creates and starts a warm container of image. It sleeps
+// for the MaxLifetime of the pool, so that it exits, and is then removed by
+// the janitor, if the API stops before reaping it.
+func startWarmContainer(d *Docker, image string, config *apiContext.WarmPoolConfig, options ContainerOptions) (*warmContainer, error) {
+	options.Labels = map[string]string{WarmPoolLabel: "true"}
+	idleCmd := fmt.Sprintf("sleep %d", int(config.MaxLifetime.Seconds()))
+	CID, err := d.CreateContainerWithVolume(image, idleCmd, "", options)
+	if err != nil {
+		return nil, err
+	}
+	w := &warmContainer{docker: *d, created: time.Now()}
+	w.docker.CID = CID
+	if err := w.docker.StartContainer(); err != nil {
+		log.Error(logActionWarmPool, logInfoHuskyDocker, 3015, err)
+		w.docker.removeWarmContainer()
+		return nil, err
+	}
+	changes, err := w.docker.client.ContainerDiff(goContext.Background(), CID)
+	if err != nil {
+		log.Error(logActionWarmPool, logInfoHuskyDocker, 3034, CID, err)
+		w.docker.removeWarmContainer()
+		return nil, err
+	}
+	w.baseline = map[string]bool{}
+	for _, change := range changes {
+		w.baseline[change.Path] = true
+	}
+	log.Info(logActionWarmPool, logInfoHuskyDocker, 32, image, CID)
+	return w, nil
+}
+
+// changedOutsideTmp returns the first path outside /tmp the filesystem of w
+// changed since it started, or an empty string if there is none.
+func (w *warmContainer) changedOutsideTmp() (string, error) {
+	changes, err := w.docker.client.ContainerDiff(goContext.Background(), w.docker.CID)
+	if err != nil {
+		return "", err
+	}
+	for _, change := range changes {
+		if change.Path == "/tmp" || strings.HasPrefix(change.Path, "/tmp/") || w.baseline[change.Path] {
+			continue
+		}
+		return change.Path, nil
+	}
+	return "", nil
+}
+
+// giveBackWarmContainer kills the processes left by the job of w, empties its
+// /tmp and puts it back in the pool of key. w is removed instead if the pool
+// already holds size idle containers of key, if it could not be cleaned up or
+// if the job changed its filesystem outside /tmp.
+func giveBackWarmContainer(key string, w *warmContainer, size int) {
+	if _, err := w.docker.Exec(jobCleanupCmd, nil, jobCleanupTimeout, 0); err != nil {
+		log.Error(logActionWarmPool, logInfoHuskyDocker, 3034, w.docker.CID, err)
+		w.docker.removeWarmContainer()
+		return
+	}
+	changed, err := w.changedOutsideTmp()
+	if err != nil {
+		log.Error(logActionWarmPool, logInfoHuskyDocker, 3034, w.docker.CID, err)
+		w.docker.removeWarmContainer()
+		return
+	}
+	if changed != "" {
+		log.Warning(logActionWarmPool, logInfoHuskyDocker, 134, w.docker.CID, changed)
+		w.docker.removeWarmContainer()
+		return
+	}
+	warmPool.Lock()
+	if len(warmPool.idle[key]) >= size {
+		warmPool.Unlock()
+		w.docker.removeWarmContainer()
+		return
+	}
+	w.idleSince = time.Now()
+	warmPool.idle[key] = append(warmPool.idle[key], w)
+	warmPool.Unlock()
+}
+
+// ReapWarmContainers removes the warm containers idle since before
+// idleBefore or created before createdBefore, and returns how many were
+// removed.
+func ReapWarmContainers(idleBefore, createdBefore time.Time) int {
+	reaped := []*warmContainer{}
+	warmPool.Lock()
+	for key, idle := range warmPool.idle {
+		kept := idle[:0]
+		for _, w := range idle {
+			if w.idleSince.Before(idleBefore) || w.created.Before(createdBefore) {
+				reaped = append(reaped, w)
+				continue
+			}
+			kept = append(kept, w)
+		}
+		if len(kept) == 0 {
+			delete(warmPool.idle, key)
+		} else {
+			warmPool.idle[key] = kept
+		}
+	}
+	warmPool.Unlock()
+
+	for _, w := range reaped {
+		w.docker.removeWarmContainer()
+	}
+	return len(reaped)
+}
+
+// ScheduleWarmPoolReaper reaps the warm containers idle for longer than the
+// IdleTimeout of config or older than its MaxLifetime every minute. It never
+// returns and is meant to run in its own goroutine.
+func ScheduleWarmPoolReaper(config *apiContext.WarmPoolConfig) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		now := time.Now()
+		if reaped := ReapWarmContainers(now.Add(-config.IdleTimeout), now.Add(-config.MaxLifetime)); reaped > 0 {
+			log.Info(logActionWarmPool, logInfoHuskyDocker, 67, reaped)
+		}
+	}
+}
+
+// removeWarmContainer kills and removes the warm container of d, which keeps
+// running between jobs.
+func (d Docker) removeWarmContainer() {
+	ctx := goContext.Background()
+	if err := d.client.ContainerRemove(ctx, d.CID, dockerTypes.ContainerRemoveOptions{Force: true, RemoveVolumes: true}); err != nil {
+		log.Error(logActionWarmPool, logInfoAPI, 3023, err)
+	}
+}
+
+// Exec runs cmd in the running container of d, with env added to its
+// environment, and returns its output, cut at limit bytes if limit is
+// positive. It returns ErrTimeout, with the output read so far, once cmd ran
+// for timeOutInSeconds, if it is positive, and an error if cmd exits with a
+// status code other than 0.
+func (d Docker) Exec(cmd string, env []string, timeOutInSeconds int, limit int64) (string, error) {
+	ctx := goContext.Background()
+	execConfig := dockerTypes.ExecConfig{
+		Tty:          true,
+		AttachStdout: true,
+		AttachStderr: true,
+		Env:          env,
+		Cmd:          d.shellCommand(cmd),
+	}
+	created, err := d.client.ContainerExecCreate(ctx, d.CID, execConfig)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errExecStart, err)
+	}
+	attached, err := d.client.ContainerExecAttach(ctx, created.ID, dockerTypes.ExecStartCheck{Tty: true})
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errExecStart, err)
+	}
+	defer attached.Close()
+
+	type capture struct {
+		output *spool.File
+		err    error
+	}
+	captured := make(chan capture, 1)
+	go func() {
+		output, err := spool.Capture(attached.Reader, limit)
+		// a cut output leaves the rest of it, which cmd waits to write
+		io.Copy(io.Discard, attached.Reader)
+		captured <- capture{output, err}
+	}()
+
+	var timeout <-chan time.Time
+	if timeOutInSeconds > 0 {
+		timer := time.NewTimer(time.Duration(timeOutInSeconds) * time.Second)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	var result capture
+	timedOut := false
+	select {
+	case result = <-captured:
+	case <-timeout:
+		// stop reading, keeping what cmd printed so far
+		timedOut = true
+		attached.Close()
+		result = <-captured
+	}
+	output := ""
+	if result.output != nil {
+		defer result.output.Close()
+		if result.output.Truncated {
+			log.Warning("Exec", logInfoAPI, 127, d.CID, limit)
+		}
+		if output, err = result.output.String(); err != nil {
+			return "", err
+		}
+	}
+	if timedOut {
+		return output, fmt.Errorf("%w after %d seconds", ErrTimeout, timeOutInSeconds)
+	}
+	if result.err != nil {
+		log.Error("Exec", logInfoAPI, 3007, result.err)
+		return "", result.err
+	}
+
+	inspect, err := d.client.ContainerExecInspect(ctx, created.ID)
+	// the output may end right before the exec is seen as finished
+	for tries := 0; err == nil && inspect.Running && tries < 10; tries++ {
+		time.Sleep(100 * time.Millisecond)
+		inspect, err = d.client.ContainerExecInspect(ctx, created.ID)
+	}
+	if err != nil {
+		return "", err
+	}
+	if inspect.ExitCode != 0 {
+		return "", fmt.Errorf("Error in POST to wait the container with statusCode %d", inspect.ExitCode)
+	}
+	return output, nil
+}
@@ -0,0 +1,74 @@
+package dockers
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	dockerTypes "github.com/docker/docker/api/types"
+	goContext "golang.org/x/net/context"
+)
+
+// WorkspacePath is where the code of an analysis is found in its containers.
+const WorkspacePath = "/workspace"
+
+// CopyDirectory copies the regular files and directories of dir, a directory
+// of the API, to containerPath in the container of d, which must not be
+// running yet. containerPath must be a volume of the container, as the root
+// filesystem of a hardened container is read-only.
+func (d Docker) CopyDirectory(dir, containerPath string) error {
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(tarDirectory(dir, containerPath, writer))
+	}()
+	defer reader.Close()
+	ctx := goContext.Background()
+	return d.client.CopyToContainer(ctx, d.CID, "/", reader, dockerTypes.CopyToContainerOptions{})
+}
+
+// tarDirectory writes to w a tar archive of the regular files and directories
+// of dir, named after their path in dir joined to prefix.
+func tarDirectory(dir, prefix string, w io.Writer) error {
+	tw := tar.NewWriter(w)
+	err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() && !info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = path.Join(strings.TrimPrefix(prefix, "/"), filepath.ToSlash(rel))
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		header.Uid, header.Gid, header.Uname, header.Gname = 0, 0, "", ""
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
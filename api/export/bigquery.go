@@ -0,0 +1,226 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBigQueryEndpoint is the API of BigQuery, used when no endpoint is
+// configured.
+const DefaultBigQueryEndpoint = "https://bigquery.googleapis.com"
+
+const bigQueryScope = "https://www.googleapis.com/auth/bigquery.insertdata"
+
+// BigQueryConfig is the table findings are exported to. CredentialsFile is
+// the JSON key of the service account inserting the rows.
+type BigQueryConfig struct {
+	Project         string
+	Dataset         string
+	Table           string
+	CredentialsFile string
+	Endpoint        string
+}
+
+// BigQuery streams records into a BigQuery table, each record being a row
+// whose columns are the JSON fields of Record.
+type BigQuery struct {
+	Config BigQueryConfig
+	Client *http.Client
+	now    func() time.Time
+
+	mutex       sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// NewBigQuery returns a BigQuery sink based on config.
+func NewBigQuery(config BigQueryConfig) *BigQuery {
+	if config.Endpoint == "" {
+		config.Endpoint = DefaultBigQueryEndpoint
+	}
+	return &BigQuery{Config: config, Client: &http.Client{Timeout: time.Minute}, now: time.Now}
+}
+
+// Name returns the name of the sink.
+func (b *BigQuery) Name() string {
+	return SinkBigQuery
+}
+
+type insertAllResponse struct {
+	InsertErrors []struct {
+		Index  int `json:"index"`
+		Errors []struct {
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	} `json:"insertErrors"`
+}
+
+// Export inserts records as rows. Each row is inserted with the ID of its
+// record, which BigQuery uses to drop the rows of a batch sent twice.
+func (b *BigQuery) Export(ctx context.Context, records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	rows := make([]map[string]interface{}, 0, len(records))
+	for _, record := range records {
+		rows = append(rows, map[string]interface{}{"insertId": record.ID, "json": record})
+	}
+	payload, err := json.Marshal(map[string]interface{}{"kind": "bigquery#tableDataInsertAllRequest", "rows": rows})
+	if err != nil {
+		return err
+	}
+	token, err := b.token(ctx)
+	if err != nil {
+		return err
+	}
+	endpoint := fmt.Sprintf("%s/bigquery/v2/projects/%s/datasets/%s/tables/%s/insertAll", strings.TrimSuffix(b.Config.Endpoint, "/"),
+		url.PathEscape(b.Config.Project), url.PathEscape(b.Config.Dataset), url.PathEscape(b.Config.Table))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := b.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("bigquery answered %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	result := insertAllResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	if len(result.InsertErrors) == 0 {
+		return nil
+	}
+	reason := ""
+	if len(result.InsertErrors[0].Errors) > 0 {
+		reason = fmt.Sprintf("%s: %s", result.InsertErrors[0].Errors[0].Reason, result.InsertErrors[0].Errors[0].Message)
+	}
+	return fmt.Errorf("bigquery could not insert %d findings: %s", len(result.InsertErrors), reason)
+}
+
+// serviceAccount is the part of the JSON key of a service account used to
+// get access tokens.
+type serviceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// token returns an access token of the service account of CredentialsFile,
+// asking a new one a minute before the last one expires. It returns an empty
+// token if there is no CredentialsFile, such as behind an authenticating
+// proxy.
+func (b *BigQuery) token(ctx context.Context) (string, error) {
+	if b.Config.CredentialsFile == "" {
+		return "", nil
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.accessToken != "" && b.now().Before(b.expiresAt.Add(-time.Minute)) {
+		return b.accessToken, nil
+	}
+
+	content, err := os.ReadFile(b.Config.CredentialsFile)
+	if err != nil {
+		return "", err
+	}
+	account := serviceAccount{}
+	if err := json.Unmarshal(content, &account); err != nil {
+		return "", fmt.Errorf("invalid service account key: %w", err)
+	}
+	assertion, err := signJWT(account, b.now())
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{}
+	form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+	form.Set("assertion", assertion)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := b.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("could not get a bigquery access token, %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	result := struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	b.accessToken = result.AccessToken
+	b.expiresAt = b.now().Add(time.Duration(result.ExpiresIn) * time.Second)
+	return b.accessToken, nil
+}
+
+// signJWT returns the JWT account asks an access token to insert rows with,
+// signed with its private key.
+func signJWT(account serviceAccount, now time.Time) (string, error) {
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil {
+		return "", errors.New("invalid service account private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return "", err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", errors.New("service account private key is not an RSA key")
+	}
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   account.ClientEmail,
+		"scope": bigQueryScope,
+		"aud":   account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
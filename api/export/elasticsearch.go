@@ -0,0 +1,112 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ElasticsearchConfig is the index of an Elasticsearch cluster findings are
+// exported to. APIKey, if set, is used instead of Username and Password.
+type ElasticsearchConfig struct {
+	URL      string
+	Index    string
+	Username string
+	Password string
+	APIKey   string
+}
+
+// Elasticsearch indexes records with the bulk API of Elasticsearch.
+type Elasticsearch struct {
+	Config ElasticsearchConfig
+	Client *http.Client
+}
+
+// NewElasticsearch returns an Elasticsearch sink based on config.
+func NewElasticsearch(config ElasticsearchConfig) *Elasticsearch {
+	if config.Index == "" {
+		config.Index = DefaultIndex
+	}
+	return &Elasticsearch{Config: config, Client: &http.Client{Timeout: time.Minute}}
+}
+
+// Name returns the name of the sink.
+func (e *Elasticsearch) Name() string {
+	return SinkElasticsearch
+}
+
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+// Export indexes records, each one under its ID so that a batch sent twice
+// replaces the documents it already indexed.
+func (e *Elasticsearch) Export(ctx context.Context, records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	body := &bytes.Buffer{}
+	encoder := json.NewEncoder(body)
+	for _, record := range records {
+		action := map[string]interface{}{"index": map[string]string{"_index": e.Config.Index, "_id": record.ID}}
+		if err := encoder.Encode(action); err != nil {
+			return err
+		}
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(e.Config.URL, "/")+"/_bulk", body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	switch {
+	case e.Config.APIKey != "":
+		req.Header.Set("Authorization", "ApiKey "+e.Config.APIKey)
+	case e.Config.Username != "":
+		req.SetBasicAuth(e.Config.Username, e.Config.Password)
+	}
+	resp, err := e.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("elasticsearch answered %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	result := bulkResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	if !result.Errors {
+		return nil
+	}
+	failed := 0
+	reason := ""
+	for _, item := range result.Items {
+		for _, outcome := range item {
+			if outcome.Status/100 == 2 {
+				continue
+			}
+			failed++
+			if reason == "" {
+				reason = fmt.Sprintf("%s: %s", outcome.Error.Type, outcome.Error.Reason)
+			}
+		}
+	}
+	return fmt.Errorf("elasticsearch could not index %d findings: %s", failed, reason)
+}
@@ -0,0 +1,251 @@
+// Package export ships the findings of finished analyses to the sinks
+// configured, S3 as JSON lines, a BigQuery table or an Elasticsearch index,
+// for analytics across every repository. Findings are queued, sent in batches
+// and sent again when a sink fails.
+package export
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/storage"
+	"github.com/huskyci-org/huskyCI/api/types"
+)
+
+const logInfoExport = "EXPORT"
+
+// Sinks findings can be exported to.
+const (
+	SinkS3            = "s3"
+	SinkBigQuery      = "bigquery"
+	SinkElasticsearch = "elasticsearch"
+)
+
+// Defaults used when the configuration does not set them.
+const (
+	DefaultBatchSize     = 500
+	DefaultFlushInterval = 30 * time.Second
+	DefaultMaxRetries    = 5
+	DefaultRetryBackoff  = time.Second
+	DefaultIndex         = "huskyci-findings"
+)
+
+// queuedBatches is how many batches of findings are queued before the
+// findings of new analyses are dropped.
+const queuedBatches = 20
+
+// Config represents the export configuration. No Sinks disables the export.
+type Config struct {
+	Sinks         []string
+	BatchSize     int
+	FlushInterval time.Duration
+	MaxRetries    int
+	RetryBackoff  time.Duration
+	S3            *storage.Config
+	BigQuery      BigQueryConfig
+	Elasticsearch ElasticsearchConfig
+}
+
+// Record is a finding of an analysis, flattened with the analysis it was
+// found by so that each one can be stored on its own.
+type Record struct {
+	ID            string    `json:"id"`
+	RID           string    `json:"RID"`
+	RepositoryURL string    `json:"repositoryURL"`
+	Branch        string    `json:"branch"`
+	Commit        string    `json:"commit,omitempty"`
+	Result        string    `json:"result"`
+	FinishedAt    time.Time `json:"finishedAt"`
+	SecurityTool  string    `json:"securityTool"`
+	Rule          string    `json:"rule,omitempty"`
+	Language      string    `json:"language,omitempty"`
+	Severity      string    `json:"severity"`
+	Confidence    string    `json:"confidence,omitempty"`
+	Title         string    `json:"title,omitempty"`
+	Details       string    `json:"details,omitempty"`
+	File          string    `json:"file,omitempty"`
+	Line          string    `json:"line,omitempty"`
+	Package       string    `json:"package,omitempty"`
+	Version       string    `json:"version,omitempty"`
+	FixVersion    string    `json:"fixVersion,omitempty"`
+	Author        string    `json:"author,omitempty"`
+	AuthorEmail   string    `json:"authorEmail,omitempty"`
+	Fingerprint   string    `json:"fingerprint,omitempty"`
+}
+
+// Records returns a Record of each one of findings of analysis. The ID of a
+// Record is made of the RID and of the position of the finding, so that a
+// sink storing a batch twice keeps a single copy of each finding.
+func Records(analysis types.Analysis, findings []types.HuskyCIVulnerability) []Record {
+	records := make([]Record, 0, len(findings))
+	for i, finding := range findings {
+		records = append(records, Record{
+			ID:            fmt.Sprintf("%s-%d", analysis.RID, i),
+			RID:           analysis.RID,
+			RepositoryURL: analysis.URL,
+			Branch:        analysis.Branch,
+			Commit:        analysis.Commit,
+			Result:        analysis.Result,
+			FinishedAt:    analysis.FinishedAt,
+			SecurityTool:  finding.SecurityTool,
+			Rule:          finding.Rule,
+			Language:      finding.Language,
+			Severity:      finding.Severity,
+			Confidence:    finding.Confidence,
+			Title:         finding.Title,
+			Details:       finding.Details,
+			File:          finding.File,
+			Line:          finding.Line,
+			Package:       finding.Package,
+			Version:       finding.Version,
+			FixVersion:    finding.FixVersion,
+			Author:        finding.Author,
+			AuthorEmail:   finding.AuthorEmail,
+			Fingerprint:   finding.Fingerprint,
+		})
+	}
+	return records
+}
+
+// Sink stores batches of records. As a batch is sent again when Export
+// fails, Export must not store a record twice.
+type Sink interface {
+	Name() string
+	Export(ctx context.Context, records []Record) error
+}
+
+// Exporter queues the records of finished analyses and ships them in batches
+// to every sink.
+type Exporter struct {
+	Sinks         []Sink
+	BatchSize     int
+	FlushInterval time.Duration
+	MaxRetries    int
+	RetryBackoff  time.Duration
+	queue         chan Record
+}
+
+// New returns the Exporter of the sinks configured, or nil if there are none.
+func New(config *Config) (*Exporter, error) {
+	if config == nil || len(config.Sinks) == 0 {
+		return nil, nil
+	}
+	sinks := []Sink{}
+	for _, name := range config.Sinks {
+		switch strings.ToLower(name) {
+		case SinkS3:
+			if config.S3 == nil {
+				return nil, fmt.Errorf("s3 export requires a bucket")
+			}
+			objectStorage, err := storage.New(config.S3)
+			if err != nil {
+				return nil, err
+			}
+			sinks = append(sinks, &S3{Storage: objectStorage})
+		case SinkBigQuery:
+			if config.BigQuery.Project == "" || config.BigQuery.Dataset == "" || config.BigQuery.Table == "" {
+				return nil, fmt.Errorf("bigquery export requires a project, a dataset and a table")
+			}
+			sinks = append(sinks, NewBigQuery(config.BigQuery))
+		case SinkElasticsearch:
+			if config.Elasticsearch.URL == "" {
+				return nil, fmt.Errorf("elasticsearch export requires a URL")
+			}
+			sinks = append(sinks, NewElasticsearch(config.Elasticsearch))
+		default:
+			return nil, fmt.Errorf("unsupported export sink: %s", name)
+		}
+	}
+	return NewExporter(sinks, config.BatchSize, config.FlushInterval, config.MaxRetries, config.RetryBackoff), nil
+}
+
+// NewExporter returns an Exporter of sinks. Zero values are replaced by
+// their defaults.
+func NewExporter(sinks []Sink, batchSize int, flushInterval time.Duration, maxRetries int, retryBackoff time.Duration) *Exporter {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	if flushInterval <= 0 {
+		flushInterval = DefaultFlushInterval
+	}
+	if maxRetries < 0 {
+		maxRetries = DefaultMaxRetries
+	}
+	if retryBackoff <= 0 {
+		retryBackoff = DefaultRetryBackoff
+	}
+	return &Exporter{
+		Sinks:         sinks,
+		BatchSize:     batchSize,
+		FlushInterval: flushInterval,
+		MaxRetries:    maxRetries,
+		RetryBackoff:  retryBackoff,
+		queue:         make(chan Record, batchSize*queuedBatches),
+	}
+}
+
+// Enqueue queues records to be exported and returns how many of them were
+// dropped because the queue is full.
+func (e *Exporter) Enqueue(records []Record) int {
+	for i, record := range records {
+		select {
+		case e.queue <- record:
+		default:
+			return len(records) - i
+		}
+	}
+	return 0
+}
+
+// Run ships the queued records to every sink, once BatchSize of them are
+// queued or every FlushInterval. It never returns and is meant to run in its
+// own goroutine.
+func (e *Exporter) Run() {
+	ticker := time.NewTicker(e.FlushInterval)
+	defer ticker.Stop()
+	batch := []Record{}
+	for {
+		select {
+		case record := <-e.queue:
+			batch = append(batch, record)
+			if len(batch) < e.BatchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+		e.flush(batch)
+		batch = []Record{}
+	}
+}
+
+// flush sends batch to every sink, each one MaxRetries more times at most.
+func (e *Exporter) flush(batch []Record) {
+	for _, sink := range e.Sinks {
+		if err := e.send(sink, batch); err != nil {
+			log.Error("Export", logInfoExport, 1066, sink.Name(), len(batch), err)
+			continue
+		}
+		log.Info("Export", logInfoExport, 55, sink.Name(), len(batch))
+	}
+}
+
+// send exports batch to sink, waiting twice as long before each new attempt.
+func (e *Exporter) send(sink Sink, batch []Record) error {
+	backoff := e.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		err := sink.Export(ctx, batch)
+		cancel()
+		if err == nil || attempt >= e.MaxRetries {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
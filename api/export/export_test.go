@@ -0,0 +1,229 @@
+package export_test
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/huskyci-org/huskyCI/api/export"
+	"github.com/huskyci-org/huskyCI/api/types"
+)
+
+// fakeSink records the batches it receives and fails the first failures
+// times it is called.
+type fakeSink struct {
+	mu       sync.Mutex
+	failures int
+	calls    int
+	batches  [][]export.Record
+}
+
+func (s *fakeSink) Name() string {
+	return "fake"
+}
+
+func (s *fakeSink) Export(ctx context.Context, records []export.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	if s.calls <= s.failures {
+		return errors.New("sink down")
+	}
+	s.batches = append(s.batches, records)
+	return nil
+}
+
+func (s *fakeSink) received() ([][]export.Record, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.batches, s.calls
+}
+
+func records(n int) []export.Record {
+	analysis := types.Analysis{RID: "rid", URL: "https://github.com/org/repo.git", Branch: "main", Result: "failed"}
+	findings := []types.HuskyCIVulnerability{}
+	for i := 0; i < n; i++ {
+		findings = append(findings, types.HuskyCIVulnerability{SecurityTool: "GoSec", Severity: "High", File: "main.go"})
+	}
+	return export.Records(analysis, findings)
+}
+
+var _ = Describe("Export", func() {
+	Describe("Records", func() {
+		It("Should flatten each finding with its analysis under a distinct ID", func() {
+			result := records(2)
+			Expect(result).To(HaveLen(2))
+			Expect(result[0].ID).To(Equal("rid-0"))
+			Expect(result[1].ID).To(Equal("rid-1"))
+			Expect(result[1].RepositoryURL).To(Equal("https://github.com/org/repo.git"))
+			Expect(result[1].SecurityTool).To(Equal("GoSec"))
+		})
+	})
+
+	Describe("New", func() {
+		Context("When no sink is configured", func() {
+			It("Should return a nil Exporter and a nil error", func() {
+				exporter, err := export.New(&export.Config{})
+				Expect(exporter).To(BeNil())
+				Expect(err).To(BeNil())
+			})
+		})
+		Context("When a sink is unknown", func() {
+			It("Should return an error", func() {
+				_, err := export.New(&export.Config{Sinks: []string{"kafka"}})
+				Expect(err).To(HaveOccurred())
+			})
+		})
+		Context("When the bigquery sink has no table", func() {
+			It("Should return an error", func() {
+				_, err := export.New(&export.Config{Sinks: []string{"bigquery"}, BigQuery: export.BigQueryConfig{Project: "p", Dataset: "d"}})
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("Exporter", func() {
+		Context("When BatchSize records are queued", func() {
+			It("Should export them in one batch without waiting", func() {
+				sink := &fakeSink{}
+				exporter := export.NewExporter([]export.Sink{sink}, 3, time.Hour, 0, time.Millisecond)
+				go exporter.Run()
+				Expect(exporter.Enqueue(records(3))).To(Equal(0))
+				Eventually(func() int {
+					batches, _ := sink.received()
+					return len(batches)
+				}).Should(Equal(1))
+				batches, _ := sink.received()
+				Expect(batches[0]).To(HaveLen(3))
+			})
+		})
+		Context("When fewer records are queued", func() {
+			It("Should export them after FlushInterval", func() {
+				sink := &fakeSink{}
+				exporter := export.NewExporter([]export.Sink{sink}, 100, 20*time.Millisecond, 0, time.Millisecond)
+				go exporter.Run()
+				exporter.Enqueue(records(2))
+				Eventually(func() int {
+					batches, _ := sink.received()
+					return len(batches)
+				}).Should(Equal(1))
+			})
+		})
+		Context("When a sink fails", func() {
+			It("Should send the batch again up to MaxRetries times", func() {
+				sink := &fakeSink{failures: 2}
+				exporter := export.NewExporter([]export.Sink{sink}, 1, time.Hour, 2, time.Millisecond)
+				go exporter.Run()
+				exporter.Enqueue(records(1))
+				Eventually(func() int {
+					batches, _ := sink.received()
+					return len(batches)
+				}).Should(Equal(1))
+				_, calls := sink.received()
+				Expect(calls).To(Equal(3))
+			})
+		})
+		Context("When the queue is full", func() {
+			It("Should return how many records were dropped", func() {
+				exporter := export.NewExporter([]export.Sink{&fakeSink{}}, 1, time.Hour, 0, time.Millisecond)
+				Expect(exporter.Enqueue(records(25))).To(Equal(5))
+			})
+		})
+	})
+
+	Describe("Elasticsearch", func() {
+		It("Should index each record under its ID with the bulk API", func() {
+			var lines []string
+			var authorization string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Expect(r.URL.Path).To(Equal("/_bulk"))
+				authorization = r.Header.Get("Authorization")
+				scanner := bufio.NewScanner(r.Body)
+				for scanner.Scan() {
+					lines = append(lines, scanner.Text())
+				}
+				io.WriteString(w, `{"errors":false,"items":[]}`)
+			}))
+			defer server.Close()
+
+			sink := export.NewElasticsearch(export.ElasticsearchConfig{URL: server.URL, APIKey: "key"})
+			Expect(sink.Export(context.Background(), records(2))).To(Succeed())
+			Expect(authorization).To(Equal("ApiKey key"))
+			Expect(lines).To(HaveLen(4))
+			Expect(lines[0]).To(Equal(`{"index":{"_id":"rid-0","_index":"huskyci-findings"}}`))
+			Expect(lines[3]).To(ContainSubstring(`"id":"rid-1"`))
+		})
+		It("Should return an error when a document is not indexed", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, `{"errors":true,"items":[{"index":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"bad line"}}}]}`)
+			}))
+			defer server.Close()
+
+			sink := export.NewElasticsearch(export.ElasticsearchConfig{URL: server.URL})
+			err := sink.Export(context.Background(), records(1))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("bad line"))
+		})
+	})
+
+	Describe("BigQuery", func() {
+		It("Should insert the records with an access token of the service account", func() {
+			var body map[string]interface{}
+			var authorization string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.URL.Path == "/token":
+					Expect(r.ParseForm()).To(Succeed())
+					Expect(strings.Count(r.PostForm.Get("assertion"), ".")).To(Equal(2))
+					io.WriteString(w, `{"access_token":"token","expires_in":3600}`)
+				case r.URL.Path == "/bigquery/v2/projects/p/datasets/d/tables/t/insertAll":
+					authorization = r.Header.Get("Authorization")
+					Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+					io.WriteString(w, `{"kind":"bigquery#tableDataInsertAllResponse"}`)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			key, err := rsa.GenerateKey(rand.Reader, 2048)
+			Expect(err).NotTo(HaveOccurred())
+			der, err := x509.MarshalPKCS8PrivateKey(key)
+			Expect(err).NotTo(HaveOccurred())
+			credentials, err := json.Marshal(map[string]string{
+				"client_email": "huskyci@project.iam.gserviceaccount.com",
+				"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+				"token_uri":    server.URL + "/token",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			dir, err := os.MkdirTemp("", "export")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(dir)
+			credentialsFile := filepath.Join(dir, "credentials.json")
+			Expect(os.WriteFile(credentialsFile, credentials, 0600)).To(Succeed())
+
+			sink := export.NewBigQuery(export.BigQueryConfig{Project: "p", Dataset: "d", Table: "t", CredentialsFile: credentialsFile, Endpoint: server.URL})
+			Expect(sink.Export(context.Background(), records(2))).To(Succeed())
+			Expect(authorization).To(Equal("Bearer token"))
+			rows := body["rows"].([]interface{})
+			Expect(rows).To(HaveLen(2))
+			Expect(rows[1].(map[string]interface{})["insertId"]).To(Equal("rid-1"))
+		})
+	})
+})
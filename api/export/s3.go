@@ -0,0 +1,50 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/huskyci-org/huskyCI/api/storage"
+)
+
+// S3 stores each batch of records as a JSON lines object of an object
+// storage, under findings/ and the day the first record was found.
+type S3 struct {
+	Storage storage.Storage
+}
+
+// Name returns the name of the sink.
+func (s *S3) Name() string {
+	return SinkS3
+}
+
+// Export stores records as one JSON lines object. The object is named after
+// the hash of its content, so a batch sent twice overwrites itself.
+func (s *S3) Export(ctx context.Context, records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	body, err := jsonLines(records)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(body)
+	key := fmt.Sprintf("findings/%s/%s.jsonl", records[0].FinishedAt.UTC().Format("2006/01/02"), hex.EncodeToString(sum[:16]))
+	return s.Storage.Put(key, bytes.NewReader(body), int64(len(body)))
+}
+
+// jsonLines returns records as JSON, one per line.
+func jsonLines(records []Record) ([]byte, error) {
+	body := &bytes.Buffer{}
+	encoder := json.NewEncoder(body)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return nil, err
+		}
+	}
+	return body.Bytes(), nil
+}
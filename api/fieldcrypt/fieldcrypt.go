@@ -0,0 +1,181 @@
+// Package fieldcrypt encrypts the sensitive fields the huskyCI API stores in
+// its database, such as the secrets of git credentials, status reporters and
+// remediations, with AES-256-GCM. Several keys can be configured at once so
+// that they can be rotated: values are encrypted with the current key and
+// decrypted with the key they were encrypted with.
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// prefix starts the values encrypted by a Keyring, followed by the ID of the
+// key they were encrypted with. Values without it were encrypted with the
+// legacy passphrase.
+const prefix = "v1:"
+
+// ErrNoKey is returned when no key is configured, so sensitive fields can
+// neither be stored nor read.
+var ErrNoKey = errors.New("no database encryption key is configured, set HUSKYCI_DB_ENCRYPTION_KEYS or HUSKYCI_GIT_CREDENTIALS_KEY")
+
+// ErrInvalidCiphertext is returned when a stored value can not be decrypted
+// with the configured keys.
+var ErrInvalidCiphertext = errors.New("invalid encrypted field")
+
+// Config holds the keys sensitive fields are encrypted with.
+type Config struct {
+	// Keys are comma separated id:key pairs, the keys being 32 bytes
+	// encoded in base64.
+	Keys string
+	// KeysFile is a file holding Keys, such as one mounted by a KMS or a
+	// secret manager. Its keys are added to Keys.
+	KeysFile string
+	// CurrentKeyID is the ID of the key values are encrypted with, the
+	// first of Keys by default.
+	CurrentKeyID string
+	// LegacyPassphrase is the passphrase values were encrypted with before
+	// keys were configured. It decrypts them, and encrypts new ones if no
+	// key is configured.
+	LegacyPassphrase string
+}
+
+// Keyring encrypts and decrypts values with the keys of a Config.
+type Keyring struct {
+	current string
+	keys    map[string]cipher.AEAD
+	legacy  cipher.AEAD
+}
+
+// NewKeyring returns the Keyring of config. A Keyring without any key is
+// returned as is: it fails with ErrNoKey.
+func NewKeyring(config Config) (*Keyring, error) {
+	keyring := &Keyring{keys: map[string]cipher.AEAD{}}
+	if config.LegacyPassphrase != "" {
+		key := sha256.Sum256([]byte(config.LegacyPassphrase))
+		gcm, err := newGCM(key[:])
+		if err != nil {
+			return nil, err
+		}
+		keyring.legacy = gcm
+	}
+
+	keys := config.Keys
+	if config.KeysFile != "" {
+		content, err := os.ReadFile(config.KeysFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read the database encryption keys: %w", err)
+		}
+		keys = strings.Join([]string{keys, string(content)}, ",")
+	}
+	for _, pair := range strings.FieldsFunc(keys, func(r rune) bool { return r == ',' || r == '\n' }) {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		id, encoded, found := strings.Cut(pair, ":")
+		if !found || id == "" {
+			return nil, fmt.Errorf("invalid database encryption key '%s', expected id:base64key", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("invalid database encryption key '%s', expected 32 bytes encoded in base64", id)
+		}
+		if _, ok := keyring.keys[id]; ok {
+			return nil, fmt.Errorf("duplicated database encryption key '%s'", id)
+		}
+		gcm, err := newGCM(key)
+		if err != nil {
+			return nil, err
+		}
+		keyring.keys[id] = gcm
+		if keyring.current == "" {
+			keyring.current = id
+		}
+	}
+
+	if config.CurrentKeyID != "" {
+		if _, ok := keyring.keys[config.CurrentKeyID]; !ok {
+			return nil, fmt.Errorf("the current database encryption key '%s' is not configured", config.CurrentKeyID)
+		}
+		keyring.current = config.CurrentKeyID
+	}
+	return keyring, nil
+}
+
+// Enabled returns true if k can encrypt values.
+func (k *Keyring) Enabled() bool {
+	return k != nil && (k.current != "" || k.legacy != nil)
+}
+
+// Encrypt seals plaintext with the current key and returns it base64 encoded,
+// nonce first, after the prefix and the ID of the key. Without keys, it is
+// sealed with the legacy passphrase and returned without prefix.
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	if !k.Enabled() {
+		return "", ErrNoKey
+	}
+	gcm, header := k.legacy, ""
+	if k.current != "" {
+		gcm, header = k.keys[k.current], prefix+k.current+":"
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return header + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value sealed by Encrypt with any of the keys of k.
+func (k *Keyring) Decrypt(ciphertext string) (string, error) {
+	if k == nil {
+		return "", ErrNoKey
+	}
+	gcm, encoded := k.legacy, ciphertext
+	if rest, ok := strings.CutPrefix(ciphertext, prefix); ok {
+		id, value, _ := strings.Cut(rest, ":")
+		gcm, encoded = k.keys[id], value
+		if gcm == nil {
+			return "", fmt.Errorf("%w: unknown key '%s'", ErrInvalidCiphertext, id)
+		}
+	}
+	if gcm == nil {
+		return "", ErrNoKey
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return "", ErrInvalidCiphertext
+	}
+	nonce, sealed := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRotation returns true if ciphertext was not encrypted with the current
+// key, so it should be encrypted again once the keys changed.
+func (k *Keyring) NeedsRotation(ciphertext string) bool {
+	if k == nil || k.current == "" || ciphertext == "" {
+		return false
+	}
+	return !strings.HasPrefix(ciphertext, prefix+k.current+":")
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
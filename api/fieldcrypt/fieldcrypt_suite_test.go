@@ -0,0 +1,13 @@
+package fieldcrypt_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestFieldcrypt(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Fieldcrypt Suite")
+}
@@ -0,0 +1,119 @@
+package fieldcrypt_test
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/huskyci-org/huskyCI/api/fieldcrypt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func newKey(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(rune(b)), 32)))
+}
+
+// legacyEncrypt seals plaintext as secrets were sealed with
+// HUSKYCI_GIT_CREDENTIALS_KEY before keys were configured.
+func legacyEncrypt(passphrase, plaintext string) string {
+	key := sha256.Sum256([]byte(passphrase))
+	block, _ := aes.NewCipher(key[:])
+	gcm, _ := cipher.NewGCM(block)
+	nonce := make([]byte, gcm.NonceSize())
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(plaintext), nil))
+}
+
+var _ = Describe("Fieldcrypt", func() {
+
+	Describe("NewKeyring", func() {
+		It("Should reject keys that are not 32 bytes encoded in base64", func() {
+			_, err := NewKeyring(Config{Keys: "k1:c2hvcnQ="})
+			Expect(err).To(HaveOccurred())
+			_, err = NewKeyring(Config{Keys: "k1"})
+			Expect(err).To(HaveOccurred())
+		})
+		It("Should reject a current key that is not configured", func() {
+			_, err := NewKeyring(Config{Keys: "k1:" + newKey('a'), CurrentKeyID: "k2"})
+			Expect(err).To(HaveOccurred())
+		})
+		It("Should read the keys of KeysFile", func() {
+			dir, err := os.MkdirTemp("", "fieldcrypt")
+			Expect(err).To(BeNil())
+			defer os.RemoveAll(dir)
+			keysFile := filepath.Join(dir, "keys")
+			Expect(os.WriteFile(keysFile, []byte("k2:"+newKey('b')+"\n"), 0600)).To(Succeed())
+			keyring, err := NewKeyring(Config{Keys: "k1:" + newKey('a'), KeysFile: keysFile, CurrentKeyID: "k2"})
+			Expect(err).To(BeNil())
+			sealed, err := keyring.Encrypt("ghp_token")
+			Expect(err).To(BeNil())
+			Expect(sealed).To(HavePrefix("v1:k2:"))
+		})
+	})
+
+	Describe("Encrypt and Decrypt", func() {
+		Context("When the same keys are used", func() {
+			It("Should return the original value", func() {
+				keyring, err := NewKeyring(Config{Keys: "k1:" + newKey('a')})
+				Expect(err).To(BeNil())
+				sealed, err := keyring.Encrypt("ghp_token")
+				Expect(err).To(BeNil())
+				Expect(sealed).To(HavePrefix("v1:k1:"))
+				Expect(sealed).ToNot(ContainSubstring("ghp_token"))
+				value, err := keyring.Decrypt(sealed)
+				Expect(err).To(BeNil())
+				Expect(value).To(Equal("ghp_token"))
+			})
+		})
+		Context("When the key of the value was removed", func() {
+			It("Should return ErrInvalidCiphertext", func() {
+				previous, _ := NewKeyring(Config{Keys: "k1:" + newKey('a')})
+				sealed, err := previous.Encrypt("ghp_token")
+				Expect(err).To(BeNil())
+				keyring, _ := NewKeyring(Config{Keys: "k2:" + newKey('b')})
+				_, err = keyring.Decrypt(sealed)
+				Expect(errors.Is(err, ErrInvalidCiphertext)).To(BeTrue())
+			})
+		})
+		Context("When the value was encrypted with the legacy passphrase", func() {
+			It("Should decrypt it and need a rotation", func() {
+				keyring, err := NewKeyring(Config{Keys: "k1:" + newKey('a'), LegacyPassphrase: "passphrase"})
+				Expect(err).To(BeNil())
+				sealed := legacyEncrypt("passphrase", "ghp_token")
+				value, err := keyring.Decrypt(sealed)
+				Expect(err).To(BeNil())
+				Expect(value).To(Equal("ghp_token"))
+				Expect(keyring.NeedsRotation(sealed)).To(BeTrue())
+			})
+		})
+		Context("When no key is configured", func() {
+			It("Should return ErrNoKey", func() {
+				keyring, err := NewKeyring(Config{})
+				Expect(err).To(BeNil())
+				_, err = keyring.Encrypt("ghp_token")
+				Expect(err).To(Equal(ErrNoKey))
+				var nilKeyring *Keyring
+				_, err = nilKeyring.Decrypt("v1:k1:AAAA")
+				Expect(err).To(Equal(ErrNoKey))
+			})
+		})
+	})
+
+	Describe("NeedsRotation", func() {
+		It("Should be true for the values of another key only", func() {
+			previous, _ := NewKeyring(Config{Keys: "k1:" + newKey('a')})
+			old, _ := previous.Encrypt("ghp_token")
+			keyring, _ := NewKeyring(Config{Keys: "k1:" + newKey('a') + ",k2:" + newKey('b'), CurrentKeyID: "k2"})
+			current, _ := keyring.Encrypt("ghp_token")
+			Expect(keyring.NeedsRotation(old)).To(BeTrue())
+			Expect(keyring.NeedsRotation(current)).To(BeFalse())
+			Expect(keyring.NeedsRotation("")).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,96 @@
+// Package fingerprint identifies a finding across analyses, so that the
+// findings of two branches or of two runs of the same branch can be compared.
+package fingerprint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/huskyci-org/huskyCI/api/types"
+)
+
+// Of returns the fingerprint of vuln. It is made of what identifies the
+// finding in the code, leaving out its line and severity, which change as the
+// code around it does or as the securityTest is updated. The package path of
+// a dependency is only part of it outside of the root of the repository, so
+// that the fingerprints of the findings found there before the package paths
+// were recorded do not change.
+func Of(vuln types.HuskyCIVulnerability) string {
+	parts := []string{
+		strings.ToLower(vuln.SecurityTool),
+		vuln.Type,
+		vuln.Title,
+		File(vuln.File),
+		strings.Join(strings.Fields(vuln.Code), " "),
+		vuln.Package,
+		vuln.Version,
+	}
+	if vuln.PackagePath != "" {
+		parts = append(parts, vuln.PackagePath)
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// File returns the path of file relative to the root of the repository.
+// Paths inside the securityTest containers, such as /go/src/code/main.go, are
+// made relative to the code directory the repository was cloned in.
+func File(file string) string {
+	file = strings.TrimPrefix(strings.TrimSpace(file), "./")
+	if strings.HasPrefix(file, "/") {
+		if i := strings.Index(file, "/code/"); i >= 0 {
+			return file[i+len("/code/"):]
+		}
+	}
+	return file
+}
+
+// Comparison holds the findings found in only one of two sets of findings, A
+// and B, and those found in both.
+type Comparison struct {
+	OnlyInA []types.HuskyCIVulnerability `json:"onlyInA"`
+	OnlyInB []types.HuskyCIVulnerability `json:"onlyInB"`
+	InBoth  []types.HuskyCIVulnerability `json:"inBoth"`
+}
+
+// Compare matches the findings of a and b by fingerprint. A fingerprint found
+// n times in a and m times in b is in both min(n, m) times, the others only in
+// the set with more of them. Findings in both are returned as found in b.
+func Compare(a, b []types.HuskyCIVulnerability) Comparison {
+	comparison := Comparison{
+		OnlyInA: []types.HuskyCIVulnerability{},
+		OnlyInB: []types.HuskyCIVulnerability{},
+		InBoth:  []types.HuskyCIVulnerability{},
+	}
+	unmatched := map[string]int{}
+	for _, vuln := range a {
+		unmatched[withFingerprint(vuln).Fingerprint]++
+	}
+	for _, vuln := range b {
+		vuln = withFingerprint(vuln)
+		if unmatched[vuln.Fingerprint] > 0 {
+			unmatched[vuln.Fingerprint]--
+			comparison.InBoth = append(comparison.InBoth, vuln)
+			continue
+		}
+		comparison.OnlyInB = append(comparison.OnlyInB, vuln)
+	}
+	for _, vuln := range a {
+		vuln = withFingerprint(vuln)
+		if unmatched[vuln.Fingerprint] > 0 {
+			unmatched[vuln.Fingerprint]--
+			comparison.OnlyInA = append(comparison.OnlyInA, vuln)
+		}
+	}
+	return comparison
+}
+
+// withFingerprint returns vuln with its fingerprint, computing it for the
+// findings stored before fingerprints were.
+func withFingerprint(vuln types.HuskyCIVulnerability) types.HuskyCIVulnerability {
+	if vuln.Fingerprint == "" {
+		vuln.Fingerprint = Of(vuln)
+	}
+	return vuln
+}
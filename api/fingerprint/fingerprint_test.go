@@ -0,0 +1,90 @@
+package fingerprint_test
+
+import (
+	"github.com/huskyci-org/huskyCI/api/fingerprint"
+	"github.com/huskyci-org/huskyCI/api/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Fingerprint", func() {
+
+	sqlInjection := types.HuskyCIVulnerability{
+		SecurityTool: "GoSec",
+		Severity:     "MEDIUM",
+		Details:      "SQL string formatting",
+		File:         "/go/src/code/db/query.go",
+		Line:         "42",
+		Code:         "42: query := fmt.Sprintf(\"SELECT * FROM users WHERE id = %s\", id)",
+	}
+
+	Describe("Of", func() {
+		It("Should ignore the line, the severity and the container path", func() {
+			moved := sqlInjection
+			moved.File = "db/query.go"
+			moved.Line = "57"
+			moved.Severity = "HIGH"
+			Expect(fingerprint.Of(moved)).To(Equal(fingerprint.Of(sqlInjection)))
+		})
+		It("Should tell apart findings of different files", func() {
+			other := sqlInjection
+			other.File = "/go/src/code/db/admin.go"
+			Expect(fingerprint.Of(other)).NotTo(Equal(fingerprint.Of(sqlInjection)))
+		})
+		It("Should tell apart vulnerable versions of a dependency", func() {
+			lodash := types.HuskyCIVulnerability{SecurityTool: "NpmAudit", Title: "Prototype Pollution", Package: "lodash", Version: "4.17.4"}
+			upgraded := lodash
+			upgraded.Version = "4.17.15"
+			Expect(fingerprint.Of(upgraded)).NotTo(Equal(fingerprint.Of(lodash)))
+		})
+		It("Should tell apart a dependency of two packages of a monorepo", func() {
+			lodash := types.HuskyCIVulnerability{SecurityTool: "NpmAudit", Title: "Prototype Pollution", Package: "lodash", Version: "4.17.4"}
+			web := lodash
+			web.PackagePath = "packages/web"
+			api := lodash
+			api.PackagePath = "packages/api"
+			Expect(fingerprint.Of(web)).NotTo(Equal(fingerprint.Of(api)))
+			Expect(fingerprint.Of(web)).NotTo(Equal(fingerprint.Of(lodash)))
+		})
+	})
+
+	Describe("File", func() {
+		It("Should return paths relative to the root of the repository", func() {
+			Expect(fingerprint.File("/go/src/code/main.go")).To(Equal("main.go"))
+			Expect(fingerprint.File("/tmp/code/src/App.java")).To(Equal("src/App.java"))
+			Expect(fingerprint.File("./app/views.py")).To(Equal("app/views.py"))
+			Expect(fingerprint.File("app/views.py")).To(Equal("app/views.py"))
+		})
+	})
+
+	Describe("Compare", func() {
+		It("Should split the findings found in A only, in B only and in both", func() {
+			hardcodedKey := types.HuskyCIVulnerability{SecurityTool: "GitLeaks", Title: "Hard Coded AWS Secret Key in: config.py", File: "config.py"}
+			lodash := types.HuskyCIVulnerability{SecurityTool: "NpmAudit", Title: "Prototype Pollution", Package: "lodash", Version: "4.17.4"}
+			movedSQLInjection := sqlInjection
+			movedSQLInjection.Line = "57"
+
+			comparison := fingerprint.Compare(
+				[]types.HuskyCIVulnerability{sqlInjection, hardcodedKey},
+				[]types.HuskyCIVulnerability{movedSQLInjection, lodash},
+			)
+			Expect(comparison.OnlyInA).To(HaveLen(1))
+			Expect(comparison.OnlyInA[0].Title).To(Equal(hardcodedKey.Title))
+			Expect(comparison.OnlyInB).To(HaveLen(1))
+			Expect(comparison.OnlyInB[0].Package).To(Equal("lodash"))
+			Expect(comparison.InBoth).To(HaveLen(1))
+			Expect(comparison.InBoth[0].Line).To(Equal("57"))
+			Expect(comparison.InBoth[0].Fingerprint).To(Equal(fingerprint.Of(sqlInjection)))
+		})
+		It("Should match repeated findings one to one", func() {
+			comparison := fingerprint.Compare(
+				[]types.HuskyCIVulnerability{sqlInjection, sqlInjection},
+				[]types.HuskyCIVulnerability{sqlInjection},
+			)
+			Expect(comparison.InBoth).To(HaveLen(1))
+			Expect(comparison.OnlyInA).To(HaveLen(1))
+			Expect(comparison.OnlyInB).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,282 @@
+// Package fixversion computes the minimal version a vulnerable dependency must
+// be upgraded to, from the version ranges reported by the dependency
+// securityTests (safety, npm audit and yarn audit).
+package fixversion
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// constraint is a single comparison of a version range, such as "<1.2.3".
+type constraint struct {
+	op      string
+	version string
+}
+
+// FromVulnerableRange returns the lowest version above installed that is out of
+// the vulnerable range, the upper bound of one of its "<" comparisons. It
+// returns "" when the range can not be parsed or has no such bound. installed
+// may be empty when it is unknown.
+func FromVulnerableRange(vulnerable, installed string) string {
+	alternatives, ok := parseRange(vulnerable)
+	if !ok {
+		return ""
+	}
+	candidates := []string{}
+	for _, alternative := range alternatives {
+		for _, c := range alternative {
+			if c.op == "<" {
+				candidates = append(candidates, c.version)
+			}
+		}
+	}
+	for _, candidate := range sortVersions(candidates) {
+		if installed != "" && Compare(candidate, installed) <= 0 {
+			continue
+		}
+		if !matches(alternatives, candidate) {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// FromPatchedRange returns the lowest version above installed that is in the
+// patched range, the lower bound of one of its ">=" or "=" comparisons. It
+// returns "" when the range can not be parsed or no version is patched.
+func FromPatchedRange(patched, installed string) string {
+	alternatives, ok := parseRange(patched)
+	if !ok {
+		return ""
+	}
+	candidates := []string{}
+	for _, alternative := range alternatives {
+		for _, c := range alternative {
+			if c.op == ">=" || c.op == "=" {
+				candidates = append(candidates, c.version)
+			}
+		}
+	}
+	for _, candidate := range sortVersions(candidates) {
+		if installed != "" && Compare(candidate, installed) <= 0 {
+			continue
+		}
+		if matches(alternatives, candidate) {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// Compare returns -1, 0 or 1 when version a is lower than, equal to or greater
+// than version b. Versions are compared dot by dot, numerically, and a
+// pre-release ("1.2.0-rc.1", "1.2.0rc1") is lower than its release.
+func Compare(a, b string) int {
+	mainA, preA := splitVersion(a)
+	mainB, preB := splitVersion(b)
+	partsA := strings.Split(mainA, ".")
+	partsB := strings.Split(mainB, ".")
+	for i := 0; i < len(partsA) || i < len(partsB); i++ {
+		partA, partB := "0", "0"
+		if i < len(partsA) {
+			partA = partsA[i]
+		}
+		if i < len(partsB) {
+			partB = partsB[i]
+		}
+		if result := comparePart(partA, partB); result != 0 {
+			return result
+		}
+	}
+	switch {
+	case preA == preB:
+		return 0
+	case preA == "":
+		return 1
+	case preB == "":
+		return -1
+	case preA < preB:
+		return -1
+	}
+	return 1
+}
+
+// splitVersion returns the dotted release of version and its pre-release,
+// without any "v" prefix or build metadata.
+func splitVersion(version string) (string, string) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.Index(version, "+"); i >= 0 {
+		version = version[:i]
+	}
+	if i := strings.Index(version, "-"); i >= 0 {
+		return version[:i], version[i+1:]
+	}
+	return version, ""
+}
+
+// comparePart compares a part of a dotted release: its leading number first,
+// then whatever follows it, a part without suffix ("0") being greater than one
+// with a pre-release suffix ("0rc1").
+func comparePart(a, b string) int {
+	numberA, suffixA := leadingNumber(a)
+	numberB, suffixB := leadingNumber(b)
+	switch {
+	case numberA < numberB:
+		return -1
+	case numberA > numberB:
+		return 1
+	case suffixA == suffixB:
+		return 0
+	case suffixA == "":
+		return 1
+	case suffixB == "":
+		return -1
+	case suffixA < suffixB:
+		return -1
+	}
+	return 1
+}
+
+func leadingNumber(part string) (int, string) {
+	i := 0
+	for i < len(part) && part[i] >= '0' && part[i] <= '9' {
+		i++
+	}
+	number, _ := strconv.Atoi(part[:i])
+	return number, part[i:]
+}
+
+func sortVersions(versions []string) []string {
+	sort.SliceStable(versions, func(i, j int) bool {
+		return Compare(versions[i], versions[j]) < 0
+	})
+	return versions
+}
+
+// matches tells whether version is in any of the alternatives of a range.
+func matches(alternatives [][]constraint, version string) bool {
+	for _, alternative := range alternatives {
+		inAlternative := true
+		for _, c := range alternative {
+			if !c.matches(version) {
+				inAlternative = false
+				break
+			}
+		}
+		if inAlternative {
+			return true
+		}
+	}
+	return false
+}
+
+func (c constraint) matches(version string) bool {
+	result := Compare(version, c.version)
+	switch c.op {
+	case "<":
+		return result < 0
+	case "<=":
+		return result <= 0
+	case ">":
+		return result > 0
+	case ">=":
+		return result >= 0
+	case "!=":
+		return result != 0
+	}
+	return result == 0
+}
+
+var spaceAfterOperator = regexp.MustCompile(`([<>=!~^]+)\s+`)
+
+// parseRange parses a range as written by npm ("<1.2.3 || >=2.0.0 <2.0.5"),
+// yarn or PyPI ("<1.2.3,>=1.0"): alternatives separated by "||", each a list
+// of comparisons that must all hold. It returns false if a comparison is not
+// understood.
+func parseRange(versionRange string) ([][]constraint, bool) {
+	if strings.TrimSpace(versionRange) == "" {
+		return nil, false
+	}
+	alternatives := [][]constraint{}
+	for _, rawAlternative := range strings.Split(versionRange, "||") {
+		rawAlternative = strings.ReplaceAll(rawAlternative, ",", " ")
+		rawAlternative = spaceAfterOperator.ReplaceAllString(rawAlternative, "$1")
+		fields := strings.Fields(rawAlternative)
+
+		alternative := []constraint{}
+		for i := 0; i < len(fields); i++ {
+			// hyphen range: "1.2.3 - 2.3.4"
+			if i+2 < len(fields) && fields[i+1] == "-" {
+				alternative = append(alternative, constraint{">=", fields[i]}, constraint{"<=", fields[i+2]})
+				i += 2
+				continue
+			}
+			constraints, ok := parseConstraint(fields[i])
+			if !ok {
+				return nil, false
+			}
+			alternative = append(alternative, constraints...)
+		}
+		alternatives = append(alternatives, alternative)
+	}
+	return alternatives, true
+}
+
+var constraintRegexp = regexp.MustCompile(`^(<=|>=|<|>|===|==|=|!=|~=|\^|~)?v?([0-9][0-9A-Za-z.+-]*)$`)
+
+// parseConstraint parses a single comparison, expanding the caret and tilde
+// ranges of npm and the compatible release "~=" of PyPI into two.
+func parseConstraint(field string) ([]constraint, bool) {
+	if field == "*" || field == "x" || field == "X" || field == "latest" {
+		return nil, true
+	}
+	match := constraintRegexp.FindStringSubmatch(field)
+	if match == nil {
+		return nil, false
+	}
+	op, version := match[1], match[2]
+	switch op {
+	case "", "=", "==", "===":
+		return []constraint{{"=", version}}, true
+	case "^":
+		return []constraint{{">=", version}, {"<", caretUpperBound(version)}}, true
+	case "~":
+		return []constraint{{">=", version}, {"<", bump(version, 1)}}, true
+	case "~=":
+		parts := strings.Split(version, ".")
+		if len(parts) < 2 {
+			return nil, false
+		}
+		return []constraint{{">=", version}, {"<", bump(version, len(parts)-2)}}, true
+	}
+	return []constraint{{op, version}}, true
+}
+
+// caretUpperBound returns the first version a caret range of version excludes:
+// the next major, or the next minor or patch for 0.x versions.
+func caretUpperBound(version string) string {
+	release, _ := splitVersion(version)
+	parts := strings.Split(release, ".")
+	for i, part := range parts {
+		if number, _ := leadingNumber(part); number != 0 || i == len(parts)-1 {
+			return bump(version, i)
+		}
+	}
+	return bump(version, 0)
+}
+
+// bump increments the part at index of the release of version and drops the
+// parts after it: bump("1.4.5", 1) is "1.5".
+func bump(version string, index int) string {
+	release, _ := splitVersion(version)
+	parts := strings.Split(release, ".")
+	for len(parts) <= index {
+		parts = append(parts, "0")
+	}
+	number, _ := leadingNumber(parts[index])
+	parts[index] = strconv.Itoa(number + 1)
+	return strings.Join(parts[:index+1], ".")
+}
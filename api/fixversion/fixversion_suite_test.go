@@ -0,0 +1,13 @@
+package fixversion_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestFixversion(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Fixversion Suite")
+}
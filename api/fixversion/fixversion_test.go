@@ -0,0 +1,63 @@
+package fixversion_test
+
+import (
+	"github.com/huskyci-org/huskyCI/api/fixversion"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Fixversion", func() {
+
+	Context("Compare", func() {
+		It("should compare versions part by part, numerically", func() {
+			Expect(fixversion.Compare("1.10.0", "1.9.3")).To(Equal(1))
+			Expect(fixversion.Compare("2.2", "2.2.0")).To(Equal(0))
+			Expect(fixversion.Compare("v1.0.0", "1.0.1")).To(Equal(-1))
+		})
+		It("should order pre-releases before their release", func() {
+			Expect(fixversion.Compare("1.2.0-rc.1", "1.2.0")).To(Equal(-1))
+			Expect(fixversion.Compare("2.0rc1", "2.0")).To(Equal(-1))
+			Expect(fixversion.Compare("1.2.0-beta", "1.2.0-alpha")).To(Equal(1))
+		})
+	})
+
+	Context("FromVulnerableRange", func() {
+		It("should return the upper bound of a PyPI range", func() {
+			Expect(fixversion.FromVulnerableRange("<2.2.28", "2.2.1")).To(Equal("2.2.28"))
+			Expect(fixversion.FromVulnerableRange("<1.11.29,>=1.11", "")).To(Equal("1.11.29"))
+		})
+		It("should return the lowest bound above the installed version", func() {
+			vulnerable := "<1.2.6 || >=2.0.0 <2.1.4"
+			Expect(fixversion.FromVulnerableRange(vulnerable, "1.0.0")).To(Equal("1.2.6"))
+			Expect(fixversion.FromVulnerableRange(vulnerable, "2.0.3")).To(Equal("2.1.4"))
+		})
+		It("should skip bounds still in another vulnerable alternative", func() {
+			Expect(fixversion.FromVulnerableRange("<1.5.0 || >=1.5.0 <1.6.2", "")).To(Equal("1.6.2"))
+		})
+		It("should return an empty version when no bound fixes the advisory", func() {
+			Expect(fixversion.FromVulnerableRange("<=4.17.20", "4.17.19")).To(BeEmpty())
+			Expect(fixversion.FromVulnerableRange("*", "")).To(BeEmpty())
+			Expect(fixversion.FromVulnerableRange("not a range", "")).To(BeEmpty())
+		})
+	})
+
+	Context("FromPatchedRange", func() {
+		It("should return the lower bound of the patched range", func() {
+			Expect(fixversion.FromPatchedRange(">=4.17.21", "4.17.15")).To(Equal("4.17.21"))
+			Expect(fixversion.FromPatchedRange(">= 1.2.3", "")).To(Equal("1.2.3"))
+		})
+		It("should return the lowest patched version above the installed version", func() {
+			patched := ">=1.2.6 <2.0.0 || >=2.1.4"
+			Expect(fixversion.FromPatchedRange(patched, "1.2.0")).To(Equal("1.2.6"))
+			Expect(fixversion.FromPatchedRange(patched, "2.0.1")).To(Equal("2.1.4"))
+		})
+		It("should expand caret and tilde ranges", func() {
+			Expect(fixversion.FromPatchedRange("^3.0.8", "3.0.1")).To(Equal("3.0.8"))
+			Expect(fixversion.FromPatchedRange("~1.4.2 || ^2.0.1", "1.9.0")).To(Equal("2.0.1"))
+		})
+		It("should return an empty version when nothing is patched", func() {
+			Expect(fixversion.FromPatchedRange("<0.0.0", "")).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,31 @@
+package gitauth
+
+import (
+	"strings"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/types"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// NormalizeURL returns repositoryURL in the form credentials are stored and
+// looked up with, so that "https://host/org/repo.git/" and
+// "https://host/org/repo" share the same credential.
+func NormalizeURL(repositoryURL string) string {
+	normalized := strings.TrimSuffix(strings.TrimSpace(repositoryURL), "/")
+	return strings.TrimSuffix(normalized, ".git")
+}
+
+// Find returns the decrypted credential stored for repositoryURL. The boolean
+// is false if the repository has no credential of its own.
+func Find(repositoryURL string) (types.GitCredential, bool, error) {
+	query := map[string]interface{}{"repositoryURL": NormalizeURL(repositoryURL)}
+	credential, err := apiContext.APIConfiguration.DBInstance.FindOneDBGitCredential(query)
+	if err != nil {
+		if err == mongo.ErrNoDocuments || err.Error() == "No data found" {
+			return types.GitCredential{}, false, nil
+		}
+		return types.GitCredential{}, false, err
+	}
+	return credential, true, nil
+}
@@ -0,0 +1,13 @@
+package gitauth_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestGitauth(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Gitauth Suite")
+}
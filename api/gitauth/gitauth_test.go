@@ -0,0 +1,18 @@
+package gitauth_test
+
+import (
+	. "github.com/huskyci-org/huskyCI/api/gitauth"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Gitauth", func() {
+
+	Describe("NormalizeURL", func() {
+		It("Should drop the trailing slash and .git suffix", func() {
+			Expect(NormalizeURL("https://github.com/org/repo.git/")).To(Equal("https://github.com/org/repo"))
+			Expect(NormalizeURL("git@github.com:org/repo.git")).To(Equal("git@github.com:org/repo"))
+		})
+	})
+})
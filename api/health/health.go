@@ -0,0 +1,104 @@
+// Package health checks the dependencies the API needs to run analyses.
+package health
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	docker "github.com/huskyci-org/huskyCI/api/dockers"
+	kube "github.com/huskyci-org/huskyCI/api/kubernetes"
+	apiUtil "github.com/huskyci-org/huskyCI/api/util/api"
+)
+
+// Statuses of the API and of its dependencies.
+const (
+	Up   = "up"
+	Down = "down"
+)
+
+// Timeout bounds how long a dependency may take to answer.
+const Timeout = 5 * time.Second
+
+// Dependency is a service the API relies on, up if Check returns no error.
+type Dependency struct {
+	Name  string
+	Check func() error
+}
+
+// Check is the state of a dependency.
+type Check struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latencyMs"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Report is the state of the API, up if every dependency checked is up.
+type Report struct {
+	Status string  `json:"status"`
+	Checks []Check `json:"checks"`
+}
+
+// Run checks every dependency at once, each for up to timeout.
+func Run(dependencies []Dependency, timeout time.Duration) Report {
+	report := Report{Status: Up, Checks: make([]Check, len(dependencies))}
+	var wg sync.WaitGroup
+	for i, dependency := range dependencies {
+		wg.Add(1)
+		go func(i int, dependency Dependency) {
+			defer wg.Done()
+			report.Checks[i] = run(dependency, timeout)
+		}(i, dependency)
+	}
+	wg.Wait()
+	for _, check := range report.Checks {
+		if check.Status != Up {
+			report.Status = Down
+		}
+	}
+	return report
+}
+
+// run checks dependency. A check that times out is left running, as the
+// clients of the dependencies do not all take a context.
+func run(dependency Dependency, timeout time.Duration) Check {
+	check := Check{Name: dependency.Name, Status: Up}
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- dependency.Check()
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			check.Status = Down
+			check.Error = err.Error()
+		}
+	case <-time.After(timeout):
+		check.Status = Down
+		check.Error = fmt.Sprintf("no answer after %s", timeout)
+	}
+	check.LatencyMs = time.Since(start).Milliseconds()
+	return check
+}
+
+// Dependencies returns the database and the Docker API hosts or the
+// Kubernetes cluster the analyses run on.
+func Dependencies(configAPI *apiContext.APIConfig) []Dependency {
+	dependencies := []Dependency{{Name: "database", Check: configAPI.DBInstance.PingDB}}
+	switch os.Getenv("HUSKYCI_INFRASTRUCTURE_USE") {
+	case "docker":
+		for _, host := range apiUtil.DockerHosts(configAPI) {
+			host := host
+			dependencies = append(dependencies, Dependency{Name: "docker " + host, Check: func() error {
+				return docker.HealthCheckDockerAPI(host)
+			}})
+		}
+	case "kubernetes":
+		dependencies = append(dependencies, Dependency{Name: "kubernetes", Check: kube.HealthCheckKubernetesAPI})
+	}
+	return dependencies
+}
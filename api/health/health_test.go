@@ -0,0 +1,65 @@
+package health_test
+
+import (
+	"errors"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/api/health"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Run", func() {
+
+	up := health.Dependency{Name: "database", Check: func() error { return nil }}
+	down := health.Dependency{Name: "docker https://dockerapi:2376", Check: func() error {
+		return errors.New("connection refused")
+	}}
+	slow := health.Dependency{Name: "kubernetes", Check: func() error {
+		time.Sleep(time.Second)
+		return nil
+	}}
+
+	Context("When every dependency is up", func() {
+		It("Should report the API up", func() {
+			report := health.Run([]health.Dependency{up}, time.Second)
+			Expect(report.Status).To(Equal(health.Up))
+			Expect(report.Checks).To(HaveLen(1))
+			Expect(report.Checks[0].Name).To(Equal("database"))
+			Expect(report.Checks[0].Status).To(Equal(health.Up))
+			Expect(report.Checks[0].Error).To(BeEmpty())
+		})
+	})
+
+	Context("When a dependency is down", func() {
+		It("Should report the API down with the error of the dependency", func() {
+			report := health.Run([]health.Dependency{up, down}, time.Second)
+			Expect(report.Status).To(Equal(health.Down))
+			Expect(report.Checks[0].Status).To(Equal(health.Up))
+			Expect(report.Checks[1].Status).To(Equal(health.Down))
+			Expect(report.Checks[1].Error).To(Equal("connection refused"))
+		})
+	})
+
+	Context("When a dependency does not answer in time", func() {
+		It("Should report it down without waiting for it", func() {
+			start := time.Now()
+			report := health.Run([]health.Dependency{slow, up}, 50*time.Millisecond)
+			Expect(time.Since(start)).To(BeNumerically("<", 500*time.Millisecond))
+			Expect(report.Status).To(Equal(health.Down))
+			Expect(report.Checks[0].Status).To(Equal(health.Down))
+			Expect(report.Checks[0].Error).To(Equal("no answer after 50ms"))
+			Expect(report.Checks[0].LatencyMs).To(BeNumerically(">=", 50))
+			Expect(report.Checks[1].Status).To(Equal(health.Up))
+		})
+	})
+
+	Context("When there is no dependency", func() {
+		It("Should report the API up", func() {
+			report := health.Run(nil, time.Second)
+			Expect(report.Status).To(Equal(health.Up))
+			Expect(report.Checks).To(BeEmpty())
+		})
+	})
+})
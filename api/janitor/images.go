@@ -0,0 +1,111 @@
+package janitor
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	huskydocker "github.com/huskyci-org/huskyCI/api/dockers"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/metrics"
+	"github.com/huskyci-org/huskyCI/api/signature"
+	apiUtil "github.com/huskyci-org/huskyCI/api/util/api"
+)
+
+// imageGCLockTTL bounds how long a replica may hold the image garbage
+// collection lock if it dies mid-run.
+const imageGCLockTTL = time.Hour
+
+// ErrImageGCRunning is returned when an image garbage collection starts
+// while another replica runs one.
+var ErrImageGCRunning = errors.New("image garbage collection already running")
+
+// ImageGCStats holds the images removed by the image garbage collection
+// since the API started.
+type ImageGCStats struct {
+	Runs          int       `json:"runs"`
+	LastRun       time.Time `json:"lastRun"`
+	ImagesRemoved int       `json:"imagesRemoved"`
+	ImagesPruned  int       `json:"danglingImagesPruned"`
+	Bytes         int64     `json:"bytesReclaimed"`
+	LastErrors    []string  `json:"lastErrors,omitempty"`
+}
+
+// CollectImages removes from every Docker API host the versions of the
+// securityTest images that no securityTest references anymore, such as the
+// previous version of a scanner whose tag was bumped, and the dangling
+// images, once they have been so for the ProtectionWindow of config. Only
+// one collection runs at a time across all replicas.
+func CollectImages(config *apiContext.ImageGCConfig) (ImageGCStats, error) {
+	run := ImageGCStats{LastRun: time.Now()}
+	if os.Getenv("HUSKYCI_INFRASTRUCTURE_USE") != "docker" {
+		return run, errors.New("image garbage collection is only supported with docker infrastructure")
+	}
+
+	hostname, _ := os.Hostname()
+	acquired, err := apiContext.APIConfiguration.DBInstance.AcquireLock("imagegc", hostname, imageGCLockTTL)
+	if err != nil {
+		return run, err
+	}
+	if !acquired {
+		return run, ErrImageGCRunning
+	}
+	defer apiContext.APIConfiguration.DBInstance.ReleaseLock("imagegc", hostname)
+
+	securityTests, err := apiContext.APIConfiguration.DBInstance.FindAllDBSecurityTest(map[string]interface{}{})
+	if err != nil {
+		return run, err
+	}
+	repositories := map[string]bool{}
+	keep := map[string]bool{}
+	for _, securityTest := range securityTests {
+		if securityTest.Image == "" {
+			continue
+		}
+		securityTest.Image = apiContext.APIConfiguration.OfflineConfig.MirrorImage(securityTest.Image)
+		reference := signature.ImageReference(securityTest)
+		repositories[huskydocker.ImageRepository(reference)] = true
+		keep[huskydocker.FamiliarReference(reference)] = true
+	}
+
+	for _, host := range apiUtil.DockerHosts(apiContext.APIConfiguration) {
+		result, err := huskydocker.CollectImages(host, repositories, keep, config.ProtectionWindow)
+		run.ImagesRemoved += result.ImagesRemoved
+		run.ImagesPruned += result.ImagesPruned
+		run.Bytes += result.Reclaimed
+		if err != nil {
+			log.Error(logActionJanitor, logInfoJanitor, 3029, host, err)
+			run.LastErrors = append(run.LastErrors, err.Error())
+		}
+	}
+	log.Info(logActionJanitor, logInfoJanitor, 82, run.ImagesRemoved, run.ImagesPruned, run.Bytes)
+
+	statsMu.Lock()
+	stats.ImageGC.Runs++
+	stats.ImageGC.LastRun = run.LastRun
+	stats.ImageGC.ImagesRemoved += run.ImagesRemoved
+	stats.ImageGC.ImagesPruned += run.ImagesPruned
+	stats.ImageGC.Bytes += run.Bytes
+	stats.ImageGC.LastErrors = run.LastErrors
+	total := stats.ImageGC
+	statsMu.Unlock()
+
+	metrics.DefaultRegistry.SetGauge("huskyci_image_gc_images_removed", "Unreferenced securityTest images removed since the API started.", float64(total.ImagesRemoved))
+	metrics.DefaultRegistry.SetGauge("huskyci_image_gc_dangling_images_pruned", "Dangling images pruned since the API started.", float64(total.ImagesPruned))
+	metrics.DefaultRegistry.SetGauge("huskyci_image_gc_reclaimed_bytes", "Disk space reclaimed by the image garbage collection since the API started.", float64(total.Bytes))
+	metrics.DefaultRegistry.SetGauge("huskyci_image_gc_last_run_timestamp_seconds", "Time the last image garbage collection ran.", float64(run.LastRun.Unix()))
+	return run, nil
+}
+
+// ScheduleImageGC calls CollectImages every config.Interval. It never returns
+// and is meant to run in its own goroutine.
+func ScheduleImageGC(config *apiContext.ImageGCConfig) {
+	ticker := time.NewTicker(config.Interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if _, err := CollectImages(config); err != nil && err != ErrImageGCRunning {
+			log.Error(logActionJanitor, logInfoJanitor, 3029, err)
+		}
+	}
+}
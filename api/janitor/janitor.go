@@ -0,0 +1,117 @@
+package janitor
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	huskydocker "github.com/huskyci-org/huskyCI/api/dockers"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/util"
+	apiUtil "github.com/huskyci-org/huskyCI/api/util/api"
+)
+
+const logActionJanitor = "Janitor"
+const logInfoJanitor = "JANITOR"
+
+// Stats holds the resources reclaimed by the janitor since the API started.
+type Stats struct {
+	Runs              int          `json:"runs"`
+	LastRun           time.Time    `json:"lastRun"`
+	ContainersRemoved int          `json:"containersRemoved"`
+	ContainerBytes    int64        `json:"containerBytesReclaimed"`
+	VolumesRemoved    int          `json:"volumesRemoved"`
+	ZipEntriesRemoved int          `json:"zipEntriesRemoved"`
+	ZipBytes          int64        `json:"zipBytesReclaimed"`
+	ObjectsExpired    int          `json:"objectsExpired"`
+	LastErrors        []string     `json:"lastErrors,omitempty"`
+	ImageGC           ImageGCStats `json:"imageGC"`
+}
+
+var (
+	stats   Stats
+	statsMu sync.Mutex
+)
+
+// GetStats returns a copy of the janitor stats.
+func GetStats() Stats {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	current := stats
+	current.LastErrors = append([]string(nil), stats.LastErrors...)
+	current.ImageGC.LastErrors = append([]string(nil), stats.ImageGC.LastErrors...)
+	return current
+}
+
+// Run removes exited huskyCI containers and analysis volumes older than
+// ContainerMaxAge from every Docker API host and uploaded zips and extracted directories older than
+// ZipMaxAge from local disk and from the object storage, if configured.
+func Run(config *apiContext.JanitorConfig) Stats {
+	now := time.Now()
+	run := Stats{LastRun: now}
+
+	if os.Getenv("HUSKYCI_INFRASTRUCTURE_USE") == "docker" {
+		for _, host := range apiUtil.DockerHosts(apiContext.APIConfiguration) {
+			removed, reclaimed, err := huskydocker.CleanupExitedContainers(host, now.Add(-config.ContainerMaxAge))
+			if err != nil {
+				log.Error(logActionJanitor, logInfoJanitor, 3029, host, err)
+				run.LastErrors = append(run.LastErrors, err.Error())
+				continue
+			}
+			run.ContainersRemoved += removed
+			run.ContainerBytes += reclaimed
+
+			volumes, err := huskydocker.CleanupStaleVolumes(host, now.Add(-config.ContainerMaxAge))
+			if err != nil {
+				log.Error(logActionJanitor, logInfoJanitor, 3029, host, err)
+				run.LastErrors = append(run.LastErrors, err.Error())
+				continue
+			}
+			run.VolumesRemoved += volumes
+		}
+	}
+
+	removed, reclaimed, err := util.CleanupStaleZips(now.Add(-config.ZipMaxAge))
+	if err != nil {
+		log.Error(logActionJanitor, logInfoJanitor, 3029, err)
+		run.LastErrors = append(run.LastErrors, err.Error())
+	}
+	run.ZipEntriesRemoved = removed
+	run.ZipBytes = reclaimed
+
+	if objectStorage := apiContext.APIConfiguration.Storage; objectStorage != nil {
+		expired, err := objectStorage.Expire("zips/", now.Add(-config.ZipMaxAge))
+		if err != nil {
+			log.Error(logActionJanitor, logInfoJanitor, 1044, err)
+			run.LastErrors = append(run.LastErrors, err.Error())
+		}
+		run.ObjectsExpired = expired
+	}
+
+	log.Info(logActionJanitor, logInfoJanitor, 39, run.ContainersRemoved, run.ContainerBytes, run.VolumesRemoved, run.ZipEntriesRemoved, run.ZipBytes, run.ObjectsExpired)
+
+	statsMu.Lock()
+	stats.Runs++
+	stats.LastRun = run.LastRun
+	stats.ContainersRemoved += run.ContainersRemoved
+	stats.ContainerBytes += run.ContainerBytes
+	stats.VolumesRemoved += run.VolumesRemoved
+	stats.ZipEntriesRemoved += run.ZipEntriesRemoved
+	stats.ZipBytes += run.ZipBytes
+	stats.ObjectsExpired += run.ObjectsExpired
+	stats.LastErrors = run.LastErrors
+	statsMu.Unlock()
+
+	return run
+}
+
+// Schedule calls Run every config.Interval. It never returns and is meant to
+// run in its own goroutine.
+func Schedule(config *apiContext.JanitorConfig) {
+	ticker := time.NewTicker(config.Interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		Run(config)
+	}
+}
@@ -3,13 +3,20 @@ package kubernetes
 import (
 	"errors"
 	"fmt"
-	"io"
+	"strconv"
+	"strings"
 
 	apiContext "github.com/huskyci-org/huskyCI/api/context"
 	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/registryauth"
+	"github.com/huskyci-org/huskyCI/api/spool"
+	"github.com/huskyci-org/huskyCI/api/types"
 	goContext "golang.org/x/net/context"
 
+	batch "k8s.io/api/batch/v1"
 	core "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/watch"
 	kube "k8s.io/client-go/kubernetes"
@@ -19,12 +26,35 @@ import (
 // Kubernetes is the Kubernetes struct
 type Kubernetes struct {
 	PID              string `json:"Id"`
-	client           *kube.Clientset
+	client           kube.Interface
 	Namespace        string
 	ProxyAddress     string
 	NoProxyAddresses string
+	JobBackoffLimit  int
+	JobTTL           int
 }
 
+// PodOptions holds the optional settings of a pod created by huskyCI.
+// NetworkMode is one of the types.NetworkMode values, Env is a list of
+// KEY=value variables and Mounts are host directories mounted read-only
+// unless they are Writable. MaxOutputSize is the size in bytes the output of
+// the pod is cut at, if positive.
+type PodOptions struct {
+	NetworkMode   string
+	Env           []string
+	Mounts        []types.VolumeMount
+	MaxOutputSize int64
+}
+
+// NetworkModeLabel is the pod label holding the securityTest network mode.
+const NetworkModeLabel = "huskyci-network"
+
+const noNetworkPolicyName = "huskyci-network-none"
+
+// pullSecretPrefix starts the names of the image pull secrets huskyCI writes
+// with the credentials of the registries.
+const pullSecretPrefix = "huskyci-registry-"
+
 const logActionNew = "NewKubernetes"
 const logInfoAPI = "KUBERNETES"
 
@@ -53,21 +83,43 @@ func NewKubernetes() (*Kubernetes, error) {
 		Namespace:        configAPI.KubernetesConfig.Namespace,
 		ProxyAddress:     configAPI.KubernetesConfig.ProxyAddress,
 		NoProxyAddresses: configAPI.KubernetesConfig.NoProxyAddresses,
+		JobBackoffLimit:  configAPI.KubernetesConfig.JobBackoffLimit,
+		JobTTL:           configAPI.KubernetesConfig.JobTTL,
 	}
 
 	return kubernetes, nil
 
 }
 
-// CreatePod creates a new Kubernetes pod with the specified image, command, and configuration.
-func (k Kubernetes) CreatePod(image, cmd, podName, securityTestName string) (string, error) {
-	return k.CreatePodWithVolume(image, cmd, podName, securityTestName, "")
+// NewKubernetesWithClient returns a Kubernetes sending its requests to c in
+// namespace.
+func NewKubernetesWithClient(c kube.Interface, namespace string) *Kubernetes {
+	return &Kubernetes{client: c, Namespace: namespace}
+}
+
+// CreateJob creates a new Kubernetes Job running the specified image and command.
+func (k Kubernetes) CreateJob(image, cmd, podName, securityTestName string, deadlineInSeconds int) (*batch.Job, error) {
+	return k.CreateJobWithVolume(image, cmd, podName, securityTestName, "", PodOptions{}, deadlineInSeconds)
 }
 
-// CreatePodWithVolume creates a new Kubernetes pod with an optional volume mount
-func (k Kubernetes) CreatePodWithVolume(image, cmd, podName, securityTestName, volumePath string) (string, error) {
+// CreateJobWithVolume creates a new Kubernetes Job, named after podName, whose
+// pod has an optional volume mount. The pod is labeled with its network mode
+// so NetworkPolicies can select it. Kubernetes retries the pod up to the
+// JobBackoffLimit of the configuration, stops the Job once it has been active
+// for deadlineInSeconds and removes it JobTTL seconds after it finished.
+func (k Kubernetes) CreateJobWithVolume(image, cmd, podName, securityTestName, volumePath string, options PodOptions, deadlineInSeconds int) (*batch.Job, error) {
 	ctx := goContext.Background()
 
+	networkMode := options.NetworkMode
+	if networkMode == "" {
+		networkMode = types.NetworkModeFull
+	}
+	if networkMode == types.NetworkModeNone {
+		if err := k.ensureNoNetworkPolicy(ctx); err != nil {
+			return nil, err
+		}
+	}
+
 	container := core.Container{
 		Name:            podName,
 		Image:           image,
@@ -77,7 +129,9 @@ func (k Kubernetes) CreatePodWithVolume(image, cmd, podName, securityTestName, v
 			"-c",
 			cmd,
 		},
-		Env: []core.EnvVar{
+	}
+	if networkMode != types.NetworkModeNone {
+		container.Env = []core.EnvVar{
 			{
 				Name:  "http_proxy",
 				Value: k.ProxyAddress,
@@ -90,7 +144,11 @@ func (k Kubernetes) CreatePodWithVolume(image, cmd, podName, securityTestName, v
 				Name:  "no_proxy",
 				Value: k.NoProxyAddresses,
 			},
-		},
+		}
+	}
+	for _, env := range options.Env {
+		name, value, _ := strings.Cut(env, "=")
+		container.Env = append(container.Env, core.EnvVar{Name: name, Value: value})
 	}
 
 	// Add volume mount if volumePath is provided
@@ -103,6 +161,25 @@ func (k Kubernetes) CreatePodWithVolume(image, cmd, podName, securityTestName, v
 			},
 		}
 	}
+	for i, mount := range options.Mounts {
+		container.VolumeMounts = append(container.VolumeMounts, core.VolumeMount{
+			Name:      fmt.Sprintf("mount-%d", i),
+			MountPath: mount.ContainerPath,
+			ReadOnly:  !mount.Writable,
+		})
+	}
+
+	security := apiContext.APIConfiguration.ContainerSecurityConfig
+	container.SecurityContext = securityContext(security)
+	writableVolumes := writableVolumes(security)
+	for _, volume := range writableVolumes {
+		container.VolumeMounts = append(container.VolumeMounts, core.VolumeMount{Name: volume.Name, MountPath: volume.path})
+	}
+
+	pullSecret, err := k.ensurePullSecret(ctx, image)
+	if err != nil {
+		return nil, err
+	}
 
 	podSpec := core.PodSpec{
 		Containers: []core.Container{container},
@@ -120,6 +197,9 @@ func (k Kubernetes) CreatePodWithVolume(image, cmd, podName, securityTestName, v
 		},
 		RestartPolicy: "Never",
 	}
+	if pullSecret != "" {
+		podSpec.ImagePullSecrets = []core.LocalObjectReference{{Name: pullSecret}}
+	}
 
 	// Add volume if volumePath is provided
 	if volumePath != "" {
@@ -138,110 +218,275 @@ func (k Kubernetes) CreatePodWithVolume(image, cmd, podName, securityTestName, v
 			},
 		}
 	}
+	for i, mount := range options.Mounts {
+		podSpec.Volumes = append(podSpec.Volumes, core.Volume{
+			Name: fmt.Sprintf("mount-%d", i),
+			VolumeSource: core.VolumeSource{
+				HostPath: &core.HostPathVolumeSource{Path: mount.HostPath, Type: hostPathType(mount)},
+			},
+		})
+	}
+
+	for _, volume := range writableVolumes {
+		podSpec.Volumes = append(podSpec.Volumes, volume.Volume)
+	}
 
-	podToCreate := &core.Pod{
+	labels := map[string]string{
+		"name":           podName,
+		"huskyCI":        securityTestName,
+		NetworkModeLabel: networkMode,
+	}
+	template := core.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: labels},
+		Spec:       podSpec,
+	}
+	if security != nil && security.AppArmorProfile != "" {
+		template.ObjectMeta.Annotations = map[string]string{
+			core.AppArmorBetaContainerAnnotationKeyPrefix + podName: core.AppArmorBetaProfileNamePrefix + security.AppArmorProfile,
+		}
+	}
+
+	// the generated name lets a securityTest run again while the Job of its
+	// previous run waits for its TTL
+	jobToCreate := &batch.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: podName + "-",
+			Labels:       labels,
+		},
+		Spec: batch.JobSpec{
+			Template:                template,
+			BackoffLimit:            int32Ptr(int32(k.JobBackoffLimit)),
+			ActiveDeadlineSeconds:   int64Ptr(int64(deadlineInSeconds)),
+			TTLSecondsAfterFinished: int32Ptr(int32(k.JobTTL)),
+		},
+	}
+
+	return k.client.BatchV1().Jobs(k.Namespace).Create(ctx, jobToCreate, metav1.CreateOptions{})
+}
+
+// securityContext returns the hardening in security as the security context of
+// a container. The seccomp profile, if any, is a path relative to the seccomp
+// directory of the kubelet, and the runtime default profile is used otherwise.
+func securityContext(security *apiContext.ContainerSecurityConfig) *core.SecurityContext {
+	if security == nil {
+		return nil
+	}
+	capAdd := []core.Capability{}
+	for _, capability := range security.CapAdd {
+		capAdd = append(capAdd, core.Capability(capability))
+	}
+	allowPrivilegeEscalation := !security.NoNewPrivileges
+	readOnlyRootFilesystem := security.ReadOnlyRootfs
+	context := &core.SecurityContext{
+		Capabilities:             &core.Capabilities{Drop: []core.Capability{"ALL"}, Add: capAdd},
+		AllowPrivilegeEscalation: &allowPrivilegeEscalation,
+		ReadOnlyRootFilesystem:   &readOnlyRootFilesystem,
+		SeccompProfile:           &core.SeccompProfile{Type: core.SeccompProfileTypeRuntimeDefault},
+	}
+	if security.SeccompProfile != "" {
+		profile := security.SeccompProfile
+		context.SeccompProfile = &core.SeccompProfile{Type: core.SeccompProfileTypeLocalhost, LocalhostProfile: &profile}
+	}
+	// pods can only run as numeric IDs
+	uid, gid, hasGroup := strings.Cut(security.User, ":")
+	if user, err := strconv.ParseInt(uid, 10, 64); err == nil {
+		runAsNonRoot := user != 0
+		context.RunAsUser = &user
+		context.RunAsNonRoot = &runAsNonRoot
+	}
+	if group, err := strconv.ParseInt(gid, 10, 64); hasGroup && err == nil {
+		context.RunAsGroup = &group
+	}
+	return context
+}
+
+// writableVolume is an in-memory volume mounted at path.
+type writableVolume struct {
+	core.Volume
+	path string
+}
+
+// writableVolumes returns the volumes mounted at the writable paths of a pod
+// whose root filesystem is read-only.
+func writableVolumes(security *apiContext.ContainerSecurityConfig) []writableVolume {
+	if security == nil || !security.ReadOnlyRootfs {
+		return nil
+	}
+	volumes := []writableVolume{}
+	for i, path := range security.WritablePaths {
+		volumes = append(volumes, writableVolume{
+			Volume: core.Volume{
+				Name:         fmt.Sprintf("writable-%d", i),
+				VolumeSource: core.VolumeSource{EmptyDir: &core.EmptyDirVolumeSource{Medium: core.StorageMediumMemory}},
+			},
+			path: path,
+		})
+	}
+	return volumes
+}
+
+// ensureNoNetworkPolicy creates, if it does not exist yet, the NetworkPolicy
+// that denies all traffic to and from pods labeled with the none network mode.
+func (k Kubernetes) ensureNoNetworkPolicy(ctx goContext.Context) error {
+	policy := &networking.NetworkPolicy{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: podName,
-			Labels: map[string]string{
-				"name":    podName,
-				"huskyCI": securityTestName,
+			Name: noNetworkPolicyName,
+		},
+		Spec: networking.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{
+				MatchLabels: map[string]string{NetworkModeLabel: types.NetworkModeNone},
 			},
+			PolicyTypes: []networking.PolicyType{networking.PolicyTypeIngress, networking.PolicyTypeEgress},
 		},
-		Spec: podSpec,
 	}
+	_, err := k.client.NetworkingV1().NetworkPolicies(k.Namespace).Create(ctx, policy, metav1.CreateOptions{})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
 
-	pod, err := k.client.CoreV1().Pods(k.Namespace).Create(ctx, podToCreate, metav1.CreateOptions{})
+// pullSecretName returns the name of the image pull secret holding the
+// credential of registry.
+func pullSecretName(registry string) string {
+	name := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '-'
+	}, strings.ToLower(registry))
+	return strings.Trim(pullSecretPrefix+name, "-")
+}
+
+// ensurePullSecret writes the credential of the registry of image, if it has
+// one, to the image pull secret of the registry and returns its name, or an
+// empty name if the registry needs none. The secret is written before every
+// pod so that it follows the credentials the admins or Vault change.
+func (k Kubernetes) ensurePullSecret(ctx goContext.Context, image string) (string, error) {
+	credential, found, err := registryauth.ForImage(image)
+	if err != nil || !found {
+		return "", err
+	}
+	config, err := registryauth.DockerConfigJSON(credential)
 	if err != nil {
 		return "", err
 	}
-
-	return string(pod.UID), nil
+	secret := &core.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: pullSecretName(credential.Registry)},
+		Type:       core.SecretTypeDockerConfigJson,
+		Data:       map[string][]byte{core.DockerConfigJsonKey: config},
+	}
+	secrets := k.client.CoreV1().Secrets(k.Namespace)
+	_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = secrets.Create(ctx, secret, metav1.CreateOptions{})
+	}
+	if err != nil {
+		log.Error("ensurePullSecret", logInfoAPI, 5006, secret.Name, err)
+		return "", err
+	}
+	return secret.Name, nil
 }
 
-// WaitPod waits for a pod to be scheduled and complete execution, with configurable timeouts.
-func (k Kubernetes) WaitPod(name string, podSchedulingTimeoutInSeconds, testTimeOutInSeconds int) (string, error) {
+// jobDeadlineGrace is how many seconds a Job is waited for after its deadline, so
+// that Kubernetes reports it failed with DeadlineExceeded before the watch
+// gives up on it.
+const jobDeadlineGrace = 30
+
+// WaitJob waits for the pod of a Job to be scheduled and for the Job to
+// complete, with configurable timeouts. It returns ErrTimeout if the Job was
+// stopped at its deadline.
+func (k Kubernetes) WaitJob(name string, podSchedulingTimeoutInSeconds, deadlineInSeconds int) error {
 	ctx := goContext.Background()
 
-	// Wait for pod scheduling
-	scheduled, phase, err := k.waitForPodScheduling(ctx, name, podSchedulingTimeoutInSeconds)
+	scheduled, err := k.waitForJobScheduling(ctx, name, podSchedulingTimeoutInSeconds)
 	if err != nil {
-		return "", err
+		return err
 	}
 	if !scheduled {
 		return k.handleSchedulingTimeout(name)
 	}
-	if phase != "" {
-		return phase, nil
-	}
 
-	// Wait for pod completion
-	return k.waitForPodCompletion(ctx, name, testTimeOutInSeconds)
+	return k.waitForJobCompletion(ctx, name, deadlineInSeconds+jobDeadlineGrace)
 }
 
-func (k Kubernetes) waitForPodScheduling(ctx goContext.Context, name string, timeoutSeconds int) (bool, string, error) {
+// waitForJobScheduling returns true once a pod of the Job started running.
+func (k Kubernetes) waitForJobScheduling(ctx goContext.Context, name string, timeoutSeconds int) (bool, error) {
 	timeout := int64Ptr(int64(timeoutSeconds))
 	watchScheduling, err := k.client.CoreV1().Pods(k.Namespace).Watch(ctx, metav1.ListOptions{
-		LabelSelector:  fmt.Sprintf("name=%s", name),
+		LabelSelector:  fmt.Sprintf("job-name=%s", name),
 		Watch:          true,
 		TimeoutSeconds: timeout,
 	})
 	if err != nil {
-		return false, "", err
+		return false, err
 	}
 	defer watchScheduling.Stop()
 
 	for event := range watchScheduling.ResultChan() {
 		pod, err := extractPodFromEvent(event)
 		if err != nil {
-			return false, "", err
-		}
-
-		phase, err := handleSchedulingPhase(pod.Status.Phase)
-		if err != nil {
-			return false, "", err
-		}
-		if phase != "" {
-			return true, phase, nil
+			return false, err
 		}
-		if pod.Status.Phase == "Running" {
-			return true, "", nil
+		switch pod.Status.Phase {
+		case core.PodRunning, core.PodSucceeded, core.PodFailed:
+			return true, nil
+		case core.PodUnknown:
+			return false, errors.New("Pod terminated with Unknown status")
 		}
 	}
 
-	return false, "", nil
+	return false, nil
 }
 
-func (k Kubernetes) waitForPodCompletion(ctx goContext.Context, name string, timeoutSeconds int) (string, error) {
+// waitForJobCompletion waits for the Job to complete or fail. A failed pod is
+// retried by Kubernetes, so only the failure of the Job is an error.
+func (k Kubernetes) waitForJobCompletion(ctx goContext.Context, name string, timeoutSeconds int) error {
 	timeout := int64Ptr(int64(timeoutSeconds))
-	watchRunning, err := k.client.CoreV1().Pods(k.Namespace).Watch(ctx, metav1.ListOptions{
-		LabelSelector:  fmt.Sprintf("name=%s", name),
+	watchRunning, err := k.client.BatchV1().Jobs(k.Namespace).Watch(ctx, metav1.ListOptions{
+		FieldSelector:  fmt.Sprintf("metadata.name=%s", name),
 		Watch:          true,
 		TimeoutSeconds: timeout,
 	})
 	if err != nil {
-		return "", err
+		return err
 	}
 	defer watchRunning.Stop()
 
 	for event := range watchRunning.ResultChan() {
-		pod, err := extractPodFromEvent(event)
-		if err != nil {
-			return "", err
+		job, ok := event.Object.(*batch.Job)
+		if !ok {
+			return errors.New("Unexpected Event while waiting for Job")
 		}
-
-		phase, err := handleCompletionPhase(pod.Status.Phase)
-		if err != nil {
-			return "", err
-		}
-		if phase != "" {
-			watchRunning.Stop()
-			return phase, nil
+		finished, err := jobResult(job)
+		if finished {
+			return err
 		}
 	}
 
 	return k.handleCompletionTimeout(name)
 }
 
+// jobResult returns true if job finished, with an error if it failed: an
+// ErrTimeout if it was stopped at its deadline.
+func jobResult(job *batch.Job) (bool, error) {
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != core.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batch.JobComplete:
+			return true, nil
+		case batch.JobFailed:
+			if condition.Reason == "DeadlineExceeded" {
+				return true, fmt.Errorf("%w: %s", ErrTimeout, job.Name)
+			}
+			return true, fmt.Errorf("Job execution failed: %s: %s", condition.Reason, condition.Message)
+		}
+	}
+	return false, nil
+}
+
 func extractPodFromEvent(event watch.Event) (*core.Pod, error) {
 	pod, ok := event.Object.(*core.Pod)
 	if !ok {
@@ -250,82 +495,98 @@ func extractPodFromEvent(event watch.Event) (*core.Pod, error) {
 	return pod, nil
 }
 
-func handleSchedulingPhase(phase core.PodPhase) (string, error) {
-	switch string(phase) {
-	case "Succeeded", "Completed":
-		return string(phase), nil
-	case "Failed":
-		return "", errors.New("Pod execution failed")
-	case "Unknown":
-		return "", errors.New("Pod terminated with Unknown status")
-	default:
-		return "", nil
+func (k Kubernetes) handleSchedulingTimeout(name string) error {
+	if err := k.RemoveJob(name); err != nil {
+		return err
 	}
+	return fmt.Errorf("timed-out waiting for pod scheduling: %s", name)
 }
 
-func handleCompletionPhase(phase core.PodPhase) (string, error) {
-	switch string(phase) {
-	case "Succeeded", "Completed":
-		return string(phase), nil
-	case "Failed":
-		return "", errors.New("Pod execution failed")
-	case "Unknown":
-		return "", errors.New("Pod terminated with Unknown status")
-	default:
-		return "", nil
-	}
-}
+// ErrTimeout is returned when a pod runs for longer than its timeout.
+var ErrTimeout = errors.New("pod timed out")
 
-func (k Kubernetes) handleSchedulingTimeout(name string) (string, error) {
-	if err := k.RemovePod(name); err != nil {
-		return "", err
+func (k Kubernetes) handleCompletionTimeout(name string) error {
+	if err := k.RemoveJob(name); err != nil {
+		return err
 	}
-	return "", fmt.Errorf("timed-out waiting for pod scheduling: %s", name)
+	return fmt.Errorf("%w waiting for it to finish: %s", ErrTimeout, name)
 }
 
-func (k Kubernetes) handleCompletionTimeout(name string) (string, error) {
-	if err := k.RemovePod(name); err != nil {
-		return "", err
-	}
-	return "", fmt.Errorf("timed-out waiting for pod to finish: %s", name)
+func int32Ptr(i int32) *int32 {
+	return &i
 }
 
 func int64Ptr(i int64) *int64 {
 	return &i
 }
 
-// ReadOutput reads the logs from a Kubernetes pod.
-func (k Kubernetes) ReadOutput(name string) (string, error) {
+// jobPod returns the name of the pod of the Job whose output is read: the one
+// that succeeded or else the last one started.
+func (k Kubernetes) jobPod(ctx goContext.Context, name string) (string, error) {
+	pods, err := k.client.CoreV1().Pods(k.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("job-name=%s", name),
+	})
+	if err != nil {
+		return "", err
+	}
+	var last *core.Pod
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.Phase == core.PodSucceeded {
+			return pod.Name, nil
+		}
+		if last == nil || last.CreationTimestamp.Before(&pod.CreationTimestamp) {
+			last = pod
+		}
+	}
+	if last == nil {
+		return "", fmt.Errorf("no pod found for Job: %s", name)
+	}
+	return last.Name, nil
+}
+
+// ReadOutput reads the logs from the pod of a Kubernetes Job, spooled on disk
+// while they are read and cut at limit bytes if limit is positive.
+func (k Kubernetes) ReadOutput(name string, limit int64) (string, error) {
 	ctx := goContext.Background()
 
-	req := k.client.CoreV1().Pods(k.Namespace).GetLogs(name, &core.PodLogOptions{})
+	podName, err := k.jobPod(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	req := k.client.CoreV1().Pods(k.Namespace).GetLogs(podName, &core.PodLogOptions{})
 	podLogs, err := req.Stream(ctx)
 	if err != nil {
-		errRemovePod := k.RemovePod(name)
-		if errRemovePod != nil {
-			return "", errRemovePod
-		}
 		return "", err
 	}
 	defer podLogs.Close()
 
-	result, err := io.ReadAll(podLogs)
+	output, err := spool.Capture(podLogs, limit)
 	if err != nil {
-		errRemovePod := k.RemovePod(name)
-		if errRemovePod != nil {
-			return "", errRemovePod
-		}
 		return "", err
 	}
+	defer output.Close()
+	if output.Truncated {
+		log.Warning("ReadOutput", logInfoAPI, 127, name, limit)
+	}
 
-	return string(result), nil
+	return output.String()
 }
 
-// RemovePod deletes a Kubernetes pod by name.
-func (k Kubernetes) RemovePod(name string) error {
+// RemoveJob deletes a Kubernetes Job by name, along with its pods. Finished
+// Jobs are removed by Kubernetes once their TTL passed; this is only needed
+// for the ones huskyCI gives up on.
+func (k Kubernetes) RemoveJob(name string) error {
 	ctx := goContext.Background()
 
-	return k.client.CoreV1().Pods(k.Namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	propagation := metav1.DeletePropagationBackground
+	err := k.client.BatchV1().Jobs(k.Namespace).Delete(ctx, name, metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil {
+		log.Error("RemoveJob", logInfoAPI, 5005, name, err)
+		return err
+	}
+	log.Info("RemoveJob", logInfoAPI, 45, name)
+	return nil
 }
 
 // HealthCheckKubernetesAPI returns true if a 200 status code is received from kubernetes or false otherwise.
@@ -344,3 +605,13 @@ func HealthCheckKubernetesAPI() error {
 	}
 	return nil
 }
+
+// hostPathType returns the type of the host directory backing mount. A
+// writable mount, such as the git mirror cache, is created on first use.
+func hostPathType(mount types.VolumeMount) *core.HostPathType {
+	t := core.HostPathUnset
+	if mount.Writable {
+		t = core.HostPathDirectoryOrCreate
+	}
+	return &t
+}
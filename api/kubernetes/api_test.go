@@ -0,0 +1,128 @@
+package kubernetes_test
+
+import (
+	"errors"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/api/kubernetes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	batch "k8s.io/api/batch/v1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// jobWithCondition returns a Job whose condition conditionType is true.
+func jobWithCondition(conditionType batch.JobConditionType, reason string) *batch.Job {
+	return &batch.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "rid-gosec-x1"},
+		Status: batch.JobStatus{
+			Conditions: []batch.JobCondition{
+				{Type: conditionType, Status: core.ConditionTrue, Reason: reason, Message: "message"},
+			},
+		},
+	}
+}
+
+// jobPod returns a pod of the Job job in phase, created at created.
+func jobPod(name, job string, phase core.PodPhase, created time.Time) *core.Pod {
+	return &core.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "huskyci",
+			Labels:            map[string]string{"job-name": job},
+			CreationTimestamp: metav1.NewTime(created),
+		},
+		Status: core.PodStatus{Phase: phase},
+	}
+}
+
+var _ = Describe("JobResult", func() {
+
+	Context("When the Job completed", func() {
+		It("Should return that it finished without an error", func() {
+			finished, err := kubernetes.JobResult(jobWithCondition(batch.JobComplete, ""))
+			Expect(finished).To(BeTrue())
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Context("When the Job failed", func() {
+		It("Should return that it finished with the reason of the failure", func() {
+			finished, err := kubernetes.JobResult(jobWithCondition(batch.JobFailed, "BackoffLimitExceeded"))
+			Expect(finished).To(BeTrue())
+			Expect(err).To(MatchError(ContainSubstring("BackoffLimitExceeded")))
+			Expect(errors.Is(err, kubernetes.ErrTimeout)).To(BeFalse())
+		})
+	})
+
+	Context("When the Job was stopped at its deadline", func() {
+		It("Should return that it finished with ErrTimeout", func() {
+			finished, err := kubernetes.JobResult(jobWithCondition(batch.JobFailed, "DeadlineExceeded"))
+			Expect(finished).To(BeTrue())
+			Expect(errors.Is(err, kubernetes.ErrTimeout)).To(BeTrue())
+		})
+	})
+
+	Context("When the Job is still running", func() {
+		It("Should return that it has not finished", func() {
+			job := jobWithCondition(batch.JobComplete, "")
+			job.Status.Conditions[0].Status = core.ConditionFalse
+			finished, err := kubernetes.JobResult(job)
+			Expect(finished).To(BeFalse())
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+})
+
+var _ = Describe("JobPod", func() {
+
+	started := time.Now()
+
+	Context("When a pod of the Job succeeded", func() {
+		It("Should return it rather than the last pod started", func() {
+			client := fake.NewSimpleClientset(
+				jobPod("failed", "rid-gosec-x1", core.PodFailed, started),
+				jobPod("succeeded", "rid-gosec-x1", core.PodSucceeded, started.Add(time.Second)),
+				jobPod("retried", "rid-gosec-x1", core.PodFailed, started.Add(2*time.Second)),
+			)
+			pod, err := kubernetes.NewKubernetesWithClient(client, "huskyci").JobPod("rid-gosec-x1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod).To(Equal("succeeded"))
+		})
+	})
+
+	Context("When no pod of the Job succeeded", func() {
+		It("Should return the last pod started", func() {
+			client := fake.NewSimpleClientset(
+				jobPod("retried", "rid-gosec-x1", core.PodFailed, started.Add(time.Second)),
+				jobPod("failed", "rid-gosec-x1", core.PodFailed, started),
+				jobPod("other", "rid-enry-x2", core.PodFailed, started.Add(2*time.Second)),
+			)
+			pod, err := kubernetes.NewKubernetesWithClient(client, "huskyci").JobPod("rid-gosec-x1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod).To(Equal("retried"))
+		})
+	})
+
+	Context("When the Job has no pod", func() {
+		It("Should return an error", func() {
+			client := fake.NewSimpleClientset(jobPod("other", "rid-enry-x2", core.PodSucceeded, started))
+			_, err := kubernetes.NewKubernetesWithClient(client, "huskyci").JobPod("rid-gosec-x1")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
+
+var _ = Describe("ReadOutput", func() {
+
+	It("Should read the logs of the pod of the Job", func() {
+		client := fake.NewSimpleClientset(jobPod("succeeded", "rid-gosec-x1", core.PodSucceeded, time.Now()))
+		output, err := kubernetes.NewKubernetesWithClient(client, "huskyci").ReadOutput("rid-gosec-x1", 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output).To(Equal("fake logs"))
+	})
+})
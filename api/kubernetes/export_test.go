@@ -0,0 +1,17 @@
+package kubernetes
+
+import (
+	goContext "golang.org/x/net/context"
+
+	batch "k8s.io/api/batch/v1"
+)
+
+// JobResult exports jobResult to the tests.
+func JobResult(job *batch.Job) (bool, error) {
+	return jobResult(job)
+}
+
+// JobPod exports jobPod to the tests.
+func (k Kubernetes) JobPod(name string) (string, error) {
+	return k.jobPod(goContext.Background(), name)
+}
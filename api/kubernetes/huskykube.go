@@ -5,10 +5,13 @@
 package kubernetes
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
+	"time"
 
+	"github.com/huskyci-org/huskyCI/api/debugtrace"
 	"github.com/huskyci-org/huskyCI/api/log"
 )
 
@@ -17,8 +20,13 @@ const logInfoHuskyKube = "HUSKYKUBE"
 
 const urlRegexp = `([\w\-_]+(?:(?:\.[\w\-_]+)+))([\w\-\.,@?^=%&amp;:/~\+#]*[\w\-\@?^=%&amp;/~\+#])?`
 
+// configureImagePath returns the canonical and full reference of image.
+// A tag in the sha256:<hex> form is a digest and is joined with "@".
 func configureImagePath(image, tag string) (string, string) {
 	fullContainerImage := fmt.Sprintf("%s:%s", image, tag)
+	if strings.HasPrefix(tag, "sha256:") {
+		fullContainerImage = fmt.Sprintf("%s@%s", image, tag)
+	}
 	regex := regexp.MustCompile(urlRegexp)
 	canonicalURL := image
 	if !regex.MatchString(canonicalURL) {
@@ -31,13 +39,15 @@ func configureImagePath(image, tag string) (string, string) {
 	return canonicalURL, fullContainerImage
 }
 
-// KubeRun starts a new pod and returns its output and an error.
+// KubeRun starts a new Job and returns its output and an error.
 func KubeRun(image, imageTag, cmd, securityTestName, id string, podSchedulingTimeoutInSeconds, timeOutInSeconds int) (string, string, error) {
-	return KubeRunWithVolume(image, imageTag, cmd, securityTestName, id, "", podSchedulingTimeoutInSeconds, timeOutInSeconds)
+	return KubeRunWithVolume(image, imageTag, cmd, securityTestName, id, "", PodOptions{}, podSchedulingTimeoutInSeconds, timeOutInSeconds)
 }
 
-// KubeRunWithVolume starts a new pod with an optional volume mount and returns its output and an error.
-func KubeRunWithVolume(image, imageTag, cmd, securityTestName, id, volumePath string, podSchedulingTimeoutInSeconds, timeOutInSeconds int) (string, string, error) {
+// KubeRunWithVolume starts a new Job with an optional volume mount and options and returns its output and an error.
+// The Job is stopped once it has been active for the pod scheduling timeout and
+// timeOutInSeconds, and Kubernetes removes it once its TTL passed.
+func KubeRunWithVolume(image, imageTag, cmd, securityTestName, id, volumePath string, options PodOptions, podSchedulingTimeoutInSeconds, timeOutInSeconds int) (string, string, error) {
 
 	// step 1: create a new Kubernetes API client
 	k, err := NewKubernetes()
@@ -49,28 +59,36 @@ func KubeRunWithVolume(image, imageTag, cmd, securityTestName, id, volumePath st
 
 	_, fullContainerImage := configureImagePath(image, imageTag)
 	podName := fmt.Sprintf("%s-%s", strings.ToLower(id), securityTestName)
+	deadlineInSeconds := podSchedulingTimeoutInSeconds + timeOutInSeconds
 
-	// step 3: create a new container given an image and it's cmd
-	podUID, err := k.CreatePodWithVolume(fullContainerImage, cmd, podName, securityTestName, volumePath)
+	// step 3: create a new Job given an image and it's cmd
+	job, err := k.CreateJobWithVolume(fullContainerImage, cmd, podName, securityTestName, volumePath, options, deadlineInSeconds)
 	if err != nil {
 		log.Error(logActionRun, logInfoHuskyKube, 5002, fullContainerImage, k.PID, err.Error())
 		return "", "", err
 	}
-	k.PID = podUID
+	k.PID = string(job.UID)
 
 	log.Info(logActionRun, logInfoHuskyKube, 42, fullContainerImage, k.PID)
+	debugtrace.Record(debugtrace.Lifecycle, "kubernetes", "create", id, "job", job.Name, "image", fullContainerImage, "volume", volumePath, "network", options.NetworkMode)
+	started := time.Now()
 
-	// step 5: wait container finish
-	_, err = k.WaitPod(podName, podSchedulingTimeoutInSeconds, timeOutInSeconds)
+	// step 5: wait Job finish
+	err = k.WaitJob(job.Name, podSchedulingTimeoutInSeconds, deadlineInSeconds)
 	if err != nil {
 		log.Error(logActionRun, logInfoHuskyKube, 5003, fullContainerImage, k.PID, err.Error())
+		debugtrace.Record(debugtrace.Lifecycle, "kubernetes", "wait.failed", id, "job", job.Name, "duration", time.Since(started), "error", err)
+		if errors.Is(err, ErrTimeout) {
+			return k.PID, "", err
+		}
 		return "", "", err
 	}
+	debugtrace.Record(debugtrace.Lifecycle, "kubernetes", "exit", id, "job", job.Name, "duration", time.Since(started))
 
 	log.Info(logActionRun, logInfoHuskyKube, 43, fullContainerImage, k.PID)
 
-	// step 6: read container's output when it finishes
-	cOutput, err := k.ReadOutput(podName)
+	// step 6: read the output of its pod; Kubernetes removes the Job once its TTL passed
+	cOutput, err := k.ReadOutput(job.Name, options.MaxOutputSize)
 	if err != nil {
 		log.Error(logActionRun, logInfoHuskyKube, 5004, fullContainerImage, k.PID, err.Error())
 		return "", "", err
@@ -78,13 +96,5 @@ func KubeRunWithVolume(image, imageTag, cmd, securityTestName, id, volumePath st
 
 	log.Info(logActionRun, logInfoHuskyKube, 44, fullContainerImage, k.PID)
 
-	// step 7: remove container from docker API
-	if err := k.RemovePod(podName); err != nil {
-		log.Error(logActionRun, logInfoHuskyKube, 5005, fullContainerImage, k.PID, err.Error())
-		return "", "", err
-	}
-
-	log.Info(logActionRun, logInfoHuskyKube, 45, fullContainerImage, k.PID)
-
-	return podUID, cOutput, nil
+	return k.PID, cOutput, nil
 }
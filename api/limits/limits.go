@@ -0,0 +1,116 @@
+// Package limits bounds the requests the huskyCI API reads: the size of their
+// bodies and how deeply their JSON is nested, so that a request can not
+// exhaust the memory of the API.
+package limits
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/api/apierror"
+	"github.com/huskyci-org/huskyCI/pkg/errcode"
+	"github.com/labstack/echo/v4"
+)
+
+// DefaultMaxBodySize is the largest request body accepted when no size is
+// configured.
+const DefaultMaxBodySize = 1 << 20
+
+// DefaultMaxJSONDepth is how deeply the JSON of a request can be nested when
+// no depth is configured.
+const DefaultMaxJSONDepth = 32
+
+// DefaultTimeoutSeconds is how long a request can take when no timeout is
+// configured.
+const DefaultTimeoutSeconds = 60
+
+// errTooDeep is returned when the JSON of a request is nested too deeply.
+var errTooDeep = errors.New("JSON nested too deeply")
+
+// Config represents the limits of the requests: MaxBodySize bytes for their
+// bodies, MaxJSONDepth levels of nesting for their JSON and Timeout for
+// their handlers.
+type Config struct {
+	MaxBodySize  int64
+	MaxJSONDepth int
+	Timeout      time.Duration
+}
+
+// Body returns a middleware reading the body of the requests up to the
+// largest size their route accepts, the one of routes for their path as
+// registered in Echo or MaxBodySize, and checking how deeply their JSON is
+// nested. It answers 413 to the requests that are too large and 422 to the
+// ones whose JSON is nested too deeply. A route whose size is 0 is left to
+// its handler, such as the zip uploads.
+func Body(config *Config, routes map[string]int64) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			maxSize, ok := routes[c.Path()]
+			if !ok {
+				maxSize = config.MaxBodySize
+			}
+			if maxSize <= 0 || req.Body == nil || req.Body == http.NoBody {
+				return next(c)
+			}
+			if req.ContentLength > maxSize {
+				return replyTooLarge(c, maxSize)
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(c.Response(), req.Body, maxSize))
+			req.Body.Close()
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return replyTooLarge(c, maxSize)
+			}
+			if err != nil {
+				reply := apierror.Reply(c, errcode.InvalidRequest, "invalid request", "The request body could not be read.")
+				return c.JSON(http.StatusBadRequest, reply)
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+
+			if err := checkDepth(body, config.MaxJSONDepth); errors.Is(err, errTooDeep) {
+				reply := apierror.Reply(c, errcode.Rejected, "invalid JSON", fmt.Sprintf("The JSON of the request is nested deeper than the %d levels accepted by the huskyCI API.", config.MaxJSONDepth))
+				return c.JSON(http.StatusUnprocessableEntity, reply)
+			}
+			return next(c)
+		}
+	}
+}
+
+// replyTooLarge answers 413 to a request larger than maxSize bytes.
+func replyTooLarge(c echo.Context, maxSize int64) error {
+	reply := apierror.Reply(c, errcode.TooLarge, "request too large", fmt.Sprintf("The request body is larger than the %d bytes accepted by this endpoint.", maxSize))
+	return c.JSON(http.StatusRequestEntityTooLarge, reply)
+}
+
+// checkDepth returns errTooDeep if body is JSON nested deeper than maxDepth.
+// Bodies that are not valid JSON are left to the handler, which answers with
+// its own message.
+func checkDepth(body []byte, maxDepth int) error {
+	if maxDepth <= 0 {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+			if depth > maxDepth {
+				return errTooDeep
+			}
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+}
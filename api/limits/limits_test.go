@@ -0,0 +1,77 @@
+package limits_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/huskyci-org/huskyCI/api/limits"
+	"github.com/labstack/echo/v4"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Body", func() {
+
+	config := &Config{MaxBodySize: 64, MaxJSONDepth: 3}
+	routes := map[string]int64{"/analysis/upload": 0, "/token": 16}
+
+	serve := func(path, body string, chunked bool) *httptest.ResponseRecorder {
+		e := echo.New()
+		e.Use(Body(config, routes))
+		handler := func(c echo.Context) error {
+			content, err := io.ReadAll(c.Request().Body)
+			if err != nil {
+				return err
+			}
+			return c.String(http.StatusOK, string(content))
+		}
+		e.POST("/analysis", handler)
+		e.POST("/analysis/upload", handler)
+		e.POST("/token", handler)
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if chunked {
+			req.ContentLength = -1
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	Context("When the body is within the limits", func() {
+		It("Should pass it to the handler", func() {
+			rec := serve("/analysis", `{"repositoryURL":"https://github.com/org/repo"}`, false)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(Equal(`{"repositoryURL":"https://github.com/org/repo"}`))
+		})
+	})
+	Context("When the body is larger than the default size", func() {
+		It("Should return 413, with or without Content-Length", func() {
+			body := `{"repositoryURL":"` + strings.Repeat("a", 64) + `"}`
+			Expect(serve("/analysis", body, false).Code).To(Equal(http.StatusRequestEntityTooLarge))
+			Expect(serve("/analysis", body, true).Code).To(Equal(http.StatusRequestEntityTooLarge))
+		})
+	})
+	Context("When the route has its own size", func() {
+		It("Should apply it", func() {
+			Expect(serve("/token", `{"repositoryURL":"https://x"}`, false).Code).To(Equal(http.StatusRequestEntityTooLarge))
+		})
+		It("Should leave the routes without size to their handler", func() {
+			Expect(serve("/analysis/upload", strings.Repeat("a", 128), false).Code).To(Equal(http.StatusOK))
+		})
+	})
+	Context("When the JSON is nested too deeply", func() {
+		It("Should return 422", func() {
+			rec := serve("/analysis", `{"a":[{"b":[1]}]}`, false)
+			Expect(rec.Code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(rec.Body.String()).To(ContainSubstring("nested deeper than the 3 levels"))
+		})
+	})
+	Context("When the body is not valid JSON", func() {
+		It("Should leave it to the handler", func() {
+			Expect(serve("/analysis", `{"a":`, false).Code).To(Equal(http.StatusOK))
+		})
+	})
+})
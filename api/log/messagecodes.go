@@ -17,6 +17,51 @@ var MsgCode = map[int]string{
 	24: "URL received to generate a new token: ",
 	25: "Zip file upload request received: ",
 	26: "Zip file uploaded successfully: ",
+	27: "Expired objects removed from object storage: ",
+	85: "Workspace removed: ",
+	28: "SecurityTest updated by an admin: ",
+	29: "Git credential stored by an admin: ",
+	30: "Git credential removed by an admin: ",
+	40: "Analysis cancelled: ",
+	46: "Client watching analysis: ",
+	47: "Status reporter stored by an admin: ",
+	48: "Status reporter removed by an admin: ",
+	49: "Analysis status reported: ",
+	50: "Remediation pull request opened: ",
+	51: "Remediation stored by an admin: ",
+	52: "Remediation removed by an admin: ",
+	53: "Policy stored by an admin: ",
+	54: "Policy removed by an admin: ",
+	55: "Findings exported: ",
+	56: "TLS certificate reloaded: ",
+	57: "Serving the TLS certificates obtained with ACME for: ",
+	58: "Encrypted fields rotated to the current key: ",
+	59: "Vault secrets read again: ",
+	60: "Client streaming the analyses events",
+	61: "Runner hosts scaling requested: ",
+	62: "Repository group stored by an admin: ",
+	63: "Repository group removed by an admin: ",
+	64: "Analyses of the repository group started: ",
+	65: "Findings searched: ",
+	66: "SecurityTest of an analysis run again: ",
+	67: "Idle warm containers reaped: ",
+	68: "Benchmark started (jobs, concurrency, kinds): ",
+	69: "Benchmark finished: ",
+	70: "Analysis returned again for its Idempotency-Key (RID): ",
+	71: "Analysis queued behind the running analysis of its branch (RID): ",
+	72: "Queued analysis replaced by a newer request (RID): ",
+	73: "Queued analysis started (RID): ",
+	74: "User created by an admin (username, role): ",
+	75: "User changed by an admin (username, role, disabled): ",
+	76: "Password of a user set by an admin: ",
+	77: "User removed by an admin: ",
+	78: "Docker host registered (address, state): ",
+	79: "Docker host changed (address, state): ",
+	80: "Docker host removed: ",
+	81: "Idle Docker API clients closed: ",
+	82: "Image garbage collection reclaimed (images, dangling images, bytes): ",
+	83: "Registry credential stored by an admin: ",
+	84: "Registry credential removed by an admin: ",
 
 	// HuskyCI API warnings
 	101: "Analysis started: ",
@@ -32,6 +77,27 @@ var MsgCode = map[int]string{
 	112: "Invalid user input for metric type: ",
 	113: "Successful retrieval of analysis data: ",
 	114: "Retrieving analysis data for RID: ",
+	115: "Could not verify securityTest image signature: ",
+	116: "Listing analyses with the following filters: ",
+	117: "Analysis is not running and cannot be cancelled: ",
+	118: "Analysis watch stopped: ",
+	119: "Analysis status not reported, no commit was given: ",
+	120: "Remediation pull request already opened: ",
+	121: "Could not attribute the findings to their commits: ",
+	122: "No finished analysis found for the branch: ",
+	123: "Upload rejected: ",
+	124: "Could not create the database indexes: ",
+	125: "Findings not exported, the export queue is full: ",
+	126: "Analyses events stream stopped: ",
+	127: "Container output truncated at its size limit: ",
+	128: "SecurityTest of an analysis cannot run again: ",
+	129: "Benchmark not started: ",
+	130: "Container output not found (RID, securityTest): ",
+	131: "Idempotency-Key sent again with another request (RID): ",
+	132: "Basic auth failed (username, address): ",
+	133: "Basic auth locked out after too many failures (username, address, duration): ",
+	134: "Warm container removed, its job changed its filesystem outside /tmp (CID, path): ",
+	135: "Could not refresh the git mirror of the repository analyzed (RID): ",
 
 	// HuskyCI API errors
 	1001: "Error(s) found when starting HuskyCI API: ",
@@ -75,6 +141,53 @@ var MsgCode = map[int]string{
 	1039: "Could not Unmarshall the following spotbugsOutput: ",
 	1040: "Could not Unmarshall the following tfsecOutput: ",
 	1041: "Could not Unmarshall the following securitycodescanOutput: ",
+	1042: "Could not store object in object storage: ",
+	1043: "Could not fetch object from object storage: ",
+	1044: "Could not expire objects in object storage: ",
+	1088: "Could not remove a workspace: ",
+	1045: "Received an invalid securityTest update JSON: ",
+	1046: "SecurityTest image rejected by signature verification: ",
+	1047: "Could not load the git credential of repository: ",
+	1048: "Received an invalid git credential JSON: ",
+	1049: "Could not list analyses: ",
+	1050: "Could not cancel analysis: ",
+	1051: "Could not read the watched analysis: ",
+	1052: "Could not report the analysis status: ",
+	1053: "Received an invalid status reporter JSON: ",
+	1054: "Received an invalid commit: ",
+	1055: "Could not access the status reporters: ",
+	1056: "Could not open the remediation pull request: ",
+	1057: "Received an invalid remediation JSON: ",
+	1058: "Could not access the remediations: ",
+	1059: "Could not compare the branches: ",
+	1060: "Could not scan the uploaded zip: ",
+	1061: "Could not reach the status cache: ",
+	1062: "Received an invalid policy JSON: ",
+	1063: "Could not access the policies: ",
+	1064: "Could not Unmarshall the following trivyConfigOutput: ",
+	1065: "Could not summarize the repository: ",
+	1066: "Could not export the findings: ",
+	1067: "Could not reload the TLS certificate: ",
+	1068: "Could not rotate the encrypted fields: ",
+	1069: "Could not renew the Vault lease: ",
+	1070: "Could not read the Vault secrets: ",
+	1071: "Could not read the running analyses of the events stream: ",
+	1072: "Could not scale the runner hosts: ",
+	1073: "Could not estimate the load of the runner hosts: ",
+	1074: "Could not plan the analysis: ",
+	1075: "Could not read the durations of the last analyses: ",
+	1076: "Received an invalid repository group JSON: ",
+	1077: "Could not access the repository groups: ",
+	1078: "Could not search the findings: ",
+	1079: "Could not run a securityTest of an analysis again: ",
+	1080: "Benchmark failed: ",
+	1081: "Could not read the raw output of the container (RID, securityTest): ",
+	1082: "Received an invalid user JSON: ",
+	1083: "Could not access the users: ",
+	1084: "Received an invalid Docker host JSON: ",
+	1085: "Could not access the Docker hosts: ",
+	1086: "Could not load the registry credentials: ",
+	1087: "Received an invalid registry credential JSON: ",
 
 	// MongoDB infos
 	21: "Connecting to MongoDB.",
@@ -106,6 +219,12 @@ var MsgCode = map[int]string{
 	2015: "Could not create a new repository: ",
 	2016: "Could not create a new securityTest: ",
 	2017: "Error running the MongoDB aggregation for the following metric: ",
+	2018: "Could not acquire or release distributed lock: ",
+	2019: "Could not store the raw output of the container (RID, securityTest): ",
+	2020: "Could not read or clear the Idempotency-Key of an analysis: ",
+	2021: "Could not queue an analysis or start the queued one: ",
+	2022: "Could not compare the findings of a pull request with its target branch: ",
+	2023: "Could not disconnect the previous MongoDB client: ",
 
 	// Docker API info
 	31: "Waiting pull image...",
@@ -114,19 +233,21 @@ var MsgCode = map[int]string{
 	34: "Container finished successfully: ",
 	35: "Container image has been pulled successfully: ",
 	36: "Container cOutput read sucessfully for CID: ",
+	37: "Image pre-pull started (hosts, securityTests): ",
+	38: "Image pre-pull finished (pulled, errors): ",
+	39: "Janitor reclaimed (containers, bytes, volumes, zips, bytes, objects): ",
 
 	// Kubernetes info
 	41: "Kubernetes API client created",
-	42: "Pod started successfully: ",
-	43: "Pod completed execution successfully",
-	44: "Pod cOutput read successfully for CID: ",
-	45: "Pod removed successfully",
+	42: "Job started successfully: ",
+	43: "Job completed execution successfully",
+	44: "Job cOutput read successfully for CID: ",
+	45: "Job removed successfully: ",
 
 	// Docker API warning
 	301: "",
 
 	// Docker API errors
-	3001: "Could not set DOCKER_HOST enviroment variable.",
 	3002: "Could not start a new Docker API client: ",
 	3005: "Could not create a new container via d.client: ",
 	3006: "Could not get containers' logs: ",
@@ -142,8 +263,7 @@ var MsgCode = map[int]string{
 	3016: "Could not wait container via HuskyCI: ",
 	3017: "Could not read container output via huskyCI: ",
 	3018: "Unexpected securityTest.Name: ",
-	3019: "Could not set DOCKER_CERT_PATH enviroment variable: ",
-	3020: "Could not set DOCKER_TLS_VERIFY enviroment variable: ",
+	3019: "Could not load the TLS material of a Docker API host: ",
 	3021: "Could not list current active containers: ",
 	3022: "Could not stop a container via d.client: ",
 	3023: "Could not remove a container via d.client: ",
@@ -151,6 +271,16 @@ var MsgCode = map[int]string{
 	3025: "Could not update listed containers: ",
 	3026: "Could not initialize default configurations: ",
 	3027: "Could not remove container via huskyCI: ",
+	3028: "Could not pre-pull image: ",
+	3029: "Janitor could not clean up: ",
+	3030: "Could not create the volume of an analysis: ",
+	3031: "Could not remove the volume of an analysis: ",
+	3032: "Could not copy the code of an analysis into its container: ",
+	3033: "Could not read the operating system of the Docker API: ",
+	3034: "Could not run a job in a warm container: ",
+	3035: "Docker API client failed its health check and was closed: ",
+	3036: "Could not list the images of a Docker API host: ",
+	3037: "Could not remove an image from a Docker API host: ",
 
 	// Util package errors
 	4001: "Could not read certificate file: ",
@@ -158,8 +288,9 @@ var MsgCode = map[int]string{
 
 	// Kubernetes errors
 	5001: "Could not start a new Kubernetes API client: ",
-	5002: "Could not create a new pod: ",
-	5003: "Could not wait for pod to finish: ",
-	5004: "Could not get pod logs: ",
-	5005: "Could not remove pod via huskyCI: ",
+	5002: "Could not create a new Job: ",
+	5003: "Could not wait for Job to finish: ",
+	5004: "Could not get the logs of the pod of a Job: ",
+	5005: "Could not remove Job via huskyCI: ",
+	5006: "Could not write the image pull secret of a registry: ",
 }
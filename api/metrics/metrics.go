@@ -0,0 +1,153 @@
+// Package metrics counts the requests the huskyCI API serves, by route and
+// status, and how long they took, and exposes them in the text format of
+// Prometheus.
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// unmatched is the route of the requests no route matched.
+const unmatched = "unmatched"
+
+// key identifies the requests counted together.
+type key struct {
+	method string
+	route  string
+	status int
+}
+
+// series is what is known of the requests of a key.
+type series struct {
+	count   uint64
+	seconds float64
+}
+
+// gauge is a value set by the API, such as the load of the runner hosts.
+type gauge struct {
+	help  string
+	value float64
+}
+
+// Registry holds the metrics of the requests served and the gauges set.
+type Registry struct {
+	mu       sync.Mutex
+	inFlight int64
+	requests map[key]*series
+	gauges   map[string]gauge
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{requests: map[key]*series{}, gauges: map[string]gauge{}}
+}
+
+// SetGauge sets the gauge name, described by help, to value.
+func (r *Registry) SetGauge(name, help string, value float64) {
+	r.mu.Lock()
+	r.gauges[name] = gauge{help: help, value: value}
+	r.mu.Unlock()
+}
+
+// DefaultRegistry is the Registry of the huskyCI API.
+var DefaultRegistry = NewRegistry()
+
+// Middleware returns a middleware counting the requests in r, by the route
+// they matched as registered in Echo so that paths holding IDs are counted
+// together.
+func (r *Registry) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			r.mu.Lock()
+			r.inFlight++
+			r.mu.Unlock()
+
+			start := time.Now()
+			err := next(c)
+			elapsed := time.Since(start).Seconds()
+
+			status := c.Response().Status
+			var httpError *echo.HTTPError
+			if errors.As(err, &httpError) {
+				status = httpError.Code
+			} else if err != nil && !c.Response().Committed {
+				status = http.StatusInternalServerError
+			}
+			route := c.Path()
+			if route == "" {
+				route = unmatched
+			}
+
+			r.mu.Lock()
+			r.inFlight--
+			k := key{method: c.Request().Method, route: route, status: status}
+			s, ok := r.requests[k]
+			if !ok {
+				s = &series{}
+				r.requests[k] = s
+			}
+			s.count++
+			s.seconds += elapsed
+			r.mu.Unlock()
+			return err
+		}
+	}
+}
+
+// Handler writes the metrics of r in the text format of Prometheus.
+func (r *Registry) Handler(c echo.Context) error {
+	r.mu.Lock()
+	keys := make([]key, 0, len(r.requests))
+	for k := range r.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].route != keys[j].route {
+			return keys[i].route < keys[j].route
+		}
+		if keys[i].method != keys[j].method {
+			return keys[i].method < keys[j].method
+		}
+		return keys[i].status < keys[j].status
+	})
+
+	var b strings.Builder
+	b.WriteString("# HELP huskyci_http_requests_in_flight Requests being served.\n")
+	b.WriteString("# TYPE huskyci_http_requests_in_flight gauge\n")
+	fmt.Fprintf(&b, "huskyci_http_requests_in_flight %d\n", r.inFlight)
+	b.WriteString("# HELP huskyci_http_requests_total Requests served, by method, route and status.\n")
+	b.WriteString("# TYPE huskyci_http_requests_total counter\n")
+	for _, k := range keys {
+		fmt.Fprintf(&b, "huskyci_http_requests_total{%s} %d\n", labels(k), r.requests[k].count)
+	}
+	b.WriteString("# HELP huskyci_http_request_duration_seconds Time spent serving requests, by method, route and status.\n")
+	b.WriteString("# TYPE huskyci_http_request_duration_seconds summary\n")
+	for _, k := range keys {
+		fmt.Fprintf(&b, "huskyci_http_request_duration_seconds_sum{%s} %g\n", labels(k), r.requests[k].seconds)
+		fmt.Fprintf(&b, "huskyci_http_request_duration_seconds_count{%s} %d\n", labels(k), r.requests[k].count)
+	}
+	names := make([]string, 0, len(r.gauges))
+	for name := range r.gauges {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n%s %g\n", name, r.gauges[name].help, name, name, r.gauges[name].value)
+	}
+	r.mu.Unlock()
+
+	return c.Blob(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(b.String()))
+}
+
+// labels returns the labels of the series of k.
+func labels(k key) string {
+	return fmt.Sprintf("method=%q,route=%q,status=\"%d\"", k.method, k.route, k.status)
+}
@@ -0,0 +1,61 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/huskyci-org/huskyCI/api/metrics"
+	"github.com/labstack/echo/v4"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Registry", func() {
+
+	var (
+		registry *Registry
+		e        *echo.Echo
+	)
+
+	BeforeEach(func() {
+		registry = NewRegistry()
+		e = echo.New()
+		e.Use(registry.Middleware())
+		e.GET("/analysis/:id", func(c echo.Context) error {
+			if c.Param("id") == "missing" {
+				return echo.NewHTTPError(http.StatusNotFound, "not found")
+			}
+			return c.String(http.StatusOK, "ok")
+		})
+		e.GET("/metrics", registry.Handler)
+	})
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	It("Should count the requests by route and status", func() {
+		serve("/analysis/1")
+		serve("/analysis/2")
+		serve("/analysis/missing")
+		serve("/unknown")
+
+		rec := serve("/metrics")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(ContainSubstring(`huskyci_http_requests_total{method="GET",route="/analysis/:id",status="200"} 2`))
+		Expect(rec.Body.String()).To(ContainSubstring(`huskyci_http_requests_total{method="GET",route="/analysis/:id",status="404"} 1`))
+		Expect(rec.Body.String()).To(ContainSubstring(`huskyci_http_requests_total{method="GET",route="unmatched",status="404"} 1`))
+		Expect(rec.Body.String()).To(ContainSubstring(`huskyci_http_request_duration_seconds_count{method="GET",route="/analysis/:id",status="200"} 2`))
+		Expect(rec.Body.String()).To(ContainSubstring("huskyci_http_requests_in_flight 1"))
+	})
+
+	It("Should expose the gauges set", func() {
+		registry.SetGauge("huskyci_analyses_running", "Analyses running.", 3)
+		registry.SetGauge("huskyci_analyses_running", "Analyses running.", 5)
+
+		rec := serve("/metrics")
+		Expect(rec.Body.String()).To(ContainSubstring("# TYPE huskyci_analyses_running gauge\nhuskyci_analyses_running 5\n"))
+	})
+})
@@ -0,0 +1,252 @@
+package openapi
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Client returns the source of the Go client package packageName for api. The
+// generated code relies on the Client type and do method written by hand in
+// that package. WebSocket and server-sent events routes are left out, as they
+// need a client of their own.
+func (api *API) Client(packageName string) ([]byte, error) {
+	body := &bytes.Buffer{}
+	imports := map[string]bool{"context": true}
+
+	names := make([]string, 0, len(api.Components))
+	for name := range api.Components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(body, "// %s is the %s schema of the huskyCI API.\n", name, name)
+		fmt.Fprintf(body, "type %s struct {\n", name)
+		for _, field := range api.Components[name].Fields {
+			tag := field.JSON
+			if field.OmitEmpty {
+				tag += ",omitempty"
+			}
+			fmt.Fprintf(body, "%s %s `json:\"%s\"`\n", field.Go, goType(field.Schema, imports), tag)
+		}
+		body.WriteString("}\n\n")
+	}
+
+	for _, operation := range api.Operations {
+		if operation.WebSocket() || operation.Events() {
+			continue
+		}
+		writeOperation(body, operation, imports)
+	}
+
+	source := &bytes.Buffer{}
+	source.WriteString("// Code generated by api/openapi/gen from the huskyCI API route annotations. DO NOT EDIT.\n\n")
+	fmt.Fprintf(source, "package %s\n\nimport (\n", packageName)
+	importPaths := make([]string, 0, len(imports))
+	for path := range imports {
+		importPaths = append(importPaths, path)
+	}
+	sort.Strings(importPaths)
+	for _, path := range importPaths {
+		fmt.Fprintf(source, "%q\n", path)
+	}
+	source.WriteString(")\n\n")
+	source.Write(body.Bytes())
+
+	formatted, err := format.Source(source.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated client does not compile: %w", err)
+	}
+	return formatted, nil
+}
+
+func writeOperation(body *bytes.Buffer, operation Operation, imports map[string]bool) {
+	args := []string{"ctx context.Context"}
+	pathExpr := []string{}
+	literal := ""
+	for _, segment := range strings.Split(operation.Path, "/")[1:] {
+		if !strings.HasPrefix(segment, ":") {
+			literal += "/" + segment
+			continue
+		}
+		name := goIdent(segment[1:], false)
+		args = append(args, name+" string")
+		pathExpr = append(pathExpr, fmt.Sprintf("%q", literal+"/"), "url.PathEscape("+name+")")
+		literal = ""
+		imports["net/url"] = true
+	}
+	if literal != "" || len(pathExpr) == 0 {
+		pathExpr = append(pathExpr, fmt.Sprintf("%q", literal))
+	}
+
+	required, optional := []Param{}, []Param{}
+	for _, param := range operation.Params {
+		switch {
+		case param.In != "query":
+		case param.Required:
+			required = append(required, param)
+			args = append(args, goIdent(param.Name, false)+" "+goType(param.Schema, imports))
+		default:
+			optional = append(optional, param)
+		}
+	}
+	paramsType := operation.ID + "Params"
+	if len(optional) > 0 {
+		fmt.Fprintf(body, "// %s holds the optional query string parameters of %s.\n", paramsType, operation.ID)
+		fmt.Fprintf(body, "type %s struct {\n", paramsType)
+		for _, param := range optional {
+			fmt.Fprintf(body, "// %s\n%s %s\n", param.Description, goIdent(param.Name, true), goType(param.Schema, imports))
+		}
+		body.WriteString("}\n\n")
+		args = append(args, "params *"+paramsType)
+	}
+	if operation.Body != nil {
+		args = append(args, "body "+goType(operation.Body, imports))
+	}
+	for _, file := range operation.FormFiles {
+		name := goIdent(file.Name, false)
+		args = append(args, name+" io.Reader", name+"Name string")
+		imports["io"] = true
+	}
+
+	result := ""
+	success := operation.Successes[0]
+	if success.Schema != nil {
+		result = goType(success.Schema, imports)
+		if success.Schema.Ref != "" && !success.Schema.Nullable {
+			result = "*" + result
+		}
+	}
+
+	summary := []rune(operation.Summary)
+	summary[0] = unicode.ToLower(summary[0])
+	fmt.Fprintf(body, "// %s calls %s %s to %s.\n", operation.ID, operation.Method, EchoPath(operation.Path), string(summary))
+	if result == "" {
+		fmt.Fprintf(body, "func (c *Client) %s(%s) error {\n", operation.ID, strings.Join(args, ", "))
+	} else {
+		fmt.Fprintf(body, "func (c *Client) %s(%s) (%s, error) {\n", operation.ID, strings.Join(args, ", "), result)
+	}
+
+	fields := []string{
+		fmt.Sprintf("method: %q", operation.Method),
+		"path: " + strings.Join(pathExpr, " + "),
+	}
+	if operation.Security != "" {
+		fields = append(fields, "auth: "+operation.Security)
+	}
+	if len(required)+len(optional) > 0 {
+		imports["net/url"] = true
+		body.WriteString("query := url.Values{}\n")
+		for _, param := range required {
+			fmt.Fprintf(body, "query.Set(%q, %s)\n", param.Name, queryValue(goIdent(param.Name, false), param.Schema, imports))
+		}
+		if len(optional) > 0 {
+			body.WriteString("if params != nil {\n")
+			for _, param := range optional {
+				field := "params." + goIdent(param.Name, true)
+				condition := field + " != " + zeroValue(param.Schema)
+				if param.Schema.Type == "boolean" {
+					condition = field
+				}
+				fmt.Fprintf(body, "if %s {\nquery.Set(%q, %s)\n}\n", condition, param.Name, queryValue(field, param.Schema, imports))
+			}
+			body.WriteString("}\n")
+		}
+		fields = append(fields, "query: query")
+	}
+	if operation.Body != nil {
+		fields = append(fields, "body: body")
+	}
+	for _, file := range operation.FormFiles {
+		name := goIdent(file.Name, false)
+		fields = append(fields, fmt.Sprintf("file: &formFile{field: %q, name: %sName, content: %s}", file.Name, name, name))
+	}
+	request := "request{" + strings.Join(fields, ", ") + "}"
+
+	switch {
+	case result == "":
+		fmt.Fprintf(body, "return c.do(ctx, %s, nil)\n", request)
+	case strings.HasPrefix(result, "*"):
+		fmt.Fprintf(body, "out := &%s{}\n", result[1:])
+		fmt.Fprintf(body, "if err := c.do(ctx, %s, out); err != nil {\nreturn nil, err\n}\nreturn out, nil\n", request)
+	default:
+		fmt.Fprintf(body, "var out %s\n", result)
+		fmt.Fprintf(body, "err := c.do(ctx, %s, &out)\nreturn out, err\n", request)
+	}
+	body.WriteString("}\n\n")
+}
+
+func goType(schema *Schema, imports map[string]bool) string {
+	name := ""
+	switch {
+	case schema.Ref != "":
+		name = schema.Ref
+	case schema.Type == "string" && schema.Format == "date-time":
+		imports["time"] = true
+		name = "time.Time"
+	case schema.Type == "string" && schema.Format == "byte":
+		name = "[]byte"
+	case schema.Type == "string":
+		name = "string"
+	case schema.Type == "integer" && schema.Format == "int64":
+		name = "int64"
+	case schema.Type == "integer":
+		name = "int"
+	case schema.Type == "number":
+		name = "float64"
+	case schema.Type == "boolean":
+		name = "bool"
+	case schema.Type == "array":
+		return "[]" + goType(schema.Items, imports)
+	case schema.Type == "object" && schema.Values != nil:
+		return "map[string]" + goType(schema.Values, imports)
+	case schema.Type == "object":
+		return "map[string]interface{}"
+	default:
+		return "interface{}"
+	}
+	if schema.Nullable {
+		return "*" + name
+	}
+	return name
+}
+
+func zeroValue(schema *Schema) string {
+	switch schema.Type {
+	case "integer", "number":
+		return "0"
+	}
+	return `""`
+}
+
+func queryValue(name string, schema *Schema, imports map[string]bool) string {
+	switch schema.Type {
+	case "integer", "number", "boolean":
+		imports["fmt"] = true
+		return fmt.Sprintf("fmt.Sprint(%s)", name)
+	}
+	return name
+}
+
+// importedNames are the packages the generated client may import, which an
+// unexported identifier must not shadow.
+var importedNames = map[string]bool{"context": true, "fmt": true, "io": true, "time": true, "url": true}
+
+// goIdent turns a parameter name such as metric_type or repositoryURL into a
+// Go identifier, exported or not.
+func goIdent(name string, exported bool) string {
+	parts := strings.Split(name, "_")
+	for i, part := range parts {
+		if i > 0 || exported {
+			parts[i] = GoName(part)
+		}
+	}
+	ident := strings.Join(parts, "")
+	if !exported && importedNames[ident] {
+		ident += "Param"
+	}
+	return ident
+}
@@ -0,0 +1,46 @@
+// Command gen writes the OpenAPI 3 definition of the huskyCI API and the
+// typed Go client generated from the annotations of the route handlers.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/huskyci-org/huskyCI/api/openapi"
+)
+
+func main() {
+	apiDir := flag.String("api", ".", "directory of the huskyCI API module")
+	specPath := flag.String("spec", "openapi.json", "file the OpenAPI definition is written to")
+	clientPath := flag.String("client", "", "file the Go client is written to")
+	flag.Parse()
+
+	if err := generate(*apiDir, *specPath, *clientPath); err != nil {
+		fmt.Fprintln(os.Stderr, "openapi:", err)
+		os.Exit(1)
+	}
+}
+
+func generate(apiDir, specPath, clientPath string) error {
+	api, err := openapi.Parse(apiDir)
+	if err != nil {
+		return err
+	}
+	spec, err := api.Spec()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(specPath, spec, 0644); err != nil {
+		return err
+	}
+	if clientPath == "" {
+		return nil
+	}
+	client, err := api.Client(filepath.Base(filepath.Dir(clientPath)))
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(clientPath, client, 0644)
+}
@@ -0,0 +1,734 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+)
+
+const modulePath = "github.com/huskyci-org/huskyCI/api"
+
+// replySchema is the name of the {success, error, code, message, docsURL,
+// details} reply every route answers with when it fails, built by
+// api/apierror.
+const replySchema = "Reply"
+
+// Operation is a route described by the annotations of its handler.
+type Operation struct {
+	ID          string
+	Summary     string
+	Description string
+	Tags        []string
+	Security    string
+	Method      string
+	Path        string
+	Params      []Param
+	Body        *Schema
+	FormFiles   []Param
+	Successes   []Response
+	Failures    []Response
+}
+
+// Param is a path, query or header parameter, or a multipart form file.
+type Param struct {
+	Name        string
+	In          string
+	Description string
+	Required    bool
+	Schema      *Schema
+}
+
+// Response is a status code a route answers with. A nil Schema means an
+// empty body, Text means a text/plain body and Events a text/event-stream
+// body whose events hold Schema.
+type Response struct {
+	Code        int
+	Description string
+	Schema      *Schema
+	Text        bool
+	Events      bool
+}
+
+// Schema is either a reference to a named component (Ref), a primitive, an
+// array (Items), a map (Values) or, for components, an object (Fields). A
+// Schema without Type nor Ref accepts any JSON value.
+type Schema struct {
+	Ref      string
+	Type     string
+	Format   string
+	Nullable bool
+	Items    *Schema
+	Values   *Schema
+	Fields   []Field
+}
+
+// Field is a property of a component, with the Go name it had in the API.
+type Field struct {
+	JSON      string
+	Go        string
+	OmitEmpty bool
+	Schema    *Schema
+}
+
+// API is everything parsed from the annotated handlers.
+type API struct {
+	Version    string
+	Operations []Operation
+	Components map[string]*Schema
+}
+
+type goPackage struct {
+	path    string
+	types   map[string]*ast.TypeSpec
+	imports map[string]string
+}
+
+type parserState struct {
+	apiDir     string
+	packages   map[string]*goPackage
+	components map[string]*Schema
+	origins    map[string]string
+}
+
+// Parse reads the annotated handlers of the routes package found in apiDir
+// and resolves the Go types they reference.
+func Parse(apiDir string) (*API, error) {
+	state := &parserState{
+		apiDir:     apiDir,
+		packages:   map[string]*goPackage{},
+		components: map[string]*Schema{},
+		origins:    map[string]string{},
+	}
+	state.components[replySchema] = &Schema{Type: "object", Fields: []Field{
+		{JSON: "success", Go: "Success", Schema: &Schema{Type: "boolean"}},
+		{JSON: "error", Go: "Error", Schema: &Schema{Type: "string"}},
+		{JSON: "code", Go: "Code", OmitEmpty: true, Schema: &Schema{Type: "string"}},
+		{JSON: "message", Go: "Message", Schema: &Schema{Type: "string"}},
+		{JSON: "docsURL", Go: "DocsURL", OmitEmpty: true, Schema: &Schema{Type: "string"}},
+		{JSON: "details", Go: "Details", OmitEmpty: true, Schema: &Schema{Type: "object", Values: &Schema{Type: "string"}}},
+	}}
+	state.origins[replySchema] = "builtin"
+
+	routes, err := state.load(modulePath + "/routes")
+	if err != nil {
+		return nil, err
+	}
+	files, err := parseDir(filepath.Join(apiDir, "routes"))
+	if err != nil {
+		return nil, err
+	}
+
+	api := &API{Version: apiContext.DefaultConf.GetAPIVersion(), Components: state.components}
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			funcDecl, ok := decl.(*ast.FuncDecl)
+			if !ok || funcDecl.Doc == nil || funcDecl.Recv != nil {
+				continue
+			}
+			operation, found, err := state.parseOperation(routes, funcDecl)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", funcDecl.Name.Name, err)
+			}
+			if found {
+				api.Operations = append(api.Operations, operation)
+			}
+		}
+	}
+	sort.Slice(api.Operations, func(i, j int) bool {
+		if api.Operations[i].Path != api.Operations[j].Path {
+			return api.Operations[i].Path < api.Operations[j].Path
+		}
+		return api.Operations[i].Method < api.Operations[j].Method
+	})
+	return api, nil
+}
+
+func (s *parserState) parseOperation(pkg *goPackage, funcDecl *ast.FuncDecl) (Operation, bool, error) {
+	operation := Operation{ID: funcDecl.Name.Name}
+	description := []string{}
+	found := false
+	for _, comment := range funcDecl.Doc.List {
+		line := strings.TrimSpace(strings.TrimPrefix(comment.Text, "//"))
+		if !strings.HasPrefix(line, "@") {
+			if !found && line != "" {
+				description = append(description, line)
+			}
+			continue
+		}
+		found = true
+		keyword, value, _ := strings.Cut(line, " ")
+		value = strings.TrimSpace(value)
+		var err error
+		switch keyword {
+		case "@Summary":
+			operation.Summary = value
+		case "@Tags":
+			operation.Tags = strings.Split(value, ",")
+		case "@Security":
+			if value != "basicAuth" && value != "huskyToken" {
+				return operation, false, fmt.Errorf("unknown security scheme %q", value)
+			}
+			operation.Security = value
+		case "@Param":
+			var param Param
+			param, err = s.parseParam(pkg, value)
+			operation.Params = append(operation.Params, param)
+		case "@Body":
+			operation.Body, err = s.parseType(pkg, value)
+		case "@FormFile":
+			name, desc, _ := strings.Cut(value, " ")
+			desc, err = strconv.Unquote(strings.TrimSpace(desc))
+			operation.FormFiles = append(operation.FormFiles, Param{Name: name, Description: desc, Required: true})
+		case "@Success":
+			var response Response
+			response, err = s.parseSuccess(pkg, value)
+			operation.Successes = append(operation.Successes, response)
+		case "@Failure":
+			var response Response
+			response, err = s.parseFailure(pkg, value)
+			operation.Failures = append(operation.Failures, response)
+		case "@Router":
+			method, path, _ := strings.Cut(value, " ")
+			operation.Method = strings.ToUpper(method)
+			operation.Path = strings.TrimSpace(path)
+		default:
+			err = fmt.Errorf("unknown annotation %s", keyword)
+		}
+		if err != nil {
+			return operation, false, fmt.Errorf("%s: %w", keyword, err)
+		}
+	}
+	if !found {
+		return operation, false, nil
+	}
+	if operation.Method == "" || operation.Path == "" {
+		return operation, false, fmt.Errorf("missing @Router")
+	}
+	if len(operation.Successes) == 0 {
+		return operation, false, fmt.Errorf("missing @Success")
+	}
+	operation.Description = strings.Join(description, " ")
+	return operation, true, nil
+}
+
+// parseParam parses `name in type required "description"`.
+func (s *parserState) parseParam(pkg *goPackage, value string) (Param, error) {
+	fields := strings.SplitN(value, " ", 5)
+	if len(fields) != 5 {
+		return Param{}, fmt.Errorf("expected name, in, type, required and description: %q", value)
+	}
+	param := Param{Name: fields[0], In: fields[1]}
+	if param.In != "path" && param.In != "query" && param.In != "header" {
+		return param, fmt.Errorf("invalid parameter location %q", param.In)
+	}
+	var err error
+	if param.Schema, err = s.parseType(pkg, fields[2]); err != nil {
+		return param, err
+	}
+	if param.Required, err = strconv.ParseBool(fields[3]); err != nil {
+		return param, err
+	}
+	param.Description, err = strconv.Unquote(fields[4])
+	return param, err
+}
+
+// parseSuccess parses `code [events] [type]`. A string type is answered as
+// text/plain, and events of type as text/event-stream.
+func (s *parserState) parseSuccess(pkg *goPackage, value string) (Response, error) {
+	code, typeName, _ := strings.Cut(value, " ")
+	status, err := strconv.Atoi(code)
+	if err != nil {
+		return Response{}, err
+	}
+	response := Response{Code: status}
+	typeName = strings.TrimSpace(typeName)
+	if rest, ok := strings.CutPrefix(typeName, "events "); ok {
+		response.Events = true
+		typeName = strings.TrimSpace(rest)
+	}
+	switch typeName {
+	case "":
+	case "string":
+		response.Text = true
+		response.Schema = &Schema{Type: "string"}
+	default:
+		response.Schema, err = s.parseType(pkg, typeName)
+	}
+	return response, err
+}
+
+// parseFailure parses `code description`, answered with the Reply schema, or
+// `code type "description"`.
+func (s *parserState) parseFailure(pkg *goPackage, value string) (Response, error) {
+	code, desc, _ := strings.Cut(value, " ")
+	status, err := strconv.Atoi(code)
+	if err != nil {
+		return Response{}, err
+	}
+	response := Response{Code: status, Description: desc, Schema: &Schema{Ref: replySchema}}
+	if !strings.HasSuffix(desc, `"`) {
+		return response, nil
+	}
+	typeName, quoted, _ := strings.Cut(desc, " ")
+	if response.Description, err = strconv.Unquote(strings.TrimSpace(quoted)); err != nil {
+		return response, err
+	}
+	response.Schema, err = s.parseType(pkg, typeName)
+	return response, err
+}
+
+// parseType parses a Go type expression or an inline object written as
+// Name{field:type, ...}, which is registered as the Name component.
+func (s *parserState) parseType(pkg *goPackage, value string) (*Schema, error) {
+	open := strings.Index(value, "{")
+	if open < 0 {
+		expr, err := parser.ParseExpr(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid type %q: %w", value, err)
+		}
+		return s.schemaFor(pkg, expr)
+	}
+	if !strings.HasSuffix(value, "}") {
+		return nil, fmt.Errorf("invalid inline object %q", value)
+	}
+	name := value[:open]
+	component := &Schema{Type: "object"}
+	for _, property := range strings.Split(value[open+1:len(value)-1], ",") {
+		jsonName, typeName, ok := strings.Cut(strings.TrimSpace(property), ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid property %q of %s", property, name)
+		}
+		schema, err := s.parseType(pkg, strings.TrimSpace(typeName))
+		if err != nil {
+			return nil, err
+		}
+		component.Fields = append(component.Fields, Field{JSON: jsonName, Go: GoName(jsonName), Schema: schema})
+	}
+	if err := s.register(name, "inline", component); err != nil {
+		return nil, err
+	}
+	return &Schema{Ref: name}, nil
+}
+
+func (s *parserState) register(name, origin string, component *Schema) error {
+	if existing, ok := s.origins[name]; ok {
+		if existing == origin && origin != "inline" {
+			return nil
+		}
+		if existing != origin || !reflect.DeepEqual(s.components[name], component) {
+			return fmt.Errorf("schema %s is defined twice", name)
+		}
+	}
+	s.components[name] = component
+	s.origins[name] = origin
+	return nil
+}
+
+func (s *parserState) schemaFor(pkg *goPackage, expr ast.Expr) (*Schema, error) {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		switch t.Name {
+		case "string":
+			return &Schema{Type: "string"}, nil
+		case "bool":
+			return &Schema{Type: "boolean"}, nil
+		case "int", "int8", "int16", "int32", "uint", "uint8", "uint16", "uint32":
+			return &Schema{Type: "integer"}, nil
+		case "int64", "uint64":
+			return &Schema{Type: "integer", Format: "int64"}, nil
+		case "float32", "float64":
+			return &Schema{Type: "number"}, nil
+		case "any":
+			return &Schema{}, nil
+		}
+		if _, declared := pkg.types[t.Name]; !declared && s.components[t.Name] != nil {
+			return &Schema{Ref: t.Name}, nil
+		}
+		return s.namedSchema(pkg, t.Name)
+	case *ast.SelectorExpr:
+		pkgName, ok := t.X.(*ast.Ident)
+		if !ok {
+			return nil, fmt.Errorf("unsupported type %T", t.X)
+		}
+		importPath := pkg.imports[pkgName.Name]
+		if importPath == "time" && t.Sel.Name == "Time" {
+			return &Schema{Type: "string", Format: "date-time"}, nil
+		}
+		if !strings.HasPrefix(importPath, modulePath+"/") {
+			// Types from other modules are not described.
+			return &Schema{}, nil
+		}
+		other, err := s.load(importPath)
+		if err != nil {
+			return nil, err
+		}
+		return s.namedSchema(other, t.Sel.Name)
+	case *ast.StarExpr:
+		schema, err := s.schemaFor(pkg, t.X)
+		if err != nil {
+			return nil, err
+		}
+		nullable := *schema
+		nullable.Nullable = true
+		return &nullable, nil
+	case *ast.ArrayType:
+		if ident, ok := t.Elt.(*ast.Ident); ok && ident.Name == "byte" {
+			return &Schema{Type: "string", Format: "byte"}, nil
+		}
+		items, err := s.schemaFor(pkg, t.Elt)
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "array", Items: items}, nil
+	case *ast.MapType:
+		values, err := s.schemaFor(pkg, t.Value)
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "object", Values: values}, nil
+	case *ast.InterfaceType:
+		return &Schema{}, nil
+	}
+	return nil, fmt.Errorf("unsupported type %T", expr)
+}
+
+// namedSchema returns a reference to the component of the struct name declared
+// in pkg, registering it first, or the schema of its underlying type.
+func (s *parserState) namedSchema(pkg *goPackage, name string) (*Schema, error) {
+	typeSpec, ok := pkg.types[name]
+	if !ok {
+		return nil, fmt.Errorf("type %s not found in %s", name, pkg.path)
+	}
+	structType, ok := typeSpec.Type.(*ast.StructType)
+	if !ok {
+		return s.schemaFor(pkg, typeSpec.Type)
+	}
+	origin := pkg.path + "." + name
+	if s.origins[name] == origin {
+		return &Schema{Ref: name}, nil
+	}
+	// Registered before its fields so recursive types terminate.
+	component := &Schema{Type: "object"}
+	if err := s.register(name, origin, component); err != nil {
+		return nil, err
+	}
+	fields, err := s.structFields(pkg, structType)
+	if err != nil {
+		return nil, err
+	}
+	component.Fields = fields
+	return &Schema{Ref: name}, nil
+}
+
+func (s *parserState) structFields(pkg *goPackage, structType *ast.StructType) ([]Field, error) {
+	fields := []Field{}
+	for _, field := range structType.Fields.List {
+		jsonName, omitEmpty := "", false
+		if field.Tag != nil {
+			tag, _ := strconv.Unquote(field.Tag.Value)
+			jsonTag := reflect.StructTag(tag).Get("json")
+			if jsonTag == "-" {
+				continue
+			}
+			parts := strings.Split(jsonTag, ",")
+			jsonName = parts[0]
+			for _, option := range parts[1:] {
+				omitEmpty = omitEmpty || option == "omitempty"
+			}
+		}
+		if len(field.Names) == 0 {
+			// Embedded structs are flattened, as encoding/json does.
+			embedded, err := s.schemaFor(pkg, field.Type)
+			if err != nil {
+				return nil, err
+			}
+			if embedded.Ref != "" {
+				fields = append(fields, s.components[embedded.Ref].Fields...)
+			}
+			continue
+		}
+		schema, err := s.schemaFor(pkg, field.Type)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range field.Names {
+			if !name.IsExported() {
+				continue
+			}
+			property := jsonName
+			if property == "" {
+				property = name.Name
+			}
+			fields = append(fields, Field{JSON: property, Go: name.Name, OmitEmpty: omitEmpty, Schema: schema})
+		}
+	}
+	return fields, nil
+}
+
+// load parses the non test files of the package importPath of this module.
+func (s *parserState) load(importPath string) (*goPackage, error) {
+	if pkg, ok := s.packages[importPath]; ok {
+		return pkg, nil
+	}
+	dir := filepath.Join(s.apiDir, filepath.FromSlash(strings.TrimPrefix(importPath, modulePath)))
+	files, err := parseDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	pkg := &goPackage{path: importPath, types: map[string]*ast.TypeSpec{}, imports: map[string]string{}}
+	for _, file := range files {
+		for _, spec := range file.Imports {
+			path, _ := strconv.Unquote(spec.Path.Value)
+			name := path[strings.LastIndex(path, "/")+1:]
+			if spec.Name != nil {
+				name = spec.Name.Name
+			}
+			pkg.imports[name] = path
+		}
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				pkg.types[typeSpec.Name.Name] = typeSpec
+			}
+		}
+	}
+	s.packages[importPath] = pkg
+	return pkg, nil
+}
+
+func parseDir(dir string) ([]*ast.File, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	files := []*ast.File{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// GoName returns the exported Go name of a JSON property.
+func GoName(property string) string {
+	switch strings.ToLower(property) {
+	case "id", "rid", "url", "uuid":
+		return strings.ToUpper(property)
+	case "huskytoken":
+		return "HuskyToken"
+	}
+	runes := []rune(property)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+// EchoPath converts an echo route path such as /analysis/:id to the OpenAPI
+// form /analysis/{id}.
+func EchoPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// Spec returns the OpenAPI 3 document of api.
+func (api *API) Spec() ([]byte, error) {
+	paths := map[string]map[string]interface{}{}
+	for _, operation := range api.Operations {
+		path := EchoPath(operation.Path)
+		if paths[path] == nil {
+			paths[path] = map[string]interface{}{}
+		}
+		paths[path][strings.ToLower(operation.Method)] = operation.spec()
+	}
+	schemas := map[string]interface{}{}
+	for name, component := range api.Components {
+		schemas[name] = component.spec()
+	}
+	document := map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "huskyCI API",
+			"description": "huskyCI performs security tests inside CI pipelines.",
+			"version":     api.Version,
+			"license": map[string]interface{}{
+				"name": "BSD-3-Clause",
+				"url":  "https://github.com/huskyci-org/huskyCI/blob/main/LICENSE.md",
+			},
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"securitySchemes": map[string]interface{}{
+				"basicAuth":  map[string]interface{}{"type": "http", "scheme": "basic"},
+				"huskyToken": map[string]interface{}{"type": "apiKey", "in": "header", "name": "Husky-Token"},
+			},
+		},
+	}
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func (operation Operation) spec() map[string]interface{} {
+	spec := map[string]interface{}{
+		"operationId": operation.ID,
+		"summary":     operation.Summary,
+		"tags":        operation.Tags,
+	}
+	if operation.Description != "" {
+		spec["description"] = operation.Description
+	}
+	if operation.Security != "" {
+		spec["security"] = []map[string][]string{{operation.Security: {}}}
+	}
+	if len(operation.Params) > 0 {
+		params := []interface{}{}
+		for _, param := range operation.Params {
+			params = append(params, map[string]interface{}{
+				"name":        param.Name,
+				"in":          param.In,
+				"description": param.Description,
+				"required":    param.Required,
+				"schema":      param.Schema.spec(),
+			})
+		}
+		spec["parameters"] = params
+	}
+	switch {
+	case operation.Body != nil:
+		spec["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  map[string]interface{}{"application/json": map[string]interface{}{"schema": operation.Body.spec()}},
+		}
+	case len(operation.FormFiles) > 0:
+		properties := map[string]interface{}{}
+		required := []string{}
+		for _, file := range operation.FormFiles {
+			properties[file.Name] = map[string]interface{}{"type": "string", "format": "binary", "description": file.Description}
+			required = append(required, file.Name)
+		}
+		schema := map[string]interface{}{"type": "object", "properties": properties, "required": required}
+		spec["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  map[string]interface{}{"multipart/form-data": map[string]interface{}{"schema": schema}},
+		}
+	}
+	responses := map[string]interface{}{}
+	for _, response := range append(append([]Response{}, operation.Successes...), operation.Failures...) {
+		description := response.Description
+		if description == "" {
+			description = statusDescription(response.Code)
+		}
+		entry := map[string]interface{}{"description": description}
+		if response.Schema != nil {
+			contentType := "application/json"
+			switch {
+			case response.Text:
+				contentType = "text/plain"
+			case response.Events:
+				contentType = "text/event-stream"
+			}
+			entry["content"] = map[string]interface{}{contentType: map[string]interface{}{"schema": response.Schema.spec()}}
+		}
+		responses[strconv.Itoa(response.Code)] = entry
+	}
+	spec["responses"] = responses
+	return spec
+}
+
+// WebSocket returns true if the route upgrades the connection to a WebSocket,
+// in which case its success schema describes the messages it pushes.
+func (operation Operation) WebSocket() bool {
+	return operation.Successes[0].Code == http.StatusSwitchingProtocols
+}
+
+// Events returns true if the route pushes server-sent events, in which case
+// its success schema describes the events.
+func (operation Operation) Events() bool {
+	return operation.Successes[0].Events
+}
+
+func statusDescription(code int) string {
+	switch code {
+	case 101:
+		return "Switching Protocols"
+	case 200:
+		return "OK"
+	case 201:
+		return "Created"
+	case 202:
+		return "Accepted"
+	case 204:
+		return "No Content"
+	}
+	return "Success"
+}
+
+func (schema *Schema) spec() map[string]interface{} {
+	if schema.Ref != "" {
+		ref := map[string]interface{}{"$ref": "#/components/schemas/" + schema.Ref}
+		if schema.Nullable {
+			// Siblings of $ref are ignored in OpenAPI 3.0.
+			return map[string]interface{}{"allOf": []interface{}{ref}, "nullable": true}
+		}
+		return ref
+	}
+	spec := map[string]interface{}{}
+	if schema.Type != "" {
+		spec["type"] = schema.Type
+	}
+	if schema.Format != "" {
+		spec["format"] = schema.Format
+	}
+	if schema.Nullable {
+		spec["nullable"] = true
+	}
+	if schema.Items != nil {
+		spec["items"] = schema.Items.spec()
+	}
+	if schema.Values != nil {
+		spec["additionalProperties"] = schema.Values.spec()
+	}
+	if schema.Type == "object" && schema.Values == nil {
+		properties := map[string]interface{}{}
+		for _, field := range schema.Fields {
+			properties[field.JSON] = field.Schema.spec()
+		}
+		spec["properties"] = properties
+	}
+	return spec
+}
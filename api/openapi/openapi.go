@@ -0,0 +1,45 @@
+// Package openapi serves the OpenAPI 3 definition of the huskyCI API and
+// generates it, along with the typed Go client in pkg/apiclient, from the
+// annotations of the route handlers.
+package openapi
+
+import (
+	_ "embed" // embeds openapi.json
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+//go:generate go run ./gen -api .. -spec openapi.json -client ../../pkg/apiclient/apiclient_gen.go
+
+// Spec is the OpenAPI 3 definition of every huskyCI API route.
+//
+//go:embed openapi.json
+var Spec []byte
+
+const uiPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>huskyCI API</title>
+  <link rel="stylesheet" href="https://cdn.jsdelivr.net/npm/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://cdn.jsdelivr.net/npm/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({url: "/swagger/openapi.json", dom_id: "#swagger-ui"});
+  </script>
+</body>
+</html>
+`
+
+// SpecHandler returns the OpenAPI 3 definition of the API.
+func SpecHandler(c echo.Context) error {
+	return c.Blob(http.StatusOK, echo.MIMEApplicationJSONCharsetUTF8, Spec)
+}
+
+// UIHandler returns a Swagger UI page browsing the OpenAPI 3 definition.
+func UIHandler(c echo.Context) error {
+	return c.HTML(http.StatusOK, uiPage)
+}
@@ -0,0 +1,99 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/api/openapi"
+	"github.com/huskyci-org/huskyCI/api/router"
+	"github.com/labstack/echo/v4"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// unversioned returns path without the prefix of its version of the API.
+func unversioned(path string) string {
+	for _, prefix := range []string{router.V1Prefix, router.V2Prefix} {
+		if strings.HasPrefix(path, prefix+"/") {
+			return strings.TrimPrefix(path, prefix)
+		}
+	}
+	return path
+}
+
+var _ = Describe("OpenAPI", func() {
+
+	api, err := openapi.Parse("..")
+
+	Context("When the annotations of the route handlers are parsed", func() {
+		It("Should not return an error", func() {
+			Expect(err).NotTo(HaveOccurred())
+		})
+		It("Should match the committed openapi.json", func() {
+			spec, err := api.Spec()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(openapi.Spec)).To(Equal(string(spec)), "run go generate ./openapi")
+		})
+		It("Should match the committed Go client", func() {
+			client, err := api.Client("apiclient")
+			Expect(err).NotTo(HaveOccurred())
+			committed, err := os.ReadFile("../../pkg/apiclient/apiclient_gen.go")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(committed)).To(Equal(string(client)), "run go generate ./openapi")
+		})
+		It("Should describe every route of every version of the API", func() {
+			e := echo.New()
+			router.Register(e, time.Time{})
+			described := map[string]bool{}
+			for _, operation := range api.Operations {
+				described[operation.Method+" "+unversioned(operation.Path)] = true
+			}
+			Expect(e.Routes()).NotTo(BeEmpty())
+			for _, route := range e.Routes() {
+				if strings.HasPrefix(route.Path, "/swagger") {
+					// The definition does not describe itself.
+					continue
+				}
+				Expect(described).To(HaveKey(route.Method + " " + unversioned(route.Path)))
+			}
+		})
+	})
+
+	Context("When the OpenAPI definition is requested", func() {
+		It("Should return a valid OpenAPI 3 JSON document", func() {
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/swagger/openapi.json", nil), rec)
+			Expect(openapi.SpecHandler(c)).To(Succeed())
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Header().Get(echo.HeaderContentType)).To(HavePrefix(echo.MIMEApplicationJSON))
+			document := map[string]interface{}{}
+			Expect(json.Unmarshal(rec.Body.Bytes(), &document)).To(Succeed())
+			Expect(document["openapi"]).To(HavePrefix("3."))
+			Expect(document["paths"]).To(HaveKey("/analysis/{id}"))
+		})
+	})
+
+	Context("When the Swagger UI is requested", func() {
+		It("Should return a page loading the OpenAPI definition", func() {
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/swagger", nil), rec)
+			Expect(openapi.UIHandler(c)).To(Succeed())
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(strings.Contains(rec.Body.String(), "/swagger/openapi.json")).To(BeTrue())
+		})
+	})
+
+	Context("When an echo path has parameters", func() {
+		It("Should return the OpenAPI path template", func() {
+			Expect(openapi.EchoPath("/stats/:metric_type")).To(Equal("/stats/{metric_type}"))
+			Expect(openapi.EchoPath("/analyses")).To(Equal("/analyses"))
+		})
+	})
+})
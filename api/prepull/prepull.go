@@ -0,0 +1,103 @@
+package prepull
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	huskydocker "github.com/huskyci-org/huskyCI/api/dockers"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/signature"
+	apiUtil "github.com/huskyci-org/huskyCI/api/util/api"
+)
+
+const logActionPrepull = "Prepull"
+const logInfoPrepull = "PREPULL"
+
+// lockTTL bounds how long a replica may hold the pre-pull lock if it dies mid-run.
+const lockTTL = time.Hour
+
+// ErrAlreadyRunning is returned when a pre-pull is triggered while another one is in progress.
+var ErrAlreadyRunning = errors.New("image pre-pull already running")
+
+var running int32
+
+// Result summarizes a pre-pull run.
+type Result struct {
+	Hosts  int      `json:"hosts"`
+	Images int      `json:"images"`
+	Pulled int      `json:"pulled"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Run pulls every securityTest image stored in MongoDB on every Docker API host.
+// If refresh is true, images already present are pulled again to pick up new
+// digests of mutable tags. Only one pre-pull runs at a time across all replicas.
+func Run(refresh bool) (Result, error) {
+	result := Result{}
+	if os.Getenv("HUSKYCI_INFRASTRUCTURE_USE") != "docker" {
+		return result, errors.New("image pre-pull is only supported with docker infrastructure")
+	}
+	if !atomic.CompareAndSwapInt32(&running, 0, 1) {
+		return result, ErrAlreadyRunning
+	}
+	defer atomic.StoreInt32(&running, 0)
+
+	hostname, _ := os.Hostname()
+	acquired, err := apiContext.APIConfiguration.DBInstance.AcquireLock("prepull", hostname, lockTTL)
+	if err != nil {
+		return result, err
+	}
+	if !acquired {
+		return result, ErrAlreadyRunning
+	}
+	defer apiContext.APIConfiguration.DBInstance.ReleaseLock("prepull", hostname)
+
+	securityTests, err := apiContext.APIConfiguration.DBInstance.FindAllDBSecurityTest(map[string]interface{}{})
+	if err != nil {
+		return result, err
+	}
+	hosts := apiUtil.DockerHosts(apiContext.APIConfiguration)
+	result.Hosts = len(hosts)
+
+	images := map[string]bool{}
+	log.Info(logActionPrepull, logInfoPrepull, 37, len(hosts), len(securityTests))
+	for _, securityTest := range securityTests {
+		if securityTest.Image == "" {
+			continue
+		}
+		securityTest.Image = apiContext.APIConfiguration.OfflineConfig.MirrorImage(securityTest.Image)
+		fullImage := signature.ImageReference(securityTest)
+		if images[fullImage] {
+			continue
+		}
+		images[fullImage] = true
+		for _, host := range hosts {
+			if err := huskydocker.PrepullImage(securityTest.Image, signature.ImageVersion(securityTest), host, refresh); err != nil {
+				log.Error(logActionPrepull, logInfoPrepull, 3028, fullImage, host, err)
+				result.Errors = append(result.Errors, fmt.Sprintf("%s on %s: %v", fullImage, host, err))
+				continue
+			}
+			result.Pulled++
+		}
+	}
+	result.Images = len(images)
+	log.Info(logActionPrepull, logInfoPrepull, 38, result.Pulled, len(result.Errors))
+	return result, nil
+}
+
+// Schedule runs a pre-pull right away and then refreshes the images every
+// interval. It never returns and is meant to run in its own goroutine.
+func Schedule(interval time.Duration) {
+	refresh := false
+	for {
+		if _, err := Run(refresh); err != nil && err != ErrAlreadyRunning {
+			log.Error(logActionPrepull, logInfoPrepull, 3028, err)
+		}
+		refresh = true
+		time.Sleep(interval)
+	}
+}
@@ -0,0 +1,139 @@
+// Package registryauth finds the credentials the images of private container
+// registries are pulled with, stored by the admins or set in
+// HUSKYCI_REGISTRY_CREDENTIALS, and encodes them for the Docker API and for
+// the image pull secrets of Kubernetes.
+package registryauth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types/registry"
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/types"
+	"github.com/huskyci-org/huskyCI/api/vault"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// DockerHub is the registry of the images whose reference names none.
+const DockerHub = "docker.io"
+
+// dockerHubServer is the address Docker Hub credentials are given for.
+const dockerHubServer = "https://index.docker.io/v1/"
+
+// CredentialsEnv is the environment variable holding the credentials of the
+// registries as comma separated registry=username:secret entries. Vault can
+// fill it like any other secret of the API.
+const CredentialsEnv = "HUSKYCI_REGISTRY_CREDENTIALS"
+
+// NormalizeRegistry returns registry in the form credentials are stored and
+// looked up with: a lower case host, with its port if any, without scheme or
+// path, Docker Hub being docker.io.
+func NormalizeRegistry(registry string) string {
+	normalized := strings.ToLower(strings.TrimSpace(registry))
+	normalized = strings.TrimPrefix(strings.TrimPrefix(normalized, "https://"), "http://")
+	if i := strings.Index(normalized, "/"); i >= 0 {
+		normalized = normalized[:i]
+	}
+	switch normalized {
+	case "index.docker.io", "registry-1.docker.io", "registry.hub.docker.com":
+		return DockerHub
+	}
+	return normalized
+}
+
+// Registry returns the registry image is pulled from: the host its reference
+// starts with, such as registry.example.com:5000 in
+// registry.example.com:5000/org/scanner:1.0, or else docker.io.
+func Registry(image string) string {
+	parts := strings.SplitN(strings.TrimSpace(image), "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		return NormalizeRegistry(parts[0])
+	}
+	return DockerHub
+}
+
+// ParseCredentials returns the credentials of value, a list of comma
+// separated registry=username:secret entries, by registry.
+func ParseCredentials(value string) (map[string]types.RegistryCredential, error) {
+	credentials := map[string]types.RegistryCredential{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		host, userSecret, _ := strings.Cut(entry, "=")
+		username, secret, _ := strings.Cut(userSecret, ":")
+		host = NormalizeRegistry(host)
+		if host == "" || username == "" || secret == "" {
+			return nil, fmt.Errorf("invalid registry credential '%s=...' in %s, expected registry=username:secret", host, CredentialsEnv)
+		}
+		credentials[host] = types.RegistryCredential{Registry: host, Username: username, Secret: secret}
+	}
+	return credentials, nil
+}
+
+// Find returns the credential of registry: the one stored by the admins, or
+// else the one of HUSKYCI_REGISTRY_CREDENTIALS, read when used so that the
+// credentials Vault renews are picked up. The boolean is false if the
+// registry has no credential.
+func Find(registry string) (types.RegistryCredential, bool, error) {
+	registry = NormalizeRegistry(registry)
+	if apiContext.APIConfiguration != nil && apiContext.APIConfiguration.DBInstance != nil {
+		query := map[string]interface{}{"registry": registry}
+		credential, err := apiContext.APIConfiguration.DBInstance.FindOneDBRegistryCredential(query)
+		if err == nil {
+			return credential, true, nil
+		}
+		if err != mongo.ErrNoDocuments && err.Error() != "No data found" {
+			return types.RegistryCredential{}, false, err
+		}
+	}
+	credentials, err := ParseCredentials(vault.Getenv(CredentialsEnv))
+	if err != nil {
+		return types.RegistryCredential{}, false, err
+	}
+	credential, ok := credentials[registry]
+	return credential, ok, nil
+}
+
+// ForImage returns the credential of the registry image is pulled from, as
+// Find does.
+func ForImage(image string) (types.RegistryCredential, bool, error) {
+	return Find(Registry(image))
+}
+
+// serverAddress returns the address the credentials of registry are given
+// for to the Docker API.
+func serverAddress(registry string) string {
+	if registry == DockerHub {
+		return dockerHubServer
+	}
+	return registry
+}
+
+// EncodeAuth returns credential encoded as the RegistryAuth of the image
+// pulls of the Docker API.
+func EncodeAuth(credential types.RegistryCredential) (string, error) {
+	return registry.EncodeAuthConfig(registry.AuthConfig{
+		Username:      credential.Username,
+		Password:      credential.Secret,
+		ServerAddress: serverAddress(credential.Registry),
+	})
+}
+
+// DockerConfigJSON returns credentials in the .dockerconfigjson format of
+// the image pull secrets of Kubernetes.
+func DockerConfigJSON(credentials ...types.RegistryCredential) ([]byte, error) {
+	auths := map[string]registry.AuthConfig{}
+	for _, credential := range credentials {
+		auths[serverAddress(credential.Registry)] = registry.AuthConfig{
+			Username: credential.Username,
+			Password: credential.Secret,
+			Auth:     base64.StdEncoding.EncodeToString([]byte(credential.Username + ":" + credential.Secret)),
+		}
+	}
+	return json.Marshal(map[string]interface{}{"auths": auths})
+}
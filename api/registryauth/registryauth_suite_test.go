@@ -0,0 +1,13 @@
+package registryauth_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestRegistryauth(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Registryauth Suite")
+}
@@ -0,0 +1,94 @@
+package registryauth_test
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/docker/docker/api/types/registry"
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	. "github.com/huskyci-org/huskyCI/api/registryauth"
+	"github.com/huskyci-org/huskyCI/api/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Registryauth", func() {
+
+	Describe("Registry", func() {
+		It("Should return the host the image reference starts with, or docker.io", func() {
+			Expect(Registry("huskyci/gosec:2.0")).To(Equal("docker.io"))
+			Expect(Registry("alpine")).To(Equal("docker.io"))
+			Expect(Registry("docker.io/huskyci/gosec:2.0")).To(Equal("docker.io"))
+			Expect(Registry("Registry.Example.com:5000/org/scanner@sha256:abc")).To(Equal("registry.example.com:5000"))
+			Expect(Registry("localhost/scanner")).To(Equal("localhost"))
+		})
+	})
+
+	Describe("NormalizeRegistry", func() {
+		It("Should drop the scheme and path and name Docker Hub docker.io", func() {
+			Expect(NormalizeRegistry("https://registry.example.com/v2/")).To(Equal("registry.example.com"))
+			Expect(NormalizeRegistry("index.docker.io")).To(Equal("docker.io"))
+		})
+	})
+
+	Describe("ParseCredentials", func() {
+		It("Should read registry=username:secret entries", func() {
+			credentials, err := ParseCredentials("registry.example.com=huskyci:s3:cr3t, docker.io=bot:token")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(credentials).To(HaveLen(2))
+			Expect(credentials["registry.example.com"]).To(Equal(types.RegistryCredential{Registry: "registry.example.com", Username: "huskyci", Secret: "s3:cr3t"}))
+		})
+		It("Should reject an entry without secret, without revealing it", func() {
+			_, err := ParseCredentials("registry.example.com=huskyci")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("registry=username:secret"))
+		})
+	})
+
+	Describe("Find", func() {
+		var previousConfig *apiContext.APIConfig
+		BeforeEach(func() {
+			previousConfig = apiContext.APIConfiguration
+			apiContext.APIConfiguration = &apiContext.APIConfig{}
+			os.Setenv(CredentialsEnv, "registry.example.com=huskyci:s3cr3t")
+		})
+		AfterEach(func() {
+			apiContext.APIConfiguration = previousConfig
+			os.Unsetenv(CredentialsEnv)
+		})
+
+		It("Should find the credential of the registry of an image in the environment", func() {
+			credential, found, err := ForImage("registry.example.com/org/scanner:1.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(credential.Username).To(Equal("huskyci"))
+
+			_, found, err = ForImage("huskyci/gosec:2.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeFalse())
+		})
+	})
+
+	Describe("EncodeAuth", func() {
+		It("Should encode the credential for the Docker API", func() {
+			encoded, err := EncodeAuth(types.RegistryCredential{Registry: "docker.io", Username: "bot", Secret: "token"})
+			Expect(err).NotTo(HaveOccurred())
+			auth, err := registry.DecodeAuthConfig(encoded)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(auth.Username).To(Equal("bot"))
+			Expect(auth.Password).To(Equal("token"))
+			Expect(auth.ServerAddress).To(Equal("https://index.docker.io/v1/"))
+		})
+	})
+
+	Describe("DockerConfigJSON", func() {
+		It("Should hold the auth of each registry", func() {
+			config, err := DockerConfigJSON(types.RegistryCredential{Registry: "registry.example.com", Username: "huskyci", Secret: "s3cr3t"})
+			Expect(err).NotTo(HaveOccurred())
+			parsed := map[string]map[string]map[string]string{}
+			Expect(json.Unmarshal(config, &parsed)).To(Succeed())
+			Expect(parsed["auths"]["registry.example.com"]["auth"]).To(Equal("aHVza3ljaTpzM2NyM3Q="))
+		})
+	})
+})
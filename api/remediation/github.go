@@ -0,0 +1,118 @@
+package remediation
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// github opens pull requests through the REST API of GitHub or GitHub
+// Enterprise Server.
+type github struct {
+	endpoint   string
+	repository string
+	token      string
+	// shas holds the blob SHA of the files read, required to update them.
+	shas map[string]string
+}
+
+func (g *github) setAuth(req *http.Request) {
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+g.token)
+}
+
+func (g *github) url(format string, args ...interface{}) string {
+	return g.endpoint + "/repos/" + g.repository + fmt.Sprintf(format, args...)
+}
+
+// escapePath escapes each segment of a file path of the repository.
+func escapePath(filePath string) string {
+	segments := strings.Split(filePath, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func (g *github) listFiles(ctx context.Context, ref string) ([]string, error) {
+	tree := struct {
+		Tree []struct {
+			Path string `json:"path"`
+			Type string `json:"type"`
+		} `json:"tree"`
+	}{}
+	if _, err := do(ctx, g.setAuth, http.MethodGet, g.url("/git/trees/%s?recursive=1", url.PathEscape(ref)), nil, &tree); err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for _, entry := range tree.Tree {
+		if entry.Type == "blob" {
+			files = append(files, entry.Path)
+		}
+	}
+	return files, nil
+}
+
+func (g *github) readFile(ctx context.Context, filePath, ref string) (string, error) {
+	file := struct {
+		Content  string `json:"content"`
+		Encoding string `json:"encoding"`
+		SHA      string `json:"sha"`
+	}{}
+	if _, err := do(ctx, g.setAuth, http.MethodGet, g.url("/contents/%s?ref=%s", escapePath(filePath), url.QueryEscape(ref)), nil, &file); err != nil {
+		return "", err
+	}
+	if file.Encoding != "base64" {
+		return "", fmt.Errorf("unexpected encoding '%s' of %s", file.Encoding, filePath)
+	}
+	content, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(file.Content, "\n", ""))
+	if err != nil {
+		return "", err
+	}
+	g.shas[filePath] = file.SHA
+	return string(content), nil
+}
+
+func (g *github) openPullRequest(ctx context.Context, pr pullRequest) (string, error) {
+	baseRef := struct {
+		Object struct {
+			SHA string `json:"sha"`
+		} `json:"object"`
+	}{}
+	if _, err := do(ctx, g.setAuth, http.MethodGet, g.url("/git/ref/heads/%s", escapePath(pr.base)), nil, &baseRef); err != nil {
+		return "", err
+	}
+	newRef := map[string]string{"ref": "refs/heads/" + pr.branch, "sha": baseRef.Object.SHA}
+	if _, err := do(ctx, g.setAuth, http.MethodPost, g.url("/git/refs"), newRef, nil); err != nil {
+		var statusErr *statusError
+		if errors.As(err, &statusErr) && statusErr.code == http.StatusUnprocessableEntity && strings.Contains(statusErr.body, "already exists") {
+			return "", ErrAlreadyOpen
+		}
+		return "", err
+	}
+
+	for _, filePath := range pr.paths() {
+		update := map[string]string{
+			"message": fmt.Sprintf("%s\n\nUpdate %s.", pr.title, filePath),
+			"content": base64.StdEncoding.EncodeToString([]byte(pr.files[filePath])),
+			"sha":     g.shas[filePath],
+			"branch":  pr.branch,
+		}
+		if _, err := do(ctx, g.setAuth, http.MethodPut, g.url("/contents/%s", escapePath(filePath)), update, nil); err != nil {
+			return "", err
+		}
+	}
+
+	pull := struct {
+		HTMLURL string `json:"html_url"`
+	}{}
+	newPull := map[string]string{"title": pr.title, "head": pr.branch, "base": pr.base, "body": pr.body}
+	if _, err := do(ctx, g.setAuth, http.MethodPost, g.url("/pulls"), newPull, &pull); err != nil {
+		return "", err
+	}
+	return pull.HTMLURL, nil
+}
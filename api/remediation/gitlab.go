@@ -0,0 +1,104 @@
+package remediation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// gitlabPageSize is the number of files listed per request, the maximum GitLab
+// allows.
+const gitlabPageSize = 100
+
+// gitlabMaxPages bounds the listing of the files of very large repositories.
+const gitlabMaxPages = 100
+
+// gitlab opens merge requests through the REST API of GitLab.
+type gitlab struct {
+	endpoint string
+	project  string
+	token    string
+}
+
+func (g *gitlab) setAuth(req *http.Request) {
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("PRIVATE-TOKEN", g.token)
+}
+
+func (g *gitlab) url(format string, args ...interface{}) string {
+	return g.endpoint + "/projects/" + g.project + fmt.Sprintf(format, args...)
+}
+
+func (g *gitlab) listFiles(ctx context.Context, ref string) ([]string, error) {
+	files := []string{}
+	for page := 1; page <= gitlabMaxPages; page++ {
+		entries := []struct {
+			Path string `json:"path"`
+			Type string `json:"type"`
+		}{}
+		treeURL := g.url("/repository/tree?recursive=true&ref=%s&per_page=%d&page=%d", url.QueryEscape(ref), gitlabPageSize, page)
+		if _, err := do(ctx, g.setAuth, http.MethodGet, treeURL, nil, &entries); err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.Type == "blob" {
+				files = append(files, entry.Path)
+			}
+		}
+		if len(entries) < gitlabPageSize {
+			break
+		}
+	}
+	return files, nil
+}
+
+func (g *gitlab) readFile(ctx context.Context, filePath, ref string) (string, error) {
+	fileURL := g.url("/repository/files/%s/raw?ref=%s", url.PathEscape(filePath), url.QueryEscape(ref))
+	content, err := do(ctx, g.setAuth, http.MethodGet, fileURL, nil, nil)
+	return string(content), err
+}
+
+// gitlabAction is a change of a file in a GitLab commit.
+type gitlabAction struct {
+	Action   string `json:"action"`
+	FilePath string `json:"file_path"`
+	Content  string `json:"content"`
+}
+
+func (g *gitlab) openPullRequest(ctx context.Context, pr pullRequest) (string, error) {
+	actions := []gitlabAction{}
+	for _, filePath := range pr.paths() {
+		actions = append(actions, gitlabAction{Action: "update", FilePath: filePath, Content: pr.files[filePath]})
+	}
+	commit := map[string]interface{}{
+		"branch":         pr.branch,
+		"start_branch":   pr.base,
+		"commit_message": pr.title,
+		"actions":        actions,
+	}
+	if _, err := do(ctx, g.setAuth, http.MethodPost, g.url("/repository/commits"), commit, nil); err != nil {
+		var statusErr *statusError
+		if errors.As(err, &statusErr) && statusErr.code == http.StatusBadRequest && strings.Contains(statusErr.body, "already exists") {
+			return "", ErrAlreadyOpen
+		}
+		return "", err
+	}
+
+	mergeRequest := struct {
+		WebURL string `json:"web_url"`
+	}{}
+	newMergeRequest := map[string]interface{}{
+		"source_branch":        pr.branch,
+		"target_branch":        pr.base,
+		"title":                pr.title,
+		"description":          pr.body,
+		"remove_source_branch": true,
+	}
+	if _, err := do(ctx, g.setAuth, http.MethodPost, g.url("/merge_requests"), newMergeRequest, &mergeRequest); err != nil {
+		return "", err
+	}
+	return mergeRequest.WebURL, nil
+}
@@ -0,0 +1,174 @@
+package remediation
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/huskyci-org/huskyCI/api/fixversion"
+	"github.com/huskyci-org/huskyCI/pkg/packagejson"
+)
+
+// maxManifestDepth is how deep manifests are looked for in a repository, as
+// the safety securityTest does when it looks for requirements files.
+const maxManifestDepth = 3
+
+// manifestEcosystem returns the ecosystem of the dependencies declared by the
+// file at filePath, "" if it is not a manifest remediations change.
+func manifestEcosystem(filePath string) string {
+	segments := strings.Split(filePath, "/")
+	if len(segments) > maxManifestDepth {
+		return ""
+	}
+	for _, segment := range segments[:len(segments)-1] {
+		switch segment {
+		case "node_modules", "vendor", ".git":
+			return ""
+		}
+	}
+	name := path.Base(filePath)
+	switch {
+	case name == "package.json":
+		return EcosystemNpm
+	case name == "go.mod":
+		return EcosystemGo
+	case strings.HasPrefix(name, "requirements") && strings.HasSuffix(name, ".txt"):
+		return EcosystemPyPI
+	}
+	return ""
+}
+
+// bumpManifest returns content with the dependencies of upgrades it declares
+// upgraded, and the upgrades it applied.
+func bumpManifest(filePath, content string, upgrades []Upgrade) (string, []Upgrade) {
+	ecosystem := manifestEcosystem(filePath)
+	ecosystemUpgrades := []Upgrade{}
+	for _, upgrade := range upgrades {
+		if upgrade.Ecosystem == ecosystem {
+			ecosystemUpgrades = append(ecosystemUpgrades, upgrade)
+		}
+	}
+	if len(ecosystemUpgrades) == 0 {
+		return content, nil
+	}
+	switch ecosystem {
+	case EcosystemPyPI:
+		return bumpLines(content, ecosystemUpgrades, bumpRequirement)
+	case EcosystemNpm:
+		return bumpPackageJSON(content, ecosystemUpgrades)
+	}
+	return bumpGoMod(content, ecosystemUpgrades)
+}
+
+// bumpLines rewrites each line of content with bump, for every upgrade.
+func bumpLines(content string, upgrades []Upgrade, bump func(line string, upgrade Upgrade) string) (string, []Upgrade) {
+	lines := strings.Split(content, "\n")
+	applied := []Upgrade{}
+	for _, upgrade := range upgrades {
+		changed := false
+		for i, line := range lines {
+			if newLine := bump(line, upgrade); newLine != line {
+				lines[i] = newLine
+				changed = true
+			}
+		}
+		if changed {
+			applied = append(applied, upgrade)
+		}
+	}
+	return strings.Join(lines, "\n"), applied
+}
+
+var requirementRegexp = regexp.MustCompile(`^(\s*)([A-Za-z0-9][A-Za-z0-9._-]*)(\s*\[[^\]]*\])?(\s*)([^;#]*?)(\s*(?:[;#].*)?)$`)
+
+var pypiNameSeparators = regexp.MustCompile(`[-_.]+`)
+
+// bumpRequirement upgrades a line of a requirements file declaring the package
+// of upgrade: a pinned requirement stays pinned, others get a lower bound.
+func bumpRequirement(line string, upgrade Upgrade) string {
+	match := requirementRegexp.FindStringSubmatch(line)
+	if match == nil || pypiName(match[2]) != pypiName(upgrade.Package) {
+		return line
+	}
+	specifier := strings.TrimSpace(match[5])
+	if pinned := strings.TrimPrefix(specifier, "=="); pinned != specifier && !strings.ContainsAny(pinned, ",<>!=~*") {
+		if fixversion.Compare(pinned, upgrade.Version) >= 0 {
+			return line
+		}
+		specifier = "==" + upgrade.Version
+	} else if lower := strings.TrimPrefix(specifier, ">="); lower != specifier && !strings.ContainsAny(lower, ",<>!=~*") && fixversion.Compare(lower, upgrade.Version) >= 0 {
+		return line
+	} else {
+		specifier = ">=" + upgrade.Version
+	}
+	return match[1] + match[2] + match[3] + match[4] + specifier + match[6]
+}
+
+// pypiName returns the normalized name of a PyPI package, as names are case
+// insensitive and treat "-", "_" and "." alike.
+func pypiName(name string) string {
+	return pypiNameSeparators.ReplaceAllString(strings.ToLower(name), "-")
+}
+
+// bumpPackageJSON upgrades the dependencies of a package.json, editing its
+// lines in place so its formatting is kept, and the range prefix (^, ~ or >=)
+// of each dependency too.
+func bumpPackageJSON(content string, upgrades []Upgrade) (string, []Upgrade) {
+	manifest, err := packagejson.Parse([]byte(content))
+	if err != nil {
+		return content, nil
+	}
+	return bumpLines(content, upgrades, func(line string, upgrade Upgrade) string {
+		if !strings.Contains(line, `"`+upgrade.Package+`"`) {
+			return line
+		}
+		dependencyRegexp := regexp.MustCompile(`^(\s*"` + regexp.QuoteMeta(upgrade.Package) + `"\s*:\s*")([^"]*)(".*)$`)
+		match := dependencyRegexp.FindStringSubmatch(line)
+		if match == nil || !manifest.Specs(upgrade.Package)[match[2]] || !packagejson.VersionSpecRegexp.MatchString(match[2]) {
+			return line
+		}
+		prefix := packagejson.VersionSpecRegexp.FindStringSubmatch(match[2])[1]
+		current := strings.TrimPrefix(strings.TrimPrefix(match[2], prefix), "v")
+		if !strings.ContainsAny(current, " <>|*xX") && fixversion.Compare(current, upgrade.Version) >= 0 {
+			return line
+		}
+		return match[1] + prefix + upgrade.Version + match[3]
+	})
+}
+
+var goRequireRegexp = regexp.MustCompile(`^(\s*(?:require\s+)?)(\S+)(\s+)(v[0-9]\S*)(.*)$`)
+
+// bumpGoMod upgrades the requirements of a go.mod, inside require blocks and
+// single line require directives only.
+func bumpGoMod(content string, upgrades []Upgrade) (string, []Upgrade) {
+	lines := strings.Split(content, "\n")
+	applied := []Upgrade{}
+	for _, upgrade := range upgrades {
+		version := "v" + strings.TrimPrefix(upgrade.Version, "v")
+		changed := false
+		inRequire := false
+		for i, line := range lines {
+			trimmed := strings.TrimSpace(line)
+			switch {
+			case strings.HasPrefix(trimmed, "require") && strings.HasSuffix(trimmed, "("):
+				inRequire = true
+				continue
+			case inRequire && trimmed == ")":
+				inRequire = false
+				continue
+			case !inRequire && !strings.HasPrefix(trimmed, "require "):
+				continue
+			}
+			match := goRequireRegexp.FindStringSubmatch(line)
+			if match == nil || match[2] != upgrade.Package || fixversion.Compare(match[4], version) >= 0 {
+				continue
+			}
+			lines[i] = match[1] + match[2] + match[3] + version + match[5]
+			changed = true
+		}
+		if changed {
+			applied = append(applied, upgrade)
+		}
+	}
+	return strings.Join(lines, "\n"), applied
+}
@@ -0,0 +1,371 @@
+// Package remediation opens pull requests upgrading the vulnerable
+// dependencies an analysis found to their fix versions, on the repositories
+// that opted in, hosted on GitHub or GitLab.
+package remediation
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/fixversion"
+	"github.com/huskyci-org/huskyCI/api/gitauth"
+	"github.com/huskyci-org/huskyCI/api/types"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Ecosystems of the dependencies remediations upgrade.
+const (
+	EcosystemPyPI = "pypi"
+	EcosystemNpm  = "npm"
+	EcosystemGo   = "go"
+)
+
+// DefaultGitHubEndpoint is the API of github.com, used when a github
+// remediation has no endpoint of its own.
+const DefaultGitHubEndpoint = "https://api.github.com"
+
+// DefaultGitLabEndpoint is the API of gitlab.com, used when a gitlab
+// remediation has no endpoint of its own.
+const DefaultGitLabEndpoint = "https://gitlab.com/api/v4"
+
+// ErrAlreadyOpen is returned by Open when the branch of a pull request with the
+// same upgrades already exists, so it was opened by a previous analysis.
+var ErrAlreadyOpen = errors.New("a pull request with the same upgrades was already opened")
+
+// HTTPClient sends the requests to GitHub and GitLab.
+var HTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// Upgrade is the version a vulnerable dependency is upgraded to, the highest
+// fix version of its advisories.
+type Upgrade struct {
+	Ecosystem string
+	Package   string
+	Version   string
+}
+
+// ecosystems maps the language of a vulnerability to the ecosystem of its
+// package. Go modules are upgraded once a securityTest reports the fix version
+// of vulnerable modules.
+var ecosystems = map[string]string{
+	"Python":     EcosystemPyPI,
+	"JavaScript": EcosystemNpm,
+	"Go":         EcosystemGo,
+}
+
+// Upgrades returns the upgrades fixing the vulnerable dependencies of results
+// that have a fix version, sorted by ecosystem and package. Vulnerabilities
+// marked nosec are left out.
+func Upgrades(results types.HuskyCIResults) []Upgrade {
+	outputs := []types.HuskyCISecurityTestOutput{
+		results.PythonResults.HuskyCISafetyOutput,
+		results.JavaScriptResults.HuskyCINpmAuditOutput,
+		results.JavaScriptResults.HuskyCIYarnAuditOutput,
+	}
+	byPackage := map[string]Upgrade{}
+	for _, output := range outputs {
+		vulns := append(append(append([]types.HuskyCIVulnerability{}, output.HighVulns...), output.MediumVulns...), output.LowVulns...)
+		for _, vuln := range vulns {
+			ecosystem, ok := ecosystems[vuln.Language]
+			if !ok || vuln.Package == "" || vuln.FixVersion == "" {
+				continue
+			}
+			key := ecosystem + "/" + vuln.Package
+			if ecosystem == EcosystemPyPI {
+				key = ecosystem + "/" + pypiName(vuln.Package)
+			}
+			current, found := byPackage[key]
+			if !found || fixversion.Compare(vuln.FixVersion, current.Version) > 0 {
+				byPackage[key] = Upgrade{Ecosystem: ecosystem, Package: vuln.Package, Version: vuln.FixVersion}
+			}
+		}
+	}
+	upgrades := []Upgrade{}
+	for _, upgrade := range byPackage {
+		upgrades = append(upgrades, upgrade)
+	}
+	sortUpgrades(upgrades)
+	return upgrades
+}
+
+func sortUpgrades(upgrades []Upgrade) {
+	sort.Slice(upgrades, func(i, j int) bool {
+		if upgrades[i].Ecosystem != upgrades[j].Ecosystem {
+			return upgrades[i].Ecosystem < upgrades[j].Ecosystem
+		}
+		return upgrades[i].Package < upgrades[j].Package
+	})
+}
+
+// Find returns the remediation of repositoryURL, its secret decrypted. The
+// boolean is false if the repository did not opt in.
+func Find(repositoryURL string) (types.Remediation, bool, error) {
+	query := map[string]interface{}{"repositoryURL": gitauth.NormalizeURL(repositoryURL)}
+	remediation, err := apiContext.APIConfiguration.DBInstance.FindOneDBRemediation(query)
+	if err != nil {
+		if err == mongo.ErrNoDocuments || err.Error() == "No data found" {
+			return types.Remediation{}, false, nil
+		}
+		return types.Remediation{}, false, err
+	}
+	return remediation, true, nil
+}
+
+// Open opens a pull request upgrading the vulnerable dependencies found by
+// analysis, if its repository opted in. It returns the URL of the pull request,
+// empty when the repository did not opt in or no manifest declares the
+// dependencies to upgrade.
+func Open(ctx context.Context, analysis types.Analysis) (string, error) {
+	remediation, found, err := Find(analysis.URL)
+	if err != nil || !found {
+		return "", err
+	}
+	upgrades := Upgrades(analysis.HuskyCIResults)
+	if len(upgrades) == 0 {
+		return "", nil
+	}
+	return OpenPullRequest(ctx, remediation, analysis, upgrades)
+}
+
+// host is a git hosting service pull requests are opened on.
+type host interface {
+	// listFiles returns the path of every file of the repository at ref.
+	listFiles(ctx context.Context, ref string) ([]string, error)
+	// readFile returns the content of a file of the repository at ref.
+	readFile(ctx context.Context, filePath, ref string) (string, error)
+	// openPullRequest commits the files of pr on a new branch and opens a pull
+	// request from it, returning its URL.
+	openPullRequest(ctx context.Context, pr pullRequest) (string, error)
+}
+
+// pullRequest is a pull request changing the content of some files.
+type pullRequest struct {
+	base   string
+	branch string
+	title  string
+	body   string
+	files  map[string]string
+}
+
+// paths returns the files of pr, sorted.
+func (pr pullRequest) paths() []string {
+	paths := []string{}
+	for filePath := range pr.files {
+		paths = append(paths, filePath)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// OpenPullRequest upgrades the dependencies of upgrades in the manifests of the
+// repository of analysis that declare them, and opens a pull request with the
+// changes with remediation.
+func OpenPullRequest(ctx context.Context, remediation types.Remediation, analysis types.Analysis, upgrades []Upgrade) (string, error) {
+	repositoryHost, err := newHost(remediation, analysis.URL)
+	if err != nil {
+		return "", err
+	}
+	base := remediation.BaseBranch
+	if base == "" {
+		base = analysis.Branch
+	}
+	upgradedEcosystems := map[string]bool{}
+	for _, upgrade := range upgrades {
+		upgradedEcosystems[upgrade.Ecosystem] = true
+	}
+
+	files, err := repositoryHost.listFiles(ctx, base)
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+	changed := map[string]string{}
+	applied := map[Upgrade]bool{}
+	for _, filePath := range files {
+		if !upgradedEcosystems[manifestEcosystem(filePath)] {
+			continue
+		}
+		content, err := repositoryHost.readFile(ctx, filePath, base)
+		if err != nil {
+			return "", err
+		}
+		newContent, manifestUpgrades := bumpManifest(filePath, content, upgrades)
+		if newContent == content {
+			continue
+		}
+		changed[filePath] = newContent
+		for _, upgrade := range manifestUpgrades {
+			applied[upgrade] = true
+		}
+	}
+	if len(changed) == 0 {
+		return "", nil
+	}
+
+	appliedUpgrades := []Upgrade{}
+	for upgrade := range applied {
+		appliedUpgrades = append(appliedUpgrades, upgrade)
+	}
+	sortUpgrades(appliedUpgrades)
+	pr := pullRequest{
+		base:   base,
+		branch: branchName(appliedUpgrades),
+		title:  "Upgrade vulnerable dependencies found by huskyCI",
+		files:  changed,
+	}
+	pr.body = pullRequestBody(analysis, appliedUpgrades, pr.paths())
+	return repositoryHost.openPullRequest(ctx, pr)
+}
+
+// branchName returns the branch of the pull request applying upgrades, the same
+// for the same upgrades so that a pull request is only opened once.
+func branchName(upgrades []Upgrade) string {
+	hash := sha256.New()
+	for _, upgrade := range upgrades {
+		fmt.Fprintf(hash, "%s/%s@%s\n", upgrade.Ecosystem, upgrade.Package, upgrade.Version)
+	}
+	return "huskyci/upgrade-" + hex.EncodeToString(hash.Sum(nil))[:12]
+}
+
+func pullRequestBody(analysis types.Analysis, upgrades []Upgrade, files []string) string {
+	body := &strings.Builder{}
+	fmt.Fprintf(body, "The huskyCI analysis %s found vulnerable dependencies, fixed by these upgrades:\n\n", analysis.RID)
+	body.WriteString("| Package | Ecosystem | Upgraded to |\n|---|---|---|\n")
+	for _, upgrade := range upgrades {
+		fmt.Fprintf(body, "| %s | %s | %s |\n", upgrade.Package, upgrade.Ecosystem, upgrade.Version)
+	}
+	body.WriteString("\nChanged manifests:\n")
+	for _, file := range files {
+		fmt.Fprintf(body, "- `%s`\n", file)
+	}
+	body.WriteString("\nLock files are not updated: run `npm install`, `yarn install` or `go mod tidy` on this branch if the project has some.\n")
+	return body.String()
+}
+
+func newHost(remediation types.Remediation, repositoryURL string) (host, error) {
+	repository, err := repositoryPath(repositoryURL)
+	if err != nil {
+		return nil, err
+	}
+	switch remediation.Provider {
+	case types.RemediationGitHub:
+		endpoint := remediation.Endpoint
+		if endpoint == "" {
+			endpoint = DefaultGitHubEndpoint
+		}
+		return &github{endpoint: strings.TrimSuffix(endpoint, "/"), repository: repository, token: remediation.Secret, shas: map[string]string{}}, nil
+	case types.RemediationGitLab:
+		endpoint := remediation.Endpoint
+		if endpoint == "" {
+			endpoint = DefaultGitLabEndpoint
+		}
+		return &gitlab{endpoint: strings.TrimSuffix(endpoint, "/"), project: url.PathEscape(repository), token: remediation.Secret}, nil
+	}
+	return nil, fmt.Errorf("unknown remediation provider '%s'", remediation.Provider)
+}
+
+// repositoryPath returns the path of a repository URL, HTTPS or SSH, such as
+// "org/repo" or "group/subgroup/repo".
+func repositoryPath(repositoryURL string) (string, error) {
+	normalized := gitauth.NormalizeURL(repositoryURL)
+	repository := ""
+	if parsed, err := url.Parse(normalized); err == nil && parsed.Host != "" {
+		repository = parsed.Path
+	} else if i := strings.Index(normalized, ":"); i >= 0 {
+		repository = normalized[i+1:]
+	}
+	repository = strings.Trim(repository, "/")
+	if strings.Count(repository, "/") < 1 || strings.Contains(repository, "//") {
+		return "", fmt.Errorf("'%s' is not a GitHub or GitLab repository URL", repositoryURL)
+	}
+	return repository, nil
+}
+
+// statusError is the error of a request answered with a non 2xx status.
+type statusError struct {
+	method string
+	url    string
+	code   int
+	status string
+	body   string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s %s returned %s: %s", e.method, e.url, e.status, e.body)
+}
+
+// do sends a request authenticated by setAuth and decodes the JSON response
+// into response, unless it is nil. It returns the raw body of the response.
+func do(ctx context.Context, setAuth func(*http.Request), method, endpoint string, body, response interface{}) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	setAuth(req)
+
+	resp, err := HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &statusError{method: method, url: endpoint, code: resp.StatusCode, status: resp.Status, body: strings.TrimSpace(string(respBody))}
+	}
+	if response != nil {
+		if err := json.Unmarshal(respBody, response); err != nil {
+			return nil, fmt.Errorf("invalid response to %s %s: %w", method, endpoint, err)
+		}
+	}
+	return respBody, nil
+}
+
+// Validate checks that remediation can open pull requests on its repository: a
+// known provider, an HTTP(S) endpoint, a token and a repository URL with a
+// path.
+func Validate(remediation types.Remediation) error {
+	if remediation.Provider != types.RemediationGitHub && remediation.Provider != types.RemediationGitLab {
+		return errors.New("'provider' must be github or gitlab")
+	}
+	if _, err := repositoryPath(remediation.RepositoryURL); err != nil {
+		return err
+	}
+	if remediation.Endpoint != "" {
+		parsed, err := url.Parse(remediation.Endpoint)
+		if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+			return errors.New("'endpoint' must be an HTTP(S) URL")
+		}
+	}
+	if strings.ContainsAny(remediation.BaseBranch, " ~^:?*[\\") {
+		return errors.New("'baseBranch' is not a valid branch name")
+	}
+	if remediation.Secret == "" {
+		return errors.New("'secret' can not be empty")
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+package remediation_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestRemediation(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Remediation Suite")
+}
@@ -0,0 +1,228 @@
+package remediation_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/huskyci-org/huskyCI/api/remediation"
+	"github.com/huskyci-org/huskyCI/api/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// repositoryFiles is the content of the repository served by the fake hosts.
+var repositoryFiles = map[string]string{
+	"requirements.txt":                "Django==2.2.1  # web\nrequests>=2.0\n",
+	"web/package.json":                "{\n  \"dependencies\": {\n    \"lodash\": \"^4.17.4\"\n  }\n}\n",
+	"web/node_modules/a/package.json": "{\"dependencies\": {\"lodash\": \"4.0.0\"}}",
+	"go.mod":                          "module example.com/app\n\nrequire (\n\tgolang.org/x/net v0.7.0 // indirect\n)\n",
+	"README.md":                       "lodash",
+}
+
+var upgrades = []remediation.Upgrade{
+	{Ecosystem: remediation.EcosystemGo, Package: "golang.org/x/net", Version: "0.17.0"},
+	{Ecosystem: remediation.EcosystemNpm, Package: "lodash", Version: "4.17.21"},
+	{Ecosystem: remediation.EcosystemNpm, Package: "minimist", Version: "1.2.6"},
+	{Ecosystem: remediation.EcosystemPyPI, Package: "django", Version: "2.2.28"},
+}
+
+var expectedFiles = map[string]string{
+	"requirements.txt": "Django==2.2.28  # web\nrequests>=2.0\n",
+	"web/package.json": "{\n  \"dependencies\": {\n    \"lodash\": \"^4.17.21\"\n  }\n}\n",
+	"go.mod":           "module example.com/app\n\nrequire (\n\tgolang.org/x/net v0.17.0 // indirect\n)\n",
+}
+
+var analysis = types.Analysis{RID: "RID", URL: "git@example.com:org/repo.git", Branch: "main"}
+
+// fakeGitHub serves the repository org/repo and records the files committed
+// and the pull requests opened.
+func fakeGitHub(committed map[string]string, pulls *[]map[string]string) *httptest.Server {
+	refs := map[string]bool{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Expect(r.Header.Get("Authorization")).To(Equal("Bearer token"))
+		path := strings.TrimPrefix(r.URL.Path, "/repos/org/repo")
+		switch {
+		case r.Method == http.MethodGet && path == "/git/trees/main":
+			tree := []map[string]string{{"path": "web", "type": "tree"}}
+			for filePath := range repositoryFiles {
+				tree = append(tree, map[string]string{"path": filePath, "type": "blob"})
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"tree": tree})
+		case r.Method == http.MethodGet && strings.HasPrefix(path, "/contents/"):
+			content := repositoryFiles[strings.TrimPrefix(path, "/contents/")]
+			json.NewEncoder(w).Encode(map[string]string{"content": base64.StdEncoding.EncodeToString([]byte(content)), "encoding": "base64", "sha": "blob"})
+		case r.Method == http.MethodGet && path == "/git/ref/heads/main":
+			w.Write([]byte(`{"object": {"sha": "abc1234"}}`))
+		case r.Method == http.MethodPost && path == "/git/refs":
+			ref := map[string]string{}
+			json.NewDecoder(r.Body).Decode(&ref)
+			if refs[ref["ref"]] {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				w.Write([]byte(`{"message": "Reference already exists"}`))
+				return
+			}
+			refs[ref["ref"]] = true
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodPut && strings.HasPrefix(path, "/contents/"):
+			update := map[string]string{}
+			json.NewDecoder(r.Body).Decode(&update)
+			Expect(update["sha"]).To(Equal("blob"))
+			content, _ := base64.StdEncoding.DecodeString(update["content"])
+			committed[strings.TrimPrefix(path, "/contents/")] = string(content)
+		case r.Method == http.MethodPost && path == "/pulls":
+			pull := map[string]string{}
+			json.NewDecoder(r.Body).Decode(&pull)
+			*pulls = append(*pulls, pull)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"html_url": "https://github.example.com/org/repo/pull/1"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+// fakeGitLab serves the project org/repo and records the commit and the
+// merge request created.
+func fakeGitLab(commits *[]map[string]interface{}, mergeRequests *[]map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Expect(r.Header.Get("PRIVATE-TOKEN")).To(Equal("token"))
+		path := strings.TrimPrefix(r.URL.EscapedPath(), "/projects/org%2Frepo")
+		switch {
+		case r.Method == http.MethodGet && path == "/repository/tree":
+			entries := []map[string]string{}
+			if r.URL.Query().Get("page") == "1" {
+				for filePath := range repositoryFiles {
+					entries = append(entries, map[string]string{"path": filePath, "type": "blob"})
+				}
+			}
+			json.NewEncoder(w).Encode(entries)
+		case r.Method == http.MethodGet && strings.HasPrefix(path, "/repository/files/"):
+			filePath := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/projects/org/repo/repository/files/"), "/raw")
+			fmt.Fprint(w, repositoryFiles[filePath])
+		case r.Method == http.MethodPost && path == "/repository/commits":
+			commit := map[string]interface{}{}
+			json.NewDecoder(r.Body).Decode(&commit)
+			*commits = append(*commits, commit)
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodPost && path == "/merge_requests":
+			mergeRequest := map[string]interface{}{}
+			json.NewDecoder(r.Body).Decode(&mergeRequest)
+			*mergeRequests = append(*mergeRequests, mergeRequest)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"web_url": "https://gitlab.example.com/org/repo/-/merge_requests/1"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+var _ = Describe("Remediation", func() {
+
+	Describe("Upgrades", func() {
+		It("Should return the highest fix version of each vulnerable dependency", func() {
+			results := types.HuskyCIResults{}
+			results.PythonResults.HuskyCISafetyOutput.HighVulns = []types.HuskyCIVulnerability{
+				{Language: "Python", Package: "Django", FixVersion: "2.2.24"},
+				{Language: "Python", Package: "django", FixVersion: "2.2.28"},
+				{Language: "Python", Title: "No requirements.txt found."},
+			}
+			results.JavaScriptResults.HuskyCIYarnAuditOutput.LowVulns = []types.HuskyCIVulnerability{
+				{Language: "JavaScript", Package: "lodash", FixVersion: "4.17.21"},
+			}
+			results.JavaScriptResults.HuskyCINpmAuditOutput.NoSecVulns = []types.HuskyCIVulnerability{
+				{Language: "JavaScript", Package: "minimist", FixVersion: "1.2.6"},
+			}
+			Expect(remediation.Upgrades(results)).To(Equal([]remediation.Upgrade{
+				{Ecosystem: remediation.EcosystemNpm, Package: "lodash", Version: "4.17.21"},
+				{Ecosystem: remediation.EcosystemPyPI, Package: "django", Version: "2.2.28"},
+			}))
+		})
+	})
+
+	Describe("OpenPullRequest", func() {
+		Context("When the repository is hosted on GitHub", func() {
+			It("Should open a single pull request upgrading the manifests", func() {
+				committed := map[string]string{}
+				pulls := []map[string]string{}
+				server := fakeGitHub(committed, &pulls)
+				defer server.Close()
+
+				settings := types.Remediation{Provider: types.RemediationGitHub, Endpoint: server.URL, Secret: "token"}
+				pullURL, err := remediation.OpenPullRequest(context.Background(), settings, analysis, upgrades)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(pullURL).To(Equal("https://github.example.com/org/repo/pull/1"))
+				Expect(committed).To(Equal(expectedFiles))
+				Expect(pulls).To(HaveLen(1))
+				Expect(pulls[0]["base"]).To(Equal("main"))
+				Expect(pulls[0]["head"]).To(HavePrefix("huskyci/upgrade-"))
+				Expect(pulls[0]["body"]).To(ContainSubstring("| lodash | npm | 4.17.21 |"))
+				Expect(pulls[0]["body"]).NotTo(ContainSubstring("minimist"))
+
+				_, err = remediation.OpenPullRequest(context.Background(), settings, analysis, upgrades)
+				Expect(err).To(Equal(remediation.ErrAlreadyOpen))
+				Expect(pulls).To(HaveLen(1))
+			})
+		})
+
+		Context("When the repository is hosted on GitLab", func() {
+			It("Should open a merge request from a single commit", func() {
+				commits := []map[string]interface{}{}
+				mergeRequests := []map[string]interface{}{}
+				server := fakeGitLab(&commits, &mergeRequests)
+				defer server.Close()
+
+				settings := types.Remediation{Provider: types.RemediationGitLab, Endpoint: server.URL, Secret: "token", BaseBranch: "develop"}
+				mergeRequestURL, err := remediation.OpenPullRequest(context.Background(), settings, analysis, upgrades)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mergeRequestURL).To(Equal("https://gitlab.example.com/org/repo/-/merge_requests/1"))
+				Expect(commits).To(HaveLen(1))
+				Expect(commits[0]["start_branch"]).To(Equal("develop"))
+				Expect(commits[0]["actions"]).To(HaveLen(3))
+				Expect(mergeRequests).To(HaveLen(1))
+				Expect(mergeRequests[0]["target_branch"]).To(Equal("develop"))
+				Expect(mergeRequests[0]["source_branch"]).To(Equal(commits[0]["branch"]))
+			})
+		})
+
+		Context("When no manifest declares the vulnerable dependencies", func() {
+			It("Should not open a pull request", func() {
+				committed := map[string]string{}
+				pulls := []map[string]string{}
+				server := fakeGitHub(committed, &pulls)
+				defer server.Close()
+
+				settings := types.Remediation{Provider: types.RemediationGitHub, Endpoint: server.URL, Secret: "token"}
+				onlyTransitive := []remediation.Upgrade{{Ecosystem: remediation.EcosystemNpm, Package: "minimist", Version: "1.2.6"}}
+				pullURL, err := remediation.OpenPullRequest(context.Background(), settings, analysis, onlyTransitive)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(pullURL).To(BeEmpty())
+				Expect(pulls).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("Validate", func() {
+		It("Should accept a complete remediation", func() {
+			settings := types.Remediation{RepositoryURL: "https://gitlab.com/group/sub/repo", Provider: types.RemediationGitLab, Secret: "token"}
+			Expect(remediation.Validate(settings)).To(Succeed())
+		})
+		It("Should reject an unknown provider", func() {
+			settings := types.Remediation{RepositoryURL: "https://github.com/org/repo", Provider: "bitbucket", Secret: "token"}
+			Expect(remediation.Validate(settings)).To(MatchError(ContainSubstring("'provider'")))
+		})
+		It("Should reject a repository URL without a path", func() {
+			settings := types.Remediation{RepositoryURL: "https://github.com/repo", Provider: types.RemediationGitHub, Secret: "token"}
+			Expect(remediation.Validate(settings)).NotTo(Succeed())
+		})
+		It("Should reject an empty secret", func() {
+			settings := types.Remediation{RepositoryURL: "https://github.com/org/repo", Provider: types.RemediationGitHub}
+			Expect(remediation.Validate(settings)).To(MatchError(ContainSubstring("'secret'")))
+		})
+	})
+})
@@ -0,0 +1,275 @@
+// Package router mounts the routes of each version of the huskyCI API.
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/huskyci-org/huskyCI/api/auth"
+	"github.com/huskyci-org/huskyCI/api/openapi"
+	"github.com/huskyci-org/huskyCI/api/routes"
+)
+
+const (
+	// V1Prefix is the prefix of the first version of the API, which is also
+	// mounted at the root as clients have always called it there.
+	V1Prefix = "/api/1.0"
+	// V2Prefix is the prefix of the current version of the API.
+	V2Prefix = "/api/v2"
+)
+
+// V1DeprecatedSince is when v1 was deprecated in favor of v2.
+var V1DeprecatedSince = time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC)
+
+// mount is a group of routes sharing a prefix and middlewares.
+type mount struct {
+	prefix     string
+	group      *echo.Group
+	middleware []echo.MiddlewareFunc
+}
+
+// Router adds each route to every prefix a version of the API is mounted at.
+// Its middlewares only run for the routes added, unlike the ones of an
+// echo.Group, which also catch the paths no route matches.
+type Router struct {
+	mounts []mount
+}
+
+// New returns a Router adding its routes to e under each of prefixes.
+func New(e *echo.Echo, prefixes ...string) *Router {
+	r := &Router{}
+	for _, prefix := range prefixes {
+		r.mounts = append(r.mounts, mount{prefix: prefix, group: e.Group(prefix)})
+	}
+	return r
+}
+
+// Use adds middlewares to the routes added afterwards.
+func (r *Router) Use(m ...echo.MiddlewareFunc) {
+	for i := range r.mounts {
+		r.mounts[i].middleware = append(r.mounts[i].middleware, m...)
+	}
+}
+
+// Group returns a Router adding its routes under prefix, with middlewares m
+// on top of the ones of r.
+func (r *Router) Group(prefix string, m ...echo.MiddlewareFunc) *Router {
+	sub := &Router{}
+	for _, parent := range r.mounts {
+		middlewares := append(append([]echo.MiddlewareFunc{}, parent.middleware...), m...)
+		sub.mounts = append(sub.mounts, mount{prefix: parent.prefix + prefix, group: parent.group.Group(prefix), middleware: middlewares})
+	}
+	return sub
+}
+
+// Deprecate flags the routes added afterwards as deprecated since since, to
+// be removed at sunset unless it is zero, and links each of them to the same
+// path under successor.
+func (r *Router) Deprecate(successor string, since, sunset time.Time) {
+	for i := range r.mounts {
+		r.mounts[i].middleware = append(r.mounts[i].middleware, Deprecated(r.mounts[i].prefix, successor, since, sunset))
+	}
+}
+
+func (r *Router) add(method, path string, h echo.HandlerFunc, m []echo.MiddlewareFunc) {
+	for _, mount := range r.mounts {
+		middlewares := append(append([]echo.MiddlewareFunc{}, mount.middleware...), m...)
+		mount.group.Add(method, path, h, middlewares...)
+	}
+}
+
+// GET adds a GET route to every prefix of r.
+func (r *Router) GET(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) {
+	r.add(http.MethodGet, path, h, m)
+}
+
+// POST adds a POST route to every prefix of r.
+func (r *Router) POST(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) {
+	r.add(http.MethodPost, path, h, m)
+}
+
+// PUT adds a PUT route to every prefix of r.
+func (r *Router) PUT(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) {
+	r.add(http.MethodPut, path, h, m)
+}
+
+// DELETE adds a DELETE route to every prefix of r.
+func (r *Router) DELETE(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) {
+	r.add(http.MethodDelete, path, h, m)
+}
+
+// Deprecated sets on the responses of the routes under prefix the
+// Deprecation and Sunset headers of RFC 9745 and RFC 8594, and a Link to the
+// same path under successor.
+func Deprecated(prefix, successor string, since, sunset time.Time) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			header := c.Response().Header()
+			header.Set("Deprecation", fmt.Sprintf("@%d", since.Unix()))
+			if !sunset.IsZero() {
+				header.Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+			}
+			path := strings.TrimPrefix(c.Request().URL.EscapedPath(), prefix)
+			header.Add("Link", fmt.Sprintf("<%s%s>; rel=\"successor-version\"", successor, path))
+			return next(c)
+		}
+	}
+}
+
+// smallBodySize is the request size of the routes that only receive
+// credentials.
+const smallBodySize = 16 << 10
+
+// BodyLimits returns the request sizes the routes accept instead of the
+// default one, by path as registered in Echo. The zip uploads are limited by
+// their handler, to the size of the uploads.
+func BodyLimits() map[string]int64 {
+	bodyLimits := map[string]int64{}
+	for _, prefix := range []string{"", V1Prefix, V2Prefix} {
+		bodyLimits[prefix+"/analysis/upload"] = 0
+		bodyLimits[prefix+"/token"] = smallBodySize
+		bodyLimits[prefix+"/token/deactivate"] = smallBodySize
+		bodyLimits[prefix+"/user"] = smallBodySize
+	}
+	bodyLimits[V2Prefix+"/workspace"] = 0
+	for _, path := range []string{"/admin/users", "/admin/users/:username", "/admin/users/:username/password"} {
+		bodyLimits[V2Prefix+path] = smallBodySize
+	}
+	return bodyLimits
+}
+
+// LongLived returns true for the requests of the websocket, events and
+// upload routes, which are neither timed out nor compressed.
+func LongLived(c echo.Context) bool {
+	path := c.Path()
+	return strings.HasSuffix(path, "/analysis/upload") || strings.HasSuffix(path, "/workspace") || strings.HasSuffix(path, "/ws/analysis/:id") || strings.HasSuffix(path, "/events")
+}
+
+// Register adds every route of the API to e. v1 is deprecated and, if
+// v1Sunset is not zero, removed at v1Sunset.
+func Register(e *echo.Echo, v1Sunset time.Time) {
+	// generic routes
+	e.GET("/healthcheck", routes.HealthCheck)
+	e.GET("/healthz", routes.Liveness)
+	e.GET("/readyz", routes.Readiness)
+	e.GET("/version", routes.GetAPIVersion)
+	e.GET("/metrics", routes.GetMetrics, auth.AllowAdminIPs)
+
+	// OpenAPI definition and Swagger UI
+	e.GET("/swagger", openapi.UIHandler)
+	e.GET("/swagger/openapi.json", openapi.SpecHandler)
+
+	v1 := New(e, "", V1Prefix)
+	v1.Deprecate(V2Prefix, V1DeprecatedSince, v1Sunset)
+	registerV1(v1)
+
+	v2 := New(e, V2Prefix)
+	registerV1(v2)
+	registerV2(v2)
+}
+
+// registerV1 adds the routes of v1 to r. They are all part of v2 as well.
+// Besides the routes of the first releases, v1 has the admin, cancel, watch
+// and list routes added before v2, which the client, the CLI and the SDK
+// call without its prefix.
+func registerV1(r *Router) {
+	basicAuth := auth.BasicAuth(auth.DefaultLockout())
+
+	// token routes with basic auth
+	r.POST("/token", routes.HandleToken, auth.AllowTokenIPs, auth.RequireClientCert, basicAuth)
+	r.POST("/token/deactivate", routes.HandleDeactivation, auth.AllowTokenIPs, auth.RequireClientCert, basicAuth)
+
+	// admin routes with basic auth
+	admin := r.Group("/admin", auth.AllowAdminIPs, auth.RequireClientCert, basicAuth)
+	admin.GET("/securitytests", routes.GetSecurityTests)
+	admin.PUT("/securitytests/:name", routes.UpdateSecurityTest)
+	admin.POST("/prepull", routes.TriggerPrepull)
+	admin.GET("/janitor", routes.GetJanitorStats)
+	admin.GET("/credentials", routes.GetGitCredentials)
+	admin.PUT("/credentials", routes.PutGitCredential)
+	admin.DELETE("/credentials", routes.DeleteGitCredential)
+	admin.GET("/statusreporters", routes.GetStatusReporters)
+	admin.PUT("/statusreporters", routes.PutStatusReporter)
+	admin.DELETE("/statusreporters", routes.DeleteStatusReporter)
+	admin.GET("/remediations", routes.GetRemediations)
+	admin.PUT("/remediations", routes.PutRemediation)
+	admin.DELETE("/remediations", routes.DeleteRemediation)
+
+	// analysis routes
+	r.POST("/analysis", routes.ReceiveRequest, auth.AllowAnalysisIPs, auth.RequireClientCert)
+	r.POST("/analysis/upload", routes.UploadZip, auth.AllowAnalysisIPs, auth.RequireClientCert)
+	r.GET("/analysis/:id", routes.GetAnalysis)
+	r.POST("/analysis/:id/cancel", routes.CancelAnalysis)
+	r.GET("/ws/analysis/:id", routes.WatchAnalysis)
+	r.GET("/analyses", routes.ListAnalyses)
+
+	// stats routes
+	r.GET("/stats/:metric_type", routes.GetMetric)
+
+	// user routes
+	r.PUT("/user", routes.UpdateUser)
+}
+
+// registerV2 adds the routes only v2 has to r.
+func registerV2(r *Router) {
+	basicAuth := auth.BasicAuth(auth.DefaultLockout())
+
+	// admin routes with basic auth
+	admin := r.Group("/admin", auth.AllowAdminIPs, auth.RequireClientCert, basicAuth)
+	admin.GET("/debug/trace", routes.GetDebugTrace)
+	admin.GET("/policies", routes.GetPolicies)
+	admin.PUT("/policies", routes.PutPolicy)
+	admin.DELETE("/policies", routes.DeletePolicy)
+	admin.GET("/scaling", routes.GetScalingHints)
+	admin.POST("/bench", routes.StartBench)
+	admin.GET("/bench", routes.GetBench)
+	admin.GET("/groups", routes.GetGroups)
+	admin.PUT("/groups/:name", routes.PutGroup)
+	admin.DELETE("/groups/:name", routes.DeleteGroup)
+
+	// user routes only admins can use
+	users := admin.Group("/users", auth.RequireAdmin)
+	users.GET("", routes.GetUsers)
+	users.POST("", routes.CreateUser)
+	users.PUT("/:username", routes.UpdateUserAccount)
+	users.PUT("/:username/password", routes.SetUserPassword)
+	users.DELETE("/:username", routes.DeleteUser)
+
+	// docker host routes
+	admin.GET("/dockerhosts", routes.GetDockerHosts)
+	admin.POST("/dockerhosts", routes.RegisterDockerHost)
+	admin.PUT("/dockerhosts/:address", routes.UpdateDockerHost)
+	admin.DELETE("/dockerhosts/:address", routes.DeleteDockerHost)
+	admin.GET("/registries", routes.GetRegistryCredentials)
+	admin.PUT("/registries", routes.PutRegistryCredential)
+	admin.DELETE("/registries", routes.DeleteRegistryCredential)
+
+	// analysis routes
+	r.POST("/analysis/plan", routes.PlanAnalysis, auth.AllowAnalysisIPs, auth.RequireClientCert)
+	r.POST("/analysis/:id/tests/:tool/rerun", routes.RerunSecurityTest, auth.AllowAnalysisIPs, auth.RequireClientCert)
+	r.GET("/analysis/:id/owners", routes.GetAnalysisOwners)
+	r.GET("/analysis/:id/containers/:tool/output", routes.GetContainerOutput)
+	r.GET("/events", routes.StreamEvents)
+	r.GET("/findings/search", routes.SearchFindings)
+
+	// repository routes
+	r.GET("/repository/:url/compare", routes.CompareBranches)
+	r.GET("/repository/:url/summary", routes.GetRepositorySummary)
+	r.GET("/repository/:url/badge.svg", routes.GetRepositoryBadge)
+	r.GET("/policy", routes.GetPolicy)
+
+	// workspace routes
+	r.POST("/workspace", routes.CreateWorkspace, auth.AllowAnalysisIPs, auth.RequireClientCert)
+	r.DELETE("/workspace/:id", routes.DeleteWorkspace, auth.AllowAnalysisIPs, auth.RequireClientCert)
+
+	// repository group routes
+	r.POST("/groups/:name/analyze", routes.AnalyzeGroup, auth.AllowAnalysisIPs, auth.RequireClientCert)
+	r.GET("/groups/:name/report", routes.GetGroupReport)
+
+	// securityTest routes
+	r.GET("/securitytests", routes.ListSecurityTests)
+}
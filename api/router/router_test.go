@@ -0,0 +1,142 @@
+package router_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/api/router"
+	"github.com/labstack/echo/v4"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Router", func() {
+
+	since := time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2027, time.April, 1, 0, 0, 0, 0, time.UTC)
+	ok := func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	}
+	serve := func(e *echo.Echo, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	Context("When a route is added to a deprecated version", func() {
+		e := echo.New()
+		v1 := router.New(e, "", router.V1Prefix)
+		v1.Deprecate(router.V2Prefix, since, sunset)
+		v1.Group("/admin").GET("/janitor", ok)
+		v2 := router.New(e, router.V2Prefix)
+		v2.GET("/admin/janitor", ok)
+
+		It("Should serve it under every prefix with the deprecation headers", func() {
+			for _, path := range []string{"/admin/janitor", "/api/1.0/admin/janitor"} {
+				rec := serve(e, path)
+				Expect(rec.Code).To(Equal(http.StatusOK))
+				Expect(rec.Header().Get("Deprecation")).To(Equal("@1792108800"))
+				Expect(rec.Header().Get("Sunset")).To(Equal("Thu, 01 Apr 2027 00:00:00 GMT"))
+				Expect(rec.Header().Get("Link")).To(Equal(`</api/v2/admin/janitor>; rel="successor-version"`))
+			}
+		})
+		It("Should not flag its successor", func() {
+			rec := serve(e, "/api/v2/admin/janitor")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Header().Get("Deprecation")).To(BeEmpty())
+			Expect(rec.Header().Get("Link")).To(BeEmpty())
+		})
+		It("Should not flag the paths no route matches", func() {
+			rec := serve(e, "/api/1.0/unknown")
+			Expect(rec.Code).To(Equal(http.StatusNotFound))
+			Expect(rec.Header().Get("Deprecation")).To(BeEmpty())
+		})
+	})
+
+	Context("When a deprecated version has no sunset", func() {
+		It("Should not set the Sunset header", func() {
+			e := echo.New()
+			v1 := router.New(e, router.V1Prefix)
+			v1.Deprecate(router.V2Prefix, since, time.Time{})
+			v1.GET("/version", ok)
+			rec := serve(e, "/api/1.0/version")
+			Expect(rec.Header().Get("Deprecation")).NotTo(BeEmpty())
+			Expect(rec.Header().Get("Sunset")).To(BeEmpty())
+		})
+	})
+
+	Context("When every route of the API is registered", func() {
+		e := echo.New()
+		router.Register(e, time.Time{})
+		registered := map[string]bool{}
+		for _, route := range e.Routes() {
+			registered[route.Method+" "+route.Path] = true
+		}
+
+		It("Should mount v1 at the root and at its prefix", func() {
+			Expect(registered).To(HaveKey("POST /analysis"))
+			Expect(registered).To(HaveKey("POST /api/1.0/analysis"))
+			Expect(registered).To(HaveKey("POST /token"))
+			Expect(registered).To(HaveKey("POST /api/1.0/token"))
+		})
+		It("Should mount v1 in v2", func() {
+			Expect(registered).To(HaveKey("POST /api/v2/analysis"))
+			Expect(registered).To(HaveKey("POST /api/v2/token"))
+			Expect(registered).To(HaveKey("GET /api/v2/admin/janitor"))
+		})
+		It("Should only mount the new routes in v2", func() {
+			Expect(registered).To(HaveKey("GET /api/v2/repository/:url/compare"))
+			Expect(registered).NotTo(HaveKey("GET /repository/:url/compare"))
+			Expect(registered).NotTo(HaveKey("GET /api/1.0/repository/:url/compare"))
+			Expect(registered).To(HaveKey("POST /api/v2/workspace"))
+			Expect(registered).To(HaveKey("DELETE /api/v2/workspace/:id"))
+			Expect(registered).NotTo(HaveKey("POST /workspace"))
+			Expect(registered).To(HaveKey("GET /api/v2/analysis/:id/owners"))
+			Expect(registered).NotTo(HaveKey("GET /analysis/:id/owners"))
+			Expect(registered).To(HaveKey("POST /api/v2/analysis/plan"))
+			Expect(registered).NotTo(HaveKey("POST /analysis/plan"))
+			Expect(registered).To(HaveKey("POST /api/v2/groups/:name/analyze"))
+			Expect(registered).To(HaveKey("PUT /api/v2/admin/groups/:name"))
+			Expect(registered).NotTo(HaveKey("POST /groups/:name/analyze"))
+			Expect(registered).To(HaveKey("GET /api/v2/findings/search"))
+			Expect(registered).NotTo(HaveKey("GET /findings/search"))
+			Expect(registered).To(HaveKey("GET /api/v2/events"))
+			Expect(registered).To(HaveKey("GET /api/v2/admin/scaling"))
+			Expect(registered).NotTo(HaveKey("GET /admin/scaling"))
+			Expect(registered).To(HaveKey("POST /api/v2/admin/users"))
+			Expect(registered).To(HaveKey("PUT /api/v2/admin/users/:username/password"))
+			Expect(registered).NotTo(HaveKey("POST /admin/users"))
+			Expect(registered).To(HaveKey("PUT /api/v2/admin/dockerhosts/:address"))
+			Expect(registered).NotTo(HaveKey("GET /admin/dockerhosts"))
+			Expect(registered).To(HaveKey("PUT /api/v2/admin/registries"))
+			Expect(registered).NotTo(HaveKey("PUT /admin/registries"))
+			Expect(registered).NotTo(HaveKey("GET /events"))
+		})
+		It("Should not version the generic routes", func() {
+			Expect(registered).To(HaveKey("GET /healthcheck"))
+			Expect(registered).To(HaveKey("GET /healthz"))
+			Expect(registered).To(HaveKey("GET /readyz"))
+			Expect(registered).To(HaveKey("GET /metrics"))
+			Expect(registered).NotTo(HaveKey("GET /api/v2/healthcheck"))
+		})
+	})
+
+	Context("When a route is long-lived", func() {
+		It("Should be skipped by the timeout and compression", func() {
+			e := echo.New()
+			for path, longLived := range map[string]bool{
+				"/analysis/upload":                true,
+				"/api/v2/ws/analysis/:id":         true,
+				"/api/v2/events":                  true,
+				"/api/1.0/analysis/:id":           false,
+				"/api/v2/repository/:url/compare": false,
+			} {
+				c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+				c.SetPath(path)
+				Expect(router.LongLived(c)).To(Equal(longLived), path)
+			}
+		})
+	})
+})
@@ -2,25 +2,33 @@ package routes
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
-	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/huskyci-org/huskyCI/api/analysis"
+	"github.com/huskyci-org/huskyCI/api/apierror"
 	"github.com/huskyci-org/huskyCI/api/auth"
 	apiContext "github.com/huskyci-org/huskyCI/api/context"
-	huskydocker "github.com/huskyci-org/huskyCI/api/dockers"
+	"github.com/huskyci-org/huskyCI/api/db"
 	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/securitytest"
+	"github.com/huskyci-org/huskyCI/api/storage"
 	"github.com/huskyci-org/huskyCI/api/token"
 	"github.com/huskyci-org/huskyCI/api/types"
 	"github.com/huskyci-org/huskyCI/api/util"
-	apiUtil "github.com/huskyci-org/huskyCI/api/util/api"
+	"github.com/huskyci-org/huskyCI/pkg/errcode"
 	"github.com/labstack/echo/v4"
 	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/net/websocket"
 )
 
 var (
@@ -43,7 +51,17 @@ const logActionReceiveRequest = "ReceiveRequest"
 const logActionGetAnalysis = "GetAnalysis"
 const logInfoAnalysis = "ANALYSIS"
 
-// GetAnalysis returns the status of a given analysis given a RID.
+// GetAnalysis returns the status of a given analysis given a RID. A running or
+// queued analysis comes with a Retry-After header telling when to poll it again.
+// @Summary Get an analysis
+// @Tags analysis
+// @Security huskyToken
+// @Param id path string true "Analysis RID"
+// @Success 200 types.Analysis
+// @Failure 401 Token is not allowed to read this analysis
+// @Failure 404 Analysis not found
+// @Failure 500 Internal error
+// @Router GET /analysis/:id
 func GetAnalysis(c echo.Context) error {
 
 	RID := c.Param("id")
@@ -54,144 +72,409 @@ func GetAnalysis(c echo.Context) error {
 		return err
 	}
 
-	analysisQuery := map[string]interface{}{"RID": RID}
 	log.Info(logActionGetAnalysis, logInfoAnalysis, 114, RID)
-	analysisResult, err := apiContext.APIConfiguration.DBInstance.FindOneDBAnalysis(analysisQuery)
+	analysisResult, err := analysis.Find(RID)
 
 	if err != nil {
 		if err == mongo.ErrNoDocuments || err.Error() == "No data found" {
 			log.Warning(logActionGetAnalysis, logInfoAnalysis, 106, RID)
-			reply := map[string]interface{}{
-				"success": false,
-				"error":   "analysis not found",
-				"message": fmt.Sprintf("No analysis found with RID: %s. Please verify the RID and try again.", RID),
-				"rid":     RID,
-			}
+			reply := apierror.Reply(c, errcode.NotFound, "analysis not found", fmt.Sprintf("No analysis found with RID: %s. Please verify the RID and try again.", RID))
+			reply["rid"] = RID
 			return c.JSON(http.StatusNotFound, reply)
 		}
 		log.Error(logActionGetAnalysis, logInfoAnalysis, 1020, err)
-		reply := map[string]interface{}{
-			"success": false,
-			"error":   "internal server error",
-			"message": "An unexpected error occurred while retrieving the analysis. Please try again later or contact support if the issue persists.",
-		}
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while retrieving the analysis. Please try again later or contact support if the issue persists.")
 		return c.JSON(http.StatusInternalServerError, reply)
 	}
 
 	if !tokenValidator.HasAuthorization(attemptToken, analysisResult.URL) {
 		log.Error(logActionGetAnalysis, logInfoAnalysis, 1027, RID)
-		reply := map[string]interface{}{
-			"success": false,
-			"error":   "permission denied",
-			"message": "The provided token does not have permission to access this analysis. Please verify your token has access to the repository.",
-		}
+		reply := apierror.Reply(c, errcode.Unauthorized, "permission denied", "The provided token does not have permission to access this analysis. Please verify your token has access to the repository.")
 		return c.JSON(http.StatusUnauthorized, reply)
 	}
 
+	// tell the clients polling a running analysis when to come back
+	if analysisResult.Status == "running" {
+		hint := analysis.PollHint(analysisResult.StartedAt, time.Now())
+		c.Response().Header().Set("Retry-After", strconv.Itoa(int(hint.Seconds())))
+		if remaining, estimated := analysis.EstimateRemaining(analysisResult, time.Now()); estimated {
+			analysisResult.EstimatedSecondsRemaining = &remaining
+		}
+	}
+	if analysisResult.Status == analysis.StatusQueued {
+		hint := analysis.PollHint(analysisResult.StartedAt, time.Now())
+		c.Response().Header().Set("Retry-After", strconv.Itoa(int(hint.Seconds())))
+	}
+
 	log.Info(logActionGetAnalysis, logInfoAnalysis, 113, "Analysis data retrieved successfully for RID:", RID)
 	return c.JSON(http.StatusOK, analysisResult)
 }
 
-// UploadZip handles zip file uploads for local repository analysis
-func UploadZip(c echo.Context) error {
-	log.Info("UploadZip", logInfoAnalysis, 25, fmt.Sprintf("RID from query: %s", c.QueryParam("rid")))
-	RID := c.Response().Header().Get(echo.HeaderXRequestID)
-	_ = util.GetTokenFromRequest(c) // Token retrieved for potential future validation
+const logActionGetAnalysisOwners = "GetAnalysisOwners"
 
-	// Get RID from query parameter or use request ID
-	requestedRID := c.QueryParam("rid")
-	if requestedRID == "" {
-		requestedRID = RID
+// GetAnalysisOwners returns the authors of the lines of the findings of a
+// given analysis, attributed with git blame, from the one with the most
+// findings. Notifications can mention them as the likely owners of the fixes.
+// @Summary Get the likely owners of the findings of an analysis
+// @Tags analysis
+// @Security huskyToken
+// @Param id path string true "Analysis RID"
+// @Success 200 []types.Owner
+// @Failure 401 Token is not allowed to read this analysis
+// @Failure 404 Analysis not found
+// @Failure 500 Internal error
+// @Router GET /api/v2/analysis/:id/owners
+func GetAnalysisOwners(c echo.Context) error {
+	RID := c.Param("id")
+	attemptToken := util.GetTokenFromRequest(c)
+
+	if err := util.CheckMaliciousRID(RID, c); err != nil {
+		log.Error(logActionGetAnalysisOwners, logInfoAnalysis, 1017, RID)
+		return err
 	}
-	log.Info("UploadZip", logInfoAnalysis, 25, fmt.Sprintf("Using RID: %s (query: %s, header: %s)", requestedRID, c.QueryParam("rid"), RID))
 
-	if requestedRID == "" {
-		reply := map[string]interface{}{
-			"success": false,
-			"error":   "missing RID",
-			"message": "RID parameter is required. Provide it as query parameter 'rid' or it will be generated from request ID.",
+	analysisResult, err := apiContext.APIConfiguration.DBInstance.FindOneDBAnalysis(map[string]interface{}{"RID": RID})
+	if err != nil {
+		if err == mongo.ErrNoDocuments || err.Error() == "No data found" {
+			log.Warning(logActionGetAnalysisOwners, logInfoAnalysis, 106, RID)
+			reply := apierror.Reply(c, errcode.NotFound, "analysis not found", fmt.Sprintf("No analysis found with RID: %s. Please verify the RID and try again.", RID))
+			reply["rid"] = RID
+			return c.JSON(http.StatusNotFound, reply)
 		}
-		return c.JSON(http.StatusBadRequest, reply)
+		log.Error(logActionGetAnalysisOwners, logInfoAnalysis, 1020, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while retrieving the analysis. Please try again later or contact support if the issue persists.")
+		return c.JSON(http.StatusInternalServerError, reply)
 	}
 
-	// Validate RID format
-	if err := util.CheckMaliciousRID(requestedRID, c); err != nil {
-		log.Error("UploadZip", logInfoAnalysis, 1017, requestedRID)
+	if !tokenValidator.HasAuthorization(attemptToken, analysisResult.URL) {
+		log.Error(logActionGetAnalysisOwners, logInfoAnalysis, 1027, RID)
+		reply := apierror.Reply(c, errcode.Unauthorized, "permission denied", "The provided token does not have permission to access this analysis. Please verify your token has access to the repository.")
+		return c.JSON(http.StatusUnauthorized, reply)
+	}
+
+	return c.JSON(http.StatusOK, securitytest.Owners(analysisResult.HuskyCIResults))
+}
+
+const logActionGetContainerOutput = "GetContainerOutput"
+
+// GetContainerOutput returns what the container of a securityTest printed as
+// it ran in a given analysis, so that users can tell why it ended in error
+// without reading the database. The output is cut at
+// HUSKYCI_CONTAINER_RAW_OUTPUT_MAX_SIZE_KB, and then ends with a
+// "[huskyCI] output truncated" line.
+// @Summary Get the raw output of a securityTest container of an analysis
+// @Tags analysis
+// @Security huskyToken
+// @Param id path string true "Analysis RID"
+// @Param tool path string true "SecurityTest name, such as gosec"
+// @Success 200 string
+// @Failure 400 Invalid securityTest name
+// @Failure 401 Token is not allowed to read this analysis
+// @Failure 404 Analysis not found, or no output of the securityTest was stored
+// @Failure 500 Internal error
+// @Router GET /api/v2/analysis/:id/containers/:tool/output
+func GetContainerOutput(c echo.Context) error {
+	RID := c.Param("id")
+	securityTestName := strings.ToLower(c.Param("tool"))
+	attemptToken := util.GetTokenFromRequest(c)
+
+	if err := util.CheckMaliciousRID(RID, c); err != nil {
+		log.Error(logActionGetContainerOutput, logInfoAnalysis, 1017, RID)
 		return err
 	}
+	if !securityTestNameRegexp.MatchString(securityTestName) {
+		log.Warning(logActionGetContainerOutput, logInfoAnalysis, 108, securityTestName)
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid securityTest name", "The securityTest name may only contain letters, numbers, '-' and '_'.")
+		return c.JSON(http.StatusBadRequest, reply)
+	}
 
-	// Ensure zip storage directory exists
-	if err := util.EnsureZipStorageDir(); err != nil {
-		log.Error("UploadZip", logInfoAnalysis, 1019, err)
-		reply := map[string]interface{}{
-			"success": false,
-			"error":   "internal server error",
-			"message": "Failed to initialize zip storage directory.",
+	database := apiContext.APIConfiguration.DBInstance
+	analysisResult, err := database.FindOneDBAnalysis(map[string]interface{}{"RID": RID})
+	if err != nil {
+		if err == mongo.ErrNoDocuments || err.Error() == "No data found" {
+			log.Warning(logActionGetContainerOutput, logInfoAnalysis, 106, RID)
+			reply := apierror.Reply(c, errcode.NotFound, "analysis not found", fmt.Sprintf("No analysis found with RID: %s. Please verify the RID and try again.", RID))
+			return c.JSON(http.StatusNotFound, reply)
 		}
+		log.Error(logActionGetContainerOutput, logInfoAnalysis, 1020, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while retrieving the analysis. Please try again later or contact support if the issue persists.")
 		return c.JSON(http.StatusInternalServerError, reply)
 	}
 
-	// Get uploaded file
-	file, err := c.FormFile("zipfile")
+	if !tokenValidator.HasAuthorization(attemptToken, analysisResult.URL) {
+		log.Error(logActionGetContainerOutput, logInfoAnalysis, 1027, RID)
+		reply := apierror.Reply(c, errcode.Unauthorized, "permission denied", "The provided token does not have permission to access this analysis. Please verify your token has access to the repository.")
+		return c.JSON(http.StatusUnauthorized, reply)
+	}
+
+	outputQuery := map[string]interface{}{"RID": RID, "securityTest": securityTestName}
+	containerOutput, err := database.FindOneDBContainerOutput(outputQuery)
 	if err != nil {
-		log.Error("UploadZip", logInfoAnalysis, 1020, err)
-		reply := map[string]interface{}{
-			"success": false,
-			"error":   "invalid request",
-			"message": "No zip file provided. Use multipart/form-data with 'zipfile' field.",
+		if err == mongo.ErrNoDocuments || err.Error() == "No data found" {
+			log.Warning(logActionGetContainerOutput, logInfoAnalysis, 130, RID, securityTestName)
+			reply := apierror.Reply(c, errcode.NotFound, "output not found", fmt.Sprintf("No output of %s was stored for the analysis %s. It did not run the securityTest, or its container printed nothing.", securityTestName, RID))
+			return c.JSON(http.StatusNotFound, reply)
 		}
-		return c.JSON(http.StatusBadRequest, reply)
+		log.Error(logActionGetContainerOutput, logInfoAnalysis, 1081, RID, securityTestName, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while retrieving the output. Please try again later or contact support if the issue persists.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+	output, err := db.DecompressOutput(containerOutput)
+	if err != nil {
+		log.Error(logActionGetContainerOutput, logInfoAnalysis, 1081, RID, securityTestName, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while retrieving the output. Please try again later or contact support if the issue persists.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+	return c.String(http.StatusOK, output)
+}
+
+const logActionCancelAnalysis = "CancelAnalysis"
+
+// CancelAnalysis cancels a running or queued analysis given a RID. The
+// securityTests that have not started yet are skipped.
+// @Summary Cancel a running analysis
+// @Tags analysis
+// @Security huskyToken
+// @Param id path string true "Analysis RID"
+// @Success 200 Reply
+// @Failure 401 Token is not allowed to cancel this analysis
+// @Failure 404 Analysis not found
+// @Failure 409 Analysis is neither running nor queued
+// @Failure 500 Internal error
+// @Router POST /analysis/:id/cancel
+func CancelAnalysis(c echo.Context) error {
+
+	RID := c.Param("id")
+	attemptToken := util.GetTokenFromRequest(c)
+
+	if err := util.CheckMaliciousRID(RID, c); err != nil {
+		log.Error(logActionCancelAnalysis, logInfoAnalysis, 1017, RID)
+		return err
 	}
 
-	// Validate file extension
-	if filepath.Ext(file.Filename) != ".zip" {
-		reply := map[string]interface{}{
-			"success": false,
-			"error":   "invalid file type",
-			"message": "File must be a .zip archive.",
+	analysisQuery := map[string]interface{}{"RID": RID}
+	analysisResult, err := apiContext.APIConfiguration.DBInstance.FindOneDBAnalysis(analysisQuery)
+	if err != nil {
+		if err == mongo.ErrNoDocuments || err.Error() == "No data found" {
+			log.Warning(logActionCancelAnalysis, logInfoAnalysis, 106, RID)
+			reply := apierror.Reply(c, errcode.NotFound, "analysis not found", fmt.Sprintf("No analysis found with RID: %s. Please verify the RID and try again.", RID))
+			return c.JSON(http.StatusNotFound, reply)
 		}
+		log.Error(logActionCancelAnalysis, logInfoAnalysis, 1050, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while retrieving the analysis. Please try again later or contact support if the issue persists.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+
+	if !tokenValidator.HasAuthorization(attemptToken, analysisResult.URL) {
+		log.Error(logActionCancelAnalysis, logInfoAnalysis, 1027, RID)
+		reply := apierror.Reply(c, errcode.Unauthorized, "permission denied", "The provided token does not have permission to cancel this analysis. Please verify your token has access to the repository.")
+		return c.JSON(http.StatusUnauthorized, reply)
+	}
+
+	if analysisResult.Status != "running" && analysisResult.Status != analysis.StatusQueued {
+		log.Warning(logActionCancelAnalysis, logInfoAnalysis, 117, RID)
+		reply := apierror.Reply(c, errcode.Conflict, "analysis not running", fmt.Sprintf("Analysis %s is %s and cannot be cancelled.", RID, analysisResult.Status))
+		return c.JSON(http.StatusConflict, reply)
+	}
+
+	if err := analysis.Cancel(RID, analysisResult.Status); err != nil {
+		log.Error(logActionCancelAnalysis, logInfoAnalysis, 1050, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while cancelling the analysis. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+
+	reply := map[string]interface{}{
+		"success": true,
+		"error":   "",
+		"message": fmt.Sprintf("Analysis %s cancelled", RID),
+	}
+	return c.JSON(http.StatusOK, reply)
+}
+
+const logActionRerunSecurityTest = "RerunSecurityTest"
+
+// RerunSecurityTest runs a securityTest of a finished analysis again given a
+// RID and the name of the securityTest, on the same code, replacing its results
+// and deciding the result of the analysis again, such as after it failed for a
+// flaky reason. The analysis is running until it is done.
+// @Summary Run a securityTest of an analysis again
+// @Tags analysis
+// @Security huskyToken
+// @Param id path string true "Analysis RID"
+// @Param tool path string true "SecurityTest name, such as gosec"
+// @Success 202 AnalysisStarted{success:bool, error:string, message:string, rid:string}
+// @Failure 400 Invalid securityTest name, or the code uploaded for the analysis was removed
+// @Failure 401 Token is not allowed to analyze this repository
+// @Failure 404 Analysis not found, or it did not run the securityTest
+// @Failure 409 Analysis is not finished, or an analysis of its branch is running
+// @Failure 500 Internal error
+// @Router POST /analysis/:id/tests/:tool/rerun
+func RerunSecurityTest(c echo.Context) error {
+
+	RID := c.Param("id")
+	securityTestName := strings.ToLower(c.Param("tool"))
+	attemptToken := util.GetTokenFromRequest(c)
+
+	if err := util.CheckMaliciousRID(RID, c); err != nil {
+		log.Error(logActionRerunSecurityTest, logInfoAnalysis, 1017, RID)
+		return err
+	}
+	if !securityTestNameRegexp.MatchString(securityTestName) {
+		log.Warning(logActionRerunSecurityTest, logInfoAnalysis, 108, securityTestName)
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid securityTest name", "The securityTest name may only contain letters, numbers, '-' and '_'.")
 		return c.JSON(http.StatusBadRequest, reply)
 	}
 
-	// Open uploaded file
-	src, err := file.Open()
+	analysisQuery := map[string]interface{}{"RID": RID}
+	analysisResult, err := apiContext.APIConfiguration.DBInstance.FindOneDBAnalysis(analysisQuery)
 	if err != nil {
-		log.Error("UploadZip", logInfoAnalysis, 1021, err)
-		reply := map[string]interface{}{
-			"success": false,
-			"error":   "internal server error",
-			"message": "Failed to open uploaded file.",
+		if err == mongo.ErrNoDocuments || err.Error() == "No data found" {
+			log.Warning(logActionRerunSecurityTest, logInfoAnalysis, 106, RID)
+			reply := apierror.Reply(c, errcode.NotFound, "analysis not found", fmt.Sprintf("No analysis found with RID: %s. Please verify the RID and try again.", RID))
+			return c.JSON(http.StatusNotFound, reply)
 		}
+		log.Error(logActionRerunSecurityTest, logInfoAnalysis, 1050, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while retrieving the analysis. Please try again later or contact support if the issue persists.")
 		return c.JSON(http.StatusInternalServerError, reply)
 	}
-	defer src.Close()
 
-	// Save file
-	zipPath := util.GetZipFilePath(requestedRID)
-	log.Info("UploadZip", logInfoAnalysis, 25, fmt.Sprintf("Saving zip file to: %s", zipPath))
-	dst, err := os.Create(zipPath)
-	if err != nil {
-		log.Error("UploadZip", logInfoAnalysis, 1022, fmt.Sprintf("Failed to create file at %s: %v", zipPath, err))
-		reply := map[string]interface{}{
-			"success": false,
-			"error":   "internal server error",
-			"message": fmt.Sprintf("Failed to save uploaded file to %s: %v", zipPath, err),
+	if !tokenValidator.HasAuthorization(attemptToken, analysisResult.URL) {
+		log.Error(logActionRerunSecurityTest, logInfoAnalysis, 1027, RID)
+		reply := apierror.Reply(c, errcode.Unauthorized, "permission denied", "The provided token does not have permission to analyze this repository. Please verify your token has access to the repository.")
+		return c.JSON(http.StatusUnauthorized, reply)
+	}
+
+	if util.IsFileURL(analysisResult.URL) {
+		if status, reply := prepareUpload(c, util.ExtractRIDFromFileURL(analysisResult.URL)); reply != nil {
+			return c.JSON(status, reply)
 		}
+	}
+
+	err = analysis.Rerun(c.Request().Context(), analysisResult, securityTestName)
+	switch {
+	case errors.Is(err, analysis.ErrNotRun):
+		log.Warning(logActionRerunSecurityTest, logInfoAnalysis, 128, RID, securityTestName)
+		reply := apierror.Reply(c, errcode.NotFound, "securityTest not run", fmt.Sprintf("Analysis %s did not run %s, so it cannot run it again.", RID, securityTestName))
+		return c.JSON(http.StatusNotFound, reply)
+	case errors.Is(err, analysis.ErrNotFinished):
+		log.Warning(logActionRerunSecurityTest, logInfoAnalysis, 128, RID, securityTestName)
+		reply := apierror.Reply(c, errcode.Conflict, "analysis not finished", fmt.Sprintf("Analysis %s is %s: only finished analyses can run a securityTest again.", RID, analysisResult.Status))
+		return c.JSON(http.StatusConflict, reply)
+	case errors.Is(err, analysis.ErrAlreadyRunning):
+		reply := apierror.Reply(c, errcode.Conflict, "analysis already running", fmt.Sprintf("An analysis for repository '%s' on branch '%s' is already in progress. Please wait for it to complete.", analysisResult.URL, analysisResult.Branch))
+		return c.JSON(http.StatusConflict, reply)
+	case err != nil:
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while running the securityTest again. Please try again later.")
 		return c.JSON(http.StatusInternalServerError, reply)
 	}
-	defer dst.Close()
 
-	if _, err = io.Copy(dst, src); err != nil {
-		log.Error("UploadZip", logInfoAnalysis, 1023, fmt.Sprintf("Failed to copy file content: %v", err))
-		reply := map[string]interface{}{
-			"success": false,
-			"error":   "internal server error",
-			"message": fmt.Sprintf("Failed to save uploaded file: %v", err),
+	reply := map[string]interface{}{
+		"success": true,
+		"error":   "",
+		"message": fmt.Sprintf("Running %s again for analysis %s", securityTestName, RID),
+		"rid":     RID,
+	}
+	return c.JSON(http.StatusAccepted, reply)
+}
+
+const logActionWatchAnalysis = "WatchAnalysis"
+
+// WatchAnalysis upgrades the connection to a WebSocket pushing the events of
+// an analysis given a RID as JSON messages: its status transitions and its
+// securityTests as they finish. The connection is closed once the analysis is
+// over.
+// @Summary Watch an analysis over a WebSocket
+// @Tags analysis
+// @Security huskyToken
+// @Param id path string true "Analysis RID"
+// @Success 101 types.AnalysisEvent
+// @Failure 401 Token is not allowed to read this analysis
+// @Failure 404 Analysis not found
+// @Failure 500 Internal error
+// @Router GET /ws/analysis/:id
+func WatchAnalysis(c echo.Context) error {
+
+	RID := c.Param("id")
+	attemptToken := util.GetTokenFromRequest(c)
+
+	if err := util.CheckMaliciousRID(RID, c); err != nil {
+		log.Error(logActionWatchAnalysis, logInfoAnalysis, 1017, RID)
+		return err
+	}
+
+	analysisQuery := map[string]interface{}{"RID": RID}
+	analysisResult, err := apiContext.APIConfiguration.DBInstance.FindOneDBAnalysis(analysisQuery)
+	if err != nil {
+		if err == mongo.ErrNoDocuments || err.Error() == "No data found" {
+			log.Warning(logActionWatchAnalysis, logInfoAnalysis, 106, RID)
+			reply := apierror.Reply(c, errcode.NotFound, "analysis not found", fmt.Sprintf("No analysis found with RID: %s. Please verify the RID and try again.", RID))
+			return c.JSON(http.StatusNotFound, reply)
 		}
+		log.Error(logActionWatchAnalysis, logInfoAnalysis, 1051, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while retrieving the analysis. Please try again later or contact support if the issue persists.")
 		return c.JSON(http.StatusInternalServerError, reply)
 	}
 
-	log.Info("UploadZip", logInfoAnalysis, 26, fmt.Sprintf("RID: %s, Filename: %s, Path: %s", requestedRID, file.Filename, zipPath))
+	if !tokenValidator.HasAuthorization(attemptToken, analysisResult.URL) {
+		log.Error(logActionWatchAnalysis, logInfoAnalysis, 1027, RID)
+		reply := apierror.Reply(c, errcode.Unauthorized, "permission denied", "The provided token does not have permission to access this analysis. Please verify your token has access to the repository.")
+		return c.JSON(http.StatusUnauthorized, reply)
+	}
+
+	log.Info(logActionWatchAnalysis, logInfoAnalysis, 46, RID)
+	// the Husky-Token header authenticates the client, so any origin is accepted
+	server := websocket.Server{Handler: func(ws *websocket.Conn) {
+		defer ws.Close()
+		err := analysis.Watch(c.Request().Context(), RID, func(event types.AnalysisEvent) error {
+			return websocket.JSON.Send(ws, event)
+		})
+		if err != nil {
+			log.Warning(logActionWatchAnalysis, logInfoAnalysis, 118, err)
+		}
+	}}
+	server.ServeHTTP(c.Response(), c.Request())
+	return nil
+}
+
+// UploadZip handles zip file uploads for local repository analysis
+// @Summary Upload the zipped code of a local repository
+// @Tags analysis
+// @Security huskyToken
+// @Param rid query string false "RID the zip belongs to, defaults to the request ID"
+// @FormFile zipfile "Zip file with the repository code"
+// @Success 201 AnalysisStarted{success:bool, error:string, message:string, rid:string}
+// @Failure 400 Missing or invalid zip file
+// @Failure 413 Zip is too large
+// @Failure 422 Zip is not a zip archive or was rejected by the antivirus
+// @Failure 500 Zip could not be stored
+// @Failure 503 Zip could not be scanned
+// @Router POST /analysis/upload
+func UploadZip(c echo.Context) error {
+	log.Info("UploadZip", logInfoAnalysis, 25, fmt.Sprintf("RID from query: %s", c.QueryParam("rid")))
+	RID := c.Response().Header().Get(echo.HeaderXRequestID)
+	_ = util.GetTokenFromRequest(c) // Token retrieved for potential future validation
+
+	// Get RID from query parameter or use request ID
+	requestedRID := c.QueryParam("rid")
+	if requestedRID == "" {
+		requestedRID = RID
+	}
+	log.Info("UploadZip", logInfoAnalysis, 25, fmt.Sprintf("Using RID: %s (query: %s, header: %s)", requestedRID, c.QueryParam("rid"), RID))
+
+	if requestedRID == "" {
+		reply := apierror.Reply(c, errcode.InvalidRequest, "missing RID", "RID parameter is required. Provide it as query parameter 'rid' or it will be generated from request ID.")
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+
+	// Validate RID format
+	if err := util.CheckMaliciousRID(requestedRID, c); err != nil {
+		log.Error("UploadZip", logInfoAnalysis, 1017, requestedRID)
+		return err
+	}
+
+	if stored, err := storeZip(c, requestedRID); !stored {
+		return err
+	}
 
 	reply := map[string]interface{}{
 		"success": true,
@@ -202,7 +485,120 @@ func UploadZip(c echo.Context) error {
 	return c.JSON(http.StatusCreated, reply)
 }
 
+// uploadedZip returns the path of the zip uploaded under uploadID, fetched
+// from the object storage if it was uploaded through another API replica,
+// and false if there is none.
+func uploadedZip(uploadID string) (string, bool) {
+	zipPath := util.GetZipFilePath(uploadID)
+	if _, err := os.Stat(zipPath); os.IsNotExist(err) && apiContext.APIConfiguration.Storage != nil {
+		if err := util.EnsureZipStorageDir(); err == nil {
+			if err := storage.DownloadFile(apiContext.APIConfiguration.Storage, storage.ZipKey(uploadID), zipPath); err != nil {
+				log.Error(logActionReceiveRequest, logInfoAnalysis, 1043, uploadID, err)
+			}
+		}
+	}
+	_, err := os.Stat(zipPath)
+	return zipPath, !os.IsNotExist(err)
+}
+
+// prepareUpload makes the zip uploaded under uploadID available to the
+// containers of its analysis. It returns the status and the reply to send
+// when it cannot, and a nil reply otherwise.
+func prepareUpload(c echo.Context, uploadID string) (int, map[string]interface{}) {
+	zipPath, found := uploadedZip(uploadID)
+	if !found {
+		reply := apierror.Reply(c, errcode.InvalidRequest, "zip file not found", fmt.Sprintf("Zip file for RID '%s' not found. Please upload the zip file first using POST /analysis/upload", uploadID))
+		return http.StatusBadRequest, reply
+	}
+	// Docker analyses extract the zip into a volume of their own, unless the
+	// code is copied into their containers from the directory it is
+	// extracted to in the API container, which Kubernetes pods mount
+	extractedDir := util.GetExtractedDir(uploadID)
+	extractLocally := os.Getenv("HUSKYCI_INFRASTRUCTURE_USE") != "docker" || apiContext.APIConfiguration.DockerHostsConfig.CopiesCode()
+	if _, err := os.Stat(extractedDir); os.IsNotExist(err) && extractLocally {
+		if err := util.ExtractZip(zipPath, extractedDir); err != nil {
+			log.Error(logActionReceiveRequest, logInfoAnalysis, 1018, err)
+			reply := apierror.Reply(c, errcode.Internal, "failed to extract zip file", fmt.Sprintf("Failed to extract zip file: %v", err))
+			return http.StatusInternalServerError, reply
+		}
+	}
+	return 0, nil
+}
+
+// idempotencyKeyHeader is the header that makes a POST /analysis safe to
+// retry: the same request sent again with the same key returns the analysis
+// it started.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeySize is the longest Idempotency-Key accepted, in bytes.
+const maxIdempotencyKeySize = 255
+
+// idempotencyOf returns the Idempotency-Key of the request of c, if it has
+// one, with the hash of body.
+func idempotencyOf(c echo.Context, body []byte) (types.Idempotency, error) {
+	key := c.Request().Header.Get(idempotencyKeyHeader)
+	if key == "" {
+		return types.Idempotency{}, nil
+	}
+	if len(key) > maxIdempotencyKeySize {
+		return types.Idempotency{}, fmt.Errorf("the %s header is longer than %d characters", idempotencyKeyHeader, maxIdempotencyKeySize)
+	}
+	for _, char := range key {
+		if char < '!' || char > '~' {
+			return types.Idempotency{}, fmt.Errorf("the %s header must only hold printable ASCII characters", idempotencyKeyHeader)
+		}
+	}
+	hash := sha256.Sum256(body)
+	return types.Idempotency{Key: key, Hash: hex.EncodeToString(hash[:])}, nil
+}
+
+// replyStarted replies that RID analyzes repository. replayed tells the
+// analysis was started by an earlier request with the same Idempotency-Key.
+func replyStarted(c echo.Context, RID string, repository types.Repository, replayed bool) error {
+	reply := map[string]interface{}{
+		"success": true,
+		"error":   "",
+		"message": fmt.Sprintf("Analysis started successfully for repository '%s' on branch '%s'", repository.URL, repository.Branch),
+		"rid":     RID,
+	}
+	if replayed {
+		c.Response().Header().Set("Idempotent-Replayed", "true")
+		reply["message"] = fmt.Sprintf("Analysis of repository '%s' on branch '%s' already started by a request with the same %s", repository.URL, repository.Branch, idempotencyKeyHeader)
+	}
+	return c.JSON(http.StatusCreated, reply)
+}
+
+// replyQueued replies that RID analyzes repository once the analysis of its
+// branch running finishes.
+func replyQueued(c echo.Context, RID string, repository types.Repository) error {
+	reply := map[string]interface{}{
+		"success": true,
+		"error":   "",
+		"message": fmt.Sprintf("Analysis queued for repository '%s' on branch '%s'. It starts once the analysis of the branch running finishes.", repository.URL, repository.Branch),
+		"rid":     RID,
+	}
+	return c.JSON(http.StatusAccepted, reply)
+}
+
 // ReceiveRequest receives the request and performs several checks before starting a new analysis.
+// With an Idempotency-Key header, the same request sent again returns the analysis it started.
+// With HUSKYCI_API_BRANCH_CONCURRENCY set to queue, a request for a branch already analyzed is queued
+// rather than rejected, and replaces the request queued before it.
+// With a pullRequest, the merge of the pull request is analyzed and its findings are compared with those of its
+// target branch.
+// @Summary Start an analysis
+// @Tags analysis
+// @Security huskyToken
+// @Param Idempotency-Key header string false "Key of the request, to send it again safely"
+// @Body types.Repository
+// @Success 201 AnalysisStarted{success:bool, error:string, message:string, rid:string}
+// @Success 202 AnalysisStarted{success:bool, error:string, message:string, rid:string}
+// @Failure 400 Invalid repository URL, branch, pull request, securityTests or Idempotency-Key
+// @Failure 401 Token is not allowed to analyze this repository
+// @Failure 409 An analysis is already running for this repository and branch, and analyses are not queued
+// @Failure 422 The Idempotency-Key was sent with another request
+// @Failure 500 Internal error
+// @Router POST /analysis
 func ReceiveRequest(c echo.Context) error {
 
 	RID := c.Response().Header().Get(echo.HeaderXRequestID)
@@ -217,20 +613,17 @@ func ReceiveRequest(c echo.Context) error {
 	err := json.Unmarshal(bodyBytes, &repository)
 	if err != nil {
 		log.Error(logActionReceiveRequest, logInfoAnalysis, 1015, err)
-		reply := map[string]interface{}{
-			"success": false,
-			"error":   "invalid request format",
-			"message": "The request body must be valid JSON with 'repositoryURL' and 'repositoryBranch' fields. Example: {\"repositoryURL\": \"https://github.com/user/repo.git\", \"repositoryBranch\": \"main\"}",
-		}
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid request format", "The request body must be valid JSON with 'repositoryURL' and 'repositoryBranch' fields. Example: {\"repositoryURL\": \"https://github.com/user/repo.git\", \"repositoryBranch\": \"main\"}")
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+	if err := analysis.ResolveType(&repository); err != nil {
+		log.Error(logActionReceiveRequest, logInfoAnalysis, 1015, err)
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid analysis type", fmt.Sprintf("%v. Git analyses need a 'repositoryURL', upload analyses the 'uploadID' used with POST /analysis/upload.", err))
 		return c.JSON(http.StatusBadRequest, reply)
 	}
 	if !tokenValidator.HasAuthorization(attemptToken, repository.URL) {
 		log.Error("ReceivedRequest", logInfoAnalysis, 1027, RID)
-		reply := map[string]interface{}{
-			"success": false,
-			"error":   "permission denied",
-			"message": fmt.Sprintf("The provided token does not have permission to analyze repository: %s. Please verify your token has access to this repository.", repository.URL),
-		}
+		reply := apierror.Reply(c, errcode.Unauthorized, "permission denied", fmt.Sprintf("The provided token does not have permission to analyze repository: %s. Please verify your token has access to this repository.", repository.URL))
 		return c.JSON(http.StatusUnauthorized, reply)
 	}
 	// step-01: Check malicious inputs
@@ -239,149 +632,222 @@ func ReceiveRequest(c echo.Context) error {
 		return err
 	}
 	repository.URL = sanitizedRepoURL
+	if err := analysis.CheckEnryOutput(repository); err != nil {
+		log.Error(logActionReceiveRequest, logInfoAnalysis, 1015, err)
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid enry output", fmt.Sprintf("%v.", err))
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+	if err := analysis.CheckPullRequest(&repository); err != nil {
+		log.Error(logActionReceiveRequest, logInfoAnalysis, 1015, err)
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid pull request", fmt.Sprintf("%v.", err))
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+	if err := analysis.CheckSecurityTests(&repository); err != nil {
+		if !errors.Is(err, analysis.ErrSecurityTests) {
+			reply := apierror.Reply(c, errcode.Internal, "internal server error", "The selected securityTests could not be checked. Please try again later.")
+			return c.JSON(http.StatusInternalServerError, reply)
+		}
+		log.Error(logActionReceiveRequest, logInfoAnalysis, 1015, err)
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid securityTests", fmt.Sprintf("%v.", err))
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+	if repository.Idempotency, err = idempotencyOf(c, bodyBytes); err != nil {
+		log.Error(logActionReceiveRequest, logInfoAnalysis, 1015, err)
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid idempotency key", fmt.Sprintf("%v.", err))
+		return c.JSON(http.StatusBadRequest, reply)
+	}
 
-	// step-01a: If this is a file:// URL, verify the zip file exists
-	if util.IsFileURL(repository.URL) {
-		log.Info(logActionReceiveRequest, logInfoAnalysis, 26, fmt.Sprintf("Processing file:// URL: %s", repository.URL))
-		extractedRID := util.ExtractRIDFromFileURL(repository.URL)
-		if extractedRID == "" {
-			reply := map[string]interface{}{
-				"success": false,
-				"error":   "invalid file URL",
-				"message": "Invalid file:// URL format. Expected format: file://<RID>",
-			}
-			return c.JSON(http.StatusBadRequest, reply)
+	// step-01a: was this same request already received?
+	if replayRID, err := analysis.Replay(repository); err != nil {
+		if errors.Is(err, analysis.ErrIdempotencyKeyReused) {
+			reply := apierror.Reply(c, errcode.Rejected, "idempotency key reused", fmt.Sprintf("The %s was already sent with another request. Send a new key with each new analysis.", idempotencyKeyHeader))
+			return c.JSON(http.StatusUnprocessableEntity, reply)
 		}
-		zipPath := util.GetZipFilePath(extractedRID)
-		if _, err := os.Stat(zipPath); os.IsNotExist(err) {
-			reply := map[string]interface{}{
-				"success": false,
-				"error":   "zip file not found",
-				"message": fmt.Sprintf("Zip file for RID '%s' not found. Please upload the zip file first using POST /analysis/upload", extractedRID),
-			}
-			return c.JSON(http.StatusBadRequest, reply)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while processing your request. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	} else if replayRID != "" {
+		return replyStarted(c, replayRID, repository, true)
+	}
+
+	// step-01b: If this is an upload analysis, verify the zip file exists
+	if repository.AnalysisType == types.AnalysisTypeUpload {
+		log.Info(logActionReceiveRequest, logInfoAnalysis, 26, fmt.Sprintf("Processing upload: %s", repository.UploadID))
+		if status, reply := prepareUpload(c, repository.UploadID); reply != nil {
+			return c.JSON(status, reply)
 		}
-		// Extract the zip file if not already extracted in API container
-		extractedDir := util.GetExtractedDir(extractedRID)
-		if _, err := os.Stat(extractedDir); os.IsNotExist(err) {
-			// Extract in API container first (for API's own use)
-			if err := util.ExtractZip(zipPath, extractedDir); err != nil {
-				log.Error(logActionReceiveRequest, logInfoAnalysis, 1018, err)
-				reply := map[string]interface{}{
-					"success": false,
-					"error":   "failed to extract zip file",
-					"message": fmt.Sprintf("Failed to extract zip file: %v", err),
-				}
-				return c.JSON(http.StatusInternalServerError, reply)
+	}
+
+	// Debug: Log EnryOutput if present
+	if repository.AnalysisType == types.AnalysisTypeUpload {
+		enryOutputMsg := fmt.Sprintf("Received upload: %s, EnryOutput present: %v, length: %d", repository.UploadID, repository.EnryOutput != "", len(repository.EnryOutput))
+		log.Info(logActionReceiveRequest, logInfoAnalysis, 16, enryOutputMsg)
+		if repository.EnryOutput != "" {
+			preview := repository.EnryOutput
+			if len(preview) > 200 {
+				preview = preview[:200] + "..."
 			}
+			log.Info(logActionReceiveRequest, logInfoAnalysis, 16, fmt.Sprintf("EnryOutput preview: %s", preview))
+		}
+	}
+
+	// step-02: lets start this analysis, unless the same branch is already analyzed!
+	runningRID, err := analysis.Trigger(c.Request().Context(), RID, repository)
+	if errors.Is(err, analysis.ErrAlreadyRunning) {
+		// the same request sent twice at once is told the analysis it started
+		if replayRID, _ := analysis.Replay(repository); replayRID != "" {
+			return replyStarted(c, replayRID, repository, true)
 		}
-		
-		// Always extract in dockerapi to ensure dockerapi's Docker daemon can see the files
-		// This is necessary because docker-in-docker doesn't properly share bind mounts
-		// Even if files exist in API container, dockerapi can't see them
-		if os.Getenv("HUSKYCI_INFRASTRUCTURE_USE") == "docker" {
-			log.Info(logActionReceiveRequest, logInfoAnalysis, 26, fmt.Sprintf("Attempting to extract zip in dockerapi for RID: %s", extractedRID))
-			dockerAPIHost, err := apiContext.APIConfiguration.DBInstance.FindAndModifyDockerAPIAddresses()
+		if apiContext.APIConfiguration.BranchConcurrency == apiContext.BranchConcurrencyQueue {
+			queuedRID, err := analysis.Enqueue(RID, repository)
 			if err != nil {
-				log.Error(logActionReceiveRequest, logInfoAnalysis, 1018, fmt.Errorf("failed to get dockerapi host (non-fatal): %v", err))
-			} else {
-				apiHost, err := apiUtil.FormatDockerHostAddress(dockerAPIHost, apiContext.APIConfiguration)
-				if err != nil {
-					log.Error(logActionReceiveRequest, logInfoAnalysis, 1018, fmt.Errorf("failed to format dockerapi host (non-fatal): %v", err))
-				} else {
-					log.Info(logActionReceiveRequest, logInfoAnalysis, 26, fmt.Sprintf("Extracting zip in dockerapi: zipPath=%s, destDir=%s", zipPath, extractedDir))
-					// Extract files in dockerapi using a temporary container
-					// This ensures dockerapi can see the files even if they already exist in API container
-					if err := huskydocker.ExtractZipInDockerAPI(apiHost, zipPath, extractedDir); err != nil {
-						// Log but don't fail - extraction in API container may have succeeded
-						log.Error(logActionReceiveRequest, logInfoAnalysis, 1018, fmt.Errorf("failed to extract zip in dockerapi (non-fatal): %v", err))
-					} else {
-						log.Info(logActionReceiveRequest, logInfoAnalysis, 26, fmt.Sprintf("Successfully extracted zip in dockerapi for RID: %s", extractedRID))
-					}
-				}
+				reply := apierror.Reply(c, errcode.Internal, "internal server error", "The analysis could not be queued. Please try again later.")
+				return c.JSON(http.StatusInternalServerError, reply)
 			}
+			return replyQueued(c, queuedRID, repository)
+		}
+		reply := apierror.Reply(c, errcode.Conflict, "analysis already running", fmt.Sprintf("An analysis for repository '%s' on branch '%s' is already being started. Please wait for it to complete.", repository.URL, repository.Branch))
+		if runningRID != "" {
+			reply["message"] = fmt.Sprintf("An analysis for repository '%s' on branch '%s' is already in progress. Please wait for it to complete or use the existing analysis RID: %s", repository.URL, repository.Branch, runningRID)
+			reply["rid"] = runningRID
 		}
+		return c.JSON(http.StatusConflict, reply)
+	}
+	if err != nil {
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while processing your request. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
 	}
+	return replyStarted(c, RID, repository, false)
+}
+
+const logActionListAnalyses = "ListAnalyses"
+
+// AnalysisList is a page of the analyses returned by GET /analyses.
+type AnalysisList struct {
+	Page     int                     `json:"page"`
+	PageSize int                     `json:"pageSize"`
+	Total    int64                   `json:"total"`
+	Analyses []types.AnalysisSummary `json:"analyses"`
+}
 
-	// step-02: is this repository already in MongoDB?
-	repositoryQuery := map[string]interface{}{"repositoryURL": repository.URL}
-	_, err = apiContext.APIConfiguration.DBInstance.FindOneDBRepository(repositoryQuery)
+// ListAnalyses returns a page of the analyses matching the repo, branch, status, from and to query string
+// parameters. Without a repo, only the analyses the Husky-Token is scoped to are listed.
+// @Summary List analyses
+// @Tags analysis
+// @Security huskyToken
+// @Param repo query string false "Repository URL"
+// @Param branch query string false "Repository branch"
+// @Param status query string false "Analysis status, such as running or finished"
+// @Param from query string false "Only analyses started after this date, as YYYY-MM-DD or RFC 3339"
+// @Param to query string false "Only analyses started before this date, as YYYY-MM-DD or RFC 3339"
+// @Param sort query string false "startedAt, finishedAt, repositoryURL or status, prefixed with - for descending order"
+// @Param page query int false "Page to return, starting at 1"
+// @Param pageSize query int false "Analyses per page, up to 100"
+// @Success 200 AnalysisList
+// @Failure 400 Invalid query string parameter
+// @Failure 401 Token is not allowed to list these analyses
+// @Failure 500 Internal error
+// @Router GET /analyses
+func ListAnalyses(c echo.Context) error {
+	filter, err := analysisFilterFromRequest(c)
 	if err != nil {
-		if err == mongo.ErrNoDocuments || err.Error() == "No data found" {
-			// step-02-o1: repository not found! insert it into MongoDB
-			repository.CreatedAt = time.Now()
-			err = apiContext.APIConfiguration.DBInstance.InsertDBRepository(repository)
-			if err != nil {
-				log.Error(logActionReceiveRequest, logInfoAnalysis, 1010, err)
-				reply := map[string]interface{}{
-					"success": false,
-					"error":   "internal server error",
-					"message": "Failed to register the repository. Please try again later or contact support if the issue persists.",
-				}
-				return c.JSON(http.StatusInternalServerError, reply)
-			}
-		} else {
-			// step-02-o2: another error searching for repositoryQuery
-			log.Error(logActionReceiveRequest, logInfoAnalysis, 1013, err)
-			reply := map[string]interface{}{
-				"success": false,
-				"error":   "internal server error",
-				"message": "An unexpected error occurred while processing your request. Please try again later.",
-			}
-			return c.JSON(http.StatusInternalServerError, reply)
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid query string parameter", err.Error())
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+
+	attemptToken := util.GetTokenFromRequest(c)
+	if filter.URL != "" {
+		if !tokenValidator.HasAuthorization(attemptToken, filter.URL) {
+			log.Error(logActionListAnalyses, logInfoAnalysis, 1027, filter.URL)
+			reply := apierror.Reply(c, errcode.Unauthorized, "permission denied", fmt.Sprintf("The provided token does not have permission to list the analyses of repository: %s.", filter.URL))
+			return c.JSON(http.StatusUnauthorized, reply)
 		}
-	} else { // err == nil
-		// step-03: repository found! does it have a running status analysis?
-		analysisQuery := map[string]interface{}{"repositoryURL": repository.URL, "repositoryBranch": repository.Branch}
-		analysisResult, err := apiContext.APIConfiguration.DBInstance.FindOneDBAnalysis(analysisQuery)
+	} else {
+		scope, err := tokenValidator.AuthorizedRepository(attemptToken)
 		if err != nil {
-			if err == mongo.ErrNoDocuments || err.Error() == "No data found" {
-				// nice! we can start this analysis!
-			} else {
-				// step-03-err: another error searching for analysisQuery
-				log.Error(logActionReceiveRequest, logInfoAnalysis, 1009, err)
-				reply := map[string]interface{}{
-					"success": false,
-					"error":   "internal server error",
-					"message": "An unexpected error occurred while checking for existing analyses. Please try again later.",
-				}
-				return c.JSON(http.StatusInternalServerError, reply)
-			}
-		} else { // err == nil
-			// step 03-a: Ops, this analysis is already running!
-			if analysisResult.Status == "running" {
-				log.Warning(logActionReceiveRequest, logInfoAnalysis, 104, analysisResult.URL)
-				reply := map[string]interface{}{
-					"success": false,
-					"error":   "analysis already running",
-					"message": fmt.Sprintf("An analysis for repository '%s' on branch '%s' is already in progress. Please wait for it to complete or use the existing analysis RID: %s", repository.URL, repository.Branch, analysisResult.RID),
-					"rid":     analysisResult.RID,
-				}
-				return c.JSON(http.StatusConflict, reply)
-			}
+			log.Error(logActionListAnalyses, logInfoAnalysis, 1027, err)
+			reply := apierror.Reply(c, errcode.Unauthorized, "permission denied", "A valid Husky-Token is required to list analyses without the 'repo' query string parameter.")
+			return c.JSON(http.StatusUnauthorized, reply)
 		}
+		filter.URL = scope
 	}
 
-	// step 04: lets start this analysis!
-	log.Info(logActionReceiveRequest, logInfoAnalysis, 16, repository.Branch, repository.URL)
-	// Debug: Log EnryOutput if present
-	if util.IsFileURL(repository.URL) {
-		enryOutputMsg := fmt.Sprintf("Received file:// URL: %s, EnryOutput present: %v, length: %d", repository.URL, repository.EnryOutput != "", len(repository.EnryOutput))
-		log.Info(logActionReceiveRequest, logInfoAnalysis, 16, enryOutputMsg)
-		if repository.EnryOutput != "" {
-			preview := repository.EnryOutput
-			if len(preview) > 200 {
-				preview = preview[:200] + "..."
-			}
-			log.Info(logActionReceiveRequest, logInfoAnalysis, 16, fmt.Sprintf("EnryOutput preview: %s", preview))
+	log.Info(logActionListAnalyses, logInfoAnalysis, 116, c.QueryString())
+	analyses, total, err := apiContext.APIConfiguration.DBInstance.FindPageDBAnalysis(filter)
+	if err != nil && err != mongo.ErrNoDocuments && err.Error() != "No data found" {
+		log.Error(logActionListAnalyses, logInfoAnalysis, 1049, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while listing analyses. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+	if analyses == nil {
+		analyses = []types.AnalysisSummary{}
+	}
+	return c.JSON(http.StatusOK, AnalysisList{
+		Page:     filter.Page,
+		PageSize: filter.PageSize,
+		Total:    total,
+		Analyses: analyses,
+	})
+}
+
+// analysisFilterFromRequest parses the query string parameters of GET /analyses. Analyses are sorted by
+// -startedAt, newest first, unless sort names another field; a leading "-" sorts in descending order.
+func analysisFilterFromRequest(c echo.Context) (types.AnalysisFilter, error) {
+	filter := types.AnalysisFilter{
+		URL:            c.QueryParam("repo"),
+		Branch:         c.QueryParam("branch"),
+		Status:         c.QueryParam("status"),
+		SortField:      "startedAt",
+		SortDescending: true,
+		Page:           1,
+		PageSize:       20,
+	}
+	if page := c.QueryParam("page"); page != "" {
+		value, err := strconv.Atoi(page)
+		if err != nil || value < 1 {
+			return filter, fmt.Errorf("'page' must be a positive integer")
 		}
+		filter.Page = value
 	}
-	go analysis.StartAnalysis(RID, repository)
-	reply := map[string]interface{}{
-		"success": true,
-		"error":   "",
-		"message": fmt.Sprintf("Analysis started successfully for repository '%s' on branch '%s'", repository.URL, repository.Branch),
-		"rid":     RID,
+	if pageSize := c.QueryParam("pageSize"); pageSize != "" {
+		value, err := strconv.Atoi(pageSize)
+		if err != nil || value < 1 || value > 100 {
+			return filter, fmt.Errorf("'pageSize' must be an integer between 1 and 100")
+		}
+		filter.PageSize = value
 	}
-	return c.JSON(http.StatusCreated, reply)
+	if sort := c.QueryParam("sort"); sort != "" {
+		filter.SortDescending = strings.HasPrefix(sort, "-")
+		filter.SortField = strings.TrimPrefix(sort, "-")
+		switch filter.SortField {
+		case "startedAt", "finishedAt", "repositoryURL", "status":
+		default:
+			return filter, fmt.Errorf("'sort' must be one of startedAt, finishedAt, repositoryURL or status, optionally prefixed with '-'")
+		}
+	}
+	var err error
+	if filter.From, err = parseAnalysisTime(c.QueryParam("from"), false); err != nil {
+		return filter, fmt.Errorf("'from' must be a RFC 3339 time or a YYYY-MM-DD date")
+	}
+	if filter.To, err = parseAnalysisTime(c.QueryParam("to"), true); err != nil {
+		return filter, fmt.Errorf("'to' must be a RFC 3339 time or a YYYY-MM-DD date")
+	}
+	return filter, nil
+}
+
+// parseAnalysisTime parses a RFC 3339 time or a date. A date used as the end of a range includes the whole day.
+func parseAnalysisTime(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+	parsed, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		parsed = parsed.Add(24*time.Hour - time.Nanosecond)
+	}
+	return parsed, nil
 }
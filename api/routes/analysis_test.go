@@ -0,0 +1,131 @@
+package routes_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/db"
+	"github.com/huskyci-org/huskyCI/api/routes"
+	"github.com/huskyci-org/huskyCI/api/types"
+	"github.com/labstack/echo/v4"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ListAnalyses", func() {
+
+	list := func(queryString string) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/analyses?"+queryString, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		Expect(routes.ListAnalyses(c)).To(Succeed())
+		return rec
+	}
+
+	Context("When page is not a positive integer", func() {
+		It("Should return 400", func() {
+			rec := list("page=0")
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(ContainSubstring("'page'"))
+		})
+	})
+	Context("When pageSize is too large", func() {
+		It("Should return 400", func() {
+			rec := list("pageSize=1000")
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+	Context("When sort names an unknown field", func() {
+		It("Should return 400", func() {
+			rec := list("sort=-containers")
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(ContainSubstring("'sort'"))
+		})
+	})
+	Context("When from is not a date", func() {
+		It("Should return 400", func() {
+			rec := list("from=yesterday")
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(ContainSubstring("'from'"))
+		})
+	})
+})
+
+// outputDB is a searchDB also holding the raw outputs of the containers of
+// the analyses.
+type outputDB struct {
+	searchDB
+	outputs []types.ContainerOutput
+}
+
+func (o *outputDB) FindOneDBContainerOutput(mapParams map[string]interface{}) (types.ContainerOutput, error) {
+	for _, output := range o.outputs {
+		if output.RID == mapParams["RID"] && output.SecurityTest == mapParams["securityTest"] {
+			return output, nil
+		}
+	}
+	return types.ContainerOutput{}, errors.New("No data found")
+}
+
+var _ = Describe("GetContainerOutput", func() {
+
+	var previousConfig *apiContext.APIConfig
+
+	get := func(RID, tool string) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/api/v2/analysis/"+RID+"/containers/"+tool+"/output", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id", "tool")
+		c.SetParamValues(RID, tool)
+		Expect(routes.GetContainerOutput(c)).To(Succeed())
+		return rec
+	}
+
+	BeforeEach(func() {
+		output, err := db.CompressOutput("finished", "gosec", "go: cannot find main module\n", 1024)
+		Expect(err).NotTo(HaveOccurred())
+		previousConfig = apiContext.APIConfiguration
+		apiContext.APIConfiguration = &apiContext.APIConfig{DBInstance: &outputDB{
+			searchDB: searchDB{analyses: []types.Analysis{{RID: "finished", URL: "https://github.com/org/repo.git", Status: "finished"}}},
+			outputs:  []types.ContainerOutput{output},
+		}}
+	})
+
+	AfterEach(func() {
+		apiContext.APIConfiguration = previousConfig
+	})
+
+	Context("When the output of the securityTest was stored", func() {
+		It("Should return it as text", func() {
+			rec := get("finished", "GoSec")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Header().Get(echo.HeaderContentType)).To(HavePrefix("text/plain"))
+			Expect(rec.Body.String()).To(Equal("go: cannot find main module\n"))
+		})
+	})
+	Context("When no output of the securityTest was stored", func() {
+		It("Should return 404", func() {
+			rec := get("finished", "bandit")
+			Expect(rec.Code).To(Equal(http.StatusNotFound))
+			Expect(rec.Body.String()).To(ContainSubstring("output not found"))
+		})
+	})
+	Context("When the analysis does not exist", func() {
+		It("Should return 404", func() {
+			rec := get("missing", "gosec")
+			Expect(rec.Code).To(Equal(http.StatusNotFound))
+			Expect(rec.Body.String()).To(ContainSubstring("analysis not found"))
+		})
+	})
+	Context("When the securityTest name is invalid", func() {
+		It("Should return 400", func() {
+			rec := get("finished", "go$ec")
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+})
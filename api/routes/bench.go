@@ -0,0 +1,71 @@
+package routes
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/huskyci-org/huskyCI/api/apierror"
+	"github.com/huskyci-org/huskyCI/api/bench"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/pkg/errcode"
+	"github.com/labstack/echo/v4"
+)
+
+const logActionBench = "AdminBench"
+const logInfoBench = "BENCH"
+
+// StartBench starts a benchmark of the Docker API hosts in background: jobs
+// no-op containers and jobs deliveries of a synthetic upload run on each
+// host, concurrency of them at once. Its report is read with GET
+// /api/v2/admin/bench.
+// @Summary Start a benchmark of the runner hosts
+// @Tags admin
+// @Security basicAuth
+// @Body bench.Workload
+// @Success 202 bench.Benchmark
+// @Failure 400 Invalid workload
+// @Failure 401 Invalid credentials
+// @Failure 409 A benchmark is already running
+// @Failure 501 Benchmarks are only run with the docker infrastructure
+// @Router POST /api/v2/admin/bench
+func StartBench(c echo.Context) error {
+	if os.Getenv("HUSKYCI_INFRASTRUCTURE_USE") != "docker" {
+		reply := apierror.Reply(c, errcode.NotImplemented, "not supported", "Benchmarks are only supported with docker infrastructure.")
+		return c.JSON(http.StatusNotImplemented, reply)
+	}
+	workload := bench.Workload{}
+	if err := c.Bind(&workload); err != nil {
+		log.Warning(logActionBench, logInfoBench, 129, err)
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid benchmark JSON", "The request body must be a JSON with optional 'jobs', 'concurrency', 'kinds' and 'zipFiles' fields.")
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+	if err := workload.Normalize(); err != nil {
+		log.Warning(logActionBench, logInfoBench, 129, err)
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid benchmark JSON", err.Error())
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+	if err := bench.Start(workload); err != nil {
+		log.Warning(logActionBench, logInfoBench, 129, err)
+		reply := apierror.Reply(c, errcode.Conflict, "benchmark already running", "A benchmark is already running. Read its report with GET /api/v2/admin/bench.")
+		return c.JSON(http.StatusConflict, reply)
+	}
+	return c.JSON(http.StatusAccepted, bench.Last())
+}
+
+// GetBench returns the report of the running or last benchmark of the Docker
+// API hosts.
+// @Summary Get the report of a benchmark of the runner hosts
+// @Tags admin
+// @Security basicAuth
+// @Success 200 bench.Benchmark
+// @Failure 401 Invalid credentials
+// @Failure 404 No benchmark ran
+// @Router GET /api/v2/admin/bench
+func GetBench(c echo.Context) error {
+	result := bench.Last()
+	if result == nil {
+		reply := apierror.Reply(c, errcode.NotFound, "not found", "No benchmark ran since the API started.")
+		return c.JSON(http.StatusNotFound, reply)
+	}
+	return c.JSON(http.StatusOK, result)
+}
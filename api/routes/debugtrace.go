@@ -0,0 +1,35 @@
+package routes
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/huskyci-org/huskyCI/api/apierror"
+	"github.com/huskyci-org/huskyCI/api/debugtrace"
+	"github.com/huskyci-org/huskyCI/pkg/errcode"
+	"github.com/labstack/echo/v4"
+)
+
+// GetDebugTrace returns the latest events recorded by the debug tracing, the
+// ones of a single analysis if rid is set.
+// @Summary Get the debug trace
+// @Tags admin
+// @Security basicAuth
+// @Param rid query string false "Only the events of this analysis"
+// @Param limit query int false "Only the latest events, up to this number"
+// @Success 200 debugtrace.Trace
+// @Failure 400 Invalid limit
+// @Failure 401 Invalid credentials
+// @Router GET /api/v2/admin/debug/trace
+func GetDebugTrace(c echo.Context) error {
+	limit := 0
+	if value := c.QueryParam("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			reply := apierror.Reply(c, errcode.InvalidRequest, "invalid limit", "The limit must be a positive integer.")
+			return c.JSON(http.StatusBadRequest, reply)
+		}
+	}
+	return c.JSON(http.StatusOK, debugtrace.Get(c.QueryParam("rid"), limit))
+}
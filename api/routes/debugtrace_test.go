@@ -0,0 +1,53 @@
+package routes_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/huskyci-org/huskyCI/api/debugtrace"
+	"github.com/huskyci-org/huskyCI/api/routes"
+	"github.com/labstack/echo/v4"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GetDebugTrace", func() {
+
+	BeforeEach(func() {
+		debugtrace.Init(&debugtrace.Config{Level: debugtrace.Lifecycle, Size: 10})
+		debugtrace.Record(debugtrace.Lifecycle, "docker", "create", "A")
+		debugtrace.Record(debugtrace.Lifecycle, "docker", "create", "B")
+		debugtrace.Record(debugtrace.Lifecycle, "docker", "remove", "A")
+	})
+	AfterEach(func() {
+		debugtrace.Init(&debugtrace.Config{Level: debugtrace.Off})
+	})
+
+	getDebugTrace := func(query string) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/api/v2/admin/debug/trace?"+query, nil)
+		rec := httptest.NewRecorder()
+		Expect(routes.GetDebugTrace(e.NewContext(req, rec))).To(Succeed())
+		return rec
+	}
+
+	Context("When an analysis and a limit are requested", func() {
+		It("Should return the latest events of the analysis", func() {
+			rec := getDebugTrace("rid=A&limit=1")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			trace := debugtrace.Trace{}
+			Expect(json.Unmarshal(rec.Body.Bytes(), &trace)).To(Succeed())
+			Expect(trace.Level).To(Equal(debugtrace.Lifecycle))
+			Expect(trace.Events).To(HaveLen(1))
+			Expect(trace.Events[0].Action).To(Equal("remove"))
+		})
+	})
+
+	Context("When the limit is not a number", func() {
+		It("Should return a 400", func() {
+			Expect(getDebugTrace("limit=all").Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+})
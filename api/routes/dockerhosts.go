@@ -0,0 +1,347 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/api/apierror"
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	huskydocker "github.com/huskyci-org/huskyCI/api/dockers"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/types"
+	apiUtil "github.com/huskyci-org/huskyCI/api/util/api"
+	"github.com/huskyci-org/huskyCI/pkg/errcode"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const logActionDockerHosts = "AdminDockerHosts"
+const logInfoDockerHost = "DOCKERHOST"
+
+var dockerHostRegexp = regexp.MustCompile(`^[a-zA-Z0-9][-.a-zA-Z0-9]{0,252}$`)
+
+// certFiles are the files of the TLS material of a Docker API host, as read
+// by the Docker client from its directory.
+var certFiles = []string{"ca.pem", "cert.pem", "key.pem"}
+
+// DockerHostInfo is a registered Docker API host and, unless it is disabled,
+// how many securityTests it runs: a draining host is drained once it runs
+// none. Running is left out if the host could not be reached.
+type DockerHostInfo struct {
+	Address   string    `json:"address"`
+	State     string    `json:"state"`
+	CertPath  string    `json:"certPath,omitempty"`
+	Running   *int      `json:"running,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// DockerHostRequest is the body received to register a Docker API host. Its
+// state is active unless it is set, and it uses the TLS material of
+// HUSKYCI_DOCKERAPI_CERT_PATH unless certPath is set.
+type DockerHostRequest struct {
+	Address  string `json:"address"`
+	State    string `json:"state"`
+	CertPath string `json:"certPath"`
+}
+
+// DockerHostChange is the body received to change the state of a Docker API
+// host or its TLS material, an empty certPath going back to the one of
+// HUSKYCI_DOCKERAPI_CERT_PATH. The fields left out are not changed.
+type DockerHostChange struct {
+	State    *string `json:"state"`
+	CertPath *string `json:"certPath"`
+}
+
+// GetDockerHosts returns every registered Docker API host.
+// @Summary List Docker API hosts
+// @Tags admin
+// @Security basicAuth
+// @Success 200 []DockerHostInfo
+// @Failure 401 Invalid credentials
+// @Failure 500 Internal error
+// @Failure 501 Docker API hosts are only used with the docker infrastructure
+// @Router GET /api/v2/admin/dockerhosts
+func GetDockerHosts(c echo.Context) error {
+	if os.Getenv("HUSKYCI_INFRASTRUCTURE_USE") != "docker" {
+		return dockerHostsNotSupported(c)
+	}
+	hosts, err := apiContext.APIConfiguration.DBInstance.FindAllDBDockerHost(map[string]interface{}{})
+	if err != nil && err != mongo.ErrNoDocuments && err.Error() != "No data found" {
+		log.Error(logActionDockerHosts, logInfoDockerHost, 1085, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while retrieving Docker hosts. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+
+	infos := make([]DockerHostInfo, len(hosts))
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		infos[i] = dockerHostInfo(host)
+		if host.State == types.DockerHostDisabled {
+			continue
+		}
+		wg.Add(1)
+		go func(info *DockerHostInfo) {
+			defer wg.Done()
+			if running, err := huskydocker.RunningContainers(apiUtil.DockerHostURL(apiContext.APIConfiguration, info.Address)); err == nil {
+				info.Running = &running
+			}
+		}(&infos[i])
+	}
+	wg.Wait()
+	return c.JSON(http.StatusOK, infos)
+}
+
+// RegisterDockerHost registers a Docker API host. The analyses started from
+// then on are spread on it too if it is active.
+// @Summary Register a Docker API host
+// @Tags admin
+// @Security basicAuth
+// @Body DockerHostRequest
+// @Success 201 DockerHostInfo
+// @Failure 400 Invalid Docker host
+// @Failure 401 Invalid credentials
+// @Failure 409 Docker host already registered
+// @Failure 500 Internal error
+// @Failure 501 Docker API hosts are only used with the docker infrastructure
+// @Router POST /api/v2/admin/dockerhosts
+func RegisterDockerHost(c echo.Context) error {
+	if os.Getenv("HUSKYCI_INFRASTRUCTURE_USE") != "docker" {
+		return dockerHostsNotSupported(c)
+	}
+	request := DockerHostRequest{}
+	if err := c.Bind(&request); err != nil {
+		log.Error(logActionDockerHosts, logInfoDockerHost, 1084, err)
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid Docker host JSON", "The request body must be a JSON with 'address', 'state' and 'certPath' fields.")
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+	request.Address = strings.TrimSpace(request.Address)
+	if !dockerHostRegexp.MatchString(request.Address) {
+		return invalidDockerHost(c, "The address must be the hostname or the IPv4 address of the Docker API host, its port being the one of HUSKYCI_DOCKERAPI_PORT.")
+	}
+	if request.State == "" {
+		request.State = types.DockerHostActive
+	}
+	if !isValidDockerHostState(request.State) {
+		return invalidDockerHostState(c)
+	}
+	if err := checkCertPath(request.CertPath); err != nil {
+		return invalidDockerHost(c, err.Error())
+	}
+
+	hostQuery := map[string]interface{}{"address": request.Address}
+	if _, err := apiContext.APIConfiguration.DBInstance.FindOneDBDockerHost(hostQuery); err == nil {
+		reply := apierror.Reply(c, errcode.Conflict, "Docker host already registered", fmt.Sprintf("A Docker host is already registered at: %s", request.Address))
+		return c.JSON(http.StatusConflict, reply)
+	} else if err != mongo.ErrNoDocuments && err.Error() != "No data found" {
+		log.Error(logActionDockerHosts, logInfoDockerHost, 1085, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while retrieving the Docker host. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+
+	now := time.Now()
+	host := types.DockerHost{
+		Address:   request.Address,
+		State:     request.State,
+		CertPath:  request.CertPath,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := storeDockerHost(c, host); err != nil || c.Response().Committed {
+		return err
+	}
+	log.Info(logActionDockerHosts, logInfoDockerHost, 78, host.Address, host.State)
+	return c.JSON(http.StatusCreated, dockerHostInfo(host))
+}
+
+// UpdateDockerHost changes the state of a Docker API host or its TLS
+// material. Analyses stop starting on a host as soon as it is draining or
+// disabled, and start on it again once it is active.
+// @Summary Change a Docker API host
+// @Tags admin
+// @Security basicAuth
+// @Param address path string true "Address of the Docker API host"
+// @Body DockerHostChange
+// @Success 200 DockerHostInfo
+// @Failure 400 Invalid state or TLS material
+// @Failure 401 Invalid credentials
+// @Failure 404 Docker host not found
+// @Failure 500 Internal error
+// @Failure 501 Docker API hosts are only used with the docker infrastructure
+// @Router PUT /api/v2/admin/dockerhosts/:address
+func UpdateDockerHost(c echo.Context) error {
+	if os.Getenv("HUSKYCI_INFRASTRUCTURE_USE") != "docker" {
+		return dockerHostsNotSupported(c)
+	}
+	change := DockerHostChange{}
+	if err := c.Bind(&change); err != nil {
+		log.Error(logActionDockerHosts, logInfoDockerHost, 1084, err)
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid Docker host JSON", "The request body must be a JSON with 'state' or 'certPath' fields.")
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+	if change.State != nil && !isValidDockerHostState(*change.State) {
+		return invalidDockerHostState(c)
+	}
+	if change.CertPath != nil {
+		if err := checkCertPath(*change.CertPath); err != nil {
+			return invalidDockerHost(c, err.Error())
+		}
+	}
+
+	host, err := findDockerHost(c)
+	if err != nil || c.Response().Committed {
+		return err
+	}
+	if change.State != nil {
+		host.State = *change.State
+	}
+	if change.CertPath != nil {
+		host.CertPath = *change.CertPath
+	}
+	host.UpdatedAt = time.Now()
+	if err := storeDockerHost(c, host); err != nil || c.Response().Committed {
+		return err
+	}
+	log.Info(logActionDockerHosts, logInfoDockerHost, 79, host.Address, host.State)
+	return c.JSON(http.StatusOK, dockerHostInfo(host))
+}
+
+// DeleteDockerHost removes a Docker API host, which must be draining or
+// disabled first. The hosts of HUSKYCI_DOCKERAPI_ADDR are registered again
+// when the API restarts.
+// @Summary Remove a Docker API host
+// @Tags admin
+// @Security basicAuth
+// @Param address path string true "Address of the Docker API host"
+// @Success 204
+// @Failure 401 Invalid credentials
+// @Failure 404 Docker host not found
+// @Failure 409 Docker host still active
+// @Failure 500 Internal error
+// @Failure 501 Docker API hosts are only used with the docker infrastructure
+// @Router DELETE /api/v2/admin/dockerhosts/:address
+func DeleteDockerHost(c echo.Context) error {
+	if os.Getenv("HUSKYCI_INFRASTRUCTURE_USE") != "docker" {
+		return dockerHostsNotSupported(c)
+	}
+	host, err := findDockerHost(c)
+	if err != nil || c.Response().Committed {
+		return err
+	}
+	if host.State == types.DockerHostActive {
+		reply := apierror.Reply(c, errcode.Conflict, "Docker host still active", "Drain or disable the Docker host before removing it.")
+		return c.JSON(http.StatusConflict, reply)
+	}
+	hostQuery := map[string]interface{}{"address": host.Address}
+	if err := apiContext.APIConfiguration.DBInstance.DeleteOneDBDockerHost(hostQuery); err != nil {
+		if err == mongo.ErrNoDocuments || err.Error() == "No data found" {
+			return dockerHostNotFound(c, host.Address)
+		}
+		log.Error(logActionDockerHosts, logInfoDockerHost, 1085, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "Failed to remove the Docker host. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+	if _, err := apiUtil.SyncDockerHosts(apiContext.APIConfiguration); err != nil {
+		return dockerHostsNotSynced(c, err)
+	}
+	log.Info(logActionDockerHosts, logInfoDockerHost, 80, host.Address)
+	return c.NoContent(http.StatusNoContent)
+}
+
+// findDockerHost returns the Docker API host of the address of the path of
+// c, replying 404 if it is not registered.
+func findDockerHost(c echo.Context) (types.DockerHost, error) {
+	address, err := url.PathUnescape(c.Param("address"))
+	if err != nil {
+		address = c.Param("address")
+	}
+	host, err := apiContext.APIConfiguration.DBInstance.FindOneDBDockerHost(map[string]interface{}{"address": address})
+	if err != nil {
+		if err == mongo.ErrNoDocuments || err.Error() == "No data found" {
+			return types.DockerHost{}, dockerHostNotFound(c, address)
+		}
+		log.Error(logActionDockerHosts, logInfoDockerHost, 1085, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while retrieving the Docker host. Please try again later.")
+		return types.DockerHost{}, c.JSON(http.StatusInternalServerError, reply)
+	}
+	return host, nil
+}
+
+// storeDockerHost stores host and shares the registered hosts with the
+// scheduler, replying 500 if it fails.
+func storeDockerHost(c echo.Context, host types.DockerHost) error {
+	hostQuery := map[string]interface{}{"address": host.Address}
+	if err := apiContext.APIConfiguration.DBInstance.UpsertOneDBDockerHost(hostQuery, host); err != nil {
+		log.Error(logActionDockerHosts, logInfoDockerHost, 1085, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "Failed to store the Docker host. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+	if _, err := apiUtil.SyncDockerHosts(apiContext.APIConfiguration); err != nil {
+		return dockerHostsNotSynced(c, err)
+	}
+	return nil
+}
+
+// checkCertPath returns why certPath is not a directory holding the TLS
+// material of a Docker API host, or nil. An empty certPath is valid.
+func checkCertPath(certPath string) error {
+	if certPath == "" {
+		return nil
+	}
+	if !filepath.IsAbs(certPath) {
+		return fmt.Errorf("The certPath must be an absolute path of the API: %s", certPath)
+	}
+	for _, file := range certFiles {
+		if _, err := os.Stat(filepath.Join(certPath, file)); err != nil {
+			return fmt.Errorf("The certPath must hold %s, %s is missing.", strings.Join(certFiles, ", "), file)
+		}
+	}
+	return nil
+}
+
+func isValidDockerHostState(state string) bool {
+	return state == types.DockerHostActive || state == types.DockerHostDraining || state == types.DockerHostDisabled
+}
+
+func dockerHostInfo(host types.DockerHost) DockerHostInfo {
+	return DockerHostInfo{
+		Address:   host.Address,
+		State:     host.State,
+		CertPath:  host.CertPath,
+		CreatedAt: host.CreatedAt,
+		UpdatedAt: host.UpdatedAt,
+	}
+}
+
+func invalidDockerHost(c echo.Context, message string) error {
+	reply := apierror.Reply(c, errcode.InvalidRequest, "invalid Docker host", message)
+	return c.JSON(http.StatusBadRequest, reply)
+}
+
+func invalidDockerHostState(c echo.Context) error {
+	reply := apierror.Reply(c, errcode.InvalidRequest, "invalid state", fmt.Sprintf("The state must be %s, %s or %s.", types.DockerHostActive, types.DockerHostDraining, types.DockerHostDisabled))
+	return c.JSON(http.StatusBadRequest, reply)
+}
+
+func dockerHostNotFound(c echo.Context, address string) error {
+	reply := apierror.Reply(c, errcode.NotFound, "Docker host not found", fmt.Sprintf("No Docker host is registered at: %s", address))
+	return c.JSON(http.StatusNotFound, reply)
+}
+
+func dockerHostsNotSynced(c echo.Context, err error) error {
+	log.Error(logActionDockerHosts, logInfoDockerHost, 1085, err)
+	reply := apierror.Reply(c, errcode.Internal, "internal server error", "The Docker host was stored but could not be shared with the scheduler. Please try again later.")
+	return c.JSON(http.StatusInternalServerError, reply)
+}
+
+func dockerHostsNotSupported(c echo.Context) error {
+	reply := apierror.Reply(c, errcode.NotImplemented, "not supported", "Docker hosts are only managed with docker infrastructure.")
+	return c.JSON(http.StatusNotImplemented, reply)
+}
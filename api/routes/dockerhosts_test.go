@@ -0,0 +1,147 @@
+package routes_test
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/db"
+	"github.com/huskyci-org/huskyCI/api/routes"
+	"github.com/huskyci-org/huskyCI/api/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// dockerHostDB keeps the Docker API hosts by address, along with the ones
+// last shared with the scheduler.
+type dockerHostDB struct {
+	db.Requests
+	hosts     map[string]types.DockerHost
+	scheduled []types.DockerHost
+}
+
+func (d *dockerHostDB) FindOneDBDockerHost(mapParams map[string]interface{}) (types.DockerHost, error) {
+	host, ok := d.hosts[mapParams["address"].(string)]
+	if !ok {
+		return types.DockerHost{}, errors.New("No data found")
+	}
+	return host, nil
+}
+
+func (d *dockerHostDB) FindAllDBDockerHost(mapParams map[string]interface{}) ([]types.DockerHost, error) {
+	hosts := []types.DockerHost{}
+	for _, host := range d.hosts {
+		hosts = append(hosts, host)
+	}
+	return hosts, nil
+}
+
+func (d *dockerHostDB) UpsertOneDBDockerHost(mapParams map[string]interface{}, host types.DockerHost) error {
+	d.hosts[mapParams["address"].(string)] = host
+	return nil
+}
+
+func (d *dockerHostDB) DeleteOneDBDockerHost(mapParams map[string]interface{}) error {
+	if _, ok := d.hosts[mapParams["address"].(string)]; !ok {
+		return errors.New("No data found")
+	}
+	delete(d.hosts, mapParams["address"].(string))
+	return nil
+}
+
+func (d *dockerHostDB) UpsertDockerAPIAddresses(hosts []types.DockerHost) error {
+	d.scheduled = hosts
+	return nil
+}
+
+var _ = Describe("DockerHosts", func() {
+
+	var previousInfrastructure string
+	var previousConfig *apiContext.APIConfig
+	var stored *dockerHostDB
+	var certPath string
+
+	BeforeEach(func() {
+		previousInfrastructure = os.Getenv("HUSKYCI_INFRASTRUCTURE_USE")
+		os.Setenv("HUSKYCI_INFRASTRUCTURE_USE", "docker")
+		stored = &dockerHostDB{hosts: map[string]types.DockerHost{}}
+		previousConfig = apiContext.APIConfiguration
+		apiContext.APIConfiguration = &apiContext.APIConfig{DBInstance: stored}
+		var err error
+		certPath, err = os.MkdirTemp("", "huskyci-certs")
+		Expect(err).NotTo(HaveOccurred())
+		for _, file := range []string{"ca.pem", "cert.pem"} {
+			Expect(os.WriteFile(filepath.Join(certPath, file), []byte("pem"), 0600)).To(Succeed())
+		}
+	})
+	AfterEach(func() {
+		os.Setenv("HUSKYCI_INFRASTRUCTURE_USE", previousInfrastructure)
+		apiContext.APIConfiguration = previousConfig
+		os.RemoveAll(certPath)
+	})
+
+	Context("When a host is registered, drained, disabled and removed", func() {
+		It("Should store each change and share it with the scheduler", func() {
+			Expect(os.WriteFile(filepath.Join(certPath, "key.pem"), []byte("pem"), 0600)).To(Succeed())
+			rec := serve(routes.RegisterDockerHost, http.MethodPost, "/api/v2/admin/dockerhosts", `{"address": " docker2 ", "certPath": "`+certPath+`"}`)
+			Expect(rec.Code).To(Equal(http.StatusCreated))
+			Expect(stored.hosts).To(HaveKey("docker2"))
+			Expect(stored.hosts["docker2"].State).To(Equal(types.DockerHostActive))
+			Expect(stored.hosts["docker2"].CertPath).To(Equal(certPath))
+			Expect(stored.scheduled).To(HaveLen(1))
+
+			rec = serve(routes.RegisterDockerHost, http.MethodPost, "/api/v2/admin/dockerhosts", `{"address": "docker2"}`)
+			Expect(rec.Code).To(Equal(http.StatusConflict))
+			rec = serve(routes.DeleteDockerHost, http.MethodDelete, "/api/v2/admin/dockerhosts/docker2", "", "address", "docker2")
+			Expect(rec.Code).To(Equal(http.StatusConflict))
+
+			rec = serve(routes.UpdateDockerHost, http.MethodPut, "/api/v2/admin/dockerhosts/docker2", `{"state": "disabled"}`, "address", "docker2")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(stored.hosts["docker2"].State).To(Equal(types.DockerHostDisabled))
+			Expect(stored.scheduled[0].State).To(Equal(types.DockerHostDisabled))
+
+			rec = serve(routes.GetDockerHosts, http.MethodGet, "/api/v2/admin/dockerhosts", "")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(ContainSubstring(`"state":"disabled"`))
+			Expect(rec.Body.String()).NotTo(ContainSubstring("running"))
+
+			rec = serve(routes.DeleteDockerHost, http.MethodDelete, "/api/v2/admin/dockerhosts/docker2", "", "address", "docker2")
+			Expect(rec.Code).To(Equal(http.StatusNoContent))
+			Expect(stored.hosts).To(BeEmpty())
+			Expect(stored.scheduled).To(BeEmpty())
+		})
+	})
+	Context("When the host to change is not registered", func() {
+		It("Should return 404", func() {
+			rec := serve(routes.UpdateDockerHost, http.MethodPut, "/api/v2/admin/dockerhosts/docker3", `{"state": "draining"}`, "address", "docker3")
+			Expect(rec.Code).To(Equal(http.StatusNotFound))
+		})
+	})
+	Context("When the infrastructure is not docker", func() {
+		It("Should return 501", func() {
+			os.Setenv("HUSKYCI_INFRASTRUCTURE_USE", "kubernetes")
+			rec := serve(routes.GetDockerHosts, http.MethodGet, "/api/v2/admin/dockerhosts", "")
+			Expect(rec.Code).To(Equal(http.StatusNotImplemented))
+		})
+	})
+	Context("When the host is invalid", func() {
+		It("Should return 400 and store nothing", func() {
+			for body, reason := range map[string]string{
+				`{"address": "https://docker2:2376"}`:                    "invalid Docker host",
+				`{"address": "docker2", "state": "paused"}`:              "invalid state",
+				`{"address": "docker2", "certPath": "` + certPath + `"}`: "key.pem is missing",
+			} {
+				rec := serve(routes.RegisterDockerHost, http.MethodPost, "/api/v2/admin/dockerhosts", body)
+				Expect(rec.Code).To(Equal(http.StatusBadRequest))
+				Expect(rec.Body.String()).To(ContainSubstring(reason))
+			}
+			rec := serve(routes.UpdateDockerHost, http.MethodPut, "/api/v2/admin/dockerhosts/docker2", `{"certPath": "certs/docker2"}`, "address", "docker2")
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(ContainSubstring("absolute path"))
+			Expect(stored.hosts).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,77 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/huskyci-org/huskyCI/api/analysis"
+	"github.com/huskyci-org/huskyCI/api/apierror"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/types"
+	"github.com/huskyci-org/huskyCI/api/util"
+	"github.com/huskyci-org/huskyCI/pkg/errcode"
+	"github.com/labstack/echo/v4"
+)
+
+const logActionStreamEvents = "StreamEvents"
+
+// StreamEvents pushes the events of every analysis as server-sent events,
+// named after their type and holding them as JSON: created, started,
+// securitytest, status and keepalive. Only the analyses of the repository
+// the Husky-Token is scoped to, or of the repo query string parameter, are
+// pushed, so that dashboards and bots do not have to watch each analysis.
+// @Summary Stream the events of every analysis
+// @Tags analysis
+// @Security huskyToken
+// @Param repo query string false "Repository URL"
+// @Success 200 events types.AnalysisEvent
+// @Failure 401 Token is not allowed to read these analyses
+// @Router GET /api/v2/events
+func StreamEvents(c echo.Context) error {
+	attemptToken := util.GetTokenFromRequest(c)
+	repositoryURL := c.QueryParam("repo")
+	if repositoryURL != "" {
+		if !tokenValidator.HasAuthorization(attemptToken, repositoryURL) {
+			log.Error(logActionStreamEvents, logInfoAnalysis, 1027, repositoryURL)
+			reply := apierror.Reply(c, errcode.Unauthorized, "permission denied", fmt.Sprintf("The provided token does not have permission to read the analyses of repository: %s.", repositoryURL))
+			return c.JSON(http.StatusUnauthorized, reply)
+		}
+	} else {
+		scope, err := tokenValidator.AuthorizedRepository(attemptToken)
+		if err != nil {
+			log.Error(logActionStreamEvents, logInfoAnalysis, 1027, err)
+			reply := apierror.Reply(c, errcode.Unauthorized, "permission denied", "A valid Husky-Token is required to stream the events without the 'repo' query string parameter.")
+			return c.JSON(http.StatusUnauthorized, reply)
+		}
+		repositoryURL = scope
+	}
+
+	log.Info(logActionStreamEvents, logInfoAnalysis, 60)
+	response := c.Response()
+	response.Header().Set(echo.HeaderContentType, "text/event-stream")
+	response.Header().Set(echo.HeaderCacheControl, "no-cache")
+	// proxies such as nginx would otherwise hold the events back
+	response.Header().Set("X-Accel-Buffering", "no")
+	response.WriteHeader(http.StatusOK)
+	response.Flush()
+
+	err := analysis.Stream(c.Request().Context(), func(event types.AnalysisEvent) error {
+		if event.Type != analysis.EventKeepAlive && repositoryURL != "" && event.URL != repositoryURL {
+			return nil
+		}
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(response, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+			return err
+		}
+		response.Flush()
+		return nil
+	})
+	if err != nil && c.Request().Context().Err() == nil {
+		log.Warning(logActionStreamEvents, logInfoAnalysis, 126, err)
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/api/apierror"
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/securitytest"
+	"github.com/huskyci-org/huskyCI/api/types"
+	"github.com/huskyci-org/huskyCI/api/util"
+	"github.com/huskyci-org/huskyCI/pkg/errcode"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const logActionSearchFindings = "SearchFindings"
+
+// SearchMaxAnalyses is how many of the finished analyses started since the
+// date searched are read by GET /findings/search, newest first.
+var SearchMaxAnalyses = 1000
+
+// searchPageSize is how many analyses are summarized at once while searching.
+const searchPageSize = 100
+
+// defaultSearchSince is how far back findings are searched by default.
+const defaultSearchSince = 30 * 24 * time.Hour
+
+// AffectedRepository is a branch of a repository whose latest finished
+// analysis has findings matching a search.
+type AffectedRepository struct {
+	RepositoryURL string                       `json:"repositoryURL"`
+	Branch        string                       `json:"repositoryBranch"`
+	RID           string                       `json:"RID"`
+	FinishedAt    time.Time                    `json:"finishedAt"`
+	Findings      []types.HuskyCIVulnerability `json:"findings"`
+}
+
+// FindingSearch is returned by GET /findings/search. Truncated tells that
+// more than SearchMaxAnalyses finished analyses started since the date
+// searched, the oldest of which were not read.
+type FindingSearch struct {
+	Rule         string               `json:"rule,omitempty"`
+	Severity     string               `json:"severity,omitempty"`
+	Since        time.Time            `json:"since"`
+	Analyses     int                  `json:"analyses"`
+	Truncated    bool                 `json:"truncated"`
+	Repositories []AffectedRepository `json:"repositories"`
+}
+
+// SearchFindings returns the branches of repositories whose latest finished
+// analysis started since a date has findings of a rule, such as G107 or a CVE,
+// and/or of a severity, with those findings. The rule of a finding is the ID
+// of the check that found it or else its title, as findings of analyses that
+// finished before huskyCI stored their rule have none. Without a repo, only
+// the repositories the Husky-Token is scoped to are searched.
+// @Summary Search the findings of the latest analyses
+// @Tags analysis
+// @Security huskyToken
+// @Param rule query string false "Rule ID, such as G107 or CVE-2021-44228"
+// @Param severity query string false "high, medium or low"
+// @Param since query string false "How far back to search, such as 30d or 12h, or a YYYY-MM-DD or RFC 3339 date, 30d by default"
+// @Param repo query string false "Repository URL"
+// @Success 200 FindingSearch
+// @Failure 400 Invalid query string parameter
+// @Failure 401 Token is not allowed to read these analyses
+// @Failure 500 Internal error
+// @Router GET /api/v2/findings/search
+func SearchFindings(c echo.Context) error {
+	rule := strings.TrimSpace(c.QueryParam("rule"))
+	severity := strings.ToLower(strings.TrimSpace(c.QueryParam("severity")))
+	since, err := parseSearchSince(c.QueryParam("since"), time.Now())
+	switch {
+	case rule == "" && severity == "":
+		err = fmt.Errorf("'rule' or 'severity' is required")
+	case severity != "" && severity != "high" && severity != "medium" && severity != "low":
+		err = fmt.Errorf("'severity' must be high, medium or low")
+	}
+	if err != nil {
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid query string parameter", err.Error())
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+
+	repositoryURL := c.QueryParam("repo")
+	attemptToken := util.GetTokenFromRequest(c)
+	if repositoryURL != "" {
+		if !tokenValidator.HasAuthorization(attemptToken, repositoryURL) {
+			log.Error(logActionSearchFindings, logInfoAnalysis, 1027, repositoryURL)
+			reply := apierror.Reply(c, errcode.Unauthorized, "permission denied", fmt.Sprintf("The provided token does not have permission to read the analyses of repository: %s.", repositoryURL))
+			return c.JSON(http.StatusUnauthorized, reply)
+		}
+	} else {
+		scope, err := tokenValidator.AuthorizedRepository(attemptToken)
+		if err != nil {
+			log.Error(logActionSearchFindings, logInfoAnalysis, 1027, err)
+			reply := apierror.Reply(c, errcode.Unauthorized, "permission denied", "A valid Husky-Token is required to search findings without the 'repo' query string parameter.")
+			return c.JSON(http.StatusUnauthorized, reply)
+		}
+		repositoryURL = scope
+	}
+
+	log.Info(logActionSearchFindings, logInfoAnalysis, 65, c.QueryString())
+	search, err := searchFindings(repositoryURL, rule, severity, since)
+	if err != nil {
+		log.Error(logActionSearchFindings, logInfoAnalysis, 1078, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while searching findings. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+	return c.JSON(http.StatusOK, search)
+}
+
+// searchFindings reads the finished analyses of repositoryURL, or of every
+// repository if it is empty, started since since, newest first, and returns
+// the findings of rule and severity of the latest one of each branch.
+func searchFindings(repositoryURL, rule, severity string, since time.Time) (FindingSearch, error) {
+	search := FindingSearch{Rule: rule, Severity: severity, Since: since, Repositories: []AffectedRepository{}}
+	database := apiContext.APIConfiguration.DBInstance
+	filter := types.AnalysisFilter{
+		URL:            repositoryURL,
+		Status:         "finished",
+		From:           since,
+		SortField:      "finishedAt",
+		SortDescending: true,
+		PageSize:       searchPageSize,
+	}
+	searched := map[string]bool{}
+	for filter.Page = 1; ; filter.Page++ {
+		summaries, total, err := database.FindPageDBAnalysis(filter)
+		if err != nil && err != mongo.ErrNoDocuments && err.Error() != "No data found" {
+			return search, err
+		}
+		for _, summary := range summaries {
+			if search.Analyses == SearchMaxAnalyses {
+				search.Truncated = true
+				return search, nil
+			}
+			search.Analyses++
+			branch := summary.URL + "\x00" + summary.Branch
+			if searched[branch] {
+				continue
+			}
+			searched[branch] = true
+
+			analysis, err := database.FindOneDBAnalysis(map[string]interface{}{"RID": summary.RID})
+			if err != nil {
+				return search, err
+			}
+			affected := AffectedRepository{RepositoryURL: analysis.URL, Branch: analysis.Branch, RID: analysis.RID, FinishedAt: analysis.FinishedAt, Findings: []types.HuskyCIVulnerability{}}
+			for _, finding := range securitytest.FindingsOfSeverity(analysis.HuskyCIResults, severity) {
+				if rule == "" || ruleMatches(finding, rule) {
+					affected.Findings = append(affected.Findings, finding)
+				}
+			}
+			if len(affected.Findings) > 0 {
+				search.Repositories = append(search.Repositories, affected)
+			}
+		}
+		if len(summaries) < filter.PageSize || int64(filter.Page*filter.PageSize) >= total {
+			return search, nil
+		}
+	}
+}
+
+// ruleMatches returns true if rule is the rule of finding or, if it has none,
+// its title.
+func ruleMatches(finding types.HuskyCIVulnerability, rule string) bool {
+	if finding.Rule != "" {
+		return strings.EqualFold(finding.Rule, rule)
+	}
+	return strings.EqualFold(finding.Title, rule)
+}
+
+// parseSearchSince returns the date since which findings are searched: value
+// days or hours before now, such as 30d or 12h, or a date.
+func parseSearchSince(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return now.Add(-defaultSearchSince), nil
+	}
+	if days, found := strings.CutSuffix(value, "d"); found {
+		if count, err := strconv.Atoi(days); err == nil && count > 0 {
+			return now.AddDate(0, 0, -count), nil
+		}
+	}
+	if duration, err := time.ParseDuration(value); err == nil && duration > 0 {
+		return now.Add(-duration), nil
+	}
+	since, err := parseAnalysisTime(value, false)
+	if err != nil {
+		return since, fmt.Errorf("'since' must be a number of days or hours, such as 30d or 12h, or a RFC 3339 time or a YYYY-MM-DD date")
+	}
+	return since, nil
+}
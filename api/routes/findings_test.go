@@ -0,0 +1,119 @@
+package routes_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/db"
+	"github.com/huskyci-org/huskyCI/api/routes"
+	"github.com/huskyci-org/huskyCI/api/types"
+	"github.com/labstack/echo/v4"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// searchDB is a database without tokens holding finished analyses, newest
+// first.
+type searchDB struct {
+	db.Requests
+	analyses []types.Analysis
+}
+
+func (s *searchDB) FindOneDBAccessToken(mapParams map[string]interface{}) (types.DBToken, error) {
+	return types.DBToken{}, errors.New("No data found")
+}
+
+func (s *searchDB) FindPageDBAnalysis(filter types.AnalysisFilter) ([]types.AnalysisSummary, int64, error) {
+	found := []types.AnalysisSummary{}
+	for _, analysis := range s.analyses {
+		if filter.URL == "" || analysis.URL == filter.URL {
+			found = append(found, types.AnalysisSummary{RID: analysis.RID, URL: analysis.URL, Branch: analysis.Branch, Status: analysis.Status})
+		}
+	}
+	return found, int64(len(found)), nil
+}
+
+func (s *searchDB) FindOneDBAnalysis(mapParams map[string]interface{}) (types.Analysis, error) {
+	for _, analysis := range s.analyses {
+		if analysis.RID == mapParams["RID"] {
+			return analysis, nil
+		}
+	}
+	return types.Analysis{}, errors.New("No data found")
+}
+
+func gosecAnalysis(RID, URL string, high, low []types.HuskyCIVulnerability) types.Analysis {
+	analysis := types.Analysis{RID: RID, URL: URL, Branch: "main", Status: "finished", FinishedAt: time.Now()}
+	analysis.HuskyCIResults.GoResults.HuskyCIGosecOutput = types.HuskyCISecurityTestOutput{HighVulns: high, LowVulns: low}
+	return analysis
+}
+
+var _ = Describe("SearchFindings", func() {
+
+	var previousConfig *apiContext.APIConfig
+
+	search := func(queryString string) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/api/v2/findings/search?"+queryString, nil)
+		rec := httptest.NewRecorder()
+		Expect(routes.SearchFindings(e.NewContext(req, rec))).To(Succeed())
+		return rec
+	}
+
+	BeforeEach(func() {
+		ssrf := types.HuskyCIVulnerability{SecurityTool: "GoSec", Rule: "G107", Title: "Url provided to HTTP request as taint input", File: "client.go"}
+		weakRand := types.HuskyCIVulnerability{SecurityTool: "GoSec", Rule: "G404", File: "token.go"}
+		previousConfig = apiContext.APIConfiguration
+		apiContext.APIConfiguration = &apiContext.APIConfig{DBInstance: &searchDB{
+			analyses: []types.Analysis{
+				gosecAnalysis("new", "https://github.com/org/payments.git", nil, []types.HuskyCIVulnerability{weakRand}),
+				gosecAnalysis("old", "https://github.com/org/payments.git", []types.HuskyCIVulnerability{ssrf}, nil),
+				gosecAnalysis("other", "https://github.com/org/other.git", []types.HuskyCIVulnerability{ssrf, weakRand}, nil),
+			},
+		}}
+	})
+
+	AfterEach(func() {
+		apiContext.APIConfiguration = previousConfig
+	})
+
+	Context("When neither a rule nor a severity is given", func() {
+		It("Should return 400", func() {
+			rec := search("since=30d")
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(ContainSubstring("'rule' or 'severity'"))
+		})
+	})
+	Context("When since is not a duration nor a date", func() {
+		It("Should return 400", func() {
+			rec := search("rule=G107&since=lastweek")
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(ContainSubstring("'since'"))
+		})
+	})
+	Context("When a rule is searched", func() {
+		It("Should only return the latest analysis of each branch having its findings", func() {
+			rec := search("rule=g107&severity=high&since=30d&repo=https://github.com/org/other.git")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			result := routes.FindingSearch{}
+			Expect(json.Unmarshal(rec.Body.Bytes(), &result)).To(Succeed())
+			Expect(result.Repositories).To(HaveLen(1))
+			Expect(result.Repositories[0].RID).To(Equal("other"))
+			Expect(result.Repositories[0].Findings).To(HaveLen(1))
+			Expect(result.Repositories[0].Findings[0].File).To(Equal("client.go"))
+		})
+		It("Should skip the branches fixed since", func() {
+			rec := search("rule=G107&repo=https://github.com/org/payments.git")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			result := routes.FindingSearch{}
+			Expect(json.Unmarshal(rec.Body.Bytes(), &result)).To(Succeed())
+			Expect(result.Analyses).To(Equal(2))
+			Expect(result.Repositories).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,188 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/api/apierror"
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/fieldcrypt"
+	"github.com/huskyci-org/huskyCI/api/gitauth"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/types"
+	"github.com/huskyci-org/huskyCI/pkg/errcode"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const logActionGitCredentials = "AdminGitCredentials"
+const logInfoGitCredential = "GITCREDENTIAL"
+
+// GitCredentialRequest is the body received to store the credential used to
+// clone a private repository.
+type GitCredentialRequest struct {
+	RepositoryURL string `json:"repositoryURL"`
+	Type          string `json:"type"`
+	Username      string `json:"username"`
+	Secret        string `json:"secret"`
+}
+
+// GitCredentialView is a stored git credential without its secret.
+type GitCredentialView struct {
+	RepositoryURL string    `json:"repositoryURL"`
+	Type          string    `json:"type"`
+	Username      string    `json:"username,omitempty"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// GetGitCredentials returns the repositories that have a credential of their
+// own. Secrets are never returned.
+// @Summary List git credentials
+// @Tags admin
+// @Security basicAuth
+// @Success 200 []GitCredentialView
+// @Failure 401 Invalid credentials
+// @Failure 500 Internal error
+// @Router GET /admin/credentials
+func GetGitCredentials(c echo.Context) error {
+	credentials, err := apiContext.APIConfiguration.DBInstance.FindAllDBGitCredential(map[string]interface{}{})
+	if err != nil && err != mongo.ErrNoDocuments && err.Error() != "No data found" {
+		log.Error(logActionGitCredentials, logInfoGitCredential, 1047, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while retrieving git credentials. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+	views := []GitCredentialView{}
+	for _, credential := range credentials {
+		views = append(views, GitCredentialView{
+			RepositoryURL: credential.RepositoryURL,
+			Type:          credential.Type,
+			Username:      credential.Username,
+			UpdatedAt:     credential.UpdatedAt,
+		})
+	}
+	return c.JSON(http.StatusOK, views)
+}
+
+// PutGitCredential stores, encrypted, the deploy key or HTTPS token used to
+// clone a repository, replacing any credential it already had.
+// @Summary Set the git credential of a repository
+// @Tags admin
+// @Security basicAuth
+// @Body GitCredentialRequest
+// @Success 200 GitCredentialView
+// @Failure 400 Invalid git credential
+// @Failure 401 Invalid credentials
+// @Failure 500 Internal error
+// @Failure 503 No database encryption key is configured
+// @Router PUT /admin/credentials
+func PutGitCredential(c echo.Context) error {
+	request := GitCredentialRequest{}
+	if err := c.Bind(&request); err != nil {
+		log.Error(logActionGitCredentials, logInfoGitCredential, 1048, err)
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid git credential JSON", "The request body must be a JSON with 'repositoryURL', 'type', 'username' and 'secret' fields.")
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+	if err := validateGitCredential(request); err != nil {
+		log.Error(logActionGitCredentials, logInfoGitCredential, 1048, err)
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid git credential JSON", err.Error())
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+
+	repositoryURL := gitauth.NormalizeURL(request.RepositoryURL)
+	credential := types.GitCredential{
+		RepositoryURL: repositoryURL,
+		Type:          request.Type,
+		Username:      request.Username,
+		Secret:        request.Secret,
+		UpdatedAt:     time.Now(),
+	}
+	credentialQuery := map[string]interface{}{"repositoryURL": repositoryURL}
+	if err := apiContext.APIConfiguration.DBInstance.UpsertOneDBGitCredential(credentialQuery, credential); err != nil {
+		log.Error(logActionGitCredentials, logInfoGitCredential, 1047, err)
+		if errors.Is(err, fieldcrypt.ErrNoKey) {
+			reply := apierror.Reply(c, errcode.Unavailable, "git credentials disabled", "Set HUSKYCI_DB_ENCRYPTION_KEYS in the huskyCI API to store git credentials.")
+			return c.JSON(http.StatusServiceUnavailable, reply)
+		}
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "Failed to store the git credential. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+
+	log.Info(logActionGitCredentials, logInfoGitCredential, 29, repositoryURL, request.Type)
+	return c.JSON(http.StatusOK, GitCredentialView{
+		RepositoryURL: credential.RepositoryURL,
+		Type:          credential.Type,
+		Username:      credential.Username,
+		UpdatedAt:     credential.UpdatedAt,
+	})
+}
+
+// DeleteGitCredential removes the credential of the repository given in the
+// repositoryURL query string parameter.
+// @Summary Remove the git credential of a repository
+// @Tags admin
+// @Security basicAuth
+// @Param repositoryURL query string true "Repository URL"
+// @Success 204
+// @Failure 400 Missing repository URL
+// @Failure 401 Invalid credentials
+// @Failure 404 Git credential not found
+// @Failure 500 Internal error
+// @Router DELETE /admin/credentials
+func DeleteGitCredential(c echo.Context) error {
+	repositoryURL := gitauth.NormalizeURL(c.QueryParam("repositoryURL"))
+	if repositoryURL == "" {
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid repository URL", "The 'repositoryURL' query string parameter is required.")
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+
+	credentialQuery := map[string]interface{}{"repositoryURL": repositoryURL}
+	if err := apiContext.APIConfiguration.DBInstance.DeleteOneDBGitCredential(credentialQuery); err != nil {
+		if err == mongo.ErrNoDocuments || err.Error() == "No data found" {
+			reply := apierror.Reply(c, errcode.NotFound, "git credential not found", fmt.Sprintf("No git credential found for repository: %s", repositoryURL))
+			return c.JSON(http.StatusNotFound, reply)
+		}
+		log.Error(logActionGitCredentials, logInfoGitCredential, 1047, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "Failed to remove the git credential. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+
+	log.Info(logActionGitCredentials, logInfoGitCredential, 30, repositoryURL)
+	return c.NoContent(http.StatusNoContent)
+}
+
+func validateGitCredential(request GitCredentialRequest) error {
+	repositoryURL := strings.TrimSpace(request.RepositoryURL)
+	isHTTP := strings.HasPrefix(repositoryURL, "https://") || strings.HasPrefix(repositoryURL, "http://")
+	isSSH := strings.HasPrefix(repositoryURL, "ssh://") || strings.HasPrefix(repositoryURL, "git@")
+	if !isHTTP && !isSSH {
+		return fmt.Errorf("'repositoryURL' must be an HTTP(S) or SSH git URL")
+	}
+	if strings.ContainsAny(repositoryURL, " \t\n'") {
+		return fmt.Errorf("'repositoryURL' can not contain spaces or quotes")
+	}
+	if request.Secret == "" {
+		return fmt.Errorf("'secret' can not be empty")
+	}
+	switch request.Type {
+	case types.GitCredentialSSH:
+		if !strings.Contains(request.Secret, "PRIVATE KEY") {
+			return fmt.Errorf("'secret' must be a PEM or OpenSSH private key for an ssh credential")
+		}
+		if strings.Contains(request.Secret, "'") {
+			return fmt.Errorf("'secret' can not contain single quotes")
+		}
+	case types.GitCredentialToken:
+		if !isHTTP {
+			return fmt.Errorf("a token credential requires an HTTP(S) 'repositoryURL'")
+		}
+		if strings.ContainsAny(request.Secret, " \t\n") || strings.ContainsAny(request.Username, " \t\n:@") {
+			return fmt.Errorf("'username' and 'secret' can not contain spaces")
+		}
+	default:
+		return fmt.Errorf("'type' must be either ssh or token")
+	}
+	return nil
+}
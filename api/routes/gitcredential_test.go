@@ -0,0 +1,116 @@
+package routes_test
+
+import (
+	"errors"
+	"net/http"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/db"
+	"github.com/huskyci-org/huskyCI/api/routes"
+	"github.com/huskyci-org/huskyCI/api/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// gitCredentialDB keeps the git credentials, as stored, by repository URL.
+type gitCredentialDB struct {
+	db.Requests
+	credentials map[string]types.GitCredential
+}
+
+func (g *gitCredentialDB) FindAllDBGitCredential(mapParams map[string]interface{}) ([]types.GitCredential, error) {
+	credentials := []types.GitCredential{}
+	for _, credential := range g.credentials {
+		credentials = append(credentials, credential)
+	}
+	return credentials, nil
+}
+
+func (g *gitCredentialDB) UpsertOneDBGitCredential(mapParams map[string]interface{}, credential types.GitCredential) error {
+	g.credentials[mapParams["repositoryURL"].(string)] = credential
+	return nil
+}
+
+func (g *gitCredentialDB) DeleteOneDBGitCredential(mapParams map[string]interface{}) error {
+	if _, ok := g.credentials[mapParams["repositoryURL"].(string)]; !ok {
+		return errors.New("No data found")
+	}
+	delete(g.credentials, mapParams["repositoryURL"].(string))
+	return nil
+}
+
+var _ = Describe("GitCredentials", func() {
+
+	var previousConfig *apiContext.APIConfig
+	var stored *gitCredentialDB
+
+	BeforeEach(func() {
+		stored = &gitCredentialDB{credentials: map[string]types.GitCredential{}}
+		previousConfig = apiContext.APIConfiguration
+		apiContext.APIConfiguration = &apiContext.APIConfig{DBInstance: encrypted(stored)}
+	})
+
+	AfterEach(func() {
+		apiContext.APIConfiguration = previousConfig
+	})
+
+	put := func(body string) (int, string) {
+		rec := serve(routes.PutGitCredential, http.MethodPut, "/admin/credentials", body)
+		return rec.Code, rec.Body.String()
+	}
+
+	Context("When a token is set for an HTTPS repository URL", func() {
+		It("Should store it encrypted under the normalized URL and leave it out of the responses", func() {
+			code, body := put(`{"repositoryURL": "https://github.com/org/repo.git/", "type": "token", "username": "huskyci", "secret": "ghp_s3cr3t"}`)
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(body).To(ContainSubstring(`"repositoryURL":"https://github.com/org/repo"`))
+			Expect(body).NotTo(ContainSubstring("ghp_s3cr3t"))
+
+			Expect(stored.credentials).To(HaveKey("https://github.com/org/repo"))
+			secret := stored.credentials["https://github.com/org/repo"].Secret
+			Expect(secret).To(HavePrefix("v1:k1:"))
+			Expect(secret).NotTo(ContainSubstring("ghp_s3cr3t"))
+
+			rec := serve(routes.GetGitCredentials, http.MethodGet, "/admin/credentials", "")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(ContainSubstring(`"username":"huskyci"`))
+			Expect(rec.Body.String()).NotTo(ContainSubstring("ghp_s3cr3t"))
+			Expect(rec.Body.String()).NotTo(ContainSubstring("secret"))
+		})
+	})
+	Context("When a credential is removed", func() {
+		It("Should return 204, then 404 once it is gone", func() {
+			code, _ := put(`{"repositoryURL": "https://github.com/org/repo", "type": "token", "secret": "ghp_s3cr3t"}`)
+			Expect(code).To(Equal(http.StatusOK))
+			rec := serve(routes.DeleteGitCredential, http.MethodDelete, "/admin/credentials?repositoryURL=https://github.com/org/repo.git", "")
+			Expect(rec.Code).To(Equal(http.StatusNoContent))
+			Expect(stored.credentials).To(BeEmpty())
+			rec = serve(routes.DeleteGitCredential, http.MethodDelete, "/admin/credentials?repositoryURL=https://github.com/org/repo.git", "")
+			Expect(rec.Code).To(Equal(http.StatusNotFound))
+		})
+	})
+	Context("When no database encryption key is configured", func() {
+		It("Should return 503 and store nothing", func() {
+			apiContext.APIConfiguration.DBInstance = db.NewEncryptedRequests(stored, nil)
+			code, _ := put(`{"repositoryURL": "https://github.com/org/repo", "type": "token", "secret": "ghp_s3cr3t"}`)
+			Expect(code).To(Equal(http.StatusServiceUnavailable))
+			Expect(stored.credentials).To(BeEmpty())
+		})
+	})
+	Context("When the credential is invalid", func() {
+		It("Should return 400 and store nothing", func() {
+			for body, reason := range map[string]string{
+				`{"repositoryURL": "file:///etc", "type": "token", "secret": "abc"}`:                    "repositoryURL",
+				`{"repositoryURL": "https://github.com/org/repo", "type": "password", "secret": "abc"}`: "type",
+				`{"repositoryURL": "git@github.com:org/repo.git", "type": "token", "secret": "abc"}`:    "HTTP(S) 'repositoryURL'",
+				`{"repositoryURL": "git@github.com:org/repo.git", "type": "ssh", "secret": "abc"}`:      "private key",
+			} {
+				code, body := put(body)
+				Expect(code).To(Equal(http.StatusBadRequest))
+				Expect(body).To(ContainSubstring(reason))
+			}
+			Expect(stored.credentials).To(BeEmpty())
+		})
+	})
+})
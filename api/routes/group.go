@@ -0,0 +1,347 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/huskyci-org/huskyCI/api/analysis"
+	"github.com/huskyci-org/huskyCI/api/apierror"
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/securitytest"
+	"github.com/huskyci-org/huskyCI/api/types"
+	"github.com/huskyci-org/huskyCI/api/util"
+	"github.com/huskyci-org/huskyCI/pkg/errcode"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const logActionGroups = "AdminGroups"
+const logActionAnalyzeGroup = "AnalyzeGroup"
+const logActionGroupReport = "GroupReport"
+const logInfoGroup = "GROUP"
+
+var groupNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][-_.a-zA-Z0-9]{0,63}$`)
+
+// GroupRequest is the body received to set the repositories of a group. The
+// default branch of a repository can be left out, the branch it was last
+// analyzed on being used, or else main.
+type GroupRequest struct {
+	Description  string                  `json:"description"`
+	Repositories []types.GroupRepository `json:"repositories"`
+}
+
+// GroupAnalysis is the outcome of starting the analysis of a repository of a
+// group: started, running if one of the same branch already was, or failed.
+type GroupAnalysis struct {
+	RepositoryURL string `json:"repositoryURL"`
+	Branch        string `json:"repositoryBranch"`
+	Status        string `json:"status"`
+	RID           string `json:"RID,omitempty"`
+}
+
+// GroupAnalyses is returned by POST /groups/:name/analyze.
+type GroupAnalyses struct {
+	Group    string          `json:"group"`
+	Analyses []GroupAnalysis `json:"analyses"`
+}
+
+// GroupReportEntry is the result and the number of findings of the latest
+// finished analysis of a repository of a group, if it has one.
+type GroupReportEntry struct {
+	RepositoryURL string     `json:"repositoryURL"`
+	Branch        string     `json:"repositoryBranch"`
+	Analyzed      bool       `json:"analyzed"`
+	RID           string     `json:"RID,omitempty"`
+	Result        string     `json:"result,omitempty"`
+	FinishedAt    *time.Time `json:"finishedAt,omitempty"`
+	HighVulns     int        `json:"highVulns"`
+	MediumVulns   int        `json:"mediumVulns"`
+	LowVulns      int        `json:"lowVulns"`
+}
+
+// GroupReport is returned by GET /groups/:name/report: the latest finished
+// analysis of each repository of a group, and how many of them had each
+// result and findings of each severity.
+type GroupReport struct {
+	Group        string             `json:"group"`
+	Results      map[string]int     `json:"results"`
+	HighVulns    int                `json:"highVulns"`
+	MediumVulns  int                `json:"mediumVulns"`
+	LowVulns     int                `json:"lowVulns"`
+	Repositories []GroupReportEntry `json:"repositories"`
+}
+
+// GetGroups returns every repository group.
+// @Summary List repository groups
+// @Tags admin
+// @Security basicAuth
+// @Success 200 []types.RepositoryGroup
+// @Failure 401 Invalid credentials
+// @Failure 500 Internal error
+// @Router GET /api/v2/admin/groups
+func GetGroups(c echo.Context) error {
+	groups, err := apiContext.APIConfiguration.DBInstance.FindAllDBGroup(map[string]interface{}{})
+	if err != nil && err != mongo.ErrNoDocuments && err.Error() != "No data found" {
+		log.Error(logActionGroups, logInfoGroup, 1077, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while retrieving repository groups. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+	if groups == nil {
+		groups = []types.RepositoryGroup{}
+	}
+	return c.JSON(http.StatusOK, groups)
+}
+
+// PutGroup sets the repositories of a group, replacing the ones it already
+// had.
+// @Summary Set the repositories of a group
+// @Tags admin
+// @Security basicAuth
+// @Param name path string true "Group name"
+// @Body GroupRequest
+// @Success 200 types.RepositoryGroup
+// @Failure 400 Invalid group
+// @Failure 401 Invalid credentials
+// @Failure 500 Internal error
+// @Router PUT /api/v2/admin/groups/:name
+func PutGroup(c echo.Context) error {
+	name := c.Param("name")
+	if !groupNameRegexp.MatchString(name) {
+		return invalidGroupName(c)
+	}
+	request := GroupRequest{}
+	if err := c.Bind(&request); err != nil {
+		log.Error(logActionGroups, logInfoGroup, 1076, err)
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid group JSON", "The request body must be a JSON with a 'repositories' list of 'repositoryURL' and 'repositoryBranch' fields.")
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+	if len(request.Repositories) == 0 {
+		log.Error(logActionGroups, logInfoGroup, 1076, name)
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid group JSON", "A group needs at least one repository.")
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+
+	group := types.RepositoryGroup{
+		Name:         name,
+		Description:  request.Description,
+		Repositories: []types.GroupRepository{},
+		UpdatedAt:    time.Now(),
+	}
+	seen := map[types.GroupRepository]bool{}
+	for _, member := range request.Repositories {
+		repository := types.Repository{URL: strings.TrimSpace(member.URL), Branch: strings.TrimSpace(member.Branch)}
+		if util.IsFileURL(repository.URL) {
+			log.Error(logActionGroups, logInfoGroup, 1076, repository.URL)
+			reply := apierror.Reply(c, errcode.InvalidRequest, "invalid repository URL", "Uploaded code cannot be part of a group, only git repositories.")
+			return c.JSON(http.StatusBadRequest, reply)
+		}
+		sanitizedRepoURL, err := util.CheckValidInput(repository, c)
+		if err != nil || c.Response().Committed {
+			return err
+		}
+		member = types.GroupRepository{URL: sanitizedRepoURL, Branch: repository.Branch}
+		if member.Branch == "" {
+			if member.Branch, err = defaultBranch(member.URL); err != nil {
+				log.Error(logActionGroups, logInfoGroup, 1077, err)
+				reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while looking for the default branch of the repositories. Please try again later.")
+				return c.JSON(http.StatusInternalServerError, reply)
+			}
+		}
+		if !seen[member] {
+			seen[member] = true
+			group.Repositories = append(group.Repositories, member)
+		}
+	}
+
+	groupQuery := map[string]interface{}{"name": name}
+	if err := apiContext.APIConfiguration.DBInstance.UpsertOneDBGroup(groupQuery, group); err != nil {
+		log.Error(logActionGroups, logInfoGroup, 1077, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "Failed to store the repository group. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+
+	log.Info(logActionGroups, logInfoGroup, 62, name, len(group.Repositories))
+	return c.JSON(http.StatusOK, group)
+}
+
+// DeleteGroup removes a repository group. The analyses of its repositories
+// are kept.
+// @Summary Remove a repository group
+// @Tags admin
+// @Security basicAuth
+// @Param name path string true "Group name"
+// @Success 204
+// @Failure 401 Invalid credentials
+// @Failure 404 Group not found
+// @Failure 500 Internal error
+// @Router DELETE /api/v2/admin/groups/:name
+func DeleteGroup(c echo.Context) error {
+	name := c.Param("name")
+	groupQuery := map[string]interface{}{"name": name}
+	if err := apiContext.APIConfiguration.DBInstance.DeleteOneDBGroup(groupQuery); err != nil {
+		if err == mongo.ErrNoDocuments || err.Error() == "No data found" {
+			return groupNotFound(c, name)
+		}
+		log.Error(logActionGroups, logInfoGroup, 1077, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "Failed to remove the repository group. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+
+	log.Info(logActionGroups, logInfoGroup, 63, name)
+	return c.NoContent(http.StatusNoContent)
+}
+
+// AnalyzeGroup starts the analysis of the default branch of every repository
+// of a group at once. The Husky-Token must be allowed to analyze all of
+// them. Repositories whose branch is already analyzed are left running.
+// @Summary Analyze every repository of a group
+// @Tags analysis
+// @Security huskyToken
+// @Param name path string true "Group name"
+// @Success 201 GroupAnalyses
+// @Failure 401 Token is not allowed to analyze a repository of the group
+// @Failure 404 Group not found
+// @Failure 409 Every repository of the group is already analyzed
+// @Failure 500 Internal error
+// @Router POST /api/v2/groups/:name/analyze
+func AnalyzeGroup(c echo.Context) error {
+	group, err := authorizedGroup(c, logActionAnalyzeGroup)
+	if err != nil || c.Response().Committed {
+		return err
+	}
+
+	analyses := GroupAnalyses{Group: group.Name, Analyses: []GroupAnalysis{}}
+	started, failed := 0, 0
+	for _, member := range group.Repositories {
+		repository := types.Repository{URL: member.URL, Branch: member.Branch, AnalysisType: types.AnalysisTypeGit}
+		RID, err := analysis.Trigger(c.Request().Context(), uuid.New().String(), repository)
+		outcome := GroupAnalysis{RepositoryURL: member.URL, Branch: member.Branch, RID: RID}
+		switch {
+		case errors.Is(err, analysis.ErrAlreadyRunning):
+			outcome.Status = "running"
+		case err != nil:
+			outcome.Status = "failed"
+			failed++
+		default:
+			outcome.Status = "started"
+			started++
+		}
+		analyses.Analyses = append(analyses.Analyses, outcome)
+	}
+	log.Info(logActionAnalyzeGroup, logInfoGroup, 64, group.Name, started)
+
+	switch {
+	case started > 0:
+		return c.JSON(http.StatusCreated, analyses)
+	case failed > 0:
+		return c.JSON(http.StatusInternalServerError, analyses)
+	default:
+		return c.JSON(http.StatusConflict, analyses)
+	}
+}
+
+// GetGroupReport returns the result and the number of findings of the latest
+// finished analysis of each repository of a group, along with their totals.
+// The Husky-Token must be allowed to read the analyses of all of them.
+// @Summary Report on the latest analyses of a group
+// @Tags analysis
+// @Security huskyToken
+// @Param name path string true "Group name"
+// @Success 200 GroupReport
+// @Failure 401 Token is not allowed to read the analyses of a repository of the group
+// @Failure 404 Group not found
+// @Failure 500 Internal error
+// @Router GET /api/v2/groups/:name/report
+func GetGroupReport(c echo.Context) error {
+	group, err := authorizedGroup(c, logActionGroupReport)
+	if err != nil || c.Response().Committed {
+		return err
+	}
+
+	report := GroupReport{Group: group.Name, Results: map[string]int{}, Repositories: []GroupReportEntry{}}
+	for _, member := range group.Repositories {
+		entry := GroupReportEntry{RepositoryURL: member.URL, Branch: member.Branch}
+		analysis, found, err := latestFinishedAnalysis(member.URL, member.Branch)
+		if err != nil {
+			log.Error(logActionGroupReport, logInfoGroup, 1077, err)
+			reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while reading the analyses of the group. Please try again later.")
+			return c.JSON(http.StatusInternalServerError, reply)
+		}
+		if found {
+			finishedAt := analysis.FinishedAt
+			entry.Analyzed = true
+			entry.RID = analysis.RID
+			entry.Result = analysis.Result
+			entry.FinishedAt = &finishedAt
+			entry.HighVulns, entry.MediumVulns, entry.LowVulns = securitytest.Counts(analysis.HuskyCIResults)
+			report.Results[analysis.Result]++
+			report.HighVulns += entry.HighVulns
+			report.MediumVulns += entry.MediumVulns
+			report.LowVulns += entry.LowVulns
+		}
+		report.Repositories = append(report.Repositories, entry)
+	}
+	return c.JSON(http.StatusOK, report)
+}
+
+// authorizedGroup returns the group named in the path, once the Husky-Token
+// of c is found allowed to access every repository of it. Otherwise, the
+// reply is sent.
+func authorizedGroup(c echo.Context, action string) (types.RepositoryGroup, error) {
+	name := c.Param("name")
+	group, err := apiContext.APIConfiguration.DBInstance.FindOneDBGroup(map[string]interface{}{"name": name})
+	if err != nil {
+		if err == mongo.ErrNoDocuments || err.Error() == "No data found" {
+			return group, groupNotFound(c, name)
+		}
+		log.Error(action, logInfoGroup, 1077, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while retrieving the repository group. Please try again later.")
+		return group, c.JSON(http.StatusInternalServerError, reply)
+	}
+
+	attemptToken := util.GetTokenFromRequest(c)
+	for _, member := range group.Repositories {
+		if !tokenValidator.HasAuthorization(attemptToken, member.URL) {
+			log.Error(action, logInfoGroup, 1027, member.URL)
+			reply := apierror.Reply(c, errcode.Unauthorized, "permission denied", fmt.Sprintf("The provided token does not have permission to access repository %s of group %s.", member.URL, name))
+			return group, c.JSON(http.StatusUnauthorized, reply)
+		}
+	}
+	return group, nil
+}
+
+// defaultBranch returns the branch repositoryURL was last analyzed on, or
+// else the first of defaultBranches, as the API cannot ask the git server.
+func defaultBranch(repositoryURL string) (string, error) {
+	filter := types.AnalysisFilter{
+		URL:            repositoryURL,
+		SortField:      "startedAt",
+		SortDescending: true,
+		Page:           1,
+		PageSize:       1,
+	}
+	summaries, _, err := apiContext.APIConfiguration.DBInstance.FindPageDBAnalysis(filter)
+	if err != nil && err != mongo.ErrNoDocuments && err.Error() != "No data found" {
+		return "", err
+	}
+	if len(summaries) == 0 {
+		return defaultBranches[0], nil
+	}
+	return summaries[0].Branch, nil
+}
+
+func invalidGroupName(c echo.Context) error {
+	reply := apierror.Reply(c, errcode.InvalidRequest, "invalid group name", "Group names are made of up to 64 letters, digits, dots, hyphens and underscores, such as payments-team.")
+	return c.JSON(http.StatusBadRequest, reply)
+}
+
+func groupNotFound(c echo.Context, name string) error {
+	reply := apierror.Reply(c, errcode.NotFound, "group not found", fmt.Sprintf("No repository group found named: %s", name))
+	return c.JSON(http.StatusNotFound, reply)
+}
@@ -0,0 +1,124 @@
+package routes_test
+
+import (
+	"errors"
+	"net/http"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/db"
+	"github.com/huskyci-org/huskyCI/api/routes"
+	"github.com/huskyci-org/huskyCI/api/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// groupDB keeps the repository groups by name, along with the branch each
+// repository was last analyzed on.
+type groupDB struct {
+	db.Requests
+	groups   map[string]types.RepositoryGroup
+	branches map[string]string
+}
+
+func (g *groupDB) FindAllDBGroup(mapParams map[string]interface{}) ([]types.RepositoryGroup, error) {
+	groups := []types.RepositoryGroup{}
+	for _, group := range g.groups {
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+func (g *groupDB) UpsertOneDBGroup(mapParams map[string]interface{}, group types.RepositoryGroup) error {
+	g.groups[mapParams["name"].(string)] = group
+	return nil
+}
+
+func (g *groupDB) DeleteOneDBGroup(mapParams map[string]interface{}) error {
+	if _, ok := g.groups[mapParams["name"].(string)]; !ok {
+		return errors.New("No data found")
+	}
+	delete(g.groups, mapParams["name"].(string))
+	return nil
+}
+
+func (g *groupDB) FindPageDBAnalysis(filter types.AnalysisFilter) ([]types.AnalysisSummary, int64, error) {
+	branch, ok := g.branches[filter.URL]
+	if !ok {
+		return nil, 0, errors.New("No data found")
+	}
+	return []types.AnalysisSummary{{URL: filter.URL, Branch: branch}}, 1, nil
+}
+
+var _ = Describe("Groups", func() {
+
+	var previousConfig *apiContext.APIConfig
+	var stored *groupDB
+
+	BeforeEach(func() {
+		stored = &groupDB{
+			groups:   map[string]types.RepositoryGroup{},
+			branches: map[string]string{"https://github.com/org/api.git": "develop"},
+		}
+		previousConfig = apiContext.APIConfiguration
+		apiContext.APIConfiguration = &apiContext.APIConfig{DBInstance: stored}
+	})
+
+	AfterEach(func() {
+		apiContext.APIConfiguration = previousConfig
+	})
+
+	put := func(name, body string) (int, string) {
+		rec := serve(routes.PutGroup, http.MethodPut, "/api/v2/admin/groups/"+name, body, "name", name)
+		return rec.Code, rec.Body.String()
+	}
+
+	Context("When a group is set", func() {
+		It("Should store its repositories once each, on their default branch when none is given", func() {
+			code, _ := put("payments-team", `{"description": "Payments", "repositories": [
+				{"repositoryURL": "https://github.com/org/web.git", "repositoryBranch": "release"},
+				{"repositoryURL": "https://github.com/org/web.git", "repositoryBranch": "release"},
+				{"repositoryURL": "https://github.com/org/api.git"},
+				{"repositoryURL": "https://github.com/org/new.git"}]}`)
+			Expect(code).To(Equal(http.StatusOK))
+
+			Expect(stored.groups).To(HaveKey("payments-team"))
+			Expect(stored.groups["payments-team"].Description).To(Equal("Payments"))
+			Expect(stored.groups["payments-team"].Repositories).To(Equal([]types.GroupRepository{
+				{URL: "https://github.com/org/web.git", Branch: "release"},
+				{URL: "https://github.com/org/api.git", Branch: "develop"},
+				{URL: "https://github.com/org/new.git", Branch: "main"},
+			}))
+
+			rec := serve(routes.GetGroups, http.MethodGet, "/api/v2/admin/groups", "")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(ContainSubstring(`"name":"payments-team"`))
+		})
+	})
+	Context("When a group is removed", func() {
+		It("Should return 204, then 404 once it is gone", func() {
+			code, _ := put("payments-team", `{"repositories": [{"repositoryURL": "https://github.com/org/web.git"}]}`)
+			Expect(code).To(Equal(http.StatusOK))
+			rec := serve(routes.DeleteGroup, http.MethodDelete, "/api/v2/admin/groups/payments-team", "", "name", "payments-team")
+			Expect(rec.Code).To(Equal(http.StatusNoContent))
+			Expect(stored.groups).To(BeEmpty())
+			rec = serve(routes.DeleteGroup, http.MethodDelete, "/api/v2/admin/groups/payments-team", "", "name", "payments-team")
+			Expect(rec.Code).To(Equal(http.StatusNotFound))
+		})
+	})
+	Context("When the group is invalid", func() {
+		It("Should return 400 and store nothing", func() {
+			for _, invalid := range []struct{ name, body, reason string }{
+				{"payments$team", `{"repositories": [{"repositoryURL": "https://github.com/org/repo.git"}]}`, "invalid group name"},
+				{"payments-team", `{"description": "Payments", "repositories": []}`, "at least one repository"},
+				{"payments-team", `{"repositories": [{"repositoryURL": "file://a1b2"}]}`, "only git repositories"},
+				{"payments-team", `{"repositories": [{"repositoryURL": "https://github.com/org/repo.git", "repositoryBranch": "main;rm -rf"}]}`, "invalid repository branch"},
+			} {
+				code, body := put(invalid.name, invalid.body)
+				Expect(code).To(Equal(http.StatusBadRequest))
+				Expect(body).To(ContainSubstring(invalid.reason))
+			}
+			Expect(stored.groups).To(BeEmpty())
+		})
+	})
+})
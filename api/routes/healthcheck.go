@@ -3,10 +3,55 @@ package routes
 import (
 	"net/http"
 
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/health"
+	"github.com/huskyci-org/huskyCI/api/metrics"
 	"github.com/labstack/echo/v4"
 )
 
 // HealthCheck is the heath check function.
+// @Summary Check that the API is up
+// @Tags health
+// @Success 200 string
+// @Router GET /healthcheck
 func HealthCheck(c echo.Context) error {
 	return c.String(http.StatusOK, "WORKING\n")
 }
+
+// Liveness answers as long as the API serves requests, whatever the state of
+// its dependencies, for orchestrators to restart it when it does not.
+// @Summary Check that the API is alive
+// @Tags health
+// @Success 200 health.Report
+// @Router GET /healthz
+func Liveness(c echo.Context) error {
+	return c.JSON(http.StatusOK, health.Report{Status: health.Up, Checks: []health.Check{}})
+}
+
+// Readiness checks every dependency the API needs to run analyses and details
+// the state and latency of each one, for orchestrators to only send requests
+// to ready replicas.
+// @Summary Check that the API is ready to run analyses
+// @Tags health
+// @Success 200 health.Report
+// @Failure 503 health.Report "A dependency is down"
+// @Router GET /readyz
+func Readiness(c echo.Context) error {
+	report := health.Run(health.Dependencies(apiContext.APIConfiguration), health.Timeout)
+	if report.Status != health.Up {
+		return c.JSON(http.StatusServiceUnavailable, report)
+	}
+	return c.JSON(http.StatusOK, report)
+}
+
+// GetMetrics returns the requests the API served, by route and status, in the
+// text format of Prometheus. Only the networks of the admin routes can read
+// them.
+// @Summary Get the metrics of the API
+// @Tags health
+// @Success 200 string
+// @Failure 403 Address not allowed
+// @Router GET /metrics
+func GetMetrics(c echo.Context) error {
+	return metrics.DefaultRegistry.Handler(c)
+}
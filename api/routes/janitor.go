@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/huskyci-org/huskyCI/api/janitor"
+	"github.com/labstack/echo/v4"
+)
+
+// GetJanitorStats returns the resources reclaimed by the janitor since the API started.
+// @Summary Get janitor stats
+// @Tags admin
+// @Security basicAuth
+// @Success 200 janitor.Stats
+// @Failure 401 Invalid credentials
+// @Router GET /admin/janitor
+func GetJanitorStats(c echo.Context) error {
+	return c.JSON(http.StatusOK, janitor.GetStats())
+}
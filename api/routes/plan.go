@@ -0,0 +1,89 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/huskyci-org/huskyCI/api/analysis"
+	"github.com/huskyci-org/huskyCI/api/apierror"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/types"
+	"github.com/huskyci-org/huskyCI/api/util"
+	"github.com/huskyci-org/huskyCI/pkg/errcode"
+)
+
+const logActionPlanAnalysis = "PlanAnalysis"
+
+// PlanAnalysis returns the plan of the analysis POST /analysis would start
+// with the same body: its languages, the securityTests it would run with
+// their images and how long it should take. Nothing is run or registered.
+// @Summary Plan an analysis without running it
+// @Tags analysis
+// @Security huskyToken
+// @Body types.Repository
+// @Success 200 analysis.Plan
+// @Failure 400 Invalid repository URL, branch, upload or securityTests
+// @Failure 401 Token is not allowed to analyze this repository
+// @Failure 500 Internal error
+// @Router POST /api/v2/analysis/plan
+func PlanAnalysis(c echo.Context) error {
+	attemptToken := util.GetTokenFromRequest(c)
+
+	bodyBytes, _ := io.ReadAll(c.Request().Body)
+	repository := types.Repository{}
+	if err := json.Unmarshal(bodyBytes, &repository); err != nil {
+		log.Error(logActionPlanAnalysis, logInfoAnalysis, 1015, err)
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid request format", "The request body must be valid JSON with 'repositoryURL' and 'repositoryBranch' fields, as sent to POST /analysis.")
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+	if err := analysis.ResolveType(&repository); err != nil {
+		log.Error(logActionPlanAnalysis, logInfoAnalysis, 1015, err)
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid analysis type", fmt.Sprintf("%v. Git analyses need a 'repositoryURL', upload analyses the 'uploadID' used with POST /analysis/upload.", err))
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+	if !tokenValidator.HasAuthorization(attemptToken, repository.URL) {
+		log.Error(logActionPlanAnalysis, logInfoAnalysis, 1027, repository.URL)
+		reply := apierror.Reply(c, errcode.Unauthorized, "permission denied", fmt.Sprintf("The provided token does not have permission to analyze repository: %s.", repository.URL))
+		return c.JSON(http.StatusUnauthorized, reply)
+	}
+	sanitizedRepoURL, err := util.CheckValidInput(repository, c)
+	if err != nil || c.Response().Committed {
+		return err
+	}
+	repository.URL = sanitizedRepoURL
+	if err := analysis.CheckEnryOutput(repository); err != nil {
+		log.Error(logActionPlanAnalysis, logInfoAnalysis, 1015, err)
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid enry output", fmt.Sprintf("%v.", err))
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+	if err := analysis.CheckSecurityTests(&repository); err != nil {
+		if !errors.Is(err, analysis.ErrSecurityTests) {
+			reply := apierror.Reply(c, errcode.Internal, "internal server error", "The selected securityTests could not be checked. Please try again later.")
+			return c.JSON(http.StatusInternalServerError, reply)
+		}
+		log.Error(logActionPlanAnalysis, logInfoAnalysis, 1015, err)
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid securityTests", fmt.Sprintf("%v.", err))
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+
+	zipPath := ""
+	if repository.AnalysisType == types.AnalysisTypeUpload {
+		var found bool
+		if zipPath, found = uploadedZip(repository.UploadID); !found {
+			reply := apierror.Reply(c, errcode.InvalidRequest, "zip file not found", fmt.Sprintf("Zip file for RID '%s' not found. Please upload the zip file first using POST /analysis/upload", repository.UploadID))
+			return c.JSON(http.StatusBadRequest, reply)
+		}
+	}
+
+	plan, err := analysis.NewPlan(repository, zipPath)
+	if err != nil {
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "The analysis could not be planned. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+	return c.JSON(http.StatusOK, plan)
+}
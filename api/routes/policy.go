@@ -0,0 +1,246 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/api/apierror"
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/gitauth"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/types"
+	"github.com/huskyci-org/huskyCI/pkg/errcode"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const logActionPolicies = "AdminPolicies"
+const logActionGetPolicy = "GetPolicy"
+const logInfoPolicy = "POLICY"
+
+// PolicyRequest is the body received to set the lowest severity of the
+// vulnerabilities failing the CI of a repository, medium by default, the
+// rules whose findings never fail it and the severity of the findings of the
+// rules in severityOverrides, such as {"generic-api-key": "high"}, and the
+// securityTests the analyses selecting theirs must run.
+type PolicyRequest struct {
+	RepositoryURL         string            `json:"repositoryURL"`
+	FailOn                string            `json:"failOn"`
+	IgnoreRules           []string          `json:"ignoreRules"`
+	SeverityOverrides     map[string]string `json:"severityOverrides"`
+	RequiredSecurityTests []string          `json:"requiredSecurityTests"`
+}
+
+// maxPolicyRules is how many rules a policy can ignore, and how many it can
+// override the severity of.
+const maxPolicyRules = 200
+
+// maxRuleLength is the longest rule a policy accepts. Findings without a rule
+// ID, such as the ones of npm audit, are matched by their title.
+const maxRuleLength = 200
+
+// ValidFailOn returns true if failOn is a severity analyses can fail on.
+func ValidFailOn(failOn string) bool {
+	switch failOn {
+	case types.FailOnLow, types.FailOnMedium, types.FailOnHigh, types.FailOnNever:
+		return true
+	}
+	return false
+}
+
+// GetPolicy returns the policy of the repository given in the repositoryURL
+// query string parameter, for clients to know which vulnerabilities fail its
+// CI when they are not told otherwise.
+// @Summary Get the policy of a repository
+// @Tags policy
+// @Param repositoryURL query string true "Repository URL"
+// @Success 200 types.Policy
+// @Failure 400 Missing repository URL
+// @Failure 404 Policy not found
+// @Failure 500 Internal error
+// @Router GET /api/v2/policy
+func GetPolicy(c echo.Context) error {
+	repositoryURL := gitauth.NormalizeURL(c.QueryParam("repositoryURL"))
+	if repositoryURL == "" {
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid repository URL", "The 'repositoryURL' query string parameter is required.")
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+
+	policyQuery := map[string]interface{}{"repositoryURL": repositoryURL}
+	policy, err := apiContext.APIConfiguration.DBInstance.FindOneDBPolicy(policyQuery)
+	if err != nil {
+		if err == mongo.ErrNoDocuments || err.Error() == "No data found" {
+			reply := apierror.Reply(c, errcode.NotFound, "policy not found", fmt.Sprintf("No policy found for repository: %s", repositoryURL))
+			return c.JSON(http.StatusNotFound, reply)
+		}
+		log.Error(logActionGetPolicy, logInfoPolicy, 1063, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while retrieving the policy. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+	return c.JSON(http.StatusOK, policy)
+}
+
+// GetPolicies returns the policies of every repository.
+// @Summary List policies
+// @Tags admin
+// @Security basicAuth
+// @Success 200 []types.Policy
+// @Failure 401 Invalid credentials
+// @Failure 500 Internal error
+// @Router GET /api/v2/admin/policies
+func GetPolicies(c echo.Context) error {
+	policies, err := apiContext.APIConfiguration.DBInstance.FindAllDBPolicy(map[string]interface{}{})
+	if err != nil && err != mongo.ErrNoDocuments && err.Error() != "No data found" {
+		log.Error(logActionPolicies, logInfoPolicy, 1063, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while retrieving policies. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+	if policies == nil {
+		policies = []types.Policy{}
+	}
+	return c.JSON(http.StatusOK, policies)
+}
+
+// PutPolicy sets the lowest severity of the vulnerabilities failing the CI of
+// a repository, the rules whose findings the analyses of the repository
+// ignore, the severity they give to the findings of other rules and the
+// securityTests they run even when they select theirs, replacing any policy
+// it already had.
+// @Summary Set the policy of a repository
+// @Tags admin
+// @Security basicAuth
+// @Body PolicyRequest
+// @Success 200 types.Policy
+// @Failure 400 Invalid policy
+// @Failure 401 Invalid credentials
+// @Failure 500 Internal error
+// @Router PUT /api/v2/admin/policies
+func PutPolicy(c echo.Context) error {
+	request := PolicyRequest{}
+	if err := c.Bind(&request); err != nil {
+		log.Error(logActionPolicies, logInfoPolicy, 1062, err)
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid policy JSON", "The request body must be a JSON with 'repositoryURL', 'failOn', 'ignoreRules', 'severityOverrides' and 'requiredSecurityTests' fields.")
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+
+	repositoryURL := gitauth.NormalizeURL(request.RepositoryURL)
+	policy := types.Policy{
+		RepositoryURL: repositoryURL,
+		FailOn:        strings.ToLower(strings.TrimSpace(request.FailOn)),
+		IgnoreRules:   []string{},
+		UpdatedAt:     time.Now(),
+	}
+	if policy.FailOn == "" {
+		policy.FailOn = types.FailOnMedium
+	}
+	seen := map[string]bool{}
+	for _, rule := range request.IgnoreRules {
+		rule = strings.TrimSpace(rule)
+		if !seen[strings.ToLower(rule)] {
+			seen[strings.ToLower(rule)] = true
+			policy.IgnoreRules = append(policy.IgnoreRules, rule)
+		}
+	}
+	if len(request.SeverityOverrides) > 0 {
+		policy.SeverityOverrides = map[string]string{}
+		for rule, severity := range request.SeverityOverrides {
+			policy.SeverityOverrides[strings.TrimSpace(rule)] = strings.ToLower(strings.TrimSpace(severity))
+		}
+	}
+	required := map[string]bool{}
+	for _, name := range request.RequiredSecurityTests {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !required[name] {
+			required[name] = true
+			policy.RequiredSecurityTests = append(policy.RequiredSecurityTests, name)
+		}
+	}
+	if err := validatePolicy(policy); err != nil {
+		log.Error(logActionPolicies, logInfoPolicy, 1062, err)
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid policy JSON", err.Error())
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+
+	policyQuery := map[string]interface{}{"repositoryURL": repositoryURL}
+	if err := apiContext.APIConfiguration.DBInstance.UpsertOneDBPolicy(policyQuery, policy); err != nil {
+		log.Error(logActionPolicies, logInfoPolicy, 1063, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "Failed to store the policy. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+
+	log.Info(logActionPolicies, logInfoPolicy, 53, repositoryURL, policy.FailOn, policy.IgnoreRules, policy.SeverityOverrides, policy.RequiredSecurityTests)
+	return c.JSON(http.StatusOK, policy)
+}
+
+// DeletePolicy removes the policy of the repository given in the
+// repositoryURL query string parameter, which clients then fail on medium.
+// @Summary Remove the policy of a repository
+// @Tags admin
+// @Security basicAuth
+// @Param repositoryURL query string true "Repository URL"
+// @Success 204
+// @Failure 400 Missing repository URL
+// @Failure 401 Invalid credentials
+// @Failure 404 Policy not found
+// @Failure 500 Internal error
+// @Router DELETE /api/v2/admin/policies
+func DeletePolicy(c echo.Context) error {
+	repositoryURL := gitauth.NormalizeURL(c.QueryParam("repositoryURL"))
+	if repositoryURL == "" {
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid repository URL", "The 'repositoryURL' query string parameter is required.")
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+
+	policyQuery := map[string]interface{}{"repositoryURL": repositoryURL}
+	if err := apiContext.APIConfiguration.DBInstance.DeleteOneDBPolicy(policyQuery); err != nil {
+		if err == mongo.ErrNoDocuments || err.Error() == "No data found" {
+			reply := apierror.Reply(c, errcode.NotFound, "policy not found", fmt.Sprintf("No policy found for repository: %s", repositoryURL))
+			return c.JSON(http.StatusNotFound, reply)
+		}
+		log.Error(logActionPolicies, logInfoPolicy, 1063, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "Failed to remove the policy. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+
+	log.Info(logActionPolicies, logInfoPolicy, 54, repositoryURL)
+	return c.NoContent(http.StatusNoContent)
+}
+
+func validatePolicy(policy types.Policy) error {
+	repositoryURL := policy.RepositoryURL
+	isHTTP := strings.HasPrefix(repositoryURL, "https://") || strings.HasPrefix(repositoryURL, "http://")
+	isSSH := strings.HasPrefix(repositoryURL, "ssh://") || strings.HasPrefix(repositoryURL, "git@")
+	if !isHTTP && !isSSH {
+		return fmt.Errorf("'repositoryURL' must be an HTTP(S) or SSH git URL")
+	}
+	if !ValidFailOn(policy.FailOn) {
+		return fmt.Errorf("'failOn' must be high, medium, low or never")
+	}
+	if len(policy.IgnoreRules) > maxPolicyRules {
+		return fmt.Errorf("'ignoreRules' can hold up to %d rules", maxPolicyRules)
+	}
+	for _, rule := range policy.IgnoreRules {
+		if rule == "" || len(rule) > maxRuleLength {
+			return fmt.Errorf("'ignoreRules' must hold rule IDs, such as G104 or B101")
+		}
+	}
+	if len(policy.SeverityOverrides) > maxPolicyRules {
+		return fmt.Errorf("'severityOverrides' can hold up to %d rules", maxPolicyRules)
+	}
+	for rule, severity := range policy.SeverityOverrides {
+		if rule == "" || len(rule) > maxRuleLength {
+			return fmt.Errorf("'severityOverrides' must map rule IDs, such as generic-api-key, to severities")
+		}
+		if severity != "high" && severity != "medium" && severity != "low" {
+			return fmt.Errorf("'severityOverrides' severity of %s must be high, medium or low", rule)
+		}
+	}
+	for _, name := range policy.RequiredSecurityTests {
+		if !securityTestNameRegexp.MatchString(name) {
+			return fmt.Errorf("'requiredSecurityTests' must hold securityTest names, such as gosec or gitleaks")
+		}
+	}
+	return nil
+}
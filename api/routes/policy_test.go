@@ -0,0 +1,130 @@
+package routes_test
+
+import (
+	"errors"
+	"net/http"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/db"
+	"github.com/huskyci-org/huskyCI/api/routes"
+	"github.com/huskyci-org/huskyCI/api/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// policyDB keeps the policies by repository URL.
+type policyDB struct {
+	db.Requests
+	policies map[string]types.Policy
+}
+
+func (p *policyDB) FindOneDBPolicy(mapParams map[string]interface{}) (types.Policy, error) {
+	policy, ok := p.policies[mapParams["repositoryURL"].(string)]
+	if !ok {
+		return types.Policy{}, errors.New("No data found")
+	}
+	return policy, nil
+}
+
+func (p *policyDB) UpsertOneDBPolicy(mapParams map[string]interface{}, policy types.Policy) error {
+	p.policies[mapParams["repositoryURL"].(string)] = policy
+	return nil
+}
+
+var _ = Describe("Policies", func() {
+
+	var previousConfig *apiContext.APIConfig
+	var stored *policyDB
+
+	BeforeEach(func() {
+		stored = &policyDB{policies: map[string]types.Policy{}}
+		previousConfig = apiContext.APIConfiguration
+		apiContext.APIConfiguration = &apiContext.APIConfig{DBInstance: stored}
+	})
+
+	AfterEach(func() {
+		apiContext.APIConfiguration = previousConfig
+	})
+
+	put := func(body string) (int, string) {
+		rec := serve(routes.PutPolicy, http.MethodPut, "/api/v2/admin/policies", body)
+		return rec.Code, rec.Body.String()
+	}
+
+	Context("When a policy is set", func() {
+		It("Should store it normalized and return it to the clients of the repository", func() {
+			code, _ := put(`{"repositoryURL": "https://github.com/org/repo.git", "failOn": " High ", "ignoreRules": ["G104", "g104", "B101"], "severityOverrides": {"generic-api-key": "LOW"}, "requiredSecurityTests": ["GoSec", "gosec"]}`)
+			Expect(code).To(Equal(http.StatusOK))
+
+			Expect(stored.policies).To(HaveKey("https://github.com/org/repo"))
+			policy := stored.policies["https://github.com/org/repo"]
+			Expect(policy.FailOn).To(Equal("high"))
+			Expect(policy.IgnoreRules).To(Equal([]string{"G104", "B101"}))
+			Expect(policy.SeverityOverrides).To(Equal(map[string]string{"generic-api-key": "low"}))
+			Expect(policy.RequiredSecurityTests).To(Equal([]string{"gosec"}))
+
+			rec := serve(routes.GetPolicy, http.MethodGet, "/api/v2/policy?repositoryURL=https://github.com/org/repo", "")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(ContainSubstring(`"failOn":"high"`))
+		})
+	})
+	Context("When a policy sets no severity", func() {
+		It("Should fail on medium", func() {
+			code, body := put(`{"repositoryURL": "https://github.com/org/repo"}`)
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(body).To(ContainSubstring(`"failOn":"medium"`))
+		})
+	})
+	Context("When the policy is invalid", func() {
+		It("Should return 400 and store nothing", func() {
+			for body, reason := range map[string]string{
+				`{"repositoryURL": "file:///etc", "failOn": "high"}`:                                                     "repositoryURL",
+				`{"repositoryURL": "https://github.com/org/repo", "failOn": "critical"}`:                                 "failOn",
+				`{"repositoryURL": "https://github.com/org/repo", "ignoreRules": ["G104", " "]}`:                         "ignoreRules",
+				`{"repositoryURL": "https://github.com/org/repo", "severityOverrides": {"generic-api-key": "critical"}}`: "severityOverrides",
+			} {
+				code, body := put(body)
+				Expect(code).To(Equal(http.StatusBadRequest))
+				Expect(body).To(ContainSubstring(reason))
+			}
+			Expect(stored.policies).To(BeEmpty())
+		})
+	})
+})
+
+var _ = Describe("GetPolicy", func() {
+
+	var previousConfig *apiContext.APIConfig
+
+	BeforeEach(func() {
+		previousConfig = apiContext.APIConfiguration
+		apiContext.APIConfiguration = &apiContext.APIConfig{DBInstance: &policyDB{policies: map[string]types.Policy{}}}
+	})
+
+	AfterEach(func() {
+		apiContext.APIConfiguration = previousConfig
+	})
+
+	Context("When the repository URL is missing", func() {
+		It("Should return 400", func() {
+			rec := serve(routes.GetPolicy, http.MethodGet, "/api/v2/policy", "")
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+	Context("When the repository has no policy", func() {
+		It("Should return 404", func() {
+			rec := serve(routes.GetPolicy, http.MethodGet, "/api/v2/policy?repositoryURL=https://github.com/org/repo", "")
+			Expect(rec.Code).To(Equal(http.StatusNotFound))
+		})
+	})
+})
+
+var _ = Describe("ValidFailOn", func() {
+	It("Should accept the severities and never", func() {
+		for _, failOn := range []string{"high", "medium", "low", "never"} {
+			Expect(routes.ValidFailOn(failOn)).To(BeTrue())
+		}
+		Expect(routes.ValidFailOn("none")).To(BeFalse())
+	})
+})
@@ -0,0 +1,41 @@
+package routes
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/huskyci-org/huskyCI/api/apierror"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/prepull"
+	"github.com/huskyci-org/huskyCI/pkg/errcode"
+	"github.com/labstack/echo/v4"
+)
+
+// TriggerPrepull starts pulling every securityTest image on every Docker API
+// host in background. Set refresh=true to pull images that are already loaded.
+// @Summary Pull securityTest images
+// @Tags admin
+// @Security basicAuth
+// @Param refresh query bool false "Pull images that are already loaded as well"
+// @Success 202 Reply
+// @Failure 401 Invalid credentials
+// @Failure 501 Images are only pulled with the docker infrastructure
+// @Router POST /admin/prepull
+func TriggerPrepull(c echo.Context) error {
+	if os.Getenv("HUSKYCI_INFRASTRUCTURE_USE") != "docker" {
+		reply := apierror.Reply(c, errcode.NotImplemented, "not supported", "Image pre-pull is only supported with docker infrastructure.")
+		return c.JSON(http.StatusNotImplemented, reply)
+	}
+	refresh := c.QueryParam("refresh") == "true"
+	go func() {
+		if _, err := prepull.Run(refresh); err != nil {
+			log.Error("TriggerPrepull", "PREPULL", 3028, err)
+		}
+	}()
+	reply := map[string]interface{}{
+		"success": true,
+		"error":   "",
+		"message": "Image pre-pull started.",
+	}
+	return c.JSON(http.StatusAccepted, reply)
+}
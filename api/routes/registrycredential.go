@@ -0,0 +1,171 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/api/apierror"
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/fieldcrypt"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/registryauth"
+	"github.com/huskyci-org/huskyCI/api/types"
+	"github.com/huskyci-org/huskyCI/pkg/errcode"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const logActionRegistryCredentials = "AdminRegistryCredentials"
+const logInfoRegistryCredential = "REGISTRYCREDENTIAL"
+
+// RegistryCredentialRequest is the body received to store the credential
+// used to pull the images of a private container registry.
+type RegistryCredentialRequest struct {
+	Registry string `json:"registry"`
+	Username string `json:"username"`
+	Secret   string `json:"secret"`
+}
+
+// RegistryCredentialView is a stored registry credential without its secret.
+type RegistryCredentialView struct {
+	Registry  string    `json:"registry"`
+	Username  string    `json:"username"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// GetRegistryCredentials returns the registries that have a credential
+// stored. Secrets are never returned.
+// @Summary List registry credentials
+// @Tags admin
+// @Security basicAuth
+// @Success 200 []RegistryCredentialView
+// @Failure 401 Invalid credentials
+// @Failure 500 Internal error
+// @Router GET /admin/registries
+func GetRegistryCredentials(c echo.Context) error {
+	credentials, err := apiContext.APIConfiguration.DBInstance.FindAllDBRegistryCredential(map[string]interface{}{})
+	if err != nil && err != mongo.ErrNoDocuments && err.Error() != "No data found" {
+		log.Error(logActionRegistryCredentials, logInfoRegistryCredential, 1086, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while retrieving registry credentials. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+	views := []RegistryCredentialView{}
+	for _, credential := range credentials {
+		views = append(views, RegistryCredentialView{
+			Registry:  credential.Registry,
+			Username:  credential.Username,
+			UpdatedAt: credential.UpdatedAt,
+		})
+	}
+	return c.JSON(http.StatusOK, views)
+}
+
+// PutRegistryCredential stores, encrypted, the username and password or
+// access token the images of a registry are pulled with, replacing any
+// credential it already had. It takes precedence over the one of
+// HUSKYCI_REGISTRY_CREDENTIALS.
+// @Summary Set the credential of a container registry
+// @Tags admin
+// @Security basicAuth
+// @Body RegistryCredentialRequest
+// @Success 200 RegistryCredentialView
+// @Failure 400 Invalid registry credential
+// @Failure 401 Invalid credentials
+// @Failure 500 Internal error
+// @Failure 503 No database encryption key is configured
+// @Router PUT /admin/registries
+func PutRegistryCredential(c echo.Context) error {
+	request := RegistryCredentialRequest{}
+	if err := c.Bind(&request); err != nil {
+		log.Error(logActionRegistryCredentials, logInfoRegistryCredential, 1087, err)
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid registry credential JSON", "The request body must be a JSON with 'registry', 'username' and 'secret' fields.")
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+	if err := validateRegistryCredential(request); err != nil {
+		log.Error(logActionRegistryCredentials, logInfoRegistryCredential, 1087, err)
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid registry credential JSON", err.Error())
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+
+	registry := registryauth.NormalizeRegistry(request.Registry)
+	credential := types.RegistryCredential{
+		Registry:  registry,
+		Username:  request.Username,
+		Secret:    request.Secret,
+		UpdatedAt: time.Now(),
+	}
+	credentialQuery := map[string]interface{}{"registry": registry}
+	if err := apiContext.APIConfiguration.DBInstance.UpsertOneDBRegistryCredential(credentialQuery, credential); err != nil {
+		log.Error(logActionRegistryCredentials, logInfoRegistryCredential, 1086, err)
+		if errors.Is(err, fieldcrypt.ErrNoKey) {
+			reply := apierror.Reply(c, errcode.Unavailable, "registry credentials disabled", "Set HUSKYCI_DB_ENCRYPTION_KEYS in the huskyCI API to store registry credentials.")
+			return c.JSON(http.StatusServiceUnavailable, reply)
+		}
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "Failed to store the registry credential. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+
+	log.Info(logActionRegistryCredentials, logInfoRegistryCredential, 83, registry)
+	return c.JSON(http.StatusOK, RegistryCredentialView{
+		Registry:  credential.Registry,
+		Username:  credential.Username,
+		UpdatedAt: credential.UpdatedAt,
+	})
+}
+
+// DeleteRegistryCredential removes the credential of the registry given in
+// the registry query string parameter.
+// @Summary Remove the credential of a container registry
+// @Tags admin
+// @Security basicAuth
+// @Param registry query string true "Registry host, such as registry.example.com:5000"
+// @Success 204
+// @Failure 400 Missing registry
+// @Failure 401 Invalid credentials
+// @Failure 404 Registry credential not found
+// @Failure 500 Internal error
+// @Router DELETE /admin/registries
+func DeleteRegistryCredential(c echo.Context) error {
+	registry := registryauth.NormalizeRegistry(c.QueryParam("registry"))
+	if registry == "" {
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid registry", "The 'registry' query string parameter is required.")
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+
+	credentialQuery := map[string]interface{}{"registry": registry}
+	if err := apiContext.APIConfiguration.DBInstance.DeleteOneDBRegistryCredential(credentialQuery); err != nil {
+		if err == mongo.ErrNoDocuments || err.Error() == "No data found" {
+			reply := apierror.Reply(c, errcode.NotFound, "registry credential not found", fmt.Sprintf("No registry credential found for registry: %s", registry))
+			return c.JSON(http.StatusNotFound, reply)
+		}
+		log.Error(logActionRegistryCredentials, logInfoRegistryCredential, 1086, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "Failed to remove the registry credential. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+
+	log.Info(logActionRegistryCredentials, logInfoRegistryCredential, 84, registry)
+	return c.NoContent(http.StatusNoContent)
+}
+
+func validateRegistryCredential(request RegistryCredentialRequest) error {
+	registry := strings.TrimSpace(request.Registry)
+	if registry == "" {
+		return fmt.Errorf("'registry' can not be empty")
+	}
+	if strings.Contains(strings.TrimPrefix(strings.TrimPrefix(registry, "https://"), "http://"), "/") {
+		return fmt.Errorf("'registry' must be a registry host, such as registry.example.com:5000, without path")
+	}
+	if strings.ContainsAny(registry, " \t\n") {
+		return fmt.Errorf("'registry' can not contain spaces")
+	}
+	if request.Username == "" || request.Secret == "" {
+		return fmt.Errorf("'username' and 'secret' can not be empty")
+	}
+	if strings.ContainsAny(request.Username, " \t\n:") {
+		return fmt.Errorf("'username' can not contain spaces or colons")
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package routes_test
+
+import (
+	"net/http"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/db"
+	"github.com/huskyci-org/huskyCI/api/routes"
+	"github.com/huskyci-org/huskyCI/api/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// registryCredentialDB keeps the registry credentials, as stored, by registry.
+type registryCredentialDB struct {
+	db.Requests
+	credentials map[string]types.RegistryCredential
+}
+
+func (r *registryCredentialDB) FindAllDBRegistryCredential(mapParams map[string]interface{}) ([]types.RegistryCredential, error) {
+	credentials := []types.RegistryCredential{}
+	for _, credential := range r.credentials {
+		credentials = append(credentials, credential)
+	}
+	return credentials, nil
+}
+
+func (r *registryCredentialDB) UpsertOneDBRegistryCredential(mapParams map[string]interface{}, credential types.RegistryCredential) error {
+	r.credentials[mapParams["registry"].(string)] = credential
+	return nil
+}
+
+var _ = Describe("RegistryCredentials", func() {
+
+	var previousConfig *apiContext.APIConfig
+	var stored *registryCredentialDB
+
+	BeforeEach(func() {
+		stored = &registryCredentialDB{credentials: map[string]types.RegistryCredential{}}
+		previousConfig = apiContext.APIConfiguration
+		apiContext.APIConfiguration = &apiContext.APIConfig{DBInstance: encrypted(stored)}
+	})
+
+	AfterEach(func() {
+		apiContext.APIConfiguration = previousConfig
+	})
+
+	put := func(body string) (int, string) {
+		rec := serve(routes.PutRegistryCredential, http.MethodPut, "/api/v2/admin/registries", body)
+		return rec.Code, rec.Body.String()
+	}
+
+	Context("When a credential is set for a registry", func() {
+		It("Should store it encrypted under the normalized registry and leave it out of the responses", func() {
+			code, body := put(`{"registry": "Registry.Example.com:5000", "username": "huskyci", "secret": "s3cr3t-password"}`)
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(body).To(ContainSubstring(`"registry":"registry.example.com:5000"`))
+			Expect(body).NotTo(ContainSubstring("s3cr3t-password"))
+
+			Expect(stored.credentials).To(HaveKey("registry.example.com:5000"))
+			secret := stored.credentials["registry.example.com:5000"].Secret
+			Expect(secret).To(HavePrefix("v1:k1:"))
+			Expect(secret).NotTo(ContainSubstring("s3cr3t-password"))
+
+			rec := serve(routes.GetRegistryCredentials, http.MethodGet, "/api/v2/admin/registries", "")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(ContainSubstring(`"username":"huskyci"`))
+			Expect(rec.Body.String()).NotTo(ContainSubstring("s3cr3t-password"))
+			Expect(rec.Body.String()).NotTo(ContainSubstring("secret"))
+		})
+	})
+	Context("When no database encryption key is configured", func() {
+		It("Should return 503 and store nothing", func() {
+			apiContext.APIConfiguration.DBInstance = db.NewEncryptedRequests(stored, nil)
+			code, _ := put(`{"registry": "registry.example.com", "username": "huskyci", "secret": "s3cr3t-password"}`)
+			Expect(code).To(Equal(http.StatusServiceUnavailable))
+			Expect(stored.credentials).To(BeEmpty())
+		})
+	})
+	Context("When the credential is invalid", func() {
+		It("Should return 400 and store nothing", func() {
+			for body, reason := range map[string]string{
+				`{"username": "huskyci", "secret": "abc"}`:                                                 "registry",
+				`{"registry": "registry.example.com/org/scanner", "username": "huskyci", "secret": "abc"}`: "without path",
+				`{"registry": "registry.example.com", "username": "huskyci"}`:                              "secret",
+				`{"registry": "registry.example.com", "username": "husky:ci", "secret": "abc"}`:            "colons",
+			} {
+				code, body := put(body)
+				Expect(code).To(Equal(http.StatusBadRequest))
+				Expect(body).To(ContainSubstring(reason))
+			}
+			Expect(stored.credentials).To(BeEmpty())
+		})
+	})
+})
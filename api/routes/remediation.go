@@ -0,0 +1,170 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/api/apierror"
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/fieldcrypt"
+	"github.com/huskyci-org/huskyCI/api/gitauth"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/remediation"
+	"github.com/huskyci-org/huskyCI/api/types"
+	"github.com/huskyci-org/huskyCI/pkg/errcode"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const logActionRemediations = "AdminRemediations"
+const logInfoRemediation = "REMEDIATION"
+
+// RemediationRequest is the body received to open pull requests upgrading the
+// vulnerable dependencies found by the analyses of a repository.
+type RemediationRequest struct {
+	RepositoryURL string `json:"repositoryURL"`
+	Provider      string `json:"provider"`
+	Endpoint      string `json:"endpoint"`
+	Secret        string `json:"secret"`
+	BaseBranch    string `json:"baseBranch"`
+}
+
+// RemediationView is a stored remediation without its secret.
+type RemediationView struct {
+	RepositoryURL string    `json:"repositoryURL"`
+	Provider      string    `json:"provider"`
+	Endpoint      string    `json:"endpoint,omitempty"`
+	BaseBranch    string    `json:"baseBranch,omitempty"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+func remediationView(settings types.Remediation) RemediationView {
+	return RemediationView{
+		RepositoryURL: settings.RepositoryURL,
+		Provider:      settings.Provider,
+		Endpoint:      settings.Endpoint,
+		BaseBranch:    settings.BaseBranch,
+		UpdatedAt:     settings.UpdatedAt,
+	}
+}
+
+// GetRemediations returns the repositories that opted in to pull requests
+// upgrading their vulnerable dependencies. Secrets are never returned.
+// @Summary List remediations
+// @Tags admin
+// @Security basicAuth
+// @Success 200 []RemediationView
+// @Failure 401 Invalid credentials
+// @Failure 500 Internal error
+// @Router GET /admin/remediations
+func GetRemediations(c echo.Context) error {
+	remediations, err := apiContext.APIConfiguration.DBInstance.FindAllDBRemediation(map[string]interface{}{})
+	if err != nil && err != mongo.ErrNoDocuments && err.Error() != "No data found" {
+		log.Error(logActionRemediations, logInfoRemediation, 1058, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while retrieving remediations. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+	views := []RemediationView{}
+	for _, settings := range remediations {
+		views = append(views, remediationView(settings))
+	}
+	return c.JSON(http.StatusOK, views)
+}
+
+// PutRemediation opts a repository in to pull requests upgrading the vulnerable
+// dependencies its analyses find, replacing any remediation it already had.
+// The secret is stored encrypted.
+// @Summary Set the remediation of a repository
+// @Tags admin
+// @Security basicAuth
+// @Body RemediationRequest
+// @Success 200 RemediationView
+// @Failure 400 Invalid remediation
+// @Failure 401 Invalid credentials
+// @Failure 500 Internal error
+// @Failure 503 No database encryption key is configured
+// @Router PUT /admin/remediations
+func PutRemediation(c echo.Context) error {
+	request := RemediationRequest{}
+	if err := c.Bind(&request); err != nil {
+		log.Error(logActionRemediations, logInfoRemediation, 1057, err)
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid remediation JSON", "The request body must be a JSON with 'repositoryURL', 'provider', 'endpoint', 'secret' and 'baseBranch' fields.")
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+
+	repositoryURL := gitauth.NormalizeURL(request.RepositoryURL)
+	settings := types.Remediation{
+		RepositoryURL: repositoryURL,
+		Provider:      request.Provider,
+		Endpoint:      strings.TrimSuffix(strings.TrimSpace(request.Endpoint), "/"),
+		Secret:        request.Secret,
+		BaseBranch:    strings.TrimSpace(request.BaseBranch),
+		UpdatedAt:     time.Now(),
+	}
+	if err := validateRemediation(settings); err != nil {
+		log.Error(logActionRemediations, logInfoRemediation, 1057, err)
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid remediation JSON", err.Error())
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+
+	remediationQuery := map[string]interface{}{"repositoryURL": repositoryURL}
+	if err := apiContext.APIConfiguration.DBInstance.UpsertOneDBRemediation(remediationQuery, settings); err != nil {
+		log.Error(logActionRemediations, logInfoRemediation, 1058, err)
+		if errors.Is(err, fieldcrypt.ErrNoKey) {
+			reply := apierror.Reply(c, errcode.Unavailable, "remediations disabled", "Set HUSKYCI_DB_ENCRYPTION_KEYS in the huskyCI API to store remediations.")
+			return c.JSON(http.StatusServiceUnavailable, reply)
+		}
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "Failed to store the remediation. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+
+	log.Info(logActionRemediations, logInfoRemediation, 51, repositoryURL, settings.Provider)
+	return c.JSON(http.StatusOK, remediationView(settings))
+}
+
+// DeleteRemediation opts the repository given in the repositoryURL query
+// string parameter out of remediation pull requests.
+// @Summary Remove the remediation of a repository
+// @Tags admin
+// @Security basicAuth
+// @Param repositoryURL query string true "Repository URL"
+// @Success 204
+// @Failure 400 Missing repository URL
+// @Failure 401 Invalid credentials
+// @Failure 404 Remediation not found
+// @Failure 500 Internal error
+// @Router DELETE /admin/remediations
+func DeleteRemediation(c echo.Context) error {
+	repositoryURL := gitauth.NormalizeURL(c.QueryParam("repositoryURL"))
+	if repositoryURL == "" {
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid repository URL", "The 'repositoryURL' query string parameter is required.")
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+
+	remediationQuery := map[string]interface{}{"repositoryURL": repositoryURL}
+	if err := apiContext.APIConfiguration.DBInstance.DeleteOneDBRemediation(remediationQuery); err != nil {
+		if err == mongo.ErrNoDocuments || err.Error() == "No data found" {
+			reply := apierror.Reply(c, errcode.NotFound, "remediation not found", fmt.Sprintf("No remediation found for repository: %s", repositoryURL))
+			return c.JSON(http.StatusNotFound, reply)
+		}
+		log.Error(logActionRemediations, logInfoRemediation, 1058, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "Failed to remove the remediation. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+
+	log.Info(logActionRemediations, logInfoRemediation, 52, repositoryURL)
+	return c.NoContent(http.StatusNoContent)
+}
+
+func validateRemediation(settings types.Remediation) error {
+	repositoryURL := settings.RepositoryURL
+	isHTTP := strings.HasPrefix(repositoryURL, "https://") || strings.HasPrefix(repositoryURL, "http://")
+	isSSH := strings.HasPrefix(repositoryURL, "ssh://") || strings.HasPrefix(repositoryURL, "git@")
+	if !isHTTP && !isSSH {
+		return fmt.Errorf("'repositoryURL' must be an HTTP(S) or SSH git URL")
+	}
+	return remediation.Validate(settings)
+}
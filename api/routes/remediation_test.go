@@ -0,0 +1,96 @@
+package routes_test
+
+import (
+	"net/http"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/db"
+	"github.com/huskyci-org/huskyCI/api/routes"
+	"github.com/huskyci-org/huskyCI/api/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// remediationDB keeps the remediations, as stored, by repository URL.
+type remediationDB struct {
+	db.Requests
+	remediations map[string]types.Remediation
+}
+
+func (r *remediationDB) FindAllDBRemediation(mapParams map[string]interface{}) ([]types.Remediation, error) {
+	remediations := []types.Remediation{}
+	for _, remediation := range r.remediations {
+		remediations = append(remediations, remediation)
+	}
+	return remediations, nil
+}
+
+func (r *remediationDB) UpsertOneDBRemediation(mapParams map[string]interface{}, remediation types.Remediation) error {
+	r.remediations[mapParams["repositoryURL"].(string)] = remediation
+	return nil
+}
+
+var _ = Describe("Remediations", func() {
+
+	var previousConfig *apiContext.APIConfig
+	var stored *remediationDB
+
+	BeforeEach(func() {
+		stored = &remediationDB{remediations: map[string]types.Remediation{}}
+		previousConfig = apiContext.APIConfiguration
+		apiContext.APIConfiguration = &apiContext.APIConfig{DBInstance: encrypted(stored)}
+	})
+
+	AfterEach(func() {
+		apiContext.APIConfiguration = previousConfig
+	})
+
+	put := func(body string) (int, string) {
+		rec := serve(routes.PutRemediation, http.MethodPut, "/admin/remediations", body)
+		return rec.Code, rec.Body.String()
+	}
+
+	Context("When a GitHub remediation is set", func() {
+		It("Should store its token encrypted and leave it out of the responses", func() {
+			code, body := put(`{"repositoryURL": "https://github.com/org/repo.git", "provider": "github", "secret": "ghp_s3cr3t", "baseBranch": " develop "}`)
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(body).To(ContainSubstring(`"baseBranch":"develop"`))
+			Expect(body).NotTo(ContainSubstring("ghp_s3cr3t"))
+
+			Expect(stored.remediations).To(HaveKey("https://github.com/org/repo"))
+			secret := stored.remediations["https://github.com/org/repo"].Secret
+			Expect(secret).To(HavePrefix("v1:k1:"))
+			Expect(secret).NotTo(ContainSubstring("ghp_s3cr3t"))
+
+			rec := serve(routes.GetRemediations, http.MethodGet, "/admin/remediations", "")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(ContainSubstring(`"provider":"github"`))
+			Expect(rec.Body.String()).NotTo(ContainSubstring("ghp_s3cr3t"))
+			Expect(rec.Body.String()).NotTo(ContainSubstring("secret"))
+		})
+	})
+	Context("When no database encryption key is configured", func() {
+		It("Should return 503 and store nothing", func() {
+			apiContext.APIConfiguration.DBInstance = db.NewEncryptedRequests(stored, nil)
+			code, _ := put(`{"repositoryURL": "https://gitlab.com/group/repo.git", "provider": "gitlab", "secret": "glpat_s3cr3t"}`)
+			Expect(code).To(Equal(http.StatusServiceUnavailable))
+			Expect(stored.remediations).To(BeEmpty())
+		})
+	})
+	Context("When the remediation is invalid", func() {
+		It("Should return 400 and store nothing", func() {
+			for body, reason := range map[string]string{
+				`{"repositoryURL": "file:///etc", "provider": "github", "secret": "abc"}`:                                              "repositoryURL",
+				`{"repositoryURL": "https://github.com/org/repo", "provider": "bitbucket-cloud", "secret": "abc"}`:                     "provider",
+				`{"repositoryURL": "https://github.com/org/repo", "provider": "github", "secret": "abc", "baseBranch": "main branch"}`: "baseBranch",
+				`{"repositoryURL": "https://gitlab.com/group/repo.git", "provider": "gitlab"}`:                                         "secret",
+			} {
+				code, body := put(body)
+				Expect(code).To(Equal(http.StatusBadRequest))
+				Expect(body).To(ContainSubstring(reason))
+			}
+			Expect(stored.remediations).To(BeEmpty())
+		})
+	})
+})
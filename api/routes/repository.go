@@ -0,0 +1,299 @@
+package routes
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/api/apierror"
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/fingerprint"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/securitytest"
+	"github.com/huskyci-org/huskyCI/api/types"
+	"github.com/huskyci-org/huskyCI/api/util"
+	"github.com/huskyci-org/huskyCI/pkg/errcode"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const logActionCompareBranches = "CompareBranches"
+const logActionGetRepositorySummary = "GetRepositorySummary"
+const logActionGetRepositoryBadge = "GetRepositoryBadge"
+const logInfoRepository = "REPOSITORY"
+
+// ComparedAnalysis is the analysis of a branch findings were compared from.
+type ComparedAnalysis struct {
+	Branch     string    `json:"branch"`
+	RID        string    `json:"RID"`
+	Result     string    `json:"result"`
+	FinishedAt time.Time `json:"finishedAt"`
+}
+
+// BranchComparison is returned by GET /repository/:url/compare: the findings
+// of the latest finished analysis of branch A only, of branch B only, and of
+// both, matched by fingerprint.
+type BranchComparison struct {
+	RepositoryURL string           `json:"repositoryURL"`
+	BranchA       ComparedAnalysis `json:"branchA"`
+	BranchB       ComparedAnalysis `json:"branchB"`
+	fingerprint.Comparison
+}
+
+// CompareBranches compares the findings of the latest finished analyses of
+// two branches of a repository, such as a release branch and the branch it is
+// merged into. The repository URL is given path escaped.
+// @Summary Compare the findings of two branches
+// @Tags analysis
+// @Security huskyToken
+// @Param url path string true "Repository URL, path escaped"
+// @Param branchA query string true "First branch"
+// @Param branchB query string true "Second branch"
+// @Success 200 BranchComparison
+// @Failure 400 Invalid repository URL or branch
+// @Failure 401 Token is not allowed to read the analyses of this repository
+// @Failure 404 No finished analysis of a branch
+// @Failure 500 Internal error
+// @Router GET /api/v2/repository/:url/compare
+func CompareBranches(c echo.Context) error {
+	repositoryURL, ok := repositoryURLParam(c)
+	if !ok {
+		return invalidRepositoryURL(c)
+	}
+	branches := []string{c.QueryParam("branchA"), c.QueryParam("branchB")}
+	for i, name := range []string{"branchA", "branchB"} {
+		if branches[i] == "" {
+			reply := apierror.Reply(c, errcode.InvalidRequest, "invalid branch", fmt.Sprintf("The '%s' query string parameter is required.", name))
+			return c.JSON(http.StatusBadRequest, reply)
+		}
+	}
+
+	attemptToken := util.GetTokenFromRequest(c)
+	if !tokenValidator.HasAuthorization(attemptToken, repositoryURL) {
+		log.Error(logActionCompareBranches, logInfoRepository, 1027, repositoryURL)
+		reply := apierror.Reply(c, errcode.Unauthorized, "permission denied", fmt.Sprintf("The provided token does not have permission to read the analyses of repository: %s.", repositoryURL))
+		return c.JSON(http.StatusUnauthorized, reply)
+	}
+
+	analyses := []types.Analysis{}
+	for _, branch := range branches {
+		analysis, found, err := latestFinishedAnalysis(repositoryURL, branch)
+		if err != nil {
+			log.Error(logActionCompareBranches, logInfoRepository, 1059, err)
+			reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while comparing the branches. Please try again later.")
+			return c.JSON(http.StatusInternalServerError, reply)
+		}
+		if !found {
+			log.Warning(logActionCompareBranches, logInfoRepository, 122, repositoryURL, branch)
+			reply := apierror.Reply(c, errcode.NotFound, "analysis not found", fmt.Sprintf("No finished analysis found for branch %s of repository: %s.", branch, repositoryURL))
+			return c.JSON(http.StatusNotFound, reply)
+		}
+		analyses = append(analyses, analysis)
+	}
+
+	comparison := fingerprint.Compare(securitytest.Findings(analyses[0].HuskyCIResults), securitytest.Findings(analyses[1].HuskyCIResults))
+	return c.JSON(http.StatusOK, BranchComparison{
+		RepositoryURL: repositoryURL,
+		BranchA:       comparedAnalysis(analyses[0]),
+		BranchB:       comparedAnalysis(analyses[1]),
+		Comparison:    comparison,
+	})
+}
+
+// RepositorySummary is returned by GET /repository/:url/summary: the result
+// and the number of findings of the latest finished analysis of a branch.
+type RepositorySummary struct {
+	RepositoryURL string `json:"repositoryURL"`
+	ComparedAnalysis
+	HighVulns   int `json:"highVulns"`
+	MediumVulns int `json:"mediumVulns"`
+	LowVulns    int `json:"lowVulns"`
+}
+
+// defaultBranches are the branches tried, in order, when no branch is given to
+// summarize a repository.
+var defaultBranches = []string{"main", "master"}
+
+// GetRepositorySummary returns the result and the number of findings of the
+// latest finished analysis of a branch of a repository, main or else master
+// by default, for dashboards to poll. The repository URL is given path
+// escaped.
+// @Summary Summarize the latest analysis of a repository
+// @Tags analysis
+// @Security huskyToken
+// @Param url path string true "Repository URL, path escaped"
+// @Param branch query string false "Branch, main or else master by default"
+// @Success 200 RepositorySummary
+// @Failure 400 Invalid repository URL
+// @Failure 401 Token is not allowed to read the analyses of this repository
+// @Failure 404 No finished analysis of the branch
+// @Failure 500 Internal error
+// @Router GET /api/v2/repository/:url/summary
+func GetRepositorySummary(c echo.Context) error {
+	repositoryURL, ok := repositoryURLParam(c)
+	if !ok {
+		return invalidRepositoryURL(c)
+	}
+
+	attemptToken := util.GetTokenFromRequest(c)
+	if !tokenValidator.HasAuthorization(attemptToken, repositoryURL) {
+		log.Error(logActionGetRepositorySummary, logInfoRepository, 1027, repositoryURL)
+		reply := apierror.Reply(c, errcode.Unauthorized, "permission denied", fmt.Sprintf("The provided token does not have permission to read the analyses of repository: %s.", repositoryURL))
+		return c.JSON(http.StatusUnauthorized, reply)
+	}
+
+	analysis, found, err := latestBranchAnalysis(repositoryURL, c.QueryParam("branch"))
+	if err != nil {
+		log.Error(logActionGetRepositorySummary, logInfoRepository, 1065, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while summarizing the repository. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+	if !found {
+		log.Warning(logActionGetRepositorySummary, logInfoRepository, 122, repositoryURL, c.QueryParam("branch"))
+		reply := apierror.Reply(c, errcode.NotFound, "analysis not found", fmt.Sprintf("No finished analysis found for repository: %s.", repositoryURL))
+		return c.JSON(http.StatusNotFound, reply)
+	}
+
+	high, medium, low := securitytest.Counts(analysis.HuskyCIResults)
+	return c.JSON(http.StatusOK, RepositorySummary{
+		RepositoryURL:    repositoryURL,
+		ComparedAnalysis: comparedAnalysis(analysis),
+		HighVulns:        high,
+		MediumVulns:      medium,
+		LowVulns:         low,
+	})
+}
+
+// badgeColors are the colors of the results of the badges, other results
+// being grey.
+var badgeColors = map[string]string{
+	"passed":  "#4c1",
+	"warning": "#dfb317",
+	"failed":  "#e05d44",
+}
+
+// GetRepositoryBadge returns an SVG badge of the result of the latest finished
+// analysis of a branch of a repository, main or else master by default, to be
+// embedded in its README. It needs no token, as images of READMEs are
+// fetched anonymously, and only tells the result. The repository URL is
+// given path escaped.
+// @Summary Get a status badge of a repository
+// @Tags analysis
+// @Param url path string true "Repository URL, path escaped"
+// @Param branch query string false "Branch, main or else master by default"
+// @Success 200 string
+// @Failure 400 Invalid repository URL
+// @Failure 500 Internal error
+// @Router GET /api/v2/repository/:url/badge.svg
+func GetRepositoryBadge(c echo.Context) error {
+	repositoryURL, ok := repositoryURLParam(c)
+	if !ok {
+		return invalidRepositoryURL(c)
+	}
+
+	analysis, found, err := latestBranchAnalysis(repositoryURL, c.QueryParam("branch"))
+	if err != nil {
+		log.Error(logActionGetRepositoryBadge, logInfoRepository, 1065, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while summarizing the repository. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+	result := "unknown"
+	if found && analysis.Result != "" {
+		result = analysis.Result
+	}
+
+	// badges are cached by the proxies of code hosting services unless told not to
+	c.Response().Header().Set("Cache-Control", "no-cache, max-age=0")
+	return c.Blob(http.StatusOK, "image/svg+xml", badge("huskyCI", result))
+}
+
+// badge returns a flat SVG badge of label and message, sized after their
+// length.
+func badge(label, message string) []byte {
+	color, ok := badgeColors[message]
+	if !ok {
+		color = "#9f9f9f"
+	}
+	labelWidth := 7*len(label) + 10
+	messageWidth := 7*len(message) + 10
+	width := labelWidth + messageWidth
+	label = html.EscapeString(label)
+	message = html.EscapeString(message)
+	return []byte(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[4]s: %[5]s">`+
+		`<title>%[4]s: %[5]s</title>`+
+		`<rect width="%[2]d" height="20" fill="#555"/>`+
+		`<rect x="%[2]d" width="%[3]d" height="20" fill="%[6]s"/>`+
+		`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`+
+		`<text x="%[7]d" y="14">%[4]s</text>`+
+		`<text x="%[8]d" y="14">%[5]s</text>`+
+		`</g></svg>`,
+		width, labelWidth, messageWidth, label, message, color, labelWidth/2, labelWidth+messageWidth/2))
+}
+
+// repositoryURLParam returns the path unescaped url parameter of c.
+func repositoryURLParam(c echo.Context) (string, bool) {
+	repositoryURL, err := url.PathUnescape(c.Param("url"))
+	if err != nil || repositoryURL == "" {
+		return "", false
+	}
+	return repositoryURL, true
+}
+
+func invalidRepositoryURL(c echo.Context) error {
+	reply := apierror.Reply(c, errcode.InvalidRequest, "invalid repository URL", "The repository URL must be given path escaped, such as https:%2F%2Fgithub.com%2Forg%2Frepo.git.")
+	return c.JSON(http.StatusBadRequest, reply)
+}
+
+// latestBranchAnalysis returns the latest finished analysis of branch of
+// repositoryURL or, if branch is empty, of the first of defaultBranches
+// that has one.
+func latestBranchAnalysis(repositoryURL, branch string) (types.Analysis, bool, error) {
+	branches := defaultBranches
+	if branch != "" {
+		branches = []string{branch}
+	}
+	for _, branch := range branches {
+		analysis, found, err := latestFinishedAnalysis(repositoryURL, branch)
+		if err != nil || found {
+			return analysis, found, err
+		}
+	}
+	return types.Analysis{}, false, nil
+}
+
+// latestFinishedAnalysis returns the analysis of branch of repositoryURL that
+// started last among the finished ones.
+func latestFinishedAnalysis(repositoryURL, branch string) (types.Analysis, bool, error) {
+	filter := types.AnalysisFilter{
+		URL:            repositoryURL,
+		Branch:         branch,
+		Status:         "finished",
+		SortField:      "startedAt",
+		SortDescending: true,
+		Page:           1,
+		PageSize:       1,
+	}
+	summaries, _, err := apiContext.APIConfiguration.DBInstance.FindPageDBAnalysis(filter)
+	if err != nil && err != mongo.ErrNoDocuments && err.Error() != "No data found" {
+		return types.Analysis{}, false, err
+	}
+	if len(summaries) == 0 {
+		return types.Analysis{}, false, nil
+	}
+	analysis, err := apiContext.APIConfiguration.DBInstance.FindOneDBAnalysis(map[string]interface{}{"RID": summaries[0].RID})
+	if err != nil {
+		return types.Analysis{}, false, err
+	}
+	return analysis, true, nil
+}
+
+func comparedAnalysis(analysis types.Analysis) ComparedAnalysis {
+	return ComparedAnalysis{
+		Branch:     analysis.Branch,
+		RID:        analysis.RID,
+		Result:     analysis.Result,
+		FinishedAt: analysis.FinishedAt,
+	}
+}
@@ -0,0 +1,70 @@
+package routes_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/huskyci-org/huskyCI/api/routes"
+	"github.com/labstack/echo/v4"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CompareBranches", func() {
+
+	compare := func(repositoryURL, query string) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/repository/url/compare?"+query, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("url")
+		c.SetParamValues(repositoryURL)
+		Expect(routes.CompareBranches(c)).To(Succeed())
+		return rec
+	}
+
+	Context("When the repository URL is badly escaped", func() {
+		It("Should return 400", func() {
+			rec := compare("https:%2F%2Fgithub.com%2Forg%ZZ", "branchA=main&branchB=release")
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(ContainSubstring("invalid repository URL"))
+		})
+	})
+	Context("When branchB is missing", func() {
+		It("Should return 400", func() {
+			rec := compare("https:%2F%2Fgithub.com%2Forg%2Frepo.git", "branchA=main")
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(ContainSubstring("branchB"))
+		})
+	})
+})
+
+var _ = Describe("GetRepositorySummary", func() {
+	Context("When the repository URL is badly escaped", func() {
+		It("Should return 400", func() {
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/repository/url/summary", nil), rec)
+			c.SetParamNames("url")
+			c.SetParamValues("https:%2F%2Fgithub.com%2Forg%ZZ")
+			Expect(routes.GetRepositorySummary(c)).To(Succeed())
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(ContainSubstring("invalid repository URL"))
+		})
+	})
+})
+
+var _ = Describe("GetRepositoryBadge", func() {
+	Context("When the repository URL is empty", func() {
+		It("Should return 400", func() {
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/repository/url/badge.svg", nil), rec)
+			c.SetParamNames("url")
+			c.SetParamValues("")
+			Expect(routes.GetRepositoryBadge(c)).To(Succeed())
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+})
@@ -1,8 +1,15 @@
 package routes_test
 
 import (
+	"encoding/base64"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/huskyci-org/huskyCI/api/db"
+	"github.com/huskyci-org/huskyCI/api/fieldcrypt"
+	"github.com/labstack/echo/v4"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -11,3 +18,29 @@ func TestRoutes(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "Routes Suite")
 }
+
+// serve calls handler with a request to target holding body as JSON. params
+// are the names and values of the path parameters, in pairs.
+func serve(handler echo.HandlerFunc, method, target, body string, params ...string) *httptest.ResponseRecorder {
+	e := echo.New()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	names, values := []string{}, []string{}
+	for i := 0; i+1 < len(params); i += 2 {
+		names, values = append(names, params[i]), append(values, params[i+1])
+	}
+	c.SetParamNames(names...)
+	c.SetParamValues(values...)
+	Expect(handler(c)).To(Succeed())
+	return rec
+}
+
+// encrypted returns requests with their secrets encrypted by the key k1, as
+// the API stores them once HUSKYCI_DB_ENCRYPTION_KEYS is set.
+func encrypted(requests db.Requests) *db.EncryptedRequests {
+	keyring, err := fieldcrypt.NewKeyring(fieldcrypt.Config{Keys: "k1:" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("a", 32)))})
+	Expect(err).NotTo(HaveOccurred())
+	return db.NewEncryptedRequests(requests, keyring)
+}
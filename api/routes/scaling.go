@@ -0,0 +1,35 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/huskyci-org/huskyCI/api/apierror"
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/scaling"
+	"github.com/huskyci-org/huskyCI/pkg/errcode"
+	"github.com/labstack/echo/v4"
+)
+
+const logActionGetScalingHints = "GetScalingHints"
+const logInfoScaling = "SCALING"
+
+// GetScalingHints returns how loaded the runner hosts are: the analyses
+// running beyond their capacity, how long they should wait and how many hosts
+// would run every analysis at once.
+// @Summary Get scaling hints
+// @Tags admin
+// @Security basicAuth
+// @Success 200 scaling.Hints
+// @Failure 401 Invalid credentials
+// @Failure 500 Internal error
+// @Router GET /api/v2/admin/scaling
+func GetScalingHints(c echo.Context) error {
+	hints, err := scaling.Read(apiContext.APIConfiguration.ScalingConfig)
+	if err != nil {
+		log.Error(logActionGetScalingHints, logInfoScaling, 1073, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while estimating the load of the runner hosts. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+	return c.JSON(http.StatusOK, hints)
+}
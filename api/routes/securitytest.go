@@ -0,0 +1,226 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/huskyci-org/huskyCI/api/apierror"
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/signature"
+	"github.com/huskyci-org/huskyCI/api/types"
+	"github.com/huskyci-org/huskyCI/pkg/errcode"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const logActionAdminSecurityTests = "AdminSecurityTests"
+const logActionListSecurityTests = "ListSecurityTests"
+const logInfoSecurityTest = "SECURITYTEST"
+
+var securityTestNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// SecurityTestUpdate holds the securityTest fields an admin is allowed to
+// change at runtime. Nil fields are kept untouched.
+type SecurityTestUpdate struct {
+	Image            *string `json:"image"`
+	ImageTag         *string `json:"imageTag"`
+	ImageDigest      *string `json:"imageDigest"`
+	Cmd              *string `json:"cmd"`
+	Default          *bool   `json:"default"`
+	TimeOutInSeconds *int    `json:"timeOutSeconds"`
+	NetworkMode      *string `json:"networkMode"`
+	MaxOutputSizeKB  *int    `json:"maxOutputSizeKB"`
+	WarmPool         *bool   `json:"warmPool"`
+}
+
+// SecurityTestView is a securityTest as shown to the clients, without the
+// command it runs.
+type SecurityTestView struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Language string `json:"language,omitempty"`
+	Image    string `json:"image"`
+	ImageTag string `json:"imageTag"`
+	Default  bool   `json:"default"`
+}
+
+// SecurityTestViews returns the Language and Generic securityTests, the ones
+// that look for vulnerabilities, sorted by type, language and name.
+func SecurityTestViews(securityTests []types.SecurityTest) []SecurityTestView {
+	views := []SecurityTestView{}
+	for _, securityTest := range securityTests {
+		if securityTest.Type != "Language" && securityTest.Type != "Generic" {
+			continue
+		}
+		views = append(views, SecurityTestView{
+			Name:     securityTest.Name,
+			Type:     securityTest.Type,
+			Language: securityTest.Language,
+			Image:    securityTest.Image,
+			ImageTag: securityTest.ImageTag,
+			Default:  securityTest.Default,
+		})
+	}
+	sort.Slice(views, func(i, j int) bool {
+		if views[i].Type != views[j].Type {
+			return views[i].Type > views[j].Type
+		}
+		if views[i].Language != views[j].Language {
+			return views[i].Language < views[j].Language
+		}
+		return views[i].Name < views[j].Name
+	})
+	return views
+}
+
+// ListSecurityTests returns the securityTests an analysis may run, for the
+// clients to show which ones apply to the languages of a repository. Only the
+// default ones run unless an operator changes them.
+// @Summary List the securityTests an analysis may run
+// @Tags securitytest
+// @Success 200 []SecurityTestView
+// @Failure 500 Internal error
+// @Router GET /api/v2/securitytests
+func ListSecurityTests(c echo.Context) error {
+	securityTests, err := apiContext.APIConfiguration.DBInstance.FindAllDBSecurityTest(map[string]interface{}{})
+	if err != nil && err != mongo.ErrNoDocuments && err.Error() != "No data found" {
+		log.Error(logActionListSecurityTests, logInfoSecurityTest, 2012, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while retrieving securityTests. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+	return c.JSON(http.StatusOK, SecurityTestViews(securityTests))
+}
+
+// GetSecurityTests returns all securityTests stored in MongoDB.
+// @Summary List securityTests
+// @Tags admin
+// @Security basicAuth
+// @Success 200 []types.SecurityTest
+// @Failure 401 Invalid credentials
+// @Failure 500 Internal error
+// @Router GET /admin/securitytests
+func GetSecurityTests(c echo.Context) error {
+	securityTests, err := apiContext.APIConfiguration.DBInstance.FindAllDBSecurityTest(map[string]interface{}{})
+	if err != nil && err != mongo.ErrNoDocuments && err.Error() != "No data found" {
+		log.Error(logActionAdminSecurityTests, logInfoSecurityTest, 2012, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while retrieving securityTests. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+	return c.JSON(http.StatusOK, securityTests)
+}
+
+// UpdateSecurityTest updates the image, image tag, cmd, default flag or
+// timeout of a securityTest stored in MongoDB.
+// @Summary Update a securityTest
+// @Tags admin
+// @Security basicAuth
+// @Param name path string true "SecurityTest name"
+// @Body SecurityTestUpdate
+// @Success 200 types.SecurityTest
+// @Failure 400 Invalid securityTest update
+// @Failure 401 Invalid credentials
+// @Failure 404 SecurityTest not found
+// @Failure 500 Internal error
+// @Router PUT /admin/securitytests/:name
+func UpdateSecurityTest(c echo.Context) error {
+	securityTestName := c.Param("name")
+	if !securityTestNameRegexp.MatchString(securityTestName) {
+		log.Warning(logActionAdminSecurityTests, logInfoSecurityTest, 108, securityTestName)
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid securityTest name", "The securityTest name may only contain letters, numbers, '-' and '_'.")
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+
+	update := SecurityTestUpdate{}
+	if err := c.Bind(&update); err != nil {
+		log.Error(logActionAdminSecurityTests, logInfoSecurityTest, 1045, err)
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid securityTest JSON", "The request body must be a JSON with any of 'image', 'imageTag', 'cmd', 'default' and 'timeOutSeconds' fields.")
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+	if err := validateSecurityTestUpdate(update); err != nil {
+		log.Error(logActionAdminSecurityTests, logInfoSecurityTest, 1045, err)
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid securityTest JSON", err.Error())
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+
+	securityTestQuery := map[string]interface{}{"name": securityTestName}
+	securityTest, err := apiContext.APIConfiguration.DBInstance.FindOneDBSecurityTest(securityTestQuery)
+	if err != nil {
+		if err == mongo.ErrNoDocuments || err.Error() == "No data found" {
+			reply := apierror.Reply(c, errcode.NotFound, "securityTest not found", fmt.Sprintf("No securityTest found with name: %s", securityTestName))
+			return c.JSON(http.StatusNotFound, reply)
+		}
+		log.Error(logActionAdminSecurityTests, logInfoSecurityTest, 2012, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while retrieving the securityTest. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+
+	if update.Image != nil {
+		securityTest.Image = *update.Image
+	}
+	if update.ImageTag != nil {
+		securityTest.ImageTag = *update.ImageTag
+	}
+	if update.ImageDigest != nil {
+		securityTest.ImageDigest = *update.ImageDigest
+	}
+	if update.Cmd != nil {
+		securityTest.Cmd = *update.Cmd
+	}
+	if update.Default != nil {
+		securityTest.Default = *update.Default
+	}
+	if update.TimeOutInSeconds != nil {
+		securityTest.TimeOutInSeconds = *update.TimeOutInSeconds
+	}
+	if update.NetworkMode != nil {
+		securityTest.NetworkMode = *update.NetworkMode
+	}
+	if update.MaxOutputSizeKB != nil {
+		securityTest.MaxOutputSizeKB = *update.MaxOutputSizeKB
+	}
+	if update.WarmPool != nil {
+		securityTest.WarmPool = *update.WarmPool
+	}
+
+	if _, err := apiContext.APIConfiguration.DBInstance.UpsertOneDBSecurityTest(securityTestQuery, securityTest); err != nil {
+		log.Error(logActionAdminSecurityTests, logInfoSecurityTest, 1023, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "Failed to update the securityTest. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+
+	log.Info(logActionAdminSecurityTests, logInfoSecurityTest, 28, securityTestName)
+	return c.JSON(http.StatusOK, securityTest)
+}
+
+func validateSecurityTestUpdate(update SecurityTestUpdate) error {
+	if update.Image != nil && (*update.Image == "" || strings.ContainsAny(*update.Image, " \t\n")) {
+		return fmt.Errorf("'image' must be a non empty image name without spaces")
+	}
+	if update.ImageTag != nil && (*update.ImageTag == "" || strings.ContainsAny(*update.ImageTag, " \t\n")) {
+		return fmt.Errorf("'imageTag' must be a non empty tag without spaces")
+	}
+	if update.ImageDigest != nil && *update.ImageDigest != "" && !signature.ValidDigest(*update.ImageDigest) {
+		return fmt.Errorf("'imageDigest' must be empty or in the sha256:<hex> form")
+	}
+	if update.Cmd != nil && strings.TrimSpace(*update.Cmd) == "" {
+		return fmt.Errorf("'cmd' can not be empty")
+	}
+	if update.TimeOutInSeconds != nil && *update.TimeOutInSeconds <= 0 {
+		return fmt.Errorf("'timeOutSeconds' must be greater than zero")
+	}
+	if update.NetworkMode != nil {
+		switch *update.NetworkMode {
+		case types.NetworkModeNone, types.NetworkModeEgressProxy, types.NetworkModeFull:
+		default:
+			return fmt.Errorf("'networkMode' must be one of none, egress-proxy or full")
+		}
+	}
+	if update.MaxOutputSizeKB != nil && *update.MaxOutputSizeKB < 0 {
+		return fmt.Errorf("'maxOutputSizeKB' can not be negative")
+	}
+	return nil
+}
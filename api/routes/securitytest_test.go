@@ -0,0 +1,92 @@
+package routes_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/huskyci-org/huskyCI/api/routes"
+	"github.com/huskyci-org/huskyCI/api/types"
+	"github.com/labstack/echo/v4"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("UpdateSecurityTest", func() {
+
+	update := func(name, body string) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPut, "/admin/securitytests/"+name, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("name")
+		c.SetParamValues(name)
+		Expect(routes.UpdateSecurityTest(c)).To(Succeed())
+		return rec
+	}
+
+	Context("When the securityTest name is invalid", func() {
+		It("Should return 400", func() {
+			rec := update("gosec;rm", `{"imageTag": "latest"}`)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(ContainSubstring("invalid securityTest name"))
+		})
+	})
+	Context("When the timeout is not positive", func() {
+		It("Should return 400", func() {
+			rec := update("gosec", `{"timeOutSeconds": 0}`)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(ContainSubstring("timeOutSeconds"))
+		})
+	})
+	Context("When the image tag has spaces", func() {
+		It("Should return 400", func() {
+			rec := update("gosec", `{"imageTag": "latest; echo"}`)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+	Context("When the image digest is not a sha256 digest", func() {
+		It("Should return 400", func() {
+			rec := update("gosec", `{"imageDigest": "latest"}`)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(ContainSubstring("imageDigest"))
+		})
+	})
+	Context("When the network mode is unknown", func() {
+		It("Should return 400", func() {
+			rec := update("gosec", `{"networkMode": "host"}`)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(ContainSubstring("networkMode"))
+		})
+	})
+	Context("When the output size limit is negative", func() {
+		It("Should return 400", func() {
+			rec := update("gosec", `{"maxOutputSizeKB": -1}`)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(ContainSubstring("maxOutputSizeKB"))
+		})
+	})
+})
+
+var _ = Describe("SecurityTestViews", func() {
+	It("Should keep the Language and Generic securityTests, sorted, without their command", func() {
+		securityTests := []types.SecurityTest{
+			{Name: "gitleaks", Type: "Generic", Image: "huskyci/gitleaks", ImageTag: "2.1.0", Cmd: "gitleaks", Default: true},
+			{Name: "enry", Type: "Enry", Image: "huskyci/enry", ImageTag: "latest", Default: true},
+			{Name: "safety", Type: "Language", Language: "Python", Image: "huskyci/safety", ImageTag: "1.8.5", Default: true},
+			{Name: "bandit", Type: "Language", Language: "Python", Image: "huskyci/bandit", ImageTag: "1.6.2", Default: true},
+			{Name: "gosec", Type: "Language", Language: "Go", Image: "huskyci/gosec", ImageTag: "2.3.0", Default: false},
+		}
+		Expect(routes.SecurityTestViews(securityTests)).To(Equal([]routes.SecurityTestView{
+			{Name: "gosec", Type: "Language", Language: "Go", Image: "huskyci/gosec", ImageTag: "2.3.0", Default: false},
+			{Name: "bandit", Type: "Language", Language: "Python", Image: "huskyci/bandit", ImageTag: "1.6.2", Default: true},
+			{Name: "safety", Type: "Language", Language: "Python", Image: "huskyci/safety", ImageTag: "1.8.5", Default: true},
+			{Name: "gitleaks", Type: "Generic", Image: "huskyci/gitleaks", ImageTag: "2.1.0", Default: true},
+		}))
+	})
+	It("Should return an empty list when there is no securityTest", func() {
+		Expect(routes.SecurityTestViews(nil)).To(BeEmpty())
+	})
+})
@@ -8,14 +8,24 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/patrickmn/go-cache"
 
+	"github.com/huskyci-org/huskyCI/api/apierror"
 	apiContext "github.com/huskyci-org/huskyCI/api/context"
 	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/pkg/errcode"
 )
 
 const logActionGetMetric = "GetMetric"
 const logInfoStats = "STATS"
 
 // GetMetric returns data about the metric received
+// @Summary Get a metric
+// @Tags stats
+// @Param metric_type path string true "Metric: language, container, analysis, repository, author, severity, historyanalysis, analysisperday, tooltime, toprules or openfindings"
+// @Param time_range query string false "Time range, such as today, yesterday, last7days or last30days"
+// @Success 200 any
+// @Failure 400 Invalid metric or time range
+// @Failure 500 Internal error
+// @Router GET /stats/:metric_type
 func GetMetric(c echo.Context) error {
 	url := c.Request().URL.String()
 	metricType := strings.ToLower(c.Param("metric_type"))
@@ -27,7 +37,7 @@ func GetMetric(c echo.Context) error {
 
 	result, err := apiContext.APIConfiguration.DBInstance.GetMetricByType(metricType, queryParams)
 	if err != nil {
-		httpStatus, reply := checkError(err, metricType)
+		httpStatus, reply := checkError(c, err, metricType)
 		return c.JSON(httpStatus, reply)
 	}
 
@@ -36,31 +46,19 @@ func GetMetric(c echo.Context) error {
 	return c.JSON(http.StatusOK, result)
 }
 
-func checkError(err error, metricType string) (int, map[string]interface{}) {
+func checkError(c echo.Context, err error, metricType string) (int, map[string]interface{}) {
 	switch err.Error() {
 	case "invalid time_range query string param":
 		log.Warning(logActionGetMetric, logInfoStats, 111, err)
-		reply := map[string]interface{}{
-			"success": false,
-			"error":   "invalid time_range parameter",
-			"message": "The 'time_range' query parameter is invalid. Please provide a valid time range format.",
-		}
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid time_range parameter", "The 'time_range' query parameter is invalid. Please provide a valid time range format.")
 		return http.StatusBadRequest, reply
 	case "invalid metric type":
 		log.Warning(logActionGetMetric, logInfoStats, 112, metricType, err)
-		reply := map[string]interface{}{
-			"success": false,
-			"error":   "invalid metric type",
-			"message": fmt.Sprintf("The metric type '%s' is not valid. Please check the available metric types and try again.", metricType),
-		}
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid metric type", fmt.Sprintf("The metric type '%s' is not valid. Please check the available metric types and try again.", metricType))
 		return http.StatusBadRequest, reply
 	default:
 		log.Error(logActionGetMetric, logInfoStats, 2017, metricType, err)
-		reply := map[string]interface{}{
-			"success": false,
-			"error":   "internal server error",
-			"message": "An unexpected error occurred while retrieving metrics. Please try again later.",
-		}
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while retrieving metrics. Please try again later.")
 		return http.StatusInternalServerError, reply
 	}
 }
@@ -0,0 +1,178 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/api/apierror"
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/fieldcrypt"
+	"github.com/huskyci-org/huskyCI/api/gitauth"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/statusreport"
+	"github.com/huskyci-org/huskyCI/api/types"
+	"github.com/huskyci-org/huskyCI/pkg/errcode"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const logActionStatusReporters = "AdminStatusReporters"
+const logInfoStatusReporter = "STATUSREPORTER"
+
+// StatusReporterRequest is the body received to post the result of the
+// analyses of a repository to Bitbucket or Gerrit.
+type StatusReporterRequest struct {
+	RepositoryURL string `json:"repositoryURL"`
+	Provider      string `json:"provider"`
+	Endpoint      string `json:"endpoint"`
+	Username      string `json:"username"`
+	Secret        string `json:"secret"`
+	Label         string `json:"label"`
+	DetailsURL    string `json:"detailsURL"`
+}
+
+// StatusReporterView is a stored status reporter without its secret.
+type StatusReporterView struct {
+	RepositoryURL string    `json:"repositoryURL"`
+	Provider      string    `json:"provider"`
+	Endpoint      string    `json:"endpoint,omitempty"`
+	Username      string    `json:"username,omitempty"`
+	Label         string    `json:"label,omitempty"`
+	DetailsURL    string    `json:"detailsURL,omitempty"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+func statusReporterView(reporter types.StatusReporter) StatusReporterView {
+	return StatusReporterView{
+		RepositoryURL: reporter.RepositoryURL,
+		Provider:      reporter.Provider,
+		Endpoint:      reporter.Endpoint,
+		Username:      reporter.Username,
+		Label:         reporter.Label,
+		DetailsURL:    reporter.DetailsURL,
+		UpdatedAt:     reporter.UpdatedAt,
+	}
+}
+
+// GetStatusReporters returns the repositories whose analyses are reported to
+// Bitbucket or Gerrit. Secrets are never returned.
+// @Summary List status reporters
+// @Tags admin
+// @Security basicAuth
+// @Success 200 []StatusReporterView
+// @Failure 401 Invalid credentials
+// @Failure 500 Internal error
+// @Router GET /admin/statusreporters
+func GetStatusReporters(c echo.Context) error {
+	reporters, err := apiContext.APIConfiguration.DBInstance.FindAllDBStatusReporter(map[string]interface{}{})
+	if err != nil && err != mongo.ErrNoDocuments && err.Error() != "No data found" {
+		log.Error(logActionStatusReporters, logInfoStatusReporter, 1055, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while retrieving status reporters. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+	views := []StatusReporterView{}
+	for _, reporter := range reporters {
+		views = append(views, statusReporterView(reporter))
+	}
+	return c.JSON(http.StatusOK, views)
+}
+
+// PutStatusReporter stores where the result of the analyses of a repository is
+// posted once they finish, replacing any status reporter it already had. The
+// secret is stored encrypted.
+// @Summary Set the status reporter of a repository
+// @Tags admin
+// @Security basicAuth
+// @Body StatusReporterRequest
+// @Success 200 StatusReporterView
+// @Failure 400 Invalid status reporter
+// @Failure 401 Invalid credentials
+// @Failure 500 Internal error
+// @Failure 503 No database encryption key is configured
+// @Router PUT /admin/statusreporters
+func PutStatusReporter(c echo.Context) error {
+	request := StatusReporterRequest{}
+	if err := c.Bind(&request); err != nil {
+		log.Error(logActionStatusReporters, logInfoStatusReporter, 1053, err)
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid status reporter JSON", "The request body must be a JSON with 'repositoryURL', 'provider', 'endpoint', 'username', 'secret', 'label' and 'detailsURL' fields.")
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+
+	repositoryURL := gitauth.NormalizeURL(request.RepositoryURL)
+	reporter := types.StatusReporter{
+		RepositoryURL: repositoryURL,
+		Provider:      request.Provider,
+		Endpoint:      strings.TrimSuffix(strings.TrimSpace(request.Endpoint), "/"),
+		Username:      request.Username,
+		Secret:        request.Secret,
+		Label:         request.Label,
+		DetailsURL:    strings.TrimSpace(request.DetailsURL),
+		UpdatedAt:     time.Now(),
+	}
+	if err := validateStatusReporter(reporter); err != nil {
+		log.Error(logActionStatusReporters, logInfoStatusReporter, 1053, err)
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid status reporter JSON", err.Error())
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+
+	reporterQuery := map[string]interface{}{"repositoryURL": repositoryURL}
+	if err := apiContext.APIConfiguration.DBInstance.UpsertOneDBStatusReporter(reporterQuery, reporter); err != nil {
+		log.Error(logActionStatusReporters, logInfoStatusReporter, 1055, err)
+		if errors.Is(err, fieldcrypt.ErrNoKey) {
+			reply := apierror.Reply(c, errcode.Unavailable, "status reporters disabled", "Set HUSKYCI_DB_ENCRYPTION_KEYS in the huskyCI API to store status reporters.")
+			return c.JSON(http.StatusServiceUnavailable, reply)
+		}
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "Failed to store the status reporter. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+
+	log.Info(logActionStatusReporters, logInfoStatusReporter, 47, repositoryURL, reporter.Provider)
+	return c.JSON(http.StatusOK, statusReporterView(reporter))
+}
+
+// DeleteStatusReporter removes the status reporter of the repository given in
+// the repositoryURL query string parameter.
+// @Summary Remove the status reporter of a repository
+// @Tags admin
+// @Security basicAuth
+// @Param repositoryURL query string true "Repository URL"
+// @Success 204
+// @Failure 400 Missing repository URL
+// @Failure 401 Invalid credentials
+// @Failure 404 Status reporter not found
+// @Failure 500 Internal error
+// @Router DELETE /admin/statusreporters
+func DeleteStatusReporter(c echo.Context) error {
+	repositoryURL := gitauth.NormalizeURL(c.QueryParam("repositoryURL"))
+	if repositoryURL == "" {
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid repository URL", "The 'repositoryURL' query string parameter is required.")
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+
+	reporterQuery := map[string]interface{}{"repositoryURL": repositoryURL}
+	if err := apiContext.APIConfiguration.DBInstance.DeleteOneDBStatusReporter(reporterQuery); err != nil {
+		if err == mongo.ErrNoDocuments || err.Error() == "No data found" {
+			reply := apierror.Reply(c, errcode.NotFound, "status reporter not found", fmt.Sprintf("No status reporter found for repository: %s", repositoryURL))
+			return c.JSON(http.StatusNotFound, reply)
+		}
+		log.Error(logActionStatusReporters, logInfoStatusReporter, 1055, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "Failed to remove the status reporter. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+
+	log.Info(logActionStatusReporters, logInfoStatusReporter, 48, repositoryURL)
+	return c.NoContent(http.StatusNoContent)
+}
+
+func validateStatusReporter(reporter types.StatusReporter) error {
+	repositoryURL := reporter.RepositoryURL
+	isHTTP := strings.HasPrefix(repositoryURL, "https://") || strings.HasPrefix(repositoryURL, "http://")
+	isSSH := strings.HasPrefix(repositoryURL, "ssh://") || strings.HasPrefix(repositoryURL, "git@")
+	if !isHTTP && !isSSH {
+		return fmt.Errorf("'repositoryURL' must be an HTTP(S) or SSH git URL")
+	}
+	return statusreport.Validate(reporter)
+}
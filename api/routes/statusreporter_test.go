@@ -0,0 +1,96 @@
+package routes_test
+
+import (
+	"net/http"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/db"
+	"github.com/huskyci-org/huskyCI/api/routes"
+	"github.com/huskyci-org/huskyCI/api/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// statusReporterDB keeps the status reporters, as stored, by repository URL.
+type statusReporterDB struct {
+	db.Requests
+	reporters map[string]types.StatusReporter
+}
+
+func (s *statusReporterDB) FindAllDBStatusReporter(mapParams map[string]interface{}) ([]types.StatusReporter, error) {
+	reporters := []types.StatusReporter{}
+	for _, reporter := range s.reporters {
+		reporters = append(reporters, reporter)
+	}
+	return reporters, nil
+}
+
+func (s *statusReporterDB) UpsertOneDBStatusReporter(mapParams map[string]interface{}, reporter types.StatusReporter) error {
+	s.reporters[mapParams["repositoryURL"].(string)] = reporter
+	return nil
+}
+
+var _ = Describe("StatusReporters", func() {
+
+	var previousConfig *apiContext.APIConfig
+	var stored *statusReporterDB
+
+	BeforeEach(func() {
+		stored = &statusReporterDB{reporters: map[string]types.StatusReporter{}}
+		previousConfig = apiContext.APIConfiguration
+		apiContext.APIConfiguration = &apiContext.APIConfig{DBInstance: encrypted(stored)}
+	})
+
+	AfterEach(func() {
+		apiContext.APIConfiguration = previousConfig
+	})
+
+	put := func(body string) (int, string) {
+		rec := serve(routes.PutStatusReporter, http.MethodPut, "/admin/statusreporters", body)
+		return rec.Code, rec.Body.String()
+	}
+
+	Context("When a Bitbucket Server reporter is set", func() {
+		It("Should store its secret encrypted and leave it out of the responses", func() {
+			code, body := put(`{"repositoryURL": "https://bitbucket.example.com/scm/team/repo.git", "provider": "bitbucket-server", "endpoint": "https://bitbucket.example.com/", "secret": "bbs_s3cr3t", "label": "huskyCI"}`)
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(body).To(ContainSubstring(`"endpoint":"https://bitbucket.example.com"`))
+			Expect(body).NotTo(ContainSubstring("bbs_s3cr3t"))
+
+			Expect(stored.reporters).To(HaveKey("https://bitbucket.example.com/scm/team/repo"))
+			secret := stored.reporters["https://bitbucket.example.com/scm/team/repo"].Secret
+			Expect(secret).To(HavePrefix("v1:k1:"))
+			Expect(secret).NotTo(ContainSubstring("bbs_s3cr3t"))
+
+			rec := serve(routes.GetStatusReporters, http.MethodGet, "/admin/statusreporters", "")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(ContainSubstring(`"label":"huskyCI"`))
+			Expect(rec.Body.String()).NotTo(ContainSubstring("bbs_s3cr3t"))
+			Expect(rec.Body.String()).NotTo(ContainSubstring("secret"))
+		})
+	})
+	Context("When no database encryption key is configured", func() {
+		It("Should return 503 and store nothing", func() {
+			apiContext.APIConfiguration.DBInstance = db.NewEncryptedRequests(stored, nil)
+			code, _ := put(`{"repositoryURL": "https://bitbucket.org/team/repo.git", "provider": "bitbucket-cloud", "username": "huskyci", "secret": "bbc_s3cr3t"}`)
+			Expect(code).To(Equal(http.StatusServiceUnavailable))
+			Expect(stored.reporters).To(BeEmpty())
+		})
+	})
+	Context("When the reporter is invalid", func() {
+		It("Should return 400 and store nothing", func() {
+			for body, reason := range map[string]string{
+				`{"repositoryURL": "file:///etc", "provider": "bitbucket-cloud", "secret": "abc"}`:                                      "repositoryURL",
+				`{"repositoryURL": "https://github.com/org/repo", "provider": "github", "secret": "abc"}`:                               "provider",
+				`{"repositoryURL": "https://bitbucket.example.com/scm/team/repo.git", "provider": "bitbucket-server", "secret": "abc"}`: "endpoint",
+				`{"repositoryURL": "https://bitbucket.org/team/repo.git", "provider": "bitbucket-cloud"}`:                               "secret",
+			} {
+				code, body := put(body)
+				Expect(code).To(Equal(http.StatusBadRequest))
+				Expect(body).To(ContainSubstring(reason))
+			}
+			Expect(stored.reporters).To(BeEmpty())
+		})
+	})
+})
@@ -4,10 +4,12 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/huskyci-org/huskyCI/api/apierror"
 	"github.com/huskyci-org/huskyCI/api/auth"
 	"github.com/huskyci-org/huskyCI/api/log"
 	"github.com/huskyci-org/huskyCI/api/token"
 	"github.com/huskyci-org/huskyCI/api/types"
+	"github.com/huskyci-org/huskyCI/pkg/errcode"
 	"github.com/labstack/echo/v4"
 )
 
@@ -27,15 +29,21 @@ func init() {
 // HandleToken generate an access token for a specific repository or a generic token.
 // If repositoryURL is provided, the token will be scoped to that repository.
 // If repositoryURL is empty or omitted, a generic token will be created that works with any repository.
+// @Summary Generate an access token
+// @Tags token
+// @Security basicAuth
+// @Body types.TokenRequest
+// @Success 201 TokenResponse{success:bool, huskytoken:string, tokenType:string, message:string}
+// @Failure 400 Invalid request body
+// @Failure 401 Invalid credentials
+// @Failure 429 Too many failed basic auths for the username or address
+// @Failure 500 Token could not be generated
+// @Router POST /api/1.0/token
 func HandleToken(c echo.Context) error {
 	repoRequest := types.TokenRequest{}
 	if err := c.Bind(&repoRequest); err != nil {
 		log.Error("HandleToken", "TOKEN", 1025, err)
-		return c.JSON(http.StatusBadRequest, map[string]interface{}{
-			"success": false,
-			"error":   "invalid request format",
-			"message": "The request body must be valid JSON. Provide 'repositoryURL' for a repository-specific token, or omit it for a generic token. Example: {\"repositoryURL\": \"https://github.com/user/repo.git\"} or {}",
-		})
+		return c.JSON(http.StatusBadRequest, apierror.Reply(c, errcode.InvalidRequest, "invalid request format", "The request body must be valid JSON. Provide 'repositoryURL' for a repository-specific token, or omit it for a generic token. Example: {\"repositoryURL\": \"https://github.com/user/repo.git\"} or {}"))
 	}
 	
 	tokenType := "repository-specific"
@@ -49,11 +57,7 @@ func HandleToken(c echo.Context) error {
 	accessToken, err := tokenHandler.GenerateAccessToken(repoRequest)
 	if err != nil {
 		log.Error("HandleToken ", "TOKEN", 1026, err)
-		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-			"success": false,
-			"error":   "token generation failure",
-			"message": "Failed to generate access token. Please verify the repository URL and try again.",
-		})
+		return c.JSON(http.StatusInternalServerError, apierror.Reply(c, errcode.Internal, "token generation failure", "Failed to generate access token. Please verify the repository URL and try again."))
 	}
 	
 	var message string
@@ -73,23 +77,25 @@ func HandleToken(c echo.Context) error {
 
 // HandleDeactivation will deactivate an access token passed in the body
 // of the request
+// @Summary Deactivate an access token
+// @Tags token
+// @Security basicAuth
+// @Body types.AccessToken
+// @Success 200 Reply
+// @Failure 400 Invalid request body
+// @Failure 401 Invalid credentials
+// @Failure 429 Too many failed basic auths for the username or address
+// @Failure 500 Token could not be deactivated
+// @Router POST /api/1.0/token/deactivate
 func HandleDeactivation(c echo.Context) error {
 	tokenRequest := types.AccessToken{}
 	if err := c.Bind(&tokenRequest); err != nil {
 		log.Error("HandleInvalidate", "TOKEN", 1025, err)
-		return c.JSON(http.StatusBadRequest, map[string]interface{}{
-			"success": false,
-			"error":   "invalid request format",
-			"message": "The request body must be valid JSON with a 'huskytoken' field. Example: {\"huskytoken\": \"your-token-here\"}",
-		})
+		return c.JSON(http.StatusBadRequest, apierror.Reply(c, errcode.InvalidRequest, "invalid request format", "The request body must be valid JSON with a 'huskytoken' field. Example: {\"huskytoken\": \"your-token-here\"}"))
 	}
 	if err := tokenHandler.InvalidateToken(tokenRequest.HuskyToken); err != nil {
 		log.Error("HandleInvalidate ", "TOKEN", 1028, err)
-		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-			"success": false,
-			"error":   "token deactivation failure",
-			"message": "Failed to deactivate the token. Please verify the token and try again.",
-		})
+		return c.JSON(http.StatusInternalServerError, apierror.Reply(c, errcode.Internal, "token deactivation failure", "Failed to deactivate the token. Please verify the token and try again."))
 	}
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"success": true,
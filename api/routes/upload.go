@@ -0,0 +1,145 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/huskyci-org/huskyCI/api/apierror"
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/debugtrace"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/storage"
+	"github.com/huskyci-org/huskyCI/api/upload"
+	"github.com/huskyci-org/huskyCI/api/util"
+	"github.com/huskyci-org/huskyCI/pkg/errcode"
+	"github.com/labstack/echo/v4"
+)
+
+// multipartOverhead is the room left for the multipart headers of an upload
+// on top of the size of the zip.
+const multipartOverhead = 1 << 20
+
+// uploadConfig returns how the uploaded zips are checked.
+func uploadConfig() *upload.Config {
+	if apiContext.APIConfiguration == nil || apiContext.APIConfiguration.UploadConfig == nil {
+		return &upload.Config{MaxSize: upload.DefaultMaxSize}
+	}
+	return apiContext.APIConfiguration.UploadConfig
+}
+
+// replyUploadError answers with a 413 status if the upload of RID was too
+// large, a 422 status if it was rejected by err, or a 503 status if it could
+// not be checked.
+func replyUploadError(c echo.Context, RID string, err error) error {
+	debugtrace.Record(debugtrace.Lifecycle, "upload", "rejected", RID, "error", err)
+	var rejected *upload.RejectedError
+	var tooLarge *http.MaxBytesError
+	status, message := http.StatusUnprocessableEntity, ""
+	switch {
+	case errors.Is(err, upload.ErrTooLarge) || errors.As(err, &tooLarge):
+		status, message = http.StatusRequestEntityTooLarge, "The zip file is larger than the maximum size accepted by the huskyCI API."
+	case errors.Is(err, upload.ErrNotZip):
+		message = "The file uploaded is not a zip archive."
+	case errors.As(err, &rejected):
+		message = "The zip file was rejected by the antivirus: " + rejected.Reason
+	default:
+		log.Error("UploadZip", logInfoAnalysis, 1060, RID, err)
+		reply := apierror.Reply(c, errcode.Unavailable, "scanner unavailable", "The zip file could not be scanned. Please try again later.")
+		return c.JSON(http.StatusServiceUnavailable, reply)
+	}
+	log.Warning("UploadZip", logInfoAnalysis, 123, RID, err)
+	reply := apierror.Reply(c, errcode.FromStatus(status), "upload rejected", message)
+	return c.JSON(status, reply)
+}
+
+// storeZip stores the zip of the zipfile field of the request under RID,
+// once checked, and shares it with the other API replicas. If it can not, it
+// replies with the reason and returns false.
+func storeZip(c echo.Context, RID string) (bool, error) {
+	// Ensure zip storage directory exists
+	if err := util.EnsureZipStorageDir(); err != nil {
+		log.Error("UploadZip", logInfoAnalysis, 1019, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "Failed to initialize zip storage directory.")
+		return false, c.JSON(http.StatusInternalServerError, reply)
+	}
+
+	// Refuse uploads larger than the configured size before storing them
+	config := uploadConfig()
+	if config.MaxSize > 0 {
+		c.Request().Body = http.MaxBytesReader(c.Response(), c.Request().Body, config.MaxSize+multipartOverhead)
+	}
+
+	// Get uploaded file
+	file, err := c.FormFile("zipfile")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return false, replyUploadError(c, RID, err)
+	}
+	if err != nil {
+		log.Error("UploadZip", logInfoAnalysis, 1020, err)
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid request", "No zip file provided. Use multipart/form-data with 'zipfile' field.")
+		return false, c.JSON(http.StatusBadRequest, reply)
+	}
+
+	// Validate file extension
+	if filepath.Ext(file.Filename) != ".zip" {
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid file type", "File must be a .zip archive.")
+		return false, c.JSON(http.StatusBadRequest, reply)
+	}
+	if config.MaxSize > 0 && file.Size > config.MaxSize {
+		return false, replyUploadError(c, RID, upload.ErrTooLarge)
+	}
+
+	// Open uploaded file
+	src, err := file.Open()
+	if err != nil {
+		log.Error("UploadZip", logInfoAnalysis, 1021, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "Failed to open uploaded file.")
+		return false, c.JSON(http.StatusInternalServerError, reply)
+	}
+	defer src.Close()
+
+	// Save file
+	zipPath := util.GetZipFilePath(RID)
+	log.Info("UploadZip", logInfoAnalysis, 25, fmt.Sprintf("Saving zip file to: %s", zipPath))
+	dst, err := os.Create(zipPath)
+	if err != nil {
+		log.Error("UploadZip", logInfoAnalysis, 1022, fmt.Sprintf("Failed to create file at %s: %v", zipPath, err))
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", fmt.Sprintf("Failed to save uploaded file to %s: %v", zipPath, err))
+		return false, c.JSON(http.StatusInternalServerError, reply)
+	}
+	defer dst.Close()
+
+	written, err := io.Copy(dst, src)
+	if err != nil {
+		log.Error("UploadZip", logInfoAnalysis, 1023, fmt.Sprintf("Failed to copy file content: %v", err))
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", fmt.Sprintf("Failed to save uploaded file: %v", err))
+		return false, c.JSON(http.StatusInternalServerError, reply)
+	}
+
+	dst.Close()
+	debugtrace.Record(debugtrace.Lifecycle, "upload", "stored", RID, "filename", file.Filename, "bytes", written, "path", zipPath)
+
+	// Check the content of the zip before any container touches it
+	if err := config.Check(c.Request().Context(), zipPath); err != nil {
+		os.Remove(zipPath)
+		return false, replyUploadError(c, RID, err)
+	}
+
+	// Share the zip with other API replicas when an object storage is configured
+	if objectStorage := apiContext.APIConfiguration.Storage; objectStorage != nil {
+		if err := storage.UploadFile(objectStorage, storage.ZipKey(RID), zipPath); err != nil {
+			log.Error("UploadZip", logInfoAnalysis, 1042, err)
+			reply := apierror.Reply(c, errcode.Internal, "internal server error", "Failed to store uploaded file in object storage.")
+			return false, c.JSON(http.StatusInternalServerError, reply)
+		}
+		debugtrace.Record(debugtrace.Lifecycle, "upload", "shared", RID, "key", storage.ZipKey(RID))
+	}
+
+	log.Info("UploadZip", logInfoAnalysis, 26, fmt.Sprintf("RID: %s, Filename: %s, Path: %s", RID, file.Filename, zipPath))
+	return true, nil
+}
@@ -0,0 +1,94 @@
+package routes_test
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/routes"
+	"github.com/huskyci-org/huskyCI/api/upload"
+	"github.com/huskyci-org/huskyCI/api/util"
+	"github.com/labstack/echo/v4"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("UploadZip", func() {
+
+	const RID = "11111111-2222-3333-4444-555555555555"
+	emptyZip := []byte("PK\x05\x06" + string(make([]byte, 18)))
+
+	apiContext.DefaultConf.SetOnceConfig()
+	var previous *upload.Config
+
+	BeforeEach(func() {
+		previous = apiContext.APIConfiguration.UploadConfig
+		apiContext.APIConfiguration.UploadConfig = &upload.Config{MaxSize: upload.DefaultMaxSize}
+	})
+	AfterEach(func() {
+		apiContext.APIConfiguration.UploadConfig = previous
+		os.Remove(util.GetZipFilePath(RID))
+	})
+
+	uploadZip := func(content []byte) *httptest.ResponseRecorder {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, err := writer.CreateFormFile("zipfile", "code.zip")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = part.Write(content)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(writer.Close()).To(Succeed())
+
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/analysis/upload?rid="+RID, body)
+		req.Header.Set(echo.HeaderContentType, writer.FormDataContentType())
+		rec := httptest.NewRecorder()
+		Expect(routes.UploadZip(e.NewContext(req, rec))).To(Succeed())
+		return rec
+	}
+
+	Context("When the upload is a zip archive", func() {
+		It("Should store it", func() {
+			rec := uploadZip(emptyZip)
+			Expect(rec.Code).To(Equal(http.StatusCreated))
+			Expect(util.GetZipFilePath(RID)).To(BeAnExistingFile())
+		})
+	})
+	Context("When the upload is not a zip archive", func() {
+		It("Should return 422 and remove it", func() {
+			rec := uploadZip([]byte("#!/bin/sh\nrm -rf /\n"))
+			Expect(rec.Code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(rec.Body.String()).To(ContainSubstring("not a zip archive"))
+			Expect(util.GetZipFilePath(RID)).NotTo(BeAnExistingFile())
+		})
+	})
+	Context("When the upload is larger than the maximum size", func() {
+		It("Should return 413", func() {
+			apiContext.APIConfiguration.UploadConfig.MaxSize = 10
+			rec := uploadZip(emptyZip)
+			Expect(rec.Code).To(Equal(http.StatusRequestEntityTooLarge))
+			Expect(rec.Body.String()).To(ContainSubstring("larger than the maximum size"))
+		})
+	})
+	Context("When the scan command finds the upload malicious", func() {
+		It("Should return 422 and remove it", func() {
+			apiContext.APIConfiguration.UploadConfig.ScanCommand = "false"
+			rec := uploadZip(emptyZip)
+			Expect(rec.Code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(rec.Body.String()).To(ContainSubstring("rejected by the antivirus"))
+			Expect(util.GetZipFilePath(RID)).NotTo(BeAnExistingFile())
+		})
+	})
+	Context("When the scan command fails", func() {
+		It("Should return 503", func() {
+			apiContext.APIConfiguration.UploadConfig.ScanCommand = "huskyci-no-such-scanner"
+			rec := uploadZip(emptyZip)
+			Expect(rec.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(util.GetZipFilePath(RID)).NotTo(BeAnExistingFile())
+		})
+	})
+})
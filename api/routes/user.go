@@ -3,20 +3,27 @@ package routes
 import (
 	"net/http"
 
-	"encoding/base64"
-	"hash"
-
+	"github.com/huskyci-org/huskyCI/api/apierror"
 	"github.com/huskyci-org/huskyCI/api/auth"
 	apiContext "github.com/huskyci-org/huskyCI/api/context"
 	"github.com/huskyci-org/huskyCI/api/log"
 	"github.com/huskyci-org/huskyCI/api/types"
+	"github.com/huskyci-org/huskyCI/pkg/errcode"
 	"github.com/labstack/echo/v4"
 	"go.mongodb.org/mongo-driver/mongo"
-	"golang.org/x/crypto/pbkdf2"
-	"gopkg.in/mgo.v2"
 )
 
 // UpdateUser edits an user
+// @Summary Change the password of a user
+// @Tags user
+// @Body types.User
+// @Success 201 UserUpdated{success:bool, error:string}
+// @Failure 400 Invalid user or new password
+// @Failure 401 Wrong password
+// @Failure 404 User not found
+// @Failure 429 Too many wrong passwords for the username or address
+// @Failure 500 Internal error
+// @Router PUT /user
 func UpdateUser(c echo.Context) error {
 
 	// step 1.1: valid JSON?
@@ -24,7 +31,7 @@ func UpdateUser(c echo.Context) error {
 	err := c.Bind(&attemptUser)
 	if err != nil {
 		log.Error("EditUser", "USER", 1024, err)
-		reply := map[string]interface{}{"success": false, "error": "invalid user JSON"}
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid user JSON", "The request body must be a JSON with 'username', 'password', 'newPassword' and 'confirmNewPassword' fields.")
 		return c.JSON(http.StatusBadRequest, reply)
 	}
 
@@ -32,68 +39,72 @@ func UpdateUser(c echo.Context) error {
 
 	// step 2.1: password/user is empty?
 	if attemptUser.Password == "" || attemptUser.Username == "" || attemptUser.NewPassword == "" {
-		reply := map[string]interface{}{"success": false, "error": "passwords/username can not be empty"}
+		reply := apierror.Reply(c, errcode.InvalidRequest, "passwords/username can not be empty", "The username, the password and the new password can not be empty.")
 		return c.JSON(http.StatusBadRequest, reply)
 	}
 
 	// step 2.2: passwords match?
 	if attemptUser.NewPassword != attemptUser.ConfirmNewPassword {
-		reply := map[string]interface{}{"success": false, "error": "passwords do not match"}
+		reply := apierror.Reply(c, errcode.InvalidRequest, "passwords do not match", "The new password and its confirmation do not match.")
 		return c.JSON(http.StatusBadRequest, reply)
 	}
 
+	// step 2.3: new password is strong enough?
+	if err := auth.CheckPasswordComplexity(attemptUser.Username, attemptUser.NewPassword); err != nil {
+		return weakPassword(c, err)
+	}
+
 	// step 3: user exists?
 	userQuery := map[string]interface{}{"username": attemptUser.Username}
 	user, err := apiContext.APIConfiguration.DBInstance.FindOneDBUser(userQuery)
 	if err != nil {
-		if err == mgo.ErrNotFound || err.Error() == "No data found" {
-			reply := map[string]interface{}{"success": false, "error": "user not found"}
+		if err == mongo.ErrNoDocuments || err.Error() == "No data found" {
+			reply := apierror.Reply(c, errcode.NotFound, "user not found", "No user has this username.")
 			return c.JSON(http.StatusNotFound, reply)
 		}
-		reply := map[string]interface{}{"success": false, "error": "internal error"}
+		reply := apierror.Reply(c, errcode.Internal, "internal error", "An unexpected error occurred. Please try again later.")
 		return c.JSON(http.StatusInternalServerError, reply)
 	}
 
-	// step 4: password is correct?
-	hashFunction, isValid := auth.GetValidHashFunction(user.HashFunction)
-	if !isValid {
-		reply := map[string]interface{}{"success": false, "error": "invalid hash function"}
-		return c.JSON(http.StatusInternalServerError, reply)
+	// step 4: password is correct and user is enabled?
+	lockout := auth.DefaultLockout()
+	if rejected, err := lockout.Reject(c, attemptUser.Username); rejected {
+		return err
 	}
-	salt, err := base64.StdEncoding.DecodeString(user.Salt)
+	isValid, err := auth.ValidateUser(attemptUser.Username, attemptUser.Password, c)
 	if err != nil {
-		reply := map[string]interface{}{"success": false, "error": "failed to update user data"}
+		reply := apierror.Reply(c, errcode.Internal, "failed to update user data", "The password of the user could not be read.")
 		return c.JSON(http.StatusInternalServerError, reply)
 	}
-	hashedPass := pbkdf2.Key([]byte(attemptUser.Password), salt, user.Iterations, user.KeyLen, func() hash.Hash {
-		return hashFunction
-	})
-	if base64.StdEncoding.EncodeToString(hashedPass) != user.Password {
-		reply := map[string]interface{}{"success": false, "error": "unauthorized"}
+	lockout.Record(c, attemptUser.Username, isValid)
+	if !isValid {
+		reply := apierror.Reply(c, errcode.Unauthorized, "unauthorized", "The password is not the one of the user, or the user is disabled.")
 		return c.JSON(http.StatusUnauthorized, reply)
 	}
 
-	// step 5.1: prepare new user struct to be updated
-	newHashedPass := pbkdf2.Key([]byte(attemptUser.NewPassword), salt, user.Iterations, user.KeyLen, func() hash.Hash {
-		return hashFunction
-	})
-
+	// step 5.1: hash the new password with a new salt
+	if err := auth.SetPassword(&auth.Pbkdf2Caller{}, &user, attemptUser.NewPassword); err != nil {
+		reply := apierror.Reply(c, errcode.Internal, "failed to update user data", "The new password could not be hashed.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
 	updatedUser := types.User{
-		Username:     attemptUser.Username,
-		Password:     base64.StdEncoding.EncodeToString(newHashedPass),
+		Username:     user.Username,
+		Password:     user.Password,
 		Salt:         user.Salt,
 		Iterations:   user.Iterations,
 		KeyLen:       user.KeyLen,
 		HashFunction: user.HashFunction,
+		Role:         user.Role,
+		Disabled:     user.Disabled,
 	}
 
 	// step 5.2: update user
 	if err := apiContext.APIConfiguration.DBInstance.UpdateOneDBUser(userQuery, updatedUser); err != nil {
 		if err == mongo.ErrNoDocuments || err.Error() == "No data found" {
-			reply := map[string]interface{}{"success": false, "error": "user not found"}
+			reply := apierror.Reply(c, errcode.NotFound, "user not found", "No user has this username.")
 			return c.JSON(http.StatusNotFound, reply)
 		}
-		reply := map[string]interface{}{"success": false, "error": "internal error"}
+		reply := apierror.Reply(c, errcode.Internal, "internal error", "An unexpected error occurred. Please try again later.")
 		return c.JSON(http.StatusInternalServerError, reply)
 	}
 
@@ -0,0 +1,310 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/huskyci-org/huskyCI/api/apierror"
+	"github.com/huskyci-org/huskyCI/api/auth"
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/types"
+	"github.com/huskyci-org/huskyCI/pkg/errcode"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const logActionUsers = "AdminUsers"
+const logInfoUser = "USER"
+
+var usernameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][-_.@a-zA-Z0-9]{0,63}$`)
+
+// UserInfo is a user of the API as returned to admins, without its password.
+type UserInfo struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+	Disabled bool   `json:"disabled"`
+}
+
+// UserRequest is the body received to create a user. Its role is operator
+// unless it is set to admin.
+type UserRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+// UserChange is the body received to change the role of a user or to
+// disable it. The fields left out are not changed.
+type UserChange struct {
+	Role     *string `json:"role"`
+	Disabled *bool   `json:"disabled"`
+}
+
+// PasswordRequest is the body received to set the password of a user.
+type PasswordRequest struct {
+	Password string `json:"password"`
+}
+
+// GetUsers returns every user of the API.
+// @Summary List users
+// @Tags admin
+// @Security basicAuth
+// @Success 200 []UserInfo
+// @Failure 401 Invalid credentials
+// @Failure 403 Not an admin
+// @Failure 500 Internal error
+// @Router GET /api/v2/admin/users
+func GetUsers(c echo.Context) error {
+	users, err := apiContext.APIConfiguration.DBInstance.FindAllDBUser(map[string]interface{}{})
+	if err != nil && err != mongo.ErrNoDocuments && err.Error() != "No data found" {
+		log.Error(logActionUsers, logInfoUser, 1083, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while retrieving users. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+	infos := make([]UserInfo, 0, len(users))
+	for _, user := range users {
+		infos = append(infos, userInfo(user))
+	}
+	return c.JSON(http.StatusOK, infos)
+}
+
+// CreateUser creates a user with a password hashed with PBKDF2.
+// @Summary Create a user
+// @Tags admin
+// @Security basicAuth
+// @Body UserRequest
+// @Success 201 UserInfo
+// @Failure 400 Invalid user or weak password
+// @Failure 401 Invalid credentials
+// @Failure 403 Not an admin
+// @Failure 409 User already exists
+// @Failure 500 Internal error
+// @Router POST /api/v2/admin/users
+func CreateUser(c echo.Context) error {
+	request := UserRequest{}
+	if err := c.Bind(&request); err != nil {
+		log.Error(logActionUsers, logInfoUser, 1082, err)
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid user JSON", "The request body must be a JSON with 'username', 'password' and 'role' fields.")
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+	if !usernameRegexp.MatchString(request.Username) {
+		return invalidUsername(c)
+	}
+	if request.Role == "" {
+		request.Role = auth.RoleOperator
+	}
+	if !auth.IsValidRole(request.Role) {
+		return invalidRole(c)
+	}
+	if err := auth.CheckPasswordComplexity(request.Username, request.Password); err != nil {
+		return weakPassword(c, err)
+	}
+
+	userQuery := map[string]interface{}{"username": request.Username}
+	if _, err := apiContext.APIConfiguration.DBInstance.FindOneDBUser(userQuery); err == nil {
+		reply := apierror.Reply(c, errcode.Conflict, "user already exists", fmt.Sprintf("A user named %s already exists.", request.Username))
+		return c.JSON(http.StatusConflict, reply)
+	} else if err != mongo.ErrNoDocuments && err.Error() != "No data found" {
+		log.Error(logActionUsers, logInfoUser, 1083, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while retrieving the user. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+
+	user := types.User{Username: request.Username, Role: request.Role}
+	if err := auth.SetPassword(&auth.Pbkdf2Caller{}, &user, request.Password); err != nil {
+		log.Error(logActionUsers, logInfoUser, 1083, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "Failed to hash the password of the user. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+	if err := apiContext.APIConfiguration.DBInstance.InsertDBUser(user); err != nil {
+		log.Error(logActionUsers, logInfoUser, 1083, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "Failed to store the user. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+
+	log.Info(logActionUsers, logInfoUser, 74, user.Username, user.Role)
+	return c.JSON(http.StatusCreated, userInfo(user))
+}
+
+// UpdateUserAccount changes the role of a user or disables it. Admins can
+// not demote or disable themselves, so that an instance always keeps an
+// admin.
+// @Summary Change the role of a user or disable it
+// @Tags admin
+// @Security basicAuth
+// @Param username path string true "Username"
+// @Body UserChange
+// @Success 200 UserInfo
+// @Failure 400 Invalid role
+// @Failure 401 Invalid credentials
+// @Failure 403 Not an admin, or an admin changing their own account
+// @Failure 404 User not found
+// @Failure 500 Internal error
+// @Router PUT /api/v2/admin/users/:username
+func UpdateUserAccount(c echo.Context) error {
+	change := UserChange{}
+	if err := c.Bind(&change); err != nil {
+		log.Error(logActionUsers, logInfoUser, 1082, err)
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid user JSON", "The request body must be a JSON with 'role' or 'disabled' fields.")
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+	if change.Role != nil && !auth.IsValidRole(*change.Role) {
+		return invalidRole(c)
+	}
+	user, err := findUser(c)
+	if err != nil || c.Response().Committed {
+		return err
+	}
+	demoted := change.Role != nil && *change.Role != auth.RoleAdmin
+	disabled := change.Disabled != nil && *change.Disabled
+	if (demoted || disabled) && isCurrentUser(c, user) {
+		return ownAccount(c)
+	}
+
+	if change.Role != nil {
+		user.Role = *change.Role
+	}
+	if change.Disabled != nil {
+		user.Disabled = *change.Disabled
+	}
+	if err := storeUser(c, user); err != nil || c.Response().Committed {
+		return err
+	}
+	log.Info(logActionUsers, logInfoUser, 75, user.Username, auth.RoleOf(user), user.Disabled)
+	return c.JSON(http.StatusOK, userInfo(user))
+}
+
+// SetUserPassword sets the password of a user, without asking for the one it
+// had, such as when it was forgotten.
+// @Summary Set the password of a user
+// @Tags admin
+// @Security basicAuth
+// @Param username path string true "Username"
+// @Body PasswordRequest
+// @Success 200 UserInfo
+// @Failure 400 Weak password
+// @Failure 401 Invalid credentials
+// @Failure 403 Not an admin
+// @Failure 404 User not found
+// @Failure 500 Internal error
+// @Router PUT /api/v2/admin/users/:username/password
+func SetUserPassword(c echo.Context) error {
+	request := PasswordRequest{}
+	if err := c.Bind(&request); err != nil {
+		log.Error(logActionUsers, logInfoUser, 1082, err)
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid password JSON", "The request body must be a JSON with a 'password' field.")
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+	if err := auth.CheckPasswordComplexity(c.Param("username"), request.Password); err != nil {
+		return weakPassword(c, err)
+	}
+	user, err := findUser(c)
+	if err != nil || c.Response().Committed {
+		return err
+	}
+
+	if err := auth.SetPassword(&auth.Pbkdf2Caller{}, &user, request.Password); err != nil {
+		log.Error(logActionUsers, logInfoUser, 1083, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "Failed to hash the password of the user. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+	if err := storeUser(c, user); err != nil || c.Response().Committed {
+		return err
+	}
+	log.Info(logActionUsers, logInfoUser, 76, user.Username)
+	return c.JSON(http.StatusOK, userInfo(user))
+}
+
+// DeleteUser removes a user. Admins can not remove themselves.
+// @Summary Remove a user
+// @Tags admin
+// @Security basicAuth
+// @Param username path string true "Username"
+// @Success 204
+// @Failure 401 Invalid credentials
+// @Failure 403 Not an admin, or an admin removing their own account
+// @Failure 404 User not found
+// @Failure 500 Internal error
+// @Router DELETE /api/v2/admin/users/:username
+func DeleteUser(c echo.Context) error {
+	username := c.Param("username")
+	if current, _ := c.Get(auth.UsernameKey).(string); current == username {
+		return ownAccount(c)
+	}
+	userQuery := map[string]interface{}{"username": username}
+	if err := apiContext.APIConfiguration.DBInstance.DeleteOneDBUser(userQuery); err != nil {
+		if err == mongo.ErrNoDocuments || err.Error() == "No data found" {
+			return userNotFound(c, username)
+		}
+		log.Error(logActionUsers, logInfoUser, 1083, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "Failed to remove the user. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+
+	log.Info(logActionUsers, logInfoUser, 77, username)
+	return c.NoContent(http.StatusNoContent)
+}
+
+// findUser returns the user of the username path parameter, replying 404 if
+// there is none.
+func findUser(c echo.Context) (types.User, error) {
+	username := c.Param("username")
+	user, err := apiContext.APIConfiguration.DBInstance.FindOneDBUser(map[string]interface{}{"username": username})
+	if err != nil {
+		if err == mongo.ErrNoDocuments || err.Error() == "No data found" {
+			return user, userNotFound(c, username)
+		}
+		log.Error(logActionUsers, logInfoUser, 1083, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "An unexpected error occurred while retrieving the user. Please try again later.")
+		return user, c.JSON(http.StatusInternalServerError, reply)
+	}
+	return user, nil
+}
+
+// storeUser replaces the stored user of the same username with user.
+func storeUser(c echo.Context, user types.User) error {
+	userQuery := map[string]interface{}{"username": user.Username}
+	if err := apiContext.APIConfiguration.DBInstance.UpdateOneDBUser(userQuery, user); err != nil {
+		log.Error(logActionUsers, logInfoUser, 1083, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "Failed to update the user. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+	return nil
+}
+
+// isCurrentUser returns whether user is the one that authenticated the request.
+func isCurrentUser(c echo.Context, user types.User) bool {
+	current, _ := c.Get(auth.UsernameKey).(string)
+	return current == user.Username
+}
+
+func userInfo(user types.User) UserInfo {
+	return UserInfo{Username: user.Username, Role: auth.RoleOf(user), Disabled: user.Disabled}
+}
+
+func invalidUsername(c echo.Context) error {
+	reply := apierror.Reply(c, errcode.InvalidRequest, "invalid username", "Usernames are made of up to 64 letters, digits, dots, hyphens, underscores and @, such as jane.doe.")
+	return c.JSON(http.StatusBadRequest, reply)
+}
+
+func invalidRole(c echo.Context) error {
+	reply := apierror.Reply(c, errcode.InvalidRequest, "invalid role", "The role of a user must be admin or operator.")
+	return c.JSON(http.StatusBadRequest, reply)
+}
+
+func weakPassword(c echo.Context, err error) error {
+	reply := apierror.Reply(c, errcode.InvalidRequest, "weak password", fmt.Sprintf("The password is too weak: %s.", err))
+	return c.JSON(http.StatusBadRequest, reply)
+}
+
+func ownAccount(c echo.Context) error {
+	reply := apierror.Reply(c, errcode.Forbidden, "own account", "Admins can not demote, disable or remove their own account. Ask another admin.")
+	return c.JSON(http.StatusForbidden, reply)
+}
+
+func userNotFound(c echo.Context, username string) error {
+	reply := apierror.Reply(c, errcode.NotFound, "user not found", fmt.Sprintf("No user found named: %s", username))
+	return c.JSON(http.StatusNotFound, reply)
+}
@@ -0,0 +1,76 @@
+package routes_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/huskyci-org/huskyCI/api/auth"
+	"github.com/huskyci-org/huskyCI/api/routes"
+	"github.com/labstack/echo/v4"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Users", func() {
+
+	serve := func(handler echo.HandlerFunc, method, username, body string) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(method, "/api/v2/admin/users", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set(auth.UsernameKey, "admin")
+		c.Set(auth.RoleKey, auth.RoleAdmin)
+		if username != "" {
+			c.SetParamNames("username")
+			c.SetParamValues(username)
+		}
+		Expect(handler(c)).To(Succeed())
+		return rec
+	}
+
+	Context("When a user is created with an invalid username", func() {
+		It("Should return 400", func() {
+			rec := serve(routes.CreateUser, http.MethodPost, "", `{"username": "jane doe", "password": "correct-Horse-battery"}`)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(ContainSubstring("invalid username"))
+		})
+	})
+	Context("When a user is created with an unknown role", func() {
+		It("Should return 400", func() {
+			rec := serve(routes.CreateUser, http.MethodPost, "", `{"username": "jane", "password": "correct-Horse-battery", "role": "root"}`)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(ContainSubstring("invalid role"))
+		})
+	})
+	Context("When a user is created with a weak password", func() {
+		It("Should return 400", func() {
+			rec := serve(routes.CreateUser, http.MethodPost, "", `{"username": "jane", "password": "password"}`)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(ContainSubstring("weak password"))
+		})
+	})
+	Context("When the password of a user is set to a weak one", func() {
+		It("Should return 400", func() {
+			rec := serve(routes.SetUserPassword, http.MethodPut, "jane", `{"password": "jane-Password-1"}`)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(ContainSubstring("username"))
+		})
+	})
+	Context("When a user is given an unknown role", func() {
+		It("Should return 400", func() {
+			rec := serve(routes.UpdateUserAccount, http.MethodPut, "jane", `{"role": "root"}`)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(ContainSubstring("invalid role"))
+		})
+	})
+	Context("When an admin removes their own account", func() {
+		It("Should return 403", func() {
+			rec := serve(routes.DeleteUser, http.MethodDelete, "admin", "")
+			Expect(rec.Code).To(Equal(http.StatusForbidden))
+			Expect(rec.Body.String()).To(ContainSubstring("own account"))
+		})
+	})
+})
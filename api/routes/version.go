@@ -7,17 +7,62 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
+// BuildInfo describes the build of the API and the optional features it runs
+// with. MinClientVersion is the oldest huskyci-client it supports, if any.
+type BuildInfo struct {
+	Version          string   `json:"version"`
+	Date             string   `json:"date"`
+	Commit           string   `json:"commit,omitempty"`
+	BuildDate        string   `json:"buildDate,omitempty"`
+	Features         []string `json:"features"`
+	MinClientVersion string   `json:"minClientVersion,omitempty"`
+}
+
 // GetAPIVersion returns the API version
+// @Summary Get the API version, build and enabled features
+// @Tags health
+// @Success 200 BuildInfo
+// @Router GET /version
 func GetAPIVersion(c echo.Context) error {
 	configAPI := apiContext.APIConfiguration
 	return c.JSON(http.StatusOK, GetRequestResult(configAPI))
 }
 
-// GetRequestResult returns a map containing API's version and release date
-func GetRequestResult(configAPI *apiContext.APIConfig) map[string]string {
-	requestResult := map[string]string{
-		"version": configAPI.Version,
-		"date":    configAPI.ReleaseDate,
+// GetRequestResult returns the version, release date and build of the API.
+func GetRequestResult(configAPI *apiContext.APIConfig) BuildInfo {
+	return BuildInfo{
+		Version:          configAPI.Version,
+		Date:             configAPI.ReleaseDate,
+		Commit:           configAPI.Commit,
+		BuildDate:        configAPI.BuildDate,
+		Features:         enabledFeatures(configAPI),
+		MinClientVersion: configAPI.MinClientVersion,
+	}
+}
+
+// enabledFeatures returns the optional features configAPI turns on.
+func enabledFeatures(configAPI *apiContext.APIConfig) []string {
+	features := []string{}
+	if configAPI.Storage != nil {
+		features = append(features, "storage")
+	}
+	if configAPI.UploadConfig != nil && (configAPI.UploadConfig.ClamdAddress != "" || configAPI.UploadConfig.ScanCommand != "") {
+		features = append(features, "uploadscan")
+	}
+	if configAPI.SignatureConfig != nil && configAPI.SignatureConfig.Enforce {
+		features = append(features, "signature")
+	}
+	if configAPI.OfflineConfig != nil && configAPI.OfflineConfig.Enabled {
+		features = append(features, "offline")
+	}
+	if configAPI.TracingConfig != nil && configAPI.TracingConfig.Endpoint != "" {
+		features = append(features, "tracing")
+	}
+	if configAPI.StatusCache != nil {
+		features = append(features, "statuscache")
+	}
+	if configAPI.Exporter != nil {
+		features = append(features, "export")
 	}
-	return requestResult
+	return features
 }
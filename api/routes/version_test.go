@@ -3,6 +3,7 @@ package routes_test
 import (
 	apiContext "github.com/huskyci-org/huskyCI/api/context"
 	"github.com/huskyci-org/huskyCI/api/routes"
+	"github.com/huskyci-org/huskyCI/api/signature"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -10,17 +11,22 @@ import (
 
 var _ = Describe("getRequestResult", func() {
 
-	expected := map[string]string{
-		"version": apiContext.DefaultConf.GetAPIVersion(),
-		"date":    apiContext.DefaultConf.GetAPIReleaseDate(),
-	}
-
 	apiContext.DefaultConf.SetOnceConfig()
 	config := apiContext.APIConfiguration
 
 	Context("When version and date are requested", func() {
-		It("Should return a map with API version and date", func() {
-			Expect(routes.GetRequestResult(config)).To(Equal(expected))
+		It("Should return the API version and date", func() {
+			result := routes.GetRequestResult(config)
+			Expect(result.Version).To(Equal(apiContext.DefaultConf.GetAPIVersion()))
+			Expect(result.Date).To(Equal(apiContext.DefaultConf.GetAPIReleaseDate()))
+		})
+	})
+
+	Context("When signatures are enforced", func() {
+		It("Should list the signature feature", func() {
+			enforced := *config
+			enforced.SignatureConfig = &signature.Config{Enforce: true}
+			Expect(routes.GetRequestResult(&enforced).Features).To(ContainElement("signature"))
 		})
 	})
 
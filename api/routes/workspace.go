@@ -0,0 +1,97 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+
+	"github.com/google/uuid"
+	"github.com/huskyci-org/huskyCI/api/apierror"
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/debugtrace"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/storage"
+	"github.com/huskyci-org/huskyCI/api/util"
+	"github.com/huskyci-org/huskyCI/pkg/errcode"
+	"github.com/labstack/echo/v4"
+)
+
+const logActionWorkspace = "Workspace"
+
+// workspaceIDRegexp matches the IDs of the workspaces, as the uploadID of
+// the upload analyses.
+var workspaceIDRegexp = regexp.MustCompile(`^[-a-zA-Z0-9]+$`)
+
+// CreateWorkspace stores an uploaded zip under a new workspace ID, which
+// upload analyses then reference as their uploadID. The zip is pushed once
+// and stays until it is removed with DeleteWorkspace or by the janitor.
+// @Summary Upload the zipped code of a workspace
+// @Tags workspace
+// @Security huskyToken
+// @FormFile zipfile "Zip file with the repository code"
+// @Success 201 WorkspaceCreated{success:bool, error:string, message:string, workspaceID:string}
+// @Failure 400 Missing or invalid zip file
+// @Failure 422 Zip is too large, is not a zip archive or was rejected by the antivirus
+// @Failure 500 Zip could not be stored
+// @Failure 503 Zip could not be scanned
+// @Router POST /api/v2/workspace
+func CreateWorkspace(c echo.Context) error {
+	workspaceID := uuid.New().String()
+	if stored, err := storeZip(c, workspaceID); !stored {
+		return err
+	}
+
+	reply := map[string]interface{}{
+		"success":     true,
+		"error":       "",
+		"message":     fmt.Sprintf("Workspace created: %s. Start upload analyses with it as their uploadID.", workspaceID),
+		"workspaceID": workspaceID,
+	}
+	return c.JSON(http.StatusCreated, reply)
+}
+
+// DeleteWorkspace removes the zip of a workspace, the directory it was
+// extracted to and its copy in the object storage. Docker analyses already
+// started keep the volume their code was extracted to.
+// @Summary Remove a workspace
+// @Tags workspace
+// @Security huskyToken
+// @Param id path string true "Workspace ID"
+// @Success 204
+// @Failure 400 Invalid workspace ID
+// @Failure 404 Workspace not found
+// @Failure 500 Internal error
+// @Router DELETE /api/v2/workspace/:id
+func DeleteWorkspace(c echo.Context) error {
+	workspaceID := c.Param("id")
+	if !workspaceIDRegexp.MatchString(workspaceID) {
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid workspace ID", "The workspace ID must only contain letters, numbers and hyphens.")
+		return c.JSON(http.StatusBadRequest, reply)
+	}
+
+	_, zipErr := os.Stat(util.GetZipFilePath(workspaceID))
+	_, dirErr := os.Stat(util.GetExtractedDir(workspaceID))
+	objectStorage := apiContext.APIConfiguration.Storage
+	if os.IsNotExist(zipErr) && os.IsNotExist(dirErr) && objectStorage == nil {
+		reply := apierror.Reply(c, errcode.NotFound, "workspace not found", fmt.Sprintf("No workspace found with ID: %s", workspaceID))
+		return c.JSON(http.StatusNotFound, reply)
+	}
+
+	if err := util.CleanupZip(workspaceID); err != nil {
+		log.Error(logActionWorkspace, logInfoAnalysis, 1088, workspaceID, err)
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "Failed to remove the workspace. Please try again later.")
+		return c.JSON(http.StatusInternalServerError, reply)
+	}
+	if objectStorage != nil {
+		if err := objectStorage.Delete(storage.ZipKey(workspaceID)); err != nil {
+			log.Error(logActionWorkspace, logInfoAnalysis, 1088, workspaceID, err)
+			reply := apierror.Reply(c, errcode.Internal, "internal server error", "Failed to remove the workspace from the object storage. Please try again later.")
+			return c.JSON(http.StatusInternalServerError, reply)
+		}
+	}
+
+	log.Info(logActionWorkspace, logInfoAnalysis, 85, workspaceID)
+	debugtrace.Record(debugtrace.Lifecycle, "upload", "removed", workspaceID)
+	return c.NoContent(http.StatusNoContent)
+}
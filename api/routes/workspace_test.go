@@ -0,0 +1,97 @@
+package routes_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/routes"
+	"github.com/huskyci-org/huskyCI/api/upload"
+	"github.com/huskyci-org/huskyCI/api/util"
+	"github.com/labstack/echo/v4"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Workspace", func() {
+
+	emptyZip := []byte("PK\x05\x06" + string(make([]byte, 18)))
+
+	apiContext.DefaultConf.SetOnceConfig()
+	var previous *upload.Config
+
+	BeforeEach(func() {
+		previous = apiContext.APIConfiguration.UploadConfig
+		apiContext.APIConfiguration.UploadConfig = &upload.Config{MaxSize: upload.DefaultMaxSize}
+	})
+	AfterEach(func() {
+		apiContext.APIConfiguration.UploadConfig = previous
+	})
+
+	createWorkspace := func(content []byte) *httptest.ResponseRecorder {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, err := writer.CreateFormFile("zipfile", "code.zip")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = part.Write(content)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(writer.Close()).To(Succeed())
+
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/workspace", body)
+		req.Header.Set(echo.HeaderContentType, writer.FormDataContentType())
+		rec := httptest.NewRecorder()
+		Expect(routes.CreateWorkspace(e.NewContext(req, rec))).To(Succeed())
+		return rec
+	}
+
+	deleteWorkspace := func(workspaceID string) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodDelete, "/workspace/"+workspaceID, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues(workspaceID)
+		Expect(routes.DeleteWorkspace(c)).To(Succeed())
+		return rec
+	}
+
+	Context("When a zip archive is uploaded", func() {
+		It("Should store it under a new workspace ID until it is deleted", func() {
+			rec := createWorkspace(emptyZip)
+			Expect(rec.Code).To(Equal(http.StatusCreated))
+			reply := map[string]interface{}{}
+			Expect(json.Unmarshal(rec.Body.Bytes(), &reply)).To(Succeed())
+			workspaceID, _ := reply["workspaceID"].(string)
+			Expect(workspaceID).NotTo(BeEmpty())
+			Expect(util.GetZipFilePath(workspaceID)).To(BeAnExistingFile())
+
+			Expect(deleteWorkspace(workspaceID).Code).To(Equal(http.StatusNoContent))
+			_, err := os.Stat(util.GetZipFilePath(workspaceID))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
+
+	Context("When the upload is not a zip archive", func() {
+		It("Should reject it", func() {
+			Expect(createWorkspace([]byte("not a zip")).Code).To(Equal(http.StatusUnprocessableEntity))
+		})
+	})
+
+	Context("When the workspace does not exist", func() {
+		It("Should return 404", func() {
+			Expect(deleteWorkspace("00000000-0000-0000-0000-000000000000").Code).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Context("When the workspace ID is invalid", func() {
+		It("Should return 400", func() {
+			Expect(deleteWorkspace("..").Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+})
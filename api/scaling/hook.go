@@ -0,0 +1,131 @@
+package scaling
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	autoscaling "k8s.io/api/autoscaling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kube "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Hook asks for more runner hosts once the backlog exceeds its threshold.
+type Hook interface {
+	Scale(hints Hints) error
+}
+
+// NewHook returns the Hook configured in configAPI: a Webhook, a Kubernetes
+// workload or none of them.
+func NewHook(configAPI *apiContext.APIConfig) (Hook, error) {
+	config := configAPI.ScalingConfig
+	switch {
+	case config == nil:
+		return nil, nil
+	case config.WebhookURL != "":
+		return &Webhook{URL: config.WebhookURL, Client: &http.Client{Timeout: 30 * time.Second}}, nil
+	case config.KubernetesTarget != "":
+		kind, name, err := parseTarget(config.KubernetesTarget)
+		if err != nil {
+			return nil, err
+		}
+		restConfig, err := clientcmd.BuildConfigFromFlags("", configAPI.KubernetesConfig.ConfigFilePath)
+		if err != nil {
+			return nil, err
+		}
+		clientset, err := kube.NewForConfig(restConfig)
+		if err != nil {
+			return nil, err
+		}
+		namespace := config.KubernetesNamespace
+		if namespace == "" {
+			namespace = configAPI.KubernetesConfig.Namespace
+		}
+		return &Kubernetes{Namespace: namespace, Kind: kind, Name: name, Client: clientset}, nil
+	}
+	return nil, nil
+}
+
+// parseTarget splits a target such as deployment/runner into its kind and
+// name.
+func parseTarget(target string) (string, string, error) {
+	kind, name, found := strings.Cut(target, "/")
+	kind = strings.ToLower(kind)
+	if !found || name == "" || (kind != "deployment" && kind != "statefulset") {
+		return "", "", fmt.Errorf("invalid scaling target %q: expected deployment/<name> or statefulset/<name>", target)
+	}
+	return kind, name, nil
+}
+
+// Webhook posts the Hints as JSON to URL.
+type Webhook struct {
+	URL    string
+	Client *http.Client
+}
+
+// Scale posts hints to the webhook.
+func (w *Webhook) Scale(hints Hints) error {
+	body, err := json.Marshal(hints)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("scaling webhook answered %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Kubernetes sets the replicas of the Deployment or StatefulSet running the
+// runner hosts through its scale subresource.
+type Kubernetes struct {
+	Namespace string
+	Kind      string
+	Name      string
+	Client    kube.Interface
+}
+
+// Scale sets the replicas of the workload to hints.DesiredHosts. It never
+// scales the workload down, which is left to its operators.
+func (k *Kubernetes) Scale(hints Hints) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	apps := k.Client.AppsV1()
+
+	var scale *autoscaling.Scale
+	var err error
+	if k.Kind == "statefulset" {
+		scale, err = apps.StatefulSets(k.Namespace).GetScale(ctx, k.Name, metav1.GetOptions{})
+	} else {
+		scale, err = apps.Deployments(k.Namespace).GetScale(ctx, k.Name, metav1.GetOptions{})
+	}
+	if err != nil {
+		return err
+	}
+	if scale.Spec.Replicas >= int32(hints.DesiredHosts) {
+		return nil
+	}
+
+	scale.Spec.Replicas = int32(hints.DesiredHosts)
+	if k.Kind == "statefulset" {
+		_, err = apps.StatefulSets(k.Namespace).UpdateScale(ctx, k.Name, scale, metav1.UpdateOptions{})
+	} else {
+		_, err = apps.Deployments(k.Namespace).UpdateScale(ctx, k.Name, scale, metav1.UpdateOptions{})
+	}
+	return err
+}
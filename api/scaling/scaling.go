@@ -0,0 +1,144 @@
+// Package scaling tells how loaded the runner hosts are, so that they can be
+// scaled automatically: the analyses running beyond what the hosts can run
+// at once and how long they should wait. Once this backlog grows, a hook asks
+// for more hosts, through a webhook or the scale subresource of a Kubernetes
+// workload.
+package scaling
+
+import (
+	"math"
+	"os"
+	"time"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/metrics"
+	"github.com/huskyci-org/huskyCI/api/types"
+)
+
+const logActionScaling = "Scaling"
+const logInfoScaling = "SCALING"
+
+// lockName is the lock held for a cooldown by the replica that called the
+// hook, so that the hosts are scaled once whatever the number of replicas.
+const lockName = "scaling"
+
+// recentAnalyses is how many of the last finished analyses the duration of
+// an analysis is estimated from.
+const recentAnalyses = 20
+
+// Hints tells how loaded the runner hosts are. Capacity is how many analyses
+// the Hosts run at once and Backlog how many run beyond it, which should take
+// EstimatedWaitSeconds to catch up with. DesiredHosts would run every
+// analysis at once.
+type Hints struct {
+	Running                int64     `json:"running"`
+	Hosts                  int       `json:"hosts"`
+	Capacity               int       `json:"capacity"`
+	Backlog                int64     `json:"backlog"`
+	AverageDurationSeconds float64   `json:"averageDurationSeconds"`
+	EstimatedWaitSeconds   float64   `json:"estimatedWaitSeconds"`
+	DesiredHosts           int       `json:"desiredHosts"`
+	Time                   time.Time `json:"time"`
+}
+
+// Estimate returns the Hints of running analyses on the hosts of config,
+// their duration estimated from the recent finished analyses.
+func Estimate(config *apiContext.ScalingConfig, running int64, recent []types.AnalysisSummary) Hints {
+	hints := Hints{
+		Running:  running,
+		Hosts:    config.Hosts,
+		Capacity: config.Hosts * config.AnalysesPerHost,
+		Time:     time.Now(),
+	}
+	if backlog := running - int64(hints.Capacity); backlog > 0 {
+		hints.Backlog = backlog
+	}
+
+	total, finished := 0.0, 0
+	for _, analysis := range recent {
+		if analysis.StartedAt.IsZero() || analysis.FinishedAt.Before(analysis.StartedAt) {
+			continue
+		}
+		total += analysis.FinishedAt.Sub(analysis.StartedAt).Seconds()
+		finished++
+	}
+	if finished > 0 {
+		hints.AverageDurationSeconds = total / float64(finished)
+	}
+	if hints.Capacity > 0 {
+		hints.EstimatedWaitSeconds = math.Ceil(float64(hints.Backlog)/float64(hints.Capacity)) * hints.AverageDurationSeconds
+	}
+
+	hints.DesiredHosts = int(math.Ceil(float64(running) / float64(config.AnalysesPerHost)))
+	if hints.DesiredHosts < config.MinHosts {
+		hints.DesiredHosts = config.MinHosts
+	}
+	if config.MaxHosts > 0 && hints.DesiredHosts > config.MaxHosts {
+		hints.DesiredHosts = config.MaxHosts
+	}
+	return hints
+}
+
+// Read returns the Hints of the analyses running now.
+func Read(config *apiContext.ScalingConfig) (Hints, error) {
+	database := apiContext.APIConfiguration.DBInstance
+	_, running, err := database.FindPageDBAnalysis(types.AnalysisFilter{Status: "running", SortField: "startedAt", Page: 1, PageSize: 1})
+	if err != nil {
+		return Hints{}, err
+	}
+	recent, _, err := database.FindPageDBAnalysis(types.AnalysisFilter{Status: "finished", SortField: "finishedAt", SortDescending: true, Page: 1, PageSize: recentAnalyses})
+	if err != nil {
+		return Hints{}, err
+	}
+	return Estimate(config, running, recent), nil
+}
+
+// publish sets the gauges of hints, scraped at /metrics.
+func publish(hints Hints) {
+	metrics.DefaultRegistry.SetGauge("huskyci_analyses_running", "Analyses running.", float64(hints.Running))
+	metrics.DefaultRegistry.SetGauge("huskyci_analyses_capacity", "Analyses the runner hosts run at once.", float64(hints.Capacity))
+	metrics.DefaultRegistry.SetGauge("huskyci_analyses_backlog", "Analyses running beyond the capacity of the runner hosts.", float64(hints.Backlog))
+	metrics.DefaultRegistry.SetGauge("huskyci_analyses_estimated_wait_seconds", "Time the runner hosts should take to catch up with the backlog.", hints.EstimatedWaitSeconds)
+	metrics.DefaultRegistry.SetGauge("huskyci_runner_hosts", "Runner hosts.", float64(hints.Hosts))
+	metrics.DefaultRegistry.SetGauge("huskyci_runner_hosts_desired", "Runner hosts that would run every analysis at once.", float64(hints.DesiredHosts))
+}
+
+// Check reads the Hints, publishes them as metrics and calls hook, if not
+// nil, once the backlog exceeds the threshold of config. The hook is called
+// at most once per cooldown across all replicas.
+func Check(config *apiContext.ScalingConfig, hook Hook) (Hints, error) {
+	hints, err := Read(config)
+	if err != nil {
+		log.Error(logActionScaling, logInfoScaling, 1073, err)
+		return hints, err
+	}
+	publish(hints)
+	if hook == nil || hints.Backlog <= int64(config.BacklogThreshold) {
+		return hints, nil
+	}
+
+	hostname, _ := os.Hostname()
+	acquired, err := apiContext.APIConfiguration.DBInstance.AcquireLock(lockName, hostname, config.Cooldown)
+	if err != nil || !acquired {
+		return hints, err
+	}
+	if err := hook.Scale(hints); err != nil {
+		log.Error(logActionScaling, logInfoScaling, 1072, err)
+		// the next check tries again
+		apiContext.APIConfiguration.DBInstance.ReleaseLock(lockName, hostname)
+		return hints, err
+	}
+	log.Info(logActionScaling, logInfoScaling, 61, hints.DesiredHosts)
+	return hints, nil
+}
+
+// Schedule calls Check every config.Interval. It never returns and is meant
+// to run in its own goroutine.
+func Schedule(config *apiContext.ScalingConfig, hook Hook) {
+	ticker := time.NewTicker(config.Interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		Check(config, hook)
+	}
+}
@@ -0,0 +1,13 @@
+package scaling_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestScaling(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Scaling Suite")
+}
@@ -0,0 +1,230 @@
+package scaling_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/db"
+	"github.com/huskyci-org/huskyCI/api/scaling"
+	"github.com/huskyci-org/huskyCI/api/types"
+	autoscaling "k8s.io/api/autoscaling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// loadDB is a database with running analyses and a lock.
+type loadDB struct {
+	db.Requests
+	running int64
+	locked  bool
+}
+
+func (l *loadDB) FindPageDBAnalysis(filter types.AnalysisFilter) ([]types.AnalysisSummary, int64, error) {
+	if filter.Status == "running" {
+		return nil, l.running, nil
+	}
+	return []types.AnalysisSummary{}, 0, nil
+}
+
+func (l *loadDB) AcquireLock(name, owner string, ttl time.Duration) (bool, error) {
+	if l.locked {
+		return false, nil
+	}
+	l.locked = true
+	return true, nil
+}
+
+func (l *loadDB) ReleaseLock(name, owner string) error {
+	l.locked = false
+	return nil
+}
+
+// hookFunc is a Hook calling itself.
+type hookFunc func(hints scaling.Hints) error
+
+func (h hookFunc) Scale(hints scaling.Hints) error {
+	return h(hints)
+}
+
+var _ = Describe("Scaling", func() {
+
+	config := &apiContext.ScalingConfig{Hosts: 2, AnalysesPerHost: 3, BacklogThreshold: 1, MinHosts: 1, MaxHosts: 5, Cooldown: time.Minute}
+
+	Describe("Estimate", func() {
+		started := time.Now().Add(-time.Hour)
+		recent := []types.AnalysisSummary{
+			{StartedAt: started, FinishedAt: started.Add(2 * time.Minute)},
+			{StartedAt: started, FinishedAt: started.Add(4 * time.Minute)},
+			{FinishedAt: started},
+		}
+
+		Context("When the hosts run every analysis", func() {
+			It("Should have no backlog", func() {
+				hints := scaling.Estimate(config, 5, recent)
+				Expect(hints.Capacity).To(Equal(6))
+				Expect(hints.Backlog).To(BeZero())
+				Expect(hints.EstimatedWaitSeconds).To(BeZero())
+				Expect(hints.AverageDurationSeconds).To(Equal(180.0))
+				Expect(hints.DesiredHosts).To(Equal(2))
+			})
+		})
+		Context("When analyses run beyond the capacity", func() {
+			It("Should estimate the wait and the hosts needed", func() {
+				hints := scaling.Estimate(config, 13, recent)
+				Expect(hints.Backlog).To(Equal(int64(7)))
+				Expect(hints.EstimatedWaitSeconds).To(Equal(360.0))
+				Expect(hints.DesiredHosts).To(Equal(5))
+			})
+		})
+		Context("When more hosts than the maximum are needed", func() {
+			It("Should ask for the maximum", func() {
+				Expect(scaling.Estimate(config, 100, recent).DesiredHosts).To(Equal(5))
+			})
+		})
+	})
+
+	Describe("Check", func() {
+		var database *loadDB
+		var previousConfig *apiContext.APIConfig
+		var calls []scaling.Hints
+		var hook scaling.Hook
+
+		BeforeEach(func() {
+			database = &loadDB{}
+			previousConfig = apiContext.APIConfiguration
+			apiContext.APIConfiguration = &apiContext.APIConfig{DBInstance: database}
+			calls = nil
+			hook = hookFunc(func(hints scaling.Hints) error {
+				calls = append(calls, hints)
+				return nil
+			})
+		})
+
+		AfterEach(func() {
+			apiContext.APIConfiguration = previousConfig
+		})
+
+		Context("When the backlog is under the threshold", func() {
+			It("Should not call the hook", func() {
+				database.running = 7
+				hints, err := scaling.Check(config, hook)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(hints.Backlog).To(Equal(int64(1)))
+				Expect(calls).To(BeEmpty())
+			})
+		})
+		Context("When the backlog exceeds the threshold", func() {
+			It("Should call the hook once per cooldown", func() {
+				database.running = 10
+				_, err := scaling.Check(config, hook)
+				Expect(err).NotTo(HaveOccurred())
+				_, err = scaling.Check(config, hook)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(calls).To(HaveLen(1))
+				Expect(calls[0].DesiredHosts).To(Equal(4))
+			})
+		})
+		Context("When the hook fails", func() {
+			It("Should call it again on the next check", func() {
+				database.running = 10
+				_, err := scaling.Check(config, hookFunc(func(hints scaling.Hints) error {
+					return errors.New("unreachable")
+				}))
+				Expect(err).To(MatchError("unreachable"))
+				Expect(database.locked).To(BeFalse())
+			})
+		})
+	})
+
+	Describe("Webhook", func() {
+		Context("When the webhook accepts the hints", func() {
+			It("Should post them as JSON", func() {
+				var received scaling.Hints
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					Expect(r.Method).To(Equal(http.MethodPost))
+					Expect(json.NewDecoder(r.Body).Decode(&received)).To(Succeed())
+				}))
+				defer server.Close()
+
+				webhook := &scaling.Webhook{URL: server.URL, Client: server.Client()}
+				Expect(webhook.Scale(scaling.Hints{Backlog: 3, DesiredHosts: 4})).To(Succeed())
+				Expect(received.Backlog).To(Equal(int64(3)))
+				Expect(received.DesiredHosts).To(Equal(4))
+			})
+		})
+		Context("When the webhook fails", func() {
+			It("Should return an error", func() {
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusBadGateway)
+				}))
+				defer server.Close()
+
+				webhook := &scaling.Webhook{URL: server.URL, Client: server.Client()}
+				Expect(webhook.Scale(scaling.Hints{})).To(MatchError("scaling webhook answered 502"))
+			})
+		})
+	})
+
+	Describe("Kubernetes", func() {
+		var client *fake.Clientset
+		var updated *autoscaling.Scale
+
+		BeforeEach(func() {
+			updated = nil
+			client = fake.NewSimpleClientset()
+			client.PrependReactor("get", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if action.GetSubresource() != "scale" {
+					return false, nil, nil
+				}
+				scale := &autoscaling.Scale{ObjectMeta: metav1.ObjectMeta{Name: "runner", Namespace: "huskyci"}}
+				scale.Spec.Replicas = 2
+				return true, scale, nil
+			})
+			client.PrependReactor("update", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				updated = action.(k8stesting.UpdateAction).GetObject().(*autoscaling.Scale)
+				return true, updated, nil
+			})
+		})
+
+		Context("When more hosts are desired", func() {
+			It("Should scale the workload up", func() {
+				kubernetes := &scaling.Kubernetes{Namespace: "huskyci", Kind: "deployment", Name: "runner", Client: client}
+				Expect(kubernetes.Scale(scaling.Hints{DesiredHosts: 4})).To(Succeed())
+				Expect(updated).NotTo(BeNil())
+				Expect(updated.Spec.Replicas).To(Equal(int32(4)))
+			})
+		})
+		Context("When fewer hosts are desired", func() {
+			It("Should not scale the workload down", func() {
+				kubernetes := &scaling.Kubernetes{Namespace: "huskyci", Kind: "deployment", Name: "runner", Client: client}
+				Expect(kubernetes.Scale(scaling.Hints{DesiredHosts: 1})).To(Succeed())
+				Expect(updated).To(BeNil())
+			})
+		})
+	})
+
+	Describe("NewHook", func() {
+		Context("When no hook is configured", func() {
+			It("Should return none", func() {
+				hook, err := scaling.NewHook(&apiContext.APIConfig{ScalingConfig: &apiContext.ScalingConfig{}})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(hook).To(BeNil())
+			})
+		})
+		Context("When the Kubernetes target is invalid", func() {
+			It("Should return an error", func() {
+				_, err := scaling.NewHook(&apiContext.APIConfig{ScalingConfig: &apiContext.ScalingConfig{KubernetesTarget: "pod/runner"}})
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+})
@@ -9,6 +9,7 @@ import (
 	"github.com/huskyci-org/huskyCI/api/log"
 	"github.com/huskyci-org/huskyCI/api/types"
 	"github.com/huskyci-org/huskyCI/api/util"
+	shared "github.com/huskyci-org/huskyCI/pkg/securitytest"
 )
 
 // EnryOutput is the struct that holds all data from Gosec output.
@@ -18,7 +19,8 @@ type EnryOutput struct {
 
 func analyzeEnry(enryScan *SecTestScanInfo) error {
 	// Unmarshall rawOutput into finalOutput, that is a EnryOutput struct.
-	if err := json.Unmarshal([]byte(enryScan.Container.COutput), &enryScan.FinalOutput); err != nil {
+	document, _ := shared.ExtractJSON(enryScan.Container.COutput)
+	if err := json.Unmarshal([]byte(document), &enryScan.FinalOutput); err != nil {
 		log.Error("analyzeEnry", "ENRY", 1003, enryScan.Container.COutput, err)
 		enryScan.ErrorFound = util.HandleScanError(enryScan.Container.COutput, err)
 		return enryScan.ErrorFound
@@ -42,7 +44,8 @@ func (enryScan *SecTestScanInfo) prepareEnryOutput() error {
 	}
 	log.Info("prepareEnryOutput", "ENRY", 16, fmt.Sprintf("Enry raw output (first 500 chars): %s", outputPreview))
 	
-	err := json.Unmarshal([]byte(enryScan.Container.COutput), &mapLanguages)
+	document, _ := shared.ExtractJSON(enryScan.Container.COutput)
+	err := json.Unmarshal([]byte(document), &mapLanguages)
 	if err != nil {
 		log.Error("prepareEnryOutput", "ENRY", 1003, enryScan.Container.COutput, err)
 		return err
@@ -81,7 +84,7 @@ func (enryScan *SecTestScanInfo) prepareEnryOutput() error {
 }
 
 // ParseProvidedEnryOutput parses Enry JSON output provided by CLI and populates enryScan.Codes
-// This is used for file:// URLs to avoid docker-in-docker issues
+// This is used for upload analyses to avoid docker-in-docker issues
 func (enryScan *SecTestScanInfo) ParseProvidedEnryOutput(enryOutputJSON string, languageExclusions map[string]bool) error {
 	repositoryLanguages := []types.Code{}
 	mapLanguages := make(map[string][]interface{})
@@ -0,0 +1,42 @@
+package securitytest
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/gitauth"
+)
+
+// EnryCacheExpiration is how long the output of Enry for a commit of a
+// repository is cached. The languages of a commit never change.
+var EnryCacheExpiration = 24 * time.Hour
+
+func enryCacheKey(URL, commit string) string {
+	return fmt.Sprintf("enry:%s@%s", gitauth.NormalizeURL(URL), strings.ToLower(commit))
+}
+
+// LoadCachedEnryOutput sets the Codes of enryScan from the output of Enry
+// cached for commit of its repository. It returns false if there is none.
+func (enryScan *SecTestScanInfo) LoadCachedEnryOutput(commit string) bool {
+	cache := apiContext.APIConfiguration.Cache
+	if cache == nil || commit == "" {
+		return false
+	}
+	output, found := cache.Get(enryCacheKey(enryScan.URL, commit))
+	if !found {
+		return false
+	}
+	return enryScan.ParseProvidedEnryOutput(output.(string), enryScan.LanguageExclusions) == nil
+}
+
+// CacheEnryOutput keeps the output of the Enry container of enryScan as the
+// one of commit of its repository.
+func (enryScan *SecTestScanInfo) CacheEnryOutput(commit string) {
+	cache := apiContext.APIConfiguration.Cache
+	if cache == nil || commit == "" || enryScan.Container.COutput == "" {
+		return
+	}
+	cache.Set(enryCacheKey(enryScan.URL, commit), enryScan.Container.COutput, EnryCacheExpiration)
+}
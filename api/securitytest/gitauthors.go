@@ -6,6 +6,7 @@ import (
 
 	"github.com/huskyci-org/huskyCI/api/log"
 	"github.com/huskyci-org/huskyCI/api/util"
+	shared "github.com/huskyci-org/huskyCI/pkg/securitytest"
 )
 
 // GitAuthorsOutput is the struct that holds all commit authors from a branch.
@@ -26,7 +27,8 @@ func analyzeGitAuthors(gitAuthorsScan *SecTestScanInfo) error {
 		gitAuthorsScan.prepareContainerAfterScan()
 		return nil
 	}
-	if err := json.Unmarshal([]byte(gitAuthorsScan.Container.COutput), &gitAuthorsOutput); err != nil {
+	document, _ := shared.ExtractJSON(output)
+	if err := json.Unmarshal([]byte(document), &gitAuthorsOutput); err != nil {
 		// For empty/invalid JSON, treat as no authors so analysis can complete (e.g. file:// has no git)
 		if strings.Contains(err.Error(), "unexpected end of JSON input") || strings.Contains(err.Error(), "EOF") {
 			log.Info("analyzeGitAuthors", "GITAUTHORS", 16, "GitAuthors output empty or invalid JSON, treating as no authors")
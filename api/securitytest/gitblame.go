@@ -0,0 +1,239 @@
+package securitytest
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/huskyci-org/huskyCI/api/fingerprint"
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/types"
+)
+
+const gitblame = "gitblame"
+
+// maxBlameLocations bounds the lines blamed by a single gitblame run.
+const maxBlameLocations = 1000
+
+// Blame is the last commit that changed a line of a repository.
+type Blame struct {
+	Commit      string `json:"commit"`
+	Author      string `json:"author"`
+	AuthorEmail string `json:"authorEmail"`
+}
+
+// GitBlameOutput is the struct that holds the blame of each "file:line" of
+// the findings of an analysis.
+type GitBlameOutput struct {
+	Blames map[string]Blame `json:"blames"`
+}
+
+// analyzeGitBlame reads the lines printed by gitblame, one tab separated
+// location, commit, author email and author name per line blamed.
+func analyzeGitBlame(gitBlameScan *SecTestScanInfo) error {
+	gitBlameOutput := GitBlameOutput{Blames: map[string]Blame{}}
+	for _, line := range strings.Split(gitBlameScan.Container.COutput, "\n") {
+		fields := strings.SplitN(strings.TrimRight(line, "\r"), "\t", 4)
+		if len(fields) != 4 || fields[1] == "" {
+			continue
+		}
+		gitBlameOutput.Blames[fields[0]] = Blame{Commit: fields[1], AuthorEmail: fields[2], Author: fields[3]}
+	}
+	gitBlameScan.FinalOutput = gitBlameOutput
+	gitBlameScan.Blames = gitBlameOutput
+	gitBlameScan.prepareContainerAfterScan()
+	return nil
+}
+
+// handleBlameLocations replaces %GIT_BLAME_LOCATIONS% in cmd with the
+// locations to blame, base64 encoded as their file names come from the
+// repository analyzed and are not safe to put in a shell command.
+func (scanInfo *SecTestScanInfo) handleBlameLocations(cmd string) string {
+	if !strings.Contains(cmd, "%GIT_BLAME_LOCATIONS%") {
+		return cmd
+	}
+	locations := strings.Join(scanInfo.BlameLocations, "\n") + "\n"
+	return strings.Replace(cmd, "%GIT_BLAME_LOCATIONS%", base64.StdEncoding.EncodeToString([]byte(locations)), -1)
+}
+
+// blameLocation returns the "file:line" of vuln relative to the root of the
+// repository, or an empty string if it has no line.
+func blameLocation(vuln types.HuskyCIVulnerability) string {
+	line, err := strconv.Atoi(strings.TrimSpace(vuln.Line))
+	if err != nil || line <= 0 || vuln.File == "" {
+		return ""
+	}
+	file := fingerprint.File(vuln.File)
+	if file == "" || strings.HasPrefix(file, "/") || strings.ContainsAny(file, "\n\r") {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", file, line)
+}
+
+// securityTestOutputs returns every securityTest output of results.
+func securityTestOutputs(results *types.HuskyCIResults) []*types.HuskyCISecurityTestOutput {
+	return []*types.HuskyCISecurityTestOutput{
+		&results.GoResults.HuskyCIGosecOutput,
+		&results.PythonResults.HuskyCIBanditOutput,
+		&results.PythonResults.HuskyCISafetyOutput,
+		&results.JavaScriptResults.HuskyCINpmAuditOutput,
+		&results.JavaScriptResults.HuskyCIYarnAuditOutput,
+		&results.RubyResults.HuskyCIBrakemanOutput,
+		&results.JavaResults.HuskyCISpotBugsOutput,
+		&results.HclResults.HuskyCITFSecOutput,
+		&results.IaCResults.HuskyCITrivyConfigOutput,
+		&results.CSharpResults.HuskyCISecurityCodeScanOutput,
+		&results.GenericResults.HuskyCIGitleaksOutput,
+		&results.GenericResults.HuskyCITrivyOutput,
+	}
+}
+
+// findings calls fn with every high, medium and low severity finding of
+// results, which fn may change.
+func findings(results *types.HuskyCIResults, fn func(vuln *types.HuskyCIVulnerability)) {
+	for _, output := range securityTestOutputs(results) {
+		for _, vulns := range [][]types.HuskyCIVulnerability{output.HighVulns, output.MediumVulns, output.LowVulns} {
+			for i := range vulns {
+				fn(&vulns[i])
+			}
+		}
+	}
+}
+
+// Findings returns every high, medium and low severity finding of results.
+func Findings(results types.HuskyCIResults) []types.HuskyCIVulnerability {
+	vulns := []types.HuskyCIVulnerability{}
+	findings(&results, func(vuln *types.HuskyCIVulnerability) {
+		vulns = append(vulns, *vuln)
+	})
+	return vulns
+}
+
+// FindingsOfSeverity returns the findings of results of severity high,
+// medium or low, or every one of them if severity is empty.
+func FindingsOfSeverity(results types.HuskyCIResults, severity string) []types.HuskyCIVulnerability {
+	vulns := []types.HuskyCIVulnerability{}
+	for _, output := range securityTestOutputs(&results) {
+		switch severity {
+		case "":
+			vulns = append(vulns, output.HighVulns...)
+			vulns = append(vulns, output.MediumVulns...)
+			vulns = append(vulns, output.LowVulns...)
+		case "high":
+			vulns = append(vulns, output.HighVulns...)
+		case "medium":
+			vulns = append(vulns, output.MediumVulns...)
+		case "low":
+			vulns = append(vulns, output.LowVulns...)
+		}
+	}
+	return vulns
+}
+
+// Counts returns how many high, medium and low severity findings results has.
+func Counts(results types.HuskyCIResults) (high, medium, low int) {
+	for _, output := range securityTestOutputs(&results) {
+		high += len(output.HighVulns)
+		medium += len(output.MediumVulns)
+		low += len(output.LowVulns)
+	}
+	return high, medium, low
+}
+
+// setFingerprints stores in each finding of results its fingerprint.
+func (results *RunAllInfo) setFingerprints() {
+	findings(&results.HuskyCIResults, func(vuln *types.HuskyCIVulnerability) {
+		vuln.Fingerprint = fingerprint.Of(*vuln)
+	})
+}
+
+// blameLocations returns the distinct locations of the findings of results,
+// up to maxBlameLocations.
+func blameLocations(results *types.HuskyCIResults) []string {
+	seen := map[string]bool{}
+	locations := []string{}
+	findings(results, func(vuln *types.HuskyCIVulnerability) {
+		location := blameLocation(*vuln)
+		if location == "" || seen[location] || len(locations) >= maxBlameLocations {
+			return
+		}
+		seen[location] = true
+		locations = append(locations, location)
+	})
+	return locations
+}
+
+// applyBlames stores in each finding of results the commit and author blamed
+// for its line.
+func applyBlames(results *types.HuskyCIResults, blames map[string]Blame) {
+	findings(results, func(vuln *types.HuskyCIVulnerability) {
+		blame, found := blames[blameLocation(*vuln)]
+		if !found {
+			return
+		}
+		vuln.Commit = blame.Commit
+		vuln.Author = blame.Author
+		vuln.AuthorEmail = blame.AuthorEmail
+	})
+}
+
+// attributeFindings runs gitblame on the branch analyzed to attribute each
+// finding to the commit and author that last changed its line. Attribution is
+// best effort: when it fails, the findings are kept as they are.
+func (results *RunAllInfo) attributeFindings(enryScan SecTestScanInfo) {
+	if enryScan.isUpload() {
+		return
+	}
+	locations := blameLocations(&results.HuskyCIResults)
+	if len(locations) == 0 {
+		return
+	}
+	gitBlameScan := SecTestScanInfo{Ctx: enryScan.Ctx, Commit: enryScan.Commit, PullRequest: enryScan.PullRequest}
+	if err := gitBlameScan.New(enryScan.RID, enryScan.URL, enryScan.Branch, gitblame, nil, enryScan.DockerHost); err != nil {
+		log.Warning("attributeFindings", "SECURITYTEST", 121, enryScan.RID, err)
+		return
+	}
+	gitBlameScan.BlameLocations = locations
+	if err := gitBlameScan.Start(); err != nil {
+		log.Warning("attributeFindings", "SECURITYTEST", 121, enryScan.RID, err)
+		return
+	}
+	applyBlames(&results.HuskyCIResults, gitBlameScan.Blames.Blames)
+}
+
+// Owners returns the authors of the lines of the findings of results, the
+// likely owners of their fix, from the one with the most findings.
+func Owners(results types.HuskyCIResults) []types.Owner {
+	byEmail := map[string]*types.Owner{}
+	commits := map[string]map[string]bool{}
+	findings(&results, func(vuln *types.HuskyCIVulnerability) {
+		if vuln.AuthorEmail == "" {
+			return
+		}
+		email := strings.ToLower(vuln.AuthorEmail)
+		owner, found := byEmail[email]
+		if !found {
+			owner = &types.Owner{Author: vuln.Author, AuthorEmail: vuln.AuthorEmail, Commits: []string{}}
+			byEmail[email] = owner
+			commits[email] = map[string]bool{}
+		}
+		owner.Findings++
+		if vuln.Commit != "" && !commits[email][vuln.Commit] {
+			commits[email][vuln.Commit] = true
+			owner.Commits = append(owner.Commits, vuln.Commit)
+		}
+	})
+	owners := []types.Owner{}
+	for _, owner := range byEmail {
+		owners = append(owners, *owner)
+	}
+	sort.Slice(owners, func(i, j int) bool {
+		if owners[i].Findings != owners[j].Findings {
+			return owners[i].Findings > owners[j].Findings
+		}
+		return owners[i].AuthorEmail < owners[j].AuthorEmail
+	})
+	return owners
+}
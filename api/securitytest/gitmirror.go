@@ -0,0 +1,62 @@
+package securitytest
+
+import (
+	"os"
+	"strings"
+)
+
+// gitMirror is the name the containers refreshing the mirror of a repository
+// in the git cache run under.
+const gitMirror = "gitmirror"
+
+// gitMirrorTimeout bounds how long the mirror of a repository may take to be
+// refreshed, in seconds. The first refresh fetches every branch.
+const gitMirrorTimeout = 600
+
+// gitMirrorCmd creates the mirror at %GIT_MIRROR% if needed and fetches the
+// branches of %GIT_REPO% into it.
+const gitMirrorCmd = `mkdir -p ~/.ssh &&
+echo '%GIT_PRIVATE_SSH_KEY%' > ~/.ssh/huskyci_id_rsa &&
+chmod 600 ~/.ssh/huskyci_id_rsa &&
+echo "IdentityFile ~/.ssh/huskyci_id_rsa" >> /etc/ssh/ssh_config &&
+echo "StrictHostKeyChecking no" >> /etc/ssh/ssh_config &&
+git init --quiet --bare %GIT_MIRROR% &&
+GIT_TERMINAL_PROMPT=0 git -C %GIT_MIRROR% fetch --quiet --prune %GIT_REPO% '+refs/heads/*:refs/heads/*'`
+
+// RefreshGitMirror fetches the repository of scanInfo into its mirror in the
+// git cache, which the securityTests then clone from. The mirror is only
+// written here, by a container of the gitauthors image running the command
+// of the API, as the securityTests, which may run the code they scan, mount
+// it read-only. Nothing is done for uploads or without a git cache.
+func (scanInfo *SecTestScanInfo) RefreshGitMirror() error {
+	mirrorPath := scanInfo.gitMirrorPath()
+	if mirrorPath == "" || scanInfo.isUpload() {
+		return nil
+	}
+	mirrorScan := SecTestScanInfo{
+		Ctx:        scanInfo.Ctx,
+		RID:        scanInfo.RID,
+		URL:        scanInfo.URL,
+		Branch:     scanInfo.Branch,
+		DockerHost: scanInfo.DockerHost,
+	}
+	if err := mirrorScan.setSecurityTestContainer("gitauthors"); err != nil {
+		return err
+	}
+	mirrorScan.SecurityTestName = gitMirror
+	mirrorScan.Container.SecurityTest.Name = gitMirror
+	mirrorScan.Container.SecurityTest.Cmd = strings.Replace(gitMirrorCmd, "%GIT_MIRROR%", mirrorPath, -1)
+	mirrorScan.Container.SecurityTest.WarmPool = false
+	if err := mirrorScan.verifyImage(); err != nil {
+		return err
+	}
+	mirrorScan.loadGitCredential()
+
+	switch os.Getenv("HUSKYCI_INFRASTRUCTURE_USE") {
+	case "kubernetes":
+		return mirrorScan.kubeRun(gitMirrorTimeout)
+	case "docker":
+		return mirrorScan.dockerRun(gitMirrorTimeout)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package securitytest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/huskyci-org/huskyCI/api/types"
+)
+
+// Lockfiles audited by the npmaudit and yarnaudit securityTests.
+const (
+	npmLockfile  = "package-lock.json"
+	pnpmLockfile = "pnpm-lock.yaml"
+	yarnLockfile = "yarn.lock"
+)
+
+// AuditWorkspace is the audit of one of the lockfiles of a repository, or of
+// one of the workspaces of an npm lockfile. The npmaudit and yarnaudit
+// containers print one per line. Path is the directory of the audited
+// packages, empty at the root of the repository, and Error is set instead of
+// Audit if the auditor failed.
+type AuditWorkspace struct {
+	Path     string          `json:"path"`
+	Lockfile string          `json:"lockfile"`
+	Audit    json.RawMessage `json:"audit,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// parseAuditWorkspaces returns the audits printed in cOutput. An output that
+// is a single audit, as older containers print, is returned as the audit of
+// lockfile at the root of the repository.
+func parseAuditWorkspaces(cOutput, lockfile string) ([]AuditWorkspace, error) {
+	workspaces := []AuditWorkspace{}
+	decoder := json.NewDecoder(strings.NewReader(cOutput))
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err == io.EOF {
+			return workspaces, nil
+		} else if err != nil {
+			return nil, err
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return nil, err
+		}
+		if _, ok := fields["lockfile"]; !ok {
+			workspaces = append(workspaces, AuditWorkspace{Lockfile: lockfile, Audit: raw})
+			continue
+		}
+		workspace := AuditWorkspace{}
+		if err := json.Unmarshal(raw, &workspace); err != nil {
+			return nil, err
+		}
+		workspaces = append(workspaces, workspace)
+	}
+}
+
+// auditErrorVuln returns the low vulnerability reporting that securityTool
+// could not audit workspace.
+func auditErrorVuln(securityTool string, workspace AuditWorkspace) types.HuskyCIVulnerability {
+	where := "at the root of the repository"
+	if workspace.Path != "" {
+		where = "in " + workspace.Path
+	}
+	return types.HuskyCIVulnerability{
+		Language:     "JavaScript",
+		SecurityTool: securityTool,
+		Severity:     "low",
+		Title:        fmt.Sprintf("Error while auditing the %s %s.", workspace.Lockfile, where),
+		Details:      strings.TrimSpace(workspace.Error),
+		PackagePath:  workspace.Path,
+	}
+}
+
+// addAuditVuln adds vuln to results with the severity an audit of npm, pnpm
+// or yarn gave it.
+func addAuditVuln(results *types.HuskyCISecurityTestOutput, vuln types.HuskyCIVulnerability, severity string) {
+	switch severity {
+	case "info", "low":
+		vuln.Severity = "low"
+		results.LowVulns = append(results.LowVulns, vuln)
+	case "moderate":
+		vuln.Severity = "medium"
+		results.MediumVulns = append(results.MediumVulns, vuln)
+	case "high", "critical":
+		vuln.Severity = "high"
+		results.HighVulns = append(results.HighVulns, vuln)
+	}
+}
+
+// pnpmPackagePaths returns the directories, relative to the root of the
+// repository, of the packages depending on issue. pnpm starts the path of
+// each finding with the importer of the dependency: "." for the package at
+// the root of its workspace, or its directory with its slashes replaced by
+// "__".
+func pnpmPackagePaths(workspacePath string, issue YarnIssue) []string {
+	paths := []string{}
+	seen := map[string]bool{}
+	for _, finding := range issue.Findings {
+		for _, findingPath := range finding.Paths {
+			importer := strings.TrimSpace(strings.SplitN(findingPath, ">", 2)[0])
+			path := workspacePath
+			if importer != "." && importer != "" {
+				path = strings.TrimPrefix(workspacePath+"/"+strings.ReplaceAll(importer, "__", "/"), "/")
+			}
+			if !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+	if len(paths) == 0 {
+		paths = append(paths, workspacePath)
+	}
+	return paths
+}
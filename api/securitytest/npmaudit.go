@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/huskyci-org/huskyCI/api/fixversion"
 	"github.com/huskyci-org/huskyCI/api/log"
 	"github.com/huskyci-org/huskyCI/api/types"
 	"github.com/huskyci-org/huskyCI/api/util"
@@ -31,6 +32,10 @@ type Vulnerability struct {
 // FixAvailableType holds the information about whether a fix is available for a vulnerability.
 type FixAvailableType struct {
 	Text string
+	// Name and Version are the dependency to install to fix the vulnerability,
+	// when npm reports it.
+	Name    string
+	Version string
 }
 
 // FixAvailableTypeNPM holds the information of the dependency that originated the vulnerability.
@@ -132,15 +137,32 @@ func (e *FixAvailableType) UnmarshalJSON(data []byte) error {
 			return err
 		}
 		e.Text = fmt.Sprintf("Fix available: %s %s", tmp.Name, tmp.Version)
+		e.Name = tmp.Name
+		e.Version = tmp.Version
 		return nil
 	}
 	return fmt.Errorf("unsupported fixAvailable field")
 }
 
+// PnpmAuditOutput is the struct that stores all pnpm audit output, which has
+// the advisories format of yarn audit.
+type PnpmAuditOutput struct {
+	Advisories map[string]YarnIssue `json:"advisories"`
+	Metadata   Metadata             `json:"metadata"`
+}
+
+// NpmAuditWorkspaceOutput is the audit by npm or by pnpm, depending on its
+// lockfile, of a workspace of the repository.
+type NpmAuditWorkspaceOutput struct {
+	Workspace AuditWorkspace
+	Npm       NpmAuditOutput
+	Pnpm      PnpmAuditOutput
+}
+
 func analyzeNpmaudit(npmAuditScan *SecTestScanInfo) error {
 
-	npmAuditOutput := NpmAuditOutput{}
-	npmAuditScan.FinalOutput = npmAuditOutput
+	npmAuditOutputs := []NpmAuditWorkspaceOutput{}
+	npmAuditScan.FinalOutput = npmAuditOutputs
 
 	// if package-lock was not found, a warning will be genrated as a low vuln
 	packageNotFound := strings.Contains(npmAuditScan.Container.COutput, "ERROR_PACKAGE_LOCK_NOT_FOUND")
@@ -152,19 +174,20 @@ func analyzeNpmaudit(npmAuditScan *SecTestScanInfo) error {
 	}
 
 	// nil cOutput states that no Issues were found.
-	if npmAuditScan.Container.COutput == "" {
+	if strings.TrimSpace(npmAuditScan.Container.COutput) == "" {
 		npmAuditScan.prepareContainerAfterScan()
 		return nil
 	}
 
-	// Unmarshall rawOutput into finalOutput, that is a NpmAuditOutput struct.
-	if err := json.Unmarshal([]byte(npmAuditScan.Container.COutput), &npmAuditOutput); err != nil {
+	// Unmarshall rawOutput into finalOutput, the audits of every workspace.
+	npmAuditOutputs, err := parseNpmAuditOutputs(npmAuditScan.Container.COutput)
+	if err != nil {
 		log.Error("analyzeNpmaudit", "NPMAUDIT", 1014, npmAuditScan.Container.COutput, err)
 		npmAuditScan.ErrorFound = util.HandleScanError(npmAuditScan.Container.COutput, err)
 		npmAuditScan.prepareContainerAfterScan()
 		return npmAuditScan.ErrorFound
 	}
-	npmAuditScan.FinalOutput = npmAuditOutput
+	npmAuditScan.FinalOutput = npmAuditOutputs
 
 	// step 4: find Issues that have severity "MEDIUM" or "HIGH" and confidence "HIGH".
 	npmAuditScan.prepareNpmAuditVulns()
@@ -172,10 +195,48 @@ func analyzeNpmaudit(npmAuditScan *SecTestScanInfo) error {
 	return nil
 }
 
+// parseNpmAuditOutputs returns the audits of every workspace printed in
+// cOutput by the npmaudit container.
+func parseNpmAuditOutputs(cOutput string) ([]NpmAuditWorkspaceOutput, error) {
+	workspaces, err := parseAuditWorkspaces(cOutput, npmLockfile)
+	if err != nil {
+		return nil, err
+	}
+	npmAuditOutputs := []NpmAuditWorkspaceOutput{}
+	for _, workspace := range workspaces {
+		npmAuditOutput := NpmAuditWorkspaceOutput{Workspace: workspace}
+		switch {
+		case workspace.Error != "":
+		case workspace.Lockfile == pnpmLockfile:
+			err = json.Unmarshal(workspace.Audit, &npmAuditOutput.Pnpm)
+		default:
+			err = json.Unmarshal(workspace.Audit, &npmAuditOutput.Npm)
+		}
+		if err != nil {
+			return nil, err
+		}
+		npmAuditOutputs = append(npmAuditOutputs, npmAuditOutput)
+	}
+	return npmAuditOutputs, nil
+}
+
+// npmAuditFix returns the dependency to upgrade to fix issue and the version
+// to upgrade it to: the one npm reports, which may be a dependency depending on
+// the vulnerable package, or else the upper bound of the vulnerable range.
+func npmAuditFix(issue Vulnerability) (string, string) {
+	if issue.FixAvailable.Name != "" && issue.FixAvailable.Version != "" {
+		return issue.FixAvailable.Name, issue.FixAvailable.Version
+	}
+	if issue.FixAvailable.Text == "false" {
+		return issue.Name, ""
+	}
+	return issue.Name, fixversion.FromVulnerableRange(issue.VulnerableVersions, "")
+}
+
 func (npmAuditScan *SecTestScanInfo) prepareNpmAuditVulns() {
 
 	huskyCInpmauditResults := types.HuskyCISecurityTestOutput{}
-	npmAuditOutput := npmAuditScan.FinalOutput.(NpmAuditOutput)
+	npmAuditOutputs := npmAuditScan.FinalOutput.([]NpmAuditWorkspaceOutput)
 
 	if npmAuditScan.PackageNotFound {
 		npmauditVuln := types.HuskyCIVulnerability{}
@@ -183,12 +244,29 @@ func (npmAuditScan *SecTestScanInfo) prepareNpmAuditVulns() {
 		npmauditVuln.SecurityTool = "NpmAudit"
 		npmauditVuln.Severity = "low"
 		npmauditVuln.Title = "No package-lock.json found."
-		npmauditVuln.Details = "It looks like your project doesn't have a package-lock.json file. If you use NPM to handle your dependencies, it would be a good idea to commit it so huskyCI can check for vulnerabilities."
+		npmauditVuln.Details = "It looks like your project doesn't have a package-lock.json, pnpm-lock.yaml or yarn.lock file. If you use NPM or pnpm to handle your dependencies, it would be a good idea to commit its lockfile so huskyCI can check for vulnerabilities."
 
 		npmAuditScan.Vulnerabilities.LowVulns = append(npmAuditScan.Vulnerabilities.LowVulns, npmauditVuln)
 		return
 	}
 
+	for _, npmAuditOutput := range npmAuditOutputs {
+		switch {
+		case npmAuditOutput.Workspace.Error != "":
+			huskyCInpmauditResults.LowVulns = append(huskyCInpmauditResults.LowVulns, auditErrorVuln("NpmAudit", npmAuditOutput.Workspace))
+		case npmAuditOutput.Workspace.Lockfile == pnpmLockfile:
+			addPnpmAuditVulns(&huskyCInpmauditResults, npmAuditOutput.Workspace.Path, npmAuditOutput.Pnpm)
+		default:
+			addNpmAuditVulns(&huskyCInpmauditResults, npmAuditOutput.Workspace.Path, npmAuditOutput.Npm)
+		}
+	}
+
+	npmAuditScan.Vulnerabilities = huskyCInpmauditResults
+}
+
+// addNpmAuditVulns adds to results the vulnerabilities npm found in the
+// packages at packagePath.
+func addNpmAuditVulns(results *types.HuskyCISecurityTestOutput, packagePath string, npmAuditOutput NpmAuditOutput) {
 	for _, issue := range npmAuditOutput.Vulnerabilities {
 		npmauditVuln := types.HuskyCIVulnerability{}
 		npmauditVuln.Language = "JavaScript"
@@ -199,25 +277,37 @@ func (npmAuditScan *SecTestScanInfo) prepareNpmAuditVulns() {
 		}
 		npmauditVuln.VunerableBelow = issue.VulnerableVersions
 		npmauditVuln.Code = issue.Name
+		npmauditVuln.Package, npmauditVuln.FixVersion = npmAuditFix(issue)
+		npmauditVuln.PackagePath = packagePath
 		npmauditVuln.Version = ""
 		for i, via := range issue.Via {
 			npmauditVuln.Version += fmt.Sprintf("Advisories and information (Via %d):\n", i)
 			npmauditVuln.Version += fmt.Sprintf("%s\n", via.Text)
 		}
+		addAuditVuln(results, npmauditVuln, issue.Severity)
+	}
+}
 
-		switch issue.Severity {
-		case "info", "low":
-			npmauditVuln.Severity = "low"
-			huskyCInpmauditResults.LowVulns = append(huskyCInpmauditResults.LowVulns, npmauditVuln)
-		case "moderate":
-			npmauditVuln.Severity = "medium"
-			huskyCInpmauditResults.MediumVulns = append(huskyCInpmauditResults.MediumVulns, npmauditVuln)
-		case "high", "critical":
-			npmauditVuln.Severity = "high"
-			huskyCInpmauditResults.HighVulns = append(huskyCInpmauditResults.HighVulns, npmauditVuln)
+// addPnpmAuditVulns adds to results the vulnerabilities pnpm found in the
+// packages of the workspace at workspacePath, once for each package depending
+// on them.
+func addPnpmAuditVulns(results *types.HuskyCISecurityTestOutput, workspacePath string, pnpmAuditOutput PnpmAuditOutput) {
+	for _, issue := range pnpmAuditOutput.Advisories {
+		pnpmauditVuln := types.HuskyCIVulnerability{}
+		pnpmauditVuln.Language = "JavaScript"
+		pnpmauditVuln.SecurityTool = "PnpmAudit"
+		pnpmauditVuln.Details = issue.Overview
+		pnpmauditVuln.Title = fmt.Sprintf("Vulnerable Dependency: %s %s (%s)", issue.ModuleName, issue.VulnerableVersions, issue.Title)
+		pnpmauditVuln.VunerableBelow = issue.VulnerableVersions
+		pnpmauditVuln.Code = issue.ModuleName
+		for _, findings := range issue.Findings {
+			pnpmauditVuln.Version = findings.Version
+		}
+		pnpmauditVuln.Package = issue.ModuleName
+		pnpmauditVuln.FixVersion = fixversion.FromPatchedRange(issue.PatchedVersions, pnpmauditVuln.Version)
+		for _, packagePath := range pnpmPackagePaths(workspacePath, issue) {
+			pnpmauditVuln.PackagePath = packagePath
+			addAuditVuln(results, pnpmauditVuln, issue.Severity)
 		}
-
 	}
-
-	npmAuditScan.Vulnerabilities = huskyCInpmauditResults
 }
@@ -0,0 +1,88 @@
+package securitytest
+
+import (
+	"strings"
+
+	"github.com/huskyci-org/huskyCI/api/types"
+)
+
+// IgnoreRules returns the rules of policy as IgnoreRules, lower cased.
+func IgnoreRules(policy types.Policy) map[string]bool {
+	if len(policy.IgnoreRules) == 0 {
+		return nil
+	}
+	rules := map[string]bool{}
+	for _, rule := range policy.IgnoreRules {
+		rules[strings.ToLower(rule)] = true
+	}
+	return rules
+}
+
+// ignoreByPolicy moves the high, medium and low severity findings of
+// IgnoreRules to the NoSecVulns, flagged as ignored by policy, before they
+// decide the result of the securityTest. They keep their severity.
+func (scanInfo *SecTestScanInfo) ignoreByPolicy() {
+	if len(scanInfo.IgnoreRules) == 0 {
+		return
+	}
+	vulns := &scanInfo.Vulnerabilities
+	for _, severity := range []*[]types.HuskyCIVulnerability{&vulns.HighVulns, &vulns.MediumVulns, &vulns.LowVulns} {
+		kept := (*severity)[:0]
+		for _, vuln := range *severity {
+			if vuln.Rule == "" || !scanInfo.IgnoreRules[strings.ToLower(vuln.Rule)] {
+				kept = append(kept, vuln)
+				continue
+			}
+			vuln.Status = types.VulnIgnoredByPolicy
+			vulns.NoSecVulns = append(vulns.NoSecVulns, vuln)
+		}
+		*severity = kept
+	}
+}
+
+// SeverityOverrides returns the severity overrides of policy with their rules
+// lower cased.
+func SeverityOverrides(policy types.Policy) map[string]string {
+	if len(policy.SeverityOverrides) == 0 {
+		return nil
+	}
+	overrides := map[string]string{}
+	for rule, severity := range policy.SeverityOverrides {
+		overrides[strings.ToLower(rule)] = strings.ToLower(severity)
+	}
+	return overrides
+}
+
+// overrideSeverities moves the high, medium and low severity findings of the
+// rules of SeverityOverrides to the severity the policy gives them, before
+// they decide the result of the securityTest. Findings without a rule, such as
+// the ones of npm audit, are matched by their title.
+func (scanInfo *SecTestScanInfo) overrideSeverities() {
+	if len(scanInfo.SeverityOverrides) == 0 {
+		return
+	}
+	vulns := &scanInfo.Vulnerabilities
+	severities := []string{"high", "medium", "low"}
+	lists := map[string]*[]types.HuskyCIVulnerability{"high": &vulns.HighVulns, "medium": &vulns.MediumVulns, "low": &vulns.LowVulns}
+	moved := map[string][]types.HuskyCIVulnerability{}
+	for _, from := range severities {
+		kept := (*lists[from])[:0]
+		for _, vuln := range *lists[from] {
+			rule := vuln.Rule
+			if rule == "" {
+				rule = vuln.Title
+			}
+			to, found := scanInfo.SeverityOverrides[strings.ToLower(rule)]
+			if !found || to == from || lists[to] == nil {
+				kept = append(kept, vuln)
+				continue
+			}
+			vuln.Severity = to
+			moved[to] = append(moved[to], vuln)
+		}
+		*lists[from] = kept
+	}
+	for _, to := range severities {
+		*lists[to] = append(*lists[to], moved[to]...)
+	}
+}
@@ -0,0 +1,108 @@
+package securitytest
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/huskyci-org/huskyCI/api/types"
+)
+
+// Results returns the results of the finished analysis, for one of its
+// securityTests to run again with Rerun.
+func Results(analysis types.Analysis) *RunAllInfo {
+	results := &RunAllInfo{
+		RID:            analysis.RID,
+		Status:         analysis.Status,
+		Containers:     analysis.Containers,
+		CommitAuthors:  analysis.CommitAuthors,
+		Codes:          analysis.Codes,
+		FinalResult:    analysis.Result,
+		HuskyCIResults: analysis.HuskyCIResults,
+	}
+	if analysis.ErrorFound != "" {
+		results.ErrorFound = errors.New(analysis.ErrorFound)
+	}
+	// setWarnings lists the securityTests that timed out again
+	for _, warning := range analysis.Warnings {
+		if !strings.Contains(warning, " timed out after ") {
+			results.Warnings = append(results.Warnings, warning)
+		}
+	}
+	return results
+}
+
+// Rerun runs the securityTest of scan again on the code of the analysis of
+// results, replacing the container and the findings it had, and decides the
+// result of the analysis again. The error of the analysis is kept unless this
+// securityTest caused it, and is set again if it fails once more.
+func (results *RunAllInfo) Rerun(scan SecTestScanInfo) error {
+	name := scan.SecurityTestName
+	err := scan.Start()
+	if scan.TimedOut() {
+		err = nil
+	}
+
+	failedBefore, othersFailed := false, false
+	containers := []types.Container{}
+	for _, container := range results.Containers {
+		if container.SecurityTest.Name == name {
+			failedBefore = failedBefore || container.CResult == "error"
+			continue
+		}
+		othersFailed = othersFailed || container.CResult == "error"
+		containers = append(containers, container)
+	}
+	results.Containers = containers
+	results.addContainer(scan.Container)
+
+	if output := securityTestOutput(&results.HuskyCIResults, name); output != nil {
+		*output = types.HuskyCISecurityTestOutput{}
+	}
+	if strings.EqualFold(name, "gitauthors") {
+		results.CommitAuthors = scan.CommitAuthors.Authors
+	} else {
+		results.setVulns(scan)
+	}
+
+	previousErr := results.ErrorFound
+	results.ErrorFound = err
+	if err == nil && previousErr != nil && (!failedBefore || othersFailed) {
+		results.ErrorFound = previousErr
+	}
+	if results.ErrorFound == nil {
+		results.attributeFindings(scan)
+		results.setFingerprints()
+	}
+	results.setToAnalysis()
+	return err
+}
+
+// securityTestOutput returns the findings of the securityTest name in
+// results, or nil if it has none, such as gitauthors.
+func securityTestOutput(results *types.HuskyCIResults, name string) *types.HuskyCISecurityTestOutput {
+	switch name {
+	case bandit:
+		return &results.PythonResults.HuskyCIBanditOutput
+	case brakeman:
+		return &results.RubyResults.HuskyCIBrakemanOutput
+	case safety:
+		return &results.PythonResults.HuskyCISafetyOutput
+	case gosec:
+		return &results.GoResults.HuskyCIGosecOutput
+	case npmaudit:
+		return &results.JavaScriptResults.HuskyCINpmAuditOutput
+	case yarnaudit:
+		return &results.JavaScriptResults.HuskyCIYarnAuditOutput
+	case spotbugs:
+		return &results.JavaResults.HuskyCISpotBugsOutput
+	case gitleaks:
+		return &results.GenericResults.HuskyCIGitleaksOutput
+	case tfsec:
+		return &results.HclResults.HuskyCITFSecOutput
+	case trivyconfig:
+		return &results.IaCResults.HuskyCITrivyConfigOutput
+	case securitycodescan:
+		return &results.CSharpResults.HuskyCISecurityCodeScanOutput
+	}
+	return nil
+}
@@ -2,13 +2,13 @@ package securitytest
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 
 	apiContext "github.com/huskyci-org/huskyCI/api/context"
 	"github.com/huskyci-org/huskyCI/api/log"
 	"github.com/huskyci-org/huskyCI/api/types"
-	"github.com/huskyci-org/huskyCI/api/util"
 )
 
 // RunAllInfo store all scans results of an Analysis
@@ -20,7 +20,13 @@ type RunAllInfo struct {
 	Codes          []types.Code
 	FinalResult    string
 	ErrorFound     error
+	Warnings       []string
 	HuskyCIResults types.HuskyCIResults
+	// OnTestFinished, if not nil, is called with the container of every
+	// securityTest as soon as it finishes.
+	OnTestFinished func(container types.Container)
+	mutex          sync.Mutex
+	selected       map[string]bool
 }
 
 const bandit = "bandit"
@@ -32,12 +38,14 @@ const yarnaudit = "yarnaudit"
 const spotbugs = "spotbugs"
 const gitleaks = "gitleaks"
 const tfsec = "tfsec"
+const trivyconfig = "trivyconfig"
 const securitycodescan = "securitycodescan"
 
 // Start runs both generic and language security
 func (results *RunAllInfo) Start(enryScan SecTestScanInfo) error {
 
 	results.Codes = enryScan.Codes
+	results.selected = enryScan.SecurityTests
 	// Buffered so both goroutines can send without blocking; avoids "send on closed channel" when both error
 	errChan := make(chan error, 2)
 	waitChan := make(chan struct{})
@@ -92,6 +100,9 @@ func (results *RunAllInfo) Start(enryScan SecTestScanInfo) error {
 		return scanError
 	}
 
+	results.attributeFindings(enryScan)
+	results.setFingerprints()
+
 	// Set the FinalResult based on the scan results
 	results.setFinalResult()
 	return nil
@@ -115,18 +126,21 @@ func (results *RunAllInfo) runGenericScans(enryScan SecTestScanInfo) error {
 	for genericTestIndex := range genericTests {
 		genericTest := &genericTests[genericTestIndex]
 		
-		// Skip gitauthors for file:// URLs since extracted directories don't have git history
-		if strings.EqualFold(genericTest.Name, "gitauthors") && util.IsFileURL(enryScan.URL) {
-			log.Info("runGenericScans", "SECURITYTEST", 16, fmt.Sprintf("Skipping gitauthors for file:// URL: %s (extracted directories don't have git history)", enryScan.URL))
-			// Set empty authors for file:// URLs
+		// Skip gitauthors for upload analyses since uploaded code has no git history
+		if strings.EqualFold(genericTest.Name, "gitauthors") && enryScan.isUpload() {
+			log.Info("runGenericScans", "SECURITYTEST", 16, fmt.Sprintf("Skipping gitauthors for upload: %s (uploaded code has no git history)", enryScan.UploadID))
+			// Set empty authors for upload analyses
 			results.CommitAuthors = []string{}
 			continue
 		}
+		if !enryScan.selects(genericTest.Name) {
+			continue
+		}
 		
 		wg.Add(1)
 		go func(genericTest *types.SecurityTest) {
 			defer wg.Done()
-			newGenericScan := SecTestScanInfo{}
+			newGenericScan := SecTestScanInfo{Ctx: enryScan.Ctx, Commit: enryScan.Commit, PullRequest: enryScan.PullRequest, UploadID: enryScan.UploadID, IgnoreRules: enryScan.IgnoreRules, SeverityOverrides: enryScan.SeverityOverrides}
 			// LanguageExclusions is only utilized on first scan (enryScan) therefore set as nil
 			enryScan.LanguageExclusions = nil
 			if err := newGenericScan.New(enryScan.RID, enryScan.URL, enryScan.Branch, genericTest.Name, enryScan.LanguageExclusions, enryScan.DockerHost); err != nil {
@@ -137,7 +151,8 @@ func (results *RunAllInfo) runGenericScans(enryScan SecTestScanInfo) error {
 					return
 				}
 			}
-			if err := newGenericScan.Start(); err != nil {
+			if err := newGenericScan.Start(); err != nil && !newGenericScan.TimedOut() {
+				results.addContainer(newGenericScan.Container)
 				select {
 				case <-syncChan:
 					return
@@ -145,10 +160,10 @@ func (results *RunAllInfo) runGenericScans(enryScan SecTestScanInfo) error {
 					return
 				}
 			}
-			results.Containers = append(results.Containers, newGenericScan.Container)
+			results.addContainer(newGenericScan.Container)
 			if strings.EqualFold(genericTest.Name, "gitauthors") {
 				results.CommitAuthors = newGenericScan.CommitAuthors.Authors
-			} else if genericTest.Name == "gitleaks" {
+			} else if genericTest.Name == gitleaks || genericTest.Name == trivyconfig {
 				results.setVulns(newGenericScan)
 			}
 		}(genericTest)
@@ -176,7 +191,11 @@ func (results *RunAllInfo) runLanguageScans(enryScan SecTestScanInfo) error {
 		if err != nil {
 			return err
 		}
-		languageTests = append(languageTests, codeTests...)
+		for _, codeTest := range codeTests {
+			if enryScan.selects(codeTest.Name) {
+				languageTests = append(languageTests, codeTest)
+			}
+		}
 	}
 	// Buffered so multiple goroutines can send without blocking; avoids "send on closed channel"
 	errChan := make(chan error, len(languageTests))
@@ -191,7 +210,7 @@ func (results *RunAllInfo) runLanguageScans(enryScan SecTestScanInfo) error {
 		wg.Add(1)
 		go func(languageTest *types.SecurityTest) {
 			defer wg.Done()
-			newLanguageScan := SecTestScanInfo{}
+			newLanguageScan := SecTestScanInfo{Ctx: enryScan.Ctx, Commit: enryScan.Commit, PullRequest: enryScan.PullRequest, UploadID: enryScan.UploadID, IgnoreRules: enryScan.IgnoreRules, SeverityOverrides: enryScan.SeverityOverrides}
 			// LanguageExclusions is only utilized on first scan (enryScan) therefore set as nil
 			enryScan.LanguageExclusions = nil
 			if err := newLanguageScan.New(enryScan.RID, enryScan.URL, enryScan.Branch, languageTest.Name, enryScan.LanguageExclusions, enryScan.DockerHost); err != nil {
@@ -202,8 +221,8 @@ func (results *RunAllInfo) runLanguageScans(enryScan SecTestScanInfo) error {
 					return
 				}
 			}
-			if err := newLanguageScan.Start(); err != nil {
-				results.Containers = append(results.Containers, newLanguageScan.Container)
+			if err := newLanguageScan.Start(); err != nil && !newLanguageScan.TimedOut() {
+				results.addContainer(newLanguageScan.Container)
 				select {
 				case <-syncChan:
 					return
@@ -211,7 +230,7 @@ func (results *RunAllInfo) runLanguageScans(enryScan SecTestScanInfo) error {
 					return
 				}
 			}
-			results.Containers = append(results.Containers, newLanguageScan.Container)
+			results.addContainer(newLanguageScan.Container)
 			results.setVulns(newLanguageScan)
 		}(&languageTests[languageTestIndex])
 	}
@@ -230,6 +249,16 @@ func (results *RunAllInfo) runLanguageScans(enryScan SecTestScanInfo) error {
 	}
 }
 
+// addContainer adds the container of a finished securityTest to results.
+func (results *RunAllInfo) addContainer(container types.Container) {
+	results.mutex.Lock()
+	results.Containers = append(results.Containers, container)
+	results.mutex.Unlock()
+	if results.OnTestFinished != nil {
+		results.OnTestFinished(container)
+	}
+}
+
 func (results *RunAllInfo) setVulns(securityTestScan SecTestScanInfo) {
 
 	for _, highVuln := range securityTestScan.Vulnerabilities.HighVulns {
@@ -252,6 +281,8 @@ func (results *RunAllInfo) setVulns(securityTestScan SecTestScanInfo) {
 			results.HuskyCIResults.GenericResults.HuskyCIGitleaksOutput.HighVulns = append(results.HuskyCIResults.GenericResults.HuskyCIGitleaksOutput.HighVulns, highVuln)
 		case tfsec:
 			results.HuskyCIResults.HclResults.HuskyCITFSecOutput.HighVulns = append(results.HuskyCIResults.HclResults.HuskyCITFSecOutput.HighVulns, highVuln)
+		case trivyconfig:
+			results.HuskyCIResults.IaCResults.HuskyCITrivyConfigOutput.HighVulns = append(results.HuskyCIResults.IaCResults.HuskyCITrivyConfigOutput.HighVulns, highVuln)
 		case securitycodescan:
 			results.HuskyCIResults.CSharpResults.HuskyCISecurityCodeScanOutput.HighVulns = append(results.HuskyCIResults.CSharpResults.HuskyCISecurityCodeScanOutput.HighVulns, highVuln)
 		}
@@ -277,6 +308,8 @@ func (results *RunAllInfo) setVulns(securityTestScan SecTestScanInfo) {
 			results.HuskyCIResults.GenericResults.HuskyCIGitleaksOutput.MediumVulns = append(results.HuskyCIResults.GenericResults.HuskyCIGitleaksOutput.MediumVulns, mediumVuln)
 		case tfsec:
 			results.HuskyCIResults.HclResults.HuskyCITFSecOutput.MediumVulns = append(results.HuskyCIResults.HclResults.HuskyCITFSecOutput.MediumVulns, mediumVuln)
+		case trivyconfig:
+			results.HuskyCIResults.IaCResults.HuskyCITrivyConfigOutput.MediumVulns = append(results.HuskyCIResults.IaCResults.HuskyCITrivyConfigOutput.MediumVulns, mediumVuln)
 		case securitycodescan:
 			results.HuskyCIResults.CSharpResults.HuskyCISecurityCodeScanOutput.MediumVulns = append(results.HuskyCIResults.CSharpResults.HuskyCISecurityCodeScanOutput.MediumVulns, mediumVuln)
 		}
@@ -302,6 +335,8 @@ func (results *RunAllInfo) setVulns(securityTestScan SecTestScanInfo) {
 			results.HuskyCIResults.GenericResults.HuskyCIGitleaksOutput.LowVulns = append(results.HuskyCIResults.GenericResults.HuskyCIGitleaksOutput.LowVulns, lowVuln)
 		case tfsec:
 			results.HuskyCIResults.HclResults.HuskyCITFSecOutput.LowVulns = append(results.HuskyCIResults.HclResults.HuskyCITFSecOutput.LowVulns, lowVuln)
+		case trivyconfig:
+			results.HuskyCIResults.IaCResults.HuskyCITrivyConfigOutput.LowVulns = append(results.HuskyCIResults.IaCResults.HuskyCITrivyConfigOutput.LowVulns, lowVuln)
 		case securitycodescan:
 			results.HuskyCIResults.CSharpResults.HuskyCISecurityCodeScanOutput.LowVulns = append(results.HuskyCIResults.CSharpResults.HuskyCISecurityCodeScanOutput.LowVulns, lowVuln)
 		}
@@ -327,6 +362,8 @@ func (results *RunAllInfo) setVulns(securityTestScan SecTestScanInfo) {
 			results.HuskyCIResults.GenericResults.HuskyCIGitleaksOutput.NoSecVulns = append(results.HuskyCIResults.GenericResults.HuskyCIGitleaksOutput.NoSecVulns, noSec)
 		case tfsec:
 			results.HuskyCIResults.HclResults.HuskyCITFSecOutput.NoSecVulns = append(results.HuskyCIResults.HclResults.HuskyCITFSecOutput.NoSecVulns, noSec)
+		case trivyconfig:
+			results.HuskyCIResults.IaCResults.HuskyCITrivyConfigOutput.NoSecVulns = append(results.HuskyCIResults.IaCResults.HuskyCITrivyConfigOutput.NoSecVulns, noSec)
 		case securitycodescan:
 			results.HuskyCIResults.CSharpResults.HuskyCISecurityCodeScanOutput.NoSecVulns = append(results.HuskyCIResults.CSharpResults.HuskyCISecurityCodeScanOutput.NoSecVulns, noSec)
 		}
@@ -342,6 +379,7 @@ func (results *RunAllInfo) SetAnalysisError(err error) {
 
 func (results *RunAllInfo) setToAnalysis() {
 
+	results.setWarnings()
 	results.Status = "finished"
 	results.FinalResult = "passed"
 
@@ -372,6 +410,79 @@ func (results *RunAllInfo) setToAnalysis() {
 	}
 }
 
+// setWarnings lists in Warnings why the results are partial: the
+// securityTests that timed out, whose findings are missing.
+func (results *RunAllInfo) setWarnings() {
+	results.mutex.Lock()
+	defer results.mutex.Unlock()
+	for _, container := range results.Containers {
+		if container.CResult == "timeout" {
+			warning := fmt.Sprintf("%s timed out after %d seconds: its findings are missing", container.SecurityTest.Name, container.SecurityTest.TimeOutInSeconds)
+			results.Warnings = append(results.Warnings, warning)
+		}
+	}
+	if len(results.selected) > 0 {
+		selected := []string{}
+		for name := range results.selected {
+			selected = append(selected, name)
+		}
+		sort.Strings(selected)
+		warning := fmt.Sprintf("only %s were selected: the findings of the other securityTests are missing", strings.Join(selected, ", "))
+		results.Warnings = append(results.Warnings, warning)
+	}
+	sort.Strings(results.Warnings)
+}
+
+// SelectSecurityTests returns the securityTests selected by the request of an
+// analysis as SecurityTests, or nil if it selected none.
+func SelectSecurityTests(names []string) map[string]bool {
+	if len(names) == 0 {
+		return nil
+	}
+	selected := map[string]bool{}
+	for _, name := range names {
+		selected[name] = true
+	}
+	return selected
+}
+
+// selects returns true if the securityTest name runs in the analysis: every
+// one when its request selected none, and else the ones it selected along
+// with gitauthors, whose authors the findings are attributed to.
+func (scanInfo SecTestScanInfo) selects(name string) bool {
+	return len(scanInfo.SecurityTests) == 0 || scanInfo.SecurityTests[name] || strings.EqualFold(name, "gitauthors")
+}
+
+// PlannedSecurityTests returns the securityTests Start runs on codes: the
+// generic ones, but gitauthors for an upload, and the ones of each language,
+// among the selected ones if any were.
+func PlannedSecurityTests(codes []types.Code, upload bool, selected map[string]bool) ([]types.SecurityTest, error) {
+	scanInfo := SecTestScanInfo{SecurityTests: selected}
+	planned := []types.SecurityTest{}
+	genericTests, err := getAllDefaultSecurityTests("Generic", "")
+	if err != nil {
+		return nil, err
+	}
+	for _, genericTest := range genericTests {
+		if (strings.EqualFold(genericTest.Name, "gitauthors") && upload) || !scanInfo.selects(genericTest.Name) {
+			continue
+		}
+		planned = append(planned, genericTest)
+	}
+	for _, code := range codes {
+		languageTests, err := getAllDefaultSecurityTests("Language", code.Language)
+		if err != nil {
+			return nil, err
+		}
+		for _, languageTest := range languageTests {
+			if scanInfo.selects(languageTest.Name) {
+				planned = append(planned, languageTest)
+			}
+		}
+	}
+	return planned, nil
+}
+
 func getAllDefaultSecurityTests(typeOf, language string) ([]types.SecurityTest, error) {
 	securityTestQuery := map[string]interface{}{"type": typeOf, "default": true}
 	if language != "" {
@@ -6,9 +6,11 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/huskyci-org/huskyCI/api/fixversion"
 	"github.com/huskyci-org/huskyCI/api/log"
 	"github.com/huskyci-org/huskyCI/api/types"
 	"github.com/huskyci-org/huskyCI/api/util"
+	shared "github.com/huskyci-org/huskyCI/pkg/securitytest"
 )
 
 // SafetyOutput is the struct that holds issues, messages and errors found on a Safety scan.
@@ -54,19 +56,15 @@ func analyzeSafety(safetyScan *SecTestScanInfo) error {
 		return nil
 	}
 
-	// check if warning were found and handle its output
+	// the unpinned requirements are printed as warnings around the JSON
+	document, lines := shared.ExtractJSON(safetyScan.Container.COutput)
 	if warningFound {
 		safetyScan.WarningFound = true
-		outputJSON := util.GetLastLine(safetyScan.Container.COutput)
-		safetyOutput.OutputWarnings = util.GetAllLinesButLast(safetyScan.Container.COutput)
-		safetyScan.Container.COutput = outputJSON
+		safetyOutput.OutputWarnings = lines
 	}
 
-	cOutputSanitized := util.SanitizeSafetyJSON(safetyScan.Container.COutput)
-	safetyScan.Container.COutput = cOutputSanitized
-
 	// Unmarshall rawOutput into finalOutput, that is a Safety struct.
-	if err := json.Unmarshal([]byte(safetyScan.Container.COutput), &safetyOutput); err != nil {
+	if err := json.Unmarshal([]byte(document), &safetyOutput); err != nil {
 		log.Error("analyzeSafety", "SAFETY", 1018, safetyScan.Container.COutput, err)
 		safetyScan.ErrorFound = util.HandleScanError(safetyScan.Container.COutput, err)
 		safetyScan.prepareContainerAfterScan()
@@ -92,7 +90,7 @@ func (safetyScan *SecTestScanInfo) prepareSafetyVulns() {
 		safetyVuln.SecurityTool = "Safety"
 		safetyVuln.Severity = "low"
 		safetyVuln.Title = "No requirements.txt found."
-		safetyVuln.Details = "It looks like your project doesn't have a requirements.txt file. huskyCI was not able to run safety properly."
+		safetyVuln.Details = "It looks like your project doesn't have a requirements.txt, Pipfile.lock, poetry.lock or pyproject.toml file declaring pinned dependencies. huskyCI was not able to run safety properly."
 
 		huskyCIsafetyResults.LowVulns = append(huskyCIsafetyResults.LowVulns, safetyVuln)
 		safetyScan.Vulnerabilities = huskyCIsafetyResults
@@ -124,10 +122,13 @@ func (safetyScan *SecTestScanInfo) prepareSafetyVulns() {
 		safetyVuln.Language = "Python"
 		safetyVuln.SecurityTool = "Safety"
 		safetyVuln.Severity = "high"
+		safetyVuln.Rule = issue.ID
 		safetyVuln.Details = issue.Comment
 		safetyVuln.Code = issue.Dependency + " " + issue.Version
 		safetyVuln.Title = fmt.Sprintf("Vulnerable Dependency: %s (%s)", issue.Dependency, issue.Below)
 		safetyVuln.VunerableBelow = issue.Below
+		safetyVuln.Package = issue.Dependency
+		safetyVuln.FixVersion = fixversion.FromVulnerableRange(issue.Below, issue.Version)
 
 		huskyCIsafetyResults.HighVulns = append(huskyCIsafetyResults.HighVulns, safetyVuln)
 	}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/huskyci-org/huskyCI/api/util"
+	shared "github.com/huskyci-org/huskyCI/pkg/securitytest"
 
 	"github.com/huskyci-org/huskyCI/api/log"
 	"github.com/huskyci-org/huskyCI/api/types"
@@ -64,7 +65,8 @@ func analyzeSecurityCodeScan(securitycodescanScan *SecTestScanInfo) error {
 	}
 
 	// Unmarshall rawOutput into finalOutput, that is a SecurityCodeScanOutput struct.
-	if err := json.Unmarshal([]byte(securitycodescanScan.Container.COutput), &securitycodescanOutput); err != nil {
+	document, _ := shared.ExtractJSON(securitycodescanScan.Container.COutput)
+	if err := json.Unmarshal([]byte(document), &securitycodescanOutput); err != nil {
 		log.Error("analyzeSecurityCodeScan", "SecurityCodeScan", 1041, securitycodescanScan.Container.COutput, err)
 		securitycodescanScan.ErrorFound = util.HandleScanError(securitycodescanScan.Container.COutput, err)
 		return securitycodescanScan.ErrorFound
@@ -105,6 +107,7 @@ func (s *SecTestScanInfo) prepareSecurityCodeScanVulns() {
 		securityCodeScanVuln.Language = "C#"
 		securityCodeScanVuln.SecurityTool = "Security Code Scan"
 		securityCodeScanVuln.Severity = result.Level
+		securityCodeScanVuln.Rule = result.RuleID
 		securityCodeScanVuln.Title = result.RuleID
 		securityCodeScanVuln.Details = result.Message.Text
 		if len(result.Locations) > 0 {
@@ -1,18 +1,30 @@
 package securitytest
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/db"
+	"github.com/huskyci-org/huskyCI/api/debugtrace"
 	huskydocker "github.com/huskyci-org/huskyCI/api/dockers"
+	"github.com/huskyci-org/huskyCI/api/gitauth"
 	huskykube "github.com/huskyci-org/huskyCI/api/kubernetes"
 	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/signature"
+	"github.com/huskyci-org/huskyCI/api/storage"
+	"github.com/huskyci-org/huskyCI/api/tracing"
 	"github.com/huskyci-org/huskyCI/api/types"
 	"github.com/huskyci-org/huskyCI/api/util"
+	shared "github.com/huskyci-org/huskyCI/pkg/securitytest"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 var securityTestAnalyze = map[string]func(scanInfo *SecTestScanInfo) error{
@@ -20,6 +32,7 @@ var securityTestAnalyze = map[string]func(scanInfo *SecTestScanInfo) error{
 	"brakeman":         analyzeBrakeman,
 	"enry":             analyzeEnry,
 	"gitauthors":       analyzeGitAuthors,
+	"gitblame":         analyzeGitBlame,
 	"gosec":            analyzeGosec,
 	"npmaudit":         analyzeNpmaudit,
 	"yarnaudit":        analyzeYarnaudit,
@@ -28,14 +41,23 @@ var securityTestAnalyze = map[string]func(scanInfo *SecTestScanInfo) error{
 	"safety":           analyzeSafety,
 	"tfsec":            analyzeTFSec,
 	"trivy":            analyzeTrivy,
+	"trivyconfig":      analyzeTrivyConfig,
 	"securitycodescan": analyzeSecurityCodeScan,
 }
 
-// SecTestScanInfo holds all information of securityTest scan.
+// SecTestScanInfo holds all information of securityTest scan. UploadID is
+// set for the scans of an upload analysis, which scan the code uploaded under
+// it instead of cloning URL. The findings of IgnoreRules, the rules the policy
+// of the repository ignores, are kept with the NoSecVulns, and the ones of the
+// rules of SeverityOverrides are given the severity the policy sets. Only the
+// SecurityTests selected by the request of the analysis run, all of them when
+// it selected none.
 type SecTestScanInfo struct {
 	RID                          string
 	URL                          string
 	Branch                       string
+	Commit                       string
+	PullRequest                  *types.PullRequest
 	SecurityTestName             string
 	LanguageExclusions           map[string]bool
 	ErrorFound                   error
@@ -44,17 +66,23 @@ type SecTestScanInfo struct {
 	PackageNotFound              bool
 	YarnLockNotFound             bool
 	YarnErrorRunning             bool
-	GitleaksErrorRunning         bool
-	GitleaksTimeout              bool
 	SecurityCodeScanErrorRunning bool
 	// SecurityCodeScanErrorRestore bool
 	CommitAuthorsNotFound bool
 	CommitAuthors         GitAuthorsOutput
+	BlameLocations        []string
+	Blames                GitBlameOutput
 	Codes                 []types.Code
 	Container             types.Container
 	FinalOutput           interface{}
 	Vulnerabilities       types.HuskyCISecurityTestOutput
 	DockerHost            string
+	UploadID              string
+	IgnoreRules           map[string]bool
+	SeverityOverrides     map[string]string
+	SecurityTests         map[string]bool
+	Ctx                   context.Context
+	gitCredential         *types.GitCredential
 }
 
 // New creates a new huskyCI scan based given RID, URL, Branch and a securityTest name and returns an error.
@@ -76,22 +104,46 @@ func (scanInfo *SecTestScanInfo) setSecurityTestContainer(securityTestName strin
 		log.Error("createSecurityTestContainer", "SECURITYTEST", 2012, err)
 		return err
 	}
+	securityTest.Image = apiContext.APIConfiguration.OfflineConfig.MirrorImage(securityTest.Image)
 	scanInfo.Container.StartedAt = time.Now()
 	scanInfo.Container.SecurityTest = securityTest
 	return nil
 }
 
 // Start starts a new huskyCI scan!
-func (scanInfo *SecTestScanInfo) Start() error {
+func (scanInfo *SecTestScanInfo) Start() (err error) {
+	ctx, span := tracing.StartSpan(scanInfo.Ctx, "securitytest."+scanInfo.SecurityTestName,
+		attribute.String("huskyci.rid", scanInfo.RID),
+		attribute.String("huskyci.securitytest", scanInfo.SecurityTestName))
+	defer func() { tracing.EndSpan(span, err) }()
+	scanInfo.Ctx = ctx
+
+	// the analysis was cancelled before this securityTest got to run
+	if err := ctx.Err(); err != nil {
+		scanInfo.ErrorFound = err
+		return err
+	}
+
+	if err := scanInfo.verifyImage(); err != nil {
+		scanInfo.ErrorFound = err
+		scanInfo.prepareContainerAfterScan()
+		return scanInfo.ErrorFound
+	}
+	scanInfo.loadGitCredential()
+
 	if os.Getenv("HUSKYCI_INFRASTRUCTURE_USE") == "kubernetes" {
-		if err := scanInfo.kubeRun(scanInfo.Container.SecurityTest.TimeOutInSeconds); err != nil {
+		err := scanInfo.kubeRun(scanInfo.Container.SecurityTest.TimeOutInSeconds)
+		scanInfo.storeRawOutput()
+		if err != nil {
 			scanInfo.ErrorFound = err
 			scanInfo.prepareContainerAfterScan()
 			return scanInfo.ErrorFound
 		}
 	}
 	if os.Getenv("HUSKYCI_INFRASTRUCTURE_USE") == "docker" {
-		if err := scanInfo.dockerRun(scanInfo.Container.SecurityTest.TimeOutInSeconds); err != nil {
+		err := scanInfo.dockerRun(scanInfo.Container.SecurityTest.TimeOutInSeconds)
+		scanInfo.storeRawOutput()
+		if err != nil {
 			scanInfo.ErrorFound = err
 			scanInfo.prepareContainerAfterScan()
 			return scanInfo.ErrorFound
@@ -104,65 +156,343 @@ func (scanInfo *SecTestScanInfo) Start() error {
 		return scanInfo.ErrorFound
 	}
 
+	scanInfo.overrideSeverities()
+	scanInfo.ignoreByPolicy()
 	scanInfo.prepareContainerAfterScan()
 	return nil
 }
 
-func (scanInfo *SecTestScanInfo) dockerRun(timeOutInSeconds int) error {
+func (scanInfo *SecTestScanInfo) dockerRun(timeOutInSeconds int) (err error) {
+	_, span := tracing.StartSpan(scanInfo.Ctx, "docker.run",
+		attribute.String("docker.host", scanInfo.DockerHost),
+		attribute.String("docker.image", signature.ImageReference(scanInfo.Container.SecurityTest)))
+	defer func() { tracing.EndSpan(span, err) }()
+
 	image := scanInfo.Container.SecurityTest.Image
-	imageTag := scanInfo.Container.SecurityTest.ImageTag
-	cmd := util.HandleCmd(scanInfo.URL, scanInfo.Branch, scanInfo.Container.SecurityTest.Cmd)
+	imageTag := signature.ImageVersion(scanInfo.Container.SecurityTest)
+	cmd := scanInfo.handleCmd(scanInfo.Container.SecurityTest.Cmd)
+	cmd = scanInfo.handleBlameLocations(cmd)
 	cmd = util.HandleGitURLSubstitution(cmd)
-	finalCMD := util.HandlePrivateSSHKey(cmd)
+	finalCMD := scanInfo.handlePrivateSSHKey(cmd)
 	
-	// Check if this is a file:// URL and get the volume path
-	var volumePath string
-	if util.IsFileURL(scanInfo.URL) {
-		RID := util.ExtractRIDFromFileURL(scanInfo.URL)
-		if RID != "" {
-			volumePath = util.GetExtractedDir(RID)
-			log.Info("dockerRun", "SECURITYTEST", 16, fmt.Sprintf("File:// URL detected, RID: %s, Volume path: %s", RID, volumePath))
-			log.Info("dockerRun", "SECURITYTEST", 16, fmt.Sprintf("Command after HandleCmd: %s", cmd))
-		}
+	// For upload analyses, mount the volume holding the code of this analysis only,
+	// or copy the code into the container
+	var volumePath, workspace string
+	if scanInfo.isUpload() && apiContext.APIConfiguration.DockerHostsConfig.CopiesCode() {
+		workspace = util.GetExtractedDir(scanInfo.UploadID)
+		log.Info("dockerRun", "SECURITYTEST", 16, fmt.Sprintf("Upload analysis, RID: %s, Workspace: %s", scanInfo.RID, workspace))
+	} else if scanInfo.isUpload() {
+		volumePath = huskydocker.AnalysisVolume(scanInfo.RID)
+		log.Info("dockerRun", "SECURITYTEST", 16, fmt.Sprintf("Upload analysis, RID: %s, Volume: %s", scanInfo.RID, volumePath))
 	}
 	
-	CID, cOutput, err := huskydocker.DockerRunWithVolume(image, imageTag, finalCMD, scanInfo.DockerHost, volumePath, timeOutInSeconds)
+	options, err := huskydocker.NetworkOptions(scanInfo.networkMode())
 	if err != nil {
 		return err
 	}
+	env, mounts := scanInfo.offlineOptions()
+	options.RID = scanInfo.RID
+	options.Workspace = workspace
+	options.MaxOutputSize = scanInfo.maxOutputSize()
+	options.Env = append(options.Env, env...)
+	options.Mounts = append(mounts, scanInfo.gitCacheMounts()...)
+	scanInfo.traceCommand(cmd)
+	var CID, cOutput string
+	if scanInfo.Container.SecurityTest.WarmPool && !scanInfo.isUpload() {
+		// the code is cloned by the job, so the container of another job can run it
+		CID, cOutput, err = huskydocker.DockerRunWarm(image, imageTag, finalCMD, scanInfo.DockerHost, timeOutInSeconds, options)
+	} else {
+		CID, cOutput, err = huskydocker.DockerRunWithVolume(image, imageTag, finalCMD, scanInfo.DockerHost, volumePath, timeOutInSeconds, options)
+	}
+	// a container that timed out still has a CID and what it printed so far
 	scanInfo.Container.CID = CID
-	scanInfo.Container.COutput = cOutput
+	scanInfo.Container.COutput = scanInfo.redactGitCredential(cOutput)
+	if err != nil {
+		return err
+	}
+	scanInfo.traceOutput()
 	return nil
 }
 
-func (scanInfo *SecTestScanInfo) kubeRun(timeOutInSeconds int) error {
+func (scanInfo *SecTestScanInfo) kubeRun(timeOutInSeconds int) (err error) {
+	_, span := tracing.StartSpan(scanInfo.Ctx, "kubernetes.run",
+		attribute.String("kubernetes.image", signature.ImageReference(scanInfo.Container.SecurityTest)))
+	defer func() { tracing.EndSpan(span, err) }()
+
 	image := scanInfo.Container.SecurityTest.Image
-	imageTag := scanInfo.Container.SecurityTest.ImageTag
-	cmd := util.HandleCmd(scanInfo.URL, scanInfo.Branch, scanInfo.Container.SecurityTest.Cmd)
+	imageTag := signature.ImageVersion(scanInfo.Container.SecurityTest)
+	cmd := scanInfo.handleCmd(scanInfo.Container.SecurityTest.Cmd)
+	cmd = scanInfo.handleBlameLocations(cmd)
 	cmd = util.HandleGitURLSubstitution(cmd)
-	finalCMD := util.HandlePrivateSSHKey(cmd)
+	finalCMD := scanInfo.handlePrivateSSHKey(cmd)
 	
-	// Check if this is a file:// URL and get the volume path
+	// For upload analyses, mount the directory the upload was extracted to
 	var volumePath string
-	if util.IsFileURL(scanInfo.URL) {
-		RID := util.ExtractRIDFromFileURL(scanInfo.URL)
-		if RID != "" {
-			volumePath = util.GetExtractedDir(RID)
-		}
+	if scanInfo.isUpload() {
+		volumePath = util.GetExtractedDir(scanInfo.UploadID)
 	}
 	
 	podSchedulingTimeoutInSeconds := apiContext.APIConfiguration.KubernetesConfig.PodSchedulingTimeout
-	CID, cOutput, err := huskykube.KubeRunWithVolume(image, imageTag, finalCMD, scanInfo.SecurityTestName, scanInfo.RID, volumePath, podSchedulingTimeoutInSeconds, timeOutInSeconds)
+	env, mounts := scanInfo.offlineOptions()
+	options := huskykube.PodOptions{
+		NetworkMode:   scanInfo.networkMode(),
+		Env:           env,
+		Mounts:        append(mounts, scanInfo.gitCacheMounts()...),
+		MaxOutputSize: scanInfo.maxOutputSize(),
+	}
+	scanInfo.traceCommand(cmd)
+	CID, cOutput, err := huskykube.KubeRunWithVolume(image, imageTag, finalCMD, scanInfo.SecurityTestName, scanInfo.RID, volumePath, options, podSchedulingTimeoutInSeconds, timeOutInSeconds)
+	scanInfo.Container.CID = CID
+	scanInfo.Container.COutput = scanInfo.redactGitCredential(cOutput)
 	if err != nil {
 		return err
 	}
-	scanInfo.Container.CID = CID
-	scanInfo.Container.COutput = cOutput
+	scanInfo.traceOutput()
+	return nil
+}
+
+// traceCommand records in the debug trace the command of the securityTest
+// container, before the private SSH key is written into it and without the
+// repository secret.
+func (scanInfo *SecTestScanInfo) traceCommand(cmd string) {
+	debugtrace.Record(debugtrace.Verbose, "securitytest", "command", scanInfo.RID, "securityTest", scanInfo.SecurityTestName, "cmd", scanInfo.redactGitCredential(cmd))
+}
+
+// traceOutput records in the debug trace the output of the securityTest
+// container.
+func (scanInfo *SecTestScanInfo) traceOutput() {
+	debugtrace.Record(debugtrace.Verbose, "securitytest", "output", scanInfo.RID, "securityTest", scanInfo.SecurityTestName, "cid", scanInfo.Container.CID, "output", scanInfo.Container.COutput)
+}
+
+// loadGitCredential looks up the credential stored for the repository being
+// analyzed. Without one, the global HUSKYCI_API_GIT_PRIVATE_SSH_KEY is used.
+func (scanInfo *SecTestScanInfo) loadGitCredential() {
+	if scanInfo.isUpload() {
+		return
+	}
+	credential, found, err := gitauth.Find(scanInfo.URL)
+	if err != nil {
+		log.Error("loadGitCredential", "SECURITYTEST", 1047, scanInfo.URL, err)
+		return
+	}
+	if found {
+		scanInfo.gitCredential = &credential
+	}
+}
+
+// isUpload returns true if the scan is part of an upload analysis.
+func (scanInfo *SecTestScanInfo) isUpload() bool {
+	return scanInfo.UploadID != ""
+}
+
+// handleCmd replaces the placeholders of cmd with the repository to clone, at
+// its commit when one is set and merged for a pull request, or with the
+// uploaded code for an upload analysis.
+func (scanInfo *SecTestScanInfo) handleCmd(cmd string) string {
+	cmd = handleJavaBuildTimeout(cmd)
+	if scanInfo.isUpload() {
+		return util.HandleUploadCmd(scanInfo.Branch, cmd)
+	}
+	cmd = util.HandleCheckout(scanInfo.Commit, scanInfo.handleCloneOptions(cmd))
+	return util.HandleCmd(scanInfo.cloneURL(), scanInfo.Branch, scanInfo.handlePullRequest(cmd))
+}
+
+// handlePullRequest makes cmd scan the merge of the pull request analyzed, if
+// any, and replaces %PR_NUMBER%, %PR_SOURCE_BRANCH% and %PR_TARGET_BRANCH%
+// with what describes it, or with nothing outside of a pull request.
+func (scanInfo *SecTestScanInfo) handlePullRequest(cmd string) string {
+	pullRequest := types.PullRequest{}
+	if scanInfo.PullRequest != nil {
+		pullRequest = *scanInfo.PullRequest
+		cmd = util.HandleMerge(pullRequest.MergeRef, pullRequest.TargetBranch, cmd)
+	}
+	number := ""
+	if pullRequest.Number > 0 {
+		number = strconv.Itoa(pullRequest.Number)
+	}
+	return strings.NewReplacer(
+		"%PR_NUMBER%", number,
+		"%PR_SOURCE_BRANCH%", pullRequest.SourceBranch,
+		"%PR_TARGET_BRANCH%", pullRequest.TargetBranch,
+	).Replace(cmd)
+}
+
+// cloneURL returns the URL the securityTest container clones, carrying the
+// repository token when one is stored.
+func (scanInfo *SecTestScanInfo) cloneURL() string {
+	if scanInfo.gitCredential != nil && scanInfo.gitCredential.Type == types.GitCredentialToken {
+		return util.AuthenticatedURL(scanInfo.URL, scanInfo.gitCredential.Username, scanInfo.gitCredential.Secret)
+	}
+	return scanInfo.URL
+}
+
+// handlePrivateSSHKey replaces %GIT_PRIVATE_SSH_KEY% in cmd with the repository
+// deploy key if one is stored, or with the global private SSH key otherwise.
+func (scanInfo *SecTestScanInfo) handlePrivateSSHKey(cmd string) string {
+	if scanInfo.gitCredential != nil && scanInfo.gitCredential.Type == types.GitCredentialSSH {
+		return util.HandleDeployKey(cmd, scanInfo.gitCredential.Secret)
+	}
+	return util.HandlePrivateSSHKey(cmd)
+}
+
+// redactGitCredential removes the repository secret from a container output,
+// as git may print the clone URL, where it is escaped, when it fails.
+func (scanInfo *SecTestScanInfo) redactGitCredential(output string) string {
+	if scanInfo.gitCredential == nil {
+		return output
+	}
+	return util.RedactCredential(output, scanInfo.gitCredential.Secret)
+}
+
+// handleCloneOptions replaces %GIT_CLONE_OPTIONS% in cmd with the configured
+// clone depth and git mirror cache. Uploaded code is never cloned.
+func (scanInfo *SecTestScanInfo) handleCloneOptions(cmd string) string {
+	cloneConfig := apiContext.APIConfiguration.GitCloneConfig
+	if cloneConfig == nil || scanInfo.isUpload() {
+		return cmd
+	}
+	return util.HandleCloneOptions(cmd, cloneConfig.Depth, scanInfo.gitMirrorPath())
+}
+
+// handleJavaBuildTimeout replaces %JAVA_BUILD_TIMEOUT% in cmd with the
+// seconds the build of a Java project may take before it is stopped.
+func handleJavaBuildTimeout(cmd string) string {
+	timeout := apiContext.APIConfiguration.JavaBuildTimeout
+	return strings.Replace(cmd, "%JAVA_BUILD_TIMEOUT%", strconv.Itoa(int(timeout.Seconds())), -1)
+}
+
+// gitMirrorPath returns where the mirror of the repository being analyzed is
+// mounted inside securityTest containers, or an empty string without a cache.
+func (scanInfo *SecTestScanInfo) gitMirrorPath() string {
+	name := scanInfo.gitMirrorName()
+	if name == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/%s.git", apiContext.GitCachePath, name)
+}
+
+// gitMirrorName returns the name of the mirror of the repository being
+// analyzed in the git cache, or an empty string without a cache.
+func (scanInfo *SecTestScanInfo) gitMirrorName() string {
+	cloneConfig := apiContext.APIConfiguration.GitCloneConfig
+	if cloneConfig == nil || cloneConfig.CacheVolume == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(gitauth.NormalizeURL(scanInfo.URL)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// gitCacheMounts returns the mount of the mirror of the repository being
+// analyzed, its own directory of the git cache when it is a host directory or
+// else its own volume. Only RefreshGitMirror writes to it, so that the
+// securityTest containers can neither read nor alter the mirrors of other
+// repositories.
+func (scanInfo *SecTestScanInfo) gitCacheMounts() []types.VolumeMount {
+	mirrorPath := scanInfo.gitMirrorPath()
+	if mirrorPath == "" || scanInfo.isUpload() {
+		return nil
+	}
+	cacheVolume := apiContext.APIConfiguration.GitCloneConfig.CacheVolume
+	source := cacheVolume + "-" + scanInfo.gitMirrorName()
+	if strings.HasPrefix(cacheVolume, "/") {
+		source = strings.TrimSuffix(cacheVolume, "/") + "/" + scanInfo.gitMirrorName() + ".git"
+	}
+	return []types.VolumeMount{{HostPath: source, ContainerPath: mirrorPath, Writable: scanInfo.SecurityTestName == gitMirror}}
+}
+
+// maxOutputSize returns the size in bytes the output of the securityTest
+// container is cut at: the maxOutputSizeKB of the securityTest or else
+// HUSKYCI_CONTAINER_MAX_OUTPUT_SIZE_MB.
+func (scanInfo *SecTestScanInfo) maxOutputSize() int64 {
+	if maxOutputSizeKB := scanInfo.Container.SecurityTest.MaxOutputSizeKB; maxOutputSizeKB > 0 {
+		return int64(maxOutputSizeKB) << 10
+	}
+	return apiContext.APIConfiguration.MaxOutputSize
+}
+
+// networkMode returns the network mode the securityTest container runs with. A
+// securityTest configured without network still has to clone remote
+// repositories, so it is relaxed to egress-proxy unless the code was uploaded.
+func (scanInfo *SecTestScanInfo) networkMode() string {
+	networkMode := scanInfo.Container.SecurityTest.NetworkMode
+	if networkMode == types.NetworkModeNone && !scanInfo.isUpload() {
+		return types.NetworkModeEgressProxy
+	}
+	return networkMode
+}
+
+// offlineOptions returns the environment variables and the local advisory
+// database mount the securityTest container needs to run in air-gapped mode.
+func (scanInfo *SecTestScanInfo) offlineOptions() ([]string, []types.VolumeMount) {
+	offlineConfig := apiContext.APIConfiguration.OfflineConfig
+	if offlineConfig == nil {
+		return nil, nil
+	}
+	env := []string{}
+	if offlineConfig.Enabled {
+		env = append(env, "HUSKYCI_OFFLINE=true")
+	}
+	if offlineConfig.NpmRegistry != "" && (scanInfo.SecurityTestName == "npmaudit" || scanInfo.SecurityTestName == "yarnaudit") {
+		env = append(env, "npm_config_registry="+offlineConfig.NpmRegistry, "YARN_REGISTRY="+offlineConfig.NpmRegistry)
+	}
+	var mounts []types.VolumeMount
+	if hostPath, ok := offlineConfig.AdvisoryDBs[scanInfo.SecurityTestName]; ok {
+		mounts = append(mounts, types.VolumeMount{HostPath: hostPath, ContainerPath: apiContext.AdvisoryDBPath})
+	}
+	return env, mounts
+}
+
+// verifyImage checks the securityTest image signature before it is run. A
+// failed verification only stops the scan when HUSKYCI_IMAGE_SIGNATURE_ENFORCE
+// is enabled; otherwise it is logged as a warning.
+func (scanInfo *SecTestScanInfo) verifyImage() error {
+	signatureConfig := apiContext.APIConfiguration.SignatureConfig
+	err := signature.Verify(signatureConfig, scanInfo.Container.SecurityTest)
+	if err == nil {
+		return nil
+	}
+	if signatureConfig.Enforce {
+		log.Error("verifyImage", "SECURITYTEST", 1046, scanInfo.SecurityTestName, err)
+		return err
+	}
+	log.Warning("verifyImage", "SECURITYTEST", 115, scanInfo.SecurityTestName, err)
 	return nil
 }
 
+// storeRawOutput keeps what the container printed, as it ran and before it is
+// cut for the analysis: compressed in the database, up to
+// HUSKYCI_CONTAINER_RAW_OUTPUT_MAX_SIZE_KB, so that users can read it with GET
+// /api/v2/analysis/:id/containers/:tool/output, and up to its size limit in
+// the object storage when HUSKYCI_STORAGE_RAW_OUTPUTS is enabled.
+func (scanInfo *SecTestScanInfo) storeRawOutput() {
+	if scanInfo.Container.COutput == "" {
+		return
+	}
+	if limit := apiContext.APIConfiguration.RawOutputMaxSize; limit > 0 {
+		containerOutput, err := db.CompressOutput(scanInfo.RID, scanInfo.SecurityTestName, scanInfo.Container.COutput, limit)
+		if err == nil {
+			outputQuery := map[string]interface{}{"RID": scanInfo.RID, "securityTest": scanInfo.SecurityTestName}
+			err = apiContext.APIConfiguration.DBInstance.UpsertOneDBContainerOutput(outputQuery, containerOutput)
+		}
+		if err != nil {
+			log.Error("storeRawOutput", "SECURITYTEST", 2019, scanInfo.RID, scanInfo.SecurityTestName, err)
+		}
+	}
+
+	objectStorage := apiContext.APIConfiguration.Storage
+	if objectStorage == nil || !apiContext.APIConfiguration.StorageConfig.RawOutputs {
+		return
+	}
+	key := storage.OutputKey(scanInfo.RID, scanInfo.SecurityTestName)
+	output := strings.NewReader(scanInfo.Container.COutput)
+	if err := objectStorage.Put(key, output, output.Size()); err != nil {
+		log.Error("storeRawOutput", "SECURITYTEST", 1042, key, err)
+	}
+}
+
 func (scanInfo *SecTestScanInfo) analyze() error {
-	errorCloning := strings.Contains(scanInfo.Container.COutput, "ERROR_CLONING")
+	scanInfo.setToolConfigs()
+	errorCloning := strings.Contains(scanInfo.Container.COutput, shared.ErrorCloning)
 	if errorCloning {
 		errorMsg := errors.New("error cloning")
 		log.Error("analyze", "SECURITYTEST", 1031, scanInfo.URL, scanInfo.Branch, errorMsg)
@@ -173,6 +503,24 @@ func (scanInfo *SecTestScanInfo) analyze() error {
 	return securityTestAnalyze(scanInfo)
 }
 
+// setToolConfigs records in the container the configuration files of the
+// repository the securityTest applied, and removes their line from its output.
+func (scanInfo *SecTestScanInfo) setToolConfigs() {
+	configs, output := shared.ToolConfigs(scanInfo.Container.COutput)
+	if configs == nil {
+		return
+	}
+	scanInfo.Container.ToolConfigs = configs
+	scanInfo.Container.COutput = output
+}
+
+// TimedOut returns true if the container of the securityTest ran for longer
+// than its timeout. Its findings are then missing, but the other securityTests
+// of the analysis still run.
+func (scanInfo *SecTestScanInfo) TimedOut() bool {
+	return errors.Is(scanInfo.ErrorFound, huskydocker.ErrTimeout) || errors.Is(scanInfo.ErrorFound, huskykube.ErrTimeout)
+}
+
 func (scanInfo *SecTestScanInfo) prepareContainerAfterScan() {
 
 	cOutputMaxSize := 1000000
@@ -186,6 +534,13 @@ func (scanInfo *SecTestScanInfo) prepareContainerAfterScan() {
 		scanInfo.Container.COutput = "Container Output is too large."
 	}
 
+	if scanInfo.TimedOut() {
+		scanInfo.Container.CInfo = fmt.Sprintf("Timed out after %d seconds.", scanInfo.Container.SecurityTest.TimeOutInSeconds)
+		scanInfo.Container.CResult = "timeout"
+		scanInfo.Container.CStatus = "timeout"
+		return
+	}
+
 	if scanInfo.ErrorFound != nil {
 		scanInfo.Container.CInfo = "Error found running container"
 		scanInfo.Container.CResult = "error"
@@ -232,3 +587,40 @@ func (scanInfo *SecTestScanInfo) prepareContainerAfterScan() {
 	}
 
 }
+
+// toHuskyCIOutput returns the findings a shared parser returned as they are
+// stored in the analysis.
+func toHuskyCIOutput(output shared.Output) types.HuskyCISecurityTestOutput {
+	return types.HuskyCISecurityTestOutput{
+		NoSecVulns:  toHuskyCIVulnerabilities(output.NoSecVulns),
+		LowVulns:    toHuskyCIVulnerabilities(output.LowVulns),
+		MediumVulns: toHuskyCIVulnerabilities(output.MediumVulns),
+		HighVulns:   toHuskyCIVulnerabilities(output.HighVulns),
+	}
+}
+
+func toHuskyCIVulnerabilities(vulns []shared.Vulnerability) []types.HuskyCIVulnerability {
+	if vulns == nil {
+		return nil
+	}
+	huskyCIVulns := make([]types.HuskyCIVulnerability, 0, len(vulns))
+	for _, vuln := range vulns {
+		huskyCIVulns = append(huskyCIVulns, types.HuskyCIVulnerability{
+			Language:     vuln.Language,
+			SecurityTool: vuln.SecurityTool,
+			Rule:         vuln.Rule,
+			Severity:     vuln.Severity,
+			Confidence:   vuln.Confidence,
+			File:         vuln.File,
+			Line:         vuln.Line,
+			Code:         vuln.Code,
+			Details:      vuln.Details,
+			Type:         vuln.Type,
+			Title:        vuln.Title,
+			Commit:       vuln.Commit,
+			Author:       vuln.Author,
+			AuthorEmail:  vuln.AuthorEmail,
+		})
+	}
+	return huskyCIVulns
+}
@@ -64,46 +64,51 @@ type SourceLine struct {
 	SourcePath    string   `xml:"sourcepath,attr"`
 }
 
+// spotBugsBuildToolPrefix starts the line the spotbugs container prints first,
+// naming the build tool it detected: gradle, gradle-kotlin, maven, prebuilt or
+// unsupported.
+const spotBugsBuildToolPrefix = "HUSKYCI_JAVA_BUILD_TOOL="
+
+// spotBugsBuildLogLines is how many lines of the build log are kept in the
+// details of a build error.
+const spotBugsBuildLogLines = 20
+
+// spotBugsErrors are the errors the spotbugs container reports instead of its
+// XML output, followed by the end of the build log if there is one.
+var spotBugsErrors = []struct {
+	marker  string
+	message string
+}{
+	{"ERROR_UNSUPPORTED_JAVA_PROJECT", "unsupported java project: no pom.xml, build.gradle, build.gradle.kts or compiled classes found"},
+	{"ERROR_BUILD_TIMEOUT", "the build did not finish within the build timeout"},
+	{"ERROR_RUNNING_GRADLE_BUILD", "error running gradle"},
+	{"ERROR_RUNNING_MAVEN_BUILD", "error running maven"},
+	{"ERROR_NO_CLASSES_FOUND", "the build produced no classes to scan"},
+}
+
 func analyzeSpotBugs(spotbugsScan *SecTestScanInfo) error {
 
 	spotBugsOutput := SpotBugsOutput{}
 	spotbugsScan.FinalOutput = spotBugsOutput
 
-	// check if GRADLE failed running
-	errorGradleFound := strings.Contains(spotbugsScan.Container.COutput, "ERROR_RUNNING_GRADLE_BUILD")
-	if errorGradleFound {
-		spotbugsScan.ErrorFound = errors.New("error running gradle")
-		spotbugsScan.prepareSpotBugsVulns()
-		spotbugsScan.prepareContainerAfterScan()
-		return nil
-	}
-
-	// check if Maven failed running
-	errorMavenFound := strings.Contains(spotbugsScan.Container.COutput, "ERROR_RUNNING_MAVEN_BUILD")
-	if errorMavenFound {
-		spotbugsScan.ErrorFound = errors.New("error running maven")
-		spotbugsScan.prepareSpotBugsVulns()
-		spotbugsScan.prepareContainerAfterScan()
-		return nil
-	}
+	buildTool, cOutput := splitSpotBugsBuildTool(spotbugsScan.Container.COutput)
 
-	// check if unsuported java project was found
-	errorUnsuported := strings.Contains(spotbugsScan.Container.COutput, "ERROR_UNSUPPORTED_JAVA_PROJECT")
-	if errorUnsuported {
-		spotbugsScan.ErrorFound = errors.New("error unsuported java project")
+	// check if the project could not be built or scanned
+	if err := spotBugsError(buildTool, cOutput); err != nil {
+		spotbugsScan.ErrorFound = err
 		spotbugsScan.prepareSpotBugsVulns()
 		spotbugsScan.prepareContainerAfterScan()
 		return nil
 	}
 
 	// nil cOutput states that no Issues were found.
-	if spotbugsScan.Container.COutput == "" {
+	if strings.TrimSpace(cOutput) == "" {
 		spotbugsScan.prepareContainerAfterScan()
 		return nil
 	}
 
 	// Unmarshall rawOutput into finalOutput, that is a SpotBugsOutput struct.
-	spotBugsOutput, err := parseXMLtoJSON([]byte(spotbugsScan.Container.COutput))
+	spotBugsOutput, err := parseXMLtoJSON([]byte(cOutput))
 	if err != nil {
 		log.Error("analyzeSpotBugs", "SPOTBUGS", 1039, spotbugsScan.Container.COutput, err)
 		spotbugsScan.ErrorFound = util.HandleScanError(spotbugsScan.Container.COutput, err)
@@ -119,6 +124,43 @@ func analyzeSpotBugs(spotbugsScan *SecTestScanInfo) error {
 	return nil
 }
 
+// splitSpotBugsBuildTool returns the build tool the spotbugs container
+// detected, if it printed one, and the rest of its output.
+func splitSpotBugsBuildTool(cOutput string) (string, string) {
+	if !strings.HasPrefix(cOutput, spotBugsBuildToolPrefix) {
+		return "", cOutput
+	}
+	line, rest := cOutput, ""
+	if i := strings.Index(cOutput, "\n"); i >= 0 {
+		line, rest = cOutput[:i], cOutput[i+1:]
+	}
+	return strings.TrimSpace(strings.TrimPrefix(line, spotBugsBuildToolPrefix)), rest
+}
+
+// spotBugsError returns the error the spotbugs container reported in cOutput,
+// with the build tool and the end of the build log, or nil if it reported none.
+func spotBugsError(buildTool, cOutput string) error {
+	for _, spotBugsErr := range spotBugsErrors {
+		i := strings.Index(cOutput, spotBugsErr.marker)
+		if i < 0 {
+			continue
+		}
+		message := spotBugsErr.message
+		if buildTool != "" {
+			message = fmt.Sprintf("%s (build tool: %s)", message, buildTool)
+		}
+		buildLog := strings.Split(strings.TrimSpace(cOutput[i+len(spotBugsErr.marker):]), "\n")
+		if len(buildLog) > spotBugsBuildLogLines {
+			buildLog = buildLog[len(buildLog)-spotBugsBuildLogLines:]
+		}
+		if strings.Join(buildLog, "") != "" {
+			message = fmt.Sprintf("%s:\n%s", message, strings.Join(buildLog, "\n"))
+		}
+		return errors.New(message)
+	}
+	return nil
+}
+
 func parseXMLtoJSON(byteValue []byte) (SpotBugsOutput, error) {
 	var bugs SpotBugsOutput
 	if err := xml.Unmarshal(byteValue, &bugs); err != nil {
@@ -8,6 +8,7 @@ import (
 	"github.com/huskyci-org/huskyCI/api/log"
 	"github.com/huskyci-org/huskyCI/api/types"
 	"github.com/huskyci-org/huskyCI/api/util"
+	shared "github.com/huskyci-org/huskyCI/pkg/securitytest"
 )
 
 // TFSecOutput is the struct that holds all data from TFSec output.
@@ -37,7 +38,8 @@ func analyzeTFSec(tfsecScan *SecTestScanInfo) error {
 	tfsecOutput := TFSecOutput{}
 
 	// Unmarshall rawOutput into finalOutput, that is a TFSec struct.
-	if err := json.Unmarshal([]byte(tfsecScan.Container.COutput), &tfsecOutput); err != nil {
+	document, _ := shared.ExtractJSON(tfsecScan.Container.COutput)
+	if err := json.Unmarshal([]byte(document), &tfsecOutput); err != nil {
 		log.Error("analyzeTFSec", "TFSEC", 1040, tfsecScan.Container.COutput, err)
 		tfsecScan.ErrorFound = util.HandleScanError(tfsecScan.Container.COutput, err)
 		return tfsecScan.ErrorFound
@@ -66,6 +68,7 @@ func (tfsecScan *SecTestScanInfo) prepareTFSecVulns() {
 		tfsecVuln.Language = "HCL"
 		tfsecVuln.SecurityTool = "TFSec"
 		tfsecVuln.Severity = result.Severity
+		tfsecVuln.Rule = result.RuleID
 		tfsecVuln.Title = result.Description
 		tfsecVuln.Details = result.RuleID + " @ [" + result.Description + "]"
 		startLine := strconv.Itoa(result.Location.StartLine)
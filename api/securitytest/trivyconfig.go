@@ -0,0 +1,21 @@
+package securitytest
+
+import (
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/util"
+	shared "github.com/huskyci-org/huskyCI/pkg/securitytest"
+)
+
+func analyzeTrivyConfig(trivyConfigScan *SecTestScanInfo) error {
+
+	trivyConfigOutput, err := shared.ParseTrivyConfig(trivyConfigScan.Container.COutput)
+	if err != nil {
+		log.Error("analyzeTrivyConfig", "TRIVYCONFIG", 1064, trivyConfigScan.Container.COutput, err)
+		trivyConfigScan.ErrorFound = util.HandleScanError(trivyConfigScan.Container.COutput, err)
+		return trivyConfigScan.ErrorFound
+	}
+
+	trivyConfigScan.Vulnerabilities = toHuskyCIOutput(trivyConfigOutput)
+	trivyConfigScan.prepareContainerAfterScan()
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/huskyci-org/huskyCI/api/fixversion"
 	"github.com/huskyci-org/huskyCI/api/log"
 	"github.com/huskyci-org/huskyCI/api/types"
 	"github.com/huskyci-org/huskyCI/api/util"
@@ -24,14 +25,17 @@ type YarnIssue struct {
 	ID                 int           `json:"id"`
 	ModuleName         string        `json:"module_name"`
 	VulnerableVersions string        `json:"vulnerable_versions"`
+	PatchedVersions    string        `json:"patched_versions"`
 	Severity           string        `json:"severity"`
 	Overview           string        `json:"overview"`
 	Title              string        `json:"title"`
 }
 
-// YarnFinding holds the version of a given yarn security issue found
+// YarnFinding holds the version of a given yarn security issue found, and
+// the paths of dependencies leading to it.
 type YarnFinding struct {
-	Version string `json:"version"`
+	Version string   `json:"version"`
+	Paths   []string `json:"paths"`
 }
 
 // YarnMetadata is the struct that holds vulnerabilities summary
@@ -48,10 +52,17 @@ type YarnVulnerabilitiesSummary struct {
 	Critical int `json:"critical"`
 }
 
+// YarnAuditWorkspaceOutput is the audit by yarn of a workspace of the
+// repository.
+type YarnAuditWorkspaceOutput struct {
+	Workspace AuditWorkspace
+	Yarn      YarnAuditOutput
+}
+
 func analyzeYarnaudit(yarnAuditScan *SecTestScanInfo) error {
 
-	yarnAuditOutput := YarnAuditOutput{}
-	yarnAuditScan.FinalOutput = yarnAuditOutput
+	yarnAuditOutputs := []YarnAuditWorkspaceOutput{}
+	yarnAuditScan.FinalOutput = yarnAuditOutputs
 
 	// if package-lock was not found, a warning will be genrated as a low vuln
 	yarnLockNotFound := strings.Contains(yarnAuditScan.Container.COutput, "ERROR_YARN_LOCK_NOT_FOUND")
@@ -72,19 +83,20 @@ func analyzeYarnaudit(yarnAuditScan *SecTestScanInfo) error {
 	}
 
 	// nil cOutput states that no Issues were found.
-	if yarnAuditScan.Container.COutput == "" {
+	if strings.TrimSpace(yarnAuditScan.Container.COutput) == "" {
 		yarnAuditScan.prepareContainerAfterScan()
 		return nil
 	}
 
-	// Unmarshall rawOutput into finalOutput, that is a YarnAuditOutput struct.
-	if err := json.Unmarshal([]byte(yarnAuditScan.Container.COutput), &yarnAuditOutput); err != nil {
+	// Unmarshall rawOutput into finalOutput, the audits of every workspace.
+	yarnAuditOutputs, err := parseYarnAuditOutputs(yarnAuditScan.Container.COutput)
+	if err != nil {
 		log.Error("analyzeYarnaudit", "YARNAUDIT", 1036, yarnAuditScan.Container.COutput, err)
 		yarnAuditScan.ErrorFound = util.HandleScanError(yarnAuditScan.Container.COutput, err)
 		yarnAuditScan.prepareContainerAfterScan()
 		return yarnAuditScan.ErrorFound
 	}
-	yarnAuditScan.FinalOutput = yarnAuditOutput
+	yarnAuditScan.FinalOutput = yarnAuditOutputs
 
 	// step 4: find Issues that have severity "MEDIUM" or "HIGH" and confidence "HIGH".
 	yarnAuditScan.prepareYarnAuditVulns()
@@ -92,10 +104,30 @@ func analyzeYarnaudit(yarnAuditScan *SecTestScanInfo) error {
 	return nil
 }
 
+// parseYarnAuditOutputs returns the audits of every workspace printed in
+// cOutput by the yarnaudit container.
+func parseYarnAuditOutputs(cOutput string) ([]YarnAuditWorkspaceOutput, error) {
+	workspaces, err := parseAuditWorkspaces(cOutput, yarnLockfile)
+	if err != nil {
+		return nil, err
+	}
+	yarnAuditOutputs := []YarnAuditWorkspaceOutput{}
+	for _, workspace := range workspaces {
+		yarnAuditOutput := YarnAuditWorkspaceOutput{Workspace: workspace}
+		if workspace.Error == "" {
+			if err := json.Unmarshal(workspace.Audit, &yarnAuditOutput.Yarn); err != nil {
+				return nil, err
+			}
+		}
+		yarnAuditOutputs = append(yarnAuditOutputs, yarnAuditOutput)
+	}
+	return yarnAuditOutputs, nil
+}
+
 func (yarnAuditScan *SecTestScanInfo) prepareYarnAuditVulns() {
 
 	huskyCIyarnauditResults := types.HuskyCISecurityTestOutput{}
-	yarnAuditOutput := yarnAuditScan.FinalOutput.(YarnAuditOutput)
+	yarnAuditOutputs := yarnAuditScan.FinalOutput.([]YarnAuditWorkspaceOutput)
 
 	if yarnAuditScan.YarnLockNotFound {
 		yarnauditVuln := types.HuskyCIVulnerability{}
@@ -121,37 +153,46 @@ func (yarnAuditScan *SecTestScanInfo) prepareYarnAuditVulns() {
 		return
 	}
 
-	for _, issue := range yarnAuditOutput.Advisories {
-		yarnauditVuln := types.HuskyCIVulnerability{}
-		yarnauditVuln.Language = "JavaScript"
-		yarnauditVuln.SecurityTool = "YarnAudit"
-		yarnauditVuln.Details = issue.Overview
-		yarnauditVuln.Title = fmt.Sprintf("Vulnerable Dependency: %s %s (%s)", issue.ModuleName, issue.VulnerableVersions, issue.Title)
-		yarnauditVuln.VunerableBelow = issue.VulnerableVersions
-		yarnauditVuln.Code = issue.ModuleName
-		yarnauditVuln.Occurrences = 1
-		for _, findings := range issue.Findings {
-			yarnauditVuln.Version = findings.Version
+	for _, yarnAuditOutput := range yarnAuditOutputs {
+		if yarnAuditOutput.Workspace.Error != "" {
+			huskyCIyarnauditResults.LowVulns = append(huskyCIyarnauditResults.LowVulns, auditErrorVuln("YarnAudit", yarnAuditOutput.Workspace))
+			continue
 		}
-
-		switch issue.Severity {
-		case "info", "low":
-			yarnauditVuln.Severity = "low"
-			if !vulnListContains(huskyCIyarnauditResults.LowVulns, yarnauditVuln) {
-				huskyCIyarnauditResults.LowVulns = append(huskyCIyarnauditResults.LowVulns, yarnauditVuln)
+		for _, issue := range yarnAuditOutput.Yarn.Advisories {
+			yarnauditVuln := types.HuskyCIVulnerability{}
+			yarnauditVuln.Language = "JavaScript"
+			yarnauditVuln.SecurityTool = "YarnAudit"
+			yarnauditVuln.Details = issue.Overview
+			yarnauditVuln.Title = fmt.Sprintf("Vulnerable Dependency: %s %s (%s)", issue.ModuleName, issue.VulnerableVersions, issue.Title)
+			yarnauditVuln.VunerableBelow = issue.VulnerableVersions
+			yarnauditVuln.Code = issue.ModuleName
+			yarnauditVuln.Occurrences = 1
+			for _, findings := range issue.Findings {
+				yarnauditVuln.Version = findings.Version
 			}
-		case "moderate":
-			yarnauditVuln.Severity = "medium"
-			if !vulnListContains(huskyCIyarnauditResults.MediumVulns, yarnauditVuln) {
-				huskyCIyarnauditResults.MediumVulns = append(huskyCIyarnauditResults.MediumVulns, yarnauditVuln)
+			yarnauditVuln.Package = issue.ModuleName
+			yarnauditVuln.FixVersion = fixversion.FromPatchedRange(issue.PatchedVersions, yarnauditVuln.Version)
+			yarnauditVuln.PackagePath = yarnAuditOutput.Workspace.Path
+
+			switch issue.Severity {
+			case "info", "low":
+				yarnauditVuln.Severity = "low"
+				if !vulnListContains(huskyCIyarnauditResults.LowVulns, yarnauditVuln) {
+					huskyCIyarnauditResults.LowVulns = append(huskyCIyarnauditResults.LowVulns, yarnauditVuln)
+				}
+			case "moderate":
+				yarnauditVuln.Severity = "medium"
+				if !vulnListContains(huskyCIyarnauditResults.MediumVulns, yarnauditVuln) {
+					huskyCIyarnauditResults.MediumVulns = append(huskyCIyarnauditResults.MediumVulns, yarnauditVuln)
+				}
+			case "high", "critical":
+				yarnauditVuln.Severity = "high"
+				if !vulnListContains(huskyCIyarnauditResults.HighVulns, yarnauditVuln) {
+					huskyCIyarnauditResults.HighVulns = append(huskyCIyarnauditResults.HighVulns, yarnauditVuln)
+				}
 			}
-		case "high", "critical":
-			yarnauditVuln.Severity = "high"
-			if !vulnListContains(huskyCIyarnauditResults.HighVulns, yarnauditVuln) {
-				huskyCIyarnauditResults.HighVulns = append(huskyCIyarnauditResults.HighVulns, yarnauditVuln)
-			}
-		}
 
+		}
 	}
 
 	yarnAuditScan.Vulnerabilities = huskyCIyarnauditResults
@@ -160,7 +201,7 @@ func (yarnAuditScan *SecTestScanInfo) prepareYarnAuditVulns() {
 // vulnListContains increments the occurrence counter in case a vulnerability is found again
 func vulnListContains(vulnList []types.HuskyCIVulnerability, vuln types.HuskyCIVulnerability) bool {
 	for i := range vulnList {
-		if vulnList[i].Details == vuln.Details && vulnList[i].Code == vuln.Code {
+		if vulnList[i].Details == vuln.Details && vulnList[i].Code == vuln.Code && vulnList[i].PackagePath == vuln.PackagePath {
 			vulnList[i].Occurrences = vulnList[i].Occurrences + 1
 			return true
 		}
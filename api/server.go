@@ -1,23 +1,63 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
 
-	"github.com/huskyci-org/huskyCI/api/auth"
+	"github.com/huskyci-org/huskyCI/api/analysis"
+	"github.com/huskyci-org/huskyCI/api/apierror"
 	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/db"
+	"github.com/huskyci-org/huskyCI/api/debugtrace"
+	huskydocker "github.com/huskyci-org/huskyCI/api/dockers"
+	"github.com/huskyci-org/huskyCI/api/janitor"
+	"github.com/huskyci-org/huskyCI/api/limits"
 	"github.com/huskyci-org/huskyCI/api/log"
-	"github.com/huskyci-org/huskyCI/api/routes"
+	"github.com/huskyci-org/huskyCI/api/metrics"
+	"github.com/huskyci-org/huskyCI/api/prepull"
+	"github.com/huskyci-org/huskyCI/api/router"
+	"github.com/huskyci-org/huskyCI/api/scaling"
+	"github.com/huskyci-org/huskyCI/api/storage"
+	"github.com/huskyci-org/huskyCI/api/tlscert"
+	"github.com/huskyci-org/huskyCI/api/tracing"
 	"github.com/huskyci-org/huskyCI/api/util"
 	apiUtil "github.com/huskyci-org/huskyCI/api/util/api"
+	"github.com/huskyci-org/huskyCI/api/vault"
+)
+
+// version, commit and date are set by the Makefile when building the API.
+var (
+	version string
+	commit  string
+	date    string
 )
 
 func main() {
 
+	apiContext.SetBuildInfo(version, commit, date)
+
+	var vaultClient *vault.Client
+	if vaultConfig := apiContext.DefaultConf.GetVaultConfig(); vaultConfig.Enabled() {
+		var err error
+		if vaultClient, err = vault.New(vaultConfig); err == nil {
+			err = vaultClient.Load()
+		}
+		if err != nil {
+			fmt.Println("Error reading the secrets from Vault: ", err)
+			os.Exit(1)
+		}
+	}
+
 	configAPI, err := apiContext.DefaultConf.GetAPIConfig()
 
 	if err != nil {
@@ -33,6 +73,15 @@ func main() {
 		configAPI.GraylogConfig.Tag)
 	log.Info("main", "SERVER", 11)
 
+	debugtrace.Init(configAPI.DebugTraceConfig)
+
+	shutdownTracing, err := tracing.Init(configAPI.TracingConfig, configAPI.Version)
+	if err != nil {
+		log.Error("main", "SERVER", 1001, err)
+		os.Exit(1)
+	}
+	defer shutdownTracing(context.Background())
+
 	checkHandler := &apiUtil.CheckUtils{}
 
 	huskyUtils := apiUtil.HuskyUtils{
@@ -44,65 +93,192 @@ func main() {
 		os.Exit(1)
 	}
 
-	echoInstance := echo.New()
-	echoInstance.HideBanner = true
+	if configAPI.Storage != nil {
+		go storage.RunExpiration(configAPI.Storage, configAPI.StorageConfig.Expiration, time.Hour)
+	}
 
-	echoInstance.Use(middleware.Logger())
-	echoInstance.Use(middleware.Recover())
-	echoInstance.Use(middleware.RequestID())
+	if configAPI.PrepullInterval > 0 && os.Getenv("HUSKYCI_INFRASTRUCTURE_USE") == "docker" {
+		go prepull.Schedule(configAPI.PrepullInterval)
+	}
 
-	echoInstance.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins: []string{configAPI.AllowOriginValue},
-		AllowMethods: []string{http.MethodGet, http.MethodPut, http.MethodPost, http.MethodDelete},
-	}))
+	go janitor.Schedule(configAPI.JanitorConfig)
 
-	// set new object for /api/1.0 route
-	g := echoInstance.Group("/api/1.0")
+	if configAPI.BranchConcurrency == apiContext.BranchConcurrencyQueue {
+		go analysis.StartQueued()
+	}
 
-	// use basic auth middleware
-	g.Use(middleware.BasicAuth(auth.ValidateUser))
+	if configAPI.ImageGCConfig.Interval > 0 && os.Getenv("HUSKYCI_INFRASTRUCTURE_USE") == "docker" {
+		go janitor.ScheduleImageGC(configAPI.ImageGCConfig)
+	}
 
-	// /token route with basic auth
-	g.POST("/token", routes.HandleToken)
-	g.POST("/token/deactivate", routes.HandleDeactivation)
+	if configAPI.WarmPoolConfig.Size > 0 && os.Getenv("HUSKYCI_INFRASTRUCTURE_USE") == "docker" {
+		go huskydocker.ScheduleWarmPoolReaper(configAPI.WarmPoolConfig)
+	}
 
-	// generic routes
-	echoInstance.GET("/healthcheck", routes.HealthCheck)
-	echoInstance.GET("/version", routes.GetAPIVersion)
+	if configAPI.DockerClientPoolConfig.IdleTimeout > 0 && os.Getenv("HUSKYCI_INFRASTRUCTURE_USE") == "docker" {
+		go huskydocker.ScheduleClientReaper(configAPI.DockerClientPoolConfig)
+	}
+
+	scalingHook, err := scaling.NewHook(configAPI)
+	if err != nil {
+		log.Error("main", "SERVER", 1072, err)
+		os.Exit(1)
+	}
+	go scaling.Schedule(configAPI.ScalingConfig, scalingHook)
+
+	if vaultClient != nil {
+		go vaultClient.Run(context.Background(), func(envVars []string) {
+			applyVaultSecrets(configAPI, envVars)
+		})
+	}
 
-	// analysis routes
-	echoInstance.POST("/analysis", routes.ReceiveRequest)
-	echoInstance.POST("/analysis/upload", routes.UploadZip)
-	echoInstance.GET("/analysis/:id", routes.GetAnalysis)
-	// echoInstance.PUT("/analysis/:id", routes.UpdateAnalysis)
-	// echoInstance.DELETE("/analysis/:id", routes.DeleteAnalysis)
+	if encrypted, ok := configAPI.DBInstance.(*db.EncryptedRequests); ok {
+		go rotateEncryptedFields(encrypted)
+	}
 
-	// stats routes
-	echoInstance.GET("/stats/:metric_type", routes.GetMetric)
+	if configAPI.Exporter != nil {
+		go configAPI.Exporter.Run()
+	}
 
-	// securityTest routes
-	// echoInstance.GET("securityTest/:securityTestName", routes.GetSecurityTest)
-	// echoInstance.POST("/securitytest", routes.CreateNewSecurityTest)
-	// echoInstance.PUT("/securityTest/:securityTestName", routes.UpdateSecurityTest)
-	// echoInstance.DELETE("/securityTest/:securityTestName", routes.DeleteSecurityTest)
+	echoInstance := echo.New()
+	echoInstance.HideBanner = true
+	echoInstance.IPExtractor = ipExtractor(configAPI.IPAllowListConfig.TrustedProxies)
+	echoInstance.HTTPErrorHandler = apierror.Handler
 
-	// repository routes
-	// echoInstance.GET("/repository/:repoID", routes.GetRepository)
-	// echoInstance.POST("/repository", routes.CreateNewRepository)
-	// echoInstance.PUT("/repository/:repoID)
-	// echoInstance.DELETE("/repository/:repoID)
+	echoInstance.Use(middleware.RequestID())
+	echoInstance.Use(middleware.Logger())
+	echoInstance.Use(middleware.Recover())
+	echoInstance.Use(otelecho.Middleware(configAPI.TracingConfig.ServiceName))
+	echoInstance.Use(metrics.DefaultRegistry.Middleware())
+	echoInstance.Use(middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowOrigins:  configAPI.AllowOrigins,
+		AllowMethods:  []string{http.MethodGet, http.MethodPut, http.MethodPost, http.MethodDelete},
+		ExposeHeaders: []string{"Deprecation", "Sunset", "Link", echo.HeaderXRequestID, "Idempotent-Replayed"},
+	}))
+	if configAPI.GzipLevel != 0 {
+		echoInstance.Use(middleware.GzipWithConfig(middleware.GzipConfig{
+			Skipper: router.LongLived,
+			Level:   configAPI.GzipLevel,
+		}))
+	}
+	echoInstance.Use(limits.Body(configAPI.RequestLimitsConfig, router.BodyLimits()))
+	echoInstance.Use(middleware.ContextTimeoutWithConfig(middleware.ContextTimeoutConfig{
+		Skipper: router.LongLived,
+		Timeout: configAPI.RequestLimitsConfig.Timeout,
+	}))
 
-	// user routes
-	// echoInstance.GET("/user", routes.GetUser)
-	// echoInstance.POST("/user", routes.CreateNewUser)
-	echoInstance.PUT("/user", routes.UpdateUser)
-	// echoInstance.DELETE("/user)
+	router.Register(echoInstance, configAPI.V1Sunset)
 
 	huskyAPIport := fmt.Sprintf(":%d", configAPI.Port)
 
 	if !configAPI.UseTLS {
+		if configAPI.ClientCAFile != "" {
+			log.Error("main", "SERVER", 1001, "HUSKYCI_API_TLS_CLIENT_CA requires HUSKYCI_API_ENABLE_HTTPS")
+			os.Exit(1)
+		}
 		echoInstance.Logger.Fatal(echoInstance.Start(huskyAPIport))
 	} else {
-		echoInstance.Logger.Fatal(echoInstance.StartTLS(huskyAPIport, util.CertFile, util.KeyFile))
+		tlsConfig, err := serverTLSConfig(configAPI)
+		if err != nil {
+			log.Error("main", "SERVER", 1001, err)
+			os.Exit(1)
+		}
+		echoInstance.TLSServer.Addr = huskyAPIport
+		echoInstance.TLSServer.TLSConfig = tlsConfig
+		echoInstance.Logger.Fatal(echoInstance.StartServer(echoInstance.TLSServer))
+	}
+}
+
+// ipExtractor returns how the address of clients is read: from the
+// connection, or from X-Forwarded-For for the requests coming from
+// trustedProxies, so that clients can not choose their own address.
+func ipExtractor(trustedProxies []*net.IPNet) echo.IPExtractor {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, proxy := range trustedProxies {
+		options = append(options, echo.TrustIPRange(proxy))
+	}
+	return echo.ExtractIPFromXFFHeader(options...)
+}
+
+// rotateEncryptedFields encrypts again with the current key the fields of the
+// database that were encrypted with a previous one.
+func rotateEncryptedFields(encrypted *db.EncryptedRequests) {
+	hostname, _ := os.Hostname()
+	rotated, err := encrypted.RotateKeys(hostname, 10*time.Minute)
+	if err != nil {
+		log.Error("main", "SERVER", 1068, err)
+		return
+	}
+	if rotated > 0 {
+		log.Info("main", "SERVER", 58, rotated)
+	}
+}
+
+// applyVaultSecrets makes the API use the secrets Vault issued again, held
+// by envVars: it connects to the database with its new credentials and
+// writes the new TLS material of the Docker API hosts. The other secrets,
+// such as the git deploy key, are read from Vault when used.
+func applyVaultSecrets(configAPI *apiContext.APIConfig, envVars []string) {
+	dbCredentials, dockerCertificates := false, false
+	for _, envVar := range envVars {
+		switch {
+		case envVar == "HUSKYCI_DATABASE_DB_USERNAME" || envVar == "HUSKYCI_DATABASE_DB_PASSWORD":
+			dbCredentials = true
+		case strings.HasPrefix(envVar, "HUSKYCI_DOCKERAPI_") && strings.HasSuffix(envVar, "_VALUE"):
+			dockerCertificates = true
+		}
+	}
+	if dbCredentials {
+		username, password := vault.Getenv("HUSKYCI_DATABASE_DB_USERNAME"), vault.Getenv("HUSKYCI_DATABASE_DB_PASSWORD")
+		configAPI.DBConfig.SetCredentials(username, password)
+		if err := configAPI.DBInstance.ConnectDB(
+			configAPI.DBConfig.Address,
+			configAPI.DBConfig.DatabaseName,
+			username,
+			password,
+			configAPI.DBConfig.Timeout,
+			configAPI.DBConfig.PoolLimit,
+			configAPI.DBConfig.Port,
+			configAPI.DBConfig.MaxOpenConns,
+			configAPI.DBConfig.MaxIdleConns,
+			configAPI.DBConfig.ConnMaxLifetime); err != nil {
+			log.Error("main", "SERVER", 1070, "database credentials", err)
+		}
+	}
+	if dockerCertificates {
+		if err := apiUtil.CreateAPIKeys(); err != nil {
+			log.Error("main", "SERVER", 1070, "Docker API certificates", err)
+		}
+	}
+}
+
+// serverTLSConfig returns the TLS configuration the API is served with, and
+// starts keeping its certificate up to date: obtaining and renewing it with
+// ACME, or reloading it when its files are rotated.
+func serverTLSConfig(configAPI *apiContext.APIConfig) (*tls.Config, error) {
+	if !configAPI.TLSCertConfig.ACME() {
+		reloader, err := tlscert.NewReloader(util.CertFile, util.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		go func() {
+			if err := reloader.Watch(context.Background()); err != nil {
+				log.Error("main", "SERVER", 1067, err)
+			}
+		}()
+		return tlscert.ServerConfig(reloader.GetCertificate, configAPI.ClientCAFile)
+	}
+
+	manager := tlscert.NewACMEManager(configAPI.TLSCertConfig)
+	if addr := configAPI.TLSCertConfig.ACMEHTTPAddr; addr != "" {
+		// answers the http-01 challenges, and redirects the rest to HTTPS
+		go func() {
+			log.Error("main", "SERVER", 1001, http.ListenAndServe(addr, manager.HTTPHandler(nil)))
+		}()
 	}
+	log.Info("main", "SERVER", 57, configAPI.TLSCertConfig.ACMEDomains)
+	return tlscert.ACMEServerConfig(manager, configAPI.ClientCAFile)
 }
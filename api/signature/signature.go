@@ -0,0 +1,118 @@
+package signature
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/api/types"
+)
+
+const defaultCosignPath = "cosign"
+
+var digestRegexp = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// ErrImageNotPinned is returned when verification is enforced and a
+// securityTest image is referenced by a mutable tag instead of a digest.
+var ErrImageNotPinned = errors.New("securityTest image is not pinned by digest")
+
+// ErrNoPublicKey is returned when verification is enforced but no cosign
+// public key has been configured.
+var ErrNoPublicKey = errors.New("no cosign public key configured")
+
+// Config represents the scanner image signature verification configuration.
+type Config struct {
+	Enforce    bool
+	PublicKey  string
+	CosignPath string
+	Timeout    time.Duration
+}
+
+// verified holds every image@digest reference already verified. A digest is
+// immutable, so a successful verification never needs to be repeated.
+var verified sync.Map
+
+// ValidDigest returns true if digest is in the sha256:<64 hex chars> form.
+func ValidDigest(digest string) bool {
+	return digestRegexp.MatchString(digest)
+}
+
+// ImageReference returns the reference a securityTest container should be
+// started from: image@digest when the image is pinned, image:tag otherwise.
+func ImageReference(securityTest types.SecurityTest) string {
+	if securityTest.ImageDigest != "" {
+		return fmt.Sprintf("%s@%s", securityTest.Image, securityTest.ImageDigest)
+	}
+	return fmt.Sprintf("%s:%s", securityTest.Image, securityTest.ImageTag)
+}
+
+// ImageVersion returns the digest of securityTest image if it is pinned or
+// its tag otherwise.
+func ImageVersion(securityTest types.SecurityTest) string {
+	if securityTest.ImageDigest != "" {
+		return securityTest.ImageDigest
+	}
+	return securityTest.ImageTag
+}
+
+// Verify checks securityTest image against config. Images pinned by digest are
+// verified with cosign whenever a public key is configured. If config enforces
+// verification, unpinned images and missing keys are rejected as well.
+func Verify(config *Config, securityTest types.SecurityTest) error {
+	if config == nil || (!config.Enforce && config.PublicKey == "") {
+		return nil
+	}
+	if securityTest.ImageDigest == "" {
+		if config.Enforce {
+			return fmt.Errorf("%w: %s", ErrImageNotPinned, ImageReference(securityTest))
+		}
+		return nil
+	}
+	if !ValidDigest(securityTest.ImageDigest) {
+		return fmt.Errorf("invalid image digest: %s", securityTest.ImageDigest)
+	}
+	if config.PublicKey == "" {
+		return ErrNoPublicKey
+	}
+
+	reference := ImageReference(securityTest)
+	cacheKey := config.PublicKey + "|" + reference
+	if _, ok := verified.Load(cacheKey); ok {
+		return nil
+	}
+	if err := runCosign(config, reference); err != nil {
+		return err
+	}
+	verified.Store(cacheKey, true)
+	return nil
+}
+
+func runCosign(config *Config, reference string) error {
+	cosignPath := config.CosignPath
+	if cosignPath == "" {
+		cosignPath = defaultCosignPath
+	}
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, cosignPath, "verify", "--key", config.PublicKey, reference)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("cosign verify %s timed out after %s", reference, timeout)
+		}
+		return fmt.Errorf("cosign verify %s failed: %v: %s", reference, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package signature_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/huskyci-org/huskyCI/api/signature"
+	"github.com/huskyci-org/huskyCI/api/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const validDigest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+// fakeCosign writes a shell script that records its arguments and exits with exitCode.
+func fakeCosign(dir string, exitCode string) (string, string) {
+	calls := filepath.Join(dir, "calls")
+	script := filepath.Join(dir, "cosign")
+	content := "#!/bin/sh\necho \"$@\" >> " + calls + "\necho 'no matching signatures' >&2\nexit " + exitCode + "\n"
+	Expect(os.WriteFile(script, []byte(content), 0o755)).To(Succeed())
+	return script, calls
+}
+
+func countCalls(calls string) int {
+	content, err := os.ReadFile(calls)
+	if err != nil {
+		return 0
+	}
+	return strings.Count(string(content), "\n")
+}
+
+var _ = Describe("Signature", func() {
+
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "huskyci-cosign")
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	Describe("ImageReference", func() {
+		It("Should use the digest when the image is pinned", func() {
+			securityTest := types.SecurityTest{Image: "securego/gosec", ImageTag: "2.18.2", ImageDigest: validDigest}
+			Expect(ImageReference(securityTest)).To(Equal("securego/gosec@" + validDigest))
+			Expect(ImageVersion(securityTest)).To(Equal(validDigest))
+		})
+		It("Should use the tag when the image is not pinned", func() {
+			securityTest := types.SecurityTest{Image: "securego/gosec", ImageTag: "2.18.2"}
+			Expect(ImageReference(securityTest)).To(Equal("securego/gosec:2.18.2"))
+			Expect(ImageVersion(securityTest)).To(Equal("2.18.2"))
+		})
+	})
+
+	Describe("Verify", func() {
+		Context("When verification is not configured", func() {
+			It("Should accept any image", func() {
+				Expect(Verify(&Config{}, types.SecurityTest{Image: "alpine", ImageTag: "latest"})).To(Succeed())
+				Expect(Verify(nil, types.SecurityTest{Image: "alpine", ImageTag: "latest"})).To(Succeed())
+			})
+		})
+		Context("When verification is enforced", func() {
+			It("Should reject images that are not pinned by digest", func() {
+				err := Verify(&Config{Enforce: true, PublicKey: "cosign.pub"}, types.SecurityTest{Image: "alpine", ImageTag: "latest"})
+				Expect(errors.Is(err, ErrImageNotPinned)).To(BeTrue())
+			})
+			It("Should reject pinned images if no public key is configured", func() {
+				err := Verify(&Config{Enforce: true}, types.SecurityTest{Image: "alpine", ImageDigest: validDigest})
+				Expect(err).To(Equal(ErrNoPublicKey))
+			})
+			It("Should reject images whose signature is invalid", func() {
+				cosign, _ := fakeCosign(dir, "1")
+				config := &Config{Enforce: true, PublicKey: "cosign.pub", CosignPath: cosign}
+				err := Verify(config, types.SecurityTest{Image: "huskyci/invalid", ImageDigest: validDigest})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("no matching signatures"))
+			})
+			It("Should accept signed images and cache the verification", func() {
+				cosign, calls := fakeCosign(dir, "0")
+				config := &Config{Enforce: true, PublicKey: filepath.Join(dir, "cosign.pub"), CosignPath: cosign}
+				securityTest := types.SecurityTest{Image: "huskyci/gosec", ImageDigest: validDigest}
+				Expect(Verify(config, securityTest)).To(Succeed())
+				Expect(Verify(config, securityTest)).To(Succeed())
+				Expect(countCalls(calls)).To(Equal(1))
+			})
+		})
+		Context("When the digest is malformed", func() {
+			It("Should return an error", func() {
+				err := Verify(&Config{PublicKey: "cosign.pub"}, types.SecurityTest{Image: "alpine", ImageDigest: "sha256:abc"})
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+})
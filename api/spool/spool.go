@@ -0,0 +1,82 @@
+// Package spool captures the output of the securityTest containers in
+// temporary files, up to a size, so that verbose tools running on large
+// repositories do not hold hundreds of megabytes in the memory of the API
+// while their output is read.
+package spool
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// TruncationMarker is appended to an output cut at its size limit, with the
+// limit in bytes.
+const TruncationMarker = "\n[huskyCI] output truncated at %d bytes\n"
+
+// File is an output spooled on disk. Truncated is true if it was cut at its
+// size limit.
+type File struct {
+	file      *os.File
+	size      int64
+	Truncated bool
+}
+
+// Capture copies r into a temporary file, up to limit bytes if limit is
+// positive. An output larger than limit is cut there and ends with the
+// TruncationMarker, and the rest of r is not read. The File must be closed to
+// remove it.
+func Capture(r io.Reader, limit int64) (*File, error) {
+	file, err := os.CreateTemp("", "huskyci-output-")
+	if err != nil {
+		return nil, err
+	}
+	spooled := &File{file: file}
+
+	source := r
+	if limit > 0 {
+		source = io.LimitReader(r, limit+1)
+	}
+	spooled.size, err = io.Copy(file, source)
+	if err == nil && limit > 0 && spooled.size > limit {
+		marker := fmt.Sprintf(TruncationMarker, limit)
+		if err = file.Truncate(limit); err == nil {
+			_, err = file.WriteAt([]byte(marker), limit)
+		}
+		spooled.size = limit + int64(len(marker))
+		spooled.Truncated = true
+	}
+	if err != nil {
+		spooled.Close()
+		return nil, err
+	}
+	return spooled, nil
+}
+
+// Size returns the size of the output in bytes, marker included.
+func (f *File) Size() int64 {
+	return f.size
+}
+
+// Reader returns a reader of the output from its start, so that parsers can
+// stream it instead of reading it at once.
+func (f *File) Reader() io.Reader {
+	return io.NewSectionReader(f.file, 0, f.size)
+}
+
+// String reads the whole output.
+func (f *File) String() (string, error) {
+	var output strings.Builder
+	output.Grow(int(f.size))
+	if _, err := io.Copy(&output, f.Reader()); err != nil {
+		return "", err
+	}
+	return output.String(), nil
+}
+
+// Close removes the file of the output.
+func (f *File) Close() error {
+	f.file.Close()
+	return os.Remove(f.file.Name())
+}
@@ -0,0 +1,60 @@
+package spool_test
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/huskyci-org/huskyCI/api/spool"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Capture", func() {
+
+	Context("When the output is within the limit", func() {
+		It("Should keep all of it", func() {
+			output, err := spool.Capture(strings.NewReader("gosec output"), 1024)
+			Expect(err).NotTo(HaveOccurred())
+			defer output.Close()
+			Expect(output.Truncated).To(BeFalse())
+			Expect(output.String()).To(Equal("gosec output"))
+		})
+	})
+
+	Context("When the output is larger than the limit", func() {
+		It("Should cut it and append the marker", func() {
+			output, err := spool.Capture(strings.NewReader(strings.Repeat("a", 100)), 10)
+			Expect(err).NotTo(HaveOccurred())
+			defer output.Close()
+			Expect(output.Truncated).To(BeTrue())
+			expected := strings.Repeat("a", 10) + fmt.Sprintf(spool.TruncationMarker, 10)
+			Expect(output.String()).To(Equal(expected))
+			Expect(output.Size()).To(Equal(int64(len(expected))))
+		})
+	})
+
+	Context("When there is no limit", func() {
+		It("Should keep all of it", func() {
+			output, err := spool.Capture(strings.NewReader(strings.Repeat("a", 100)), 0)
+			Expect(err).NotTo(HaveOccurred())
+			defer output.Close()
+			Expect(output.Truncated).To(BeFalse())
+			Expect(output.Size()).To(Equal(int64(100)))
+		})
+	})
+
+	Context("When the output is read twice", func() {
+		It("Should read it from its start each time", func() {
+			output, err := spool.Capture(strings.NewReader("findings"), 0)
+			Expect(err).NotTo(HaveOccurred())
+			defer output.Close()
+			first, _ := io.ReadAll(output.Reader())
+			second, _ := io.ReadAll(output.Reader())
+			Expect(string(first)).To(Equal("findings"))
+			Expect(string(second)).To(Equal("findings"))
+		})
+	})
+
+})
@@ -0,0 +1,114 @@
+package statuscache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/api/log"
+	"github.com/huskyci-org/huskyCI/api/types"
+	"github.com/redis/go-redis/v9"
+)
+
+const logActionRedis = "StatusCache"
+const logInfoStatusCache = "STATUSCACHE"
+
+// redisTimeout bounds every command sent to Redis, which must answer faster
+// than the database to be of any use.
+const redisTimeout = time.Second
+
+// RedisRetryAfter is how long Redis is left out once it failed: the analyses
+// are read from the database meanwhile, rather than each request waiting for
+// Redis to time out.
+var RedisRetryAfter = 30 * time.Second
+
+// redisKeyPrefix prefixes the keys of the analyses in Redis.
+const redisKeyPrefix = "huskyci:analysis:"
+
+// Redis keeps the analyses in a Redis server, shared by every replica of the
+// API, through a pool of connections.
+type Redis struct {
+	client *redis.Client
+	ttl    time.Duration
+	// failedAt is when Redis last failed, in Unix nanoseconds
+	failedAt atomic.Int64
+}
+
+// NewRedis returns a Redis keeping the analyses for ttl in the Redis server
+// at address, a host:port, authenticated with password if it is not empty.
+func NewRedis(address, password string, ttl time.Duration) *Redis {
+	client := redis.NewClient(&redis.Options{
+		Addr:            address,
+		Password:        password,
+		Protocol:        2,
+		DisableIdentity: true,
+		DialTimeout:     redisTimeout,
+		ReadTimeout:     redisTimeout,
+		WriteTimeout:    redisTimeout,
+		PoolTimeout:     redisTimeout,
+		// a command that failed is a miss, not worth waiting for again
+		MaxRetries: -1,
+	})
+	return &Redis{client: client, ttl: ttl}
+}
+
+// Get returns the analysis RID if it is kept.
+func (r *Redis) Get(RID string) (types.Analysis, bool) {
+	if !r.available() {
+		return types.Analysis{}, false
+	}
+	reply, err := r.client.Get(context.Background(), redisKeyPrefix+RID).Result()
+	if errors.Is(err, redis.Nil) {
+		return types.Analysis{}, false
+	}
+	if err != nil {
+		r.fail(err)
+		return types.Analysis{}, false
+	}
+	analysis := types.Analysis{}
+	if err := json.Unmarshal([]byte(reply), &analysis); err != nil {
+		log.Error(logActionRedis, logInfoStatusCache, 1061, err)
+		return types.Analysis{}, false
+	}
+	return analysis, true
+}
+
+// Set keeps analysis for the TTL of r.
+func (r *Redis) Set(analysis types.Analysis) {
+	if !r.available() {
+		return
+	}
+	value, err := json.Marshal(analysis)
+	if err != nil {
+		log.Error(logActionRedis, logInfoStatusCache, 1061, err)
+		return
+	}
+	if err := r.client.Set(context.Background(), redisKeyPrefix+analysis.RID, value, r.ttl).Err(); err != nil {
+		r.fail(err)
+	}
+}
+
+// Delete forgets the analysis RID. While Redis is left out, the analysis is
+// only kept until its TTL expires.
+func (r *Redis) Delete(RID string) {
+	if !r.available() {
+		return
+	}
+	if err := r.client.Del(context.Background(), redisKeyPrefix+RID).Err(); err != nil {
+		r.fail(err)
+	}
+}
+
+// available returns false for RedisRetryAfter once Redis failed.
+func (r *Redis) available() bool {
+	failedAt := r.failedAt.Load()
+	return failedAt == 0 || time.Since(time.Unix(0, failedAt)) >= RedisRetryAfter
+}
+
+// fail logs err and leaves Redis out for RedisRetryAfter.
+func (r *Redis) fail(err error) {
+	log.Error(logActionRedis, logInfoStatusCache, 1061, err)
+	r.failedAt.Store(time.Now().UnixNano())
+}
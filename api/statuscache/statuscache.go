@@ -0,0 +1,92 @@
+// Package statuscache keeps the running analyses for a few seconds, so that
+// the clients polling them are not each answered from the database.
+package statuscache
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/api/types"
+	"github.com/patrickmn/go-cache"
+)
+
+// DefaultTTL is how long an analysis is kept when no TTL is configured.
+const DefaultTTL = 5 * time.Second
+
+// Backends of the cache.
+const (
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+)
+
+// Config represents the status cache configuration. An empty Backend
+// disables the cache.
+type Config struct {
+	Backend       string
+	RedisAddress  string
+	RedisPassword string
+	TTL           time.Duration
+}
+
+// Cache keeps analyses by RID for a short time. Failing to reach the cache is
+// a miss, as the database still has the analyses.
+type Cache interface {
+	Get(RID string) (types.Analysis, bool)
+	Set(analysis types.Analysis)
+	Delete(RID string)
+}
+
+// New returns the Cache of the configured backend, or nil if it is disabled.
+func New(config *Config) (Cache, error) {
+	if config == nil {
+		return nil, nil
+	}
+	ttl := config.TTL
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	switch strings.ToLower(config.Backend) {
+	case "", "off":
+		return nil, nil
+	case BackendMemory:
+		return NewMemory(ttl), nil
+	case BackendRedis:
+		if config.RedisAddress == "" {
+			return nil, fmt.Errorf("redis status cache requires an address")
+		}
+		return NewRedis(config.RedisAddress, config.RedisPassword, ttl), nil
+	default:
+		return nil, fmt.Errorf("unsupported status cache backend: %s", config.Backend)
+	}
+}
+
+// Memory keeps the analyses in the memory of the API. Each replica has its
+// own, so a replica not running an analysis may serve it up to its TTL late.
+type Memory struct {
+	cache *cache.Cache
+}
+
+// NewMemory returns a Memory keeping the analyses for ttl.
+func NewMemory(ttl time.Duration) *Memory {
+	return &Memory{cache: cache.New(ttl, 2*ttl)}
+}
+
+// Get returns the analysis RID if it is kept.
+func (m *Memory) Get(RID string) (types.Analysis, bool) {
+	analysis, found := m.cache.Get(RID)
+	if !found {
+		return types.Analysis{}, false
+	}
+	return analysis.(types.Analysis), true
+}
+
+// Set keeps analysis.
+func (m *Memory) Set(analysis types.Analysis) {
+	m.cache.SetDefault(analysis.RID, analysis)
+}
+
+// Delete forgets the analysis RID.
+func (m *Memory) Delete(RID string) {
+	m.cache.Delete(RID)
+}
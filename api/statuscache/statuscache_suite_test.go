@@ -0,0 +1,13 @@
+package statuscache_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestStatusCache(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "StatusCache Suite")
+}
@@ -0,0 +1,196 @@
+package statuscache_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/api/statuscache"
+	"github.com/huskyci-org/huskyCI/api/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fakeRedis answers the GET, SET and DEL commands of its clients from a map,
+// and requires password if it is not empty. It knows no HELLO, as Redis 5. It returns its address and the
+// commands it received.
+func fakeRedis(password string) (string, func() []string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).NotTo(HaveOccurred())
+	var mu sync.Mutex
+	values := map[string]string{}
+	commands := []string{}
+	go func() {
+		defer listener.Close()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveRedis(conn, password, &mu, values, &commands)
+		}
+	}()
+	return listener.Addr().String(), func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string{}, commands...)
+	}
+}
+
+// serveRedis answers the commands of a client of fakeRedis until it leaves.
+func serveRedis(conn net.Conn, password string, mu *sync.Mutex, values map[string]string, commands *[]string) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	authenticated := password == ""
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		args[0] = strings.ToUpper(args[0])
+		mu.Lock()
+		*commands = append(*commands, args[0])
+		switch {
+		case args[0] == "AUTH" && args[len(args)-1] == password:
+			authenticated = true
+			io.WriteString(conn, "+OK\r\n")
+		case !authenticated:
+			io.WriteString(conn, "-NOAUTH Authentication required.\r\n")
+		case args[0] == "GET":
+			if value, found := values[args[1]]; found {
+				fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(value), value)
+			} else {
+				io.WriteString(conn, "$-1\r\n")
+			}
+		case args[0] == "SET":
+			values[args[1]] = args[2]
+			io.WriteString(conn, "+OK\r\n")
+		case args[0] == "DEL":
+			delete(values, args[1])
+			io.WriteString(conn, ":1\r\n")
+		default:
+			io.WriteString(conn, "-ERR unknown command\r\n")
+		}
+		mu.Unlock()
+	}
+}
+
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+	args := make([]string, count)
+	for i := range args {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		value := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, value); err != nil {
+			return nil, err
+		}
+		args[i] = string(value[:size])
+	}
+	return args, nil
+}
+
+var _ = Describe("New", func() {
+	It("Should return no cache when it is disabled", func() {
+		statusCache, err := statuscache.New(&statuscache.Config{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(statusCache).To(BeNil())
+	})
+	It("Should return a Memory cache", func() {
+		statusCache, err := statuscache.New(&statuscache.Config{Backend: "memory"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(statusCache).To(BeAssignableToTypeOf(&statuscache.Memory{}))
+	})
+	It("Should require the address of Redis", func() {
+		_, err := statuscache.New(&statuscache.Config{Backend: "redis"})
+		Expect(err).To(HaveOccurred())
+	})
+	It("Should refuse an unknown backend", func() {
+		_, err := statuscache.New(&statuscache.Config{Backend: "memcached"})
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Memory", func() {
+	It("Should keep an analysis until it is deleted", func() {
+		statusCache := statuscache.NewMemory(time.Minute)
+		statusCache.Set(types.Analysis{RID: "abc", Status: "running"})
+		analysis, found := statusCache.Get("abc")
+		Expect(found).To(BeTrue())
+		Expect(analysis.Status).To(Equal("running"))
+
+		statusCache.Delete("abc")
+		_, found = statusCache.Get("abc")
+		Expect(found).To(BeFalse())
+	})
+	It("Should forget an analysis after its TTL", func() {
+		statusCache := statuscache.NewMemory(10 * time.Millisecond)
+		statusCache.Set(types.Analysis{RID: "abc"})
+		Eventually(func() bool {
+			_, found := statusCache.Get("abc")
+			return found
+		}).Should(BeFalse())
+	})
+})
+
+var _ = Describe("Redis", func() {
+	It("Should keep an analysis until it is deleted", func() {
+		address, commands := fakeRedis("secret")
+		statusCache := statuscache.NewRedis(address, "secret", time.Minute)
+
+		_, found := statusCache.Get("abc")
+		Expect(found).To(BeFalse())
+
+		startedAt := time.Date(2026, time.October, 16, 10, 0, 0, 0, time.UTC)
+		statusCache.Set(types.Analysis{RID: "abc", URL: "https://github.com/org/repo", Status: "running", StartedAt: startedAt})
+		analysis, found := statusCache.Get("abc")
+		Expect(found).To(BeTrue())
+		Expect(analysis).To(Equal(types.Analysis{RID: "abc", URL: "https://github.com/org/repo", Status: "running", StartedAt: startedAt}))
+
+		statusCache.Delete("abc")
+		_, found = statusCache.Get("abc")
+		Expect(found).To(BeFalse())
+		Expect(commands()).To(Equal([]string{"HELLO", "AUTH", "GET", "SET", "GET", "DEL", "GET"}))
+	})
+	It("Should miss when Redis can not be reached", func() {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		address := listener.Addr().String()
+		listener.Close()
+
+		statusCache := statuscache.NewRedis(address, "", time.Minute)
+		statusCache.Set(types.Analysis{RID: "abc"})
+		_, found := statusCache.Get("abc")
+		Expect(found).To(BeFalse())
+	})
+	It("Should leave Redis out for a while once it failed", func() {
+		previousRetryAfter := statuscache.RedisRetryAfter
+		defer func() { statuscache.RedisRetryAfter = previousRetryAfter }()
+		address, commands := fakeRedis("secret")
+		statusCache := statuscache.NewRedis(address, "", time.Minute)
+
+		statuscache.RedisRetryAfter = time.Minute
+		_, found := statusCache.Get("abc")
+		Expect(found).To(BeFalse())
+		statusCache.Set(types.Analysis{RID: "abc"})
+		statusCache.Delete("abc")
+		Expect(commands()).To(Equal([]string{"HELLO", "GET"}))
+
+		statuscache.RedisRetryAfter = 0
+		statusCache.Set(types.Analysis{RID: "abc"})
+		Expect(commands()).To(Equal([]string{"HELLO", "GET", "SET"}))
+	})
+})
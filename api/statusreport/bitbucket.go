@@ -0,0 +1,76 @@
+package statusreport
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/huskyci-org/huskyCI/api/gitauth"
+	"github.com/huskyci-org/huskyCI/api/types"
+)
+
+// bitbucketBuildStatus is the build status of a commit, as read by both
+// Bitbucket Cloud and Bitbucket Server.
+type bitbucketBuildStatus struct {
+	Key         string `json:"key"`
+	State       string `json:"state"`
+	Name        string `json:"name"`
+	URL         string `json:"url"`
+	Description string `json:"description"`
+}
+
+func buildStatus(reporter types.StatusReporter, analysis types.Analysis) bitbucketBuildStatus {
+	result := analysisOutcome(analysis)
+	return bitbucketBuildStatus{
+		Key:         "HUSKYCI",
+		State:       result.state,
+		Name:        "huskyCI",
+		URL:         detailsURL(reporter, analysis),
+		Description: result.description,
+	}
+}
+
+// sendBitbucketCloud sets the huskyCI build status of the commit of analysis
+// through the Bitbucket Cloud API.
+func sendBitbucketCloud(ctx context.Context, reporter types.StatusReporter, analysis types.Analysis) error {
+	repository, err := bitbucketCloudRepository(analysis.URL)
+	if err != nil {
+		return err
+	}
+	endpoint := reporter.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultBitbucketCloudEndpoint
+	}
+	statusURL := fmt.Sprintf("%s/2.0/repositories/%s/commit/%s/statuses/build",
+		strings.TrimSuffix(endpoint, "/"), repository, url.PathEscape(analysis.Commit))
+	_, err = do(ctx, reporter, http.MethodPost, statusURL, buildStatus(reporter, analysis))
+	return err
+}
+
+// sendBitbucketServer sets the huskyCI build status of the commit of analysis
+// through the build status API of Bitbucket Server or Data Center.
+func sendBitbucketServer(ctx context.Context, reporter types.StatusReporter, analysis types.Analysis) error {
+	statusURL := fmt.Sprintf("%s/rest/build-status/1.0/commits/%s",
+		strings.TrimSuffix(reporter.Endpoint, "/"), url.PathEscape(analysis.Commit))
+	_, err := do(ctx, reporter, http.MethodPost, statusURL, buildStatus(reporter, analysis))
+	return err
+}
+
+// bitbucketCloudRepository returns the "workspace/repo_slug" of a Bitbucket
+// Cloud repository URL, HTTPS or SSH.
+func bitbucketCloudRepository(repositoryURL string) (string, error) {
+	normalized := gitauth.NormalizeURL(repositoryURL)
+	path := ""
+	if parsed, err := url.Parse(normalized); err == nil && parsed.Host != "" {
+		path = parsed.Path
+	} else if i := strings.Index(normalized, ":"); i >= 0 {
+		path = normalized[i+1:]
+	}
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", fmt.Errorf("'%s' is not a Bitbucket Cloud repository URL", repositoryURL)
+	}
+	return url.PathEscape(parts[0]) + "/" + url.PathEscape(parts[1]), nil
+}
@@ -0,0 +1,62 @@
+package statusreport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/huskyci-org/huskyCI/api/types"
+)
+
+// gerritMagicPrefix starts every JSON response of Gerrit, to prevent XSSI.
+var gerritMagicPrefix = []byte(")]}'")
+
+// gerritChange is a change returned by a Gerrit change query.
+type gerritChange struct {
+	Number int `json:"_number"`
+}
+
+// gerritReview is the review posted on the revision of a change.
+type gerritReview struct {
+	Tag     string         `json:"tag"`
+	Message string         `json:"message"`
+	Labels  map[string]int `json:"labels,omitempty"`
+}
+
+// sendGerrit reviews the patch set of the commit of analysis, voting on the
+// label of reporter when the analysis finished.
+func sendGerrit(ctx context.Context, reporter types.StatusReporter, analysis types.Analysis) error {
+	endpoint := strings.TrimSuffix(reporter.Endpoint, "/")
+	queryURL := fmt.Sprintf("%s/a/changes/?q=%s", endpoint, url.QueryEscape("commit:"+analysis.Commit))
+	body, err := do(ctx, reporter, http.MethodGet, queryURL, nil)
+	if err != nil {
+		return err
+	}
+	changes := []gerritChange{}
+	if err := json.Unmarshal(bytes.TrimPrefix(body, gerritMagicPrefix), &changes); err != nil {
+		return fmt.Errorf("invalid Gerrit changes response: %w", err)
+	}
+	if len(changes) == 0 {
+		return fmt.Errorf("no Gerrit change found for commit %s", analysis.Commit)
+	}
+
+	result := analysisOutcome(analysis)
+	review := gerritReview{
+		Tag:     "autogenerated:huskyci",
+		Message: fmt.Sprintf("%s\n\n%s", result.description, detailsURL(reporter, analysis)),
+	}
+	if result.vote != 0 {
+		label := reporter.Label
+		if label == "" {
+			label = DefaultGerritLabel
+		}
+		review.Labels = map[string]int{label: result.vote}
+	}
+	reviewURL := fmt.Sprintf("%s/a/changes/%d/revisions/%s/review", endpoint, changes[0].Number, url.PathEscape(analysis.Commit))
+	_, err = do(ctx, reporter, http.MethodPost, reviewURL, review)
+	return err
+}
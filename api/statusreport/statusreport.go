@@ -0,0 +1,211 @@
+// Package statusreport posts the result of finished analyses to the code
+// review tool of their repository, Bitbucket or Gerrit, so that it shows up
+// inline next to the commit or change analyzed.
+package statusreport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	"github.com/huskyci-org/huskyCI/api/gitauth"
+	"github.com/huskyci-org/huskyCI/api/types"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// DefaultBitbucketCloudEndpoint is the API of Bitbucket Cloud, used when a
+// bitbucket-cloud status reporter has no endpoint of its own.
+const DefaultBitbucketCloudEndpoint = "https://api.bitbucket.org"
+
+// DefaultGerritLabel is the label voted on when a gerrit status reporter has no
+// label of its own.
+const DefaultGerritLabel = "Verified"
+
+// ErrNoCommit is returned by Report when the repository has a status reporter
+// but the analysis was started without the commit to report on.
+var ErrNoCommit = errors.New("the analysis was started without a commit")
+
+// HTTPClient sends the requests of the status reporters.
+var HTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// Find returns the status reporter of repositoryURL, its secret decrypted. The
+// boolean is false if the repository has no status reporter.
+func Find(repositoryURL string) (types.StatusReporter, bool, error) {
+	query := map[string]interface{}{"repositoryURL": gitauth.NormalizeURL(repositoryURL)}
+	reporter, err := apiContext.APIConfiguration.DBInstance.FindOneDBStatusReporter(query)
+	if err != nil {
+		if err == mongo.ErrNoDocuments || err.Error() == "No data found" {
+			return types.StatusReporter{}, false, nil
+		}
+		return types.StatusReporter{}, false, err
+	}
+	return reporter, true, nil
+}
+
+// Report posts the result of analysis with the status reporter of its
+// repository. It returns false when the repository has no status reporter.
+func Report(ctx context.Context, analysis types.Analysis) (bool, error) {
+	reporter, found, err := Find(analysis.URL)
+	if err != nil || !found {
+		return false, err
+	}
+	if analysis.Commit == "" {
+		return false, ErrNoCommit
+	}
+	return true, Send(ctx, reporter, analysis)
+}
+
+// Send posts the result of analysis with reporter.
+func Send(ctx context.Context, reporter types.StatusReporter, analysis types.Analysis) error {
+	switch reporter.Provider {
+	case types.StatusReporterBitbucketCloud:
+		return sendBitbucketCloud(ctx, reporter, analysis)
+	case types.StatusReporterBitbucketServer:
+		return sendBitbucketServer(ctx, reporter, analysis)
+	case types.StatusReporterGerrit:
+		return sendGerrit(ctx, reporter, analysis)
+	}
+	return fmt.Errorf("unknown status reporter provider '%s'", reporter.Provider)
+}
+
+// outcome is the result of an analysis as told to the code review tools.
+type outcome struct {
+	state       string
+	description string
+	vote        int
+}
+
+// Bitbucket build states.
+const (
+	stateSuccessful = "SUCCESSFUL"
+	stateFailed     = "FAILED"
+	stateStopped    = "STOPPED"
+)
+
+func analysisOutcome(analysis types.Analysis) outcome {
+	switch {
+	case analysis.Status == "cancelled":
+		return outcome{state: stateStopped, description: "huskyCI analysis cancelled"}
+	case analysis.Status == "finished" && analysis.Result == "passed":
+		return outcome{state: stateSuccessful, description: "huskyCI found no vulnerabilities", vote: 1}
+	case analysis.Status == "finished" && analysis.Result == "warning":
+		return outcome{state: stateSuccessful, description: "huskyCI found low severity vulnerabilities only", vote: 1}
+	case analysis.Status == "finished":
+		return outcome{state: stateFailed, description: "huskyCI found vulnerabilities", vote: -1}
+	}
+	description := "huskyCI analysis could not run"
+	if analysis.ErrorFound != "" {
+		description += ": " + analysis.ErrorFound
+	}
+	if len(description) > 255 {
+		description = description[:252] + "..."
+	}
+	return outcome{state: stateFailed, description: description}
+}
+
+// detailsURL returns the page the status of analysis links to: the DetailsURL
+// of reporter with %RID% replaced, or else the web page of the repository.
+func detailsURL(reporter types.StatusReporter, analysis types.Analysis) string {
+	if reporter.DetailsURL != "" {
+		return strings.ReplaceAll(reporter.DetailsURL, "%RID%", analysis.RID)
+	}
+	repositoryURL := gitauth.NormalizeURL(analysis.URL)
+	if strings.HasPrefix(repositoryURL, "https://") || strings.HasPrefix(repositoryURL, "http://") {
+		return repositoryURL
+	}
+	return reporter.Endpoint
+}
+
+// do sends a request with the credentials of reporter and returns the body of
+// the response, an error if its status is not a 2xx one.
+func do(ctx context.Context, reporter types.StatusReporter, method, endpoint string, body interface{}) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if reporter.Username != "" {
+		req.SetBasicAuth(reporter.Username, reporter.Secret)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+reporter.Secret)
+	}
+
+	resp, err := HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("%s %s returned %s: %s", method, endpoint, resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	return respBody, nil
+}
+
+// Validate checks that reporter can post the result of the analyses of its
+// repository: a known provider, the endpoint and credentials it requires and,
+// for Bitbucket Cloud, a repository URL it hosts.
+func Validate(reporter types.StatusReporter) error {
+	switch reporter.Provider {
+	case types.StatusReporterBitbucketCloud:
+		if _, err := bitbucketCloudRepository(reporter.RepositoryURL); err != nil {
+			return err
+		}
+	case types.StatusReporterBitbucketServer:
+		if reporter.Endpoint == "" {
+			return errors.New("'endpoint' is required for a bitbucket-server status reporter")
+		}
+	case types.StatusReporterGerrit:
+		if reporter.Endpoint == "" {
+			return errors.New("'endpoint' is required for a gerrit status reporter")
+		}
+		if reporter.Username == "" {
+			return errors.New("'username' is required for a gerrit status reporter")
+		}
+		if !gerritLabelRegexp.MatchString(reporter.Label) {
+			return errors.New("'label' must only contain letters, numbers and hyphens")
+		}
+	default:
+		return errors.New("'provider' must be bitbucket-cloud, bitbucket-server or gerrit")
+	}
+	if reporter.Endpoint != "" && !isHTTPURL(reporter.Endpoint) {
+		return errors.New("'endpoint' must be an HTTP(S) URL")
+	}
+	if reporter.DetailsURL != "" && !isHTTPURL(reporter.DetailsURL) {
+		return errors.New("'detailsURL' must be an HTTP(S) URL")
+	}
+	if reporter.Secret == "" {
+		return errors.New("'secret' can not be empty")
+	}
+	return nil
+}
+
+var gerritLabelRegexp = regexp.MustCompile(`^[a-zA-Z0-9-]*$`)
+
+func isHTTPURL(rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+	return err == nil && (parsed.Scheme == "https" || parsed.Scheme == "http") && parsed.Host != ""
+}
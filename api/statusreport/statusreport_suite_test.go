@@ -0,0 +1,13 @@
+package statusreport_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestStatusreport(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Statusreport Suite")
+}
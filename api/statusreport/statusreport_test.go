@@ -0,0 +1,160 @@
+package statusreport_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/huskyci-org/huskyCI/api/statusreport"
+	"github.com/huskyci-org/huskyCI/api/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// request is a request received by a fake code review tool.
+type request struct {
+	Method        string
+	Path          string
+	Query         string
+	Authorization string
+	Body          map[string]interface{}
+}
+
+// fakeServer records the requests it receives and answers them with reply.
+func fakeServer(requests *[]request, reply func(w http.ResponseWriter, r *http.Request)) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received := request{Method: r.Method, Path: r.URL.EscapedPath(), Query: r.URL.RawQuery, Authorization: r.Header.Get("Authorization")}
+		json.NewDecoder(r.Body).Decode(&received.Body)
+		*requests = append(*requests, received)
+		reply(w, r)
+	}))
+}
+
+var _ = Describe("Statusreport", func() {
+
+	var requests []request
+	var server *httptest.Server
+
+	failed := types.Analysis{RID: "RID", URL: "https://bitbucket.org/team/repo.git", Commit: "abc1234", Status: "finished", Result: "failed"}
+
+	BeforeEach(func() {
+		requests = nil
+	})
+
+	AfterEach(func() {
+		if server != nil {
+			server.Close()
+		}
+	})
+
+	Describe("Send", func() {
+		Context("When the repository is hosted on Bitbucket Cloud", func() {
+			It("Should set the build status of the commit", func() {
+				server = fakeServer(&requests, func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusCreated) })
+				reporter := types.StatusReporter{Provider: types.StatusReporterBitbucketCloud, Endpoint: server.URL, Secret: "token"}
+				Expect(statusreport.Send(context.Background(), reporter, failed)).To(Succeed())
+				Expect(requests).To(HaveLen(1))
+				Expect(requests[0].Method).To(Equal(http.MethodPost))
+				Expect(requests[0].Path).To(Equal("/2.0/repositories/team/repo/commit/abc1234/statuses/build"))
+				Expect(requests[0].Authorization).To(Equal("Bearer token"))
+				Expect(requests[0].Body).To(HaveKeyWithValue("state", "FAILED"))
+				Expect(requests[0].Body).To(HaveKeyWithValue("key", "HUSKYCI"))
+				Expect(requests[0].Body).To(HaveKeyWithValue("url", "https://bitbucket.org/team/repo"))
+			})
+		})
+
+		Context("When the repository is hosted on Bitbucket Server", func() {
+			It("Should set the build status of the commit", func() {
+				server = fakeServer(&requests, func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+				reporter := types.StatusReporter{Provider: types.StatusReporterBitbucketServer, Endpoint: server.URL, Username: "huskyci", Secret: "password", DetailsURL: "https://huskyci.example.com/analysis/%RID%"}
+				analysis := failed
+				analysis.Result = "passed"
+				Expect(statusreport.Send(context.Background(), reporter, analysis)).To(Succeed())
+				Expect(requests).To(HaveLen(1))
+				Expect(requests[0].Path).To(Equal("/rest/build-status/1.0/commits/abc1234"))
+				Expect(requests[0].Authorization).To(HavePrefix("Basic "))
+				Expect(requests[0].Body).To(HaveKeyWithValue("state", "SUCCESSFUL"))
+				Expect(requests[0].Body).To(HaveKeyWithValue("url", "https://huskyci.example.com/analysis/RID"))
+			})
+		})
+
+		Context("When the repository is reviewed on Gerrit", func() {
+			It("Should vote on the patch set of the commit", func() {
+				server = fakeServer(&requests, func(w http.ResponseWriter, r *http.Request) {
+					if r.Method == http.MethodGet {
+						w.Write([]byte(")]}'\n[{\"id\": \"repo~master~I0123\", \"_number\": 42}]"))
+					}
+				})
+				reporter := types.StatusReporter{Provider: types.StatusReporterGerrit, Endpoint: server.URL, Username: "huskyci", Secret: "password", Label: "Security-Review"}
+				Expect(statusreport.Send(context.Background(), reporter, failed)).To(Succeed())
+				Expect(requests).To(HaveLen(2))
+				Expect(requests[0].Path).To(Equal("/a/changes/"))
+				Expect(requests[0].Query).To(Equal("q=commit%3Aabc1234"))
+				Expect(requests[1].Path).To(Equal("/a/changes/42/revisions/abc1234/review"))
+				Expect(requests[1].Body).To(HaveKeyWithValue("labels", map[string]interface{}{"Security-Review": float64(-1)}))
+			})
+
+			It("Should not vote when the analysis could not run", func() {
+				server = fakeServer(&requests, func(w http.ResponseWriter, r *http.Request) {
+					if r.Method == http.MethodGet {
+						w.Write([]byte(")]}'\n[{\"_number\": 42}]"))
+					}
+				})
+				reporter := types.StatusReporter{Provider: types.StatusReporterGerrit, Endpoint: server.URL, Username: "huskyci", Secret: "password"}
+				analysis := failed
+				analysis.Status = "error running"
+				analysis.Result = "error"
+				Expect(statusreport.Send(context.Background(), reporter, analysis)).To(Succeed())
+				Expect(requests).To(HaveLen(2))
+				Expect(requests[1].Body).NotTo(HaveKey("labels"))
+				Expect(requests[1].Body["message"]).To(ContainSubstring("could not run"))
+			})
+
+			It("Should fail when no change has the commit", func() {
+				server = fakeServer(&requests, func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(")]}'\n[]")) })
+				reporter := types.StatusReporter{Provider: types.StatusReporterGerrit, Endpoint: server.URL, Username: "huskyci", Secret: "password"}
+				Expect(statusreport.Send(context.Background(), reporter, failed)).To(MatchError(ContainSubstring("no Gerrit change")))
+			})
+		})
+
+		Context("When the code review tool refuses the status", func() {
+			It("Should return its response", func() {
+				server = fakeServer(&requests, func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusUnauthorized)
+					w.Write([]byte("bad credentials"))
+				})
+				reporter := types.StatusReporter{Provider: types.StatusReporterBitbucketServer, Endpoint: server.URL, Secret: "token"}
+				Expect(statusreport.Send(context.Background(), reporter, failed)).To(MatchError(ContainSubstring("bad credentials")))
+			})
+		})
+	})
+
+	Describe("Validate", func() {
+		Context("When the status reporter is complete", func() {
+			It("Should return no error", func() {
+				reporter := types.StatusReporter{RepositoryURL: "git@bitbucket.org:team/repo", Provider: types.StatusReporterBitbucketCloud, Secret: "token"}
+				Expect(statusreport.Validate(reporter)).To(Succeed())
+			})
+		})
+		Context("When a Bitbucket Cloud repository URL has no workspace", func() {
+			It("Should return an error", func() {
+				reporter := types.StatusReporter{RepositoryURL: "https://bitbucket.org/repo", Provider: types.StatusReporterBitbucketCloud, Secret: "token"}
+				Expect(statusreport.Validate(reporter)).NotTo(Succeed())
+			})
+		})
+		Context("When a Gerrit status reporter has no username", func() {
+			It("Should return an error", func() {
+				reporter := types.StatusReporter{RepositoryURL: "https://gerrit.example.com/repo", Provider: types.StatusReporterGerrit, Endpoint: "https://gerrit.example.com", Secret: "password"}
+				Expect(statusreport.Validate(reporter)).To(MatchError(ContainSubstring("username")))
+			})
+		})
+		Context("When the provider is unknown", func() {
+			It("Should return an error", func() {
+				reporter := types.StatusReporter{RepositoryURL: "https://github.com/org/repo", Provider: "github", Secret: "token"}
+				Expect(statusreport.Validate(reporter)).To(MatchError(ContainSubstring("provider")))
+			})
+		})
+	})
+})
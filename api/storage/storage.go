@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/api/log"
+)
+
+const logInfoStorage = "STORAGE"
+
+// ErrObjectNotFound is returned when a requested key is not present in the backend.
+var ErrObjectNotFound = errors.New("object not found")
+
+// Storage is implemented by every object storage backend huskyCI can use
+// to keep uploaded zips and raw tool outputs shared between API replicas.
+type Storage interface {
+	Put(key string, body io.Reader, size int64) error
+	Get(key string) (io.ReadCloser, error)
+	Delete(key string) error
+	Expire(prefix string, olderThan time.Time) (int, error)
+}
+
+// Config represents an object storage configuration.
+type Config struct {
+	Backend    string
+	Endpoint   string
+	Region     string
+	Bucket     string
+	AccessKey  string
+	SecretKey  string
+	Prefix     string
+	UseSSL     bool
+	Expiration time.Duration
+	RawOutputs bool
+}
+
+// New returns the Storage implementation for the configured backend. It
+// returns a nil Storage if the backend is empty or "local", meaning that
+// uploaded zips are kept only on the API local disk.
+func New(config *Config) (Storage, error) {
+	if config == nil {
+		return nil, nil
+	}
+	switch strings.ToLower(config.Backend) {
+	case "", "local":
+		return nil, nil
+	case "s3":
+		if config.Endpoint == "" {
+			region := config.Region
+			if region == "" {
+				region = "us-east-1"
+			}
+			config.Endpoint = fmt.Sprintf("s3.%s.amazonaws.com", region)
+		}
+	case "gcs":
+		// GCS is reached through its S3 compatible XML API using HMAC keys.
+		if config.Endpoint == "" {
+			config.Endpoint = "storage.googleapis.com"
+		}
+		if config.Region == "" {
+			config.Region = "auto"
+		}
+	case "minio":
+		if config.Endpoint == "" {
+			return nil, errors.New("minio storage requires an endpoint")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported storage backend: %s", config.Backend)
+	}
+	if config.Bucket == "" {
+		return nil, errors.New("object storage requires a bucket")
+	}
+	return NewS3(config), nil
+}
+
+// ZipKey returns the object key used to store the uploaded zip of a RID.
+func ZipKey(RID string) string {
+	return fmt.Sprintf("zips/%s.zip", RID)
+}
+
+// OutputKey returns the object key used to store the raw output of a
+// securityTest container that ran for a RID.
+func OutputKey(RID, securityTestName string) string {
+	return fmt.Sprintf("outputs/%s/%s.txt", RID, securityTestName)
+}
+
+// UploadFile stores the local file at filePath under key.
+func UploadFile(s Storage, key, filePath string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	return s.Put(key, file, info.Size())
+}
+
+// DownloadFile writes the object stored under key to the local file at filePath.
+func DownloadFile(s Storage, key, filePath string) error {
+	body, err := s.Get(key)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, body); err != nil {
+		file.Close()
+		os.Remove(filePath)
+		return err
+	}
+	return file.Close()
+}
+
+// RunExpiration removes zips and raw outputs older than expiration from s
+// every interval. It never returns and is meant to run in its own goroutine.
+func RunExpiration(s Storage, expiration, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		olderThan := time.Now().Add(-expiration)
+		for _, prefix := range []string{"zips/", "outputs/"} {
+			removed, err := s.Expire(prefix, olderThan)
+			if err != nil {
+				log.Error("RunExpiration", logInfoStorage, 1044, prefix, err)
+				continue
+			}
+			if removed > 0 {
+				log.Info("RunExpiration", logInfoStorage, 27, prefix, removed)
+			}
+		}
+		<-ticker.C
+	}
+}
@@ -0,0 +1,141 @@
+package storage_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/huskyci-org/huskyCI/api/storage"
+)
+
+type fakeBucket struct {
+	mu      sync.Mutex
+	objects map[string]string
+}
+
+func (b *fakeBucket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		b.objects[key] = string(body)
+	case http.MethodGet:
+		if r.URL.Query().Get("list-type") == "2" {
+			io.WriteString(w, `<ListBucketResult>`)
+			for k := range b.objects {
+				if strings.HasPrefix(k, r.URL.Query().Get("prefix")) {
+					io.WriteString(w, `<Contents><Key>`+k+`</Key><LastModified>2020-01-01T00:00:00.000Z</LastModified></Contents>`)
+				}
+			}
+			io.WriteString(w, `</ListBucketResult>`)
+			return
+		}
+		object, ok := b.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		io.WriteString(w, object)
+	case http.MethodDelete:
+		delete(b.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+var _ = Describe("Storage", func() {
+	Describe("New", func() {
+		Context("When the backend is empty or local", func() {
+			It("Should return a nil Storage and a nil error", func() {
+				objectStorage, err := storage.New(&storage.Config{Backend: "local"})
+				Expect(objectStorage).To(BeNil())
+				Expect(err).To(BeNil())
+			})
+		})
+		Context("When the backend is unknown", func() {
+			It("Should return an error", func() {
+				_, err := storage.New(&storage.Config{Backend: "ftp", Bucket: "bucket"})
+				Expect(err).To(HaveOccurred())
+			})
+		})
+		Context("When the backend is minio without an endpoint", func() {
+			It("Should return an error", func() {
+				_, err := storage.New(&storage.Config{Backend: "minio", Bucket: "bucket"})
+				Expect(err).To(HaveOccurred())
+			})
+		})
+		Context("When the backend is gcs", func() {
+			It("Should use the GCS XML API endpoint", func() {
+				config := &storage.Config{Backend: "gcs", Bucket: "bucket"}
+				objectStorage, err := storage.New(config)
+				Expect(err).To(BeNil())
+				Expect(objectStorage).ToNot(BeNil())
+				Expect(config.Endpoint).To(Equal("storage.googleapis.com"))
+			})
+		})
+	})
+
+	Describe("S3", func() {
+		var (
+			bucket        *fakeBucket
+			server        *httptest.Server
+			objectStorage storage.Storage
+		)
+		BeforeEach(func() {
+			bucket = &fakeBucket{objects: map[string]string{}}
+			server = httptest.NewServer(bucket)
+			var err error
+			objectStorage, err = storage.New(&storage.Config{
+				Backend:   "minio",
+				Endpoint:  server.URL,
+				Bucket:    "bucket",
+				AccessKey: "key",
+				SecretKey: "secret",
+				Prefix:    "huskyci",
+			})
+			Expect(err).To(BeNil())
+		})
+		AfterEach(func() {
+			server.Close()
+		})
+		Context("When an object is stored", func() {
+			It("Should be retrieved with the same content", func() {
+				content := "zip content"
+				Expect(objectStorage.Put(storage.ZipKey("rid"), strings.NewReader(content), int64(len(content)))).To(Succeed())
+				Expect(bucket.objects).To(HaveKey("huskyci/zips/rid.zip"))
+				body, err := objectStorage.Get(storage.ZipKey("rid"))
+				Expect(err).To(BeNil())
+				defer body.Close()
+				Expect(io.ReadAll(body)).To(Equal([]byte(content)))
+			})
+		})
+		Context("When an object does not exist", func() {
+			It("Should return ErrObjectNotFound", func() {
+				_, err := objectStorage.Get(storage.ZipKey("missing"))
+				Expect(err).To(Equal(storage.ErrObjectNotFound))
+			})
+		})
+		Context("When objects are older than the expiration", func() {
+			It("Should remove them", func() {
+				bucket.objects["huskyci/zips/old.zip"] = "old"
+				bucket.objects["huskyci/outputs/old/gosec.txt"] = "old"
+				removed, err := objectStorage.Expire("zips/", time.Now())
+				Expect(err).To(BeNil())
+				Expect(removed).To(Equal(1))
+				Expect(bucket.objects).ToNot(HaveKey("huskyci/zips/old.zip"))
+				Expect(bucket.objects).To(HaveKey("huskyci/outputs/old/gosec.txt"))
+			})
+		})
+	})
+})
@@ -0,0 +1,39 @@
+package tlscert
+
+import (
+	"crypto/tls"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// NewACMEManager returns the manager obtaining and renewing the certificates
+// of the ACMEDomains of config, from Let's Encrypt unless ACMEDirectoryURL is
+// set. Certificates are kept in ACMECacheDir across restarts.
+func NewACMEManager(config *Config) *autocert.Manager {
+	cacheDir := config.ACMECacheDir
+	if cacheDir == "" {
+		cacheDir = DefaultACMECacheDir
+	}
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(config.ACMEDomains...),
+		Cache:      autocert.DirCache(cacheDir),
+		Email:      config.ACMEEmail,
+	}
+	if config.ACMEDirectoryURL != "" {
+		manager.Client = &acme.Client{DirectoryURL: config.ACMEDirectoryURL}
+	}
+	return manager
+}
+
+// ACMEServerConfig returns the TLS configuration serving the certificates of
+// manager, which also answers the tls-alpn-01 challenges of the ACME server.
+func ACMEServerConfig(manager *autocert.Manager, clientCAFile string) (*tls.Config, error) {
+	config, err := ServerConfig(manager.GetCertificate, clientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	config.NextProtos = []string{"h2", "http/1.1", acme.ALPNProto}
+	return config, nil
+}
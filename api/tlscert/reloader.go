@@ -0,0 +1,129 @@
+package tlscert
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/huskyci-org/huskyCI/api/log"
+)
+
+const (
+	logActionReload = "Reload"
+	logInfoTLS      = "TLS"
+)
+
+// Reloader serves the certificate of a certificate and key file, and loads
+// it again when they change, so that rotating it needs no restart.
+type Reloader struct {
+	certFile string
+	keyFile  string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+// NewReloader returns a Reloader of certFile and keyFile, with their
+// certificate loaded.
+func NewReloader(certFile, keyFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload loads the certificate of the files of r. The certificate served so
+// far is kept if they do not hold a valid one, such as while they are
+// being written.
+func (r *Reloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("could not load the TLS certificate: %w", err)
+	}
+	r.mu.Lock()
+	r.cert = &cert
+	r.mu.Unlock()
+	return nil
+}
+
+// GetCertificate returns the certificate last loaded, as the
+// tls.Config.GetCertificate of the API.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// Watch reloads the certificate whenever its files change, or the API
+// receives SIGHUP, until ctx is done. The directories of the files are
+// watched rather than the files, which are often replaced rather than
+// written, such as the Kubernetes secrets swapping a symlink.
+func (r *Reloader) Watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	for _, dir := range uniqueDirs(r.certFile, r.keyFile) {
+		if err := watcher.Add(dir); err != nil {
+			return fmt.Errorf("could not watch %s: %w", dir, err)
+		}
+	}
+
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-hangup:
+			r.reloadAndLog("SIGHUP")
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Has(fsnotify.Chmod) {
+				continue
+			}
+			r.reloadAndLog(event.Name)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Error(logActionReload, logInfoTLS, 1067, err)
+		}
+	}
+}
+
+// reloadAndLog reloads the certificate because of cause, and logs whether
+// it succeeded.
+func (r *Reloader) reloadAndLog(cause string) {
+	if err := r.Reload(); err != nil {
+		log.Error(logActionReload, logInfoTLS, 1067, cause, err)
+		return
+	}
+	log.Info(logActionReload, logInfoTLS, 56, cause)
+}
+
+// uniqueDirs returns the directories of files, each once.
+func uniqueDirs(files ...string) []string {
+	dirs := []string{}
+	seen := map[string]bool{}
+	for _, file := range files {
+		dir := filepath.Dir(file)
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
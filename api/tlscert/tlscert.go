@@ -0,0 +1,56 @@
+// Package tlscert serves the TLS certificate of the huskyCI API: the one of
+// its certificate and key files, reloaded whenever they are rotated, or the
+// ones it obtains with ACME, such as from Let's Encrypt.
+package tlscert
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// DefaultACMECacheDir is where the certificates obtained with ACME are kept
+// when no cache directory is configured.
+const DefaultACMECacheDir = "api/acme-cache"
+
+// Config represents how the certificate of the API is obtained. Without
+// ACMEDomains, it is read from its certificate and key files.
+type Config struct {
+	ACMEDomains      []string
+	ACMEEmail        string
+	ACMECacheDir     string
+	ACMEDirectoryURL string
+	ACMEHTTPAddr     string
+}
+
+// ACME returns true if the certificate of the API is obtained with ACME.
+func (c *Config) ACME() bool {
+	return c != nil && len(c.ACMEDomains) > 0
+}
+
+// ServerConfig returns the TLS configuration the API is served with, with the
+// certificates getCertificate returns. When clientCAFile is set, the
+// certificates the clients present are verified against the CAs it bundles.
+// A client may still connect without one, as the routes requiring it are
+// guarded by auth.RequireClientCert.
+func ServerConfig(getCertificate func(*tls.ClientHelloInfo) (*tls.Certificate, error), clientCAFile string) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: getCertificate,
+	}
+	if clientCAFile == "" {
+		return config, nil
+	}
+	bundle, err := os.ReadFile(clientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the client CA bundle: %w", err)
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(bundle) {
+		return nil, fmt.Errorf("no certificate found in the client CA bundle %s", clientCAFile)
+	}
+	config.ClientCAs = clientCAs
+	config.ClientAuth = tls.VerifyClientCertIfGiven
+	return config, nil
+}
@@ -0,0 +1,130 @@
+package tlscert_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/api/tlscert"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// writeSelfSigned writes a self-signed certificate of commonName and its key
+// to dir and returns their paths.
+func writeSelfSigned(dir, commonName string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).NotTo(HaveOccurred())
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	Expect(err).NotTo(HaveOccurred())
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	Expect(os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)).To(Succeed())
+	Expect(os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)).To(Succeed())
+	return certFile, keyFile
+}
+
+// commonName returns the common name of the certificate r serves.
+func commonName(r *tlscert.Reloader) string {
+	cert, err := r.GetCertificate(nil)
+	Expect(err).NotTo(HaveOccurred())
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	Expect(err).NotTo(HaveOccurred())
+	return leaf.Subject.CommonName
+}
+
+var _ = Describe("TLSCert", func() {
+	var dir, certFile, keyFile string
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "huskyci-tls")
+		Expect(err).NotTo(HaveOccurred())
+		certFile, keyFile = writeSelfSigned(dir, "first")
+	})
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	Describe("ServerConfig", func() {
+		Context("When no client CA bundle is set", func() {
+			It("Should not ask the clients for a certificate", func() {
+				config, err := tlscert.ServerConfig(nil, "")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.ClientAuth).To(Equal(tls.NoClientCert))
+			})
+		})
+
+		Context("When a client CA bundle is set", func() {
+			It("Should verify the certificates the clients give", func() {
+				config, err := tlscert.ServerConfig(nil, certFile)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.ClientAuth).To(Equal(tls.VerifyClientCertIfGiven))
+				Expect(config.ClientCAs).NotTo(BeNil())
+			})
+			It("Should fail when the bundle has no certificate", func() {
+				_, err := tlscert.ServerConfig(nil, keyFile)
+				Expect(err).To(HaveOccurred())
+			})
+			It("Should fail when the bundle does not exist", func() {
+				_, err := tlscert.ServerConfig(nil, filepath.Join(dir, "missing.pem"))
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("Reloader", func() {
+		It("Should fail when the files hold no certificate", func() {
+			_, err := tlscert.NewReloader(keyFile, keyFile)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Should keep the certificate it serves when the files are invalid", func() {
+			reloader, err := tlscert.NewReloader(certFile, keyFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(certFile, []byte("partial"), 0600)).To(Succeed())
+			Expect(reloader.Reload()).NotTo(Succeed())
+			Expect(commonName(reloader)).To(Equal("first"))
+		})
+
+		It("Should serve the new certificate once the files are rotated", func() {
+			reloader, err := tlscert.NewReloader(certFile, keyFile)
+			Expect(err).NotTo(HaveOccurred())
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go reloader.Watch(ctx)
+			time.Sleep(100 * time.Millisecond)
+
+			writeSelfSigned(dir, "rotated")
+			Eventually(func() string { return commonName(reloader) }, 5*time.Second).Should(Equal("rotated"))
+		})
+	})
+
+	Describe("Config", func() {
+		It("Should only obtain the certificates with ACME when domains are set", func() {
+			Expect((*tlscert.Config)(nil).ACME()).To(BeFalse())
+			Expect((&tlscert.Config{}).ACME()).To(BeFalse())
+			Expect((&tlscert.Config{ACMEDomains: []string{"huskyci.example.com"}}).ACME()).To(BeTrue())
+		})
+	})
+})
@@ -124,6 +124,27 @@ func (tH *THandler) ValidateToken(token, repositoryURL string) error {
 	return tH.ValidateRandomData(randomData, accessToken.HuskyToken, accessToken.Salt)
 }
 
+// TokenScope will validate the received token and
+// return the repository URL it was generated for. A
+// generic token returns an empty repository URL.
+func (tH *THandler) TokenScope(token string) (string, error) {
+	uUID, randomData, err := tH.GetSplitted(token)
+	if err != nil {
+		return "", err
+	}
+	accessToken, err := tH.External.FindAccessToken(uUID)
+	if err != nil {
+		return "", err
+	}
+	if !accessToken.IsValid {
+		return "", errors.New("Access token is invalid")
+	}
+	if err := tH.ValidateRandomData(randomData, accessToken.HuskyToken, accessToken.Salt); err != nil {
+		return "", err
+	}
+	return accessToken.URL, nil
+}
+
 // VerifyRepo will verify if exists an entry
 // for the received repository. It also checks for generic tokens
 // (tokens with empty URL) that can work with any repository.
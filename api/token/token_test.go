@@ -594,6 +594,49 @@ var _ = Describe("Token", func() {
 			})
 		})
 	})
+	Describe("TokenScope", func() {
+		Context("When access token from DB is not valid", func() {
+			It("Should return the expected error", func() {
+				fakeExt := FakeExternal{
+					expectedAccessToken: types.DBToken{
+						IsValid:    false,
+						HuskyToken: "StoredHash",
+					},
+					expectedDecodedString: "UUID:RandomVal",
+				}
+				tokenVal := THandler{
+					External: &fakeExt,
+				}
+				_, err := tokenVal.TokenScope("EncodedRcvToken")
+				Expect(err).To(Equal(errors.New("Access token is invalid")))
+			})
+		})
+		Context("When the access token is valid", func() {
+			It("Should return the repository URL stored in DB", func() {
+				fakeExt := FakeExternal{
+					expectedAccessToken: types.DBToken{
+						IsValid:    true,
+						HuskyToken: "StoredHash",
+						URL:        "MyValidURL",
+						Salt:       "MySalt",
+					},
+					expectedDecodedString: "UUID:RandomVal",
+				}
+				fakeHash := FakeHashGen{
+					expectedDecodedSalt: []byte("MySaltDecoded"),
+					expectedHashName:    "Sha512",
+					expectedKeyLength:   256,
+					expectedHashValue:   "StoredHash",
+				}
+				tokenVal := THandler{
+					External: &fakeExt,
+					HashGen:  &fakeHash,
+				}
+				Expect(tokenVal.TokenScope("EncodedRcvToken")).To(Equal("MyValidURL"))
+			})
+		})
+	})
+
 	Describe("VerifyRepo", func() {
 		Context("When ValidateURL returns an error", func() {
 			It("Should return the same error", func() {
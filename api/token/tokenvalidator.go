@@ -25,3 +25,11 @@ func (tV TValidator) HasAuthorization(accessToken, repositoryURL string) bool {
 	}
 	return true
 }
+
+// AuthorizedRepository will validate the received
+// access token and return the repository URL it is
+// scoped to. An empty string is returned for a generic
+// token, that can be used with any repository.
+func (tV TValidator) AuthorizedRepository(accessToken string) (string, error) {
+	return tV.TokenVerifier.TokenScope(accessToken)
+}
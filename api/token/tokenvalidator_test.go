@@ -13,6 +13,8 @@ import (
 type FakeVerifier struct {
 	expectedValidateError error
 	expectedVerifyError   error
+	expectedScope         string
+	expectedScopeError    error
 }
 
 func (fV *FakeVerifier) GenerateAccessToken(repo types.TokenRequest) (string, error) {
@@ -27,6 +29,10 @@ func (fV *FakeVerifier) VerifyRepo(repositoryURL string) error {
 	return fV.expectedVerifyError
 }
 
+func (fV *FakeVerifier) TokenScope(token string) (string, error) {
+	return fV.expectedScope, fV.expectedScopeError
+}
+
 var _ = Describe("Tokenvalidator", func() {
 	Describe("HasAuthorization", func() {
 		Context("When VerifyRepo returns an error", func() {
@@ -40,6 +40,7 @@ type TInterface interface {
 	GenerateAccessToken(repo types.TokenRequest) (string, error)
 	ValidateToken(token, repositoryURL string) error
 	VerifyRepo(repositoryURL string) error
+	TokenScope(token string) (string, error)
 }
 
 // TValidator is used to validate an access token
@@ -0,0 +1,81 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/huskyci-org/huskyCI/api"
+
+// Config represents the OpenTelemetry tracing configuration.
+type Config struct {
+	Endpoint    string
+	ServiceName string
+	Insecure    bool
+}
+
+// Init configures the global OpenTelemetry tracer provider to export spans to
+// the OTLP/HTTP collector set in config. If no endpoint is configured, tracing
+// stays disabled and the global no-op provider is kept. The returned function
+// flushes pending spans and must be called on shutdown.
+func Init(config *Config, version string) (func(context.Context) error, error) {
+	if config == nil || config.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(config.Endpoint)}
+	if config.Insecure {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(context.Background(), options...)
+	if err != nil {
+		return nil, err
+	}
+
+	res := resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(config.ServiceName),
+		semconv.ServiceVersion(version),
+	)
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// StartSpan starts a new span called name as a child of the span found in ctx.
+func StartSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// StartLinkedSpan starts a new root span called name linked to the span found
+// in parent. It is used for work that outlives the request that triggered it,
+// such as an analysis started in background by POST /analysis.
+func StartLinkedSpan(parent context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	link := trace.LinkFromContext(parent)
+	return otel.Tracer(tracerName).Start(context.Background(), name,
+		trace.WithNewRoot(), trace.WithLinks(link), trace.WithAttributes(attributes...))
+}
+
+// EndSpan records err into span, if any, and ends it.
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
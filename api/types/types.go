@@ -5,12 +5,45 @@ import (
 )
 
 // Repository is the struct that stores all data from repository to be analyzed.
+// An upload analysis is run on the zip uploaded under UploadID instead of
+// cloning URL.
 type Repository struct {
 	URL                string          `bson:"repositoryURL" json:"repositoryURL"`
 	Branch             string          `json:"repositoryBranch"`
 	LanguageExclusions map[string]bool `json:"languageExclusions"`
-	EnryOutput         string          `bson:"enryOutput,omitempty" json:"enryOutput,omitempty"` // Optional: Enry JSON output from CLI for file:// URLs
+	AnalysisType       string          `bson:"analysisType,omitempty" json:"analysisType,omitempty"`   // Optional: git or upload, git by default
+	UploadID           string          `bson:"uploadID,omitempty" json:"uploadID,omitempty"`           // Optional: RID the zip of an upload analysis was uploaded under
+	EnryOutput         string          `bson:"enryOutput,omitempty" json:"enryOutput,omitempty"`       // Optional: Enry JSON output from the client
+	EnryTree           string          `bson:"enryTree,omitempty" json:"enryTree,omitempty"`           // Optional: hash of the tree of Commit EnryOutput was computed on, required with it for git analyses
+	Commit             string          `bson:"commit,omitempty" json:"commit,omitempty"`               // Optional: commit analyzed, checked out right after the clone, and the analysis status is reported on
+	SecurityTests      []string        `bson:"securityTests,omitempty" json:"securityTests,omitempty"` // Optional: securityTests to run, all of them by default
+	PullRequest        *PullRequest    `bson:"pullRequest,omitempty" json:"pullRequest,omitempty"`     // Optional: pull request whose merge is analyzed, for git analyses
 	CreatedAt          time.Time       `bson:"createdAt" json:"createdAt"`
+	Idempotency        Idempotency     `bson:"-" json:"-"` // Idempotency-Key of the request that started the analysis, if it had one
+}
+
+// Analysis types. A git analysis clones the URL of its repository and an
+// upload analysis scans a zip uploaded with POST /analysis/upload.
+const (
+	AnalysisTypeGit    = "git"
+	AnalysisTypeUpload = "upload"
+)
+
+// Network modes a securityTest container can run with. NetworkModeNone
+// disables networking, NetworkModeEgressProxy only allows egress through the
+// configured proxy and NetworkModeFull keeps the default network.
+const (
+	NetworkModeNone        = "none"
+	NetworkModeEgressProxy = "egress-proxy"
+	NetworkModeFull        = "full"
+)
+
+// VolumeMount is a host directory mounted into a securityTest container,
+// read-only unless Writable is set.
+type VolumeMount struct {
+	HostPath      string
+	ContainerPath string
+	Writable      bool
 }
 
 // SecurityTest is the struct that stores all data from the security tests to be executed.
@@ -18,11 +51,127 @@ type SecurityTest struct {
 	Name             string `bson:"name" json:"name"`
 	Image            string `bson:"image" json:"image"`
 	ImageTag         string `bson:"imageTag" json:"imageTag"`
+	ImageDigest      string `bson:"imageDigest" json:"imageDigest"`
 	Cmd              string `bson:"cmd" json:"cmd"`
 	Type             string `bson:"type" json:"type"`
 	Language         string `bson:"language" json:"language"`
 	Default          bool   `bson:"default" json:"default"`
 	TimeOutInSeconds int    `bson:"timeOutSeconds" json:"timeOutSeconds"`
+	NetworkMode      string `bson:"networkMode" json:"networkMode"`
+	MaxOutputSizeKB  int    `bson:"maxOutputSizeKB" json:"maxOutputSizeKB"`
+	WarmPool         bool   `bson:"warmPool" json:"warmPool"`
+}
+
+// Git credential types. A GitCredentialSSH secret is a deploy key and a
+// GitCredentialToken secret is an HTTPS access token.
+const (
+	GitCredentialSSH   = "ssh"
+	GitCredentialToken = "token"
+)
+
+// GitCredential is the struct that stores the credential used to clone a
+// private repository. Secret is encrypted in the database, see fieldcrypt.
+type GitCredential struct {
+	RepositoryURL string    `bson:"repositoryURL" json:"repositoryURL"`
+	Type          string    `bson:"type" json:"type"`
+	Username      string    `bson:"username" json:"username"`
+	Secret        string    `bson:"secret" json:"secret"`
+	UpdatedAt     time.Time `bson:"updatedAt" json:"updatedAt"`
+}
+
+// RegistryCredential is the struct that stores the credential used to pull
+// the images of a private container registry, by its host such as
+// registry.example.com:5000. Secret is a password or an access token and is
+// encrypted in the database, see fieldcrypt.
+type RegistryCredential struct {
+	Registry  string    `bson:"registry" json:"registry"`
+	Username  string    `bson:"username" json:"username"`
+	Secret    string    `bson:"secret" json:"secret"`
+	UpdatedAt time.Time `bson:"updatedAt" json:"updatedAt"`
+}
+
+// Status reporter providers, the code review tools the result of the analyses
+// of a repository can be posted to.
+const (
+	StatusReporterBitbucketCloud  = "bitbucket-cloud"
+	StatusReporterBitbucketServer = "bitbucket-server"
+	StatusReporterGerrit          = "gerrit"
+)
+
+// StatusReporter is the struct that stores where the result of the analyses of
+// a repository is posted once they finish. Secret is encrypted in the
+// database, see fieldcrypt.
+type StatusReporter struct {
+	RepositoryURL string    `bson:"repositoryURL" json:"repositoryURL"`
+	Provider      string    `bson:"provider" json:"provider"`
+	Endpoint      string    `bson:"endpoint" json:"endpoint"`
+	Username      string    `bson:"username" json:"username"`
+	Secret        string    `bson:"secret" json:"secret"`
+	Label         string    `bson:"label" json:"label"`
+	DetailsURL    string    `bson:"detailsURL" json:"detailsURL"`
+	UpdatedAt     time.Time `bson:"updatedAt" json:"updatedAt"`
+}
+
+// Remediation providers, the git hosting services remediation pull requests
+// can be opened on.
+const (
+	RemediationGitHub = "github"
+	RemediationGitLab = "gitlab"
+)
+
+// Remediation is the struct that stores the opt-in of a repository to pull
+// requests upgrading the vulnerable dependencies its analyses find. Secret is
+// encrypted in the database, see fieldcrypt.
+type Remediation struct {
+	RepositoryURL string    `bson:"repositoryURL" json:"repositoryURL"`
+	Provider      string    `bson:"provider" json:"provider"`
+	Endpoint      string    `bson:"endpoint" json:"endpoint"`
+	Secret        string    `bson:"secret" json:"secret"`
+	BaseBranch    string    `bson:"baseBranch" json:"baseBranch"`
+	UpdatedAt     time.Time `bson:"updatedAt" json:"updatedAt"`
+}
+
+// Severities the analyses of a repository fail on, from the strictest.
+const (
+	FailOnLow    = "low"
+	FailOnMedium = "medium"
+	FailOnHigh   = "high"
+	FailOnNever  = "never"
+)
+
+// Policy is the struct that stores the lowest severity of the vulnerabilities
+// failing the CI of a repository, which clients use unless they are told
+// otherwise, the rules whose findings never fail it, such as G104, and the
+// severity SeverityOverrides gives to the findings of some rules instead of
+// the one their securityTest found, such as high for generic-api-key. The
+// analyses of the repository selecting their securityTests must select the
+// RequiredSecurityTests.
+type Policy struct {
+	RepositoryURL         string            `bson:"repositoryURL" json:"repositoryURL"`
+	FailOn                string            `bson:"failOn" json:"failOn"`
+	IgnoreRules           []string          `bson:"ignoreRules,omitempty" json:"ignoreRules,omitempty"`
+	SeverityOverrides     map[string]string `bson:"severityOverrides,omitempty" json:"severityOverrides,omitempty"`
+	RequiredSecurityTests []string          `bson:"requiredSecurityTests,omitempty" json:"requiredSecurityTests,omitempty"`
+	UpdatedAt             time.Time         `bson:"updatedAt" json:"updatedAt"`
+}
+
+// VulnIgnoredByPolicy is the status of the findings of a rule the policy of
+// their repository ignores. They are kept with the NoSecVulns.
+const VulnIgnoredByPolicy = "ignored-by-policy"
+
+// RepositoryGroup is a named set of repositories, such as the ones of a team,
+// whose default branches are analyzed at once.
+type RepositoryGroup struct {
+	Name         string            `bson:"name" json:"name"`
+	Description  string            `bson:"description,omitempty" json:"description,omitempty"`
+	Repositories []GroupRepository `bson:"repositories" json:"repositories"`
+	UpdatedAt    time.Time         `bson:"updatedAt" json:"updatedAt"`
+}
+
+// GroupRepository is a repository of a RepositoryGroup and its default branch.
+type GroupRepository struct {
+	URL    string `bson:"repositoryURL" json:"repositoryURL"`
+	Branch string `bson:"repositoryBranch" json:"repositoryBranch"`
 }
 
 // Analysis is the struct that stores all data from analysis performed.
@@ -30,6 +179,7 @@ type Analysis struct {
 	RID            string         `bson:"RID" json:"RID"`
 	URL            string         `bson:"repositoryURL" json:"repositoryURL"`
 	Branch         string         `bson:"repositoryBranch" json:"repositoryBranch"`
+	Commit         string         `bson:"commit,omitempty" json:"commit,omitempty"`
 	CommitAuthors  []string       `bson:"commitAuthors" json:"commitAuthors"`
 	Status         string         `bson:"status" json:"status"`
 	Result         string         `bson:"result,omitempty" json:"result"`
@@ -39,6 +189,104 @@ type Analysis struct {
 	FinishedAt     time.Time      `bson:"finishedAt" json:"finishedAt"`
 	Codes          []Code         `bson:"codes" json:"codes"`
 	HuskyCIResults HuskyCIResults `bson:"huskyciresults,omitempty" json:"huskyciresults"`
+	// Warnings tell why the results of a finished analysis are partial, such
+	// as the securityTests that timed out. Complete analyses have none.
+	Warnings []string `bson:"warnings,omitempty" json:"warnings,omitempty"`
+	// EstimatedSecondsRemaining is how long a running analysis should still
+	// take, estimated when it is read from the last analyses of its
+	// repository. It is never stored.
+	EstimatedSecondsRemaining *int `bson:"-" json:"estimatedSecondsRemaining,omitempty"`
+	// CompressedResults holds the HuskyCIResults and Codes of the analyses
+	// too large to store them inline. The DB layer restores them on reads.
+	CompressedResults []byte `bson:"compressedResults,omitempty" json:"-"`
+	// IdempotencyKey and IdempotencyHash are the Idempotency-Key header of
+	// the POST /analysis that started the analysis and the hash of its body,
+	// so that the same request sent again returns this analysis. The API
+	// never returns them.
+	IdempotencyKey  string `bson:"idempotencyKey,omitempty" json:"-"`
+	IdempotencyHash string `bson:"idempotencyHash,omitempty" json:"-"`
+	// QueuedRequest is the request of a queued analysis, started once the
+	// analysis of its branch running finishes.
+	QueuedRequest *Repository `bson:"queuedRequest,omitempty" json:"queuedRequest,omitempty"`
+	// PullRequest is the pull request whose merge was analyzed, and
+	// TargetDiff the comparison of its findings with those of its target
+	// branch, set once the analysis finished.
+	PullRequest *PullRequest `bson:"pullRequest,omitempty" json:"pullRequest,omitempty"`
+	TargetDiff  *TargetDiff  `bson:"targetDiff,omitempty" json:"targetDiff,omitempty"`
+}
+
+// PullRequest describes the pull request a git analysis scans the merge of.
+// MergeRef is the ref the host keeps the merge at, such as refs/pull/42/merge
+// on GitHub. Without it, TargetBranch is merged into SourceBranch after the
+// clone.
+type PullRequest struct {
+	Number       int    `bson:"number" json:"number"`
+	SourceBranch string `bson:"sourceBranch" json:"sourceBranch"`
+	TargetBranch string `bson:"targetBranch" json:"targetBranch"`
+	MergeRef     string `bson:"mergeRef,omitempty" json:"mergeRef,omitempty"`
+}
+
+// TargetDiff compares the findings of the analysis of a pull request with
+// those of the latest finished analysis of its target branch, RID, matched by
+// fingerprint. Without an analysis of the target branch, RID is empty and
+// every finding is new.
+type TargetDiff struct {
+	RID       string                 `bson:"RID,omitempty" json:"RID,omitempty"`
+	New       []HuskyCIVulnerability `bson:"new" json:"new"`
+	Fixed     []HuskyCIVulnerability `bson:"fixed" json:"fixed"`
+	Unchanged int                    `bson:"unchanged" json:"unchanged"`
+}
+
+// Idempotency is the Idempotency-Key header of a POST /analysis and the hash
+// of its body.
+type Idempotency struct {
+	Key  string
+	Hash string
+}
+
+// AnalysisSummary is an analysis without its containers, codes and results,
+// as returned when analyses are listed.
+type AnalysisSummary struct {
+	RID        string    `bson:"RID" json:"RID"`
+	URL        string    `bson:"repositoryURL" json:"repositoryURL"`
+	Branch     string    `bson:"repositoryBranch" json:"repositoryBranch"`
+	Status     string    `bson:"status" json:"status"`
+	Result     string    `bson:"result,omitempty" json:"result"`
+	ErrorFound string    `bson:"errorFound,omitempty" json:"errorFound"`
+	StartedAt  time.Time `bson:"startedAt" json:"startedAt"`
+	FinishedAt time.Time `bson:"finishedAt" json:"finishedAt"`
+}
+
+// AnalysisFilter holds the filters, sorting and page used to list analyses.
+// Empty fields do not filter. Page starts at 1 and SortField is one of
+// startedAt, finishedAt, repositoryURL or status.
+type AnalysisFilter struct {
+	URL            string
+	Branch         string
+	Status         string
+	From           time.Time
+	To             time.Time
+	SortField      string
+	SortDescending bool
+	Page           int
+	PageSize       int
+}
+
+// AnalysisEvent is pushed to the clients watching an analysis. Type is status
+// when the analysis status changes, securitytest when a securityTest finishes
+// and keepalive when nothing happened for a while. The clients streaming every
+// analysis are also pushed created when an analysis is registered and started
+// when its securityTests start.
+type AnalysisEvent struct {
+	Type         string    `json:"type"`
+	RID          string    `json:"RID"`
+	URL          string    `json:"repositoryURL,omitempty"`
+	Status       string    `json:"status,omitempty"`
+	Result       string    `json:"result,omitempty"`
+	SecurityTest string    `json:"securityTest,omitempty"`
+	CStatus      string    `json:"cStatus,omitempty"`
+	CResult      string    `json:"cResult,omitempty"`
+	Time         time.Time `json:"time"`
 }
 
 // Container is the struct that stores all data from a container run.
@@ -49,10 +297,23 @@ type Container struct {
 	COutput      string       `bson:"cOutput" json:"cOutput"`
 	CResult      string       `bson:"cResult" json:"cResult"`
 	CInfo        string       `bson:"cInfo" json:"cInfo"`
+	ToolConfigs  []string     `bson:"toolConfigs,omitempty" json:"toolConfigs,omitempty"`
 	StartedAt    time.Time    `bson:"startedAt" json:"startedAt"`
 	FinishedAt   time.Time    `bson:"finishedAt" json:"finishedAt"`
 }
 
+// ContainerOutput is the raw output of the container of a securityTest that
+// ran in an analysis, gzip compressed. Size is its size before it was
+// compressed, and Truncated is true if it was cut at its size limit.
+type ContainerOutput struct {
+	RID          string    `bson:"RID" json:"RID"`
+	SecurityTest string    `bson:"securityTest" json:"securityTest"`
+	Output       []byte    `bson:"output" json:"output"`
+	Size         int       `bson:"size" json:"size"`
+	Truncated    bool      `bson:"truncated" json:"truncated"`
+	CreatedAt    time.Time `bson:"createdAt" json:"createdAt"`
+}
+
 // Code is the struct that stores all data from code found in a repository.
 type Code struct {
 	Language string   `bson:"language" json:"language"`
@@ -69,12 +330,16 @@ type User struct {
 	HashFunction       string `bson:"hashfunction,omitempty" json:"hashfunction"`
 	NewPassword        string `bson:"newPassword,omitempty" json:"newPassword"`
 	ConfirmNewPassword string `bson:"confirmNewPassword,omitempty" json:"confirmNewPassword"`
+	Role               string `bson:"role,omitempty" json:"role"`
+	Disabled           bool   `bson:"disabled,omitempty" json:"disabled"`
 }
 
 // HuskyCIVulnerability is the struct that stores vulnerability information.
 type HuskyCIVulnerability struct {
 	Language       string `bson:"language" json:"language,omitempty"`
 	SecurityTool   string `bson:"securitytool" json:"securitytool,omitempty"`
+	Rule           string `bson:"rule,omitempty" json:"rule,omitempty"`
+	Status         string `bson:"status,omitempty" json:"status,omitempty"`
 	Severity       string `bson:"severity,omitempty" json:"severity,omitempty"`
 	Confidence     string `bson:"confidence,omitempty" json:"confidence,omitempty"`
 	File           string `bson:"file,omitempty" json:"file,omitempty"`
@@ -86,6 +351,22 @@ type HuskyCIVulnerability struct {
 	VunerableBelow string `bson:"vulnerablebelow,omitempty" json:"vulnerablebelow,omitempty"`
 	Version        string `bson:"version,omitempty" json:"version,omitempty"`
 	Occurrences    int    `bson:"occurrences,omitempty" json:"occurrences,omitempty"`
+	Package        string `bson:"package,omitempty" json:"package,omitempty"`
+	FixVersion     string `bson:"fixversion,omitempty" json:"fixversion,omitempty"`
+	PackagePath    string `bson:"packagepath,omitempty" json:"packagepath,omitempty"`
+	Commit         string `bson:"commit,omitempty" json:"commit,omitempty"`
+	Author         string `bson:"author,omitempty" json:"author,omitempty"`
+	AuthorEmail    string `bson:"authoremail,omitempty" json:"authoremail,omitempty"`
+	Fingerprint    string `bson:"fingerprint,omitempty" json:"fingerprint,omitempty"`
+}
+
+// Owner is an author of the lines of an analysis findings, a likely owner of
+// their fix.
+type Owner struct {
+	Author      string   `json:"author,omitempty"`
+	AuthorEmail string   `json:"authorEmail"`
+	Findings    int      `json:"findings"`
+	Commits     []string `json:"commits"`
 }
 
 // HuskyCIResults is a struct that represents huskyCI scan results.
@@ -96,6 +377,7 @@ type HuskyCIResults struct {
 	RubyResults       RubyResults       `bson:"rubyresults,omitempty" json:"rubyresults,omitempty"`
 	JavaResults       JavaResults       `bson:"javaresults,omitempty" json:"javaresults,omitempty"`
 	HclResults        HclResults        `bson:"hclresults,omitempty" json:"hclresults,omitempty"`
+	IaCResults        IaCResults        `bson:"iacresults,omitempty" json:"iacresults,omitempty"`
 	CSharpResults     CsharpResults     `bson:"csharpresults,omitempty" json:"csharpresults,omitempty"`
 	GenericResults    GenericResults    `bson:"genericresults,omitempty" json:"genericresults,omitempty"`
 }
@@ -138,6 +420,13 @@ type HclResults struct {
 	HuskyCITFSecOutput HuskyCISecurityTestOutput `bson:"tfsecoutput,omitempty" json:"tfsecoutput,omitempty"`
 }
 
+// IaCResults represents all infrastructure as code security tests results,
+// such as the misconfigurations of Terraform, CloudFormation, Kubernetes and
+// Helm files.
+type IaCResults struct {
+	HuskyCITrivyConfigOutput HuskyCISecurityTestOutput `bson:"trivyconfigoutput,omitempty" json:"trivyconfigoutput,omitempty"`
+}
+
 // CsharpResults represents all C# security tests results.
 type CsharpResults struct {
 	HuskyCISecurityCodeScanOutput HuskyCISecurityTestOutput `bson:"securitycodescanoutput,omitempty" json:"securitycodescanoutput,omitempty"`
@@ -173,10 +462,33 @@ type DBToken struct {
 	UUID       string    `bson:"uuid" json:"uuid"`
 }
 
-// DockerAPIAddresses defines the struct that stores information about docker API hosts
+// DockerAPIAddresses defines the struct that stores information about docker API hosts.
+// HostList are the addresses of the active Hosts, which analyses are spread
+// on in turn.
 type DockerAPIAddresses struct {
-	CurrentHostIndex int      `bson:"currentHostIndex"`
-	HostList         []string `bson:"hostList"`
+	CurrentHostIndex int          `bson:"currentHostIndex"`
+	HostList         []string     `bson:"hostList"`
+	Hosts            []DockerHost `bson:"hosts,omitempty"`
+}
+
+// States of a DockerHost. Analyses only start on active hosts: a draining
+// host finishes the ones it runs and a disabled host runs none.
+const (
+	DockerHostActive   = "active"
+	DockerHostDraining = "draining"
+	DockerHostDisabled = "disabled"
+)
+
+// DockerHost is a Docker API host analyses run on, registered through the
+// admin routes or from HUSKYCI_DOCKERAPI_ADDR at startup. CertPath is the
+// directory holding the ca.pem, cert.pem and key.pem of the host, if it does
+// not use the ones of HUSKYCI_DOCKERAPI_CERT_PATH.
+type DockerHost struct {
+	Address   string    `bson:"address" json:"address"`
+	State     string    `bson:"state" json:"state"`
+	CertPath  string    `bson:"certPath,omitempty" json:"certPath,omitempty"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time `bson:"updatedAt" json:"updatedAt"`
 }
 
 // NohuskyFunction represents all the #nohusky verifier methods.
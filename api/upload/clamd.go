@@ -0,0 +1,99 @@
+package upload
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+)
+
+// clamdChunkSize is the size of the chunks the upload is streamed to clamd in.
+const clamdChunkSize = 64 << 10
+
+// Clamd streams the upload to a clamd daemon listening on Address, a
+// host:port or the path of a unix socket.
+type Clamd struct {
+	Address string
+}
+
+// Name returns clamd.
+func (clamd *Clamd) Name() string {
+	return "clamd"
+}
+
+func (clamd *Clamd) dial(ctx context.Context) (net.Conn, error) {
+	dialer := &net.Dialer{}
+	address := strings.TrimPrefix(clamd.Address, "unix://")
+	if strings.HasPrefix(address, "/") {
+		return dialer.DialContext(ctx, "unix", address)
+	}
+	return dialer.DialContext(ctx, "tcp", strings.TrimPrefix(address, "tcp://"))
+}
+
+// Scan sends the file at path to clamd with the INSTREAM command.
+func (clamd *Clamd) Scan(ctx context.Context, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	conn, err := clamd.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return err
+	}
+	chunk := make([]byte, clamdChunkSize)
+	size := make([]byte, 4)
+	for {
+		n, err := file.Read(chunk)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+			if _, err := conn.Write(append(size, chunk[:n]...)); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return err
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	return parseClamdReply(strings.TrimRight(reply, "\x00\n"))
+}
+
+// parseClamdReply reads the "stream: OK", "stream: <signature> FOUND" or
+// "<message> ERROR" reply of clamd.
+func parseClamdReply(reply string) error {
+	result := strings.TrimSpace(strings.TrimPrefix(reply, "stream:"))
+	switch {
+	case result == "OK":
+		return nil
+	case strings.HasSuffix(result, " FOUND"):
+		return &RejectedError{Scanner: "clamd", Reason: strings.TrimSuffix(result, " FOUND") + " found"}
+	case result == "":
+		return fmt.Errorf("no reply from clamd")
+	default:
+		return fmt.Errorf("clamd replied %q", result)
+	}
+}
@@ -0,0 +1,152 @@
+// Package upload checks the zips uploaded for analysis before any container
+// touches them.
+package upload
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// DefaultMaxSize is the largest zip accepted when no size is configured.
+const DefaultMaxSize = 100 << 20
+
+// zipMagics are the first bytes of a zip archive: a local file header or, for
+// an empty archive, the end of central directory record.
+var zipMagics = [][]byte{[]byte("PK\x03\x04"), []byte("PK\x05\x06")}
+
+// ErrTooLarge is returned when an upload exceeds the configured size.
+var ErrTooLarge = errors.New("upload is too large")
+
+// ErrNotZip is returned when an upload does not start like a zip archive.
+var ErrNotZip = errors.New("upload is not a zip archive")
+
+// Config represents the checks of the zips uploaded for analysis. Uploads are
+// scanned by clamd at ClamdAddress and by ScanCommand, split on white space
+// without shell quoting, when they are set.
+type Config struct {
+	MaxSize      int64
+	ClamdAddress string
+	ScanCommand  string
+	Timeout      time.Duration
+}
+
+// RejectedError is returned when a scanner finds an upload malicious.
+type RejectedError struct {
+	Scanner string
+	Reason  string
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("upload rejected by %s: %s", e.Scanner, e.Reason)
+}
+
+// Scanner inspects the file at path. It returns a *RejectedError if the file
+// is malicious and any other error if it could not inspect it.
+type Scanner interface {
+	Name() string
+	Scan(ctx context.Context, path string) error
+}
+
+// Scanners returns the scanners configured in config.
+func (config *Config) Scanners() []Scanner {
+	scanners := []Scanner{}
+	if config.ClamdAddress != "" {
+		scanners = append(scanners, &Clamd{Address: config.ClamdAddress})
+	}
+	if config.ScanCommand != "" {
+		scanners = append(scanners, &Command{Args: strings.Fields(config.ScanCommand)})
+	}
+	return scanners
+}
+
+// Check returns ErrTooLarge, ErrNotZip or a *RejectedError if the file at
+// path must not be analyzed, or any other error if a scanner failed.
+func (config *Config) Check(ctx context.Context, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if config.MaxSize > 0 && info.Size() > config.MaxSize {
+		return ErrTooLarge
+	}
+	if err := CheckMagic(path); err != nil {
+		return err
+	}
+	if config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.Timeout)
+		defer cancel()
+	}
+	for _, scanner := range config.Scanners() {
+		if err := scanner.Scan(ctx, path); err != nil {
+			var rejected *RejectedError
+			if errors.As(err, &rejected) {
+				return err
+			}
+			return fmt.Errorf("%s: %w", scanner.Name(), err)
+		}
+	}
+	return nil
+}
+
+// CheckMagic returns ErrNotZip if the file at path does not start like a zip
+// archive, whatever its name.
+func CheckMagic(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(file, header); err != nil {
+		return ErrNotZip
+	}
+	for _, magic := range zipMagics {
+		if bytes.Equal(header, magic) {
+			return nil
+		}
+	}
+	return ErrNotZip
+}
+
+// Command runs a command with the path of the upload as its last argument,
+// following the exit codes of clamscan: 0 if it is clean, 1 if it is
+// malicious, with the reason on the standard output, and any other code if
+// the command failed.
+type Command struct {
+	Args []string
+}
+
+// Name returns the name of the command.
+func (command *Command) Name() string {
+	return command.Args[0]
+}
+
+// Scan runs the command on the file at path.
+func (command *Command) Scan(ctx context.Context, path string) error {
+	args := append(append([]string{}, command.Args[1:]...), path)
+	cmd := exec.CommandContext(ctx, command.Args[0], args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		reason := strings.TrimSpace(strings.ReplaceAll(stdout.String(), path, "upload"))
+		if reason == "" {
+			reason = "malicious content found"
+		}
+		return &RejectedError{Scanner: command.Name(), Reason: reason}
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
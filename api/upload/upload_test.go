@@ -0,0 +1,145 @@
+package upload_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/huskyci-org/huskyCI/api/upload"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fakeClamd answers every INSTREAM command with reply and sends the stream
+// it received on streams.
+func fakeClamd(reply string, streams chan<- []byte) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).NotTo(HaveOccurred())
+	go func() {
+		defer listener.Close()
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		command := make([]byte, len("zINSTREAM\x00"))
+		if _, err := io.ReadFull(conn, command); err != nil {
+			return
+		}
+		stream := []byte{}
+		size := make([]byte, 4)
+		for {
+			if _, err := io.ReadFull(conn, size); err != nil {
+				return
+			}
+			n := binary.BigEndian.Uint32(size)
+			if n == 0 {
+				break
+			}
+			chunk := make([]byte, n)
+			if _, err := io.ReadFull(conn, chunk); err != nil {
+				return
+			}
+			stream = append(stream, chunk...)
+		}
+		streams <- stream
+		conn.Write([]byte(reply + "\x00"))
+	}()
+	return listener.Addr().String()
+}
+
+var _ = Describe("Upload", func() {
+
+	var dir string
+	emptyZip := []byte("PK\x05\x06" + string(make([]byte, 18)))
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "upload")
+		Expect(err).NotTo(HaveOccurred())
+	})
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	script := func(body string) string {
+		path := filepath.Join(dir, "scan.sh")
+		Expect(os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0700)).To(Succeed())
+		return path
+	}
+	write := func(content []byte) string {
+		path := filepath.Join(dir, "code.zip")
+		Expect(os.WriteFile(path, content, 0600)).To(Succeed())
+		return path
+	}
+
+	Describe("Check", func() {
+		It("Should accept a zip archive", func() {
+			config := &upload.Config{MaxSize: upload.DefaultMaxSize}
+			Expect(config.Check(context.Background(), write(emptyZip))).To(Succeed())
+		})
+		It("Should return ErrNotZip for a file that does not start like a zip archive", func() {
+			config := &upload.Config{MaxSize: upload.DefaultMaxSize}
+			err := config.Check(context.Background(), write([]byte("MZ\x90\x00 not a zip")))
+			Expect(err).To(Equal(upload.ErrNotZip))
+		})
+		It("Should return ErrNotZip for an empty file", func() {
+			config := &upload.Config{}
+			Expect(config.Check(context.Background(), write([]byte{}))).To(Equal(upload.ErrNotZip))
+		})
+		It("Should return ErrTooLarge for a file larger than MaxSize", func() {
+			config := &upload.Config{MaxSize: 10}
+			Expect(config.Check(context.Background(), write(emptyZip))).To(Equal(upload.ErrTooLarge))
+		})
+		It("Should return a RejectedError when the scan command exits with 1", func() {
+			config := &upload.Config{ScanCommand: script("echo \"$1: Eicar-Signature FOUND\"; exit 1")}
+			err := config.Check(context.Background(), write(emptyZip))
+			rejected := &upload.RejectedError{}
+			Expect(errors.As(err, &rejected)).To(BeTrue())
+			Expect(rejected.Reason).To(Equal("upload: Eicar-Signature FOUND"))
+		})
+		It("Should return an error when the scan command fails", func() {
+			config := &upload.Config{ScanCommand: script("exit 2")}
+			err := config.Check(context.Background(), write(emptyZip))
+			Expect(err).To(HaveOccurred())
+			rejected := &upload.RejectedError{}
+			Expect(errors.As(err, &rejected)).To(BeFalse())
+		})
+		It("Should accept a file the scan command finds clean", func() {
+			config := &upload.Config{ScanCommand: "true"}
+			Expect(config.Check(context.Background(), write(emptyZip))).To(Succeed())
+		})
+	})
+
+	Describe("Clamd", func() {
+		It("Should stream the file and accept it when clamd replies OK", func() {
+			streams := make(chan []byte, 1)
+			content := append(append([]byte{}, emptyZip...), bytes.Repeat([]byte("x"), 200<<10)...)
+			clamd := &upload.Clamd{Address: fakeClamd("stream: OK", streams)}
+			Expect(clamd.Scan(context.Background(), write(content))).To(Succeed())
+			Expect(<-streams).To(Equal(content))
+		})
+		It("Should return a RejectedError when clamd finds a signature", func() {
+			streams := make(chan []byte, 1)
+			clamd := &upload.Clamd{Address: fakeClamd("stream: Win.Test.EICAR_HDB-1 FOUND", streams)}
+			err := clamd.Scan(context.Background(), write(emptyZip))
+			rejected := &upload.RejectedError{}
+			Expect(errors.As(err, &rejected)).To(BeTrue())
+			Expect(rejected.Reason).To(Equal("Win.Test.EICAR_HDB-1 found"))
+		})
+		It("Should return an error when clamd fails", func() {
+			streams := make(chan []byte, 1)
+			clamd := &upload.Clamd{Address: fakeClamd("INSTREAM size limit exceeded. ERROR", streams)}
+			err := clamd.Scan(context.Background(), write(emptyZip))
+			Expect(err).To(HaveOccurred())
+			rejected := &upload.RejectedError{}
+			Expect(errors.As(err, &rejected)).To(BeFalse())
+		})
+	})
+})
@@ -42,6 +42,7 @@ func InsertDefaultUser() error {
 	}
 	newUser := types.User{}
 	newUser.Username = DefaultAPIUser
+	newUser.Role = auth.RoleAdmin
 	newUser.HashFunction = defaultHashFunction
 	newUser.Iterations = iterations
 	newUser.KeyLen = keyLength
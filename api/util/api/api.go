@@ -14,6 +14,7 @@ import (
 	"github.com/huskyci-org/huskyCI/api/log"
 	"github.com/huskyci-org/huskyCI/api/types"
 	"github.com/huskyci-org/huskyCI/api/user"
+	"github.com/huskyci-org/huskyCI/api/vault"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
@@ -126,7 +127,7 @@ func checkInfrastructure(checkHandler CheckInterface, configAPI *apiContext.APIC
 
 func (cH *CheckUtils) checkDockerHosts(configAPI *apiContext.APIConfig) error {
 	// writes necessary keys for TLS to respective files
-	if err := createAPIKeys(); err != nil {
+	if err := CreateAPIKeys(); err != nil {
 		return err
 	}
 
@@ -142,11 +143,12 @@ func (cH *CheckUtils) checkKubernetesHosts(configAPI *apiContext.APIConfig) erro
 }
 
 func (cH *CheckUtils) checkDB(configAPI *apiContext.APIConfig) error {
+	username, password := configAPI.DBConfig.Credentials()
 	if err := configAPI.DBInstance.ConnectDB(
 		configAPI.DBConfig.Address,
 		configAPI.DBConfig.DatabaseName,
-		configAPI.DBConfig.Username,
-		configAPI.DBConfig.Password,
+		username,
+		password,
 		configAPI.DBConfig.Timeout,
 		configAPI.DBConfig.PoolLimit,
 		configAPI.DBConfig.Port,
@@ -156,11 +158,22 @@ func (cH *CheckUtils) checkDB(configAPI *apiContext.APIConfig) error {
 		dbError := fmt.Sprintf("Check DB: %s", err)
 		return errors.New(dbError)
 	}
+	// the API still works without the indexes, only slower
+	if err := configAPI.DBInstance.EnsureIndexes(); err != nil {
+		log.Warning("checkDB", logInfoAPIUtil, 124, err)
+	}
+	// share Docker API hosts and their round-robin index between API replicas
+	if os.Getenv("HUSKYCI_INFRASTRUCTURE_USE") == "docker" {
+		if err := RegisterDockerHosts(configAPI); err != nil {
+			dbError := fmt.Sprintf("Check DB: %s", err)
+			return errors.New(dbError)
+		}
+	}
 	return nil
 }
 
 func (cH *CheckUtils) checkEachSecurityTest(configAPI *apiContext.APIConfig) error {
-	securityTests := []string{"enry", "gitauthors", "gosec", "brakeman", "bandit", "npmaudit", "yarnaudit", "spotbugs", "gitleaks", "safety", "tfsec", "securitycodescan"}
+	securityTests := []string{"enry", "gitauthors", "gitblame", "gosec", "brakeman", "bandit", "npmaudit", "yarnaudit", "spotbugs", "gitleaks", "safety", "tfsec", "trivyconfig", "securitycodescan"}
 	for _, securityTest := range securityTests {
 		if err := checkSecurityTest(securityTest, configAPI); err != nil {
 			errMsg := fmt.Sprintf("%s %s", securityTest, err)
@@ -211,10 +224,39 @@ func formatDockerHost(address string, port int) string {
 	if strings.HasPrefix(address, "/") {
 		return fmt.Sprintf("unix://%s", address)
 	}
-	// For TCP addresses, format as https://host:port (dind serves TLS on 2376; use DOCKER_TLS_VERIFY=0 to skip cert verification)
+	// For TCP addresses, format as https://host:port (dind serves TLS on 2376; set HUSKYCI_DOCKERAPI_TLS_VERIFY=false to skip cert verification)
 	return fmt.Sprintf("https://%s:%d", address, port)
 }
 
+// DockerHosts returns every Docker API host analyses may run on, formatted to
+// be used by a Docker client: the registered hosts that are not disabled, or
+// else the ones configured in HUSKYCI_DOCKERAPI_ADDR.
+func DockerHosts(configAPI *apiContext.APIConfig) []string {
+	port := 2376
+	if configAPI != nil && configAPI.DockerHostsConfig != nil {
+		port = configAPI.DockerHostsConfig.DockerAPIPort
+	}
+	hosts := []string{}
+	if configAPI != nil && configAPI.DBInstance != nil {
+		registered, err := configAPI.DBInstance.FindAllDBDockerHost(map[string]interface{}{})
+		if err == nil && len(registered) > 0 {
+			for _, host := range registered {
+				if host.State != types.DockerHostDisabled {
+					hosts = append(hosts, formatDockerHost(host.Address, port))
+				}
+			}
+			return hosts
+		}
+	}
+	for _, address := range strings.Fields(os.Getenv("HUSKYCI_DOCKERAPI_ADDR")) {
+		hosts = append(hosts, formatDockerHost(address, port))
+	}
+	if len(hosts) == 0 && configAPI != nil && configAPI.DockerHostsConfig != nil && configAPI.DockerHostsConfig.Address != "" {
+		hosts = append(hosts, formatDockerHost(configAPI.DockerHostsConfig.Address, port))
+	}
+	return hosts
+}
+
 // FormatDockerHostAddress formats the Docker host address based on the current host index.
 // When HUSKYCI_DOCKERAPI_ADDR is set to a TCP host (e.g. dockerapi), that value is always
 // used so Docker-in-Docker works even if the DB has a unix socket path or empty host.
@@ -233,8 +275,13 @@ func FormatDockerHostAddress(dockerHost types.DockerAPIAddresses, configAPI *api
 			}
 		}
 	}
-	// Prefer configured TCP host when set (e.g. dockerapi in Docker Compose)
-	if configAddr != "" && !strings.HasPrefix(configAddr, "/") && !strings.HasPrefix(configAddr, "unix://") {
+	if len(dockerHost.Hosts) > 0 && len(dockerHost.HostList) == 0 {
+		return "", errors.New("every Docker host is draining or disabled")
+	}
+	// Prefer configured TCP host when set (e.g. dockerapi in Docker Compose),
+	// unless several hosts are shared for round-robin between API replicas
+	// or the hosts were registered
+	if configAddr != "" && len(dockerHost.HostList) <= 1 && len(dockerHost.Hosts) == 0 && !strings.HasPrefix(configAddr, "/") && !strings.HasPrefix(configAddr, "unix://") {
 		return formatDockerHost(configAddr, port), nil
 	}
 	if len(dockerHost.HostList) == 0 {
@@ -257,6 +304,8 @@ func checkSecurityTest(securityTestName string, configAPI *apiContext.APIConfig)
 		securityTestConfig = *configAPI.EnrySecurityTest
 	case "gitauthors":
 		securityTestConfig = *configAPI.GitAuthorsSecurityTest
+	case "gitblame":
+		securityTestConfig = *configAPI.GitBlameSecurityTest
 	case "gosec":
 		securityTestConfig = *configAPI.GosecSecurityTest
 	case "brakeman":
@@ -275,6 +324,8 @@ func checkSecurityTest(securityTestName string, configAPI *apiContext.APIConfig)
 		securityTestConfig = *configAPI.SafetySecurityTest
 	case "tfsec":
 		securityTestConfig = *configAPI.TFSecSecurityTest
+	case "trivyconfig":
+		securityTestConfig = *configAPI.TrivyConfigSecurityTest
 	case "securitycodescan":
 		securityTestConfig = *configAPI.SecurityCodeScanSecurityTest
 	default:
@@ -289,7 +340,9 @@ func checkSecurityTest(securityTestName string, configAPI *apiContext.APIConfig)
 	return nil
 }
 
-func createAPIKeys() error {
+// CreateAPIKeys writes the TLS material of the Docker API hosts held by the
+// HUSKYCI_DOCKERAPI_*_VALUE environment variables to their files.
+func CreateAPIKeys() error {
 	err := createAPICert()
 	if err != nil {
 		return err
@@ -319,9 +372,9 @@ func createAPIKeys() error {
 }
 
 func createAPICert() error {
-	certValue, check := os.LookupEnv("HUSKYCI_DOCKERAPI_CERT_FILE_VALUE")
+	certValue, check := vault.LookupEnv("HUSKYCI_DOCKERAPI_CERT_FILE_VALUE")
 	if check {
-		f, err := os.OpenFile("/home/application/current/api/cert.pem", os.O_WRONLY|os.O_CREATE, 0600)
+		f, err := os.OpenFile("/home/application/current/api/cert.pem", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 		if err != nil {
 			return err
 		}
@@ -340,9 +393,9 @@ func createAPICert() error {
 }
 
 func createAPIKey() error {
-	certKeyValue, check := os.LookupEnv("HUSKYCI_DOCKERAPI_CERT_KEY_VALUE")
+	certKeyValue, check := vault.LookupEnv("HUSKYCI_DOCKERAPI_CERT_KEY_VALUE")
 	if check {
-		f, err := os.OpenFile("/home/application/current/api/key.pem", os.O_WRONLY|os.O_CREATE, 0600)
+		f, err := os.OpenFile("/home/application/current/api/key.pem", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 		if err != nil {
 			return err
 		}
@@ -361,9 +414,9 @@ func createAPIKey() error {
 }
 
 func createAPITLSCert() error {
-	apiCertValue, check := os.LookupEnv("HUSKYCI_DOCKERAPI_API_TLS_CERT_VALUE")
+	apiCertValue, check := vault.LookupEnv("HUSKYCI_DOCKERAPI_API_TLS_CERT_VALUE")
 	if check {
-		f, err := os.OpenFile("/home/application/current/api/api-tls-cert.pem", os.O_WRONLY|os.O_CREATE, 0600)
+		f, err := os.OpenFile("/home/application/current/api/api-tls-cert.pem", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 		if err != nil {
 			return err
 		}
@@ -382,9 +435,9 @@ func createAPITLSCert() error {
 }
 
 func createAPITLSKey() error {
-	apiKeyValue, check := os.LookupEnv("HUSKYCI_DOCKERAPI_API_TLS_KEY_VALUE")
+	apiKeyValue, check := vault.LookupEnv("HUSKYCI_DOCKERAPI_API_TLS_KEY_VALUE")
 	if check {
-		f, err := os.OpenFile("/home/application/current/api/api-tls-key.pem", os.O_WRONLY|os.O_CREATE, 0600)
+		f, err := os.OpenFile("/home/application/current/api/api-tls-key.pem", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 		if err != nil {
 			return err
 		}
@@ -403,9 +456,9 @@ func createAPITLSKey() error {
 }
 
 func createAPICA() error {
-	caValue, check := os.LookupEnv("HUSKYCI_DOCKERAPI_CERT_CA_VALUE")
+	caValue, check := vault.LookupEnv("HUSKYCI_DOCKERAPI_CERT_CA_VALUE")
 	if check {
-		f, err := os.OpenFile("/home/application/current/api/ca.pem", os.O_WRONLY|os.O_CREATE, 0600)
+		f, err := os.OpenFile("/home/application/current/api/ca.pem", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,70 @@
+package util
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	apiContext "github.com/huskyci-org/huskyCI/api/context"
+	docker "github.com/huskyci-org/huskyCI/api/dockers"
+	"github.com/huskyci-org/huskyCI/api/types"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// RegisterDockerHosts registers the Docker API hosts of
+// HUSKYCI_DOCKERAPI_ADDR as active, keeping the state of the ones already
+// registered, such as through the admin routes, and shares every registered
+// host with the API replicas.
+func RegisterDockerHosts(configAPI *apiContext.APIConfig) error {
+	now := time.Now()
+	for _, address := range strings.Fields(os.Getenv("HUSKYCI_DOCKERAPI_ADDR")) {
+		hostQuery := map[string]interface{}{"address": address}
+		_, err := configAPI.DBInstance.FindOneDBDockerHost(hostQuery)
+		if err == nil {
+			continue
+		}
+		if err != mongo.ErrNoDocuments && err.Error() != "No data found" {
+			return err
+		}
+		host := types.DockerHost{
+			Address:   address,
+			State:     types.DockerHostActive,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if err := configAPI.DBInstance.UpsertOneDBDockerHost(hostQuery, host); err != nil {
+			return err
+		}
+	}
+	_, err := SyncDockerHosts(configAPI)
+	return err
+}
+
+// SyncDockerHosts shares the registered Docker API hosts with the scheduler
+// of every API replica, so that analyses only start on the active ones from
+// the next one on, and sets the TLS material of the hosts having their own.
+// It returns the registered hosts.
+func SyncDockerHosts(configAPI *apiContext.APIConfig) ([]types.DockerHost, error) {
+	hosts, err := configAPI.DBInstance.FindAllDBDockerHost(map[string]interface{}{})
+	if err != nil && err != mongo.ErrNoDocuments && err.Error() != "No data found" {
+		return nil, err
+	}
+	if hosts == nil {
+		hosts = []types.DockerHost{}
+	}
+	if err := configAPI.DBInstance.UpsertDockerAPIAddresses(hosts); err != nil {
+		return nil, err
+	}
+	docker.SetHostCertPaths(hosts)
+	return hosts, nil
+}
+
+// DockerHostURL returns the address of a registered Docker API host formatted
+// to be used by a Docker client.
+func DockerHostURL(configAPI *apiContext.APIConfig, address string) string {
+	port := 2376
+	if configAPI != nil && configAPI.DockerHostsConfig != nil {
+		port = configAPI.DockerHostsConfig.DockerAPIPort
+	}
+	return formatDockerHost(address, port)
+}
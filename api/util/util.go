@@ -1,19 +1,22 @@
 package util
 
 import (
-	"bufio"
 	"net/http"
+	"net/url"
 	"os"
 	"regexp"
-	"strconv"
 	"strings"
 	"time"
 
 	"errors"
 	"fmt"
 
+	"github.com/huskyci-org/huskyCI/api/apierror"
 	"github.com/huskyci-org/huskyCI/api/log"
 	"github.com/huskyci-org/huskyCI/api/types"
+	"github.com/huskyci-org/huskyCI/api/vault"
+	"github.com/huskyci-org/huskyCI/pkg/errcode"
+	"github.com/huskyci-org/huskyCI/pkg/securitytest"
 	"github.com/labstack/echo/v4"
 )
 
@@ -31,54 +34,52 @@ const (
 )
 
 // HandleCmd will extract %GIT_REPO%, %GIT_BRANCH% from cmd and replace it with the proper repository URL.
-// For file:// URLs, it replaces git clone commands with commands to use the mounted volume at /workspace.
 func HandleCmd(repositoryURL, repositoryBranch, cmd string) string {
-	if repositoryURL != "" && repositoryBranch != "" && cmd != "" {
-		// Check if this is a file:// URL (local repository)
-		if IsFileURL(repositoryURL) {
-			// Replace git clone commands with commands to copy from mounted volume
-			// The volume is mounted at /workspace in the container
-			// Handle various git clone patterns that may have prefixes/suffixes
-			
-			// Pattern 1: git clone -b %GIT_BRANCH% --single-branch %GIT_REPO% code (with optional prefix/suffix)
-			// Match the entire line containing this pattern (handles GIT_TERMINAL_PROMPT=0 prefix, --quiet suffix, etc.)
-			// Use cp -r /workspace/. code to copy contents (not the directory itself), or cp -r /workspace/* code
-			re1 := regexp.MustCompile(`(?m)^[^\n]*git clone -b %GIT_BRANCH% --single-branch %GIT_REPO% code[^\n]*$`)
-			if re1.MatchString(cmd) {
-				// Copy contents of /workspace into code directory
-				cmd = re1.ReplaceAllString(cmd, "mkdir -p code && cp -r /workspace/. code/ 2>/dev/null || cp -r /workspace/* code/")
-			}
-			
-			// Pattern 2: git clone %GIT_REPO% code (with optional prefix/suffix)
-			re2 := regexp.MustCompile(`(?m)^[^\n]*git clone %GIT_REPO% code[^\n]*$`)
-			if re2.MatchString(cmd) && !strings.Contains(cmd, "cp -r /workspace") {
-				cmd = re2.ReplaceAllString(cmd, "mkdir -p code && cp -r /workspace/. code/ 2>/dev/null || cp -r /workspace/* code/")
-			}
-			
-			// Pattern 3: Fallback - any git clone with %GIT_REPO% that wasn't caught above
-			if strings.Contains(cmd, "git clone") && strings.Contains(cmd, "%GIT_REPO%") && !strings.Contains(cmd, "cp -r /workspace") {
-				// Match any line containing git clone with %GIT_REPO% and code
-				re3 := regexp.MustCompile(`(?m)^[^\n]*git clone[^\n]*%GIT_REPO%[^\n]*code[^\n]*$`)
-				cmd = re3.ReplaceAllString(cmd, "mkdir -p code && cp -r /workspace/. code/ 2>/dev/null || cp -r /workspace/* code/")
-			}
-			
-			// Remove remaining placeholders since we're using extracted files
-			cmd = strings.Replace(cmd, "%GIT_BRANCH%", repositoryBranch, -1)
-			cmd = strings.Replace(cmd, "%GIT_REPO%", repositoryURL, -1)
-			return cmd
-		}
-		// Standard git repository handling
-		replace1 := strings.Replace(cmd, "%GIT_REPO%", repositoryURL, -1)
-		replace2 := strings.Replace(replace1, "%GIT_BRANCH%", repositoryBranch, -1)
-		return replace2
+	return securitytest.Command(repositoryURL, repositoryBranch, cmd)
+}
+
+// HandleCheckout makes cmd check out commit right after cloning the repository, so the code scanned stays the one
+// of commit when its branch moves. cmd is returned untouched without a commit. It must run before HandleCmd.
+func HandleCheckout(commit, cmd string) string {
+	return securitytest.Checkout(commit, cmd)
+}
+
+// HandleMerge makes cmd scan the merge of a pull request into targetBranch right after cloning the repository, by
+// checking out mergeRef or else merging targetBranch. cmd is returned untouched without a target branch. It must run
+// before HandleCmd.
+func HandleMerge(mergeRef, targetBranch, cmd string) string {
+	return securitytest.Merge(mergeRef, targetBranch, cmd)
+}
+
+// UploadRepository is the repository the code of an upload analysis is
+// committed to inside securityTest containers.
+const UploadRepository = securitytest.UploadRepository
+
+// HandleUploadCmd makes cmd scan the uploaded code mounted at /workspace. The
+// code is first committed on repositoryBranch to UploadRepository, which cmd
+// then clones as it would clone any repository.
+func HandleUploadCmd(repositoryBranch, cmd string) string {
+	return securitytest.UploadCommand(repositoryBranch, cmd)
+}
+
+// HandleCloneOptions will replace %GIT_CLONE_OPTIONS% in cmd with a shallow clone depth, if depth is positive, and
+// with a reference to the git mirror at mirrorPath, if it is set. The mirror is only read, as it is refreshed by the
+// API before the securityTests run, and the clone copies what it takes from it. It must run before HandleCmd.
+func HandleCloneOptions(cmd string, depth int, mirrorPath string) string {
+	options := []string{}
+	if depth > 0 {
+		options = append(options, fmt.Sprintf("--depth %d", depth))
 	}
-	return ""
+	if mirrorPath != "" {
+		options = append(options, fmt.Sprintf("--reference-if-able %s --dissociate", mirrorPath))
+	}
+	return strings.Replace(cmd, "%GIT_CLONE_OPTIONS%", strings.Join(options, " "), -1)
 }
 
 // HandleGitURLSubstitution will extract GIT_SSH_URL and GIT_URL_TO_SUBSTITUTE from cmd and replace it with the SSH equivalent.
 func HandleGitURLSubstitution(rawString string) string {
-	gitSSHURL := os.Getenv("HUSKYCI_API_GIT_SSH_URL")
-	gitURLToSubstitute := os.Getenv("HUSKYCI_API_GIT_URL_TO_SUBSTITUTE")
+	gitSSHURL := vault.Getenv("HUSKYCI_API_GIT_SSH_URL")
+	gitURLToSubstitute := vault.Getenv("HUSKYCI_API_GIT_URL_TO_SUBSTITUTE")
 
 	if gitSSHURL == "" || gitURLToSubstitute == "" {
 		gitSSHURL = "nil"
@@ -92,47 +93,42 @@ func HandleGitURLSubstitution(rawString string) string {
 
 // HandlePrivateSSHKey will extract %GIT_PRIVATE_SSH_KEY% from cmd and replace it with the proper private SSH key.
 func HandlePrivateSSHKey(rawString string) string {
-	privKey := os.Getenv("HUSKYCI_API_GIT_PRIVATE_SSH_KEY")
+	privKey := vault.Getenv("HUSKYCI_API_GIT_PRIVATE_SSH_KEY")
 	cmdReplaced := strings.Replace(rawString, "%GIT_PRIVATE_SSH_KEY%", privKey, -1)
 	return cmdReplaced
 }
 
-// GetLastLine receives a string with multiple lines and returns it's last
-func GetLastLine(s string) string {
-	if s == "" {
-		return ""
-	}
-	var lines []string
-	scanner := bufio.NewScanner(strings.NewReader(s))
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
-	}
-	return lines[len(lines)-1]
+// HandleDeployKey will extract %GIT_PRIVATE_SSH_KEY% from cmd and replace it with the deploy key of the repository being analyzed.
+func HandleDeployKey(rawString, deployKey string) string {
+	return strings.Replace(rawString, "%GIT_PRIVATE_SSH_KEY%", deployKey, -1)
 }
 
-// GetAllLinesButLast receives a string with multiple lines and returns all but the last line.
-func GetAllLinesButLast(s string) []string {
-	if s == "" {
-		return []string{}
+// AuthenticatedURL returns an HTTP(S) repositoryURL carrying username and token, so git can clone it without prompting.
+// Any other URL, such as an SSH one, is returned untouched.
+func AuthenticatedURL(repositoryURL, username, token string) string {
+	parsed, err := url.Parse(repositoryURL)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") {
+		return repositoryURL
 	}
-	var lines []string
-	scanner := bufio.NewScanner(strings.NewReader(s))
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+	if username == "" {
+		username = "x-access-token"
 	}
-	lines = lines[:len(lines)-1]
-	return lines
+	parsed.User = url.UserPassword(username, token)
+	return parsed.String()
 }
 
-// SanitizeSafetyJSON returns a sanitized string from Safety container logs.
-// Safety might return a JSON with the "\" and "\"" characters, which needs to be sanitized to be unmarshalled correctly.
-func SanitizeSafetyJSON(s string) string {
-	if s == "" {
-		return ""
+// RedactCredential replaces secret in output with asterisks, as it is written
+// and as it is escaped in the userinfo, the query or the path of a URL, such
+// as the one AuthenticatedURL returns.
+func RedactCredential(output, secret string) string {
+	if secret == "" {
+		return output
 	}
-	s1 := strings.Replace(s, "\\", "\\\\", -1)
-	s2 := strings.Replace(s1, "\\\"", "\\\\\"", -1)
-	return s2
+	userinfo := strings.TrimPrefix(url.UserPassword("", secret).String(), ":")
+	for _, form := range []string{secret, userinfo, url.QueryEscape(secret), url.PathEscape(secret)} {
+		output = strings.ReplaceAll(output, form, "*****")
+	}
+	return output
 }
 
 // RemoveDuplicates remove duplicated itens from a slice.
@@ -167,19 +163,11 @@ func CheckValidInput(repository types.Repository, c echo.Context) (string, error
 	if err != nil {
 		if sanitiziedURL == "" {
 			log.Error(logActionReceiveRequest, logInfoAnalysis, 1016, repository.URL)
-			reply := map[string]interface{}{
-				"success": false,
-				"error":   "invalid repository URL",
-				"message": fmt.Sprintf("The repository URL '%s' is not in a valid format. Please provide a valid Git repository URL (e.g., https://github.com/user/repo.git or git@github.com:user/repo.git)", repository.URL),
-			}
+			reply := apierror.Reply(c, errcode.InvalidRequest, "invalid repository URL", fmt.Sprintf("The repository URL '%s' is not in a valid format. Please provide a valid Git repository URL (e.g., https://github.com/user/repo.git or git@github.com:user/repo.git)", repository.URL))
 			return "", c.JSON(http.StatusBadRequest, reply)
 		}
 		log.Error(logActionReceiveRequest, logInfoAnalysis, 1008, "Repository URL regexp ", err)
-		reply := map[string]interface{}{
-			"success": false,
-			"error":   "internal server error",
-			"message": "An error occurred while validating the repository URL. Please try again.",
-		}
+		reply := apierror.Reply(c, errcode.Internal, "internal server error", "An error occurred while validating the repository URL. Please try again.")
 		return "", c.JSON(http.StatusInternalServerError, reply)
 	}
 
@@ -187,6 +175,10 @@ func CheckValidInput(repository types.Repository, c echo.Context) (string, error
 		return "", err
 	}
 
+	if err := CheckMaliciousCommit(repository.Commit, c); err != nil {
+		return "", err
+	}
+
 	return sanitiziedURL, nil
 }
 
@@ -220,37 +212,44 @@ func CheckMaliciousRepoBranch(repositoryBranch string, c echo.Context) error {
 	valid, err := regexp.MatchString(regexpBranch, repositoryBranch)
 	if err != nil {
 		log.Error(logActionReceiveRequest, logInfoAnalysis, 1008, "Repository Branch regexp ", err)
-		reply := map[string]interface{}{"success": false, "error": errInternalError}
+		reply := apierror.Reply(c, errcode.Internal, errInternalError, "An unexpected error occurred. Please try again later.")
 		return c.JSON(http.StatusInternalServerError, reply)
 	}
 	if !valid {
 		log.Error(logActionReceiveRequest, logInfoAnalysis, 1017, repositoryBranch)
-		reply := map[string]interface{}{
-			"success": false,
-			"error":   "invalid repository branch",
-			"message": fmt.Sprintf("The branch name '%s' contains invalid characters. Branch names can only contain letters, numbers, underscores, forward slashes, dots, hyphens, plus signs, and accented characters.", repositoryBranch),
-		}
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid repository branch", fmt.Sprintf("The branch name '%s' contains invalid characters. Branch names can only contain letters, numbers, underscores, forward slashes, dots, hyphens, plus signs, and accented characters.", repositoryBranch))
 		return c.JSON(http.StatusBadRequest, reply)
 	}
 	return nil
 }
 
+// CheckMaliciousCommit verifies if a given commit is a git object name. An
+// empty commit is valid, as it is optional.
+func CheckMaliciousCommit(commit string, c echo.Context) error {
+	if commit == "" {
+		return nil
+	}
+	regexpCommit := `^[0-9a-fA-F]{7,64}$`
+	if valid, _ := regexp.MatchString(regexpCommit, commit); valid {
+		return nil
+	}
+	log.Error(logActionReceiveRequest, logInfoAnalysis, 1054, commit)
+	reply := apierror.Reply(c, errcode.InvalidRequest, "invalid commit", fmt.Sprintf("The commit '%s' is not valid. It must be the full or abbreviated hexadecimal hash of a git commit.", commit))
+	return c.JSON(http.StatusBadRequest, reply)
+}
+
 // CheckMaliciousRID verifies if a given RID is "malicious" or not
 func CheckMaliciousRID(RID string, c echo.Context) error {
 	regexpRID := `^[-a-zA-Z0-9]*$`
 	valid, err := regexp.MatchString(regexpRID, RID)
 	if err != nil {
 		log.Error("GetAnalysis", logInfoAnalysis, 1008, "RID regexp ", err)
-		reply := map[string]interface{}{"success": false, "error": errInternalError}
+		reply := apierror.Reply(c, errcode.Internal, errInternalError, "An unexpected error occurred. Please try again later.")
 		return c.JSON(http.StatusInternalServerError, reply)
 	}
 	if !valid {
 		log.Warning("GetAnalysis", logInfoAnalysis, 107, RID)
-		reply := map[string]interface{}{
-			"success": false,
-			"error":   "invalid RID format",
-			"message": fmt.Sprintf("The RID '%s' contains invalid characters. RID must only contain letters, numbers, hyphens, and underscores.", RID),
-		}
+		reply := apierror.Reply(c, errcode.InvalidRequest, "invalid RID format", fmt.Sprintf("The RID '%s' contains invalid characters. RID must only contain letters, numbers, hyphens, and underscores.", RID))
 		return c.JSON(http.StatusBadRequest, reply)
 	}
 	return nil
@@ -293,17 +292,7 @@ func CountDigits(i int) int {
 }
 
 func banditCase(code string, lineNumber int) bool {
-	lineNumberLength := CountDigits(lineNumber)
-	splitCode := strings.Split(code, "\n")
-	for _, codeLine := range splitCode {
-		if len(codeLine) > 0 {
-			codeLineNumber := codeLine[:lineNumberLength]
-			if strings.Contains(codeLine, "#nohusky") && (codeLineNumber == strconv.Itoa(lineNumber)) {
-				return true
-			}
-		}
-	}
-	return false
+	return securitytest.NoHusky(code, lineNumber)
 }
 
 // VerifyNoHusky verifies if the code string is marked with the #nohusky tag.
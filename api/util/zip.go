@@ -7,12 +7,12 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
-const (
-	// ZipStorageDir is the directory where uploaded zip files are stored
-	ZipStorageDir = "/tmp/huskyci-zips"
-)
+// ZipStorageDir is the directory where uploaded zip files are stored. Tests
+// point it at a temporary directory.
+var ZipStorageDir = "/tmp/huskyci-zips"
 
 // EnsureZipStorageDir creates the zip storage directory if it doesn't exist
 func EnsureZipStorageDir() error {
@@ -113,6 +113,12 @@ func IsFileURL(url string) bool {
 	return strings.HasPrefix(url, "file://")
 }
 
+// UploadURL returns the file:// URL an upload analysis of the zip uploaded
+// under RID is stored with.
+func UploadURL(RID string) string {
+	return "file://" + RID
+}
+
 // ExtractRIDFromFileURL extracts the RID from a file:// URL
 func ExtractRIDFromFileURL(url string) string {
 	if !IsFileURL(url) {
@@ -125,3 +131,45 @@ func ExtractRIDFromFileURL(url string) string {
 	}
 	return parts[1]
 }
+
+// CleanupStaleZips removes uploaded zip files and extracted directories last
+// modified before olderThan. It returns how many entries were removed and how
+// many bytes were reclaimed.
+func CleanupStaleZips(olderThan time.Time) (int, int64, error) {
+	entries, err := os.ReadDir(ZipStorageDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, 0, nil
+		}
+		return 0, 0, err
+	}
+
+	removed := 0
+	var reclaimed int64
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(olderThan) {
+			continue
+		}
+		path := filepath.Join(ZipStorageDir, entry.Name())
+		size := dirSize(path)
+		if err := os.RemoveAll(path); err != nil {
+			return removed, reclaimed, fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+		removed++
+		reclaimed += size
+	}
+	return removed, reclaimed, nil
+}
+
+// dirSize returns the size of a file or the sum of all files under a directory.
+func dirSize(path string) int64 {
+	var size int64
+	filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
@@ -0,0 +1,52 @@
+package util_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/api/util"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Zip", func() {
+
+	var previousDir string
+
+	BeforeEach(func() {
+		previousDir = util.ZipStorageDir
+		dir, err := os.MkdirTemp("", "huskyci-zips")
+		Expect(err).NotTo(HaveOccurred())
+		util.ZipStorageDir = dir
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(util.ZipStorageDir)
+		util.ZipStorageDir = previousDir
+	})
+
+	Describe("CleanupStaleZips", func() {
+		Context("When there are zips older than the given time", func() {
+			It("Should remove only the stale entries and report reclaimed bytes", func() {
+				Expect(util.EnsureZipStorageDir()).To(Succeed())
+				staleRID := "janitor-test-stale"
+				freshRID := "janitor-test-fresh"
+				Expect(os.WriteFile(util.GetZipFilePath(staleRID), []byte("12345"), 0600)).To(Succeed())
+				Expect(os.WriteFile(util.GetZipFilePath(freshRID), []byte("12345"), 0600)).To(Succeed())
+				old := time.Now().Add(-2 * time.Hour)
+				Expect(os.Chtimes(util.GetZipFilePath(staleRID), old, old)).To(Succeed())
+
+				removed, reclaimed, err := util.CleanupStaleZips(time.Now().Add(-time.Hour))
+				Expect(err).To(BeNil())
+				Expect(removed).To(Equal(1))
+				Expect(reclaimed).To(BeNumerically("==", 5))
+				_, err = os.Stat(util.GetZipFilePath(staleRID))
+				Expect(os.IsNotExist(err)).To(BeTrue())
+				_, err = os.Stat(filepath.Join(util.ZipStorageDir, freshRID+".zip"))
+				Expect(err).To(BeNil())
+			})
+		})
+	})
+})
@@ -0,0 +1,487 @@
+// Package vault reads the secrets of the huskyCI API from HashiCorp Vault,
+// such as the credentials of its database, the TLS material of the Docker API
+// hosts and its git deploy key, instead of the environment variables holding
+// them. Each secret stands for the environment variable that would have held
+// it, read at startup and again whenever its lease can not be renewed. The
+// secrets are handed to the API through Getenv, never through its
+// environment, which the commands it runs would inherit.
+package vault
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/api/log"
+)
+
+const logActionVault = "Vault"
+const logInfoVault = "VAULT"
+
+// Auth methods the API can log in to Vault with.
+const (
+	AuthToken      = "token"
+	AuthAppRole    = "approle"
+	AuthKubernetes = "kubernetes"
+)
+
+// DefaultKubernetesTokenFile is the service account token the API logs in
+// with the kubernetes auth method.
+const DefaultKubernetesTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// minRenewal is the shortest time the API waits for before renewing a lease.
+const minRenewal = 5 * time.Second
+
+// Config holds how the API reaches Vault and the secrets it reads from it.
+type Config struct {
+	Address    string
+	Namespace  string
+	CACert     string
+	AuthMethod string
+	AuthMount  string
+	Token      string
+	RoleID     string
+	SecretID   string
+	Role       string
+	// Secrets are comma separated ENV_VAR=path#field references, such as
+	// HUSKYCI_DATABASE_DB_PASSWORD=database/creds/huskyci#password.
+	Secrets string
+}
+
+// Enabled returns true if the API reads its secrets from Vault.
+func (c *Config) Enabled() bool {
+	return c != nil && c.Address != ""
+}
+
+// issued holds the secrets read from Vault by environment variable.
+var issued = struct {
+	sync.RWMutex
+	values map[string]string
+}{values: map[string]string{}}
+
+// LookupEnv returns the secret read from Vault for envVar, or the value of
+// the environment variable when it is not read from Vault.
+func LookupEnv(envVar string) (string, bool) {
+	issued.RLock()
+	value, ok := issued.values[envVar]
+	issued.RUnlock()
+	if ok {
+		return value, true
+	}
+	return os.LookupEnv(envVar)
+}
+
+// Getenv is LookupEnv without telling whether envVar is set.
+func Getenv(envVar string) string {
+	value, _ := LookupEnv(envVar)
+	return value
+}
+
+// reference is where the value of an environment variable is read from.
+type reference struct {
+	envVar string
+	field  string
+}
+
+// lease is a lease of Vault, on a secret or on the token of the API.
+type lease struct {
+	id       string
+	duration time.Duration
+	// initial is the duration of the lease when it was issued: a renewal
+	// granting less than half of it means its max TTL is near.
+	initial   time.Duration
+	renewable bool
+	renewAt   time.Time
+}
+
+// schedule sets when l should be renewed, halfway through its duration.
+func (l *lease) schedule(now time.Time) {
+	l.renewAt = now.Add(max(l.duration/2, minRenewal))
+}
+
+// Client reads the secrets of a Config from Vault and keeps their leases.
+type Client struct {
+	config  *Config
+	client  *http.Client
+	secrets map[string][]reference
+	token   string
+	auth    *lease
+	mu      sync.Mutex
+	leases  map[string]*lease
+	now     func() time.Time
+}
+
+// New returns a Client of config logged in to Vault.
+func New(config *Config) (*Client, error) {
+	secrets, err := parseSecrets(config.Secrets)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config.CACert != "" {
+		pem, err := os.ReadFile(config.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("could not read the Vault CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("invalid Vault CA certificate")
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	c := &Client{
+		config:  config,
+		client:  &http.Client{Transport: transport, Timeout: 30 * time.Second},
+		secrets: secrets,
+		leases:  map[string]*lease{},
+		now:     time.Now,
+	}
+	if err := c.login(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// parseSecrets returns the references of secrets by Vault path, so that
+// the fields of a path are read at once: every read of a dynamic secret
+// issues new credentials.
+func parseSecrets(secrets string) (map[string][]reference, error) {
+	byPath := map[string][]reference{}
+	for _, secret := range strings.Split(secrets, ",") {
+		secret = strings.TrimSpace(secret)
+		if secret == "" {
+			continue
+		}
+		envVar, location, _ := strings.Cut(secret, "=")
+		path, field, _ := strings.Cut(location, "#")
+		envVar, path, field = strings.TrimSpace(envVar), strings.Trim(strings.TrimSpace(path), "/"), strings.TrimSpace(field)
+		if envVar == "" || path == "" || field == "" {
+			return nil, fmt.Errorf("invalid Vault secret '%s', expected ENV_VAR=path#field", secret)
+		}
+		byPath[path] = append(byPath[path], reference{envVar: envVar, field: field})
+	}
+	if len(byPath) == 0 {
+		return nil, errors.New("no Vault secret is configured, set HUSKYCI_VAULT_SECRETS")
+	}
+	return byPath, nil
+}
+
+// response is the part of the answers of Vault the API reads.
+type response struct {
+	LeaseID       string                 `json:"lease_id"`
+	LeaseDuration int                    `json:"lease_duration"`
+	Renewable     bool                   `json:"renewable"`
+	Data          map[string]interface{} `json:"data"`
+	Auth          *struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int    `json:"lease_duration"`
+		Renewable     bool   `json:"renewable"`
+	} `json:"auth"`
+	Errors []string `json:"errors"`
+}
+
+// do sends a request to Vault and returns its answer, or an error carrying
+// the errors of Vault if it did not succeed.
+func (c *Client) do(method, path string, body interface{}) (*response, error) {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(c.config.Address, "/")+"/v1/"+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if c.token != "" {
+		req.Header.Set("X-Vault-Token", c.token)
+	}
+	if c.config.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.config.Namespace)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	answer := &response{}
+	if resp.StatusCode != http.StatusNoContent {
+		if err := json.NewDecoder(resp.Body).Decode(answer); err != nil && resp.StatusCode/100 == 2 {
+			return nil, fmt.Errorf("invalid answer of Vault to %s: %w", path, err)
+		}
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("Vault answered %d to %s: %s", resp.StatusCode, path, strings.Join(answer.Errors, ", "))
+	}
+	return answer, nil
+}
+
+// login gets the token of the API with its auth method.
+func (c *Client) login() error {
+	method := c.config.AuthMethod
+	if method == "" {
+		method = AuthToken
+	}
+	mount := c.config.AuthMount
+	if mount == "" {
+		mount = method
+	}
+
+	var body map[string]interface{}
+	switch method {
+	case AuthToken:
+		if c.config.Token == "" {
+			return errors.New("no Vault token is configured, set HUSKYCI_VAULT_TOKEN")
+		}
+		c.token = c.config.Token
+		answer, err := c.do(http.MethodGet, "auth/token/lookup-self", nil)
+		if err != nil {
+			return err
+		}
+		ttl, _ := answer.Data["ttl"].(float64)
+		renewable, _ := answer.Data["renewable"].(bool)
+		c.setAuth(time.Duration(ttl)*time.Second, renewable)
+		return nil
+	case AuthAppRole:
+		body = map[string]interface{}{"role_id": c.config.RoleID, "secret_id": c.config.SecretID}
+	case AuthKubernetes:
+		jwt, err := os.ReadFile(DefaultKubernetesTokenFile)
+		if err != nil {
+			return fmt.Errorf("could not read the service account token: %w", err)
+		}
+		body = map[string]interface{}{"role": c.config.Role, "jwt": strings.TrimSpace(string(jwt))}
+	default:
+		return fmt.Errorf("invalid Vault auth method '%s'", method)
+	}
+
+	c.token = ""
+	answer, err := c.do(http.MethodPost, "auth/"+mount+"/login", body)
+	if err != nil {
+		return err
+	}
+	if answer.Auth == nil || answer.Auth.ClientToken == "" {
+		return errors.New("Vault did not return a token")
+	}
+	c.token = answer.Auth.ClientToken
+	c.setAuth(time.Duration(answer.Auth.LeaseDuration)*time.Second, answer.Auth.Renewable)
+	return nil
+}
+
+// setAuth keeps the lease of the token of the API, if it expires.
+func (c *Client) setAuth(duration time.Duration, renewable bool) {
+	c.auth = nil
+	if duration > 0 {
+		c.auth = &lease{duration: duration, initial: duration, renewable: renewable}
+		c.auth.schedule(c.now())
+	}
+}
+
+// Load reads every secret from Vault.
+func (c *Client) Load() error {
+	for _, path := range c.paths() {
+		if _, err := c.read(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// paths returns the Vault paths of the secrets, sorted.
+func (c *Client) paths() []string {
+	paths := make([]string, 0, len(c.secrets))
+	for path := range c.secrets {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// read reads the secrets of path and keeps its lease. It returns the
+// environment variables whose value changed.
+func (c *Client) read(path string) ([]string, error) {
+	answer, err := c.do(http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	data := answer.Data
+	// the secrets of the KV version 2 engine are nested under data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			data = nested
+		}
+	}
+
+	changed := []string{}
+	for _, ref := range c.secrets[path] {
+		value, ok := data[ref.field]
+		if !ok {
+			return nil, fmt.Errorf("Vault secret %s has no field %s", path, ref.field)
+		}
+		text := fmt.Sprint(value)
+		issued.Lock()
+		if previous, ok := issued.values[ref.envVar]; !ok || previous != text {
+			issued.values[ref.envVar] = text
+			changed = append(changed, ref.envVar)
+		}
+		issued.Unlock()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.leases, path)
+	if answer.LeaseDuration > 0 {
+		duration := time.Duration(answer.LeaseDuration) * time.Second
+		l := &lease{id: answer.LeaseID, duration: duration, initial: duration, renewable: answer.Renewable && answer.LeaseID != ""}
+		l.schedule(c.now())
+		c.leases[path] = l
+	}
+	return changed, nil
+}
+
+// Run renews the token of the API and the leases of its secrets until ctx is
+// done. Secrets whose lease can not be renewed any more are read again, and
+// onChange is called with the environment variables whose value changed.
+func (c *Client) Run(ctx context.Context, onChange func(envVars []string)) {
+	for {
+		wait, ok := c.next()
+		if !ok {
+			return
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if changed := c.Renew(c.now()); len(changed) > 0 && onChange != nil {
+			onChange(changed)
+		}
+	}
+}
+
+// next returns how long to wait for before the next renewal, and false if
+// nothing has to be renewed.
+func (c *Client) next() (time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var earliest time.Time
+	if c.auth != nil {
+		earliest = c.auth.renewAt
+	}
+	for _, l := range c.leases {
+		if earliest.IsZero() || l.renewAt.Before(earliest) {
+			earliest = l.renewAt
+		}
+	}
+	if earliest.IsZero() {
+		return 0, false
+	}
+	return max(earliest.Sub(c.now()), 0), true
+}
+
+// Renew renews the token of the API and the leases that are due at now, and
+// reads again the secrets whose lease could not be renewed. It returns the
+// environment variables whose value changed.
+func (c *Client) Renew(now time.Time) []string {
+	if c.auth != nil && !c.auth.renewAt.After(now) {
+		if err := c.renewToken(); err != nil {
+			log.Error(logActionVault, logInfoVault, 1069, "token", err)
+		}
+	}
+
+	c.mu.Lock()
+	due := []string{}
+	for path, l := range c.leases {
+		if !l.renewAt.After(now) {
+			due = append(due, path)
+		}
+	}
+	c.mu.Unlock()
+	sort.Strings(due)
+
+	changed := []string{}
+	for _, path := range due {
+		if c.renewLease(path) {
+			continue
+		}
+		envVars, err := c.read(path)
+		if err != nil {
+			log.Error(logActionVault, logInfoVault, 1070, path, err)
+			c.retry(path)
+			continue
+		}
+		log.Info(logActionVault, logInfoVault, 59, path)
+		changed = append(changed, envVars...)
+	}
+	return changed
+}
+
+// renewToken renews the token of the API, or logs in again if it can not be
+// renewed.
+func (c *Client) renewToken() error {
+	if c.auth.renewable {
+		answer, err := c.do(http.MethodPost, "auth/token/renew-self", map[string]interface{}{"increment": int(c.auth.initial.Seconds())})
+		if err == nil && answer.Auth != nil && time.Duration(answer.Auth.LeaseDuration)*time.Second >= c.auth.initial/2 {
+			c.auth.duration = time.Duration(answer.Auth.LeaseDuration) * time.Second
+			c.auth.schedule(c.now())
+			return nil
+		}
+	}
+	if c.config.AuthMethod == "" || c.config.AuthMethod == AuthToken {
+		// a token given to the API can not be issued again
+		c.auth.duration = c.auth.duration / 2
+		c.auth.schedule(c.now())
+		return errors.New("the Vault token can not be renewed any more")
+	}
+	return c.login()
+}
+
+// renewLease renews the lease of the secrets of path. It returns false if
+// they should be read again instead.
+func (c *Client) renewLease(path string) bool {
+	c.mu.Lock()
+	l := c.leases[path]
+	c.mu.Unlock()
+	if l == nil || !l.renewable {
+		return false
+	}
+	answer, err := c.do(http.MethodPut, "sys/leases/renew", map[string]interface{}{"lease_id": l.id, "increment": int(l.initial.Seconds())})
+	if err != nil {
+		log.Error(logActionVault, logInfoVault, 1069, path, err)
+		return false
+	}
+	duration := time.Duration(answer.LeaseDuration) * time.Second
+	if duration < l.initial/2 {
+		// the max TTL of the lease is near, new secrets are read before it
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l.duration = duration
+	l.schedule(c.now())
+	return true
+}
+
+// retry makes the secrets of path be read again shortly, after a failure.
+func (c *Client) retry(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if l := c.leases[path]; l != nil {
+		l.renewable = false
+		l.renewAt = c.now().Add(minRenewal)
+	}
+}
@@ -0,0 +1,146 @@
+package vault_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"time"
+
+	. "github.com/huskyci-org/huskyCI/api/vault"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fakeVault answers an AppRole login, a KV version 2 secret and the dynamic
+// credentials of a database, whose leases can be renewed renewals times.
+type fakeVault struct {
+	mu       sync.Mutex
+	reads    int
+	renewals int
+}
+
+func (f *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.URL.Path != "/v1/auth/approle/login" && r.Header.Get("X-Vault-Token") != "s.token" {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"errors":["permission denied"]}`)
+		return
+	}
+	var answer map[string]interface{}
+	switch r.URL.Path {
+	case "/v1/auth/approle/login":
+		answer = map[string]interface{}{"auth": map[string]interface{}{"client_token": "s.token", "lease_duration": 3600, "renewable": true}}
+	case "/v1/secret/data/huskyci/docker":
+		answer = map[string]interface{}{"data": map[string]interface{}{
+			"data":     map[string]interface{}{"cert": "-----BEGIN CERTIFICATE-----"},
+			"metadata": map[string]interface{}{"version": 1},
+		}}
+	case "/v1/database/creds/huskyci":
+		f.reads++
+		answer = map[string]interface{}{
+			"lease_id":       "database/creds/huskyci/lease",
+			"lease_duration": 3600,
+			"renewable":      true,
+			"data":           map[string]interface{}{"username": fmt.Sprintf("huskyci-%d", f.reads), "password": "secret"},
+		}
+	case "/v1/sys/leases/renew":
+		duration := 3600
+		if f.renewals == 0 {
+			duration = 60
+		}
+		f.renewals--
+		answer = map[string]interface{}{"lease_id": "database/creds/huskyci/lease", "lease_duration": duration, "renewable": true}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"errors":[]}`)
+		return
+	}
+	json.NewEncoder(w).Encode(answer)
+}
+
+var _ = Describe("Vault", func() {
+
+	var (
+		fake   *fakeVault
+		server *httptest.Server
+		config *Config
+	)
+
+	BeforeEach(func() {
+		fake = &fakeVault{renewals: 1}
+		server = httptest.NewServer(fake)
+		config = &Config{
+			Address:    server.URL,
+			AuthMethod: AuthAppRole,
+			RoleID:     "role",
+			SecretID:   "secret",
+			Secrets: "HUSKYCI_VAULT_TEST_USERNAME=database/creds/huskyci#username," +
+				"HUSKYCI_VAULT_TEST_PASSWORD=database/creds/huskyci#password," +
+				"HUSKYCI_VAULT_TEST_CERT=secret/data/huskyci/docker#cert",
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("New", func() {
+		It("Should reject invalid secret references", func() {
+			config.Secrets = "HUSKYCI_DATABASE_DB_PASSWORD=database/creds/huskyci"
+			_, err := New(config)
+			Expect(err).To(HaveOccurred())
+		})
+		It("Should return the errors of Vault", func() {
+			config.AuthMethod = AuthToken
+			config.Token = "s.invalid"
+			_, err := New(config)
+			Expect(err).To(MatchError(ContainSubstring("permission denied")))
+		})
+	})
+
+	Describe("Load", func() {
+		It("Should read every secret, without putting it in the environment", func() {
+			client, err := New(config)
+			Expect(err).To(BeNil())
+			Expect(client.Load()).To(Succeed())
+			Expect(Getenv("HUSKYCI_VAULT_TEST_USERNAME")).To(Equal("huskyci-1"))
+			Expect(Getenv("HUSKYCI_VAULT_TEST_PASSWORD")).To(Equal("secret"))
+			Expect(Getenv("HUSKYCI_VAULT_TEST_CERT")).To(Equal("-----BEGIN CERTIFICATE-----"))
+			Expect(fake.reads).To(Equal(1))
+			for _, envVar := range []string{"HUSKYCI_VAULT_TEST_USERNAME", "HUSKYCI_VAULT_TEST_PASSWORD", "HUSKYCI_VAULT_TEST_CERT"} {
+				_, set := os.LookupEnv(envVar)
+				Expect(set).To(BeFalse())
+			}
+		})
+	})
+
+	Describe("Getenv", func() {
+		It("Should fall back to the environment for the secrets not read from Vault", func() {
+			os.Setenv("HUSKYCI_VAULT_TEST_OTHER", "value")
+			defer os.Unsetenv("HUSKYCI_VAULT_TEST_OTHER")
+			Expect(Getenv("HUSKYCI_VAULT_TEST_OTHER")).To(Equal("value"))
+		})
+	})
+
+	Describe("Renew", func() {
+		It("Should renew the leases, then read new secrets once their max TTL is near", func() {
+			client, err := New(config)
+			Expect(err).To(BeNil())
+			Expect(client.Load()).To(Succeed())
+
+			Expect(client.Renew(time.Now())).To(BeEmpty())
+			Expect(client.Renew(time.Now().Add(31 * time.Minute))).To(BeEmpty())
+			Expect(fake.renewals).To(Equal(0))
+			Expect(fake.reads).To(Equal(1))
+
+			Expect(client.Renew(time.Now().Add(2 * time.Hour))).To(ConsistOf("HUSKYCI_VAULT_TEST_USERNAME"))
+			Expect(fake.reads).To(Equal(2))
+			Expect(Getenv("HUSKYCI_VAULT_TEST_USERNAME")).To(Equal("huskyci-2"))
+		})
+	})
+})
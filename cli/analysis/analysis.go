@@ -1,13 +1,9 @@
 package analysis
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
-	"io"
-	"mime/multipart"
-	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -15,23 +11,23 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/huskyci-org/huskyCI/cli/config"
+	"github.com/huskyci-org/huskyCI/cli/log"
 	"github.com/huskyci-org/huskyCI/cli/types"
 	"github.com/huskyci-org/huskyCI/cli/util"
 	"github.com/huskyci-org/huskyCI/cli/vulnerability"
+	"github.com/huskyci-org/huskyCI/pkg/apiclient"
+	"github.com/huskyci-org/huskyCI/pkg/formatter"
+	"github.com/huskyci-org/huskyCI/pkg/i18n"
+	"github.com/huskyci-org/huskyCI/pkg/sdk"
 	"github.com/src-d/enry/v2"
 )
 
-// verboseMode stores whether verbose mode is enabled
-var verboseMode bool
+// requestTimeouts bounds every request sent to the huskyCI API
+var requestTimeouts sdk.Timeouts
 
-// SetVerbose sets the verbose mode flag
-func SetVerbose(v bool) {
-	verboseMode = v
-}
-
-// IsVerbose returns whether verbose mode is enabled
-func IsVerbose() bool {
-	return verboseMode
+// SetTimeouts sets the connect and read timeouts of the requests sent to the huskyCI API
+func SetTimeouts(timeouts sdk.Timeouts) {
+	requestTimeouts = timeouts
 }
 
 // Analysis is the struct that stores all data from analysis performed.
@@ -42,11 +38,19 @@ type Analysis struct {
 	Errors          []string                      `bson:"errorsFound,omitempty" json:"errorsFound"`
 	Languages       []string                      `bson:"languages" json:"languages"`
 	Path            string                        `json:"-"` // Path being analyzed (for Enry generation)
+	Branch          string                        `json:"-"` // Branch sent to the API, "local" if empty
 	StartedAt       time.Time                     `bson:"startedAt" json:"startedAt"`
 	FinishedAt      time.Time                     `bson:"finishedAt" json:"finishedAt"`
 	Vulnerabilities []vulnerability.Vulnerability `bson:"vulnerabilities" json:"vulnerabilities"`
 	Result          Result                        `bson:"result,omitempty" json:"result"`
+	Warnings        []string                      `bson:"warnings,omitempty" json:"warnings,omitempty"`
 	APITarget       *types.Target                 `json:"-"` // API target configuration
+	Prefix          string                        `json:"-"` // Printed before each line of progress, such as the target of the analysis
+	Local           bool                          `json:"-"` // Run with the local Docker daemon instead of the huskyCI API
+	LocalConfig     string                        `json:"-"` // huskyCI API configuration of the securityTests of a local analysis
+	SecurityTests   []string                      `json:"-"` // securityTests to run, all of them if empty
+
+	localSecurityTests []LocalSecurityTest
 }
 
 // CompressedFile holds the info from the compressed file
@@ -68,46 +72,61 @@ func New() *Analysis {
 	}
 }
 
+// printf prints a line of progress of the analysis, translated, after its
+// Prefix, unless the CLI runs with --quiet. The blank lines format starts
+// with are printed before the Prefix.
+func (a *Analysis) printf(format string, args ...interface{}) {
+	if log.Quiet() {
+		return
+	}
+	line := i18n.Sprintf(format, args...)
+	if a.Prefix == "" {
+		formatter.Print(line)
+		return
+	}
+	text := strings.TrimLeft(line, "\n")
+	formatter.Print(line[:len(line)-len(text)] + a.Prefix + text)
+}
+
+// println prints a line of progress of the analysis, after its Prefix.
+func (a *Analysis) println(line string) {
+	a.printf("%s\n", i18n.T(line))
+}
+
 // CheckPath checks the given path to check which languages were found and do some others security checks
 func (a *Analysis) CheckPath(path string) error {
 
 	fullPath, err := filepath.Abs(path)
 	if err != nil {
-		return fmt.Errorf("error resolving path '%s': %w", path, err)
+		return i18n.Errorf("error resolving path '%s': %w", path, err)
 	}
 
-	if IsVerbose() {
-		fmt.Printf("[VERBOSE] Resolved path: %s\n", fullPath)
-	}
+	log.Debug("Resolved path", "path", fullPath)
 
 	// Check if path exists
 	if _, err := os.Stat(fullPath); os.IsNotExist(err) {
-		return fmt.Errorf("path does not exist: %s\n\nTip: Make sure the path is correct and try again", fullPath)
+		return i18n.Errorf("path does not exist: %s\n\nTip: Make sure the path is correct and try again", fullPath)
 	}
 
-	fmt.Printf("🔍 Scanning code from: %s\n", fullPath)
+	a.printf("🔍 Scanning code from: %s\n", fullPath)
 
 	// Store path for later use (e.g., Enry output generation)
 	a.Path = fullPath
 
 	if err := a.setLanguages(fullPath); err != nil {
 		if err.Error() == "no languages found" {
-			return fmt.Errorf("no supported programming languages found in '%s'\n\nTip: Make sure the directory contains code files in supported languages (Python, Ruby, JavaScript, Go, Java, C#, HCL)", fullPath)
+			return i18n.Errorf("no supported programming languages found in '%s'\n\nTip: Make sure the directory contains code files in supported languages (Python, Ruby, JavaScript, Go, Java, C#, HCL)", fullPath)
 		}
-		return fmt.Errorf("error detecting languages: %w", err)
+		return i18n.Errorf("error detecting languages: %w", err)
 	}
 
-	if IsVerbose() {
-		fmt.Printf("[VERBOSE] Detected %d languages: %v\n", len(a.Languages), a.Languages)
-	}
+	log.Debug("Detected languages", "count", len(a.Languages), "languages", a.Languages)
 
-	fmt.Println("\n📋 Detected languages:")
+	a.println("\n📋 Detected languages:")
 	securityTests := a.getAvailableSecurityTests(a.Languages)
 	for language := range securityTests {
-		fmt.Printf("  ✓ %s\n", language)
-		if IsVerbose() {
-			fmt.Printf("    [VERBOSE] Security tests: %v\n", securityTests[language])
-		}
+		a.printf("  ✓ %s\n", language)
+		log.Debug("Security tests of language", "language", language, "securityTests", securityTests[language])
 	}
 
 	return nil
@@ -116,471 +135,344 @@ func (a *Analysis) CheckPath(path string) error {
 // CompressFiles will compress all files from a given path into a single file named GUID
 func (a *Analysis) CompressFiles(path string) error {
 
-	fmt.Println("\n📦 Compressing code...")
+	a.println("\n📦 Compressing code...")
 
-	if IsVerbose() {
-		fmt.Printf("[VERBOSE] Compressing files from path: %s\n", path)
-	}
+	log.Debug("Compressing files", "path", path)
 
 	if err := a.HouseCleaning(); err != nil {
 		// it's ok. maybe the file is not there yet.
-		if IsVerbose() {
-			fmt.Printf("[VERBOSE] Could not clean previous zip file (this is OK if it doesn't exist): %v\n", err)
-		}
+		log.Debug("Could not clean previous zip file, which is OK if it doesn't exist", "error", err)
 	}
 
 	allFilesAndDirNames, err := util.GetAllAllowedFilesAndDirsFromPath(path)
 	if err != nil {
-		return fmt.Errorf("error reading files from path: %w", err)
+		return i18n.Errorf("error reading files from path: %w", err)
 	}
 
-	if IsVerbose() {
-		fmt.Printf("[VERBOSE] Found %d files/directories to compress\n", len(allFilesAndDirNames))
-	}
+	log.Debug("Found files and directories to compress", "count", len(allFilesAndDirNames))
 
 	zipFilePath, err := util.CompressFiles(allFilesAndDirNames)
 	if err != nil {
-		return fmt.Errorf("error compressing files: %w", err)
+		return i18n.Errorf("error compressing files: %w", err)
 	}
 
-	if IsVerbose() {
-		fmt.Printf("[VERBOSE] Zip file created at: %s\n", zipFilePath)
-	}
+	log.Debug("Zip file created", "path", zipFilePath)
 
 	if err := a.setZipSize(zipFilePath); err != nil {
-		return fmt.Errorf("error calculating archive size: %w", err)
+		return i18n.Errorf("error calculating archive size: %w", err)
 	}
 
-	fmt.Printf("✓ Compressed successfully! Size: %s\n", a.CompressedFile.Size)
+	a.printf("✓ Compressed successfully! Size: %s\n", a.CompressedFile.Size)
 
 	return nil
 }
 
 // SendZip will send the zip file to the huskyCI API to start the analysis
-func (a *Analysis) SendZip() error {
-	fmt.Println("\n🚀 Sending code to huskyCI API...")
-
-	// Get API target configuration
-	target, err := config.GetCurrentTarget()
-	if err != nil {
-		return fmt.Errorf("failed to get API target configuration: %w\n\nTip: Configure a target using 'huskyci target-add <name> <endpoint>'", err)
+func (a *Analysis) SendZip(ctx context.Context) error {
+	a.println("\n🚀 Sending code to huskyCI API...")
+
+	// Get API target configuration, unless the analysis runs on a given one
+	target := a.APITarget
+	if target == nil {
+		var err error
+		target, err = config.GetCurrentTarget()
+		if err != nil {
+			return i18n.Errorf("failed to get API target configuration: %w\n\nTip: Configure a target using 'huskyci target-add <name> <endpoint>'", err)
+		}
 	}
 
 	if target.Token == "" {
-		return fmt.Errorf("authentication token not found\n\nTip: Set HUSKYCI_CLI_TOKEN environment variable or configure token storage")
+		return i18n.Errorf("authentication token not found\n\nTip: Set HUSKYCI_CLI_TOKEN environment variable or configure token storage")
 	}
 
 	a.APITarget = target
 
-	if IsVerbose() {
-		zipFilePath, err := config.GetHuskyZipFilePath()
-		if err == nil {
-			fmt.Printf("[VERBOSE] Zip file path: %s\n", zipFilePath)
-		}
-		fmt.Printf("[VERBOSE] Analysis ID: %s\n", a.ID)
-		fmt.Printf("[VERBOSE] API endpoint: %s\n", target.Endpoint)
-	}
+	log.Debug("Sending code", "rid", a.ID, "endpoint", target.Endpoint)
 
-	// Create HTTP client
-	useTLS := util.IsHTTPS(target.Endpoint)
-	httpClient, err := util.NewHTTPClient(useTLS)
+	client, err := newClient(target)
 	if err != nil {
-		return fmt.Errorf("failed to create HTTP client: %w", err)
+		return err
 	}
 
 	// For local file analysis, upload the zip file first
 	zipFilePath, err := config.GetHuskyZipFilePath()
 	if err != nil {
-		return fmt.Errorf("failed to get zip file path: %w", err)
+		return i18n.Errorf("failed to get zip file path: %w", err)
 	}
 
 	// Upload zip file for local analysis
-	fmt.Println("📤 Uploading zip file...")
-	if IsVerbose() {
-		fmt.Printf("[VERBOSE] Preparing to upload zip file: %s\n", zipFilePath)
-		fmt.Printf("[VERBOSE] Analysis ID (RID): %s\n", a.ID)
-	}
-	normalizedEndpoint := util.NormalizeURL(target.Endpoint)
-	uploadURL := fmt.Sprintf("%s/analysis/upload?rid=%s", normalizedEndpoint, a.ID)
-	
-	if IsVerbose() {
-		fmt.Printf("[VERBOSE] Upload URL: %s\n", uploadURL)
-	}
-	
-	zipFile, err := os.Open(zipFilePath)
-	if err != nil {
-		return fmt.Errorf("failed to open zip file: %w", err)
-	}
-	defer zipFile.Close()
-	
-	if IsVerbose() {
-		fileInfo, _ := zipFile.Stat()
-		fmt.Printf("[VERBOSE] Zip file opened successfully, size: %d bytes\n", fileInfo.Size())
-	}
-
-	var uploadBody bytes.Buffer
-	writer := multipart.NewWriter(&uploadBody)
-	part, err := writer.CreateFormFile("zipfile", filepath.Base(zipFilePath))
-	if err != nil {
-		return fmt.Errorf("failed to create form file: %w", err)
-	}
-	
-	if _, err := io.Copy(part, zipFile); err != nil {
-		return fmt.Errorf("failed to copy file content: %w", err)
-	}
-	writer.Close()
-
-	uploadReq, err := http.NewRequest("POST", uploadURL, &uploadBody)
-	if err != nil {
-		return fmt.Errorf("failed to create upload request: %w", err)
-	}
-	uploadReq.Header.Set("Content-Type", writer.FormDataContentType())
-	uploadReq.Header.Add("Husky-Token", target.Token)
-	uploadReq.Header.Add("User-Agent", "huskyci-cli")
-
-	if IsVerbose() {
-		fmt.Printf("[VERBOSE] Sending upload request to: %s\n", uploadURL)
-		fmt.Printf("[VERBOSE] Content-Type: %s\n", writer.FormDataContentType())
-		fmt.Printf("[VERBOSE] Upload body size: %d bytes\n", uploadBody.Len())
-	}
+	a.println("📤 Uploading zip file...")
+	log.Debug("Uploading zip file", "path", zipFilePath, "rid", a.ID)
 
-	uploadResp, err := httpClient.Do(uploadReq)
+	uploadedRID, err := client.UploadZip(ctx, a.ID, zipFilePath)
 	if err != nil {
-		return fmt.Errorf("failed to upload zip file: %w\n\nTip: Check your network connection and verify the API endpoint is accessible", err)
-	}
-	defer uploadResp.Body.Close()
-
-	if IsVerbose() {
-		fmt.Printf("[VERBOSE] Upload response status: %d\n", uploadResp.StatusCode)
-	}
-
-	uploadBodyBytes, _ := io.ReadAll(uploadResp.Body)
-	if uploadResp.StatusCode != http.StatusCreated {
-		if IsVerbose() {
-			fmt.Printf("[VERBOSE] Upload failed, response: %s\n", string(uploadBodyBytes))
+		if errors.Is(err, sdk.ErrNetwork) {
+			return i18n.Errorf("failed to upload zip file: %w\n\nTip: Check your network connection and verify the API endpoint is accessible", err)
 		}
-		return fmt.Errorf("failed to upload zip file\n\nStatus Code: %d\nResponse: %s\n\nTip: Verify the API supports zip file uploads", uploadResp.StatusCode, string(uploadBodyBytes))
-	}
-
-	// Verify the upload response contains the correct RID
-	var uploadRespData map[string]interface{}
-	if err := json.Unmarshal(uploadBodyBytes, &uploadRespData); err == nil {
-		if respRID, ok := uploadRespData["rid"].(string); ok && respRID != "" {
-			if respRID != a.ID {
-				if IsVerbose() {
-					fmt.Printf("[VERBOSE] Warning: Upload response RID (%s) differs from expected RID (%s)\n", respRID, a.ID)
-				}
-				// Use the RID from the response if different
-				a.ID = respRID
-			}
-			if IsVerbose() {
-				fmt.Printf("[VERBOSE] Upload confirmed with RID: %s\n", respRID)
-			}
+		if errors.Is(err, sdk.ErrRejected) {
+			return i18n.Errorf("the huskyCI API rejected the zip file\n\n%s\n\nTip: Check the size and the content of the code to analyze", err)
 		}
+		return i18n.Errorf("failed to upload zip file\n\n%s\n\nTip: Verify the API supports zip file uploads", err)
 	}
 
-	if IsVerbose() {
-		fmt.Printf("[VERBOSE] Zip file uploaded successfully with RID: %s\n", a.ID)
-		fmt.Printf("[VERBOSE] Upload response: %s\n", string(uploadBodyBytes))
+	// Use the RID the zip was stored under if it differs from the expected one
+	if uploadedRID != a.ID {
+		log.Warn("Upload response RID differs from the expected one", "rid", uploadedRID, "expected", a.ID)
+		a.ID = uploadedRID
 	}
-	fmt.Println("✓ Zip file uploaded successfully!")
 
-	// Generate Enry output locally for file:// URLs
+	log.Debug("Zip file uploaded", "rid", a.ID)
+	a.println("✓ Zip file uploaded successfully!")
+
+	// Generate Enry output locally for the upload
 	// This avoids docker-in-docker issues where Enry can't see extracted files
 	var enryOutput string
 	if a.Path != "" {
-		if IsVerbose() {
-			fmt.Printf("[VERBOSE] Generating Enry output locally from path: %s\n", a.Path)
-		}
+		log.Debug("Generating Enry output locally", "path", a.Path)
 		enryOutput, err = a.generateEnryOutput(a.Path)
 		if err != nil {
-			if IsVerbose() {
-				fmt.Printf("[VERBOSE] Warning: Failed to generate Enry output locally: %v (API will run Enry instead)\n", err)
-			}
+			log.Warn("Failed to generate Enry output locally, the API will run Enry instead", "error", err)
 			// Continue without Enry output - API will run Enry
 			enryOutput = ""
 		} else {
-			if IsVerbose() {
-				fmt.Printf("[VERBOSE] Generated Enry output: %s\n", enryOutput)
-			}
+			log.Trace("Generated Enry output", "output", enryOutput)
 		}
 	}
 	
 	// Prepare request payload for analysis
-	requestPayload := types.JSONPayload{
-		RepositoryURL:      fmt.Sprintf("file://%s", a.ID), // Using analysis ID as identifier
-		RepositoryBranch:   "local",
+	requestPayload := apiclient.Repository{
+		AnalysisType:       "upload",
+		UploadID:           a.ID,
+		Branch:             "local",
 		LanguageExclusions: make(map[string]bool),
 		EnryOutput:         enryOutput, // Send Enry output to API
+		SecurityTests:      a.SecurityTests,
 	}
-
-	marshalPayload, err := json.Marshal(requestPayload)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request payload: %w", err)
+	if a.Branch != "" {
+		requestPayload.Branch = a.Branch
 	}
 
-	// Create POST request to start analysis
-	apiURL := fmt.Sprintf("%s/analysis", normalizedEndpoint)
-	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(marshalPayload))
-	if err != nil {
-		return fmt.Errorf("failed to create HTTP request: %w", err)
-	}
-
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Husky-Token", target.Token)
-	req.Header.Add("User-Agent", "huskyci-cli")
-
-	if IsVerbose() {
-		fmt.Printf("[VERBOSE] Sending POST request to: %s\n", apiURL)
-		fmt.Printf("[VERBOSE] Request payload: %s\n", string(marshalPayload))
-	}
+	log.Debug("Starting analysis of upload", "upload", requestPayload.UploadID)
 
-	// Send request
-	resp, err := httpClient.Do(req)
+	RID, err := client.StartAnalysis(ctx, requestPayload)
 	if err != nil {
-		return fmt.Errorf("failed to send request to API: %w\n\nTip: Check your network connection and verify the API endpoint is accessible", err)
-	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-
-	if resp.StatusCode != http.StatusCreated {
-		if resp.StatusCode == http.StatusUnauthorized {
-			return fmt.Errorf("authentication failed: The provided token is invalid or expired\n\nTip: Generate a new token using the huskyCI API")
-		}
-		if resp.StatusCode == http.StatusBadRequest {
-			// Try to extract error message from response
-			var errorResp map[string]interface{}
-			errorMsg := "Invalid request parameters"
-			if err := json.Unmarshal(body, &errorResp); err == nil {
-				if msg, ok := errorResp["message"].(string); ok && msg != "" {
-					errorMsg = msg
-				} else if errStr, ok := errorResp["error"].(string); ok && errStr != "" {
-					errorMsg = errStr
-				}
+		sdkErr := &sdk.Error{}
+		errors.As(err, &sdkErr)
+		switch {
+		case errors.Is(err, sdk.ErrNetwork):
+			return i18n.Errorf("failed to send request to API: %w\n\nTip: Check your network connection and verify the API endpoint is accessible", err)
+		case errors.Is(err, sdk.ErrUnauthorized):
+			return i18n.Errorf("authentication failed: The provided token is invalid or expired\n\nTip: Generate a new token using the huskyCI API")
+		case errors.Is(err, sdk.ErrBadRequest):
+			body := string(sdkErr.Body)
+			// Check if the upload is missing (zip file not found)
+			if sdkErr.Reason == "zip file not found" {
+				return i18n.Errorf("zip file not found on server\n\nRID used: %s\nStatus: %d\nResponse: %s\n\nPossible causes:\n  1. The zip file upload may have failed silently\n  2. The API server may not have write permissions to /tmp/huskyci-zips\n  3. There may be a mismatch between the upload RID and analysis RID\n\nTroubleshooting:\n  - Run with --verbose flag to see detailed logs\n  - Check API server logs for upload errors\n  - Verify the API server has write access to /tmp/huskyci-zips directory\n  - Try uploading again: huskyci run %s", a.ID, sdkErr.StatusCode, body, a.ID)
 			}
-			
-			// Check if this is a file:// URL issue (zip file not found)
-			if strings.Contains(requestPayload.RepositoryURL, "file://") {
-				if strings.Contains(string(body), "zip file not found") || strings.Contains(errorMsg, "zip file not found") {
-					return fmt.Errorf("zip file not found on server\n\nRID used: %s\nStatus: %d\nResponse: %s\n\nPossible causes:\n  1. The zip file upload may have failed silently\n  2. The API server may not have write permissions to /tmp/huskyci-zips\n  3. There may be a mismatch between the upload RID and analysis RID\n\nTroubleshooting:\n  - Run with --verbose flag to see detailed logs\n  - Check API server logs for upload errors\n  - Verify the API server has write access to /tmp/huskyci-zips directory\n  - Try uploading again: huskyci run %s", a.ID, resp.StatusCode, string(body), a.ID)
-				}
-				return fmt.Errorf("local file analysis error\n\nRID: %s\nStatus: %d\nResponse: %s\n\nTip: The zip file was uploaded but the analysis request failed. Check the API logs for more details.", a.ID, resp.StatusCode, string(body))
-			}
-			
-			return fmt.Errorf("bad request: %s\n\nStatus: %d\nResponse: %s\n\nTip: Verify that the repository URL is a valid git repository URL (e.g., https://github.com/user/repo.git)", errorMsg, resp.StatusCode, string(body))
+			return i18n.Errorf("local file analysis error\n\nRID: %s\nStatus: %d\nResponse: %s\n\nTip: The zip file was uploaded but the analysis request failed. Check the API logs for more details.", a.ID, sdkErr.StatusCode, body)
+		case errors.Is(err, sdk.ErrConflict):
+			return i18n.Errorf("conflict: An analysis is already running\n\nStatus: %d\nResponse: %s", sdkErr.StatusCode, string(sdkErr.Body))
 		}
-		if resp.StatusCode == http.StatusConflict {
-			return fmt.Errorf("conflict: An analysis is already running\n\nStatus: %d\nResponse: %s", resp.StatusCode, string(body))
-		}
-		return fmt.Errorf("failed to start analysis: Unexpected response from API\n\nStatus Code: %d\nResponse: %s\n\nTip: Check the huskyCI API status and try again", resp.StatusCode, string(body))
-	}
-
-	// Extract RID from response header
-	RID := resp.Header.Get("X-Request-Id")
-	if RID == "" {
-		// Try to parse from response body
-		var responseBody map[string]interface{}
-		if err := json.Unmarshal(body, &responseBody); err == nil {
-			if rid, ok := responseBody["rid"].(string); ok {
-				RID = rid
-			}
+		if sdkErr.DocsURL != "" {
+			return i18n.Errorf("failed to start analysis: %s\n\n%s\n\nTip: See %s", sdkErr.Code, err, sdkErr.DocsURL)
 		}
-	}
-
-	if RID == "" {
-		return fmt.Errorf("failed to start analysis: No request ID (RID) received from the API\n\nTip: This may indicate an issue with the huskyCI API. Please check the API status")
+		return i18n.Errorf("failed to start analysis: Unexpected response from API\n\n%s\n\nTip: Check the huskyCI API status and try again", err)
 	}
 
 	a.RID = RID
 	a.Result.Status = "running"
 	a.StartedAt = time.Now()
 
-	if IsVerbose() {
-		fmt.Printf("[VERBOSE] Analysis started successfully with RID: %s\n", RID)
-	}
+	log.Debug("Analysis started", "rid", RID)
 
-	fmt.Println("✓ Code sent successfully!")
+	a.println("✓ Code sent successfully!")
 	return nil
 }
 
-// CheckStatus is a worker to check the huskyCI API for the status of the particular analysis
-func (a *Analysis) CheckStatus() error {
+// CheckStatus is a worker to check the huskyCI API for the status of the particular analysis.
+// It gives up after timeout and cancels the analysis when ctx is cancelled, on an interrupt for instance.
+func (a *Analysis) CheckStatus(ctx context.Context, timeout time.Duration) error {
 	if a.RID == "" {
-		return fmt.Errorf("no RID available - analysis was not started successfully")
+		return i18n.Errorf("no RID available - analysis was not started successfully")
 	}
 
 	if a.APITarget == nil {
 		target, err := config.GetCurrentTarget()
 		if err != nil {
-			return fmt.Errorf("failed to get API target configuration: %w", err)
+			return i18n.Errorf("failed to get API target configuration: %w", err)
 		}
 		a.APITarget = target
 	}
 
-	fmt.Println("\n⏳ Checking analysis status...")
+	a.println("\n⏳ Checking analysis status...")
 
-	if IsVerbose() {
-		fmt.Printf("[VERBOSE] Analysis RID: %s\n", a.RID)
-		fmt.Printf("[VERBOSE] API endpoint: %s\n", a.APITarget.Endpoint)
-	}
+	log.Debug("Checking analysis status", "rid", a.RID, "endpoint", a.APITarget.Endpoint)
 
-	// Create HTTP client
-	useTLS := util.IsHTTPS(a.APITarget.Endpoint)
-	httpClient, err := util.NewHTTPClient(useTLS)
+	client, err := newClient(a.APITarget)
 	if err != nil {
-		return fmt.Errorf("failed to create HTTP client: %w", err)
+		return err
 	}
 
-	// Poll API for analysis status
-	timeout := time.After(60 * time.Minute)
-	ticker := time.NewTicker(5 * time.Second) // Check every 5 seconds
-	defer ticker.Stop()
-
-	checkCount := 0
-
-	for {
-		select {
-		case <-timeout:
-			return fmt.Errorf("analysis timed out after 60 minutes\n\nTip: Large codebases may take longer to analyze. Try again or contact support if this persists")
-		case <-ticker.C:
-			checkCount++
-
-			// Create GET request
-			normalizedEndpoint := util.NormalizeURL(a.APITarget.Endpoint)
-			apiURL := fmt.Sprintf("%s/analysis/%s", normalizedEndpoint, a.RID)
-			req, err := http.NewRequest("GET", apiURL, nil)
-			if err != nil {
-				return fmt.Errorf("failed to create HTTP request: %w", err)
-			}
-
-			req.Header.Add("Husky-Token", a.APITarget.Token)
-			req.Header.Add("User-Agent", "huskyci-cli")
-
-			if IsVerbose() && checkCount%12 == 0 { // Log every minute (12 * 5 seconds)
-				fmt.Printf("[VERBOSE] Checking analysis status (attempt #%d)...\n", checkCount)
-			}
-
-			// Send request
-			resp, err := httpClient.Do(req)
-			if err != nil {
-				if IsVerbose() {
-					fmt.Printf("[VERBOSE] Network error (will retry): %v\n", err)
-				}
-				continue // Retry on network errors
-			}
-
-			body, _ := io.ReadAll(resp.Body)
-			resp.Body.Close()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
 
-			if resp.StatusCode == http.StatusNotFound {
-				if checkCount < 3 {
-					// Analysis might not be created yet, wait a bit
-					continue
-				}
-				return fmt.Errorf("analysis not found: No analysis found with RID '%s'\n\nTip: Verify the RID is correct and the analysis exists", a.RID)
-			}
-
-			if resp.StatusCode == http.StatusUnauthorized {
-				return fmt.Errorf("authentication failed: Invalid or expired token\n\nTip: Generate a new token using the huskyCI API")
-			}
-
-			if resp.StatusCode != http.StatusOK {
-				if IsVerbose() {
-					fmt.Printf("[VERBOSE] Unexpected status code %d, will retry\n", resp.StatusCode)
+	// the estimate of the API is read again whenever a securityTest finishes
+	bar := newProgress(time.Now())
+	estimate := func() {
+		if current, err := client.GetAnalysis(ctx, a.RID); err == nil {
+			bar.update(current, time.Now())
+		}
+	}
+	waiting := make(chan struct{})
+	defer close(waiting)
+	go func() {
+		estimate()
+		ticker := time.NewTicker(progressTick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-waiting:
+				return
+			case <-ticker.C:
+				if line, moved := bar.line(time.Now()); moved {
+					a.println(line)
 				}
-				continue // Retry on other errors
 			}
+		}
+	}()
 
-			// Parse response
-			var apiAnalysis types.Analysis
-			if err := json.Unmarshal(body, &apiAnalysis); err != nil {
-				if IsVerbose() {
-					fmt.Printf("[VERBOSE] Failed to parse response (will retry): %v\n", err)
-				}
-				continue
-			}
+	client.OnEvent = func(event apiclient.AnalysisEvent) {
+		switch {
+		case event.Type == sdk.EventSecurityTest:
+			a.printf("  ✓ %s finished (%s)\n", event.SecurityTest, event.CResult)
+			go estimate()
+		default:
+			log.Debug("Analysis status pushed by the API", "rid", a.RID, "status", event.Status)
+		}
+	}
 
-			// Update analysis status
-			a.Result.Status = apiAnalysis.Status
-			if apiAnalysis.ErrorFound != "" {
-				a.Errors = append(a.Errors, apiAnalysis.ErrorFound)
-			}
+	checkCount := 0
+	result, err := client.WaitAnalysis(ctx, a.RID, func(current *apiclient.Analysis) {
+		checkCount++
+		a.update(current)
+		bar.update(current, time.Now())
+		log.Debug("Current status", "rid", a.RID, "status", a.Result.Status, "check", checkCount)
+	})
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return i18n.Errorf("analysis timed out after %s\n\nTip: Large codebases may take longer to analyze. Raise it with --timeout or contact support if this persists", timeout)
+	case errors.Is(err, context.Canceled):
+		return a.cancel(client)
+	case errors.Is(err, sdk.ErrCancelled):
+		return i18n.Errorf("analysis %s was cancelled before it finished", a.RID)
+	case errors.Is(err, sdk.ErrAnalysisFailed):
+		errorMsg := result.ErrorFound
+		if errorMsg == "" {
+			errorMsg = "Unknown error occurred during analysis"
+		}
+		return i18n.Errorf("analysis failed: %s\n\nTip: Check the analysis details for more information", errorMsg)
+	case errors.Is(err, sdk.ErrNotFound):
+		return i18n.Errorf("analysis not found: No analysis found with RID '%s'\n\nTip: Verify the RID is correct and the analysis exists", a.RID)
+	case errors.Is(err, sdk.ErrUnauthorized):
+		return i18n.Errorf("authentication failed: Invalid or expired token\n\nTip: Generate a new token using the huskyCI API")
+	case err != nil:
+		return i18n.Errorf("failed to check analysis status: %w", err)
+	}
 
-			if !apiAnalysis.StartedAt.IsZero() {
-				a.StartedAt = apiAnalysis.StartedAt
-			}
-			if !apiAnalysis.FinishedAt.IsZero() {
-				a.FinishedAt = apiAnalysis.FinishedAt
-			}
+	log.Debug("Analysis completed", "rid", a.RID, "checks", checkCount)
+	a.println("✓ Analysis check completed!")
+	return nil
+}
 
-			// Convert API vulnerabilities to CLI format
-			if err := a.convertAPIVulnerabilities(apiAnalysis); err != nil {
-				if IsVerbose() {
-					fmt.Printf("[VERBOSE] Warning: Failed to convert vulnerabilities: %v\n", err)
-				}
-			}
+// update copies the status, dates, errors and vulnerabilities of an analysis
+// received from the huskyCI API.
+func (a *Analysis) update(current *apiclient.Analysis) {
+	var apiAnalysis types.Analysis
+	if err := sdk.Decode(current, &apiAnalysis); err != nil {
+		log.Warn("Failed to parse response", "error", err)
+		return
+	}
 
-			if IsVerbose() {
-				fmt.Printf("[VERBOSE] Current status: %s (check #%d)\n", a.Result.Status, checkCount)
-			}
+	a.Result.Status = apiAnalysis.Status
+	a.Warnings = apiAnalysis.Warnings
+	if apiAnalysis.ErrorFound != "" {
+		a.Errors = append(a.Errors, apiAnalysis.ErrorFound)
+	}
+	if !apiAnalysis.StartedAt.IsZero() {
+		a.StartedAt = apiAnalysis.StartedAt
+	}
+	if !apiAnalysis.FinishedAt.IsZero() {
+		a.FinishedAt = apiAnalysis.FinishedAt
+	}
 
-			// Check if analysis is complete
-			if apiAnalysis.Status == "finished" {
-				if IsVerbose() {
-					fmt.Printf("[VERBOSE] Analysis completed after %d checks\n", checkCount)
-				}
-				fmt.Println("✓ Analysis check completed!")
-				return nil
-			}
+	// Convert API vulnerabilities to CLI format
+	if err := a.convertAPIVulnerabilities(apiAnalysis); err != nil {
+		log.Warn("Failed to convert vulnerabilities", "error", err)
+	}
+}
 
-			if apiAnalysis.Status == "error running" {
-				errorMsg := apiAnalysis.ErrorFound
-				if errorMsg == "" {
-					errorMsg = "Unknown error occurred during analysis"
-				}
-				return fmt.Errorf("analysis failed: %s\n\nTip: Check the analysis details for more information", errorMsg)
-			}
+// newClient returns a huskyCI SDK client of target authenticated with its token.
+func newClient(target *types.Target) (*sdk.Client, error) {
+	httpClient, err := util.NewHTTPClient(util.IsHTTPS(target.Endpoint), target.ClientCert, target.ClientKey)
+	if err != nil {
+		return nil, i18n.Errorf("failed to create HTTP client: %w", err)
+	}
+	requestTimeouts.Apply(httpClient)
+	httpClient.Transport = log.Transport(httpClient.Transport)
+	api := apiclient.New(util.NormalizeURL(target.Endpoint))
+	api.HTTPClient = httpClient
+	api.Token = target.Token
+	api.UserAgent = config.UserAgent()
+	return sdk.New(api), nil
+}
 
-			// Status is "running" or other, continue polling
-		}
-	}
+// cancel cancels the analysis the CLI stopped waiting for.
+func (a *Analysis) cancel(client *sdk.Client) error {
+	a.println("\n⏹  Interrupted, cancelling the analysis...")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	err := client.CancelAnalysis(ctx, a.RID)
+	switch {
+	case errors.Is(err, sdk.ErrConflict):
+		return i18n.Errorf("analysis interrupted after it finished (RID: %s)", a.RID)
+	case err != nil:
+		return i18n.Errorf("analysis interrupted, but it could not be cancelled: %w\n\nTip: It keeps running on the huskyCI API until it finishes (RID: %s)", err, a.RID)
+	}
+	return i18n.Errorf("analysis interrupted and cancelled (RID: %s)", a.RID)
 }
 
 // PrintVulns prints all vulnerabilities found after the analysis has been finished
 func (a *Analysis) PrintVulns() {
-	fmt.Println("\n📊 Analysis Results:")
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-
-	if IsVerbose() {
-		fmt.Printf("[VERBOSE] Analysis ID: %s\n", a.ID)
-		fmt.Printf("[VERBOSE] Status: %s\n", a.Result.Status)
-		if a.Result.Info != "" {
-			fmt.Printf("[VERBOSE] Info: %s\n", a.Result.Info)
-		}
-		fmt.Printf("[VERBOSE] Vulnerabilities count: %d\n", len(a.Vulnerabilities))
-		if len(a.Errors) > 0 {
-			fmt.Printf("[VERBOSE] Errors: %v\n", a.Errors)
+	formatter.Println(i18n.T("\n📊 Analysis Results:"))
+	formatter.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+
+	log.Debug("Analysis results", "rid", a.ID, "status", a.Result.Status, "info", a.Result.Info, "vulnerabilities", len(a.Vulnerabilities), "errors", a.Errors)
+
+	// Partial results, such as when a securityTest timed out
+	if len(a.Warnings) > 0 {
+		formatter.Println(i18n.T("\n⚠️  The results are partial:"))
+		for _, warning := range a.Warnings {
+			formatter.Printf("   • %s\n", warning)
 		}
 	}
 
 	// Check if we have any vulnerabilities to display
 	if len(a.Vulnerabilities) == 0 {
 		if a.Result.Status == "" {
-			fmt.Println("\n⚠️  No analysis results available.")
-			fmt.Println("   This may indicate that:")
-			fmt.Println("   • The analysis has not completed yet")
-			fmt.Println("   • The API integration is not fully implemented")
-			if IsVerbose() {
-				fmt.Println("   • Use --verbose flag for more debugging information")
+			formatter.Println(i18n.T("\n⚠️  No analysis results available."))
+			formatter.Println(i18n.T("   This may indicate that:"))
+			formatter.Println(i18n.T("   • The analysis has not completed yet"))
+			formatter.Println(i18n.T("   • The API integration is not fully implemented"))
+			if log.Verbose() {
+				formatter.Println(i18n.T("   • Use --verbose flag for more debugging information"))
 			}
 		} else if a.Result.Status == "finished" {
-			fmt.Println("\n✅ No vulnerabilities found!")
-			fmt.Println("   Your code appears to be secure.")
+			formatter.Println(i18n.T("\n✅ No vulnerabilities found!"))
+			formatter.Println(i18n.T("   Your code appears to be secure."))
 		} else {
-			fmt.Printf("\n📋 Analysis Status: %s\n", a.Result.Status)
+			i18n.Printf("\n📋 Analysis Status: %s\n", a.Result.Status)
 			if a.Result.Info != "" {
-				fmt.Printf("   Info: %s\n", a.Result.Info)
+				i18n.Printf("   Info: %s\n", a.Result.Info)
 			}
 		}
 		return
@@ -606,42 +498,42 @@ func (a *Analysis) PrintVulns() {
 	}
 
 	// Print summary
-	fmt.Println("\n📈 Summary:")
-	fmt.Printf("   🔴 High:   %d\n", len(highVulns))
-	fmt.Printf("   🟠 Medium: %d\n", len(mediumVulns))
-	fmt.Printf("   🟡 Low:    %d\n", len(lowVulns))
+	formatter.Println(i18n.T("\n📈 Summary:"))
+	i18n.Printf("   🔴 High:   %d\n", len(highVulns))
+	i18n.Printf("   🟠 Medium: %d\n", len(mediumVulns))
+	i18n.Printf("   🟡 Low:    %d\n", len(lowVulns))
 	if len(infoVulns) > 0 {
-		fmt.Printf("   ℹ️  Info:   %d\n", len(infoVulns))
+		i18n.Printf("   ℹ️  Info:   %d\n", len(infoVulns))
 	}
 
 	// Print vulnerabilities by severity
 	if len(highVulns) > 0 {
-		fmt.Println("\n🔴 HIGH SEVERITY VULNERABILITIES:")
-		fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+		formatter.Println(i18n.T("\n🔴 HIGH SEVERITY VULNERABILITIES:"))
+		formatter.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 		for i, vuln := range highVulns {
 			printVulnerability(vuln, i+1)
 		}
 	}
 
 	if len(mediumVulns) > 0 {
-		fmt.Println("\n🟠 MEDIUM SEVERITY VULNERABILITIES:")
-		fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+		formatter.Println(i18n.T("\n🟠 MEDIUM SEVERITY VULNERABILITIES:"))
+		formatter.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 		for i, vuln := range mediumVulns {
 			printVulnerability(vuln, i+1)
 		}
 	}
 
 	if len(lowVulns) > 0 {
-		fmt.Println("\n🟡 LOW SEVERITY VULNERABILITIES:")
-		fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+		formatter.Println(i18n.T("\n🟡 LOW SEVERITY VULNERABILITIES:"))
+		formatter.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 		for i, vuln := range lowVulns {
 			printVulnerability(vuln, i+1)
 		}
 	}
 
 	if len(infoVulns) > 0 {
-		fmt.Println("\nℹ️  INFO VULNERABILITIES:")
-		fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+		formatter.Println(i18n.T("\nℹ️  INFO VULNERABILITIES:"))
+		formatter.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 		for i, vuln := range infoVulns {
 			printVulnerability(vuln, i+1)
 		}
@@ -743,6 +635,18 @@ func (a *Analysis) convertAPIVulnerabilities(apiAnalysis types.Analysis) error {
 		a.Vulnerabilities = append(a.Vulnerabilities, convertHuskyCIVulnToCLIVuln(vuln, "HCL", "tfsec"))
 	}
 
+	// Infrastructure as code vulnerabilities (Trivy config), stored with the
+	// kind of file they were found in, such as Kubernetes or CloudFormation
+	for _, vuln := range results.IaCResults.HuskyCITrivyConfigOutput.HighVulns {
+		a.Vulnerabilities = append(a.Vulnerabilities, convertHuskyCIVulnToCLIVuln(vuln, vuln.Language, "trivyconfig"))
+	}
+	for _, vuln := range results.IaCResults.HuskyCITrivyConfigOutput.MediumVulns {
+		a.Vulnerabilities = append(a.Vulnerabilities, convertHuskyCIVulnToCLIVuln(vuln, vuln.Language, "trivyconfig"))
+	}
+	for _, vuln := range results.IaCResults.HuskyCITrivyConfigOutput.LowVulns {
+		a.Vulnerabilities = append(a.Vulnerabilities, convertHuskyCIVulnToCLIVuln(vuln, vuln.Language, "trivyconfig"))
+	}
+
 	// C# vulnerabilities (SecurityCodeScan)
 	for _, vuln := range results.CSharpResults.HuskyCISecurityCodeScanOutput.HighVulns {
 		a.Vulnerabilities = append(a.Vulnerabilities, convertHuskyCIVulnToCLIVuln(vuln, "C#", "securitycodescan"))
@@ -786,47 +690,56 @@ func convertHuskyCIVulnToCLIVuln(apiVuln types.HuskyCIVulnerability, language, s
 	vuln.VunerableBelow = apiVuln.VunerableBelow
 	vuln.Version = apiVuln.Version
 	vuln.Occurrences = apiVuln.Occurrences
+	vuln.Package = apiVuln.Package
+	vuln.FixVersion = apiVuln.FixVersion
+	vuln.PackagePath = apiVuln.PackagePath
 	return *vuln
 }
 
 // printVulnerability prints a single vulnerability in a formatted way
 func printVulnerability(vuln vulnerability.Vulnerability, index int) {
-	fmt.Printf("\n[%d] %s\n", index, vuln.Type)
+	formatter.Printf("\n[%d] %s\n", index, vuln.Type)
 	if vuln.Language != "" {
-		fmt.Printf("    Language: %s\n", vuln.Language)
+		i18n.Printf("    Language: %s\n", vuln.Language)
 	}
 	if vuln.SecurityTest != "" {
-		fmt.Printf("    Security Test: %s\n", vuln.SecurityTest)
+		i18n.Printf("    Security Test: %s\n", vuln.SecurityTest)
 	}
 	if vuln.File != "" {
-		fmt.Printf("    File: %s", vuln.File)
+		i18n.Printf("    File: %s", vuln.File)
 		if vuln.Line != "" {
-			fmt.Printf(" (Line: %s)", vuln.Line)
+			i18n.Printf(" (Line: %s)", vuln.Line)
 		}
-		fmt.Println()
+		formatter.Println()
 	}
 	if vuln.Code != "" {
-		fmt.Printf("    Code: %s\n", vuln.Code)
+		i18n.Printf("    Code: %s\n", vuln.Code)
 	}
 	if vuln.Details != "" {
-		fmt.Printf("    Details: %s\n", vuln.Details)
+		i18n.Printf("    Details: %s\n", vuln.Details)
 	}
 	if vuln.Severity != "" {
-		fmt.Printf("    Severity: %s", vuln.Severity)
+		i18n.Printf("    Severity: %s", vuln.Severity)
 		if vuln.Confidence != "" {
-			fmt.Printf(" (Confidence: %s)", vuln.Confidence)
+			i18n.Printf(" (Confidence: %s)", vuln.Confidence)
 		}
-		fmt.Println()
+		formatter.Println()
 	}
 	if vuln.Version != "" {
-		fmt.Printf("    Version: %s", vuln.Version)
+		i18n.Printf("    Version: %s", vuln.Version)
 		if vuln.VunerableBelow != "" {
-			fmt.Printf(" (Vulnerable below: %s)", vuln.VunerableBelow)
+			i18n.Printf(" (Vulnerable below: %s)", vuln.VunerableBelow)
 		}
-		fmt.Println()
+		formatter.Println()
+	}
+	if vuln.PackagePath != "" {
+		i18n.Printf("    Package path: %s\n", vuln.PackagePath)
+	}
+	if vuln.FixVersion != "" {
+		i18n.Printf("    Fix: upgrade %s to %s\n", vuln.Package, vuln.FixVersion)
 	}
 	if vuln.Occurrences > 1 {
-		fmt.Printf("    Occurrences: %d\n", vuln.Occurrences)
+		i18n.Printf("    Occurrences: %d\n", vuln.Occurrences)
 	}
 }
 
@@ -904,13 +817,13 @@ func (a *Analysis) generateEnryOutput(pathReceived string) (string, error) {
 		})
 	
 	if err != nil {
-		return "", fmt.Errorf("error generating Enry output: %w", err)
+		return "", i18n.Errorf("error generating Enry output: %w", err)
 	}
 	
 	// Convert to JSON
 	enryJSON, err := json.Marshal(enryMap)
 	if err != nil {
-		return "", fmt.Errorf("error marshaling Enry output: %w", err)
+		return "", i18n.Errorf("error marshaling Enry output: %w", err)
 	}
 	
 	return string(enryJSON), nil
@@ -956,9 +869,42 @@ func normalizeLanguageName(lang string) string {
 	return ""
 }
 
-// getAvailableSecurityTests returns the huskyCI securityTests available.
-// Later on this check can be done using an API endpoint via cache.
+// securityTestsTimeout bounds the request listing the securityTests of the
+// huskyCI API, as they are only displayed.
+const securityTestsTimeout = 5 * time.Second
+
+// getAvailableSecurityTests returns the huskyCI securityTests that run on
+// languages, as listed by the huskyCI API of the current target, or the ones
+// huskyCI ships with when the API can not be reached.
 func (a *Analysis) getAvailableSecurityTests(languages []string) map[string][]string {
+	if a.Local {
+		if err := a.loadLocalSecurityTests(); err != nil {
+			return map[string][]string{}
+		}
+		return a.localSecurityTestImages()
+	}
+	target, err := config.GetCurrentTarget()
+	if err == nil {
+		var client *sdk.Client
+		client, err = newClient(target)
+		if err == nil {
+			client.Retries = 0
+			ctx, cancel := context.WithTimeout(context.Background(), securityTestsTimeout)
+			defer cancel()
+			var securityTests []apiclient.SecurityTestView
+			securityTests, err = client.ListSecurityTests(ctx)
+			if err == nil {
+				return sdk.DefaultSecurityTests(securityTests, languages)
+			}
+		}
+	}
+	log.Debug("Could not list the securityTests of the API, showing the default ones", "error", err)
+	return defaultSecurityTests(languages)
+}
+
+// defaultSecurityTests returns the securityTests huskyCI ships with that run
+// on languages.
+func defaultSecurityTests(languages []string) map[string][]string {
 
 	var list = make(map[string][]string)
 
@@ -0,0 +1,164 @@
+package analysis
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/cli/config"
+	"github.com/huskyci-org/huskyCI/cli/util"
+	"github.com/huskyci-org/huskyCI/pkg/i18n"
+)
+
+// CacheKey identifies the results of an analysis that can be reused instead
+// of sending the same code again: the huskyCI API they came from, the
+// repository or path analyzed, the hash of the code sent and the version of
+// the policy of the repository.
+type CacheKey struct {
+	Endpoint      string
+	Scope         string
+	TreeHash      string
+	PolicyVersion string
+}
+
+// cachedResult is the last successful analysis of a scope on a huskyCI API.
+type cachedResult struct {
+	TreeHash      string    `json:"treeHash"`
+	PolicyVersion string    `json:"policyVersion"`
+	CachedAt      time.Time `json:"cachedAt"`
+	Analysis      *Analysis `json:"analysis"`
+}
+
+// CacheDir returns the folder the results of analyses are cached in,
+// HUSKYCI_CLI_CACHE_DIR if it is set, which a CI can keep between its jobs,
+// or else $HOME/.huskyci/cache.
+func CacheDir() (string, error) {
+	if dir := os.Getenv("HUSKYCI_CLI_CACHE_DIR"); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	huskyHome, err := config.CheckAndCreateConfigFolder(home, false)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(huskyHome, "cache"), nil
+}
+
+// docExtensions are the extensions of the documentation files left out of the
+// hash of the code, so that changing the docs only reuses the last analysis.
+var docExtensions = map[string]bool{".md": true, ".markdown": true, ".rst": true, ".adoc": true}
+
+// TreeHash returns the SHA-256 of the files of path sent to the huskyCI API,
+// from their paths and contents, so that it changes with any of them but the
+// documentation and the .git folder, which changes with every commit.
+func TreeHash(path string) (string, error) {
+	entries, err := util.GetAllAllowedFilesAndDirsFromPath(path)
+	if err != nil {
+		return "", i18n.Errorf("error reading files from path: %w", err)
+	}
+	tree := sha256.New()
+	for _, entry := range entries {
+		err := filepath.Walk(entry, func(file string, info os.FileInfo, err error) error {
+			switch {
+			case err != nil:
+				return err
+			case info.IsDir() && info.Name() == ".git":
+				return filepath.SkipDir
+			case info.IsDir() || docExtensions[strings.ToLower(filepath.Ext(file))]:
+				return nil
+			}
+			relPath, err := filepath.Rel(path, file)
+			if err != nil {
+				return err
+			}
+			content, err := fileHash(file)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(tree, "%s\x00%s\n", filepath.ToSlash(relPath), content)
+			return nil
+		})
+		if err != nil {
+			return "", i18n.Errorf("error hashing files from path: %w", err)
+		}
+	}
+	return hex.EncodeToString(tree.Sum(nil)), nil
+}
+
+// fileHash returns the SHA-256 of the content of file.
+func fileHash(file string) (string, error) {
+	f, err := os.Open(file) // #nosec -> file is one of the files sent to the API
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// cacheFile returns the file caching the last successful analysis of the
+// scope of key on its huskyCI API.
+func cacheFile(key CacheKey) (string, error) {
+	dir, err := CacheDir()
+	if err != nil {
+		return "", err
+	}
+	name := sha256.Sum256([]byte(key.Endpoint + "\x00" + key.Scope))
+	return filepath.Join(dir, hex.EncodeToString(name[:])+".json"), nil
+}
+
+// CachedAnalysis returns the last successful analysis of the scope of key on
+// its huskyCI API, if the code it analyzed and the policy it was decided with
+// did not change since.
+func CachedAnalysis(key CacheKey) (*Analysis, bool) {
+	file, err := cacheFile(key)
+	if err != nil {
+		return nil, false
+	}
+	content, err := os.ReadFile(file) // #nosec -> file is in the cache folder of the CLI
+	if err != nil {
+		return nil, false
+	}
+	cached := cachedResult{}
+	if err := json.Unmarshal(content, &cached); err != nil || cached.Analysis == nil {
+		return nil, false
+	}
+	if cached.TreeHash != key.TreeHash || cached.PolicyVersion != key.PolicyVersion {
+		return nil, false
+	}
+	return cached.Analysis, true
+}
+
+// Cache stores a as the last successful analysis of the scope of key on its
+// huskyCI API, replacing the one cached before.
+func (a *Analysis) Cache(key CacheKey) error {
+	file, err := cacheFile(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0750); err != nil {
+		return err
+	}
+	content, err := json.Marshal(cachedResult{
+		TreeHash:      key.TreeHash,
+		PolicyVersion: key.PolicyVersion,
+		CachedAt:      time.Now(),
+		Analysis:      a,
+	})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, content, 0600)
+}
@@ -0,0 +1,75 @@
+package analysis
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTreeHash(t *testing.T) {
+	dir, err := os.MkdirTemp("", "huskyci-tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name, content string) {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hash := func() string {
+		h, err := TreeHash(dir)
+		if err != nil {
+			t.Fatalf("cache: fail to hash the tree (%v)", err)
+		}
+		return h
+	}
+
+	write("main.go", "package main")
+	write("docs/README.md", "# docs")
+	first := hash()
+	write("docs/README.md", "# new docs")
+	write(".git/HEAD", "ref: refs/heads/main")
+	if hash() != first {
+		t.Error("cache: the hash changed with the docs or the .git folder")
+	}
+	write("main.go", "package main\n")
+	if hash() == first {
+		t.Error("cache: the hash did not change with the code")
+	}
+}
+
+func TestCachedAnalysis(t *testing.T) {
+	dir, err := os.MkdirTemp("", "huskyci-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	t.Setenv("HUSKYCI_CLI_CACHE_DIR", dir)
+
+	key := CacheKey{Endpoint: "https://huskyci.example.com", Scope: "https://github.com/org/repo", TreeHash: "tree", PolicyVersion: "v1"}
+	if _, found := CachedAnalysis(key); found {
+		t.Fatal("cache: found an analysis in an empty cache")
+	}
+	if err := ciAnalysis().Cache(key); err != nil {
+		t.Fatalf("cache: fail to cache the analysis (%v)", err)
+	}
+	cached, found := CachedAnalysis(key)
+	if !found || cached.RID != "RID" || len(cached.Vulnerabilities) != 4 {
+		t.Fatalf("cache: unexpected cached analysis %+v", cached)
+	}
+
+	changed := []CacheKey{key, key, key}
+	changed[0].TreeHash = "other"
+	changed[1].PolicyVersion = "v2"
+	changed[2].Endpoint = "https://other.example.com"
+	for _, other := range changed {
+		if _, found := CachedAnalysis(other); found {
+			t.Errorf("cache: reused the analysis for %+v", other)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package analysis
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/huskyci-org/huskyCI/cli/vulnerability"
+)
+
+// SeverityRank orders the huskyCI severities: 3 for high, 2 for medium, 1 for
+// low and 0 for anything else, info included.
+func SeverityRank(severity string) int {
+	switch strings.ToLower(severity) {
+	case "high", "critical":
+		return 3
+	case "medium":
+		return 2
+	case "low":
+		return 1
+	}
+	return 0
+}
+
+// CountAtLeast returns how many vulnerabilities of the analysis, nosec ones
+// aside, have a severity of at least severity.
+func (a *Analysis) CountAtLeast(severity string) int {
+	rank := SeverityRank(severity)
+	count := 0
+	for _, vuln := range a.Vulnerabilities {
+		if !vuln.Nosec && SeverityRank(vuln.Severity) >= rank {
+			count++
+		}
+	}
+	return count
+}
+
+// WriteGitHubAnnotations writes the vulnerabilities of the analysis, nosec ones
+// aside, to w as GitHub Actions workflow commands, so that they are shown on the
+// lines of the pull request they were found at.
+func (a *Analysis) WriteGitHubAnnotations(w io.Writer) error {
+	for _, vuln := range a.Vulnerabilities {
+		if vuln.Nosec {
+			continue
+		}
+		command := "notice"
+		switch SeverityRank(vuln.Severity) {
+		case 3:
+			command = "error"
+		case 2:
+			command = "warning"
+		}
+		properties := []string{}
+		if file := annotationFile(vuln); file != "" {
+			properties = append(properties, "file="+escapeProperty(file))
+			if line, err := strconv.Atoi(vuln.Line); err == nil && line > 0 {
+				properties = append(properties, "line="+strconv.Itoa(line))
+			}
+		}
+		properties = append(properties, "title="+escapeProperty(fmt.Sprintf("huskyCI %s: %s", vuln.SecurityTest, vulnTitle(vuln))))
+		if _, err := fmt.Fprintf(w, "::%s %s::%s\n", command, strings.Join(properties, ","), escapeData(vulnMessage(vuln))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteGitHubSummary writes the Markdown summary of the analysis shown on the
+// page of a GitHub Actions job.
+func (a *Analysis) WriteGitHubSummary(w io.Writer) error {
+	counts := map[int]int{}
+	for _, vuln := range a.Vulnerabilities {
+		if !vuln.Nosec {
+			counts[SeverityRank(vuln.Severity)]++
+		}
+	}
+	summary := &strings.Builder{}
+	fmt.Fprintf(summary, "## huskyCI analysis\n\n")
+	fmt.Fprintf(summary, "RID: `%s`\n\n", a.RID)
+	fmt.Fprintf(summary, "| Severity | Vulnerabilities |\n| --- | --- |\n")
+	fmt.Fprintf(summary, "| 🔴 High | %d |\n| 🟠 Medium | %d |\n| 🟡 Low | %d |\n", counts[3], counts[2], counts[1])
+	if counts[0] > 0 {
+		fmt.Fprintf(summary, "| ℹ️ Info | %d |\n", counts[0])
+	}
+	if len(a.Vulnerabilities) > 0 {
+		fmt.Fprintf(summary, "\n| Severity | Security Test | Location | Vulnerability |\n| --- | --- | --- | --- |\n")
+		for _, vuln := range a.Vulnerabilities {
+			if vuln.Nosec {
+				continue
+			}
+			location := annotationFile(vuln)
+			if location != "" && vuln.Line != "" {
+				location += ":" + vuln.Line
+			}
+			fmt.Fprintf(summary, "| %s | %s | %s | %s |\n", vuln.Severity, vuln.SecurityTest, escapeCell(location), escapeCell(vulnTitle(vuln)))
+		}
+	}
+	_, err := io.WriteString(w, summary.String()+"\n")
+	return err
+}
+
+// CodeQualityIssue is a vulnerability in a GitLab Code Quality report.
+type CodeQualityIssue struct {
+	Description string              `json:"description"`
+	CheckName   string              `json:"check_name"`
+	Fingerprint string              `json:"fingerprint"`
+	Severity    string              `json:"severity"`
+	Location    CodeQualityLocation `json:"location"`
+}
+
+// CodeQualityLocation is the file and line a vulnerability was found at.
+type CodeQualityLocation struct {
+	Path  string           `json:"path"`
+	Lines CodeQualityLines `json:"lines"`
+}
+
+// CodeQualityLines is the line a vulnerability was found at.
+type CodeQualityLines struct {
+	Begin int `json:"begin"`
+}
+
+// CodeQuality returns the vulnerabilities of the analysis, nosec ones aside, as
+// the issues of a GitLab Code Quality report, shown on the merge request widget
+// and diff.
+func (a *Analysis) CodeQuality() []CodeQualityIssue {
+	issues := []CodeQualityIssue{}
+	for _, vuln := range a.Vulnerabilities {
+		if vuln.Nosec {
+			continue
+		}
+		issue := CodeQualityIssue{
+			Description: fmt.Sprintf("%s: %s", vuln.SecurityTest, vulnMessage(vuln)),
+			CheckName:   "huskyci/" + vuln.SecurityTest + "/" + vulnTitle(vuln),
+			Severity:    codeQualitySeverity(vuln.Severity),
+			Location:    CodeQualityLocation{Path: annotationFile(vuln), Lines: CodeQualityLines{Begin: 1}},
+		}
+		if issue.Location.Path == "" {
+			issue.Location.Path = "."
+		}
+		if line, err := strconv.Atoi(vuln.Line); err == nil && line > 0 {
+			issue.Location.Lines.Begin = line
+		}
+		fingerprint := sha256.Sum256([]byte(strings.Join([]string{vuln.SecurityTest, vulnTitle(vuln), issue.Location.Path, vuln.Line, vuln.Details}, "\x00")))
+		issue.Fingerprint = hex.EncodeToString(fingerprint[:])
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
+// WriteCodeQuality writes the vulnerabilities of the analysis to w as an
+// indented GitLab Code Quality report.
+func (a *Analysis) WriteCodeQuality(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(a.CodeQuality())
+}
+
+// codeQualitySeverity maps a huskyCI severity to a GitLab Code Quality one.
+func codeQualitySeverity(severity string) string {
+	switch SeverityRank(severity) {
+	case 3:
+		return "critical"
+	case 2:
+		return "major"
+	case 1:
+		return "minor"
+	}
+	return "info"
+}
+
+// annotationFile returns the path of the file of vuln relative to the root of
+// the analyzed directory. A vulnerable dependency of a package of a monorepo
+// is reported on the package.json of that package.
+func annotationFile(vuln vulnerability.Vulnerability) string {
+	if vuln.File == "" && vuln.PackagePath != "" {
+		return vuln.PackagePath + "/package.json"
+	}
+	return strings.TrimPrefix(vuln.File, "./")
+}
+
+func vulnTitle(vuln vulnerability.Vulnerability) string {
+	if vuln.Type != "" {
+		return vuln.Type
+	}
+	return vuln.SecurityTest
+}
+
+func vulnMessage(vuln vulnerability.Vulnerability) string {
+	if vuln.Details != "" {
+		return vuln.Details
+	}
+	return vulnTitle(vuln)
+}
+
+// escapeData escapes the message of a GitHub Actions workflow command.
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeProperty escapes a property of a GitHub Actions workflow command.
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// escapeCell keeps s on a single cell of a Markdown table.
+func escapeCell(s string) string {
+	return strings.NewReplacer("|", "\\|", "\r", " ", "\n", " ").Replace(s)
+}
@@ -0,0 +1,86 @@
+package analysis
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/huskyci-org/huskyCI/cli/vulnerability"
+)
+
+func ciAnalysis() *Analysis {
+	a := New()
+	a.RID = "RID"
+	a.Vulnerabilities = []vulnerability.Vulnerability{
+		{SecurityTest: "gosec", Severity: "HIGH", File: "./cmd/main.go", Line: "12", Type: "G101", Details: "hardcoded credentials:\npassword, token"},
+		{SecurityTest: "gosec", Severity: "MEDIUM", File: "main.go", Line: "n/a", Type: "G304"},
+		{SecurityTest: "gitleaks", Severity: "LOW", Details: "AWS key"},
+		{SecurityTest: "gosec", Severity: "HIGH", File: "skip.go", Nosec: true},
+	}
+	return a
+}
+
+func TestCountAtLeast(t *testing.T) {
+	a := ciAnalysis()
+	for severity, expected := range map[string]int{"high": 1, "medium": 2, "low": 3} {
+		if count := a.CountAtLeast(severity); count != expected {
+			t.Errorf("CI: %d vulnerabilities of at least %s severity, expected %d", count, severity, expected)
+		}
+	}
+}
+
+func TestWriteGitHubAnnotations(t *testing.T) {
+	output := &bytes.Buffer{}
+	if err := ciAnalysis().WriteGitHubAnnotations(output); err != nil {
+		t.Fatalf("CI: fail to write annotations (%v)", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+	expected := []string{
+		"::error file=cmd/main.go,line=12,title=huskyCI gosec%3A G101::hardcoded credentials:%0Apassword, token",
+		"::warning file=main.go,title=huskyCI gosec%3A G304::G304",
+		"::notice title=huskyCI gitleaks%3A gitleaks::AWS key",
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("CI: unexpected annotations\n%s", output)
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("CI: annotation %d is %q, expected %q", i, lines[i], expected[i])
+		}
+	}
+}
+
+func TestCodeQuality(t *testing.T) {
+	issues := ciAnalysis().CodeQuality()
+	if len(issues) != 3 {
+		t.Fatalf("CI: expected 3 issues, got %d", len(issues))
+	}
+	high := issues[0]
+	if high.Severity != "critical" || high.Location.Path != "cmd/main.go" || high.Location.Lines.Begin != 12 || high.CheckName != "huskyci/gosec/G101" {
+		t.Fatalf("CI: unexpected high severity issue (%+v)", high)
+	}
+	if issues[1].Severity != "major" || issues[1].Location.Lines.Begin != 1 {
+		t.Fatalf("CI: unexpected medium severity issue (%+v)", issues[1])
+	}
+	if issues[2].Severity != "minor" || issues[2].Location.Path != "." {
+		t.Fatalf("CI: unexpected low severity issue (%+v)", issues[2])
+	}
+	if high.Fingerprint == issues[1].Fingerprint || len(high.Fingerprint) != 64 {
+		t.Fatalf("CI: fingerprints should be unique sha256 hashes (%s, %s)", high.Fingerprint, issues[1].Fingerprint)
+	}
+}
+
+func TestCodeQualityPackagePath(t *testing.T) {
+	a := New()
+	a.Vulnerabilities = []vulnerability.Vulnerability{
+		{SecurityTest: "npmaudit", Severity: "HIGH", Type: "lodash", PackagePath: "packages/web"},
+		{SecurityTest: "npmaudit", Severity: "HIGH", Type: "lodash", PackagePath: "packages/api"},
+	}
+	issues := a.CodeQuality()
+	if issues[0].Location.Path != "packages/web/package.json" || issues[1].Location.Path != "packages/api/package.json" {
+		t.Fatalf("CI: dependencies should be reported on the package.json of their package (%+v)", issues)
+	}
+	if issues[0].Fingerprint == issues[1].Fingerprint {
+		t.Fatalf("CI: the same dependency of two packages should have different fingerprints")
+	}
+}
@@ -0,0 +1,230 @@
+package analysis
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/cli/log"
+	"github.com/huskyci-org/huskyCI/cli/util"
+	"github.com/huskyci-org/huskyCI/cli/vulnerability"
+	"github.com/huskyci-org/huskyCI/pkg/i18n"
+	"github.com/huskyci-org/huskyCI/pkg/sdk"
+	"github.com/huskyci-org/huskyCI/pkg/securitytest"
+	"go.yaml.in/yaml/v3"
+)
+
+//go:generate cp ../../api/config.yaml securitytests.yaml
+
+// defaultLocalConfig is a copy of the securityTests configuration of the
+// huskyCI API, so that local analyses run the same command templates.
+//
+//go:embed securitytests.yaml
+var defaultLocalConfig []byte
+
+// localBranch is the branch the working directory is committed to inside
+// the securityTest containers of a local analysis.
+const localBranch = "local"
+
+// LocalSecurityTest is a securityTest of the configuration of the huskyCI
+// API, as run by a local analysis.
+type LocalSecurityTest struct {
+	Name             string `yaml:"name"`
+	Image            string `yaml:"image"`
+	ImageTag         string `yaml:"imageTag"`
+	Cmd              string `yaml:"cmd"`
+	Type             string `yaml:"type"`
+	Language         string `yaml:"language"`
+	Default          bool   `yaml:"default"`
+	TimeOutInSeconds int    `yaml:"timeOutInSeconds"`
+}
+
+// image returns the image of the securityTest with its tag.
+func (s LocalSecurityTest) image() string {
+	if s.ImageTag == "" {
+		return s.Image
+	}
+	return s.Image + ":" + s.ImageTag
+}
+
+// loadLocalSecurityTests sets the securityTests the local analysis runs on
+// the languages of a: the default ones of the huskyCI API configuration at
+// LocalConfig, or of the configuration the CLI ships with if it is empty,
+// whose output can be parsed outside of the API, among its SecurityTests if
+// it has some.
+func (a *Analysis) loadLocalSecurityTests() error {
+	if a.localSecurityTests != nil {
+		return nil
+	}
+	path := a.LocalConfig
+	content := defaultLocalConfig
+	if path != "" {
+		var err error
+		if content, err = os.ReadFile(path); err != nil {
+			return i18n.Errorf("error reading the securityTests configuration: %w", err)
+		}
+	}
+	configured := map[string]LocalSecurityTest{}
+	if err := yaml.Unmarshal(content, &configured); err != nil {
+		return i18n.Errorf("invalid securityTests configuration '%s': %w", path, err)
+	}
+
+	languages := map[string]bool{sdk.Generic: true}
+	for _, language := range a.Languages {
+		languages[normalizeLanguageName(language)] = true
+	}
+	selected := map[string]bool{}
+	for _, name := range a.SecurityTests {
+		selected[strings.ToLower(name)] = true
+	}
+	a.localSecurityTests = []LocalSecurityTest{}
+	for name, securityTest := range configured {
+		if securityTest.Name == "" {
+			securityTest.Name = name
+		}
+		if _, ok := securitytest.Parsers[securityTest.Name]; !ok || !securityTest.Default || securityTest.Cmd == "" {
+			continue
+		}
+		if len(selected) > 0 && !selected[securityTest.Name] {
+			continue
+		}
+		if securityTest.Type == sdk.Generic {
+			securityTest.Language = sdk.Generic
+		} else if securityTest.Type != "Language" || !languages[securityTest.Language] {
+			continue
+		}
+		a.localSecurityTests = append(a.localSecurityTests, securityTest)
+	}
+	sort.Slice(a.localSecurityTests, func(i, j int) bool {
+		return a.localSecurityTests[i].Name < a.localSecurityTests[j].Name
+	})
+	return nil
+}
+
+// localSecurityTestImages returns the images of the securityTests of the
+// local analysis, by language.
+func (a *Analysis) localSecurityTestImages() map[string][]string {
+	list := map[string][]string{}
+	for _, securityTest := range a.localSecurityTests {
+		list[securityTest.Language] = append(list[securityTest.Language], securityTest.image())
+	}
+	return list
+}
+
+// RunLocal runs the securityTests of a on its Path with the local Docker
+// daemon, without the huskyCI API. The images are pulled if they are
+// missing. A securityTest that fails is reported as a warning, so that the
+// findings of the others are kept.
+func (a *Analysis) RunLocal(ctx context.Context) error {
+	docker, err := util.NewDockerEngine()
+	if err != nil {
+		return err
+	}
+	if err := docker.Ping(ctx); err != nil {
+		return i18n.Errorf("could not reach Docker: %w\n\nTip: Local analyses run the securityTests with Docker. Make sure it is running, or set DOCKER_HOST", err)
+	}
+	if err := a.loadLocalSecurityTests(); err != nil {
+		return err
+	}
+	if len(a.localSecurityTests) == 0 {
+		return errors.New(i18n.T("no securityTest can run locally on the languages found\n\nTip: Run the analysis on the huskyCI API instead, without --local"))
+	}
+
+	a.StartedAt = time.Now()
+	a.Vulnerabilities = []vulnerability.Vulnerability{}
+	a.printf("🐳 Running %d securityTests locally...\n", len(a.localSecurityTests))
+	for _, securityTest := range a.localSecurityTests {
+		vulns, err := a.runLocalSecurityTest(ctx, docker, securityTest)
+		if ctx.Err() != nil {
+			return i18n.Errorf("local analysis interrupted: %w", ctx.Err())
+		}
+		if err != nil {
+			a.printf("  ❌ %s: %s\n", securityTest.Name, firstLine(err.Error()))
+			a.Warnings = append(a.Warnings, fmt.Sprintf("%s did not run: %s", securityTest.Name, firstLine(err.Error())))
+			continue
+		}
+		a.printf("  ✓ %s: %d findings\n", securityTest.Name, len(vulns))
+		a.Vulnerabilities = append(a.Vulnerabilities, vulns...)
+	}
+	a.FinishedAt = time.Now()
+	a.Result.Status = "finished"
+	return nil
+}
+
+// runLocalSecurityTest runs securityTest on the Path of a and returns its
+// findings.
+func (a *Analysis) runLocalSecurityTest(ctx context.Context, docker *util.DockerEngine, securityTest LocalSecurityTest) ([]vulnerability.Vulnerability, error) {
+	image := securityTest.image()
+	found, err := docker.HasImage(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		a.printf("  ⬇️  Pulling %s...\n", image)
+		if err := docker.PullImage(ctx, image); err != nil {
+			return nil, err
+		}
+	}
+
+	timeout := time.Duration(securityTest.TimeOutInSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Minute
+	}
+	log.Debug("Running securityTest locally", "securityTest", securityTest.Name, "image", image, "path", a.Path, "timeout", timeout)
+	output, err := docker.Run(ctx, image, localCommand(securityTest.Cmd), a.Path, securitytest.Workspace, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(output, securitytest.ErrorCloning) {
+		return nil, i18n.Errorf("could not read the code: %s", strings.TrimSpace(output))
+	}
+	results, err := securitytest.Parse(securityTest.Name, output)
+	if err != nil {
+		return nil, i18n.Errorf("invalid output: %w", err)
+	}
+	return localVulnerabilities(securityTest, results), nil
+}
+
+// localCommand returns cmd scanning the code mounted at the workspace of the
+// container, with the placeholders only the huskyCI API can fill emptied.
+func localCommand(cmd string) string {
+	cmd = strings.NewReplacer(
+		"%GIT_PRIVATE_SSH_KEY%", "",
+		"%GIT_SSH_URL%", "nil",
+		"%GIT_URL_TO_SUBSTITUTE%", "nil",
+	).Replace(cmd)
+	return securitytest.UploadCommand(localBranch, cmd)
+}
+
+// localVulnerabilities returns the findings of securityTest in results. The
+// nosec ones are left out, as in the analyses of the huskyCI API.
+func localVulnerabilities(securityTest LocalSecurityTest, results securitytest.Output) []vulnerability.Vulnerability {
+	vulns := []vulnerability.Vulnerability{}
+	for _, found := range [][]securitytest.Vulnerability{results.HighVulns, results.MediumVulns, results.LowVulns} {
+		for _, localVuln := range found {
+			vuln := vulnerability.New()
+			vuln.Language = securityTest.Language
+			if securityTest.Language == sdk.Generic && localVuln.Language != "" {
+				vuln.Language = localVuln.Language
+			}
+			vuln.SecurityTest = securityTest.Name
+			vuln.Severity = localVuln.Severity
+			vuln.Confidence = localVuln.Confidence
+			vuln.File = localVuln.File
+			vuln.Line = localVuln.Line
+			vuln.Code = localVuln.Code
+			vuln.Details = localVuln.Details
+			vuln.Type = localVuln.Type
+			if vuln.Type == "" {
+				vuln.Type = localVuln.Title
+			}
+			vulns = append(vulns, *vuln)
+		}
+	}
+	return vulns
+}
@@ -0,0 +1,105 @@
+package analysis
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLocalConfigUpToDate(t *testing.T) {
+	apiConfig, err := os.ReadFile("../../api/config.yaml")
+	if err != nil {
+		t.Skip("the huskyCI API configuration is not available:", err)
+	}
+	if !bytes.Equal(apiConfig, defaultLocalConfig) {
+		t.Error("securitytests.yaml differs from the huskyCI API configuration, run go generate ./...")
+	}
+}
+
+func TestLoadLocalSecurityTests(t *testing.T) {
+	a := &Analysis{Languages: []string{"Go", "Java"}}
+	if err := a.loadLocalSecurityTests(); err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, securityTest := range a.localSecurityTests {
+		names = append(names, securityTest.Name)
+	}
+	// spotbugs has no shared parser, and bandit runs on Python only
+	if strings.Join(names, ",") != "gitleaks,gosec,trivy,trivyconfig" {
+		t.Errorf("unexpected securityTests %v", names)
+	}
+	if images := a.localSecurityTestImages(); len(images["Go"]) != 1 || !strings.HasPrefix(images["Go"][0], "huskyciorg/gosec:") {
+		t.Errorf("unexpected images %v", images)
+	}
+
+	a = &Analysis{LocalConfig: "does-not-exist.yaml"}
+	if err := a.loadLocalSecurityTests(); err == nil {
+		t.Error("missing configuration was loaded")
+	}
+}
+
+func TestLocalCommand(t *testing.T) {
+	cmd := localCommand("echo '%GIT_PRIVATE_SSH_KEY%' > key && git clone -b %GIT_BRANCH% --single-branch %GIT_CLONE_OPTIONS% %GIT_REPO% code")
+	if strings.Contains(cmd, "%") {
+		t.Errorf("placeholders left in %q", cmd)
+	}
+	if !strings.Contains(cmd, "git clone -b local --single-branch  /tmp/huskyci-upload code") || !strings.Contains(cmd, "--work-tree=/workspace") {
+		t.Errorf("unexpected command %q", cmd)
+	}
+}
+
+// fakeDocker answers as a Docker daemon whose containers print output.
+func fakeDocker(t *testing.T, output string) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/_ping":
+			fmt.Fprint(w, "OK")
+		case strings.HasPrefix(r.URL.Path, "/images/") && strings.HasSuffix(r.URL.Path, "/json"):
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"No such image"}`)
+		case r.URL.Path == "/images/create":
+			fmt.Fprint(w, `{"status":"Downloaded newer image"}`+"\n")
+		case r.URL.Path == "/containers/create":
+			fmt.Fprint(w, `{"Id":"abc"}`)
+		case r.URL.Path == "/containers/abc/logs":
+			fmt.Fprint(w, output)
+		default:
+			fmt.Fprint(w, `{"StatusCode":0}`)
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("DOCKER_HOST", "tcp://"+strings.TrimPrefix(server.URL, "http://"))
+}
+
+func TestRunLocal(t *testing.T) {
+	fakeDocker(t, `{"Issues":[{"severity":"HIGH","confidence":"HIGH","details":"Potential hardcoded credentials","file":"main.go","code":"password := \"x\"","line":"12"}],"Stats":{"nosec":1}}`)
+	a := &Analysis{Path: t.TempDir(), localSecurityTests: []LocalSecurityTest{{Name: "gosec", Image: "huskyciorg/gosec", Cmd: "gosec ./...", Language: "Go"}}}
+
+	if err := a.RunLocal(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if a.Result.Status != "finished" || len(a.Vulnerabilities) != 1 {
+		t.Fatalf("unexpected analysis %+v", a)
+	}
+	if vuln := a.Vulnerabilities[0]; vuln.SecurityTest != "gosec" || vuln.Language != "Go" || vuln.Type != "Potential hardcoded credentials" {
+		t.Errorf("unexpected vulnerability %+v", vuln)
+	}
+}
+
+func TestRunLocalKeepsFindingsOfOtherSecurityTests(t *testing.T) {
+	fakeDocker(t, "not json")
+	a := &Analysis{Path: t.TempDir(), localSecurityTests: []LocalSecurityTest{{Name: "bandit", Image: "huskyciorg/bandit", Cmd: "bandit", Language: "Python"}}}
+
+	if err := a.RunLocal(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Warnings) != 1 || !strings.HasPrefix(a.Warnings[0], "bandit did not run: invalid output") {
+		t.Errorf("unexpected warnings %v", a.Warnings)
+	}
+}
@@ -0,0 +1,64 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/pkg/apiclient"
+	"github.com/huskyci-org/huskyCI/pkg/sdk"
+)
+
+// progressWidth is how many characters the progress bar takes.
+const progressWidth = 20
+
+// progressTick is how often the progress bar is checked for a new line.
+var progressTick = time.Second
+
+// progress tracks how far a running analysis is from the seconds the
+// huskyCI API estimates it should still take. It starts when the CLI starts
+// waiting for the analysis, so that the clocks of the CLI and of the API do
+// not have to agree.
+type progress struct {
+	sync.Mutex
+	startedAt time.Time
+	endsAt    time.Time
+	printed   int
+}
+
+func newProgress(now time.Time) *progress {
+	return &progress{startedAt: now, printed: -1}
+}
+
+// update sets when the analysis should end, if the API estimated it.
+func (p *progress) update(analysis *apiclient.Analysis, now time.Time) {
+	if analysis == nil || analysis.Status != sdk.StatusRunning || analysis.EstimatedSecondsRemaining == nil {
+		return
+	}
+	p.Lock()
+	defer p.Unlock()
+	p.endsAt = now.Add(time.Duration(*analysis.EstimatedSecondsRemaining) * time.Second)
+}
+
+// line returns the progress bar at now, or false if it did not move by a
+// tenth since it was last returned. It stops at 99% while the analysis runs
+// longer than estimated.
+func (p *progress) line(now time.Time) (string, bool) {
+	p.Lock()
+	defer p.Unlock()
+	if !p.endsAt.After(p.startedAt) {
+		return "", false
+	}
+	percent := int(100 * now.Sub(p.startedAt) / p.endsAt.Sub(p.startedAt))
+	percent = min(max(percent, 0), 99)
+	if percent/10 <= p.printed {
+		return "", false
+	}
+	p.printed = percent / 10
+
+	filled := progressWidth * percent / 100
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", progressWidth-filled)
+	remaining := max(p.endsAt.Sub(now).Round(time.Second), 0)
+	return fmt.Sprintf("  [%s] %2d%%, about %s left", bar, percent, remaining), true
+}
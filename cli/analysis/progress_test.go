@@ -0,0 +1,45 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/pkg/apiclient"
+)
+
+func TestProgress(t *testing.T) {
+	now := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+	bar := newProgress(now)
+	if _, moved := bar.line(now); moved {
+		t.Fatal("progress: printed a bar without an estimate")
+	}
+
+	remaining := 100
+	bar.update(&apiclient.Analysis{Status: "running", EstimatedSecondsRemaining: &remaining}, now)
+	line, moved := bar.line(now)
+	if !moved || !strings.Contains(line, " 0%, about 1m40s left") || !strings.Contains(line, strings.Repeat("░", progressWidth)) {
+		t.Fatalf("progress: unexpected first line %q", line)
+	}
+	if _, moved := bar.line(now.Add(5 * time.Second)); moved {
+		t.Error("progress: printed a bar that did not move by a tenth")
+	}
+	line, moved = bar.line(now.Add(45 * time.Second))
+	if !moved || !strings.Contains(line, "45%, about 55s left") || !strings.Contains(line, strings.Repeat("█", 9)+"░") {
+		t.Errorf("progress: unexpected line %q", line)
+	}
+	line, moved = bar.line(now.Add(5 * time.Minute))
+	if !moved || !strings.Contains(line, "99%, about 0s left") {
+		t.Errorf("progress: unexpected line of an analysis running late %q", line)
+	}
+	if _, moved := bar.line(now.Add(6 * time.Minute)); moved {
+		t.Error("progress: printed a bar past 99%")
+	}
+
+	finished := 0
+	bar = newProgress(now)
+	bar.update(&apiclient.Analysis{Status: "finished", EstimatedSecondsRemaining: &finished}, now)
+	if _, moved := bar.line(now); moved {
+		t.Error("progress: printed a bar of a finished analysis")
+	}
+}
@@ -0,0 +1,179 @@
+package analysis
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/huskyci-org/huskyCI/cli/types"
+)
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+// FromAPI returns the analysis stored in the huskyCI API as apiAnalysis, with
+// its vulnerabilities converted so they can be printed by PrintVulns.
+func FromAPI(apiAnalysis types.Analysis) *Analysis {
+	a := New()
+	a.RID = apiAnalysis.RID
+	a.StartedAt = apiAnalysis.StartedAt
+	a.FinishedAt = apiAnalysis.FinishedAt
+	a.Result.Status = apiAnalysis.Status
+	a.Result.Info = apiAnalysis.Result
+	a.Warnings = apiAnalysis.Warnings
+	if apiAnalysis.ErrorFound != "" {
+		a.Errors = append(a.Errors, apiAnalysis.ErrorFound)
+	}
+	a.convertAPIVulnerabilities(apiAnalysis)
+	return a
+}
+
+// SARIFLog is a SARIF 2.1.0 log, as read by code scanning tools.
+type SARIFLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []SARIFRun `json:"runs"`
+}
+
+// SARIFRun holds the results of a single securityTest of an analysis.
+type SARIFRun struct {
+	Tool              SARIFTool              `json:"tool"`
+	AutomationDetails SARIFAutomationDetails `json:"automationDetails"`
+	Results           []SARIFResult          `json:"results"`
+}
+
+// SARIFTool describes the securityTest that produced a run.
+type SARIFTool struct {
+	Driver SARIFDriver `json:"driver"`
+}
+
+// SARIFDriver is the securityTest name and the rules it reported.
+type SARIFDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []SARIFRule `json:"rules"`
+}
+
+// SARIFRule is a kind of vulnerability reported by a securityTest.
+type SARIFRule struct {
+	ID               string       `json:"id"`
+	ShortDescription SARIFMessage `json:"shortDescription"`
+}
+
+// SARIFAutomationDetails identifies the analysis a run belongs to.
+type SARIFAutomationDetails struct {
+	ID string `json:"id"`
+}
+
+// SARIFResult is a single vulnerability.
+type SARIFResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   SARIFMessage    `json:"message"`
+	Locations []SARIFLocation `json:"locations,omitempty"`
+}
+
+// SARIFMessage is a plain text message.
+type SARIFMessage struct {
+	Text string `json:"text"`
+}
+
+// SARIFLocation is the file and line a vulnerability was found at.
+type SARIFLocation struct {
+	PhysicalLocation SARIFPhysicalLocation `json:"physicalLocation"`
+}
+
+// SARIFPhysicalLocation is the file and region a vulnerability was found at.
+type SARIFPhysicalLocation struct {
+	ArtifactLocation SARIFArtifactLocation `json:"artifactLocation"`
+	Region           *SARIFRegion          `json:"region,omitempty"`
+}
+
+// SARIFArtifactLocation is the path of a file relative to the repository root.
+type SARIFArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// SARIFRegion is the line of a file a vulnerability was found at.
+type SARIFRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// SARIF returns the vulnerabilities of analyses as a SARIF log with one run
+// per securityTest of each analysis.
+func SARIF(analyses []*Analysis) SARIFLog {
+	log := SARIFLog{Schema: sarifSchema, Version: "2.1.0", Runs: []SARIFRun{}}
+	for _, a := range analyses {
+		runs := map[string]*SARIFRun{}
+		order := []string{}
+		for _, vuln := range a.Vulnerabilities {
+			run, ok := runs[vuln.SecurityTest]
+			if !ok {
+				run = &SARIFRun{
+					Tool: SARIFTool{Driver: SARIFDriver{
+						Name:           vuln.SecurityTest,
+						InformationURI: "https://github.com/huskyci-org/huskyCI",
+						Rules:          []SARIFRule{},
+					}},
+					AutomationDetails: SARIFAutomationDetails{ID: "huskyci/" + a.RID},
+					Results:           []SARIFResult{},
+				}
+				runs[vuln.SecurityTest] = run
+				order = append(order, vuln.SecurityTest)
+			}
+
+			ruleID := vuln.Type
+			if ruleID == "" {
+				ruleID = vuln.SecurityTest
+			}
+			if !hasRule(run.Tool.Driver.Rules, ruleID) {
+				run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, SARIFRule{ID: ruleID, ShortDescription: SARIFMessage{Text: ruleID}})
+			}
+			message := vuln.Details
+			if message == "" {
+				message = ruleID
+			}
+			result := SARIFResult{RuleID: ruleID, Level: sarifLevel(vuln.Severity), Message: SARIFMessage{Text: message}}
+			if vuln.File != "" {
+				location := SARIFLocation{PhysicalLocation: SARIFPhysicalLocation{ArtifactLocation: SARIFArtifactLocation{URI: vuln.File}}}
+				if line, err := strconv.Atoi(vuln.Line); err == nil && line > 0 {
+					location.PhysicalLocation.Region = &SARIFRegion{StartLine: line}
+				}
+				result.Locations = []SARIFLocation{location}
+			}
+			run.Results = append(run.Results, result)
+		}
+		for _, securityTest := range order {
+			log.Runs = append(log.Runs, *runs[securityTest])
+		}
+	}
+	return log
+}
+
+// WriteSARIF writes the vulnerabilities of analyses to w as an indented SARIF log.
+func WriteSARIF(w io.Writer, analyses []*Analysis) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(SARIF(analyses))
+}
+
+func hasRule(rules []SARIFRule, ruleID string) bool {
+	for _, rule := range rules {
+		if rule.ID == ruleID {
+			return true
+		}
+	}
+	return false
+}
+
+// sarifLevel maps a huskyCI severity to a SARIF result level.
+func sarifLevel(severity string) string {
+	switch strings.ToLower(severity) {
+	case "high", "critical":
+		return "error"
+	case "medium":
+		return "warning"
+	default:
+		return "note"
+	}
+}
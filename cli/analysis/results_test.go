@@ -0,0 +1,37 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/huskyci-org/huskyCI/cli/types"
+)
+
+func TestSARIF(t *testing.T) {
+	apiAnalysis := types.Analysis{RID: "RID", Status: "finished"}
+	apiAnalysis.HuskyCIResults.GoResults.HuskyCIGosecOutput.HighVulns = []types.HuskyCIVulnerability{
+		{Severity: "HIGH", File: "main.go", Line: "12", Details: "G101: hardcoded credentials", Type: "G101"},
+	}
+	apiAnalysis.HuskyCIResults.GoResults.HuskyCIGosecOutput.LowVulns = []types.HuskyCIVulnerability{
+		{Severity: "LOW", File: "main.go", Line: "n/a", Details: "G104: unhandled error", Type: "G104"},
+	}
+
+	log := SARIF([]*Analysis{FromAPI(apiAnalysis)})
+	if len(log.Runs) != 1 {
+		t.Fatalf("SARIF: expected a single gosec run, got %d", len(log.Runs))
+	}
+	run := log.Runs[0]
+	if run.Tool.Driver.Name != "gosec" || run.AutomationDetails.ID != "huskyci/RID" {
+		t.Fatalf("SARIF: unexpected run identification (%v, %v)", run.Tool.Driver, run.AutomationDetails)
+	}
+	if len(run.Results) != 2 || len(run.Tool.Driver.Rules) != 2 {
+		t.Fatalf("SARIF: expected 2 results and 2 rules, got %d and %d", len(run.Results), len(run.Tool.Driver.Rules))
+	}
+	high := run.Results[0]
+	if high.Level != "error" || high.Locations[0].PhysicalLocation.Region.StartLine != 12 {
+		t.Fatalf("SARIF: unexpected high severity result (%+v)", high)
+	}
+	low := run.Results[1]
+	if low.Level != "note" || low.Locations[0].PhysicalLocation.Region != nil {
+		t.Fatalf("SARIF: unexpected low severity result (%+v)", low)
+	}
+}
@@ -0,0 +1,194 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/huskyci-org/huskyCI/cli/types"
+	"github.com/huskyci-org/huskyCI/cli/vulnerability"
+	"github.com/huskyci-org/huskyCI/pkg/i18n"
+)
+
+// TargetRun is the analysis of the code on a target and the error that
+// stopped it, if any.
+type TargetRun struct {
+	Target   *types.Target
+	Analysis *Analysis
+	Err      error
+}
+
+// RunOnTargets sends the code compressed for base to every target at once and
+// waits for each analysis to finish, for up to timeout. The runs are returned
+// in the order of targets.
+func RunOnTargets(ctx context.Context, base *Analysis, targets []*types.Target, timeout time.Duration) []TargetRun {
+	width := 0
+	for _, target := range targets {
+		width = max(width, len(target.Label))
+	}
+	runs := make([]TargetRun, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		current := &Analysis{
+			ID:             uuid.New().String(),
+			CompressedFile: base.CompressedFile,
+			Languages:      base.Languages,
+			Path:           base.Path,
+			Branch:         base.Branch,
+			SecurityTests:  base.SecurityTests,
+			APITarget:      target,
+			Prefix:         fmt.Sprintf("[%-*s] ", width, target.Label),
+		}
+		runs[i] = TargetRun{Target: target, Analysis: current}
+		wg.Add(1)
+		go func(run *TargetRun) {
+			defer wg.Done()
+			if run.Target.Token == "" {
+				run.Err = i18n.Errorf("authentication token not found for target '%s'", run.Target.Label)
+				return
+			}
+			if run.Err = run.Analysis.SendZip(ctx); run.Err != nil {
+				return
+			}
+			run.Err = run.Analysis.CheckStatus(ctx, timeout)
+		}(&runs[i])
+	}
+	wg.Wait()
+	return runs
+}
+
+// WriteComparison writes the results of runs side by side: the status and the
+// vulnerabilities of each severity found on each target, and the
+// vulnerabilities that were not found on every target.
+func WriteComparison(w io.Writer, runs []TargetRun) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "TARGET\tSTATUS\tHIGH\tMEDIUM\tLOW\tINFO\tRID")
+	for _, run := range runs {
+		status := run.Analysis.Result.Status
+		if run.Err != nil {
+			status = "error"
+		}
+		counts := severityCounts(run.Analysis.Vulnerabilities)
+		fmt.Fprintf(table, "%s\t%s\t%d\t%d\t%d\t%d\t%s\n", run.Target.Label, status, counts[3], counts[2], counts[1], counts[0], run.Analysis.RID)
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	for _, run := range runs {
+		if run.Err != nil {
+			fmt.Fprintf(w, "\n❌ %s: %s\n", run.Target.Label, firstLine(run.Err.Error()))
+		}
+	}
+
+	differences := differingVulnerabilities(runs)
+	if len(differences) == 0 {
+		fmt.Fprintln(w, i18n.T("\n✅ Every target found the same vulnerabilities."))
+		return nil
+	}
+	i18n.Fprintf(w, "\n⚠️  %d vulnerabilities were not found on every target:\n", len(differences))
+	table = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := []string{"SEVERITY", "SECURITY TEST", "LOCATION", "TITLE"}
+	for _, run := range runs {
+		header = append(header, strings.ToUpper(run.Target.Label))
+	}
+	fmt.Fprintln(table, strings.Join(header, "\t"))
+	for _, difference := range differences {
+		row := []string{difference.vuln.Severity, difference.vuln.SecurityTest, location(difference.vuln), vulnTitle(difference.vuln)}
+		for i := range runs {
+			mark := "-"
+			if difference.found[i] {
+				mark = "✓"
+			}
+			row = append(row, mark)
+		}
+		fmt.Fprintln(table, strings.Join(row, "\t"))
+	}
+	return table.Flush()
+}
+
+// difference is a vulnerability and whether each run found it.
+type difference struct {
+	vuln  vulnerability.Vulnerability
+	found []bool
+}
+
+// differingVulnerabilities returns the vulnerabilities found by some of the
+// runs that finished but not by all of them, from the most severe.
+func differingVulnerabilities(runs []TargetRun) []difference {
+	byKey := map[string]*difference{}
+	keys := []string{}
+	finished := 0
+	for i, run := range runs {
+		if run.Err != nil {
+			continue
+		}
+		finished++
+		for _, vuln := range run.Analysis.Vulnerabilities {
+			key := vulnerabilityKey(vuln)
+			if byKey[key] == nil {
+				byKey[key] = &difference{vuln: vuln, found: make([]bool, len(runs))}
+				keys = append(keys, key)
+			}
+			byKey[key].found[i] = true
+		}
+	}
+
+	differences := []difference{}
+	for _, key := range keys {
+		found := 0
+		for _, f := range byKey[key].found {
+			if f {
+				found++
+			}
+		}
+		if found < finished {
+			differences = append(differences, *byKey[key])
+		}
+	}
+	sort.SliceStable(differences, func(i, j int) bool {
+		return SeverityRank(differences[i].vuln.Severity) > SeverityRank(differences[j].vuln.Severity)
+	})
+	return differences
+}
+
+// vulnerabilityKey identifies a vulnerability across the analyses of several
+// targets, which give it different IDs.
+func vulnerabilityKey(vuln vulnerability.Vulnerability) string {
+	return strings.Join([]string{vuln.SecurityTest, strings.ToLower(vuln.Severity), vuln.File, vuln.Line, vuln.Type, vuln.Details}, "\x00")
+}
+
+// severityCounts returns how many vulnerabilities there are of each severity
+// rank, nosec ones aside.
+func severityCounts(vulns []vulnerability.Vulnerability) map[int]int {
+	counts := map[int]int{}
+	for _, vuln := range vulns {
+		if !vuln.Nosec {
+			counts[SeverityRank(vuln.Severity)]++
+		}
+	}
+	return counts
+}
+
+// location returns the file and line of vuln, if any.
+func location(vuln vulnerability.Vulnerability) string {
+	switch {
+	case vuln.File == "":
+		return "-"
+	case vuln.Line == "" || vuln.Line == "n/a":
+		return vuln.File
+	}
+	return vuln.File + ":" + vuln.Line
+}
+
+// firstLine returns the first line of s, without the tips errors end with.
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
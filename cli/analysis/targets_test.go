@@ -0,0 +1,39 @@
+package analysis
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/huskyci-org/huskyCI/cli/types"
+	"github.com/huskyci-org/huskyCI/cli/vulnerability"
+)
+
+func TestWriteComparison(t *testing.T) {
+	shared := vulnerability.Vulnerability{SecurityTest: "gosec", Severity: "HIGH", File: "main.go", Line: "12", Type: "G101"}
+	onlyProd := vulnerability.Vulnerability{SecurityTest: "gitleaks", Severity: "LOW", File: "config.yml", Line: "3", Type: "aws-key"}
+	runs := []TargetRun{
+		{Target: &types.Target{Label: "prod"}, Analysis: &Analysis{RID: "a", Result: Result{Status: "finished"}, Vulnerabilities: []vulnerability.Vulnerability{shared, onlyProd}}},
+		{Target: &types.Target{Label: "staging"}, Analysis: &Analysis{RID: "b", Result: Result{Status: "finished"}, Vulnerabilities: []vulnerability.Vulnerability{shared}}},
+		{Target: &types.Target{Label: "old"}, Analysis: &Analysis{}, Err: errors.New("failed to upload zip file\n\nTip: retry")},
+	}
+
+	out := &bytes.Buffer{}
+	if err := WriteComparison(out, runs); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(out.String(), "\n")
+	if !strings.HasPrefix(lines[1], "prod") || !strings.Contains(lines[1], "finished  1     0       1") {
+		t.Errorf("unexpected prod row %q", lines[1])
+	}
+	if !strings.Contains(out.String(), "❌ old: failed to upload zip file\n") {
+		t.Errorf("the error of the old target is missing:\n%s", out)
+	}
+	if !strings.Contains(out.String(), "1 vulnerabilities were not found on every target") || !strings.Contains(out.String(), "config.yml:3") {
+		t.Errorf("the vulnerability only found on prod is missing:\n%s", out)
+	}
+	if strings.Contains(out.String(), "main.go:12") {
+		t.Errorf("the vulnerability found on every target is listed:\n%s", out)
+	}
+}
@@ -0,0 +1,78 @@
+// Package ci detects the continuous integration service the CLI runs on and
+// the repository, branch and pull request it builds.
+package ci
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Providers the CLI knows the environment of.
+const (
+	GitHubActions = "github"
+	GitLabCI      = "gitlab"
+	Generic       = "generic"
+)
+
+var pullRequestRefRegexp = regexp.MustCompile(`^refs/pull/(\d+)/`)
+
+// Environment describes the build of a continuous integration service. Fields
+// the service does not provide are left empty.
+type Environment struct {
+	Provider      string
+	RepositoryURL string
+	Branch        string
+	PullRequest   string
+	Commit        string
+	Workspace     string
+}
+
+// Detect returns the Environment described by the variables read with getenv,
+// os.Getenv usually.
+func Detect(getenv func(string) string) Environment {
+	switch {
+	case getenv("GITHUB_ACTIONS") == "true":
+		env := Environment{
+			Provider:  GitHubActions,
+			Branch:    getenv("GITHUB_HEAD_REF"),
+			Commit:    getenv("GITHUB_SHA"),
+			Workspace: getenv("GITHUB_WORKSPACE"),
+		}
+		if server, repository := getenv("GITHUB_SERVER_URL"), getenv("GITHUB_REPOSITORY"); server != "" && repository != "" {
+			env.RepositoryURL = strings.TrimSuffix(server, "/") + "/" + repository
+		}
+		if env.Branch == "" {
+			env.Branch = getenv("GITHUB_REF_NAME")
+		}
+		if match := pullRequestRefRegexp.FindStringSubmatch(getenv("GITHUB_REF")); match != nil {
+			env.PullRequest = match[1]
+		}
+		return env
+	case getenv("GITLAB_CI") == "true":
+		env := Environment{
+			Provider:      GitLabCI,
+			RepositoryURL: getenv("CI_PROJECT_URL"),
+			Branch:        getenv("CI_MERGE_REQUEST_SOURCE_BRANCH_NAME"),
+			PullRequest:   getenv("CI_MERGE_REQUEST_IID"),
+			Commit:        getenv("CI_COMMIT_SHA"),
+			Workspace:     getenv("CI_PROJECT_DIR"),
+		}
+		if env.Branch == "" {
+			env.Branch = getenv("CI_COMMIT_REF_NAME")
+		}
+		return env
+	}
+	return Environment{Provider: Generic}
+}
+
+// Name returns the name of the continuous integration service, as printed to
+// the user.
+func (e Environment) Name() string {
+	switch e.Provider {
+	case GitHubActions:
+		return "GitHub Actions"
+	case GitLabCI:
+		return "GitLab CI"
+	}
+	return "CI"
+}
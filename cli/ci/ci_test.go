@@ -0,0 +1,60 @@
+package ci
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := map[string]struct {
+		vars     map[string]string
+		expected Environment
+	}{
+		"github pull request": {
+			vars: map[string]string{
+				"GITHUB_ACTIONS":    "true",
+				"GITHUB_SERVER_URL": "https://github.com",
+				"GITHUB_REPOSITORY": "org/repo",
+				"GITHUB_REF":        "refs/pull/42/merge",
+				"GITHUB_REF_NAME":   "42/merge",
+				"GITHUB_HEAD_REF":   "feature",
+				"GITHUB_SHA":        "abc123",
+				"GITHUB_WORKSPACE":  "/home/runner/work/repo",
+			},
+			expected: Environment{Provider: GitHubActions, RepositoryURL: "https://github.com/org/repo", Branch: "feature", PullRequest: "42", Commit: "abc123", Workspace: "/home/runner/work/repo"},
+		},
+		"github push": {
+			vars: map[string]string{
+				"GITHUB_ACTIONS":    "true",
+				"GITHUB_SERVER_URL": "https://github.com",
+				"GITHUB_REPOSITORY": "org/repo",
+				"GITHUB_REF":        "refs/heads/main",
+				"GITHUB_REF_NAME":   "main",
+			},
+			expected: Environment{Provider: GitHubActions, RepositoryURL: "https://github.com/org/repo", Branch: "main"},
+		},
+		"gitlab merge request": {
+			vars: map[string]string{
+				"GITLAB_CI":                           "true",
+				"CI_PROJECT_URL":                      "https://gitlab.com/org/repo",
+				"CI_COMMIT_REF_NAME":                  "feature",
+				"CI_MERGE_REQUEST_SOURCE_BRANCH_NAME": "feature",
+				"CI_MERGE_REQUEST_IID":                "7",
+				"CI_COMMIT_SHA":                       "def456",
+				"CI_PROJECT_DIR":                      "/builds/org/repo",
+			},
+			expected: Environment{Provider: GitLabCI, RepositoryURL: "https://gitlab.com/org/repo", Branch: "feature", PullRequest: "7", Commit: "def456", Workspace: "/builds/org/repo"},
+		},
+		"gitlab branch": {
+			vars:     map[string]string{"GITLAB_CI": "true", "CI_COMMIT_REF_NAME": "main"},
+			expected: Environment{Provider: GitLabCI, Branch: "main"},
+		},
+		"other": {
+			vars:     map[string]string{"CI": "true"},
+			expected: Environment{Provider: Generic},
+		},
+	}
+	for name, test := range tests {
+		env := Detect(func(key string) string { return test.vars[key] })
+		if env != test.expected {
+			t.Errorf("CI: %s detected as %+v, expected %+v", name, env, test.expected)
+		}
+	}
+}
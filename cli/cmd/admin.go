@@ -0,0 +1,1115 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/cli/log"
+	"github.com/huskyci-org/huskyCI/pkg/apiclient"
+	"github.com/huskyci-org/huskyCI/pkg/formatter"
+	"github.com/huskyci-org/huskyCI/pkg/i18n"
+	"github.com/huskyci-org/huskyCI/pkg/sdk"
+	"github.com/spf13/cobra"
+)
+
+// imageReference returns image@digest when the securityTest image is pinned, image:tag otherwise.
+func imageReference(s apiclient.SecurityTest) string {
+	if s.ImageDigest != "" {
+		return fmt.Sprintf("%s@%s", s.Image, s.ImageDigest)
+	}
+	return fmt.Sprintf("%s:%s", s.Image, s.ImageTag)
+}
+
+// adminCmd represents the admin command
+var adminCmd = &cobra.Command{
+	Use:   "admin",
+	Short: "Administrative commands for a huskyCI API",
+	Long: `Administrative commands for a huskyCI API.
+
+Admin commands authenticate with the huskyCI API user credentials, read from
+the --username and --password flags or from the HUSKYCI_ADMIN_USERNAME and
+HUSKYCI_ADMIN_PASSWORD environment variables.`,
+}
+
+// adminSecurityTestsCmd represents the admin securitytests command
+var adminSecurityTestsCmd = &cobra.Command{
+	Use:   "securitytests",
+	Short: "List securityTests images, versions and timeouts",
+	Long: `List the securityTests stored in the huskyCI API, with their images,
+versions and timeouts.
+
+Examples:
+  # List all securityTests
+  huskyci admin securitytests
+
+  # Print the full cmd template of each securityTest
+  huskyci admin securitytests --verbose`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := adminClient(cmd)
+		if err != nil {
+			return err
+		}
+		securityTests, err := client.GetSecurityTests(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(formatter.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tIMAGE\tTYPE\tLANGUAGE\tDEFAULT\tTIMEOUT\tNETWORK")
+		for _, securityTest := range securityTests {
+			networkMode := securityTest.NetworkMode
+			if networkMode == "" {
+				networkMode = "full"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\t%ds\t%s\n", securityTest.Name, imageReference(securityTest),
+				securityTest.Type, securityTest.Language, securityTest.Default, securityTest.TimeOutInSeconds, networkMode)
+		}
+		w.Flush()
+
+		if log.Verbose() {
+			for _, securityTest := range securityTests {
+				formatter.Printf("\n[%s] cmd:\n%s\n", securityTest.Name, securityTest.Cmd)
+			}
+		}
+		return nil
+	},
+}
+
+// adminSecurityTestsUpdateCmd represents the admin securitytests update command
+var adminSecurityTestsUpdateCmd = &cobra.Command{
+	Use:   "update <name>",
+	Short: "Update a securityTest image, version, cmd or timeout",
+	Long: `Update a securityTest stored in the huskyCI API at runtime.
+
+Only the flags that are set are changed.
+
+Examples:
+  # Bump gosec image tag
+  huskyci admin securitytests update gosec --image-tag 2.18.2
+
+  # Pin gosec image by digest
+  huskyci admin securitytests update gosec --image-digest sha256:<hex>
+
+  # Run gosec without network access
+  huskyci admin securitytests update gosec --network-mode none
+
+  # Increase spotbugs timeout
+  huskyci admin securitytests update spotbugs --timeout 900
+
+  # Replace the cmd template from a file
+  huskyci admin securitytests update bandit --cmd-file ./bandit.sh`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		update := apiclient.SecurityTestUpdate{}
+		changed := false
+		stringFlag := func(name string) *string {
+			if !cmd.Flags().Changed(name) {
+				return nil
+			}
+			changed = true
+			value, _ := cmd.Flags().GetString(name)
+			return &value
+		}
+		update.Image = stringFlag("image")
+		update.ImageTag = stringFlag("image-tag")
+		update.ImageDigest = stringFlag("image-digest")
+		update.NetworkMode = stringFlag("network-mode")
+		if cmd.Flags().Changed("cmd-file") {
+			cmdFile, _ := cmd.Flags().GetString("cmd-file")
+			content, err := os.ReadFile(cmdFile)
+			if err != nil {
+				return i18n.Errorf("could not read cmd file: %w", err)
+			}
+			cmdTemplate := string(content)
+			update.Cmd = &cmdTemplate
+			changed = true
+		}
+		if cmd.Flags().Changed("timeout") {
+			timeout, _ := cmd.Flags().GetInt("timeout")
+			update.TimeOutInSeconds = &timeout
+			changed = true
+		}
+		if cmd.Flags().Changed("default") {
+			isDefault, _ := cmd.Flags().GetBool("default")
+			update.Default = &isDefault
+			changed = true
+		}
+		if !changed {
+			return errors.New(i18n.T("nothing to update\n\nTip: set at least one of --image, --image-tag, --image-digest, --cmd-file, --timeout, --network-mode or --default"))
+		}
+
+		client, err := adminClient(cmd)
+		if err != nil {
+			return err
+		}
+		securityTest, err := client.UpdateSecurityTest(cmd.Context(), args[0], update)
+		if err != nil {
+			return err
+		}
+		i18n.Printf("✓ %s updated: %s (timeout %ds)\n", securityTest.Name, imageReference(*securityTest), securityTest.TimeOutInSeconds)
+		return nil
+	},
+}
+
+// adminCredentialsCmd represents the admin credentials command
+var adminCredentialsCmd = &cobra.Command{
+	Use:   "credentials",
+	Short: "List the repositories cloned with a credential of their own",
+	Long: `List the repositories that have their own deploy key or HTTPS token.
+Secrets are never returned by the huskyCI API.
+
+Examples:
+  # List git credentials
+  huskyci admin credentials`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := adminClient(cmd)
+		if err != nil {
+			return err
+		}
+		credentials, err := client.GetGitCredentials(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(formatter.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "REPOSITORY\tTYPE\tUSERNAME\tUPDATED")
+		for _, credential := range credentials {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", credential.RepositoryURL, credential.Type, credential.Username,
+				credential.UpdatedAt.Local().Format("2006-01-02 15:04"))
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+// adminCredentialsSetCmd represents the admin credentials set command
+var adminCredentialsSetCmd = &cobra.Command{
+	Use:   "set <repository-url>",
+	Short: "Set the deploy key or HTTPS token used to clone a repository",
+	Long: `Set the deploy key or HTTPS token used to clone a repository, replacing
+the one it had. The secret is read from a file so it never shows up in the
+shell history.
+
+Examples:
+  # Use a deploy key
+  huskyci admin credentials set git@github.com:org/private.git --type ssh --secret-file ./deploy_key
+
+  # Use an HTTPS token
+  huskyci admin credentials set https://github.com/org/private.git --type token --secret-file ./token`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		credentialType, _ := cmd.Flags().GetString("type")
+		if credentialType != "ssh" && credentialType != "token" {
+			return errors.New(i18n.T("invalid credential type\n\nTip: use --type ssh or --type token"))
+		}
+		secretFile, _ := cmd.Flags().GetString("secret-file")
+		if secretFile == "" {
+			return errors.New(i18n.T("no secret given\n\nTip: use --secret-file with the private key or token"))
+		}
+		secret, err := os.ReadFile(secretFile)
+		if err != nil {
+			return i18n.Errorf("could not read secret file: %w", err)
+		}
+		tokenUsername, _ := cmd.Flags().GetString("token-username")
+
+		client, err := adminClient(cmd)
+		if err != nil {
+			return err
+		}
+		credential, err := client.PutGitCredential(cmd.Context(), apiclient.GitCredentialRequest{
+			RepositoryURL: args[0],
+			Type:          credentialType,
+			Username:      tokenUsername,
+			Secret:        strings.TrimSpace(string(secret)) + secretSuffix(credentialType),
+		})
+		if err != nil {
+			return err
+		}
+		i18n.Printf("✓ %s credential set for %s\n", credential.Type, credential.RepositoryURL)
+		return nil
+	},
+}
+
+// adminCredentialsDeleteCmd represents the admin credentials delete command
+var adminCredentialsDeleteCmd = &cobra.Command{
+	Use:   "delete <repository-url>",
+	Short: "Remove the credential of a repository",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := adminClient(cmd)
+		if err != nil {
+			return err
+		}
+		if err := client.DeleteGitCredential(cmd.Context(), args[0]); err != nil {
+			return err
+		}
+		i18n.Printf("✓ credential removed for %s\n", args[0])
+		return nil
+	},
+}
+
+// adminStatusReportersCmd represents the admin statusreporters command
+var adminStatusReportersCmd = &cobra.Command{
+	Use:   "statusreporters",
+	Short: "List the repositories whose analyses are reported to Bitbucket or Gerrit",
+	Long: `List the repositories whose analysis results are posted to Bitbucket as a
+build status or to Gerrit as a review. Secrets are never returned by the
+huskyCI API.
+
+Examples:
+  # List status reporters
+  huskyci admin statusreporters`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := adminClient(cmd)
+		if err != nil {
+			return err
+		}
+		reporters, err := client.GetStatusReporters(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(formatter.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "REPOSITORY\tPROVIDER\tENDPOINT\tUSERNAME\tUPDATED")
+		for _, reporter := range reporters {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", reporter.RepositoryURL, reporter.Provider, reporter.Endpoint,
+				reporter.Username, reporter.UpdatedAt.Local().Format("2006-01-02 15:04"))
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+// adminStatusReportersSetCmd represents the admin statusreporters set command
+var adminStatusReportersSetCmd = &cobra.Command{
+	Use:   "set <repository-url>",
+	Short: "Report the analyses of a repository to Bitbucket or Gerrit",
+	Long: `Post the result of the analyses of a repository to Bitbucket as a build
+status or to Gerrit as a review, replacing the status reporter it had. The
+secret, an app password or HTTP password, is read from a file so it never shows
+up in the shell history.
+
+Analyses started by huskyci-client are reported on the commit given in its
+HUSKYCI_CLIENT_REPO_COMMIT variable.
+
+Examples:
+  # Bitbucket Cloud, with an app password
+  huskyci admin statusreporters set https://bitbucket.org/org/repo.git --provider bitbucket-cloud --reporter-username bot --secret-file ./app_password
+
+  # Bitbucket Server, with an HTTP access token
+  huskyci admin statusreporters set https://bitbucket.example.com/scm/prj/repo.git --provider bitbucket-server --endpoint https://bitbucket.example.com --secret-file ./token
+
+  # Gerrit, voting on Verified
+  huskyci admin statusreporters set https://gerrit.example.com/repo --provider gerrit --endpoint https://gerrit.example.com --reporter-username huskyci --secret-file ./http_password`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		provider, _ := cmd.Flags().GetString("provider")
+		if provider != "bitbucket-cloud" && provider != "bitbucket-server" && provider != "gerrit" {
+			return errors.New(i18n.T("invalid provider\n\nTip: use --provider bitbucket-cloud, bitbucket-server or gerrit"))
+		}
+		secretFile, _ := cmd.Flags().GetString("secret-file")
+		if secretFile == "" {
+			return errors.New(i18n.T("no secret given\n\nTip: use --secret-file with the app password, token or HTTP password"))
+		}
+		secret, err := os.ReadFile(secretFile)
+		if err != nil {
+			return i18n.Errorf("could not read secret file: %w", err)
+		}
+		endpoint, _ := cmd.Flags().GetString("endpoint")
+		reporterUsername, _ := cmd.Flags().GetString("reporter-username")
+		label, _ := cmd.Flags().GetString("label")
+		detailsURL, _ := cmd.Flags().GetString("details-url")
+
+		client, err := adminClient(cmd)
+		if err != nil {
+			return err
+		}
+		reporter, err := client.PutStatusReporter(cmd.Context(), apiclient.StatusReporterRequest{
+			RepositoryURL: args[0],
+			Provider:      provider,
+			Endpoint:      endpoint,
+			Username:      reporterUsername,
+			Secret:        strings.TrimSpace(string(secret)),
+			Label:         label,
+			DetailsURL:    detailsURL,
+		})
+		if err != nil {
+			return err
+		}
+		i18n.Printf("✓ %s status reporter set for %s\n", reporter.Provider, reporter.RepositoryURL)
+		return nil
+	},
+}
+
+// adminStatusReportersDeleteCmd represents the admin statusreporters delete command
+var adminStatusReportersDeleteCmd = &cobra.Command{
+	Use:   "delete <repository-url>",
+	Short: "Stop reporting the analyses of a repository",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := adminClient(cmd)
+		if err != nil {
+			return err
+		}
+		if err := client.DeleteStatusReporter(cmd.Context(), args[0]); err != nil {
+			return err
+		}
+		i18n.Printf("✓ status reporter removed for %s\n", args[0])
+		return nil
+	},
+}
+
+// adminRemediationsCmd represents the admin remediations command
+var adminRemediationsCmd = &cobra.Command{
+	Use:   "remediations",
+	Short: "List the repositories that get pull requests upgrading vulnerable dependencies",
+	Long: `List the repositories on GitHub or GitLab that opted in to pull requests
+upgrading the vulnerable dependencies their analyses find. Secrets are never
+returned by the huskyCI API.
+
+Examples:
+  # List remediations
+  huskyci admin remediations`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := adminClient(cmd)
+		if err != nil {
+			return err
+		}
+		remediations, err := client.GetRemediations(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(formatter.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "REPOSITORY\tPROVIDER\tENDPOINT\tBASE BRANCH\tUPDATED")
+		for _, remediation := range remediations {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", remediation.RepositoryURL, remediation.Provider, remediation.Endpoint,
+				remediation.BaseBranch, remediation.UpdatedAt.Local().Format("2006-01-02 15:04"))
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+// adminRemediationsSetCmd represents the admin remediations set command
+var adminRemediationsSetCmd = &cobra.Command{
+	Use:   "set <repository-url>",
+	Short: "Open pull requests upgrading the vulnerable dependencies of a repository",
+	Long: `Open a pull request, or a merge request on GitLab, whenever an analysis of
+the repository finds dependencies with a fix version, replacing the
+remediation it had. The pull request upgrades the requirements files,
+package.json and go.mod manifests declaring them; lock files are left to the
+repository CI. The token, which must be allowed to push branches and open pull
+requests, is read from a file so it never shows up in the shell history.
+
+Examples:
+  # GitHub, with a fine-grained personal access token
+  huskyci admin remediations set https://github.com/org/repo.git --provider github --secret-file ./token
+
+  # Self-managed GitLab, targeting the develop branch
+  huskyci admin remediations set git@gitlab.example.com:group/repo.git --provider gitlab --endpoint https://gitlab.example.com/api/v4 --base-branch develop --secret-file ./token`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		provider, _ := cmd.Flags().GetString("provider")
+		if provider != "github" && provider != "gitlab" {
+			return errors.New(i18n.T("invalid provider\n\nTip: use --provider github or gitlab"))
+		}
+		secretFile, _ := cmd.Flags().GetString("secret-file")
+		if secretFile == "" {
+			return errors.New(i18n.T("no secret given\n\nTip: use --secret-file with the access token"))
+		}
+		secret, err := os.ReadFile(secretFile)
+		if err != nil {
+			return i18n.Errorf("could not read secret file: %w", err)
+		}
+		endpoint, _ := cmd.Flags().GetString("endpoint")
+		baseBranch, _ := cmd.Flags().GetString("base-branch")
+
+		client, err := adminClient(cmd)
+		if err != nil {
+			return err
+		}
+		remediation, err := client.PutRemediation(cmd.Context(), apiclient.RemediationRequest{
+			RepositoryURL: args[0],
+			Provider:      provider,
+			Endpoint:      endpoint,
+			Secret:        strings.TrimSpace(string(secret)),
+			BaseBranch:    baseBranch,
+		})
+		if err != nil {
+			return err
+		}
+		i18n.Printf("✓ %s remediation set for %s\n", remediation.Provider, remediation.RepositoryURL)
+		return nil
+	},
+}
+
+// adminRemediationsDeleteCmd represents the admin remediations delete command
+var adminRemediationsDeleteCmd = &cobra.Command{
+	Use:   "delete <repository-url>",
+	Short: "Stop opening remediation pull requests on a repository",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := adminClient(cmd)
+		if err != nil {
+			return err
+		}
+		if err := client.DeleteRemediation(cmd.Context(), args[0]); err != nil {
+			return err
+		}
+		i18n.Printf("✓ remediation removed for %s\n", args[0])
+		return nil
+	},
+}
+
+// adminPoliciesCmd represents the admin policies command
+var adminPoliciesCmd = &cobra.Command{
+	Use:   "policies",
+	Short: "List the severities the CI of each repository fails on",
+	Long: `List the policies of the repositories: the lowest severity of the
+vulnerabilities failing their CI when huskyci-client and huskyci ci are not
+told otherwise. Repositories without a policy fail on medium.
+
+Examples:
+  # List policies
+  huskyci admin policies`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := adminClient(cmd)
+		if err != nil {
+			return err
+		}
+		policies, err := client.GetPolicies(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(formatter.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "REPOSITORY\tFAIL ON\tIGNORED RULES\tSEVERITY OVERRIDES\tREQUIRED TESTS\tUPDATED")
+		for _, policy := range policies {
+			ignored := strings.Join(policy.IgnoreRules, ",")
+			if ignored == "" {
+				ignored = "-"
+			}
+			overrides := strings.Join(severityOverrides(policy.SeverityOverrides), ",")
+			if overrides == "" {
+				overrides = "-"
+			}
+			required := strings.Join(policy.RequiredSecurityTests, ",")
+			if required == "" {
+				required = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", policy.RepositoryURL, policy.FailOn, ignored, overrides, required, policy.UpdatedAt.Local().Format("2006-01-02 15:04"))
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+// adminPoliciesSetCmd represents the admin policies set command
+var adminPoliciesSetCmd = &cobra.Command{
+	Use:   "set <repository-url>",
+	Short: "Set the severity the CI of a repository fails on",
+	Long: `Set the lowest severity of the vulnerabilities failing the CI of a
+repository, replacing the policy it had. never only reports them, which helps
+rolling huskyCI out. HUSKYCI_CLIENT_FAIL_ON and --warn-only of huskyci-client,
+and --fail-on of huskyci ci, still take precedence.
+
+The findings of the rules given with --ignore-rule, such as G104 or B101, are
+still recorded by the next analyses, flagged as ignored-by-policy, but never
+fail them. The findings of the rules given with --severity-override, such as
+generic-api-key=high, get that severity instead of the one their securityTest
+found. Findings without a rule ID, such as the ones of npm audit, are matched
+by their title. The securityTests given with --require-test, such as gitleaks,
+cannot be left out of the analyses selecting the securityTests they run.
+
+Examples:
+  # Only fail on high severity vulnerabilities
+  huskyci admin policies set https://github.com/org/repo.git --fail-on high
+
+  # Report the vulnerabilities without failing
+  huskyci admin policies set https://github.com/org/repo.git --fail-on never
+
+  # Ignore gosec G104 and bandit B101
+  huskyci admin policies set https://github.com/org/repo.git --fail-on medium --ignore-rule G104,B101
+
+  # Fail on gitleaks generic API keys
+  huskyci admin policies set https://github.com/org/repo.git --fail-on high --severity-override generic-api-key=high
+
+  # Always run gitleaks, even when only some securityTests are selected
+  huskyci admin policies set https://github.com/org/repo.git --fail-on medium --require-test gitleaks`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		failOn, _ := cmd.Flags().GetString("fail-on")
+		ignoreRules, _ := cmd.Flags().GetStringSlice("ignore-rule")
+		overrides, _ := cmd.Flags().GetStringToString("severity-override")
+		requiredTests, _ := cmd.Flags().GetStringSlice("require-test")
+		if !sdk.ValidFailOn(failOn) {
+			return errors.New(i18n.T("invalid severity\n\nTip: use --fail-on high, medium, low or never"))
+		}
+		client, err := adminClient(cmd)
+		if err != nil {
+			return err
+		}
+		policy, err := client.PutPolicy(cmd.Context(), apiclient.PolicyRequest{
+			RepositoryURL:         args[0],
+			FailOn:                failOn,
+			IgnoreRules:           ignoreRules,
+			SeverityOverrides:     overrides,
+			RequiredSecurityTests: requiredTests,
+		})
+		if err != nil {
+			return err
+		}
+		i18n.Printf("✓ %s fails on %s\n", policy.RepositoryURL, policy.FailOn)
+		if len(policy.IgnoreRules) > 0 {
+			i18n.Printf("  ignoring the findings of %s\n", strings.Join(policy.IgnoreRules, ", "))
+		}
+		if len(policy.SeverityOverrides) > 0 {
+			i18n.Printf("  overriding the severity of %s\n", strings.Join(severityOverrides(policy.SeverityOverrides), ", "))
+		}
+		if len(policy.RequiredSecurityTests) > 0 {
+			i18n.Printf("  always running %s\n", strings.Join(policy.RequiredSecurityTests, ", "))
+		}
+		return nil
+	},
+}
+
+// severityOverrides returns the severity overrides of a policy as sorted
+// rule=severity pairs.
+func severityOverrides(overrides map[string]string) []string {
+	pairs := make([]string, 0, len(overrides))
+	for rule, severity := range overrides {
+		pairs = append(pairs, rule+"="+severity)
+	}
+	sort.Strings(pairs)
+	return pairs
+}
+
+// adminPoliciesDeleteCmd represents the admin policies delete command
+var adminPoliciesDeleteCmd = &cobra.Command{
+	Use:   "delete <repository-url>",
+	Short: "Remove the policy of a repository, which then fails on medium",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := adminClient(cmd)
+		if err != nil {
+			return err
+		}
+		if err := client.DeletePolicy(cmd.Context(), args[0]); err != nil {
+			return err
+		}
+		i18n.Printf("✓ policy removed for %s\n", args[0])
+		return nil
+	},
+}
+
+// secretSuffix keeps the trailing newline ssh requires at the end of a private key.
+func secretSuffix(credentialType string) string {
+	if credentialType == "ssh" {
+		return "\n"
+	}
+	return ""
+}
+
+// adminBenchCmd represents the admin bench command
+var adminBenchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Benchmark the runner hosts with a synthetic workload",
+	Long: `Benchmark the Docker API hosts of the huskyCI API with a synthetic
+workload, one host after the other, and print the throughput and latency
+percentiles of each kind of job: noop jobs run a container that exits at once
+and unzip jobs deliver a synthetic upload to the containers of an analysis.
+Use it to size the runner hosts and to check the tuning of their Docker
+daemons before production analyses run there.
+
+Examples:
+  # Run 20 jobs of each kind, 4 at once, on each host
+  huskyci admin bench
+
+  # Run 200 noop jobs, 16 at once
+  huskyci admin bench --kinds noop --jobs 200 --concurrency 16
+
+  # Start a benchmark and read its report later
+  huskyci admin bench --detach
+  huskyci admin bench report`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := adminClient(cmd)
+		if err != nil {
+			return err
+		}
+		workload := apiclient.Workload{}
+		workload.Jobs, _ = cmd.Flags().GetInt("jobs")
+		workload.Concurrency, _ = cmd.Flags().GetInt("concurrency")
+		workload.Kinds, _ = cmd.Flags().GetStringSlice("kinds")
+		workload.ZipFiles, _ = cmd.Flags().GetInt("zip-files")
+		benchmark, err := client.StartBench(cmd.Context(), workload)
+		if err != nil {
+			return err
+		}
+		i18n.Printf("✓ benchmark started: %d jobs of %s, %d at once\n", benchmark.Workload.Jobs,
+			strings.Join(benchmark.Workload.Kinds, " and "), benchmark.Workload.Concurrency)
+		if detach, _ := cmd.Flags().GetBool("detach"); detach {
+			return nil
+		}
+		for benchmark.Running {
+			select {
+			case <-cmd.Context().Done():
+				return cmd.Context().Err()
+			case <-time.After(2 * time.Second):
+			}
+			if benchmark, err = client.GetBench(cmd.Context()); err != nil {
+				return err
+			}
+		}
+		return printBenchmark(benchmark)
+	},
+}
+
+// adminBenchReportCmd represents the admin bench report command
+var adminBenchReportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Print the report of the running or last benchmark",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := adminClient(cmd)
+		if err != nil {
+			return err
+		}
+		benchmark, err := client.GetBench(cmd.Context())
+		if err != nil {
+			return err
+		}
+		if benchmark.Running {
+			i18n.Printf("benchmark running since %s\n", benchmark.StartedAt.Local().Format("2006-01-02 15:04:05"))
+		}
+		return printBenchmark(benchmark)
+	},
+}
+
+// printBenchmark prints the throughput and latency of each kind of job of a
+// benchmark on each host, and the errors of its failed jobs.
+func printBenchmark(benchmark *apiclient.Benchmark) error {
+	w := tabwriter.NewWriter(formatter.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "HOST\tKIND\tJOBS\tFAILED\tJOBS/S\tP50\tP90\tP99\tMAX")
+	for _, host := range benchmark.Hosts {
+		for _, kind := range host.Kinds {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%.2f\t%.2fs\t%.2fs\t%.2fs\t%.2fs\n", host.Host, kind.Kind, kind.Jobs, kind.Failed,
+				kind.Throughput, kind.Latency.P50, kind.Latency.P90, kind.Latency.P99, kind.Latency.Max)
+		}
+	}
+	w.Flush()
+	for _, host := range benchmark.Hosts {
+		for _, kind := range host.Kinds {
+			for _, jobError := range kind.Errors {
+				i18n.Printf("  %s %s job failed: %s\n", host.Host, kind.Kind, jobError)
+			}
+		}
+	}
+	if benchmark.Error != "" {
+		return i18n.Errorf("benchmark failed: %s", benchmark.Error)
+	}
+	return nil
+}
+
+// adminUsersCmd represents the admin users command
+var adminUsersCmd = &cobra.Command{
+	Use:   "users",
+	Short: "Manage the huskyCI API users",
+	Long: `Manage the users of the huskyCI API, the credentials admin commands and
+token requests authenticate with.
+
+Examples:
+  # Rotate the password of the API user
+  huskyci admin users password --new-password-file ./new_password`,
+}
+
+// adminUsersPasswordCmd represents the admin users password command
+var adminUsersPasswordCmd = &cobra.Command{
+	Use:   "password",
+	Short: "Change the password of the API user",
+	Long: `Change the password of the huskyCI API user admin commands authenticate
+with, --username and --password being its current credentials. The new
+password is read from a file, or from the standard input with -, so it never
+shows up in the shell history.
+
+Examples:
+  # Read the new password from a file
+  huskyci admin users password --new-password-file ./new_password
+
+  # Read it from a secret manager
+  vault kv get -field=password secret/huskyci | huskyci admin users password --new-password-file -`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		newPasswordFile, _ := cmd.Flags().GetString("new-password-file")
+		if newPasswordFile == "" {
+			return errors.New(i18n.T("no new password given\n\nTip: use --new-password-file with a file, or - to read it from stdin"))
+		}
+		newPassword, err := readSecret(newPasswordFile)
+		if err != nil {
+			return i18n.Errorf("could not read new password: %w", err)
+		}
+		if newPassword == "" {
+			return errors.New(i18n.T("the new password is empty"))
+		}
+		username, password, err := adminCredentials(cmd)
+		if err != nil {
+			return err
+		}
+
+		client, err := adminClient(cmd)
+		if err != nil {
+			return err
+		}
+		if _, err := client.UpdateUser(cmd.Context(), apiclient.User{
+			Username:           username,
+			Password:           password,
+			NewPassword:        newPassword,
+			ConfirmNewPassword: newPassword,
+		}); err != nil {
+			return err
+		}
+		i18n.Printf("✓ password of %s changed\n", username)
+		formatter.Println(i18n.T("  Update HUSKYCI_ADMIN_PASSWORD and the clients using this user."))
+		return nil
+	},
+}
+
+// adminTokensCmd represents the admin tokens command
+var adminTokensCmd = &cobra.Command{
+	Use:   "tokens",
+	Short: "Create or deactivate the access tokens of repositories",
+	Long: `Create or deactivate the access tokens huskyci-client and the CLI send
+along with the analyses of a repository.
+
+Examples:
+  # Create a token for a repository
+  huskyci admin tokens create https://github.com/org/repo.git
+
+  # Deactivate a leaked token
+  huskyci admin tokens deactivate --token-file ./token`,
+}
+
+// adminTokensCreateCmd represents the admin tokens create command
+var adminTokensCreateCmd = &cobra.Command{
+	Use:   "create <repository-url>",
+	Short: "Create an access token for a repository",
+	Long: `Create an access token for the analyses of a repository. The token is
+printed once and can not be read again, so store it right away, for instance
+with huskyci target-add --token-stdin.
+
+Examples:
+  # Print the token
+  huskyci admin tokens create https://github.com/org/repo.git
+
+  # Print the token alone, to pipe it
+  huskyci admin tokens create https://github.com/org/repo.git --quiet | huskyci target-add production https://api.huskyci.example.com --token-stdin`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := adminClient(cmd)
+		if err != nil {
+			return err
+		}
+		token, err := client.HandleToken(cmd.Context(), apiclient.TokenRequest{RepositoryURL: args[0]})
+		if err != nil {
+			return err
+		}
+		if log.Quiet() {
+			fmt.Println(token.HuskyToken)
+			return nil
+		}
+		i18n.Printf("✓ token created for %s\n\n", args[0])
+		fmt.Println(token.HuskyToken)
+		formatter.Println(i18n.T("\n  Store it now: it can not be read again."))
+		return nil
+	},
+}
+
+// adminTokensDeactivateCmd represents the admin tokens deactivate command
+var adminTokensDeactivateCmd = &cobra.Command{
+	Use:   "deactivate",
+	Short: "Deactivate an access token",
+	Long: `Deactivate an access token, so that the analyses sent along with it are
+refused. The token is read from a file, or from the standard input with -, so
+it never shows up in the shell history.
+
+Examples:
+  # Deactivate the token stored in a file
+  huskyci admin tokens deactivate --token-file ./token
+
+  # Deactivate the token of an environment variable
+  echo "$HUSKYCI_CLIENT_TOKEN" | huskyci admin tokens deactivate --token-file -`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tokenFile, _ := cmd.Flags().GetString("token-file")
+		if tokenFile == "" {
+			return errors.New(i18n.T("no token given\n\nTip: use --token-file with a file, or - to read it from stdin"))
+		}
+		token, err := readSecret(tokenFile)
+		if err != nil {
+			return i18n.Errorf("could not read token: %w", err)
+		}
+		if token == "" {
+			return errors.New(i18n.T("the token is empty"))
+		}
+
+		client, err := adminClient(cmd)
+		if err != nil {
+			return err
+		}
+		if _, err := client.HandleDeactivation(cmd.Context(), apiclient.AccessToken{HuskyToken: token}); err != nil {
+			return err
+		}
+		formatter.Println(i18n.T("✓ token deactivated"))
+		return nil
+	},
+}
+
+// adminDockerHostsCmd represents the admin docker-hosts command
+var adminDockerHostsCmd = &cobra.Command{
+	Use:   "docker-hosts",
+	Short: "Show the load of the runner hosts and maintain them",
+	Long: `Show how loaded the Docker API hosts of the huskyCI API are: the analyses
+running and waiting, the capacity of the hosts and how many hosts the backlog
+calls for.
+
+Examples:
+  # Show the scaling hints
+  huskyci admin docker-hosts
+
+  # Pull the securityTest images on every host
+  huskyci admin docker-hosts prepull
+
+  # Show what the janitor cleaned up
+  huskyci admin docker-hosts janitor`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := adminClient(cmd)
+		if err != nil {
+			return err
+		}
+		hints, err := client.GetScalingHints(cmd.Context())
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(formatter.Stdout, 0, 0, 2, ' ', 0)
+		i18n.Fprintf(w, "Hosts:\t%d\n", hints.Hosts)
+		i18n.Fprintf(w, "Capacity:\t%d analyses\n", hints.Capacity)
+		i18n.Fprintf(w, "Running:\t%d analyses\n", hints.Running)
+		i18n.Fprintf(w, "Waiting:\t%d analyses\n", hints.Backlog)
+		i18n.Fprintf(w, "Average duration:\t%s\n", time.Duration(hints.AverageDurationSeconds*float64(time.Second)).Round(time.Second))
+		i18n.Fprintf(w, "Estimated wait:\t%s\n", time.Duration(hints.EstimatedWaitSeconds*float64(time.Second)).Round(time.Second))
+		i18n.Fprintf(w, "Desired hosts:\t%d\n", hints.DesiredHosts)
+		return w.Flush()
+	},
+}
+
+// adminDockerHostsPrepullCmd represents the admin docker-hosts prepull command
+var adminDockerHostsPrepullCmd = &cobra.Command{
+	Use:   "prepull",
+	Short: "Pull the securityTest images on the runner hosts",
+	Long: `Pull the images of the securityTests on every Docker API host in the
+background, so that the first analyses after a deploy or an image update do
+not wait for them.
+
+Examples:
+  # Pull the images missing from the hosts
+  huskyci admin docker-hosts prepull
+
+  # Pull every image again, after a tag was moved
+  huskyci admin docker-hosts prepull --refresh`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := adminClient(cmd)
+		if err != nil {
+			return err
+		}
+		refresh, _ := cmd.Flags().GetBool("refresh")
+		if _, err := client.TriggerPrepull(cmd.Context(), &apiclient.TriggerPrepullParams{Refresh: refresh}); err != nil {
+			return err
+		}
+		formatter.Println(i18n.T("✓ prepull started"))
+		return nil
+	},
+}
+
+// adminDockerHostsJanitorCmd represents the admin docker-hosts janitor command
+var adminDockerHostsJanitorCmd = &cobra.Command{
+	Use:   "janitor",
+	Short: "Show what the janitor cleaned up on the runner hosts",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := adminClient(cmd)
+		if err != nil {
+			return err
+		}
+		stats, err := client.GetJanitorStats(cmd.Context())
+		if err != nil {
+			return err
+		}
+		lastRun := i18n.T("never")
+		if !stats.LastRun.IsZero() {
+			lastRun = stats.LastRun.Local().Format("2006-01-02 15:04:05")
+		}
+		w := tabwriter.NewWriter(formatter.Stdout, 0, 0, 2, ' ', 0)
+		i18n.Fprintf(w, "Runs:\t%d\n", stats.Runs)
+		i18n.Fprintf(w, "Last run:\t%s\n", lastRun)
+		i18n.Fprintf(w, "Containers removed:\t%d (%s)\n", stats.ContainersRemoved, byteSize(stats.ContainerBytes))
+		i18n.Fprintf(w, "Volumes removed:\t%d\n", stats.VolumesRemoved)
+		i18n.Fprintf(w, "Uploads removed:\t%d (%s)\n", stats.ZipEntriesRemoved, byteSize(stats.ZipBytes))
+		i18n.Fprintf(w, "Objects expired:\t%d\n", stats.ObjectsExpired)
+		w.Flush()
+		for _, lastError := range stats.LastErrors {
+			formatter.Printf("  ⚠ %s\n", lastError)
+		}
+		return nil
+	},
+}
+
+// byteSize returns n bytes in a unit people read, such as 12.3 MB.
+func byteSize(n int64) string {
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, prefix := float64(n)/unit, 0
+	for value >= unit && prefix < 4 {
+		value /= unit
+		prefix++
+	}
+	return fmt.Sprintf("%.1f %cB", value, "kMGTP"[prefix])
+}
+
+func init() {
+	rootCmd.AddCommand(adminCmd)
+	adminCmd.AddCommand(adminSecurityTestsCmd)
+	adminSecurityTestsCmd.AddCommand(adminSecurityTestsUpdateCmd)
+	adminCmd.AddCommand(adminCredentialsCmd)
+	adminCredentialsCmd.AddCommand(adminCredentialsSetCmd)
+	adminCredentialsCmd.AddCommand(adminCredentialsDeleteCmd)
+	adminCmd.AddCommand(adminStatusReportersCmd)
+	adminStatusReportersCmd.AddCommand(adminStatusReportersSetCmd)
+	adminStatusReportersCmd.AddCommand(adminStatusReportersDeleteCmd)
+	adminCmd.AddCommand(adminRemediationsCmd)
+	adminRemediationsCmd.AddCommand(adminRemediationsSetCmd)
+	adminRemediationsCmd.AddCommand(adminRemediationsDeleteCmd)
+	adminCmd.AddCommand(adminPoliciesCmd)
+	adminPoliciesCmd.AddCommand(adminPoliciesSetCmd)
+	adminPoliciesCmd.AddCommand(adminPoliciesDeleteCmd)
+	adminCmd.AddCommand(adminBenchCmd)
+	adminBenchCmd.AddCommand(adminBenchReportCmd)
+	adminCmd.AddCommand(adminUsersCmd)
+	adminUsersCmd.AddCommand(adminUsersPasswordCmd)
+	adminCmd.AddCommand(adminTokensCmd)
+	adminTokensCmd.AddCommand(adminTokensCreateCmd)
+	adminTokensCmd.AddCommand(adminTokensDeactivateCmd)
+	adminCmd.AddCommand(adminDockerHostsCmd)
+	adminDockerHostsCmd.AddCommand(adminDockerHostsPrepullCmd)
+	adminDockerHostsCmd.AddCommand(adminDockerHostsJanitorCmd)
+
+	adminCmd.PersistentFlags().String("username", "", "huskyCI API username (default is $HUSKYCI_ADMIN_USERNAME)")
+	adminCmd.PersistentFlags().String("password", "", "huskyCI API password (default is $HUSKYCI_ADMIN_PASSWORD)")
+
+	adminSecurityTestsUpdateCmd.Flags().String("image", "", "new container image")
+	adminSecurityTestsUpdateCmd.Flags().String("image-tag", "", "new container image tag")
+	adminSecurityTestsUpdateCmd.Flags().String("image-digest", "", "pin the image to a sha256 digest (empty to unpin)")
+	adminSecurityTestsUpdateCmd.Flags().String("cmd-file", "", "file with the new cmd template")
+	adminSecurityTestsUpdateCmd.Flags().Int("timeout", 0, "new timeout in seconds")
+	adminSecurityTestsUpdateCmd.Flags().String("network-mode", "", "container network mode: none, egress-proxy or full")
+	adminSecurityTestsUpdateCmd.Flags().Bool("default", false, "whether the securityTest runs by default")
+
+	adminCredentialsSetCmd.Flags().String("type", "", "credential type: ssh or token")
+	adminCredentialsSetCmd.Flags().String("secret-file", "", "file with the private key or token")
+	adminCredentialsSetCmd.Flags().String("token-username", "", "username sent along with the token (default x-access-token)")
+
+	adminStatusReportersSetCmd.Flags().String("provider", "", "code review tool: bitbucket-cloud, bitbucket-server or gerrit")
+	adminStatusReportersSetCmd.Flags().String("endpoint", "", "base URL of Bitbucket Server or Gerrit (default https://api.bitbucket.org for bitbucket-cloud)")
+	adminStatusReportersSetCmd.Flags().String("reporter-username", "", "username sent along with the secret (bearer token if empty, required by gerrit)")
+	adminStatusReportersSetCmd.Flags().String("secret-file", "", "file with the app password, token or HTTP password")
+	adminStatusReportersSetCmd.Flags().String("label", "", "Gerrit label voted on (default Verified)")
+	adminStatusReportersSetCmd.Flags().String("details-url", "", "page the status links to, %RID% is replaced by the analysis RID")
+
+	adminRemediationsSetCmd.Flags().String("provider", "", "git hosting service: github or gitlab")
+	adminRemediationsSetCmd.Flags().String("endpoint", "", "base URL of the API (default https://api.github.com or https://gitlab.com/api/v4)")
+	adminRemediationsSetCmd.Flags().String("secret-file", "", "file with the access token")
+	adminRemediationsSetCmd.Flags().String("base-branch", "", "branch the pull requests target (default the analyzed branch)")
+
+	adminPoliciesSetCmd.Flags().String("fail-on", "", "lowest severity of the vulnerabilities failing the CI: high, medium, low or never")
+	adminPoliciesSetCmd.Flags().StringSlice("ignore-rule", nil, "rules whose findings never fail the CI, such as G104,B101")
+	adminPoliciesSetCmd.Flags().StringToString("severity-override", nil, "severities of the findings of rules, such as generic-api-key=high")
+	adminPoliciesSetCmd.Flags().StringSlice("require-test", nil, "securityTests analyses cannot leave out, such as gitleaks")
+
+	adminBenchCmd.Flags().Int("jobs", 0, "jobs of each kind run on each host (default 20)")
+	adminBenchCmd.Flags().Int("concurrency", 0, "jobs running at once on a host (default 4)")
+	adminBenchCmd.Flags().StringSlice("kinds", nil, "kinds of job: noop, unzip (default both)")
+	adminBenchCmd.Flags().Int("zip-files", 0, "files of 16 KB in the synthetic upload of the unzip jobs (default 64)")
+	adminBenchCmd.Flags().Bool("detach", false, "start the benchmark without waiting for its report")
+
+	adminUsersPasswordCmd.Flags().String("new-password-file", "", "file with the new password, - for stdin")
+
+	adminTokensDeactivateCmd.Flags().String("token-file", "", "file with the token, - for stdin")
+
+	adminDockerHostsPrepullCmd.Flags().Bool("refresh", false, "pull the images the hosts already have too")
+}
+
+// adminCredentials returns the API user credentials of the --username and
+// --password flags or of the HUSKYCI_ADMIN_USERNAME and HUSKYCI_ADMIN_PASSWORD
+// environment variables.
+func adminCredentials(cmd *cobra.Command) (string, string, error) {
+	username, _ := cmd.Flags().GetString("username")
+	if username == "" {
+		username = os.Getenv("HUSKYCI_ADMIN_USERNAME")
+	}
+	password, _ := cmd.Flags().GetString("password")
+	if password == "" {
+		password = os.Getenv("HUSKYCI_ADMIN_PASSWORD")
+	}
+	if username == "" || password == "" {
+		return "", "", errors.New(i18n.T("admin credentials are required\n\nTip: use --username/--password or set HUSKYCI_ADMIN_USERNAME and HUSKYCI_ADMIN_PASSWORD"))
+	}
+	return username, password, nil
+}
+
+// adminClient returns a huskyCI API client of the current target authenticated
+// with the API user credentials.
+func adminClient(cmd *cobra.Command) (*apiclient.Client, error) {
+	username, password, err := adminCredentials(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	client, _, err := newAPIClient()
+	if err != nil {
+		return nil, err
+	}
+	client.Username = username
+	client.Password = password
+	return client, nil
+}
+
+// readSecret returns the trimmed content of file, or of the standard input
+// when file is -.
+func readSecret(file string) (string, error) {
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/huskyci-org/huskyCI/cli/config"
+	"github.com/huskyci-org/huskyCI/cli/log"
+	"github.com/huskyci-org/huskyCI/cli/types"
+	"github.com/huskyci-org/huskyCI/pkg/apiclient"
+	"github.com/huskyci-org/huskyCI/pkg/i18n"
+)
+
+// newAPIClient returns a huskyCI API client of the current target.
+func newAPIClient() (*apiclient.Client, *types.Target, error) {
+	target, err := config.GetCurrentTarget()
+	if err != nil {
+		return nil, nil, err
+	}
+	if target.Endpoint == "" {
+		return nil, nil, errors.New(i18n.T("no huskyCI API target configured\n\nTip: use 'huskyci target-add <name> <endpoint>' or set HUSKYCI_CLIENT_API_ADDR"))
+	}
+	httpClient, err := createHTTPClient(target)
+	if err != nil {
+		return nil, nil, err
+	}
+	RequestTimeouts().Apply(httpClient)
+	httpClient.Transport = log.Transport(httpClient.Transport)
+
+	client := apiclient.New(target.Endpoint)
+	client.HTTPClient = httpClient
+	client.UserAgent = config.UserAgent()
+	return client, target, nil
+}
+
+// tokenClient returns a huskyCI API client of the current target authenticated with its token.
+func tokenClient() (*apiclient.Client, error) {
+	client, target, err := newAPIClient()
+	if err != nil {
+		return nil, err
+	}
+	client.Token = target.Token
+	return client, nil
+}
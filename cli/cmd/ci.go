@@ -0,0 +1,256 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/cli/analysis"
+	"github.com/huskyci-org/huskyCI/cli/ci"
+	"github.com/huskyci-org/huskyCI/cli/config"
+	"github.com/huskyci-org/huskyCI/cli/errorcli"
+	"github.com/huskyci-org/huskyCI/pkg/apiclient"
+	"github.com/huskyci-org/huskyCI/pkg/formatter"
+	"github.com/huskyci-org/huskyCI/pkg/i18n"
+	"github.com/huskyci-org/huskyCI/pkg/sdk"
+	"github.com/spf13/cobra"
+)
+
+// exitVulnerabilities is the exit code of an analysis that found
+// vulnerabilities of at least the --fail-on severity, as huskyci-client does.
+const exitVulnerabilities = 190
+
+// ciCmd represents the ci command
+var ciCmd = &cobra.Command{
+	Use:   "ci [path]",
+	Short: "Run a security analysis from a CI pipeline",
+	Long: `Run a security analysis of the repository built by a CI pipeline.
+
+The branch, pull request and workspace are read from the variables set by
+GitHub Actions and GitLab CI, and the vulnerabilities found are reported in the
+native format of the CI:
+  GitHub Actions  annotations on the pull request and a job summary
+  GitLab CI       a Code Quality report, to be declared as an artifact
+
+The path defaults to the workspace of the CI. The command exits with code 190
+when vulnerabilities of at least the --fail-on severity are found, and 1 when the
+analysis could not run. Without --fail-on, the severity is the one set by the
+policy of the repository on the huskyCI API, medium if it has none.
+
+The results of the last successful analysis are cached, and reused instead of
+sending the code again when neither the code, the documentation and the .git
+folder aside, nor the policy of the repository changed since, such as when
+only the docs changed. They are cached per huskyCI API and repository in
+HUSKYCI_CLI_CACHE_DIR, which the CI can keep between its jobs, or else in
+$HOME/.huskyci/cache. --no-cache always sends the code.
+
+With --tests, only the securityTests listed run, as long as they include the
+ones the policy of the repository requires.
+
+The huskyCI API is set with the HUSKYCI_CLIENT_API_ADDR and HUSKYCI_CLI_TOKEN
+variables, or with the current target.
+
+Examples:
+  # Fail on medium and high severity vulnerabilities
+  huskyci ci
+
+  # Only fail on high severity vulnerabilities
+  huskyci ci --fail-on high
+
+  # Report the vulnerabilities without failing
+  huskyci ci --fail-on none
+
+  # Analyze the code again even if it did not change
+  huskyci ci --no-cache
+
+  # Only run gosec and gitleaks, for a faster analysis
+  huskyci ci --tests gosec,gitleaks`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		failOn, _ := cmd.Flags().GetString("fail-on")
+		if failOn != "" && failOn != "none" && failOn != sdk.FailOnNever && analysis.SeverityRank(failOn) == 0 {
+			return i18n.Errorf("invalid --fail-on severity '%s': use high, medium, low or none", failOn)
+		}
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		reportPath, _ := cmd.Flags().GetString("codequality-report")
+		noCache, _ := cmd.Flags().GetBool("no-cache")
+		securityTests, _ := cmd.Flags().GetStringSlice("tests")
+
+		env := ci.Detect(os.Getenv)
+		policy, policyErr := repositoryPolicy(cmd.Context(), env.RepositoryURL)
+		if failOn == "" {
+			failOn = sdk.DefaultFailOn
+			if policyErr != nil {
+				i18n.Fprintf(formatter.Stderr, "[HUSKYCI] Could not retrieve the policy of the repository, failing on %s: %s\n", failOn, policyErr)
+			} else if sdk.ValidFailOn(policy.FailOn) {
+				failOn = policy.FailOn
+			}
+		}
+		pathReceived := "."
+		switch {
+		case len(args) == 1:
+			pathReceived = args[0]
+		case env.Workspace != "":
+			pathReceived = env.Workspace
+		}
+
+		i18n.Printf("[HUSKYCI] Running on %s\n", env.Name())
+		if env.RepositoryURL != "" {
+			i18n.Printf("   Repository:   %s\n", env.RepositoryURL)
+		}
+		if env.Branch != "" {
+			i18n.Printf("   Branch:       %s\n", env.Branch)
+		}
+		if env.PullRequest != "" {
+			i18n.Printf("   Pull request: #%s\n", env.PullRequest)
+		}
+
+		currentAnalysis := analysis.New()
+		currentAnalysis.Branch = env.Branch
+		currentAnalysis.SecurityTests = securityTests
+		analysis.SetTimeouts(RequestTimeouts())
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		if err := currentAnalysis.CheckPath(pathReceived); err != nil {
+			errorcli.Handle(err)
+		}
+		cacheKey, cacheable := ciCacheKey(env, pathReceived, securityTests, policy)
+		cacheable = cacheable && !noCache && policyErr == nil
+		var cached *analysis.Analysis
+		if cacheable {
+			cached, _ = analysis.CachedAnalysis(cacheKey)
+		}
+		if cached != nil {
+			i18n.Printf("\n♻️  Reusing the results of analysis %s, as neither the code nor the policy changed since\n", cached.RID)
+			currentAnalysis = cached
+			currentAnalysis.PrintVulns()
+		} else {
+			if err := currentAnalysis.CompressFiles(pathReceived); err != nil {
+				errorcli.Handle(err)
+			}
+			if err := currentAnalysis.SendZip(ctx); err != nil {
+				errorcli.Handle(err)
+			}
+			if err := currentAnalysis.CheckStatus(ctx, timeout); err != nil {
+				errorcli.Handle(err)
+			}
+			currentAnalysis.PrintVulns()
+			if err := currentAnalysis.HouseCleaning(); err != nil {
+				errorcli.Handle(err)
+			}
+			if cacheable {
+				if err := currentAnalysis.Cache(cacheKey); err != nil {
+					i18n.Fprintf(formatter.Stderr, "[HUSKYCI] Could not cache the results of the analysis: %s\n", err)
+				}
+			}
+		}
+
+		if err := reportCI(env, currentAnalysis, reportPath); err != nil {
+			errorcli.Handle(err)
+		}
+
+		if failOn != "none" && failOn != sdk.FailOnNever {
+			if found := currentAnalysis.CountAtLeast(failOn); found > 0 {
+				i18n.Fprintf(formatter.Stderr, "\n[HUSKYCI] ❌ %d vulnerabilities of %s severity or higher found\n", found, failOn)
+				os.Exit(exitVulnerabilities)
+			}
+		}
+		return nil
+	},
+}
+
+// repositoryPolicy returns the policy of repositoryURL on the huskyCI API,
+// which is empty if it has none or repositoryURL is empty.
+func repositoryPolicy(ctx context.Context, repositoryURL string) (apiclient.Policy, error) {
+	if repositoryURL == "" {
+		return apiclient.Policy{}, nil
+	}
+	api, err := tokenClient()
+	if err != nil {
+		return apiclient.Policy{}, err
+	}
+	return sdk.New(api).GetPolicy(ctx, repositoryURL)
+}
+
+// ciCacheKey returns the key of the results of the analysis of path on the
+// current target, cached per repository, or per path outside of a known
+// repository, and per selection of securityTests. It returns false if the
+// results cannot be cached.
+func ciCacheKey(env ci.Environment, path string, securityTests []string, policy apiclient.Policy) (analysis.CacheKey, bool) {
+	target, err := config.GetCurrentTarget()
+	if err != nil || target.Endpoint == "" {
+		return analysis.CacheKey{}, false
+	}
+	scope := env.RepositoryURL
+	if scope == "" {
+		if scope, err = filepath.Abs(path); err != nil {
+			return analysis.CacheKey{}, false
+		}
+	}
+	if len(securityTests) > 0 {
+		selected := make([]string, len(securityTests))
+		for i, name := range securityTests {
+			selected[i] = strings.ToLower(strings.TrimSpace(name))
+		}
+		sort.Strings(selected)
+		scope += "\x00" + strings.Join(selected, ",")
+	}
+	treeHash, err := analysis.TreeHash(path)
+	if err != nil {
+		return analysis.CacheKey{}, false
+	}
+	key := analysis.CacheKey{Endpoint: target.Endpoint, Scope: scope, TreeHash: treeHash}
+	if !policy.UpdatedAt.IsZero() {
+		key.PolicyVersion = policy.UpdatedAt.UTC().Format(time.RFC3339Nano)
+	}
+	return key, true
+}
+
+// reportCI reports the vulnerabilities of a in the native format of the CI env.
+func reportCI(env ci.Environment, a *analysis.Analysis, reportPath string) error {
+	switch env.Provider {
+	case ci.GitHubActions:
+		formatter.Println()
+		if err := a.WriteGitHubAnnotations(os.Stdout); err != nil {
+			return err
+		}
+		summaryPath := os.Getenv("GITHUB_STEP_SUMMARY")
+		if summaryPath == "" {
+			return nil
+		}
+		summary, err := os.OpenFile(summaryPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return i18n.Errorf("error writing the job summary: %w", err)
+		}
+		defer summary.Close()
+		return a.WriteGitHubSummary(summary)
+	case ci.GitLabCI:
+		report, err := os.Create(reportPath)
+		if err != nil {
+			return i18n.Errorf("error writing the Code Quality report: %w", err)
+		}
+		defer report.Close()
+		if err := a.WriteCodeQuality(report); err != nil {
+			return i18n.Errorf("error writing the Code Quality report: %w", err)
+		}
+		i18n.Printf("\n✓ Code Quality report written to %s\n", reportPath)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(ciCmd)
+
+	ciCmd.Flags().String("fail-on", "", "lowest severity of the vulnerabilities failing the pipeline: high, medium, low or none (default is the policy of the repository, or medium)")
+	ciCmd.Flags().Duration("timeout", sdk.DefaultWaitTimeout, "time to wait for the analysis to finish")
+	ciCmd.Flags().String("codequality-report", "gl-code-quality-report.json", "path of the Code Quality report written on GitLab CI")
+	ciCmd.Flags().StringSlice("tests", nil, "securityTests to run, such as gosec,gitleaks (default is all of them)")
+	ciCmd.Flags().Bool("no-cache", false, "send the code even if it did not change since the last successful analysis")
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/huskyci-org/huskyCI/cli/analysis"
+	"github.com/huskyci-org/huskyCI/cli/errorcli"
+	"github.com/huskyci-org/huskyCI/cli/fix"
+	"github.com/huskyci-org/huskyCI/cli/types"
+	"github.com/huskyci-org/huskyCI/pkg/formatter"
+	"github.com/huskyci-org/huskyCI/pkg/i18n"
+	"github.com/huskyci-org/huskyCI/pkg/sdk"
+	"github.com/spf13/cobra"
+)
+
+// fixCmd represents the fix command
+var fixCmd = &cobra.Command{
+	Use:   "fix [path]",
+	Short: "Upgrade the vulnerable dependencies of a project",
+	Long: `Upgrade the dependencies huskyCI found vulnerable to the lowest version
+fixing their advisories, in the requirements files and package.json manifests
+of a project.
+
+The vulnerabilities are those of a new analysis of the path, which defaults to
+the current directory, or of an analysis already stored in the huskyCI API with
+--rid. Dependencies reported by safety, npm audit and yarn audit are upgraded;
+transitive dependencies no manifest declares are only listed.
+
+Examples:
+  # Print the changes without writing them
+  huskyci fix --dry-run
+
+  # Apply the fixes of a past analysis of the current directory
+  huskyci fix --rid 4f6b1c1e-8a55-4b4c-9d7a-0c2b9a1f3e21`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pathReceived := "."
+		if len(args) == 1 {
+			pathReceived = args[0]
+		}
+		RID, _ := cmd.Flags().GetString("rid")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+
+		analysis.SetTimeouts(RequestTimeouts())
+
+		var currentAnalysis *analysis.Analysis
+		if RID != "" {
+			api, err := tokenClient()
+			if err != nil {
+				return err
+			}
+			result, err := sdk.New(api).GetAnalysis(cmd.Context(), RID)
+			if err != nil {
+				return err
+			}
+			apiAnalysis := types.Analysis{}
+			if err := sdk.Decode(result, &apiAnalysis); err != nil {
+				return err
+			}
+			currentAnalysis = analysis.FromAPI(apiAnalysis)
+		} else {
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			currentAnalysis = analysis.New()
+			if err := currentAnalysis.CheckPath(pathReceived); err != nil {
+				errorcli.Handle(err)
+			}
+			if err := currentAnalysis.CompressFiles(pathReceived); err != nil {
+				errorcli.Handle(err)
+			}
+			if err := currentAnalysis.SendZip(ctx); err != nil {
+				errorcli.Handle(err)
+			}
+			if err := currentAnalysis.CheckStatus(ctx, timeout); err != nil {
+				errorcli.Handle(err)
+			}
+			if err := currentAnalysis.HouseCleaning(); err != nil {
+				errorcli.Handle(err)
+			}
+		}
+
+		suggestions := fix.Suggestions(currentAnalysis.Vulnerabilities)
+		if len(suggestions) == 0 {
+			formatter.Println(i18n.T("\n[HUSKYCI] ✓ No dependency upgrade to suggest"))
+			return nil
+		}
+		changes, unmatched, err := fix.Plan(pathReceived, suggestions)
+		if err != nil {
+			return err
+		}
+		printFixChanges(pathReceived, changes, unmatched)
+
+		if dryRun || len(changes) == 0 {
+			return nil
+		}
+		if err := fix.Apply(changes); err != nil {
+			return err
+		}
+		i18n.Printf("\n[HUSKYCI] ✓ %d dependencies upgraded\n", len(changes))
+		for _, change := range changes {
+			if filepath.Base(change.File) == "package.json" {
+				formatter.Println(i18n.T("Tip: run npm install or yarn install to update the lock file"))
+				break
+			}
+		}
+		return nil
+	},
+}
+
+// printFixChanges prints the manifest lines changed by the fixes, relative to
+// root, and the upgrades no manifest could take.
+func printFixChanges(root string, changes []fix.Change, unmatched []fix.Suggestion) {
+	i18n.Printf("\n[HUSKYCI] %d manifest changes fix the vulnerable dependencies:\n", len(changes))
+	for _, change := range changes {
+		file, err := filepath.Rel(root, change.File)
+		if err != nil {
+			file = change.File
+		}
+		formatter.Printf("\n%s:%d\n", file, change.Line)
+		formatter.Printf("  - %s\n", change.Old)
+		formatter.Printf("  + %s\n", change.New)
+	}
+	for _, suggestion := range unmatched {
+		i18n.Printf("\n⚠️  %s (%s) is not declared by any manifest: upgrade the dependency requiring it so that it gets %s or later\n",
+			suggestion.Package, suggestion.Ecosystem, suggestion.Version)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(fixCmd)
+
+	fixCmd.Flags().String("rid", "", "use the results of this analysis instead of running a new one")
+	fixCmd.Flags().Bool("dry-run", false, "print the changes without writing them")
+	fixCmd.Flags().Duration("timeout", sdk.DefaultWaitTimeout, "time to wait for the analysis to finish")
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/huskyci-org/huskyCI/pkg/apiclient"
+	"github.com/huskyci-org/huskyCI/pkg/i18n"
+	"github.com/spf13/cobra"
+)
+
+// listCmd represents the list command
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List past analyses",
+	Long: `List the analyses stored in the huskyCI API, newest first.
+
+Without --repo, only the analyses of the repository the current token was
+generated for are listed, or every analysis for a generic token.
+
+Examples:
+  # List the last analyses
+  huskyci list
+
+  # List the running analyses of a branch
+  huskyci list --repo https://github.com/org/repo.git --branch main --status running
+
+  # List the analyses started in January, 50 per page
+  huskyci list --from 2026-01-01 --to 2026-01-31 --page-size 50 --page 2`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		params := &apiclient.ListAnalysesParams{}
+		params.Repo, _ = cmd.Flags().GetString("repo")
+		params.Branch, _ = cmd.Flags().GetString("branch")
+		params.Status, _ = cmd.Flags().GetString("status")
+		params.From, _ = cmd.Flags().GetString("from")
+		params.To, _ = cmd.Flags().GetString("to")
+		params.Sort, _ = cmd.Flags().GetString("sort")
+		params.Page, _ = cmd.Flags().GetInt("page")
+		params.PageSize, _ = cmd.Flags().GetInt("page-size")
+
+		client, err := tokenClient()
+		if err != nil {
+			return err
+		}
+		list, err := client.ListAnalyses(cmd.Context(), params)
+		if err != nil {
+			return err
+		}
+		if len(list.Analyses) == 0 {
+			fmt.Println(i18n.T("No analyses found."))
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "RID\tREPOSITORY\tBRANCH\tSTATUS\tRESULT\tSTARTED")
+		for _, analysis := range list.Analyses {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", analysis.RID, analysis.URL, analysis.Branch,
+				analysis.Status, analysis.Result, analysis.StartedAt.Local().Format("2006-01-02 15:04"))
+		}
+		w.Flush()
+		pages := (list.Total + int64(list.PageSize) - 1) / int64(list.PageSize)
+		i18n.Printf("\nPage %d of %d (%d analyses)\n", list.Page, pages, list.Total)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+
+	listCmd.Flags().String("repo", "", "only list analyses of this repository URL")
+	listCmd.Flags().String("branch", "", "only list analyses of this branch")
+	listCmd.Flags().String("status", "", "only list analyses with this status, such as running or finished")
+	listCmd.Flags().String("from", "", "only list analyses started after this date (YYYY-MM-DD or RFC 3339)")
+	listCmd.Flags().String("to", "", "only list analyses started before this date (YYYY-MM-DD or RFC 3339)")
+	listCmd.Flags().String("sort", "", "sort by startedAt, finishedAt, repositoryURL or status; prefix with - for descending order (default -startedAt)")
+	listCmd.Flags().Int("page", 1, "page to list")
+	listCmd.Flags().Int("page-size", 20, "analyses per page, up to 100")
+}
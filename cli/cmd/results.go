@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/huskyci-org/huskyCI/cli/analysis"
+	"github.com/huskyci-org/huskyCI/cli/types"
+	"github.com/huskyci-org/huskyCI/pkg/apiclient"
+	"github.com/huskyci-org/huskyCI/pkg/i18n"
+	"github.com/huskyci-org/huskyCI/pkg/sdk"
+	"github.com/spf13/cobra"
+)
+
+// resultsCmd represents the results command
+var resultsCmd = &cobra.Command{
+	Use:   "results [RID]",
+	Short: "Show the results of past analyses",
+	Long: `Show the vulnerabilities found by an analysis already stored in the
+huskyCI API, or by the last analyses of a repository.
+
+Examples:
+  # Show the results of an analysis
+  huskyci results 4f6b1c1e-8a55-4b4c-9d7a-0c2b9a1f3e21
+
+  # Show the results of the last 5 analyses of a repository
+  huskyci results --repo https://github.com/org/repo.git --last 5
+
+  # Export the last analysis of a branch as SARIF
+  huskyci results --repo https://github.com/org/repo.git --branch main --sarif > huskyci.sarif
+
+  # Show what the gosec container printed, such as when it ended in error
+  huskyci results 4f6b1c1e-8a55-4b4c-9d7a-0c2b9a1f3e21 --output gosec`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo, _ := cmd.Flags().GetString("repo")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		asSARIF, _ := cmd.Flags().GetBool("sarif")
+		if asJSON && asSARIF {
+			return errors.New(i18n.T("--json and --sarif can not be used together"))
+		}
+
+		api, err := tokenClient()
+		if err != nil {
+			return err
+		}
+		client := sdk.New(api)
+
+		if output, _ := cmd.Flags().GetString("output"); output != "" {
+			if len(args) != 1 || repo != "" || asJSON || asSARIF {
+				return errors.New(i18n.T("--output needs a RID and can not be used with --repo, --json or --sarif"))
+			}
+			rawOutput, err := api.GetContainerOutput(cmd.Context(), args[0], output)
+			if err != nil {
+				return err
+			}
+			fmt.Print(rawOutput)
+			return nil
+		}
+
+		var RIDs []string
+		switch {
+		case len(args) == 1 && repo != "":
+			return errors.New(i18n.T("use either a RID or --repo, not both"))
+		case len(args) == 1:
+			RIDs = args
+		case repo != "":
+			last, _ := cmd.Flags().GetInt("last")
+			if last < 1 || last > 100 {
+				return errors.New(i18n.T("--last must be between 1 and 100"))
+			}
+			params := &apiclient.ListAnalysesParams{Repo: repo, PageSize: last}
+			params.Branch, _ = cmd.Flags().GetString("branch")
+			list, err := api.ListAnalyses(cmd.Context(), params)
+			if err != nil {
+				return err
+			}
+			for _, summary := range list.Analyses {
+				RIDs = append(RIDs, summary.RID)
+			}
+			if len(RIDs) == 0 {
+				return i18n.Errorf("no analyses found for repository %s", repo)
+			}
+		default:
+			return errors.New(i18n.T("a RID or --repo is required\n\nExample: huskyci results <RID> or huskyci results --repo <url> --last 5"))
+		}
+
+		apiAnalyses := []types.Analysis{}
+		analyses := []*analysis.Analysis{}
+		for _, RID := range RIDs {
+			result, err := client.GetAnalysis(cmd.Context(), RID)
+			if err != nil {
+				return err
+			}
+			apiAnalysis := types.Analysis{}
+			if err := sdk.Decode(result, &apiAnalysis); err != nil {
+				return err
+			}
+			apiAnalyses = append(apiAnalyses, apiAnalysis)
+			analyses = append(analyses, analysis.FromAPI(apiAnalysis))
+		}
+
+		switch {
+		case asSARIF:
+			return analysis.WriteSARIF(os.Stdout, analyses)
+		case asJSON:
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if len(analyses) == 1 && len(args) == 1 {
+				return encoder.Encode(analyses[0])
+			}
+			return encoder.Encode(analyses)
+		}
+
+		for i, a := range analyses {
+			apiAnalysis := apiAnalyses[i]
+			i18n.Printf("\n🔎 Analysis %s\n", apiAnalysis.RID)
+			if apiAnalysis.URL != "" {
+				i18n.Printf("   Repository: %s (%s)\n", apiAnalysis.URL, apiAnalysis.Branch)
+			}
+			if !apiAnalysis.StartedAt.IsZero() {
+				i18n.Printf("   Started at: %s\n", apiAnalysis.StartedAt.Local().Format("2006-01-02 15:04:05"))
+			}
+			a.PrintVulns()
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(resultsCmd)
+
+	resultsCmd.Flags().String("repo", "", "show the last analyses of this repository URL")
+	resultsCmd.Flags().String("branch", "", "only consider analyses of this branch (with --repo)")
+	resultsCmd.Flags().Int("last", 1, "how many analyses of the repository to show (with --repo)")
+	resultsCmd.Flags().Bool("json", false, "print the results as JSON")
+	resultsCmd.Flags().Bool("sarif", false, "print the results as a SARIF 2.1.0 log")
+	resultsCmd.Flags().String("output", "", "print the raw output of the container of this securityTest of the analysis")
+}
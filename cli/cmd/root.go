@@ -5,17 +5,26 @@
 package cmd
 
 import (
-	"fmt"
 	"os"
+	"strconv"
 
 	"github.com/huskyci-org/huskyCI/cli/config"
+	"github.com/huskyci-org/huskyCI/cli/errorcli"
+	"github.com/huskyci-org/huskyCI/cli/log"
+	"github.com/huskyci-org/huskyCI/pkg/formatter"
+	"github.com/huskyci-org/huskyCI/pkg/i18n"
+	"github.com/huskyci-org/huskyCI/pkg/sdk"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var (
-	cfgFile string
-	verbose bool
+	cfgFile   string
+	verbosity int
+	quiet     bool
+	logFormat string
+	plain     plainValue
+	timeouts  sdk.Timeouts
 
 	rootCmd = &cobra.Command{
 		Use:   "huskyci",
@@ -39,7 +48,7 @@ Examples:
   # Run a security analysis on a local directory
   huskyci run ./my-project
 
-  # Run with verbose output for debugging
+  # Run with verbose output for debugging, -vv for the requests too
   huskyci run ./my-project --verbose
 
   # Add a new API target
@@ -63,16 +72,50 @@ func init() {
 	cobra.OnInitialize(initConfig)
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.huskyci/config.yaml)")
-	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "enable verbose output for debugging")
+	rootCmd.PersistentFlags().CountVarP(&verbosity, "verbose", "v", "print debugging logs, -vv for the requests sent too")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "print only results and errors")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", log.FormatText, "format of the logs printed to stderr, text or json")
+	rootCmd.PersistentFlags().Var(&plain, "plain", "print without emoji, unicode symbols or colors (also set by HUSKYCI_NO_EMOJI)")
+	rootCmd.PersistentFlags().Lookup("plain").NoOptDefVal = "true"
+	rootCmd.PersistentFlags().DurationVar(&timeouts.Connect, "connect-timeout", sdk.DefaultConnectTimeout, "time allowed to connect to the huskyCI API")
+	rootCmd.PersistentFlags().DurationVar(&timeouts.Read, "read-timeout", sdk.DefaultReadTimeout, "time allowed for the huskyCI API to start answering a request")
+	rootCmd.SetOut(formatter.Stdout)
+	rootCmd.SetErr(formatter.Stderr)
 }
 
-// IsVerbose returns whether verbose mode is enabled
-func IsVerbose() bool {
-	return verbose
+// plainValue is the value of --plain, which turns plain mode on as soon as
+// it is parsed, so that the help of commands is printed plain too.
+type plainValue bool
+
+func (p *plainValue) String() string {
+	return strconv.FormatBool(bool(*p))
+}
+
+func (p *plainValue) Set(value string) error {
+	on, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	*p = plainValue(on)
+	formatter.SetPlain(on)
+	return nil
+}
+
+func (p *plainValue) Type() string {
+	return "bool"
+}
+
+// RequestTimeouts returns the connect and read timeouts of the requests sent to the huskyCI API
+func RequestTimeouts() sdk.Timeouts {
+	return timeouts
 }
 
 // initConfig reads in config file and ENV variables if set.
 func initConfig() {
+	if err := log.Configure(verbosity, quiet, logFormat); err != nil {
+		errorcli.Handle(err)
+	}
+
 	// Skip initialization messages when generating completion scripts
 	isCompletion := false
 	for _, arg := range os.Args {
@@ -89,7 +132,7 @@ func initConfig() {
 		// Find home directory.
 		home, err := os.UserHomeDir()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Client error reading home folder: %s (%s)\n", home, err.Error())
+			i18n.Fprintf(formatter.Stderr, "Client error reading home folder: %s (%s)\n", home, err.Error())
 			os.Exit(1)
 		}
 
@@ -122,10 +165,10 @@ func initConfig() {
 	// If a config file is found, read it in.
 	err := viper.ReadInConfig()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Client error reading config file (%s)\n", err.Error())
+		i18n.Fprintf(formatter.Stderr, "Client error reading config file (%s)\n", err.Error())
 		os.Exit(1)
 	}
 	if !isCompletion {
-		fmt.Fprintf(os.Stderr, "Using config file: %s\n\n", viper.ConfigFileUsed())
+		log.Info("Using config file", "path", viper.ConfigFileUsed())
 	}
 }
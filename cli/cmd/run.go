@@ -1,11 +1,19 @@
 package cmd
 
 import (
+	"context"
 	"errors"
-	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/huskyci-org/huskyCI/cli/analysis"
+	"github.com/huskyci-org/huskyCI/cli/config"
 	"github.com/huskyci-org/huskyCI/cli/errorcli"
+	"github.com/huskyci-org/huskyCI/pkg/formatter"
+	"github.com/huskyci-org/huskyCI/pkg/i18n"
+	"github.com/huskyci-org/huskyCI/pkg/sdk"
 	"github.com/spf13/cobra"
 )
 
@@ -30,10 +38,36 @@ Examples:
   huskyci run ./my-project
 
   # Analyze a specific subdirectory
-  huskyci run ./src/main`,
+  huskyci run ./src/main
+
+  # Wait up to 2 hours for a large codebase
+  huskyci run . --timeout 2h
+
+  # Analyze on every configured target at once and compare the results
+  huskyci run . --all-targets
+
+  # Analyze on the prod and staging targets only
+  huskyci run . --targets prod,staging
+
+  # Analyze without the huskyCI API, with the local Docker daemon
+  huskyci run . --local
+
+  # Only run gosec and gitleaks, for a faster analysis
+  huskyci run . --tests gosec,gitleaks
+
+Interrupting the command with Ctrl+C cancels the analysis on the huskyCI API.
+
+With --tests, only the securityTests listed run, as long as they include the
+ones the policy of the repository requires. The analysis then warns that the
+findings of the other securityTests are missing.
+
+With --local, the securityTests huskyCI can parse outside of its API
+(bandit, brakeman, gitleaks, gosec, trivy and trivyconfig) run on the
+directory in containers of the local Docker daemon, with the command
+templates of the huskyCI API configuration, and nothing is sent to the API.`,
 	Args: func(cmd *cobra.Command, args []string) error {
 		if len(args) < 1 {
-			return errors.New("path argument is required\n\nExample: huskyci run ./my-project")
+			return errors.New(i18n.T("path argument is required\n\nExample: huskyci run ./my-project"))
 		}
 		return nil
 	},
@@ -42,30 +76,55 @@ Examples:
 		pathReceived := args[0]
 		currentAnalysis := analysis.New()
 
-		// Set verbose mode from flag
-		analysis.SetVerbose(IsVerbose())
+		analysis.SetTimeouts(RequestTimeouts())
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		allTargets, _ := cmd.Flags().GetBool("all-targets")
+		targetNames, _ := cmd.Flags().GetStringSlice("targets")
+		currentAnalysis.Local, _ = cmd.Flags().GetBool("local")
+		currentAnalysis.LocalConfig, _ = cmd.Flags().GetString("local-config")
+		currentAnalysis.SecurityTests, _ = cmd.Flags().GetStringSlice("tests")
+		if currentAnalysis.Local && (allTargets || len(targetNames) > 0) {
+			return errors.New(i18n.T("--local can not be used with --all-targets or --targets"))
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
 
-		fmt.Println()
+		formatter.Println()
 		if err := currentAnalysis.CheckPath(pathReceived); err != nil {
 			errorcli.Handle(err)
 		}
 
-		fmt.Println()
+		if currentAnalysis.Local {
+			formatter.Println()
+			if err := currentAnalysis.RunLocal(ctx); err != nil {
+				errorcli.Handle(err)
+			}
+			formatter.Println()
+			currentAnalysis.PrintVulns()
+			return nil
+		}
+
+		formatter.Println()
 		if err := currentAnalysis.CompressFiles(pathReceived); err != nil {
 			errorcli.Handle(err)
 		}
 
-		fmt.Println()
-		if err := currentAnalysis.SendZip(); err != nil {
+		if allTargets || len(targetNames) > 0 {
+			return runOnTargets(ctx, currentAnalysis, targetNames, timeout)
+		}
+
+		formatter.Println()
+		if err := currentAnalysis.SendZip(ctx); err != nil {
 			errorcli.Handle(err)
 		}
 
-		fmt.Println()
-		if err := currentAnalysis.CheckStatus(); err != nil {
+		formatter.Println()
+		if err := currentAnalysis.CheckStatus(ctx, timeout); err != nil {
 			errorcli.Handle(err)
 		}
 
-		fmt.Println()
+		formatter.Println()
 		currentAnalysis.PrintVulns()
 
 		if err := currentAnalysis.HouseCleaning(); err != nil {
@@ -76,6 +135,47 @@ Examples:
 	},
 }
 
+// runOnTargets analyzes the code compressed for currentAnalysis on the
+// targets named, or on every target if there are none, and prints their
+// results side by side.
+func runOnTargets(ctx context.Context, currentAnalysis *analysis.Analysis, names []string, timeout time.Duration) error {
+	targets, err := config.GetTargets(names)
+	if err != nil {
+		errorcli.Handle(err)
+	}
+
+	i18n.Printf("\n🚀 Sending code to %d huskyCI API targets...\n", len(targets))
+	runs := analysis.RunOnTargets(ctx, currentAnalysis, targets, timeout)
+
+	formatter.Println(i18n.T("\n📊 Results by target:"))
+	formatter.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	if err := analysis.WriteComparison(formatter.Stdout, runs); err != nil {
+		errorcli.Handle(err)
+	}
+
+	if err := currentAnalysis.HouseCleaning(); err != nil {
+		errorcli.Handle(err)
+	}
+
+	failed := 0
+	for _, run := range runs {
+		if run.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return i18n.Errorf("the analysis failed on %d of %d targets", failed, len(runs))
+	}
+	return nil
+}
+
 func init() {
 	rootCmd.AddCommand(runCmd)
+
+	runCmd.Flags().Duration("timeout", sdk.DefaultWaitTimeout, "time to wait for the analysis to finish")
+	runCmd.Flags().Bool("all-targets", false, "analyze on every configured target at once and compare the results")
+	runCmd.Flags().StringSlice("targets", nil, "analyze on these configured targets at once and compare the results, such as prod,staging")
+	runCmd.Flags().Bool("local", false, "run the securityTests with the local Docker daemon instead of the huskyCI API")
+	runCmd.Flags().StringSlice("tests", nil, "securityTests to run, such as gosec,gitleaks (default is all of them)")
+	runCmd.Flags().String("local-config", "", "huskyCI API configuration file of the securityTests run with --local, instead of the one the CLI ships with")
 }
@@ -14,9 +14,13 @@ import (
 	"regexp"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/huskyci-org/huskyCI/cli/config"
+	"github.com/huskyci-org/huskyCI/cli/types"
 	"github.com/huskyci-org/huskyCI/cli/util"
+	"github.com/huskyci-org/huskyCI/pkg/formatter"
+	"github.com/huskyci-org/huskyCI/pkg/i18n"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -114,32 +118,32 @@ func (w *setupWizard) run() error {
 // ============================================================================
 
 func (w *setupWizard) printWelcome() {
-	fmt.Println()
-	fmt.Println(separatorLine)
-	fmt.Println("  🐕  Welcome to huskyCI Setup Wizard!")
-	fmt.Println(separatorLine)
-	fmt.Println()
-	fmt.Println("This wizard will help you configure huskyCI CLI to work with your huskyCI API.")
-	fmt.Println()
+	formatter.Println()
+	formatter.Println(separatorLine)
+	formatter.Println(i18n.T("  🐕  Welcome to huskyCI Setup Wizard!"))
+	formatter.Println(separatorLine)
+	formatter.Println()
+	formatter.Println(i18n.T("This wizard will help you configure huskyCI CLI to work with your huskyCI API."))
+	formatter.Println()
 }
 
 func (w *setupWizard) printSection(title string) {
-	fmt.Println()
-	fmt.Println(title)
-	fmt.Println(strings.Repeat("─", len(title)))
-	fmt.Println()
+	formatter.Println()
+	formatter.Println(title)
+	formatter.Println(strings.Repeat("─", utf8.RuneCountInString(title)))
+	formatter.Println()
 }
 
 func (w *setupWizard) printSuccess(message string) {
-	fmt.Printf("✓ %s\n", message)
+	formatter.Printf("✓ %s\n", message)
 }
 
 func (w *setupWizard) printWarning(message string) {
-	fmt.Printf("⚠️  %s\n", message)
+	formatter.Printf("⚠️  %s\n", message)
 }
 
 func (w *setupWizard) printError(message string) {
-	fmt.Printf("✗ %s\n", message)
+	formatter.Printf("✗ %s\n", message)
 }
 
 // ============================================================================
@@ -162,22 +166,22 @@ const (
 
 func (w *setupWizard) showMenu(title string, options []menuOption) menuResult {
 	for {
-		fmt.Println()
+		formatter.Println()
 		if title != "" {
-			fmt.Println(title)
+			formatter.Println(title)
 		}
 		for _, opt := range options {
-			fmt.Printf("  %s. %s\n", opt.key, opt.description)
+			formatter.Printf("  %s. %s\n", opt.key, opt.description)
 		}
-		fmt.Println()
-		fmt.Print("Enter your choice: ")
+		formatter.Println()
+		formatter.Print(i18n.T("Enter your choice: "))
 
 		if !w.scanner.Scan() {
 			return menuExit
 		}
 
 		choice := strings.TrimSpace(w.scanner.Text())
-		fmt.Println()
+		formatter.Println()
 
 		for _, opt := range options {
 			if opt.key == choice {
@@ -185,7 +189,7 @@ func (w *setupWizard) showMenu(title string, options []menuOption) menuResult {
 			}
 		}
 
-		w.printWarning("Invalid choice. Please try again.")
+		w.printWarning(i18n.T("Invalid choice. Please try again."))
 	}
 }
 
@@ -198,36 +202,36 @@ func (w *setupWizard) handleExistingTargets() bool {
 		return true
 	}
 
-	w.printSection("Existing Configuration")
-	fmt.Println("You already have some targets configured:")
-	fmt.Println()
+	w.printSection(i18n.T("Existing Configuration"))
+	formatter.Println(i18n.T("You already have some targets configured:"))
+	formatter.Println()
 
 	for name, v := range w.existingTargets {
 		target := v.(map[string]interface{})
 		current := ""
 		if target["current"] != nil && target["current"].(bool) {
-			current = " (current)"
+			current = i18n.T(" (current)")
 		}
-		fmt.Printf("  • %s: %s%s\n", name, target["endpoint"], current)
+		formatter.Printf("  • %s: %s%s\n", name, target["endpoint"], current)
 	}
-	fmt.Println()
+	formatter.Println()
 
-	result := w.showMenu("What would you like to do?", []menuOption{
-		{"1", "Add a new target", func() menuResult {
+	result := w.showMenu(i18n.T("What would you like to do?"), []menuOption{
+		{"1", i18n.T("Add a new target"), func() menuResult {
 			return menuContinue
 		}},
-		{"2", "Test connection to current target", func() menuResult {
+		{"2", i18n.T("Test connection to current target"), func() menuResult {
 			return w.handleTestConnection()
 		}},
-		{"3", "Configure authentication token", func() menuResult {
+		{"3", i18n.T("Configure authentication token"), func() menuResult {
 			return w.handleConfigureToken()
 		}},
-		{"4", "View current configuration", func() menuResult {
+		{"4", i18n.T("View current configuration"), func() menuResult {
 			return w.handleViewConfiguration()
 		}},
-		{"5", "Exit", func() menuResult {
-			w.printSuccess("Exiting setup wizard.")
-			fmt.Println("  Any changes made during this session have been applied.")
+		{"5", i18n.T("Exit"), func() menuResult {
+			w.printSuccess(i18n.T("Exiting setup wizard."))
+			formatter.Println(i18n.T("  Any changes made during this session have been applied."))
 			return menuExit
 		}},
 	})
@@ -237,33 +241,33 @@ func (w *setupWizard) handleExistingTargets() bool {
 
 func (w *setupWizard) handleTestConnection() menuResult {
 	if _, err := config.GetCurrentTarget(); err != nil {
-		w.printError(fmt.Sprintf("Error getting current target: %v", err))
-		fmt.Println()
+		w.printError(i18n.Sprintf("Error getting current target: %v", err))
+		formatter.Println()
 		return w.askNextAction()
 	}
 
-	fmt.Println("Running connection test...")
-	fmt.Println()
+	formatter.Println(i18n.T("Running connection test..."))
+	formatter.Println()
 
 	if err := runConnectionTest("", "", false); err != nil {
-		fmt.Println()
+		formatter.Println()
 		return w.askNextAction()
 	}
 
-	fmt.Println()
+	formatter.Println()
 	return w.askNextAction()
 }
 
 func (w *setupWizard) handleConfigureToken() menuResult {
-	w.printSection("Token Configuration")
-	fmt.Println("How would you like to configure your authentication token?")
-	fmt.Println()
+	w.printSection(i18n.T("Token Configuration"))
+	formatter.Println(i18n.T("How would you like to configure your authentication token?"))
+	formatter.Println()
 
 	result := w.showMenu("", []menuOption{
-		{"1", "Enter token manually", func() menuResult {
+		{"1", i18n.T("Enter token manually"), func() menuResult {
 			return w.handleManualToken()
 		}},
-		{"2", "Generate token via API", func() menuResult {
+		{"2", i18n.T("Generate token via API"), func() menuResult {
 			return w.handleGenerateToken()
 		}},
 	})
@@ -275,8 +279,8 @@ func (w *setupWizard) handleConfigureToken() menuResult {
 }
 
 func (w *setupWizard) handleManualToken() menuResult {
-	w.printSection("Manual Token Configuration")
-	fmt.Print("Enter your huskyCI API token: ")
+	w.printSection(i18n.T("Manual Token Configuration"))
+	formatter.Print(i18n.T("Enter your huskyCI API token: "))
 
 	if !w.scanner.Scan() {
 		return menuExit
@@ -284,8 +288,8 @@ func (w *setupWizard) handleManualToken() menuResult {
 
 	token := strings.TrimSpace(w.scanner.Text())
 	if token == "" {
-		w.printWarning("No token provided. Token configuration cancelled.")
-		fmt.Println()
+		w.printWarning(i18n.T("No token provided. Token configuration cancelled."))
+		formatter.Println()
 		return menuReturn
 	}
 
@@ -294,13 +298,13 @@ func (w *setupWizard) handleManualToken() menuResult {
 }
 
 func (w *setupWizard) handleGenerateToken() menuResult {
-	w.printSection("Generate Token via API")
+	w.printSection(i18n.T("Generate Token via API"))
 
 	target, err := config.GetCurrentTarget()
 	if err != nil {
-		w.printError(fmt.Sprintf("Error getting current target: %v", err))
-		fmt.Println("  Please configure a target first using option 1.")
-		fmt.Println()
+		w.printError(i18n.Sprintf("Error getting current target: %v", err))
+		formatter.Println(i18n.T("  Please configure a target first using option 1."))
+		formatter.Println()
 		return menuReturn
 	}
 
@@ -308,7 +312,7 @@ func (w *setupWizard) handleGenerateToken() menuResult {
 	token, err := w.generateTokenFromAPI(endpoint)
 	if err != nil {
 		w.printError(err.Error())
-		fmt.Println()
+		formatter.Println()
 		return menuReturn
 	}
 
@@ -317,55 +321,55 @@ func (w *setupWizard) handleGenerateToken() menuResult {
 }
 
 func (w *setupWizard) handleViewConfiguration() menuResult {
-	w.printSection("Current Configuration")
+	w.printSection(i18n.T("Current Configuration"))
 
 	target, err := config.GetCurrentTarget()
 	if err == nil {
-		fmt.Printf("Current Target: %s\n", target.Label)
-		fmt.Printf("Endpoint: %s\n", target.Endpoint)
+		i18n.Printf("Current Target: %s\n", target.Label)
+		i18n.Printf("Endpoint: %s\n", target.Endpoint)
 		if target.Token != "" {
-			fmt.Printf("Token: %s... (configured)\n", target.Token[:min(10, len(target.Token))])
+			i18n.Printf("Token: %s... (configured)\n", target.Token[:min(10, len(target.Token))])
 		} else {
-			fmt.Println("Token: Not configured")
+			formatter.Println(i18n.T("Token: Not configured"))
 		}
-		fmt.Println()
+		formatter.Println()
 	}
 
-	fmt.Println("All Targets:")
+	formatter.Println(i18n.T("All Targets:"))
 	for name, v := range w.existingTargets {
 		target := v.(map[string]interface{})
 		current := ""
 		if target["current"] != nil && target["current"].(bool) {
-			current = " (current)"
+			current = i18n.T(" (current)")
 		}
-		fmt.Printf("  • %s: %s%s\n", name, target["endpoint"], current)
+		formatter.Printf("  • %s: %s%s\n", name, target["endpoint"], current)
 	}
-	fmt.Println()
+	formatter.Println()
 
 	if os.Getenv("HUSKYCI_CLIENT_API_ADDR") != "" {
-		fmt.Println("Environment Variables:")
-		fmt.Printf("  HUSKYCI_CLIENT_API_ADDR: %s\n", os.Getenv("HUSKYCI_CLIENT_API_ADDR"))
+		formatter.Println(i18n.T("Environment Variables:"))
+		formatter.Printf("  HUSKYCI_CLIENT_API_ADDR: %s\n", os.Getenv("HUSKYCI_CLIENT_API_ADDR"))
 		token := config.GetTokenFromEnv()
 		if token != "" {
-			fmt.Printf("  HUSKYCI_CLI_TOKEN: %s... (configured)\n", token[:min(10, len(token))])
+			i18n.Printf("  HUSKYCI_CLI_TOKEN: %s... (configured)\n", token[:min(10, len(token))])
 		}
-		fmt.Println()
+		formatter.Println()
 	}
 
 	return w.askNextAction()
 }
 
 func (w *setupWizard) askNextAction() menuResult {
-	return w.showMenu("What would you like to do next?", []menuOption{
-		{"1", "Return to main menu", func() menuResult {
+	return w.showMenu(i18n.T("What would you like to do next?"), []menuOption{
+		{"1", i18n.T("Return to main menu"), func() menuResult {
 			return menuReturn
 		}},
-		{"2", "Add a new target", func() menuResult {
+		{"2", i18n.T("Add a new target"), func() menuResult {
 			return menuContinue
 		}},
-		{"3", "Exit", func() menuResult {
-			w.printSuccess("Exiting setup wizard.")
-			fmt.Println("  Any changes made during this session have been applied.")
+		{"3", i18n.T("Exit"), func() menuResult {
+			w.printSuccess(i18n.T("Exiting setup wizard."))
+			formatter.Println(i18n.T("  Any changes made during this session have been applied."))
 			return menuExit
 		}},
 	})
@@ -380,16 +384,16 @@ func (w *setupWizard) collectEndpoint() (string, error) {
 		endpoint := os.Getenv("HUSKYCI_CLIENT_API_ADDR")
 		if endpoint == "" {
 			endpoint = "http://localhost:8888"
-			fmt.Printf("Using default endpoint: %s\n", endpoint)
+			i18n.Printf("Using default endpoint: %s\n", endpoint)
 		}
 		return endpoint, nil
 	}
 
-	w.printSection("Step 1: Configure API Endpoint")
-	fmt.Print("Enter your huskyCI API endpoint URL (e.g., https://api.huskyci.example.com or http://localhost:8888): ")
+	w.printSection(i18n.T("Step 1: Configure API Endpoint"))
+	formatter.Print(i18n.T("Enter your huskyCI API endpoint URL (e.g., https://api.huskyci.example.com or http://localhost:8888): "))
 
 	if !w.scanner.Scan() {
-		return "", fmt.Errorf("failed to read input")
+		return "", i18n.Errorf("failed to read input")
 	}
 
 	endpoint := strings.TrimSpace(w.scanner.Text())
@@ -406,15 +410,15 @@ func (w *setupWizard) collectTargetName() (string, error) {
 		if len(w.existingTargets) > 0 {
 			targetName = fmt.Sprintf("target-%d", len(w.existingTargets)+1)
 		}
-		fmt.Printf("Using target name: %s\n", targetName)
+		i18n.Printf("Using target name: %s\n", targetName)
 		return targetName, nil
 	}
 
-	w.printSection("Step 2: Configure Target Name")
-	fmt.Print("Enter a name for this target (letters, numbers, underscores only, e.g., 'production', 'staging'): ")
+	w.printSection(i18n.T("Step 2: Configure Target Name"))
+	formatter.Print(i18n.T("Enter a name for this target (letters, numbers, underscores only, e.g., 'production', 'staging'): "))
 
 	if !w.scanner.Scan() {
-		return "", fmt.Errorf("failed to read input")
+		return "", i18n.Errorf("failed to read input")
 	}
 
 	targetName := strings.TrimSpace(w.scanner.Text())
@@ -431,8 +435,8 @@ func (w *setupWizard) collectToken() (string, bool) {
 		return token, token != ""
 	}
 
-	fmt.Println()
-	fmt.Print("Do you want to configure an authentication token now? (y/n): ")
+	formatter.Println()
+	formatter.Print(i18n.T("Do you want to configure an authentication token now? (y/n): "))
 
 	if !w.scanner.Scan() {
 		return "", false
@@ -443,7 +447,7 @@ func (w *setupWizard) collectToken() (string, bool) {
 		return "", false
 	}
 
-	fmt.Print("Enter your huskyCI API token: ")
+	formatter.Print(i18n.T("Enter your huskyCI API token: "))
 
 	if !w.scanner.Scan() {
 		return "", false
@@ -451,8 +455,8 @@ func (w *setupWizard) collectToken() (string, bool) {
 
 	token := strings.TrimSpace(w.scanner.Text())
 	if token == "" {
-		w.printWarning("No token provided. You can set it later using:")
-		fmt.Println("   export HUSKYCI_CLI_TOKEN=\"your-token\"")
+		w.printWarning(i18n.T("No token provided. You can set it later using:"))
+		formatter.Println("   export HUSKYCI_CLI_TOKEN=\"your-token\"")
 		return "", false
 	}
 
@@ -465,16 +469,16 @@ func (w *setupWizard) collectToken() (string, bool) {
 
 func validateEndpoint(endpoint string) error {
 	if endpoint == "" {
-		return fmt.Errorf("endpoint URL cannot be empty")
+		return i18n.Errorf("endpoint URL cannot be empty")
 	}
 
 	parsedURL, err := url.Parse(endpoint)
 	if err != nil {
-		return fmt.Errorf("invalid URL format: %w\n\nPlease enter a valid URL (e.g., https://api.huskyci.example.com)", err)
+		return i18n.Errorf("invalid URL format: %w\n\nPlease enter a valid URL (e.g., https://api.huskyci.example.com)", err)
 	}
 
 	if parsedURL.Scheme == "" || parsedURL.Host == "" {
-		return fmt.Errorf("URL must include scheme (http:// or https://) and host\n\nExample: https://api.huskyci.example.com")
+		return i18n.Errorf("URL must include scheme (http:// or https://) and host\n\nExample: https://api.huskyci.example.com")
 	}
 
 	return nil
@@ -482,20 +486,20 @@ func validateEndpoint(endpoint string) error {
 
 func validateTargetName(targetName string, existingTargets map[string]interface{}) error {
 	if targetName == "" {
-		return fmt.Errorf("target name cannot be empty")
+		return i18n.Errorf("target name cannot be empty")
 	}
 
 	match, err := regexp.MatchString(`^\w+$`, targetName)
 	if err != nil {
-		return fmt.Errorf("error validating target name: %w", err)
+		return i18n.Errorf("error validating target name: %w", err)
 	}
 	if !match {
-		return fmt.Errorf("invalid target name '%s': target name must contain only letters, numbers, and underscores\n\nExample: production, staging, local", targetName)
+		return i18n.Errorf("invalid target name '%s': target name must contain only letters, numbers, and underscores\n\nExample: production, staging, local", targetName)
 	}
 
 	for k := range existingTargets {
 		if k == targetName {
-			return fmt.Errorf("target name '%s' already exists\n\nTip: Use 'huskyci target-remove %s' to remove it first, or choose a different name", targetName, targetName)
+			return i18n.Errorf("target name '%s' already exists\n\nTip: Use 'huskyci target-remove %s' to remove it first, or choose a different name", targetName, targetName)
 		}
 	}
 
@@ -524,12 +528,12 @@ func (w *setupWizard) saveTarget(targetName, endpoint string) error {
 
 	viper.Set("targets", targets)
 	if err := viper.WriteConfig(); err != nil {
-		return fmt.Errorf("error saving configuration: %w\n\nTip: Check if you have write permissions to the config file", err)
+		return i18n.Errorf("error saving configuration: %w\n\nTip: Check if you have write permissions to the config file", err)
 	}
 
-	fmt.Println()
-	w.printSuccess(fmt.Sprintf("Successfully added target '%s' -> %s", targetName, endpoint))
-	w.printSuccess(fmt.Sprintf("Set '%s' as the current target", targetName))
+	formatter.Println()
+	w.printSuccess(i18n.Sprintf("Successfully added target '%s' -> %s", targetName, endpoint))
+	w.printSuccess(i18n.Sprintf("Set '%s' as the current target", targetName))
 	
 	return nil
 }
@@ -539,20 +543,24 @@ func (w *setupWizard) saveTarget(targetName, endpoint string) error {
 // ============================================================================
 
 func (w *setupWizard) setupToken(token string) {
-	fmt.Println()
-	fmt.Println("How would you like to set the token?")
-	fmt.Println()
+	formatter.Println()
+	formatter.Println(i18n.T("How would you like to set the token?"))
+	formatter.Println()
 
 	result := w.showMenu("", []menuOption{
-		{"1", "Show command to set for current session", func() menuResult {
+		{"1", i18n.T("Store in the OS keychain (recommended)"), func() menuResult {
+			w.storeTokenInKeyring(token)
+			return menuContinue
+		}},
+		{"2", i18n.T("Show command to set for current session"), func() menuResult {
 			w.showTokenCommand(token, false)
 			return menuContinue
 		}},
-		{"2", "Add to shell profile (detected automatically)", func() menuResult {
+		{"3", i18n.T("Add to shell profile in plain text (detected automatically)"), func() menuResult {
 			w.addTokenToProfile(token)
 			return menuContinue
 		}},
-		{"3", "Just show the command (I'll set it myself)", func() menuResult {
+		{"4", i18n.T("Just show the command (I'll set it myself)"), func() menuResult {
 			w.showTokenCommand(token, true)
 			return menuContinue
 		}},
@@ -562,10 +570,10 @@ func (w *setupWizard) setupToken(token string) {
 		return
 	}
 
-	fmt.Println()
-	fmt.Println("After setting up your token, you can test the connection using:")
-	fmt.Println("  huskyci test-connection")
-	fmt.Println()
+	formatter.Println()
+	formatter.Println(i18n.T("After setting up your token, you can test the connection using:"))
+	formatter.Println("  huskyci test-connection")
+	formatter.Println()
 }
 
 func (w *setupWizard) showTokenCommand(token string, manual bool) {
@@ -573,66 +581,84 @@ func (w *setupWizard) showTokenCommand(token string, manual bool) {
 	exportCmd := getShellExportCommand(token, profileFile)
 
 	if manual {
-		fmt.Println("To set the token manually, run:")
-		fmt.Printf("  %s\n", exportCmd)
+		formatter.Println(i18n.T("To set the token manually, run:"))
+		formatter.Printf("  %s\n", exportCmd)
 	} else {
-		fmt.Println("To set the token for your current shell session, run:")
-		fmt.Printf("  %s\n", exportCmd)
-		fmt.Println()
-		fmt.Println("  Note: This will only last for this terminal session")
-		fmt.Println("  To make it permanent, choose option 2 to add it to your shell profile")
+		formatter.Println(i18n.T("To set the token for your current shell session, run:"))
+		formatter.Printf("  %s\n", exportCmd)
+		formatter.Println()
+		formatter.Println(i18n.T("  Note: This will only last for this terminal session"))
+		formatter.Println(i18n.T("  To make it permanent, choose option 1 to store it in the OS keychain"))
+	}
+}
+
+// storeTokenInKeyring stores token in the OS keychain as the token of the current target.
+func (w *setupWizard) storeTokenInKeyring(token string) {
+	target, err := config.GetCurrentTarget()
+	if err == nil && target.Label == "env-var" {
+		err = i18n.Errorf("the target comes from HUSKYCI_CLIENT_API_ADDR")
 	}
+	if err == nil {
+		err = config.SaveTargetToken(target.Label, token)
+	}
+	if err != nil {
+		w.printError(i18n.Sprintf("Error storing token in the OS keychain: %v", err))
+		formatter.Println(i18n.T("  Choose another option to set the token."))
+		return
+	}
+	w.printSuccess(i18n.Sprintf("Token stored in the OS keychain for target '%s'", target.Label))
+	formatter.Println(i18n.T("  HUSKYCI_CLI_TOKEN, when set, still takes precedence over it"))
 }
 
 func (w *setupWizard) addTokenToProfile(token string) {
 	if err := addTokenToShellProfile(token); err != nil {
-		w.printError(fmt.Sprintf("Error adding to shell profile: %v", err))
+		w.printError(i18n.Sprintf("Error adding to shell profile: %v", err))
 		_, profileFile, _ := getDetectedShell()
 		exportCmd := getShellExportCommand(token, profileFile)
-		fmt.Println("  You can manually add this line to your shell profile:")
-		fmt.Printf("  %s\n", exportCmd)
-		fmt.Printf("  (Add to: %s)\n", profileFile)
+		formatter.Println(i18n.T("  You can manually add this line to your shell profile:"))
+		formatter.Printf("  %s\n", exportCmd)
+		formatter.Printf("  (Add to: %s)\n", profileFile)
 	} else {
 		_, profileFile, _ := getDetectedShell()
-		w.printSuccess("Token added to shell profile")
-		fmt.Printf("  Please restart your terminal or run: source %s\n", profileFile)
+		w.printSuccess(i18n.T("Token added to shell profile"))
+		i18n.Printf("  Please restart your terminal or run: source %s\n", profileFile)
 	}
 }
 
 func (w *setupWizard) generateTokenFromAPI(endpoint string) (string, error) {
-	fmt.Println("Please provide the following information:")
-	fmt.Println()
+	formatter.Println(i18n.T("Please provide the following information:"))
+	formatter.Println()
 
 	// Get username
-	fmt.Print("API Username: ")
+	formatter.Print(i18n.T("API Username: "))
 	if !w.scanner.Scan() {
-		return "", fmt.Errorf("failed to read username")
+		return "", i18n.Errorf("failed to read username")
 	}
 	username := strings.TrimSpace(w.scanner.Text())
 	if username == "" {
-		return "", fmt.Errorf("username cannot be empty")
+		return "", i18n.Errorf("username cannot be empty")
 	}
 
 	// Get password
-	fmt.Print("API Password: ")
+	formatter.Print(i18n.T("API Password: "))
 	if !w.scanner.Scan() {
-		return "", fmt.Errorf("failed to read password")
+		return "", i18n.Errorf("failed to read password")
 	}
 	password := strings.TrimSpace(w.scanner.Text())
 	if password == "" {
-		return "", fmt.Errorf("password cannot be empty")
+		return "", i18n.Errorf("password cannot be empty")
 	}
 
 	// Get repository URL (optional - empty for generic token)
-	fmt.Print("Repository URL (e.g., https://github.com/user/repo.git) or press Enter for generic token: ")
+	formatter.Print(i18n.T("Repository URL (e.g., https://github.com/user/repo.git) or press Enter for generic token: "))
 	if !w.scanner.Scan() {
-		return "", fmt.Errorf("failed to read repository URL")
+		return "", i18n.Errorf("failed to read repository URL")
 	}
 	repoURL := strings.TrimSpace(w.scanner.Text())
 	// Empty URL is now valid - it creates a generic token
 
-	fmt.Println()
-	fmt.Println("Generating token...")
+	formatter.Println()
+	formatter.Println(i18n.T("Generating token..."))
 
 	tokenURL := endpoint + "/api/1.0/token"
 	payload := map[string]string{
@@ -640,12 +666,12 @@ func (w *setupWizard) generateTokenFromAPI(endpoint string) (string, error) {
 	}
 	jsonPayload, err := json.Marshal(payload)
 	if err != nil {
-		return "", fmt.Errorf("error creating request: %w", err)
+		return "", i18n.Errorf("error creating request: %w", err)
 	}
 
 	req, err := http.NewRequest("POST", tokenURL, bytes.NewBuffer(jsonPayload))
 	if err != nil {
-		return "", fmt.Errorf("error creating request: %w", err)
+		return "", i18n.Errorf("error creating request: %w", err)
 	}
 
 	auth := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
@@ -653,15 +679,16 @@ func (w *setupWizard) generateTokenFromAPI(endpoint string) (string, error) {
 	req.Header.Set("Content-Type", "application/json")
 
 	useHTTPS := util.IsHTTPS(endpoint)
-	client, err := util.NewHTTPClient(useHTTPS)
+	certFile, keyFile := config.GetClientCertFromEnv()
+	client, err := util.NewHTTPClient(useHTTPS, certFile, keyFile)
 	if err != nil {
-		return "", fmt.Errorf("error creating HTTP client: %w", err)
+		return "", i18n.Errorf("error creating HTTP client: %w", err)
 	}
 	client.Timeout = 30 * time.Second
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("error connecting to API: %w\n\nPlease verify:\n  - The API endpoint is correct\n  - The API server is running\n  - Your network connection is working", err)
+		return "", i18n.Errorf("error connecting to API: %w\n\nPlease verify:\n  - The API endpoint is correct\n  - The API server is running\n  - Your network connection is working", err)
 	}
 	defer resp.Body.Close()
 
@@ -672,10 +699,10 @@ func (w *setupWizard) generateTokenFromAPI(endpoint string) (string, error) {
 		var errorResponse map[string]interface{}
 		if err := json.Unmarshal(body, &errorResponse); err == nil {
 			if errorMsg, ok := errorResponse["error"].(string); ok {
-				return "", fmt.Errorf("token generation failed (status %d)\n  Error: %s\n\nPlease verify:\n  - Your API credentials are correct\n  - The repository URL is valid\n  - The API server is functioning properly", resp.StatusCode, errorMsg)
+				return "", i18n.Errorf("token generation failed (status %d)\n  Error: %s\n\nPlease verify:\n  - Your API credentials are correct\n  - The repository URL is valid\n  - The API server is functioning properly", resp.StatusCode, errorMsg)
 			}
 		}
-		return "", fmt.Errorf("token generation failed (status %d)\n  Response: %s\n\nPlease verify:\n  - Your API credentials are correct\n  - The repository URL is valid\n  - The API server is functioning properly", resp.StatusCode, string(body))
+		return "", i18n.Errorf("token generation failed (status %d)\n  Response: %s\n\nPlease verify:\n  - Your API credentials are correct\n  - The repository URL is valid\n  - The API server is functioning properly", resp.StatusCode, string(body))
 	}
 
 	var token string
@@ -694,10 +721,10 @@ func (w *setupWizard) generateTokenFromAPI(endpoint string) (string, error) {
 	}
 
 	if token == "" {
-		return "", fmt.Errorf("token generation succeeded but no token found in response\n  Response: %s", string(body))
+		return "", i18n.Errorf("token generation succeeded but no token found in response\n  Response: %s", string(body))
 	}
 
-	w.printSuccess("Token generated successfully!")
+	w.printSuccess(i18n.T("Token generated successfully!"))
 	return token, nil
 }
 
@@ -709,16 +736,16 @@ func (w *setupWizard) verifyConnection(endpoint, token string, useToken bool) {
 	if w.nonInteractive {
 		if useToken {
 			if err := verifyConnection(endpoint, token); err != nil {
-				w.printWarning(fmt.Sprintf("Connection verification failed: %v", err))
+				w.printWarning(i18n.Sprintf("Connection verification failed: %v", err))
 			} else {
-				w.printSuccess("Connection verified successfully!")
+				w.printSuccess(i18n.T("Connection verified successfully!"))
 			}
 		}
 		return
 	}
 
-	fmt.Println()
-	fmt.Print("Do you want to verify the connection to the API? (y/n): ")
+	formatter.Println()
+	formatter.Print(i18n.T("Do you want to verify the connection to the API? (y/n): "))
 
 	if !w.scanner.Scan() {
 		return
@@ -733,18 +760,18 @@ func (w *setupWizard) verifyConnection(endpoint, token string, useToken bool) {
 	if err != nil {
 		// If connection refused to a local endpoint, offer to start Docker and retry once
 		if !w.nonInteractive && util.IsConnectionRefused(err) && util.IsLocalEndpoint(endpoint) && util.PromptAndStartDocker(os.Stdin) {
-			fmt.Println("   Retrying connection in 5 seconds...")
+			formatter.Println(i18n.T("   Retrying connection in 5 seconds..."))
 			time.Sleep(5 * time.Second)
 			err = verifyConnection(endpoint, token)
 		}
 		if err != nil {
-			w.printWarning(fmt.Sprintf("Connection verification failed: %v", err))
-			fmt.Println("   You can still use huskyCI CLI, but please verify your endpoint and token.")
+			w.printWarning(i18n.Sprintf("Connection verification failed: %v", err))
+			formatter.Println(i18n.T("   You can still use huskyCI CLI, but please verify your endpoint and token."))
 		} else {
-			w.printSuccess("Connection verified successfully!")
+			w.printSuccess(i18n.T("Connection verified successfully!"))
 		}
 	} else {
-		w.printSuccess("Connection verified successfully!")
+		w.printSuccess(i18n.T("Connection verified successfully!"))
 	}
 }
 
@@ -753,34 +780,34 @@ func (w *setupWizard) verifyConnection(endpoint, token string, useToken bool) {
 // ============================================================================
 
 func (w *setupWizard) printSummary(endpoint string, useToken bool) {
-	fmt.Println()
-	fmt.Println(separatorLine)
-	fmt.Println("  ✓ Setup Complete!")
-	fmt.Println(separatorLine)
-	fmt.Println()
-	fmt.Println("Next steps:")
-	fmt.Println()
+	formatter.Println()
+	formatter.Println(separatorLine)
+	formatter.Println(i18n.T("  ✓ Setup Complete!"))
+	formatter.Println(separatorLine)
+	formatter.Println()
+	formatter.Println(i18n.T("Next steps:"))
+	formatter.Println()
 
 	if !useToken {
-		fmt.Println("  1. Set your authentication token:")
-		fmt.Println("     export HUSKYCI_CLI_TOKEN=\"your-token\"")
-		fmt.Println()
-		fmt.Println("     Or generate a token via the API:")
-		fmt.Printf("     curl -X POST %s/api/1.0/token \\\n", endpoint)
-		fmt.Println("       -u username:password \\")
-		fmt.Println("       -H \"Content-Type: application/json\" \\")
-		fmt.Println("       -d '{\"repositoryURL\": \"https://github.com/user/repo.git\"}'")
-		fmt.Println()
-	}
-
-	fmt.Println("  2. Run your first security analysis:")
-	fmt.Println("     huskyci run ./my-project")
-	fmt.Println()
-	fmt.Println("  3. List all configured targets:")
-	fmt.Println("     huskyci target-list")
-	fmt.Println()
-	fmt.Println("For more information, visit: https://github.com/huskyci-org/huskyCI")
-	fmt.Println()
+		formatter.Println(i18n.T("  1. Set your authentication token:"))
+		formatter.Println("     export HUSKYCI_CLI_TOKEN=\"your-token\"")
+		formatter.Println()
+		formatter.Println(i18n.T("     Or generate a token via the API:"))
+		formatter.Printf("     curl -X POST %s/api/1.0/token \\\n", endpoint)
+		formatter.Println("       -u username:password \\")
+		formatter.Println("       -H \"Content-Type: application/json\" \\")
+		formatter.Println("       -d '{\"repositoryURL\": \"https://github.com/user/repo.git\"}'")
+		formatter.Println()
+	}
+
+	formatter.Println(i18n.T("  2. Run your first security analysis:"))
+	formatter.Println("     huskyci run ./my-project")
+	formatter.Println()
+	formatter.Println(i18n.T("  3. List all configured targets:"))
+	formatter.Println("     huskyci target-list")
+	formatter.Println()
+	formatter.Println(i18n.T("For more information, visit: https://github.com/huskyci-org/huskyCI"))
+	formatter.Println()
 }
 
 // ============================================================================
@@ -788,7 +815,8 @@ func (w *setupWizard) printSummary(endpoint string, useToken bool) {
 // ============================================================================
 
 func verifyConnection(endpoint string, token string) error {
-	client, err := createHTTPClient(endpoint)
+	certFile, keyFile := config.GetClientCertFromEnv()
+	client, err := createHTTPClient(&types.Target{Endpoint: endpoint, ClientCert: certFile, ClientKey: keyFile})
 	if err != nil {
 		return err
 	}
@@ -802,11 +830,11 @@ func verifyConnection(endpoint string, token string) error {
 	return tryConnection(client, rootURL, token, endpoint)
 }
 
-func createHTTPClient(endpoint string) (*http.Client, error) {
-	useHTTPS := util.IsHTTPS(endpoint)
-	client, err := util.NewHTTPClient(useHTTPS)
+func createHTTPClient(target *types.Target) (*http.Client, error) {
+	useHTTPS := util.IsHTTPS(target.Endpoint)
+	client, err := util.NewHTTPClient(useHTTPS, target.ClientCert, target.ClientKey)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+		return nil, i18n.Errorf("failed to create HTTP client: %w", err)
 	}
 	client.Timeout = 10 * time.Second
 	return client, nil
@@ -822,29 +850,29 @@ func normalizeURL(url string) string {
 func tryConnection(client *http.Client, url, token, endpoint string) error {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return i18n.Errorf("failed to create request: %w", err)
 	}
 
 	if token != "" {
 		req.Header.Set("Husky-Token", token)
 	}
-	req.Header.Set("User-Agent", "huskyci-cli")
+	req.Header.Set("User-Agent", config.UserAgent())
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("unable to connect to %s: %w\n\nPlease verify:\n  - The API endpoint is correct\n  - The API server is running\n  - Your network connection is working", endpoint, err)
+		return i18n.Errorf("unable to connect to %s: %w\n\nPlease verify:\n  - The API endpoint is correct\n  - The API server is running\n  - Your network connection is working", endpoint, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 && resp.StatusCode < 500 {
 		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
-			return fmt.Errorf("endpoint is reachable but authentication failed (status %d)\n\nTip: Verify your token is correct. You can still use huskyCI CLI, but authentication may be required for actual operations", resp.StatusCode)
+			return i18n.Errorf("endpoint is reachable but authentication failed (status %d)\n\nTip: Verify your token is correct. You can still use huskyCI CLI, but authentication may be required for actual operations", resp.StatusCode)
 		}
 		return nil
 	}
 
 	if resp.StatusCode >= 500 {
-		return fmt.Errorf("endpoint is reachable but server returned error (status %d)\n\nTip: The API server may be experiencing issues. You can still use huskyCI CLI, but operations may fail", resp.StatusCode)
+		return i18n.Errorf("endpoint is reachable but server returned error (status %d)\n\nTip: The API server may be experiencing issues. You can still use huskyCI CLI, but operations may fail", resp.StatusCode)
 	}
 
 	return nil
@@ -869,7 +897,7 @@ func getDetectedShell() (shellName string, profileFile string, err error) {
 		configDir := home + "/.config/fish"
 		profileFile = configDir + "/config.fish"
 		if err := os.MkdirAll(configDir, 0755); err != nil {
-			return "", "", fmt.Errorf("failed to create fish config directory: %w", err)
+			return "", "", i18n.Errorf("failed to create fish config directory: %w", err)
 		}
 		return "fish", profileFile, nil
 	case strings.Contains(shellBase, "zsh"):
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// targetCmd represents the target command
+var targetCmd = &cobra.Command{
+	Use:   "target",
+	Short: "Share the target configuration across machines",
+	Long: `Share the target configuration across machines and CI environments.
+
+Targets are exported to and imported from a single YAML file with the same
+layout as the targets of the config file. Tokens are never exported: set
+HUSKYCI_CLI_TOKEN or store them with 'huskyci target-add --token-stdin'.
+
+Examples:
+  # Export all targets to a file
+  huskyci target export -o targets.yaml
+
+  # Import them on another machine
+  huskyci target import targets.yaml`,
+}
+
+func init() {
+	rootCmd.AddCommand(targetCmd)
+}
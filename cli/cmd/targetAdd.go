@@ -1,10 +1,14 @@
 package cmd
 
 import (
-	"fmt"
+	"bufio"
 	"net/url"
+	"os"
 	"regexp"
+	"strings"
 
+	"github.com/huskyci-org/huskyCI/cli/config"
+	"github.com/huskyci-org/huskyCI/pkg/i18n"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -26,25 +30,31 @@ Examples:
   huskyci target-add staging https://staging-api.huskyci.example.com --set-current
 
   # Add a local development target
-  huskyci target-add local http://localhost:8888`,
+  huskyci target-add local http://localhost:8888
+
+  # Add a target requiring mutual TLS, with the client certificate to present
+  huskyci target-add production https://api.huskyci.example.com --client-cert ~/.huskyci/client.pem --client-key ~/.huskyci/client-key.pem
+
+  # Add a target and store its token in the OS keychain
+  echo "$TOKEN" | huskyci target-add production https://api.huskyci.example.com --token-stdin`,
 	Args: cobra.ExactArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
 
 		match, err := regexp.MatchString(`^\w+$`, args[0])
 		if err != nil {
-			return fmt.Errorf("error validating target name '%s': %w\n\nTip: Target name must contain only letters, numbers, and underscores", args[0], err)
+			return i18n.Errorf("error validating target name '%s': %w\n\nTip: Target name must contain only letters, numbers, and underscores", args[0], err)
 		}
 		if !match {
-			return fmt.Errorf("invalid target name '%s': target name must contain only letters, numbers, and underscores\n\nExample: huskyci target-add production https://api.huskyci.example.com", args[0])
+			return i18n.Errorf("invalid target name '%s': target name must contain only letters, numbers, and underscores\n\nExample: huskyci target-add production https://api.huskyci.example.com", args[0])
 		}
 
 		// check huskyci-api-endpoint
 		parsedURL, err := url.Parse(args[1])
 		if err != nil {
-			return fmt.Errorf("invalid endpoint URL '%s': %w\n\nTip: Please provide a valid URL (e.g., https://api.huskyci.example.com)", args[1], err)
+			return i18n.Errorf("invalid endpoint URL '%s': %w\n\nTip: Please provide a valid URL (e.g., https://api.huskyci.example.com)", args[1], err)
 		}
 		if parsedURL.Scheme == "" || parsedURL.Host == "" {
-			return fmt.Errorf("invalid endpoint URL '%s': URL must include scheme (http:// or https://) and host\n\nExample: https://api.huskyci.example.com", args[1])
+			return i18n.Errorf("invalid endpoint URL '%s': URL must include scheme (http:// or https://) and host\n\nExample: https://api.huskyci.example.com", args[1])
 		}
 
 		// check if target name is used before
@@ -52,14 +62,14 @@ Examples:
 		for k, v := range targets {
 			if k == args[0] {
 				target := v.(map[string]interface{})
-				return fmt.Errorf("target name '%s' already exists with endpoint: %s\n\nTip: Use 'huskyci target-remove %s' to remove it first, or choose a different name", k, target["endpoint"], k)
+				return i18n.Errorf("target name '%s' already exists with endpoint: %s\n\nTip: Use 'huskyci target-remove %s' to remove it first, or choose a different name", k, target["endpoint"], k)
 			}
 		}
 
 		// if new target must be current, we unset all others
 		setCurrent, err := cmd.Flags().GetBool("set-current")
 		if err != nil {
-			return fmt.Errorf("error parsing --set-current flag: %w", err)
+			return i18n.Errorf("error parsing --set-current flag: %w", err)
 		}
 
 		if setCurrent {
@@ -70,20 +80,45 @@ Examples:
 		}
 
 		// add new entry to data struct
-		targets[args[0]] = map[string]interface{}{"current": setCurrent, "endpoint": args[1]}
+		entry := map[string]interface{}{"current": setCurrent, "endpoint": args[1]}
+		clientCert, _ := cmd.Flags().GetString("client-cert")
+		clientKey, _ := cmd.Flags().GetString("client-key")
+		if (clientCert == "") != (clientKey == "") {
+			return i18n.Errorf("--client-cert and --client-key must be set together\n\nExample: huskyci target-add %s %s --client-cert client.pem --client-key client-key.pem", args[0], args[1])
+		}
+		if clientCert != "" {
+			entry["client-cert"] = clientCert
+			entry["client-key"] = clientKey
+		}
+		targets[args[0]] = entry
 
 		// save config
 		viper.Set("targets", targets)
 		err = viper.WriteConfig()
 		if err != nil {
-			return fmt.Errorf("error saving configuration: %w\n\nTip: Check if you have write permissions to the config file", err)
+			return i18n.Errorf("error saving configuration: %w\n\nTip: Check if you have write permissions to the config file", err)
 		}
 		
 		currentStatus := ""
 		if setCurrent {
-			currentStatus = " (set as current)"
+			currentStatus = i18n.T(" (set as current)")
+		}
+		i18n.Printf("✓ Successfully added target '%s' -> %s%s\n", args[0], args[1], currentStatus)
+
+		// store the token given on stdin in the OS keychain
+		tokenStdin, _ := cmd.Flags().GetBool("token-stdin")
+		if tokenStdin {
+			scanner := bufio.NewScanner(os.Stdin)
+			scanner.Scan()
+			token := strings.TrimSpace(scanner.Text())
+			if token == "" {
+				return i18n.Errorf("no token read from stdin\n\nTip: pipe the token into the command, e.g. echo \"$TOKEN\" | huskyci target-add %s %s --token-stdin", args[0], args[1])
+			}
+			if err := config.SaveTargetToken(args[0], token); err != nil {
+				return err
+			}
+			i18n.Printf("✓ Token of target '%s' stored in the OS keychain\n", args[0])
 		}
-		fmt.Printf("✓ Successfully added target '%s' -> %s%s\n", args[0], args[1], currentStatus)
 		return nil
 	},
 }
@@ -91,4 +126,7 @@ Examples:
 func init() {
 	rootCmd.AddCommand(targetAddCmd)
 	targetAddCmd.Flags().BoolP("set-current", "s", false, "Add and define the target as the current target")
+	targetAddCmd.Flags().Bool("token-stdin", false, "Read the target token from stdin and store it in the OS keychain")
+	targetAddCmd.Flags().String("client-cert", "", "Certificate file presented to a target requiring mutual TLS")
+	targetAddCmd.Flags().String("client-key", "", "Key file of the client certificate")
 }
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/huskyci-org/huskyCI/cli/config"
+	"github.com/huskyci-org/huskyCI/pkg/formatter"
+	"github.com/huskyci-org/huskyCI/pkg/i18n"
+	"github.com/spf13/cobra"
+)
+
+// targetExportCmd represents the target export command
+var targetExportCmd = &cobra.Command{
+	Use:   "export [name...]",
+	Short: "Export targets to a YAML file",
+	Long: `Export the given targets, or all of them, to a YAML file.
+
+The endpoint and current flag of each target are exported; tokens are not.
+
+Examples:
+  # Print all targets
+  huskyci target export
+
+  # Export the production and staging targets to a file
+  huskyci target export production staging -o targets.yaml`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		data, err := config.ExportTargets(args...)
+		if err != nil {
+			return i18n.Errorf("%w\n\nTip: Use 'huskyci target-list' to see available targets", err)
+		}
+
+		output, _ := cmd.Flags().GetString("output")
+		if output == "" {
+			fmt.Print(string(data))
+			return nil
+		}
+		if err := os.WriteFile(output, data, 0600); err != nil {
+			return i18n.Errorf("error writing targets file: %w", err)
+		}
+		i18n.Fprintf(formatter.Stderr, "✓ Successfully exported targets to %s\n", output)
+		return nil
+	},
+}
+
+func init() {
+	targetCmd.AddCommand(targetExportCmd)
+	targetExportCmd.Flags().StringP("output", "o", "", "Write the targets to a file instead of stdout")
+}
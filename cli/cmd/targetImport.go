@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/huskyci-org/huskyCI/cli/config"
+	"github.com/huskyci-org/huskyCI/pkg/i18n"
+	"github.com/spf13/cobra"
+)
+
+// targetImportCmd represents the target import command
+var targetImportCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Import targets from a YAML file",
+	Long: `Import the targets of a YAML file written by 'huskyci target export'.
+
+Use - as the file to read it from stdin. A target marked as current in the
+file becomes the current target. Importing a target that already exists fails
+unless --overwrite is given, in which case its endpoint is replaced and its
+stored token kept.
+
+Examples:
+  # Import targets from a file
+  huskyci target import targets.yaml
+
+  # Replace existing targets in a CI job
+  cat targets.yaml | huskyci target import - --overwrite`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		var data []byte
+		var err error
+		if args[0] == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(args[0])
+		}
+		if err != nil {
+			return i18n.Errorf("error reading targets file: %w", err)
+		}
+
+		overwrite, _ := cmd.Flags().GetBool("overwrite")
+		names, err := config.ImportTargets(data, overwrite)
+		if errors.Is(err, config.ErrTargetExists) {
+			return i18n.Errorf("%w\n\nTip: Use --overwrite to replace existing targets", err)
+		}
+		if err != nil {
+			return err
+		}
+
+		i18n.Printf("✓ Successfully imported targets: %s\n", strings.Join(names, ", "))
+		return nil
+	},
+}
+
+func init() {
+	targetCmd.AddCommand(targetImportCmd)
+	targetImportCmd.Flags().Bool("overwrite", false, "Replace targets that already exist")
+}
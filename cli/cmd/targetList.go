@@ -2,7 +2,10 @@ package cmd
 
 import (
 	"fmt"
+	"sort"
 
+	"github.com/huskyci-org/huskyCI/cli/config"
+	"github.com/huskyci-org/huskyCI/pkg/i18n"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -28,27 +31,39 @@ Examples:
 		targets := viper.GetStringMap("targets")
 		
 		if len(targets) == 0 {
-			fmt.Println("No targets configured.")
-			fmt.Println("\nTip: Use 'huskyci target-add <name> <endpoint>' to add a new target")
-			fmt.Println("Example: huskyci target-add production https://api.huskyci.example.com")
+			fmt.Println(i18n.T("No targets configured."))
+			fmt.Println(i18n.T("\nTip: Use 'huskyci target-add <name> <endpoint>' to add a new target"))
+			fmt.Println(i18n.T("Example: huskyci target-add production https://api.huskyci.example.com"))
 			return
 		}
 
-		fmt.Println("Configured targets:")
+		fmt.Println(i18n.T("Configured targets:"))
 		fmt.Println()
+		names := make([]string, 0, len(targets))
 		for k, v := range targets {
-			target := v.(map[string]interface{})
+			if v != nil {
+				names = append(names, k)
+			}
+		}
+		sort.Strings(names)
+		for _, k := range names {
+			target := targets[k].(map[string]interface{})
 
 			// format output for activated target
 			marker := " "
-			if target["current"].(bool) {
+			if current, _ := target["current"].(bool); current {
 				marker = "*"
 			}
 
-			fmt.Printf("  %s %s (%s)\n", marker, k, target["endpoint"])
+			storage := ""
+			if target["token-storage"] == config.TokenStorageKeyring {
+				storage = i18n.T(" [token in OS keychain]")
+			}
+
+			fmt.Printf("  %s %s (%s)%s\n", marker, k, target["endpoint"], storage)
 		}
 		fmt.Println()
-		fmt.Println("Legend: * = current target")
+		fmt.Println(i18n.T("Legend: * = current target"))
 	},
 }
 
@@ -1,8 +1,8 @@
 package cmd
 
 import (
-	"fmt"
-
+	"github.com/huskyci-org/huskyCI/cli/config"
+	"github.com/huskyci-org/huskyCI/pkg/i18n"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -31,21 +31,26 @@ Examples:
 			}
 		}
 		if notUsed {
-			return fmt.Errorf("target '%s' does not exist\n\nTip: Use 'huskyci target-list' to see available targets", args[0])
+			return i18n.Errorf("target '%s' does not exist\n\nTip: Use 'huskyci target-list' to see available targets", args[0])
 		}
 
 		// remove entry from data struct but, before, storing data to show to user
 		target := targets[args[0]].(map[string]interface{})
 		endpoint := target["endpoint"].(string)
+		if target["token-storage"] == config.TokenStorageKeyring {
+			if err := config.DeleteTargetToken(args[0]); err != nil {
+				return err
+			}
+		}
 		targets[args[0]] = nil
 
 		// save config
 		err := viper.WriteConfig()
 		if err != nil {
-			return fmt.Errorf("error saving configuration: %w\n\nTip: Check if you have write permissions to the config file", err)
+			return i18n.Errorf("error saving configuration: %w\n\nTip: Check if you have write permissions to the config file", err)
 		}
 
-		fmt.Printf("✓ Successfully removed target '%s' (%s) from target list\n", args[0], endpoint)
+		i18n.Printf("✓ Successfully removed target '%s' (%s) from target list\n", args[0], endpoint)
 		return nil
 	},
 }
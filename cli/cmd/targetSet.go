@@ -1,10 +1,10 @@
 package cmd
 
 import (
-	"fmt"
-
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+
+	"github.com/huskyci-org/huskyCI/pkg/i18n"
 )
 
 // targetSetCmd represents the targetSet command
@@ -42,15 +42,15 @@ Examples:
 			}
 		}
 		if notUsed {
-			return fmt.Errorf("target '%s' does not exist\n\nTip: Use 'huskyci target-list' to see available targets, or 'huskyci target-add' to add a new one", args[0])
+			return i18n.Errorf("target '%s' does not exist\n\nTip: Use 'huskyci target-list' to see available targets, or 'huskyci target-add' to add a new one", args[0])
 		}
 
 		// save config (only if target is found)
 		err := viper.WriteConfig()
 		if err != nil {
-			return fmt.Errorf("error saving configuration: %w\n\nTip: Check if you have write permissions to the config file", err)
+			return i18n.Errorf("error saving configuration: %w\n\nTip: Check if you have write permissions to the config file", err)
 		}
-		fmt.Printf("✓ Successfully set '%s' (%s) as the current target\n", args[0], endpoint)
+		i18n.Printf("✓ Successfully set '%s' (%s) as the current target\n", args[0], endpoint)
 		return nil
 	},
 }
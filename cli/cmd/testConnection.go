@@ -12,8 +12,11 @@ import (
 	"time"
 
 	"github.com/huskyci-org/huskyCI/cli/config"
+	"github.com/huskyci-org/huskyCI/cli/log"
 	"github.com/huskyci-org/huskyCI/cli/types"
 	"github.com/huskyci-org/huskyCI/cli/util"
+	"github.com/huskyci-org/huskyCI/pkg/formatter"
+	"github.com/huskyci-org/huskyCI/pkg/i18n"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -27,8 +30,9 @@ var testConnectionCmd = &cobra.Command{
 This command performs several connection tests:
   1. Basic connectivity to the API endpoint
   2. Health check endpoint (/healthcheck)
-  3. Version endpoint (/version)
-  4. Authentication (if token is configured)
+  3. Readiness of the API dependencies (/readyz)
+  4. Version endpoint (/version)
+  5. Authentication (if token is configured)
 
 You can test:
   - Current target (default)
@@ -76,96 +80,107 @@ type ConnectionTestResult struct {
 	ResponseTime  time.Duration
 	ErrorMessage  string
 	ResponseBody  string
+	Details       []string
 }
 
 // runConnectionTest executes connection tests
 func runConnectionTest(customEndpoint, targetName string, skipAuth bool) error {
+	var target *types.Target
 	var endpoint string
 	var token string
 	var label string
 
-	fmt.Println()
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-	fmt.Println("  🔌 huskyCI API Connection Test")
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-	fmt.Println()
+	formatter.Println()
+	formatter.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	formatter.Println(i18n.T("  🔌 huskyCI API Connection Test"))
+	formatter.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	formatter.Println()
 
 	// Determine endpoint and token
 	if customEndpoint != "" {
 		endpoint = customEndpoint
-		label = "custom endpoint"
+		label = i18n.T("custom endpoint")
 		token = config.GetTokenFromEnv() // Check environment variable for token
-		if IsVerbose() {
-			fmt.Printf("[VERBOSE] Using custom endpoint: %s\n", endpoint)
-		}
+		target = &types.Target{Endpoint: endpoint, Token: token}
+		target.ClientCert, target.ClientKey = config.GetClientCertFromEnv()
+		log.Debug("Using custom endpoint", "endpoint", endpoint)
 	} else {
-		target, err := getTargetForTest(targetName)
+		var err error
+		target, err = getTargetForTest(targetName)
 		if err != nil {
 			return err
 		}
 		endpoint = target.Endpoint
 		token = target.Token
 		label = target.Label
-		fmt.Printf("Testing target: %s\n", label)
-		fmt.Printf("Endpoint: %s\n", endpoint)
-		fmt.Println()
+		i18n.Printf("Testing target: %s\n", label)
+		i18n.Printf("Endpoint: %s\n", endpoint)
+		formatter.Println()
 	}
 
 	// Run tests
 	results := []ConnectionTestResult{}
 
 	// Test 1: Basic connectivity
-	fmt.Println("Test 1: Basic Connectivity")
-	fmt.Println("──────────────────────────────────────────────────────────────────────────────")
-	result := testBasicConnectivity(endpoint)
+	formatter.Println(i18n.T("Test 1: Basic Connectivity"))
+	formatter.Println("──────────────────────────────────────────────────────────────────────────────")
+	result := testBasicConnectivity(target)
 	// If connection refused to a local endpoint, offer to start Docker and retry once
 	if !result.Success && util.IsConnectionRefused(errors.New(result.ErrorMessage)) && util.IsLocalEndpoint(endpoint) {
 		if util.PromptAndStartDocker(os.Stdin) {
-			fmt.Println("  Retrying connection in 5 seconds...")
+			formatter.Println(i18n.T("  Retrying connection in 5 seconds..."))
 			time.Sleep(5 * time.Second)
-			result = testBasicConnectivity(endpoint)
+			result = testBasicConnectivity(target)
 		}
 	}
 	results = append(results, result)
 	printTestResult(result)
-	fmt.Println()
+	formatter.Println()
 
 	if !result.Success {
-		fmt.Println("⚠️  Basic connectivity failed. Skipping remaining tests.")
+		formatter.Println(i18n.T("⚠️  Basic connectivity failed. Skipping remaining tests."))
 		printTestSummary(results)
-		return fmt.Errorf("connection test failed: %s", result.ErrorMessage)
+		return i18n.Errorf("connection test failed: %s", result.ErrorMessage)
 	}
 
 	// Test 2: Health check
-	fmt.Println("Test 2: Health Check Endpoint")
-	fmt.Println("──────────────────────────────────────────────────────────────────────────────")
-	result = testHealthCheck(endpoint)
+	formatter.Println(i18n.T("Test 2: Health Check Endpoint"))
+	formatter.Println("──────────────────────────────────────────────────────────────────────────────")
+	result = testHealthCheck(target)
 	results = append(results, result)
 	printTestResult(result)
-	fmt.Println()
+	formatter.Println()
 
-	// Test 3: Version endpoint
-	fmt.Println("Test 3: Version Endpoint")
-	fmt.Println("──────────────────────────────────────────────────────────────────────────────")
-	result = testVersionEndpoint(endpoint)
+	// Test 3: Readiness
+	formatter.Println(i18n.T("Test 3: Readiness"))
+	formatter.Println("──────────────────────────────────────────────────────────────────────────────")
+	result = testReadiness(target)
 	results = append(results, result)
 	printTestResult(result)
-	fmt.Println()
+	formatter.Println()
 
-	// Test 4: Authentication (if token available and not skipped)
+	// Test 4: Version endpoint
+	formatter.Println(i18n.T("Test 4: Version Endpoint"))
+	formatter.Println("──────────────────────────────────────────────────────────────────────────────")
+	result = testVersionEndpoint(target)
+	results = append(results, result)
+	printTestResult(result)
+	formatter.Println()
+
+	// Test 5: Authentication (if token available and not skipped)
 	if !skipAuth && token != "" {
-		fmt.Println("Test 4: Authentication")
-		fmt.Println("──────────────────────────────────────────────────────────────────────────────")
-		result = testAuthentication(endpoint, token)
+		formatter.Println(i18n.T("Test 5: Authentication"))
+		formatter.Println("──────────────────────────────────────────────────────────────────────────────")
+		result = testAuthentication(target, token)
 		results = append(results, result)
 		printTestResult(result)
-		fmt.Println()
+		formatter.Println()
 	} else if !skipAuth && token == "" {
-		fmt.Println("Test 4: Authentication")
-		fmt.Println("──────────────────────────────────────────────────────────────────────────────")
-		fmt.Println("⚠️  Skipped: No authentication token configured")
-		fmt.Println("   Tip: Set HUSKYCI_CLI_TOKEN or configure token storage")
-		fmt.Println()
+		formatter.Println(i18n.T("Test 5: Authentication"))
+		formatter.Println("──────────────────────────────────────────────────────────────────────────────")
+		formatter.Println(i18n.T("⚠️  Skipped: No authentication token configured"))
+		formatter.Println(i18n.T("   Tip: Set HUSKYCI_CLI_TOKEN or configure token storage"))
+		formatter.Println()
 	}
 
 	// Print summary
@@ -174,7 +189,7 @@ func runConnectionTest(customEndpoint, targetName string, skipAuth bool) error {
 	// Return error if any critical test failed
 	for _, r := range results {
 		if !r.Success && r.TestName != "Authentication" {
-			return fmt.Errorf("connection test failed: %s", r.ErrorMessage)
+			return i18n.Errorf("connection test failed: %s", r.ErrorMessage)
 		}
 	}
 
@@ -190,19 +205,22 @@ func getTargetForTest(targetName string) (*types.Target, error) {
 			targetMap := target.(map[string]interface{})
 			// Get token from environment if available
 			token := config.GetTokenFromEnv()
+			clientCert, clientKey := config.TargetClientCert(targetMap)
 			return &types.Target{
-				Label:    targetName,
-				Endpoint: targetMap["endpoint"].(string),
-				Token:    token,
+				Label:      targetName,
+				Endpoint:   targetMap["endpoint"].(string),
+				Token:      token,
+				ClientCert: clientCert,
+				ClientKey:  clientKey,
 			}, nil
 		}
-		return nil, fmt.Errorf("target '%s' not found\n\nTip: Use 'huskyci target-list' to see available targets", targetName)
+		return nil, i18n.Errorf("target '%s' not found\n\nTip: Use 'huskyci target-list' to see available targets", targetName)
 	}
 
 	// Get current target
 	target, err := config.GetCurrentTarget()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get target: %w\n\nTip: Configure a target using 'huskyci target-add <name> <endpoint>' or 'huskyci setup'", err)
+		return nil, i18n.Errorf("failed to get target: %w\n\nTip: Configure a target using 'huskyci target-add <name> <endpoint>' or 'huskyci setup'", err)
 	}
 
 	return target, nil
@@ -211,21 +229,22 @@ func getTargetForTest(targetName string) (*types.Target, error) {
 // testBasicConnectivity tests basic connectivity to the endpoint
 // A 404 or other 4xx response indicates the server is reachable (just the path doesn't exist)
 // Only 5xx errors or connection failures indicate actual connectivity issues
-func testBasicConnectivity(endpoint string) ConnectionTestResult {
+func testBasicConnectivity(target *types.Target) ConnectionTestResult {
+	endpoint := target.Endpoint
 	start := time.Now()
 	result := ConnectionTestResult{
 		TestName: "Basic Connectivity",
 	}
 
-	client, err := createHTTPClient(endpoint)
+	client, err := createHTTPClient(target)
 	if err != nil {
-		result.ErrorMessage = fmt.Sprintf("Failed to create HTTP client: %v", err)
+		result.ErrorMessage = i18n.Sprintf("Failed to create HTTP client: %v", err)
 		return result
 	}
 
 	req, err := http.NewRequest("GET", endpoint, nil)
 	if err != nil {
-		result.ErrorMessage = fmt.Sprintf("Failed to create request: %v", err)
+		result.ErrorMessage = i18n.Sprintf("Failed to create request: %v", err)
 		return result
 	}
 
@@ -233,7 +252,7 @@ func testBasicConnectivity(endpoint string) ConnectionTestResult {
 	result.ResponseTime = time.Since(start)
 
 	if err != nil {
-		result.ErrorMessage = fmt.Sprintf("Connection failed: %v", err)
+		result.ErrorMessage = i18n.Sprintf("Connection failed: %v", err)
 		return result
 	}
 	defer resp.Body.Close()
@@ -247,39 +266,40 @@ func testBasicConnectivity(endpoint string) ConnectionTestResult {
 	if resp.StatusCode < 500 {
 		result.Success = true
 		if resp.StatusCode == http.StatusNotFound {
-			result.Status = "Endpoint is reachable (404 - path not found, but server is responding)"
+			result.Status = i18n.T("Endpoint is reachable (404 - path not found, but server is responding)")
 		} else if resp.StatusCode < 400 {
-			result.Status = "Endpoint is reachable"
+			result.Status = i18n.T("Endpoint is reachable")
 		} else {
-			result.Status = fmt.Sprintf("Endpoint is reachable (status %d)", resp.StatusCode)
+			result.Status = i18n.Sprintf("Endpoint is reachable (status %d)", resp.StatusCode)
 		}
 	} else {
 		// 5xx errors indicate server issues, but connectivity is still successful
 		result.Success = true
-		result.Status = fmt.Sprintf("Endpoint is reachable but server returned error (status %d)", resp.StatusCode)
-		result.ErrorMessage = fmt.Sprintf("Server error: status %d", resp.StatusCode)
+		result.Status = i18n.Sprintf("Endpoint is reachable but server returned error (status %d)", resp.StatusCode)
+		result.ErrorMessage = i18n.Sprintf("Server error: status %d", resp.StatusCode)
 	}
 
 	return result
 }
 
 // testHealthCheck tests the /healthcheck endpoint
-func testHealthCheck(endpoint string) ConnectionTestResult {
+func testHealthCheck(target *types.Target) ConnectionTestResult {
+	endpoint := target.Endpoint
 	start := time.Now()
 	result := ConnectionTestResult{
 		TestName: "Health Check",
 	}
 
-	client, err := createHTTPClient(endpoint)
+	client, err := createHTTPClient(target)
 	if err != nil {
-		result.ErrorMessage = fmt.Sprintf("Failed to create HTTP client: %v", err)
+		result.ErrorMessage = i18n.Sprintf("Failed to create HTTP client: %v", err)
 		return result
 	}
 
 	healthURL := normalizeURL(endpoint) + "/healthcheck"
 	req, err := http.NewRequest("GET", healthURL, nil)
 	if err != nil {
-		result.ErrorMessage = fmt.Sprintf("Failed to create request: %v", err)
+		result.ErrorMessage = i18n.Sprintf("Failed to create request: %v", err)
 		return result
 	}
 
@@ -287,7 +307,7 @@ func testHealthCheck(endpoint string) ConnectionTestResult {
 	result.ResponseTime = time.Since(start)
 
 	if err != nil {
-		result.ErrorMessage = fmt.Sprintf("Request failed: %v", err)
+		result.ErrorMessage = i18n.Sprintf("Request failed: %v", err)
 		return result
 	}
 	defer resp.Body.Close()
@@ -300,34 +320,105 @@ func testHealthCheck(endpoint string) ConnectionTestResult {
 
 	if resp.StatusCode == http.StatusOK && bodyStr == "WORKING" {
 		result.Success = true
-		result.Status = "Health check passed"
+		result.Status = i18n.T("Health check passed")
 	} else if resp.StatusCode == http.StatusOK {
 		result.Success = true
-		result.Status = fmt.Sprintf("Health check responded (unexpected body: %s)", bodyStr)
+		result.Status = i18n.Sprintf("Health check responded (unexpected body: %s)", bodyStr)
 	} else {
-		result.ErrorMessage = fmt.Sprintf("Health check failed: status %d, body: %s", resp.StatusCode, bodyStr)
+		result.ErrorMessage = i18n.Sprintf("Health check failed: status %d, body: %s", resp.StatusCode, bodyStr)
+	}
+
+	return result
+}
+
+// readinessReport is the state of the dependencies of the API answered by /readyz.
+type readinessReport struct {
+	Status string `json:"status"`
+	Checks []struct {
+		Name      string `json:"name"`
+		Status    string `json:"status"`
+		LatencyMs int64  `json:"latencyMs"`
+		Error     string `json:"error"`
+	} `json:"checks"`
+}
+
+// testReadiness tests the /readyz endpoint and reports the state of each
+// dependency of the API
+func testReadiness(target *types.Target) ConnectionTestResult {
+	endpoint := target.Endpoint
+	start := time.Now()
+	result := ConnectionTestResult{
+		TestName: "Readiness",
+	}
+
+	client, err := createHTTPClient(target)
+	if err != nil {
+		result.ErrorMessage = i18n.Sprintf("Failed to create HTTP client: %v", err)
+		return result
+	}
+
+	resp, err := client.Get(normalizeURL(endpoint) + "/readyz")
+	result.ResponseTime = time.Since(start)
+	if err != nil {
+		result.ErrorMessage = i18n.Sprintf("Request failed: %v", err)
+		return result
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	result.StatusCode = resp.StatusCode
+	result.Status = resp.Status
+	result.ResponseBody = strings.TrimSpace(string(body))
+
+	if resp.StatusCode == http.StatusNotFound {
+		// APIs released before /readyz only have /healthcheck
+		result.Success = true
+		result.Status = i18n.T("Readiness not reported by this API version")
+		return result
 	}
 
+	report := readinessReport{}
+	if err := json.Unmarshal(body, &report); err != nil || report.Status == "" {
+		result.ErrorMessage = i18n.Sprintf("Readiness check failed: status %d, body: %s", resp.StatusCode, result.ResponseBody)
+		return result
+	}
+	down := []string{}
+	for _, check := range report.Checks {
+		detail := fmt.Sprintf("%s: %s (%dms)", check.Name, check.Status, check.LatencyMs)
+		if check.Error != "" {
+			detail += " - " + check.Error
+			down = append(down, check.Name)
+		}
+		result.Details = append(result.Details, detail)
+	}
+	if resp.StatusCode == http.StatusOK {
+		result.Success = true
+		result.Status = i18n.T("API is ready to run analyses")
+		return result
+	}
+	result.Status = i18n.T("API is not ready to run analyses")
+	result.ErrorMessage = i18n.Sprintf("dependencies down: %s", strings.Join(down, ", "))
 	return result
 }
 
 // testVersionEndpoint tests the /version endpoint
-func testVersionEndpoint(endpoint string) ConnectionTestResult {
+func testVersionEndpoint(target *types.Target) ConnectionTestResult {
+	endpoint := target.Endpoint
 	start := time.Now()
 	result := ConnectionTestResult{
 		TestName: "Version Endpoint",
 	}
 
-	client, err := createHTTPClient(endpoint)
+	client, err := createHTTPClient(target)
 	if err != nil {
-		result.ErrorMessage = fmt.Sprintf("Failed to create HTTP client: %v", err)
+		result.ErrorMessage = i18n.Sprintf("Failed to create HTTP client: %v", err)
 		return result
 	}
 
 	versionURL := normalizeURL(endpoint) + "/version"
 	req, err := http.NewRequest("GET", versionURL, nil)
 	if err != nil {
-		result.ErrorMessage = fmt.Sprintf("Failed to create request: %v", err)
+		result.ErrorMessage = i18n.Sprintf("Failed to create request: %v", err)
 		return result
 	}
 
@@ -335,7 +426,7 @@ func testVersionEndpoint(endpoint string) ConnectionTestResult {
 	result.ResponseTime = time.Since(start)
 
 	if err != nil {
-		result.ErrorMessage = fmt.Sprintf("Request failed: %v", err)
+		result.ErrorMessage = i18n.Sprintf("Request failed: %v", err)
 		return result
 	}
 	defer resp.Body.Close()
@@ -348,24 +439,25 @@ func testVersionEndpoint(endpoint string) ConnectionTestResult {
 
 	if resp.StatusCode == http.StatusOK {
 		result.Success = true
-		result.Status = fmt.Sprintf("API Version: %s", bodyStr)
+		result.Status = i18n.Sprintf("API Version: %s", bodyStr)
 	} else {
-		result.ErrorMessage = fmt.Sprintf("Version endpoint failed: status %d, body: %s", resp.StatusCode, bodyStr)
+		result.ErrorMessage = i18n.Sprintf("Version endpoint failed: status %d, body: %s", resp.StatusCode, bodyStr)
 	}
 
 	return result
 }
 
 // testAuthentication tests authentication with the provided token
-func testAuthentication(endpoint, token string) ConnectionTestResult {
+func testAuthentication(target *types.Target, token string) ConnectionTestResult {
+	endpoint := target.Endpoint
 	start := time.Now()
 	result := ConnectionTestResult{
 		TestName: "Authentication",
 	}
 
-	client, err := createHTTPClient(endpoint)
+	client, err := createHTTPClient(target)
 	if err != nil {
-		result.ErrorMessage = fmt.Sprintf("Failed to create HTTP client: %v", err)
+		result.ErrorMessage = i18n.Sprintf("Failed to create HTTP client: %v", err)
 		return result
 	}
 
@@ -385,25 +477,25 @@ func testAuthentication(endpoint, token string) ConnectionTestResult {
 	}
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
-		result.ErrorMessage = fmt.Sprintf("Failed to create request payload: %v", err)
+		result.ErrorMessage = i18n.Sprintf("Failed to create request payload: %v", err)
 		return result
 	}
 
 	req, err := http.NewRequest("POST", testURL, bytes.NewBuffer(jsonData))
 	if err != nil {
-		result.ErrorMessage = fmt.Sprintf("Failed to create request: %v", err)
+		result.ErrorMessage = i18n.Sprintf("Failed to create request: %v", err)
 		return result
 	}
 
 	req.Header.Set("Husky-Token", token)
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("User-Agent", "huskyci-cli")
+	req.Header.Set("User-Agent", config.UserAgent())
 
 	resp, err := client.Do(req)
 	result.ResponseTime = time.Since(start)
 
 	if err != nil {
-		result.ErrorMessage = fmt.Sprintf("Request failed: %v", err)
+		result.ErrorMessage = i18n.Sprintf("Request failed: %v", err)
 		return result
 	}
 	defer resp.Body.Close()
@@ -419,15 +511,15 @@ func testAuthentication(endpoint, token string) ConnectionTestResult {
 	case http.StatusUnauthorized, http.StatusForbidden:
 		// Token is invalid or doesn't have permission
 		// The server checked auth and rejected it
-		result.ErrorMessage = fmt.Sprintf("Authentication failed: token is invalid or expired (status %d)", resp.StatusCode)
+		result.ErrorMessage = i18n.Sprintf("Authentication failed: token is invalid or expired (status %d)", resp.StatusCode)
 		if bodyStr != "" {
 			// Try to extract error message from JSON response
 			var errorResp map[string]interface{}
 			if err := json.Unmarshal(body, &errorResp); err == nil {
 				if msg, ok := errorResp["message"].(string); ok {
-					result.ErrorMessage = fmt.Sprintf("Authentication failed: %s", msg)
+					result.ErrorMessage = i18n.Sprintf("Authentication failed: %s", msg)
 				} else if errMsg, ok := errorResp["error"].(string); ok {
-					result.ErrorMessage = fmt.Sprintf("Authentication failed: %s", errMsg)
+					result.ErrorMessage = i18n.Sprintf("Authentication failed: %s", errMsg)
 				}
 			}
 		}
@@ -444,47 +536,47 @@ func testAuthentication(endpoint, token string) ConnectionTestResult {
 					// If error mentions JSON format, it's a JSON validation error (unlikely)
 					// Otherwise, it's likely URL validation (which happens after auth)
 					if strings.Contains(strings.ToLower(msg), "json") || strings.Contains(strings.ToLower(msg), "format") {
-						result.ErrorMessage = fmt.Sprintf("Unexpected JSON validation error: %s", msg)
+						result.ErrorMessage = i18n.Sprintf("Unexpected JSON validation error: %s", msg)
 					} else {
 						// URL validation error means auth passed!
 						result.Success = true
-						result.Status = "Authentication successful (token is valid)"
-						if IsVerbose() {
-							result.Status += fmt.Sprintf(" - URL validation failed as expected: %s", msg)
+						result.Status = i18n.T("Authentication successful (token is valid)")
+						if log.Verbose() {
+							result.Status += i18n.Sprintf(" - URL validation failed as expected: %s", msg)
 						}
 					}
 				} else {
 					// No message field, assume auth passed (URL validation failed)
 					result.Success = true
-					result.Status = "Authentication successful (token is valid)"
+					result.Status = i18n.T("Authentication successful (token is valid)")
 				}
 			} else {
 				// Couldn't parse JSON, but 400 with our valid JSON likely means auth passed
 				result.Success = true
-				result.Status = "Authentication successful (token is valid)"
+				result.Status = i18n.T("Authentication successful (token is valid)")
 			}
 		} else {
 			// Empty body with 400, assume auth passed
 			result.Success = true
-			result.Status = "Authentication successful (token is valid)"
+			result.Status = i18n.T("Authentication successful (token is valid)")
 		}
 	case http.StatusOK:
 		// Request succeeded completely - authentication successful
 		result.Success = true
-		result.Status = "Authentication successful (token is valid)"
+		result.Status = i18n.T("Authentication successful (token is valid)")
 	default:
 		// Other status codes
 		if resp.StatusCode < 400 {
 			result.Success = true
-			result.Status = fmt.Sprintf("Authentication successful (status %d)", resp.StatusCode)
+			result.Status = i18n.Sprintf("Authentication successful (status %d)", resp.StatusCode)
 		} else if resp.StatusCode >= 500 {
-			result.ErrorMessage = fmt.Sprintf("Server error during authentication test (status %d)", resp.StatusCode)
+			result.ErrorMessage = i18n.Sprintf("Server error during authentication test (status %d)", resp.StatusCode)
 			if bodyStr != "" {
 				result.ErrorMessage += fmt.Sprintf(" - %s", bodyStr)
 			}
 		} else {
 			// Other 4xx errors
-			result.ErrorMessage = fmt.Sprintf("Unexpected response: status %d", resp.StatusCode)
+			result.ErrorMessage = i18n.Sprintf("Unexpected response: status %d", resp.StatusCode)
 			if bodyStr != "" {
 				result.ErrorMessage += fmt.Sprintf(" - %s", bodyStr)
 			}
@@ -497,31 +589,34 @@ func testAuthentication(endpoint, token string) ConnectionTestResult {
 // printTestResult prints the result of a single test
 func printTestResult(result ConnectionTestResult) {
 	if result.Success {
-		fmt.Printf("✓ %s\n", result.Status)
+		formatter.Printf("✓ %s\n", result.Status)
 	} else {
-		fmt.Printf("✗ %s\n", result.Status)
+		formatter.Printf("✗ %s\n", result.Status)
 		if result.ErrorMessage != "" {
-			fmt.Printf("  Error: %s\n", result.ErrorMessage)
+			i18n.Printf("  Error: %s\n", result.ErrorMessage)
 		}
 	}
+	for _, detail := range result.Details {
+		formatter.Printf("  %s\n", detail)
+	}
 
-	if IsVerbose() {
-		fmt.Printf("  Status Code: %d\n", result.StatusCode)
-		fmt.Printf("  Response Time: %v\n", result.ResponseTime)
+	if log.Verbose() {
+		i18n.Printf("  Status Code: %d\n", result.StatusCode)
+		i18n.Printf("  Response Time: %v\n", result.ResponseTime)
 		if result.ResponseBody != "" {
-			fmt.Printf("  Response Body: %s\n", result.ResponseBody)
+			i18n.Printf("  Response Body: %s\n", result.ResponseBody)
 		}
 	} else {
-		fmt.Printf("  Response Time: %v\n", result.ResponseTime)
+		i18n.Printf("  Response Time: %v\n", result.ResponseTime)
 	}
 }
 
 // printTestSummary prints a summary of all test results
 func printTestSummary(results []ConnectionTestResult) {
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-	fmt.Println("  📊 Test Summary")
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-	fmt.Println()
+	formatter.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	formatter.Println(i18n.T("  📊 Test Summary"))
+	formatter.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	formatter.Println()
 
 	passed := 0
 	failed := 0
@@ -530,36 +625,36 @@ func printTestSummary(results []ConnectionTestResult) {
 	for _, result := range results {
 		if result.Success {
 			passed++
-			fmt.Printf("✓ %s\n", result.TestName)
+			formatter.Printf("✓ %s\n", i18n.T(result.TestName))
 		} else if result.ErrorMessage != "" {
 			failed++
-			fmt.Printf("✗ %s: %s\n", result.TestName, result.ErrorMessage)
+			formatter.Printf("✗ %s: %s\n", i18n.T(result.TestName), result.ErrorMessage)
 		} else {
 			skipped++
-			fmt.Printf("⊘ %s (skipped)\n", result.TestName)
+			i18n.Printf("⊘ %s (skipped)\n", i18n.T(result.TestName))
 		}
 	}
 
-	fmt.Println()
-	fmt.Printf("Total: %d passed, %d failed", passed, failed)
+	formatter.Println()
+	i18n.Printf("Total: %d passed, %d failed", passed, failed)
 	if skipped > 0 {
-		fmt.Printf(", %d skipped", skipped)
+		i18n.Printf(", %d skipped", skipped)
 	}
-	fmt.Println()
+	formatter.Println()
 
 	if failed == 0 {
-		fmt.Println()
-		fmt.Println("✅ All connection tests passed!")
-		fmt.Println("   Your huskyCI CLI is properly configured and ready to use.")
+		formatter.Println()
+		formatter.Println(i18n.T("✅ All connection tests passed!"))
+		formatter.Println(i18n.T("   Your huskyCI CLI is properly configured and ready to use."))
 	} else {
-		fmt.Println()
-		fmt.Println("⚠️  Some tests failed. Please check:")
-		fmt.Println("   • Network connectivity")
-		fmt.Println("   • API endpoint URL is correct")
-		fmt.Println("   • API server is running")
-		fmt.Println("   • Authentication token is valid (if required)")
-		fmt.Println()
-		fmt.Println("   Use 'huskyci setup' to reconfigure or 'huskyci target-list' to check targets.")
-	}
-	fmt.Println()
+		formatter.Println()
+		formatter.Println(i18n.T("⚠️  Some tests failed. Please check:"))
+		formatter.Println(i18n.T("   • Network connectivity"))
+		formatter.Println(i18n.T("   • API endpoint URL is correct"))
+		formatter.Println(i18n.T("   • API server is running"))
+		formatter.Println(i18n.T("   • Authentication token is valid (if required)"))
+		formatter.Println()
+		formatter.Println(i18n.T("   Use 'huskyci setup' to reconfigure or 'huskyci target-list' to check targets."))
+	}
+	formatter.Println()
 }
@@ -3,6 +3,8 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/huskyci-org/huskyCI/cli/config"
+	"github.com/huskyci-org/huskyCI/pkg/i18n"
 	"github.com/spf13/cobra"
 )
 
@@ -19,7 +21,17 @@ Examples:
   # Show version
   huskyci version`,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("huskyCI CLI version: 0.12")
-		fmt.Println("For more information, visit: https://github.com/huskyci-org/huskyCI")
+		version := config.Version
+		if version == "" {
+			version = "dev"
+		}
+		i18n.Printf("huskyCI CLI version: %s\n", version)
+		if config.Commit != "" {
+			i18n.Printf("Commit: %s\n", config.Commit)
+		}
+		if config.BuildDate != "" {
+			i18n.Printf("Build date: %s\n", config.BuildDate)
+		}
+		fmt.Println(i18n.T("For more information, visit: https://github.com/huskyci-org/huskyCI"))
 	},
 }
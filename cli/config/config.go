@@ -7,8 +7,10 @@ package config
 import (
 	"fmt"
 	"os"
+	"sort"
 
 	"github.com/huskyci-org/huskyCI/cli/types"
+	"github.com/huskyci-org/huskyCI/pkg/i18n"
 	"github.com/spf13/viper"
 )
 
@@ -19,6 +21,12 @@ func GetTokenFromEnv() string {
 	return os.Getenv("HUSKYCI_CLI_TOKEN")
 }
 
+// GetClientCertFromEnv returns the files of the client certificate and key
+// set in HUSKYCI_CLIENT_API_CERT_FILE and HUSKYCI_CLIENT_API_KEY_FILE.
+func GetClientCertFromEnv() (string, string) {
+	return os.Getenv("HUSKYCI_CLIENT_API_CERT_FILE"), os.Getenv("HUSKYCI_CLIENT_API_KEY_FILE")
+}
+
 // GetCurrentTarget return a types.Target with current target
 func GetCurrentTarget() (*types.Target, error) {
 	// Get current targets
@@ -29,6 +37,7 @@ func GetCurrentTarget() (*types.Target, error) {
 		currentTarget.Label = "env-var"
 		currentTarget.TokenStorage = "env-var"
 		currentTarget.Token = GetTokenFromEnv()
+		currentTarget.ClientCert, currentTarget.ClientKey = GetClientCertFromEnv()
 	} else {
 		targets := viper.GetStringMap("targets")
 		for k, v := range targets {
@@ -36,7 +45,7 @@ func GetCurrentTarget() (*types.Target, error) {
 
 			// check if target is properly configured
 			if target["current"] == nil {
-				return nil, fmt.Errorf("You need to configure a target using target-add command")
+				return nil, i18n.Errorf("You need to configure a target using target-add command")
 			}
 
 			// format output for activated target
@@ -46,15 +55,18 @@ func GetCurrentTarget() (*types.Target, error) {
 
 				// check token storage
 				if target["token-storage"] == nil {
-					fmt.Fprintf(os.Stderr, "Token storage is not set. You can set it using the -s flag at 'huskyci login' command\n")
+					i18n.Fprintf(os.Stderr, "Token storage is not set. You can set it using the -s flag at 'huskyci login' command\n")
 					currentTarget.TokenStorage = ""
 				} else {
 					currentTarget.TokenStorage = target["token-storage"].(string)
 				}
 
-				// Always check for token from environment variable
-				currentTarget.Token = GetTokenFromEnv()
-
+				token, err := targetToken(k, currentTarget.TokenStorage)
+				if err != nil {
+					return nil, err
+				}
+				currentTarget.Token = token
+				currentTarget.ClientCert, currentTarget.ClientKey = TargetClientCert(target)
 			}
 		}
 
@@ -63,6 +75,63 @@ func GetCurrentTarget() (*types.Target, error) {
 	return currentTarget, nil
 }
 
+// GetTargets returns the configured targets named in names, or all of them
+// sorted by name if names is empty, along with their tokens.
+func GetTargets(names []string) ([]*types.Target, error) {
+	targets := viper.GetStringMap("targets")
+	if len(names) == 0 {
+		for name, v := range targets {
+			if v != nil {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+	}
+	if len(names) == 0 {
+		return nil, i18n.Errorf("You need to configure a target using target-add command")
+	}
+	found := make([]*types.Target, 0, len(names))
+	for _, name := range names {
+		target, ok := targets[name].(map[string]interface{})
+		if !ok {
+			return nil, i18n.Errorf("target '%s' is not configured\n\nTip: List the configured targets with 'huskyci target-list'", name)
+		}
+		endpoint, _ := target["endpoint"].(string)
+		tokenStorage, _ := target["token-storage"].(string)
+		token, err := targetToken(name, tokenStorage)
+		if err != nil {
+			return nil, err
+		}
+		clientCert, clientKey := TargetClientCert(target)
+		found = append(found, &types.Target{Label: name, Endpoint: endpoint, TokenStorage: tokenStorage, Token: token, ClientCert: clientCert, ClientKey: clientKey})
+	}
+	return found, nil
+}
+
+// targetToken returns the token of the target name. The token from environment
+// variable takes precedence over the stored one.
+func targetToken(name, tokenStorage string) (string, error) {
+	if token := GetTokenFromEnv(); token != "" {
+		return token, nil
+	}
+	if tokenStorage == TokenStorageKeyring {
+		return GetTargetToken(name)
+	}
+	return "", nil
+}
+
+// TargetClientCert returns the files of the client certificate and key of
+// target. The ones from environment variables take precedence over the
+// configured ones.
+func TargetClientCert(target map[string]interface{}) (string, string) {
+	if certFile, keyFile := GetClientCertFromEnv(); certFile != "" || keyFile != "" {
+		return certFile, keyFile
+	}
+	certFile, _ := target["client-cert"].(string)
+	keyFile, _ := target["client-key"].(string)
+	return certFile, keyFile
+}
+
 // CheckAndCreateConfigFolder check if config folder exists and create if it doesn't exists
 func CheckAndCreateConfigFolder(home string, debug bool) (string, error) {
 	// check if .huskyci folder exists and creates if it not exists
@@ -71,13 +140,13 @@ func CheckAndCreateConfigFolder(home string, debug bool) (string, error) {
 		err := os.Mkdir(path, 0750)
 		if err != nil {
 			if debug {
-				fmt.Fprintf(os.Stderr, "Client error creating config folder: %s (%s)\n", path, err.Error())
+				i18n.Fprintf(os.Stderr, "Client error creating config folder: %s (%s)\n", path, err.Error())
 			}
 			return "", err
 		}
 	}
 	if debug {
-		fmt.Fprintf(os.Stderr, "Client created config folder: %s\n", path)
+		i18n.Fprintf(os.Stderr, "Client created config folder: %s\n", path)
 	}
 	return path, nil
 }
@@ -88,19 +157,19 @@ func CreateConfigFile(path string, debug bool) (string, error) {
 	file, err := os.Create(configFile)
 	if err != nil {
 		if debug {
-			fmt.Fprintf(os.Stderr, "Client error creating config file: %s (%s)\n", configFile, err.Error())
+			i18n.Fprintf(os.Stderr, "Client error creating config file: %s (%s)\n", configFile, err.Error())
 		}
 		return "", err
 	}
 	err = file.Close()
 	if err != nil {
 		if debug {
-			fmt.Fprintf(os.Stderr, "Client error closing config file: %s (%s)\n", configFile, err.Error())
+			i18n.Fprintf(os.Stderr, "Client error closing config file: %s (%s)\n", configFile, err.Error())
 		}
 		return "", err
 	}
 	if debug {
-		fmt.Fprintf(os.Stderr, "Client created new config file: %s\n", configFile)
+		i18n.Fprintf(os.Stderr, "Client created new config file: %s\n", configFile)
 	}
 	return configFile, nil
 }
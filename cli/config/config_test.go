@@ -83,6 +83,45 @@ func TestGetCurrentTarget(t *testing.T) {
 		})
 }
 
+func TestGetTargets(t *testing.T) {
+	os.Setenv("HUSKYCI_CLI_TOKEN", "token")
+	defer os.Unsetenv("HUSKYCI_CLI_TOKEN")
+	viper.Set("targets", map[string]interface{}{
+		"staging": map[string]interface{}{"endpoint": "https://staging.example.com"},
+		"prod":    map[string]interface{}{"current": true, "endpoint": "https://prod.example.com"},
+	})
+
+	targets, err := GetTargets(nil)
+	if err != nil {
+		t.Fatalf("CONFIG: fail to read every target (%v)", err)
+	}
+	if len(targets) != 2 || targets[0].Label != "prod" || targets[1].Label != "staging" || targets[1].Token != "token" {
+		t.Fatalf("CONFIG: unexpected targets (%+v, %+v)", targets[0], targets[1])
+	}
+	targets, err = GetTargets([]string{"staging"})
+	if err != nil || len(targets) != 1 || targets[0].Endpoint != "https://staging.example.com" {
+		t.Fatalf("CONFIG: fail to read the staging target (%v)", err)
+	}
+	if _, err := GetTargets([]string{"unknown"}); err == nil {
+		t.Fatalf("CONFIG: an unknown target was found")
+	}
+}
+
+func TestTargetClientCert(t *testing.T) {
+	target := map[string]interface{}{"endpoint": "https://prod.example.com", "client-cert": "client.pem", "client-key": "client-key.pem"}
+	if certFile, keyFile := TargetClientCert(target); certFile != "client.pem" || keyFile != "client-key.pem" {
+		t.Fatalf("CONFIG: fail to read the client certificate of the target (%s, %s)", certFile, keyFile)
+	}
+
+	os.Setenv("HUSKYCI_CLIENT_API_CERT_FILE", "env.pem")
+	os.Setenv("HUSKYCI_CLIENT_API_KEY_FILE", "env-key.pem")
+	defer os.Unsetenv("HUSKYCI_CLIENT_API_CERT_FILE")
+	defer os.Unsetenv("HUSKYCI_CLIENT_API_KEY_FILE")
+	if certFile, keyFile := TargetClientCert(target); certFile != "env.pem" || keyFile != "env-key.pem" {
+		t.Fatalf("CONFIG: the client certificate from env vars did not take precedence (%s, %s)", certFile, keyFile)
+	}
+}
+
 func TestCheckAndCreateConfigFolder(t *testing.T) {
 	t.Run(
 		"Test CheckAndCreateConfigFolder()",
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"errors"
+
+	"github.com/huskyci-org/huskyCI/pkg/i18n"
+	"github.com/spf13/viper"
+	"github.com/zalando/go-keyring"
+)
+
+// TokenStorageKeyring is the token-storage of the targets whose token is kept
+// in the OS keychain: Keychain on macOS, Secret Service on Linux and
+// Credential Manager on Windows.
+const TokenStorageKeyring = "keyring"
+
+// keyringService is the service the target tokens are stored under, with the
+// target name as the user.
+const keyringService = "huskyci"
+
+// SaveTargetToken stores the token of target in the OS keychain and sets the
+// target token-storage to keyring.
+func SaveTargetToken(target, token string) error {
+	targets := viper.GetStringMap("targets")
+	targetConfig, ok := targets[target].(map[string]interface{})
+	if !ok {
+		return i18n.Errorf("target '%s' does not exist", target)
+	}
+	if err := keyring.Set(keyringService, target, token); err != nil {
+		return i18n.Errorf("could not store the token in the OS keychain: %w", err)
+	}
+	targetConfig["token-storage"] = TokenStorageKeyring
+	viper.Set("targets", targets)
+	return viper.WriteConfig()
+}
+
+// GetTargetToken returns the token of target stored in the OS keychain, or an
+// empty string if there is none.
+func GetTargetToken(target string) (string, error) {
+	token, err := keyring.Get(keyringService, target)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", i18n.Errorf("could not read the token of target '%s' from the OS keychain: %w", target, err)
+	}
+	return token, nil
+}
+
+// DeleteTargetToken removes the token of target from the OS keychain, if any.
+func DeleteTargetToken(target string) error {
+	err := keyring.Delete(keyringService, target)
+	if err != nil && !errors.Is(err, keyring.ErrNotFound) {
+		return i18n.Errorf("could not remove the token of target '%s' from the OS keychain: %w", target, err)
+	}
+	return nil
+}
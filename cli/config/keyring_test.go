@@ -0,0 +1,54 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/zalando/go-keyring"
+)
+
+func TestTargetTokenInKeyring(t *testing.T) {
+	keyring.MockInit()
+	os.Unsetenv("HUSKYCI_CLIENT_API_ADDR")
+	os.Unsetenv("HUSKYCI_CLI_TOKEN")
+	viper.SetConfigFile(filepath.Join(t.TempDir(), "config.yaml"))
+	viper.Set("targets", map[string]interface{}{
+		"keyring": map[string]interface{}{"current": true, "endpoint": "https://keyring.example.com"},
+	})
+
+	if err := SaveTargetToken("keyring", "secret"); err != nil {
+		t.Fatalf("CONFIG: fail to store token in keyring (%v)", err)
+	}
+	currentTarget, err := GetCurrentTarget()
+	if err != nil {
+		t.Fatalf("CONFIG: fail to read target (%v)", err)
+	}
+	if currentTarget.TokenStorage != TokenStorageKeyring || currentTarget.Token != "secret" {
+		t.Fatalf("CONFIG: fail to read token from keyring (%+v)", currentTarget)
+	}
+
+	os.Setenv("HUSKYCI_CLI_TOKEN", "from-env")
+	defer os.Unsetenv("HUSKYCI_CLI_TOKEN")
+	currentTarget, err = GetCurrentTarget()
+	if err != nil || currentTarget.Token != "from-env" {
+		t.Fatalf("CONFIG: token from env var should take precedence (%+v, %v)", currentTarget, err)
+	}
+
+	if err := DeleteTargetToken("keyring"); err != nil {
+		t.Fatalf("CONFIG: fail to remove token from keyring (%v)", err)
+	}
+	if token, err := GetTargetToken("keyring"); err != nil || token != "" {
+		t.Fatalf("CONFIG: token still in keyring (%q, %v)", token, err)
+	}
+}
+
+func TestSaveTargetTokenUnknownTarget(t *testing.T) {
+	keyring.MockInit()
+	viper.Set("targets", map[string]interface{}{})
+
+	if err := SaveTargetToken("missing", "secret"); err == nil {
+		t.Fatal("CONFIG: token stored for a missing target")
+	}
+}
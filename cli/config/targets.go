@@ -0,0 +1,146 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/huskyci-org/huskyCI/pkg/i18n"
+	"github.com/spf13/viper"
+	"go.yaml.in/yaml/v3"
+)
+
+var targetNameRegexp = regexp.MustCompile(`^\w+$`)
+
+// ErrTargetExists is returned by ImportTargets when a target of the file is
+// already configured and overwrite is not set.
+var ErrTargetExists = errors.New("targets already exist")
+
+// TargetsFile is the YAML document written by target export and read by
+// target import. It has the layout of the targets of the config file, without
+// the token-storage, as tokens are never exported.
+type TargetsFile struct {
+	Targets map[string]TargetEntry `yaml:"targets"`
+}
+
+// TargetEntry is a target of a TargetsFile.
+type TargetEntry struct {
+	Endpoint string `yaml:"endpoint"`
+	Current  bool   `yaml:"current,omitempty"`
+}
+
+// ValidateTarget checks that name contains only letters, numbers and
+// underscores and that endpoint is a URL with a scheme and a host.
+func ValidateTarget(name, endpoint string) error {
+	if !targetNameRegexp.MatchString(name) {
+		return i18n.Errorf("invalid target name '%s': target name must contain only letters, numbers, and underscores", name)
+	}
+	parsedURL, err := url.Parse(endpoint)
+	if err != nil {
+		return i18n.Errorf("invalid endpoint URL '%s' of target '%s': %w", endpoint, name, err)
+	}
+	if parsedURL.Scheme == "" || parsedURL.Host == "" {
+		return i18n.Errorf("invalid endpoint URL '%s' of target '%s': URL must include scheme (http:// or https://) and host", endpoint, name)
+	}
+	return nil
+}
+
+// ExportTargets returns the YAML TargetsFile of the configured targets named
+// in names, or of all of them if names is empty.
+func ExportTargets(names ...string) ([]byte, error) {
+	targets := viper.GetStringMap("targets")
+	if len(names) == 0 {
+		for name, v := range targets {
+			if v != nil {
+				names = append(names, name)
+			}
+		}
+	}
+	if len(names) == 0 {
+		return nil, errors.New(i18n.T("there are no targets to export"))
+	}
+
+	file := TargetsFile{Targets: map[string]TargetEntry{}}
+	for _, name := range names {
+		target, ok := targets[name].(map[string]interface{})
+		if !ok {
+			return nil, i18n.Errorf("target '%s' does not exist", name)
+		}
+		endpoint, _ := target["endpoint"].(string)
+		current, _ := target["current"].(bool)
+		file.Targets[name] = TargetEntry{Endpoint: endpoint, Current: current}
+	}
+	return yaml.Marshal(file)
+}
+
+// ImportTargets adds the targets of the YAML TargetsFile data to the config
+// file and returns their names. Targets that already exist are an error unless
+// overwrite is set, in which case their endpoint is replaced and the token kept.
+// A target marked as current in data becomes the current target.
+func ImportTargets(data []byte, overwrite bool) ([]string, error) {
+	file := TargetsFile{}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, i18n.Errorf("invalid targets file: %w", err)
+	}
+	if len(file.Targets) == 0 {
+		return nil, errors.New(i18n.T("invalid targets file: no targets found"))
+	}
+
+	names := make([]string, 0, len(file.Targets))
+	newCurrent := ""
+	for name, entry := range file.Targets {
+		if err := ValidateTarget(name, entry.Endpoint); err != nil {
+			return nil, err
+		}
+		if entry.Current {
+			if newCurrent != "" {
+				return nil, i18n.Errorf("invalid targets file: both '%s' and '%s' are marked as current", newCurrent, name)
+			}
+			newCurrent = name
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	targets := viper.GetStringMap("targets")
+	if !overwrite {
+		var existing []string
+		for _, name := range names {
+			if targets[name] != nil {
+				existing = append(existing, name)
+			}
+		}
+		if len(existing) > 0 {
+			return nil, fmt.Errorf("%w: %s", ErrTargetExists, strings.Join(existing, ", "))
+		}
+	}
+
+	if newCurrent != "" {
+		for _, v := range targets {
+			if target, ok := v.(map[string]interface{}); ok {
+				target["current"] = false
+			}
+		}
+	}
+	for _, name := range names {
+		entry := file.Targets[name]
+		target, ok := targets[name].(map[string]interface{})
+		if !ok {
+			target = map[string]interface{}{"current": false}
+			targets[name] = target
+		}
+		target["endpoint"] = entry.Endpoint
+		if entry.Current {
+			target["current"] = true
+		}
+	}
+
+	viper.Set("targets", targets)
+	if err := viper.WriteConfig(); err != nil {
+		return nil, i18n.Errorf("error saving configuration: %w", err)
+	}
+	return names, nil
+}
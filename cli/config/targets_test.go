@@ -0,0 +1,77 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestExportImportTargets(t *testing.T) {
+	viper.SetConfigFile(filepath.Join(t.TempDir(), "config.yaml"))
+	viper.Set("targets", map[string]interface{}{
+		"production": map[string]interface{}{"current": true, "endpoint": "https://api.example.com", "token-storage": TokenStorageKeyring},
+		"staging":    map[string]interface{}{"current": false, "endpoint": "https://staging.example.com"},
+	})
+
+	exported, err := ExportTargets()
+	if err != nil {
+		t.Fatalf("CONFIG: fail to export targets (%v)", err)
+	}
+	expected := "targets:\n    production:\n        endpoint: https://api.example.com\n        current: true\n    staging:\n        endpoint: https://staging.example.com\n"
+	if string(exported) != expected {
+		t.Fatalf("CONFIG: unexpected export\n%s", exported)
+	}
+
+	viper.Set("targets", map[string]interface{}{
+		"local": map[string]interface{}{"current": true, "endpoint": "http://localhost:8888"},
+	})
+	names, err := ImportTargets(exported, false)
+	if err != nil {
+		t.Fatalf("CONFIG: fail to import targets (%v)", err)
+	}
+	if !reflect.DeepEqual(names, []string{"production", "staging"}) {
+		t.Fatalf("CONFIG: unexpected imported targets %v", names)
+	}
+	currentTarget, err := GetCurrentTarget()
+	if err != nil || currentTarget.Label != "production" {
+		t.Fatalf("CONFIG: imported current target not set (%+v, %v)", currentTarget, err)
+	}
+	if currentTarget.TokenStorage != "" {
+		t.Fatalf("CONFIG: token-storage should not be imported (%+v)", currentTarget)
+	}
+
+	if _, err := ImportTargets(exported, false); err == nil {
+		t.Fatal("CONFIG: existing targets imported without overwrite")
+	}
+	if _, err := ImportTargets([]byte("targets:\n  staging:\n    endpoint: https://new.example.com\n"), true); err != nil {
+		t.Fatalf("CONFIG: fail to overwrite target (%v)", err)
+	}
+	staging := viper.GetStringMap("targets")["staging"].(map[string]interface{})
+	if staging["endpoint"] != "https://new.example.com" {
+		t.Fatalf("CONFIG: target not overwritten (%v)", staging)
+	}
+	currentTarget, err = GetCurrentTarget()
+	if err != nil || currentTarget.Label != "production" {
+		t.Fatalf("CONFIG: current target changed by overwrite (%+v, %v)", currentTarget, err)
+	}
+}
+
+func TestImportTargetsInvalid(t *testing.T) {
+	viper.SetConfigFile(filepath.Join(t.TempDir(), "config.yaml"))
+	viper.Set("targets", map[string]interface{}{})
+
+	invalid := map[string]string{
+		"empty":       "targets: {}\n",
+		"bad name":    "targets:\n  bad-name:\n    endpoint: https://api.example.com\n",
+		"no scheme":   "targets:\n  prod:\n    endpoint: api.example.com\n",
+		"two current": "targets:\n  a:\n    endpoint: https://a.example.com\n    current: true\n  b:\n    endpoint: https://b.example.com\n    current: true\n",
+		"not yaml":    "targets: [",
+	}
+	for name, data := range invalid {
+		if _, err := ImportTargets([]byte(data), false); err == nil {
+			t.Errorf("CONFIG: %s targets file imported", name)
+		}
+	}
+}
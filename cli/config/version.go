@@ -0,0 +1,17 @@
+package config
+
+import "github.com/huskyci-org/huskyCI/pkg/sdk"
+
+// Build information of the CLI, set by main from the flags the Makefile links
+// into it. They are empty for development builds.
+var (
+	Version   string
+	Commit    string
+	BuildDate string
+)
+
+// UserAgent returns the User-Agent the CLI sends to huskyCI API, with its
+// version.
+func UserAgent() string {
+	return sdk.UserAgent("huskyci-cli", Version)
+}
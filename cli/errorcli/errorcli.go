@@ -2,8 +2,10 @@ package errorcli
 
 import (
 	"errors"
-	"fmt"
 	"os"
+
+	"github.com/huskyci-org/huskyCI/pkg/formatter"
+	"github.com/huskyci-org/huskyCI/pkg/i18n"
 )
 
 var (
@@ -13,8 +15,8 @@ var (
 
 // Handle prints the error message in the cli format
 func Handle(errorFound error) {
-	fmt.Fprintf(os.Stderr, "\n[HUSKYCI] ❌ Error: %s\n\n", errorFound)
-	fmt.Fprintf(os.Stderr, "Tip: Use 'huskyci --help' for more information about available commands.\n")
-	fmt.Fprintf(os.Stderr, "For troubleshooting, visit: https://github.com/huskyci-org/huskyCI/wiki\n\n")
+	i18n.Fprintf(formatter.Stderr, "\n[HUSKYCI] ❌ Error: %s\n\n", errorFound)
+	i18n.Fprintf(formatter.Stderr, "Tip: Use 'huskyci --help' for more information about available commands.\n")
+	i18n.Fprintf(formatter.Stderr, "For troubleshooting, visit: https://github.com/huskyci-org/huskyCI/wiki\n\n")
 	os.Exit(1)
 }
@@ -0,0 +1,300 @@
+// Package fix turns the upgrades the huskyCI API computes for vulnerable
+// dependencies into changes of the manifests of a project: requirements files
+// for PyPI packages and package.json for npm packages.
+package fix
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/huskyci-org/huskyCI/cli/vulnerability"
+	"github.com/huskyci-org/huskyCI/pkg/i18n"
+	"github.com/huskyci-org/huskyCI/pkg/packagejson"
+)
+
+// Ecosystems of the dependencies huskyCI suggests upgrades for.
+const (
+	PyPI = "pypi"
+	Npm  = "npm"
+)
+
+// maxDepth is how deep manifests are looked for below the project root, as the
+// huskyCI API does when it looks for requirements files.
+const maxDepth = 3
+
+// Suggestion is the upgrade of a dependency fixing all of its advisories.
+type Suggestion struct {
+	Ecosystem string
+	Package   string
+	Version   string
+}
+
+// Change is a line of a manifest rewritten to apply a Suggestion.
+type Change struct {
+	File       string
+	Line       int
+	Old        string
+	New        string
+	Suggestion Suggestion
+}
+
+// ecosystems maps the securityTests reporting vulnerable dependencies to the
+// ecosystem of the packages they report.
+var ecosystems = map[string]string{
+	"safety":    PyPI,
+	"npmaudit":  Npm,
+	"yarnaudit": Npm,
+}
+
+// Suggestions returns the upgrades fixing vulns, one per dependency: the
+// highest of the fix versions of its advisories. Vulnerabilities marked nosec
+// and those without a fix version are left out.
+func Suggestions(vulns []vulnerability.Vulnerability) []Suggestion {
+	byPackage := map[string]Suggestion{}
+	for _, vuln := range vulns {
+		ecosystem, ok := ecosystems[vuln.SecurityTest]
+		if !ok || vuln.Nosec || vuln.Package == "" || vuln.FixVersion == "" {
+			continue
+		}
+		key := ecosystem + "/" + packageKey(ecosystem, vuln.Package)
+		current, found := byPackage[key]
+		if !found || compareVersions(vuln.FixVersion, current.Version) > 0 {
+			byPackage[key] = Suggestion{Ecosystem: ecosystem, Package: vuln.Package, Version: vuln.FixVersion}
+		}
+	}
+	suggestions := []Suggestion{}
+	for _, suggestion := range byPackage {
+		suggestions = append(suggestions, suggestion)
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Ecosystem != suggestions[j].Ecosystem {
+			return suggestions[i].Ecosystem < suggestions[j].Ecosystem
+		}
+		return suggestions[i].Package < suggestions[j].Package
+	})
+	return suggestions
+}
+
+// Plan returns the changes applying suggestions to the manifests found below
+// root, and the suggestions no manifest declares the dependency of, such as
+// transitive npm dependencies.
+func Plan(root string, suggestions []Suggestion) ([]Change, []Suggestion, error) {
+	manifests, err := findManifests(root)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	changes := []Change{}
+	unmatched := []Suggestion{}
+	for _, suggestion := range suggestions {
+		matched := false
+		for _, manifest := range manifests[suggestion.Ecosystem] {
+			var manifestChanges []Change
+			var declared bool
+			if suggestion.Ecosystem == PyPI {
+				manifestChanges, declared, err = planRequirements(manifest, suggestion)
+			} else {
+				manifestChanges, declared, err = planPackageJSON(manifest, suggestion)
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			matched = matched || declared
+			changes = append(changes, manifestChanges...)
+		}
+		if !matched {
+			unmatched = append(unmatched, suggestion)
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].File != changes[j].File {
+			return changes[i].File < changes[j].File
+		}
+		return changes[i].Line < changes[j].Line
+	})
+	return changes, unmatched, nil
+}
+
+// Apply rewrites the lines of changes in their manifests. It fails without
+// writing a manifest if one of its lines is no longer the one planned.
+func Apply(changes []Change) error {
+	byFile := map[string][]Change{}
+	files := []string{}
+	for _, change := range changes {
+		if _, found := byFile[change.File]; !found {
+			files = append(files, change.File)
+		}
+		byFile[change.File] = append(byFile[change.File], change)
+	}
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		lines := strings.Split(string(content), "\n")
+		for _, change := range byFile[file] {
+			if change.Line < 1 || change.Line > len(lines) || lines[change.Line-1] != change.Old {
+				return i18n.Errorf("%s changed since the fixes were planned, line %d", file, change.Line)
+			}
+			lines[change.Line-1] = change.New
+		}
+		if err := os.WriteFile(file, []byte(strings.Join(lines, "\n")), info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findManifests returns the requirements files and package.json files below
+// root by ecosystem, skipping dependency and VCS directories.
+func findManifests(root string) (map[string][]string, error) {
+	manifests := map[string][]string{}
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		depth := len(strings.Split(filepath.ToSlash(rel), "/"))
+		if entry.IsDir() {
+			switch entry.Name() {
+			case ".git", "node_modules", "vendor", "venv", ".venv", "__pycache__":
+				return filepath.SkipDir
+			}
+			if rel != "." && depth >= maxDepth {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		name := entry.Name()
+		switch {
+		case name == "package.json":
+			manifests[Npm] = append(manifests[Npm], path)
+		case strings.HasPrefix(name, "requirements") && strings.HasSuffix(name, ".txt"):
+			manifests[PyPI] = append(manifests[PyPI], path)
+		}
+		return nil
+	})
+	return manifests, err
+}
+
+var requirementRegexp = regexp.MustCompile(`^(\s*)([A-Za-z0-9][A-Za-z0-9._-]*)(\s*\[[^\]]*\])?(\s*)([^;#]*?)(\s*(?:[;#].*)?)$`)
+
+// planRequirements returns the changes applying suggestion to a requirements
+// file and whether it declares the dependency at all.
+func planRequirements(file string, suggestion Suggestion) ([]Change, bool, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, false, err
+	}
+	changes := []Change{}
+	declared := false
+	for i, line := range strings.Split(string(content), "\n") {
+		match := requirementRegexp.FindStringSubmatch(line)
+		if match == nil || packageKey(PyPI, match[2]) != packageKey(PyPI, suggestion.Package) {
+			continue
+		}
+		declared = true
+		specifier := strings.TrimSpace(match[5])
+		if pinned := strings.TrimPrefix(specifier, "=="); pinned != specifier && !strings.ContainsAny(pinned, ",<>!=~*") {
+			if compareVersions(pinned, suggestion.Version) >= 0 {
+				continue
+			}
+			specifier = "==" + suggestion.Version
+		} else {
+			specifier = ">=" + suggestion.Version
+		}
+		newLine := match[1] + match[2] + match[3] + match[4] + specifier + match[6]
+		if newLine != line {
+			changes = append(changes, Change{File: file, Line: i + 1, Old: line, New: newLine, Suggestion: suggestion})
+		}
+	}
+	return changes, declared, nil
+}
+
+// planPackageJSON returns the changes applying suggestion to a package.json
+// and whether it declares the dependency at all. The lines of the file are
+// edited in place so its formatting is kept, and the range prefix (^, ~ or >=)
+// of the dependency too.
+func planPackageJSON(file string, suggestion Suggestion) ([]Change, bool, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, false, err
+	}
+	manifest, err := packagejson.Parse(content)
+	if err != nil {
+		return nil, false, i18n.Errorf("could not parse %s: %w", file, err)
+	}
+	specs := manifest.Specs(suggestion.Package)
+	if len(specs) == 0 {
+		return nil, false, nil
+	}
+
+	dependencyRegexp := regexp.MustCompile(`^(\s*"` + regexp.QuoteMeta(suggestion.Package) + `"\s*:\s*")([^"]*)(".*)$`)
+	changes := []Change{}
+	for i, line := range strings.Split(string(content), "\n") {
+		match := dependencyRegexp.FindStringSubmatch(line)
+		if match == nil || !specs[match[2]] || !packagejson.VersionSpecRegexp.MatchString(match[2]) {
+			continue
+		}
+		prefix := packagejson.VersionSpecRegexp.FindStringSubmatch(match[2])[1]
+		current := strings.TrimPrefix(strings.TrimPrefix(match[2], prefix), "v")
+		if !strings.ContainsAny(current, " <>|*xX") && compareVersions(current, suggestion.Version) >= 0 {
+			continue
+		}
+		newLine := match[1] + prefix + suggestion.Version + match[3]
+		changes = append(changes, Change{File: file, Line: i + 1, Old: line, New: newLine, Suggestion: suggestion})
+	}
+	return changes, true, nil
+}
+
+var pypiNameSeparators = regexp.MustCompile(`[-_.]+`)
+
+// packageKey returns the name a package is known by in its ecosystem: PyPI
+// names are case insensitive and treat "-", "_" and "." alike.
+func packageKey(ecosystem, name string) string {
+	if ecosystem == PyPI {
+		return pypiNameSeparators.ReplaceAllString(strings.ToLower(name), "-")
+	}
+	return name
+}
+
+// compareVersions returns -1, 0 or 1 when version a is lower than, equal to
+// or greater than version b, comparing their dotted numbers.
+func compareVersions(a, b string) int {
+	partsA := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	partsB := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(partsA) || i < len(partsB); i++ {
+		numberA, numberB := 0, 0
+		if i < len(partsA) {
+			numberA = leadingNumber(partsA[i])
+		}
+		if i < len(partsB) {
+			numberB = leadingNumber(partsB[i])
+		}
+		if numberA != numberB {
+			if numberA < numberB {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func leadingNumber(part string) int {
+	i := 0
+	for i < len(part) && part[i] >= '0' && part[i] <= '9' {
+		i++
+	}
+	number, _ := strconv.Atoi(part[:i])
+	return number
+}
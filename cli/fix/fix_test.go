@@ -0,0 +1,102 @@
+package fix
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/huskyci-org/huskyCI/cli/vulnerability"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSuggestions(t *testing.T) {
+	vulns := []vulnerability.Vulnerability{
+		{SecurityTest: "safety", Package: "Django", FixVersion: "2.2.24"},
+		{SecurityTest: "safety", Package: "django", FixVersion: "2.2.28"},
+		{SecurityTest: "npmaudit", Package: "lodash", FixVersion: "4.17.21"},
+		{SecurityTest: "yarnaudit", Package: "lodash", FixVersion: "4.17.12"},
+		{SecurityTest: "npmaudit", Package: "minimist", FixVersion: "1.2.6", Nosec: true},
+		{SecurityTest: "yarnaudit", Package: "debug"},
+		{SecurityTest: "gosec", Package: "G101", FixVersion: "1.0.0"},
+	}
+	expected := []Suggestion{
+		{Ecosystem: Npm, Package: "lodash", Version: "4.17.21"},
+		{Ecosystem: PyPI, Package: "django", Version: "2.2.28"},
+	}
+	if suggestions := Suggestions(vulns); !reflect.DeepEqual(suggestions, expected) {
+		t.Errorf("Fix: suggestions %+v, expected %+v", suggestions, expected)
+	}
+}
+
+func TestPlanAndApply(t *testing.T) {
+	root := t.TempDir()
+	requirements := filepath.Join(root, "requirements.txt")
+	writeFile(t, requirements, "# web\nDjango==2.2.1  # pinned\nrequests>=2.0; python_version >= \"3\"\nflask==2.3.0\n")
+	packageJSON := filepath.Join(root, "web", "package.json")
+	writeFile(t, packageJSON, "{\n  \"name\": \"web\",\n  \"dependencies\": {\n    \"lodash\": \"^4.17.4\",\n    \"left-pad\": \"git+https://example.com/left-pad.git\"\n  }\n}\n")
+	writeFile(t, filepath.Join(root, "web", "node_modules", "x", "package.json"), "{\"dependencies\": {\"lodash\": \"4.0.0\"}}")
+
+	suggestions := []Suggestion{
+		{Ecosystem: Npm, Package: "left-pad", Version: "1.3.0"},
+		{Ecosystem: Npm, Package: "lodash", Version: "4.17.21"},
+		{Ecosystem: Npm, Package: "minimist", Version: "1.2.6"},
+		{Ecosystem: PyPI, Package: "django", Version: "2.2.28"},
+		{Ecosystem: PyPI, Package: "flask", Version: "2.2.5"},
+		{Ecosystem: PyPI, Package: "requests", Version: "2.31.0"},
+	}
+	changes, unmatched, err := Plan(root, suggestions)
+	if err != nil {
+		t.Fatalf("Fix: fail to plan (%v)", err)
+	}
+	if expected := []Suggestion{{Ecosystem: Npm, Package: "minimist", Version: "1.2.6"}}; !reflect.DeepEqual(unmatched, expected) {
+		t.Errorf("Fix: unmatched %+v, expected %+v", unmatched, expected)
+	}
+	lines := [][2]string{}
+	for _, change := range changes {
+		lines = append(lines, [2]string{change.Old, change.New})
+	}
+	expected := [][2]string{
+		{"Django==2.2.1  # pinned", "Django==2.2.28  # pinned"},
+		{"requests>=2.0; python_version >= \"3\"", "requests>=2.31.0; python_version >= \"3\""},
+		{"    \"lodash\": \"^4.17.4\",", "    \"lodash\": \"^4.17.21\","},
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Fatalf("Fix: changes %q, expected %q", lines, expected)
+	}
+
+	if err := Apply(changes); err != nil {
+		t.Fatalf("Fix: fail to apply (%v)", err)
+	}
+	content, _ := os.ReadFile(requirements)
+	if string(content) != "# web\nDjango==2.2.28  # pinned\nrequests>=2.31.0; python_version >= \"3\"\nflask==2.3.0\n" {
+		t.Errorf("Fix: unexpected requirements.txt %q", content)
+	}
+	if err := Apply(changes); err == nil {
+		t.Error("Fix: changes applied twice, expected an error")
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	for _, test := range []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.10.0", "1.9.9", 1},
+		{"2.2", "2.2.0", 0},
+		{"v1.0.0", "1.0.1", -1},
+	} {
+		if result := compareVersions(test.a, test.b); result != test.expected {
+			t.Errorf("Fix: compareVersions(%s, %s) = %d, expected %d", test.a, test.b, result, test.expected)
+		}
+	}
+}
@@ -9,12 +9,18 @@ require (
 	github.com/spf13/cobra v1.7.0
 	github.com/spf13/viper v1.21.0
 	github.com/src-d/enry/v2 v2.1.0
+	github.com/zalando/go-keyring v0.2.6
 	go.mongodb.org/mongo-driver v1.17.3
+	go.yaml.in/yaml/v3 v3.0.4
 )
 
 require (
+	al.essio.dev/pkg/shellescape v1.5.1 // indirect
+	github.com/danieljoos/wincred v1.2.2 // indirect
 	github.com/fsnotify/fsnotify v1.9.0 // indirect
 	github.com/go-viper/mapstructure/v2 v2.4.0 // indirect
+	github.com/godbus/dbus/v5 v5.1.0 // indirect
+	github.com/huskyci-org/huskyCI/pkg v0.0.0
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/pelletier/go-toml/v2 v2.2.4 // indirect
 	github.com/sagikazarmark/locafero v0.11.0 // indirect
@@ -25,10 +31,12 @@ require (
 	github.com/src-d/go-oniguruma v1.1.0 // indirect
 	github.com/subosito/gotenv v1.6.0 // indirect
 	github.com/toqueteos/trie v1.0.0 // indirect
-	go.yaml.in/yaml/v3 v3.0.4 // indirect
+	golang.org/x/net v0.37.0 // indirect
 	golang.org/x/sys v0.31.0 // indirect
 	golang.org/x/text v0.28.0 // indirect
 	gopkg.in/toqueteos/substring.v1 v1.0.2 // indirect
 )
 
 exclude github.com/hashicorp/hcl v1.0.0
+
+replace github.com/huskyci-org/huskyCI/pkg => ../pkg
@@ -0,0 +1,223 @@
+// Package log is the leveled logger of the huskyCI CLI. It prints the
+// diagnostics of the CLI to the standard error, apart from its output, so
+// that they can be turned up with -v and -vv, down with --quiet, and captured
+// as JSON lines with --log-format json.
+package log
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/huskyci-org/huskyCI/pkg/formatter"
+	"github.com/huskyci-org/huskyCI/pkg/i18n"
+)
+
+// Levels of the logger, from the most verbose.
+const (
+	// LevelTrace is for what happens on the wire, such as the requests sent
+	// and their headers, printed with -vv.
+	LevelTrace = slog.LevelDebug - 4
+	// LevelDebug is for the steps of a command, printed with -v.
+	LevelDebug = slog.LevelDebug
+	// LevelInfo is the default level.
+	LevelInfo = slog.LevelInfo
+	// LevelWarn is for what went wrong without failing the command.
+	LevelWarn = slog.LevelWarn
+	// LevelError is the only level printed with --quiet.
+	LevelError = slog.LevelError
+)
+
+// Formats of the logs.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+var (
+	level  = new(slog.LevelVar)
+	mu     sync.RWMutex
+	logger = slog.New(&textHandler{level: level, w: formatter.Stderr})
+)
+
+// LevelOf returns the level of the logger for verbosity, the number of -v
+// flags, and quiet.
+func LevelOf(verbosity int, quiet bool) slog.Level {
+	switch {
+	case quiet:
+		return LevelError
+	case verbosity >= 2:
+		return LevelTrace
+	case verbosity == 1:
+		return LevelDebug
+	}
+	return LevelInfo
+}
+
+// Configure sets the level of the logger and the format it prints to the
+// standard error in, text or json.
+func Configure(verbosity int, quiet bool, format string) error {
+	if quiet && verbosity > 0 {
+		return errors.New(i18n.T("--quiet can not be used with --verbose"))
+	}
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "", FormatText:
+		handler = &textHandler{level: level, w: formatter.Stderr}
+	case FormatJSON:
+		handler = slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level, ReplaceAttr: replaceLevel})
+	default:
+		return i18n.Errorf("invalid log format '%s': use text or json", format)
+	}
+	level.Set(LevelOf(verbosity, quiet))
+	SetHandler(handler)
+	return nil
+}
+
+// SetHandler makes the logger print through handler, such as in tests.
+func SetHandler(handler slog.Handler) {
+	mu.Lock()
+	defer mu.Unlock()
+	logger = slog.New(handler)
+}
+
+// SetLevel sets the lowest level the logger prints.
+func SetLevel(l slog.Level) {
+	level.Set(l)
+}
+
+// Enabled returns whether the logger prints the messages of l.
+func Enabled(l slog.Level) bool {
+	return level.Level() <= l
+}
+
+// Verbose returns whether the CLI runs with -v or -vv, so that commands
+// print details they leave out otherwise.
+func Verbose() bool {
+	return Enabled(LevelDebug)
+}
+
+// Quiet returns whether the CLI runs with --quiet, so that commands leave
+// out their progress.
+func Quiet() bool {
+	return !Enabled(LevelInfo)
+}
+
+// Logger returns the logger of the CLI.
+func Logger() *slog.Logger {
+	mu.RLock()
+	defer mu.RUnlock()
+	return logger
+}
+
+// Trace logs msg and the key-value pairs of args at LevelTrace.
+func Trace(msg string, args ...interface{}) {
+	Logger().Log(context.Background(), LevelTrace, msg, args...)
+}
+
+// Debug logs msg and the key-value pairs of args at LevelDebug.
+func Debug(msg string, args ...interface{}) {
+	Logger().Debug(msg, args...)
+}
+
+// Info logs msg and the key-value pairs of args at LevelInfo.
+func Info(msg string, args ...interface{}) {
+	Logger().Info(msg, args...)
+}
+
+// Warn logs msg and the key-value pairs of args at LevelWarn.
+func Warn(msg string, args ...interface{}) {
+	Logger().Warn(msg, args...)
+}
+
+// Error logs msg and the key-value pairs of args at LevelError.
+func Error(msg string, args ...interface{}) {
+	Logger().Error(msg, args...)
+}
+
+// levelName returns the name of l, TRACE for LevelTrace.
+func levelName(l slog.Level) string {
+	if l <= LevelTrace {
+		return "TRACE"
+	}
+	return l.String()
+}
+
+// replaceLevel names LevelTrace in JSON logs.
+func replaceLevel(groups []string, attr slog.Attr) slog.Attr {
+	if attr.Key == slog.LevelKey && len(groups) == 0 {
+		if l, ok := attr.Value.Any().(slog.Level); ok {
+			attr.Value = slog.StringValue(levelName(l))
+		}
+	}
+	return attr
+}
+
+// textHandler prints logs for people, as a line starting with their level,
+// such as [DEBUG] Zip file created path=/tmp/code.zip.
+type textHandler struct {
+	level  slog.Leveler
+	w      io.Writer
+	attrs  string
+	prefix string
+}
+
+func (h *textHandler) Enabled(_ context.Context, l slog.Level) bool {
+	return l >= h.level.Level()
+}
+
+func (h *textHandler) Handle(_ context.Context, record slog.Record) error {
+	var b strings.Builder
+	b.WriteString("[" + levelName(record.Level) + "] " + record.Message)
+	b.WriteString(h.attrs)
+	record.Attrs(func(attr slog.Attr) bool {
+		writeAttr(&b, h.prefix, attr)
+		return true
+	})
+	b.WriteByte('\n')
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var b strings.Builder
+	for _, attr := range attrs {
+		writeAttr(&b, h.prefix, attr)
+	}
+	handler := *h
+	handler.attrs += b.String()
+	return &handler
+}
+
+func (h *textHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	handler := *h
+	handler.prefix += name + "."
+	return &handler
+}
+
+// writeAttr writes attr to b as key=value, quoting its value if needed.
+func writeAttr(b *strings.Builder, prefix string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		for _, member := range attr.Value.Group() {
+			writeAttr(b, prefix+attr.Key+".", member)
+		}
+		return
+	}
+	value := attr.Value.String()
+	if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+		value = strconv.Quote(value)
+	}
+	b.WriteString(" " + prefix + attr.Key + "=" + value)
+}
@@ -0,0 +1,112 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// capture makes the logger print to a buffer at l, in text or as JSON, until
+// the test ends.
+func capture(t *testing.T, l slog.Level, asJSON bool) *bytes.Buffer {
+	t.Helper()
+	previous, previousLevel := Logger(), level.Level()
+	t.Cleanup(func() {
+		SetHandler(previous.Handler())
+		SetLevel(previousLevel)
+	})
+	buffer := &bytes.Buffer{}
+	if asJSON {
+		SetHandler(slog.NewJSONHandler(buffer, &slog.HandlerOptions{Level: level, ReplaceAttr: replaceLevel}))
+	} else {
+		SetHandler(&textHandler{level: level, w: buffer})
+	}
+	SetLevel(l)
+	return buffer
+}
+
+func TestLevelOf(t *testing.T) {
+	cases := []struct {
+		verbosity int
+		quiet     bool
+		want      slog.Level
+	}{
+		{0, false, LevelInfo},
+		{1, false, LevelDebug},
+		{2, false, LevelTrace},
+		{3, false, LevelTrace},
+		{0, true, LevelError},
+	}
+	for _, c := range cases {
+		if got := LevelOf(c.verbosity, c.quiet); got != c.want {
+			t.Errorf("LevelOf(%d, %t) = %s, want %s", c.verbosity, c.quiet, got, c.want)
+		}
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	if err := Configure(1, true, FormatText); err == nil {
+		t.Error("Configure: --quiet was accepted with --verbose")
+	}
+	if err := Configure(0, false, "xml"); err == nil {
+		t.Error("Configure: an unknown format was accepted")
+	}
+}
+
+func TestTextLogs(t *testing.T) {
+	buffer := capture(t, LevelDebug, false)
+	Trace("Left out")
+	Debug("Zip file created", "path", "/tmp/code.zip", "files", 3)
+	Warn("Failed to parse response", "error", "unexpected end of JSON input")
+
+	want := "[DEBUG] Zip file created path=/tmp/code.zip files=3\n" +
+		"[WARN] Failed to parse response error=\"unexpected end of JSON input\"\n"
+	if buffer.String() != want {
+		t.Errorf("text logs = %q, want %q", buffer.String(), want)
+	}
+	if !Verbose() || Quiet() {
+		t.Error("Verbose or Quiet does not follow the level")
+	}
+}
+
+func TestJSONLogs(t *testing.T) {
+	buffer := capture(t, LevelTrace, true)
+	Trace("Sending request", "method", "GET")
+
+	entry := map[string]interface{}{}
+	if err := json.Unmarshal(buffer.Bytes(), &entry); err != nil {
+		t.Fatalf("JSON logs: %s in %q", err, buffer.String())
+	}
+	if entry["level"] != "TRACE" || entry["msg"] != "Sending request" || entry["method"] != "GET" {
+		t.Errorf("JSON logs: unexpected entry %v", entry)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: Transport(http.DefaultTransport)}
+
+	buffer := capture(t, LevelTrace, false)
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/analysis/1", nil)
+	req.Header.Set("Husky-Token", "secret")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	logs := buffer.String()
+	if !strings.Contains(logs, "[DEBUG] Request sent method=GET url="+server.URL+"/analysis/1 status=202") {
+		t.Errorf("transport: the request was not logged in %q", logs)
+	}
+	if strings.Contains(logs, "secret") || !strings.Contains(logs, "Husky-Token:REDACTED") {
+		t.Errorf("transport: the token was not redacted in %q", logs)
+	}
+}
@@ -0,0 +1,55 @@
+package log
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// loggingTransport logs every request it sends: its answer with -v, and its
+// headers too with -vv, without the credentials they carry.
+type loggingTransport struct {
+	next http.RoundTripper
+}
+
+// secretHeaders are the headers of credentials, which are not logged.
+var secretHeaders = map[string]bool{
+	"Authorization": true,
+	"Husky-Token":   true,
+	"Cookie":        true,
+	"Set-Cookie":    true,
+}
+
+// Transport returns next, logging the requests it sends.
+func Transport(next http.RoundTripper) http.RoundTripper {
+	return loggingTransport{next: next}
+}
+
+func (t loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !Verbose() {
+		return t.next.RoundTrip(req)
+	}
+	Trace("Sending request", "method", req.Method, "url", req.URL.String(), "headers", loggedHeaders(req.Header))
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		Debug("Request failed", "method", req.Method, "url", req.URL.String(), "duration", time.Since(start), "error", err)
+		return resp, err
+	}
+	Debug("Request sent", "method", req.Method, "url", req.URL.String(), "status", resp.StatusCode, "duration", time.Since(start))
+	Trace("Received response", "method", req.Method, "url", req.URL.String(), "headers", loggedHeaders(resp.Header))
+	return resp, nil
+}
+
+// loggedHeaders returns header with the values of secretHeaders redacted.
+func loggedHeaders(header http.Header) map[string]string {
+	logged := make(map[string]string, len(header))
+	for name, values := range header {
+		if secretHeaders[http.CanonicalHeaderKey(name)] {
+			logged[name] = "REDACTED"
+			continue
+		}
+		logged[name] = strings.Join(values, ", ")
+	}
+	return logged
+}
@@ -6,10 +6,19 @@ package main
 
 import (
 	"github.com/huskyci-org/huskyCI/cli/cmd"
+	"github.com/huskyci-org/huskyCI/cli/config"
 	"github.com/huskyci-org/huskyCI/cli/errorcli"
 )
 
+// version, commit and date are set by the Makefile when building the CLI.
+var (
+	version string
+	commit  string
+	date    string
+)
+
 func main() {
+	config.Version, config.Commit, config.BuildDate = version, commit, date
 	err := cmd.Execute()
 	if err != nil {
 		errorcli.Handle(err)
@@ -29,6 +29,10 @@ type Target struct {
 	Endpoint     string
 	TokenStorage string
 	Token        string
+	// ClientCert and ClientKey are the files of the certificate presented to
+	// an API requiring mutual TLS.
+	ClientCert string
+	ClientKey  string
 }
 
 // Analysis is the struct that stores all data from analysis performed.
@@ -45,6 +49,7 @@ type Analysis struct {
 	FinishedAt     time.Time          `bson:"finishedAt" json:"finishedAt"`
 	Codes          []Code             `bson:"codes" json:"codes"`
 	HuskyCIResults HuskyCIResults     `bson:"huskyciresults,omitempty" json:"huskyciresults"`
+	Warnings       []string           `bson:"warnings,omitempty" json:"warnings,omitempty"`
 }
 
 // Code is the struct that stores all data from code found in a repository.
@@ -61,6 +66,7 @@ type HuskyCIResults struct {
 	RubyResults       RubyResults       `bson:"rubyresults,omitempty" json:"rubyresults,omitempty"`
 	JavaResults       JavaResults       `bson:"javaresults,omitempty" json:"javaresults,omitempty"`
 	HclResults        HclResults        `bson:"hclresults,omitempty" json:"hclresults,omitempty"`
+	IaCResults        IaCResults        `bson:"iacresults,omitempty" json:"iacresults,omitempty"`
 	CSharpResults     CSharpResults     `bson:"csharpresults,omitempty" json:"csharpresults,omitempty"`
 	GenericResults    GenericResults    `bson:"genericresults,omitempty" json:"genericresults,omitempty"`
 }
@@ -73,6 +79,7 @@ type Container struct {
 	COutput      string       `bson:"cOutput" json:"cOutput"`
 	CResult      string       `bson:"cResult" json:"cResult"`
 	CInfo        string       `bson:"cInfo" json:"cInfo"`
+	ToolConfigs  []string     `bson:"toolConfigs,omitempty" json:"toolConfigs,omitempty"`
 	StartedAt    time.Time    `bson:"startedAt" json:"startedAt"`
 	FinishedAt   time.Time    `bson:"finishedAt" json:"finishedAt"`
 }
@@ -104,6 +111,9 @@ type HuskyCIVulnerability struct {
 	VunerableBelow string `json:"vulnerablebelow,omitempty"`
 	Version        string `json:"version,omitempty"`
 	Occurrences    int    `json:"occurrences,omitempty"`
+	Package        string `json:"package,omitempty"`
+	FixVersion     string `json:"fixversion,omitempty"`
+	PackagePath    string `json:"packagepath,omitempty"`
 }
 
 // JSONOutput is a truct that represents huskyCI output in a JSON format.
@@ -151,6 +161,11 @@ type HclResults struct {
 	HuskyCITFSecOutput HuskyCISecurityTestOutput `bson:"tfsecoutput,omitempty" json:"tfsecoutput,omitempty"`
 }
 
+// IaCResults represents all infrastructure as code security tests results.
+type IaCResults struct {
+	HuskyCITrivyConfigOutput HuskyCISecurityTestOutput `bson:"trivyconfigoutput,omitempty" json:"trivyconfigoutput,omitempty"`
+}
+
 // CSharpResults represents all C# security tests results.
 type CSharpResults struct {
 	HuskyCISecurityCodeScanOutput HuskyCISecurityTestOutput `bson:"securitycodescanoutput,omitempty" json:"securitycodescanoutput,omitempty"`
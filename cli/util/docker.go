@@ -10,6 +10,8 @@ import (
 	"os/exec"
 	"runtime"
 	"strings"
+
+	"github.com/huskyci-org/huskyCI/pkg/i18n"
 )
 
 // ErrConnectionRefused is returned when the connection error looks like "connection refused".
@@ -43,8 +45,8 @@ func IsLocalEndpoint(endpoint string) bool {
 // false otherwise.
 func PromptAndStartDocker(r io.Reader) bool {
 	fmt.Println()
-	fmt.Println("  Docker may not be running. The huskyCI API often runs in Docker.")
-	fmt.Print("  Start Docker now? [y/N]: ")
+	fmt.Println(i18n.T("  Docker may not be running. The huskyCI API often runs in Docker."))
+	fmt.Print(i18n.T("  Start Docker now? [y/N]: "))
 	scanner := bufio.NewScanner(r)
 	if !scanner.Scan() {
 		return false
@@ -54,11 +56,11 @@ func PromptAndStartDocker(r io.Reader) bool {
 		return false
 	}
 	if err := StartDocker(); err != nil {
-		fmt.Fprintf(os.Stderr, "  Failed to start Docker: %v\n", err)
-		fmt.Fprintln(os.Stderr, "  Please start Docker manually and try again.")
+		i18n.Fprintf(os.Stderr, "  Failed to start Docker: %v\n", err)
+		fmt.Fprintln(os.Stderr, i18n.T("  Please start Docker manually and try again."))
 		return false
 	}
-	fmt.Println("  Docker is starting. Wait a moment, then the CLI will retry.")
+	fmt.Println(i18n.T("  Docker is starting. Wait a moment, then the CLI will retry."))
 	return true
 }
 
@@ -73,13 +75,13 @@ func StartDocker() error {
 		// systemctl start docker often requires root; suggest manual start
 		cmd := exec.Command("systemctl", "--user", "start", "docker")
 		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("could not start Docker: %w (try: systemctl start docker, or start Docker Desktop)", err)
+			return i18n.Errorf("could not start Docker: %w (try: systemctl start docker, or start Docker Desktop)", err)
 		}
 		return nil
 	case "windows":
 		// Docker Desktop on Windows can be started via COM or by running the executable
-		return fmt.Errorf("please start Docker Desktop manually from the Start menu")
+		return i18n.Errorf("please start Docker Desktop manually from the Start menu")
 	default:
-		return fmt.Errorf("please start Docker manually for %s", runtime.GOOS)
+		return i18n.Errorf("please start Docker manually for %s", runtime.GOOS)
 	}
 }
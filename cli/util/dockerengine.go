@@ -0,0 +1,198 @@
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/cli/log"
+	"github.com/huskyci-org/huskyCI/pkg/i18n"
+)
+
+// DefaultDockerHost is the Docker socket used when DOCKER_HOST is not set.
+const DefaultDockerHost = "unix:///var/run/docker.sock"
+
+// ErrContainerTimeout is returned by DockerEngine.Run when the container ran
+// for longer than its timeout.
+var ErrContainerTimeout = errors.New("container timed out")
+
+// DockerEngine runs containers through the API of the local Docker daemon,
+// reached on its socket or on the TCP address of DOCKER_HOST.
+type DockerEngine struct {
+	client  *http.Client
+	baseURL string
+}
+
+// NewDockerEngine returns a DockerEngine of the daemon of DOCKER_HOST, or of
+// DefaultDockerHost if it is not set.
+func NewDockerEngine() (*DockerEngine, error) {
+	host := os.Getenv("DOCKER_HOST")
+	if host == "" {
+		host = DefaultDockerHost
+	}
+	scheme, address, found := strings.Cut(host, "://")
+	if !found {
+		return nil, i18n.Errorf("invalid DOCKER_HOST '%s'", host)
+	}
+	switch scheme {
+	case "unix":
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", address)
+			},
+		}
+		return &DockerEngine{client: &http.Client{Transport: transport}, baseURL: "http://docker"}, nil
+	case "tcp", "http":
+		return &DockerEngine{client: &http.Client{}, baseURL: "http://" + address}, nil
+	}
+	return nil, i18n.Errorf("unsupported DOCKER_HOST '%s', use a unix:// or tcp:// address", host)
+}
+
+// do sends a request to the Docker daemon and returns its response, or an
+// error carrying the message of the daemon if it did not succeed.
+func (d *DockerEngine) do(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, d.baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	log.Trace("Docker request sent", "method", method, "path", path, "status", resp.StatusCode)
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		message := struct {
+			Message string `json:"message"`
+		}{}
+		content, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(content, &message) != nil || message.Message == "" {
+			message.Message = strings.TrimSpace(string(content))
+		}
+		return resp, i18n.Errorf("docker answered %d: %s", resp.StatusCode, message.Message)
+	}
+	return resp, nil
+}
+
+// Ping checks that the Docker daemon answers.
+func (d *DockerEngine) Ping(ctx context.Context) error {
+	resp, err := d.do(ctx, http.MethodGet, "/_ping", nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// HasImage returns true if image is already pulled.
+func (d *DockerEngine) HasImage(ctx context.Context, image string) (bool, error) {
+	resp, err := d.do(ctx, http.MethodGet, "/images/"+image+"/json", nil)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, resp.Body.Close()
+}
+
+// PullImage pulls image, like docker pull. The daemon reports the errors of
+// a pull in its progress stream, after answering 200.
+func (d *DockerEngine) PullImage(ctx context.Context, image string) error {
+	resp, err := d.do(ctx, http.MethodPost, "/images/create?fromImage="+url.QueryEscape(image), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		progress := struct {
+			Error string `json:"error"`
+		}{}
+		if json.Unmarshal(scanner.Bytes(), &progress) == nil && progress.Error != "" {
+			return i18n.Errorf("could not pull %s: %s", image, progress.Error)
+		}
+	}
+	return scanner.Err()
+}
+
+// Run runs cmd with /bin/sh in a container of image, with the directory
+// workspace mounted read-only at containerPath, and returns what it printed.
+// The container is removed once it stopped, or once it ran for timeout.
+func (d *DockerEngine) Run(ctx context.Context, image, cmd, workspace, containerPath string, timeout time.Duration) (string, error) {
+	create := map[string]interface{}{
+		"Image":  image,
+		"Tty":    true,
+		"Cmd":    []string{"/bin/sh", "-c", cmd},
+		"Labels": map[string]string{"huskyci": "local"},
+		"HostConfig": map[string]interface{}{
+			"Binds": []string{fmt.Sprintf("%s:%s:ro", workspace, containerPath)},
+		},
+	}
+	resp, err := d.do(ctx, http.MethodPost, "/containers/create", create)
+	if err != nil {
+		return "", err
+	}
+	created := struct {
+		ID string `json:"Id"`
+	}{}
+	err = json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	if err != nil {
+		return "", err
+	}
+	defer d.remove(created.ID)
+
+	if resp, err = d.do(ctx, http.MethodPost, "/containers/"+created.ID+"/start", nil); err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	resp, err = d.do(waitCtx, http.MethodPost, "/containers/"+created.ID+"/wait", nil)
+	if err != nil {
+		if errors.Is(waitCtx.Err(), context.DeadlineExceeded) {
+			return "", i18n.Errorf("%w after %s", ErrContainerTimeout, timeout)
+		}
+		return "", err
+	}
+	resp.Body.Close()
+
+	if resp, err = d.do(ctx, http.MethodGet, "/containers/"+created.ID+"/logs?stdout=1", nil); err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	output, err := io.ReadAll(resp.Body)
+	return string(output), err
+}
+
+// remove removes the container id, stopping it if it still runs, even once
+// the command was interrupted.
+func (d *DockerEngine) remove(id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if resp, err := d.do(ctx, http.MethodDelete, "/containers/"+id+"?force=1", nil); err == nil {
+		resp.Body.Close()
+	}
+}
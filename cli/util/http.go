@@ -3,18 +3,27 @@ package util
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"net/http"
 	"strings"
+
+	"github.com/huskyci-org/huskyCI/pkg/i18n"
 )
 
-// NewHTTPClient returns an http client with TLS support if needed.
-func NewHTTPClient(useTLS bool) (*http.Client, error) {
+// NewHTTPClient returns an http client with TLS support if needed. When
+// certFile and keyFile are set, their certificate is presented to the API,
+// for APIs requiring mutual TLS.
+func NewHTTPClient(useTLS bool, certFile, keyFile string) (*http.Client, error) {
 	if useTLS {
 		// Tries to find system's certificate pool
 		caCertPool, _ := x509.SystemCertPool() // #nosec - SystemCertPool tries to get local cert pool, if it fails, a new cert pool is created
 		if caCertPool == nil {
 			caCertPool = x509.NewCertPool()
 		}
+		certificates, err := clientCertificates(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
 
 		client := &http.Client{
 			Transport: &http.Transport{
@@ -24,6 +33,7 @@ func NewHTTPClient(useTLS bool) (*http.Client, error) {
 					PreferServerCipherSuites: true,
 					InsecureSkipVerify:       false,
 					RootCAs:                  caCertPool,
+					Certificates:             certificates,
 				},
 			},
 		}
@@ -34,6 +44,22 @@ func NewHTTPClient(useTLS bool) (*http.Client, error) {
 	return client, nil
 }
 
+// clientCertificates returns the certificate of certFile and keyFile, if
+// they are set.
+func clientCertificates(certFile, keyFile string) ([]tls.Certificate, error) {
+	if certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New(i18n.T("a client certificate needs both its certificate and its key file"))
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, i18n.Errorf("could not load the client certificate: %w", err)
+	}
+	return []tls.Certificate{cert}, nil
+}
+
 // IsHTTPS checks if a URL uses HTTPS
 func IsHTTPS(url string) bool {
 	return strings.HasPrefix(strings.ToLower(url), "https://")
@@ -11,6 +11,8 @@ import (
 
 	"github.com/huskyci-org/huskyCI/cli/config"
 	"github.com/huskyci-org/huskyCI/cli/errorcli"
+	"github.com/huskyci-org/huskyCI/cli/log"
+	"github.com/huskyci-org/huskyCI/pkg/i18n"
 )
 
 // GetAllAllowedFilesAndDirsFromPath returns a list of all files and dirs allowed to be zipped
@@ -25,6 +27,7 @@ func GetAllAllowedFilesAndDirsFromPath(path string) ([]string, error) {
 	for _, file := range filesAndDirs {
 		fileName := file.Name()
 		if err := checkFileExtension(fileName); err != nil {
+			log.Debug("Leaving file out of the analysis", "file", fileName, "error", err)
 			continue
 		} else {
 			// Return full path for zip creation
@@ -95,7 +98,7 @@ func addToZip(zipWriter *zip.Writer, filePath string) error {
 			relPath = filepath.ToSlash(relPath)
 			// Check for path traversal attempts
 			if filepath.IsAbs(relPath) || relPath == ".." || strings.HasPrefix(relPath, "../") {
-				return fmt.Errorf("illegal file path: %s", relPath)
+				return i18n.Errorf("illegal file path: %s", relPath)
 			}
 
 			return addFileToZip(zipWriter, path, relPath)
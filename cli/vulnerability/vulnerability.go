@@ -18,6 +18,9 @@ type Vulnerability struct {
 	Version        string `bson:"version,omitempty" json:"version,omitempty"`
 	Nosec          bool   `bson:"nosec" json:"nosec"`
 	Occurrences    int    `bson:"occurrences,omitempty" json:"occurrences,omitempty"`
+	Package        string `bson:"package,omitempty" json:"package,omitempty"`
+	FixVersion     string `bson:"fixversion,omitempty" json:"fixversion,omitempty"`
+	PackagePath    string `bson:"packagepath,omitempty" json:"packagepath,omitempty"`
 }
 
 // New creates a new vulnerability and sets its ID
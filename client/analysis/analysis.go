@@ -5,184 +5,186 @@
 package analysis
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"errors"
-	"fmt"
-	"io"
-	"net/http"
-	"time"
 
 	"github.com/huskyci-org/huskyCI/client/config"
 	"github.com/huskyci-org/huskyCI/client/types"
 	"github.com/huskyci-org/huskyCI/client/util"
+	"github.com/huskyci-org/huskyCI/pkg/apiclient"
+	"github.com/huskyci-org/huskyCI/pkg/formatter"
+	"github.com/huskyci-org/huskyCI/pkg/i18n"
+	"github.com/huskyci-org/huskyCI/pkg/sdk"
 )
 
-// StartAnalysis starts a container and returns its RID and error.
-func StartAnalysis() (string, error) {
+// newClient returns a huskyCI SDK client authenticated with the configured token.
+func newClient() (*sdk.Client, error) {
+	httpClient, err := util.NewClient(config.HuskyUseTLS, config.HuskyCAFile, config.HuskyCertFile, config.HuskyKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	sdk.Timeouts{Connect: config.ConnectTimeout, Read: config.ReadTimeout}.Apply(httpClient)
+	api := apiclient.New(config.HuskyAPI)
+	api.HTTPClient = httpClient
+	api.Token = config.HuskyToken
+	api.UserAgent = sdk.UserAgent("huskyci-client", config.Version)
+	client := sdk.New(api)
+	client.OutageWindow = config.OutageWindow
+	return client, nil
+}
 
-	// preparing POST to HuskyCI
-	huskyStartAnalysisURL := config.HuskyAPI + "/analysis"
+// StartAnalysis starts a container and returns its RID and error.
+func StartAnalysis(ctx context.Context) (string, error) {
 
-	requestPayload := types.JSONPayload{
-		RepositoryURL:      config.RepositoryURL,
-		RepositoryBranch:   config.RepositoryBranch,
+	requestPayload := apiclient.Repository{
+		URL:                config.RepositoryURL,
+		Branch:             config.RepositoryBranch,
+		Commit:             config.RepositoryCommit,
 		LanguageExclusions: config.LanguageExclusions,
+		SecurityTests:      config.SecurityTests,
 	}
 
-	marshalPayload, err := json.Marshal(requestPayload)
+	client, err := newClient()
 	if err != nil {
 		return "", err
 	}
 
-	httpClient, err := util.NewClient(config.HuskyUseTLS)
-	if err != nil {
-		return "", err
-	}
-
-	req, err := http.NewRequest("POST", huskyStartAnalysisURL, bytes.NewBuffer(marshalPayload))
-	if err != nil {
-		return "", err
-	}
-
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Husky-Token", config.HuskyToken)
-	req.Header.Add("User-Agent", "huskyci-client")
-
-	resp, err := httpClient.Do(req)
+	RID, err := client.StartAnalysis(ctx, requestPayload)
 	if err != nil {
-		return "", err
-	}
-
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 201 {
-		body, _ := io.ReadAll(resp.Body)
-		if resp.StatusCode == 401 {
-			errorMsg := fmt.Sprintf("Authentication failed: The provided Husky-Token is invalid or expired.\n\nTip: Generate a new token using the huskyCI API or verify your token has access to repository: %s", config.RepositoryURL)
+		sdkErr := &sdk.Error{}
+		errors.As(err, &sdkErr)
+		switch {
+		case errors.Is(err, sdk.ErrUnauthorized):
+			errorMsg := i18n.Sprintf("Authentication failed: The provided Husky-Token is invalid or expired.\n\nTip: Generate a new token using the huskyCI API or verify your token has access to repository: %s", config.RepositoryURL)
 			return "", errors.New(errorMsg)
-		}
-		if resp.StatusCode == 400 {
-			errorMsg := fmt.Sprintf("Bad request: Invalid request parameters.\n\nStatus: %d\nResponse: %s\n\nTip: Verify that the repository URL and branch are correct", resp.StatusCode, string(body))
+		case errors.Is(err, sdk.ErrBadRequest):
+			errorMsg := i18n.Sprintf("Bad request: Invalid request parameters.\n\nStatus: %d\nResponse: %s\n\nTip: Verify that the repository URL and branch are correct", sdkErr.StatusCode, string(sdkErr.Body))
 			return "", errors.New(errorMsg)
-		}
-		if resp.StatusCode == 409 {
-			errorMsg := fmt.Sprintf("Conflict: An analysis is already running for this repository and branch.\n\nStatus: %d\nResponse: %s\n\nTip: Wait for the existing analysis to complete or use a different branch", resp.StatusCode, string(body))
+		case errors.Is(err, sdk.ErrConflict):
+			errorMsg := i18n.Sprintf("Conflict: An analysis is already running for this repository and branch.\n\nStatus: %d\nResponse: %s\n\nTip: Wait for the existing analysis to complete or use a different branch", sdkErr.StatusCode, string(sdkErr.Body))
 			return "", errors.New(errorMsg)
+		case errors.Is(err, sdk.ErrNetwork):
+			return "", i18n.Errorf("network error while starting analysis: %w\n\nTip: Check your network connection and verify the API endpoint is accessible", err)
 		}
-		errorMsg := fmt.Sprintf("Failed to start analysis: Unexpected response from API.\n\nStatus Code: %d\nResponse: %s\n\nTip: Check the huskyCI API status and try again", resp.StatusCode, string(body))
-		return "", errors.New(errorMsg)
-	}
-
-	RID := resp.Header.Get("X-Request-Id")
-	if RID == "" {
-		errorMsg := "Failed to start analysis: No request ID (RID) received from the API.\n\nTip: This may indicate an issue with the huskyCI API. Please check the API status and try again."
+		errorMsg := i18n.Sprintf("Failed to start analysis: Unexpected response from API.\n\n%s\n\nTip: Check the huskyCI API status and try again", err)
 		return "", errors.New(errorMsg)
 	}
 
 	// Setting analysis values on the JSON output
-	outputJSON.Summary.URL = requestPayload.RepositoryURL
-	outputJSON.Summary.Branch = requestPayload.RepositoryBranch
+	outputJSON.Summary.URL = requestPayload.URL
+	outputJSON.Summary.Branch = requestPayload.Branch
 	outputJSON.Summary.RID = RID
 
 	return RID, nil
 }
 
 // GetAnalysis gets the results of an analysis.
-func GetAnalysis(RID string) (types.Analysis, error) {
+func GetAnalysis(ctx context.Context, RID string) (types.Analysis, error) {
 
 	analysis := types.Analysis{}
-	getAnalysisURL := config.HuskyAPI + "/analysis/" + RID
 
 	if !types.IsJSONoutput {
-		fmt.Printf("[HUSKYCI] Checking analysis status (RID: %s)...\n", RID)
+		i18n.Printf("[HUSKYCI] Checking analysis status (RID: %s)...\n", RID)
 	}
 
-	httpClient, err := util.NewClient(config.HuskyUseTLS)
+	client, err := newClient()
 	if err != nil {
 		return analysis, err
 	}
 
-	req, err := http.NewRequest("GET", getAnalysisURL, nil)
+	result, err := client.GetAnalysis(ctx, RID)
 	if err != nil {
-		return analysis, err
+		return analysis, analysisError(RID, err)
 	}
 
-	req.Header.Add("Husky-Token", config.HuskyToken)
-	req.Header.Add("User-Agent", "huskyci-client")
+	err = sdk.Decode(result, &analysis)
+	return analysis, err
+}
 
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return analysis, fmt.Errorf("network error while fetching analysis: %w\n\nTip: Check your network connection and verify the API endpoint is accessible", err)
-	}
+// MonitorAnalysis will keep monitoring an analysis until it has finished or timed out.
+// When ctx is cancelled, on an interrupt for instance, the analysis is cancelled too.
+func MonitorAnalysis(ctx context.Context, RID string) (types.Analysis, error) {
 
-	defer resp.Body.Close()
+	analysis := types.Analysis{}
+	checkCount := 0
 
-	if resp.StatusCode != 200 {
-		body, _ := io.ReadAll(resp.Body)
-		if resp.StatusCode == 404 {
-			errorMsg := fmt.Sprintf("Analysis not found: No analysis found with RID '%s'.\n\nTip: Verify the RID is correct and the analysis exists", RID)
-			return analysis, errors.New(errorMsg)
-		}
-		if resp.StatusCode == 401 {
-			errorMsg := fmt.Sprintf("Authentication failed: Invalid or expired token.\n\nTip: Generate a new token using the huskyCI API", RID)
-			return analysis, errors.New(errorMsg)
-		}
-		errorMsg := fmt.Sprintf("Failed to retrieve analysis: Unexpected response from API.\n\nStatus Code: %d\nResponse: %s\n\nTip: Check the huskyCI API status and try again", resp.StatusCode, string(body))
-		return analysis, errors.New(errorMsg)
+	if !types.IsJSONoutput {
+		formatter.Println(i18n.T("[HUSKYCI] Monitoring analysis progress..."))
+		i18n.Printf("[HUSKYCI] Analysis RID: %s\n", RID)
+		formatter.Println(i18n.T("[HUSKYCI] This may take several minutes depending on your codebase size..."))
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	client, err := newClient()
 	if err != nil {
 		return analysis, err
 	}
 
-	err = json.Unmarshal(body, &analysis)
-	if err != nil {
-		return analysis, err
+	client.OnEvent = func(event apiclient.AnalysisEvent) {
+		if !types.IsJSONoutput && event.Type == sdk.EventSecurityTest {
+			i18n.Printf("[HUSKYCI] ✓ %s finished (%s)\n", event.SecurityTest, event.CResult)
+		}
 	}
 
+	ctx, cancel := context.WithTimeout(ctx, config.AnalysisTimeout)
+	defer cancel()
+	result, err := client.WaitAnalysis(ctx, RID, func(current *apiclient.Analysis) {
+		checkCount++
+		if !types.IsJSONoutput && current.Status != sdk.StatusFinished && current.Status != sdk.StatusError {
+			i18n.Printf("[HUSKYCI] ⏳ Analysis in progress... (check #%d)\n", checkCount)
+		}
+	})
+	if result != nil {
+		if decodeErr := sdk.Decode(result, &analysis); decodeErr != nil {
+			return analysis, decodeErr
+		}
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return analysis, i18n.Errorf("analysis timed out after %s\n\nTip: Large codebases may take longer to analyze. Raise HUSKYCI_CLIENT_TIMEOUT or contact support if this persists", config.AnalysisTimeout)
+	case errors.Is(err, context.Canceled):
+		return analysis, cancelAnalysis(client, RID)
+	case errors.Is(err, sdk.ErrCancelled):
+		return analysis, i18n.Errorf("Analysis %s was cancelled before it finished", RID)
+	case errors.Is(err, sdk.ErrAnalysisFailed):
+		return analysis, i18n.Errorf("Analysis failed with error: %v\n\nTip: Check the analysis details for more information about what went wrong", analysis.ErrorFound)
+	case err != nil:
+		return analysis, analysisError(RID, err)
+	}
+
+	if !types.IsJSONoutput {
+		i18n.Printf("[HUSKYCI] ✓ Analysis completed after %d checks\n", checkCount)
+	}
 	return analysis, nil
 }
 
-// MonitorAnalysis will keep monitoring an analysis until it has finished or timed out.
-func MonitorAnalysis(RID string) (types.Analysis, error) {
-
-	analysis := types.Analysis{}
-	timeout := time.After(60 * time.Minute)
-	retryTick := time.NewTicker(60 * time.Second)
-	checkCount := 0
+// cancelAnalysis cancels the analysis RID the client stopped waiting for.
+func cancelAnalysis(client *sdk.Client, RID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), config.ConnectTimeout+config.ReadTimeout)
+	defer cancel()
+	err := client.CancelAnalysis(ctx, RID)
+	switch {
+	case errors.Is(err, sdk.ErrConflict):
+		return i18n.Errorf("Analysis interrupted after it finished (RID: %s)", RID)
+	case err != nil:
+		return i18n.Errorf("Analysis interrupted, but it could not be cancelled: %w\n\nTip: It keeps running on huskyCI API until it finishes (RID: %s)", err, RID)
+	}
+	return i18n.Errorf("Analysis interrupted and cancelled (RID: %s)", RID)
+}
 
-	if !types.IsJSONoutput {
-		fmt.Println("[HUSKYCI] Monitoring analysis progress...")
-		fmt.Printf("[HUSKYCI] Analysis RID: %s\n", RID)
-		fmt.Println("[HUSKYCI] This may take several minutes depending on your codebase size...")
-	}
-
-	for {
-		select {
-		case <-timeout:
-			return analysis, fmt.Errorf("analysis timed out after 60 minutes\n\nTip: Large codebases may take longer to analyze. Try again or contact support if this persists")
-		case <-retryTick.C:
-			checkCount++
-			analysis, err := GetAnalysis(RID)
-			if err != nil {
-				return analysis, err
-			}
-			if analysis.Status == "finished" {
-				if !types.IsJSONoutput {
-					fmt.Printf("[HUSKYCI] ✓ Analysis completed after %d checks\n", checkCount)
-				}
-				return analysis, nil
-			} else if analysis.Status == "error running" {
-				errorMsg := fmt.Sprintf("Analysis failed with error: %v\n\nTip: Check the analysis details for more information about what went wrong", analysis.ErrorFound)
-				return analysis, fmt.Errorf(errorMsg)
-			}
-			if !types.IsJSONoutput {
-				fmt.Printf("[HUSKYCI] ⏳ Analysis in progress... (check #%d)\n", checkCount)
-			}
-		}
-	}
+// analysisError explains why the analysis RID could not be retrieved.
+func analysisError(RID string, err error) error {
+	sdkErr := &sdk.Error{}
+	switch {
+	case errors.Is(err, sdk.ErrNotFound):
+		return i18n.Errorf("Analysis not found: No analysis found with RID '%s'.\n\nTip: Verify the RID is correct and the analysis exists", RID)
+	case errors.Is(err, sdk.ErrUnauthorized):
+		return errors.New(i18n.T("Authentication failed: Invalid or expired token.\n\nTip: Generate a new token using the huskyCI API"))
+	case sdk.Transient(err):
+		return i18n.Errorf("%w\n\nTip: The analysis may still be running. Wait for it from a new CI job with: huskyci-client --resume %s", err, RID)
+	case errors.As(err, &sdkErr):
+		return i18n.Errorf("Failed to retrieve analysis: Unexpected response from API.\n\nStatus Code: %d\nResponse: %s\n\nTip: Check the huskyCI API status and try again", sdkErr.StatusCode, string(sdkErr.Body))
+	}
+	return i18n.Errorf("network error while fetching analysis: %w\n\nTip: Check your network connection and verify the API endpoint is accessible", err)
 }
 
 // PrintResults prints huskyCI output either in JSON or the standard output.
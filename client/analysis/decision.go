@@ -0,0 +1,130 @@
+// Copyright 2019 Globo.com authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/huskyci-org/huskyCI/client/config"
+	"github.com/huskyci-org/huskyCI/client/types"
+	"github.com/huskyci-org/huskyCI/pkg/sdk"
+)
+
+// tool is a securityTest the client summarizes, in the order it prints them.
+type tool struct {
+	name     string
+	language string
+	summary  func(summary *types.Summary) types.HuskyCISummary
+}
+
+var tools = []tool{
+	{"gosec", "Go", func(s *types.Summary) types.HuskyCISummary { return s.GosecSummary }},
+	{"bandit", "Python", func(s *types.Summary) types.HuskyCISummary { return s.BanditSummary }},
+	{"safety", "Python", func(s *types.Summary) types.HuskyCISummary { return s.SafetySummary }},
+	{"brakeman", "Ruby", func(s *types.Summary) types.HuskyCISummary { return s.BrakemanSummary }},
+	{"npmaudit", "JavaScript", func(s *types.Summary) types.HuskyCISummary { return s.NpmAuditSummary }},
+	{"yarnaudit", "JavaScript", func(s *types.Summary) types.HuskyCISummary { return s.YarnAuditSummary }},
+	{"spotbugs", "Java", func(s *types.Summary) types.HuskyCISummary { return s.SpotBugsSummary }},
+	{"tfsec", "HCL", func(s *types.Summary) types.HuskyCISummary { return s.TFSecSummary }},
+	{"trivyconfig", "IaC", func(s *types.Summary) types.HuskyCISummary { return s.TrivyConfigSummary }},
+	{"securitycodescan", "C#", func(s *types.Summary) types.HuskyCISummary { return s.SecurityCodeScanSummary }},
+	{"gitleaks", "Generic", func(s *types.Summary) types.HuskyCISummary { return s.GitleaksSummary }},
+}
+
+// ResolveFailOn sets config.FailOn, when it was not set, to the policy of the
+// repository on huskyCI API, or to sdk.DefaultFailOn if it has none or the
+// policy could not be retrieved.
+func ResolveFailOn(ctx context.Context) {
+	if config.FailOn != "" {
+		return
+	}
+	config.FailOn = sdk.DefaultFailOn
+	client, err := newClient()
+	if err != nil {
+		return
+	}
+	failOn, err := client.GetFailOn(ctx, config.RepositoryURL)
+	if err != nil {
+		if !types.IsJSONoutput {
+			fmt.Printf("[HUSKYCI] Could not retrieve the policy of the repository, failing on %s: %s\n", config.FailOn, err)
+		}
+		return
+	}
+	config.FailOn = failOn
+}
+
+// CheckClientVersion warns when huskyCI API requires a newer client than
+// config.Version. The analysis still runs, as the API may not reject it.
+func CheckClientVersion(ctx context.Context) {
+	client, err := newClient()
+	if err != nil {
+		return
+	}
+	required, err := client.RequiredClientVersion(ctx, config.Version)
+	if err != nil || required == "" {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "[HUSKYCI][WARN] huskyCI API requires huskyci-client %s or newer, this one is %s. Please update it.\n", required, config.Version)
+}
+
+// Decision returns the blocking decision of the analysis printed.
+func Decision() types.Blocking {
+	return outputJSON.Blocking
+}
+
+// prepareDecision sets the schema version, the summary of each securityTest
+// the analysis ran and the blocking decision of the JSON output. It must run
+// after the summaries are prepared.
+func prepareDecision(analysis types.Analysis) {
+	containers := map[string]types.Container{}
+	for _, container := range analysis.Containers {
+		containers[container.SecurityTest.Name] = container
+	}
+	failOn := config.FailOn
+	if failOn == "" {
+		failOn = sdk.DefaultFailOn
+	}
+
+	outputJSON.SchemaVersion = types.SchemaVersion
+	outputJSON.Warnings = append([]string{}, analysis.Warnings...)
+	outputJSON.Tools = []types.ToolSummary{}
+	outputJSON.Blocking = types.Blocking{FailOn: failOn, WarnOnly: config.WarnOnly, Reasons: []types.BlockingReason{}}
+	for _, t := range tools {
+		summary := t.summary(&outputJSON.Summary)
+		container, ran := containers[t.name]
+		if !ran && !summary.FoundVuln && !summary.FoundInfo {
+			continue
+		}
+		toolSummary := types.ToolSummary{
+			Name:     t.name,
+			Language: t.language,
+			Result:   container.CResult,
+			Config:   container.ToolConfigs,
+			High:     summary.HighVuln,
+			Medium:   summary.MediumVuln,
+			Low:      summary.LowVuln,
+			NoSec:    summary.NoSecVuln,
+		}
+		if ran {
+			toolSummary.Image = fmt.Sprintf("%s:%s", container.SecurityTest.Image, container.SecurityTest.ImageTag)
+		}
+		for _, count := range []struct {
+			severity string
+			found    int
+		}{{"HIGH", summary.HighVuln}, {"MEDIUM", summary.MediumVuln}, {"LOW", summary.LowVuln}} {
+			if count.found > 0 && sdk.Blocks(failOn, count.severity) {
+				toolSummary.Blocking = true
+				outputJSON.Blocking.Reasons = append(outputJSON.Blocking.Reasons, types.BlockingReason{Tool: t.name, Severity: count.severity, Count: count.found})
+			}
+		}
+		outputJSON.Tools = append(outputJSON.Tools, toolSummary)
+	}
+	if len(outputJSON.Blocking.Reasons) > 0 && !config.WarnOnly {
+		outputJSON.Blocking.Blocked = true
+		outputJSON.Blocking.ExitCode = types.BlockingExitCode
+	}
+}
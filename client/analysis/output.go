@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/huskyci-org/huskyCI/client/schema"
 	"github.com/huskyci-org/huskyCI/client/types"
 )
 
@@ -20,6 +21,9 @@ func printJSONOutput() error {
 	if err != nil {
 		return err
 	}
+	if err := schema.Validate(jsonReady); err != nil {
+		return fmt.Errorf("JSON output does not follow its schema: %w", err)
+	}
 	fmt.Println(string(jsonReady))
 	return nil
 }
@@ -72,24 +76,64 @@ func printSTDOUTOutput(analysis types.Analysis) {
 	printSTDOUTOutputTFSec(outputJSON.HclResults.HuskyCITFSecOutput.MediumVulns)
 	printSTDOUTOutputTFSec(outputJSON.HclResults.HuskyCITFSecOutput.HighVulns)
 
+	// trivyconfig
+	printSTDOUTOutputTrivyConfig(outputJSON.IaCResults.HuskyCITrivyConfigOutput.LowVulns)
+	printSTDOUTOutputTrivyConfig(outputJSON.IaCResults.HuskyCITrivyConfigOutput.MediumVulns)
+	printSTDOUTOutputTrivyConfig(outputJSON.IaCResults.HuskyCITrivyConfigOutput.HighVulns)
+
 	// securitycodescan
 	printSTDOUTOutputSecurityCodeScan(outputJSON.CSharpResults.HuskyCISecurityCodeScanOutput.LowVulns)
 	printSTDOUTOutputSecurityCodeScan(outputJSON.CSharpResults.HuskyCISecurityCodeScanOutput.MediumVulns)
 	printSTDOUTOutputSecurityCodeScan(outputJSON.CSharpResults.HuskyCISecurityCodeScanOutput.HighVulns)
 
 	printAllSummary(analysis)
+	printCommit(analysis)
+	printToolConfigs(analysis)
+	printWarnings()
+}
+
+// printCommit prints the commit the analysis scanned, so that its results can
+// be reproduced even after the branch moved.
+func printCommit(analysis types.Analysis) {
+	if analysis.Commit != "" {
+		fmt.Printf("[HUSKYCI][*] Analyzed commit: %s\n", analysis.Commit)
+	}
+}
+
+// printToolConfigs prints the configuration files of the repository each
+// securityTest applied, as they may have disabled some of its checks.
+func printToolConfigs(analysis types.Analysis) {
+	for _, container := range analysis.Containers {
+		if len(container.ToolConfigs) > 0 {
+			fmt.Printf("[HUSKYCI][*] %s ran with the configuration of the repository: %s\n", container.SecurityTest.Name, strings.Join(container.ToolConfigs, ", "))
+		}
+	}
+}
+
+// printWarnings prints why the results of the analysis are partial, if they are.
+func printWarnings() {
+	if len(outputJSON.Warnings) == 0 {
+		return
+	}
+	fmt.Println()
+	fmt.Println("[HUSKYCI][WARNING] The results are partial:")
+	for _, warning := range outputJSON.Warnings {
+		fmt.Printf("[HUSKYCI][WARNING] %s\n", warning)
+	}
 }
 
 // prepareAllSummary prepares how many low, medium and high vulnerabilites were found.
 func prepareAllSummary(analysis types.Analysis) {
 	var totalNoSec, totalLow, totalMedium, totalHigh int
 
+	outputJSON.Summary.Commit = analysis.Commit
 	outputJSON.GoResults = analysis.HuskyCIResults.GoResults
 	outputJSON.JavaScriptResults = analysis.HuskyCIResults.JavaScriptResults
 	outputJSON.PythonResults = analysis.HuskyCIResults.PythonResults
 	outputJSON.RubyResults = analysis.HuskyCIResults.RubyResults
 	outputJSON.JavaResults = analysis.HuskyCIResults.JavaResults
 	outputJSON.HclResults = analysis.HuskyCIResults.HclResults
+	outputJSON.IaCResults = analysis.HuskyCIResults.IaCResults
 	outputJSON.CSharpResults = analysis.HuskyCIResults.CSharpResults
 	outputJSON.GenericResults = analysis.HuskyCIResults.GenericResults
 
@@ -196,6 +240,17 @@ func prepareAllSummary(analysis types.Analysis) {
 		outputJSON.Summary.TFSecSummary.FoundVuln = true
 	}
 
+	// TrivyConfig summary
+	outputJSON.Summary.TrivyConfigSummary.LowVuln = len(outputJSON.IaCResults.HuskyCITrivyConfigOutput.LowVulns)
+	outputJSON.Summary.TrivyConfigSummary.MediumVuln = len(outputJSON.IaCResults.HuskyCITrivyConfigOutput.MediumVulns)
+	outputJSON.Summary.TrivyConfigSummary.HighVuln = len(outputJSON.IaCResults.HuskyCITrivyConfigOutput.HighVulns)
+	if len(outputJSON.IaCResults.HuskyCITrivyConfigOutput.LowVulns) > 0 || len(outputJSON.IaCResults.HuskyCITrivyConfigOutput.NoSecVulns) > 0 {
+		outputJSON.Summary.TrivyConfigSummary.FoundInfo = true
+	}
+	if len(outputJSON.IaCResults.HuskyCITrivyConfigOutput.MediumVulns) > 0 || len(outputJSON.IaCResults.HuskyCITrivyConfigOutput.HighVulns) > 0 {
+		outputJSON.Summary.TrivyConfigSummary.FoundVuln = true
+	}
+
 	// SecurityCodeScan summary
 	outputJSON.Summary.SecurityCodeScanSummary.LowVuln = len(outputJSON.CSharpResults.HuskyCISecurityCodeScanOutput.LowVulns)
 	outputJSON.Summary.SecurityCodeScanSummary.MediumVuln = len(outputJSON.CSharpResults.HuskyCISecurityCodeScanOutput.MediumVulns)
@@ -208,32 +263,34 @@ func prepareAllSummary(analysis types.Analysis) {
 	}
 
 	// Total summary
-	if outputJSON.Summary.GosecSummary.FoundVuln || outputJSON.Summary.BanditSummary.FoundVuln || outputJSON.Summary.SafetySummary.FoundVuln || outputJSON.Summary.BrakemanSummary.FoundVuln || outputJSON.Summary.NpmAuditSummary.FoundVuln || outputJSON.Summary.YarnAuditSummary.FoundVuln || outputJSON.Summary.GitleaksSummary.FoundVuln || outputJSON.Summary.SpotBugsSummary.FoundVuln || outputJSON.Summary.TFSecSummary.FoundVuln || outputJSON.Summary.SecurityCodeScanSummary.FoundVuln {
+	if outputJSON.Summary.GosecSummary.FoundVuln || outputJSON.Summary.BanditSummary.FoundVuln || outputJSON.Summary.SafetySummary.FoundVuln || outputJSON.Summary.BrakemanSummary.FoundVuln || outputJSON.Summary.NpmAuditSummary.FoundVuln || outputJSON.Summary.YarnAuditSummary.FoundVuln || outputJSON.Summary.GitleaksSummary.FoundVuln || outputJSON.Summary.SpotBugsSummary.FoundVuln || outputJSON.Summary.TFSecSummary.FoundVuln || outputJSON.Summary.TrivyConfigSummary.FoundVuln || outputJSON.Summary.SecurityCodeScanSummary.FoundVuln {
 		outputJSON.Summary.TotalSummary.FoundVuln = true
 		types.FoundVuln = true
-	} else if outputJSON.Summary.GosecSummary.FoundInfo || outputJSON.Summary.BanditSummary.FoundInfo || outputJSON.Summary.SafetySummary.FoundInfo || outputJSON.Summary.BrakemanSummary.FoundInfo || outputJSON.Summary.NpmAuditSummary.FoundInfo || outputJSON.Summary.YarnAuditSummary.FoundInfo || outputJSON.Summary.GitleaksSummary.FoundInfo || outputJSON.Summary.SpotBugsSummary.FoundInfo || outputJSON.Summary.TFSecSummary.FoundInfo || outputJSON.Summary.SecurityCodeScanSummary.FoundInfo {
+	} else if outputJSON.Summary.GosecSummary.FoundInfo || outputJSON.Summary.BanditSummary.FoundInfo || outputJSON.Summary.SafetySummary.FoundInfo || outputJSON.Summary.BrakemanSummary.FoundInfo || outputJSON.Summary.NpmAuditSummary.FoundInfo || outputJSON.Summary.YarnAuditSummary.FoundInfo || outputJSON.Summary.GitleaksSummary.FoundInfo || outputJSON.Summary.SpotBugsSummary.FoundInfo || outputJSON.Summary.TFSecSummary.FoundInfo || outputJSON.Summary.TrivyConfigSummary.FoundInfo || outputJSON.Summary.SecurityCodeScanSummary.FoundInfo {
 		outputJSON.Summary.TotalSummary.FoundInfo = true
 		types.FoundInfo = true
 	}
 
 	totalNoSec = outputJSON.Summary.BrakemanSummary.NoSecVuln + outputJSON.Summary.BanditSummary.NoSecVuln + outputJSON.Summary.GosecSummary.NoSecVuln + outputJSON.Summary.GitleaksSummary.NoSecVuln
 
-	totalLow = outputJSON.Summary.BrakemanSummary.LowVuln + outputJSON.Summary.SafetySummary.LowVuln + outputJSON.Summary.BanditSummary.LowVuln + outputJSON.Summary.GosecSummary.LowVuln + outputJSON.Summary.NpmAuditSummary.LowVuln + outputJSON.Summary.YarnAuditSummary.LowVuln + outputJSON.Summary.GitleaksSummary.LowVuln + outputJSON.Summary.SpotBugsSummary.LowVuln + outputJSON.Summary.TFSecSummary.LowVuln + outputJSON.Summary.SecurityCodeScanSummary.LowVuln
+	totalLow = outputJSON.Summary.BrakemanSummary.LowVuln + outputJSON.Summary.SafetySummary.LowVuln + outputJSON.Summary.BanditSummary.LowVuln + outputJSON.Summary.GosecSummary.LowVuln + outputJSON.Summary.NpmAuditSummary.LowVuln + outputJSON.Summary.YarnAuditSummary.LowVuln + outputJSON.Summary.GitleaksSummary.LowVuln + outputJSON.Summary.SpotBugsSummary.LowVuln + outputJSON.Summary.TFSecSummary.LowVuln + outputJSON.Summary.TrivyConfigSummary.LowVuln + outputJSON.Summary.SecurityCodeScanSummary.LowVuln
 
-	totalMedium = outputJSON.Summary.BrakemanSummary.MediumVuln + outputJSON.Summary.SafetySummary.MediumVuln + outputJSON.Summary.BanditSummary.MediumVuln + outputJSON.Summary.GosecSummary.MediumVuln + outputJSON.Summary.NpmAuditSummary.MediumVuln + outputJSON.Summary.YarnAuditSummary.MediumVuln + outputJSON.Summary.GitleaksSummary.MediumVuln + outputJSON.Summary.SpotBugsSummary.MediumVuln + outputJSON.Summary.TFSecSummary.MediumVuln + outputJSON.Summary.SecurityCodeScanSummary.MediumVuln
+	totalMedium = outputJSON.Summary.BrakemanSummary.MediumVuln + outputJSON.Summary.SafetySummary.MediumVuln + outputJSON.Summary.BanditSummary.MediumVuln + outputJSON.Summary.GosecSummary.MediumVuln + outputJSON.Summary.NpmAuditSummary.MediumVuln + outputJSON.Summary.YarnAuditSummary.MediumVuln + outputJSON.Summary.GitleaksSummary.MediumVuln + outputJSON.Summary.SpotBugsSummary.MediumVuln + outputJSON.Summary.TFSecSummary.MediumVuln + outputJSON.Summary.TrivyConfigSummary.MediumVuln + outputJSON.Summary.SecurityCodeScanSummary.MediumVuln
 
-	totalHigh = outputJSON.Summary.BrakemanSummary.HighVuln + outputJSON.Summary.SafetySummary.HighVuln + outputJSON.Summary.BanditSummary.HighVuln + outputJSON.Summary.GosecSummary.HighVuln + outputJSON.Summary.NpmAuditSummary.HighVuln + outputJSON.Summary.YarnAuditSummary.HighVuln + outputJSON.Summary.GitleaksSummary.HighVuln + outputJSON.Summary.SpotBugsSummary.HighVuln + outputJSON.Summary.TFSecSummary.HighVuln + outputJSON.Summary.SecurityCodeScanSummary.HighVuln
+	totalHigh = outputJSON.Summary.BrakemanSummary.HighVuln + outputJSON.Summary.SafetySummary.HighVuln + outputJSON.Summary.BanditSummary.HighVuln + outputJSON.Summary.GosecSummary.HighVuln + outputJSON.Summary.NpmAuditSummary.HighVuln + outputJSON.Summary.YarnAuditSummary.HighVuln + outputJSON.Summary.GitleaksSummary.HighVuln + outputJSON.Summary.SpotBugsSummary.HighVuln + outputJSON.Summary.TFSecSummary.HighVuln + outputJSON.Summary.TrivyConfigSummary.HighVuln + outputJSON.Summary.SecurityCodeScanSummary.HighVuln
 
 	outputJSON.Summary.TotalSummary.HighVuln = totalHigh
 	outputJSON.Summary.TotalSummary.MediumVuln = totalMedium
 	outputJSON.Summary.TotalSummary.LowVuln = totalLow
 	outputJSON.Summary.TotalSummary.NoSecVuln = totalNoSec
 
+	prepareDecision(analysis)
+
 }
 
 func printAllSummary(analysis types.Analysis) {
 
-	var gosecVersion, banditVersion, safetyVersion, brakemanVersion, npmauditVersion, yarnauditVersion, gitleaksVersion, spotbugsVersion, tfsecVersion, securityCodeScanVersion string
+	var gosecVersion, banditVersion, safetyVersion, brakemanVersion, npmauditVersion, yarnauditVersion, gitleaksVersion, spotbugsVersion, tfsecVersion, trivyConfigVersion, securityCodeScanVersion string
 
 	for _, container := range analysis.Containers {
 		switch container.SecurityTest.Name {
@@ -255,6 +312,8 @@ func printAllSummary(analysis types.Analysis) {
 			gitleaksVersion = fmt.Sprintf("%s:%s", container.SecurityTest.Image, container.SecurityTest.ImageTag)
 		case "tfsec":
 			tfsecVersion = fmt.Sprintf("%s:%s", container.SecurityTest.Image, container.SecurityTest.ImageTag)
+		case "trivyconfig":
+			trivyConfigVersion = fmt.Sprintf("%s:%s", container.SecurityTest.Image, container.SecurityTest.ImageTag)
 		case "securitycodescan":
 			securityCodeScanVersion = fmt.Sprintf("%s:%s", container.SecurityTest.Image, container.SecurityTest.ImageTag)
 		}
@@ -323,7 +382,7 @@ func printAllSummary(analysis types.Analysis) {
 		fmt.Printf("[HUSKYCI][SUMMARY] NoSecHusky: %d\n", outputJSON.Summary.SpotBugsSummary.NoSecVuln)
 	}
 
-	if outputJSON.Summary.TFSecSummary.FoundVuln || outputJSON.Summary.TFSecSummary.FoundInfo {
+	if outputJSON.Summary.TFSecSummary.FoundVuln || outputJSON.Summary.TrivyConfigSummary.FoundVuln || outputJSON.Summary.TFSecSummary.FoundInfo {
 		fmt.Println()
 		fmt.Printf("[HUSKYCI][SUMMARY] HCL -> %s\n", tfsecVersion)
 		fmt.Printf("[HUSKYCI][SUMMARY] High: %d\n", outputJSON.Summary.TFSecSummary.HighVuln)
@@ -332,6 +391,15 @@ func printAllSummary(analysis types.Analysis) {
 		fmt.Printf("[HUSKYCI][SUMMARY] NoSecHusky: %d\n", outputJSON.Summary.TFSecSummary.NoSecVuln)
 	}
 
+	if outputJSON.Summary.TrivyConfigSummary.FoundVuln || outputJSON.Summary.TrivyConfigSummary.FoundInfo {
+		fmt.Println()
+		fmt.Printf("[HUSKYCI][SUMMARY] IaC -> %s\n", trivyConfigVersion)
+		fmt.Printf("[HUSKYCI][SUMMARY] High: %d\n", outputJSON.Summary.TrivyConfigSummary.HighVuln)
+		fmt.Printf("[HUSKYCI][SUMMARY] Medium: %d\n", outputJSON.Summary.TrivyConfigSummary.MediumVuln)
+		fmt.Printf("[HUSKYCI][SUMMARY] Low: %d\n", outputJSON.Summary.TrivyConfigSummary.LowVuln)
+		fmt.Printf("[HUSKYCI][SUMMARY] NoSecHusky: %d\n", outputJSON.Summary.TrivyConfigSummary.NoSecVuln)
+	}
+
 	if outputJSON.Summary.SecurityCodeScanSummary.FoundVuln || outputJSON.Summary.SecurityCodeScanSummary.FoundInfo {
 		fmt.Println()
 		fmt.Printf("[HUSKYCI][SUMMARY] C# -> %s\n", securityCodeScanVersion)
@@ -373,6 +441,7 @@ func printSTDOUTOutputGosec(issues []types.HuskyCIVulnerability) {
 		fmt.Printf("[HUSKYCI][!] Details: %s\n", issue.Details)
 		fmt.Printf("[HUSKYCI][!] File: %s\n", issue.File)
 		fmt.Printf("[HUSKYCI][!] Line: %s\n", issue.Line)
+		printAuthor(issue)
 		fmt.Printf("[HUSKYCI][!] Code: %s\n", issue.Code)
 	}
 }
@@ -388,6 +457,7 @@ func printSTDOUTOutputBandit(issues []types.HuskyCIVulnerability) {
 		fmt.Printf("[HUSKYCI][!] Details: %s\n", issue.Details)
 		fmt.Printf("[HUSKYCI][!] File: %s\n", issue.File)
 		fmt.Printf("[HUSKYCI][!] Line: %s\n", issue.Line)
+		printAuthor(issue)
 		fmt.Printf("[HUSKYCI][!] Code:\n%s\n", issue.Code)
 	}
 }
@@ -402,11 +472,35 @@ func printSTDOUTOutputSafety(issues []types.HuskyCIVulnerability) {
 		if issue.Details != "requirements.txt not found" && !strings.Contains(issue.Details, "Unpinned requirement ") {
 			fmt.Printf("[HUSKYCI][!] Code: %s\n", issue.Code)
 			fmt.Printf("[HUSKYCI][!] Vulnerable Below: %s\n", issue.VunerableBelow)
+			printFixVersion(issue)
 		}
 		fmt.Printf("[HUSKYCI][!] Details: %s\n", issue.Details)
 	}
 }
 
+// printFixVersion prints the upgrade fixing a vulnerable dependency, when one is known.
+func printFixVersion(issue types.HuskyCIVulnerability) {
+	if issue.FixVersion != "" {
+		fmt.Printf("[HUSKYCI][!] Fix: upgrade %s to %s\n", issue.Package, issue.FixVersion)
+	}
+}
+
+// printPackagePath prints the directory of the package of a monorepo depending
+// on a vulnerable dependency, when it is not at the root of the repository.
+func printPackagePath(issue types.HuskyCIVulnerability) {
+	if issue.PackagePath != "" {
+		fmt.Printf("[HUSKYCI][!] Package path: %s\n", issue.PackagePath)
+	}
+}
+
+// printAuthor prints the commit and author that last changed the line of a
+// finding, when the API could attribute it.
+func printAuthor(issue types.HuskyCIVulnerability) {
+	if issue.Commit != "" {
+		fmt.Printf("[HUSKYCI][!] Introduced by: %s <%s> in %.12s\n", issue.Author, issue.AuthorEmail, issue.Commit)
+	}
+}
+
 func printSTDOUTOutputBrakeman(issues []types.HuskyCIVulnerability) {
 	for _, issue := range issues {
 		fmt.Println()
@@ -417,6 +511,7 @@ func printSTDOUTOutputBrakeman(issues []types.HuskyCIVulnerability) {
 		fmt.Printf("[HUSKYCI][!] Details: %s\n", issue.Details)
 		fmt.Printf("[HUSKYCI][!] File: %s\n", issue.File)
 		fmt.Printf("[HUSKYCI][!] Line: %s\n", issue.Line)
+		printAuthor(issue)
 		fmt.Printf("[HUSKYCI][!] Code: %s\n", issue.Code)
 		fmt.Printf("[HUSKYCI][!] Type: %s\n", issue.Type)
 	}
@@ -433,6 +528,8 @@ func printSTDOUTOutputNpmAudit(issues []types.HuskyCIVulnerability) {
 			fmt.Printf("[HUSKYCI][!] Code: %s\n", issue.Code)
 			fmt.Printf("[HUSKYCI][!] Version: %s\n", issue.Version)
 			fmt.Printf("[HUSKYCI][!] Vulnerable Below: %s\n", issue.VunerableBelow)
+			printPackagePath(issue)
+			printFixVersion(issue)
 		}
 		fmt.Printf("[HUSKYCI][!] Details: %s\n", issue.Details)
 	}
@@ -450,6 +547,8 @@ func printSTDOUTOutputYarnAudit(issues []types.HuskyCIVulnerability) {
 			fmt.Printf("[HUSKYCI][!] Occurrences: %d\n", issue.Occurrences)
 			fmt.Printf("[HUSKYCI][!] Version: %s\n", issue.Version)
 			fmt.Printf("[HUSKYCI][!] Vulnerable Below: %s\n", issue.VunerableBelow)
+			printPackagePath(issue)
+			printFixVersion(issue)
 		}
 		fmt.Printf("[HUSKYCI][!] Details: %s\n", issue.Details)
 	}
@@ -466,6 +565,7 @@ func printSTDOUTOutputSpotBugs(issues []types.HuskyCIVulnerability) {
 		fmt.Printf("[HUSKYCI][!] Details: %s\n", issue.Details)
 		fmt.Printf("[HUSKYCI][!] File: %s\n", issue.File)
 		fmt.Printf("[HUSKYCI][!] Line: %s\n", issue.Line)
+		printAuthor(issue)
 		fmt.Printf("[HUSKYCI][!] Code: %s\n", issue.Code)
 		fmt.Printf("[HUSKYCI][!] Type: %s\n", issue.Type)
 	}
@@ -481,6 +581,22 @@ func printSTDOUTOutputTFSec(issues []types.HuskyCIVulnerability) {
 		fmt.Printf("[HUSKYCI][!] Details: %s\n", issue.Details)
 		fmt.Printf("[HUSKYCI][!] File: %s\n", issue.File)
 		fmt.Printf("[HUSKYCI][!] Line: %s\n", issue.Line)
+		printAuthor(issue)
+		fmt.Printf("[HUSKYCI][!] Code: %s\n", issue.Code)
+	}
+}
+
+func printSTDOUTOutputTrivyConfig(issues []types.HuskyCIVulnerability) {
+	for _, issue := range issues {
+		fmt.Println()
+		fmt.Printf("[HUSKYCI][!] Title: %s\n", issue.Title)
+		fmt.Printf("[HUSKYCI][!] Language: %s\n", issue.Language)
+		fmt.Printf("[HUSKYCI][!] Tool: %s\n", issue.SecurityTool)
+		fmt.Printf("[HUSKYCI][!] Severity: %s\n", issue.Severity)
+		fmt.Printf("[HUSKYCI][!] Details: %s\n", issue.Details)
+		fmt.Printf("[HUSKYCI][!] File: %s\n", issue.File)
+		fmt.Printf("[HUSKYCI][!] Line: %s\n", issue.Line)
+		printAuthor(issue)
 		fmt.Printf("[HUSKYCI][!] Code: %s\n", issue.Code)
 	}
 }
@@ -493,6 +609,7 @@ func printSTDOUTOutputGitleaks(issues []types.HuskyCIVulnerability) {
 		fmt.Printf("[HUSKYCI][!] Severity: %s\n", issue.Severity)
 		fmt.Printf("[HUSKYCI][!] Details: %s\n", issue.Details)
 		fmt.Printf("[HUSKYCI][!] File: %s\n", issue.File)
+		printAuthor(issue)
 		fmt.Printf("[HUSKYCI][!] Code: %s\n", issue.Code)
 	}
 }
@@ -508,6 +625,7 @@ func printSTDOUTOutputSecurityCodeScan(issues []types.HuskyCIVulnerability) {
 		if !strings.Contains(issue.Details, "could not run 'security-scan' on your project") {
 			fmt.Printf("[HUSKYCI][!] File: %s\n", issue.File)
 			fmt.Printf("[HUSKYCI][!] Line: %s\n", issue.Line)
+			printAuthor(issue)
 			fmt.Printf("[HUSKYCI][!] Code: %s\n", issue.Code)
 		}
 	}
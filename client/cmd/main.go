@@ -5,14 +5,21 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/huskyci-org/huskyCI/client/integration/sonarqube"
 
 	"github.com/huskyci-org/huskyCI/client/analysis"
 	"github.com/huskyci-org/huskyCI/client/config"
+	"github.com/huskyci-org/huskyCI/client/schema"
 	"github.com/huskyci-org/huskyCI/client/types"
+	"github.com/huskyci-org/huskyCI/pkg/formatter"
+	"github.com/huskyci-org/huskyCI/pkg/i18n"
 )
 
 const (
@@ -21,58 +28,98 @@ const (
 	msgSecurityTestsFailed = "[HUSKYCI][*] The following securityTests failed to run:"
 	msgNoIssuesFound = "[HUSKYCI][*] No issues were found."
 	msgLowInfoIssuesFound = "[HUSKYCI][*] However, some LOW/INFO issues were found..."
-	msgHighMediumIssuesFound = "[HUSKYCI][*] Some HIGH/MEDIUM issues were found in these securityTests:"
+	msgBlockingIssuesFound = "[HUSKYCI][*] Some %s issues were found in these securityTests:\n"
+	msgWarnOnlyIssuesFound = "[HUSKYCI][*] Some issues were found, but they do not fail the CI in warn-only mode."
+	msgBelowFailOnIssuesFound = "[HUSKYCI][*] Some issues were found, but they do not fail the CI, which fails on: %s.\n"
 )
 
+// blockingSeverities are the severities found failing the CI, by the lowest of them.
+var blockingSeverities = map[string]string{
+	"high":   "HIGH",
+	"medium": "HIGH/MEDIUM",
+	"low":    "HIGH/MEDIUM/LOW",
+}
+
+// version is set by the Makefile when building the client.
+var version string
+
 func main() {
 
 	types.FoundVuln = false
+	if len(os.Args) > 1 && os.Args[1] == "schema" {
+		os.Stdout.Write(schema.Schema)
+		return
+	}
 	setJSONOutputFlag()
 
 	// step 0: check and set huskyci-client configuration
 	if err := initializeConfig(); err != nil {
+		if errors.Is(err, config.ErrHelp) {
+			return
+		}
 		if !types.IsJSONoutput {
-			fmt.Fprintf(os.Stderr, "\n❌ Configuration Error:\n%s\n", err)
+			i18n.Fprintf(formatter.Stderr, "\n❌ Configuration Error:\n%s\n", err)
 		} else {
-			fmt.Fprintf(os.Stderr, "[HUSKYCI][ERROR] Configuration error: %s\n", err)
+			fmt.Fprintf(formatter.Stderr, "[HUSKYCI][ERROR] Configuration error: %s\n", err)
 		}
 		os.Exit(1)
 	}
 
-	// step 1: start analysis and get its RID.
-	RID, err := startAnalysis()
+	// an interrupt stops the client and cancels the analysis it started
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// step 0.5: warn if huskyCI API requires a newer client.
+	analysis.CheckClientVersion(ctx)
+
+	// step 1: start analysis and get its RID, unless a running one is resumed.
+	RID := config.ResumeRID
+	var err error
+	if RID == "" {
+		RID, err = startAnalysis(ctx)
+	} else if !types.IsJSONoutput {
+		i18n.Printf("🔁 Resuming analysis %s...\n\n", RID)
+	}
 	if err != nil {
 		if !types.IsJSONoutput {
-			fmt.Fprintf(os.Stderr, "\n❌ Failed to start analysis:\n%s\n", err)
+			i18n.Fprintf(formatter.Stderr, "\n❌ Failed to start analysis:\n%s\n", err)
 		} else {
-			fmt.Fprintf(os.Stderr, "[HUSKYCI][ERROR] Failed to start analysis: %s\n", err)
+			fmt.Fprintf(formatter.Stderr, "[HUSKYCI][ERROR] Failed to start analysis: %s\n", err)
 		}
 		os.Exit(1)
 	}
 
 	// step 2.1: keep querying huskyCI API to check if a given analysis has already finished.
-	huskyAnalysis, err := analysis.MonitorAnalysis(RID)
+	huskyAnalysis, err := analysis.MonitorAnalysis(ctx, RID)
 	if err != nil {
 		if !types.IsJSONoutput {
-			fmt.Fprintf(os.Stderr, "\n❌ Analysis monitoring failed:\n%s\n", err)
+			i18n.Fprintf(formatter.Stderr, "\n❌ Analysis monitoring failed:\n%s\n", err)
 		} else {
-			fmt.Fprintf(os.Stderr, "[HUSKYCI][ERROR] Analysis monitoring failed (RID: %s): %s\n", RID, err)
+			fmt.Fprintf(formatter.Stderr, "[HUSKYCI][ERROR] Analysis monitoring failed (RID: %s): %s\n", RID, err)
 		}
 		os.Exit(1)
 	}
 
+	// a resumed analysis follows the policy of the repository it was started on.
+	if config.RepositoryURL == "" {
+		config.RepositoryURL = huskyAnalysis.URL
+	}
+
 	// step 2.2: prepare the list of securityTests that ran in the analysis.
 	passedList, failedList, errorList := categorizeSecurityTests(huskyAnalysis)
 
+	// step 2.3: get the severity the repository fails on, unless it was set.
+	analysis.ResolveFailOn(ctx)
+
 	// step 3: print output based on os.Args(1) parameter received
 	setJSONOutputFlag()
 
 	err = analysis.PrintResults(huskyAnalysis)
 	if err != nil {
 		if !types.IsJSONoutput {
-			fmt.Fprintf(os.Stderr, "\n⚠️  Warning: Failed to print results: %s\n", err)
+			i18n.Fprintf(formatter.Stderr, "\n⚠️  Warning: Failed to print results: %s\n", err)
 		} else {
-			fmt.Fprintf(os.Stderr, "[HUSKYCI][ERROR] Failed to print results: %s\n", err)
+			fmt.Fprintf(formatter.Stderr, "[HUSKYCI][ERROR] Failed to print results: %s\n", err)
 		}
 		// Don't exit here, continue to SonarQube output generation
 	}
@@ -80,54 +127,61 @@ func main() {
 	// step 3.5: integration with SonarQube
 	if err := generateSonarQubeOutput(huskyAnalysis); err != nil {
 		if !types.IsJSONoutput {
-			fmt.Fprintf(os.Stderr, "\n⚠️  Warning: Failed to generate SonarQube output file: %s\n", err)
-			fmt.Fprintf(os.Stderr, "Tip: The analysis completed successfully, but SonarQube integration output could not be generated.\n")
+			i18n.Fprintf(formatter.Stderr, "\n⚠️  Warning: Failed to generate SonarQube output file: %s\n", err)
+			i18n.Fprintf(formatter.Stderr, "Tip: The analysis completed successfully, but SonarQube integration output could not be generated.\n")
 		} else {
-			fmt.Fprintf(os.Stderr, "[HUSKYCI][ERROR] Failed to generate SonarQube JSON file: %s\n", err)
+			fmt.Fprintf(formatter.Stderr, "[HUSKYCI][ERROR] Failed to generate SonarQube JSON file: %s\n", err)
 		}
 		// Don't exit here, continue to vulnerability handling
 	}
 
 	// step 4: block developer CI if vulnerabilities were found
-	exitCode := handleVulnerabilityResults(passedList, failedList, errorList)
+	exitCode := handleVulnerabilityResults(analysis.Decision(), passedList, failedList, errorList)
 	os.Exit(exitCode)
 }
 
 func setJSONOutputFlag() {
-	types.IsJSONoutput = len(os.Args) > 1 && os.Args[1] == "JSON"
+	types.IsJSONoutput = config.OutputFormat == config.OutputJSON || (len(os.Args) > 1 && os.Args[1] == "JSON")
 }
 
 func printErrorIfNotJSON(message string, err error) {
 	if !types.IsJSONoutput {
-		fmt.Println(message, err)
+		formatter.Println(message, err)
 	}
 }
 
 func initializeConfig() error {
-	if err := config.CheckEnvVars(); err != nil {
+	if err := config.Load(os.Args[1:]); err != nil {
 		return err
 	}
-	config.SetConfigs()
+	config.Version = version
+	if config.Plain {
+		formatter.SetPlain(true)
+	}
+	setJSONOutputFlag()
 	return nil
 }
 
-func startAnalysis() (string, error) {
+func startAnalysis(ctx context.Context) (string, error) {
 	if !types.IsJSONoutput {
-		fmt.Println("🚀 Starting huskyCI analysis...")
-		fmt.Printf("📦 Repository: %s\n", config.RepositoryURL)
-		fmt.Printf("🌿 Branch: %s\n", config.RepositoryBranch)
-		fmt.Println()
+		formatter.Println(i18n.T("🚀 Starting huskyCI analysis..."))
+		i18n.Printf("📦 Repository: %s\n", config.RepositoryURL)
+		i18n.Printf("🌿 Branch: %s\n", config.RepositoryBranch)
+		if config.RepositoryCommit != "" {
+			i18n.Printf("🔖 Commit: %s\n", config.RepositoryCommit)
+		}
+		formatter.Println()
 	}
 
-	RID, err := analysis.StartAnalysis()
+	RID, err := analysis.StartAnalysis(ctx)
 	if err != nil {
 		return "", err
 	}
 
 	if !types.IsJSONoutput {
-		fmt.Printf("✓ Analysis started successfully!\n")
-		fmt.Printf("📋 Request ID (RID): %s\n", RID)
-		fmt.Println()
+		i18n.Printf("✓ Analysis started successfully!\n")
+		i18n.Printf("📋 Request ID (RID): %s\n", RID)
+		formatter.Println()
 	}
 
 	return RID, nil
@@ -159,60 +213,91 @@ func generateSonarQubeOutput(huskyAnalysis types.Analysis) error {
 
 	if _, err := os.Stat(outputPath); os.IsNotExist(err) {
 		if err := os.MkdirAll(outputPath, os.ModePerm); err != nil {
-			return fmt.Errorf("failed to create output directory: %w", err)
+			return i18n.Errorf("failed to create output directory: %w", err)
 		}
 	}
 
 	return sonarqube.GenerateOutputFile(huskyAnalysis, outputPath, outputFileName)
 }
 
-func handleVulnerabilityResults(passedList, failedList, errorList []string) int {
+func handleVulnerabilityResults(blocking types.Blocking, passedList, failedList, errorList []string) int {
 	switch {
+	case blocking.Blocked:
+		printVulnerabilitiesFound(blocking, passedList, failedList, errorList)
+		return blocking.ExitCode
+	case len(blocking.Reasons) > 0 || types.FoundVuln:
+		printUnblockedVulnerabilitiesFound(blocking, passedList, errorList)
+		return 0
 	case !types.FoundVuln && !types.FoundInfo:
 		printNoVulnerabilitiesFound(passedList, errorList)
 		return 0
-	case !types.FoundVuln && types.FoundInfo:
+	default:
 		printInfoVulnerabilitiesFound(passedList, errorList)
 		return 0
-	default:
-		printVulnerabilitiesFound(passedList, failedList, errorList)
-		return 190
 	}
 }
 
 func printNoVulnerabilitiesFound(passedList, errorList []string) {
 	if !types.IsJSONoutput {
 		printErrorList(errorList)
-		fmt.Println(msgNoBlockingVulns)
-		fmt.Println(huskyCIPrefix, passedList)
-		fmt.Println(msgNoIssuesFound)
+		formatter.Println(i18n.T(msgNoBlockingVulns))
+		formatter.Println(huskyCIPrefix, passedList)
+		formatter.Println(i18n.T(msgNoIssuesFound))
 	}
 }
 
 func printInfoVulnerabilitiesFound(passedList, errorList []string) {
 	if !types.IsJSONoutput {
 		printErrorList(errorList)
-		fmt.Println(msgNoBlockingVulns)
-		fmt.Println(huskyCIPrefix, passedList)
-		fmt.Println(msgLowInfoIssuesFound)
+		formatter.Println(i18n.T(msgNoBlockingVulns))
+		formatter.Println(huskyCIPrefix, passedList)
+		formatter.Println(i18n.T(msgLowInfoIssuesFound))
 	}
 }
 
-func printVulnerabilitiesFound(passedList, failedList, errorList []string) {
+func printVulnerabilitiesFound(blocking types.Blocking, passedList, failedList, errorList []string) {
 	if !types.IsJSONoutput {
 		printErrorList(errorList)
 		if len(passedList) > 0 {
-			fmt.Println(msgNoBlockingVulns)
-			fmt.Println(huskyCIPrefix, passedList)
+			formatter.Println(i18n.T(msgNoBlockingVulns))
+			formatter.Println(huskyCIPrefix, passedList)
+		}
+		i18n.Printf(msgBlockingIssuesFound, blockingSeverities[blocking.FailOn])
+		formatter.Println(huskyCIPrefix, blockingTools(blocking, failedList))
+	}
+}
+
+func printUnblockedVulnerabilitiesFound(blocking types.Blocking, passedList, errorList []string) {
+	if !types.IsJSONoutput {
+		printErrorList(errorList)
+		formatter.Println(i18n.T(msgNoBlockingVulns))
+		formatter.Println(huskyCIPrefix, passedList)
+		if blocking.WarnOnly {
+			formatter.Println(i18n.T(msgWarnOnlyIssuesFound))
+		} else {
+			i18n.Printf(msgBelowFailOnIssuesFound, blocking.FailOn)
+		}
+	}
+}
+
+// blockingTools returns failedList, or the tools with blocking findings when
+// no securityTest failed, as LOW findings do not fail them.
+func blockingTools(blocking types.Blocking, failedList []string) []string {
+	if len(failedList) > 0 {
+		return failedList
+	}
+	tools := []string{}
+	for _, reason := range blocking.Reasons {
+		if len(tools) == 0 || tools[len(tools)-1] != reason.Tool {
+			tools = append(tools, reason.Tool)
 		}
-		fmt.Println(msgHighMediumIssuesFound)
-		fmt.Println(huskyCIPrefix, failedList)
 	}
+	return tools
 }
 
 func printErrorList(errorList []string) {
 	if len(errorList) > 0 {
-		fmt.Println(msgSecurityTestsFailed)
-		fmt.Println(huskyCIPrefix, errorList)
+		formatter.Println(i18n.T(msgSecurityTestsFailed))
+		formatter.Println(huskyCIPrefix, errorList)
 	}
 }
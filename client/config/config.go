@@ -5,8 +5,18 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/pkg/sdk"
+	"github.com/spf13/cast"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
 )
 
+// Version stores the version the client was built with, empty for
+// development builds.
+var Version string
+
 // RepositoryURL stores the repository URL of the project to be analyzed.
 var RepositoryURL string
 
@@ -16,63 +26,263 @@ var HuskyAPI string
 // RepositoryBranch stores the repository branch of the project to be analyzed.
 var RepositoryBranch string
 
+// RepositoryCommit stores the commit to analyze rather than the head of the
+// branch, which the result of the analysis is reported on when the repository
+// has a status reporter.
+var RepositoryCommit string
+
 // HuskyToken is the token used to scan a repository.
 var HuskyToken string
 
 // LanguageExclusions stores a map of languages to exclude from analysis.
 var LanguageExclusions map[string]bool
 
+// SecurityTests stores the securityTests the analysis runs, all of them when
+// it is empty.
+var SecurityTests []string
+
 // HuskyUseTLS stores if huskyCI is to use an HTTPS connection.
 var HuskyUseTLS bool
 
-// SetConfigs sets all configuration needed to start the client.
-func SetConfigs() {
-	RepositoryURL = os.Getenv(`HUSKYCI_CLIENT_REPO_URL`)
-	RepositoryBranch = os.Getenv(`HUSKYCI_CLIENT_REPO_BRANCH`)
-	HuskyAPI = os.Getenv(`HUSKYCI_CLIENT_API_ADDR`)
-	exclusionsEnv := os.Getenv(`HUSKYCI_LANGUAGE_EXCLUSIONS`)
-	if exclusionsEnv != "" {
-		languagesToExclude := strings.Split(exclusionsEnv, ",")
-		LanguageExclusions = make(map[string]bool)
-		for _, lang := range languagesToExclude {
-			LanguageExclusions[lang] = true
+// ConnectTimeout stores how long a connection to huskyCI API may take to open.
+var ConnectTimeout time.Duration
+
+// ReadTimeout stores how long huskyCI API may take to start answering a request.
+var ReadTimeout time.Duration
+
+// AnalysisTimeout stores how long the client waits for an analysis to finish.
+var AnalysisTimeout time.Duration
+
+// FailOn stores the lowest severity of the vulnerabilities failing the CI. When
+// it is empty, the policy of the repository on huskyCI API is used.
+var FailOn string
+
+// OutageWindow stores how long huskyCI API may keep failing while the client
+// waits for an analysis, such as while it restarts.
+var OutageWindow time.Duration
+
+// ResumeRID stores the RID of a running analysis the client waits for,
+// instead of starting a new one, as set by --resume.
+var ResumeRID string
+
+// WarnOnly stores if the vulnerabilities found are only reported, without
+// failing the CI.
+var WarnOnly bool
+
+// OutputFormat stores how the results are printed: text, or json for the
+// JSON document described by the schema of the client.
+var OutputFormat string
+
+// Plain stores if the output is printed without emoji, unicode symbols or
+// colors, as HUSKYCI_NO_EMOJI also asks.
+var Plain bool
+
+// HuskyCAFile stores the PEM file of the certificate authorities trusted in
+// addition to the ones of the system, for a huskyCI API behind a private CA.
+var HuskyCAFile string
+
+// HuskyCertFile and HuskyKeyFile store the files of the certificate the
+// client presents to a huskyCI API requiring mutual TLS.
+var (
+	HuskyCertFile string
+	HuskyKeyFile  string
+)
+
+// ConfigFile is the configuration file the client reads in the directory it
+// runs in, unless --config or HUSKYCI_CLIENT_CONFIG names another one.
+const ConfigFile = ".huskyci-client.yml"
+
+// ErrHelp is returned by Load when the client was run with --help, once the
+// flags were printed.
+var ErrHelp = pflag.ErrHelp
+
+// Output formats of the results.
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// settings are the keys of the configuration file, read from the
+// environment variables HUSKYCI_CLIENT_ followed by the key in upper case,
+// with its dots replaced by underscores, such as HUSKYCI_CLIENT_API_ADDR.
+const (
+	keyAPIAddr            = "api.addr"
+	keyAPIUseHTTPS        = "api.use_https"
+	keyAPICAFile          = "api.ca_file"
+	keyAPICertFile        = "api.cert_file"
+	keyAPIKeyFile         = "api.key_file"
+	keyRepoURL            = "repo.url"
+	keyRepoBranch         = "repo.branch"
+	keyRepoCommit         = "repo.commit"
+	keyToken              = "token"
+	keyTokenEnv           = "token_env"
+	keyTokenFile          = "token_file"
+	keyLanguageExclusions = "language_exclusions"
+	keyTests              = "tests"
+	keyOutput             = "output"
+	keyFailOn             = "fail_on"
+	keyWarnOnly           = "warn_only"
+	keyTimeout            = "timeout"
+	keyConnectTimeout     = "connect_timeout"
+	keyReadTimeout        = "read_timeout"
+	keyOutageWindow       = "outage_window"
+	keyResume             = "resume"
+	keyPlain              = "plain"
+)
+
+// Load sets the configuration of the client from its arguments, the
+// environment and its configuration file, in this order of precedence, and
+// checks it.
+func Load(args []string) error {
+	settings, err := readSettings(args)
+	if err != nil {
+		return err
+	}
+	if err := check(settings); err != nil {
+		return err
+	}
+	return setConfigs(settings)
+}
+
+// readSettings returns the settings of args, the environment and the
+// configuration file.
+func readSettings(args []string) (*viper.Viper, error) {
+	flags := pflag.NewFlagSet("huskyci-client", pflag.ContinueOnError)
+	flags.String("config", "", "configuration file (default is "+ConfigFile+" if it exists)")
+	flags.String("api-addr", "", "address of the huskyCI API")
+	flags.String("repo-url", "", "URL of the repository to analyze")
+	flags.String("repo-branch", "", "branch of the repository to analyze")
+	flags.String("repo-commit", "", "commit the result of the analysis is reported on")
+	flags.String("output", "", "format of the results, text or json")
+	flags.String("fail-on", "", "lowest severity failing the CI: high, medium, low or never")
+	flags.Bool("warn-only", false, "report the vulnerabilities found without failing the CI")
+	flags.Bool("plain", false, "print without emoji, unicode symbols or colors")
+	flags.StringSlice("exclude-languages", nil, "languages left out of the analysis, such as Java,Ruby")
+	flags.StringSlice("tests", nil, "securityTests to run, such as gosec,gitleaks (default is all of them)")
+	flags.Duration("timeout", 0, "time to wait for the analysis to finish")
+	flags.String("resume", "", "RID of a running analysis to wait for, instead of starting one")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return nil, ErrHelp
+		}
+		return nil, fmt.Errorf("%w\n\nTip: run huskyci-client --help for its flags", err)
+	}
+
+	settings := viper.New()
+	settings.SetEnvPrefix("HUSKYCI_CLIENT")
+	settings.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	settings.AutomaticEnv()
+	// the language exclusions were set before the client had a prefix
+	if err := settings.BindEnv(keyLanguageExclusions, "HUSKYCI_CLIENT_LANGUAGE_EXCLUSIONS", "HUSKYCI_LANGUAGE_EXCLUSIONS"); err != nil {
+		return nil, err
+	}
+	settings.SetDefault(keyOutput, OutputText)
+	settings.SetDefault(keyTimeout, sdk.DefaultWaitTimeout)
+	settings.SetDefault(keyConnectTimeout, sdk.DefaultConnectTimeout)
+	settings.SetDefault(keyReadTimeout, sdk.DefaultReadTimeout)
+	settings.SetDefault(keyOutageWindow, sdk.DefaultOutageWindow)
+	for key, flag := range map[string]string{
+		keyAPIAddr:            "api-addr",
+		keyRepoURL:            "repo-url",
+		keyRepoBranch:         "repo-branch",
+		keyRepoCommit:         "repo-commit",
+		keyOutput:             "output",
+		keyFailOn:             "fail-on",
+		keyWarnOnly:           "warn-only",
+		keyLanguageExclusions: "exclude-languages",
+		keyTests:              "tests",
+		keyTimeout:            "timeout",
+		keyResume:             "resume",
+		keyPlain:              "plain",
+	} {
+		if err := settings.BindPFlag(key, flags.Lookup(flag)); err != nil {
+			return nil, err
+		}
+	}
+	// the results were printed as JSON when the client was run with JSON
+	for _, arg := range flags.Args() {
+		if arg == "JSON" {
+			settings.Set(keyOutput, OutputJSON)
+		}
+	}
+
+	configFile, _ := flags.GetString("config")
+	if configFile == "" {
+		configFile = os.Getenv("HUSKYCI_CLIENT_CONFIG")
+	}
+	if configFile == "" {
+		if _, err := os.Stat(ConfigFile); err != nil {
+			return settings, nil
+		}
+		configFile = ConfigFile
+	}
+	settings.SetConfigFile(configFile)
+	settings.SetConfigType("yaml")
+	if err := settings.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("Could not read the configuration file %s: %w", configFile, err)
+	}
+	return settings, nil
+}
+
+// setConfigs sets all configuration needed to start the client from settings.
+func setConfigs(settings *viper.Viper) error {
+	RepositoryURL = settings.GetString(keyRepoURL)
+	RepositoryBranch = settings.GetString(keyRepoBranch)
+	RepositoryCommit = settings.GetString(keyRepoCommit)
+	HuskyAPI = settings.GetString(keyAPIAddr)
+	LanguageExclusions = nil
+	for _, lang := range listSetting(settings, keyLanguageExclusions) {
+		if LanguageExclusions == nil {
+			LanguageExclusions = make(map[string]bool)
 		}
+		LanguageExclusions[lang] = true
 	}
-	HuskyToken = os.Getenv(`HUSKYCI_CLIENT_TOKEN`)
-	HuskyUseTLS = getUseTLS()
+	SecurityTests = listSetting(settings, keyTests)
+	token, err := readToken(settings)
+	if err != nil {
+		return err
+	}
+	HuskyToken = token
+	HuskyUseTLS = settings.GetBool(keyAPIUseHTTPS)
+	HuskyCAFile = settings.GetString(keyAPICAFile)
+	HuskyCertFile = settings.GetString(keyAPICertFile)
+	HuskyKeyFile = settings.GetString(keyAPIKeyFile)
+	ConnectTimeout = getDuration(settings, keyConnectTimeout, sdk.DefaultConnectTimeout)
+	ReadTimeout = getDuration(settings, keyReadTimeout, sdk.DefaultReadTimeout)
+	AnalysisTimeout = getDuration(settings, keyTimeout, sdk.DefaultWaitTimeout)
+	OutageWindow = getDuration(settings, keyOutageWindow, sdk.DefaultOutageWindow)
+	ResumeRID = settings.GetString(keyResume)
+	FailOn = strings.ToLower(settings.GetString(keyFailOn))
+	WarnOnly = settings.GetBool(keyWarnOnly)
+	OutputFormat = strings.ToLower(settings.GetString(keyOutput))
+	Plain = settings.GetBool(keyPlain)
+	return nil
 }
 
-// CheckEnvVars checks if all environment vars are set.
-func CheckEnvVars() error {
-
-	envVars := []string{
-		"HUSKYCI_CLIENT_API_ADDR",
-		"HUSKYCI_CLIENT_REPO_URL",
-		"HUSKYCI_CLIENT_REPO_BRANCH",
-		// "HUSKYCI_CLIENT_TOKEN", (optional for now)
-		// "HUSKYCI_CLIENT_API_USE_HTTPS", (optional)
-		// "HUSKYCI_CLIENT_NPM_DEP_URL", (optional)
-	}
-
-	var envIsSet bool
-	var allEnvIsSet bool
-	var missingVars []string
-
-	env := make(map[string]string)
-	allEnvIsSet = true
-	for i := 0; i < len(envVars); i++ {
-		env[envVars[i]], envIsSet = os.LookupEnv(envVars[i])
-		if !envIsSet {
-			missingVars = append(missingVars, envVars[i])
-			allEnvIsSet = false
+// check returns an error listing the settings missing to start the client,
+// or naming the first invalid one.
+func check(settings *viper.Viper) error {
+	required := []string{keyAPIAddr, keyRepoURL, keyRepoBranch}
+	if settings.IsSet(keyResume) {
+		if settings.GetString(keyResume) == "" {
+			return errors.New("Missing RID after --resume\n\nExample:\n  huskyci-client --resume <RID>")
 		}
+		// the repository of a resumed analysis is the one it was started on
+		required = required[:1]
 	}
-	if !allEnvIsSet {
-		errorMsg := "Missing required environment variables:\n"
-		for _, v := range missingVars {
-			errorMsg += fmt.Sprintf("  - %s\n", v)
+
+	var missing []string
+	for _, key := range required {
+		if settings.GetString(key) == "" {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		errorMsg := "Missing required settings:\n"
+		for _, key := range missing {
+			errorMsg += fmt.Sprintf("  - %s (%s in %s)\n", envVar(key), key, ConfigFile)
 		}
-		errorMsg += "\nPlease set these environment variables before running huskyCI client.\n"
+		errorMsg += "\nPlease set these environment variables, or these keys in " + ConfigFile + ", before running huskyCI client.\n"
 		errorMsg += "\nExample:\n"
 		errorMsg += "  export HUSKYCI_CLIENT_API_ADDR=\"https://api.huskyci.example.com\"\n"
 		errorMsg += "  export HUSKYCI_CLIENT_REPO_URL=\"https://github.com/user/repo.git\"\n"
@@ -80,14 +290,67 @@ func CheckEnvVars() error {
 		errorMsg += "  export HUSKYCI_CLIENT_TOKEN=\"your-token-here\"\n"
 		return errors.New(errorMsg)
 	}
+	if failOn := strings.ToLower(settings.GetString(keyFailOn)); failOn != "" && !sdk.ValidFailOn(failOn) {
+		return fmt.Errorf("Invalid HUSKYCI_CLIENT_FAIL_ON: '%s'\n\nTip: use high, medium, low or never", failOn)
+	}
+	if (settings.GetString(keyAPICertFile) == "") != (settings.GetString(keyAPIKeyFile) == "") {
+		return errors.New("HUSKYCI_CLIENT_API_CERT_FILE and HUSKYCI_CLIENT_API_KEY_FILE must be set together\n\nTip: set api.cert_file and api.key_file in " + ConfigFile)
+	}
+	if output := strings.ToLower(settings.GetString(keyOutput)); output != OutputText && output != OutputJSON {
+		return fmt.Errorf("Invalid output format: '%s'\n\nTip: use text or json", output)
+	}
 	return nil
 }
 
-// getUseTLS returns TRUE or FALSE retrieved from an environment variable.
-func getUseTLS() bool {
-	option := os.Getenv("HUSKYCI_CLIENT_API_USE_HTTPS")
-	if option == "true" || option == "1" || option == "TRUE" {
-		return true
+// envVar returns the environment variable of the setting key.
+func envVar(key string) string {
+	return "HUSKYCI_CLIENT_" + strings.ToUpper(strings.Replace(key, ".", "_", -1))
+}
+
+// listSetting returns the values of the setting key, such as the languages to
+// exclude, set as a list in the configuration file or separated by commas
+// elsewhere.
+func listSetting(settings *viper.Viper, key string) []string {
+	values := []string{}
+	for _, setting := range settings.GetStringSlice(key) {
+		for _, value := range strings.Split(setting, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+// readToken returns the token to scan the repository with: the token set,
+// or else the value of the environment variable named by token_env, or else
+// the content of token_file. The token is best kept out of the configuration
+// file, which is often committed.
+func readToken(settings *viper.Viper) (string, error) {
+	if token := settings.GetString(keyToken); token != "" {
+		return token, nil
+	}
+	if name := settings.GetString(keyTokenEnv); name != "" {
+		if token := os.Getenv(name); token != "" {
+			return token, nil
+		}
+	}
+	if path := settings.GetString(keyTokenFile); path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("Could not read the token file: %w", err)
+		}
+		return strings.TrimSpace(string(content)), nil
+	}
+	return "", nil
+}
+
+// getDuration returns the duration, such as 90s or 2h, of the setting key,
+// or defaultValue when it is invalid.
+func getDuration(settings *viper.Viper, key string, defaultValue time.Duration) time.Duration {
+	duration, err := cast.ToDurationE(settings.Get(key))
+	if err != nil || duration <= 0 {
+		return defaultValue
 	}
-	return false
+	return duration
 }
@@ -0,0 +1,160 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/client/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// writeConfigFile writes content to a configuration file in a temporary
+// directory and returns its path.
+func writeConfigFile(content string) string {
+	dir, err := os.MkdirTemp("", "huskyci-client")
+	Expect(err).NotTo(HaveOccurred())
+	path := filepath.Join(dir, config.ConfigFile)
+	Expect(os.WriteFile(path, []byte(content), 0600)).To(Succeed())
+	return path
+}
+
+var _ = Describe("Load", func() {
+	BeforeEach(func() {
+		for _, env := range os.Environ() {
+			name, _, _ := strings.Cut(env, "=")
+			if strings.HasPrefix(name, "HUSKYCI_CLIENT_") || name == "HUSKYCI_LANGUAGE_EXCLUSIONS" {
+				os.Unsetenv(name)
+			}
+		}
+	})
+
+	Context("When the settings are only set in the environment", func() {
+		It("should set them as before", func() {
+			os.Setenv("HUSKYCI_CLIENT_API_ADDR", "http://localhost:8888")
+			os.Setenv("HUSKYCI_CLIENT_REPO_URL", "https://github.com/huskyci-org/huskyCI.git")
+			os.Setenv("HUSKYCI_CLIENT_REPO_BRANCH", "main")
+			os.Setenv("HUSKYCI_CLIENT_TOKEN", "token")
+			os.Setenv("HUSKYCI_CLIENT_API_USE_HTTPS", "true")
+			os.Setenv("HUSKYCI_CLIENT_TIMEOUT", "90m")
+			os.Setenv("HUSKYCI_LANGUAGE_EXCLUSIONS", "Java,Ruby")
+			defer os.Unsetenv("HUSKYCI_LANGUAGE_EXCLUSIONS")
+
+			Expect(config.Load(nil)).To(Succeed())
+			Expect(config.HuskyAPI).To(Equal("http://localhost:8888"))
+			Expect(config.RepositoryBranch).To(Equal("main"))
+			Expect(config.HuskyToken).To(Equal("token"))
+			Expect(config.HuskyUseTLS).To(BeTrue())
+			Expect(config.AnalysisTimeout).To(Equal(90 * time.Minute))
+			Expect(config.LanguageExclusions).To(Equal(map[string]bool{"Java": true, "Ruby": true}))
+			Expect(config.OutputFormat).To(Equal(config.OutputText))
+		})
+	})
+
+	Context("When the settings are set in a configuration file", func() {
+		var path string
+		BeforeEach(func() {
+			path = writeConfigFile(`api:
+  addr: https://huskyci.example.com
+  ca_file: /etc/ssl/huskyci-ca.pem
+repo:
+  url: https://github.com/huskyci-org/huskyCI.git
+  branch: main
+output: json
+fail_on: high
+timeout: 30m
+language_exclusions:
+  - Java
+  - Ruby
+tests:
+  - gosec
+  - gitleaks
+`)
+		})
+		AfterEach(func() {
+			os.RemoveAll(filepath.Dir(path))
+		})
+
+		It("should read them from the file named by --config", func() {
+			Expect(config.Load([]string{"--config", path})).To(Succeed())
+			Expect(config.HuskyAPI).To(Equal("https://huskyci.example.com"))
+			Expect(config.HuskyCAFile).To(Equal("/etc/ssl/huskyci-ca.pem"))
+			Expect(config.OutputFormat).To(Equal(config.OutputJSON))
+			Expect(config.FailOn).To(Equal("high"))
+			Expect(config.AnalysisTimeout).To(Equal(30 * time.Minute))
+			Expect(config.LanguageExclusions).To(Equal(map[string]bool{"Java": true, "Ruby": true}))
+			Expect(config.SecurityTests).To(Equal([]string{"gosec", "gitleaks"}))
+		})
+
+		It("should prefer the environment to the file, and the flags to both", func() {
+			os.Setenv("HUSKYCI_CLIENT_CONFIG", path)
+			os.Setenv("HUSKYCI_CLIENT_FAIL_ON", "low")
+			os.Setenv("HUSKYCI_CLIENT_REPO_BRANCH", "develop")
+			Expect(config.Load([]string{"--repo-branch", "feature", "--output", "text", "--tests", "gosec"})).To(Succeed())
+			Expect(config.FailOn).To(Equal("low"))
+			Expect(config.RepositoryBranch).To(Equal("feature"))
+			Expect(config.OutputFormat).To(Equal(config.OutputText))
+			Expect(config.SecurityTests).To(Equal([]string{"gosec"}))
+		})
+
+		It("should read the token from the environment variable named by token_env", func() {
+			os.Setenv("HUSKYCI_CLIENT_TOKEN_ENV", "CI_HUSKYCI_TOKEN")
+			os.Setenv("CI_HUSKYCI_TOKEN", "secret")
+			defer os.Unsetenv("CI_HUSKYCI_TOKEN")
+			Expect(config.Load([]string{"--config", path})).To(Succeed())
+			Expect(config.HuskyToken).To(Equal("secret"))
+		})
+
+		It("should read the token from token_file", func() {
+			tokenFile := filepath.Join(filepath.Dir(path), "token")
+			Expect(os.WriteFile(tokenFile, []byte("secret\n"), 0600)).To(Succeed())
+			os.Setenv("HUSKYCI_CLIENT_TOKEN_FILE", tokenFile)
+			Expect(config.Load([]string{"--config", path})).To(Succeed())
+			Expect(config.HuskyToken).To(Equal("secret"))
+		})
+	})
+
+	Context("When the configuration is incomplete or invalid", func() {
+		It("should name the missing settings", func() {
+			err := config.Load(nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("HUSKYCI_CLIENT_API_ADDR (api.addr"))
+			Expect(err.Error()).To(ContainSubstring("HUSKYCI_CLIENT_REPO_BRANCH (repo.branch"))
+		})
+
+		It("should only need the API address to resume an analysis", func() {
+			Expect(config.Load([]string{"--api-addr", "http://localhost:8888", "--resume", "1234"})).To(Succeed())
+			Expect(config.ResumeRID).To(Equal("1234"))
+		})
+
+		It("should fail when the configuration file named does not exist", func() {
+			err := config.Load([]string{"--config", "/nonexistent/" + config.ConfigFile})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should fail when the client certificate has no key", func() {
+			err := config.Load([]string{"--api-addr", "http://localhost:8888", "--resume", "1234"})
+			Expect(err).NotTo(HaveOccurred())
+			os.Setenv("HUSKYCI_CLIENT_API_CERT_FILE", "client.pem")
+			err = config.Load([]string{"--api-addr", "http://localhost:8888", "--resume", "1234"})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should fail on an unknown output format", func() {
+			os.Setenv("HUSKYCI_CLIENT_API_ADDR", "http://localhost:8888")
+			os.Setenv("HUSKYCI_CLIENT_REPO_URL", "https://github.com/huskyci-org/huskyCI.git")
+			os.Setenv("HUSKYCI_CLIENT_REPO_BRANCH", "main")
+			err := config.Load([]string{"--output", "xml"})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("When the client is run with JSON", func() {
+		It("should print the results as JSON", func() {
+			Expect(config.Load([]string{"JSON", "--api-addr", "http://localhost:8888", "--resume", "1234"})).To(Succeed())
+			Expect(config.OutputFormat).To(Equal(config.OutputJSON))
+		})
+	})
+})
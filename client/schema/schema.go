@@ -0,0 +1,118 @@
+// Copyright 2019 Globo.com authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package schema holds the JSON schema of the output of the client, and checks
+// documents against it.
+package schema
+
+import (
+	// embed the schema in the binary of the client
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Schema is the JSON schema of the output of the client.
+//
+//go:embed huskyci-client.schema.json
+var Schema []byte
+
+// node is the subset of JSON schema the output of the client is described
+// with.
+type node struct {
+	Type       string           `json:"type"`
+	Const      interface{}      `json:"const"`
+	Enum       []interface{}    `json:"enum"`
+	Required   []string         `json:"required"`
+	Properties map[string]*node `json:"properties"`
+	Items      *node            `json:"items"`
+}
+
+// Validate returns an error naming the first field of document that does not
+// follow Schema.
+func Validate(document []byte) error {
+	root := &node{}
+	if err := json.Unmarshal(Schema, root); err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+	var value interface{}
+	if err := json.Unmarshal(document, &value); err != nil {
+		return err
+	}
+	return root.validate("$", value)
+}
+
+func (n *node) validate(path string, value interface{}) error {
+	if n.Const != nil && !reflect.DeepEqual(n.Const, value) {
+		return fmt.Errorf("%s: expected %v, got %v", path, n.Const, value)
+	}
+	if len(n.Enum) > 0 && !contains(n.Enum, value) {
+		return fmt.Errorf("%s: %v is not one of %v", path, value, n.Enum)
+	}
+	if n.Type != "" && typeOf(value) != n.Type && !(n.Type == "number" && typeOf(value) == "integer") {
+		return fmt.Errorf("%s: expected %s, got %s", path, n.Type, typeOf(value))
+	}
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for _, field := range n.Required {
+			if _, ok := value[field]; !ok {
+				return fmt.Errorf("%s: missing %s", path, field)
+			}
+		}
+		fields := make([]string, 0, len(n.Properties))
+		for field := range n.Properties {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			if v, ok := value[field]; ok {
+				if err := n.Properties[field].validate(path+"."+field, v); err != nil {
+					return err
+				}
+			}
+		}
+	case []interface{}:
+		if n.Items == nil {
+			return nil
+		}
+		for i, item := range value {
+			if err := n.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func contains(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// typeOf returns the JSON schema type of a value decoded by encoding/json.
+func typeOf(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if value == float64(int64(value)) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
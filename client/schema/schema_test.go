@@ -0,0 +1,74 @@
+package schema_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/huskyci-org/huskyCI/client/schema"
+	"github.com/huskyci-org/huskyCI/client/types"
+)
+
+var _ = Describe("Schema", func() {
+
+	var output types.JSONOutput
+
+	BeforeEach(func() {
+		output = types.JSONOutput{
+			SchemaVersion: types.SchemaVersion,
+			Blocking: types.Blocking{
+				Blocked:  true,
+				ExitCode: types.BlockingExitCode,
+				FailOn:   "medium",
+				Reasons:  []types.BlockingReason{{Tool: "gosec", Severity: "HIGH", Count: 2}},
+			},
+			Tools:    []types.ToolSummary{{Name: "gosec", Language: "Go", Image: "huskyciorg/gosec:latest", Result: "failed", Config: []string{".gosec.json"}, High: 2, Blocking: true}},
+			Warnings: []string{"bandit timed out after 360 seconds: its findings are missing"},
+			Summary: types.Summary{
+				URL:    "https://github.com/huskyci-org/huskyCI.git",
+				Branch: "main",
+				RID:    "rid",
+			},
+		}
+	})
+
+	validate := func() error {
+		document, err := json.Marshal(output)
+		Expect(err).NotTo(HaveOccurred())
+		return schema.Validate(document)
+	}
+
+	It("is valid JSON", func() {
+		Expect(json.Valid(schema.Schema)).To(BeTrue())
+	})
+
+	It("accepts the output of the client", func() {
+		Expect(validate()).To(Succeed())
+	})
+
+	It("accepts an output without findings", func() {
+		output.Blocking = types.Blocking{FailOn: "never", Reasons: []types.BlockingReason{}}
+		output.Tools = []types.ToolSummary{}
+		Expect(validate()).To(Succeed())
+	})
+
+	It("rejects another schema version", func() {
+		output.SchemaVersion = "0"
+		Expect(validate()).To(MatchError(ContainSubstring("$.schemaVersion")))
+	})
+
+	It("rejects an unknown severity", func() {
+		output.Blocking.Reasons[0].Severity = "INFO"
+		Expect(validate()).To(MatchError(ContainSubstring("$.blocking.reasons[0].severity")))
+	})
+
+	It("rejects a document missing a required field", func() {
+		Expect(schema.Validate([]byte(`{"schemaVersion":"1","tools":[],"summary":{}}`))).To(MatchError(ContainSubstring("missing blocking")))
+	})
+
+	It("rejects a field of the wrong type", func() {
+		output.Tools = nil
+		Expect(validate()).To(MatchError(ContainSubstring("$.tools: expected array, got null")))
+	})
+})
@@ -40,6 +40,7 @@ type Analysis struct {
 	RID            string             `bson:"RID" json:"RID"`
 	URL            string             `bson:"repositoryURL" json:"repositoryURL"`
 	Branch         string             `bson:"repositoryBranch" json:"repositoryBranch"`
+	Commit         string             `bson:"commit,omitempty" json:"commit,omitempty"`
 	Status         string             `bson:"status" json:"status"`
 	Result         string             `bson:"result" json:"result"`
 	Containers     []Container        `bson:"containers" json:"containers"`
@@ -48,6 +49,7 @@ type Analysis struct {
 	FinishedAt     time.Time          `bson:"finishedAt" json:"finishedAt"`
 	Codes          []Code             `bson:"codes" json:"codes"`
 	HuskyCIResults HuskyCIResults     `bson:"huskyciresults,omitempty" json:"huskyciresults"`
+	Warnings       []string           `bson:"warnings,omitempty" json:"warnings,omitempty"`
 }
 
 // Code is the struct that stores all data from code found in a repository.
@@ -64,6 +66,7 @@ type HuskyCIResults struct {
 	RubyResults       RubyResults       `bson:"rubyresults,omitempty" json:"rubyresults,omitempty"`
 	JavaResults       JavaResults       `bson:"javaresults,omitempty" json:"javaresults,omitempty"`
 	HclResults        HclResults        `bson:"hclresults,omitempty" json:"hclresults,omitempty"`
+	IaCResults        IaCResults        `bson:"iacresults,omitempty" json:"iacresults,omitempty"`
 	CSharpResults     CSharpResults     `bson:"csharpresults,omitempty" json:"csharpresults,omitempty"`
 	GenericResults    GenericResults    `bson:"genericresults,omitempty" json:"genericresults,omitempty"`
 }
@@ -76,6 +79,7 @@ type Container struct {
 	COutput      string       `bson:"cOutput" json:"cOutput"`
 	CResult      string       `bson:"cResult" json:"cResult"`
 	CInfo        string       `bson:"cInfo" json:"cInfo"`
+	ToolConfigs  []string     `bson:"toolConfigs,omitempty" json:"toolConfigs,omitempty"`
 	StartedAt    time.Time    `bson:"startedAt" json:"startedAt"`
 	FinishedAt   time.Time    `bson:"finishedAt" json:"finishedAt"`
 }
@@ -107,16 +111,37 @@ type HuskyCIVulnerability struct {
 	VunerableBelow string `json:"vulnerablebelow,omitempty"`
 	Version        string `json:"version,omitempty"`
 	Occurrences    int    `json:"occurrences,omitempty"`
+	Package        string `json:"package,omitempty"`
+	FixVersion     string `json:"fixversion,omitempty"`
+	PackagePath    string `json:"packagepath,omitempty"`
+	Commit         string `json:"commit,omitempty"`
+	Author         string `json:"author,omitempty"`
+	AuthorEmail    string `json:"authoremail,omitempty"`
+	Fingerprint    string `json:"fingerprint,omitempty"`
 }
 
+// SchemaVersion is the version of the JSON output, following the schema
+// embedded in the client. It only changes when a field is removed or changes
+// meaning.
+const SchemaVersion = "1"
+
+// BlockingExitCode is the exit code of the client when vulnerabilities of at
+// least the severity it fails on are found.
+const BlockingExitCode = 190
+
 // JSONOutput is a truct that represents huskyCI output in a JSON format.
 type JSONOutput struct {
+	SchemaVersion     string            `json:"schemaVersion"`
+	Blocking          Blocking          `json:"blocking"`
+	Tools             []ToolSummary     `json:"tools"`
+	Warnings          []string          `json:"warnings"`
 	GoResults         GoResults         `json:"goresults,omitempty"`
 	PythonResults     PythonResults     `json:"pythonresults,omitempty"`
 	JavaScriptResults JavaScriptResults `json:"javascriptresults,omitempty"`
 	RubyResults       RubyResults       `json:"rubyresults,omitempty"`
 	JavaResults       JavaResults       `json:"javaresults,omitempty"`
 	HclResults        HclResults        `json:"hclresults,omitempty"`
+	IaCResults        IaCResults        `json:"iacresults,omitempty"`
 	CSharpResults     CSharpResults     `json:"csharpresults,omitempty"`
 	GenericResults    GenericResults    `json:"genericresults,omitempty"`
 	Summary           Summary           `json:"summary,omitempty"`
@@ -160,6 +185,11 @@ type HclResults struct {
 	HuskyCITFSecOutput HuskyCISecurityTestOutput `bson:"tfsecoutput,omitempty" json:"tfsecoutput,omitempty"`
 }
 
+// IaCResults represents all infrastructure as code security tests results.
+type IaCResults struct {
+	HuskyCITrivyConfigOutput HuskyCISecurityTestOutput `bson:"trivyconfigoutput,omitempty" json:"trivyconfigoutput,omitempty"`
+}
+
 // CSharpResults represents all C# security tests results.
 type CSharpResults struct {
 	HuskyCISecurityCodeScanOutput HuskyCISecurityTestOutput `bson:"securitycodescanoutput,omitempty" json:"securitycodescanoutput,omitempty"`
@@ -178,6 +208,7 @@ type Summary struct {
 	URL                     string         `json:"repositoryURL"`
 	Branch                  string         `json:"repositoryBranch"`
 	RID                     string         `json:"RID"`
+	Commit                  string         `json:"commit,omitempty"`
 	GosecSummary            HuskyCISummary `json:"gosecsummary,omitempty"`
 	BanditSummary           HuskyCISummary `json:"banditsummary,omitempty"`
 	SafetySummary           HuskyCISummary `json:"safetysummary,omitempty"`
@@ -187,10 +218,43 @@ type Summary struct {
 	SpotBugsSummary         HuskyCISummary `json:"spotbugssummary,omitempty"`
 	GitleaksSummary         HuskyCISummary `json:"gitleakssummary,omitempty"`
 	TFSecSummary            HuskyCISummary `json:"tfsecsummary,omitempty"`
+	TrivyConfigSummary      HuskyCISummary `json:"trivyconfigsummary,omitempty"`
 	SecurityCodeScanSummary HuskyCISummary `json:"securitycodescansummary,omitempty"`
 	TotalSummary            HuskyCISummary `json:"totalsummary,omitempty"`
 }
 
+// Blocking is the decision of the client to exit with ExitCode, and the tools
+// and severities that caused it. With WarnOnly, Reasons are listed but the CI
+// is not blocked.
+type Blocking struct {
+	Blocked  bool             `json:"blocked"`
+	ExitCode int              `json:"exitCode"`
+	FailOn   string           `json:"failOn"`
+	WarnOnly bool             `json:"warnOnly"`
+	Reasons  []BlockingReason `json:"reasons"`
+}
+
+// BlockingReason is a severity of a tool whose findings fail the CI.
+type BlockingReason struct {
+	Tool     string `json:"tool"`
+	Severity string `json:"severity"`
+	Count    int    `json:"count"`
+}
+
+// ToolSummary is the summary of the findings of a securityTest.
+type ToolSummary struct {
+	Name     string   `json:"name"`
+	Language string   `json:"language"`
+	Image    string   `json:"image,omitempty"`
+	Result   string   `json:"result,omitempty"`
+	Config   []string `json:"config,omitempty"`
+	High     int      `json:"high"`
+	Medium   int      `json:"medium"`
+	Low      int      `json:"low"`
+	NoSec    int      `json:"nosec"`
+	Blocking bool     `json:"blocking"`
+}
+
 // HuskyCISummary is the struct that holds summary information.
 type HuskyCISummary struct {
 	FoundVuln  bool `json:"foundvuln,omitempty"`
@@ -4,20 +4,40 @@ import (
 	"bufio"
 	"crypto/tls"
 	"crypto/x509"
+	"fmt"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 )
 
-// NewClient returns an http client.
-func NewClient(httpsEnable bool) (*http.Client, error) {
+// NewClient returns an http client. The certificates of caFile, if set, are
+// trusted in addition to the ones of the system. The certificate of certFile
+// and keyFile, if set, is presented to APIs requiring mutual TLS.
+func NewClient(httpsEnable bool, caFile, certFile, keyFile string) (*http.Client, error) {
 	if httpsEnable {
 		// Tries to find system's certificate pool
 		caCertPool, _ := x509.SystemCertPool() // #nosec - SystemCertPool tries to get local cert pool, if it fails, a new cer pool is created
 		if caCertPool == nil {
 			caCertPool = x509.NewCertPool()
 		}
+		if caFile != "" {
+			caCert, err := os.ReadFile(caFile)
+			if err != nil {
+				return nil, fmt.Errorf("could not read the CA file: %w", err)
+			}
+			if !caCertPool.AppendCertsFromPEM(caCert) {
+				return nil, fmt.Errorf("no certificate found in the CA file %s", caFile)
+			}
+		}
+		var certificates []tls.Certificate
+		if certFile != "" {
+			cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+			if err != nil {
+				return nil, fmt.Errorf("could not load the client certificate: %w", err)
+			}
+			certificates = append(certificates, cert)
+		}
 
 		tlsConfig := &tls.Config{
 			MinVersion: tls.VersionTLS12,
@@ -33,6 +53,7 @@ func NewClient(httpsEnable bool) (*http.Client, error) {
 					PreferServerCipherSuites: true,
 					InsecureSkipVerify:       false,
 					RootCAs:                  caCertPool,
+					Certificates:             certificates,
 				},
 			},
 		}
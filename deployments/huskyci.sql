@@ -53,6 +53,7 @@ CREATE TABLE IF NOT EXISTS public.analysis (
     "RID" text NOT NULL,
     "repositoryURL" text NOT NULL,
     "repositoryBranch" text NOT NULL,
+    commit text,
     "commitAuthors" text[],
     status text NOT NULL,
     result text,
@@ -61,9 +62,22 @@ CREATE TABLE IF NOT EXISTS public.analysis (
     "startedAt" timestamp without time zone,
     "finishedAt" timestamp without time zone,
     codes jsonb,
-    huskyciresults jsonb
+    huskyciresults jsonb,
+    warnings jsonb,
+    "idempotencyKey" text,
+    "idempotencyHash" text,
+    "queuedRequest" jsonb,
+    "pullRequest" jsonb,
+    "targetDiff" jsonb
 );
 
+ALTER TABLE public.analysis ADD COLUMN IF NOT EXISTS warnings jsonb;
+ALTER TABLE public.analysis ADD COLUMN IF NOT EXISTS "idempotencyKey" text;
+ALTER TABLE public.analysis ADD COLUMN IF NOT EXISTS "idempotencyHash" text;
+ALTER TABLE public.analysis ADD COLUMN IF NOT EXISTS "queuedRequest" jsonb;
+ALTER TABLE public.analysis ADD COLUMN IF NOT EXISTS "pullRequest" jsonb;
+ALTER TABLE public.analysis ADD COLUMN IF NOT EXISTS "targetDiff" jsonb;
+
 
 ALTER TABLE public.analysis OWNER TO "huskyCIUser";
 
@@ -81,6 +95,142 @@ CREATE TABLE IF NOT EXISTS public.repository (
 
 ALTER TABLE public.repository OWNER TO "huskyCIUser";
 
+--
+-- Name: gitCredential; Type: TABLE; Schema: public; Owner: huskyCIUser
+--
+
+CREATE TABLE IF NOT EXISTS public."gitCredential" (
+    "repositoryURL" text NOT NULL,
+    type text NOT NULL,
+    username text,
+    secret text NOT NULL,
+    "updatedAt" timestamp with time zone NOT NULL,
+    PRIMARY KEY ("repositoryURL")
+);
+
+
+ALTER TABLE public."gitCredential" OWNER TO "huskyCIUser";
+
+--
+-- Name: registryCredential; Type: TABLE; Schema: public; Owner: huskyCIUser
+--
+
+CREATE TABLE IF NOT EXISTS public."registryCredential" (
+    registry text NOT NULL,
+    username text NOT NULL,
+    secret text NOT NULL,
+    "updatedAt" timestamp with time zone NOT NULL,
+    PRIMARY KEY (registry)
+);
+
+
+ALTER TABLE public."registryCredential" OWNER TO "huskyCIUser";
+
+--
+-- Name: statusReporter; Type: TABLE; Schema: public; Owner: huskyCIUser
+--
+
+CREATE TABLE IF NOT EXISTS public."statusReporter" (
+    "repositoryURL" text NOT NULL,
+    provider text NOT NULL,
+    endpoint text NOT NULL,
+    username text,
+    secret text NOT NULL,
+    label text,
+    "detailsURL" text,
+    "updatedAt" timestamp with time zone NOT NULL,
+    PRIMARY KEY ("repositoryURL")
+);
+
+
+ALTER TABLE public."statusReporter" OWNER TO "huskyCIUser";
+
+--
+-- Name: remediation; Type: TABLE; Schema: public; Owner: huskyCIUser
+--
+
+CREATE TABLE IF NOT EXISTS public."remediation" (
+    "repositoryURL" text NOT NULL,
+    provider text NOT NULL,
+    endpoint text NOT NULL,
+    secret text NOT NULL,
+    "baseBranch" text,
+    "updatedAt" timestamp with time zone NOT NULL,
+    PRIMARY KEY ("repositoryURL")
+);
+
+
+ALTER TABLE public."remediation" OWNER TO "huskyCIUser";
+
+--
+-- Name: policy; Type: TABLE; Schema: public; Owner: huskyCIUser
+--
+
+CREATE TABLE IF NOT EXISTS public."policy" (
+    "repositoryURL" text NOT NULL,
+    "failOn" text NOT NULL,
+    "ignoreRules" text[],
+    "severityOverrides" jsonb,
+    "requiredSecurityTests" text[],
+    "updatedAt" timestamp with time zone NOT NULL,
+    PRIMARY KEY ("repositoryURL")
+);
+
+ALTER TABLE public."policy" ADD COLUMN IF NOT EXISTS "ignoreRules" text[];
+ALTER TABLE public."policy" ADD COLUMN IF NOT EXISTS "severityOverrides" jsonb;
+ALTER TABLE public."policy" ADD COLUMN IF NOT EXISTS "requiredSecurityTests" text[];
+
+
+ALTER TABLE public."policy" OWNER TO "huskyCIUser";
+
+--
+-- Name: repositoryGroup; Type: TABLE; Schema: public; Owner: huskyCIUser
+--
+
+CREATE TABLE IF NOT EXISTS public."repositoryGroup" (
+    name text NOT NULL,
+    description text,
+    repositories jsonb NOT NULL,
+    "updatedAt" timestamp with time zone NOT NULL,
+    PRIMARY KEY (name)
+);
+
+
+ALTER TABLE public."repositoryGroup" OWNER TO "huskyCIUser";
+
+--
+-- Name: dockerHost; Type: TABLE; Schema: public; Owner: huskyCIUser
+--
+
+CREATE TABLE IF NOT EXISTS public."dockerHost" (
+    address text NOT NULL,
+    state text NOT NULL,
+    "certPath" text,
+    "createdAt" timestamp with time zone NOT NULL,
+    "updatedAt" timestamp with time zone NOT NULL,
+    PRIMARY KEY (address)
+);
+
+
+ALTER TABLE public."dockerHost" OWNER TO "huskyCIUser";
+
+--
+-- Name: containerOutput; Type: TABLE; Schema: public; Owner: huskyCIUser
+--
+
+CREATE TABLE IF NOT EXISTS public."containerOutput" (
+    "RID" text NOT NULL,
+    "securityTest" text NOT NULL,
+    output bytea NOT NULL,
+    size integer NOT NULL,
+    truncated boolean NOT NULL,
+    "createdAt" timestamp with time zone NOT NULL,
+    PRIMARY KEY ("RID", "securityTest")
+);
+
+
+ALTER TABLE public."containerOutput" OWNER TO "huskyCIUser";
+
 --
 -- Name: securityTest; Type: TABLE; Schema: public; Owner: huskyCIUser
 --
@@ -90,13 +240,19 @@ CREATE TABLE IF NOT EXISTS public."securityTest" (
     name text NOT NULL,
     image text NOT NULL,
     "imageTag" text,
+    "imageDigest" text,
     cmd text NOT NULL,
     type text NOT NULL,
     language text NOT NULL,
     "default" boolean NOT NULL,
-    "timeOutSeconds" integer NOT NULL
+    "timeOutSeconds" integer NOT NULL,
+    "networkMode" text,
+    "maxOutputSizeKB" integer,
+    "warmPool" boolean
 );
 
+ALTER TABLE public."securityTest" ADD COLUMN IF NOT EXISTS "warmPool" boolean;
+
 
 ALTER TABLE public."securityTest" OWNER TO "huskyCIUser";
 
@@ -113,9 +269,14 @@ CREATE TABLE IF NOT EXISTS public."user" (
     keylen integer,
     hashfunction text,
     "newPassword" text,
-    "confirmNewPassword" text
+    "confirmNewPassword" text,
+    role text,
+    disabled boolean
 );
 
+ALTER TABLE public."user" ADD COLUMN IF NOT EXISTS role text;
+ALTER TABLE public."user" ADD COLUMN IF NOT EXISTS disabled boolean;
+
 
 ALTER TABLE public."user" OWNER TO "huskyCIUser";
 
@@ -131,7 +292,7 @@ COPY public."accessToken" (id, huskytoken, "repositoryURL", "isValid", "createdA
 -- Data for Name: analysis; Type: TABLE DATA; Schema: public; Owner: huskyCIUser
 --
 
-COPY public.analysis (id, "RID", "repositoryURL", "repositoryBranch", "commitAuthors", status, result, "errorFound", containers, "startedAt", "finishedAt", codes, huskyciresults) FROM stdin;
+COPY public.analysis (id, "RID", "repositoryURL", "repositoryBranch", commit, "commitAuthors", status, result, "errorFound", containers, "startedAt", "finishedAt", codes, huskyciresults) FROM stdin;
 \.
 
 
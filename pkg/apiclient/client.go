@@ -0,0 +1,210 @@
+// Package apiclient is a typed client of the huskyCI API. The operations and
+// types in apiclient_gen.go are generated from the API route annotations by
+// api/openapi/gen; run go generate ./openapi in api/ after changing a route.
+package apiclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client sends requests to a huskyCI API. Token is sent as the Husky-Token
+// header of the analysis routes and Username and Password authenticate the
+// token and admin routes.
+type Client struct {
+	Endpoint   string
+	HTTPClient *http.Client
+	Token      string
+	Username   string
+	Password   string
+	UserAgent  string
+}
+
+// New returns a Client of the huskyCI API listening at endpoint.
+func New(endpoint string) *Client {
+	return &Client{
+		Endpoint:   strings.TrimSuffix(endpoint, "/"),
+		HTTPClient: http.DefaultClient,
+		UserAgent:  "huskyci-apiclient",
+	}
+}
+
+// APIError is returned when the huskyCI API answers with a non 2xx status.
+// Message is the message of the API reply, if it had one, Reason its short
+// error, such as "analysis not found", and Code its code of pkg/errcode, empty
+// if the API is older than the error codes.
+type APIError struct {
+	StatusCode int
+	Code       string
+	Reason     string
+	Message    string
+	DocsURL    string
+	Body       []byte
+}
+
+func (e *APIError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("huskyCI API returned %d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("huskyCI API returned %d: %s", e.StatusCode, strings.TrimSpace(string(e.Body)))
+}
+
+type responseHeaderKey struct{}
+
+// WithResponseHeader returns a copy of ctx that makes the requests sent with it
+// store the headers of their response into header, such as the Retry-After
+// hint of GET /analysis/:id.
+func WithResponseHeader(ctx context.Context, header *http.Header) context.Context {
+	return context.WithValue(ctx, responseHeaderKey{}, header)
+}
+
+type idempotencyKey struct{}
+
+// WithIdempotencyKey returns a copy of ctx that makes the POST requests sent
+// with it carry key as their Idempotency-Key header, so that POST /analysis
+// returns the analysis it started when it is sent again.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+// IdempotencyKeyOf returns the Idempotency-Key set in ctx by
+// WithIdempotencyKey, or an empty key.
+func IdempotencyKeyOf(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKey{}).(string)
+	return key
+}
+
+type authScheme int
+
+const (
+	noAuth authScheme = iota
+	basicAuth
+	huskyToken
+)
+
+type formFile struct {
+	field   string
+	name    string
+	content io.Reader
+}
+
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	auth   authScheme
+	body   interface{}
+	file   *formFile
+}
+
+// do sends req and decodes the response into out, which is either a *string
+// for text responses or a value JSON is decoded into. A nil out discards the
+// response body.
+func (c *Client) do(ctx context.Context, req request, out interface{}) error {
+	endpoint := strings.TrimSuffix(c.Endpoint, "/") + req.path
+	if len(req.query) > 0 {
+		endpoint += "?" + req.query.Encode()
+	}
+
+	var payload io.Reader
+	contentType := ""
+	switch {
+	case req.body != nil:
+		body, err := json.Marshal(req.body)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(body)
+		contentType = "application/json"
+	case req.file != nil:
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, err := writer.CreateFormFile(req.file.field, req.file.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(part, req.file.content); err != nil {
+			return err
+		}
+		if err := writer.Close(); err != nil {
+			return err
+		}
+		payload = body
+		contentType = writer.FormDataContentType()
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, endpoint, payload)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		httpReq.Header.Set("Content-Type", contentType)
+	}
+	if c.UserAgent != "" {
+		httpReq.Header.Set("User-Agent", c.UserAgent)
+	}
+	if key := IdempotencyKeyOf(ctx); key != "" && req.method == http.MethodPost {
+		httpReq.Header.Set("Idempotency-Key", key)
+	}
+	switch req.auth {
+	case basicAuth:
+		httpReq.SetBasicAuth(c.Username, c.Password)
+	case huskyToken:
+		httpReq.Header.Set("Husky-Token", c.Token)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if header, ok := ctx.Value(responseHeaderKey{}).(*http.Header); ok {
+		*header = resp.Header.Clone()
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &APIError{StatusCode: resp.StatusCode, Body: body}
+		reply := struct {
+			Message string `json:"message"`
+			Error   string `json:"error"`
+			Code    string `json:"code"`
+			DocsURL string `json:"docsURL"`
+		}{}
+		if json.Unmarshal(body, &reply) == nil {
+			apiErr.Code, apiErr.Reason, apiErr.DocsURL = reply.Code, reply.Error, reply.DocsURL
+			apiErr.Message = reply.Message
+			if apiErr.Message == "" {
+				apiErr.Message = reply.Error
+			}
+		}
+		return apiErr
+	}
+
+	switch out := out.(type) {
+	case nil:
+		return nil
+	case *string:
+		*out = string(body)
+		return nil
+	default:
+		if err := json.Unmarshal(body, out); err != nil {
+			return fmt.Errorf("invalid response from huskyCI API: %w", err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,139 @@
+package apiclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetAnalysisSendsToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/analysis/abc" || r.Header.Get("Husky-Token") != "token" {
+			t.Errorf("unexpected request %s with token %q", r.URL.Path, r.Header.Get("Husky-Token"))
+		}
+		w.Write([]byte(`{"RID":"abc","status":"finished"}`))
+	}))
+	defer server.Close()
+
+	client := New(server.URL + "/")
+	client.Token = "token"
+	analysis, err := client.GetAnalysis(context.Background(), "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if analysis.RID != "abc" || analysis.Status != "finished" {
+		t.Errorf("unexpected analysis %+v", analysis)
+	}
+}
+
+func TestListAnalysesSetsQuery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.RawQuery; got != "branch=main&pageSize=5" {
+			t.Errorf("unexpected query %q", got)
+		}
+		w.Write([]byte(`{"page":1,"pageSize":5,"total":0,"analyses":[]}`))
+	}))
+	defer server.Close()
+
+	list, err := New(server.URL).ListAnalyses(context.Background(), &ListAnalysesParams{Branch: "main", PageSize: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.PageSize != 5 {
+		t.Errorf("unexpected page size %d", list.PageSize)
+	}
+}
+
+func TestAdminRoutesUseBasicAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "admin" || password != "secret" {
+			t.Errorf("unexpected basic auth %q %q", username, password)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := New(server.URL)
+	client.Username, client.Password = "admin", "secret"
+	if err := client.DeleteGitCredential(context.Background(), "https://github.com/org/repo"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAPIErrorMessage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"success":false,"error":"analysis not found","code":"not_found","message":"Analysis not found","docsURL":"https://docs/errors.md#not_found"}`))
+	}))
+	defer server.Close()
+
+	_, err := New(server.URL).GetAnalysis(context.Background(), "abc")
+	apiErr := &APIError{}
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected an APIError, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusNotFound || apiErr.Message != "Analysis not found" {
+		t.Errorf("unexpected error %+v", apiErr)
+	}
+	if apiErr.Code != "not_found" || apiErr.Reason != "analysis not found" || apiErr.DocsURL != "https://docs/errors.md#not_found" {
+		t.Errorf("unexpected code of error %+v", apiErr)
+	}
+}
+
+func TestUploadZipSendsMultipartFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, header, err := r.FormFile("zipfile")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		content, _ := io.ReadAll(file)
+		if header.Filename != "code.zip" || string(content) != "zip" || r.URL.Query().Get("rid") != "abc" {
+			t.Errorf("unexpected upload %s %q", header.Filename, content)
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"success":true,"rid":"abc"}`))
+	}))
+	defer server.Close()
+
+	reply, err := New(server.URL).UploadZip(context.Background(), &UploadZipParams{RID: "abc"}, strings.NewReader("zip"), "code.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.RID != "abc" {
+		t.Errorf("unexpected reply %+v", reply)
+	}
+}
+
+func TestHealthCheckReturnsText(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("WORKING\n"))
+	}))
+	defer server.Close()
+
+	body, err := New(server.URL).HealthCheck(context.Background())
+	if err != nil || body != "WORKING\n" {
+		t.Errorf("unexpected health check %q: %v", body, err)
+	}
+}
+
+func TestWithResponseHeader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "15")
+		w.Write([]byte(`{"RID":"abc","status":"running"}`))
+	}))
+	defer server.Close()
+
+	header := http.Header{}
+	if _, err := New(server.URL).GetAnalysis(WithResponseHeader(context.Background(), &header), "abc"); err != nil {
+		t.Fatal(err)
+	}
+	if header.Get("Retry-After") != "15" {
+		t.Errorf("unexpected header %v", header)
+	}
+}
@@ -0,0 +1,98 @@
+// Package errcode is the taxonomy of the errors the huskyCI API replies with.
+// Each error reply has a code, so that clients can tell the requests worth
+// sending again from the ones that will fail the same way, whatever the
+// wording of its message.
+package errcode
+
+import "net/http"
+
+// Codes of the error replies of the huskyCI API.
+const (
+	// InvalidRequest is a request with an invalid body, parameter or header.
+	InvalidRequest = "invalid_request"
+	// Unauthorized is a request without valid credentials or token for the
+	// resource.
+	Unauthorized = "unauthorized"
+	// Forbidden is a request refused whatever its credentials, such as from
+	// an address that is not allowed.
+	Forbidden = "forbidden"
+	// NotFound is a request for a resource that does not exist.
+	NotFound = "not_found"
+	// Conflict is a request the current state of the resource refuses, such
+	// as an analysis of a branch while another one runs.
+	Conflict = "conflict"
+	// TooLarge is a request with a body over the size limit of the API.
+	TooLarge = "too_large"
+	// Rejected is a valid request whose content was refused, such as an
+	// upload the malware scanner flagged.
+	Rejected = "rejected"
+	// RateLimited is a request refused until the client sends fewer of them.
+	RateLimited = "rate_limited"
+	// Internal is an error of the API or of a service it depends on, such as
+	// the database.
+	Internal = "internal"
+	// NotImplemented is a request for a feature the deployment of the API
+	// does not support, such as with its infrastructure.
+	NotImplemented = "not_implemented"
+	// Unavailable is a request refused while a service the API depends on is
+	// down or disabled.
+	Unavailable = "unavailable"
+	// Timeout is a request the API did not finish in time.
+	Timeout = "timeout"
+)
+
+// DocsURL is the page documenting the codes, each under an anchor of its name.
+const DocsURL = "https://github.com/huskyci-org/huskyCI/blob/master/docs/errors.md"
+
+// statuses are the HTTP statuses of the codes.
+var statuses = map[string]int{
+	InvalidRequest: http.StatusBadRequest,
+	Unauthorized:   http.StatusUnauthorized,
+	Forbidden:      http.StatusForbidden,
+	NotFound:       http.StatusNotFound,
+	Conflict:       http.StatusConflict,
+	TooLarge:       http.StatusRequestEntityTooLarge,
+	Rejected:       http.StatusUnprocessableEntity,
+	RateLimited:    http.StatusTooManyRequests,
+	Internal:       http.StatusInternalServerError,
+	NotImplemented: http.StatusNotImplemented,
+	Unavailable:    http.StatusServiceUnavailable,
+	Timeout:        http.StatusGatewayTimeout,
+}
+
+// Status returns the HTTP status of code, or 500 for an unknown code.
+func Status(code string) int {
+	if status, ok := statuses[code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// FromStatus returns the code of an error reply with status, for the replies
+// of the API versions that had no code.
+func FromStatus(status int) string {
+	for code, codeStatus := range statuses {
+		if codeStatus == status {
+			return code
+		}
+	}
+	if status >= 500 {
+		return Internal
+	}
+	return InvalidRequest
+}
+
+// Retryable returns true if a request that failed with code may succeed when
+// sent again as is, after a while.
+func Retryable(code string) bool {
+	switch code {
+	case RateLimited, Internal, Unavailable, Timeout:
+		return true
+	}
+	return false
+}
+
+// Docs returns the URL of the documentation of code.
+func Docs(code string) string {
+	return DocsURL + "#" + code
+}
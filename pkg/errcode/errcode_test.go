@@ -0,0 +1,36 @@
+package errcode
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestStatusAndFromStatus(t *testing.T) {
+	for code, status := range statuses {
+		if Status(code) != status {
+			t.Errorf("Status(%q) = %d, want %d", code, Status(code), status)
+		}
+		if FromStatus(status) != code {
+			t.Errorf("FromStatus(%d) = %q, want %q", status, FromStatus(status), code)
+		}
+	}
+	if FromStatus(http.StatusBadGateway) != Internal {
+		t.Errorf("FromStatus(502) = %q, want %q", FromStatus(http.StatusBadGateway), Internal)
+	}
+	if FromStatus(http.StatusMethodNotAllowed) != InvalidRequest {
+		t.Errorf("FromStatus(405) = %q, want %q", FromStatus(http.StatusMethodNotAllowed), InvalidRequest)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	for _, code := range []string{RateLimited, Internal, Unavailable, Timeout} {
+		if !Retryable(code) {
+			t.Errorf("Retryable(%q) = false, want true", code)
+		}
+	}
+	for _, code := range []string{InvalidRequest, Unauthorized, NotFound, Conflict, NotImplemented, ""} {
+		if Retryable(code) {
+			t.Errorf("Retryable(%q) = true, want false", code)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// Package formatter prints the output of the huskyCI CLI and client meant
+// for people. In plain mode, for CI log parsers, screen readers and terminals
+// without unicode, it strips what is only decoration from that output: emoji
+// and ANSI escape sequences are dropped, and the symbols that carry meaning,
+// such as check marks or box drawing, are replaced by ASCII.
+//
+// Output meant for machines, such as JSON or SARIF, must not be printed
+// through this package, so that it stays the same in plain mode.
+package formatter
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// PlainEnv is the environment variable that turns plain mode on, unless it is
+// empty, 0 or false.
+const PlainEnv = "HUSKYCI_NO_EMOJI"
+
+var (
+	plainMu  sync.RWMutex
+	plain    bool
+	detected bool
+)
+
+// FromEnv returns whether getenv, such as os.Getenv, turns plain mode on.
+func FromEnv(getenv func(string) string) bool {
+	switch strings.ToLower(strings.TrimSpace(getenv(PlainEnv))) {
+	case "", "0", "false", "no", "off":
+		return false
+	}
+	return true
+}
+
+// Plain returns whether the output is printed in plain mode, which
+// HUSKYCI_NO_EMOJI turns on unless SetPlain was called.
+func Plain() bool {
+	plainMu.RLock()
+	if detected {
+		defer plainMu.RUnlock()
+		return plain
+	}
+	plainMu.RUnlock()
+
+	plainMu.Lock()
+	defer plainMu.Unlock()
+	if !detected {
+		plain = FromEnv(os.Getenv)
+		detected = true
+	}
+	return plain
+}
+
+// SetPlain turns plain mode on or off, whatever the environment, as the
+// --plain flags do.
+func SetPlain(on bool) {
+	plainMu.Lock()
+	defer plainMu.Unlock()
+	plain = on
+	detected = true
+}
+
+// ansiEscape matches the ANSI escape sequences of colors and cursor moves.
+var ansiEscape = regexp.MustCompile(`\x1b(?:\[[0-9;?]*[ -/]*[@-~]|\][^\x07\x1b]*(?:\x07|\x1b\\)|[@-Z\\-_])`)
+
+// replacements are the symbols that carry meaning, with their ASCII
+// replacement.
+var replacements = map[rune]string{
+	'✓': "[OK]",
+	'✔': "[OK]",
+	'✅': "[OK]",
+	'❌': "[ERROR]",
+	'✗': "[ERROR]",
+	'✖': "[ERROR]",
+	'⚠': "[WARNING]",
+	'ℹ': "[INFO]",
+	'⊘': "[SKIPPED]",
+	'•': "-",
+	'…': "...",
+	'→': "->",
+	'←': "<-",
+	'█': "#",
+	'░': ".",
+	'▓': "#",
+	'▒': ".",
+	'“': `"`,
+	'”': `"`,
+	'‘': "'",
+	'’': "'",
+}
+
+// Strip returns s as printed in plain mode.
+func Strip(s string) string {
+	s = ansiEscape.ReplaceAllString(s, "")
+	var b strings.Builder
+	b.Grow(len(s))
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		replacement, replaced := replacements[r]
+		switch {
+		case replaced:
+			b.WriteString(replacement)
+		case r == '‍' || unicode.Is(unicode.Variation_Selector, r) || r == '⃣':
+			continue
+		case r >= '─' && r <= '╿':
+			b.WriteString(boxDrawing(r))
+			continue
+		case isEmoji(r):
+			// dropped
+		default:
+			b.WriteRune(r)
+			continue
+		}
+		// a symbol dropped or replaced by a word is followed by one space at
+		// most, as emoji take the width of two characters
+		j := i + 1
+		for j < len(runes) && (runes[j] == '️' || runes[j] == '‍') {
+			j++
+		}
+		spaces := 0
+		for j+spaces < len(runes) && runes[j+spaces] == ' ' {
+			spaces++
+		}
+		if replaced && spaces > 0 {
+			b.WriteByte(' ')
+		}
+		if spaces > 0 || j > i+1 {
+			i = j + spaces - 1
+		}
+	}
+	return b.String()
+}
+
+// boxDrawing returns the ASCII replacement of a box drawing character.
+func boxDrawing(r rune) string {
+	switch {
+	case r == '═':
+		return "="
+	case r == '│' || r == '┃' || r == '║' || r == '╎' || r == '╏' || r == '┆' || r == '┇' || r == '┊' || r == '┋':
+		return "|"
+	case r <= '┃' || r == '╌' || r == '╍' || r == '╴' || r == '╶' || r == '╸' || r == '╺' || r == '╼' || r == '╾' || (r >= '┄' && r <= '┋'):
+		return "-"
+	}
+	return "+"
+}
+
+// isEmoji returns whether r is an emoji or a pictograph.
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1f000 && r <= 0x1faff:
+		return true
+	case r >= 0x2600 && r <= 0x27bf:
+		return true
+	case r >= 0x2b00 && r <= 0x2bff:
+		return true
+	case r >= 0x2300 && r <= 0x23ff:
+		return true
+	case r >= 0x2190 && r <= 0x21ff:
+		return true
+	}
+	return false
+}
+
+// writer writes to the file it returns, stripped in plain mode.
+type writer func() *os.File
+
+func (w writer) Write(p []byte) (int, error) {
+	if !Plain() {
+		return w().Write(p)
+	}
+	if _, err := io.WriteString(w(), Strip(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Stdout and Stderr write to the standard output and error of the process,
+// stripped in plain mode.
+var (
+	Stdout io.Writer = writer(func() *os.File { return os.Stdout })
+	Stderr io.Writer = writer(func() *os.File { return os.Stderr })
+)
+
+// Print prints a to Stdout as fmt.Print does.
+func Print(a ...interface{}) {
+	fmt.Fprint(Stdout, a...)
+}
+
+// Println prints a to Stdout as fmt.Println does.
+func Println(a ...interface{}) {
+	fmt.Fprintln(Stdout, a...)
+}
+
+// Printf prints to Stdout according to format as fmt.Printf does.
+func Printf(format string, a ...interface{}) {
+	fmt.Fprintf(Stdout, format, a...)
+}
@@ -0,0 +1,36 @@
+package formatter
+
+import "testing"
+
+func TestStrip(t *testing.T) {
+	cases := map[string]string{
+		"🚀 Starting huskyCI analysis...":               "Starting huskyCI analysis...",
+		"📦 Repository: %s\n":                           "Repository: %s\n",
+		"✓ Analysis started successfully!\n":           "[OK] Analysis started successfully!\n",
+		"\n⚠️  Warning: %s\n":                          "\n[WARNING] Warning: %s\n",
+		"   ℹ️  Info:   3\n":                           "   [INFO] Info:   3\n",
+		"   🔴 High:   3\n":                             "   High:   3\n",
+		"[HUSKYCI] ⏳ Analysis in progress...":          "[HUSKYCI] Analysis in progress...",
+		"   • The analysis has not completed":          "   - The analysis has not completed",
+		"━━━━━━━━":                                     "--------",
+		"┌─┐\n│x│\n└─┘":                                "+-+\n|x|\n+-+",
+		"[████░░░░] 50%":                               "[####....] 50%",
+		"\x1b[31mred\x1b[0m and \x1b[1;32mgreen\x1b[m": "red and green",
+		"plain ASCII, ção and 日本語 stay":                "plain ASCII, ção and 日本語 stay",
+	}
+	for s, want := range cases {
+		if got := Strip(s); got != want {
+			t.Errorf("Strip(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	cases := map[string]bool{"": false, "0": false, "false": false, "1": true, "true": true, "yes": true}
+	for value, want := range cases {
+		getenv := func(string) string { return value }
+		if got := FromEnv(getenv); got != want {
+			t.Errorf("FromEnv with %s=%q = %v, want %v", PlainEnv, value, got, want)
+		}
+	}
+}
@@ -0,0 +1,5 @@
+module github.com/huskyci-org/huskyCI/pkg
+
+go 1.23.0
+
+require golang.org/x/net v0.37.0
@@ -0,0 +1,190 @@
+// Package i18n translates the messages the huskyCI CLI and client print for
+// people. Messages are written in English in the code and looked up, by their
+// English format, in the catalog of the language of the user. A message
+// missing from a catalog is printed in English.
+//
+// Only what people read is translated: JSON output, logs meant for machines
+// and the values sent to the API stay as they are, whatever the language.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/huskyci-org/huskyCI/pkg/formatter"
+)
+
+// English is the language messages are written in.
+const English = "en"
+
+// LanguageEnv is the environment variable that overrides the language found
+// in the locale.
+const LanguageEnv = "HUSKYCI_LANG"
+
+// localeEnvs are the environment variables of the locale, in the order of
+// precedence of POSIX.
+var localeEnvs = []string{"LC_ALL", "LC_MESSAGES", "LANG"}
+
+//go:embed catalogs/*.json
+var catalogFiles embed.FS
+
+var (
+	catalogs    map[string]map[string]string
+	catalogOnce sync.Once
+
+	languageMu sync.RWMutex
+	language   string
+	detected   bool
+)
+
+// loadCatalogs reads the embedded catalogs, named after their language.
+func loadCatalogs() {
+	catalogs = map[string]map[string]string{}
+	entries, err := catalogFiles.ReadDir("catalogs")
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		content, err := catalogFiles.ReadFile(path.Join("catalogs", entry.Name()))
+		if err != nil {
+			continue
+		}
+		catalog := map[string]string{}
+		if err := json.Unmarshal(content, &catalog); err != nil {
+			continue
+		}
+		catalogs[strings.TrimSuffix(entry.Name(), ".json")] = catalog
+	}
+}
+
+// Languages returns the languages messages are translated to, besides English.
+func Languages() []string {
+	catalogOnce.Do(loadCatalogs)
+	languages := make([]string, 0, len(catalogs))
+	for language := range catalogs {
+		languages = append(languages, language)
+	}
+	return languages
+}
+
+// Detect returns the language of the user given getenv, such as os.Getenv.
+// HUSKYCI_LANG wins over the locale, whose first variable set among LC_ALL,
+// LC_MESSAGES and LANG is used. A language without a catalog, such as the C
+// locale, is English; one whose region has no catalog falls back to another
+// region of the same language, so pt_PT.UTF-8 is pt-BR.
+func Detect(getenv func(string) string) string {
+	if language := getenv(LanguageEnv); language != "" {
+		return Match(language)
+	}
+	for _, env := range localeEnvs {
+		if locale := getenv(env); locale != "" {
+			return Match(locale)
+		}
+	}
+	return English
+}
+
+// Match returns the language with a catalog closest to locale, a language tag
+// such as pt-BR or a POSIX locale such as pt_BR.UTF-8, or English when none
+// is close.
+func Match(locale string) string {
+	catalogOnce.Do(loadCatalogs)
+	tag := normalize(locale)
+	if tag == "" {
+		return English
+	}
+	base := strings.SplitN(tag, "-", 2)[0]
+	match := ""
+	for language := range catalogs {
+		if strings.EqualFold(language, tag) {
+			return language
+		}
+		if strings.EqualFold(strings.SplitN(language, "-", 2)[0], base) && (match == "" || language < match) {
+			match = language
+		}
+	}
+	if match == "" {
+		return English
+	}
+	return match
+}
+
+// normalize turns a POSIX locale into a language tag, dropping its encoding
+// and modifier.
+func normalize(locale string) string {
+	locale = strings.TrimSpace(locale)
+	if i := strings.IndexAny(locale, ".@"); i >= 0 {
+		locale = locale[:i]
+	}
+	return strings.ReplaceAll(locale, "_", "-")
+}
+
+// Language returns the language messages are printed in, detected from the
+// environment the first time it is needed.
+func Language() string {
+	languageMu.RLock()
+	if detected {
+		defer languageMu.RUnlock()
+		return language
+	}
+	languageMu.RUnlock()
+
+	languageMu.Lock()
+	defer languageMu.Unlock()
+	if !detected {
+		language = Detect(os.Getenv)
+		detected = true
+	}
+	return language
+}
+
+// SetLanguage makes messages be printed in language, matched as Match does,
+// instead of the language of the environment.
+func SetLanguage(locale string) {
+	matched := Match(locale)
+	languageMu.Lock()
+	defer languageMu.Unlock()
+	language = matched
+	detected = true
+}
+
+// T returns the translation of message to the language of the user, or
+// message itself when it has none.
+func T(message string) string {
+	current := Language()
+	if current == English {
+		return message
+	}
+	if translation, ok := catalogs[current][message]; ok && translation != "" {
+		return translation
+	}
+	return message
+}
+
+// Sprintf formats according to the translation of format.
+func Sprintf(format string, a ...interface{}) string {
+	return fmt.Sprintf(T(format), a...)
+}
+
+// Printf prints to the standard output according to the translation of
+// format, through the formatter of the output.
+func Printf(format string, a ...interface{}) {
+	fmt.Fprintf(formatter.Stdout, T(format), a...)
+}
+
+// Fprintf prints to w according to the translation of format.
+func Fprintf(w io.Writer, format string, a ...interface{}) {
+	fmt.Fprintf(w, T(format), a...)
+}
+
+// Errorf returns an error formatted according to the translation of format.
+// Like fmt.Errorf, it wraps the errors of its %w verbs.
+func Errorf(format string, a ...interface{}) error {
+	return fmt.Errorf(T(format), a...)
+}
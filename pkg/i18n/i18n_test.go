@@ -0,0 +1,138 @@
+package i18n
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	cases := []struct {
+		env  map[string]string
+		want string
+	}{
+		{map[string]string{}, English},
+		{map[string]string{"LANG": "C"}, English},
+		{map[string]string{"LANG": "C.UTF-8"}, English},
+		{map[string]string{"LANG": "de_DE.UTF-8"}, English},
+		{map[string]string{"LANG": "pt_BR.UTF-8"}, "pt-BR"},
+		{map[string]string{"LANG": "pt_PT@euro"}, "pt-BR"},
+		{map[string]string{"LANG": "en_US.UTF-8", "LC_MESSAGES": "pt_BR"}, "pt-BR"},
+		{map[string]string{"LANG": "pt_BR.UTF-8", "LC_ALL": "en_US.UTF-8"}, English},
+		{map[string]string{"LANG": "en_US.UTF-8", "HUSKYCI_LANG": "pt-br"}, "pt-BR"},
+		{map[string]string{"LC_ALL": "pt_BR.UTF-8", "HUSKYCI_LANG": "en"}, English},
+	}
+	for _, c := range cases {
+		getenv := func(key string) string { return c.env[key] }
+		if got := Detect(getenv); got != c.want {
+			t.Errorf("Detect(%v) = %q, want %q", c.env, got, c.want)
+		}
+	}
+}
+
+func TestT(t *testing.T) {
+	defer SetLanguage(Language())
+
+	SetLanguage("pt_BR.UTF-8")
+	if got := T("\n📈 Summary:"); got != "\n📈 Resumo:" {
+		t.Errorf("T translated to %q", got)
+	}
+	if got := T("not in the catalog"); got != "not in the catalog" {
+		t.Errorf("T of a missing message = %q, want it untouched", got)
+	}
+	if got := Sprintf("   🔴 High:   %d\n", 3); got != "   🔴 Alta:   3\n" {
+		t.Errorf("Sprintf = %q", got)
+	}
+
+	SetLanguage(English)
+	if got := T("\n📈 Summary:"); got != "\n📈 Summary:" {
+		t.Errorf("T in English = %q", got)
+	}
+}
+
+// verb matches the verbs of a format.
+var verb = regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z%]`)
+
+func TestCatalogsKeepVerbs(t *testing.T) {
+	catalogOnce.Do(loadCatalogs)
+	if len(catalogs) == 0 {
+		t.Fatal("no catalog was loaded")
+	}
+	for language, catalog := range catalogs {
+		for message, translation := range catalog {
+			if !reflect.DeepEqual(verb.FindAllString(message, -1), verb.FindAllString(translation, -1)) {
+				t.Errorf("%s: %q does not keep the verbs of %q", language, translation, message)
+			}
+		}
+	}
+}
+
+// translated lists the folders whose messages go through this package.
+var translated = []string{"../../cli", "../../client"}
+
+func TestCatalogsCoverMessages(t *testing.T) {
+	catalogOnce.Do(loadCatalogs)
+	for _, root := range translated {
+		err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+				return err
+			}
+			file, err := parser.ParseFile(token.NewFileSet(), path, nil, 0)
+			if err != nil {
+				return err
+			}
+			ast.Inspect(file, func(node ast.Node) bool {
+				message, ok := literalMessage(node)
+				if !ok {
+					return true
+				}
+				for language, catalog := range catalogs {
+					if _, ok := catalog[message]; !ok {
+						t.Errorf("%s: %s has no translation of %q", path, language, message)
+					}
+				}
+				return true
+			})
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// literalMessage returns the message of a call to this package written as a
+// string literal.
+func literalMessage(node ast.Node) (string, bool) {
+	call, ok := node.(*ast.CallExpr)
+	if !ok {
+		return "", false
+	}
+	selector, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return "", false
+	}
+	if pkg, ok := selector.X.(*ast.Ident); !ok || pkg.Name != "i18n" {
+		return "", false
+	}
+	argument := 0
+	if selector.Sel.Name == "Fprintf" {
+		argument = 1
+	}
+	if len(call.Args) <= argument {
+		return "", false
+	}
+	literal, ok := call.Args[argument].(*ast.BasicLit)
+	if !ok || literal.Kind != token.STRING {
+		return "", false
+	}
+	message, err := strconv.Unquote(literal.Value)
+	return message, err == nil
+}
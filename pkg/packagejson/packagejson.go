@@ -0,0 +1,39 @@
+// Package packagejson reads the dependencies a package.json declares, so that
+// the API and the CLI upgrade the vulnerable ones the same way.
+package packagejson
+
+import (
+	"encoding/json"
+	"regexp"
+)
+
+// Manifest holds the dependencies declared by a package.json.
+type Manifest struct {
+	Dependencies         map[string]string `json:"dependencies"`
+	DevDependencies      map[string]string `json:"devDependencies"`
+	OptionalDependencies map[string]string `json:"optionalDependencies"`
+	PeerDependencies     map[string]string `json:"peerDependencies"`
+}
+
+// VersionSpecRegexp matches the version specs of a dependency that start with
+// a version, and captures their range prefix (^, ~ or >=), if any.
+var VersionSpecRegexp = regexp.MustCompile(`^(\^|~|>=)?v?[0-9]`)
+
+// Parse returns the Manifest of the content of a package.json.
+func Parse(content []byte) (Manifest, error) {
+	manifest := Manifest{}
+	err := json.Unmarshal(content, &manifest)
+	return manifest, err
+}
+
+// Specs returns the version specs the dependency name is declared with, in
+// any of the dependency lists of the manifest.
+func (m Manifest) Specs(name string) map[string]bool {
+	specs := map[string]bool{}
+	for _, dependencies := range []map[string]string{m.Dependencies, m.DevDependencies, m.OptionalDependencies, m.PeerDependencies} {
+		if spec, found := dependencies[name]; found {
+			specs[spec] = true
+		}
+	}
+	return specs
+}
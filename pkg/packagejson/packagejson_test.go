@@ -0,0 +1,53 @@
+package packagejson
+
+import "testing"
+
+func TestSpecs(t *testing.T) {
+	manifest, err := Parse([]byte(`{
+  "dependencies": {"lodash": "^4.17.15"},
+  "devDependencies": {"lodash": "~4.17.10", "mocha": "10.0.0"},
+  "peerDependencies": {"react": ">=16"}
+}`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	cases := map[string]map[string]bool{
+		"lodash": {"^4.17.15": true, "~4.17.10": true},
+		"mocha":  {"10.0.0": true},
+		"react":  {">=16": true},
+		"left":   {},
+	}
+	for name, want := range cases {
+		got := manifest.Specs(name)
+		if len(got) != len(want) {
+			t.Errorf("Specs(%q) = %v, want %v", name, got, want)
+			continue
+		}
+		for spec := range want {
+			if !got[spec] {
+				t.Errorf("Specs(%q) = %v, want %v", name, got, want)
+			}
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	if _, err := Parse([]byte(`{"dependencies": [`)); err == nil {
+		t.Error("Parse() of an invalid package.json returned no error")
+	}
+}
+
+func TestVersionSpecRegexp(t *testing.T) {
+	cases := map[string]string{"^1.2.3": "^", "~1.2": "~", ">=2": ">=", "v1.0.0": "", "1.0.0": ""}
+	for spec, prefix := range cases {
+		match := VersionSpecRegexp.FindStringSubmatch(spec)
+		if match == nil || match[1] != prefix {
+			t.Errorf("VersionSpecRegexp on %q = %v, want prefix %q", spec, match, prefix)
+		}
+	}
+	for _, spec := range []string{"latest", "git+https://github.com/org/repo.git", "*"} {
+		if VersionSpecRegexp.MatchString(spec) {
+			t.Errorf("VersionSpecRegexp matches %q", spec)
+		}
+	}
+}
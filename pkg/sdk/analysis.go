@@ -0,0 +1,212 @@
+package sdk
+
+import (
+	"context"
+	cryptorand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/pkg/apiclient"
+)
+
+// StartAnalysis asks the huskyCI API to analyze repository and returns the
+// RID of the analysis. It sends the Idempotency-Key set in ctx with
+// apiclient.WithIdempotencyKey, or a random one, and retries with it, as the
+// API returns the analysis it started when the same request is sent again.
+func (c *Client) StartAnalysis(ctx context.Context, repository apiclient.Repository) (string, error) {
+	if apiclient.IdempotencyKeyOf(ctx) == "" {
+		ctx = apiclient.WithIdempotencyKey(ctx, newIdempotencyKey())
+	}
+	var reply *apiclient.AnalysisStarted
+	err := c.retry(ctx, func() error {
+		var err error
+		reply, err = c.API.ReceiveRequest(ctx, repository)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	if reply.RID == "" {
+		return "", fmt.Errorf("%w: no RID received from the huskyCI API", ErrUnexpected)
+	}
+	return reply.RID, nil
+}
+
+// newIdempotencyKey returns a random Idempotency-Key.
+func newIdempotencyKey() string {
+	key := make([]byte, 16)
+	if _, err := cryptorand.Read(key); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(key)
+}
+
+// UploadZip uploads the zip file at zipPath as the code of the analysis RID
+// and returns the RID the API stored it under.
+func (c *Client) UploadZip(ctx context.Context, RID, zipPath string) (string, error) {
+	var reply *apiclient.AnalysisStarted
+	err := c.retry(ctx, func() error {
+		zipFile, err := os.Open(zipPath)
+		if err != nil {
+			return err
+		}
+		defer zipFile.Close()
+		reply, err = c.API.UploadZip(ctx, &apiclient.UploadZipParams{RID: RID}, zipFile, filepath.Base(zipPath))
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	if reply.RID != "" {
+		return reply.RID, nil
+	}
+	return RID, nil
+}
+
+// GetAnalysis returns the analysis RID.
+func (c *Client) GetAnalysis(ctx context.Context, RID string) (*apiclient.Analysis, error) {
+	var analysis *apiclient.Analysis
+	err := c.retry(ctx, func() error {
+		var err error
+		analysis, err = c.API.GetAnalysis(ctx, RID)
+		return err
+	})
+	return analysis, err
+}
+
+// WaitAnalysis waits for the analysis RID to finish or ctx to be done. When
+// c.WebSocket is set, the analysis is watched over its WebSocket and only
+// polled if the WebSocket fails. The first poll happens after PollInterval and
+// the interval then grows up to MaxPollInterval, with some jitter so that many
+// clients do not poll in step. A Retry-After hint returned by the API replaces
+// the interval. onPoll, if not nil, is called with every analysis received.
+// Transient errors do not stop the polling until they last OutageWindow, and
+// the analysis may be not found NotFoundRetries times, as it is created by the
+// API in background. An
+// analysis that ends with an error is returned along with ErrAnalysisFailed
+// and a cancelled one along with ErrCancelled.
+func (c *Client) WaitAnalysis(ctx context.Context, RID string, onPoll func(*apiclient.Analysis)) (*apiclient.Analysis, error) {
+	if c.WebSocket {
+		if analysis, over, err := c.waitPushed(ctx, RID, onPoll); over {
+			return analysis, err
+		}
+	}
+
+	interval := c.PollInterval
+	delay := interval
+	notFound := 0
+	var failingSince time.Time
+	for {
+		timer := time.NewTimer(jitter(delay))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		header := http.Header{}
+		analysis, err := c.GetAnalysis(apiclient.WithResponseHeader(ctx, &header), RID)
+		interval = c.nextPollInterval(interval)
+		delay = interval
+		if hint := retryAfter(header, time.Now()); hint > 0 {
+			delay = min(hint, c.MaxPollInterval)
+		}
+		switch {
+		case errors.Is(err, ErrNotFound) && notFound < c.NotFoundRetries:
+			notFound++
+			continue
+		case Transient(err):
+			if failingSince.IsZero() {
+				failingSince = time.Now()
+			}
+			if c.OutageWindow > 0 && time.Since(failingSince) >= c.OutageWindow {
+				return nil, fmt.Errorf("huskyCI API failing for %s: %w", c.OutageWindow, err)
+			}
+			continue
+		case err != nil:
+			return nil, err
+		}
+		failingSince = time.Time{}
+
+		if onPoll != nil {
+			onPoll(analysis)
+		}
+		if over, err := result(analysis); over {
+			return analysis, err
+		}
+	}
+}
+
+// result returns true if analysis is over, along with ErrAnalysisFailed or
+// ErrCancelled when it did not finish successfully.
+func result(analysis *apiclient.Analysis) (bool, error) {
+	switch analysis.Status {
+	case StatusFinished:
+		return true, nil
+	case StatusError:
+		errorFound := analysis.ErrorFound
+		if errorFound == "" {
+			errorFound = "unknown error occurred during analysis"
+		}
+		return true, fmt.Errorf("%w: %s", ErrAnalysisFailed, errorFound)
+	case StatusCancelled:
+		return true, ErrCancelled
+	}
+	return false, nil
+}
+
+// nextPollInterval returns the interval that follows interval, growing by
+// half up to MaxPollInterval.
+func (c *Client) nextPollInterval(interval time.Duration) time.Duration {
+	return min(interval+interval/2, max(c.MaxPollInterval, c.PollInterval))
+}
+
+// jitter returns delay give or take pollJitter of it.
+func jitter(delay time.Duration) time.Duration {
+	spread := float64(delay) * pollJitter
+	return delay + time.Duration(spread*(2*rand.Float64()-1))
+}
+
+// retryAfter returns the delay asked by the Retry-After header, given in
+// seconds or as an HTTP date, or 0 when there is none.
+func retryAfter(header http.Header, now time.Time) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+// CancelAnalysis cancels the running analysis RID. When the wait for the
+// analysis was interrupted, its context is done, so pass a new one.
+func (c *Client) CancelAnalysis(ctx context.Context, RID string) error {
+	return c.retry(ctx, func() error {
+		_, err := c.API.CancelAnalysis(ctx, RID)
+		return err
+	})
+}
+
+// Decode copies analysis into out, a pointer to a struct sharing the JSON
+// representation of the huskyCI API analysis.
+func Decode(analysis *apiclient.Analysis, out interface{}) error {
+	content, err := json.Marshal(analysis)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, out)
+}
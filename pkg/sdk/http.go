@@ -0,0 +1,45 @@
+package sdk
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// Default timeouts of a single request to the huskyCI API.
+const (
+	DefaultConnectTimeout = 10 * time.Second
+	DefaultReadTimeout    = 60 * time.Second
+)
+
+// Timeouts bounds a single request to the huskyCI API. Connect is the time
+// allowed to open the connection, TLS handshake included, and Read the time
+// allowed for the API to start answering once the request is sent. Zero values
+// use the defaults. The whole analysis is bounded by the context instead.
+type Timeouts struct {
+	Connect time.Duration
+	Read    time.Duration
+}
+
+// Apply sets the timeouts on the transport of httpClient, which must be nil or
+// an *http.Transport, and removes any overall client timeout, which would also
+// cut long uploads.
+func (t Timeouts) Apply(httpClient *http.Client) {
+	connect, read := t.Connect, t.Read
+	if connect <= 0 {
+		connect = DefaultConnectTimeout
+	}
+	if read <= 0 {
+		read = DefaultReadTimeout
+	}
+
+	transport, ok := httpClient.Transport.(*http.Transport)
+	if !ok || transport == nil {
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+	transport.DialContext = (&net.Dialer{Timeout: connect, KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = connect
+	transport.ResponseHeaderTimeout = read
+	httpClient.Transport = transport
+	httpClient.Timeout = 0
+}
@@ -0,0 +1,66 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/huskyci-org/huskyCI/pkg/apiclient"
+)
+
+// Severities analyses fail on, from the strictest.
+const (
+	FailOnLow    = "low"
+	FailOnMedium = "medium"
+	FailOnHigh   = "high"
+	FailOnNever  = "never"
+)
+
+// DefaultFailOn is the severity analyses fail on when their repository has no
+// policy.
+const DefaultFailOn = FailOnMedium
+
+var severityRanks = map[string]int{FailOnLow: 1, FailOnMedium: 2, FailOnHigh: 3}
+
+// ValidFailOn returns true if failOn is a severity analyses can fail on.
+func ValidFailOn(failOn string) bool {
+	return failOn == FailOnNever || severityRanks[failOn] > 0
+}
+
+// Blocks returns true if vulnerabilities of severity fail an analysis failing
+// on failOn.
+func Blocks(failOn, severity string) bool {
+	rank := severityRanks[strings.ToLower(severity)]
+	return failOn != FailOnNever && rank > 0 && rank >= severityRanks[failOn]
+}
+
+// GetPolicy returns the policy of repositoryURL on the huskyCI API, which is
+// empty if it has none.
+func (c *Client) GetPolicy(ctx context.Context, repositoryURL string) (apiclient.Policy, error) {
+	policy := apiclient.Policy{}
+	err := c.retry(ctx, func() error {
+		found, err := c.API.GetPolicy(ctx, repositoryURL)
+		if err != nil {
+			return err
+		}
+		policy = *found
+		return nil
+	})
+	if errors.Is(err, ErrNotFound) {
+		return apiclient.Policy{}, nil
+	}
+	return policy, err
+}
+
+// GetFailOn returns the severity the analyses of repositoryURL fail on, as set
+// by its policy on the huskyCI API, or DefaultFailOn if it has none.
+func (c *Client) GetFailOn(ctx context.Context, repositoryURL string) (string, error) {
+	policy, err := c.GetPolicy(ctx, repositoryURL)
+	if err != nil {
+		return DefaultFailOn, err
+	}
+	if ValidFailOn(policy.FailOn) {
+		return policy.FailOn, nil
+	}
+	return DefaultFailOn, nil
+}
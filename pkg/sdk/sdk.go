@@ -0,0 +1,185 @@
+// Package sdk is the huskyCI Go SDK shared by the huskyCI client and CLI. It
+// wraps the generated apiclient with the start, upload and poll logic of an
+// analysis, retries transient failures and returns typed errors.
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/pkg/apiclient"
+	"github.com/huskyci-org/huskyCI/pkg/errcode"
+)
+
+// Errors returned by the SDK. They are wrapped, so test them with errors.Is.
+var (
+	ErrBadRequest     = errors.New("bad request")
+	ErrUnauthorized   = errors.New("unauthorized")
+	ErrNotFound       = errors.New("not found")
+	ErrConflict       = errors.New("conflict")
+	ErrRejected       = errors.New("rejected")
+	ErrUnavailable    = errors.New("huskyCI API unavailable")
+	ErrUnexpected     = errors.New("unexpected response")
+	ErrNetwork        = errors.New("network error")
+	ErrAnalysisFailed = errors.New("analysis failed")
+	ErrCancelled      = errors.New("analysis cancelled")
+)
+
+// Status of an analysis as reported by the huskyCI API.
+const (
+	StatusRunning   = "running"
+	StatusQueued    = "queued"
+	StatusFinished  = "finished"
+	StatusError     = "error running"
+	StatusCancelled = "cancelled"
+)
+
+// Default retry and polling settings used by New.
+const (
+	DefaultRetries         = 3
+	DefaultRetryDelay      = 2 * time.Second
+	DefaultPollInterval    = 2 * time.Second
+	DefaultMaxPollInterval = 60 * time.Second
+	DefaultNotFoundRetries = 3
+	DefaultWaitTimeout     = 60 * time.Minute
+	DefaultOutageWindow    = 10 * time.Minute
+)
+
+// pollJitter is the fraction of the poll interval added to or removed from it
+// at random.
+const pollJitter = 0.2
+
+// Error is returned when the huskyCI API answers with a non 2xx status. It
+// unwraps to the sentinel error matching Code, the code of pkg/errcode of the
+// reply, or StatusCode for the replies of older APIs that have no code.
+type Error struct {
+	StatusCode int
+	Code       string
+	Reason     string
+	Message    string
+	DocsURL    string
+	Body       []byte
+	kind       error
+}
+
+func (e *Error) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("huskyCI API returned %d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("huskyCI API returned %d: %s", e.StatusCode, string(e.Body))
+}
+
+// Unwrap returns the sentinel error matching the status code.
+func (e *Error) Unwrap() error {
+	return e.kind
+}
+
+// Client runs analyses on a huskyCI API. Requests that can be repeated are
+// retried up to Retries times, RetryDelay apart, on network errors and 5xx
+// answers. Analyses are watched over a WebSocket when WebSocket is set, with
+// OnEvent called on every event pushed, and polled otherwise or when the
+// WebSocket fails: every PollInterval at first, backing off to MaxPollInterval.
+// While polling, the API may fail with network errors and 5xx answers for up
+// to OutageWindow in a row, such as while it restarts; zero tolerates them
+// until the analysis is over.
+type Client struct {
+	API             *apiclient.Client
+	Retries         int
+	RetryDelay      time.Duration
+	PollInterval    time.Duration
+	MaxPollInterval time.Duration
+	NotFoundRetries int
+	OutageWindow    time.Duration
+	WebSocket       bool
+	OnEvent         func(apiclient.AnalysisEvent)
+}
+
+// New returns a Client sending its requests with api.
+func New(api *apiclient.Client) *Client {
+	return &Client{
+		API:             api,
+		Retries:         DefaultRetries,
+		RetryDelay:      DefaultRetryDelay,
+		PollInterval:    DefaultPollInterval,
+		MaxPollInterval: DefaultMaxPollInterval,
+		NotFoundRetries: DefaultNotFoundRetries,
+		OutageWindow:    DefaultOutageWindow,
+		WebSocket:       true,
+	}
+}
+
+// wrap turns an apiclient error into an SDK error.
+func wrap(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	apiErr := &apiclient.APIError{}
+	if !errors.As(err, &apiErr) {
+		return fmt.Errorf("%w: %w", ErrNetwork, err)
+	}
+	sdkErr := &Error{
+		StatusCode: apiErr.StatusCode,
+		Code:       apiErr.Code,
+		Reason:     apiErr.Reason,
+		Message:    apiErr.Message,
+		DocsURL:    apiErr.DocsURL,
+		Body:       apiErr.Body,
+	}
+	if sdkErr.Code == "" {
+		sdkErr.Code = statusCode(apiErr.StatusCode)
+	}
+	switch {
+	case sdkErr.Code == errcode.InvalidRequest:
+		sdkErr.kind = ErrBadRequest
+	case sdkErr.Code == errcode.Unauthorized || sdkErr.Code == errcode.Forbidden:
+		sdkErr.kind = ErrUnauthorized
+	case sdkErr.Code == errcode.NotFound:
+		sdkErr.kind = ErrNotFound
+	case sdkErr.Code == errcode.Conflict:
+		sdkErr.kind = ErrConflict
+	case sdkErr.Code == errcode.TooLarge || sdkErr.Code == errcode.Rejected:
+		sdkErr.kind = ErrRejected
+	case errcode.Retryable(sdkErr.Code):
+		sdkErr.kind = ErrUnavailable
+	default:
+		sdkErr.kind = ErrUnexpected
+	}
+	return sdkErr
+}
+
+// statusCode returns the code of the error replies with status of the APIs
+// older than the error codes, or an empty code for an unexpected status.
+func statusCode(status int) string {
+	code := errcode.FromStatus(status)
+	if errcode.Status(code) == status || status >= http.StatusInternalServerError {
+		return code
+	}
+	return ""
+}
+
+// Transient returns true if err may not happen again when the request is retried.
+func Transient(err error) bool {
+	return errors.Is(err, ErrNetwork) || errors.Is(err, ErrUnavailable)
+}
+
+// retry calls request until it succeeds, fails with a non transient error or
+// Retries retries have been made.
+func (c *Client) retry(ctx context.Context, request func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := wrap(ctx, request())
+		if err == nil || attempt >= c.Retries || !Transient(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.RetryDelay):
+		}
+	}
+}
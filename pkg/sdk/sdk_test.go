@@ -0,0 +1,435 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/pkg/apiclient"
+)
+
+// testClient returns a Client of handler that retries and polls quickly,
+// without watching analyses over a WebSocket.
+func testClient(t *testing.T, handler http.HandlerFunc) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	client := New(apiclient.New(server.URL))
+	client.RetryDelay = time.Millisecond
+	client.PollInterval = time.Millisecond
+	client.MaxPollInterval = 5 * time.Millisecond
+	client.WebSocket = false
+	return client
+}
+
+func TestGetAnalysisRetriesUnavailable(t *testing.T) {
+	var calls int32
+	client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"RID":"abc","status":"running"}`)
+	})
+
+	analysis, err := client.GetAnalysis(context.Background(), "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if analysis.RID != "abc" || calls != 3 {
+		t.Errorf("unexpected analysis %+v after %d calls", analysis, calls)
+	}
+}
+
+func TestGetAnalysisErrorCodes(t *testing.T) {
+	tests := []struct {
+		status  int
+		code    string
+		want    error
+		retried bool
+	}{
+		{http.StatusTooManyRequests, "rate_limited", ErrUnavailable, true},
+		{http.StatusGatewayTimeout, "timeout", ErrUnavailable, true},
+		{http.StatusNotImplemented, "not_implemented", ErrUnexpected, false},
+		{http.StatusForbidden, "forbidden", ErrUnauthorized, false},
+	}
+	for _, test := range tests {
+		var calls int32
+		client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(test.status)
+			fmt.Fprintf(w, `{"success":false,"error":"failed","code":%q,"docsURL":"https://docs#%s"}`, test.code, test.code)
+		})
+
+		_, err := client.GetAnalysis(context.Background(), "abc")
+		if !errors.Is(err, test.want) {
+			t.Errorf("code %s: got %v, want %v", test.code, err, test.want)
+		}
+		sdkErr := &Error{}
+		if !errors.As(err, &sdkErr) || sdkErr.Code != test.code || sdkErr.Reason != "failed" || sdkErr.DocsURL != "https://docs#"+test.code {
+			t.Errorf("code %s: got %#v", test.code, err)
+		}
+		if retried := calls > 1; retried != test.retried {
+			t.Errorf("code %s: retried %d times", test.code, calls-1)
+		}
+	}
+}
+
+func TestGetAnalysisTypedErrors(t *testing.T) {
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{http.StatusBadRequest, ErrBadRequest},
+		{http.StatusUnauthorized, ErrUnauthorized},
+		{http.StatusNotFound, ErrNotFound},
+		{http.StatusConflict, ErrConflict},
+		{http.StatusRequestEntityTooLarge, ErrRejected},
+		{http.StatusUnprocessableEntity, ErrRejected},
+		{http.StatusTeapot, ErrUnexpected},
+	}
+	for _, test := range tests {
+		var calls int32
+		client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(test.status)
+			fmt.Fprint(w, `{"success":false,"error":"failed"}`)
+		})
+
+		_, err := client.GetAnalysis(context.Background(), "abc")
+		if !errors.Is(err, test.want) {
+			t.Errorf("status %d: got %v, want %v", test.status, err, test.want)
+		}
+		sdkErr := &Error{}
+		if !errors.As(err, &sdkErr) || sdkErr.StatusCode != test.status {
+			t.Errorf("status %d: got %#v", test.status, err)
+		}
+		if calls != 1 {
+			t.Errorf("status %d: retried %d times", test.status, calls-1)
+		}
+	}
+}
+
+func TestStartAnalysisRetriesWithIdempotencyKey(t *testing.T) {
+	var calls int32
+	keys := []string{}
+	client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"success":true,"rid":"abc"}`)
+	})
+
+	RID, err := client.StartAnalysis(context.Background(), apiclient.Repository{URL: "https://github.com/org/repo.git", Branch: "main"})
+	if err != nil || RID != "abc" {
+		t.Fatalf("got %q, %v after %d calls", RID, err, calls)
+	}
+	if len(keys) != 3 || keys[0] == "" || keys[1] != keys[0] || keys[2] != keys[0] {
+		t.Errorf("unexpected Idempotency-Keys %q", keys)
+	}
+
+	keys = keys[:0]
+	ctx := apiclient.WithIdempotencyKey(context.Background(), "ci-job-42")
+	if _, err := client.StartAnalysis(ctx, apiclient.Repository{URL: "https://github.com/org/repo.git", Branch: "main"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0] != "ci-job-42" {
+		t.Errorf("unexpected Idempotency-Keys %q", keys)
+	}
+}
+
+func TestWaitAnalysisToleratesNotFound(t *testing.T) {
+	var calls int32
+	client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&calls, 1) {
+		case 1, 2:
+			w.WriteHeader(http.StatusNotFound)
+		case 3:
+			fmt.Fprint(w, `{"RID":"abc","status":"running"}`)
+		default:
+			fmt.Fprint(w, `{"RID":"abc","status":"finished","result":"passed"}`)
+		}
+	})
+
+	polls := 0
+	analysis, err := client.WaitAnalysis(context.Background(), "abc", func(*apiclient.Analysis) { polls++ })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if analysis.Result != "passed" || polls != 2 {
+		t.Errorf("unexpected analysis %+v after %d polls", analysis, polls)
+	}
+}
+
+func TestWaitAnalysisFailed(t *testing.T) {
+	client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"RID":"abc","status":"error running","errorFound":"clone failed"}`)
+	})
+
+	analysis, err := client.WaitAnalysis(context.Background(), "abc", nil)
+	if !errors.Is(err, ErrAnalysisFailed) || analysis == nil {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestWaitAnalysisSurvivesOutage(t *testing.T) {
+	var calls int32
+	client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= 6 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		fmt.Fprint(w, `{"RID":"abc","status":"finished","result":"passed"}`)
+	})
+
+	analysis, err := client.WaitAnalysis(context.Background(), "abc", nil)
+	if err != nil || analysis.Result != "passed" {
+		t.Errorf("got %+v, %v", analysis, err)
+	}
+}
+
+func TestWaitAnalysisOutageWindow(t *testing.T) {
+	client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	client.OutageWindow = 20 * time.Millisecond
+
+	_, err := client.WaitAnalysis(context.Background(), "abc", nil)
+	if !errors.Is(err, ErrUnavailable) {
+		t.Errorf("got %v, want ErrUnavailable", err)
+	}
+}
+
+func TestWaitAnalysisContextDone(t *testing.T) {
+	client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"RID":"abc","status":"running"}`)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.WaitAnalysis(ctx, "abc", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestWaitAnalysisCancelled(t *testing.T) {
+	client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"RID":"abc","status":"cancelled"}`)
+	})
+
+	if _, err := client.WaitAnalysis(context.Background(), "abc", nil); !errors.Is(err, ErrCancelled) {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestCancelAnalysis(t *testing.T) {
+	client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/analysis/abc/cancel" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprint(w, `{"success":false,"error":"analysis not running"}`)
+	})
+
+	if err := client.CancelAnalysis(context.Background(), "abc"); !errors.Is(err, ErrConflict) {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestTimeoutsApply(t *testing.T) {
+	httpClient := &http.Client{Timeout: time.Second}
+	Timeouts{Read: 5 * time.Second}.Apply(httpClient)
+
+	transport, ok := httpClient.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("unexpected transport %T", httpClient.Transport)
+	}
+	if transport.ResponseHeaderTimeout != 5*time.Second || transport.TLSHandshakeTimeout != DefaultConnectTimeout {
+		t.Errorf("unexpected timeouts %s %s", transport.ResponseHeaderTimeout, transport.TLSHandshakeTimeout)
+	}
+	if httpClient.Timeout != 0 {
+		t.Errorf("overall timeout kept: %s", httpClient.Timeout)
+	}
+}
+
+func TestWaitAnalysisCapsRetryAfter(t *testing.T) {
+	var calls int32
+	client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.Header().Set("Retry-After", "3600")
+			fmt.Fprint(w, `{"RID":"abc","status":"running"}`)
+			return
+		}
+		fmt.Fprint(w, `{"RID":"abc","status":"finished"}`)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := client.WaitAnalysis(ctx, "abc", nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNextPollInterval(t *testing.T) {
+	client := New(nil)
+	interval := client.PollInterval
+	for i := 0; i < 20; i++ {
+		next := client.nextPollInterval(interval)
+		if next < interval || next > DefaultMaxPollInterval {
+			t.Fatalf("interval went from %s to %s", interval, next)
+		}
+		interval = next
+	}
+	if interval != DefaultMaxPollInterval {
+		t.Errorf("interval capped at %s", interval)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		if delay := jitter(10 * time.Second); delay < 8*time.Second || delay > 12*time.Second {
+			t.Fatalf("delay %s out of bounds", delay)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"30", 30 * time.Second},
+		{"soon", 0},
+		{now.Add(time.Minute).Format(http.TimeFormat), time.Minute},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+	}
+	for _, test := range tests {
+		header := http.Header{}
+		header.Set("Retry-After", test.value)
+		if got := retryAfter(header, now); got != test.want {
+			t.Errorf("Retry-After %q: got %s, want %s", test.value, got, test.want)
+		}
+	}
+}
+
+func TestListSecurityTests(t *testing.T) {
+	client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/securitytests" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `[{"name":"gosec","type":"Language","language":"Go","image":"huskyci/gosec","imageTag":"2.3.0","default":true}]`)
+	})
+
+	securityTests, err := client.ListSecurityTests(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(securityTests) != 1 || securityTests[0].Name != "gosec" {
+		t.Errorf("unexpected securityTests %+v", securityTests)
+	}
+}
+
+func TestDefaultSecurityTests(t *testing.T) {
+	securityTests := []apiclient.SecurityTestView{
+		{Name: "gosec", Type: "Language", Language: "Go", Image: "huskyci/gosec", ImageTag: "2.3.0", Default: true},
+		{Name: "bandit", Type: "Language", Language: "Python", Image: "huskyci/bandit", ImageTag: "1.6.2", Default: true},
+		{Name: "spotbugs", Type: "Language", Language: "Java", Image: "huskyci/spotbugs", ImageTag: "latest", Default: false},
+		{Name: "gitleaks", Type: "Generic", Image: "huskyci/gitleaks", Default: true},
+	}
+
+	list := DefaultSecurityTests(securityTests, []string{"Go", "Java"})
+	want := map[string][]string{
+		"Go":    {"huskyci/gosec:2.3.0"},
+		Generic: {"huskyci/gitleaks"},
+	}
+	if fmt.Sprint(list) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", list, want)
+	}
+}
+
+func TestGetFailOn(t *testing.T) {
+	client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/policy" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("repositoryURL") != "https://github.com/org/repo.git" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"repositoryURL":"https://github.com/org/repo.git","failOn":"high"}`)
+	})
+
+	failOn, err := client.GetFailOn(context.Background(), "https://github.com/org/repo.git")
+	if err != nil || failOn != FailOnHigh {
+		t.Errorf("got %q, %v, want high", failOn, err)
+	}
+	failOn, err = client.GetFailOn(context.Background(), "https://github.com/org/other.git")
+	if err != nil || failOn != DefaultFailOn {
+		t.Errorf("got %q, %v, want %s", failOn, err, DefaultFailOn)
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	tests := []struct {
+		failOn, severity string
+		want             bool
+	}{
+		{FailOnMedium, "HIGH", true},
+		{FailOnMedium, "MEDIUM", true},
+		{FailOnMedium, "LOW", false},
+		{FailOnLow, "LOW", true},
+		{FailOnHigh, "MEDIUM", false},
+		{FailOnNever, "HIGH", false},
+		{FailOnLow, "NOSEC", false},
+	}
+	for _, test := range tests {
+		if got := Blocks(test.failOn, test.severity); got != test.want {
+			t.Errorf("Blocks(%s, %s) = %v, want %v", test.failOn, test.severity, got, test.want)
+		}
+	}
+}
+
+func TestOlderVersion(t *testing.T) {
+	tests := []struct {
+		version, minimum string
+		want             bool
+	}{
+		{"1.2.0", "1.3.0", true},
+		{"v1.2.0", "1.2", false},
+		{"1.10.0", "1.9.1", false},
+		{"1.2", "1.2.1", true},
+		{"1.3.0-rc1", "1.3.0", false},
+		{"", "1.3.0", false},
+		{"dev", "1.3.0", false},
+		{"1.2.0", "", false},
+	}
+	for _, test := range tests {
+		if got := OlderVersion(test.version, test.minimum); got != test.want {
+			t.Errorf("OlderVersion(%q, %q) = %v, want %v", test.version, test.minimum, got, test.want)
+		}
+	}
+}
+
+func TestRequiredClientVersion(t *testing.T) {
+	client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"version":"1.4.0","date":"2026-10-01","features":[],"minClientVersion":"1.2.0"}`)
+	})
+
+	required, err := client.RequiredClientVersion(context.Background(), "1.1.0")
+	if err != nil || required != "1.2.0" {
+		t.Errorf("got %q, %v, want 1.2.0", required, err)
+	}
+	required, err = client.RequiredClientVersion(context.Background(), "1.2.0")
+	if err != nil || required != "" {
+		t.Errorf("got %q, %v, want no required version", required, err)
+	}
+}
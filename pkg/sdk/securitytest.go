@@ -0,0 +1,52 @@
+package sdk
+
+import (
+	"context"
+
+	"github.com/huskyci-org/huskyCI/pkg/apiclient"
+)
+
+// Generic is the language the securityTests running on every repository are
+// listed under.
+const Generic = "Generic"
+
+// ListSecurityTests returns the securityTests an analysis may run on the
+// huskyCI API.
+func (c *Client) ListSecurityTests(ctx context.Context) ([]apiclient.SecurityTestView, error) {
+	var securityTests []apiclient.SecurityTestView
+	err := c.retry(ctx, func() error {
+		var err error
+		securityTests, err = c.API.ListSecurityTests(ctx)
+		return err
+	})
+	return securityTests, err
+}
+
+// DefaultSecurityTests returns the images, with their tags, of the default
+// securityTests an analysis of a repository written in languages runs, by
+// language. The Generic ones are listed under Generic.
+func DefaultSecurityTests(securityTests []apiclient.SecurityTestView, languages []string) map[string][]string {
+	found := map[string]bool{Generic: true}
+	for _, language := range languages {
+		found[language] = true
+	}
+	list := map[string][]string{}
+	for _, securityTest := range securityTests {
+		if !securityTest.Default {
+			continue
+		}
+		language := securityTest.Language
+		if securityTest.Type == Generic {
+			language = Generic
+		}
+		if !found[language] {
+			continue
+		}
+		image := securityTest.Image
+		if securityTest.ImageTag != "" {
+			image += ":" + securityTest.ImageTag
+		}
+		list[language] = append(list[language], image)
+	}
+	return list
+}
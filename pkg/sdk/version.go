@@ -0,0 +1,78 @@
+package sdk
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// UserAgent returns the User-Agent of the huskyCI component name at version,
+// such as huskyci-client/1.2.0. Builds without a version send name alone.
+func UserAgent(name, version string) string {
+	if version == "" {
+		return name
+	}
+	return name + "/" + version
+}
+
+// OlderVersion returns true if version is older than minimum. Versions are
+// compared as dot separated numbers, with an optional leading v. A version
+// that is not numbered, such as a development build, is never older.
+func OlderVersion(version, minimum string) bool {
+	current, ok := parseVersion(version)
+	if !ok {
+		return false
+	}
+	required, ok := parseVersion(minimum)
+	if !ok {
+		return false
+	}
+	for i := 0; i < len(current) || i < len(required); i++ {
+		var c, r int
+		if i < len(current) {
+			c = current[i]
+		}
+		if i < len(required) {
+			r = required[i]
+		}
+		if c != r {
+			return c < r
+		}
+	}
+	return false
+}
+
+// parseVersion returns the numbers of version, ignoring any pre-release or
+// build suffix.
+func parseVersion(version string) ([]int, bool) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+	if version == "" {
+		return nil, false
+	}
+	numbers := []int{}
+	for _, part := range strings.Split(version, ".") {
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, false
+		}
+		numbers = append(numbers, number)
+	}
+	return numbers, true
+}
+
+// RequiredClientVersion returns the oldest client version the huskyCI API
+// supports if version is older than it, or an empty string if version is
+// supported. It is not retried, as the check only leads to a warning.
+func (c *Client) RequiredClientVersion(ctx context.Context, version string) (string, error) {
+	info, err := c.API.GetAPIVersion(ctx)
+	if err != nil {
+		return "", wrap(ctx, err)
+	}
+	if OlderVersion(version, info.MinClientVersion) {
+		return info.MinClientVersion, nil
+	}
+	return "", nil
+}
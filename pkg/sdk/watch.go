@@ -0,0 +1,132 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/pkg/apiclient"
+	"golang.org/x/net/websocket"
+)
+
+// Types of the events pushed by the huskyCI API to the clients watching an
+// analysis.
+const (
+	EventStatus       = "status"
+	EventSecurityTest = "securitytest"
+	EventKeepAlive    = "keepalive"
+)
+
+// watchIdleTimeout is how long a watch may go without any event, keepalives
+// included, before the connection is considered lost.
+const watchIdleTimeout = 90 * time.Second
+
+// WatchAnalysis calls onEvent, if not nil, with the events the huskyCI API
+// pushes over the WebSocket of the analysis RID, keepalives aside, and returns
+// the status the analysis ended with. Failing to connect, as with an API older
+// than the WebSocket or a proxy blocking it, and losing the connection are
+// reported as ErrNetwork.
+func (c *Client) WatchAnalysis(ctx context.Context, RID string, onEvent func(apiclient.AnalysisEvent)) (string, error) {
+	conn, err := c.dialWatch(ctx, RID)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", fmt.Errorf("%w: %w", ErrNetwork, err)
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	for {
+		if err := conn.SetReadDeadline(time.Now().Add(watchIdleTimeout)); err != nil {
+			return "", fmt.Errorf("%w: %w", ErrNetwork, err)
+		}
+		event := apiclient.AnalysisEvent{}
+		if err := websocket.JSON.Receive(conn, &event); err != nil {
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+			return "", fmt.Errorf("%w: %w", ErrNetwork, err)
+		}
+		if event.Type == EventKeepAlive {
+			continue
+		}
+		if onEvent != nil {
+			onEvent(event)
+		}
+		if event.Type == EventStatus && event.Status != StatusRunning && event.Status != StatusQueued {
+			return event.Status, nil
+		}
+	}
+}
+
+// waitPushed waits for the analysis RID over its WebSocket and returns it once
+// over, with true. The WebSocket may be refused NotFoundRetries times,
+// PollInterval apart, as the analysis is created by the API in background. It
+// returns false when the analysis must be polled instead.
+func (c *Client) waitPushed(ctx context.Context, RID string, onPoll func(*apiclient.Analysis)) (*apiclient.Analysis, bool, error) {
+	for attempt := 0; ; attempt++ {
+		_, err := c.WatchAnalysis(ctx, RID, c.OnEvent)
+		dialErr := &websocket.DialError{}
+		switch {
+		case ctx.Err() != nil:
+			return nil, true, ctx.Err()
+		case errors.As(err, &dialErr) && errors.Is(dialErr.Err, websocket.ErrBadStatus) && attempt < c.NotFoundRetries:
+			select {
+			case <-ctx.Done():
+				return nil, true, ctx.Err()
+			case <-time.After(c.PollInterval):
+			}
+			continue
+		case err != nil:
+			return nil, false, nil
+		}
+
+		analysis, err := c.GetAnalysis(ctx, RID)
+		if err != nil {
+			return nil, false, nil
+		}
+		if onPoll != nil {
+			onPoll(analysis)
+		}
+		over, err := result(analysis)
+		return analysis, over, err
+	}
+}
+
+// dialWatch opens the WebSocket of the analysis RID, authenticated as the
+// requests of c.API are.
+func (c *Client) dialWatch(ctx context.Context, RID string) (*websocket.Conn, error) {
+	endpoint := strings.TrimSuffix(c.API.Endpoint, "/")
+	location, err := url.Parse(endpoint + "/ws/analysis/" + url.PathEscape(RID))
+	if err != nil {
+		return nil, err
+	}
+	switch location.Scheme {
+	case "https":
+		location.Scheme = "wss"
+	case "http":
+		location.Scheme = "ws"
+	}
+	config, err := websocket.NewConfig(location.String(), endpoint)
+	if err != nil {
+		return nil, err
+	}
+	config.Header.Set("Husky-Token", c.API.Token)
+	if c.API.UserAgent != "" {
+		config.Header.Set("User-Agent", c.API.UserAgent)
+	}
+	config.Dialer = &net.Dialer{Timeout: DefaultConnectTimeout}
+	if c.API.HTTPClient != nil {
+		if transport, ok := c.API.HTTPClient.Transport.(*http.Transport); ok && transport.TLSClientConfig != nil {
+			config.TlsConfig = transport.TLSClientConfig.Clone()
+		}
+	}
+	return config.DialContext(ctx)
+}
@@ -0,0 +1,99 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/huskyci-org/huskyCI/pkg/apiclient"
+	"golang.org/x/net/websocket"
+)
+
+// watchClient returns a Client of an API pushing events over the WebSocket of
+// the analysis abc, which it refuses refusals times first, and answering the
+// other requests with analysis.
+func watchClient(t *testing.T, refusals int32, events []apiclient.AnalysisEvent, analysis string) (*Client, *int32) {
+	var polls, dials int32
+	ws := websocket.Server{Handler: func(conn *websocket.Conn) {
+		if conn.Request().Header.Get("Husky-Token") != "token" {
+			t.Errorf("unexpected Husky-Token %q", conn.Request().Header.Get("Husky-Token"))
+		}
+		for _, event := range events {
+			if err := websocket.JSON.Send(conn, event); err != nil {
+				t.Error(err)
+			}
+		}
+		conn.Close()
+	}}
+	client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ws/analysis/abc" {
+			if atomic.AddInt32(&dials, 1) <= refusals {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			ws.ServeHTTP(w, r)
+			return
+		}
+		atomic.AddInt32(&polls, 1)
+		fmt.Fprint(w, analysis)
+	})
+	client.API.Token = "token"
+	client.WebSocket = true
+	return client, &polls
+}
+
+func TestWaitAnalysisOverWebSocket(t *testing.T) {
+	client, polls := watchClient(t, 1, []apiclient.AnalysisEvent{
+		{Type: EventStatus, RID: "abc", Status: StatusRunning},
+		{Type: EventKeepAlive, RID: "abc"},
+		{Type: EventSecurityTest, RID: "abc", SecurityTest: "gosec", CResult: "passed"},
+		{Type: EventStatus, RID: "abc", Status: StatusFinished, Result: "passed"},
+	}, `{"RID":"abc","status":"finished","result":"passed"}`)
+	received := []string{}
+	client.OnEvent = func(event apiclient.AnalysisEvent) {
+		received = append(received, event.Type+" "+event.Status+event.SecurityTest)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	analysis, err := client.WaitAnalysis(ctx, "abc", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if analysis.Result != "passed" || *polls != 1 {
+		t.Errorf("unexpected analysis %+v after %d requests", analysis, *polls)
+	}
+	want := []string{"status running", "securitytest gosec", "status finished"}
+	if fmt.Sprint(received) != fmt.Sprint(want) {
+		t.Errorf("got events %v, want %v", received, want)
+	}
+}
+
+func TestWaitAnalysisFallsBackToPolling(t *testing.T) {
+	client, polls := watchClient(t, 100, nil, `{"RID":"abc","status":"cancelled"}`)
+
+	_, err := client.WaitAnalysis(context.Background(), "abc", nil)
+	if err != ErrCancelled {
+		t.Fatalf("got %v, want %v", err, ErrCancelled)
+	}
+	if *polls != 1 {
+		t.Errorf("polled %d times", *polls)
+	}
+}
+
+func TestWaitAnalysisWebSocketLost(t *testing.T) {
+	client, polls := watchClient(t, 0, []apiclient.AnalysisEvent{
+		{Type: EventStatus, RID: "abc", Status: StatusRunning},
+	}, `{"RID":"abc","status":"finished","result":"passed"}`)
+
+	analysis, err := client.WaitAnalysis(context.Background(), "abc", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if analysis.Result != "passed" || *polls != 1 {
+		t.Errorf("unexpected analysis %+v after %d polls", analysis, *polls)
+	}
+}
@@ -0,0 +1,70 @@
+package securitytest
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// BanditOutput is the struct that holds all data from Bandit output.
+type BanditOutput struct {
+	Results []BanditResult `json:"results"`
+}
+
+// BanditResult is the struct that holds detailed information of issues from Bandit output.
+type BanditResult struct {
+	Code            string `json:"code"`
+	Filename        string `json:"filename"`
+	IssueConfidence string `json:"issue_confidence"`
+	IssueSeverity   string `json:"issue_severity"`
+	IssueText       string `json:"issue_text"`
+	LineNumber      int    `json:"line_number"`
+	LineRange       []int  `json:"line_range"`
+	TestID          string `json:"test_id"`
+	TestName        string `json:"test_name"`
+}
+
+// ParseBandit returns the findings of the output of Bandit. Findings whose
+// line is marked with #nohusky are NoSecVulns.
+func ParseBandit(output string) (Output, error) {
+	banditOutput := BanditOutput{}
+	document, _ := ExtractJSON(output)
+	if err := json.Unmarshal([]byte(document), &banditOutput); err != nil {
+		return Output{}, err
+	}
+
+	results := Output{}
+	for _, issue := range banditOutput.Results {
+		banditVuln := Vulnerability{
+			Language:     "Python",
+			SecurityTool: "Bandit",
+			Rule:         issue.TestID,
+			Severity:     issue.IssueSeverity,
+			Confidence:   issue.IssueConfidence,
+			Title:        issue.IssueText,
+			Details:      issue.IssueText,
+			File:         issue.Filename,
+			Line:         strconv.Itoa(issue.LineNumber),
+			Code:         issue.Code,
+		}
+		if NoHusky(issue.Code, issue.LineNumber) {
+			banditVuln.Severity = "NOSEC"
+			results.NoSecVulns = append(results.NoSecVulns, banditVuln)
+			continue
+		}
+		results.add(banditVuln)
+	}
+	return results, nil
+}
+
+// NoHusky returns true if the line lineNumber of code, whose lines start with
+// their number as in the output of Bandit, is marked with #nohusky.
+func NoHusky(code string, lineNumber int) bool {
+	number := strconv.Itoa(lineNumber)
+	for _, codeLine := range strings.Split(code, "\n") {
+		if strings.HasPrefix(codeLine, number) && strings.Contains(codeLine, "#nohusky") {
+			return true
+		}
+	}
+	return false
+}
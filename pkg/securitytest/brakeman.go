@@ -0,0 +1,65 @@
+package securitytest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// BrakemanOutput is the struct that holds issues and stats found on a Brakeman scan.
+type BrakemanOutput struct {
+	Warnings        []WarningItem `json:"warnings"`
+	IgnoredWarnings []WarningItem `json:"ignored_warnings"`
+}
+
+// WarningItem is the struct that holds all detailed information of a vulnerability found.
+type WarningItem struct {
+	Type       string `json:"warning_type"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	File       string `json:"file"`
+	Line       int    `json:"line"`
+	Details    string `json:"link"`
+	Confidence string `json:"confidence"`
+}
+
+// ParseBrakeman returns the findings of the output of Brakeman, whose
+// severity is their confidence. An empty output states that no issues were
+// found, and the ignored warnings are NoSecVulns.
+func ParseBrakeman(output string) (Output, error) {
+	results := Output{}
+	if output == "" {
+		return results, nil
+	}
+	brakemanOutput := BrakemanOutput{}
+	document, _ := ExtractJSON(output)
+	if err := json.Unmarshal([]byte(document), &brakemanOutput); err != nil {
+		return results, err
+	}
+
+	for _, warning := range brakemanOutput.Warnings {
+		brakemanVuln := brakemanVulnerability(warning)
+		brakemanVuln.Severity = warning.Confidence
+		results.add(brakemanVuln)
+	}
+	for _, ignoredWarning := range brakemanOutput.IgnoredWarnings {
+		brakemanVuln := brakemanVulnerability(ignoredWarning)
+		brakemanVuln.Severity = "NOSEC"
+		results.NoSecVulns = append(results.NoSecVulns, brakemanVuln)
+	}
+	return results, nil
+}
+
+func brakemanVulnerability(warning WarningItem) Vulnerability {
+	return Vulnerability{
+		Language:     "Ruby",
+		SecurityTool: "Brakeman",
+		Confidence:   warning.Confidence,
+		Title:        fmt.Sprintf("Vulnerable Dependency: %s %s", warning.Type, warning.Message),
+		Details:      warning.Details,
+		File:         warning.File,
+		Line:         strconv.Itoa(warning.Line),
+		Code:         warning.Code,
+		Type:         warning.Type,
+	}
+}
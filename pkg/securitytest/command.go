@@ -0,0 +1,86 @@
+package securitytest
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// UploadRepository is the repository the code of an upload analysis is
+// committed to inside securityTest containers.
+const UploadRepository = "/tmp/huskyci-upload"
+
+// Workspace is where securityTest containers find the code that was not
+// cloned, such as uploaded code or the working directory of the CLI.
+const Workspace = "/workspace"
+
+// cloneLine matches the lines of a securityTest command that clone the
+// repository into code, along with the file their errors are written to.
+var cloneLine = regexp.MustCompile(`(?m)^([^\n]*git clone [^\n]* code --quiet)(?: 2> (\S+))?[ \t]*$`)
+
+// Checkout makes cmd check out commit right after it clones the repository,
+// so that the code scanned is the one of commit even if its branch moved
+// since. A commit missing from the clone, shallow or of a single branch, is
+// fetched first. The clone fails if commit cannot be checked out, and cmd is
+// returned untouched without a commit.
+func Checkout(commit, cmd string) string {
+	if commit == "" {
+		return cmd
+	}
+	return afterClone(cmd, fmt.Sprintf("{ git -C code checkout --quiet --detach %[1]s 2> /dev/null || { GIT_TERMINAL_PROMPT=0 git -C code fetch --quiet --depth 1 origin %[1]s && git -C code checkout --quiet --detach %[1]s; }; }", commit))
+}
+
+// Merge makes cmd scan the merge of a pull request into targetBranch right
+// after it clones the repository: mergeRef, where the host keeps the merge,
+// is fetched and checked out, or else targetBranch is merged into the branch
+// cloned, which is unshallowed first. The clone fails if the merge cannot be
+// made, on conflicts for instance, and cmd is returned untouched without a
+// target branch.
+func Merge(mergeRef, targetBranch, cmd string) string {
+	if targetBranch == "" {
+		return cmd
+	}
+	if mergeRef != "" {
+		return afterClone(cmd, fmt.Sprintf("{ GIT_TERMINAL_PROMPT=0 git -C code fetch --quiet origin %s && git -C code checkout --quiet --detach FETCH_HEAD; }", mergeRef))
+	}
+	unshallow := "{ [ \"$(git -C code rev-parse --is-shallow-repository)\" != true ] || GIT_TERMINAL_PROMPT=0 git -C code fetch --quiet --unshallow; }"
+	merge := fmt.Sprintf("GIT_TERMINAL_PROMPT=0 git -C code fetch --quiet origin %s && git -c user.name=huskyCI -c user.email=huskyci@localhost -C code merge --quiet --no-edit FETCH_HEAD", targetBranch)
+	return afterClone(cmd, "{ "+unshallow+" && "+merge+"; }")
+}
+
+// afterClone makes cmd run step right after each of its clones of the
+// repository, failing the clone if step fails. The errors of step are written
+// along with those of the clone.
+func afterClone(cmd, step string) string {
+	return cloneLine.ReplaceAllStringFunc(cmd, func(line string) string {
+		match := cloneLine.FindStringSubmatch(line)
+		if match[2] == "" {
+			return match[1] + " && " + step
+		}
+		return fmt.Sprintf("%s 2> %s && %s 2>> %s", match[1], match[2], step, match[2])
+	})
+}
+
+// Command will replace %GIT_REPO% and %GIT_BRANCH% in cmd with repositoryURL
+// and repositoryBranch. It returns an empty string if any of them is empty.
+func Command(repositoryURL, repositoryBranch, cmd string) string {
+	if repositoryURL == "" || repositoryBranch == "" || cmd == "" {
+		return ""
+	}
+	cmd = strings.Replace(cmd, "%GIT_REPO%", repositoryURL, -1)
+	return strings.Replace(cmd, "%GIT_BRANCH%", repositoryBranch, -1)
+}
+
+// UploadCommand makes cmd scan the code mounted at Workspace. The code is
+// first committed on repositoryBranch to UploadRepository, which cmd then
+// clones as it would clone any repository.
+func UploadCommand(repositoryBranch, cmd string) string {
+	if repositoryBranch == "" || cmd == "" {
+		return ""
+	}
+	git := fmt.Sprintf("git -c safe.directory='*' -c user.name=huskyCI -c user.email=huskyci@localhost -C %s --work-tree=%s", UploadRepository, Workspace)
+	commit := fmt.Sprintf("{ git init --quiet %s && %s symbolic-ref HEAD refs/heads/%s && %s add --all && %s commit --quiet --allow-empty -m upload; } > /dev/null 2>&1\n",
+		UploadRepository, git, repositoryBranch, git, git)
+	cmd = strings.Replace(cmd, "%GIT_CLONE_OPTIONS%", "", -1)
+	return commit + Command(UploadRepository, repositoryBranch, cmd)
+}
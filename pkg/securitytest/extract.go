@@ -0,0 +1,83 @@
+package securitytest
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// maxJSONCandidates bounds how many starts of a JSON document ExtractJSON
+// tries, so that a large output with no document is not decoded over and
+// over.
+const maxJSONCandidates = 100
+
+// ExtractJSON returns the JSON document printed by a securityTest among other
+// lines, such as the warnings and the progress of its tool, and these lines.
+// The document is the largest object or array starting a line, or anywhere
+// if none does, the last one when several are as large, as tools print their
+// results last. Without a document, output is returned trimmed, so that its
+// parser reports the error.
+func ExtractJSON(output string) (string, []string) {
+	start, end := findJSON(output, true)
+	if start < 0 {
+		start, end = findJSON(output, false)
+	}
+	if start < 0 {
+		return strings.TrimSpace(output), nil
+	}
+	lines := append(nonEmptyLines(output[:start]), nonEmptyLines(output[end:])...)
+	return output[start:end], lines
+}
+
+// findJSON returns the bounds of the largest JSON document of output, only
+// trying the ones starting a line if lineStart is true, or -1.
+func findJSON(output string, lineStart bool) (int, int) {
+	bestStart, bestEnd := -1, -1
+	tries := 0
+	for i := 0; i < len(output) && tries < maxJSONCandidates; i++ {
+		if output[i] != '{' && output[i] != '[' {
+			continue
+		}
+		if lineStart && !startsLine(output, i) {
+			continue
+		}
+		tries++
+		decoder := json.NewDecoder(strings.NewReader(output[i:]))
+		var document json.RawMessage
+		if err := decoder.Decode(&document); err != nil {
+			continue
+		}
+		end := i + int(decoder.InputOffset())
+		if end-i >= bestEnd-bestStart {
+			bestStart, bestEnd = i, end
+		}
+		// the documents nested in this one are smaller
+		i = end - 1
+	}
+	return bestStart, bestEnd
+}
+
+// startsLine returns true if only spaces are found between the start of the
+// line of output[i] and i.
+func startsLine(output string, i int) bool {
+	for j := i - 1; j >= 0; j-- {
+		switch output[j] {
+		case '\n':
+			return true
+		case ' ', '\t', '\r':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// nonEmptyLines returns the lines of s that are not blank, trimmed.
+func nonEmptyLines(s string) []string {
+	lines := []string{}
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
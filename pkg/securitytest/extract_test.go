@@ -0,0 +1,138 @@
+package securitytest
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExtractJSON(t *testing.T) {
+	tests := []struct {
+		name      string
+		output    string
+		prefix    string
+		lines     []string
+		wantValid bool
+	}{
+		{
+			name:      "safety warnings",
+			output:    testdata(t, "safety.txt"),
+			prefix:    `{"issues": [{"dependency": "jinja2"`,
+			lines:     []string{"Warning: unpinned requirement 'flask' found in safety_huskyci_analysis_requirements_raw.txt, unable to check.", "Warning: unpinned requirement 'requests' found in safety_huskyci_analysis_requirements_raw.txt, unable to check."},
+			wantValid: true,
+		},
+		{
+			name:      "trivy logs",
+			output:    testdata(t, "trivy.txt"),
+			prefix:    "{\n  \"SchemaVersion\": 2",
+			lines:     []string{"2024-05-02T10:00:00.000Z\tINFO\tNeed to update DB", "2024-05-02T10:00:00.000Z\tINFO\tDownloading DB...", "2024-05-02T10:00:03.512Z\tINFO\tVulnerability scanning is enabled", "2024-05-02T10:00:03.513Z\tINFO\tNumber of language-specific files: 1"},
+			wantValid: true,
+		},
+		{
+			name:      "gitleaks logs around the results",
+			output:    testdata(t, "gitleaks.txt"),
+			prefix:    `[{"line":"aws_secret_access_key`,
+			lines:     []string{"INFO[2024-05-02T10:00:00Z] opening /workspace/code", "INFO[2024-05-02T10:00:01Z] scanning 12 commits", "WARN[2024-05-02T10:00:02Z] 1 leaks detected. 12 commits scanned in 1.2s"},
+			wantValid: true,
+		},
+		{
+			name:      "gosec progress and braces in strings",
+			output:    testdata(t, "gosec.txt"),
+			prefix:    "{\n\t\"Golang errors\": {}",
+			lines:     []string{"[gosec] 2024/05/02 10:00:00 Including rules: default", "[gosec] 2024/05/02 10:00:00 Excluding rules: default", "[gosec] 2024/05/02 10:00:00 Import directory: /workspace/code/cmd", "[gosec] 2024/05/02 10:00:01 Checking package: main", "[gosec] 2024/05/02 10:00:01 Checking file: /workspace/code/cmd/main.go"},
+			wantValid: true,
+		},
+		{
+			name:      "smaller JSON log line first",
+			output:    testdata(t, "brakeman.txt"),
+			prefix:    `{"scan_info":`,
+			lines:     []string{`{"level":"info","msg":"loading the configuration"}`, "Loading scanner...", "Parsing files...", "Processing application...", "Running checks in parallel...", "- CheckSQL", "- CheckRender"},
+			wantValid: true,
+		},
+		{
+			name:      "brackets in a log line",
+			output:    "[WARN] could not reach the update server\n{\"results\":[]}\n",
+			prefix:    `{"results":[]}`,
+			lines:     []string{"[WARN] could not reach the update server"},
+			wantValid: true,
+		},
+		{
+			name:      "document within a line",
+			output:    "results: {\"results\":[]} done",
+			prefix:    `{"results":[]}`,
+			lines:     []string{"results:", "done"},
+			wantValid: true,
+		},
+		{
+			name:   "no JSON",
+			output: "\nError: unable to read the requirements\n",
+			prefix: "Error: unable to read the requirements",
+		},
+		{
+			name:   "truncated JSON",
+			output: "{\"results\":[{\"code\":\"x\"",
+			prefix: "{\"results\":[{\"code\":\"x\"",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			document, lines := ExtractJSON(test.output)
+			if !strings.HasPrefix(document, test.prefix) {
+				t.Errorf("unexpected document %q", document)
+			}
+			if json.Valid([]byte(document)) != test.wantValid {
+				t.Errorf("document %q valid: %v", document, !test.wantValid)
+			}
+			if strings.Join(lines, "\n") != strings.Join(test.lines, "\n") {
+				t.Errorf("unexpected lines %q", lines)
+			}
+		})
+	}
+}
+
+func TestParseInterleavedOutput(t *testing.T) {
+	results, err := Parse("gosec", testdata(t, "gosec.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results.MediumVulns) != 1 || results.MediumVulns[0].Line != "17" {
+		t.Errorf("unexpected gosec results %+v", results)
+	}
+
+	results, err = Parse("trivy", testdata(t, "trivy.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results.HighVulns) != 1 {
+		t.Errorf("unexpected trivy results %+v", results)
+	}
+
+	results, err = Parse("gitleaks", testdata(t, "gitleaks.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results.HighVulns)+len(results.MediumVulns)+len(results.LowVulns) != 1 {
+		t.Errorf("unexpected gitleaks results %+v", results)
+	}
+
+	results, err = Parse("brakeman", testdata(t, "brakeman.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results.HighVulns) != 1 {
+		t.Errorf("unexpected brakeman results %+v", results)
+	}
+}
+
+// testdata returns the content of the captured output name.
+func testdata(t *testing.T, name string) string {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
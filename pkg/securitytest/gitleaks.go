@@ -0,0 +1,109 @@
+package securitytest
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// GitleaksOutput is the struct that holds all data from Gitleaks output.
+type GitleaksOutput []GitLeaksIssue
+
+// GitLeaksIssue is the struct that holds all isssues from Gitleaks output.
+type GitLeaksIssue struct {
+	Line          string `json:"line"`
+	Commit        string `json:"commit"`
+	Offender      string `json:"offender"`
+	Rule          string `json:"rule"`
+	Info          string `json:"info"`
+	CommitMessage string `json:"commitMsg"`
+	Author        string `json:"author"`
+	Email         string `json:"email"`
+	File          string `json:"file"`
+	Repository    string `json:"repo"`
+	Date          string `json:"date"`
+	Tags          string `json:"tags"`
+	Severity      string `json:"severity"`
+}
+
+// gitleaksHighRules and gitleaksMediumRules are the rules of the secrets
+// found by Gitleaks reported as high and medium findings. The findings of any
+// other rule are low.
+var (
+	gitleaksHighRules   = []string{"PKCS8", "RSA", "SSH", "PGP", "EC"}
+	gitleaksMediumRules = []string{"AWS Secret Key", "Facebook Secret Key", "Facebook access token", "Twitter Secret Key", "LinkedIn Secret Key", "Google OAuth access token", "Google Cloud Platform API key", "Heroku API key", "MailChimp API key", "Mailgun API key", "PayPal Braintree access token", "Picatic API key", "Stripe API key", "Twilio API key"}
+)
+
+// ParseGitleaks returns the findings of the output of Gitleaks. An empty
+// output states that no secrets were found. Gitleaks timing out or failing is
+// reported as a low finding, and the secrets of dependencies are left out.
+func ParseGitleaks(output string) (Output, error) {
+	results := Output{}
+	if output == "" {
+		return results, nil
+	}
+
+	if strings.Contains(output, "ERROR_TIMEOUT_GITLEAKS") {
+		results.LowVulns = append(results.LowVulns, Vulnerability{
+			Language:     "Generic",
+			SecurityTool: "Gitleaks",
+			Severity:     "low",
+			Title:        "Too big project for Gitleaks scan",
+			Details:      "It looks like your project is too big and huskyCI was not able to run Gitleaks.",
+		})
+		return results, nil
+	}
+
+	if strings.Contains(output, "ERROR_RUNNING_GITLEAKS") {
+		results.LowVulns = append(results.LowVulns, Vulnerability{
+			Language:     "Generic",
+			SecurityTool: "Gitleaks",
+			Severity:     "low",
+			Title:        "Gitleaks internal error",
+			Details:      "Internal error running Gitleaks.",
+		})
+		return results, nil
+	}
+
+	gitleaksOutput := GitleaksOutput{}
+	document, _ := ExtractJSON(output)
+	if err := json.Unmarshal([]byte(document), &gitleaksOutput); err != nil {
+		return results, err
+	}
+
+	for _, issue := range gitleaksOutput {
+		// dependencies issues will not checked at this moment by huskyCI
+		if strings.Contains(issue.File, "vendor/") || strings.Contains(issue.File, "node_modules/") {
+			continue
+		}
+
+		gitleaksVuln := Vulnerability{
+			SecurityTool: "GitLeaks",
+			Rule:         issue.Rule,
+			Title:        "Hard Coded " + issue.Rule + " in: " + issue.File,
+			File:         issue.File,
+			Code:         issue.Line,
+			// gitleaks already knows the commit that introduced the secret
+			Commit:      issue.Commit,
+			Author:      issue.Author,
+			AuthorEmail: issue.Email,
+			Severity:    "LOW",
+		}
+		switch {
+		case contains(gitleaksHighRules, issue.Rule):
+			gitleaksVuln.Severity = "HIGH"
+		case contains(gitleaksMediumRules, issue.Rule):
+			gitleaksVuln.Severity = "MEDIUM"
+		}
+		results.add(gitleaksVuln)
+	}
+	return results, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package securitytest
+
+import "encoding/json"
+
+// GosecOutput is the struct that holds all data from Gosec output.
+type GosecOutput struct {
+	GosecIssues []GosecIssue `json:"Issues"`
+	GosecStats  GosecStats   `json:"Stats"`
+}
+
+// GosecIssue is the struct that holds all issues from Gosec output.
+type GosecIssue struct {
+	Severity   string `json:"severity"`
+	Confidence string `json:"confidence"`
+	RuleID     string `json:"rule_id"`
+	Details    string `json:"details"`
+	File       string `json:"file"`
+	Code       string `json:"code"`
+	Line       string `json:"line"`
+}
+
+// GosecStats is the struct that holds all stats from Gosec output.
+type GosecStats struct {
+	Files int `json:"files"`
+	Lines int `json:"lines"`
+	Nosec int `json:"nosec"`
+	Found int `json:"found"`
+}
+
+// ParseGosec returns the findings of the output of Gosec. An empty output
+// states that no issues were found. Gosec only counts the issues marked with
+// #nosec, so each of them is an empty NoSecVuln.
+func ParseGosec(output string) (Output, error) {
+	results := Output{}
+	if output == "" {
+		return results, nil
+	}
+	gosecOutput := GosecOutput{}
+	document, _ := ExtractJSON(output)
+	if err := json.Unmarshal([]byte(document), &gosecOutput); err != nil {
+		return results, err
+	}
+
+	for _, issue := range gosecOutput.GosecIssues {
+		results.add(Vulnerability{
+			Language:     "Go",
+			SecurityTool: "GoSec",
+			Rule:         issue.RuleID,
+			Title:        issue.Details,
+			Severity:     issue.Severity,
+			Confidence:   issue.Confidence,
+			Details:      issue.Details,
+			File:         issue.File,
+			Line:         issue.Line,
+			Code:         issue.Code,
+		})
+	}
+	for i := 0; i < gosecOutput.GosecStats.Nosec; i++ {
+		results.NoSecVulns = append(results.NoSecVulns, Vulnerability{})
+	}
+	return results, nil
+}
@@ -0,0 +1,110 @@
+// Package securitytest holds what the huskyCI API and the CLI share to run
+// securityTests: the placeholders of their command templates and the parsers
+// of their outputs. The API runs them on analyses and the CLI runs them on
+// the working directory with huskyci run --local.
+package securitytest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrorCloning is printed by a securityTest container that could not clone
+// the code to scan.
+const ErrorCloning = "ERROR_CLONING"
+
+// toolConfigPrefix starts the line a securityTest prints first when it runs
+// with configuration files of the repository, such as a .bandit or a
+// config/brakeman.ignore, followed by their paths.
+const toolConfigPrefix = "HUSKYCI_TOOL_CONFIG="
+
+// Vulnerability is a finding of a securityTest. Rule is the ID of the check
+// of the securityTest that found it, such as G107 or a CVE, if it has one.
+type Vulnerability struct {
+	Language     string
+	SecurityTool string
+	Rule         string
+	Severity     string
+	Confidence   string
+	File         string
+	Line         string
+	Code         string
+	Details      string
+	Type         string
+	Title        string
+	Commit       string
+	Author       string
+	AuthorEmail  string
+}
+
+// Output holds the findings of a securityTest by severity. NoSecVulns are the
+// findings the code marked as accepted, such as with #nohusky.
+type Output struct {
+	NoSecVulns  []Vulnerability
+	LowVulns    []Vulnerability
+	MediumVulns []Vulnerability
+	HighVulns   []Vulnerability
+}
+
+// Parser returns the findings of a securityTest from the output of its
+// container.
+type Parser func(output string) (Output, error)
+
+// Parsers are the parsers of the securityTests whose output can be parsed
+// outside of the huskyCI API, by securityTest name.
+var Parsers = map[string]Parser{
+	"bandit":      ParseBandit,
+	"brakeman":    ParseBrakeman,
+	"gitleaks":    ParseGitleaks,
+	"gosec":       ParseGosec,
+	"trivy":       ParseTrivy,
+	"trivyconfig": ParseTrivyConfig,
+}
+
+// Parse returns the findings of the securityTest name from the output of its
+// container.
+func Parse(name, output string) (Output, error) {
+	parser, ok := Parsers[name]
+	if !ok {
+		return Output{}, fmt.Errorf("no parser for securityTest %s", name)
+	}
+	_, output = ToolConfigs(output)
+	return parser(output)
+}
+
+// Names returns the names of the securityTests that have a parser, sorted.
+func Names() []string {
+	names := make([]string, 0, len(Parsers))
+	for name := range Parsers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ToolConfigs returns the configuration files of the repository a
+// securityTest applied, and its output without the line listing them.
+func ToolConfigs(output string) ([]string, string) {
+	if !strings.HasPrefix(output, toolConfigPrefix) {
+		return nil, output
+	}
+	line, rest := output, ""
+	if i := strings.Index(line, "\n"); i >= 0 {
+		line, rest = line[:i], line[i+1:]
+	}
+	return strings.Fields(strings.TrimPrefix(line, toolConfigPrefix)), rest
+}
+
+// add appends vuln to the findings of its severity. Findings of any other
+// severity are dropped.
+func (o *Output) add(vuln Vulnerability) {
+	switch strings.ToUpper(vuln.Severity) {
+	case "LOW":
+		o.LowVulns = append(o.LowVulns, vuln)
+	case "MEDIUM":
+		o.MediumVulns = append(o.MediumVulns, vuln)
+	case "HIGH":
+		o.HighVulns = append(o.HighVulns, vuln)
+	}
+}
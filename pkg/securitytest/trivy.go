@@ -0,0 +1,44 @@
+package securitytest
+
+import "encoding/json"
+
+// TrivyOutput represents the output structure from a Trivy security scan.
+type TrivyOutput struct {
+	Results []TrivyResult `json:"Results"`
+}
+
+// TrivyResult represents a single scan result from Trivy, containing target information and vulnerabilities.
+type TrivyResult struct {
+	Target          string `json:"Target"`
+	Vulnerabilities []struct {
+		VulnerabilityID string `json:"VulnerabilityID"`
+		PkgName         string `json:"PkgName"`
+		Severity        string `json:"Severity"`
+		Description     string `json:"Description"`
+	} `json:"Vulnerabilities"`
+}
+
+// ParseTrivy returns the vulnerable dependencies found by Trivy.
+func ParseTrivy(output string) (Output, error) {
+	results := Output{}
+	trivyOutput := TrivyOutput{}
+	document, _ := ExtractJSON(output)
+	if err := json.Unmarshal([]byte(document), &trivyOutput); err != nil {
+		return results, err
+	}
+
+	for _, result := range trivyOutput.Results {
+		for _, vuln := range result.Vulnerabilities {
+			results.add(Vulnerability{
+				Language:     "generic",
+				SecurityTool: "Trivy",
+				Rule:         vuln.VulnerabilityID,
+				Severity:     vuln.Severity,
+				Title:        vuln.VulnerabilityID,
+				Details:      vuln.Description,
+				File:         result.Target,
+			})
+		}
+	}
+	return results, nil
+}
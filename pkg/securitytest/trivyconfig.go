@@ -0,0 +1,139 @@
+package securitytest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// trivyConfigErrorRunning is printed by the trivyconfig container, followed by
+// the errors of trivy, when trivy could not scan the repository.
+const trivyConfigErrorRunning = "ERROR_RUNNING_TRIVY_CONFIG"
+
+// TrivyConfigOutput is the struct that holds all data from the output of
+// trivy config.
+type TrivyConfigOutput struct {
+	Results []TrivyConfigResult `json:"Results"`
+}
+
+// TrivyConfigResult is the struct that holds the misconfigurations trivy
+// config found in a file. Type is the kind of the file, such as terraform,
+// cloudformation, kubernetes or helm.
+type TrivyConfigResult struct {
+	Target            string                  `json:"Target"`
+	Type              string                  `json:"Type"`
+	Misconfigurations []TrivyMisconfiguration `json:"Misconfigurations"`
+}
+
+// TrivyMisconfiguration is the struct that holds detailed information of a
+// misconfiguration found by trivy config.
+type TrivyMisconfiguration struct {
+	ID            string             `json:"ID"`
+	Title         string             `json:"Title"`
+	Message       string             `json:"Message"`
+	Resolution    string             `json:"Resolution"`
+	Severity      string             `json:"Severity"`
+	PrimaryURL    string             `json:"PrimaryURL"`
+	Status        string             `json:"Status"`
+	CauseMetadata TrivyCauseMetadata `json:"CauseMetadata"`
+}
+
+// TrivyCauseMetadata is the struct that holds the lines causing a
+// misconfiguration.
+type TrivyCauseMetadata struct {
+	StartLine int `json:"StartLine"`
+	EndLine   int `json:"EndLine"`
+	Code      struct {
+		Lines []struct {
+			Number  int    `json:"Number"`
+			Content string `json:"Content"`
+		} `json:"Lines"`
+	} `json:"Code"`
+}
+
+// trivyConfigLanguages are the languages the findings of trivy config are
+// stored with, by the type of their file.
+var trivyConfigLanguages = map[string]string{
+	"terraform":      "HCL",
+	"terraformplan":  "HCL",
+	"cloudformation": "CloudFormation",
+	"kubernetes":     "Kubernetes",
+	"helm":           "Helm",
+	"dockerfile":     "Dockerfile",
+	"azure-arm":      "AzureARM",
+}
+
+// ParseTrivyConfig returns the failed checks of the output of trivy config.
+// Trivy failing is reported as a low finding, so that the findings of the
+// other securityTests are kept.
+func ParseTrivyConfig(output string) (Output, error) {
+	results := Output{}
+	if strings.HasPrefix(output, trivyConfigErrorRunning) {
+		results.LowVulns = append(results.LowVulns, Vulnerability{
+			Language:     "IaC",
+			SecurityTool: "TrivyConfig",
+			Severity:     "Low",
+			Title:        "Error running trivy config.",
+			Details:      strings.TrimSpace(strings.TrimPrefix(output, trivyConfigErrorRunning)),
+		})
+		return results, nil
+	}
+
+	trivyConfigOutput := TrivyConfigOutput{}
+	document, _ := ExtractJSON(output)
+	if err := json.Unmarshal([]byte(document), &trivyConfigOutput); err != nil {
+		return results, err
+	}
+
+	for _, result := range trivyConfigOutput.Results {
+		language, ok := trivyConfigLanguages[result.Type]
+		if !ok {
+			language = result.Type
+		}
+		for _, misconfiguration := range result.Misconfigurations {
+			if misconfiguration.Status != "" && misconfiguration.Status != "FAIL" {
+				continue
+			}
+			trivyConfigVuln := Vulnerability{
+				Language:     language,
+				SecurityTool: "TrivyConfig",
+				Rule:         misconfiguration.ID,
+				Title:        misconfiguration.Title,
+				Details:      misconfiguration.ID + " @ [" + misconfiguration.Message + "]",
+				File:         result.Target,
+				Code:         trivyCauseCode(misconfiguration.CauseMetadata),
+			}
+			if misconfiguration.Resolution != "" {
+				trivyConfigVuln.Details += "\n" + misconfiguration.Resolution
+			}
+			if misconfiguration.PrimaryURL != "" {
+				trivyConfigVuln.Details += "\n" + misconfiguration.PrimaryURL
+			}
+			if misconfiguration.CauseMetadata.StartLine > 0 {
+				trivyConfigVuln.Line = strconv.Itoa(misconfiguration.CauseMetadata.StartLine)
+			}
+
+			switch misconfiguration.Severity {
+			case "UNKNOWN", "LOW":
+				trivyConfigVuln.Severity = "Low"
+			case "MEDIUM":
+				trivyConfigVuln.Severity = "Medium"
+			case "HIGH", "CRITICAL":
+				trivyConfigVuln.Severity = "High"
+			}
+			results.add(trivyConfigVuln)
+		}
+	}
+	return results, nil
+}
+
+// trivyCauseCode returns the lines causing a misconfiguration, each prefixed
+// by its number.
+func trivyCauseCode(cause TrivyCauseMetadata) string {
+	lines := []string{}
+	for _, line := range cause.Code.Lines {
+		lines = append(lines, fmt.Sprintf("%d: %s", line.Number, line.Content))
+	}
+	return strings.Join(lines, "\n")
+}